@@ -7,16 +7,21 @@ type Resource struct {
 	ResourcePatternType AclResourcePatternType
 }
 
-func (r *Resource) encode(pe packetEncoder, version int16) error {
+func (r *Resource) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt8(int8(r.ResourceType))
 
-	if err := pe.putString(r.ResourceName); err != nil {
+	if version >= 2 {
+		err = pe.putCompactString(r.ResourceName)
+	} else {
+		err = pe.putString(r.ResourceName)
+	}
+	if err != nil {
 		return err
 	}
 
-	if version == 1 {
+	if version >= 1 {
 		if r.ResourcePatternType == AclPatternUnknown {
-			Logger.Print("Cannot encode an unknown resource pattern type, using Literal instead")
+			warnf("Cannot encode an unknown resource pattern type, using Literal instead")
 			r.ResourcePatternType = AclPatternLiteral
 		}
 		pe.putInt8(int8(r.ResourcePatternType))
@@ -32,10 +37,15 @@ func (r *Resource) decode(pd packetDecoder, version int16) (err error) {
 	}
 	r.ResourceType = AclResourceType(resourceType)
 
-	if r.ResourceName, err = pd.getString(); err != nil {
+	if version >= 2 {
+		r.ResourceName, err = pd.getCompactString()
+	} else {
+		r.ResourceName, err = pd.getString()
+	}
+	if err != nil {
 		return err
 	}
-	if version == 1 {
+	if version >= 1 {
 		pattern, err := pd.getInt8()
 		if err != nil {
 			return err
@@ -54,12 +64,22 @@ type Acl struct {
 	PermissionType AclPermissionType
 }
 
-func (a *Acl) encode(pe packetEncoder) error {
-	if err := pe.putString(a.Principal); err != nil {
+func (a *Acl) encode(pe packetEncoder, version int16) (err error) {
+	if version >= 2 {
+		err = pe.putCompactString(a.Principal)
+	} else {
+		err = pe.putString(a.Principal)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := pe.putString(a.Host); err != nil {
+	if version >= 2 {
+		err = pe.putCompactString(a.Host)
+	} else {
+		err = pe.putString(a.Host)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -70,11 +90,21 @@ func (a *Acl) encode(pe packetEncoder) error {
 }
 
 func (a *Acl) decode(pd packetDecoder, version int16) (err error) {
-	if a.Principal, err = pd.getString(); err != nil {
+	if version >= 2 {
+		a.Principal, err = pd.getCompactString()
+	} else {
+		a.Principal, err = pd.getString()
+	}
+	if err != nil {
 		return err
 	}
 
-	if a.Host, err = pd.getString(); err != nil {
+	if version >= 2 {
+		a.Host, err = pd.getCompactString()
+	} else {
+		a.Host, err = pd.getString()
+	}
+	if err != nil {
 		return err
 	}
 
@@ -104,24 +134,38 @@ func (r *ResourceAcls) encode(pe packetEncoder, version int16) error {
 		return err
 	}
 
-	if err := pe.putArrayLength(len(r.Acls)); err != nil {
+	if version >= 2 {
+		pe.putCompactArrayLength(len(r.Acls))
+	} else if err := pe.putArrayLength(len(r.Acls)); err != nil {
 		return err
 	}
 	for _, acl := range r.Acls {
-		if err := acl.encode(pe); err != nil {
+		if err := acl.encode(pe, version); err != nil {
 			return err
 		}
+		if version >= 2 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
 }
 
-func (r *ResourceAcls) decode(pd packetDecoder, version int16) error {
+func (r *ResourceAcls) decode(pd packetDecoder, version int16) (err error) {
 	if err := r.Resource.decode(pd, version); err != nil {
 		return err
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -132,6 +176,17 @@ func (r *ResourceAcls) decode(pd packetDecoder, version int16) error {
 		if err := r.Acls[i].decode(pd, version); err != nil {
 			return err
 		}
+		if version >= 2 {
+			if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 
 	return nil
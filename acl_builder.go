@@ -0,0 +1,292 @@
+package sarama
+
+import (
+	"errors"
+	"fmt"
+)
+
+// aclClusterResourceName is the name Kafka uses for the single cluster resource.
+const aclClusterResourceName = "kafka-cluster"
+
+// AclBinding binds an Acl to the Resource it applies to.
+type AclBinding struct {
+	Resource Resource
+	Acl      Acl
+}
+
+// Filter returns an AclFilter matching exactly this binding, suitable for
+// DeleteACL.
+func (b AclBinding) Filter() AclFilter {
+	name, principal, host := b.Resource.ResourceName, b.Acl.Principal, b.Acl.Host
+	return AclFilter{
+		ResourceType:              b.Resource.ResourceType,
+		ResourceName:              &name,
+		ResourcePatternTypeFilter: b.Resource.ResourcePatternType,
+		Principal:                 &principal,
+		Host:                      &host,
+		Operation:                 b.Acl.Operation,
+		PermissionType:            b.Acl.PermissionType,
+	}
+}
+
+func (b AclBinding) String() string {
+	return fmt.Sprintf("%s %s from %s %s on %s %s %q",
+		b.Acl.PermissionType.String(), b.Acl.Principal, b.Acl.Host, b.Acl.Operation.String(),
+		b.Resource.ResourcePatternType.String(), b.Resource.ResourceType.String(), b.Resource.ResourceName)
+}
+
+// normalized returns the binding as reported by brokers which do not support
+// resource patterns, where every resource is literal.
+func (b AclBinding) normalized() AclBinding {
+	if b.Resource.ResourcePatternType == AclPatternUnknown {
+		b.Resource.ResourcePatternType = AclPatternLiteral
+	}
+	return b
+}
+
+// AclBuilder builds ACL bindings and filters fluently. Every combination of
+// the configured resources, principals, hosts and operations results in one
+// binding, for instance:
+//
+//	bindings, err := sarama.NewAclBuilder().
+//		User("alice").
+//		Topic("orders", "payments").
+//		Operations(sarama.AclOperationRead, sarama.AclOperationDescribe).
+//		Build()
+//
+// Hosts default to "*" and the permission defaults to Allow.
+type AclBuilder struct {
+	resources  []Resource
+	principals []string
+	hosts      []string
+	operations []AclOperation
+	permission AclPermissionType
+	// explicit is set once Allow or Deny was called, see Filters.
+	explicit bool
+	err      error
+}
+
+// NewAclBuilder returns an empty AclBuilder allowing access from any host.
+func NewAclBuilder() *AclBuilder {
+	return &AclBuilder{permission: AclPermissionAllow}
+}
+
+// Resource adds arbitrary resources to the builder.
+func (b *AclBuilder) Resource(resources ...Resource) *AclBuilder {
+	b.resources = append(b.resources, resources...)
+	return b
+}
+
+func (b *AclBuilder) addResources(resourceType AclResourceType, pattern AclResourcePatternType, names []string) *AclBuilder {
+	for _, name := range names {
+		if name == "" && b.err == nil {
+			b.err = fmt.Errorf("kafka: empty %s name in ACL", resourceType.String())
+		}
+		b.resources = append(b.resources, Resource{
+			ResourceType:        resourceType,
+			ResourceName:        name,
+			ResourcePatternType: pattern,
+		})
+	}
+	return b
+}
+
+// Topic adds the topics with the given literal names. "*" matches every topic.
+func (b *AclBuilder) Topic(names ...string) *AclBuilder {
+	return b.addResources(AclResourceTopic, AclPatternLiteral, names)
+}
+
+// TopicPrefix adds every topic whose name starts with one of the prefixes.
+func (b *AclBuilder) TopicPrefix(prefixes ...string) *AclBuilder {
+	return b.addResources(AclResourceTopic, AclPatternPrefixed, prefixes)
+}
+
+// Group adds the consumer groups with the given literal names.
+func (b *AclBuilder) Group(names ...string) *AclBuilder {
+	return b.addResources(AclResourceGroup, AclPatternLiteral, names)
+}
+
+// GroupPrefix adds every consumer group whose name starts with one of the prefixes.
+func (b *AclBuilder) GroupPrefix(prefixes ...string) *AclBuilder {
+	return b.addResources(AclResourceGroup, AclPatternPrefixed, prefixes)
+}
+
+// TransactionalID adds the transactional ids with the given literal names.
+func (b *AclBuilder) TransactionalID(ids ...string) *AclBuilder {
+	return b.addResources(AclResourceTransactionalID, AclPatternLiteral, ids)
+}
+
+// TransactionalIDPrefix adds every transactional id starting with one of the prefixes.
+func (b *AclBuilder) TransactionalIDPrefix(prefixes ...string) *AclBuilder {
+	return b.addResources(AclResourceTransactionalID, AclPatternPrefixed, prefixes)
+}
+
+// Cluster adds the cluster resource.
+func (b *AclBuilder) Cluster() *AclBuilder {
+	return b.addResources(AclResourceCluster, AclPatternLiteral, []string{aclClusterResourceName})
+}
+
+// Principal adds fully qualified principals, such as "User:alice".
+func (b *AclBuilder) Principal(principals ...string) *AclBuilder {
+	for _, principal := range principals {
+		if principal == "" && b.err == nil {
+			b.err = errors.New("kafka: empty principal in ACL")
+		}
+	}
+	b.principals = append(b.principals, principals...)
+	return b
+}
+
+// User adds the "User:" principals with the given names.
+func (b *AclBuilder) User(names ...string) *AclBuilder {
+	for _, name := range names {
+		if name == "" && b.err == nil {
+			b.err = errors.New("kafka: empty user name in ACL")
+		}
+		b.principals = append(b.principals, "User:"+name)
+	}
+	return b
+}
+
+// Host restricts the ACLs to the given hosts instead of "*".
+func (b *AclBuilder) Host(hosts ...string) *AclBuilder {
+	b.hosts = append(b.hosts, hosts...)
+	return b
+}
+
+// Operations adds the operations the ACLs apply to.
+func (b *AclBuilder) Operations(operations ...AclOperation) *AclBuilder {
+	b.operations = append(b.operations, operations...)
+	return b
+}
+
+// Allow makes the ACLs grant the operations. This is the default.
+func (b *AclBuilder) Allow() *AclBuilder {
+	b.permission, b.explicit = AclPermissionAllow, true
+	return b
+}
+
+// Deny makes the ACLs deny the operations.
+func (b *AclBuilder) Deny() *AclBuilder {
+	b.permission, b.explicit = AclPermissionDeny, true
+	return b
+}
+
+// Build validates the builder and returns one binding per combination of
+// resource, principal, host and operation.
+func (b *AclBuilder) Build() ([]AclBinding, error) {
+	if b.err != nil {
+		return nil, b.err
+	}
+	switch {
+	case len(b.resources) == 0:
+		return nil, errors.New("kafka: ACL requires at least one resource")
+	case len(b.principals) == 0:
+		return nil, errors.New("kafka: ACL requires at least one principal")
+	case len(b.operations) == 0:
+		return nil, errors.New("kafka: ACL requires at least one operation")
+	}
+
+	for _, resource := range b.resources {
+		switch resource.ResourceType {
+		case AclResourceUnknown, AclResourceAny:
+			return nil, fmt.Errorf("kafka: invalid ACL resource type %s", resource.ResourceType.String())
+		}
+		switch resource.ResourcePatternType {
+		case AclPatternLiteral, AclPatternPrefixed:
+		default:
+			return nil, fmt.Errorf("kafka: invalid ACL resource pattern type %s", resource.ResourcePatternType.String())
+		}
+	}
+	for _, operation := range b.operations {
+		switch operation {
+		case AclOperationUnknown, AclOperationAny:
+			return nil, fmt.Errorf("kafka: invalid ACL operation %s", operation.String())
+		}
+	}
+
+	hosts := b.hosts
+	if len(hosts) == 0 {
+		hosts = []string{"*"}
+	}
+
+	bindings := make([]AclBinding, 0, len(b.resources)*len(b.principals)*len(hosts)*len(b.operations))
+	for _, resource := range b.resources {
+		for _, principal := range b.principals {
+			for _, host := range hosts {
+				for _, operation := range b.operations {
+					bindings = append(bindings, AclBinding{
+						Resource: resource,
+						Acl: Acl{
+							Principal:      principal,
+							Host:           host,
+							Operation:      operation,
+							PermissionType: b.permission,
+						},
+					})
+				}
+			}
+		}
+	}
+	return bindings, nil
+}
+
+// Filters returns one filter per combination of the configured values, for
+// use with ListAcls or DeleteACL. Unlike Build, every dimension is optional
+// and matches anything when left unset, so NewAclBuilder().User("alice").Filters()
+// matches every ACL of alice. The permission is only filtered on when Allow
+// or Deny was called explicitly.
+func (b *AclBuilder) Filters() []AclFilter {
+	resources := b.resources
+	if len(resources) == 0 {
+		resources = []Resource{{ResourceType: AclResourceAny, ResourcePatternType: AclPatternAny}}
+	}
+	principals := stringPtrs(b.principals)
+	hosts := stringPtrs(b.hosts)
+	operations := b.operations
+	if len(operations) == 0 {
+		operations = []AclOperation{AclOperationAny}
+	}
+	permission := AclPermissionAny
+	if b.explicit {
+		permission = b.permission
+	}
+
+	var filters []AclFilter
+	for _, resource := range resources {
+		var name *string
+		if resource.ResourceType != AclResourceAny {
+			resourceName := resource.ResourceName
+			name = &resourceName
+		}
+		for _, principal := range principals {
+			for _, host := range hosts {
+				for _, operation := range operations {
+					filters = append(filters, AclFilter{
+						ResourceType:              resource.ResourceType,
+						ResourceName:              name,
+						ResourcePatternTypeFilter: resource.ResourcePatternType,
+						Principal:                 principal,
+						Host:                      host,
+						Operation:                 operation,
+						PermissionType:            permission,
+					})
+				}
+			}
+		}
+	}
+	return filters
+}
+
+// stringPtrs returns pointers to each of values, or a single nil pointer
+// matching anything when values is empty.
+func stringPtrs(values []string) []*string {
+	if len(values) == 0 {
+		return []*string{nil}
+	}
+	ptrs := make([]*string, len(values))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	return ptrs
+}
@@ -0,0 +1,91 @@
+package sarama
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAclBuilderBuild(t *testing.T) {
+	bindings, err := NewAclBuilder().
+		User("alice").
+		Topic("orders").
+		GroupPrefix("billing-").
+		Operations(AclOperationRead, AclOperationDescribe).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	alice := func(resource Resource, operation AclOperation) AclBinding {
+		return AclBinding{
+			Resource: resource,
+			Acl:      Acl{Principal: "User:alice", Host: "*", Operation: operation, PermissionType: AclPermissionAllow},
+		}
+	}
+	orders := Resource{ResourceType: AclResourceTopic, ResourceName: "orders", ResourcePatternType: AclPatternLiteral}
+	billing := Resource{ResourceType: AclResourceGroup, ResourceName: "billing-", ResourcePatternType: AclPatternPrefixed}
+	expected := []AclBinding{
+		alice(orders, AclOperationRead),
+		alice(orders, AclOperationDescribe),
+		alice(billing, AclOperationRead),
+		alice(billing, AclOperationDescribe),
+	}
+	if !reflect.DeepEqual(bindings, expected) {
+		t.Errorf("expected %v, got %v", expected, bindings)
+	}
+
+	bindings, err = NewAclBuilder().Principal("User:mallory").Host("10.0.0.1").Cluster().Operations(AclOperationAlter).Deny().Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+	denied := AclBinding{
+		Resource: Resource{ResourceType: AclResourceCluster, ResourceName: "kafka-cluster", ResourcePatternType: AclPatternLiteral},
+		Acl:      Acl{Principal: "User:mallory", Host: "10.0.0.1", Operation: AclOperationAlter, PermissionType: AclPermissionDeny},
+	}
+	if len(bindings) != 1 || bindings[0] != denied {
+		t.Errorf("expected %v, got %v", denied, bindings)
+	}
+}
+
+func TestAclBuilderBuildInvalid(t *testing.T) {
+	builders := map[string]*AclBuilder{
+		"no resource":    NewAclBuilder().User("alice").Operations(AclOperationRead),
+		"no principal":   NewAclBuilder().Topic("orders").Operations(AclOperationRead),
+		"no operation":   NewAclBuilder().User("alice").Topic("orders"),
+		"empty topic":    NewAclBuilder().User("alice").Topic("").Operations(AclOperationRead),
+		"empty user":     NewAclBuilder().User("").Topic("orders").Operations(AclOperationRead),
+		"any operation":  NewAclBuilder().User("alice").Topic("orders").Operations(AclOperationAny),
+		"match resource": NewAclBuilder().User("alice").Resource(Resource{ResourceType: AclResourceTopic, ResourceName: "orders", ResourcePatternType: AclPatternMatch}).Operations(AclOperationRead),
+	}
+	for name, builder := range builders {
+		if _, err := builder.Build(); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+func TestAclBuilderFilters(t *testing.T) {
+	filters := NewAclBuilder().User("alice").Filters()
+	if len(filters) != 1 {
+		t.Fatalf("expected 1 filter, got %d", len(filters))
+	}
+	filter := filters[0]
+	if filter.ResourceType != AclResourceAny || filter.ResourceName != nil || filter.ResourcePatternTypeFilter != AclPatternAny ||
+		filter.Host != nil || filter.Operation != AclOperationAny || filter.PermissionType != AclPermissionAny {
+		t.Errorf("expected unset dimensions to match anything, got %+v", filter)
+	}
+	if filter.Principal == nil || *filter.Principal != "User:alice" {
+		t.Errorf("expected principal User:alice, got %v", filter.Principal)
+	}
+
+	filters = NewAclBuilder().Topic("orders", "payments").Deny().Filters()
+	if len(filters) != 2 {
+		t.Fatalf("expected 2 filters, got %d", len(filters))
+	}
+	if *filters[0].ResourceName != "orders" || *filters[1].ResourceName != "payments" {
+		t.Errorf("expected one filter per topic, got %s and %s", *filters[0].ResourceName, *filters[1].ResourceName)
+	}
+	if filters[0].PermissionType != AclPermissionDeny || filters[0].Principal != nil {
+		t.Errorf("unexpected filter %+v", filters[0])
+	}
+}
@@ -7,7 +7,9 @@ type CreateAclsRequest struct {
 }
 
 func (c *CreateAclsRequest) encode(pe packetEncoder) error {
-	if err := pe.putArrayLength(len(c.AclCreations)); err != nil {
+	if c.Version >= 2 {
+		pe.putCompactArrayLength(len(c.AclCreations))
+	} else if err := pe.putArrayLength(len(c.AclCreations)); err != nil {
 		return err
 	}
 
@@ -17,12 +19,21 @@ func (c *CreateAclsRequest) encode(pe packetEncoder) error {
 		}
 	}
 
+	if c.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (c *CreateAclsRequest) decode(pd packetDecoder, version int16) (err error) {
 	c.Version = version
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -36,6 +47,12 @@ func (c *CreateAclsRequest) decode(pd packetDecoder, version int16) (err error)
 		}
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -48,11 +65,16 @@ func (c *CreateAclsRequest) version() int16 {
 }
 
 func (c *CreateAclsRequest) headerVersion() int16 {
+	if c.Version >= 2 {
+		return 2
+	}
 	return 1
 }
 
 func (c *CreateAclsRequest) requiredVersion() KafkaVersion {
 	switch c.Version {
+	case 2:
+		return V2_5_0_0
 	case 1:
 		return V2_0_0_0
 	default:
@@ -70,9 +92,12 @@ func (a *AclCreation) encode(pe packetEncoder, version int16) error {
 	if err := a.Resource.encode(pe, version); err != nil {
 		return err
 	}
-	if err := a.Acl.encode(pe); err != nil {
+	if err := a.Acl.encode(pe, version); err != nil {
 		return err
 	}
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
 
 	return nil
 }
@@ -84,6 +109,11 @@ func (a *AclCreation) decode(pd packetDecoder, version int16) (err error) {
 	if err := a.Acl.decode(pd, version); err != nil {
 		return err
 	}
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -4,6 +4,7 @@ import "time"
 
 // CreateAclsResponse is a an acl response creation type
 type CreateAclsResponse struct {
+	Version              int16
 	ThrottleTime         time.Duration
 	AclCreationResponses []*AclCreationResponse
 }
@@ -11,27 +12,40 @@ type CreateAclsResponse struct {
 func (c *CreateAclsResponse) encode(pe packetEncoder) error {
 	pe.putInt32(int32(c.ThrottleTime / time.Millisecond))
 
-	if err := pe.putArrayLength(len(c.AclCreationResponses)); err != nil {
+	if c.Version >= 2 {
+		pe.putCompactArrayLength(len(c.AclCreationResponses))
+	} else if err := pe.putArrayLength(len(c.AclCreationResponses)); err != nil {
 		return err
 	}
 
 	for _, aclCreationResponse := range c.AclCreationResponses {
-		if err := aclCreationResponse.encode(pe); err != nil {
+		if err := aclCreationResponse.encode(pe, c.Version); err != nil {
 			return err
 		}
 	}
 
+	if c.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (c *CreateAclsResponse) decode(pd packetDecoder, version int16) (err error) {
+	c.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
 	}
 	c.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -44,6 +58,12 @@ func (c *CreateAclsResponse) decode(pd packetDecoder, version int16) (err error)
 		}
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -52,15 +72,25 @@ func (c *CreateAclsResponse) key() int16 {
 }
 
 func (c *CreateAclsResponse) version() int16 {
-	return 0
+	return c.Version
 }
 
 func (c *CreateAclsResponse) headerVersion() int16 {
+	if c.Version >= 2 {
+		return 1
+	}
 	return 0
 }
 
 func (c *CreateAclsResponse) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch c.Version {
+	case 2:
+		return V2_5_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
 }
 
 // AclCreationResponse is an acl creation response type
@@ -69,13 +99,22 @@ type AclCreationResponse struct {
 	ErrMsg *string
 }
 
-func (a *AclCreationResponse) encode(pe packetEncoder) error {
+func (a *AclCreationResponse) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt16(int16(a.Err))
 
-	if err := pe.putNullableString(a.ErrMsg); err != nil {
+	if version >= 2 {
+		err = pe.putNullableCompactString(a.ErrMsg)
+	} else {
+		err = pe.putNullableString(a.ErrMsg)
+	}
+	if err != nil {
 		return err
 	}
 
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
@@ -86,9 +125,28 @@ func (a *AclCreationResponse) decode(pd packetDecoder, version int16) (err error
 	}
 	a.Err = KError(kerr)
 
-	if a.ErrMsg, err = pd.getNullableString(); err != nil {
+	if version >= 2 {
+		a.ErrMsg, err = pd.getCompactNullableString()
+	} else {
+		a.ErrMsg, err = pd.getNullableString()
+	}
+	if err != nil {
 		return err
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+func (c *CreateAclsResponse) throttleTime() time.Duration {
+	return c.ThrottleTime
+}
+
+func (c *CreateAclsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
+}
@@ -21,6 +21,18 @@ var (
 		0, 0,
 		255, 255,
 	}
+
+	createResponseArrayV2 = []byte{
+		0, 0, 0, 100,
+		3,
+		0, 42,
+		6, 'e', 'r', 'r', 'o', 'r',
+		0,
+		0, 0,
+		0,
+		0,
+		0,
+	}
 )
 
 func TestCreateAclsResponse(t *testing.T) {
@@ -38,4 +50,8 @@ func TestCreateAclsResponse(t *testing.T) {
 	resp.AclCreationResponses = append(resp.AclCreationResponses, new(AclCreationResponse))
 
 	testResponse(t, "response array", resp, createResponseArray)
+
+	resp.Version = 2
+
+	testResponse(t, "response array v2", resp, createResponseArrayV2)
 }
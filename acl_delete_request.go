@@ -7,7 +7,9 @@ type DeleteAclsRequest struct {
 }
 
 func (d *DeleteAclsRequest) encode(pe packetEncoder) error {
-	if err := pe.putArrayLength(len(d.Filters)); err != nil {
+	if d.Version >= 2 {
+		pe.putCompactArrayLength(len(d.Filters))
+	} else if err := pe.putArrayLength(len(d.Filters)); err != nil {
 		return err
 	}
 
@@ -16,6 +18,13 @@ func (d *DeleteAclsRequest) encode(pe packetEncoder) error {
 		if err := filter.encode(pe); err != nil {
 			return err
 		}
+		if d.Version >= 2 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if d.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
@@ -23,7 +32,12 @@ func (d *DeleteAclsRequest) encode(pe packetEncoder) error {
 
 func (d *DeleteAclsRequest) decode(pd packetDecoder, version int16) (err error) {
 	d.Version = int(version)
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -35,6 +49,17 @@ func (d *DeleteAclsRequest) decode(pd packetDecoder, version int16) (err error)
 		if err := d.Filters[i].decode(pd, version); err != nil {
 			return err
 		}
+		if version >= 2 {
+			if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -49,11 +74,16 @@ func (d *DeleteAclsRequest) version() int16 {
 }
 
 func (d *DeleteAclsRequest) headerVersion() int16 {
+	if d.Version >= 2 {
+		return 2
+	}
 	return 1
 }
 
 func (d *DeleteAclsRequest) requiredVersion() KafkaVersion {
 	switch d.Version {
+	case 2:
+		return V2_5_0_0
 	case 1:
 		return V2_0_0_0
 	default:
@@ -110,3 +110,32 @@ func TestDeleteAclsRequestV1(t *testing.T) {
 
 	testRequest(t, "delete request", req, aclDeleteRequestv1)
 }
+
+var aclDeleteRequestv2 = []byte{
+	2,
+	1, // any
+	7, 'f', 'i', 'l', 't', 'e', 'r',
+	1, // Any Filter
+	0, // null principal
+	5, 'h', 'o', 's', 't',
+	4, // write
+	3, // allow
+	0, // empty tagged fields
+	0, // empty tagged fields
+}
+
+func TestDeleteAclsRequestV2(t *testing.T) {
+	req := &DeleteAclsRequest{
+		Version: 2,
+		Filters: []*AclFilter{{
+			ResourceType:              AclResourceAny,
+			ResourceName:              nullString("filter"),
+			ResourcePatternTypeFilter: AclPatternAny,
+			Host:                      nullString("host"),
+			Operation:                 AclOperationWrite,
+			PermissionType:            AclPermissionAllow,
+		}},
+	}
+
+	testRequest(t, "delete request v2", req, aclDeleteRequestv2)
+}
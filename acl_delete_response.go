@@ -12,7 +12,9 @@ type DeleteAclsResponse struct {
 func (d *DeleteAclsResponse) encode(pe packetEncoder) error {
 	pe.putInt32(int32(d.ThrottleTime / time.Millisecond))
 
-	if err := pe.putArrayLength(len(d.FilterResponses)); err != nil {
+	if d.Version >= 2 {
+		pe.putCompactArrayLength(len(d.FilterResponses))
+	} else if err := pe.putArrayLength(len(d.FilterResponses)); err != nil {
 		return err
 	}
 
@@ -22,17 +24,28 @@ func (d *DeleteAclsResponse) encode(pe packetEncoder) error {
 		}
 	}
 
+	if d.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (d *DeleteAclsResponse) decode(pd packetDecoder, version int16) (err error) {
+	d.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
 	}
 	d.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -45,6 +58,12 @@ func (d *DeleteAclsResponse) decode(pd packetDecoder, version int16) (err error)
 		}
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -57,11 +76,21 @@ func (d *DeleteAclsResponse) version() int16 {
 }
 
 func (d *DeleteAclsResponse) headerVersion() int16 {
+	if d.Version >= 2 {
+		return 1
+	}
 	return 0
 }
 
 func (d *DeleteAclsResponse) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch d.Version {
+	case 2:
+		return V2_5_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
 }
 
 // FilterResponse is a filter response type
@@ -71,21 +100,32 @@ type FilterResponse struct {
 	MatchingAcls []*MatchingAcl
 }
 
-func (f *FilterResponse) encode(pe packetEncoder, version int16) error {
+func (f *FilterResponse) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt16(int16(f.Err))
-	if err := pe.putNullableString(f.ErrMsg); err != nil {
+	if version >= 2 {
+		err = pe.putNullableCompactString(f.ErrMsg)
+	} else {
+		err = pe.putNullableString(f.ErrMsg)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := pe.putArrayLength(len(f.MatchingAcls)); err != nil {
+	if version >= 2 {
+		pe.putCompactArrayLength(len(f.MatchingAcls))
+	} else if err = pe.putArrayLength(len(f.MatchingAcls)); err != nil {
 		return err
 	}
 	for _, matchingAcl := range f.MatchingAcls {
-		if err := matchingAcl.encode(pe, version); err != nil {
+		if err = matchingAcl.encode(pe, version); err != nil {
 			return err
 		}
 	}
 
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
@@ -96,11 +136,21 @@ func (f *FilterResponse) decode(pd packetDecoder, version int16) (err error) {
 	}
 	f.Err = KError(kerr)
 
-	if f.ErrMsg, err = pd.getNullableString(); err != nil {
+	if version >= 2 {
+		f.ErrMsg, err = pd.getCompactNullableString()
+	} else {
+		f.ErrMsg, err = pd.getNullableString()
+	}
+	if err != nil {
 		return err
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -112,6 +162,12 @@ func (f *FilterResponse) decode(pd packetDecoder, version int16) (err error) {
 		}
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -123,20 +179,29 @@ type MatchingAcl struct {
 	Acl
 }
 
-func (m *MatchingAcl) encode(pe packetEncoder, version int16) error {
+func (m *MatchingAcl) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt16(int16(m.Err))
-	if err := pe.putNullableString(m.ErrMsg); err != nil {
+	if version >= 2 {
+		err = pe.putNullableCompactString(m.ErrMsg)
+	} else {
+		err = pe.putNullableString(m.ErrMsg)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := m.Resource.encode(pe, version); err != nil {
+	if err = m.Resource.encode(pe, version); err != nil {
 		return err
 	}
 
-	if err := m.Acl.encode(pe); err != nil {
+	if err = m.Acl.encode(pe, version); err != nil {
 		return err
 	}
 
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
@@ -147,17 +212,36 @@ func (m *MatchingAcl) decode(pd packetDecoder, version int16) (err error) {
 	}
 	m.Err = KError(kerr)
 
-	if m.ErrMsg, err = pd.getNullableString(); err != nil {
+	if version >= 2 {
+		m.ErrMsg, err = pd.getCompactNullableString()
+	} else {
+		m.ErrMsg, err = pd.getNullableString()
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := m.Resource.decode(pd, version); err != nil {
+	if err = m.Resource.decode(pd, version); err != nil {
 		return err
 	}
 
-	if err := m.Acl.decode(pd, version); err != nil {
+	if err = m.Acl.decode(pd, version); err != nil {
 		return err
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+func (d *DeleteAclsResponse) throttleTime() time.Duration {
+	return d.ThrottleTime
+}
+
+func (d *DeleteAclsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
+}
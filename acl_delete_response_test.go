@@ -34,3 +34,38 @@ func TestDeleteAclsResponse(t *testing.T) {
 
 	testResponse(t, "", resp, deleteAclsResponse)
 }
+
+var deleteAclsResponseV2 = []byte{
+	0, 0, 0, 100,
+	2,
+	0, 0, // no error
+	0,    // no error message
+	2,    // 1 matching acl
+	0, 0, // no error
+	0, // no error message
+	2, // resource type
+	6, 't', 'o', 'p', 'i', 'c',
+	3, // literal pattern type
+	10, 'p', 'r', 'i', 'n', 'c', 'i', 'p', 'a', 'l',
+	5, 'h', 'o', 's', 't',
+	4,
+	3,
+	0, // empty tagged fields
+	0, // empty tagged fields
+	0, // empty tagged fields
+}
+
+func TestDeleteAclsResponseV2(t *testing.T) {
+	resp := &DeleteAclsResponse{
+		Version:      2,
+		ThrottleTime: 100 * time.Millisecond,
+		FilterResponses: []*FilterResponse{{
+			MatchingAcls: []*MatchingAcl{{
+				Resource: Resource{ResourceType: AclResourceTopic, ResourceName: "topic", ResourcePatternType: AclPatternLiteral},
+				Acl:      Acl{Principal: "principal", Host: "host", Operation: AclOperationWrite, PermissionType: AclPermissionAllow},
+			}},
+		}},
+	}
+
+	testResponse(t, "v2", resp, deleteAclsResponseV2)
+}
@@ -8,13 +8,27 @@ type DescribeAclsRequest struct {
 
 func (d *DescribeAclsRequest) encode(pe packetEncoder) error {
 	d.AclFilter.Version = d.Version
-	return d.AclFilter.encode(pe)
+	if err := d.AclFilter.encode(pe); err != nil {
+		return err
+	}
+	if d.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+	return nil
 }
 
 func (d *DescribeAclsRequest) decode(pd packetDecoder, version int16) (err error) {
 	d.Version = int(version)
 	d.AclFilter.Version = int(version)
-	return d.AclFilter.decode(pd, version)
+	if err = d.AclFilter.decode(pd, version); err != nil {
+		return err
+	}
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+	return nil
 }
 
 func (d *DescribeAclsRequest) key() int16 {
@@ -26,11 +40,16 @@ func (d *DescribeAclsRequest) version() int16 {
 }
 
 func (d *DescribeAclsRequest) headerVersion() int16 {
+	if d.Version >= 2 {
+		return 2
+	}
 	return 1
 }
 
 func (d *DescribeAclsRequest) requiredVersion() KafkaVersion {
 	switch d.Version {
+	case 2:
+		return V2_5_0_0
 	case 1:
 		return V2_0_0_0
 	default:
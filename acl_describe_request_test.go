@@ -63,3 +63,33 @@ func TestAclDescribeRequestV1(t *testing.T) {
 
 	testRequest(t, "", req, aclDescribeRequestV1)
 }
+
+var aclDescribeRequestV2 = []byte{
+	2, // resource type
+	6, 't', 'o', 'p', 'i', 'c',
+	1, // any Type
+	10, 'p', 'r', 'i', 'n', 'c', 'i', 'p', 'a', 'l',
+	0, // null host
+	5, // acl operation
+	3, // acl permission type
+	0, // empty tagged fields
+}
+
+func TestAclDescribeRequestV2(t *testing.T) {
+	resourcename := "topic"
+	principal := "principal"
+
+	req := &DescribeAclsRequest{
+		Version: 2,
+		AclFilter: AclFilter{
+			ResourceType:              AclResourceTopic,
+			ResourceName:              &resourcename,
+			ResourcePatternTypeFilter: AclPatternAny,
+			Principal:                 &principal,
+			Operation:                 AclOperationCreate,
+			PermissionType:            AclPermissionAllow,
+		},
+	}
+
+	testRequest(t, "", req, aclDescribeRequestV2)
+}
@@ -11,28 +11,41 @@ type DescribeAclsResponse struct {
 	ResourceAcls []*ResourceAcls
 }
 
-func (d *DescribeAclsResponse) encode(pe packetEncoder) error {
+func (d *DescribeAclsResponse) encode(pe packetEncoder) (err error) {
 	pe.putInt32(int32(d.ThrottleTime / time.Millisecond))
 	pe.putInt16(int16(d.Err))
 
-	if err := pe.putNullableString(d.ErrMsg); err != nil {
+	if d.Version >= 2 {
+		err = pe.putNullableCompactString(d.ErrMsg)
+	} else {
+		err = pe.putNullableString(d.ErrMsg)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := pe.putArrayLength(len(d.ResourceAcls)); err != nil {
+	if d.Version >= 2 {
+		pe.putCompactArrayLength(len(d.ResourceAcls))
+	} else if err = pe.putArrayLength(len(d.ResourceAcls)); err != nil {
 		return err
 	}
 
 	for _, resourceAcl := range d.ResourceAcls {
-		if err := resourceAcl.encode(pe, d.Version); err != nil {
+		if err = resourceAcl.encode(pe, d.Version); err != nil {
 			return err
 		}
 	}
 
+	if d.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (d *DescribeAclsResponse) decode(pd packetDecoder, version int16) (err error) {
+	d.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
@@ -45,15 +58,26 @@ func (d *DescribeAclsResponse) decode(pd packetDecoder, version int16) (err erro
 	}
 	d.Err = KError(kerr)
 
-	errmsg, err := pd.getString()
-	if err != nil {
-		return err
-	}
-	if errmsg != "" {
-		d.ErrMsg = &errmsg
+	if version >= 2 {
+		if d.ErrMsg, err = pd.getCompactNullableString(); err != nil {
+			return err
+		}
+	} else {
+		errmsg, err := pd.getString()
+		if err != nil {
+			return err
+		}
+		if errmsg != "" {
+			d.ErrMsg = &errmsg
+		}
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -66,6 +90,12 @@ func (d *DescribeAclsResponse) decode(pd packetDecoder, version int16) (err erro
 		}
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -78,14 +108,27 @@ func (d *DescribeAclsResponse) version() int16 {
 }
 
 func (d *DescribeAclsResponse) headerVersion() int16 {
+	if d.Version >= 2 {
+		return 1
+	}
 	return 0
 }
 
 func (d *DescribeAclsResponse) requiredVersion() KafkaVersion {
 	switch d.Version {
+	case 2:
+		return V2_5_0_0
 	case 1:
 		return V2_0_0_0
 	default:
 		return V0_11_0_0
 	}
 }
+
+func (d *DescribeAclsResponse) throttleTime() time.Duration {
+	return d.ThrottleTime
+}
+
+func (d *DescribeAclsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
+}
@@ -43,3 +43,45 @@ func TestAclDescribeResponse(t *testing.T) {
 
 	testResponse(t, "describe", resp, aclDescribeResponseError)
 }
+
+var aclDescribeResponseV2 = []byte{
+	0, 0, 0, 100,
+	0, 0, // no error
+	0, // null error message
+	2, // 1 resource
+	2, // topic type
+	6, 't', 'o', 'p', 'i', 'c',
+	3, // literal pattern type
+	2, // 1 acl
+	10, 'p', 'r', 'i', 'n', 'c', 'i', 'p', 'a', 'l',
+	5, 'h', 'o', 's', 't',
+	4, // write
+	3, // allow
+	0, // empty tagged fields
+	0, // empty tagged fields
+	0, // empty tagged fields
+}
+
+func TestAclDescribeResponseV2(t *testing.T) {
+	resp := &DescribeAclsResponse{
+		Version:      2,
+		ThrottleTime: 100 * time.Millisecond,
+		ResourceAcls: []*ResourceAcls{{
+			Resource: Resource{
+				ResourceName:        "topic",
+				ResourceType:        AclResourceTopic,
+				ResourcePatternType: AclPatternLiteral,
+			},
+			Acls: []*Acl{
+				{
+					Principal:      "principal",
+					Host:           "host",
+					Operation:      AclOperationWrite,
+					PermissionType: AclPermissionAllow,
+				},
+			},
+		}},
+	}
+
+	testResponse(t, "describe v2", resp, aclDescribeResponseV2)
+}
@@ -11,20 +11,35 @@ type AclFilter struct {
 	PermissionType            AclPermissionType
 }
 
-func (a *AclFilter) encode(pe packetEncoder) error {
+func (a *AclFilter) encode(pe packetEncoder) (err error) {
 	pe.putInt8(int8(a.ResourceType))
-	if err := pe.putNullableString(a.ResourceName); err != nil {
+	if a.Version >= 2 {
+		err = pe.putNullableCompactString(a.ResourceName)
+	} else {
+		err = pe.putNullableString(a.ResourceName)
+	}
+	if err != nil {
 		return err
 	}
 
-	if a.Version == 1 {
+	if a.Version >= 1 {
 		pe.putInt8(int8(a.ResourcePatternTypeFilter))
 	}
 
-	if err := pe.putNullableString(a.Principal); err != nil {
+	if a.Version >= 2 {
+		err = pe.putNullableCompactString(a.Principal)
+	} else {
+		err = pe.putNullableString(a.Principal)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putNullableString(a.Host); err != nil {
+	if a.Version >= 2 {
+		err = pe.putNullableCompactString(a.Host)
+	} else {
+		err = pe.putNullableString(a.Host)
+	}
+	if err != nil {
 		return err
 	}
 	pe.putInt8(int8(a.Operation))
@@ -40,11 +55,16 @@ func (a *AclFilter) decode(pd packetDecoder, version int16) (err error) {
 	}
 	a.ResourceType = AclResourceType(resourceType)
 
-	if a.ResourceName, err = pd.getNullableString(); err != nil {
+	if a.Version >= 2 {
+		a.ResourceName, err = pd.getCompactNullableString()
+	} else {
+		a.ResourceName, err = pd.getNullableString()
+	}
+	if err != nil {
 		return err
 	}
 
-	if a.Version == 1 {
+	if a.Version >= 1 {
 		pattern, err := pd.getInt8()
 		if err != nil {
 			return err
@@ -53,11 +73,21 @@ func (a *AclFilter) decode(pd packetDecoder, version int16) (err error) {
 		a.ResourcePatternTypeFilter = AclResourcePatternType(pattern)
 	}
 
-	if a.Principal, err = pd.getNullableString(); err != nil {
+	if a.Version >= 2 {
+		a.Principal, err = pd.getCompactNullableString()
+	} else {
+		a.Principal, err = pd.getNullableString()
+	}
+	if err != nil {
 		return err
 	}
 
-	if a.Host, err = pd.getNullableString(); err != nil {
+	if a.Version >= 2 {
+		a.Host, err = pd.getCompactNullableString()
+	} else {
+		a.Host, err = pd.getNullableString()
+	}
+	if err != nil {
 		return err
 	}
 
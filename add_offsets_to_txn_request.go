@@ -2,14 +2,20 @@ package sarama
 
 // AddOffsetsToTxnRequest adds offsets to a transaction request
 type AddOffsetsToTxnRequest struct {
+	Version         int16
 	TransactionalID string
 	ProducerID      int64
 	ProducerEpoch   int16
 	GroupID         string
 }
 
-func (a *AddOffsetsToTxnRequest) encode(pe packetEncoder) error {
-	if err := pe.putString(a.TransactionalID); err != nil {
+func (a *AddOffsetsToTxnRequest) encode(pe packetEncoder) (err error) {
+	if a.Version >= 3 {
+		err = pe.putCompactString(a.TransactionalID)
+	} else {
+		err = pe.putString(a.TransactionalID)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -17,15 +23,30 @@ func (a *AddOffsetsToTxnRequest) encode(pe packetEncoder) error {
 
 	pe.putInt16(a.ProducerEpoch)
 
-	if err := pe.putString(a.GroupID); err != nil {
+	if a.Version >= 3 {
+		err = pe.putCompactString(a.GroupID)
+	} else {
+		err = pe.putString(a.GroupID)
+	}
+	if err != nil {
 		return err
 	}
 
+	if a.Version >= 3 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (a *AddOffsetsToTxnRequest) decode(pd packetDecoder, version int16) (err error) {
-	if a.TransactionalID, err = pd.getString(); err != nil {
+	a.Version = version
+	if version >= 3 {
+		a.TransactionalID, err = pd.getCompactString()
+	} else {
+		a.TransactionalID, err = pd.getString()
+	}
+	if err != nil {
 		return err
 	}
 	if a.ProducerID, err = pd.getInt64(); err != nil {
@@ -34,9 +55,19 @@ func (a *AddOffsetsToTxnRequest) decode(pd packetDecoder, version int16) (err er
 	if a.ProducerEpoch, err = pd.getInt16(); err != nil {
 		return err
 	}
-	if a.GroupID, err = pd.getString(); err != nil {
+	if version >= 3 {
+		a.GroupID, err = pd.getCompactString()
+	} else {
+		a.GroupID, err = pd.getString()
+	}
+	if err != nil {
 		return err
 	}
+	if version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -45,13 +76,25 @@ func (a *AddOffsetsToTxnRequest) key() int16 {
 }
 
 func (a *AddOffsetsToTxnRequest) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *AddOffsetsToTxnRequest) headerVersion() int16 {
+	if a.Version >= 3 {
+		return 2
+	}
 	return 1
 }
 
 func (a *AddOffsetsToTxnRequest) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch a.Version {
+	case 3:
+		return V2_8_0_0
+	case 2:
+		return V2_7_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
 }
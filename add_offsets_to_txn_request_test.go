@@ -19,3 +19,23 @@ func TestAddOffsetsToTxnRequest(t *testing.T) {
 
 	testRequest(t, "", req, addOffsetsToTxnRequest)
 }
+
+var addOffsetsToTxnRequestV3 = []byte{
+	4, 't', 'x', 'n',
+	0, 0, 0, 0, 0, 0, 31, 64,
+	0, 0,
+	8, 'g', 'r', 'o', 'u', 'p', 'i', 'd',
+	0,
+}
+
+func TestAddOffsetsToTxnRequestV3(t *testing.T) {
+	req := &AddOffsetsToTxnRequest{
+		Version:         3,
+		TransactionalID: "txn",
+		ProducerID:      8000,
+		ProducerEpoch:   0,
+		GroupID:         "groupid",
+	}
+
+	testRequest(t, "", req, addOffsetsToTxnRequestV3)
+}
@@ -6,6 +6,7 @@ import (
 
 // AddOffsetsToTxnResponse is a response type for adding offsets to txns
 type AddOffsetsToTxnResponse struct {
+	Version      int16
 	ThrottleTime time.Duration
 	Err          KError
 }
@@ -13,10 +14,15 @@ type AddOffsetsToTxnResponse struct {
 func (a *AddOffsetsToTxnResponse) encode(pe packetEncoder) error {
 	pe.putInt32(int32(a.ThrottleTime / time.Millisecond))
 	pe.putInt16(int16(a.Err))
+	if a.Version >= 3 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
 func (a *AddOffsetsToTxnResponse) decode(pd packetDecoder, version int16) (err error) {
+	a.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
@@ -29,6 +35,12 @@ func (a *AddOffsetsToTxnResponse) decode(pd packetDecoder, version int16) (err e
 	}
 	a.Err = KError(kerr)
 
+	if version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -37,13 +49,37 @@ func (a *AddOffsetsToTxnResponse) key() int16 {
 }
 
 func (a *AddOffsetsToTxnResponse) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *AddOffsetsToTxnResponse) headerVersion() int16 {
+	if a.Version >= 3 {
+		return 1
+	}
 	return 0
 }
 
 func (a *AddOffsetsToTxnResponse) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch a.Version {
+	case 3:
+		return V2_8_0_0
+	case 2:
+		return V2_7_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
+}
+
+func (a *AddOffsetsToTxnResponse) throttleTime() time.Duration {
+	return a.ThrottleTime
+}
+
+func (a *AddOffsetsToTxnResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
+}
+
+func (a *AddOffsetsToTxnResponse) hasErrorCode() bool {
+	return a.Err != ErrNoError
 }
@@ -18,3 +18,19 @@ func TestAddOffsetsToTxnResponse(t *testing.T) {
 
 	testResponse(t, "", resp, addOffsetsToTxnResponse)
 }
+
+var addOffsetsToTxnResponseV3 = []byte{
+	0, 0, 0, 100,
+	0, 47,
+	0,
+}
+
+func TestAddOffsetsToTxnResponseV3(t *testing.T) {
+	resp := &AddOffsetsToTxnResponse{
+		Version:      3,
+		ThrottleTime: 100 * time.Millisecond,
+		Err:          ErrInvalidProducerEpoch,
+	}
+
+	testResponse(t, "", resp, addOffsetsToTxnResponseV3)
+}
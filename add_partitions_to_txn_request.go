@@ -2,36 +2,68 @@ package sarama
 
 // AddPartitionsToTxnRequest is a add paartition request
 type AddPartitionsToTxnRequest struct {
+	Version         int16
 	TransactionalID string
 	ProducerID      int64
 	ProducerEpoch   int16
 	TopicPartitions map[string][]int32
 }
 
-func (a *AddPartitionsToTxnRequest) encode(pe packetEncoder) error {
-	if err := pe.putString(a.TransactionalID); err != nil {
+func (a *AddPartitionsToTxnRequest) encode(pe packetEncoder) (err error) {
+	if a.Version >= 3 {
+		err = pe.putCompactString(a.TransactionalID)
+	} else {
+		err = pe.putString(a.TransactionalID)
+	}
+	if err != nil {
 		return err
 	}
 	pe.putInt64(a.ProducerID)
 	pe.putInt16(a.ProducerEpoch)
 
-	if err := pe.putArrayLength(len(a.TopicPartitions)); err != nil {
+	if a.Version >= 3 {
+		pe.putCompactArrayLength(len(a.TopicPartitions))
+	} else if err = pe.putArrayLength(len(a.TopicPartitions)); err != nil {
 		return err
 	}
 	for topic, partitions := range a.TopicPartitions {
-		if err := pe.putString(topic); err != nil {
+		if a.Version >= 3 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
+		if err != nil {
 			return err
 		}
-		if err := pe.putInt32Array(partitions); err != nil {
+		if a.Version >= 3 {
+			err = pe.putCompactInt32Array(partitions)
+		} else {
+			err = pe.putInt32Array(partitions)
+		}
+		if err != nil {
 			return err
 		}
+		if a.Version >= 3 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if a.Version >= 3 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
 }
 
 func (a *AddPartitionsToTxnRequest) decode(pd packetDecoder, version int16) (err error) {
-	if a.TransactionalID, err = pd.getString(); err != nil {
+	a.Version = version
+
+	if version >= 3 {
+		a.TransactionalID, err = pd.getCompactString()
+	} else {
+		a.TransactionalID, err = pd.getString()
+	}
+	if err != nil {
 		return err
 	}
 	if a.ProducerID, err = pd.getInt64(); err != nil {
@@ -41,24 +73,51 @@ func (a *AddPartitionsToTxnRequest) decode(pd packetDecoder, version int16) (err
 		return err
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 3 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
 
 	a.TopicPartitions = make(map[string][]int32)
 	for i := 0; i < n; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if version >= 3 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
 
-		partitions, err := pd.getInt32Array()
+		var partitions []int32
+		if version >= 3 {
+			partitions, err = pd.getCompactInt32Array()
+		} else {
+			partitions, err = pd.getInt32Array()
+		}
 		if err != nil {
 			return err
 		}
 
 		a.TopicPartitions[topic] = partitions
+
+		if version >= 3 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -69,13 +128,25 @@ func (a *AddPartitionsToTxnRequest) key() int16 {
 }
 
 func (a *AddPartitionsToTxnRequest) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *AddPartitionsToTxnRequest) headerVersion() int16 {
+	if a.Version >= 3 {
+		return 2
+	}
 	return 1
 }
 
 func (a *AddPartitionsToTxnRequest) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch a.Version {
+	case 3:
+		return V2_8_0_0
+	case 2:
+		return V2_7_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
 }
@@ -23,3 +23,28 @@ func TestAddPartitionsToTxnRequest(t *testing.T) {
 
 	testRequest(t, "", req, addPartitionsToTxnRequest)
 }
+
+var addPartitionsToTxnRequestV3 = []byte{
+	4, 't', 'x', 'n',
+	0, 0, 0, 0, 0, 0, 31, 64, // ProducerID
+	0, 0, // ProducerEpoch
+	2, // 1 topic
+	6, 't', 'o', 'p', 'i', 'c',
+	2, 0, 0, 0, 1,
+	0, // empty tagged fields
+	0, // empty tagged fields
+}
+
+func TestAddPartitionsToTxnRequestV3(t *testing.T) {
+	req := &AddPartitionsToTxnRequest{
+		Version:         3,
+		TransactionalID: "txn",
+		ProducerID:      8000,
+		ProducerEpoch:   0,
+		TopicPartitions: map[string][]int32{
+			"topic": {1},
+		},
+	}
+
+	testRequest(t, "", req, addPartitionsToTxnRequestV3)
+}
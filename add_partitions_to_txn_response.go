@@ -6,41 +6,65 @@ import (
 
 // AddPartitionsToTxnResponse is a partition errors to transaction type
 type AddPartitionsToTxnResponse struct {
+	Version      int16
 	ThrottleTime time.Duration
 	Errors       map[string][]*PartitionError
 }
 
-func (a *AddPartitionsToTxnResponse) encode(pe packetEncoder) error {
+func (a *AddPartitionsToTxnResponse) encode(pe packetEncoder) (err error) {
 	pe.putInt32(int32(a.ThrottleTime / time.Millisecond))
-	if err := pe.putArrayLength(len(a.Errors)); err != nil {
+	if a.Version >= 3 {
+		pe.putCompactArrayLength(len(a.Errors))
+	} else if err = pe.putArrayLength(len(a.Errors)); err != nil {
 		return err
 	}
 
 	for topic, e := range a.Errors {
-		if err := pe.putString(topic); err != nil {
+		if a.Version >= 3 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
+		if err != nil {
 			return err
 		}
-		if err := pe.putArrayLength(len(e)); err != nil {
+		if a.Version >= 3 {
+			pe.putCompactArrayLength(len(e))
+		} else if err = pe.putArrayLength(len(e)); err != nil {
 			return err
 		}
 		for _, partitionError := range e {
-			if err := partitionError.encode(pe); err != nil {
+			if err = partitionError.encode(pe, a.Version); err != nil {
 				return err
 			}
 		}
+		if a.Version >= 3 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if a.Version >= 3 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
 }
 
 func (a *AddPartitionsToTxnResponse) decode(pd packetDecoder, version int16) (err error) {
+	a.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
 	}
 	a.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 3 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -48,12 +72,22 @@ func (a *AddPartitionsToTxnResponse) decode(pd packetDecoder, version int16) (er
 	a.Errors = make(map[string][]*PartitionError)
 
 	for i := 0; i < n; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if version >= 3 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
 
-		m, err := pd.getArrayLength()
+		var m int
+		if version >= 3 {
+			m, err = pd.getCompactArrayLength()
+		} else {
+			m, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -66,6 +100,18 @@ func (a *AddPartitionsToTxnResponse) decode(pd packetDecoder, version int16) (er
 				return err
 			}
 		}
+
+		if version >= 3 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -76,26 +122,43 @@ func (a *AddPartitionsToTxnResponse) key() int16 {
 }
 
 func (a *AddPartitionsToTxnResponse) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *AddPartitionsToTxnResponse) headerVersion() int16 {
+	if a.Version >= 3 {
+		return 1
+	}
 	return 0
 }
 
 func (a *AddPartitionsToTxnResponse) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch a.Version {
+	case 3:
+		return V2_8_0_0
+	case 2:
+		return V2_7_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
 }
 
-// PartitionError is a partition error type
+// PartitionError is a partition error type. It is shared by the
+// AddPartitionsToTxn and TxnOffsetCommit responses, which both become
+// flexible at version 3.
 type PartitionError struct {
 	Partition int32
 	Err       KError
 }
 
-func (p *PartitionError) encode(pe packetEncoder) error {
+func (p *PartitionError) encode(pe packetEncoder, version int16) error {
 	pe.putInt32(p.Partition)
 	pe.putInt16(int16(p.Err))
+	if version >= 3 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
@@ -110,5 +173,35 @@ func (p *PartitionError) decode(pd packetDecoder, version int16) (err error) {
 	}
 	p.Err = KError(kerr)
 
+	if version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+func (a *AddPartitionsToTxnResponse) throttleTime() time.Duration {
+	return a.ThrottleTime
+}
+
+func (a *AddPartitionsToTxnResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
+}
+
+func (a *AddPartitionsToTxnResponse) hasErrorCode() bool {
+	return hasPartitionError(a.Errors)
+}
+
+// hasPartitionError reports whether some of the partitions have an error code.
+func hasPartitionError(topics map[string][]*PartitionError) bool {
+	for _, partitions := range topics {
+		for _, partition := range partitions {
+			if partition.Err != ErrNoError {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -27,3 +27,30 @@ func TestAddPartitionsToTxnResponse(t *testing.T) {
 
 	testResponse(t, "", resp, addPartitionsToTxnResponse)
 }
+
+var addPartitionsToTxnResponseV3 = []byte{
+	0, 0, 0, 100,
+	2,
+	6, 't', 'o', 'p', 'i', 'c',
+	2,          // 1 partition error
+	0, 0, 0, 2, // partition 2
+	0, 48, // error
+	0, // empty tagged fields
+	0, // empty tagged fields
+	0, // empty tagged fields
+}
+
+func TestAddPartitionsToTxnResponseV3(t *testing.T) {
+	resp := &AddPartitionsToTxnResponse{
+		Version:      3,
+		ThrottleTime: 100 * time.Millisecond,
+		Errors: map[string][]*PartitionError{
+			"topic": {{
+				Err:       ErrInvalidTxnState,
+				Partition: 2,
+			}},
+		},
+	}
+
+	testResponse(t, "", resp, addPartitionsToTxnResponseV3)
+}
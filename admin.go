@@ -1,9 +1,13 @@
 package sarama
 
 import (
+	"context"
 	"errors"
 	"fmt"
-	"math/rand"
+	"io"
+	"math"
+	"net"
+	"sort"
 	"strconv"
 	"sync"
 	"time"
@@ -20,12 +24,30 @@ type ClusterAdmin interface {
 	// may not return information about the new topic.The validateOnly option is supported from version 0.10.2.0.
 	CreateTopic(topic string, detail *TopicDetail, validateOnly bool) error
 
+	// Creates several topics at once and reports the outcome of each of them.
+	// When validateOnly is true the brokers check the topics against their
+	// policies without creating them. Brokers with version 2.4.0.0 or higher
+	// also report the partition count, replication factor and configuration
+	// each topic is (or would be) created with. The returned error is only
+	// set when the request itself failed, per-topic failures are reported in
+	// the results.
+	CreateTopics(topics map[string]*TopicDetail, validateOnly bool) (map[string]*CreateTopicResult, error)
+
 	// List the topics available in the cluster with the default options.
 	ListTopics() (map[string]TopicDetail, error)
 
 	// Describe some topics in the cluster.
 	DescribeTopics(topics []string) (metadata []*TopicMetadata, err error)
 
+	// Describe the partitions of some topics, or of every topic when topics is
+	// empty. The partitions are fetched page by page through
+	// DescribeTopicPartitions, so that large clusters never have to return the
+	// metadata of all their partitions in a single response. The pages are
+	// merged before returning, topic errors are reported in the results.
+	// This operation is supported by brokers with version 3.8.0.0 or higher,
+	// older brokers are described through a MetadataRequest instead.
+	DescribeTopicPartitions(topics []string) ([]*DescribeTopicPartitionsResponseTopic, error)
+
 	// Delete a topic. It may take several seconds after the DeleteTopic to returns success
 	// and for all the brokers to become aware that the topics are gone.
 	// During this time, listTopics  may continue to return information about the deleted topic.
@@ -50,6 +72,12 @@ type ClusterAdmin interface {
 	// This operation is supported by brokers with version 2.4.0.0 or higher.
 	ListPartitionReassignments(topics string, partitions []int32) (topicStatus map[string]map[int32]*PartitionReplicaReassignmentsStatus, err error)
 
+	// Triggers leader elections for the given partitions, or for every partition of the
+	// cluster when partitions is nil, and returns the outcome of each election.
+	// This operation is supported by brokers with version 2.2.0.0 or higher, unclean
+	// elections by brokers with version 2.4.0.0 or higher.
+	ElectLeaders(electionType ElectionType, partitions map[string][]int32) (map[string]map[int32]*PartitionResult, error)
+
 	// Delete records whose offset is smaller than the given offset of the corresponding partition.
 	// This operation is supported by brokers with version 0.11.0.0 or higher.
 	DeleteRecords(topic string, partitionOffsets map[int32]int64) error
@@ -82,6 +110,12 @@ type ClusterAdmin interface {
 	// no changes will be made. This operation is supported by brokers with version 0.11.0.0 or higher.
 	CreateACL(resource Resource, acl Acl) error
 
+	// Creates several access control lists (ACLs) in a single request.
+	// This operation is not transactional so it may succeed for some ACLs while fail for others,
+	// the returned error wraps ErrCreateACLs along with the failure of each rejected ACL.
+	// This operation is supported by brokers with version 0.11.0.0 or higher.
+	CreateACLs(resourceACLs []*ResourceAcls) error
+
 	// Lists access control lists (ACLs) according to the supplied filter.
 	// it may take some time for changes made by createAcls or deleteAcls to be reflected in the output of ListAcls
 	// This operation is supported by brokers with version 0.11.0.0 or higher.
@@ -95,19 +129,40 @@ type ClusterAdmin interface {
 	// List the consumer groups available in the cluster.
 	ListConsumerGroups() (map[string]string, error)
 
+	// List the consumer groups available in the cluster that match the
+	// given options, along with their state and type where the brokers
+	// report them. Filtering by state requires brokers with version 2.6.0.0
+	// or higher, filtering by type requires version 3.8.0.0 or higher.
+	ListConsumerGroupsWithOptions(opts ListConsumerGroupsOptions) (map[string]ConsumerGroupListing, error)
+
 	// Describe the given consumer groups.
 	DescribeConsumerGroups(groups []string) ([]*GroupDescription, error)
 
 	// List the consumer group offsets available in the cluster.
 	ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*OffsetFetchResponse, error)
 
+	// Commits the given offsets on behalf of a consumer group, for instance to reset or
+	// restore them. The group must not have any active member, otherwise the broker
+	// rejects the offsets with ErrUnknownMemberId or ErrIllegalGeneration.
+	AlterConsumerGroupOffsets(group string, offsets map[string]map[int32]GroupOffset) error
+
+	// Looks up, for each of the given partitions, the offset of the first message whose
+	// timestamp is at least time, or the earliest or latest offset when time is OffsetOldest
+	// or OffsetNewest. A nil partition list stands for every partition of the topic.
+	// The offset is -1 when no message is recent enough. The offsets are looked up as
+	// Client.GetOffsets does, refreshing the metadata of the partitions whose leader
+	// failed; if some partitions failed, it returns the offsets of the others along with
+	// the first error.
+	ListOffsets(topicPartitions map[string][]int32, time int64) (map[string]map[int32]int64, error)
+
 	// Deletes a consumer group offset
 	DeleteConsumerGroupOffset(group string, topic string, partition int32) error
 
 	// Delete a consumer group.
 	DeleteConsumerGroup(group string) error
 
-	// Get information about the nodes in the cluster
+	// Get information about the nodes in the cluster, or about the KRaft
+	// controllers when created by NewClusterAdminFromControllers.
 	DescribeCluster() (brokers []*Broker, controllerID int32, err error)
 
 	// Get information about all log directories on the given set of brokers
@@ -134,6 +189,13 @@ type ClusterAdmin interface {
 	// locally cached value if it's available.
 	Controller() (*Broker, error)
 
+	// WithContext returns a ClusterAdmin sharing the same underlying client
+	// whose operations are bound to ctx: they return ctx.Err() as soon as the
+	// context is cancelled or its deadline expires, and stop retrying. Requests
+	// already written to a broker are not recalled and may still take effect.
+	// Closing the returned admin closes the shared client.
+	WithContext(ctx context.Context) ClusterAdmin
+
 	// Close shuts down the admin and closes underlying client.
 	Close() error
 }
@@ -141,6 +203,7 @@ type ClusterAdmin interface {
 type clusterAdmin struct {
 	client Client
 	conf   *Config
+	ctx    context.Context // nil unless bound through WithContext
 }
 
 // NewClusterAdmin creates a new ClusterAdmin using the given broker addresses and configuration.
@@ -153,11 +216,24 @@ func NewClusterAdmin(addrs []string, conf *Config) (ClusterAdmin, error) {
 }
 
 // NewClusterAdminFromClient creates a new ClusterAdmin using the given client.
-// Note that underlying client will also be closed on admin's Close() call.
+// Note that underlying client will also be closed on admin's Close() call,
+// unless it was shared with ShareClient, in which case the admin only releases
+// its reference to it.
 func NewClusterAdminFromClient(client Client) (ClusterAdmin, error) {
+	if shared, ok := client.(*sharedClient); ok {
+		ref, err := shared.acquire()
+		if err != nil {
+			return nil, err
+		}
+		client = ref
+	}
+
 	// make sure we can retrieve the controller
 	_, err := client.Controller()
 	if err != nil {
+		if _, ok := client.(*sharedClient); ok {
+			_ = client.Close()
+		}
 		return nil, err
 	}
 
@@ -186,6 +262,51 @@ func isErrNoController(err error) bool {
 	return errors.Is(err, ErrNotController)
 }
 
+// isErrControllerMoved returns `true` if the given error indicates that the
+// request should be sent again to a freshly resolved controller, either
+// because the broker is no longer the controller or because it could not be
+// reached at all
+func isErrControllerMoved(err error) bool {
+	return isErrNoController(err) || errors.Is(err, ErrControllerNotAvailable) || isErrTransport(err)
+}
+
+// isErrTransport returns `true` if the given error was raised while talking to
+// the broker rather than returned by it. The request may or may not have been
+// processed by the broker in that case.
+func isErrTransport(err error) bool {
+	var netErr net.Error
+	return errors.Is(err, ErrNotConnected) ||
+		errors.Is(err, io.EOF) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.As(err, &netErr)
+}
+
+// retryOnController calls fn with the current controller, re-resolving the
+// controller and retrying whenever it has moved or could not be reached, up to
+// the maximum number of tries permitted by the admin client configuration.
+// uncertain is true when an earlier attempt failed in a way that leaves open
+// whether the cluster applied it, so that non-idempotent operations can treat
+// their "already done" errors as success.
+func (ca *clusterAdmin) retryOnController(fn func(b *Broker, uncertain bool) error) error {
+	uncertain := false
+	return ca.retryOnError(isErrControllerMoved, func() error {
+		b, err := ca.Controller()
+		if err == nil {
+			err = fn(b, uncertain)
+		}
+		if isErrControllerMoved(err) {
+			if isErrTransport(err) {
+				uncertain = true
+				if b != nil {
+					safeAsyncClose(b)
+				}
+			}
+			_, _ = ca.refreshController()
+		}
+		return err
+	})
+}
+
 // retryOnError will repeatedly call the given (error-returning) func in the
 // case that its response is non-nil and retryable (as determined by the
 // provided retryable func) up to the maximum number of tries permitted by
@@ -194,32 +315,101 @@ func (ca *clusterAdmin) retryOnError(retryable func(error) bool, fn func() error
 	var err error
 	for attempt := 0; attempt < ca.conf.Admin.Retry.Max; attempt++ {
 		err = fn()
-		if err == nil || !retryable(err) {
+		if err == nil || !retryable(err) || !ca.conf.allowRetry("admin/request") {
 			return err
 		}
-		Logger.Printf(
+		backoff := ca.conf.Admin.Retry.Backoff
+		if ca.conf.Admin.Retry.BackoffFunc != nil {
+			backoff = ca.conf.Admin.Retry.BackoffFunc(attempt+1, ca.conf.Admin.Retry.Max)
+		}
+		infof(
 			"admin/request retrying after %dms... (%d attempts remaining)\n",
-			ca.conf.Admin.Retry.Backoff/time.Millisecond, ca.conf.Admin.Retry.Max-attempt)
-		time.Sleep(ca.conf.Admin.Retry.Backoff)
-		continue
+			backoff/time.Millisecond, ca.conf.Admin.Retry.Max-attempt)
+		if ctxErr := ca.backoff(backoff); ctxErr != nil {
+			return ctxErr
+		}
 	}
 	return err
 }
 
-func (ca *clusterAdmin) CreateTopic(topic string, detail *TopicDetail, validateOnly bool) error {
-	if topic == "" {
-		return ErrInvalidTopic
+// backoff sleeps for the given duration. If the admin is bound to a context
+// it returns early with the context error once the context is done.
+func (ca *clusterAdmin) backoff(d time.Duration) error {
+	if ca.ctx == nil {
+		time.Sleep(d)
+		return nil
+	}
+
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ca.ctx.Done():
+		return ca.ctx.Err()
+	}
+}
+
+// CreateTopicResult is the outcome of creating a single topic through
+// CreateTopics.
+type CreateTopicResult struct {
+	Topic string
+	// Err is nil if the topic was created or passed validation, otherwise it
+	// holds the error returned by the broker, usually a *TopicError carrying
+	// the broker's explanation.
+	Err error
+	// Detail is what the broker created or would create, or nil on error and
+	// for brokers older than 2.4.0.0.
+	Detail *CreatedTopicDetail
+	// Warnings lists requested config entries which the broker reported with
+	// a different value, for instance because a policy overrode them.
+	Warnings []string
+}
+
+func newCreateTopicResult(topic string, requested *TopicDetail, topicErr *TopicError, created *CreatedTopicDetail) *CreateTopicResult {
+	result := &CreateTopicResult{Topic: topic}
+	if !errors.Is(topicErr.Err, ErrNoError) {
+		result.Err = topicErr
+		return result
 	}
 
-	if detail == nil {
-		return errors.New("you must specify topic details")
+	result.Detail = created
+	if created == nil {
+		return result
+	}
+	for _, config := range created.Configs {
+		want, ok := requested.ConfigEntries[config.Name]
+		if !ok || want == nil || config.Sensitive || config.Value == nil || *config.Value == *want {
+			continue
+		}
+		result.Warnings = append(result.Warnings, fmt.Sprintf(
+			"config %s was requested as %q but is %q", config.Name, *want, *config.Value))
 	}
+	sort.Strings(result.Warnings)
+	return result
+}
 
-	topicDetails := make(map[string]*TopicDetail)
-	topicDetails[topic] = detail
+func (ca *clusterAdmin) CreateTopic(topic string, detail *TopicDetail, validateOnly bool) error {
+	results, err := ca.CreateTopics(map[string]*TopicDetail{topic: detail}, validateOnly)
+	if err != nil {
+		return err
+	}
+	return results[topic].Err
+}
+
+func (ca *clusterAdmin) CreateTopics(topics map[string]*TopicDetail, validateOnly bool) (map[string]*CreateTopicResult, error) {
+	pending := make(map[string]*TopicDetail, len(topics))
+	for topic, detail := range topics {
+		if topic == "" {
+			return nil, ErrInvalidTopic
+		}
+		if detail == nil {
+			return nil, errors.New("you must specify topic details")
+		}
+		pending[topic] = detail
+	}
 
 	request := &CreateTopicsRequest{
-		TopicDetails: topicDetails,
 		ValidateOnly: validateOnly,
 		Timeout:      ca.conf.Admin.Timeout,
 	}
@@ -230,62 +420,169 @@ func (ca *clusterAdmin) CreateTopic(topic string, detail *TopicDetail, validateO
 	if ca.conf.Version.IsAtLeast(V1_0_0_0) {
 		request.Version = 2
 	}
+	if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+		request.Version = 3
+	}
+	if ca.conf.Version.IsAtLeast(V2_4_0_0) {
+		request.Version = 5
+	}
 
-	return ca.retryOnError(isErrNoController, func() error {
-		b, err := ca.Controller()
-		if err != nil {
-			return err
-		}
-
+	results := make(map[string]*CreateTopicResult, len(topics))
+	err := ca.retryOnController(func(b *Broker, uncertain bool) error {
+		// only resend the topics the previous controller did not handle, so
+		// that the ones it created are not reported as already existing
+		request.TopicDetails = pending
 		rsp, err := b.CreateTopics(request)
 		if err != nil {
 			return err
 		}
 
-		topicErr, ok := rsp.TopicErrors[topic]
-		if !ok {
-			return ErrIncompleteResponse
-		}
-
-		if !errors.Is(topicErr.Err, ErrNoError) {
+		retry := make(map[string]*TopicDetail)
+		for topic, detail := range pending {
+			topicErr, ok := rsp.TopicErrors[topic]
+			if !ok {
+				results[topic] = &CreateTopicResult{Topic: topic, Err: ErrIncompleteResponse}
+				continue
+			}
 			if errors.Is(topicErr.Err, ErrNotController) {
-				_, _ = ca.refreshController()
+				retry[topic] = detail
+				continue
 			}
-			return topicErr
+			if uncertain && !validateOnly && errors.Is(topicErr.Err, ErrTopicAlreadyExists) {
+				// created by an earlier attempt whose response was lost
+				topicErr = &TopicError{Err: ErrNoError}
+			}
+			results[topic] = newCreateTopicResult(topic, detail, topicErr, rsp.TopicDetails[topic])
 		}
 
+		pending = retry
+		if len(pending) > 0 {
+			return ErrNotController
+		}
 		return nil
 	})
-}
-
-func (ca *clusterAdmin) DescribeTopics(topics []string) (metadata []*TopicMetadata, err error) {
-	controller, err := ca.Controller()
 	if err != nil {
-		return nil, err
+		if len(results) == 0 {
+			return nil, err
+		}
+		for topic := range pending {
+			results[topic] = &CreateTopicResult{Topic: topic, Err: err}
+		}
 	}
 
+	return results, nil
+}
+
+func (ca *clusterAdmin) DescribeTopics(topics []string) (metadata []*TopicMetadata, err error) {
 	request := &MetadataRequest{
 		Topics:                 topics,
 		AllowAutoTopicCreation: false,
 	}
 
-	if ca.conf.Version.IsAtLeast(V1_0_0_0) {
+	if ca.conf.Version.IsAtLeast(V2_8_0_0) {
+		request.Version = 10
+	} else if ca.conf.Version.IsAtLeast(V1_0_0_0) {
 		request.Version = 5
 	} else if ca.conf.Version.IsAtLeast(V0_11_0_0) {
 		request.Version = 4
 	}
 
-	response, err := controller.GetMetadata(request)
+	err = ca.retryOnController(func(b *Broker, _ bool) error {
+		response, err := b.GetMetadata(request)
+		if err != nil {
+			return err
+		}
+		metadata = response.Topics
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	return response.Topics, nil
+	return metadata, nil
+}
+
+// describeTopicPartitionsLimit is the number of partitions requested per
+// DescribeTopicPartitions page, which is the default maximum of the brokers.
+const describeTopicPartitionsLimit = 2000
+
+func (ca *clusterAdmin) DescribeTopicPartitions(topics []string) ([]*DescribeTopicPartitionsResponseTopic, error) {
+	if len(topics) == 0 {
+		topics = nil
+	}
+
+	if !ca.conf.Version.IsAtLeast(V3_8_0_0) {
+		metadata, err := ca.DescribeTopics(topics)
+		if err != nil {
+			return nil, err
+		}
+		return describeTopicPartitionsFromMetadata(metadata), nil
+	}
+
+	request := &DescribeTopicPartitionsRequest{
+		Topics:                 topics,
+		ResponsePartitionLimit: describeTopicPartitionsLimit,
+	}
+
+	var results []*DescribeTopicPartitionsResponseTopic
+	byName := make(map[string]*DescribeTopicPartitionsResponseTopic)
+	for {
+		var response *DescribeTopicPartitionsResponse
+		err := ca.retryOnController(func(b *Broker, _ bool) (err error) {
+			response, err = b.DescribeTopicPartitions(request)
+			return err
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		// a topic whose partitions span several pages is returned in each
+		for _, topic := range response.Topics {
+			if existing, ok := byName[topic.Name]; ok {
+				existing.Partitions = append(existing.Partitions, topic.Partitions...)
+				continue
+			}
+			byName[topic.Name] = topic
+			results = append(results, topic)
+		}
+
+		if response.NextCursor == nil {
+			return results, nil
+		}
+		request.Cursor = response.NextCursor
+	}
+}
+
+// describeTopicPartitionsFromMetadata converts the metadata of older brokers,
+// which lacks topic IDs and leader epochs.
+func describeTopicPartitionsFromMetadata(metadata []*TopicMetadata) []*DescribeTopicPartitionsResponseTopic {
+	results := make([]*DescribeTopicPartitionsResponseTopic, 0, len(metadata))
+	for _, topic := range metadata {
+		result := &DescribeTopicPartitionsResponseTopic{
+			Err:                       topic.Err,
+			Name:                      topic.Name,
+			IsInternal:                topic.IsInternal,
+			Partitions:                make([]*DescribeTopicPartitionsResponsePartition, 0, len(topic.Partitions)),
+			TopicAuthorizedOperations: math.MinInt32,
+		}
+		for _, partition := range topic.Partitions {
+			result.Partitions = append(result.Partitions, &DescribeTopicPartitionsResponsePartition{
+				Err:             partition.Err,
+				ID:              partition.ID,
+				Leader:          partition.Leader,
+				LeaderEpoch:     -1,
+				Replicas:        partition.Replicas,
+				Isr:             partition.Isr,
+				OfflineReplicas: partition.OfflineReplicas,
+			})
+		}
+		results = append(results, result)
+	}
+	return results
 }
 
 func (ca *clusterAdmin) DescribeCluster() (brokers []*Broker, controllerID int32, err error) {
-	controller, err := ca.Controller()
-	if err != nil {
-		return nil, int32(0), err
+	if controllers, ok := ca.client.(*controllerClient); ok {
+		return controllers.describeCluster()
 	}
 
 	request := &MetadataRequest{
@@ -296,12 +593,19 @@ func (ca *clusterAdmin) DescribeCluster() (brokers []*Broker, controllerID int32
 		request.Version = 1
 	}
 
-	response, err := controller.GetMetadata(request)
+	err = ca.retryOnController(func(b *Broker, _ bool) error {
+		response, err := b.GetMetadata(request)
+		if err != nil {
+			return err
+		}
+		brokers, controllerID = response.Brokers, response.ControllerID
+		return nil
+	})
 	if err != nil {
 		return nil, int32(0), err
 	}
 
-	return response.Brokers, response.ControllerID, nil
+	return brokers, controllerID, nil
 }
 
 func (ca *clusterAdmin) findBroker(id int32) (*Broker, error) {
@@ -315,10 +619,8 @@ func (ca *clusterAdmin) findBroker(id int32) (*Broker, error) {
 }
 
 func (ca *clusterAdmin) findAnyBroker() (*Broker, error) {
-	brokers := ca.client.Brokers()
-	if len(brokers) > 0 {
-		index := rand.Intn(len(brokers))
-		return brokers[index], nil
+	if broker := ca.client.LeastLoadedBroker(); broker != nil {
+		return broker, nil
 	}
 	return nil, errors.New("no available broker")
 }
@@ -418,12 +720,7 @@ func (ca *clusterAdmin) DeleteTopic(topic string) error {
 		request.Version = 1
 	}
 
-	return ca.retryOnError(isErrNoController, func() error {
-		b, err := ca.Controller()
-		if err != nil {
-			return err
-		}
-
+	return ca.retryOnController(func(b *Broker, uncertain bool) error {
 		rsp, err := b.DeleteTopics(request)
 		if err != nil {
 			return err
@@ -435,8 +732,9 @@ func (ca *clusterAdmin) DeleteTopic(topic string) error {
 		}
 
 		if !errors.Is(topicErr, ErrNoError) {
-			if errors.Is(topicErr, ErrNotController) {
-				_, _ = ca.refreshController()
+			if uncertain && errors.Is(topicErr, ErrUnknownTopicOrPartition) {
+				// deleted by an earlier attempt whose response was lost
+				return nil
 			}
 			return topicErr
 		}
@@ -459,12 +757,7 @@ func (ca *clusterAdmin) CreatePartitions(topic string, count int32, assignment [
 		ValidateOnly:    validateOnly,
 	}
 
-	return ca.retryOnError(isErrNoController, func() error {
-		b, err := ca.Controller()
-		if err != nil {
-			return err
-		}
-
+	return ca.retryOnController(func(b *Broker, uncertain bool) error {
 		rsp, err := b.CreatePartitions(request)
 		if err != nil {
 			return err
@@ -476,8 +769,9 @@ func (ca *clusterAdmin) CreatePartitions(topic string, count int32, assignment [
 		}
 
 		if !errors.Is(topicErr.Err, ErrNoError) {
-			if errors.Is(topicErr.Err, ErrNotController) {
-				_, _ = ca.refreshController()
+			if uncertain && !validateOnly && errors.Is(topicErr.Err, ErrInvalidPartitions) && ca.hasPartitionCount(b, topic, count) {
+				// grown by an earlier attempt whose response was lost
+				return nil
 			}
 			return topicErr
 		}
@@ -486,6 +780,16 @@ func (ca *clusterAdmin) CreatePartitions(topic string, count int32, assignment [
 	})
 }
 
+// hasPartitionCount reports whether the controller's metadata shows topic
+// with exactly count partitions.
+func (ca *clusterAdmin) hasPartitionCount(b *Broker, topic string, count int32) bool {
+	rsp, err := b.GetMetadata(&MetadataRequest{Topics: []string{topic}})
+	if err != nil || len(rsp.Topics) != 1 {
+		return false
+	}
+	return errors.Is(rsp.Topics[0].Err, ErrNoError) && int32(len(rsp.Topics[0].Partitions)) == count
+}
+
 func (ca *clusterAdmin) AlterPartitionReassignments(topic string, assignment [][]int32) error {
 	if topic == "" {
 		return ErrInvalidTopic
@@ -500,29 +804,25 @@ func (ca *clusterAdmin) AlterPartitionReassignments(topic string, assignment [][
 		request.AddBlock(topic, int32(i), assignment[i])
 	}
 
-	return ca.retryOnError(isErrNoController, func() error {
-		b, err := ca.Controller()
+	return ca.retryOnController(func(b *Broker, _ bool) error {
+		rsp, err := b.AlterPartitionReassignments(request)
 		if err != nil {
 			return err
 		}
+		if errors.Is(rsp.ErrorCode, ErrNotController) {
+			return rsp.ErrorCode
+		}
 
 		errs := make([]error, 0)
+		if rsp.ErrorCode > 0 {
+			errs = append(errs, errors.New(rsp.ErrorCode.Error()))
+		}
 
-		rsp, err := b.AlterPartitionReassignments(request)
-
-		if err != nil {
-			errs = append(errs, err)
-		} else {
-			if rsp.ErrorCode > 0 {
-				errs = append(errs, errors.New(rsp.ErrorCode.Error()))
-			}
-
-			for topic, topicErrors := range rsp.Errors {
-				for partition, partitionError := range topicErrors {
-					if !errors.Is(partitionError.errorCode, ErrNoError) {
-						errStr := fmt.Sprintf("[%s-%d]: %s", topic, partition, partitionError.errorCode.Error())
-						errs = append(errs, errors.New(errStr))
-					}
+		for topic, topicErrors := range rsp.Errors {
+			for partition, partitionError := range topicErrors {
+				if !errors.Is(partitionError.errorCode, ErrNoError) {
+					errStr := fmt.Sprintf("[%s-%d]: %s", topic, partition, partitionError.errorCode.Error())
+					errs = append(errs, errors.New(errStr))
 				}
 			}
 		}
@@ -547,19 +847,52 @@ func (ca *clusterAdmin) ListPartitionReassignments(topic string, partitions []in
 
 	request.AddBlock(topic, partitions)
 
-	b, err := ca.Controller()
+	err = ca.retryOnController(func(b *Broker, _ bool) error {
+		rsp, err := b.ListPartitionReassignments(request)
+		if err != nil {
+			return err
+		}
+		if errors.Is(rsp.ErrorCode, ErrNotController) {
+			return rsp.ErrorCode
+		}
+		topicStatus = rsp.TopicStatus
+		return nil
+	})
 	if err != nil {
 		return nil, err
 	}
-	_ = b.Open(ca.client.Config())
+	return topicStatus, nil
+}
 
-	rsp, err := b.ListPartitionReassignments(request)
+func (ca *clusterAdmin) ElectLeaders(electionType ElectionType, partitions map[string][]int32) (map[string]map[int32]*PartitionResult, error) {
+	request := &ElectLeadersRequest{
+		Type:            electionType,
+		TopicPartitions: partitions,
+		TimeoutMs:       int32(ca.conf.Admin.Timeout / time.Millisecond),
+	}
 
-	if err == nil && rsp != nil {
-		return rsp.TopicStatus, nil
-	} else {
+	if ca.conf.Version.IsAtLeast(V2_4_0_0) {
+		request.Version = 2
+	} else if electionType != PreferredElection {
+		return nil, ConfigurationError("unclean leader elections require Version >= V2_4_0_0")
+	}
+
+	var results map[string]map[int32]*PartitionResult
+	err := ca.retryOnController(func(b *Broker, _ bool) error {
+		rsp, err := b.ElectLeaders(request)
+		if err != nil {
+			return err
+		}
+		if !errors.Is(rsp.ErrorCode, ErrNoError) {
+			return rsp.ErrorCode
+		}
+		results = rsp.ReplicaElectionResults
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
+	return results, nil
 }
 
 func (ca *clusterAdmin) DeleteRecords(topic string, partitionOffsets map[int32]int64) error {
@@ -790,13 +1123,60 @@ func (ca *clusterAdmin) CreateACL(resource Resource, acl Acl) error {
 		request.Version = 1
 	}
 
-	b, err := ca.Controller()
+	return ca.retryOnController(func(b *Broker, _ bool) error {
+		_, err := b.CreateAcls(request)
+		return err
+	})
+}
+
+func (ca *clusterAdmin) CreateACLs(resourceACLs []*ResourceAcls) error {
+	var acls []*AclCreation
+	for _, resourceACL := range resourceACLs {
+		for _, acl := range resourceACL.Acls {
+			acls = append(acls, &AclCreation{resourceACL.Resource, *acl})
+		}
+	}
+	if len(acls) == 0 {
+		return nil
+	}
+	request := &CreateAclsRequest{AclCreations: acls}
+
+	if ca.conf.Version.IsAtLeast(V2_0_0_0) {
+		request.Version = 1
+	}
+
+	var rsp *CreateAclsResponse
+	err := ca.retryOnController(func(b *Broker, _ bool) (err error) {
+		rsp, err = b.CreateAcls(request)
+		if rsp != nil {
+			// the failures of individual ACLs are reported below
+			return nil
+		}
+		return err
+	})
 	if err != nil {
 		return err
 	}
 
-	_, err = b.CreateAcls(request)
-	return err
+	var errs []error
+	for i, response := range rsp.AclCreationResponses {
+		if errors.Is(response.Err, ErrNoError) || i >= len(acls) {
+			continue
+		}
+		creation := acls[i]
+		err := fmt.Errorf("[%s %s] %s on %s %q: %w",
+			creation.Acl.PermissionType.String(), creation.Acl.Principal,
+			creation.Acl.Operation.String(), creation.Resource.ResourceType.String(),
+			creation.Resource.ResourceName, response.Err)
+		if response.ErrMsg != nil {
+			err = fmt.Errorf("%w: %s", err, *response.ErrMsg)
+		}
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return Wrap(ErrCreateACLs, errs...)
+	}
+	return nil
 }
 
 func (ca *clusterAdmin) ListAcls(filter AclFilter) ([]ResourceAcls, error) {
@@ -806,12 +1186,11 @@ func (ca *clusterAdmin) ListAcls(filter AclFilter) ([]ResourceAcls, error) {
 		request.Version = 1
 	}
 
-	b, err := ca.Controller()
-	if err != nil {
-		return nil, err
-	}
-
-	rsp, err := b.DescribeAcls(request)
+	var rsp *DescribeAclsResponse
+	err := ca.retryOnController(func(b *Broker, _ bool) (err error) {
+		rsp, err = b.DescribeAcls(request)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -832,12 +1211,11 @@ func (ca *clusterAdmin) DeleteACL(filter AclFilter, validateOnly bool) ([]Matchi
 		request.Version = 1
 	}
 
-	b, err := ca.Controller()
-	if err != nil {
-		return nil, err
-	}
-
-	rsp, err := b.DeleteAcls(request)
+	var rsp *DeleteAclsResponse
+	err := ca.retryOnController(func(b *Broker, _ bool) (err error) {
+		rsp, err = b.DeleteAcls(request)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -875,12 +1253,48 @@ func (ca *clusterAdmin) DescribeConsumerGroups(groups []string) (result []*Group
 	return result, nil
 }
 
-func (ca *clusterAdmin) ListConsumerGroups() (allGroups map[string]string, err error) {
-	allGroups = make(map[string]string)
+// ListConsumerGroupsOptions filters the groups returned by
+// ListConsumerGroupsWithOptions. Empty filters match every group.
+type ListConsumerGroupsOptions struct {
+	// States restricts the result to groups in one of the given states, such
+	// as "Stable" or "Empty".
+	States []string
+	// Types restricts the result to groups of one of the given types:
+	// "classic", "consumer" or "share".
+	Types []string
+}
+
+// ConsumerGroupListing describes a group returned by
+// ListConsumerGroupsWithOptions.
+type ConsumerGroupListing struct {
+	GroupID      string
+	ProtocolType string
+	State        string // empty before Kafka 2.6.0.0
+	Type         string // empty before Kafka 3.8.0.0
+}
 
-	// Query brokers in parallel, since we have to query *all* brokers
+func (ca *clusterAdmin) listGroupsRequest() *ListGroupsRequest {
+	request := &ListGroupsRequest{}
+	switch {
+	case ca.conf.Version.IsAtLeast(V3_8_0_0):
+		request.Version = 5
+	case ca.conf.Version.IsAtLeast(V2_6_0_0):
+		request.Version = 4
+	case ca.conf.Version.IsAtLeast(V2_4_0_0):
+		request.Version = 3
+	case ca.conf.Version.IsAtLeast(V2_0_0_0):
+		request.Version = 2
+	case ca.conf.Version.IsAtLeast(V0_11_0_0):
+		request.Version = 1
+	}
+	return request
+}
+
+// listGroups sends request to every broker in parallel, since groups are
+// spread over all their coordinators, and returns the responses received.
+func (ca *clusterAdmin) listGroups(request *ListGroupsRequest) ([]*ListGroupsResponse, error) {
 	brokers := ca.client.Brokers()
-	groupMaps := make(chan map[string]string, len(brokers))
+	responses := make(chan *ListGroupsResponse, len(brokers))
 	errChan := make(chan error, len(brokers))
 	wg := sync.WaitGroup{}
 
@@ -890,34 +1304,72 @@ func (ca *clusterAdmin) ListConsumerGroups() (allGroups map[string]string, err e
 			defer wg.Done()
 			_ = b.Open(conf) // Ensure that broker is opened
 
-			response, err := b.ListGroups(&ListGroupsRequest{})
+			response, err := b.ListGroups(request)
 			if err != nil {
 				errChan <- err
 				return
 			}
 
-			groups := make(map[string]string)
-			for group, typ := range response.Groups {
-				groups[group] = typ
-			}
-
-			groupMaps <- groups
+			responses <- response
 		}(b, ca.conf)
 	}
 
 	wg.Wait()
-	close(groupMaps)
+	close(responses)
 	close(errChan)
 
-	for groupMap := range groupMaps {
-		for group, protocolType := range groupMap {
+	result := make([]*ListGroupsResponse, 0, len(brokers))
+	for response := range responses {
+		result = append(result, response)
+	}
+
+	// Intentionally return only the first error for simplicity
+	return result, <-errChan
+}
+
+func (ca *clusterAdmin) ListConsumerGroups() (allGroups map[string]string, err error) {
+	allGroups = make(map[string]string)
+
+	responses, err := ca.listGroups(ca.listGroupsRequest())
+	for _, response := range responses {
+		for group, protocolType := range response.Groups {
 			allGroups[group] = protocolType
 		}
 	}
 
-	// Intentionally return only the first error for simplicity
-	err = <-errChan
-	return
+	return allGroups, err
+}
+
+func (ca *clusterAdmin) ListConsumerGroupsWithOptions(opts ListConsumerGroupsOptions) (map[string]ConsumerGroupListing, error) {
+	request := ca.listGroupsRequest()
+	if len(opts.States) > 0 && request.Version < 4 {
+		return nil, ConfigurationError("filtering consumer groups by state requires Version >= V2_6_0_0")
+	}
+	if len(opts.Types) > 0 && request.Version < 5 {
+		return nil, ConfigurationError("filtering consumer groups by type requires Version >= V3_8_0_0")
+	}
+	request.StatesFilter = opts.States
+	request.TypesFilter = opts.Types
+
+	responses, err := ca.listGroups(request)
+
+	groups := make(map[string]ConsumerGroupListing)
+	for _, response := range responses {
+		if err == nil && !errors.Is(response.Err, ErrNoError) {
+			err = response.Err
+		}
+		for group, protocolType := range response.Groups {
+			data := response.GroupsData[group]
+			groups[group] = ConsumerGroupListing{
+				GroupID:      group,
+				ProtocolType: protocolType,
+				State:        data.GroupState,
+				Type:         data.GroupType,
+			}
+		}
+	}
+
+	return groups, err
 }
 
 func (ca *clusterAdmin) ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*OffsetFetchResponse, error) {
@@ -940,6 +1392,73 @@ func (ca *clusterAdmin) ListConsumerGroupOffsets(group string, topicPartitions m
 	return coordinator.FetchOffset(request)
 }
 
+func (ca *clusterAdmin) AlterConsumerGroupOffsets(group string, offsets map[string]map[int32]GroupOffset) error {
+	return commitGroupOffsets(ca.client, group, offsets)
+}
+
+// commitGroupOffsets commits the offsets of the consumer group, which must
+// have no active members, through its coordinator.
+func commitGroupOffsets(client Client, group string, offsets map[string]map[int32]GroupOffset) error {
+	coordinator, err := client.Coordinator(group)
+	if err != nil {
+		return err
+	}
+
+	request := &OffsetCommitRequest{
+		Version:                 1,
+		ConsumerGroup:           group,
+		ConsumerGroupGeneration: GroupGenerationUndefined,
+	}
+	timestamp := ReceiveTime
+	if client.Config().Version.IsAtLeast(V0_9_0_0) {
+		request.Version = 2
+		request.RetentionTime = -1
+		timestamp = 0
+	}
+	for topic, partitions := range offsets {
+		for partition, offset := range partitions {
+			request.AddBlock(topic, partition, offset.Offset, timestamp, offset.Metadata)
+		}
+	}
+
+	rsp, err := coordinator.CommitOffset(request)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	for topic, partitions := range offsets {
+		for partition := range partitions {
+			kerr, ok := rsp.Errors[topic][partition]
+			switch {
+			case !ok:
+				errs = append(errs, fmt.Errorf("[%s-%d]: %w", topic, partition, ErrIncompleteResponse))
+			case !errors.Is(kerr, ErrNoError):
+				errs = append(errs, fmt.Errorf("[%s-%d]: %w", topic, partition, kerr))
+			}
+		}
+	}
+	if len(errs) > 0 {
+		return Wrap(ErrAlterConsumerGroupOffsets, errs...)
+	}
+	return nil
+}
+
+func (ca *clusterAdmin) ListOffsets(topicPartitions map[string][]int32, time int64) (map[string]map[int32]int64, error) {
+	partitions := make(map[string][]int32, len(topicPartitions))
+	for topic, ids := range topicPartitions {
+		if ids == nil {
+			all, err := ca.client.Partitions(topic)
+			if err != nil {
+				return nil, err
+			}
+			ids = all
+		}
+		partitions[topic] = ids
+	}
+	return ca.client.GetOffsets(partitions, time)
+}
+
 func (ca *clusterAdmin) DeleteConsumerGroupOffset(group string, topic string, partition int32) error {
 	coordinator, err := ca.client.Coordinator(group)
 	if err != nil {
@@ -1007,7 +1526,7 @@ func (ca *clusterAdmin) DescribeLogDirs(brokerIds []int32) (allLogDirs map[int32
 		wg.Add(1)
 		broker, err := ca.findBroker(b)
 		if err != nil {
-			Logger.Printf("Unable to find broker with ID = %v\n", b)
+			warnf("Unable to find broker with ID = %v\n", b)
 			continue
 		}
 		go func(b *Broker, conf *Config) {
@@ -1048,12 +1567,11 @@ func (ca *clusterAdmin) DescribeUserScramCredentials(users []string) ([]*Describ
 		})
 	}
 
-	b, err := ca.Controller()
-	if err != nil {
-		return nil, err
-	}
-
-	rsp, err := b.DescribeUserScramCredentials(req)
+	var rsp *DescribeUserScramCredentialsResponse
+	err := ca.retryOnController(func(b *Broker, _ bool) (err error) {
+		rsp, err = b.DescribeUserScramCredentials(req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -1085,12 +1603,11 @@ func (ca *clusterAdmin) AlterUserScramCredentials(u []AlterUserScramCredentialsU
 		Upsertions: u,
 	}
 
-	b, err := ca.Controller()
-	if err != nil {
-		return nil, err
-	}
-
-	rsp, err := b.AlterUserScramCredentials(req)
+	var rsp *AlterUserScramCredentialsResponse
+	err := ca.retryOnController(func(b *Broker, _ bool) (err error) {
+		rsp, err = b.AlterUserScramCredentials(req)
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -1107,12 +1624,14 @@ func (ca *clusterAdmin) DescribeClientQuotas(components []QuotaFilterComponent,
 		Strict:     strict,
 	}
 
-	b, err := ca.Controller()
-	if err != nil {
-		return nil, err
-	}
-
-	rsp, err := b.DescribeClientQuotas(request)
+	var rsp *DescribeClientQuotasResponse
+	err := ca.retryOnController(func(b *Broker, _ bool) (err error) {
+		rsp, err = b.DescribeClientQuotas(request)
+		if err == nil && errors.Is(rsp.ErrorCode, ErrNotController) {
+			err = rsp.ErrorCode
+		}
+		return err
+	})
 	if err != nil {
 		return nil, err
 	}
@@ -1138,12 +1657,14 @@ func (ca *clusterAdmin) AlterClientQuotas(entity []QuotaEntityComponent, op Clie
 		ValidateOnly: validateOnly,
 	}
 
-	b, err := ca.Controller()
-	if err != nil {
+	var rsp *AlterClientQuotasResponse
+	err := ca.retryOnController(func(b *Broker, _ bool) (err error) {
+		rsp, err = b.AlterClientQuotas(request)
+		if err == nil && len(rsp.Entries) > 0 && errors.Is(rsp.Entries[0].ErrorCode, ErrNotController) {
+			err = rsp.Entries[0].ErrorCode
+		}
 		return err
-	}
-
-	rsp, err := b.AlterClientQuotas(request)
+	})
 	if err != nil {
 		return err
 	}
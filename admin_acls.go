@@ -0,0 +1,151 @@
+package sarama
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrApplyACLs is returned when one or more changes computed by ApplyACLs
+// could not be applied
+var ErrApplyACLs = errors.New("kafka: failed to apply one or more ACL changes")
+
+// AclApplyOptions controls how ApplyACLs brings the cluster in line with the
+// desired ACL bindings.
+type AclApplyOptions struct {
+	// DryRun computes the changes without applying any of them.
+	DryRun bool
+	// DeleteUndeclared removes the existing ACLs of every principal present
+	// in the desired bindings which are not themselves desired. ACLs of other
+	// principals are never touched.
+	DeleteUndeclared bool
+}
+
+// AclChanges holds the ACL bindings ApplyACLs found to be missing from, or
+// superfluous on, the cluster.
+type AclChanges struct {
+	Create []AclBinding
+	Delete []AclBinding
+}
+
+// Empty returns true if no action is required.
+func (c *AclChanges) Empty() bool {
+	return len(c.Create) == 0 && len(c.Delete) == 0
+}
+
+// DiffACLs compares the desired ACL bindings against the ACLs of their
+// principals and returns the changes required to converge them.
+func DiffACLs(admin ClusterAdmin, desired []AclBinding, opts AclApplyOptions) (*AclChanges, error) {
+	changes := &AclChanges{}
+	if len(desired) == 0 {
+		return changes, nil
+	}
+
+	wanted := make(map[AclBinding]bool, len(desired))
+	principals := make(map[string]bool)
+	for _, binding := range desired {
+		binding = binding.normalized()
+		if binding.Acl.Principal == "" {
+			return nil, fmt.Errorf("kafka: missing principal in ACL binding %s", binding)
+		}
+		wanted[binding] = true
+		principals[binding.Acl.Principal] = true
+	}
+
+	sorted := make([]string, 0, len(principals))
+	for principal := range principals {
+		sorted = append(sorted, principal)
+	}
+	sort.Strings(sorted)
+
+	existing := make(map[AclBinding]bool)
+	for _, principal := range sorted {
+		principal := principal
+		resourceACLs, err := admin.ListAcls(AclFilter{
+			ResourceType:              AclResourceAny,
+			ResourcePatternTypeFilter: AclPatternAny,
+			Principal:                 &principal,
+			Operation:                 AclOperationAny,
+			PermissionType:            AclPermissionAny,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("listing ACLs of %s: %w", principal, err)
+		}
+
+		for _, resourceACL := range resourceACLs {
+			for _, acl := range resourceACL.Acls {
+				binding := AclBinding{Resource: resourceACL.Resource, Acl: *acl}.normalized()
+				if existing[binding] {
+					continue
+				}
+				existing[binding] = true
+				if opts.DeleteUndeclared && !wanted[binding] {
+					changes.Delete = append(changes.Delete, binding)
+				}
+			}
+		}
+	}
+
+	for _, binding := range desired {
+		binding = binding.normalized()
+		if existing[binding] {
+			continue
+		}
+		// guard against duplicates in desired
+		existing[binding] = true
+		changes.Create = append(changes.Create, binding)
+	}
+
+	return changes, nil
+}
+
+// ApplyACLChanges creates and then deletes the ACLs described by changes,
+// so that access is never revoked before its replacement is in place. The
+// returned error wraps ErrApplyACLs and the individual failures.
+func ApplyACLChanges(admin ClusterAdmin, changes *AclChanges) error {
+	var errs []error
+
+	if len(changes.Create) > 0 {
+		var resourceACLs []*ResourceAcls
+		byResource := make(map[Resource]*ResourceAcls)
+		for _, binding := range changes.Create {
+			resourceACL, ok := byResource[binding.Resource]
+			if !ok {
+				resourceACL = &ResourceAcls{Resource: binding.Resource}
+				byResource[binding.Resource] = resourceACL
+				resourceACLs = append(resourceACLs, resourceACL)
+			}
+			acl := binding.Acl
+			resourceACL.Acls = append(resourceACL.Acls, &acl)
+		}
+		if err := admin.CreateACLs(resourceACLs); err != nil {
+			errs = append(errs, err)
+		}
+	}
+
+	for _, binding := range changes.Delete {
+		if _, err := admin.DeleteACL(binding.Filter(), false); err != nil {
+			errs = append(errs, fmt.Errorf("deleting %s: %w", binding, err))
+		}
+	}
+
+	if len(errs) > 0 {
+		return Wrap(ErrApplyACLs, errs...)
+	}
+	return nil
+}
+
+// ApplyACLs idempotently converges the cluster towards the desired ACL
+// bindings, typically produced by an AclBuilder: only missing ACLs are
+// created and, with DeleteUndeclared, only superfluous ones are deleted.
+// The computed changes are returned even when applying them fails.
+func ApplyACLs(admin ClusterAdmin, desired []AclBinding, opts AclApplyOptions) (*AclChanges, error) {
+	changes, err := DiffACLs(admin, desired, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.DryRun || changes.Empty() {
+		return changes, nil
+	}
+	return changes, ApplyACLChanges(admin, changes)
+}
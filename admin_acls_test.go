@@ -0,0 +1,87 @@
+package sarama
+
+import (
+	"testing"
+)
+
+func TestApplyACLs(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	orders := Resource{ResourceType: AclResourceTopic, ResourceName: "orders", ResourcePatternType: AclPatternLiteral}
+	legacy := Resource{ResourceType: AclResourceTopic, ResourceName: "legacy", ResourcePatternType: AclPatternLiteral}
+	read := Acl{Principal: "User:alice", Host: "*", Operation: AclOperationRead, PermissionType: AclPermissionAllow}
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"DescribeAclsRequest": NewMockWrapper(&DescribeAclsResponse{
+			Version: 1,
+			Err:     ErrNoError,
+			ResourceAcls: []*ResourceAcls{
+				{Resource: orders, Acls: []*Acl{&read}},
+				{Resource: legacy, Acls: []*Acl{&read}},
+			},
+		}),
+		"CreateAclsRequest": NewMockCreateAclsResponse(t),
+		"DeleteAclsRequest": NewMockDeleteAclsResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V2_0_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	desired, err := NewAclBuilder().
+		User("alice").
+		Topic("orders").
+		Operations(AclOperationRead, AclOperationDescribe).
+		Build()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	changes, err := ApplyACLs(admin, desired, AclApplyOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Create) != 1 || changes.Create[0].Acl.Operation != AclOperationDescribe || len(changes.Delete) != 0 {
+		t.Fatalf("expected only Describe on orders to be created, got %+v", changes)
+	}
+	for _, rr := range seedBroker.History() {
+		switch rr.Request.(type) {
+		case *CreateAclsRequest, *DeleteAclsRequest:
+			t.Fatalf("dry run issued a %T", rr.Request)
+		}
+	}
+
+	changes, err = ApplyACLs(admin, desired, AclApplyOptions{DeleteUndeclared: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes.Delete) != 1 || changes.Delete[0].Resource != legacy {
+		t.Fatalf("expected the ACL on legacy to be deleted, got %+v", changes)
+	}
+
+	var created []*AclCreation
+	var deleted []*AclFilter
+	for _, rr := range seedBroker.History() {
+		switch req := rr.Request.(type) {
+		case *CreateAclsRequest:
+			created = append(created, req.AclCreations...)
+		case *DeleteAclsRequest:
+			deleted = append(deleted, req.Filters...)
+		}
+	}
+	if len(created) != 1 || created[0].Resource != orders || created[0].Acl.Operation != AclOperationDescribe {
+		t.Errorf("unexpected creations %+v", created)
+	}
+	if len(deleted) != 1 || *deleted[0].ResourceName != "legacy" || deleted[0].Operation != AclOperationRead ||
+		*deleted[0].Principal != "User:alice" || deleted[0].ResourcePatternTypeFilter != AclPatternLiteral {
+		t.Errorf("unexpected deletions %+v", deleted)
+	}
+}
@@ -0,0 +1,387 @@
+package sarama
+
+import "context"
+
+// contextClusterAdmin is the ClusterAdmin returned by WithContext. Every
+// operation is run on its own goroutine so that the caller can be released
+// as soon as the context is done, even while a broker round trip is still
+// waiting on the Net timeouts.
+type contextClusterAdmin struct {
+	ca  *clusterAdmin
+	ctx context.Context
+}
+
+func (ca *clusterAdmin) WithContext(ctx context.Context) ClusterAdmin {
+	if ctx == nil {
+		panic("nil context")
+	}
+	bound := *ca
+	bound.ctx = ctx
+	return &contextClusterAdmin{ca: &bound, ctx: ctx}
+}
+
+// run executes fn and reports whether it completed before the context was
+// done. When it returns false fn may still be running, so the variables it
+// assigns must not be read.
+func (c *contextClusterAdmin) run(fn func()) bool {
+	if c.ctx.Err() != nil {
+		return false
+	}
+
+	done := make(chan none)
+	go withRecover(func() {
+		defer close(done)
+		fn()
+	})
+
+	select {
+	case <-done:
+		return true
+	case <-c.ctx.Done():
+		return false
+	}
+}
+
+func (c *contextClusterAdmin) WithContext(ctx context.Context) ClusterAdmin {
+	return c.ca.WithContext(ctx)
+}
+
+func (c *contextClusterAdmin) Close() error {
+	return c.ca.Close()
+}
+
+func (c *contextClusterAdmin) Controller() (*Broker, error) {
+	var (
+		controller *Broker
+		err        error
+	)
+	if c.run(func() { controller, err = c.ca.Controller() }) {
+		return controller, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) CreateTopic(topic string, detail *TopicDetail, validateOnly bool) error {
+	var err error
+	if c.run(func() { err = c.ca.CreateTopic(topic, detail, validateOnly) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) CreateTopics(topics map[string]*TopicDetail, validateOnly bool) (map[string]*CreateTopicResult, error) {
+	var (
+		results map[string]*CreateTopicResult
+		err     error
+	)
+	if c.run(func() { results, err = c.ca.CreateTopics(topics, validateOnly) }) {
+		return results, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) ListTopics() (map[string]TopicDetail, error) {
+	var (
+		topics map[string]TopicDetail
+		err    error
+	)
+	if c.run(func() { topics, err = c.ca.ListTopics() }) {
+		return topics, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DescribeTopics(topics []string) ([]*TopicMetadata, error) {
+	var (
+		metadata []*TopicMetadata
+		err      error
+	)
+	if c.run(func() { metadata, err = c.ca.DescribeTopics(topics) }) {
+		return metadata, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DescribeTopicPartitions(topics []string) ([]*DescribeTopicPartitionsResponseTopic, error) {
+	var (
+		results []*DescribeTopicPartitionsResponseTopic
+		err     error
+	)
+	if c.run(func() { results, err = c.ca.DescribeTopicPartitions(topics) }) {
+		return results, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DeleteTopic(topic string) error {
+	var err error
+	if c.run(func() { err = c.ca.DeleteTopic(topic) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) CreatePartitions(topic string, count int32, assignment [][]int32, validateOnly bool) error {
+	var err error
+	if c.run(func() { err = c.ca.CreatePartitions(topic, count, assignment, validateOnly) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) AlterPartitionReassignments(topic string, assignment [][]int32) error {
+	var err error
+	if c.run(func() { err = c.ca.AlterPartitionReassignments(topic, assignment) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) ListPartitionReassignments(topic string, partitions []int32) (map[string]map[int32]*PartitionReplicaReassignmentsStatus, error) {
+	var (
+		status map[string]map[int32]*PartitionReplicaReassignmentsStatus
+		err    error
+	)
+	if c.run(func() { status, err = c.ca.ListPartitionReassignments(topic, partitions) }) {
+		return status, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) ElectLeaders(electionType ElectionType, partitions map[string][]int32) (map[string]map[int32]*PartitionResult, error) {
+	var (
+		results map[string]map[int32]*PartitionResult
+		err     error
+	)
+	if c.run(func() { results, err = c.ca.ElectLeaders(electionType, partitions) }) {
+		return results, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DeleteRecords(topic string, partitionOffsets map[int32]int64) error {
+	var err error
+	if c.run(func() { err = c.ca.DeleteRecords(topic, partitionOffsets) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DescribeConfig(resource ConfigResource) ([]ConfigEntry, error) {
+	var (
+		entries []ConfigEntry
+		err     error
+	)
+	if c.run(func() { entries, err = c.ca.DescribeConfig(resource) }) {
+		return entries, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) AlterConfig(resourceType ConfigResourceType, name string, entries map[string]*string, validateOnly bool) error {
+	var err error
+	if c.run(func() { err = c.ca.AlterConfig(resourceType, name, entries, validateOnly) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) IncrementalAlterConfig(resourceType ConfigResourceType, name string, entries map[string]IncrementalAlterConfigsEntry, validateOnly bool) error {
+	var err error
+	if c.run(func() { err = c.ca.IncrementalAlterConfig(resourceType, name, entries, validateOnly) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) CreateACL(resource Resource, acl Acl) error {
+	var err error
+	if c.run(func() { err = c.ca.CreateACL(resource, acl) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) CreateACLs(resourceACLs []*ResourceAcls) error {
+	var err error
+	if c.run(func() { err = c.ca.CreateACLs(resourceACLs) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) ListAcls(filter AclFilter) ([]ResourceAcls, error) {
+	var (
+		acls []ResourceAcls
+		err  error
+	)
+	if c.run(func() { acls, err = c.ca.ListAcls(filter) }) {
+		return acls, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DeleteACL(filter AclFilter, validateOnly bool) ([]MatchingAcl, error) {
+	var (
+		acls []MatchingAcl
+		err  error
+	)
+	if c.run(func() { acls, err = c.ca.DeleteACL(filter, validateOnly) }) {
+		return acls, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) ListConsumerGroups() (map[string]string, error) {
+	var (
+		groups map[string]string
+		err    error
+	)
+	if c.run(func() { groups, err = c.ca.ListConsumerGroups() }) {
+		return groups, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) ListConsumerGroupsWithOptions(opts ListConsumerGroupsOptions) (map[string]ConsumerGroupListing, error) {
+	var (
+		groups map[string]ConsumerGroupListing
+		err    error
+	)
+	if c.run(func() { groups, err = c.ca.ListConsumerGroupsWithOptions(opts) }) {
+		return groups, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DescribeConsumerGroups(groups []string) ([]*GroupDescription, error) {
+	var (
+		descriptions []*GroupDescription
+		err          error
+	)
+	if c.run(func() { descriptions, err = c.ca.DescribeConsumerGroups(groups) }) {
+		return descriptions, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*OffsetFetchResponse, error) {
+	var (
+		offsets *OffsetFetchResponse
+		err     error
+	)
+	if c.run(func() { offsets, err = c.ca.ListConsumerGroupOffsets(group, topicPartitions) }) {
+		return offsets, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) AlterConsumerGroupOffsets(group string, offsets map[string]map[int32]GroupOffset) error {
+	var err error
+	if c.run(func() { err = c.ca.AlterConsumerGroupOffsets(group, offsets) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) ListOffsets(topicPartitions map[string][]int32, time int64) (map[string]map[int32]int64, error) {
+	var (
+		offsets map[string]map[int32]int64
+		err     error
+	)
+	if c.run(func() { offsets, err = c.ca.ListOffsets(topicPartitions, time) }) {
+		return offsets, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DeleteConsumerGroupOffset(group string, topic string, partition int32) error {
+	var err error
+	if c.run(func() { err = c.ca.DeleteConsumerGroupOffset(group, topic, partition) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DeleteConsumerGroup(group string) error {
+	var err error
+	if c.run(func() { err = c.ca.DeleteConsumerGroup(group) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DescribeCluster() ([]*Broker, int32, error) {
+	var (
+		brokers      []*Broker
+		controllerID int32
+		err          error
+	)
+	if c.run(func() { brokers, controllerID, err = c.ca.DescribeCluster() }) {
+		return brokers, controllerID, err
+	}
+	return nil, int32(0), c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DescribeLogDirs(brokers []int32) (map[int32][]DescribeLogDirsResponseDirMetadata, error) {
+	var (
+		logDirs map[int32][]DescribeLogDirsResponseDirMetadata
+		err     error
+	)
+	if c.run(func() { logDirs, err = c.ca.DescribeLogDirs(brokers) }) {
+		return logDirs, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DescribeUserScramCredentials(users []string) ([]*DescribeUserScramCredentialsResult, error) {
+	var (
+		results []*DescribeUserScramCredentialsResult
+		err     error
+	)
+	if c.run(func() { results, err = c.ca.DescribeUserScramCredentials(users) }) {
+		return results, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DeleteUserScramCredentials(delete []AlterUserScramCredentialsDelete) ([]*AlterUserScramCredentialsResult, error) {
+	var (
+		results []*AlterUserScramCredentialsResult
+		err     error
+	)
+	if c.run(func() { results, err = c.ca.DeleteUserScramCredentials(delete) }) {
+		return results, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) UpsertUserScramCredentials(upsert []AlterUserScramCredentialsUpsert) ([]*AlterUserScramCredentialsResult, error) {
+	var (
+		results []*AlterUserScramCredentialsResult
+		err     error
+	)
+	if c.run(func() { results, err = c.ca.UpsertUserScramCredentials(upsert) }) {
+		return results, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) DescribeClientQuotas(components []QuotaFilterComponent, strict bool) ([]DescribeClientQuotasEntry, error) {
+	var (
+		entries []DescribeClientQuotasEntry
+		err     error
+	)
+	if c.run(func() { entries, err = c.ca.DescribeClientQuotas(components, strict) }) {
+		return entries, err
+	}
+	return nil, c.ctx.Err()
+}
+
+func (c *contextClusterAdmin) AlterClientQuotas(entity []QuotaEntityComponent, op ClientQuotasOp, validateOnly bool) error {
+	var err error
+	if c.run(func() { err = c.ca.AlterClientQuotas(entity, op, validateOnly) }) {
+		return err
+	}
+	return c.ctx.Err()
+}
@@ -0,0 +1,122 @@
+package sarama
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestClusterAdminWithContextCancelled(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"CreateTopicsRequest": NewMockCreateTopicsResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V1_0_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err = admin.WithContext(ctx).CreateTopic("my_topic", &TopicDetail{NumPartitions: 1, ReplicationFactor: 1}, false)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	for _, rr := range seedBroker.History() {
+		if _, ok := rr.Request.(*CreateTopicsRequest); ok {
+			t.Fatal("CreateTopicsRequest should not have been sent")
+		}
+	}
+
+	if err := admin.WithContext(context.Background()).CreateTopic("my_topic", &TopicDetail{NumPartitions: 1, ReplicationFactor: 1}, false); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestClusterAdminWithContextDeadline(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetLatency(300 * time.Millisecond)
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"DescribeConfigsRequest": NewMockDescribeConfigsResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V1_1_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	_, err = admin.WithContext(ctx).DescribeConfig(ConfigResource{Type: TopicResource, Name: "my_topic"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 300*time.Millisecond {
+		t.Errorf("expected the call to return at the deadline, took %v", elapsed)
+	}
+}
+
+func TestClusterAdminWithContextStopsRetrying(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"DeleteTopicsRequest": NewMockWrapper(&DeleteTopicsResponse{
+			TopicErrorCodes: map[string]KError{"my_topic": ErrNotController},
+		}),
+	})
+
+	config := NewTestConfig()
+	config.Version = V0_10_2_0
+	config.Admin.Retry.Max = 100
+	config.Admin.Retry.Backoff = 50 * time.Millisecond
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 75*time.Millisecond)
+	defer cancel()
+
+	if err := admin.WithContext(ctx).DeleteTopic("my_topic"); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected context.DeadlineExceeded, got %v", err)
+	}
+
+	// give an unbounded retry loop plenty of time to issue more requests
+	time.Sleep(300 * time.Millisecond)
+
+	attempts := 0
+	for _, rr := range seedBroker.History() {
+		if _, ok := rr.Request.(*DeleteTopicsRequest); ok {
+			attempts++
+		}
+	}
+	if attempts > 3 {
+		t.Errorf("expected retries to stop at the deadline, got %d attempts", attempts)
+	}
+}
@@ -0,0 +1,386 @@
+package sarama
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+)
+
+// NewClusterAdminFromControllers creates a new ClusterAdmin talking directly
+// to the KRaft controllers at the given addresses, i.e. their controller
+// listener, as the bootstrap.controllers setting of the Java admin client
+// does (KIP-919). This allows administering the cluster while its brokers are
+// down. The controllers are described by the controllers themselves, and the
+// operations are sent to the active one, or to the controller they name.
+// DescribeCluster describes the controllers rather than the brokers. The
+// operations which require the metadata of the topics, or the coordinators of
+// the consumer groups, return ErrNotSupportedByControllers; those which the
+// controllers do not implement fail with their error. Requires Kafka 3.7 or
+// higher.
+func NewClusterAdminFromControllers(addrs []string, conf *Config) (ClusterAdmin, error) {
+	if conf == nil {
+		conf = NewConfig()
+	}
+
+	if err := conf.Validate(); err != nil {
+		return nil, err
+	}
+
+	if len(addrs) < 1 {
+		return nil, ConfigurationError("You must provide at least one controller address")
+	}
+
+	if !conf.Version.IsAtLeast(V3_7_0_0) {
+		return nil, ErrUnsupportedVersion
+	}
+
+	addrs, err := conf.bootstrapAddrs(addrs)
+	if err != nil {
+		return nil, err
+	}
+
+	client := &controllerClient{
+		conf:        conf,
+		controllers: make(map[int32]*Broker),
+		activeID:    -1,
+	}
+	for _, addr := range addrs {
+		client.seeds = append(client.seeds, NewBroker(addr))
+	}
+
+	admin, err := NewClusterAdminFromClient(client)
+	if err != nil {
+		_ = client.Close()
+		return nil, err
+	}
+	return admin, nil
+}
+
+// controllerClient is the Client of a ClusterAdmin created by
+// NewClusterAdminFromControllers. It only knows about the KRaft controllers,
+// which it describes through DescribeClusterRequests, and returns
+// ErrNotSupportedByControllers for the metadata of the topics and the
+// coordinators of the consumer groups.
+type controllerClient struct {
+	conf *Config
+
+	lock        sync.RWMutex
+	seeds       []*Broker         // the controllers given to NewClusterAdminFromControllers
+	controllers map[int32]*Broker // the controllers as described by themselves
+	activeID    int32             // the active controller, -1 if unknown
+	clusterID   string
+	closed      bool
+}
+
+// describe describes the controllers, through the known controllers first,
+// then the seeds, and records them.
+func (c *controllerClient) describe() (*DescribeClusterResponse, error) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		return nil, ErrClosedClient
+	}
+
+	candidates := append(c.sortedControllers(), c.seeds...)
+
+	request := &DescribeClusterRequest{Version: 1, EndpointType: DescribeClusterControllers}
+	err := ErrOutOfBrokers
+	for _, broker := range candidates {
+		_ = broker.Open(c.conf)
+		response, rerr := broker.DescribeCluster(request)
+		if rerr != nil {
+			warnf("admin/controllers failed to describe the controllers through %s: %v\n", broker.Addr(), rerr)
+			_ = broker.Close()
+			err = Wrap(ErrOutOfBrokers, rerr)
+			continue
+		}
+		if !errors.Is(response.Err, ErrNoError) {
+			err = response.Err
+			continue
+		}
+
+		c.update(response)
+		return response, nil
+	}
+	return nil, err
+}
+
+// update records the controllers of response, keeping the connections to
+// those which did not move. You must hold the write lock.
+func (c *controllerClient) update(response *DescribeClusterResponse) {
+	controllers := make(map[int32]*Broker, len(response.Brokers))
+	for _, node := range response.Brokers {
+		controller := node.Broker()
+		if current := c.controllers[node.BrokerID]; current != nil && current.Addr() == controller.Addr() {
+			controller = current
+		}
+		controllers[node.BrokerID] = controller
+	}
+	for id, controller := range c.controllers {
+		if controllers[id] != controller {
+			safeAsyncClose(controller)
+		}
+	}
+
+	c.controllers = controllers
+	c.activeID = response.ControllerID
+	c.clusterID = response.ClusterID
+}
+
+// sortedControllers returns the controllers by ID. You must hold the lock.
+func (c *controllerClient) sortedControllers() []*Broker {
+	controllers := make([]*Broker, 0, len(c.controllers))
+	for _, controller := range c.controllers {
+		controllers = append(controllers, controller)
+	}
+	sort.Slice(controllers, func(i, j int) bool {
+		return controllers[i].ID() < controllers[j].ID()
+	})
+	return controllers
+}
+
+func (c *controllerClient) cachedController() *Broker {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.controllers[c.activeID]
+}
+
+// describeCluster implements ClusterAdmin.DescribeCluster, describing the
+// controllers.
+func (c *controllerClient) describeCluster() ([]*Broker, int32, error) {
+	response, err := c.describe()
+	if err != nil {
+		return nil, 0, err
+	}
+
+	brokers := make([]*Broker, len(response.Brokers))
+	for i, node := range response.Brokers {
+		brokers[i] = node.Broker()
+	}
+	return brokers, response.ControllerID, nil
+}
+
+func (c *controllerClient) Config() *Config {
+	return c.conf
+}
+
+func (c *controllerClient) Controller() (*Broker, error) {
+	if c.Closed() {
+		return nil, ErrClosedClient
+	}
+
+	controller := c.cachedController()
+	if controller == nil {
+		if _, err := c.describe(); err != nil {
+			return nil, err
+		}
+		controller = c.cachedController()
+	}
+
+	if controller == nil {
+		return nil, ErrControllerNotAvailable
+	}
+
+	_ = controller.Open(c.conf)
+	return controller, nil
+}
+
+func (c *controllerClient) RefreshController() (*Broker, error) {
+	if _, err := c.describe(); err != nil {
+		return nil, err
+	}
+	return c.Controller()
+}
+
+func (c *controllerClient) ControllerID() (int32, error) {
+	controller, err := c.Controller()
+	if err != nil {
+		return -1, err
+	}
+	return controller.ID(), nil
+}
+
+func (c *controllerClient) ClusterID() (string, error) {
+	if _, err := c.Controller(); err != nil {
+		return "", err
+	}
+
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+	return c.clusterID, nil
+}
+
+func (c *controllerClient) TopicID(topic string) (Uuid, error) {
+	return Uuid{}, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) MetadataSnapshot() ([]byte, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) Racks() map[int32]string {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	racks := make(map[int32]string)
+	for id, controller := range c.controllers {
+		if controller.rack != nil {
+			racks[id] = *controller.rack
+		}
+	}
+	return racks
+}
+
+func (c *controllerClient) BrokerApiVersions() map[int32]map[int16]ApiVersionsResponseKey {
+	return brokerApiVersions(c.Brokers())
+}
+
+func (c *controllerClient) ApiVersionRange(apiKey int16) (minVersion, maxVersion int16, ok bool) {
+	return apiVersionRange(c.BrokerApiVersions(), apiKey)
+}
+
+func (c *controllerClient) Brokers() []*Broker {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.sortedControllers()
+}
+
+func (c *controllerClient) Broker(brokerID int32) (*Broker, error) {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	controller, ok := c.controllers[brokerID]
+	if !ok {
+		return nil, ErrBrokerNotFound
+	}
+	_ = controller.Open(c.conf)
+	return controller, nil
+}
+
+func (c *controllerClient) LeastLoadedBroker() *Broker {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	var least *Broker
+	for _, controller := range c.sortedControllers() {
+		if least == nil || atomic.LoadInt32(&controller.pendingRequests) < atomic.LoadInt32(&least.pendingRequests) {
+			least = controller
+		}
+	}
+	return least
+}
+
+func (c *controllerClient) Topics() ([]string, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) Partitions(topic string) ([]int32, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) WritablePartitions(topic string) ([]int32, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) Leader(topic string, partitionID int32) (*Broker, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) Replicas(topic string, partitionID int32) ([]int32, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) InSyncReplicas(topic string, partitionID int32) ([]int32, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) OfflineReplicas(topic string, partitionID int32) ([]int32, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) RefreshBrokers(addrs []string) error {
+	return ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) RefreshMetadata(topics ...string) error {
+	return ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) GetOffset(topic string, partitionID int32, time int64) (int64, error) {
+	return -1, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) GetOffsets(topicPartitions map[string][]int32, time int64) (map[string]map[int32]int64, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) OffsetsForTimes(timestamps map[string]map[int32]int64) (map[string]map[int32]*OffsetAndTimestamp, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) Coordinator(consumerGroup string) (*Broker, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) RefreshCoordinator(consumerGroup string) error {
+	return ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) InitProducerID() (*InitProducerIDResponse, error) {
+	return nil, ErrNotSupportedByControllers
+}
+
+func (c *controllerClient) Subscribe(handler func(*ClientEvent)) func() {
+	// the events are only published by the metadata of the brokers
+	return func() {}
+}
+
+// DebugState reports the controllers as the brokers of the cluster.
+func (c *controllerClient) DebugState() ClientDebugState {
+	c.lock.RLock()
+	state := ClientDebugState{Closed: c.closed, ControllerID: -1}
+	if _, ok := c.controllers[c.activeID]; ok {
+		state.ControllerID = c.activeID
+	}
+	controllers := c.sortedControllers()
+	seeds := append([]*Broker(nil), c.seeds...)
+	c.lock.RUnlock()
+
+	state.Brokers = brokersDebugState(controllers)
+	state.SeedBrokers = brokersDebugState(seeds)
+	return state
+}
+
+func (c *controllerClient) Close() error {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if c.closed {
+		return ErrClosedClient
+	}
+	c.closed = true
+
+	for _, controller := range c.controllers {
+		safeAsyncClose(controller)
+	}
+	for _, seed := range c.seeds {
+		safeAsyncClose(seed)
+	}
+	return nil
+}
+
+func (c *controllerClient) CloseContext(ctx context.Context) error {
+	return c.Close()
+}
+
+func (c *controllerClient) Closed() bool {
+	c.lock.RLock()
+	defer c.lock.RUnlock()
+
+	return c.closed
+}
@@ -0,0 +1,77 @@
+package sarama
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestClusterAdminFromControllers(t *testing.T) {
+	seed := NewMockBroker(t, 3000)
+	defer seed.Close()
+	active := NewMockBroker(t, 3001)
+	defer active.Close()
+
+	describeCluster := &DescribeClusterResponse{
+		Version:      1,
+		EndpointType: DescribeClusterControllers,
+		ClusterID:    "my-cluster",
+		ControllerID: active.BrokerID(),
+	}
+	for _, controller := range []*MockBroker{seed, active} {
+		describeCluster.Brokers = append(describeCluster.Brokers, &DescribeClusterBroker{
+			BrokerID: controller.BrokerID(),
+			Host:     "127.0.0.1",
+			Port:     controller.Port(),
+		})
+	}
+	for _, controller := range []*MockBroker{seed, active} {
+		controller.SetHandlerByMap(map[string]MockResponse{
+			"ApiVersionsRequest":     NewMockApiVersionsResponse(t),
+			"DescribeClusterRequest": NewMockWrapper(describeCluster),
+			"CreateAclsRequest":      NewMockCreateAclsResponse(t),
+		})
+	}
+
+	config := NewTestConfig()
+	config.Version = V3_7_0_0
+	admin, err := NewClusterAdminFromControllers([]string{seed.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	brokers, controllerID, err := admin.DescribeCluster()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(brokers) != 2 || controllerID != active.BrokerID() {
+		t.Errorf("expected the 2 controllers with #%d active, got %d with #%d", active.BrokerID(), len(brokers), controllerID)
+	}
+
+	r := Resource{ResourceType: AclResourceTopic, ResourceName: "my_topic"}
+	a := Acl{Host: "localhost", Operation: AclOperationAlter, PermissionType: AclPermissionAny}
+	if err := admin.CreateACL(r, a); err != nil {
+		t.Fatal(err)
+	}
+	sent := false
+	for _, rr := range active.History() {
+		if _, ok := rr.Request.(*CreateAclsRequest); ok {
+			sent = true
+		}
+	}
+	if !sent {
+		t.Error("expected the ACL to be created through the active controller")
+	}
+
+	if _, err := admin.ListConsumerGroupOffsets("my-group", nil); !errors.Is(err, ErrNotSupportedByControllers) {
+		t.Errorf("expected ErrNotSupportedByControllers, got %v", err)
+	}
+}
+
+func TestClusterAdminFromControllersUnsupportedVersion(t *testing.T) {
+	config := NewTestConfig()
+	config.Version = V3_1_0_0
+	if _, err := NewClusterAdminFromControllers([]string{"localhost:9093"}, config); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion before Kafka 3.7, got %v", err)
+	}
+}
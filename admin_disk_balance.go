@@ -0,0 +1,253 @@
+package sarama
+
+import (
+	"errors"
+	"sort"
+)
+
+const defaultDiskBalanceThreshold = 0.1
+
+// DiskBalanceOptions controls how PlanDiskBalance balances disk usage.
+type DiskBalanceOptions struct {
+	// Brokers restricts the brokers to balance, every broker of the cluster
+	// is balanced when empty. Replicas are only moved between these brokers.
+	Brokers []int32
+	// Threshold is the deviation from the average disk usage, as a fraction
+	// of it, a broker may have before its replicas are moved. Defaults to 0.1.
+	Threshold float64
+	// MaxMoves caps the number of moves of the plan, zero meaning no limit.
+	MaxMoves int
+	// RackAware never moves a replica to a rack which already hosts another
+	// replica of the same partition, unless it is the rack it moves from.
+	RackAware bool
+}
+
+// DiskBalancePlan holds the moves proposed by PlanDiskBalance along with the
+// disk usage, in bytes per broker, before and after applying them.
+type DiskBalancePlan struct {
+	Usage     map[int32]int64
+	Projected map[int32]int64
+	Moves     []*ReplicaMove
+
+	// assignments holds the replicas of every partition of the topics
+	// touched by Moves, once they have been applied.
+	assignments map[string][][]int32
+}
+
+// Reassignments returns the plan in the form expected by
+// ClusterAdmin.AlterPartitionReassignments, keyed by topic. Partitions which
+// are not moved keep their current replicas.
+func (p *DiskBalancePlan) Reassignments() map[string][][]int32 {
+	return p.assignments
+}
+
+// PlanDiskBalance proposes replica moves that even out the disk usage of the
+// brokers, as reported by DescribeLogDirs across all their log directories.
+// It repeatedly moves the largest replica of the fullest broker that fits in
+// half the gap to the emptiest eligible broker, until every broker is within
+// the threshold of the average or no move improves the balance. Replicas of
+// partitions being reassigned, which are reported in two log directories, are
+// left alone. Nothing is applied: pass Reassignments to
+// AlterPartitionReassignments to execute the plan.
+func PlanDiskBalance(admin ClusterAdmin, opts DiskBalanceOptions) (*DiskBalancePlan, error) {
+	brokers, _, err := admin.DescribeCluster()
+	if err != nil {
+		return nil, err
+	}
+
+	racks := make(map[int32]string, len(brokers))
+	for _, b := range brokers {
+		racks[b.ID()] = b.Rack()
+	}
+	ids := append([]int32(nil), opts.Brokers...)
+	if len(ids) == 0 {
+		for id := range racks {
+			ids = append(ids, id)
+		}
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	logDirs, err := admin.DescribeLogDirs(ids)
+	if err != nil {
+		return nil, err
+	}
+	metadata, err := admin.DescribeTopics(nil)
+	if err != nil {
+		return nil, err
+	}
+	return planDiskBalance(ids, racks, logDirs, metadata, opts), nil
+}
+
+type topicPartitionKey struct {
+	topic     string
+	partition int32
+}
+
+type diskBalanceReplica struct {
+	topicPartitionKey
+	size int64
+}
+
+func planDiskBalance(ids []int32, racks map[int32]string, logDirs map[int32][]DescribeLogDirsResponseDirMetadata, metadata []*TopicMetadata, opts DiskBalanceOptions) *DiskBalancePlan {
+	threshold := opts.Threshold
+	if threshold <= 0 {
+		threshold = defaultDiskBalanceThreshold
+	}
+
+	plan := &DiskBalancePlan{
+		Usage:       make(map[int32]int64, len(ids)),
+		Projected:   make(map[int32]int64, len(ids)),
+		assignments: make(map[string][][]int32),
+	}
+
+	// a replica reported in more than one log dir is being moved between
+	// them, only replicas with a single, settled log are candidates
+	replicas := make(map[int32][]diskBalanceReplica, len(ids))
+	for _, id := range ids {
+		logs := make(map[topicPartitionKey]int)
+		sizes := make(map[topicPartitionKey]int64)
+		for _, dir := range logDirs[id] {
+			if !errors.Is(dir.ErrorCode, ErrNoError) {
+				continue
+			}
+			for _, topic := range dir.Topics {
+				for _, partition := range topic.Partitions {
+					plan.Usage[id] += partition.Size
+					key := topicPartitionKey{topic.Topic, partition.PartitionID}
+					logs[key]++
+					sizes[key] = partition.Size
+				}
+			}
+		}
+		plan.Projected[id] = plan.Usage[id]
+
+		for key, count := range logs {
+			if count == 1 {
+				replicas[id] = append(replicas[id], diskBalanceReplica{key, sizes[key]})
+			}
+		}
+		sort.Slice(replicas[id], func(i, j int) bool {
+			a, b := replicas[id][i], replicas[id][j]
+			if a.size != b.size {
+				return a.size > b.size
+			}
+			if a.topic != b.topic {
+				return a.topic < b.topic
+			}
+			return a.partition < b.partition
+		})
+	}
+
+	current := make(map[string]map[int32][]int32, len(metadata))
+	partitionCounts := make(map[string]int, len(metadata))
+	for _, topic := range metadata {
+		if !errors.Is(topic.Err, ErrNoError) {
+			continue
+		}
+		current[topic.Name] = make(map[int32][]int32, len(topic.Partitions))
+		for _, partition := range topic.Partitions {
+			current[topic.Name][partition.ID] = partition.Replicas
+			if int(partition.ID) >= partitionCounts[topic.Name] {
+				partitionCounts[topic.Name] = int(partition.ID) + 1
+			}
+		}
+	}
+
+	var total int64
+	for _, id := range ids {
+		total += plan.Usage[id]
+	}
+	if len(ids) < 2 || total == 0 {
+		return plan
+	}
+	limit := float64(total) / float64(len(ids)) * (1 + threshold)
+
+	moved := make(map[topicPartitionKey]bool)
+	for opts.MaxMoves <= 0 || len(plan.Moves) < opts.MaxMoves {
+		byUsage := make([]int32, len(ids))
+		copy(byUsage, ids)
+		sort.SliceStable(byUsage, func(i, j int) bool { return plan.Projected[byUsage[i]] > plan.Projected[byUsage[j]] })
+
+		from := byUsage[0]
+		if float64(plan.Projected[from]) <= limit {
+			break
+		}
+
+		move := diskBalanceMove(from, byUsage[1:], racks, replicas[from], current, moved, plan.Projected, opts.RackAware)
+		if move == nil {
+			break
+		}
+
+		moved[topicPartitionKey{move.Topic, move.Partition}] = true
+		current[move.Topic][move.Partition] = move.Target
+		plan.Projected[move.From] -= move.Size
+		plan.Projected[move.To] += move.Size
+		plan.Moves = append(plan.Moves, move)
+	}
+
+	for _, move := range plan.Moves {
+		if _, ok := plan.assignments[move.Topic]; ok {
+			continue
+		}
+		assignment := make([][]int32, partitionCounts[move.Topic])
+		for partition, replicas := range current[move.Topic] {
+			assignment[partition] = replicas
+		}
+		plan.assignments[move.Topic] = assignment
+	}
+
+	return plan
+}
+
+// diskBalanceMove returns the move of the largest replica of from which
+// narrows the gap with the emptiest eligible broker, or nil if there is none.
+func diskBalanceMove(from int32, targets []int32, racks map[int32]string, candidates []diskBalanceReplica,
+	current map[string]map[int32][]int32, moved map[topicPartitionKey]bool, usage map[int32]int64, rackAware bool,
+) *ReplicaMove {
+	for i := len(targets) - 1; i >= 0; i-- {
+		to := targets[i]
+		gap := usage[from] - usage[to]
+		for _, candidate := range candidates {
+			if moved[candidate.topicPartitionKey] || candidate.size <= 0 || 2*candidate.size > gap {
+				continue
+			}
+			replicas, ok := current[candidate.topic][candidate.partition]
+			if !ok || !diskBalanceEligible(from, to, replicas, racks, rackAware) {
+				continue
+			}
+
+			target := make([]int32, len(replicas))
+			for j, replica := range replicas {
+				target[j] = replica
+				if replica == from {
+					target[j] = to
+				}
+			}
+			return &ReplicaMove{
+				Topic:     candidate.topic,
+				Partition: candidate.partition,
+				From:      from,
+				To:        to,
+				Replicas:  replicas,
+				Target:    target,
+				Size:      candidate.size,
+			}
+		}
+	}
+	return nil
+}
+
+func diskBalanceEligible(from, to int32, replicas []int32, racks map[int32]string, rackAware bool) bool {
+	if !containsInt32(replicas, from) || containsInt32(replicas, to) {
+		return false
+	}
+	if !rackAware || racks[to] == racks[from] {
+		return true
+	}
+	for _, replica := range replicas {
+		if replica != from && racks[replica] == racks[to] {
+			return false
+		}
+	}
+	return true
+}
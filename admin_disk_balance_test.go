@@ -0,0 +1,78 @@
+package sarama
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestPlanDiskBalance(t *testing.T) {
+	logDir := func(sizes map[int32]int64) []DescribeLogDirsResponseDirMetadata {
+		topic := DescribeLogDirsResponseTopic{Topic: "orders"}
+		for partition, size := range sizes {
+			topic.Partitions = append(topic.Partitions, DescribeLogDirsResponsePartition{PartitionID: partition, Size: size})
+		}
+		return []DescribeLogDirsResponseDirMetadata{{ErrorCode: ErrNoError, Path: "/data", Topics: []DescribeLogDirsResponseTopic{topic}}}
+	}
+	logDirs := map[int32][]DescribeLogDirsResponseDirMetadata{
+		1: logDir(map[int32]int64{0: 600, 1: 300, 2: 100}),
+		2: logDir(map[int32]int64{0: 600, 1: 300}),
+		3: logDir(map[int32]int64{2: 100}),
+	}
+	metadata := []*TopicMetadata{{
+		Name: "orders",
+		Partitions: []*PartitionMetadata{
+			{ID: 0, Replicas: []int32{1, 2}},
+			{ID: 1, Replicas: []int32{1, 2}},
+			{ID: 2, Replicas: []int32{1, 3}},
+		},
+	}}
+	ids := []int32{1, 2, 3}
+
+	plan := planDiskBalance(ids, map[int32]string{1: "a", 2: "b", 3: "c"}, logDirs, metadata, DiskBalanceOptions{})
+	expected := []*ReplicaMove{{Topic: "orders", Partition: 1, From: 1, To: 3, Replicas: []int32{1, 2}, Target: []int32{3, 2}, Size: 300}}
+	if !reflect.DeepEqual(plan.Moves, expected) {
+		t.Fatalf("expected orders-1 to move from 1 to 3, got %+v", plan.Moves)
+	}
+	if plan.Usage[1] != 1000 || plan.Projected[1] != 700 || plan.Projected[3] != 400 {
+		t.Errorf("unexpected usage %v projected to %v", plan.Usage, plan.Projected)
+	}
+	if reassignments := plan.Reassignments(); !reflect.DeepEqual(reassignments["orders"], [][]int32{{1, 2}, {3, 2}, {1, 3}}) {
+		t.Errorf("unexpected reassignments %v", reassignments)
+	}
+
+	plan = planDiskBalance(ids, map[int32]string{1: "a", 2: "b", 3: "b"}, logDirs, metadata, DiskBalanceOptions{RackAware: true})
+	if len(plan.Moves) != 0 {
+		t.Errorf("expected no move as broker 3 shares its rack with broker 2, got %+v", plan.Moves)
+	}
+
+	plan = planDiskBalance(ids, map[int32]string{}, logDirs, metadata, DiskBalanceOptions{Threshold: 0.6})
+	if len(plan.Moves) != 0 {
+		t.Errorf("expected no move within the threshold, got %+v", plan.Moves)
+	}
+}
+
+type diskBalanceTestAdmin struct {
+	ClusterAdmin
+}
+
+func (a diskBalanceTestAdmin) DescribeCluster() ([]*Broker, int32, error) {
+	return []*Broker{{id: 1}, {id: 2}, {id: 3}}, 1, nil
+}
+
+func (a diskBalanceTestAdmin) DescribeLogDirs(brokers []int32) (map[int32][]DescribeLogDirsResponseDirMetadata, error) {
+	return map[int32][]DescribeLogDirsResponseDirMetadata{}, nil
+}
+
+func (a diskBalanceTestAdmin) DescribeTopics(topics []string) ([]*TopicMetadata, error) {
+	return nil, nil
+}
+
+func TestPlanDiskBalanceKeepsBrokersOrder(t *testing.T) {
+	brokers := []int32{3, 1, 2}
+	if _, err := PlanDiskBalance(diskBalanceTestAdmin{}, DiskBalanceOptions{Brokers: brokers}); err != nil {
+		t.Fatal(err)
+	}
+	if expected := []int32{3, 1, 2}; !reflect.DeepEqual(brokers, expected) {
+		t.Errorf("expected the brokers of the options to be left as %v, got %v", expected, brokers)
+	}
+}
@@ -0,0 +1,485 @@
+package sarama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrDrainBroker is returned when a broker cannot be drained, either because
+// some replicas have nowhere to go or because reassigning them failed.
+var ErrDrainBroker = errors.New("kafka: failed to drain broker")
+
+const defaultDrainPollInterval = time.Second
+
+// DrainOptions controls how replicas are moved off a broker.
+type DrainOptions struct {
+	// Targets restricts the brokers replicas may be moved to. All other
+	// brokers of the cluster are eligible when empty.
+	Targets []int32
+	// RackAware only moves a replica to a broker whose rack does not host
+	// another replica of the same partition yet, falling back to a broker in
+	// the rack of the drained broker.
+	RackAware bool
+	// Throttle limits the replication traffic caused by the moves to the
+	// given number of bytes per second and per broker. Zero disables it.
+	Throttle int64
+	// PollInterval is how often reassignment progress is checked, defaults
+	// to one second.
+	PollInterval time.Duration
+	// Progress, if set, is called every time reassignment progress is checked.
+	Progress func(DrainProgress)
+}
+
+// DrainProgress reports how many of the moves of a drain are done.
+type DrainProgress struct {
+	Broker    int32
+	Total     int
+	Remaining int
+}
+
+// ReplicaMove moves the replica of a partition from one broker to another.
+type ReplicaMove struct {
+	Topic     string
+	Partition int32
+	From      int32
+	To        int32
+	// Replicas is the assignment of the partition before the move and
+	// Target the one after it.
+	Replicas []int32
+	Target   []int32
+	// Size is the size of the replica in bytes, when known.
+	Size int64
+}
+
+// DrainPlan lists the moves required to take every replica off Broker.
+type DrainPlan struct {
+	Broker int32
+	Moves  []*ReplicaMove
+
+	// previousThrottles are the throttle configs the brokers and topics had
+	// before the plan was first executed, nil for those unset, restored once
+	// the moves complete
+	previousThrottles map[drainThrottleConfig]*string
+}
+
+// drainThrottleConfig is a throttle config of a broker or a topic.
+type drainThrottleConfig struct {
+	resourceType ConfigResourceType
+	resource     string
+	name         string
+}
+
+// PlanBrokerDrain computes the moves required to take every partition
+// replica off broker. Each replica goes to the eligible broker currently
+// hosting the fewest replicas, keeping its position in the replica list so
+// that the preferred leadership of other brokers is unaffected.
+func PlanBrokerDrain(admin ClusterAdmin, broker int32, opts DrainOptions) (*DrainPlan, error) {
+	brokers, _, err := admin.DescribeCluster()
+	if err != nil {
+		return nil, err
+	}
+	topics, err := admin.ListTopics()
+	if err != nil {
+		return nil, err
+	}
+	return planBrokerDrain(broker, brokers, topics, opts)
+}
+
+func planBrokerDrain(broker int32, brokers []*Broker, topics map[string]TopicDetail, opts DrainOptions) (*DrainPlan, error) {
+	racks := make(map[int32]string, len(brokers))
+	for _, b := range brokers {
+		racks[b.ID()] = b.Rack()
+	}
+
+	var candidates []int32
+	if len(opts.Targets) > 0 {
+		for _, target := range opts.Targets {
+			if _, ok := racks[target]; !ok {
+				return nil, fmt.Errorf("%w %d: target broker %d is not part of the cluster", ErrDrainBroker, broker, target)
+			}
+			candidates = append(candidates, target)
+		}
+	} else {
+		for id := range racks {
+			candidates = append(candidates, id)
+		}
+	}
+	sort.Slice(candidates, func(i, j int) bool { return candidates[i] < candidates[j] })
+
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	load := make(map[int32]int)
+	for _, detail := range topics {
+		for _, replicas := range detail.ReplicaAssignment {
+			for _, replica := range replicas {
+				load[replica]++
+			}
+		}
+	}
+
+	plan := &DrainPlan{Broker: broker}
+	var errs []error
+	for _, name := range names {
+		assignment := topics[name].ReplicaAssignment
+		partitions := make([]int32, 0, len(assignment))
+		for partition := range assignment {
+			partitions = append(partitions, partition)
+		}
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+
+		for _, partition := range partitions {
+			replicas := assignment[partition]
+			index := -1
+			for i, replica := range replicas {
+				if replica == broker {
+					index = i
+					break
+				}
+			}
+			if index < 0 {
+				continue
+			}
+
+			target, ok := drainTarget(broker, replicas, candidates, racks, load, opts.RackAware)
+			if !ok {
+				errs = append(errs, fmt.Errorf("[%s-%d]: no eligible broker to move replica to", name, partition))
+				continue
+			}
+			load[target]++
+			load[broker]--
+
+			moved := make([]int32, len(replicas))
+			copy(moved, replicas)
+			moved[index] = target
+			plan.Moves = append(plan.Moves, &ReplicaMove{
+				Topic:     name,
+				Partition: partition,
+				From:      broker,
+				To:        target,
+				Replicas:  replicas,
+				Target:    moved,
+			})
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, Wrap(ErrDrainBroker, errs...)
+	}
+	return plan, nil
+}
+
+// drainTarget picks the least loaded candidate which does not host a replica
+// of the partition yet, honouring racks when asked to.
+func drainTarget(broker int32, replicas, candidates []int32, racks map[int32]string, load map[int32]int, rackAware bool) (int32, bool) {
+	hosted := make(map[int32]bool, len(replicas))
+	usedRacks := make(map[string]bool, len(replicas))
+	for _, replica := range replicas {
+		hosted[replica] = true
+		if replica != broker {
+			usedRacks[racks[replica]] = true
+		}
+	}
+
+	pick := func(eligible func(int32) bool) (int32, bool) {
+		best, found := int32(-1), false
+		for _, candidate := range candidates {
+			if candidate == broker || hosted[candidate] || !eligible(candidate) {
+				continue
+			}
+			if !found || load[candidate] < load[best] {
+				best, found = candidate, true
+			}
+		}
+		return best, found
+	}
+
+	if !rackAware {
+		return pick(func(int32) bool { return true })
+	}
+	if target, ok := pick(func(id int32) bool { return !usedRacks[racks[id]] }); ok {
+		return target, true
+	}
+	return pick(func(id int32) bool { return racks[id] == racks[broker] })
+}
+
+// DrainBroker moves every partition replica off broker, typically before
+// decommissioning it, and waits for the reassignments to complete. The plan
+// is returned even when executing it fails.
+func DrainBroker(ctx context.Context, admin ClusterAdmin, broker int32, opts DrainOptions) (*DrainPlan, error) {
+	plan, err := PlanBrokerDrain(admin, broker, opts)
+	if err != nil {
+		return nil, err
+	}
+	return plan, ExecuteDrainPlan(ctx, admin, plan, opts)
+}
+
+// ExecuteDrainPlan submits the reassignments of plan and waits for them to
+// complete, reporting progress along the way. When a throttle is configured
+// it is put in place beforehand, the replicas the topics already throttled
+// staying throttled, and the throttle configs the brokers and topics had
+// before are restored once every move completed; if ctx is done first the
+// throttle is left in place and calling ExecuteDrainPlan again with the same
+// plan resumes the drain.
+//
+// Reassigning a partition resubmits the replicas of every other partition
+// of its topic unchanged, as the underlying API cancels reassignments of
+// partitions it is not given a target for.
+func ExecuteDrainPlan(ctx context.Context, admin ClusterAdmin, plan *DrainPlan, opts DrainOptions) error {
+	if len(plan.Moves) == 0 {
+		return nil
+	}
+	admin = admin.WithContext(ctx)
+
+	topics := make(map[string][]*ReplicaMove)
+	var names []string
+	for _, move := range plan.Moves {
+		if _, ok := topics[move.Topic]; !ok {
+			names = append(names, move.Topic)
+		}
+		topics[move.Topic] = append(topics[move.Topic], move)
+	}
+	sort.Strings(names)
+
+	if opts.Throttle > 0 {
+		if err := setDrainThrottle(admin, plan, names, topics, opts.Throttle); err != nil {
+			return Wrap(ErrDrainBroker, err)
+		}
+	}
+
+	current, err := admin.DescribeTopics(names)
+	if err != nil {
+		return Wrap(ErrDrainBroker, err)
+	}
+	for _, topic := range current {
+		moves := topics[topic.Name]
+		if len(moves) == 0 {
+			continue
+		}
+		assignment := make([][]int32, len(topic.Partitions))
+		for _, partition := range topic.Partitions {
+			if int(partition.ID) < len(assignment) {
+				assignment[partition.ID] = partition.Replicas
+			}
+		}
+		for _, move := range moves {
+			if int(move.Partition) >= len(assignment) {
+				return fmt.Errorf("%w %d: partition %s-%d no longer exists", ErrDrainBroker, plan.Broker, move.Topic, move.Partition)
+			}
+			assignment[move.Partition] = move.Target
+		}
+		if err := admin.AlterPartitionReassignments(topic.Name, assignment); err != nil {
+			return Wrap(ErrDrainBroker, err)
+		}
+	}
+
+	interval := opts.PollInterval
+	if interval <= 0 {
+		interval = defaultDrainPollInterval
+	}
+	for {
+		remaining, err := drainRemaining(admin, names, topics)
+		if err != nil {
+			return Wrap(ErrDrainBroker, err)
+		}
+		if opts.Progress != nil {
+			opts.Progress(DrainProgress{Broker: plan.Broker, Total: len(plan.Moves), Remaining: remaining})
+		}
+		if remaining == 0 {
+			break
+		}
+
+		timer := time.NewTimer(interval)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return Wrap(ErrDrainBroker, ctx.Err(), fmt.Errorf("%d of %d moves still in progress", remaining, len(plan.Moves)))
+		}
+	}
+
+	if opts.Throttle > 0 {
+		return removeDrainThrottle(admin, plan, names)
+	}
+	return nil
+}
+
+// drainRemaining counts the moves whose reassignment is still in progress.
+func drainRemaining(admin ClusterAdmin, names []string, topics map[string][]*ReplicaMove) (int, error) {
+	remaining := 0
+	for _, name := range names {
+		partitions := make([]int32, len(topics[name]))
+		for i, move := range topics[name] {
+			partitions[i] = move.Partition
+		}
+		status, err := admin.ListPartitionReassignments(name, partitions)
+		if err != nil {
+			return 0, err
+		}
+		for _, move := range topics[name] {
+			if _, ok := status[name][move.Partition]; ok {
+				remaining++
+			}
+		}
+	}
+	return remaining, nil
+}
+
+var (
+	drainBrokerThrottleConfigs = []string{"leader.replication.throttled.rate", "follower.replication.throttled.rate"}
+	drainTopicThrottleConfigs  = []string{"leader.replication.throttled.replicas", "follower.replication.throttled.replicas"}
+)
+
+// drainBrokers returns every broker taking part in the moves of plan.
+func drainBrokers(plan *DrainPlan) []int32 {
+	seen := make(map[int32]bool)
+	var brokers []int32
+	add := func(broker int32) {
+		if !seen[broker] {
+			seen[broker] = true
+			brokers = append(brokers, broker)
+		}
+	}
+	for _, move := range plan.Moves {
+		for _, replica := range move.Replicas {
+			add(replica)
+		}
+		add(move.To)
+	}
+	sort.Slice(brokers, func(i, j int) bool { return brokers[i] < brokers[j] })
+	return brokers
+}
+
+// setDrainThrottle throttles the replication from the current replicas of
+// the moved partitions to their new replicas, the same way
+// kafka-reassign-partitions.sh does. The throttle configs the brokers and
+// topics had are recorded in the plan on its first execution, the replicas
+// the topics throttled being added to those of the moves.
+func setDrainThrottle(admin ClusterAdmin, plan *DrainPlan, names []string, topics map[string][]*ReplicaMove, throttle int64) error {
+	brokers := drainBrokers(plan)
+	if plan.previousThrottles == nil {
+		previous, err := describeDrainThrottles(admin, brokers, names)
+		if err != nil {
+			return err
+		}
+		plan.previousThrottles = previous
+	}
+
+	rate := strconv.FormatInt(throttle, 10)
+	for _, broker := range brokers {
+		entries := make(map[string]IncrementalAlterConfigsEntry, len(drainBrokerThrottleConfigs))
+		for _, name := range drainBrokerThrottleConfigs {
+			entries[name] = IncrementalAlterConfigsEntry{Operation: IncrementalAlterConfigsOperationSet, Value: &rate}
+		}
+		if err := admin.IncrementalAlterConfig(BrokerResource, strconv.Itoa(int(broker)), entries, false); err != nil {
+			return fmt.Errorf("throttling broker %d: %w", broker, err)
+		}
+	}
+
+	for _, name := range names {
+		var leaders, followers []string
+		for _, move := range topics[name] {
+			for _, replica := range move.Replicas {
+				leaders = append(leaders, fmt.Sprintf("%d:%d", move.Partition, replica))
+			}
+			followers = append(followers, fmt.Sprintf("%d:%d", move.Partition, move.To))
+		}
+		entries := make(map[string]IncrementalAlterConfigsEntry, len(drainTopicThrottleConfigs))
+		for i, replicas := range [][]string{leaders, followers} {
+			config := drainTopicThrottleConfigs[i]
+			value := strings.Join(replicas, ",")
+			if previous := plan.previousThrottles[drainThrottleConfig{TopicResource, name, config}]; previous != nil {
+				if *previous == "*" {
+					value = *previous
+				} else {
+					value = *previous + "," + value
+				}
+			}
+			entries[config] = IncrementalAlterConfigsEntry{Operation: IncrementalAlterConfigsOperationSet, Value: &value}
+		}
+		if err := admin.IncrementalAlterConfig(TopicResource, name, entries, false); err != nil {
+			return fmt.Errorf("throttling topic %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// describeDrainThrottles returns the throttle configs set on the brokers and
+// topics, nil for those unset.
+func describeDrainThrottles(admin ClusterAdmin, brokers []int32, names []string) (map[drainThrottleConfig]*string, error) {
+	throttles := make(map[drainThrottleConfig]*string)
+	describe := func(resourceType ConfigResourceType, resource string, configs []string, source ConfigSource) error {
+		for _, config := range configs {
+			throttles[drainThrottleConfig{resourceType, resource, config}] = nil
+		}
+		entries, err := admin.DescribeConfig(ConfigResource{Type: resourceType, Name: resource, ConfigNames: configs})
+		if err != nil {
+			return err
+		}
+		for _, entry := range entries {
+			key := drainThrottleConfig{resourceType, resource, entry.Name}
+			if _, ok := throttles[key]; !ok {
+				continue
+			}
+			// the source is unknown before DescribeConfigs v1
+			if entry.Source == source || (entry.Source == SourceUnknown && !entry.Default) {
+				value := entry.Value
+				throttles[key] = &value
+			}
+		}
+		return nil
+	}
+
+	for _, broker := range brokers {
+		if err := describe(BrokerResource, strconv.Itoa(int(broker)), drainBrokerThrottleConfigs, SourceDynamicBroker); err != nil {
+			return nil, fmt.Errorf("describing the throttle of broker %d: %w", broker, err)
+		}
+	}
+	for _, name := range names {
+		if err := describe(TopicResource, name, drainTopicThrottleConfigs, SourceTopic); err != nil {
+			return nil, fmt.Errorf("describing the throttle of topic %s: %w", name, err)
+		}
+	}
+	return throttles, nil
+}
+
+// removeDrainThrottle undoes setDrainThrottle, restoring the throttle configs
+// the brokers and topics had before.
+func removeDrainThrottle(admin ClusterAdmin, plan *DrainPlan, names []string) error {
+	restore := func(resourceType ConfigResourceType, resource string, configs []string) map[string]IncrementalAlterConfigsEntry {
+		entries := make(map[string]IncrementalAlterConfigsEntry, len(configs))
+		for _, name := range configs {
+			if previous := plan.previousThrottles[drainThrottleConfig{resourceType, resource, name}]; previous != nil {
+				entries[name] = IncrementalAlterConfigsEntry{Operation: IncrementalAlterConfigsOperationSet, Value: previous}
+			} else {
+				entries[name] = IncrementalAlterConfigsEntry{Operation: IncrementalAlterConfigsOperationDelete}
+			}
+		}
+		return entries
+	}
+
+	var errs []error
+	for _, name := range names {
+		if err := admin.IncrementalAlterConfig(TopicResource, name, restore(TopicResource, name, drainTopicThrottleConfigs), false); err != nil {
+			errs = append(errs, fmt.Errorf("removing throttle of topic %s: %w", name, err))
+		}
+	}
+	for _, broker := range drainBrokers(plan) {
+		resource := strconv.Itoa(int(broker))
+		if err := admin.IncrementalAlterConfig(BrokerResource, resource, restore(BrokerResource, resource, drainBrokerThrottleConfigs), false); err != nil {
+			errs = append(errs, fmt.Errorf("removing throttle of broker %d: %w", broker, err))
+		}
+	}
+	if len(errs) > 0 {
+		return Wrap(ErrDrainBroker, errs...)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package sarama
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestPlanBrokerDrain(t *testing.T) {
+	rack := func(id int32, rack string) *Broker {
+		return &Broker{id: id, rack: &rack}
+	}
+	brokers := []*Broker{rack(1, "a"), rack(2, "b"), rack(3, "b"), rack(4, "c")}
+	topics := map[string]TopicDetail{
+		"orders": {ReplicaAssignment: map[int32][]int32{0: {1, 2}, 1: {2, 4}}},
+		"events": {ReplicaAssignment: map[int32][]int32{0: {4, 2}}},
+	}
+
+	plan, err := planBrokerDrain(1, brokers, topics, DrainOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*ReplicaMove{{Topic: "orders", Partition: 0, From: 1, To: 3, Replicas: []int32{1, 2}, Target: []int32{3, 2}}}
+	if !reflect.DeepEqual(plan.Moves, expected) {
+		t.Errorf("expected the least loaded broker 3 to be picked, got %+v", plan.Moves[0])
+	}
+
+	plan, err = planBrokerDrain(1, brokers, topics, DrainOptions{RackAware: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(plan.Moves) != 1 || plan.Moves[0].To != 4 {
+		t.Errorf("expected broker 4 to be picked as broker 3 shares its rack with broker 2, got %+v", plan.Moves)
+	}
+
+	if _, err = planBrokerDrain(1, brokers, topics, DrainOptions{Targets: []int32{2}}); err == nil {
+		t.Error("expected an error as broker 2 already hosts orders-0")
+	}
+}
+
+type drainTestAdmin struct {
+	ClusterAdmin
+	topics     []*TopicMetadata
+	inProgress int
+	altered    map[string][][]int32
+	configs    []string
+	// existing are the throttle configs set before the drain, by resource
+	// and config name
+	existing map[string]string
+}
+
+func (a *drainTestAdmin) WithContext(ctx context.Context) ClusterAdmin { return a }
+
+func (a *drainTestAdmin) DescribeTopics(topics []string) ([]*TopicMetadata, error) {
+	return a.topics, nil
+}
+
+func (a *drainTestAdmin) AlterPartitionReassignments(topic string, assignment [][]int32) error {
+	a.altered[topic] = assignment
+	return nil
+}
+
+func (a *drainTestAdmin) ListPartitionReassignments(topic string, partitions []int32) (map[string]map[int32]*PartitionReplicaReassignmentsStatus, error) {
+	status := map[string]map[int32]*PartitionReplicaReassignmentsStatus{topic: {}}
+	if a.inProgress > 0 {
+		a.inProgress--
+		for _, partition := range partitions {
+			status[topic][partition] = &PartitionReplicaReassignmentsStatus{}
+		}
+	}
+	return status, nil
+}
+
+func (a *drainTestAdmin) DescribeConfig(resource ConfigResource) ([]ConfigEntry, error) {
+	source := SourceTopic
+	if resource.Type == BrokerResource {
+		source = SourceDynamicBroker
+	}
+	var entries []ConfigEntry
+	for _, name := range resource.ConfigNames {
+		if value, ok := a.existing[resource.Name+" "+name]; ok {
+			entries = append(entries, ConfigEntry{Name: name, Value: value, Source: source})
+		} else {
+			entries = append(entries, ConfigEntry{Name: name, Value: "", Default: true, Source: SourceDefault})
+		}
+	}
+	return entries, nil
+}
+
+func (a *drainTestAdmin) IncrementalAlterConfig(resourceType ConfigResourceType, name string, entries map[string]IncrementalAlterConfigsEntry, validateOnly bool) error {
+	for config, entry := range entries {
+		value := "<deleted>"
+		if entry.Value != nil {
+			value = *entry.Value
+		}
+		a.configs = append(a.configs, name+" "+config+"="+value)
+	}
+	return nil
+}
+
+func TestExecuteDrainPlan(t *testing.T) {
+	admin := &drainTestAdmin{
+		topics: []*TopicMetadata{{
+			Name: "orders",
+			Partitions: []*PartitionMetadata{
+				{ID: 0, Replicas: []int32{1, 2}},
+				{ID: 1, Replicas: []int32{2, 3}},
+			},
+		}},
+		inProgress: 1,
+		altered:    make(map[string][][]int32),
+	}
+	plan := &DrainPlan{Broker: 1, Moves: []*ReplicaMove{
+		{Topic: "orders", Partition: 0, From: 1, To: 3, Replicas: []int32{1, 2}, Target: []int32{3, 2}},
+	}}
+
+	var progress []DrainProgress
+	err := ExecuteDrainPlan(context.Background(), admin, plan, DrainOptions{
+		Throttle:     1024,
+		PollInterval: time.Millisecond,
+		Progress:     func(p DrainProgress) { progress = append(progress, p) },
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := [][]int32{{3, 2}, {2, 3}}; !reflect.DeepEqual(admin.altered["orders"], expected) {
+		t.Errorf("expected assignment %v, got %v", expected, admin.altered["orders"])
+	}
+	if expected := []DrainProgress{{1, 1, 1}, {1, 1, 0}}; !reflect.DeepEqual(progress, expected) {
+		t.Errorf("expected progress %v, got %v", expected, progress)
+	}
+
+	set, removed := 0, 0
+	for _, config := range admin.configs {
+		switch config {
+		case "orders leader.replication.throttled.replicas=0:1,0:2",
+			"orders follower.replication.throttled.replicas=0:3",
+			"1 leader.replication.throttled.rate=1024",
+			"3 follower.replication.throttled.rate=1024":
+			set++
+		case "orders leader.replication.throttled.replicas=<deleted>",
+			"2 follower.replication.throttled.rate=<deleted>":
+			removed++
+		}
+	}
+	if set != 4 || removed != 2 {
+		t.Errorf("expected throttles to be set and removed, got %v", admin.configs)
+	}
+}
+
+func TestExecuteDrainPlanContextDone(t *testing.T) {
+	admin := &drainTestAdmin{
+		topics:     []*TopicMetadata{{Name: "orders", Partitions: []*PartitionMetadata{{ID: 0, Replicas: []int32{1, 2}}}}},
+		inProgress: 1000,
+		altered:    make(map[string][][]int32),
+	}
+	plan := &DrainPlan{Broker: 1, Moves: []*ReplicaMove{
+		{Topic: "orders", Partition: 0, From: 1, To: 3, Replicas: []int32{1, 2}, Target: []int32{3, 2}},
+	}}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	err := ExecuteDrainPlan(ctx, admin, plan, DrainOptions{PollInterval: time.Millisecond})
+	if !errors.Is(err, ErrDrainBroker) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrDrainBroker wrapping the context error, got %v", err)
+	}
+}
+
+func TestExecuteDrainPlanRestoresThrottles(t *testing.T) {
+	admin := &drainTestAdmin{
+		topics:     []*TopicMetadata{{Name: "orders", Partitions: []*PartitionMetadata{{ID: 0, Replicas: []int32{1, 2}}}}},
+		inProgress: 1,
+		altered:    make(map[string][][]int32),
+		existing: map[string]string{
+			"orders leader.replication.throttled.replicas":   "5:4",
+			"orders follower.replication.throttled.replicas": "*",
+			"2 leader.replication.throttled.rate":            "512",
+		},
+	}
+	plan := &DrainPlan{Broker: 1, Moves: []*ReplicaMove{
+		{Topic: "orders", Partition: 0, From: 1, To: 3, Replicas: []int32{1, 2}, Target: []int32{3, 2}},
+	}}
+
+	err := ExecuteDrainPlan(context.Background(), admin, plan, DrainOptions{Throttle: 1024, PollInterval: time.Millisecond})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the replicas throttled before stay throttled during the drain, and the
+	// previous throttles are restored rather than deleted afterwards
+	for _, expected := range []string{
+		"orders leader.replication.throttled.replicas=5:4,0:1,0:2",
+		"orders follower.replication.throttled.replicas=*",
+		"2 leader.replication.throttled.rate=1024",
+		"orders leader.replication.throttled.replicas=5:4",
+		"2 leader.replication.throttled.rate=512",
+		"2 follower.replication.throttled.rate=<deleted>",
+	} {
+		found := false
+		for _, config := range admin.configs {
+			found = found || config == expected
+		}
+		if !found {
+			t.Errorf("expected %q, got %v", expected, admin.configs)
+		}
+	}
+	for _, config := range admin.configs {
+		if config == "orders leader.replication.throttled.replicas=<deleted>" || config == "2 leader.replication.throttled.rate=<deleted>" {
+			t.Errorf("expected the existing throttle to be restored, got %q", config)
+		}
+	}
+}
@@ -0,0 +1,160 @@
+package sarama
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	// ErrAlterConsumerGroupOffsets is returned when some of the offsets passed
+	// to AlterConsumerGroupOffsets could not be committed.
+	ErrAlterConsumerGroupOffsets = errors.New("kafka: failed to alter one or more consumer group offsets")
+
+	// ErrConsumerGroupNotEmpty is returned when the offsets of a consumer group
+	// with active members are about to be overwritten.
+	ErrConsumerGroupNotEmpty = errors.New("kafka: consumer group is not empty")
+)
+
+// GroupOffset is the offset committed by a consumer group for a partition,
+// along with its metadata.
+type GroupOffset struct {
+	Offset   int64  `json:"offset"`
+	Metadata string `json:"metadata,omitempty"`
+}
+
+// GroupOffsetsSnapshot holds the committed offsets of a consumer group, keyed
+// by topic and partition. It can be marshalled to JSON to be stored and later
+// restored with ImportConsumerGroupOffsets.
+type GroupOffsetsSnapshot struct {
+	Group   string                           `json:"group"`
+	Offsets map[string]map[int32]GroupOffset `json:"offsets"`
+}
+
+// ExportConsumerGroupOffsets returns a snapshot of every offset committed by
+// group. Partitions without a committed offset are omitted.
+func ExportConsumerGroupOffsets(admin ClusterAdmin, group string) (*GroupOffsetsSnapshot, error) {
+	rsp, err := admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !errors.Is(rsp.Err, ErrNoError) {
+		return nil, rsp.Err
+	}
+
+	snapshot := &GroupOffsetsSnapshot{Group: group, Offsets: make(map[string]map[int32]GroupOffset)}
+	for topic, partitions := range rsp.Blocks {
+		for partition, block := range partitions {
+			if !errors.Is(block.Err, ErrNoError) {
+				return nil, fmt.Errorf("[%s-%d]: %w", topic, partition, block.Err)
+			}
+			if block.Offset < 0 {
+				continue
+			}
+			if snapshot.Offsets[topic] == nil {
+				snapshot.Offsets[topic] = make(map[int32]GroupOffset, len(partitions))
+			}
+			snapshot.Offsets[topic][partition] = GroupOffset{Offset: block.Offset, Metadata: block.Metadata}
+		}
+	}
+	return snapshot, nil
+}
+
+// ImportConsumerGroupOffsets commits the offsets of snapshot on behalf of
+// group, or of the group the snapshot was taken from when group is empty.
+// It fails with ErrConsumerGroupNotEmpty if the group has active members.
+func ImportConsumerGroupOffsets(admin ClusterAdmin, group string, snapshot *GroupOffsetsSnapshot) error {
+	if group == "" {
+		group = snapshot.Group
+	}
+	if err := ensureConsumerGroupEmpty(admin, group); err != nil {
+		return err
+	}
+	if len(snapshot.Offsets) == 0 {
+		return nil
+	}
+	return admin.AlterConsumerGroupOffsets(group, snapshot.Offsets)
+}
+
+// ResetOffsetsOptions controls how ResetConsumerGroupOffsets resets offsets.
+type ResetOffsetsOptions struct {
+	// DryRun computes the new offsets without committing them.
+	DryRun bool
+}
+
+// ResetConsumerGroupOffsets moves the offsets of group, for the given
+// partitions, to the earliest or latest offset when time is OffsetOldest or
+// OffsetNewest, or else to the first message whose timestamp in milliseconds
+// is at least time. Partitions without such message are reset to the latest
+// offset. A nil partition list stands for every partition of the topic, which
+// makes it the equivalent of kafka-consumer-groups.sh --reset-offsets.
+//
+// The group must be empty, otherwise ErrConsumerGroupNotEmpty is returned.
+// The new offsets are returned, even in DryRun mode.
+func ResetConsumerGroupOffsets(admin ClusterAdmin, group string, topicPartitions map[string][]int32, time int64, opts ResetOffsetsOptions) (map[string]map[int32]int64, error) {
+	if err := ensureConsumerGroupEmpty(admin, group); err != nil {
+		return nil, err
+	}
+
+	offsets, err := admin.ListOffsets(topicPartitions, time)
+	if err != nil {
+		return nil, err
+	}
+
+	if time != OffsetNewest {
+		missing := make(map[string][]int32)
+		for topic, partitions := range offsets {
+			for partition, offset := range partitions {
+				if offset < 0 {
+					missing[topic] = append(missing[topic], partition)
+				}
+			}
+		}
+		if len(missing) > 0 {
+			latest, err := admin.ListOffsets(missing, OffsetNewest)
+			if err != nil {
+				return nil, err
+			}
+			for topic, partitions := range latest {
+				for partition, offset := range partitions {
+					offsets[topic][partition] = offset
+				}
+			}
+		}
+	}
+
+	if opts.DryRun {
+		return offsets, nil
+	}
+
+	commit := make(map[string]map[int32]GroupOffset, len(offsets))
+	for topic, partitions := range offsets {
+		commit[topic] = make(map[int32]GroupOffset, len(partitions))
+		for partition, offset := range partitions {
+			commit[topic][partition] = GroupOffset{Offset: offset}
+		}
+	}
+	return offsets, admin.AlterConsumerGroupOffsets(group, commit)
+}
+
+// ensureConsumerGroupEmpty returns ErrConsumerGroupNotEmpty if group has
+// active members. Groups that do not exist yet are considered empty.
+func ensureConsumerGroupEmpty(admin ClusterAdmin, group string) error {
+	descriptions, err := admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return err
+	}
+	for _, description := range descriptions {
+		if description.GroupId != group {
+			continue
+		}
+		switch {
+		case errors.Is(description.Err, ErrGroupIDNotFound):
+			return nil
+		case !errors.Is(description.Err, ErrNoError):
+			return description.Err
+		case len(description.Members) > 0, description.State != "" && description.State != "Empty" && description.State != "Dead":
+			return fmt.Errorf("%w: %s is %s with %d members", ErrConsumerGroupNotEmpty, group, description.State, len(description.Members))
+		}
+	}
+	return nil
+}
@@ -0,0 +1,124 @@
+package sarama
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func newGroupOffsetsTestAdmin(t *testing.T, handlers map[string]MockResponse) (*MockBroker, ClusterAdmin) {
+	t.Helper()
+	seedBroker := NewMockBroker(t, 1)
+
+	coordinator := NewMockFindCoordinatorResponse(t)
+	for _, group := range []string{"source", "target", "busy", "idle"} {
+		coordinator.SetCoordinator(CoordinatorGroup, group, seedBroker)
+	}
+	handlers["MetadataRequest"] = NewMockMetadataResponse(t).
+		SetController(seedBroker.BrokerID()).
+		SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+		SetLeader("orders", 0, seedBroker.BrokerID()).
+		SetLeader("orders", 1, seedBroker.BrokerID())
+	handlers["FindCoordinatorRequest"] = coordinator
+	handlers["OffsetCommitRequest"] = NewMockOffsetCommitResponse(t)
+	handlers["DescribeGroupsRequest"] = NewMockDescribeGroupsResponse(t).
+		AddGroupDescription("busy", &GroupDescription{
+			GroupId: "busy",
+			State:   "Stable",
+			Members: map[string]*GroupMemberDescription{"member": {}},
+		})
+	seedBroker.SetHandlerByMap(handlers)
+
+	config := NewTestConfig()
+	config.Version = V1_0_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		seedBroker.Close()
+		t.Fatal(err)
+	}
+	return seedBroker, admin
+}
+
+func TestExportImportConsumerGroupOffsets(t *testing.T) {
+	seedBroker, admin := newGroupOffsetsTestAdmin(t, map[string]MockResponse{
+		"OffsetFetchRequest": NewMockOffsetFetchResponse(t).
+			SetOffset("source", "orders", 0, 10, "meta", ErrNoError).
+			SetOffset("source", "orders", 1, -1, "", ErrNoError),
+	})
+	defer seedBroker.Close()
+	defer safeClose(t, admin)
+
+	snapshot, err := ExportConsumerGroupOffsets(admin, "source")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(snapshot.Offsets["orders"]) != 1 || snapshot.Offsets["orders"][0] != (GroupOffset{Offset: 10, Metadata: "meta"}) {
+		t.Fatalf("unexpected snapshot %+v", snapshot)
+	}
+
+	data, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	restored := new(GroupOffsetsSnapshot)
+	if err := json.Unmarshal(data, restored); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ImportConsumerGroupOffsets(admin, "target", restored); err != nil {
+		t.Fatal(err)
+	}
+	var committed *OffsetCommitRequest
+	for _, rr := range seedBroker.History() {
+		if req, ok := rr.Request.(*OffsetCommitRequest); ok {
+			committed = req
+		}
+	}
+	if committed == nil || committed.ConsumerGroup != "target" {
+		t.Fatalf("expected the offsets to be committed for target, got %+v", committed)
+	}
+	if block := committed.blocks["orders"][0]; block == nil || block.offset != 10 || block.metadata != "meta" {
+		t.Errorf("unexpected committed offset %+v", block)
+	}
+
+	if err := ImportConsumerGroupOffsets(admin, "busy", restored); !errors.Is(err, ErrConsumerGroupNotEmpty) {
+		t.Errorf("expected ErrConsumerGroupNotEmpty, got %v", err)
+	}
+}
+
+func TestResetConsumerGroupOffsets(t *testing.T) {
+	seedBroker, admin := newGroupOffsetsTestAdmin(t, map[string]MockResponse{
+		"OffsetRequest": NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset("orders", 0, 1000, 5).
+			SetOffset("orders", 1, 1000, -1).
+			SetOffset("orders", 1, OffsetNewest, 42),
+	})
+	defer seedBroker.Close()
+	defer safeClose(t, admin)
+
+	offsets, err := ResetConsumerGroupOffsets(admin, "idle", map[string][]int32{"orders": nil}, 1000, ResetOffsetsOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offsets["orders"][0] != 5 || offsets["orders"][1] != 42 {
+		t.Errorf("expected offsets 5 and 42, got %v", offsets)
+	}
+
+	var committed *OffsetCommitRequest
+	for _, rr := range seedBroker.History() {
+		if req, ok := rr.Request.(*OffsetCommitRequest); ok {
+			committed = req
+		}
+	}
+	if committed == nil || committed.blocks["orders"][0].offset != 5 || committed.blocks["orders"][1].offset != 42 {
+		t.Fatalf("unexpected commit %+v", committed)
+	}
+	if committed.ConsumerGroupGeneration != GroupGenerationUndefined || committed.ConsumerID != "" {
+		t.Errorf("expected a commit outside of any generation, got %+v", committed)
+	}
+
+	if _, err := ResetConsumerGroupOffsets(admin, "busy", map[string][]int32{"orders": {0}}, OffsetOldest, ResetOffsetsOptions{}); !errors.Is(err, ErrConsumerGroupNotEmpty) {
+		t.Errorf("expected ErrConsumerGroupNotEmpty, got %v", err)
+	}
+}
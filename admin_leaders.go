@@ -0,0 +1,172 @@
+package sarama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrElectPreferredLeaders is returned when some of the preferred leader
+// elections triggered by RebalancePreferredLeaders failed.
+var ErrElectPreferredLeaders = errors.New("kafka: failed to elect one or more preferred leaders")
+
+const defaultPreferredLeaderBatchSize = 50
+
+// PreferredLeaderOptions controls how RebalancePreferredLeaders restores
+// preferred leadership.
+type PreferredLeaderOptions struct {
+	// Topics restricts the rebalance to the given topics, every topic of the
+	// cluster is considered when empty.
+	Topics []string
+	// BatchSize is the maximum number of partitions elected per request,
+	// defaults to 50.
+	BatchSize int
+	// Pause is the time waited between two batches, giving clients time to
+	// catch up with the leadership changes.
+	Pause time.Duration
+	// DryRun finds the partitions to elect without electing them.
+	DryRun bool
+}
+
+// PreferredLeaderElection describes a partition whose leader is not its
+// preferred replica.
+type PreferredLeaderElection struct {
+	Topic     string
+	Partition int32
+	// Leader is the leader before the election and Preferred the replica
+	// to be elected.
+	Leader    int32
+	Preferred int32
+	// Err holds the error of the election of this partition, if any.
+	Err error
+}
+
+// FindLeaderSkew returns the partitions of topics, or of every topic when
+// topics is empty, which are not led by their preferred replica even though
+// it is in sync, sorted by topic and partition.
+func FindLeaderSkew(admin ClusterAdmin, topics []string) ([]*PreferredLeaderElection, error) {
+	if len(topics) == 0 {
+		topics = nil
+	}
+	metadata, err := admin.DescribeTopics(topics)
+	if err != nil {
+		return nil, err
+	}
+
+	var skewed []*PreferredLeaderElection
+	for _, topic := range metadata {
+		if !errors.Is(topic.Err, ErrNoError) {
+			continue
+		}
+		for _, partition := range topic.Partitions {
+			if len(partition.Replicas) == 0 {
+				continue
+			}
+			preferred := partition.Replicas[0]
+			if partition.Leader == preferred || !containsInt32(partition.Isr, preferred) {
+				continue
+			}
+			skewed = append(skewed, &PreferredLeaderElection{
+				Topic:     topic.Name,
+				Partition: partition.ID,
+				Leader:    partition.Leader,
+				Preferred: preferred,
+			})
+		}
+	}
+
+	sort.Slice(skewed, func(i, j int) bool {
+		if skewed[i].Topic != skewed[j].Topic {
+			return skewed[i].Topic < skewed[j].Topic
+		}
+		return skewed[i].Partition < skewed[j].Partition
+	})
+	return skewed, nil
+}
+
+// RebalancePreferredLeaders moves the leadership of every partition found by
+// FindLeaderSkew back to its preferred replica, electing them in batches with
+// a pause in between to limit the impact on clients. It returns the
+// elections it triggered, along with their individual errors; when ctx is
+// done between two batches, the remaining partitions are left untouched.
+func RebalancePreferredLeaders(ctx context.Context, admin ClusterAdmin, opts PreferredLeaderOptions) ([]*PreferredLeaderElection, error) {
+	admin = admin.WithContext(ctx)
+
+	skewed, err := FindLeaderSkew(admin, opts.Topics)
+	if err != nil || opts.DryRun {
+		return skewed, err
+	}
+
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = defaultPreferredLeaderBatchSize
+	}
+
+	var errs []error
+	for start := 0; start < len(skewed); start += batchSize {
+		if start > 0 && opts.Pause > 0 {
+			timer := time.NewTimer(opts.Pause)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return skewed[:start], Wrap(ErrElectPreferredLeaders, append(errs, ctx.Err())...)
+			}
+		}
+
+		end := start + batchSize
+		if end > len(skewed) {
+			end = len(skewed)
+		}
+		errs = append(errs, electPreferredLeaders(admin, skewed[start:end])...)
+	}
+
+	if len(errs) > 0 {
+		return skewed, Wrap(ErrElectPreferredLeaders, errs...)
+	}
+	return skewed, nil
+}
+
+// electPreferredLeaders runs the elections of batch, recording their outcome.
+func electPreferredLeaders(admin ClusterAdmin, batch []*PreferredLeaderElection) []error {
+	partitions := make(map[string][]int32)
+	for _, election := range batch {
+		partitions[election.Topic] = append(partitions[election.Topic], election.Partition)
+	}
+
+	results, err := admin.ElectLeaders(PreferredElection, partitions)
+	if err != nil {
+		for _, election := range batch {
+			election.Err = err
+		}
+		return []error{err}
+	}
+
+	var errs []error
+	for _, election := range batch {
+		result, ok := results[election.Topic][election.Partition]
+		switch {
+		case !ok:
+			election.Err = ErrIncompleteResponse
+		case errors.Is(result.ErrorCode, ErrNoError), errors.Is(result.ErrorCode, ErrElectionNotNeeded):
+			continue
+		case result.ErrorMessage != nil:
+			election.Err = fmt.Errorf("%w: %s", result.ErrorCode, *result.ErrorMessage)
+		default:
+			election.Err = result.ErrorCode
+		}
+		errs = append(errs, fmt.Errorf("[%s-%d]: %w", election.Topic, election.Partition, election.Err))
+	}
+	return errs
+}
+
+func containsInt32(values []int32, value int32) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
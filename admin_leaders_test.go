@@ -0,0 +1,70 @@
+package sarama
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type leadersTestAdmin struct {
+	ClusterAdmin
+	topics  []*TopicMetadata
+	batches []map[string][]int32
+}
+
+func (a *leadersTestAdmin) WithContext(ctx context.Context) ClusterAdmin { return a }
+
+func (a *leadersTestAdmin) DescribeTopics(topics []string) ([]*TopicMetadata, error) {
+	return a.topics, nil
+}
+
+func (a *leadersTestAdmin) ElectLeaders(electionType ElectionType, partitions map[string][]int32) (map[string]map[int32]*PartitionResult, error) {
+	a.batches = append(a.batches, partitions)
+	results := make(map[string]map[int32]*PartitionResult)
+	for topic, ids := range partitions {
+		results[topic] = make(map[int32]*PartitionResult)
+		for _, id := range ids {
+			result := &PartitionResult{ErrorCode: ErrNoError}
+			if id == 2 {
+				result.ErrorCode = ErrPreferredLeaderNotAvailable
+			}
+			results[topic][id] = result
+		}
+	}
+	return results, nil
+}
+
+func TestRebalancePreferredLeaders(t *testing.T) {
+	admin := &leadersTestAdmin{topics: []*TopicMetadata{{
+		Name: "orders",
+		Partitions: []*PartitionMetadata{
+			{ID: 0, Leader: 2, Replicas: []int32{1, 2}, Isr: []int32{1, 2}},
+			{ID: 1, Leader: 1, Replicas: []int32{1, 2}, Isr: []int32{1, 2}}, // already balanced
+			{ID: 2, Leader: 1, Replicas: []int32{2, 1}, Isr: []int32{1, 2}},
+			{ID: 3, Leader: 1, Replicas: []int32{2, 1}, Isr: []int32{1}}, // preferred out of sync
+			{ID: 4, Leader: 1, Replicas: []int32{3, 1}, Isr: []int32{1, 3}},
+		},
+	}}}
+
+	elections, err := RebalancePreferredLeaders(context.Background(), admin, PreferredLeaderOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(elections) != 3 || elections[0].Partition != 0 || elections[1].Partition != 2 || elections[2].Partition != 4 {
+		t.Fatalf("expected partitions 0, 2 and 4 to be skewed, got %+v", elections)
+	}
+	if len(admin.batches) != 0 {
+		t.Fatal("dry run triggered elections")
+	}
+
+	elections, err = RebalancePreferredLeaders(context.Background(), admin, PreferredLeaderOptions{BatchSize: 2})
+	if !errors.Is(err, ErrElectPreferredLeaders) || !errors.Is(err, ErrPreferredLeaderNotAvailable) {
+		t.Fatalf("expected the failed election of partition 2 to be reported, got %v", err)
+	}
+	if len(admin.batches) != 2 || len(admin.batches[0]["orders"]) != 2 || len(admin.batches[1]["orders"]) != 1 {
+		t.Errorf("expected elections in batches of 2, got %v", admin.batches)
+	}
+	if elections[0].Err != nil || !errors.Is(elections[1].Err, ErrPreferredLeaderNotAvailable) || elections[2].Err != nil {
+		t.Errorf("unexpected election outcomes %v, %v, %v", elections[0].Err, elections[1].Err, elections[2].Err)
+	}
+}
@@ -0,0 +1,252 @@
+package sarama
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+)
+
+// ErrReconcileTopics is returned when one or more changes computed by
+// ReconcileTopics could not be applied
+var ErrReconcileTopics = errors.New("kafka: failed to reconcile one or more topics")
+
+// TopicReconcileOptions controls how ReconcileTopics brings the cluster in
+// line with the desired topic specifications.
+type TopicReconcileOptions struct {
+	// DryRun computes the changes without applying any of them.
+	DryRun bool
+	// DeleteUndeclaredConfigs removes topic level config overrides which are
+	// set on the cluster but are not present in the desired ConfigEntries.
+	DeleteUndeclaredConfigs bool
+}
+
+// TopicChange describes what ReconcileTopics found to differ between the
+// desired and the actual state of a single topic.
+type TopicChange struct {
+	Topic string
+	// Detail is the desired specification of the topic.
+	Detail *TopicDetail
+	// Create is true when the topic does not exist yet and will be created
+	// using Detail.
+	Create bool
+	// CurrentPartitions and DesiredPartitions differ when partitions need
+	// to be added to an existing topic.
+	CurrentPartitions int32
+	DesiredPartitions int32
+	// ConfigChanges holds the incremental config operations needed to
+	// converge the topic configuration.
+	ConfigChanges map[string]IncrementalAlterConfigsEntry
+	// Warnings lists differences that cannot be reconciled by the available
+	// APIs, such as replication factor changes or partition count decreases.
+	Warnings []string
+	// Err holds the error encountered while applying this change, if any.
+	Err error
+}
+
+// Empty returns true if no action is required for the topic.
+func (c *TopicChange) Empty() bool {
+	return !c.Create && c.CurrentPartitions == c.DesiredPartitions && len(c.ConfigChanges) == 0
+}
+
+// DiffTopics compares the desired topic specifications against the cluster
+// and returns the changes required to converge them, sorted by topic name.
+// Topics that already match are omitted unless they carry warnings.
+func DiffTopics(admin ClusterAdmin, desired map[string]*TopicDetail, opts TopicReconcileOptions) ([]*TopicChange, error) {
+	if len(desired) == 0 {
+		return nil, nil
+	}
+
+	topics := make([]string, 0, len(desired))
+	for topic, detail := range desired {
+		if topic == "" {
+			return nil, ErrInvalidTopic
+		}
+		if detail == nil {
+			return nil, fmt.Errorf("you must specify topic details for %s", topic)
+		}
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	metadata, err := admin.DescribeTopics(topics)
+	if err != nil {
+		return nil, err
+	}
+	current := make(map[string]*TopicMetadata, len(metadata))
+	for _, topic := range metadata {
+		switch {
+		case errors.Is(topic.Err, ErrNoError):
+			current[topic.Name] = topic
+		case errors.Is(topic.Err, ErrUnknownTopicOrPartition):
+			// does not exist yet
+		default:
+			return nil, fmt.Errorf("describing topic %s: %w", topic.Name, topic.Err)
+		}
+	}
+
+	var changes []*TopicChange
+	for _, topic := range topics {
+		detail := desired[topic]
+
+		existing, ok := current[topic]
+		if !ok {
+			changes = append(changes, &TopicChange{
+				Topic:             topic,
+				Create:            true,
+				Detail:            detail,
+				DesiredPartitions: detail.NumPartitions,
+			})
+			continue
+		}
+
+		change := &TopicChange{
+			Topic:             topic,
+			Detail:            detail,
+			CurrentPartitions: int32(len(existing.Partitions)),
+			DesiredPartitions: int32(len(existing.Partitions)),
+		}
+
+		switch {
+		case detail.NumPartitions > change.CurrentPartitions:
+			change.DesiredPartitions = detail.NumPartitions
+		case detail.NumPartitions > 0 && detail.NumPartitions < change.CurrentPartitions:
+			change.Warnings = append(change.Warnings, fmt.Sprintf(
+				"cannot reduce partitions from %d to %d", change.CurrentPartitions, detail.NumPartitions))
+		}
+
+		if detail.ReplicationFactor > 0 && len(existing.Partitions) > 0 {
+			if rf := int16(len(existing.Partitions[0].Replicas)); rf != detail.ReplicationFactor {
+				change.Warnings = append(change.Warnings, fmt.Sprintf(
+					"replication factor is %d but %d is desired, use AlterPartitionReassignments to change it", rf, detail.ReplicationFactor))
+			}
+		}
+
+		entries, err := admin.DescribeConfig(ConfigResource{Type: TopicResource, Name: topic})
+		if err != nil {
+			return nil, fmt.Errorf("describing config of topic %s: %w", topic, err)
+		}
+		change.ConfigChanges = diffTopicConfig(entries, detail.ConfigEntries, opts.DeleteUndeclaredConfigs)
+
+		if !change.Empty() || len(change.Warnings) > 0 {
+			changes = append(changes, change)
+		}
+	}
+
+	return changes, nil
+}
+
+// diffTopicConfig returns the incremental operations needed to move from the
+// current config entries to the desired ones. A nil desired value requests
+// the removal of the override.
+func diffTopicConfig(current []ConfigEntry, desired map[string]*string, deleteUndeclared bool) map[string]IncrementalAlterConfigsEntry {
+	ops := make(map[string]IncrementalAlterConfigsEntry)
+
+	overrides := make(map[string]ConfigEntry, len(current))
+	for _, entry := range current {
+		if isTopicConfigOverride(entry) {
+			overrides[entry.Name] = entry
+		}
+	}
+
+	for name, value := range desired {
+		override, overridden := overrides[name]
+		if value == nil {
+			if overridden {
+				ops[name] = IncrementalAlterConfigsEntry{Operation: IncrementalAlterConfigsOperationDelete}
+			}
+			continue
+		}
+		// sensitive values are never returned by the broker, so they
+		// cannot be compared and are always set
+		if overridden && !override.Sensitive && override.Value == *value {
+			continue
+		}
+		ops[name] = IncrementalAlterConfigsEntry{Operation: IncrementalAlterConfigsOperationSet, Value: value}
+	}
+
+	if deleteUndeclared {
+		for name := range overrides {
+			if _, declared := desired[name]; !declared {
+				ops[name] = IncrementalAlterConfigsEntry{Operation: IncrementalAlterConfigsOperationDelete}
+			}
+		}
+	}
+
+	if len(ops) == 0 {
+		return nil
+	}
+	return ops
+}
+
+// isTopicConfigOverride returns true if the entry is set at the topic level
+// rather than inherited from the broker configuration.
+func isTopicConfigOverride(entry ConfigEntry) bool {
+	if entry.Source != SourceUnknown {
+		return entry.Source == SourceTopic
+	}
+	// DescribeConfigs v0 does not report the source of an entry
+	return !entry.Default && !entry.ReadOnly
+}
+
+// ApplyTopicChanges issues the CreateTopics, CreatePartitions and
+// IncrementalAlterConfigs calls described by changes. It attempts every
+// change and records failures in the Err field of the corresponding
+// TopicChange, returning an ErrReconcileTopics wrapping them.
+func ApplyTopicChanges(admin ClusterAdmin, changes []*TopicChange) error {
+	errs := make([]error, 0)
+	for _, change := range changes {
+		if change.Err = applyTopicChange(admin, change); change.Err != nil {
+			errs = append(errs, fmt.Errorf("[%s]: %w", change.Topic, change.Err))
+		}
+	}
+	if len(errs) > 0 {
+		return Wrap(ErrReconcileTopics, errs...)
+	}
+	return nil
+}
+
+func applyTopicChange(admin ClusterAdmin, change *TopicChange) error {
+	if change.Create {
+		return admin.CreateTopic(change.Topic, change.Detail, false)
+	}
+
+	if change.DesiredPartitions > change.CurrentPartitions {
+		var assignment [][]int32
+		if change.Detail != nil && len(change.Detail.ReplicaAssignment) > 0 {
+			for p := change.CurrentPartitions; p < change.DesiredPartitions; p++ {
+				replicas, ok := change.Detail.ReplicaAssignment[p]
+				if !ok {
+					// let the controller choose the placement of all new
+					// partitions if any of them is left unspecified
+					assignment = nil
+					break
+				}
+				assignment = append(assignment, replicas)
+			}
+		}
+		if err := admin.CreatePartitions(change.Topic, change.DesiredPartitions, assignment, false); err != nil {
+			return err
+		}
+	}
+
+	if len(change.ConfigChanges) > 0 {
+		if err := admin.IncrementalAlterConfig(TopicResource, change.Topic, change.ConfigChanges, false); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// ReconcileTopics computes the minimal set of changes needed for the topics in
+// desired to match their specification and, unless opts.DryRun is set,
+// applies them. Topics which are not part of desired are left untouched.
+// The returned changes are always populated, even when applying them fails.
+func ReconcileTopics(admin ClusterAdmin, desired map[string]*TopicDetail, opts TopicReconcileOptions) ([]*TopicChange, error) {
+	changes, err := DiffTopics(admin, desired, opts)
+	if err != nil || opts.DryRun {
+		return changes, err
+	}
+
+	return changes, ApplyTopicChanges(admin, changes)
+}
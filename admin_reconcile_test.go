@@ -0,0 +1,141 @@
+package sarama
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestReconcileTopics(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetLeader("existing_topic", 0, seedBroker.BrokerID()),
+		"DescribeConfigsRequest":         NewMockDescribeConfigsResponse(t),
+		"CreateTopicsRequest":            NewMockCreateTopicsResponse(t),
+		"CreatePartitionsRequest":        NewMockCreatePartitionsResponse(t),
+		"IncrementalAlterConfigsRequest": NewMockIncrementalAlterConfigsResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V2_3_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	retention := "5000"
+	compact := "compact"
+	desired := map[string]*TopicDetail{
+		"existing_topic": {
+			NumPartitions:     3,
+			ReplicationFactor: 1,
+			ConfigEntries: map[string]*string{
+				"retention.ms":   &retention,
+				"cleanup.policy": &compact,
+			},
+		},
+		"new_topic": {NumPartitions: 2, ReplicationFactor: 1},
+	}
+
+	changes, err := ReconcileTopics(admin, desired, TopicReconcileOptions{DryRun: true, DeleteUndeclaredConfigs: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("expected 2 changes, got %d", len(changes))
+	}
+
+	existing := changes[0]
+	if existing.Topic != "existing_topic" || existing.Create {
+		t.Fatalf("unexpected change %+v", existing)
+	}
+	if existing.CurrentPartitions != 1 || existing.DesiredPartitions != 3 {
+		t.Errorf("expected partitions to grow from 1 to 3, got %d to %d", existing.CurrentPartitions, existing.DesiredPartitions)
+	}
+	if _, ok := existing.ConfigChanges["retention.ms"]; ok {
+		t.Error("retention.ms is already up to date and should not be altered")
+	}
+	if op := existing.ConfigChanges["cleanup.policy"]; op.Operation != IncrementalAlterConfigsOperationSet || *op.Value != compact {
+		t.Errorf("expected cleanup.policy to be set, got %+v", op)
+	}
+	if op, ok := existing.ConfigChanges["password"]; !ok || op.Operation != IncrementalAlterConfigsOperationDelete {
+		t.Errorf("expected undeclared password override to be deleted, got %+v", op)
+	}
+
+	created := changes[1]
+	if created.Topic != "new_topic" || !created.Create || created.Detail != desired["new_topic"] {
+		t.Fatalf("unexpected change %+v", created)
+	}
+
+	for _, rr := range seedBroker.History() {
+		switch rr.Request.(type) {
+		case *CreateTopicsRequest, *CreatePartitionsRequest, *IncrementalAlterConfigsRequest:
+			t.Fatalf("dry run issued a %T", rr.Request)
+		}
+	}
+
+	if _, err := ReconcileTopics(admin, desired, TopicReconcileOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	issued := make(map[string]bool)
+	for _, rr := range seedBroker.History() {
+		switch req := rr.Request.(type) {
+		case *CreateTopicsRequest:
+			if _, ok := req.TopicDetails["new_topic"]; ok {
+				issued["create"] = true
+			}
+		case *CreatePartitionsRequest:
+			if tp := req.TopicPartitions["existing_topic"]; tp != nil && tp.Count == 3 {
+				issued["partitions"] = true
+			}
+		case *IncrementalAlterConfigsRequest:
+			issued["configs"] = true
+		}
+	}
+	for _, call := range []string{"create", "partitions", "configs"} {
+		if !issued[call] {
+			t.Errorf("expected %s request to be issued", call)
+		}
+	}
+}
+
+func TestReconcileTopicsReportsFailures(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"CreateTopicsRequest": NewMockCreateTopicsResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V1_0_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	desired := map[string]*TopicDetail{
+		"_reserved": {NumPartitions: 1, ReplicationFactor: 1},
+		"allowed":   {NumPartitions: 1, ReplicationFactor: 1},
+	}
+	changes, err := ReconcileTopics(admin, desired, TopicReconcileOptions{})
+	if !errors.Is(err, ErrReconcileTopics) {
+		t.Fatalf("expected ErrReconcileTopics, got %v", err)
+	}
+	if !errors.Is(changes[0].Err, ErrTopicAuthorizationFailed) {
+		t.Errorf("expected authorization failure for _reserved, got %v", changes[0].Err)
+	}
+	if changes[1].Err != nil {
+		t.Errorf("expected allowed to be created, got %v", changes[1].Err)
+	}
+}
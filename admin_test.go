@@ -2,8 +2,10 @@ package sarama
 
 import (
 	"errors"
+	"reflect"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestClusterAdmin(t *testing.T) {
@@ -136,6 +138,110 @@ func TestClusterAdminCreateTopicWithoutAuthorization(t *testing.T) {
 	}
 }
 
+func TestClusterAdminCreateTopics(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"ApiVersionsRequest":  NewMockApiVersionsResponse(t),
+		"CreateTopicsRequest": NewMockCreateTopicsResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V2_4_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	retention := "3600000"
+	results, err := admin.CreateTopics(map[string]*TopicDetail{
+		"my_topic": {
+			NumPartitions:     3,
+			ReplicationFactor: 1,
+			ConfigEntries:     map[string]*string{"retention.ms": &retention},
+		},
+		"_reserved": {NumPartitions: 1, ReplicationFactor: 1},
+	}, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	created := results["my_topic"]
+	if created == nil || created.Err != nil {
+		t.Fatalf("expected my_topic to pass validation, got %+v", created)
+	}
+	if created.Detail == nil || created.Detail.NumPartitions != 3 || len(created.Detail.Configs) != 1 {
+		t.Errorf("expected the created topic detail to be reported, got %+v", created.Detail)
+	}
+
+	var topicErr *TopicError
+	if rejected := results["_reserved"]; rejected == nil || !errors.As(rejected.Err, &topicErr) || topicErr.ErrMsg == nil {
+		t.Fatalf("expected _reserved to be rejected with a message, got %+v", rejected)
+	}
+	if !errors.Is(topicErr, ErrTopicAuthorizationFailed) {
+		t.Errorf("expected ErrTopicAuthorizationFailed, got %v", topicErr)
+	}
+
+	for _, rr := range seedBroker.History() {
+		if req, ok := rr.Request.(*CreateTopicsRequest); ok && (!req.ValidateOnly || req.Version != 5) {
+			t.Errorf("expected a validate only v5 request, got v%d validateOnly=%v", req.Version, req.ValidateOnly)
+		}
+	}
+}
+
+func TestClusterAdminCreateTopicsReportsConfigWarnings(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+		"CreateTopicsRequest": NewMockWrapper(&CreateTopicsResponse{
+			Version:     5,
+			TopicErrors: map[string]*TopicError{"my_topic": {Err: ErrNoError}},
+			TopicDetails: map[string]*CreatedTopicDetail{
+				"my_topic": {
+					NumPartitions:     1,
+					ReplicationFactor: 1,
+					Configs: []*CreatedTopicConfig{
+						{Name: "retention.ms", Value: nullString("604800000"), Source: SourceDefault},
+					},
+				},
+			},
+		}),
+	})
+
+	config := NewTestConfig()
+	config.Version = V2_4_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	retention := "-1"
+	results, err := admin.CreateTopics(map[string]*TopicDetail{
+		"my_topic": {
+			NumPartitions:     1,
+			ReplicationFactor: 1,
+			ConfigEntries:     map[string]*string{"retention.ms": &retention},
+		},
+	}, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if warnings := results["my_topic"].Warnings; len(warnings) != 1 {
+		t.Fatalf("expected a warning about retention.ms, got %v", warnings)
+	}
+}
+
 func TestClusterAdminListTopics(t *testing.T) {
 	seedBroker := NewMockBroker(t, 1)
 	defer seedBroker.Close()
@@ -458,6 +564,157 @@ func TestClusterAdminListPartitionReassignments(t *testing.T) {
 	}
 }
 
+func TestClusterAdminElectLeaders(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"ElectLeadersRequest": NewMockElectLeadersResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V2_4_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	results, err := admin.ElectLeaders(PreferredElection, map[string][]int32{"my_topic": {0, 1}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results["my_topic"]) != 2 {
+		t.Fatalf("expected results for 2 partitions, got %v", results)
+	}
+	for partition, result := range results["my_topic"] {
+		if !errors.Is(result.ErrorCode, ErrNoError) {
+			t.Errorf("unexpected error for partition %d: %v", partition, result.ErrorCode)
+		}
+	}
+}
+
+func TestClusterAdminElectLeadersUncleanRequiresVersion(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+	})
+
+	config := NewTestConfig()
+	config.Version = V2_2_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	var target ConfigurationError
+	if _, err := admin.ElectLeaders(UncleanElection, nil); !errors.As(err, &target) {
+		t.Fatalf("expected a ConfigurationError, got %v", err)
+	}
+}
+
+func TestClusterAdminDescribeTopicPartitions(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"DescribeTopicPartitionsRequest": NewMockDescribeTopicPartitionsResponse(t).
+			SetTopic("a", 1500, 1, 2).
+			SetTopic("b", 1500, 2, 1),
+	})
+
+	config := NewTestConfig()
+	config.Version = V3_8_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	topics, err := admin.DescribeTopicPartitions(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 2 || topics[0].Name != "a" || topics[1].Name != "b" {
+		t.Fatalf("expected topics a and b, got %v", topics)
+	}
+	for _, topic := range topics {
+		if len(topic.Partitions) != 1500 {
+			t.Fatalf("expected 1500 partitions for %s, got %d", topic.Name, len(topic.Partitions))
+		}
+		for i, partition := range topic.Partitions {
+			if partition.ID != int32(i) {
+				t.Fatalf("expected partition %d of %s, got %d", i, topic.Name, partition.ID)
+			}
+		}
+	}
+	if topics[1].Partitions[0].Leader != 2 {
+		t.Errorf("expected broker 2 to lead b-0, got %d", topics[1].Partitions[0].Leader)
+	}
+
+	pages := 0
+	for _, rr := range seedBroker.History() {
+		if _, ok := rr.Request.(*DescribeTopicPartitionsRequest); ok {
+			pages++
+		}
+	}
+	if pages != 2 {
+		t.Errorf("expected 2 pages, got %d", pages)
+	}
+
+	topics, err = admin.DescribeTopicPartitions([]string{"missing"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 1 || !errors.Is(topics[0].Err, ErrUnknownTopicOrPartition) {
+		t.Fatalf("expected ErrUnknownTopicOrPartition, got %v", topics)
+	}
+}
+
+func TestClusterAdminDescribeTopicPartitionsWithMetadata(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetLeader("my_topic", 0, seedBroker.BrokerID()),
+	})
+
+	config := NewTestConfig()
+	config.Version = V1_0_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	topics, err := admin.DescribeTopicPartitions([]string{"my_topic"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(topics) != 1 || topics[0].Name != "my_topic" || len(topics[0].Partitions) != 1 {
+		t.Fatalf("unexpected topics %v", topics)
+	}
+	if partition := topics[0].Partitions[0]; partition.Leader != seedBroker.BrokerID() || partition.LeaderEpoch != -1 {
+		t.Errorf("unexpected partition %+v", partition)
+	}
+}
+
 func TestClusterAdminListPartitionReassignmentsWithDiffVersion(t *testing.T) {
 	seedBroker := NewMockBroker(t, 1)
 	defer seedBroker.Close()
@@ -1118,6 +1375,45 @@ func TestClusterAdminCreateAclErrorHandling(t *testing.T) {
 	}
 }
 
+func TestClusterAdminCreateAcls(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"CreateAclsRequest": NewMockWrapper(&CreateAclsResponse{
+			AclCreationResponses: []*AclCreationResponse{
+				{Err: ErrNoError},
+				{Err: ErrSecurityDisabled},
+			},
+		}),
+	})
+
+	config := NewTestConfig()
+	config.Version = V1_0_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	err = admin.CreateACLs([]*ResourceAcls{{
+		Resource: Resource{ResourceType: AclResourceTopic, ResourceName: "my_topic"},
+		Acls: []*Acl{
+			{Principal: "User:alice", Host: "*", Operation: AclOperationRead, PermissionType: AclPermissionAllow},
+			{Principal: "User:bob", Host: "*", Operation: AclOperationRead, PermissionType: AclPermissionAllow},
+		},
+	}})
+	if !errors.Is(err, ErrCreateACLs) || !errors.Is(err, ErrSecurityDisabled) {
+		t.Fatalf("expected ErrCreateACLs wrapping ErrSecurityDisabled, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "User:bob") || strings.Contains(err.Error(), "User:alice") {
+		t.Errorf("expected only the ACL of bob to be reported, got %v", err)
+	}
+}
+
 func TestClusterAdminListAcls(t *testing.T) {
 	seedBroker := NewMockBroker(t, 1)
 	defer seedBroker.Close()
@@ -1365,6 +1661,83 @@ func TestListConsumerGroups(t *testing.T) {
 	}
 }
 
+func TestListConsumerGroupsWithOptions(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+		"ListGroupsRequest": NewMockListGroupsResponse(t).
+			AddGroupWithData("stable-group", "consumer", GroupData{GroupState: "Stable", GroupType: "classic"}).
+			AddGroupWithData("empty-group", "consumer", GroupData{GroupState: "Empty", GroupType: "consumer"}),
+	})
+
+	config := NewTestConfig()
+	config.Version = V3_8_0_0
+
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	groups, err := admin.ListConsumerGroupsWithOptions(ListConsumerGroupsOptions{States: []string{"Stable"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 1 {
+		t.Fatalf("Expected %v results, got %v", 1, len(groups))
+	}
+	expected := ConsumerGroupListing{GroupID: "stable-group", ProtocolType: "consumer", State: "Stable", Type: "classic"}
+	if groups["stable-group"] != expected {
+		t.Fatalf("Expected %+v, got %+v", expected, groups["stable-group"])
+	}
+
+	groups, err = admin.ListConsumerGroupsWithOptions(ListConsumerGroupsOptions{Types: []string{"consumer"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := groups["empty-group"]; !ok || len(groups) != 1 {
+		t.Fatalf("Expected only empty-group to be returned, got %v", groups)
+	}
+
+	for _, rr := range seedBroker.History() {
+		if req, ok := rr.Request.(*ListGroupsRequest); ok && req.Version != 5 {
+			t.Errorf("Expected ListGroupsRequest v5, got v%d", req.Version)
+		}
+	}
+}
+
+func TestListConsumerGroupsWithOptionsUnsupportedFilter(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V2_6_0_0
+
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	var configErr ConfigurationError
+	_, err = admin.ListConsumerGroupsWithOptions(ListConsumerGroupsOptions{Types: []string{"share"}})
+	if !errors.As(err, &configErr) {
+		t.Fatalf("Expected ConfigurationError, got %v", err)
+	}
+}
+
 func TestListConsumerGroupsMultiBroker(t *testing.T) {
 	seedBroker := NewMockBroker(t, 1)
 	defer seedBroker.Close()
@@ -1650,3 +2023,139 @@ func TestDescribeLogDirs(t *testing.T) {
 		t.Fatal(err)
 	}
 }
+
+func TestClusterAdminRetriesOnControllerMove(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	newController := NewMockBroker(t, 2)
+	defer seedBroker.Close()
+	defer newController.Close()
+
+	movedMetadata := NewMockMetadataResponse(t).
+		SetController(newController.BrokerID()).
+		SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+		SetBroker(newController.Addr(), newController.BrokerID())
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockSequence(
+			NewMockMetadataResponse(t).
+				SetController(seedBroker.BrokerID()).
+				SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+				SetBroker(newController.Addr(), newController.BrokerID()),
+			movedMetadata,
+		),
+		"DeleteTopicsRequest": NewMockWrapper(&DeleteTopicsResponse{
+			TopicErrorCodes: map[string]KError{"my_topic": ErrNotController},
+		}),
+	})
+	newController.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest":     movedMetadata,
+		"DeleteTopicsRequest": NewMockDeleteTopicsResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V0_10_2_0
+	config.Admin.Retry.Backoff = 0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	if err := admin.DeleteTopic("my_topic"); err != nil {
+		t.Fatal(err)
+	}
+
+	if b, _ := admin.Controller(); b.ID() != newController.BrokerID() {
+		t.Errorf("expected controller to be rediscovered as %d, got %d", newController.BrokerID(), b.ID())
+	}
+}
+
+// mockIgnoredResponse makes the MockBroker drop the request so that the
+// client times out waiting for a response.
+type mockIgnoredResponse struct{}
+
+func (mockIgnoredResponse) For(reqBody versionedDecoder) encoderWithHeader {
+	return nil
+}
+
+func TestClusterAdminCreateTopicRetryIsIdempotent(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+		"CreateTopicsRequest": NewMockSequence(
+			mockIgnoredResponse{},
+			&CreateTopicsResponse{
+				Version:     2,
+				TopicErrors: map[string]*TopicError{"my_topic": {Err: ErrTopicAlreadyExists}},
+			},
+		),
+	})
+
+	config := NewTestConfig()
+	config.Version = V1_0_0_0
+	config.Net.ReadTimeout = 100 * time.Millisecond
+	config.Admin.Retry.Backoff = 0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	if err := admin.CreateTopic("my_topic", &TopicDetail{NumPartitions: 1, ReplicationFactor: 1}, false); err != nil {
+		t.Fatalf("expected retried creation to succeed, got %v", err)
+	}
+
+	// without a lost response an existing topic is still reported
+	err = admin.CreateTopic("my_topic", &TopicDetail{NumPartitions: 1, ReplicationFactor: 1}, false)
+	if !errors.Is(err, ErrTopicAlreadyExists) {
+		t.Fatalf("expected ErrTopicAlreadyExists, got %v", err)
+	}
+}
+
+func TestClusterAdminListOffsets(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	staleLeader := NewMockBroker(t, 2)
+	defer staleLeader.Close()
+
+	metadata := func(leader *MockBroker) *MockMetadataResponse {
+		return NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetBroker(staleLeader.Addr(), staleLeader.BrokerID()).
+			SetLeader("my_topic", 0, leader.BrokerID()).
+			SetLeader("my_topic", 1, seedBroker.BrokerID())
+	}
+	notLeader := new(OffsetResponse)
+	notLeader.AddTopicPartition("my_topic", 0, -1)
+	notLeader.Blocks["my_topic"][0].Err = ErrNotLeaderForPartition
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockSequence(metadata(staleLeader), metadata(seedBroker)),
+		"OffsetRequest": NewMockOffsetResponse(t).
+			SetOffset("my_topic", 0, OffsetNewest, 10).
+			SetOffset("my_topic", 1, OffsetNewest, 20),
+	})
+	staleLeader.SetHandlerByMap(map[string]MockResponse{"OffsetRequest": NewMockWrapper(notLeader)})
+
+	config := NewTestConfig()
+	config.Version = V1_1_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	// every partition of the topic, the stale leader of partition 0 being
+	// replaced once the metadata is refreshed
+	offsets, err := admin.ListOffsets(map[string][]int32{"my_topic": nil}, OffsetNewest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := map[string]map[int32]int64{"my_topic": {0: 10, 1: 20}}; !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("expected the offsets %v, got %v", expected, offsets)
+	}
+}
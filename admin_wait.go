@@ -0,0 +1,161 @@
+package sarama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"time"
+)
+
+// ErrTopicsNotReady is returned by WaitForTopics when the context is done
+// before every topic became ready.
+var ErrTopicsNotReady = errors.New("kafka: topics did not become ready")
+
+const (
+	waitForTopicsMinBackoff = 50 * time.Millisecond
+	waitForTopicsMaxBackoff = time.Second
+)
+
+// WaitForTopics polls the cluster metadata until every partition of the given
+// topics has a leader and at least min.insync.replicas in-sync replicas, or
+// until ctx is done. Topics which do not exist yet are waited for as well,
+// which makes it suitable right after CreateTopics returned.
+//
+// When ctx is done first, the returned error wraps both ErrTopicsNotReady and
+// the context error, along with the reason each remaining topic is not ready.
+// Other errors, such as authorization failures, are returned immediately.
+func WaitForTopics(ctx context.Context, admin ClusterAdmin, topics ...string) error {
+	if len(topics) == 0 {
+		return nil
+	}
+	for _, topic := range topics {
+		if topic == "" {
+			return ErrInvalidTopic
+		}
+	}
+
+	admin = admin.WithContext(ctx)
+	minISR := make(map[string]int, len(topics))
+	backoff := waitForTopicsMinBackoff
+
+	var notReady map[string]string
+	for {
+		reasons, err := topicsNotReady(admin, topics, minISR)
+		if err != nil {
+			if ctx.Err() != nil {
+				return topicsNotReadyError(ctx, topics, notReady)
+			}
+			return err
+		}
+		if notReady = reasons; len(notReady) == 0 {
+			return nil
+		}
+
+		timer := time.NewTimer(backoff)
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			timer.Stop()
+			return topicsNotReadyError(ctx, topics, notReady)
+		}
+
+		if backoff *= 2; backoff > waitForTopicsMaxBackoff {
+			backoff = waitForTopicsMaxBackoff
+		}
+	}
+}
+
+func topicsNotReadyError(ctx context.Context, topics []string, notReady map[string]string) error {
+	errs := []error{ctx.Err()}
+	for _, topic := range topics {
+		if reason, ok := notReady[topic]; ok {
+			errs = append(errs, fmt.Errorf("[%s]: %s", topic, reason))
+		}
+	}
+	return Wrap(ErrTopicsNotReady, errs...)
+}
+
+// topicsNotReady returns why each of the topics that is not ready yet is not,
+// keyed by topic. minISR caches min.insync.replicas across calls.
+func topicsNotReady(admin ClusterAdmin, topics []string, minISR map[string]int) (map[string]string, error) {
+	metadata, err := admin.DescribeTopics(topics)
+	if err != nil {
+		return nil, err
+	}
+
+	described := make(map[string]*TopicMetadata, len(metadata))
+	for _, topic := range metadata {
+		described[topic.Name] = topic
+	}
+
+	notReady := make(map[string]string)
+	for _, topic := range topics {
+		tm, ok := described[topic]
+		if !ok {
+			notReady[topic] = "missing from metadata"
+			continue
+		}
+
+		switch {
+		case errors.Is(tm.Err, ErrNoError):
+		case errors.Is(tm.Err, ErrUnknownTopicOrPartition), errors.Is(tm.Err, ErrLeaderNotAvailable):
+			notReady[topic] = tm.Err.Error()
+			continue
+		default:
+			return nil, fmt.Errorf("describing topic %s: %w", topic, tm.Err)
+		}
+
+		if len(tm.Partitions) == 0 {
+			notReady[topic] = "no partitions"
+			continue
+		}
+
+		required, ok := minISR[topic]
+		if !ok {
+			if required, err = topicMinISR(admin, topic); err != nil {
+				return nil, err
+			}
+			minISR[topic] = required
+		}
+
+		for _, partition := range tm.Partitions {
+			if partition.Leader < 0 || errors.Is(partition.Err, ErrLeaderNotAvailable) {
+				notReady[topic] = fmt.Sprintf("partition %d has no leader", partition.ID)
+				break
+			}
+			if len(partition.Isr) < required {
+				notReady[topic] = fmt.Sprintf("partition %d has %d in-sync replicas, %d required",
+					partition.ID, len(partition.Isr), required)
+				break
+			}
+		}
+	}
+
+	return notReady, nil
+}
+
+// topicMinISR returns the effective min.insync.replicas of topic, which is 1
+// when the broker does not report it.
+func topicMinISR(admin ClusterAdmin, topic string) (int, error) {
+	entries, err := admin.DescribeConfig(ConfigResource{
+		Type:        TopicResource,
+		Name:        topic,
+		ConfigNames: []string{"min.insync.replicas"},
+	})
+	if err != nil {
+		return 0, fmt.Errorf("describing config of topic %s: %w", topic, err)
+	}
+
+	for _, entry := range entries {
+		if entry.Name != "min.insync.replicas" {
+			continue
+		}
+		required, err := strconv.Atoi(entry.Value)
+		if err != nil {
+			return 0, fmt.Errorf("invalid min.insync.replicas %q for topic %s: %w", entry.Value, topic, err)
+		}
+		return required, nil
+	}
+	return 1, nil
+}
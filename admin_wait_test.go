@@ -0,0 +1,83 @@
+package sarama
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestWaitForTopics(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	notCreated := NewMockMetadataResponse(t).
+		SetController(seedBroker.BrokerID()).
+		SetBroker(seedBroker.Addr(), seedBroker.BrokerID())
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockSequence(
+			notCreated,
+			notCreated,
+			NewMockMetadataResponse(t).
+				SetController(seedBroker.BrokerID()).
+				SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+				SetLeader("my_topic", 0, seedBroker.BrokerID()).
+				SetLeader("my_topic", 1, seedBroker.BrokerID()),
+		),
+		"DescribeConfigsRequest": NewMockDescribeConfigsResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V1_0_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	if err := WaitForTopics(ctx, admin, "my_topic"); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestWaitForTopicsBelowMinISR(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetLeader("my_topic", 0, seedBroker.BrokerID()),
+		"DescribeConfigsRequest": NewMockWrapper(&DescribeConfigsResponse{
+			Resources: []*ResourceResponse{{
+				Type:    TopicResource,
+				Name:    "my_topic",
+				Configs: []*ConfigEntry{{Name: "min.insync.replicas", Value: "2"}},
+			}},
+		}),
+	})
+
+	config := NewTestConfig()
+	config.Version = V1_0_0_0
+	admin, err := NewClusterAdmin([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, admin)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 200*time.Millisecond)
+	defer cancel()
+
+	err = WaitForTopics(ctx, admin, "my_topic")
+	if !errors.Is(err, ErrTopicsNotReady) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected ErrTopicsNotReady and context.DeadlineExceeded, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "1 in-sync replicas, 2 required") {
+		t.Errorf("expected the error to explain why my_topic is not ready, got %v", err)
+	}
+}
@@ -12,6 +12,7 @@ package sarama
 //   validate_only => BOOLEAN
 
 type AlterClientQuotasRequest struct {
+	Version      int16                    // Version 1 is the first flexible version
 	Entries      []AlterClientQuotasEntry // The quota configuration entries to alter.
 	ValidateOnly bool                     // Whether the alteration should be validated, but not performed.
 }
@@ -29,11 +30,13 @@ type ClientQuotasOp struct {
 
 func (a *AlterClientQuotasRequest) encode(pe packetEncoder) error {
 	// Entries
-	if err := pe.putArrayLength(len(a.Entries)); err != nil {
+	if a.Version >= 1 {
+		pe.putCompactArrayLength(len(a.Entries))
+	} else if err := pe.putArrayLength(len(a.Entries)); err != nil {
 		return err
 	}
 	for _, e := range a.Entries {
-		if err := e.encode(pe); err != nil {
+		if err := e.encode(pe, a.Version); err != nil {
 			return err
 		}
 	}
@@ -41,12 +44,23 @@ func (a *AlterClientQuotasRequest) encode(pe packetEncoder) error {
 	// ValidateOnly
 	pe.putBool(a.ValidateOnly)
 
+	if a.Version >= 1 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (a *AlterClientQuotasRequest) decode(pd packetDecoder, version int16) error {
+func (a *AlterClientQuotasRequest) decode(pd packetDecoder, version int16) (err error) {
+	a.Version = version
+
 	// Entries
-	entryCount, err := pd.getArrayLength()
+	var entryCount int
+	if version >= 1 {
+		entryCount, err = pd.getCompactArrayLength()
+	} else {
+		entryCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -70,36 +84,55 @@ func (a *AlterClientQuotasRequest) decode(pd packetDecoder, version int16) error
 	}
 	a.ValidateOnly = validateOnly
 
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (a *AlterClientQuotasEntry) encode(pe packetEncoder) error {
+func (a *AlterClientQuotasEntry) encode(pe packetEncoder, version int16) error {
 	// Entity
-	if err := pe.putArrayLength(len(a.Entity)); err != nil {
+	if version >= 1 {
+		pe.putCompactArrayLength(len(a.Entity))
+	} else if err := pe.putArrayLength(len(a.Entity)); err != nil {
 		return err
 	}
 	for _, component := range a.Entity {
-		if err := component.encode(pe); err != nil {
+		if err := component.encode(pe, version); err != nil {
 			return err
 		}
 	}
 
 	// Ops
-	if err := pe.putArrayLength(len(a.Ops)); err != nil {
+	if version >= 1 {
+		pe.putCompactArrayLength(len(a.Ops))
+	} else if err := pe.putArrayLength(len(a.Ops)); err != nil {
 		return err
 	}
 	for _, o := range a.Ops {
-		if err := o.encode(pe); err != nil {
+		if err := o.encode(pe, version); err != nil {
 			return err
 		}
 	}
 
+	if version >= 1 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (a *AlterClientQuotasEntry) decode(pd packetDecoder, version int16) error {
+func (a *AlterClientQuotasEntry) decode(pd packetDecoder, version int16) (err error) {
 	// Entity
-	componentCount, err := pd.getArrayLength()
+	var componentCount int
+	if version >= 1 {
+		componentCount, err = pd.getCompactArrayLength()
+	} else {
+		componentCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -117,7 +150,12 @@ func (a *AlterClientQuotasEntry) decode(pd packetDecoder, version int16) error {
 	}
 
 	// Ops
-	opCount, err := pd.getArrayLength()
+	var opCount int
+	if version >= 1 {
+		opCount, err = pd.getCompactArrayLength()
+	} else {
+		opCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -134,12 +172,22 @@ func (a *AlterClientQuotasEntry) decode(pd packetDecoder, version int16) error {
 		a.Ops = []ClientQuotasOp{}
 	}
 
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (c *ClientQuotasOp) encode(pe packetEncoder) error {
+func (c *ClientQuotasOp) encode(pe packetEncoder, version int16) error {
 	// Key
-	if err := pe.putString(c.Key); err != nil {
+	if version >= 1 {
+		if err := pe.putCompactString(c.Key); err != nil {
+			return err
+		}
+	} else if err := pe.putString(c.Key); err != nil {
 		return err
 	}
 
@@ -149,12 +197,21 @@ func (c *ClientQuotasOp) encode(pe packetEncoder) error {
 	// Remove
 	pe.putBool(c.Remove)
 
+	if version >= 1 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (c *ClientQuotasOp) decode(pd packetDecoder, version int16) error {
+func (c *ClientQuotasOp) decode(pd packetDecoder, version int16) (err error) {
 	// Key
-	key, err := pd.getString()
+	var key string
+	if version >= 1 {
+		key, err = pd.getCompactString()
+	} else {
+		key, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
@@ -174,6 +231,12 @@ func (c *ClientQuotasOp) decode(pd packetDecoder, version int16) error {
 	}
 	c.Remove = remove
 
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -182,13 +245,21 @@ func (a *AlterClientQuotasRequest) key() int16 {
 }
 
 func (a *AlterClientQuotasRequest) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *AlterClientQuotasRequest) headerVersion() int16 {
+	if a.Version >= 1 {
+		return 2
+	}
 	return 1
 }
 
 func (a *AlterClientQuotasRequest) requiredVersion() KafkaVersion {
-	return V2_6_0_0
+	switch a.Version {
+	case 1:
+		return V2_8_0_0
+	default:
+		return V2_6_0_0
+	}
 }
@@ -60,6 +60,22 @@ var (
 		0, // remove
 		0, // validate only
 	}
+
+	alterClientQuotasRequestSingleOpV1 = []byte{
+		2,                     // entries len (compact)
+		2,                     // entity len (compact)
+		5, 'u', 's', 'e', 'r', // entity type
+		0,                                                                                            // entity value
+		0,                                                                                            // empty entity tagged fields
+		2,                                                                                            // ops len (compact)
+		19, 'p', 'r', 'o', 'd', 'u', 'c', 'e', 'r', '_', 'b', 'y', 't', 'e', '_', 'r', 'a', 't', 'e', // op key
+		65, 46, 132, 128, 0, 0, 0, 0, // op value (1000000)
+		0, // remove
+		0, // empty op tagged fields
+		0, // empty entry tagged fields
+		0, // validate only
+		0, // empty tagged fields
+	}
 )
 
 func TestAlterClientQuotasRequest(t *testing.T) {
@@ -91,6 +107,9 @@ func TestAlterClientQuotasRequest(t *testing.T) {
 	}
 	testRequest(t, "Add single Quota op", req, alterClientQuotasRequestSingleOp)
 
+	req.Version = 1
+	testRequest(t, "Add single Quota op v1", req, alterClientQuotasRequestSingleOpV1)
+
 	// Remove Quota from default user
 	op = ClientQuotasOp{
 		Key:    "producer_byte_rate",
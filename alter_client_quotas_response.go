@@ -14,6 +14,7 @@ import (
 //       entity_name => NULLABLE_STRING
 
 type AlterClientQuotasResponse struct {
+	Version      int16                            // Version 1 is the first flexible version
 	ThrottleTime time.Duration                    // The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
 	Entries      []AlterClientQuotasEntryResponse // The quota configuration entries altered.
 }
@@ -29,19 +30,27 @@ func (a *AlterClientQuotasResponse) encode(pe packetEncoder) error {
 	pe.putInt32(int32(a.ThrottleTime / time.Millisecond))
 
 	// Entries
-	if err := pe.putArrayLength(len(a.Entries)); err != nil {
+	if a.Version >= 1 {
+		pe.putCompactArrayLength(len(a.Entries))
+	} else if err := pe.putArrayLength(len(a.Entries)); err != nil {
 		return err
 	}
 	for _, e := range a.Entries {
-		if err := e.encode(pe); err != nil {
+		if err := e.encode(pe, a.Version); err != nil {
 			return err
 		}
 	}
 
+	if a.Version >= 1 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (a *AlterClientQuotasResponse) decode(pd packetDecoder, version int16) error {
+	a.Version = version
+
 	// ThrottleTime
 	throttleTime, err := pd.getInt32()
 	if err != nil {
@@ -50,7 +59,12 @@ func (a *AlterClientQuotasResponse) decode(pd packetDecoder, version int16) erro
 	a.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
 	// Entries
-	entryCount, err := pd.getArrayLength()
+	var entryCount int
+	if version >= 1 {
+		entryCount, err = pd.getCompactArrayLength()
+	} else {
+		entryCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -67,32 +81,48 @@ func (a *AlterClientQuotasResponse) decode(pd packetDecoder, version int16) erro
 		a.Entries = []AlterClientQuotasEntryResponse{}
 	}
 
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (a *AlterClientQuotasEntryResponse) encode(pe packetEncoder) error {
+func (a *AlterClientQuotasEntryResponse) encode(pe packetEncoder, version int16) error {
 	// ErrorCode
 	pe.putInt16(int16(a.ErrorCode))
 
 	// ErrorMsg
-	if err := pe.putNullableString(a.ErrorMsg); err != nil {
+	if version >= 1 {
+		if err := pe.putNullableCompactString(a.ErrorMsg); err != nil {
+			return err
+		}
+	} else if err := pe.putNullableString(a.ErrorMsg); err != nil {
 		return err
 	}
 
 	// Entity
-	if err := pe.putArrayLength(len(a.Entity)); err != nil {
+	if version >= 1 {
+		pe.putCompactArrayLength(len(a.Entity))
+	} else if err := pe.putArrayLength(len(a.Entity)); err != nil {
 		return err
 	}
 	for _, component := range a.Entity {
-		if err := component.encode(pe); err != nil {
+		if err := component.encode(pe, version); err != nil {
 			return err
 		}
 	}
 
+	if version >= 1 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (a *AlterClientQuotasEntryResponse) decode(pd packetDecoder, version int16) error {
+func (a *AlterClientQuotasEntryResponse) decode(pd packetDecoder, version int16) (err error) {
 	// ErrorCode
 	errCode, err := pd.getInt16()
 	if err != nil {
@@ -101,14 +131,22 @@ func (a *AlterClientQuotasEntryResponse) decode(pd packetDecoder, version int16)
 	a.ErrorCode = KError(errCode)
 
 	// ErrorMsg
-	errMsg, err := pd.getNullableString()
+	if version >= 1 {
+		a.ErrorMsg, err = pd.getCompactNullableString()
+	} else {
+		a.ErrorMsg, err = pd.getNullableString()
+	}
 	if err != nil {
 		return err
 	}
-	a.ErrorMsg = errMsg
 
 	// Entity
-	componentCount, err := pd.getArrayLength()
+	var componentCount int
+	if version >= 1 {
+		componentCount, err = pd.getCompactArrayLength()
+	} else {
+		componentCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -125,6 +163,12 @@ func (a *AlterClientQuotasEntryResponse) decode(pd packetDecoder, version int16)
 		a.Entity = []QuotaEntityComponent{}
 	}
 
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -133,13 +177,29 @@ func (a *AlterClientQuotasResponse) key() int16 {
 }
 
 func (a *AlterClientQuotasResponse) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *AlterClientQuotasResponse) headerVersion() int16 {
+	if a.Version >= 1 {
+		return 1
+	}
 	return 0
 }
 
 func (a *AlterClientQuotasResponse) requiredVersion() KafkaVersion {
-	return V2_6_0_0
+	switch a.Version {
+	case 1:
+		return V2_8_0_0
+	default:
+		return V2_6_0_0
+	}
+}
+
+func (a *AlterClientQuotasResponse) throttleTime() time.Duration {
+	return a.ThrottleTime
+}
+
+func (a *AlterClientQuotasResponse) shouldClientThrottle(version int16) bool {
+	return true
 }
@@ -37,6 +37,19 @@ var (
 		0, 9, 'c', 'l', 'i', 'e', 'n', 't', '-', 'i', 'd', // entityType
 		255, 255, // entityName
 	}
+
+	alterClientQuotasResponseSingleEntryV1 = []byte{
+		0, 0, 0, 0, // ThrottleTime
+		2,    // Entries len (compact)
+		0, 0, // ErrorCode
+		0,                     // ErrorMsg
+		2,                     // Entity len (compact)
+		5, 'u', 's', 'e', 'r', // entityType
+		0, // entityName
+		0, // empty entity tagged fields
+		0, // empty entry tagged fields
+		0, // empty tagged fields
+	}
 )
 
 func TestAlterClientQuotasResponse(t *testing.T) {
@@ -79,6 +92,9 @@ func TestAlterClientQuotasResponse(t *testing.T) {
 	}
 	testResponse(t, "Altered single entry", res, alterClientQuotasResponseSingleEntry)
 
+	res.Version = 1
+	testResponse(t, "Altered single entry v1", res, alterClientQuotasResponseSingleEntryV1)
+
 	// Response Altered multiple entries
 	entry1 := AlterClientQuotasEntryResponse{
 		Entity: []QuotaEntityComponent{defaultUserComponent},
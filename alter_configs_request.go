@@ -2,6 +2,7 @@ package sarama
 
 // AlterConfigsRequest is an alter config request type
 type AlterConfigsRequest struct {
+	Version      int16
 	Resources    []*AlterConfigsResource
 	ValidateOnly bool
 }
@@ -14,22 +15,35 @@ type AlterConfigsResource struct {
 }
 
 func (a *AlterConfigsRequest) encode(pe packetEncoder) error {
-	if err := pe.putArrayLength(len(a.Resources)); err != nil {
+	if a.Version >= 2 {
+		pe.putCompactArrayLength(len(a.Resources))
+	} else if err := pe.putArrayLength(len(a.Resources)); err != nil {
 		return err
 	}
 
 	for _, r := range a.Resources {
-		if err := r.encode(pe); err != nil {
+		if err := r.encode(pe, a.Version); err != nil {
 			return err
 		}
 	}
 
 	pe.putBool(a.ValidateOnly)
+
+	if a.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
-func (a *AlterConfigsRequest) decode(pd packetDecoder, version int16) error {
-	resourceCount, err := pd.getArrayLength()
+func (a *AlterConfigsRequest) decode(pd packetDecoder, version int16) (err error) {
+	a.Version = version
+
+	var resourceCount int
+	if version >= 2 {
+		resourceCount, err = pd.getCompactArrayLength()
+	} else {
+		resourceCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -51,45 +65,85 @@ func (a *AlterConfigsRequest) decode(pd packetDecoder, version int16) error {
 
 	a.ValidateOnly = validateOnly
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (a *AlterConfigsResource) encode(pe packetEncoder) error {
+func (a *AlterConfigsResource) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt8(int8(a.Type))
 
-	if err := pe.putString(a.Name); err != nil {
+	if version >= 2 {
+		err = pe.putCompactString(a.Name)
+	} else {
+		err = pe.putString(a.Name)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := pe.putArrayLength(len(a.ConfigEntries)); err != nil {
+	if version >= 2 {
+		pe.putCompactArrayLength(len(a.ConfigEntries))
+	} else if err = pe.putArrayLength(len(a.ConfigEntries)); err != nil {
 		return err
 	}
 	for configKey, configValue := range a.ConfigEntries {
-		if err := pe.putString(configKey); err != nil {
+		if version >= 2 {
+			err = pe.putCompactString(configKey)
+		} else {
+			err = pe.putString(configKey)
+		}
+		if err != nil {
 			return err
 		}
-		if err := pe.putNullableString(configValue); err != nil {
+		if version >= 2 {
+			err = pe.putNullableCompactString(configValue)
+		} else {
+			err = pe.putNullableString(configValue)
+		}
+		if err != nil {
 			return err
 		}
+		if version >= 2 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
 }
 
-func (a *AlterConfigsResource) decode(pd packetDecoder, version int16) error {
+func (a *AlterConfigsResource) decode(pd packetDecoder, version int16) (err error) {
 	t, err := pd.getInt8()
 	if err != nil {
 		return err
 	}
 	a.Type = ConfigResourceType(t)
 
-	name, err := pd.getString()
+	var name string
+	if version >= 2 {
+		name, err = pd.getCompactString()
+	} else {
+		name, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
 	a.Name = name
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -97,13 +151,34 @@ func (a *AlterConfigsResource) decode(pd packetDecoder, version int16) error {
 	if n > 0 {
 		a.ConfigEntries = make(map[string]*string, n)
 		for i := 0; i < n; i++ {
-			configKey, err := pd.getString()
+			var configKey string
+			if version >= 2 {
+				configKey, err = pd.getCompactString()
+			} else {
+				configKey, err = pd.getString()
+			}
 			if err != nil {
 				return err
 			}
-			if a.ConfigEntries[configKey], err = pd.getNullableString(); err != nil {
+			if version >= 2 {
+				a.ConfigEntries[configKey], err = pd.getCompactNullableString()
+			} else {
+				a.ConfigEntries[configKey], err = pd.getNullableString()
+			}
+			if err != nil {
 				return err
 			}
+			if version >= 2 {
+				if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
 		}
 	}
 	return err
@@ -114,13 +189,23 @@ func (a *AlterConfigsRequest) key() int16 {
 }
 
 func (a *AlterConfigsRequest) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *AlterConfigsRequest) headerVersion() int16 {
+	if a.Version >= 2 {
+		return 2
+	}
 	return 1
 }
 
 func (a *AlterConfigsRequest) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch a.Version {
+	case 2:
+		return V2_5_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
 }
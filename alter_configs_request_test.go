@@ -84,3 +84,37 @@ func TestAlterConfigsRequest(t *testing.T) {
 
 	testRequest(t, "two configs", request, doubleAlterConfigsRequest)
 }
+
+var singleAlterConfigsRequestV2 = []byte{
+	2,                // 1 config
+	2,                // a topic
+	4, 'f', 'o', 'o', // topic name: foo
+	2,  // 1 config name
+	11, // 10 chars
+	's', 'e', 'g', 'm', 'e', 'n', 't', '.', 'm', 's',
+	5,
+	'1', '0', '0', '0',
+	0, // empty tagged fields
+	0, // empty tagged fields
+	1, // validate
+	0, // empty tagged fields
+}
+
+func TestAlterConfigsRequestV2(t *testing.T) {
+	configValue := "1000"
+	request := &AlterConfigsRequest{
+		Version: 2,
+		Resources: []*AlterConfigsResource{
+			{
+				Type: TopicResource,
+				Name: "foo",
+				ConfigEntries: map[string]*string{
+					"segment.ms": &configValue,
+				},
+			},
+		},
+		ValidateOnly: true,
+	}
+
+	testRequest(t, "one config v2", request, singleAlterConfigsRequestV2)
+}
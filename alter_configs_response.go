@@ -4,6 +4,7 @@ import "time"
 
 // AlterConfigsResponse is a response type for alter config
 type AlterConfigsResponse struct {
+	Version      int16
 	ThrottleTime time.Duration
 	Resources    []*AlterConfigsResourceResponse
 }
@@ -19,27 +20,40 @@ type AlterConfigsResourceResponse struct {
 func (a *AlterConfigsResponse) encode(pe packetEncoder) error {
 	pe.putInt32(int32(a.ThrottleTime / time.Millisecond))
 
-	if err := pe.putArrayLength(len(a.Resources)); err != nil {
+	if a.Version >= 2 {
+		pe.putCompactArrayLength(len(a.Resources))
+	} else if err := pe.putArrayLength(len(a.Resources)); err != nil {
 		return err
 	}
 
 	for _, v := range a.Resources {
-		if err := v.encode(pe); err != nil {
+		if err := v.encode(pe, a.Version); err != nil {
 			return err
 		}
 	}
 
+	if a.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (a *AlterConfigsResponse) decode(pd packetDecoder, version int16) error {
+func (a *AlterConfigsResponse) decode(pd packetDecoder, version int16) (err error) {
+	a.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
 	}
 	a.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
-	responseCount, err := pd.getArrayLength()
+	var responseCount int
+	if version >= 2 {
+		responseCount, err = pd.getCompactArrayLength()
+	} else {
+		responseCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -54,35 +68,62 @@ func (a *AlterConfigsResponse) decode(pd packetDecoder, version int16) error {
 		}
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (a *AlterConfigsResourceResponse) encode(pe packetEncoder) error {
+func (a *AlterConfigsResourceResponse) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt16(a.ErrorCode)
-	err := pe.putString(a.ErrorMsg)
+	if version >= 2 {
+		err = pe.putCompactString(a.ErrorMsg)
+	} else {
+		err = pe.putString(a.ErrorMsg)
+	}
 	if err != nil {
 		return err
 	}
 	pe.putInt8(int8(a.Type))
-	err = pe.putString(a.Name)
+	if version >= 2 {
+		err = pe.putCompactString(a.Name)
+	} else {
+		err = pe.putString(a.Name)
+	}
 	if err != nil {
 		return err
 	}
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
-func (a *AlterConfigsResourceResponse) decode(pd packetDecoder, version int16) error {
+func (a *AlterConfigsResourceResponse) decode(pd packetDecoder, version int16) (err error) {
 	errCode, err := pd.getInt16()
 	if err != nil {
 		return err
 	}
 	a.ErrorCode = errCode
 
-	e, err := pd.getString()
-	if err != nil {
-		return err
+	if version >= 2 {
+		e, err := pd.getCompactNullableString()
+		if err != nil {
+			return err
+		}
+		if e != nil {
+			a.ErrorMsg = *e
+		}
+	} else {
+		e, err := pd.getString()
+		if err != nil {
+			return err
+		}
+		a.ErrorMsg = e
 	}
-	a.ErrorMsg = e
 
 	t, err := pd.getInt8()
 	if err != nil {
@@ -90,27 +131,56 @@ func (a *AlterConfigsResourceResponse) decode(pd packetDecoder, version int16) e
 	}
 	a.Type = ConfigResourceType(t)
 
-	name, err := pd.getString()
+	var name string
+	if version >= 2 {
+		name, err = pd.getCompactString()
+	} else {
+		name, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
 	a.Name = name
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (a *AlterConfigsResponse) key() int16 {
-	return 32
+	return 33
 }
 
 func (a *AlterConfigsResponse) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *AlterConfigsResponse) headerVersion() int16 {
+	if a.Version >= 2 {
+		return 1
+	}
 	return 0
 }
 
 func (a *AlterConfigsResponse) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch a.Version {
+	case 2:
+		return V2_5_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
+}
+
+func (a *AlterConfigsResponse) throttleTime() time.Duration {
+	return a.ThrottleTime
+}
+
+func (a *AlterConfigsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
 }
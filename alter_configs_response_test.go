@@ -18,6 +18,17 @@ var (
 		2, // topic
 		0, 3, 'f', 'o', 'o',
 	}
+
+	alterResponsePopulatedV2 = []byte{
+		0, 0, 0, 0, // throttle
+		2,    // response
+		0, 0, // errorcode
+		1, // string
+		2, // topic
+		4, 'f', 'o', 'o',
+		0, // empty tagged fields
+		0, // empty tagged fields
+	}
 )
 
 func TestAlterConfigsResponse(t *testing.T) {
@@ -42,4 +53,7 @@ func TestAlterConfigsResponse(t *testing.T) {
 		},
 	}
 	testResponse(t, "response with error", response, alterResponsePopulated)
+
+	response.Version = 2
+	testResponse(t, "response with error v2", response, alterResponsePopulatedV2)
 }
@@ -1,10 +1,13 @@
 package sarama
 
 import (
+	"context"
 	"encoding/binary"
 	"errors"
 	"fmt"
+	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/eapache/go-resiliency/breaker"
@@ -32,6 +35,13 @@ type AsyncProducer interface {
 	// Close on the underlying client.
 	Close() error
 
+	// CloseContext shuts down the producer like Close, waiting for the buffered
+	// messages to be flushed until ctx is done. The connections of the
+	// producer are then torn down, unless it was created from a client, and a
+	// *CloseAbandonedError reports the messages which were abandoned along with
+	// the errors returned until then.
+	CloseContext(ctx context.Context) error
+
 	// Input is the input channel for the user to write messages to that they
 	// wish to send.
 	Input() chan<- *ProducerMessage
@@ -47,6 +57,12 @@ type AsyncProducer interface {
 	// you can set Producer.Return.Errors in your config to false, which prevents
 	// errors to be returned.
 	Errors() <-chan *ProducerError
+
+	// DebugState returns a snapshot of the state of the producer: the messages
+	// queued by partition and buffered by broker, the partitions retrying
+	// messages, and the state of its client. It is meant to diagnose a stuck
+	// producer in production.
+	DebugState() ProducerDebugState
 }
 
 // transactionManager keeps the state necessary to ensure idempotent production
@@ -102,7 +118,7 @@ func newTransactionManager(conf *Config, client Client) (*transactionManager, er
 		txnmgr.sequenceNumbers = make(map[string]int32)
 		txnmgr.mutex = sync.Mutex{}
 
-		Logger.Printf("Obtained a ProducerId: %d and ProducerEpoch: %d\n", txnmgr.producerID, txnmgr.producerEpoch)
+		infof("Obtained a ProducerId: %d and ProducerEpoch: %d\n", txnmgr.producerID, txnmgr.producerEpoch)
 	}
 
 	return txnmgr, nil
@@ -115,12 +131,20 @@ type asyncProducer struct {
 	errors                    chan *ProducerError
 	input, successes, retries chan *ProducerMessage
 	inFlight                  sync.WaitGroup
+	// pendingMessages is the number of messages neither delivered nor failed
+	pendingMessages int32
 
 	brokers    map[*Broker]*brokerProducer
 	brokerRefs map[*brokerProducer]int
 	brokerLock sync.Mutex
 
+	// partitionProducers are the running partitionProducers, for DebugState
+	partitionProducers map[*partitionProducer]none
+	partitionLock      sync.Mutex
+
 	txnmgr *transactionManager
+
+	compressionRatios *compressionEstimator
 }
 
 // NewAsyncProducer creates a new AsyncProducer using the given broker addresses and configuration.
@@ -133,12 +157,18 @@ func NewAsyncProducer(addrs []string, conf *Config) (AsyncProducer, error) {
 }
 
 // NewAsyncProducerFromClient creates a new Producer using the given client. It is still
-// necessary to call Close() on the underlying client when shutting down this producer.
+// necessary to call Close() on the underlying client when shutting down this producer,
+// unless it was shared with ShareClient.
 func NewAsyncProducerFromClient(client Client) (AsyncProducer, error) {
-	// For clients passed in by the client, ensure we don't
-	// call Close() on it.
-	cli := &nopCloserClient{client}
-	return newAsyncProducer(cli)
+	cli, err := borrowClient(client)
+	if err != nil {
+		return nil, err
+	}
+	p, err := newAsyncProducer(cli)
+	if err != nil {
+		_ = cli.Close()
+	}
+	return p, err
 }
 
 func newAsyncProducer(client Client) (AsyncProducer, error) {
@@ -162,6 +192,10 @@ func newAsyncProducer(client Client) (AsyncProducer, error) {
 		brokers:    make(map[*Broker]*brokerProducer),
 		brokerRefs: make(map[*brokerProducer]int),
 		txnmgr:     txnmgr,
+
+		partitionProducers: make(map[*partitionProducer]none),
+
+		compressionRatios: newCompressionEstimator(),
 	}
 
 	// launch our singleton dispatchers
@@ -250,6 +284,19 @@ func (m *ProducerMessage) byteSize(version int) int {
 	return size
 }
 
+// tooLarge returns whether msg can never be sent, as it does not fit alone in
+// a produce request, or in a batch while the batches are not compressed.
+func (p *asyncProducer) tooLarge(msg *ProducerMessage, version int) bool {
+	size := msg.byteSize(version)
+	if version >= 2 {
+		size += recordBatchOverhead
+	}
+	if size > p.conf.maxRequestBytes() {
+		return true
+	}
+	return p.conf.Producer.Compression == CompressionNone && size > p.conf.Producer.MaxMessageBytes
+}
+
 func (m *ProducerMessage) clear() {
 	m.flags = 0
 	m.retries = 0
@@ -294,6 +341,46 @@ func (p *asyncProducer) Input() chan<- *ProducerMessage {
 	return p.input
 }
 
+func (p *asyncProducer) DebugState() ProducerDebugState {
+	state := ProducerDebugState{
+		Pending: int(atomic.LoadInt32(&p.pendingMessages)),
+		Client:  p.client.DebugState(),
+	}
+
+	p.partitionLock.Lock()
+	for pp := range p.partitionProducers {
+		state.Partitions = append(state.Partitions, ProducerPartitionState{
+			Topic:         pp.topic,
+			Partition:     pp.partition,
+			Leader:        atomic.LoadInt32(&pp.leaderID),
+			Queued:        pp.queue.len(),
+			RetryLevel:    int(atomic.LoadInt32(&pp.retryLevel)),
+			RetryBuffered: int(atomic.LoadInt32(&pp.retryBuffered)),
+		})
+	}
+	p.partitionLock.Unlock()
+	sort.Slice(state.Partitions, func(i, j int) bool {
+		a, b := state.Partitions[i], state.Partitions[j]
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Partition < b.Partition
+	})
+
+	p.brokerLock.Lock()
+	for bp, refs := range p.brokerRefs {
+		state.Brokers = append(state.Brokers, ProducerBrokerState{
+			Broker:        bp.broker.ID(),
+			Partitions:    refs,
+			Buffered:      int(atomic.LoadInt32(&bp.buffered)),
+			BufferedBytes: int(atomic.LoadInt32(&bp.bufferedBytes)),
+		})
+	}
+	p.brokerLock.Unlock()
+	sort.Slice(state.Brokers, func(i, j int) bool { return state.Brokers[i].Broker < state.Brokers[j].Broker })
+	return state
+}
+
 func (p *asyncProducer) Close() error {
 	p.AsyncClose()
 
@@ -319,6 +406,54 @@ func (p *asyncProducer) Close() error {
 	return nil
 }
 
+func (p *asyncProducer) CloseContext(ctx context.Context) error {
+	p.AsyncClose()
+
+	if p.conf.Producer.Return.Successes {
+		go withRecover(func() {
+			for range p.successes {
+			}
+		})
+	}
+
+	var errors ProducerErrors
+	for {
+		select {
+		case event, ok := <-p.errors:
+			if !ok {
+				if len(errors) > 0 {
+					return errors
+				}
+				return nil
+			}
+			errors = append(errors, event)
+		case <-ctx.Done():
+			// keep draining the errors so that the shutdown can complete
+			go withRecover(func() {
+				for range p.errors {
+				}
+			})
+			abandoned := p.abandon(ctx.Err())
+			for _, event := range errors {
+				abandoned.Errors = append(abandoned.Errors, event)
+			}
+			return abandoned
+		}
+	}
+}
+
+// abandon tears down the connections of the producer while it is shutting
+// down, failing the messages still in flight.
+func (p *asyncProducer) abandon(err error) *CloseAbandonedError {
+	abandoned := &CloseAbandonedError{Err: err, Messages: int(atomic.LoadInt32(&p.pendingMessages))}
+	infof("producer/shutdown abandoning %d messages in flight\n", abandoned.Messages)
+
+	done, cancel := context.WithCancel(context.Background())
+	cancel()
+	abandoned.merge(p.client.CloseContext(done))
+	return abandoned
+}
+
 func (p *asyncProducer) AsyncClose() {
 	go withRecover(p.shutdown)
 }
@@ -331,7 +466,7 @@ func (p *asyncProducer) dispatcher() {
 
 	for msg := range p.input {
 		if msg == nil {
-			Logger.Println("Something tried to send a nil message, it was ignored.")
+			warnf("Something tried to send a nil message, it was ignored.")
 			continue
 		}
 
@@ -347,11 +482,12 @@ func (p *asyncProducer) dispatcher() {
 				if p.conf.Producer.Return.Errors {
 					p.errors <- pErr
 				} else {
-					Logger.Println(pErr)
+					logWith(topicField(msg.Topic)).warnf("%v", pErr)
 				}
 				continue
 			}
 			p.inFlight.Add(1)
+			atomic.AddInt32(&p.pendingMessages, 1)
 		}
 
 		for _, interceptor := range p.conf.Producer.Interceptors {
@@ -365,7 +501,7 @@ func (p *asyncProducer) dispatcher() {
 			p.returnError(msg, ConfigurationError("Producing headers requires Kafka at least v0.11"))
 			continue
 		}
-		if msg.byteSize(version) > p.conf.Producer.MaxMessageBytes {
+		if p.tooLarge(msg, version) {
 			p.returnError(msg, ErrMessageSizeTooLarge)
 			continue
 		}
@@ -385,14 +521,14 @@ func (p *asyncProducer) dispatcher() {
 }
 
 // one per topic
-// partitions messages, then dispatches them by partition
+// partitions messages, then queues them by partition
 type topicProducer struct {
 	parent *asyncProducer
 	topic  string
 	input  <-chan *ProducerMessage
 
 	breaker     *breaker.Breaker
-	handlers    map[int32]chan<- *ProducerMessage
+	partitions  map[int32]*partitionProducer
 	partitioner Partitioner
 }
 
@@ -403,7 +539,7 @@ func (p *asyncProducer) newTopicProducer(topic string) chan<- *ProducerMessage {
 		topic:       topic,
 		input:       input,
 		breaker:     breaker.New(3, 1, 10*time.Second),
-		handlers:    make(map[int32]chan<- *ProducerMessage),
+		partitions:  make(map[int32]*partitionProducer),
 		partitioner: p.conf.Producer.Partitioner(topic),
 	}
 	go withRecover(tp.dispatch)
@@ -419,17 +555,23 @@ func (tp *topicProducer) dispatch() {
 			}
 		}
 
-		handler := tp.handlers[msg.Partition]
-		if handler == nil {
-			handler = tp.parent.newPartitionProducer(msg.Topic, msg.Partition)
-			tp.handlers[msg.Partition] = handler
+		pp := tp.partitions[msg.Partition]
+		if pp == nil {
+			pp = tp.parent.newPartitionProducer(msg.Topic, msg.Partition)
+			tp.partitions[msg.Partition] = pp
 		}
 
-		handler <- msg
+		if pp.queue.push(msg) {
+			go withRecover(pp.run)
+		}
 	}
 
-	for _, handler := range tp.handlers {
-		close(handler)
+	// close the queues of the partitions, their producers stopping once they
+	// are drained
+	for _, pp := range tp.partitions {
+		if pp.queue.close() {
+			go withRecover(pp.run)
+		}
 	}
 }
 
@@ -474,14 +616,17 @@ func (tp *topicProducer) partitionMessage(msg *ProducerMessage) error {
 	return nil
 }
 
-// one per partition per topic
+// one per partition per topic, running only while its queue holds messages
 // dispatches messages to the appropriate broker
 // also responsible for maintaining message order during retries
 type partitionProducer struct {
 	parent    *asyncProducer
 	topic     string
 	partition int32
-	input     <-chan *ProducerMessage
+	queue     *partitionQueue
+
+	// started is set once the leader is prefetched by the first run
+	started bool
 
 	leader         *Broker
 	breaker        *breaker.Breaker
@@ -493,6 +638,15 @@ type partitionProducer struct {
 	// therefore whether our buffer is complete and safe to flush)
 	highWatermark int
 	retryState    []partitionRetryState
+
+	// leaderID, retryLevel and retryBuffered publish the state of the
+	// partition for DebugState, as the fields above belong to dispatch
+	leaderID, retryLevel, retryBuffered int32
+}
+
+// log returns the logContext of the records about the partition.
+func (pp *partitionProducer) log() logContext {
+	return logWith(topicField(pp.topic), partitionField(pp.partition))
 }
 
 type partitionRetryState struct {
@@ -500,19 +654,21 @@ type partitionRetryState struct {
 	expectChaser bool
 }
 
-func (p *asyncProducer) newPartitionProducer(topic string, partition int32) chan<- *ProducerMessage {
-	input := make(chan *ProducerMessage, p.conf.ChannelBufferSize)
+func (p *asyncProducer) newPartitionProducer(topic string, partition int32) *partitionProducer {
 	pp := &partitionProducer{
 		parent:    p,
 		topic:     topic,
 		partition: partition,
-		input:     input,
+		queue:     newPartitionQueue(p.conf.ChannelBufferSize),
 
 		breaker:    breaker.New(3, 1, 10*time.Second),
 		retryState: make([]partitionRetryState, p.conf.Producer.Retry.Max+1),
+		leaderID:   -1,
 	}
-	go withRecover(pp.dispatch)
-	return input
+	p.partitionLock.Lock()
+	p.partitionProducers[pp] = none{}
+	p.partitionLock.Unlock()
+	return pp
 }
 
 func (pp *partitionProducer) backoff(retries int) {
@@ -528,88 +684,127 @@ func (pp *partitionProducer) backoff(retries int) {
 	}
 }
 
-func (pp *partitionProducer) dispatch() {
-	// try to prefetch the leader; if this doesn't work, we'll do a proper call to `updateLeader`
-	// on the first message
+// run dispatches the queued messages, returning once the queue is drained;
+// the next message queued starts it again.
+func (pp *partitionProducer) run() {
+	for {
+		pp.publish()
+		msg, ok, done := pp.queue.pop()
+		if !ok {
+			if done {
+				pp.stop()
+			}
+			return
+		}
+		if !pp.started {
+			pp.started = true
+			pp.start()
+		}
+		pp.dispatch(msg)
+	}
+}
+
+// start prefetches the leader of the partition; if this doesn't work, we'll
+// do a proper call to `updateLeader` on the first message
+func (pp *partitionProducer) start() {
 	pp.leader, _ = pp.parent.client.Leader(pp.topic, pp.partition)
 	if pp.leader != nil {
 		pp.brokerProducer = pp.parent.getBrokerProducer(pp.leader)
 		pp.parent.inFlight.Add(1) // we're generating a syn message; track it so we don't shut down while it's still inflight
 		pp.brokerProducer.input <- &ProducerMessage{Topic: pp.topic, Partition: pp.partition, flags: syn}
 	}
+}
 
-	defer func() {
-		if pp.brokerProducer != nil {
-			pp.parent.unrefBrokerProducer(pp.leader, pp.brokerProducer)
-		}
-	}()
+// stop releases the broker producer of the partition once its queue is
+// closed and drained.
+func (pp *partitionProducer) stop() {
+	if pp.brokerProducer != nil {
+		pp.parent.unrefBrokerProducer(pp.leader, pp.brokerProducer)
+	}
+	pp.parent.partitionLock.Lock()
+	delete(pp.parent.partitionProducers, pp)
+	pp.parent.partitionLock.Unlock()
+}
 
-	for msg := range pp.input {
-		if pp.brokerProducer != nil && pp.brokerProducer.abandoned != nil {
-			select {
-			case <-pp.brokerProducer.abandoned:
-				// a message on the abandoned channel means that our current broker selection is out of date
-				Logger.Printf("producer/leader/%s/%d abandoning broker %d\n", pp.topic, pp.partition, pp.leader.ID())
-				pp.parent.unrefBrokerProducer(pp.leader, pp.brokerProducer)
-				pp.brokerProducer = nil
-				time.Sleep(pp.parent.conf.Producer.Retry.Backoff)
-			default:
-				// producer connection is still open.
-			}
+func (pp *partitionProducer) dispatch(msg *ProducerMessage) {
+	if pp.brokerProducer != nil && pp.brokerProducer.abandoned != nil {
+		select {
+		case <-pp.brokerProducer.abandoned:
+			// a message on the abandoned channel means that our current broker selection is out of date
+			pp.log().with(brokerField(pp.leader.ID())).infof("producer/leader/%s/%d abandoning broker %d\n", pp.topic, pp.partition, pp.leader.ID())
+			pp.parent.unrefBrokerProducer(pp.leader, pp.brokerProducer)
+			pp.brokerProducer = nil
+			time.Sleep(pp.parent.conf.Producer.Retry.Backoff)
+		default:
+			// producer connection is still open.
 		}
+	}
 
-		if msg.retries > pp.highWatermark {
-			// a new, higher, retry level; handle it and then back off
-			pp.newHighWatermark(msg.retries)
-			pp.backoff(msg.retries)
-		} else if pp.highWatermark > 0 {
-			// we are retrying something (else highWatermark would be 0) but this message is not a *new* retry level
-			if msg.retries < pp.highWatermark {
-				// in fact this message is not even the current retry level, so buffer it for now (unless it's a just a fin)
-				if msg.flags&fin == fin {
-					pp.retryState[msg.retries].expectChaser = false
-					pp.parent.inFlight.Done() // this fin is now handled and will be garbage collected
-				} else {
-					pp.retryState[msg.retries].buf = append(pp.retryState[msg.retries].buf, msg)
-				}
-				continue
-			} else if msg.flags&fin == fin {
-				// this message is of the current retry level (msg.retries == highWatermark) and the fin flag is set,
-				// meaning this retry level is done and we can go down (at least) one level and flush that
-				pp.retryState[pp.highWatermark].expectChaser = false
-				pp.flushRetryBuffers()
+	if msg.retries > pp.highWatermark {
+		// a new, higher, retry level; handle it and then back off
+		pp.newHighWatermark(msg.retries)
+		pp.backoff(msg.retries)
+	} else if pp.highWatermark > 0 {
+		// we are retrying something (else highWatermark would be 0) but this message is not a *new* retry level
+		if msg.retries < pp.highWatermark {
+			// in fact this message is not even the current retry level, so buffer it for now (unless it's a just a fin)
+			if msg.flags&fin == fin {
+				pp.retryState[msg.retries].expectChaser = false
 				pp.parent.inFlight.Done() // this fin is now handled and will be garbage collected
-				continue
+			} else {
+				pp.retryState[msg.retries].buf = append(pp.retryState[msg.retries].buf, msg)
 			}
+			return
+		} else if msg.flags&fin == fin {
+			// this message is of the current retry level (msg.retries == highWatermark) and the fin flag is set,
+			// meaning this retry level is done and we can go down (at least) one level and flush that
+			pp.retryState[pp.highWatermark].expectChaser = false
+			pp.flushRetryBuffers()
+			pp.parent.inFlight.Done() // this fin is now handled and will be garbage collected
+			return
 		}
+	}
 
-		// if we made it this far then the current msg contains real data, and can be sent to the next goroutine
-		// without breaking any of our ordering guarantees
+	// if we made it this far then the current msg contains real data, and can be sent to the next goroutine
+	// without breaking any of our ordering guarantees
 
-		if pp.brokerProducer == nil {
-			if err := pp.updateLeader(); err != nil {
-				pp.parent.returnError(msg, err)
-				pp.backoff(msg.retries)
-				continue
-			}
-			Logger.Printf("producer/leader/%s/%d selected broker %d\n", pp.topic, pp.partition, pp.leader.ID())
+	if pp.brokerProducer == nil {
+		if err := pp.updateLeader(); err != nil {
+			pp.parent.returnError(msg, err)
+			pp.backoff(msg.retries)
+			return
 		}
+		pp.log().with(brokerField(pp.leader.ID())).infof("producer/leader/%s/%d selected broker %d\n", pp.topic, pp.partition, pp.leader.ID())
+	}
 
-		// Now that we know we have a broker to actually try and send this message to, generate the sequence
-		// number for it.
-		// All messages being retried (sent or not) have already had their retry count updated
-		// Also, ignore "special" syn/fin messages used to sync the brokerProducer and the topicProducer.
-		if pp.parent.conf.Producer.Idempotent && msg.retries == 0 && msg.flags == 0 {
-			msg.sequenceNumber, msg.producerEpoch = pp.parent.txnmgr.getAndIncrementSequenceNumber(msg.Topic, msg.Partition)
-			msg.hasSequence = true
-		}
+	// Now that we know we have a broker to actually try and send this message to, generate the sequence
+	// number for it.
+	// All messages being retried (sent or not) have already had their retry count updated
+	// Also, ignore "special" syn/fin messages used to sync the brokerProducer and the topicProducer.
+	if pp.parent.conf.Producer.Idempotent && msg.retries == 0 && msg.flags == 0 {
+		msg.sequenceNumber, msg.producerEpoch = pp.parent.txnmgr.getAndIncrementSequenceNumber(msg.Topic, msg.Partition)
+		msg.hasSequence = true
+	}
 
-		pp.brokerProducer.input <- msg
+	pp.brokerProducer.input <- msg
+}
+
+// publish publishes the state of the partition for DebugState.
+func (pp *partitionProducer) publish() {
+	leaderID, retryBuffered := int32(-1), 0
+	if pp.brokerProducer != nil {
+		leaderID = pp.leader.ID()
+	}
+	for _, state := range pp.retryState {
+		retryBuffered += len(state.buf)
 	}
+	atomic.StoreInt32(&pp.leaderID, leaderID)
+	atomic.StoreInt32(&pp.retryLevel, int32(pp.highWatermark))
+	atomic.StoreInt32(&pp.retryBuffered, int32(retryBuffered))
 }
 
 func (pp *partitionProducer) newHighWatermark(hwm int) {
-	Logger.Printf("producer/leader/%s/%d state change to [retrying-%d]\n", pp.topic, pp.partition, hwm)
+	pp.log().infof("producer/leader/%s/%d state change to [retrying-%d]\n", pp.topic, pp.partition, hwm)
 	pp.highWatermark = hwm
 
 	// send off a fin so that we know when everything "in between" has made it
@@ -619,13 +814,13 @@ func (pp *partitionProducer) newHighWatermark(hwm int) {
 	pp.brokerProducer.input <- &ProducerMessage{Topic: pp.topic, Partition: pp.partition, flags: fin, retries: pp.highWatermark - 1}
 
 	// a new HWM means that our current broker selection is out of date
-	Logger.Printf("producer/leader/%s/%d abandoning broker %d\n", pp.topic, pp.partition, pp.leader.ID())
+	pp.log().with(brokerField(pp.leader.ID())).infof("producer/leader/%s/%d abandoning broker %d\n", pp.topic, pp.partition, pp.leader.ID())
 	pp.parent.unrefBrokerProducer(pp.leader, pp.brokerProducer)
 	pp.brokerProducer = nil
 }
 
 func (pp *partitionProducer) flushRetryBuffers() {
-	Logger.Printf("producer/leader/%s/%d state change to [flushing-%d]\n", pp.topic, pp.partition, pp.highWatermark)
+	pp.log().infof("producer/leader/%s/%d state change to [flushing-%d]\n", pp.topic, pp.partition, pp.highWatermark)
 	for {
 		pp.highWatermark--
 
@@ -634,7 +829,7 @@ func (pp *partitionProducer) flushRetryBuffers() {
 				pp.parent.returnErrors(pp.retryState[pp.highWatermark].buf, err)
 				goto flushDone
 			}
-			Logger.Printf("producer/leader/%s/%d selected broker %d\n", pp.topic, pp.partition, pp.leader.ID())
+			pp.log().with(brokerField(pp.leader.ID())).infof("producer/leader/%s/%d selected broker %d\n", pp.topic, pp.partition, pp.leader.ID())
 		}
 
 		for _, msg := range pp.retryState[pp.highWatermark].buf {
@@ -644,10 +839,10 @@ func (pp *partitionProducer) flushRetryBuffers() {
 	flushDone:
 		pp.retryState[pp.highWatermark].buf = nil
 		if pp.retryState[pp.highWatermark].expectChaser {
-			Logger.Printf("producer/leader/%s/%d state change to [retrying-%d]\n", pp.topic, pp.partition, pp.highWatermark)
+			pp.log().infof("producer/leader/%s/%d state change to [retrying-%d]\n", pp.topic, pp.partition, pp.highWatermark)
 			break
 		} else if pp.highWatermark == 0 {
-			Logger.Printf("producer/leader/%s/%d state change to [normal]\n", pp.topic, pp.partition)
+			pp.log().infof("producer/leader/%s/%d state change to [normal]\n", pp.topic, pp.partition)
 			break
 		}
 	}
@@ -723,6 +918,7 @@ func (p *asyncProducer) newBrokerProducer(broker *Broker) *brokerProducer {
 				sendResponse(nil, err)
 				continue
 			}
+			p.compressionRatios.observeRequest(request)
 			// Callback is not called when using NoResponse
 			if p.conf.Producer.RequiredAcks == NoResponse {
 				// Provide the expected nil response
@@ -797,17 +993,23 @@ type brokerProducer struct {
 
 	closing        error
 	currentRetries map[string]map[int32]error
+
+	// buffered and bufferedBytes publish the size of buffer for DebugState
+	buffered, bufferedBytes int32
 }
 
 func (bp *brokerProducer) run() {
 	var output chan<- *produceSet
-	Logger.Printf("producer/broker/%d starting up\n", bp.broker.ID())
+	bp.broker.log().infof("producer/broker/%d starting up\n", bp.broker.ID())
 
 	for {
+		atomic.StoreInt32(&bp.buffered, int32(bp.buffer.bufferCount))
+		atomic.StoreInt32(&bp.bufferedBytes, int32(bp.buffer.bufferBytes))
+
 		select {
 		case msg, ok := <-bp.input:
 			if !ok {
-				Logger.Printf("producer/broker/%d input chan closed\n", bp.broker.ID())
+				bp.broker.log().infof("producer/broker/%d input chan closed\n", bp.broker.ID())
 				bp.shutdown()
 				return
 			}
@@ -817,7 +1019,7 @@ func (bp *brokerProducer) run() {
 			}
 
 			if msg.flags&syn == syn {
-				Logger.Printf("producer/broker/%d state change to [open] on %s/%d\n",
+				bp.broker.log().with(topicField(msg.Topic), partitionField(msg.Partition)).infof("producer/broker/%d state change to [open] on %s/%d\n",
 					bp.broker.ID(), msg.Topic, msg.Partition)
 				if bp.currentRetries[msg.Topic] == nil {
 					bp.currentRetries[msg.Topic] = make(map[int32]error)
@@ -833,7 +1035,7 @@ func (bp *brokerProducer) run() {
 				if bp.closing == nil && msg.flags&fin == fin {
 					// we were retrying this partition but we can start processing again
 					delete(bp.currentRetries[msg.Topic], msg.Partition)
-					Logger.Printf("producer/broker/%d state change to [closed] on %s/%d\n",
+					bp.broker.log().with(topicField(msg.Topic), partitionField(msg.Partition)).infof("producer/broker/%d state change to [closed] on %s/%d\n",
 						bp.broker.ID(), msg.Topic, msg.Partition)
 				}
 
@@ -841,7 +1043,7 @@ func (bp *brokerProducer) run() {
 			}
 
 			if bp.buffer.wouldOverflow(msg) {
-				Logger.Printf("producer/broker/%d maximum request accumulated, waiting for space\n", bp.broker.ID())
+				bp.broker.log().infof("producer/broker/%d maximum request accumulated, waiting for space\n", bp.broker.ID())
 				if err := bp.waitForSpace(msg, false); err != nil {
 					bp.parent.retryMessage(msg, err)
 					continue
@@ -850,7 +1052,7 @@ func (bp *brokerProducer) run() {
 
 			if bp.parent.txnmgr.producerID != noProducerID && bp.buffer.producerEpoch != msg.producerEpoch {
 				// The epoch was reset, need to roll the buffer over
-				Logger.Printf("producer/broker/%d detected epoch rollover, waiting for new buffer\n", bp.broker.ID())
+				bp.broker.log().infof("producer/broker/%d detected epoch rollover, waiting for new buffer\n", bp.broker.ID())
 				if err := bp.waitForSpace(msg, true); err != nil {
 					bp.parent.retryMessage(msg, err)
 					continue
@@ -897,7 +1099,7 @@ func (bp *brokerProducer) shutdown() {
 		bp.handleResponse(response)
 	}
 	// No more brokerProducer related goroutine should be running
-	Logger.Printf("producer/broker/%d shut down\n", bp.broker.ID())
+	bp.broker.log().infof("producer/broker/%d shut down\n", bp.broker.ID())
 }
 
 func (bp *brokerProducer) needsRetry(msg *ProducerMessage) error {
@@ -998,7 +1200,7 @@ func (bp *brokerProducer) handleSuccess(sent *produceSet, response *ProduceRespo
 		if bp.parent.conf.Producer.Idempotent {
 			err := bp.parent.client.RefreshMetadata(retryTopics...)
 			if err != nil {
-				Logger.Printf("Failed refreshing metadata because of %v\n", err)
+				warnf("Failed refreshing metadata because of %v\n", err)
 			}
 		}
 
@@ -1012,7 +1214,7 @@ func (bp *brokerProducer) handleSuccess(sent *produceSet, response *ProduceRespo
 			switch block.Err {
 			case ErrInvalidMessage, ErrUnknownTopicOrPartition, ErrLeaderNotAvailable, ErrNotLeaderForPartition,
 				ErrRequestTimedOut, ErrNotEnoughReplicas, ErrNotEnoughReplicasAfterAppend:
-				Logger.Printf("producer/broker/%d state change to [retrying] on %s/%d because %v\n",
+				bp.broker.log().with(topicField(topic), partitionField(partition)).infof("producer/broker/%d state change to [retrying] on %s/%d because %v\n",
 					bp.broker.ID(), topic, partition, block.Err)
 				if bp.currentRetries[topic] == nil {
 					bp.currentRetries[topic] = make(map[int32]error)
@@ -1031,12 +1233,18 @@ func (bp *brokerProducer) handleSuccess(sent *produceSet, response *ProduceRespo
 }
 
 func (p *asyncProducer) retryBatch(topic string, partition int32, pSet *partitionSet, kerr KError) {
-	Logger.Printf("Retrying batch for %v-%d because of %s\n", topic, partition, kerr)
+	logWith(topicField(topic), partitionField(partition)).infof("Retrying batch for %v-%d because of %s\n", topic, partition, kerr)
 	produceSet := newProduceSet(p)
 	produceSet.msgs[topic] = make(map[int32]*partitionSet)
 	produceSet.msgs[topic][partition] = pSet
 	produceSet.bufferBytes += pSet.bufferBytes
 	produceSet.bufferCount += len(pSet.msgs)
+	// the label of the log is only formatted if the budget refuses
+	if !p.conf.RetryBudget.AllowRetry() {
+		logWith(topicField(topic), partitionField(partition)).infof("producer/%s/%d not retrying as the retry budget is exhausted\n", topic, partition)
+		p.returnErrors(pSet.msgs, kerr)
+		return
+	}
 	for _, msg := range pSet.msgs {
 		if msg.retries >= p.conf.Producer.Retry.Max {
 			p.returnError(msg, kerr)
@@ -1048,7 +1256,7 @@ func (p *asyncProducer) retryBatch(topic string, partition int32, pSet *partitio
 	// it's expected that a metadata refresh has been requested prior to calling retryBatch
 	leader, err := p.client.Leader(topic, partition)
 	if err != nil {
-		Logger.Printf("Failed retrying batch for %v-%d because of %v while looking up for new leader\n", topic, partition, err)
+		logWith(topicField(topic), partitionField(partition)).warnf("Failed retrying batch for %v-%d because of %v while looking up for new leader\n", topic, partition, err)
 		for _, msg := range pSet.msgs {
 			p.returnError(msg, kerr)
 		}
@@ -1065,7 +1273,7 @@ func (bp *brokerProducer) handleError(sent *produceSet, err error) {
 			bp.parent.returnErrors(pSet.msgs, err)
 		})
 	} else {
-		Logger.Printf("producer/broker/%d state change to [closing] because %s\n", bp.broker.ID(), err)
+		bp.broker.log().infof("producer/broker/%d state change to [closing] because %s\n", bp.broker.ID(), err)
 		bp.parent.abandonBrokerConnection(bp.broker)
 		_ = bp.broker.Close()
 		bp.closing = err
@@ -1109,7 +1317,7 @@ func (p *asyncProducer) retryHandler() {
 // utility functions
 
 func (p *asyncProducer) shutdown() {
-	Logger.Println("Producer shutting down.")
+	infof("Producer shutting down.")
 	p.inFlight.Add(1)
 	p.input <- &ProducerMessage{flags: shutdown}
 
@@ -1117,7 +1325,7 @@ func (p *asyncProducer) shutdown() {
 
 	err := p.client.Close()
 	if err != nil {
-		Logger.Println("producer/shutdown failed to close the embedded client:", err)
+		warnf("producer/shutdown failed to close the embedded client: %v", err)
 	}
 
 	close(p.input)
@@ -1130,7 +1338,7 @@ func (p *asyncProducer) returnError(msg *ProducerMessage, err error) {
 	// We need to reset the producer ID epoch if we set a sequence number on it, because the broker
 	// will never see a message with this number, so we can never continue the sequence.
 	if msg.hasSequence {
-		Logger.Printf("producer/txnmanager rolling over epoch due to publish failure on %s/%d", msg.Topic, msg.Partition)
+		logWith(topicField(msg.Topic), partitionField(msg.Partition)).warnf("producer/txnmanager rolling over epoch due to publish failure on %s/%d", msg.Topic, msg.Partition)
 		p.txnmgr.bumpEpoch()
 	}
 	msg.clear()
@@ -1138,8 +1346,9 @@ func (p *asyncProducer) returnError(msg *ProducerMessage, err error) {
 	if p.conf.Producer.Return.Errors {
 		p.errors <- pErr
 	} else {
-		Logger.Println(pErr)
+		logWith(topicField(msg.Topic), partitionField(msg.Partition)).warnf("%v", pErr)
 	}
+	atomic.AddInt32(&p.pendingMessages, -1)
 	p.inFlight.Done()
 }
 
@@ -1155,6 +1364,7 @@ func (p *asyncProducer) returnSuccesses(batch []*ProducerMessage) {
 			msg.clear()
 			p.successes <- msg
 		}
+		atomic.AddInt32(&p.pendingMessages, -1)
 		p.inFlight.Done()
 	}
 }
@@ -1169,6 +1379,14 @@ func (p *asyncProducer) retryMessage(msg *ProducerMessage, err error) {
 }
 
 func (p *asyncProducer) retryMessages(batch []*ProducerMessage, err error) {
+	// the messages are retried together, which spends a single retry of the
+	// budget
+	if len(batch) > 0 && !p.conf.allowRetry("producer") {
+		for _, msg := range batch {
+			p.returnError(msg, err)
+		}
+		return
+	}
 	for _, msg := range batch {
 		p.retryMessage(msg, err)
 	}
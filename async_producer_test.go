@@ -1,6 +1,7 @@
 package sarama
 
 import (
+	"context"
 	"errors"
 	"log"
 	"os"
@@ -183,6 +184,82 @@ func TestAsyncProducerMultipleFlushes(t *testing.T) {
 	seedBroker.Close()
 }
 
+func TestAsyncProducerCloseContext(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	leader := NewMockBroker(t, 2)
+	defer leader.Close()
+
+	metadataResponse := new(MetadataResponse)
+	metadataResponse.AddBroker(leader.Addr(), leader.BrokerID())
+	metadataResponse.AddTopicPartition("my_topic", 0, leader.BrokerID(), nil, nil, nil, ErrNoError)
+	seedBroker.Returns(metadataResponse)
+	seedBroker.Returns(metadataResponse)
+
+	prodSuccess := new(ProduceResponse)
+	prodSuccess.AddTopicPartition("my_topic", 0, ErrNoError)
+	leader.Returns(prodSuccess)
+
+	config := NewTestConfig()
+	config.Producer.Return.Successes = true
+	produce := func(deadline time.Duration) error {
+		producer, err := NewAsyncProducer([]string{seedBroker.Addr()}, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		producer.Input() <- &ProducerMessage{Topic: "my_topic", Value: StringEncoder(TestMessage)}
+		for atomic.LoadInt32(&producer.(*asyncProducer).pendingMessages) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), deadline)
+		defer cancel()
+		return producer.CloseContext(ctx)
+	}
+
+	// the message is flushed before the deadline
+	if err := produce(5 * time.Second); err != nil {
+		t.Errorf("expected the producer to close cleanly, got %v", err)
+	}
+
+	// the leader does not answer the second time, so the message is abandoned
+	err := produce(100 * time.Millisecond)
+	var abandoned *CloseAbandonedError
+	if !errors.As(err, &abandoned) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a CloseAbandonedError, got %v", err)
+	}
+	if abandoned.Messages != 1 {
+		t.Errorf("expected 1 message to be abandoned, got %d", abandoned.Messages)
+	}
+}
+
+func TestAsyncProducerMessageTooLarge(t *testing.T) {
+	conf := NewTestConfig()
+	conf.Producer.MaxMessageBytes = 1000
+	conf.Producer.MaxRequestBytes = 2000
+	p := &asyncProducer{conf: conf}
+
+	// the batch overhead counts against MaxMessageBytes
+	msg := &ProducerMessage{Value: ByteEncoder(make([]byte, 1000-maximumRecordOverhead-recordBatchOverhead))}
+	if p.tooLarge(msg, 2) {
+		t.Error("expected a message filling a batch to be accepted")
+	}
+	msg.Value = ByteEncoder(make([]byte, 1001-maximumRecordOverhead-recordBatchOverhead))
+	if !p.tooLarge(msg, 2) {
+		t.Error("expected a message overflowing a batch to be rejected")
+	}
+
+	// compressed batches are only bounded by MaxRequestBytes until the broker checks them
+	conf.Producer.Compression = CompressionGZIP
+	if p.tooLarge(msg, 2) {
+		t.Error("expected a message which may compress under MaxMessageBytes to be accepted")
+	}
+	msg.Value = ByteEncoder(make([]byte, 2000))
+	if !p.tooLarge(msg, 2) {
+		t.Error("expected a message overflowing a request to be rejected")
+	}
+}
+
 func TestAsyncProducerMultipleBrokers(t *testing.T) {
 	seedBroker := NewMockBroker(t, 1)
 	leader0 := NewMockBroker(t, 2)
@@ -265,6 +342,51 @@ func TestAsyncProducerCustomPartitioner(t *testing.T) {
 	seedBroker.Close()
 }
 
+func TestAsyncProducerSlowTopicDoesNotBlockOthers(t *testing.T) {
+	broker := NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("my_topic", 0, broker.BrokerID()).
+			SetLeader("slow_topic", 0, broker.BrokerID()),
+		"ProduceRequest": NewMockProduceResponse(t),
+	})
+
+	slow := make(testPartitioner)
+	config := NewTestConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.Partitioner = func(topic string) Partitioner {
+		if topic == "slow_topic" {
+			return slow
+		}
+		return NewManualPartitioner(topic)
+	}
+	producer, err := NewAsyncProducer([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the message of slow_topic waits for its partition while my_topic is
+	// partitioned and produced on its own
+	producer.Input() <- &ProducerMessage{Topic: "slow_topic", Value: StringEncoder(TestMessage)}
+	producer.Input() <- &ProducerMessage{Topic: "my_topic", Value: StringEncoder(TestMessage)}
+	select {
+	case msg := <-producer.Successes():
+		if msg.Topic != "my_topic" {
+			t.Errorf("expected my_topic to be produced first, got %s", msg.Topic)
+		}
+	case err := <-producer.Errors():
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("my_topic blocked behind the partitioning of slow_topic")
+	}
+
+	slow.feed(0)
+	expectResults(t, producer, 1, 0)
+	closeProducer(t, producer)
+}
+
 func TestAsyncProducerFailureRetry(t *testing.T) {
 	seedBroker := NewMockBroker(t, 1)
 	leader1 := NewMockBroker(t, 2)
@@ -0,0 +1,59 @@
+package sarama
+
+import (
+	"math/rand"
+	"time"
+)
+
+// NewExponentialBackoff returns a backoff function for Metadata.Retry,
+// Producer.Retry or Admin.Retry which doubles backoff with every retry, up to
+// maxBackoff, and randomly moves each backoff by up to 20% so that the
+// clients failing together do not retry together, as KIP-580 does for the JVM
+// clients: MIN(maxBackoff, backoff * 2^(retries-1)) * random(0.8, 1.2). Wrap
+// it with ConsumerBackoff for Consumer.Retry.
+func NewExponentialBackoff(backoff, maxBackoff time.Duration) func(retries, maxRetries int) time.Duration {
+	return func(retries, maxRetries int) time.Duration {
+		d := exponentialBackoff(backoff, maxBackoff, retries)
+		return time.Duration(float64(d) * (0.8 + 0.4*rand.Float64()))
+	}
+}
+
+// NewFullJitterBackoff returns a backoff function like NewExponentialBackoff,
+// but waiting a random duration up to the exponential backoff:
+// random(0, MIN(maxBackoff, backoff * 2^(retries-1))). The retries spread
+// further apart, at the cost of some retries coming sooner.
+func NewFullJitterBackoff(backoff, maxBackoff time.Duration) func(retries, maxRetries int) time.Duration {
+	return func(retries, maxRetries int) time.Duration {
+		d := exponentialBackoff(backoff, maxBackoff, retries)
+		return time.Duration(rand.Int63n(int64(d) + 1))
+	}
+}
+
+// ConsumerBackoff adapts a backoff function of NewExponentialBackoff or
+// NewFullJitterBackoff to Consumer.Retry.BackoffFunc, whose retries are not
+// limited.
+func ConsumerBackoff(backoffFunc func(retries, maxRetries int) time.Duration) func(retries int) time.Duration {
+	return func(retries int) time.Duration {
+		return backoffFunc(retries, -1)
+	}
+}
+
+// exponentialBackoff returns backoff doubled for each retry after the first,
+// up to maxBackoff.
+func exponentialBackoff(backoff, maxBackoff time.Duration, retries int) time.Duration {
+	if backoff <= 0 {
+		return 0
+	}
+	if maxBackoff < backoff {
+		maxBackoff = backoff
+	}
+
+	d := backoff
+	for i := 1; i < retries && d < maxBackoff; i++ {
+		d *= 2
+	}
+	if d > maxBackoff {
+		d = maxBackoff
+	}
+	return d
+}
@@ -0,0 +1,41 @@
+package sarama
+
+import (
+	"testing"
+	"time"
+)
+
+func TestExponentialBackoff(t *testing.T) {
+	backoff := NewExponentialBackoff(100*time.Millisecond, time.Second)
+	for retries, expected := range []time.Duration{
+		100 * time.Millisecond, // no retry yet
+		100 * time.Millisecond,
+		200 * time.Millisecond,
+		400 * time.Millisecond,
+		800 * time.Millisecond,
+		time.Second,
+		time.Second,
+	} {
+		for i := 0; i < 10; i++ {
+			if d := backoff(retries, 10); d < expected*8/10 || d > expected*12/10 {
+				t.Errorf("retry %d: expected %s ± 20%%, got %s", retries, expected, d)
+			}
+		}
+	}
+}
+
+func TestFullJitterBackoff(t *testing.T) {
+	backoff := ConsumerBackoff(NewFullJitterBackoff(100*time.Millisecond, time.Second))
+	for i := 0; i < 100; i++ {
+		if d := backoff(3); d < 0 || d > 400*time.Millisecond {
+			t.Errorf("expected up to 400ms, got %s", d)
+		}
+		if d := backoff(100); d < 0 || d > time.Second {
+			t.Errorf("expected up to 1s, got %s", d)
+		}
+	}
+
+	if d := NewFullJitterBackoff(0, time.Second)(3, 3); d != 0 {
+		t.Errorf("expected no backoff, got %s", d)
+	}
+}
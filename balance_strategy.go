@@ -462,7 +462,7 @@ func isBalanced(currentAssignment map[string][]topicPartitionAssignment, allSubs
 	for memberID, partitions := range currentAssignment {
 		for _, partition := range partitions {
 			if _, exists := allPartitions[partition]; exists {
-				Logger.Printf("Topic %s Partition %d is assigned more than one consumer", partition.Topic, partition.Partition)
+				infof("Topic %s Partition %d is assigned more than one consumer", partition.Topic, partition.Partition)
 			}
 			allPartitions[partition] = memberID
 		}
@@ -511,13 +511,13 @@ func (s *stickyBalanceStrategy) performReassignments(reassignablePartitions []to
 
 			// the partition must have at least two consumers
 			if len(partition2AllPotentialConsumers[partition]) <= 1 {
-				Logger.Printf("Expected more than one potential consumer for partition %s topic %d", partition.Topic, partition.Partition)
+				infof("Expected more than one potential consumer for partition %s topic %d", partition.Topic, partition.Partition)
 			}
 
 			// the partition must have a consumer
 			consumer := currentPartitionConsumer[partition]
 			if consumer == "" {
-				Logger.Printf("Expected topic %s partition %d to be assigned to a consumer", partition.Topic, partition.Partition)
+				infof("Expected topic %s partition %d to be assigned to a consumer", partition.Topic, partition.Partition)
 			}
 
 			if _, exists := prevAssignment[partition]; exists {
@@ -580,7 +580,7 @@ func canConsumerParticipateInReassignment(memberID string, currentAssignment map
 	currentAssignmentSize := len(currentPartitions)
 	maxAssignmentSize := len(consumer2AllPotentialPartitions[memberID])
 	if currentAssignmentSize > maxAssignmentSize {
-		Logger.Printf("The consumer %s is assigned more partitions than the maximum possible", memberID)
+		infof("The consumer %s is assigned more partitions than the maximum possible", memberID)
 	}
 	if currentAssignmentSize < maxAssignmentSize {
 		// if a consumer is not assigned all its potential partitions it is subject to reassignment
@@ -845,7 +845,7 @@ func prepopulateCurrentAssignments(members map[string]ConsumerGroupMemberMetadat
 					if _, generationExists := consumers[consumerUserData.generation()]; generationExists {
 						// same partition is assigned to two consumers during the same rebalance.
 						// log a warning and skip this record
-						Logger.Printf("Topic %s Partition %d is assigned to multiple consumers following sticky assignment generation %d", partition.Topic, partition.Partition, consumerUserData.generation())
+						infof("Topic %s Partition %d is assigned to multiple consumers following sticky assignment generation %d", partition.Topic, partition.Partition, consumerUserData.generation())
 						continue
 					} else {
 						consumers[consumerUserData.generation()] = memberID
@@ -944,7 +944,7 @@ func (p *partitionMovements) movePartition(partition topicPartitionAssignment, o
 		// this partition has previously moved
 		existingPair := p.removeMovementRecordOfPartition(partition)
 		if existingPair.DstMemberID != oldConsumer {
-			Logger.Printf("Existing pair DstMemberID %s was not equal to the oldConsumer ID %s", existingPair.DstMemberID, oldConsumer)
+			infof("Existing pair DstMemberID %s was not equal to the oldConsumer ID %s", existingPair.DstMemberID, oldConsumer)
 		}
 		if existingPair.SrcMemberID != newConsumer {
 			// the partition is not moving back to its previous consumer
@@ -965,7 +965,7 @@ func (p *partitionMovements) getTheActualPartitionToBeMoved(partition topicParti
 	if _, exists := p.Movements[partition]; exists {
 		// this partition has previously moved
 		if oldConsumer != p.Movements[partition].DstMemberID {
-			Logger.Printf("Partition movement DstMemberID %s was not equal to the oldConsumer ID %s", p.Movements[partition].DstMemberID, oldConsumer)
+			infof("Partition movement DstMemberID %s was not equal to the oldConsumer ID %s", p.Movements[partition].DstMemberID, oldConsumer)
 		}
 		oldConsumer = p.Movements[partition].SrcMemberID
 	}
@@ -1047,7 +1047,7 @@ func (p *partitionMovements) hasCycles(pairs []consumerPair) bool {
 		if path, linked := p.isLinked(pair.DstMemberID, pair.SrcMemberID, reducedPairs, []string{pair.SrcMemberID}); linked {
 			if !p.in(path, cycles) {
 				cycles = append(cycles, path)
-				Logger.Printf("A cycle of length %d was found: %v", len(path)-1, path)
+				infof("A cycle of length %d was found: %v", len(path)-1, path)
 			}
 		}
 	}
@@ -1072,8 +1072,8 @@ func (p *partitionMovements) isSticky() bool {
 			i++
 		}
 		if p.hasCycles(movementPairs) {
-			Logger.Printf("Stickiness is violated for topic %s", topic)
-			Logger.Printf("Partition movements for this topic occurred among the following consumer pairs: %v", movements)
+			infof("Stickiness is violated for topic %s", topic)
+			infof("Partition movements for this topic occurred among the following consumer pairs: %v", movements)
 			return false
 		}
 	}
@@ -0,0 +1,111 @@
+package benchmarks
+
+import (
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func BenchmarkProduce(b *testing.B) {
+	for _, w := range Workloads {
+		b.Run(w.Name, func(b *testing.B) {
+			cluster, err := NewCluster(b, w, 0)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer cluster.Close()
+
+			producer, err := sarama.NewAsyncProducer(cluster.Addrs, w.Config())
+			if err != nil {
+				b.Fatal(err)
+			}
+			value := w.Value()
+			msgs := make([]*sarama.ProducerMessage, b.N)
+			for i := range msgs {
+				msgs[i] = w.Message(i, value)
+			}
+
+			var latencies Latencies
+			done := make(chan struct{})
+			go func() {
+				defer close(done)
+				successes, errors := producer.Successes(), producer.Errors()
+				for i := 0; i < b.N; i++ {
+					select {
+					case msg := <-successes:
+						latencies.Observe(time.Since(msg.Metadata.(time.Time)))
+					case err := <-errors:
+						b.Error(err)
+					}
+				}
+			}()
+
+			b.ReportAllocs()
+			b.SetBytes(int64(w.MessageSize))
+			b.ResetTimer()
+			for _, msg := range msgs {
+				msg.Metadata = time.Now()
+				producer.Input() <- msg
+			}
+			<-done
+			b.StopTimer()
+
+			latencies.Report(b)
+			if err := producer.Close(); err != nil {
+				b.Error(err)
+			}
+		})
+	}
+}
+
+func BenchmarkConsume(b *testing.B) {
+	for _, w := range Workloads {
+		b.Run(w.Name, func(b *testing.B) {
+			cluster, err := NewCluster(b, w, b.N)
+			if err != nil {
+				b.Fatal(err)
+			}
+			defer cluster.Close()
+
+			consumer, err := sarama.NewConsumer(cluster.Addrs, w.Config())
+			if err != nil {
+				b.Fatal(err)
+			}
+			messages := make(chan *sarama.ConsumerMessage, 1024)
+			errors := make(chan *sarama.ConsumerError, 1)
+
+			b.ReportAllocs()
+			b.SetBytes(int64(w.MessageSize))
+			b.ResetTimer()
+			for p := int32(0); p < w.Partitions; p++ {
+				pc, err := consumer.ConsumePartition(w.Topic(), p, sarama.OffsetOldest)
+				if err != nil {
+					b.Fatal(err)
+				}
+				go func(pc sarama.PartitionConsumer) {
+					for msg := range pc.Messages() {
+						messages <- msg
+					}
+				}(pc)
+				go func(pc sarama.PartitionConsumer) {
+					for err := range pc.Errors() {
+						errors <- err
+					}
+				}(pc)
+			}
+			for i := 0; i < b.N; i++ {
+				select {
+				case <-messages:
+				case err := <-errors:
+					b.Fatal(err)
+				}
+			}
+			b.StopTimer()
+
+			if err := consumer.Close(); err != nil {
+				b.Error(err)
+			}
+		})
+	}
+}
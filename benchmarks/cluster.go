@@ -0,0 +1,98 @@
+package benchmarks
+
+import (
+	"errors"
+	"os"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+// Cluster is the cluster a workload runs against, a MockBroker unless
+// BENCH_KAFKA_BROKERS is set.
+type Cluster struct {
+	// Addrs are the addresses of the brokers.
+	Addrs []string
+
+	mock  *sarama.MockBroker
+	fetch *sarama.MockFetchResponse
+}
+
+// NewCluster returns the cluster of the workload, its topic holding the
+// messages messages of the workload spread over its partitions.
+func NewCluster(t sarama.TestReporter, w Workload, messages int) (*Cluster, error) {
+	if brokers := os.Getenv("BENCH_KAFKA_BROKERS"); brokers != "" {
+		c := &Cluster{Addrs: strings.Split(brokers, ",")}
+		if err := c.prepareTopic(w, messages); err != nil {
+			return nil, err
+		}
+		return c, nil
+	}
+
+	mock := sarama.NewMockBroker(t, 1)
+	c := &Cluster{
+		Addrs: []string{mock.Addr()},
+		mock:  mock,
+		fetch: sarama.NewMockFetchResponse(t, 500),
+	}
+	metadata := sarama.NewMockMetadataResponse(t).SetBroker(mock.Addr(), mock.BrokerID())
+	offsets := sarama.NewMockOffsetResponse(t)
+	value := sarama.ByteEncoder(w.Value())
+	for p := int32(0); p < w.Partitions; p++ {
+		metadata.SetLeader(w.Topic(), p, mock.BrokerID())
+		n := int64(messages / int(w.Partitions))
+		if p < int32(messages%int(w.Partitions)) {
+			n++
+		}
+		for offset := int64(0); offset < n; offset++ {
+			c.fetch.SetMessage(w.Topic(), p, offset, value)
+		}
+		c.fetch.SetHighWaterMark(w.Topic(), p, n)
+		offsets.SetOffset(w.Topic(), p, sarama.OffsetOldest, 0)
+		offsets.SetOffset(w.Topic(), p, sarama.OffsetNewest, n)
+	}
+	mock.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": metadata,
+		"ProduceRequest":  sarama.NewMockProduceResponse(t),
+		"OffsetRequest":   offsets,
+		"FetchRequest":    c.fetch,
+	})
+	return c, nil
+}
+
+// prepareTopic creates the topic of the workload on a real cluster, and
+// produces the messages to consume.
+func (c *Cluster) prepareTopic(w Workload, messages int) error {
+	conf := w.Config()
+	admin, err := sarama.NewClusterAdmin(c.Addrs, conf)
+	if err != nil {
+		return err
+	}
+	defer admin.Close()
+	err = admin.CreateTopic(w.Topic(), &sarama.TopicDetail{NumPartitions: w.Partitions, ReplicationFactor: 1}, false)
+	if err != nil && !errors.Is(err, sarama.ErrTopicAlreadyExists) {
+		return err
+	}
+	if messages == 0 {
+		return nil
+	}
+
+	producer, err := sarama.NewSyncProducer(c.Addrs, conf)
+	if err != nil {
+		return err
+	}
+	defer producer.Close()
+	value := w.Value()
+	msgs := make([]*sarama.ProducerMessage, messages)
+	for i := range msgs {
+		msgs[i] = w.Message(i, value)
+	}
+	return producer.SendMessages(msgs)
+}
+
+// Close closes the MockBroker of the cluster.
+func (c *Cluster) Close() {
+	if c.mock != nil {
+		c.mock.Close()
+	}
+}
@@ -0,0 +1,42 @@
+package benchmarks
+
+import (
+	"sort"
+	"testing"
+	"time"
+)
+
+// Latencies records the latencies of the messages of a benchmark.
+type Latencies struct {
+	samples []time.Duration
+}
+
+// Observe records a latency.
+func (l *Latencies) Observe(d time.Duration) {
+	l.samples = append(l.samples, d)
+}
+
+// Percentile returns the p-th percentile of the latencies, 0 without any.
+func (l *Latencies) Percentile(p float64) time.Duration {
+	if len(l.samples) == 0 {
+		return 0
+	}
+	sort.Slice(l.samples, func(i, j int) bool { return l.samples[i] < l.samples[j] })
+	i := int(p / 100 * float64(len(l.samples)-1))
+	return l.samples[i]
+}
+
+// Report reports the 50th, 99th and 99.9th percentiles of the latencies as
+// metrics of the benchmark.
+func (l *Latencies) Report(b *testing.B) {
+	for _, p := range []struct {
+		percentile float64
+		unit       string
+	}{
+		{50, "p50-ns"},
+		{99, "p99-ns"},
+		{99.9, "p999-ns"},
+	} {
+		b.ReportMetric(float64(l.Percentile(p.percentile).Nanoseconds()), p.unit)
+	}
+}
@@ -0,0 +1,29 @@
+package benchmarks
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLatenciesPercentile(t *testing.T) {
+	var l Latencies
+	if p := l.Percentile(50); p != 0 {
+		t.Errorf("expected 0 without latencies, got %v", p)
+	}
+	for i := 100; i > 0; i-- {
+		l.Observe(time.Duration(i) * time.Millisecond)
+	}
+	for _, tc := range []struct {
+		percentile float64
+		expected   time.Duration
+	}{
+		{0, time.Millisecond},
+		{50, 50 * time.Millisecond},
+		{99, 99 * time.Millisecond},
+		{100, 100 * time.Millisecond},
+	} {
+		if p := l.Percentile(tc.percentile); p != tc.expected {
+			t.Errorf("expected the %vth percentile to be %v, got %v", tc.percentile, tc.expected, p)
+		}
+	}
+}
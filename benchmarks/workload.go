@@ -0,0 +1,89 @@
+/*
+Package benchmarks holds the standardized produce and consume workloads Sarama
+is benchmarked with, for the performance of releases and pull requests to be
+compared on the same grounds.
+
+The workloads run against a MockBroker by default, measuring the client alone,
+or against the cluster of the BENCH_KAFKA_BROKERS environment variable (a
+comma separated list of addresses), in topics named after the workloads and
+created as needed. The benchmarks report the allocations and, for the
+producer, the percentiles of the latency of the messages; `make bench` runs
+them and writes their CPU and allocation profiles.
+
+NOTE: this package does not fall under the API stability guarantee of Sarama,
+its workloads changing as needed to measure the client.
+*/
+package benchmarks
+
+import (
+	"bytes"
+	"strconv"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// Workload is a standardized workload of the benchmarks.
+type Workload struct {
+	// Name identifies the workload, in the benchmark names and the topics.
+	Name string
+	// Partitions is the number of partitions of the topic.
+	Partitions int32
+	// MessageSize is the size of the values of the messages, compressible
+	// like text is.
+	MessageSize int
+	// Compression is the codec the producer compresses the batches with.
+	Compression sarama.CompressionCodec
+	// FlushMessages is Producer.Flush.Messages, 0 to flush as fast as
+	// possible. The leftover messages are flushed every 10ms.
+	FlushMessages int
+}
+
+// Workloads are the standard workloads, run by the benchmarks in order.
+var Workloads = []Workload{
+	{Name: "small", Partitions: 8, MessageSize: 128},
+	{Name: "medium", Partitions: 8, MessageSize: 1024},
+	{Name: "large", Partitions: 8, MessageSize: 16 * 1024},
+	{Name: "small-single-partition", Partitions: 1, MessageSize: 128},
+	{Name: "medium-batched", Partitions: 8, MessageSize: 1024, FlushMessages: 500},
+	{Name: "medium-snappy", Partitions: 8, MessageSize: 1024, Compression: sarama.CompressionSnappy},
+	{Name: "medium-lz4", Partitions: 8, MessageSize: 1024, Compression: sarama.CompressionLZ4},
+	{Name: "medium-zstd", Partitions: 8, MessageSize: 1024, Compression: sarama.CompressionZSTD},
+}
+
+// Topic is the topic of the workload.
+func (w Workload) Topic() string {
+	return "sarama-benchmarks-" + w.Name
+}
+
+// Config returns the configuration of the clients running the workload.
+func (w Workload) Config() *sarama.Config {
+	conf := sarama.NewConfig()
+	conf.ClientID = "sarama-benchmarks"
+	conf.Version = sarama.V2_1_0_0
+	conf.Producer.Return.Successes = true
+	conf.Producer.Compression = w.Compression
+	if w.FlushMessages > 0 {
+		conf.Producer.Flush.Messages = w.FlushMessages
+		conf.Producer.Flush.Frequency = 10 * time.Millisecond
+	}
+	conf.Consumer.Return.Errors = true
+	return conf
+}
+
+// Value returns the value of the messages of the workload.
+func (w Workload) Value() []byte {
+	text := []byte("The quick brown fox jumps over the lazy dog, 0123456789. ")
+	value := bytes.Repeat(text, w.MessageSize/len(text)+1)
+	return value[:w.MessageSize]
+}
+
+// Message returns the i-th message of the workload, its key spreading the
+// messages over the partitions.
+func (w Workload) Message(i int, value []byte) *sarama.ProducerMessage {
+	return &sarama.ProducerMessage{
+		Topic: w.Topic(),
+		Key:   sarama.StringEncoder(strconv.Itoa(i)),
+		Value: sarama.ByteEncoder(value),
+	}
+}
@@ -1,11 +1,15 @@
 package sarama
 
 import (
+	"context"
 	"crypto/tls"
 	"encoding/binary"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"math/rand"
 	"net"
 	"sort"
 	"strconv"
@@ -32,27 +36,70 @@ type Broker struct {
 	responses     chan *responsePromise
 	done          chan bool
 
-	registeredMetrics []string
-
-	incomingByteRate       metrics.Meter
-	requestRate            metrics.Meter
-	requestSize            metrics.Histogram
-	requestLatency         metrics.Histogram
-	outgoingByteRate       metrics.Meter
-	responseRate           metrics.Meter
-	responseSize           metrics.Histogram
-	requestsInFlight       metrics.Counter
-	brokerIncomingByteRate metrics.Meter
-	brokerRequestRate      metrics.Meter
-	brokerRequestSize      metrics.Histogram
-	brokerRequestLatency   metrics.Histogram
-	brokerOutgoingByteRate metrics.Meter
-	brokerResponseRate     metrics.Meter
-	brokerResponseSize     metrics.Histogram
-	brokerRequestsInFlight metrics.Counter
-	brokerThrottleTime     metrics.Histogram
+	registeredMetrics []registeredMetric
+
+	// advertisedAddr is the address the broker advertised, when it differs
+	// from the address of the listener selected by Net.Listeners
+	advertisedAddr string
+
+	incomingByteRate       MetricsCounter
+	requestRate            MetricsCounter
+	requestSize            MetricsHistogram
+	requestLatency         MetricsHistogram
+	outgoingByteRate       MetricsCounter
+	responseRate           MetricsCounter
+	responseSize           MetricsHistogram
+	requestsInFlight       MetricsGauge
+	crcErrors              MetricsCounter
+	brokerIncomingByteRate MetricsCounter
+	brokerRequestRate      MetricsCounter
+	brokerRequestSize      MetricsHistogram
+	brokerRequestLatency   MetricsHistogram
+	brokerOutgoingByteRate MetricsCounter
+	brokerResponseRate     MetricsCounter
+	brokerResponseSize     MetricsHistogram
+	brokerRequestsInFlight MetricsGauge
+	brokerThrottleTime     MetricsHistogram
+	brokerThrottleWait     MetricsHistogram
+	brokerConnectionAge    MetricsGauge
+	// apiMetrics are the metrics of the requests to the broker by API key,
+	// registered once the first request of the API is sent
+	apiMetrics map[int16]*apiMetrics
 
 	kerberosAuthenticator GSSAPIKerberosAuth
+
+	// inFlight counts the requests whose responses have not been handled yet
+	inFlight sync.WaitGroup
+	// pendingRequests is the number of requests awaiting a response, which
+	// the client uses to pick the least loaded broker
+	pendingRequests int32
+	// sessionReauthenticationTime is when the SASL session of the connection
+	// must be renewed, zero if the broker never expires it
+	sessionReauthenticationTime time.Time
+	// connectedAt is when the connection was established, zero while
+	// connecting
+	connectedAt time.Time
+	// reconnecting is set once the broker connected, to count its
+	// reconnections
+	reconnecting bool
+
+	// stateLock guards state, which is read while lock is held by a
+	// connection attempt
+	stateLock sync.Mutex
+	state     BrokerConnectionState
+
+	throttleLock sync.Mutex
+	// throttleUntil is when the broker stops throttling the client, which
+	// holds back its requests until then
+	throttleUntil time.Time
+
+	// events is the event bus of the client which registered the broker, if
+	// any
+	events *eventBus
+	// coordinatorConnection is set on the Broker a client dedicates to
+	// talking to a group coordinator, whose metrics must not be mixed up
+	// with, nor unregistered along with, those of the regular Broker
+	coordinatorConnection bool
 }
 
 // SASLMechanism specifies the SASL mechanism the client uses to authenticate with the broker
@@ -68,6 +115,9 @@ const (
 	// SASLTypeSCRAMSHA512 represents the SCRAM-SHA-512 mechanism.
 	SASLTypeSCRAMSHA512 = "SCRAM-SHA-512"
 	SASLTypeGSSAPI      = "GSSAPI"
+	// SASLTypeAWSMSKIAM represents the AWS_MSK_IAM mechanism of Amazon MSK
+	// IAM access control.
+	SASLTypeAWSMSKIAM = "AWS_MSK_IAM"
 	// SASLHandshakeV0 is v0 of the Kafka SASL handshake protocol. Client and
 	// server negotiate SASL auth using opaque packets.
 	SASLHandshakeV0 = int16(0)
@@ -120,15 +170,26 @@ type SCRAMClient interface {
 
 type responsePromise struct {
 	requestTime   time.Time
+	apiKey        int16
 	correlationID int32
 	headerVersion int16
+	apiMetrics    *apiMetrics
+	wireTap       *WireTapRecord
 	handler       func([]byte, error)
 	packets       chan []byte
 	errors        chan error
+	// decoder, when set rather than handler, decodes the response and
+	// returns the callback to hand it over with, which is called in the
+	// order of the requests even if the responses are decoded concurrently
+	decoder func([]byte, error) func()
 }
 
 func (p *responsePromise) handle(packets []byte, err error) {
 	// Use callback when provided
+	if p.decoder != nil {
+		p.decoder(packets, err)()
+		return
+	}
 	if p.handler != nil {
 		p.handler(packets, err)
 		return
@@ -153,8 +214,39 @@ func NewBroker(addr string) *Broker {
 // follow it by a call to Connected(). The only errors Open will return directly are ConfigurationError or
 // AlreadyConnected. If conf is nil, the result of NewConfig() is used.
 func (b *Broker) Open(conf *Config) error {
+	_, err := b.open(context.Background(), conf)
+	return err
+}
+
+// OpenContext is Open, but waits for the connection attempt to complete,
+// including the SASL authentication and the ApiVersionsRequest, and returns its
+// error. If ctx is done first, the attempt is abandoned, the broker closed and
+// the error of ctx returned.
+func (b *Broker) OpenContext(ctx context.Context, conf *Config) error {
+	opened, err := b.open(ctx, conf)
+	if err != nil {
+		return err
+	}
+
+	select {
+	case <-opened:
+		b.lock.Lock()
+		defer b.lock.Unlock()
+		return b.connErr
+	case <-ctx.Done():
+		go withRecover(func() {
+			<-opened
+			_ = b.Close()
+		})
+		return ctx.Err()
+	}
+}
+
+// open starts connecting to the broker, dialing and authenticating until ctx
+// is done, and returns a channel closed once the attempt completes.
+func (b *Broker) open(ctx context.Context, conf *Config) (<-chan none, error) {
 	if !atomic.CompareAndSwapInt32(&b.opened, 0, 1) {
-		return ErrAlreadyConnected
+		return nil, ErrAlreadyConnected
 	}
 
 	if conf == nil {
@@ -163,15 +255,25 @@ func (b *Broker) Open(conf *Config) error {
 
 	err := conf.Validate()
 	if err != nil {
-		return err
+		return nil, err
+	}
+	conf, err = conf.forBroker(b.addr)
+	if err != nil {
+		atomic.StoreInt32(&b.opened, 0)
+		return nil, err
 	}
 
 	usingApiVersionsRequests := conf.Version.IsAtLeast(V2_4_0_0) && conf.ApiVersionsRequest
 
 	b.lock.Lock()
+	b.updateState(func(state *BrokerConnectionState) {
+		*state = BrokerConnectionState{State: BrokerConnecting, LastError: state.LastError}
+	})
 
+	opened := make(chan none)
 	go withRecover(func() {
 		defer func() {
+			defer close(opened)
 			b.lock.Unlock()
 
 			// Send an ApiVersionsRequest to identify the client (KIP-511).
@@ -184,34 +286,49 @@ func (b *Broker) Open(conf *Config) error {
 					ClientSoftwareVersion: version(),
 				})
 				if err != nil {
-					Logger.Printf("Error while sending ApiVersionsRequest to broker %s: %s\n", b.addr, err)
+					b.log().warnf("Error while sending ApiVersionsRequest to broker %s: %s\n", b.addr, err)
 				}
 			}
 		}()
-		dialer := conf.getDialer()
-		b.conn, b.connErr = dialer.Dial("tcp", b.addr)
+		b.conn, b.connErr = conf.dialContext(ctx, b.addr)
 		if b.connErr != nil {
-			Logger.Printf("Failed to connect to broker %s: %s\n", b.addr, b.connErr)
+			b.log().warnf("Failed to connect to broker %s: %s\n", b.addr, b.connErr)
 			b.conn = nil
 			atomic.StoreInt32(&b.opened, 0)
+			b.connectionFailed(b.connErr)
+			b.events.publish(&ClientEvent{Type: EventBrokerConnectFailed, Broker: b, Err: b.connErr})
 			return
 		}
+		b.updateState(func(state *BrokerConnectionState) {
+			state.ConnectionID = connectionID(b.conn)
+		})
 		if conf.Net.TLS.Enable {
-			b.conn = tls.Client(b.conn, validServerNameTLS(b.addr, conf.Net.TLS.Config))
+			b.connErr = b.handshakeTLS(ctx, conf)
+			if b.connErr != nil {
+				b.log().warnf("Failed the TLS handshake with broker %s: %s\n", b.addr, b.connErr)
+				_ = b.conn.Close()
+				b.conn = nil
+				atomic.StoreInt32(&b.opened, 0)
+				b.connectionFailed(b.connErr)
+				b.events.publish(&ClientEvent{Type: EventBrokerConnectFailed, Broker: b, Err: b.connErr})
+				return
+			}
 		}
 
 		b.conn = newBufConn(b.conn)
 		b.conf = conf
 
 		// Create or reuse the global metrics shared between brokers
-		b.incomingByteRate = metrics.GetOrRegisterMeter("incoming-byte-rate", conf.MetricRegistry)
-		b.requestRate = metrics.GetOrRegisterMeter("request-rate", conf.MetricRegistry)
-		b.requestSize = getOrRegisterHistogram("request-size", conf.MetricRegistry)
-		b.requestLatency = getOrRegisterHistogram("request-latency-in-ms", conf.MetricRegistry)
-		b.outgoingByteRate = metrics.GetOrRegisterMeter("outgoing-byte-rate", conf.MetricRegistry)
-		b.responseRate = metrics.GetOrRegisterMeter("response-rate", conf.MetricRegistry)
-		b.responseSize = getOrRegisterHistogram("response-size", conf.MetricRegistry)
-		b.requestsInFlight = metrics.GetOrRegisterCounter("requests-in-flight", conf.MetricRegistry)
+		recorder := conf.metricsRecorder()
+		b.incomingByteRate = recorder.Counter("incoming-byte-rate", nil)
+		b.requestRate = recorder.Counter("request-rate", nil)
+		b.requestSize = recorder.Histogram("request-size", nil)
+		b.requestLatency = recorder.Histogram("request-latency-in-ms", nil)
+		b.outgoingByteRate = recorder.Counter("outgoing-byte-rate", nil)
+		b.responseRate = recorder.Counter("response-rate", nil)
+		b.crcErrors = recorder.Counter("consumer-crc-errors", nil)
+		b.responseSize = recorder.Histogram("response-size", nil)
+		b.requestsInFlight = recorder.Gauge("requests-in-flight", nil)
 		// Do not gather metrics for seeded broker (only used during bootstrap) because they share
 		// the same id (-1) and are already exposed through the global metrics above
 		if b.id >= 0 && !metrics.UseNilMetrics {
@@ -219,17 +336,24 @@ func (b *Broker) Open(conf *Config) error {
 		}
 
 		if conf.Net.SASL.Enable {
+			stop := interruptOnDone(ctx, b.conn)
 			b.connErr = b.authenticateViaSASL()
+			if stop() {
+				b.connErr = ctx.Err()
+			}
 
 			if b.connErr != nil {
 				err = b.conn.Close()
 				if err == nil {
-					DebugLogger.Printf("Closed connection to broker %s\n", b.addr)
+					b.log().debugf("Closed connection to broker %s\n", b.addr)
 				} else {
-					Logger.Printf("Error while closing connection to broker %s: %s\n", b.addr, err)
+					b.log().warnf("Error while closing connection to broker %s: %s\n", b.addr, err)
 				}
 				b.conn = nil
 				atomic.StoreInt32(&b.opened, 0)
+				b.connectionFailed(b.connErr)
+				b.recordConnectionMetric(conf, "failed-authentication-rate", 1)
+				b.events.publish(&ClientEvent{Type: EventAuthenticationFailed, Broker: b, Err: b.connErr})
 				return
 			}
 		}
@@ -238,14 +362,73 @@ func (b *Broker) Open(conf *Config) error {
 		b.responses = make(chan *responsePromise, b.conf.Net.MaxOpenRequests-1)
 
 		if b.id >= 0 {
-			DebugLogger.Printf("Connected to broker at %s (registered as #%d)\n", b.addr, b.id)
+			b.log().debugf("Connected to broker at %s (registered as #%d)\n", b.addr, b.id)
 		} else {
-			DebugLogger.Printf("Connected to broker at %s (unregistered)\n", b.addr)
+			b.log().debugf("Connected to broker at %s (unregistered)\n", b.addr)
 		}
 		go withRecover(b.responseReceiver)
+		if b.reconnecting {
+			b.recordConnectionMetric(conf, "reconnect-rate", 1)
+		}
+		b.reconnecting = true
+		b.connectedAt = time.Now()
+		b.updateState(func(state *BrokerConnectionState) {
+			*state = BrokerConnectionState{
+				State:         BrokerConnected,
+				Authenticated: conf.Net.SASL.Enable,
+				ConnectedAt:   b.connectedAt,
+				ConnectionID:  state.ConnectionID,
+			}
+		})
+		b.events.publish(&ClientEvent{Type: EventBrokerConnected, Broker: b})
 	})
 
-	return nil
+	return opened, nil
+}
+
+// handshakeTLS wraps the connection to the broker in TLS and completes the
+// handshake, within Net.DialTimeout, recording how long it took.
+func (b *Broker) handshakeTLS(ctx context.Context, conf *Config) error {
+	conn := tls.Client(b.conn, validServerNameTLS(b.addr, conf.Net.TLS.Config))
+	start := time.Now()
+	if err := conn.SetDeadline(start.Add(conf.Net.DialTimeout)); err != nil {
+		return err
+	}
+	stop := interruptOnDone(ctx, conn)
+	err := conn.Handshake()
+	if stop() {
+		err = ctx.Err()
+	}
+	if err != nil {
+		return err
+	}
+	b.recordConnectionMetric(conf, "tls-handshake-time-in-ms", int64(time.Since(start)/time.Millisecond))
+	b.conn = conn
+	return conn.SetDeadline(time.Time{})
+}
+
+// interruptOnDone closes conn if ctx is done before the returned stop is
+// called, stop reporting whether it was.
+func interruptOnDone(ctx context.Context, conn net.Conn) (stop func() bool) {
+	if ctx.Done() == nil {
+		return func() bool { return false }
+	}
+
+	stopped := make(chan none)
+	interrupted := make(chan bool, 1)
+	go withRecover(func() {
+		select {
+		case <-ctx.Done():
+			_ = conn.Close()
+			interrupted <- true
+		case <-stopped:
+			interrupted <- false
+		}
+	})
+	return func() bool {
+		close(stopped)
+		return <-interrupted
+	}
 }
 
 // Connected returns true if the broker is connected and false otherwise. If the broker is not
@@ -275,6 +458,148 @@ func (b *Broker) TLSConnectionState() (state tls.ConnectionState, ok bool) {
 	return state, false
 }
 
+// BrokerState is the state of the connection to a broker.
+type BrokerState int
+
+const (
+	// BrokerClosed is the state of a broker never opened, closed, or whose
+	// last connection attempt failed.
+	BrokerClosed BrokerState = iota
+	// BrokerConnecting is the state of a broker being dialed or authenticated.
+	BrokerConnecting
+	// BrokerConnected is the state of a broker ready to send requests.
+	BrokerConnected
+)
+
+func (s BrokerState) String() string {
+	switch s {
+	case BrokerClosed:
+		return "closed"
+	case BrokerConnecting:
+		return "connecting"
+	case BrokerConnected:
+		return "connected"
+	default:
+		return fmt.Sprintf("BrokerState(%d)", int(s))
+	}
+}
+
+// BrokerConnectionState is a snapshot of the connection to a broker, as
+// returned by Broker.ConnectionState.
+type BrokerConnectionState struct {
+	// State is whether the broker is connecting, connected or closed.
+	State BrokerState
+	// Authenticated is whether the connection was authenticated with SASL.
+	Authenticated bool
+	// ConnectedAt is when the connection was established, zero unless
+	// connected.
+	ConnectedAt time.Time
+	// LastError is the error of the last connection attempt, nil if it
+	// succeeded. It is kept once the broker is closed, to inspect why it
+	// could not connect.
+	LastError error
+	// ConnectionID identifies the connection as the broker does in the
+	// connection field of its request logs: the addresses of the broker and of
+	// the client, "<broker host:port>-<client host:port>", which the broker
+	// follows with a sequence number. It is empty unless connecting or
+	// connected.
+	ConnectionID string
+	// ApiVersions are the versions of the APIs supported by the broker, by API
+	// key, as last returned by ApiVersions on the connection, which Open
+	// requests with Config.ApiVersionsRequest from V2_4_0_0. It is nil until
+	// then.
+	ApiVersions map[int16]ApiVersionsResponseKey
+}
+
+// ConnectionState returns a snapshot of the connection to the broker. Unlike
+// Connected, it does not wait for a connection attempt in progress.
+func (b *Broker) ConnectionState() BrokerConnectionState {
+	b.stateLock.Lock()
+	defer b.stateLock.Unlock()
+
+	state := b.state
+	if state.ApiVersions != nil {
+		state.ApiVersions = make(map[int16]ApiVersionsResponseKey, len(b.state.ApiVersions))
+		for key, versions := range b.state.ApiVersions {
+			state.ApiVersions[key] = versions
+		}
+	}
+	return state
+}
+
+// ApiVersionRange returns the versions of the API apiKey supported by the
+// broker, as last returned by ApiVersions on the connection (see
+// BrokerConnectionState.ApiVersions). ok is false if the broker does not
+// support the API, or if its versions are not known.
+func (b *Broker) ApiVersionRange(apiKey int16) (minVersion, maxVersion int16, ok bool) {
+	b.stateLock.Lock()
+	defer b.stateLock.Unlock()
+
+	versions, ok := b.state.ApiVersions[apiKey]
+	return versions.MinVersion, versions.MaxVersion, ok
+}
+
+// checkApiVersion returns an UnsupportedApiVersionError if the broker does not
+// support the version of rb, rather than let it fail the connection. Requests
+// are not checked until the broker returns its versions.
+func (b *Broker) checkApiVersion(rb protocolBody) error {
+	if _, ok := rb.(*ApiVersionsRequest); ok {
+		// brokers reply to any version, with the versions they support
+		return nil
+	}
+	key := rb.key()
+
+	b.stateLock.Lock()
+	apiVersions := b.state.ApiVersions
+	b.stateLock.Unlock()
+	if apiVersions == nil {
+		return nil
+	}
+
+	versions, ok := apiVersions[key]
+	if !ok {
+		versions.MinVersion, versions.MaxVersion = -1, -1
+	} else if rb.version() >= versions.MinVersion && rb.version() <= versions.MaxVersion {
+		return nil
+	}
+	return UnsupportedApiVersionError{
+		ApiKey:     key,
+		ApiName:    apiName(key),
+		Version:    rb.version(),
+		MinVersion: versions.MinVersion,
+		MaxVersion: versions.MaxVersion,
+	}
+}
+
+func (b *Broker) updateState(update func(state *BrokerConnectionState)) {
+	b.stateLock.Lock()
+	defer b.stateLock.Unlock()
+	update(&b.state)
+}
+
+// connectionID returns the ConnectionID of the connection to the broker.
+func (b *Broker) connectionID() string {
+	b.stateLock.Lock()
+	defer b.stateLock.Unlock()
+	return b.state.ConnectionID
+}
+
+// connectionID identifies conn as the brokers do in their request logs, see
+// BrokerConnectionState.ConnectionID.
+func connectionID(conn net.Conn) string {
+	local, remote := conn.LocalAddr(), conn.RemoteAddr()
+	if local == nil || remote == nil {
+		return ""
+	}
+	return remote.String() + "-" + local.String()
+}
+
+func (b *Broker) connectionFailed(err error) {
+	b.updateState(func(state *BrokerConnectionState) {
+		*state = BrokerConnectionState{State: BrokerClosed, LastError: err}
+	})
+}
+
 // Close closes the broker resources
 func (b *Broker) Close() error {
 	b.lock.Lock()
@@ -293,16 +618,21 @@ func (b *Broker) Close() error {
 	b.connErr = nil
 	b.done = nil
 	b.responses = nil
+	b.connectedAt = time.Time{}
 
 	b.unregisterMetrics()
+	b.updateState(func(state *BrokerConnectionState) {
+		*state = BrokerConnectionState{State: BrokerClosed, LastError: state.LastError}
+	})
 
 	if err == nil {
-		DebugLogger.Printf("Closed connection to broker %s\n", b.addr)
+		b.log().debugf("Closed connection to broker %s\n", b.addr)
 	} else {
-		Logger.Printf("Error while closing connection to broker %s: %s\n", b.addr, err)
+		b.log().warnf("Error while closing connection to broker %s: %s\n", b.addr, err)
 	}
 
 	atomic.StoreInt32(&b.opened, 0)
+	b.events.publish(&ClientEvent{Type: EventBrokerDisconnected, Broker: b, Err: err})
 
 	return err
 }
@@ -328,9 +658,20 @@ func (b *Broker) Rack() string {
 	return *b.rack
 }
 
+// MarshalJSON encodes the broker as listed in the metadata, with its ID,
+// address and rack, e.g. to dump the responses listing brokers.
+func (b *Broker) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		ID   int32   `json:"id"`
+		Addr string  `json:"addr"`
+		Rack *string `json:"rack"`
+	}{b.id, b.addr, b.rack})
+}
+
 // GetMetadata send a metadata request and returns a metadata response or error
 func (b *Broker) GetMetadata(request *MetadataRequest) (*MetadataResponse, error) {
 	response := new(MetadataResponse)
+	response.Version = request.Version // needed to handle the two header versions
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -355,6 +696,7 @@ func (b *Broker) GetConsumerMetadata(request *ConsumerMetadataRequest) (*Consume
 // FindCoordinator sends a find coordinate request and returns a response or error
 func (b *Broker) FindCoordinator(request *FindCoordinatorRequest) (*FindCoordinatorResponse, error) {
 	response := new(FindCoordinatorResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -367,6 +709,7 @@ func (b *Broker) FindCoordinator(request *FindCoordinatorRequest) (*FindCoordina
 // GetAvailableOffsets return an offset response or error
 func (b *Broker) GetAvailableOffsets(request *OffsetRequest) (*OffsetResponse, error) {
 	response := new(OffsetResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -400,25 +743,27 @@ func (b *Broker) AsyncProduce(request *ProduceRequest, cb ProduceCallback) error
 	if needAcks {
 		// Create ProduceResponse early to provide the header version
 		res := new(ProduceResponse)
+		res.Version = request.Version
 		promise = &responsePromise{
 			headerVersion: res.headerVersion(),
-			// Packets will be converted to a ProduceResponse in the responseReceiver goroutine
-			handler: func(packets []byte, err error) {
+			// Packets will be converted to a ProduceResponse in the responseReceiver goroutine,
+			// or concurrently with Net.ResponseHandlers
+			decoder: func(packets []byte, err error) func() {
 				if err != nil {
 					// Failed request
-					cb(nil, err)
-					return
+					return func() { cb(nil, err) }
 				}
 
-				if err := versionedDecode(packets, res, request.version()); err != nil {
+				if err := b.decodeResponse(packets, res, request.version()); err != nil {
 					// Malformed response
-					cb(nil, err)
-					return
+					promise.apiMetrics.countError()
+					return func() { cb(nil, err) }
 				}
 
 				// Wellformed response
-				b.updateThrottleMetric(res.ThrottleTime)
-				cb(res, nil)
+				b.handleThrottledResponse(res, request.version(), promise.apiMetrics)
+				promise.apiMetrics.countResponseError(res)
+				return func() { cb(res, nil) }
 			},
 		}
 	}
@@ -437,8 +782,8 @@ func (b *Broker) Produce(request *ProduceRequest) (*ProduceResponse, error) {
 		err = b.sendAndReceive(request, nil)
 	} else {
 		response = new(ProduceResponse)
+		response.Version = request.Version
 		err = b.sendAndReceive(request, response)
-		b.updateThrottleMetric(response.ThrottleTime)
 	}
 
 	if err != nil {
@@ -451,6 +796,9 @@ func (b *Broker) Produce(request *ProduceRequest) (*ProduceResponse, error) {
 // Fetch returns a FetchResponse or error
 func (b *Broker) Fetch(request *FetchRequest) (*FetchResponse, error) {
 	response := new(FetchResponse)
+	response.Version = request.Version
+	response.buffers = request.buffers
+	response.ownBlocks = request.ownBlocks
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -463,6 +811,7 @@ func (b *Broker) Fetch(request *FetchRequest) (*FetchResponse, error) {
 // CommitOffset return an Offset commit response or error
 func (b *Broker) CommitOffset(request *OffsetCommitRequest) (*OffsetCommitResponse, error) {
 	response := new(OffsetCommitResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -488,6 +837,7 @@ func (b *Broker) FetchOffset(request *OffsetFetchRequest) (*OffsetFetchResponse,
 // JoinGroup returns a join group response or error
 func (b *Broker) JoinGroup(request *JoinGroupRequest) (*JoinGroupResponse, error) {
 	response := new(JoinGroupResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -500,6 +850,7 @@ func (b *Broker) JoinGroup(request *JoinGroupRequest) (*JoinGroupResponse, error
 // SyncGroup returns a sync group response or error
 func (b *Broker) SyncGroup(request *SyncGroupRequest) (*SyncGroupResponse, error) {
 	response := new(SyncGroupResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -512,6 +863,7 @@ func (b *Broker) SyncGroup(request *SyncGroupRequest) (*SyncGroupResponse, error
 // LeaveGroup return a leave group response or error
 func (b *Broker) LeaveGroup(request *LeaveGroupRequest) (*LeaveGroupResponse, error) {
 	response := new(LeaveGroupResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -524,6 +876,7 @@ func (b *Broker) LeaveGroup(request *LeaveGroupRequest) (*LeaveGroupResponse, er
 // Heartbeat returns a heartbeat response or error
 func (b *Broker) Heartbeat(request *HeartbeatRequest) (*HeartbeatResponse, error) {
 	response := new(HeartbeatResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -536,6 +889,7 @@ func (b *Broker) Heartbeat(request *HeartbeatRequest) (*HeartbeatResponse, error
 // ListGroups return a list group response or error
 func (b *Broker) ListGroups(request *ListGroupsRequest) (*ListGroupsResponse, error) {
 	response := new(ListGroupsResponse)
+	response.Version = request.Version // needed to handle the two header versions
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -548,6 +902,7 @@ func (b *Broker) ListGroups(request *ListGroupsRequest) (*ListGroupsResponse, er
 // DescribeGroups return describe group response or error
 func (b *Broker) DescribeGroups(request *DescribeGroupsRequest) (*DescribeGroupsResponse, error) {
 	response := new(DescribeGroupsResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -560,18 +915,32 @@ func (b *Broker) DescribeGroups(request *DescribeGroupsRequest) (*DescribeGroups
 // ApiVersions return api version response or error
 func (b *Broker) ApiVersions(request *ApiVersionsRequest) (*ApiVersionsResponse, error) {
 	response := new(ApiVersionsResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
 		return nil, err
 	}
 
+	if KError(response.ErrorCode) == ErrNoError {
+		apiVersions := make(map[int16]ApiVersionsResponseKey, len(response.ApiKeys))
+		for _, key := range response.ApiKeys {
+			apiVersions[key.ApiKey] = key
+		}
+		b.updateState(func(state *BrokerConnectionState) {
+			if state.State == BrokerConnected {
+				state.ApiVersions = apiVersions
+			}
+		})
+	}
+
 	return response, nil
 }
 
 // CreateTopics send a create topic request and returns create topic response
 func (b *Broker) CreateTopics(request *CreateTopicsRequest) (*CreateTopicsResponse, error) {
 	response := new(CreateTopicsResponse)
+	response.Version = request.Version // needed to handle the two header versions
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -584,6 +953,7 @@ func (b *Broker) CreateTopics(request *CreateTopicsRequest) (*CreateTopicsRespon
 // DeleteTopics sends a delete topic request and returns delete topic response
 func (b *Broker) DeleteTopics(request *DeleteTopicsRequest) (*DeleteTopicsResponse, error) {
 	response := new(DeleteTopicsResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -597,6 +967,7 @@ func (b *Broker) DeleteTopics(request *DeleteTopicsRequest) (*DeleteTopicsRespon
 // partitions response or error
 func (b *Broker) CreatePartitions(request *CreatePartitionsRequest) (*CreatePartitionsResponse, error) {
 	response := new(CreatePartitionsResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -610,6 +981,7 @@ func (b *Broker) CreatePartitions(request *CreatePartitionsRequest) (*CreatePart
 // returns alter partition reassignments response
 func (b *Broker) AlterPartitionReassignments(request *AlterPartitionReassignmentsRequest) (*AlterPartitionReassignmentsResponse, error) {
 	response := new(AlterPartitionReassignmentsResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -623,6 +995,91 @@ func (b *Broker) AlterPartitionReassignments(request *AlterPartitionReassignment
 // returns list partition reassignments response
 func (b *Broker) ListPartitionReassignments(request *ListPartitionReassignmentsRequest) (*ListPartitionReassignmentsResponse, error) {
 	response := new(ListPartitionReassignmentsResponse)
+	response.Version = request.Version
+
+	err := b.sendAndReceive(request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// ElectLeaders sends an elect leaders request and returns an elect leaders
+// response or error
+func (b *Broker) ElectLeaders(request *ElectLeadersRequest) (*ElectLeadersResponse, error) {
+	response := new(ElectLeadersResponse)
+	response.Version = request.Version // needed to handle the two header versions
+
+	err := b.sendAndReceive(request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// DescribeTopicPartitions sends a describe topic partitions request and
+// returns a describe topic partitions response or error
+func (b *Broker) DescribeTopicPartitions(request *DescribeTopicPartitionsRequest) (*DescribeTopicPartitionsResponse, error) {
+	response := new(DescribeTopicPartitionsResponse)
+	response.Version = request.Version
+
+	err := b.sendAndReceive(request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// GetTelemetrySubscriptions sends a get telemetry subscriptions request and
+// returns a get telemetry subscriptions response or error
+func (b *Broker) GetTelemetrySubscriptions(request *GetTelemetrySubscriptionsRequest) (*GetTelemetrySubscriptionsResponse, error) {
+	response := new(GetTelemetrySubscriptionsResponse)
+	response.Version = request.Version
+
+	err := b.sendAndReceive(request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// PushTelemetry sends a push telemetry request and returns a push telemetry
+// response or error
+func (b *Broker) PushTelemetry(request *PushTelemetryRequest) (*PushTelemetryResponse, error) {
+	response := new(PushTelemetryResponse)
+	response.Version = request.Version
+
+	err := b.sendAndReceive(request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// DescribeCluster sends a describe cluster request and returns a describe
+// cluster response or error
+func (b *Broker) DescribeCluster(request *DescribeClusterRequest) (*DescribeClusterResponse, error) {
+	response := new(DescribeClusterResponse)
+	response.Version = request.Version
+
+	err := b.sendAndReceive(request, response)
+	if err != nil {
+		return nil, err
+	}
+
+	return response, nil
+}
+
+// DescribeProducers sends a describe producers request and returns a
+// describe producers response or error
+func (b *Broker) DescribeProducers(request *DescribeProducersRequest) (*DescribeProducersResponse, error) {
+	response := new(DescribeProducersResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -636,6 +1093,7 @@ func (b *Broker) ListPartitionReassignments(request *ListPartitionReassignmentsR
 // response or error
 func (b *Broker) DeleteRecords(request *DeleteRecordsRequest) (*DeleteRecordsResponse, error) {
 	response := new(DeleteRecordsResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -648,6 +1106,7 @@ func (b *Broker) DeleteRecords(request *DeleteRecordsRequest) (*DeleteRecordsRes
 // DescribeAcls sends a describe acl request and returns a response or error
 func (b *Broker) DescribeAcls(request *DescribeAclsRequest) (*DescribeAclsResponse, error) {
 	response := new(DescribeAclsResponse)
+	response.Version = int16(request.Version)
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -660,6 +1119,7 @@ func (b *Broker) DescribeAcls(request *DescribeAclsRequest) (*DescribeAclsRespon
 // CreateAcls sends a create acl request and returns a response or error
 func (b *Broker) CreateAcls(request *CreateAclsRequest) (*CreateAclsResponse, error) {
 	response := new(CreateAclsResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -683,6 +1143,7 @@ func (b *Broker) CreateAcls(request *CreateAclsRequest) (*CreateAclsResponse, er
 // DeleteAcls sends a delete acl request and returns a response or error
 func (b *Broker) DeleteAcls(request *DeleteAclsRequest) (*DeleteAclsResponse, error) {
 	response := new(DeleteAclsResponse)
+	response.Version = int16(request.Version)
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -695,6 +1156,7 @@ func (b *Broker) DeleteAcls(request *DeleteAclsRequest) (*DeleteAclsResponse, er
 // InitProducerID sends an init producer request and returns a response or error
 func (b *Broker) InitProducerID(request *InitProducerIDRequest) (*InitProducerIDResponse, error) {
 	response := new(InitProducerIDResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -708,6 +1170,7 @@ func (b *Broker) InitProducerID(request *InitProducerIDRequest) (*InitProducerID
 // a response or error
 func (b *Broker) AddPartitionsToTxn(request *AddPartitionsToTxnRequest) (*AddPartitionsToTxnResponse, error) {
 	response := new(AddPartitionsToTxnResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -721,6 +1184,7 @@ func (b *Broker) AddPartitionsToTxn(request *AddPartitionsToTxnRequest) (*AddPar
 // or error
 func (b *Broker) AddOffsetsToTxn(request *AddOffsetsToTxnRequest) (*AddOffsetsToTxnResponse, error) {
 	response := new(AddOffsetsToTxnResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -733,6 +1197,7 @@ func (b *Broker) AddOffsetsToTxn(request *AddOffsetsToTxnRequest) (*AddOffsetsTo
 // EndTxn sends a request to end txn and returns a response or error
 func (b *Broker) EndTxn(request *EndTxnRequest) (*EndTxnResponse, error) {
 	response := new(EndTxnResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -746,6 +1211,7 @@ func (b *Broker) EndTxn(request *EndTxnRequest) (*EndTxnResponse, error) {
 // a response or error
 func (b *Broker) TxnOffsetCommit(request *TxnOffsetCommitRequest) (*TxnOffsetCommitResponse, error) {
 	response := new(TxnOffsetCommitResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -759,6 +1225,7 @@ func (b *Broker) TxnOffsetCommit(request *TxnOffsetCommitRequest) (*TxnOffsetCom
 // error
 func (b *Broker) DescribeConfigs(request *DescribeConfigsRequest) (*DescribeConfigsResponse, error) {
 	response := new(DescribeConfigsResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -771,6 +1238,7 @@ func (b *Broker) DescribeConfigs(request *DescribeConfigsRequest) (*DescribeConf
 // AlterConfigs sends a request to alter config and return a response or error
 func (b *Broker) AlterConfigs(request *AlterConfigsRequest) (*AlterConfigsResponse, error) {
 	response := new(AlterConfigsResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -783,6 +1251,7 @@ func (b *Broker) AlterConfigs(request *AlterConfigsRequest) (*AlterConfigsRespon
 // IncrementalAlterConfigs sends a request to incremental alter config and return a response or error
 func (b *Broker) IncrementalAlterConfigs(request *IncrementalAlterConfigsRequest) (*IncrementalAlterConfigsResponse, error) {
 	response := new(IncrementalAlterConfigsResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -795,6 +1264,7 @@ func (b *Broker) IncrementalAlterConfigs(request *IncrementalAlterConfigsRequest
 // DeleteGroups sends a request to delete groups and returns a response or error
 func (b *Broker) DeleteGroups(request *DeleteGroupsRequest) (*DeleteGroupsResponse, error) {
 	response := new(DeleteGroupsResponse)
+	response.Version = request.Version
 
 	if err := b.sendAndReceive(request, response); err != nil {
 		return nil, err
@@ -817,6 +1287,7 @@ func (b *Broker) DeleteOffsets(request *DeleteOffsetsRequest) (*DeleteOffsetsRes
 // DescribeLogDirs sends a request to get the broker's log dir paths and sizes
 func (b *Broker) DescribeLogDirs(request *DescribeLogDirsRequest) (*DescribeLogDirsResponse, error) {
 	response := new(DescribeLogDirsResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -829,6 +1300,7 @@ func (b *Broker) DescribeLogDirs(request *DescribeLogDirsRequest) (*DescribeLogD
 // DescribeUserScramCredentials sends a request to get SCRAM users
 func (b *Broker) DescribeUserScramCredentials(req *DescribeUserScramCredentialsRequest) (*DescribeUserScramCredentialsResponse, error) {
 	res := new(DescribeUserScramCredentialsResponse)
+	res.Version = req.Version
 
 	err := b.sendAndReceive(req, res)
 	if err != nil {
@@ -840,6 +1312,7 @@ func (b *Broker) DescribeUserScramCredentials(req *DescribeUserScramCredentialsR
 
 func (b *Broker) AlterUserScramCredentials(req *AlterUserScramCredentialsRequest) (*AlterUserScramCredentialsResponse, error) {
 	res := new(AlterUserScramCredentialsResponse)
+	res.Version = req.Version
 
 	err := b.sendAndReceive(req, res)
 	if err != nil {
@@ -852,6 +1325,7 @@ func (b *Broker) AlterUserScramCredentials(req *AlterUserScramCredentialsRequest
 // DescribeClientQuotas sends a request to get the broker's quotas
 func (b *Broker) DescribeClientQuotas(request *DescribeClientQuotasRequest) (*DescribeClientQuotasResponse, error) {
 	response := new(DescribeClientQuotasResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -864,6 +1338,7 @@ func (b *Broker) DescribeClientQuotas(request *DescribeClientQuotasRequest) (*De
 // AlterClientQuotas sends a request to alter the broker's quotas
 func (b *Broker) AlterClientQuotas(request *AlterClientQuotasRequest) (*AlterClientQuotasResponse, error) {
 	response := new(AlterClientQuotasResponse)
+	response.Version = request.Version
 
 	err := b.sendAndReceive(request, response)
 	if err != nil {
@@ -893,6 +1368,39 @@ func (b *Broker) write(buf []byte) (n int, err error) {
 	return b.conn.Write(buf)
 }
 
+// writeBuffers writes the buffers of a request with a single writev where
+// the connection supports it, setting up the WriteDeadline as write does.
+func (b *Broker) writeBuffers(bufs net.Buffers) (n int, err error) {
+	if len(bufs) == 1 {
+		return b.write(bufs[0])
+	}
+	if err := b.conn.SetWriteDeadline(time.Now().Add(b.conf.Net.WriteTimeout)); err != nil {
+		return 0, err
+	}
+
+	var w io.Writer = b.conn
+	if bc, ok := b.conn.(*bufConn); ok {
+		// net.Buffers only uses writev on the connections of package net
+		w = bc.Conn
+	}
+	written, err := bufs.WriteTo(w)
+	return int(written), err
+}
+
+// encodeRequest encodes req, the records of the produce requests being
+// referenced by the buffers returned rather than copied.
+func (b *Broker) encodeRequest(req *request) (net.Buffers, error) {
+	if _, ok := req.body.(*ProduceRequest); ok {
+		bufs, _, err := encodeVectored(req, b.conf.metricsRecorder())
+		return bufs, err
+	}
+	buf, err := encode(req, b.conf.metricsRecorder())
+	if err != nil {
+		return nil, err
+	}
+	return net.Buffers{buf}, nil
+}
+
 func (b *Broker) send(rb protocolBody, promiseResponse bool, responseHeaderVersion int16) (*responsePromise, error) {
 	var promise *responsePromise
 	if promiseResponse {
@@ -913,6 +1421,10 @@ func (b *Broker) send(rb protocolBody, promiseResponse bool, responseHeaderVersi
 }
 
 func (b *Broker) sendWithPromise(rb protocolBody, promise *responsePromise) error {
+	if err := b.waitIfThrottled(); err != nil {
+		return err
+	}
+
 	b.lock.Lock()
 	defer b.lock.Unlock()
 
@@ -926,32 +1438,55 @@ func (b *Broker) sendWithPromise(rb protocolBody, promise *responsePromise) erro
 	if !b.conf.Version.IsAtLeast(rb.requiredVersion()) {
 		return ErrUnsupportedVersion
 	}
+	if err := b.checkApiVersion(rb); err != nil {
+		return err
+	}
+
+	b.conf.RetryBudget.RecordRequest()
+
+	if !b.sessionReauthenticationTime.IsZero() && time.Now().After(b.sessionReauthenticationTime) {
+		if err := b.reauthenticate(); err != nil {
+			return err
+		}
+	}
 
 	req := &request{correlationID: b.correlationID, clientID: b.conf.ClientID, body: rb}
-	buf, err := encode(req, b.conf.MetricRegistry)
+	bufs, err := b.encodeRequest(req)
 	if err != nil {
 		return err
 	}
 
+	apiMetrics := b.apiMetricsFor(rb.key())
 	requestTime := time.Now()
+	wireTap := b.newWireTapRecord(req, bufs, requestTime)
 	// Will be decremented in responseReceiver (except error or request with NoResponse)
 	b.addRequestInFlightMetrics(1)
-	bytes, err := b.write(buf)
+	bytes, err := b.writeBuffers(bufs)
 	b.updateOutgoingCommunicationMetrics(bytes)
 	if err != nil {
 		b.addRequestInFlightMetrics(-1)
+		apiMetrics.countError()
+		b.tap(wireTap, nil, err)
+		b.requestLog(rb.key(), req.correlationID).debugf("broker/%d failed to send %s request %d: %v\n", b.ID(), apiName(rb.key()), req.correlationID, err)
 		return err
 	}
 	b.correlationID++
 
 	if promise == nil {
 		// Record request latency without the response
-		b.updateRequestLatencyAndInFlightMetrics(time.Since(requestTime))
+		requestLatency := time.Since(requestTime)
+		b.updateRequestLatencyAndInFlightMetrics(requestLatency)
+		apiMetrics.observeLatency(requestLatency)
+		b.tap(wireTap, nil, nil)
 		return nil
 	}
 
 	promise.requestTime = requestTime
+	promise.apiKey = rb.key()
+	promise.apiMetrics = apiMetrics
+	promise.wireTap = wireTap
 	promise.correlationID = req.correlationID
+	b.inFlight.Add(1)
 	b.responses <- promise
 
 	return nil
@@ -974,19 +1509,51 @@ func (b *Broker) sendAndReceive(req protocolBody, res protocolBody) error {
 
 	select {
 	case buf := <-promise.packets:
-		return versionedDecode(buf, res, req.version())
+		if err := b.decodeResponse(buf, res, req.version()); err != nil {
+			promise.apiMetrics.countError()
+			return err
+		}
+		b.handleThrottledResponse(res, req.version(), promise.apiMetrics)
+		promise.apiMetrics.countResponseError(res)
+		return nil
 	case err = <-promise.errors:
 		return err
 	}
 }
 
+// decodeResponse decodes the response buf into res, ignoring the bytes trailing
+// it if the decoding is lenient, and decoding the record batches of a fetch
+// response as configured by Consumer.Fetch and Consumer.Zstd, within the
+// limits of Consumer.Fetch.
+func (b *Broker) decodeResponse(buf []byte, res versionedDecoder, version int16) error {
+	if fetch, ok := res.(*FetchResponse); ok {
+		fetch.decoding = recordsDecoding{
+			lazy:          b.conf.Consumer.Fetch.Lazy,
+			crcValidation: b.conf.Consumer.Fetch.CRCValidation,
+			crcErrors:     b.crcErrors,
+			lazyHeaders:   b.conf.Consumer.Fetch.LazyHeaders,
+			zstd:          b.conf.Consumer.Zstd,
+			limits:        newFetchLimits(b.conf),
+		}
+	}
+	if b.conf.Net.LenientDecoding {
+		return lenientVersionedDecode(buf, res, version)
+	}
+	return versionedDecode(buf, res, version)
+}
+
 func (b *Broker) decode(pd packetDecoder, version int16) (err error) {
 	b.id, err = pd.getInt32()
 	if err != nil {
 		return err
 	}
 
-	host, err := pd.getString()
+	var host string
+	if version >= 9 {
+		host, err = pd.getCompactString()
+	} else {
+		host, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
@@ -996,7 +1563,15 @@ func (b *Broker) decode(pd packetDecoder, version int16) (err error) {
 		return err
 	}
 
-	if version >= 1 {
+	if version >= 9 {
+		b.rack, err = pd.getCompactNullableString()
+		if err != nil {
+			return err
+		}
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	} else if version >= 1 {
 		b.rack, err = pd.getNullableString()
 		if err != nil {
 			return err
@@ -1024,14 +1599,24 @@ func (b *Broker) encode(pe packetEncoder, version int16) (err error) {
 
 	pe.putInt32(b.id)
 
-	err = pe.putString(host)
+	if version >= 9 {
+		err = pe.putCompactString(host)
+	} else {
+		err = pe.putString(host)
+	}
 	if err != nil {
 		return err
 	}
 
 	pe.putInt32(int32(port))
 
-	if version >= 1 {
+	if version >= 9 {
+		err = pe.putNullableCompactString(b.rack)
+		if err != nil {
+			return err
+		}
+		pe.putEmptyTaggedFieldArray()
+	} else if version >= 1 {
 		err = pe.putNullableString(b.rack)
 		if err != nil {
 			return err
@@ -1042,14 +1627,50 @@ func (b *Broker) encode(pe packetEncoder, version int16) (err error) {
 }
 
 func (b *Broker) responseReceiver() {
-	var dead error
+	var (
+		dead error
+		// handled are the responses in the order of the requests, each
+		// handed over once decoded when Net.ResponseHandlers is set, the
+		// size of its buffer bounding the responses decoded concurrently
+		handled   chan chan func()
+		delivered chan struct{}
+	)
+	handle := b.handleResponse
+	if b.conf.Net.ResponseHandlers > 0 {
+		handled = make(chan chan func(), b.conf.Net.ResponseHandlers)
+		delivered = make(chan struct{})
+		go withRecover(func() {
+			defer close(delivered)
+			for deliver := range handled {
+				(<-deliver)()
+			}
+		})
+		handle = func(response *responsePromise, packets []byte, err error) {
+			deliver := make(chan func(), 1)
+			handled <- deliver
+			if err != nil || response.decoder == nil {
+				deliver <- func() { b.handleResponse(response, packets, err) }
+				return
+			}
+			// decode the response while the next ones are read, its
+			// callback being called in order
+			go func() {
+				callback := func() {}
+				withRecover(func() { callback = response.decoder(packets, nil) })
+				deliver <- func() {
+					callback()
+					b.inFlight.Done()
+				}
+			}()
+		}
+	}
 
 	for response := range b.responses {
 		if dead != nil {
 			// This was previously incremented in send() and
 			// we are not calling updateIncomingCommunicationMetrics()
 			b.addRequestInFlightMetrics(-1)
-			response.handle(nil, dead)
+			handle(response, nil, dead)
 			continue
 		}
 
@@ -1058,10 +1679,11 @@ func (b *Broker) responseReceiver() {
 
 		bytesReadHeader, err := b.readFull(header)
 		requestLatency := time.Since(response.requestTime)
+		response.apiMetrics.observeLatency(requestLatency)
 		if err != nil {
 			b.updateIncomingCommunicationMetrics(bytesReadHeader, requestLatency)
 			dead = err
-			response.handle(nil, err)
+			handle(response, nil, err)
 			continue
 		}
 
@@ -1070,7 +1692,7 @@ func (b *Broker) responseReceiver() {
 		if err != nil {
 			b.updateIncomingCommunicationMetrics(bytesReadHeader, requestLatency)
 			dead = err
-			response.handle(nil, err)
+			handle(response, nil, err)
 			continue
 		}
 		if decodedHeader.correlationID != response.correlationID {
@@ -1078,7 +1700,8 @@ func (b *Broker) responseReceiver() {
 			// TODO if decoded ID < cur ID, discard until we catch up
 			// TODO if decoded ID > cur ID, save it so when cur ID catches up we have a response
 			dead = PacketDecodingError{fmt.Sprintf("correlation ID didn't match, wanted %d, got %d", response.correlationID, decodedHeader.correlationID)}
-			response.handle(nil, dead)
+			b.requestLog(response.apiKey, response.correlationID).warnf("broker/%d %s\n", b.ID(), dead)
+			handle(response, nil, dead)
 			continue
 		}
 
@@ -1087,15 +1710,34 @@ func (b *Broker) responseReceiver() {
 		b.updateIncomingCommunicationMetrics(bytesReadHeader+bytesReadBody, requestLatency)
 		if err != nil {
 			dead = err
-			response.handle(nil, err)
+			handle(response, nil, err)
 			continue
 		}
 
-		response.handle(buf, nil)
+		if response.wireTap != nil {
+			b.tap(response.wireTap, append(header, buf...), nil)
+		}
+		handle(response, buf, nil)
+	}
+	if handled != nil {
+		close(handled)
+		<-delivered
 	}
 	close(b.done)
 }
 
+// handleResponse hands the packets of a response, or the error reading it,
+// over to its promise.
+func (b *Broker) handleResponse(promise *responsePromise, packets []byte, err error) {
+	if err != nil {
+		promise.apiMetrics.countError()
+		b.tap(promise.wireTap, nil, err)
+		b.requestLog(promise.apiKey, promise.correlationID).debugf("broker/%d failed to read the response to %s request %d: %v\n", b.ID(), apiName(promise.apiKey), promise.correlationID, err)
+	}
+	promise.handle(packets, err)
+	b.inFlight.Done()
+}
+
 func getHeaderLength(headerVersion int16) int8 {
 	if headerVersion < 1 {
 		return 8
@@ -1106,6 +1748,8 @@ func getHeaderLength(headerVersion int16) int8 {
 }
 
 func (b *Broker) authenticateViaSASL() error {
+	b.sessionReauthenticationTime = time.Time{}
+
 	switch b.conf.Net.SASL.Mechanism {
 	case SASLTypeOAuth:
 		return b.sendAndReceiveSASLOAuth(b.conf.Net.SASL.TokenProvider)
@@ -1113,6 +1757,8 @@ func (b *Broker) authenticateViaSASL() error {
 		return b.sendAndReceiveSASLSCRAM()
 	case SASLTypeGSSAPI:
 		return b.sendAndReceiveKerberos()
+	case SASLTypeAWSMSKIAM:
+		return b.sendAndReceiveSASLAWSMSKIAM()
 	default:
 		return b.sendAndReceiveSASLPlainAuth()
 	}
@@ -1120,9 +1766,6 @@ func (b *Broker) authenticateViaSASL() error {
 
 func (b *Broker) sendAndReceiveKerberos() error {
 	b.kerberosAuthenticator.Config = &b.conf.Net.SASL.GSSAPI
-	if b.kerberosAuthenticator.NewKerberosClientFunc == nil {
-		b.kerberosAuthenticator.NewKerberosClientFunc = NewKerberosClient
-	}
 	return b.kerberosAuthenticator.Authorize(b)
 }
 
@@ -1130,7 +1773,7 @@ func (b *Broker) sendAndReceiveSASLHandshake(saslType SASLMechanism, version int
 	rb := &SaslHandshakeRequest{Mechanism: string(saslType), Version: version}
 
 	req := &request{correlationID: b.correlationID, clientID: b.conf.ClientID, body: rb}
-	buf, err := encode(req, b.conf.MetricRegistry)
+	buf, err := encode(req, b.conf.metricsRecorder())
 	if err != nil {
 		return err
 	}
@@ -1142,7 +1785,7 @@ func (b *Broker) sendAndReceiveSASLHandshake(saslType SASLMechanism, version int
 	b.updateOutgoingCommunicationMetrics(bytes)
 	if err != nil {
 		b.addRequestInFlightMetrics(-1)
-		Logger.Printf("Failed to send SASL handshake %s: %s\n", b.addr, err.Error())
+		b.log().warnf("Failed to send SASL handshake %s: %s\n", b.addr, err.Error())
 		return err
 	}
 	b.correlationID++
@@ -1151,7 +1794,7 @@ func (b *Broker) sendAndReceiveSASLHandshake(saslType SASLMechanism, version int
 	_, err = b.readFull(header)
 	if err != nil {
 		b.addRequestInFlightMetrics(-1)
-		Logger.Printf("Failed to read SASL handshake header : %s\n", err.Error())
+		b.log().warnf("Failed to read SASL handshake header : %s\n", err.Error())
 		return err
 	}
 
@@ -1160,7 +1803,7 @@ func (b *Broker) sendAndReceiveSASLHandshake(saslType SASLMechanism, version int
 	n, err := b.readFull(payload)
 	if err != nil {
 		b.addRequestInFlightMetrics(-1)
-		Logger.Printf("Failed to read SASL handshake payload : %s\n", err.Error())
+		b.log().warnf("Failed to read SASL handshake payload : %s\n", err.Error())
 		return err
 	}
 
@@ -1169,16 +1812,16 @@ func (b *Broker) sendAndReceiveSASLHandshake(saslType SASLMechanism, version int
 
 	err = versionedDecode(payload, res, 0)
 	if err != nil {
-		Logger.Printf("Failed to parse SASL handshake : %s\n", err.Error())
+		b.log().warnf("Failed to parse SASL handshake : %s\n", err.Error())
 		return err
 	}
 
 	if !errors.Is(res.Err, ErrNoError) {
-		Logger.Printf("Invalid SASL Mechanism : %s\n", res.Err.Error())
+		b.log().warnf("Invalid SASL Mechanism : %s\n", res.Err.Error())
 		return res.Err
 	}
 
-	DebugLogger.Print("Completed pre-auth SASL handshake. Available mechanisms: ", res.EnabledMechanisms)
+	b.log().debugf("Completed pre-auth SASL handshake. Available mechanisms: %v", res.EnabledMechanisms)
 	return nil
 }
 
@@ -1212,7 +1855,7 @@ func (b *Broker) sendAndReceiveSASLPlainAuth() error {
 	if b.conf.Net.SASL.Handshake {
 		handshakeErr := b.sendAndReceiveSASLHandshake(SASLTypePlaintext, b.conf.Net.SASL.Version)
 		if handshakeErr != nil {
-			Logger.Printf("Error while performing SASL handshake %s\n", b.addr)
+			b.log().warnf("Error while performing SASL handshake %s\n", b.addr)
 			return handshakeErr
 		}
 	}
@@ -1237,7 +1880,7 @@ func (b *Broker) sendAndReceiveV0SASLPlainAuth() error {
 	b.updateOutgoingCommunicationMetrics(bytesWritten)
 	if err != nil {
 		b.addRequestInFlightMetrics(-1)
-		Logger.Printf("Failed to write SASL auth header to broker %s: %s\n", b.addr, err.Error())
+		b.log().warnf("Failed to write SASL auth header to broker %s: %s\n", b.addr, err.Error())
 		return err
 	}
 
@@ -1247,11 +1890,11 @@ func (b *Broker) sendAndReceiveV0SASLPlainAuth() error {
 	// If the credentials are valid, we would get a 4 byte response filled with null characters.
 	// Otherwise, the broker closes the connection and we get an EOF
 	if err != nil {
-		Logger.Printf("Failed to read response while authenticating with SASL to broker %s: %s\n", b.addr, err.Error())
+		b.log().warnf("Failed to read response while authenticating with SASL to broker %s: %s\n", b.addr, err.Error())
 		return err
 	}
 
-	DebugLogger.Printf("SASL authentication successful with broker %s:%v - %v\n", b.addr, n, header)
+	b.log().debugf("SASL authentication successful with broker %s:%v - %v\n", b.addr, n, header)
 	return nil
 }
 
@@ -1268,7 +1911,7 @@ func (b *Broker) sendAndReceiveV1SASLPlainAuth() error {
 
 	if err != nil {
 		b.addRequestInFlightMetrics(-1)
-		Logger.Printf("Failed to write SASL auth header to broker %s: %s\n", b.addr, err.Error())
+		b.log().warnf("Failed to write SASL auth header to broker %s: %s\n", b.addr, err.Error())
 		return err
 	}
 
@@ -1279,7 +1922,7 @@ func (b *Broker) sendAndReceiveV1SASLPlainAuth() error {
 
 	// With v1 sasl we get an error message set in the response we can return
 	if err != nil {
-		Logger.Printf(
+		b.log().warnf(
 			"Error returned from broker %s during SASL authentication: %v\n",
 			b.addr, err.Error())
 		return err
@@ -1345,12 +1988,53 @@ func (b *Broker) sendClientMessage(message []byte) (bool, error) {
 	isChallenge := len(res.SaslAuthBytes) > 0
 
 	if isChallenge && err != nil {
-		Logger.Printf("Broker rejected authentication token: %s", res.SaslAuthBytes)
+		b.log().infof("Broker rejected authentication token: %s", res.SaslAuthBytes)
 	}
 
 	return isChallenge, err
 }
 
+// sendAndReceiveSASLAWSMSKIAM authenticates with the AWS_MSK_IAM mechanism,
+// sending a single SigV4 signed payload which the broker verifies.
+func (b *Broker) sendAndReceiveSASLAWSMSKIAM() error {
+	if err := b.sendAndReceiveSASLHandshake(SASLTypeAWSMSKIAM, SASLHandshakeV1); err != nil {
+		return err
+	}
+
+	host, _, err := net.SplitHostPort(b.addr)
+	if err != nil {
+		return err
+	}
+	payload, err := buildAWSMSKIAMPayload(&b.conf.Net.SASL.AWSMSKIAM, host, time.Now())
+	if err != nil {
+		return err
+	}
+
+	requestTime := time.Now()
+	// Will be decremented in updateIncomingCommunicationMetrics (except error)
+	b.addRequestInFlightMetrics(1)
+	correlationID := b.correlationID
+	bytesWritten, err := b.sendSaslAuthenticateRequest(correlationID, payload)
+	b.updateOutgoingCommunicationMetrics(bytesWritten)
+	if err != nil {
+		b.addRequestInFlightMetrics(-1)
+		b.log().warnf("Failed to write SASL auth header to broker %s: %s\n", b.addr, err.Error())
+		return err
+	}
+	b.correlationID++
+
+	res := &SaslAuthenticateResponse{}
+	bytesRead, err := b.receiveSASLServerResponse(res, correlationID)
+	b.updateIncomingCommunicationMetrics(bytesRead, time.Since(requestTime))
+	if err != nil {
+		b.log().warnf("Error returned from broker %s during SASL authentication: %v\n", b.addr, err.Error())
+		return err
+	}
+
+	b.log().debugf("SASL/AWS_MSK_IAM authentication successful with broker %s: %s\n", b.addr, res.SaslAuthBytes)
+	return nil
+}
+
 func (b *Broker) sendAndReceiveSASLSCRAM() error {
 	if b.conf.Net.SASL.Version == SASLHandshakeV0 {
 		return b.sendAndReceiveSASLSCRAMv0()
@@ -1385,7 +2069,7 @@ func (b *Broker) sendAndReceiveSASLSCRAMv0() error {
 		b.updateOutgoingCommunicationMetrics(length + 4)
 		if err != nil {
 			b.addRequestInFlightMetrics(-1)
-			Logger.Printf("Failed to write SASL auth header to broker %s: %s\n", b.addr, err.Error())
+			b.log().warnf("Failed to write SASL auth header to broker %s: %s\n", b.addr, err.Error())
 			return err
 		}
 		b.correlationID++
@@ -1393,25 +2077,25 @@ func (b *Broker) sendAndReceiveSASLSCRAMv0() error {
 		_, err = b.readFull(header)
 		if err != nil {
 			b.addRequestInFlightMetrics(-1)
-			Logger.Printf("Failed to read response header while authenticating with SASL to broker %s: %s\n", b.addr, err.Error())
+			b.log().warnf("Failed to read response header while authenticating with SASL to broker %s: %s\n", b.addr, err.Error())
 			return err
 		}
 		payload := make([]byte, int32(binary.BigEndian.Uint32(header)))
 		n, err := b.readFull(payload)
 		if err != nil {
 			b.addRequestInFlightMetrics(-1)
-			Logger.Printf("Failed to read response payload while authenticating with SASL to broker %s: %s\n", b.addr, err.Error())
+			b.log().warnf("Failed to read response payload while authenticating with SASL to broker %s: %s\n", b.addr, err.Error())
 			return err
 		}
 		b.updateIncomingCommunicationMetrics(n+4, time.Since(requestTime))
 		msg, err = scramClient.Step(string(payload))
 		if err != nil {
-			Logger.Println("SASL authentication failed", err)
+			b.log().warnf("SASL authentication failed %v", err)
 			return err
 		}
 	}
 
-	DebugLogger.Println("SASL authentication succeeded")
+	b.log().debugf("SASL authentication succeeded")
 	return nil
 }
 
@@ -1439,7 +2123,7 @@ func (b *Broker) sendAndReceiveSASLSCRAMv1() error {
 		b.updateOutgoingCommunicationMetrics(bytesWritten)
 		if err != nil {
 			b.addRequestInFlightMetrics(-1)
-			Logger.Printf("Failed to write SASL auth header to broker %s: %s\n", b.addr, err.Error())
+			b.log().warnf("Failed to write SASL auth header to broker %s: %s\n", b.addr, err.Error())
 			return err
 		}
 
@@ -1447,26 +2131,26 @@ func (b *Broker) sendAndReceiveSASLSCRAMv1() error {
 		challenge, err := b.receiveSaslAuthenticateResponse(correlationID)
 		if err != nil {
 			b.addRequestInFlightMetrics(-1)
-			Logger.Printf("Failed to read response while authenticating with SASL to broker %s: %s\n", b.addr, err.Error())
+			b.log().warnf("Failed to read response while authenticating with SASL to broker %s: %s\n", b.addr, err.Error())
 			return err
 		}
 
 		b.updateIncomingCommunicationMetrics(len(challenge), time.Since(requestTime))
 		msg, err = scramClient.Step(string(challenge))
 		if err != nil {
-			Logger.Println("SASL authentication failed", err)
+			b.log().warnf("SASL authentication failed %v", err)
 			return err
 		}
 	}
 
-	DebugLogger.Println("SASL authentication succeeded")
+	b.log().debugf("SASL authentication succeeded")
 	return nil
 }
 
 func (b *Broker) sendSaslAuthenticateRequest(correlationID int32, msg []byte) (int, error) {
-	rb := &SaslAuthenticateRequest{msg}
+	rb := &SaslAuthenticateRequest{Version: b.saslAuthenticateVersion(), SaslAuthBytes: msg}
 	req := &request{correlationID: correlationID, clientID: b.conf.ClientID, body: rb}
-	buf, err := encode(req, b.conf.MetricRegistry)
+	buf, err := encode(req, b.conf.metricsRecorder())
 	if err != nil {
 		return 0, err
 	}
@@ -1498,12 +2182,13 @@ func (b *Broker) receiveSaslAuthenticateResponse(correlationID int32) ([]byte, e
 	}
 
 	res := &SaslAuthenticateResponse{}
-	if err := versionedDecode(buf, res, 0); err != nil {
+	if err := versionedDecode(buf, res, b.saslAuthenticateVersion()); err != nil {
 		return nil, err
 	}
 	if !errors.Is(res.Err, ErrNoError) {
 		return nil, res.Err
 	}
+	b.computeSaslSessionLifetime(res)
 	return res.SaslAuthBytes, nil
 }
 
@@ -1540,9 +2225,9 @@ func mapToString(extensions map[string]string, keyValSep string, elemSep string)
 
 func (b *Broker) sendSASLPlainAuthClientResponse(correlationID int32) (int, error) {
 	authBytes := []byte(b.conf.Net.SASL.AuthIdentity + "\x00" + b.conf.Net.SASL.User + "\x00" + b.conf.Net.SASL.Password)
-	rb := &SaslAuthenticateRequest{authBytes}
+	rb := &SaslAuthenticateRequest{Version: b.saslAuthenticateVersion(), SaslAuthBytes: authBytes}
 	req := &request{correlationID: correlationID, clientID: b.conf.ClientID, body: rb}
-	buf, err := encode(req, b.conf.MetricRegistry)
+	buf, err := encode(req, b.conf.metricsRecorder())
 	if err != nil {
 		return 0, err
 	}
@@ -1551,11 +2236,11 @@ func (b *Broker) sendSASLPlainAuthClientResponse(correlationID int32) (int, erro
 }
 
 func (b *Broker) sendSASLOAuthBearerClientMessage(initialResp []byte, correlationID int32) (int, error) {
-	rb := &SaslAuthenticateRequest{initialResp}
+	rb := &SaslAuthenticateRequest{Version: b.saslAuthenticateVersion(), SaslAuthBytes: initialResp}
 
 	req := &request{correlationID: correlationID, clientID: b.conf.ClientID, body: rb}
 
-	buf, err := encode(req, b.conf.MetricRegistry)
+	buf, err := encode(req, b.conf.metricsRecorder())
 	if err != nil {
 		return 0, err
 	}
@@ -1587,7 +2272,7 @@ func (b *Broker) receiveSASLServerResponse(res *SaslAuthenticateResponse, correl
 		return bytesRead, err
 	}
 
-	if err := versionedDecode(buf, res, 0); err != nil {
+	if err := versionedDecode(buf, res, b.saslAuthenticateVersion()); err != nil {
 		return bytesRead, err
 	}
 
@@ -1599,112 +2284,362 @@ func (b *Broker) receiveSASLServerResponse(res *SaslAuthenticateResponse, correl
 		return bytesRead, err
 	}
 
+	b.computeSaslSessionLifetime(res)
 	return bytesRead, nil
 }
 
+// saslAuthenticateVersion returns the version of the SaslAuthenticate
+// requests, v1 being the first to report the session lifetime.
+func (b *Broker) saslAuthenticateVersion() int16 {
+	if b.conf.Version.IsAtLeast(V2_2_0_0) {
+		return 1
+	}
+	return 0
+}
+
+// computeSaslSessionLifetime schedules the re-authentication of the SASL
+// session reported by res, if it expires. Like the Java client, it is
+// scheduled at a random point between 85% and 95% of the session lifetime,
+// leaving room for latency and clock drift while spreading the
+// re-authentications of the connections opened together.
+func (b *Broker) computeSaslSessionLifetime(res *SaslAuthenticateResponse) {
+	if res.SessionLifetimeMs <= 0 {
+		b.sessionReauthenticationTime = time.Time{}
+		return
+	}
+	lifetime := time.Duration(res.SessionLifetimeMs) * time.Millisecond
+	reauthenticateAfter := time.Duration(float64(lifetime) * (0.85 + 0.1*rand.Float64()))
+	b.log().debugf("SASL session with broker %s expires in %s, re-authenticating after %s\n", b.addr, lifetime, reauthenticateAfter)
+	b.sessionReauthenticationTime = time.Now().Add(reauthenticateAfter)
+}
+
+// reauthenticate renews the SASL session of the connection before the broker
+// expires it, as described by KIP-368. It must be called with the lock held
+// and waits for the responses of the in-flight requests, so that the SASL
+// exchange has exclusive use of the connection.
+func (b *Broker) reauthenticate() error {
+	b.inFlight.Wait()
+
+	b.log().debugf("Re-authenticating with broker %s\n", b.addr)
+	if err := b.authenticateViaSASL(); err != nil {
+		b.log().warnf("Failed to re-authenticate with broker %s: %s\n", b.addr, err)
+		b.recordConnectionMetric(b.conf, "failed-authentication-rate", 1)
+		// the exchange may have been interrupted midway, leaving the
+		// connection unusable, let the next requests fail fast instead
+		_ = b.conn.Close()
+		return err
+	}
+	return nil
+}
+
 func (b *Broker) updateIncomingCommunicationMetrics(bytes int, requestLatency time.Duration) {
 	b.updateRequestLatencyAndInFlightMetrics(requestLatency)
-	b.responseRate.Mark(1)
+	b.responseRate.Add(1)
 
 	if b.brokerResponseRate != nil {
-		b.brokerResponseRate.Mark(1)
+		b.brokerResponseRate.Add(1)
 	}
 
 	responseSize := int64(bytes)
-	b.incomingByteRate.Mark(responseSize)
+	b.incomingByteRate.Add(responseSize)
 	if b.brokerIncomingByteRate != nil {
-		b.brokerIncomingByteRate.Mark(responseSize)
+		b.brokerIncomingByteRate.Add(responseSize)
 	}
 
-	b.responseSize.Update(responseSize)
+	b.responseSize.Observe(responseSize)
 	if b.brokerResponseSize != nil {
-		b.brokerResponseSize.Update(responseSize)
+		b.brokerResponseSize.Observe(responseSize)
 	}
 }
 
 func (b *Broker) updateRequestLatencyAndInFlightMetrics(requestLatency time.Duration) {
 	requestLatencyInMs := int64(requestLatency / time.Millisecond)
-	b.requestLatency.Update(requestLatencyInMs)
+	b.requestLatency.Observe(requestLatencyInMs)
 
 	if b.brokerRequestLatency != nil {
-		b.brokerRequestLatency.Update(requestLatencyInMs)
+		b.brokerRequestLatency.Observe(requestLatencyInMs)
 	}
 
 	b.addRequestInFlightMetrics(-1)
 }
 
 func (b *Broker) addRequestInFlightMetrics(i int64) {
-	b.requestsInFlight.Inc(i)
+	atomic.AddInt32(&b.pendingRequests, int32(i))
+	b.requestsInFlight.Add(i)
 	if b.brokerRequestsInFlight != nil {
-		b.brokerRequestsInFlight.Inc(i)
+		b.brokerRequestsInFlight.Add(i)
 	}
 }
 
 func (b *Broker) updateOutgoingCommunicationMetrics(bytes int) {
-	b.requestRate.Mark(1)
+	b.requestRate.Add(1)
 	if b.brokerRequestRate != nil {
-		b.brokerRequestRate.Mark(1)
+		b.brokerRequestRate.Add(1)
+	}
+	if b.brokerConnectionAge != nil && !b.connectedAt.IsZero() {
+		b.brokerConnectionAge.Set(int64(time.Since(b.connectedAt) / time.Millisecond))
 	}
 
 	requestSize := int64(bytes)
-	b.outgoingByteRate.Mark(requestSize)
+	b.outgoingByteRate.Add(requestSize)
 	if b.brokerOutgoingByteRate != nil {
-		b.brokerOutgoingByteRate.Mark(requestSize)
+		b.brokerOutgoingByteRate.Add(requestSize)
 	}
 
-	b.requestSize.Update(requestSize)
+	b.requestSize.Observe(requestSize)
 	if b.brokerRequestSize != nil {
-		b.brokerRequestSize.Update(requestSize)
+		b.brokerRequestSize.Observe(requestSize)
 	}
 }
 
-func (b *Broker) updateThrottleMetric(throttleTime time.Duration) {
-	if throttleTime != time.Duration(0) {
-		DebugLogger.Printf(
-			"producer/broker/%d ProduceResponse throttled %v\n",
-			b.ID(), throttleTime)
-		if b.brokerThrottleTime != nil {
-			throttleTimeInMs := int64(throttleTime / time.Millisecond)
-			b.brokerThrottleTime.Update(throttleTimeInMs)
+// throttleSupport is implemented by the responses carrying the time the
+// request was throttled for because of a quota violation.
+type throttleSupport interface {
+	throttleTime() time.Duration
+	// shouldClientThrottle reports whether, at the given version, the client
+	// is expected to back off for the throttle time. Since KIP-219 brokers
+	// answer throttled requests straight away and rely on the client to hold
+	// back, rather than delaying the response themselves.
+	shouldClientThrottle(version int16) bool
+}
+
+// handleThrottledResponse records the throttle time of res, in the metrics of
+// the broker and of its API, reports it to Net.Throttle.OnThreshold and, when
+// the broker expects it, holds back the next requests to the broker until the
+// throttle time has elapsed, as the JVM client does.
+func (b *Broker) handleThrottledResponse(res protocolBody, version int16, metrics *apiMetrics) {
+	throttled, ok := res.(throttleSupport)
+	if !ok {
+		return
+	}
+	throttleTime := throttled.throttleTime()
+	if throttleTime <= 0 {
+		return
+	}
+
+	b.log().debugf("broker/%d %T throttled %v\n", b.ID(), res, throttleTime)
+	b.updateThrottleMetric(throttleTime)
+	metrics.observeThrottleTime(throttleTime)
+	api := apiName(res.key())
+	b.events.publish(&ClientEvent{Type: EventThrottled, Broker: b, Throttle: throttleTime, API: api})
+	if b.conf.Net.Throttle.OnThreshold != nil && throttleTime > b.conf.Net.Throttle.Threshold {
+		b.conf.Net.Throttle.OnThreshold(b, api, throttleTime)
+	}
+
+	if throttled.shouldClientThrottle(version) {
+		until := time.Now().Add(throttleTime)
+		b.throttleLock.Lock()
+		if until.After(b.throttleUntil) {
+			b.throttleUntil = until
 		}
+		b.throttleLock.Unlock()
+	}
+}
+
+// waitIfThrottled blocks until the broker no longer throttles the client. It
+// returns ErrNotConnected if the broker is closed before then.
+func (b *Broker) waitIfThrottled() error {
+	b.throttleLock.Lock()
+	backoff := time.Until(b.throttleUntil)
+	b.throttleLock.Unlock()
+	if backoff <= 0 {
+		return nil
+	}
+
+	b.lock.Lock()
+	done := b.done
+	b.lock.Unlock()
+	if done == nil {
+		// not connected, which sendWithPromise reports straight away
+		return nil
+	}
+
+	b.log().debugf("broker/%d throttled, holding back request for %v\n", b.ID(), backoff)
+	if b.brokerThrottleWait != nil {
+		b.brokerThrottleWait.Observe(int64(backoff / time.Millisecond))
+	}
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-done:
+		return ErrNotConnected
+	}
+}
+
+func (b *Broker) updateThrottleMetric(throttleTime time.Duration) {
+	if b.brokerThrottleTime != nil {
+		throttleTimeInMs := int64(throttleTime / time.Millisecond)
+		b.brokerThrottleTime.Observe(throttleTimeInMs)
 	}
 }
 
+// log returns the logContext of the records about the broker.
+func (b *Broker) log() logContext {
+	c := logWith(brokerField(b.id), addrField(b.addr))
+	if id := b.connectionID(); id != "" {
+		c = c.with(connectionIDField(id))
+	}
+	return c
+}
+
+// requestLog returns the logContext of the records about the request of API
+// key with correlation ID correlationID.
+func (b *Broker) requestLog(key int16, correlationID int32) logContext {
+	return b.log().with(apiField(key), correlationIDField(correlationID))
+}
+
 func (b *Broker) registerMetrics() {
-	b.brokerIncomingByteRate = b.registerMeter("incoming-byte-rate")
-	b.brokerRequestRate = b.registerMeter("request-rate")
+	b.brokerIncomingByteRate = b.registerCounter("incoming-byte-rate")
+	b.brokerRequestRate = b.registerCounter("request-rate")
 	b.brokerRequestSize = b.registerHistogram("request-size")
 	b.brokerRequestLatency = b.registerHistogram("request-latency-in-ms")
-	b.brokerOutgoingByteRate = b.registerMeter("outgoing-byte-rate")
-	b.brokerResponseRate = b.registerMeter("response-rate")
+	b.brokerOutgoingByteRate = b.registerCounter("outgoing-byte-rate")
+	b.brokerResponseRate = b.registerCounter("response-rate")
 	b.brokerResponseSize = b.registerHistogram("response-size")
-	b.brokerRequestsInFlight = b.registerCounter("requests-in-flight")
+	b.brokerRequestsInFlight = b.registerGauge("requests-in-flight")
 	b.brokerThrottleTime = b.registerHistogram("throttle-time-in-ms")
+	b.brokerThrottleWait = b.registerHistogram("throttle-wait-in-ms")
+	b.brokerConnectionAge = b.registerGauge("connection-age-in-ms")
+}
+
+// recordConnectionMetric records value in the connection metric name, a
+// counter if it is named "*-rate" and a histogram otherwise, for all brokers
+// and, unless b is a seed broker, for b. Unlike the other metrics of the
+// broker, they are kept once it is closed, to follow its reconnections.
+func (b *Broker) recordConnectionMetric(conf *Config, name string, value int64) {
+	recorder := conf.metricsRecorder()
+	if recorder == nil || metrics.UseNilMetrics {
+		return
+	}
+	labels := []MetricLabels{nil}
+	if b.id >= 0 {
+		labels = append(labels, b.metricLabels())
+	}
+	for _, l := range labels {
+		if strings.HasSuffix(name, "-rate") {
+			recorder.Counter(name, l).Add(value)
+		} else {
+			recorder.Histogram(name, l).Observe(value)
+		}
+	}
 }
 
 func (b *Broker) unregisterMetrics() {
-	for _, name := range b.registeredMetrics {
-		b.conf.MetricRegistry.Unregister(name)
+	if recorder, ok := b.conf.metricsRecorder().(metricsUnregisterer); ok {
+		for _, metric := range b.registeredMetrics {
+			recorder.Unregister(metric.name, metric.labels)
+		}
 	}
 	b.registeredMetrics = nil
+	b.apiMetrics = nil
+}
+
+// metricLabels returns the labels of the metrics of the broker.
+func (b *Broker) metricLabels() MetricLabels {
+	id := b.id
+	if b.coordinatorConnection {
+		// the node ID the JVM client gives to the connections it dedicates
+		// to coordinators
+		id = math.MaxInt32 - id
+	}
+	return MetricLabels{MetricLabelBroker: strconv.Itoa(int(id))}
+}
+
+// registeredMetric is a metric of the broker, unregistered once it is closed.
+type registeredMetric struct {
+	name   string
+	labels MetricLabels
+}
+
+func (b *Broker) registerCounter(name string) MetricsCounter {
+	return b.registerLabelledCounter(name, b.metricLabels())
+}
+
+func (b *Broker) registerLabelledCounter(name string, labels MetricLabels) MetricsCounter {
+	b.registeredMetrics = append(b.registeredMetrics, registeredMetric{name, labels})
+	return b.conf.metricsRecorder().Counter(name, labels)
+}
+
+func (b *Broker) registerGauge(name string) MetricsGauge {
+	b.registeredMetrics = append(b.registeredMetrics, registeredMetric{name, b.metricLabels()})
+	return b.conf.metricsRecorder().Gauge(name, b.metricLabels())
+}
+
+func (b *Broker) registerHistogram(name string) MetricsHistogram {
+	return b.registerLabelledHistogram(name, b.metricLabels())
+}
+
+func (b *Broker) registerLabelledHistogram(name string, labels MetricLabels) MetricsHistogram {
+	b.registeredMetrics = append(b.registeredMetrics, registeredMetric{name, labels})
+	return b.conf.metricsRecorder().Histogram(name, labels)
+}
+
+// apiMetrics are the metrics of the requests of an API to a broker.
+type apiMetrics struct {
+	latency      MetricsHistogram
+	errorRate    MetricsCounter
+	throttleTime MetricsHistogram
+}
+
+// apiMetricsFor returns the metrics of the requests of the API key, nil when
+// the metrics of the broker are not gathered. b.lock must be held.
+func (b *Broker) apiMetricsFor(key int16) *apiMetrics {
+	if b.brokerRequestLatency == nil {
+		return nil
+	}
+	if m, ok := b.apiMetrics[key]; ok {
+		return m
+	}
+	if b.apiMetrics == nil {
+		b.apiMetrics = make(map[int16]*apiMetrics)
+	}
+	labels := b.metricLabels()
+	labels[MetricLabelAPI] = apiName(key)
+	m := &apiMetrics{
+		latency:      b.registerLabelledHistogram("api-latency-in-ms", labels),
+		errorRate:    b.registerLabelledCounter("api-error-rate", labels),
+		throttleTime: b.registerLabelledHistogram("api-throttle-time-in-ms", labels),
+	}
+	b.apiMetrics[key] = m
+	return m
 }
 
-func (b *Broker) registerMeter(name string) metrics.Meter {
-	nameForBroker := getMetricNameForBroker(name, b)
-	b.registeredMetrics = append(b.registeredMetrics, nameForBroker)
-	return metrics.GetOrRegisterMeter(nameForBroker, b.conf.MetricRegistry)
+func (m *apiMetrics) observeLatency(latency time.Duration) {
+	if m != nil {
+		m.latency.Observe(int64(latency / time.Millisecond))
+	}
+}
+
+func (m *apiMetrics) observeThrottleTime(throttleTime time.Duration) {
+	if m != nil {
+		m.throttleTime.Observe(int64(throttleTime / time.Millisecond))
+	}
+}
+
+// countError counts a request which failed, or whose response could not be
+// read.
+func (m *apiMetrics) countError() {
+	if m != nil {
+		m.errorRate.Add(1)
+	}
 }
 
-func (b *Broker) registerHistogram(name string) metrics.Histogram {
-	nameForBroker := getMetricNameForBroker(name, b)
-	b.registeredMetrics = append(b.registeredMetrics, nameForBroker)
-	return getOrRegisterHistogram(nameForBroker, b.conf.MetricRegistry)
+// countResponseError counts a request whose response carries an error code.
+func (m *apiMetrics) countResponseError(res protocolBody) {
+	if m == nil {
+		return
+	}
+	if errs, ok := res.(errorCodeSupport); ok && errs.hasErrorCode() {
+		m.errorRate.Add(1)
+	}
 }
 
-func (b *Broker) registerCounter(name string) metrics.Counter {
-	nameForBroker := getMetricNameForBroker(name, b)
-	b.registeredMetrics = append(b.registeredMetrics, nameForBroker)
-	return metrics.GetOrRegisterCounter(nameForBroker, b.conf.MetricRegistry)
+// errorCodeSupport is implemented by the responses which may carry error
+// codes, at the top level or for some of their topics or partitions.
+type errorCodeSupport interface {
+	hasErrorCode() bool
 }
 
 func validServerNameTLS(addr string, cfg *tls.Config) *tls.Config {
@@ -1720,7 +2655,7 @@ func validServerNameTLS(addr string, cfg *tls.Config) *tls.Config {
 	c := cfg.Clone()
 	sn, _, err := net.SplitHostPort(addr)
 	if err != nil {
-		Logger.Println(fmt.Errorf("failed to get ServerName from addr %w", err))
+		warnf("failed to get ServerName from addr %v", err)
 	}
 	c.ServerName = sn
 	return c
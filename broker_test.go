@@ -2,15 +2,19 @@ package sarama
 
 import (
 	"bytes"
+	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"net"
 	"reflect"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/jcmturner/gokrb5/v8/krberror"
 	"github.com/rcrowley/go-metrics"
+	"golang.org/x/net/proxy"
 )
 
 func ExampleBroker() {
@@ -110,6 +114,429 @@ func (p produceResponsePromise) Get() (*ProduceResponse, error) {
 	return resOrError.res, resOrError.err
 }
 
+func TestBrokerThrottlePacing(t *testing.T) {
+	mb := NewMockBroker(t, 0)
+	defer mb.Close()
+
+	throttled := &FindCoordinatorResponse{Version: 2, ThrottleTime: 200 * time.Millisecond, Err: ErrNoError, Coordinator: &Broker{id: 0, addr: mb.Addr()}}
+	mb.Returns(throttled)
+	mb.Returns(&FindCoordinatorResponse{Version: 2, Err: ErrNoError, Coordinator: &Broker{id: 0, addr: mb.Addr()}})
+
+	conf := NewTestConfig()
+	conf.Version = V2_0_0_0
+	broker := NewBroker(mb.Addr())
+	broker.id = 0
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, broker)
+
+	request := &FindCoordinatorRequest{Version: 2, CoordinatorKey: "group", CoordinatorType: CoordinatorGroup}
+	if _, err := broker.FindCoordinator(request); err != nil {
+		t.Fatal(err)
+	}
+	start := time.Now()
+	if _, err := broker.FindCoordinator(request); err != nil {
+		t.Fatal(err)
+	}
+	if elapsed := time.Since(start); elapsed < 150*time.Millisecond {
+		t.Errorf("expected the request following a throttled response to be held back, sent after %v", elapsed)
+	}
+
+	wait := conf.MetricRegistry.Get("throttle-wait-in-ms-for-broker-0").(metrics.Histogram)
+	if wait.Count() != 1 {
+		t.Errorf("expected one request to have waited, got %d", wait.Count())
+	}
+
+	// before KIP-219 the broker delays throttled responses itself
+	broker.handleThrottledResponse(&FindCoordinatorResponse{Version: 1, ThrottleTime: time.Minute}, 1, nil)
+	broker.throttleLock.Lock()
+	defer broker.throttleLock.Unlock()
+	if time.Until(broker.throttleUntil) > 0 {
+		t.Error("expected no backoff for a response throttled by the broker")
+	}
+}
+
+func TestBrokerThrottleWaitInterruptedByClose(t *testing.T) {
+	mb := NewMockBroker(t, 0)
+	defer mb.Close()
+
+	conf := NewTestConfig()
+	conf.Version = V2_0_0_0
+	broker := NewBroker(mb.Addr())
+	broker.id = 0
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	if connected, err := broker.Connected(); !connected || err != nil {
+		t.Fatalf("expected the broker to be connected, got %v", err)
+	}
+	broker.throttleLock.Lock()
+	broker.throttleUntil = time.Now().Add(time.Minute)
+	broker.throttleLock.Unlock()
+
+	errs := make(chan error)
+	go func() {
+		_, err := broker.FindCoordinator(&FindCoordinatorRequest{Version: 2, CoordinatorKey: "group", CoordinatorType: CoordinatorGroup})
+		errs <- err
+	}()
+	time.Sleep(50 * time.Millisecond)
+	safeClose(t, broker)
+
+	select {
+	case err := <-errs:
+		if !errors.Is(err, ErrNotConnected) {
+			t.Errorf("expected ErrNotConnected once the broker closed, got %v", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected closing the broker to interrupt the throttle wait")
+	}
+}
+
+func TestBrokerThrottleThreshold(t *testing.T) {
+	mb := NewMockBroker(t, 0)
+	defer mb.Close()
+	for _, throttleTime := range []time.Duration{50 * time.Millisecond, 200 * time.Millisecond} {
+		mb.Returns(&FindCoordinatorResponse{Version: 1, ThrottleTime: throttleTime, Err: ErrNoError, Coordinator: &Broker{id: 0, addr: mb.Addr()}})
+	}
+
+	var reported []time.Duration
+	conf := NewTestConfig()
+	conf.Version = V2_0_0_0
+	conf.Net.Throttle.Threshold = 100 * time.Millisecond
+	conf.Net.Throttle.OnThreshold = func(broker *Broker, api string, throttle time.Duration) {
+		if broker.ID() != 0 || api != "FindCoordinator" {
+			t.Errorf("unexpected throttled request of broker %d to %s", broker.ID(), api)
+		}
+		reported = append(reported, throttle)
+	}
+	broker := NewBroker(mb.Addr())
+	broker.id = 0
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, broker)
+
+	request := &FindCoordinatorRequest{Version: 1, CoordinatorKey: "group", CoordinatorType: CoordinatorGroup}
+	for i := 0; i < 2; i++ {
+		if _, err := broker.FindCoordinator(request); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if len(reported) != 1 || reported[0] != 200*time.Millisecond {
+		t.Errorf("expected the request throttled above the threshold to be reported, got %v", reported)
+	}
+	throttleTime := conf.MetricRegistry.Get("api-throttle-time-in-ms-FindCoordinator-for-broker-0").(metrics.Histogram)
+	if throttleTime.Count() != 2 || throttleTime.Max() != 200 {
+		t.Errorf("expected the throttle times to be recorded for the API, got %d up to %d", throttleTime.Count(), throttleTime.Max())
+	}
+}
+
+func TestBrokerConnectionMetrics(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).SetBroker(mb.Addr(), mb.BrokerID()),
+	})
+
+	conf := NewTestConfig()
+	broker := NewBroker(mb.Addr())
+	broker.id = 1
+	for i := 0; i < 2; i++ {
+		if err := broker.Open(conf); err != nil {
+			t.Fatal(err)
+		}
+		// the first request waits for the connection, opened asynchronously
+		if _, err := broker.GetMetadata(&MetadataRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		time.Sleep(20 * time.Millisecond)
+		if _, err := broker.GetMetadata(&MetadataRequest{}); err != nil {
+			t.Fatal(err)
+		}
+		if age := conf.MetricRegistry.Get("connection-age-in-ms-for-broker-1").(metrics.Counter); age.Count() < 20 {
+			t.Errorf("expected the connection to be at least 20ms old, got %dms", age.Count())
+		}
+		safeClose(t, broker)
+	}
+
+	if conf.MetricRegistry.Get("connection-age-in-ms-for-broker-1") != nil {
+		t.Error("expected the age of the connection to be dropped once closed")
+	}
+	for _, name := range []string{"reconnect-rate", "reconnect-rate-for-broker-1"} {
+		if reconnects := conf.MetricRegistry.Get(name).(metrics.Meter); reconnects.Count() != 1 {
+			t.Errorf("expected %s to count one reconnection, got %d", name, reconnects.Count())
+		}
+	}
+}
+
+func TestBrokerFailedAuthenticationMetrics(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"SaslHandshakeRequest": NewMockSaslHandshakeResponse(t).SetError(ErrUnsupportedSASLMechanism),
+	})
+
+	conf := NewTestConfig()
+	conf.Net.SASL.Enable = true
+	conf.Net.SASL.User = "user"
+	conf.Net.SASL.Password = "password"
+	broker := NewBroker(mb.Addr())
+	broker.id = 1
+	if err := broker.OpenContext(context.Background(), conf); !errors.Is(err, ErrUnsupportedSASLMechanism) {
+		t.Fatalf("expected the authentication to fail, got %v", err)
+	}
+
+	for _, name := range []string{"failed-authentication-rate", "failed-authentication-rate-for-broker-1"} {
+		if failures := conf.MetricRegistry.Get(name).(metrics.Meter); failures.Count() != 1 {
+			t.Errorf("expected %s to count one failure, got %d", name, failures.Count())
+		}
+	}
+}
+
+func TestBrokerDialContext(t *testing.T) {
+	mb := NewMockBroker(t, 0)
+	defer mb.Close()
+
+	var dialed []string
+	conf := NewTestConfig()
+	conf.Net.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		if _, ok := ctx.Deadline(); !ok {
+			t.Error("expected the dial context to have a deadline")
+		}
+		dialed = append(dialed, addr)
+		var dialer net.Dialer
+		return dialer.DialContext(ctx, network, addr)
+	}
+
+	broker := NewBroker(mb.Addr())
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, broker)
+	if connected, err := broker.Connected(); !connected || err != nil {
+		t.Fatalf("expected the broker to connect, got %v", err)
+	}
+	if len(dialed) != 1 || dialed[0] != mb.Addr() {
+		t.Errorf("expected %s to be dialed through DialContext, got %v", mb.Addr(), dialed)
+	}
+}
+
+func TestBrokerDialerSelector(t *testing.T) {
+	mb := NewMockBroker(t, 0)
+	defer mb.Close()
+
+	errBlocked := errors.New("blocked")
+	conf := NewTestConfig()
+	conf.Net.DialerSelector = func(addr string) DialContextFunc {
+		if addr != "blocked:9092" {
+			return nil
+		}
+		return func(context.Context, string, string) (net.Conn, error) {
+			return nil, errBlocked
+		}
+	}
+
+	blocked := NewBroker("blocked:9092")
+	if err := blocked.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := blocked.Connected(); !errors.Is(err, errBlocked) {
+		t.Errorf("expected the selected dialer to be used, got %v", err)
+	}
+
+	// other addresses fall back to the default dialer
+	broker := NewBroker(mb.Addr())
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, broker)
+	if connected, err := broker.Connected(); !connected || err != nil {
+		t.Errorf("expected the broker to connect, got %v", err)
+	}
+}
+
+func TestBrokerOpenContext(t *testing.T) {
+	mb := NewMockBroker(t, 0)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+	})
+
+	conf := NewTestConfig()
+	conf.Version = V2_4_0_0
+	broker := NewBroker(mb.Addr())
+	if state := broker.ConnectionState(); state.State != BrokerClosed {
+		t.Errorf("expected a new broker to be closed, got %s", state.State)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := broker.OpenContext(ctx, conf); err != nil {
+		t.Fatal(err)
+	}
+
+	state := broker.ConnectionState()
+	if state.State != BrokerConnected || state.Authenticated || state.ConnectedAt.IsZero() || state.LastError != nil {
+		t.Errorf("expected the broker to be connected without authentication, got %+v", state)
+	}
+	if versions, ok := state.ApiVersions[1]; !ok || versions.MinVersion != 7 || versions.MaxVersion != 12 {
+		t.Errorf("expected the versions returned by the broker, got %v", state.ApiVersions)
+	}
+
+	safeClose(t, broker)
+	if state := broker.ConnectionState(); state.State != BrokerClosed || state.ApiVersions != nil {
+		t.Errorf("expected the broker to be closed, got %+v", state)
+	}
+}
+
+func TestBrokerOpenContextDone(t *testing.T) {
+	conf := NewTestConfig()
+	dialing := make(chan none)
+	conf.Net.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		close(dialing)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+
+	broker := NewBroker("localhost:9092")
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-dialing
+		if state := broker.ConnectionState(); state.State != BrokerConnecting {
+			t.Errorf("expected the broker to be connecting, got %s", state.State)
+		}
+		cancel()
+	}()
+
+	if err := broker.OpenContext(ctx, conf); !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected the attempt to be canceled, got %v", err)
+	}
+	if _, err := broker.Connected(); !errors.Is(err, context.Canceled) {
+		t.Errorf("expected the dial to be canceled, got %v", err)
+	}
+	if state := broker.ConnectionState(); state.State != BrokerClosed || !errors.Is(state.LastError, context.Canceled) {
+		t.Errorf("expected the broker to be closed after the canceled attempt, got %+v", state)
+	}
+}
+
+func TestBrokerUnsupportedApiVersion(t *testing.T) {
+	mb := NewMockBroker(t, 0)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t).SetApiKeys([]ApiVersionsResponseKey{
+			{ApiKey: 3, MinVersion: 4, MaxVersion: 12},
+		}),
+	})
+
+	conf := NewTestConfig()
+	conf.Version = V2_4_0_0
+	broker := NewBroker(mb.Addr())
+	if err := broker.OpenContext(context.Background(), conf); err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, broker)
+
+	_, err := broker.GetMetadata(&MetadataRequest{Version: 1})
+	var versionErr UnsupportedApiVersionError
+	if !errors.As(err, &versionErr) || !errors.Is(err, ErrUnsupportedVersion) {
+		t.Fatalf("expected an UnsupportedApiVersionError, got %v", err)
+	}
+	expected := UnsupportedApiVersionError{ApiKey: 3, ApiName: "Metadata", Version: 1, MinVersion: 4, MaxVersion: 12}
+	if versionErr != expected {
+		t.Errorf("expected %+v, got %+v", expected, versionErr)
+	}
+
+	_, err = broker.Produce(&ProduceRequest{Version: 3})
+	if !errors.As(err, &versionErr) || versionErr.ApiName != "Produce" || versionErr.MinVersion != -1 {
+		t.Errorf("expected the Produce API to be unsupported, got %v", err)
+	}
+
+	for _, message := range mb.History() {
+		if _, ok := message.Request.(*ApiVersionsRequest); !ok {
+			t.Errorf("expected the unsupported requests not to be sent, got %T", message.Request)
+		}
+	}
+}
+
+type recordingProxyDialer struct {
+	lock  sync.Mutex
+	addrs []string
+}
+
+func (d *recordingProxyDialer) Dial(network, addr string) (net.Conn, error) {
+	d.lock.Lock()
+	d.addrs = append(d.addrs, addr)
+	d.lock.Unlock()
+	return net.Dial(network, addr)
+}
+
+func TestBrokerProxyDialerFunc(t *testing.T) {
+	proxied := NewMockBroker(t, 0)
+	defer proxied.Close()
+	direct := NewMockBroker(t, 1)
+	defer direct.Close()
+
+	dialer := &recordingProxyDialer{}
+	conf := NewTestConfig()
+	conf.Net.Proxy.Enable = true
+	conf.Net.Proxy.DialerFunc = func(addr string) proxy.Dialer {
+		if addr == proxied.Addr() {
+			return dialer
+		}
+		// bypass the proxy
+		return nil
+	}
+
+	for _, addr := range []string{proxied.Addr(), direct.Addr()} {
+		broker := NewBroker(addr)
+		if err := broker.Open(conf); err != nil {
+			t.Fatal(err)
+		}
+		if connected, err := broker.Connected(); !connected || err != nil {
+			t.Errorf("expected %s to connect, got %v", addr, err)
+		}
+		safeClose(t, broker)
+	}
+
+	if len(dialer.addrs) != 1 || dialer.addrs[0] != proxied.Addr() {
+		t.Errorf("expected only %s to be dialed through the proxy, got %v", proxied.Addr(), dialer.addrs)
+	}
+}
+
+func TestBrokerSecurityOverride(t *testing.T) {
+	plaintext := NewMockBroker(t, 0)
+	defer plaintext.Close()
+
+	conf := NewTestConfig()
+	conf.Net.TLS.Enable = true
+	conf.Net.BrokerSecurity = func(addr string, conf *Config) {
+		if addr == plaintext.Addr() {
+			conf.Net.TLS.Enable = false
+		} else {
+			conf.Net.SASL.Enable = true
+		}
+	}
+
+	broker := NewBroker(plaintext.Addr())
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	if connected, err := broker.Connected(); !connected || err != nil {
+		t.Errorf("expected the broker to connect without TLS, got %v", err)
+	}
+	safeClose(t, broker)
+	if !conf.Net.TLS.Enable {
+		t.Error("expected the Config not to be modified")
+	}
+
+	err := NewBroker("localhost:0").Open(conf)
+	if !errors.As(err, new(ConfigurationError)) {
+		t.Errorf("expected the adapted Config to be validated, got %v", err)
+	}
+}
+
 func TestSimpleBrokerCommunication(t *testing.T) {
 	for _, tt := range brokerTestTable {
 		tt := tt
@@ -294,14 +721,14 @@ func TestSASLOAuthBearer(t *testing.T) {
 
 			// broker executes SASL requests against mockBroker
 			broker := NewBroker(mockBroker.Addr())
-			broker.requestRate = metrics.NilMeter{}
-			broker.outgoingByteRate = metrics.NilMeter{}
-			broker.incomingByteRate = metrics.NilMeter{}
-			broker.requestSize = metrics.NilHistogram{}
-			broker.responseSize = metrics.NilHistogram{}
-			broker.responseRate = metrics.NilMeter{}
-			broker.requestLatency = metrics.NilHistogram{}
-			broker.requestsInFlight = metrics.NilCounter{}
+			broker.requestRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.outgoingByteRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.incomingByteRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.requestSize = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.responseSize = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.responseRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.requestLatency = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.requestsInFlight = goMetricsCounter{metrics.NilCounter{}}
 
 			conf := NewTestConfig()
 			conf.Net.SASL.Mechanism = SASLTypeOAuth
@@ -411,14 +838,14 @@ func TestSASLSCRAMSHAXXX(t *testing.T) {
 			mockBroker := NewMockBroker(t, 0)
 			broker := NewBroker(mockBroker.Addr())
 			// broker executes SASL requests against mockBroker
-			broker.requestRate = metrics.NilMeter{}
-			broker.outgoingByteRate = metrics.NilMeter{}
-			broker.incomingByteRate = metrics.NilMeter{}
-			broker.requestSize = metrics.NilHistogram{}
-			broker.responseSize = metrics.NilHistogram{}
-			broker.responseRate = metrics.NilMeter{}
-			broker.requestLatency = metrics.NilHistogram{}
-			broker.requestsInFlight = metrics.NilCounter{}
+			broker.requestRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.outgoingByteRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.incomingByteRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.requestSize = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.responseSize = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.responseRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.requestLatency = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.requestsInFlight = goMetricsCounter{metrics.NilCounter{}}
 
 			mockSASLAuthResponse := NewMockSaslAuthenticateResponse(t).SetAuthBytes([]byte(test.scramChallengeResp))
 			mockSASLHandshakeResponse := NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512})
@@ -534,14 +961,14 @@ func TestSASLPlainAuth(t *testing.T) {
 
 			// broker executes SASL requests against mockBroker
 			broker := NewBroker(mockBroker.Addr())
-			broker.requestRate = metrics.NilMeter{}
-			broker.outgoingByteRate = metrics.NilMeter{}
-			broker.incomingByteRate = metrics.NilMeter{}
-			broker.requestSize = metrics.NilHistogram{}
-			broker.responseSize = metrics.NilHistogram{}
-			broker.responseRate = metrics.NilMeter{}
-			broker.requestLatency = metrics.NilHistogram{}
-			broker.requestsInFlight = metrics.NilCounter{}
+			broker.requestRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.outgoingByteRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.incomingByteRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.requestSize = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.responseSize = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.responseRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.requestLatency = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.requestsInFlight = goMetricsCounter{metrics.NilCounter{}}
 
 			conf := NewTestConfig()
 			conf.Net.SASL.Mechanism = SASLTypePlaintext
@@ -603,6 +1030,54 @@ func TestSASLPlainAuth(t *testing.T) {
 	}
 }
 
+// TestSASLReauthentication ensures that the broker renews the SASL session
+// before the lifetime reported by the server expires (KIP-368)
+func TestSASLReauthentication(t *testing.T) {
+	mockBroker := NewMockBroker(t, 0)
+	defer mockBroker.Close()
+
+	mockBroker.SetHandlerByMap(map[string]MockResponse{
+		"SaslAuthenticateRequest": NewMockSaslAuthenticateResponse(t).SetSessionLifetimeMs(100),
+		"SaslHandshakeRequest":    NewMockSaslHandshakeResponse(t).SetEnabledMechanisms([]string{SASLTypePlaintext}),
+		"MetadataRequest":         NewMockMetadataResponse(t),
+	})
+
+	conf := NewTestConfig()
+	conf.Version = V2_2_0_0
+	conf.Net.SASL.Enable = true
+	conf.Net.SASL.Mechanism = SASLTypePlaintext
+	conf.Net.SASL.User = "token"
+	conf.Net.SASL.Password = "password"
+	conf.Net.SASL.Version = SASLHandshakeV1
+
+	broker := NewBroker(mockBroker.Addr())
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, broker)
+
+	if _, err := broker.GetMetadata(&MetadataRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	if _, err := broker.GetMetadata(&MetadataRequest{}); err != nil {
+		t.Fatal(err)
+	}
+
+	var authentications int
+	for _, rr := range mockBroker.History() {
+		if r, ok := rr.Request.(*SaslAuthenticateRequest); ok {
+			authentications++
+			if r.Version != 1 {
+				t.Errorf("expected SaslAuthenticateRequest v1, got v%d", r.Version)
+			}
+		}
+	}
+	if authentications != 2 {
+		t.Errorf("expected the session to be authenticated twice, got %d", authentications)
+	}
+}
+
 // TestSASLReadTimeout ensures that the broker connection won't block forever
 // if the remote end never responds after the handshake
 func TestSASLReadTimeout(t *testing.T) {
@@ -618,14 +1093,14 @@ func TestSASLReadTimeout(t *testing.T) {
 
 	broker := NewBroker(mockBroker.Addr())
 	{
-		broker.requestRate = metrics.NilMeter{}
-		broker.outgoingByteRate = metrics.NilMeter{}
-		broker.incomingByteRate = metrics.NilMeter{}
-		broker.requestSize = metrics.NilHistogram{}
-		broker.responseSize = metrics.NilHistogram{}
-		broker.responseRate = metrics.NilMeter{}
-		broker.requestLatency = metrics.NilHistogram{}
-		broker.requestsInFlight = metrics.NilCounter{}
+		broker.requestRate = goMetricsMeter{metrics.NilMeter{}}
+		broker.outgoingByteRate = goMetricsMeter{metrics.NilMeter{}}
+		broker.incomingByteRate = goMetricsMeter{metrics.NilMeter{}}
+		broker.requestSize = goMetricsHistogram{metrics.NilHistogram{}}
+		broker.responseSize = goMetricsHistogram{metrics.NilHistogram{}}
+		broker.responseRate = goMetricsMeter{metrics.NilMeter{}}
+		broker.requestLatency = goMetricsHistogram{metrics.NilHistogram{}}
+		broker.requestsInFlight = goMetricsCounter{metrics.NilCounter{}}
 	}
 
 	conf := NewTestConfig()
@@ -711,14 +1186,14 @@ func TestGSSAPIKerberosAuth_Authorize(t *testing.T) {
 				return nil
 			})
 			broker := NewBroker(mockBroker.Addr())
-			broker.requestRate = metrics.NilMeter{}
-			broker.outgoingByteRate = metrics.NilMeter{}
-			broker.incomingByteRate = metrics.NilMeter{}
-			broker.requestSize = metrics.NilHistogram{}
-			broker.responseSize = metrics.NilHistogram{}
-			broker.responseRate = metrics.NilMeter{}
-			broker.requestLatency = metrics.NilHistogram{}
-			broker.requestsInFlight = metrics.NilCounter{}
+			broker.requestRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.outgoingByteRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.incomingByteRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.requestSize = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.responseSize = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.responseRate = goMetricsMeter{metrics.NilMeter{}}
+			broker.requestLatency = goMetricsHistogram{metrics.NilHistogram{}}
+			broker.requestsInFlight = goMetricsCounter{metrics.NilCounter{}}
 			conf := NewTestConfig()
 			conf.Net.SASL.Mechanism = SASLTypeGSSAPI
 			conf.Net.SASL.GSSAPI.ServiceName = "kafka"
@@ -1296,3 +1771,135 @@ func BenchmarkBroker_No_Metrics_Open(b *testing.B) {
 		broker.Close()
 	}
 }
+
+func TestBrokerLenientDecoding(t *testing.T) {
+	metadata, err := encode(&MetadataResponse{Brokers: []*Broker{NewBroker("localhost:9092")}}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	// the fields of a newer version trailing the response
+	mb.setHandler(func(req *request) encoderWithHeader {
+		return rawResponse(append(metadata, 0xca, 0xfe))
+	})
+
+	for _, lenient := range []bool{false, true} {
+		conf := NewTestConfig()
+		conf.Net.LenientDecoding = lenient
+		broker := NewBroker(mb.Addr())
+		if err := broker.Open(conf); err != nil {
+			t.Fatal(err)
+		}
+		res, err := broker.GetMetadata(&MetadataRequest{})
+		var decodingErr PacketDecodingError
+		switch {
+		case !lenient && !errors.As(err, &decodingErr):
+			t.Errorf("expected strict decoding to fail with a PacketDecodingError, got %v", err)
+		case lenient && (err != nil || len(res.Brokers) != 1):
+			t.Errorf("expected lenient decoding to ignore the trailing bytes, got %v", err)
+		}
+		safeClose(t, broker)
+	}
+}
+
+func TestBrokerMockResponsesFollowRequestVersions(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+		"ProduceRequest":     NewMockProduceResponse(t),
+		"FetchRequest": NewMockFetchResponse(t, 1).
+			SetHighWaterMark("my_topic", 0, 14),
+		"ListPartitionReassignmentsRequest": NewMockListPartitionReassignmentsResponse(t),
+	})
+
+	conf := NewTestConfig()
+	conf.Version = V2_8_0_0
+	broker := NewBroker(mb.Addr())
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, broker)
+
+	produce := &ProduceRequest{Version: 7, RequiredAcks: WaitForLocal}
+	produce.AddBatch("my_topic", 0, &RecordBatch{Version: 2, Records: []*Record{{Value: []byte("hello")}}})
+	if res, err := broker.Produce(produce); err != nil {
+		t.Error(err)
+	} else if res.Version != 7 {
+		t.Errorf("expected a produce response of version 7, got %d", res.Version)
+	}
+
+	fetch := &FetchRequest{Version: 11}
+	fetch.AddBlock("my_topic", 0, 0, 1024)
+	if res, err := broker.Fetch(fetch); err != nil {
+		t.Error(err)
+	} else if block := res.GetBlock("my_topic", 0); res.Version != 11 || block == nil || block.HighWaterMarkOffset != 14 {
+		t.Errorf("expected a fetch response of version 11 with the high water mark, got %+v", res)
+	}
+
+	list := &ListPartitionReassignmentsRequest{TimeoutMs: 1000}
+	list.AddBlock("my_topic", []int32{0})
+	if res, err := broker.ListPartitionReassignments(list); err != nil {
+		t.Error(err)
+	} else if len(res.TopicStatus["my_topic"]) != 1 {
+		t.Errorf("expected the reassignment of the partition, got %+v", res)
+	}
+}
+
+func TestBrokerConcurrentResponseHandlers(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"ProduceRequest": NewMockProduceResponse(t),
+	})
+
+	conf := NewTestConfig()
+	conf.Net.ResponseHandlers = 4
+	broker := NewBroker(mb.Addr())
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, broker)
+
+	// the callbacks are called in the order of the requests, as the
+	// producer expects, however concurrently the responses are decoded
+	const requests = 50
+	called := make(chan int, requests)
+	for i := 0; i < requests; i++ {
+		i := i
+		err := broker.AsyncProduce(&ProduceRequest{RequiredAcks: WaitForLocal}, func(_ *ProduceResponse, err error) {
+			if err != nil {
+				t.Error(err)
+			}
+			called <- i
+		})
+		if err != nil {
+			t.Fatal(err)
+		}
+	}
+	for i := 0; i < requests; i++ {
+		select {
+		case n := <-called:
+			if n != i {
+				t.Fatalf("expected the callback of request %d, got %d", i, n)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for the callback of request %d", i)
+		}
+	}
+}
+
+func TestBrokerMarshalJSON(t *testing.T) {
+	rack := "rack1"
+	broker := NewBroker("localhost:9092")
+	broker.id = 1
+	broker.rack = &rack
+	b, err := json.Marshal(broker)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(b) != `{"id":1,"addr":"localhost:9092","rack":"rack1"}` {
+		t.Errorf("unexpected JSON %s", b)
+	}
+}
@@ -1,10 +1,15 @@
 package sarama
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"hash/fnv"
+	"math"
 	"math/rand"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -27,18 +32,54 @@ type Client interface {
 	// and stores it in the local cache. Requires Kafka 0.10 or higher.
 	RefreshController() (*Broker, error)
 
+	// ControllerID returns the ID of the cluster controller broker, as
+	// Controller does but without connecting to it. Requires Kafka 0.10 or
+	// higher.
+	ControllerID() (int32, error)
+
+	// ClusterID returns the ID of the cluster, as retrieved from cluster
+	// metadata, to tell clusters apart, e.g. in metrics. Requires Kafka 1.0 or
+	// higher.
+	ClusterID() (string, error)
+
+	// Racks returns the rack of each broker by ID, as retrieved from cluster
+	// metadata. Brokers without a rack (broker.rack) are absent. Requires
+	// Kafka 0.10 or higher.
+	Racks() map[int32]string
+
+	// BrokerApiVersions returns the versions of the APIs supported by each
+	// broker by ID, as returned by the ApiVersionsRequest sent on connecting
+	// (see Config.ApiVersionsRequest), to report mixed-version clusters.
+	// Brokers not connected yet are absent. Requires Kafka 2.4 or higher.
+	BrokerApiVersions() map[int32]map[int16]ApiVersionsResponseKey
+
+	// ApiVersionRange returns the versions of the API apiKey supported by all
+	// the brokers in BrokerApiVersions, to detect the features of the
+	// cluster. ok is false if a broker does not support the API, or if no
+	// broker versions are known. Requires Kafka 2.4 or higher.
+	ApiVersionRange(apiKey int16) (minVersion, maxVersion int16, ok bool)
+
 	// Brokers returns the current set of active brokers as retrieved from cluster metadata.
 	Brokers() []*Broker
 
 	// Broker returns the active Broker if available for the broker ID.
 	Broker(brokerID int32) (*Broker, error)
 
+	// LeastLoadedBroker returns the broker with the fewest requests awaiting a
+	// response, avoiding the brokers which recently failed a request or which
+	// the client is not connected to, or nil if no broker is available.
+	LeastLoadedBroker() *Broker
+
 	// Topics returns the set of available topics as retrieved from cluster metadata.
 	Topics() ([]string, error)
 
 	// Partitions returns the sorted list of all partition IDs for the given topic.
 	Partitions(topic string) ([]int32, error)
 
+	// TopicID returns the ID of the topic, which changes when the topic is
+	// deleted and recreated under the same name. Requires Kafka 2.8 or higher.
+	TopicID(topic string) (Uuid, error)
+
 	// WritablePartitions returns the sorted list of all writable partition IDs for
 	// the given topic, where "writable" means "having a valid leader accepting
 	// writes".
@@ -65,6 +106,10 @@ type Client interface {
 	// will be used for the next metadata fetch.
 	RefreshBrokers(addrs []string) error
 
+	// MetadataSnapshot returns the metadata cached by the client, serialized,
+	// to seed a new client with NewClientFromMetadataSnapshot.
+	MetadataSnapshot() ([]byte, error)
+
 	// RefreshMetadata takes a list of topics and queries the cluster to refresh the
 	// available metadata for those topics. If no topics are provided, it will refresh
 	// metadata for all topics.
@@ -76,6 +121,22 @@ type Client interface {
 	// OffsetNewest for the offset of the message that will be produced next, or a time.
 	GetOffset(topic string, partitionID int32, time int64) (int64, error)
 
+	// GetOffsets is GetOffset for many partitions at once: the partitions are
+	// grouped by leader, and a single request is sent to each broker. It returns
+	// the offsets by topic and partition; if some partitions failed, even after
+	// refreshing their metadata, it returns the offsets of the others along with
+	// the first error.
+	GetOffsets(topicPartitions map[string][]int32, time int64) (map[string]map[int32]int64, error)
+
+	// OffsetsForTimes looks up, for each partition of timestamps, the earliest
+	// offset whose timestamp is at or after the given one (in milliseconds),
+	// as GetOffsets does. Partitions without such a message are absent from
+	// the result; if some partitions failed, it returns the offsets of the
+	// others along with the first error. The leader epochs of the offsets
+	// require Kafka 2.1, and looking offsets up by timestamp Kafka 0.10.1:
+	// ErrUnsupportedVersion is returned for older versions.
+	OffsetsForTimes(timestamps map[string]map[int32]int64) (map[string]map[int32]*OffsetAndTimestamp, error)
+
 	// Coordinator returns the coordinating broker for a consumer group. It will
 	// return a locally cached value if it's available. You can call
 	// RefreshCoordinator to update the cached value. This function only works on
@@ -95,8 +156,28 @@ type Client interface {
 	// before you close the client.
 	Close() error
 
+	// CloseContext shuts down the client like Close, once the requests in flight
+	// on its broker connections have completed. If ctx is done first, the
+	// connections are torn down regardless and a *CloseAbandonedError lists the
+	// brokers whose requests were abandoned.
+	CloseContext(ctx context.Context) error
+
 	// Closed returns true if the client has already had Close called on it
 	Closed() bool
+
+	// Subscribe registers handler to be called with the events of the client,
+	// such as brokers connecting or leaders changing, until the returned
+	// function is called or the client is closed. The handlers are called
+	// in turn, in the order of the events, from a single goroutine: events
+	// are dropped rather than queued past Config.ChannelBufferSize while the
+	// handlers are busy, so they should not block.
+	Subscribe(handler func(*ClientEvent)) (unsubscribe func())
+
+	// DebugState returns a snapshot of the state of the client: its broker
+	// connections and the requests in flight on them, and the topics and
+	// coordinators it knows. It is meant to diagnose a client in production,
+	// and does not block on requests in flight.
+	DebugState() ClientDebugState
 }
 
 const (
@@ -112,34 +193,81 @@ const (
 	OffsetOldest int64 = -2
 )
 
+// OffsetAndTimestamp is an offset found by Client.OffsetsForTimes, with the
+// timestamp of its message and the leader epoch which wrote it, or -1 if
+// unknown.
+type OffsetAndTimestamp struct {
+	Offset      int64
+	Timestamp   int64
+	LeaderEpoch int32
+}
+
 type client struct {
 	conf           *Config
 	closer, closed chan none // for shutting down background metadata updater
+	closeOnce      sync.Once // as CloseContext may race with Close
 
 	// the broker addresses given to us through the constructor are not guaranteed to be returned in
 	// the cluster metadata (I *think* it only returns brokers who are currently leading partitions?)
 	// so we store them separately
 	seedBrokers []*Broker
 	deadSeeds   []*Broker
+	// bootstrap are the broker addresses as given, when some of them are
+	// resolved from SRV records again by the background metadata updater
+	bootstrap []string
 
 	controllerID   int32                                   // cluster controller broker id
+	clusterID      string                                  // cluster id, unknown before Kafka 1.0
 	brokers        map[int32]*Broker                       // maps broker ids to brokers
 	metadata       map[string]map[int32]*PartitionMetadata // maps topics to partition ids to metadata
 	metadataTopics map[string]none                         // topics that need to collect metadata
+	topicIDs       map[string]Uuid                         // maps topics to their IDs, known from Kafka 2.8
 	coordinators   map[string]int32                        // Maps consumer group names to coordinating broker IDs
+	// the dedicated connections to the coordinators, by broker ID, see
+	// Consumer.Group.DedicatedCoordinatorConnection
+	coordinatorBrokers map[int32]*Broker
 
 	// If the number of partitions is large, we can get some churn calling cachedPartitions,
 	// so the result is cached.  It is important to update this value whenever metadata is changed
 	cachedPartitionsResults map[string][maxPartitionIndex][]int32
 
 	lock sync.RWMutex // protects access to the maps that hold cluster state.
+
+	// routing holds the *routingTable the leader and partition lookups read
+	// without taking lock, republished whenever brokers or metadata change
+	routing atomic.Value
+
+	// brokerFailures records when requests to each broker address last
+	// failed, to avoid sending the next ones there
+	brokerFailures map[string]time.Time
+
+	// lastUsed records when the metadata of each topic was last looked up, as
+	// a *int64 of nanoseconds since the epoch updated atomically, to discard
+	// the topics unused for Metadata.MaxIdleTime
+	lastUsed sync.Map
+
+	events *eventBus
+
+	// telemetry pushes the client metrics to the brokers, when
+	// Telemetry.Enable is set
+	telemetry *telemetryReporter
 }
 
 // NewClient creates a new Client. It connects to one of the given broker addresses
 // and uses that broker to automatically fetch metadata on the rest of the kafka cluster. If metadata cannot
 // be retrieved from any of the given broker addresses, the client is not created.
+//
+// An address of the form srv://name stands for the targets of the DNS SRV records of name, such as
+// srv://_kafka._tcp.example.com. They are resolved again along with the background metadata refresh,
+// so that the seed brokers follow the records.
 func NewClient(addrs []string, conf *Config) (Client, error) {
-	DebugLogger.Println("Initializing new client")
+	return newClient(addrs, conf, nil)
+}
+
+// newClient creates a new Client, with the metadata of snapshot if it is not
+// nil.
+func newClient(addrs []string, conf *Config, snapshot *metadataSnapshot) (Client, error) {
+	debugf("Initializing new client")
 
 	if conf == nil {
 		conf = NewConfig()
@@ -153,6 +281,12 @@ func NewClient(addrs []string, conf *Config) (Client, error) {
 		return nil, ConfigurationError("You must provide at least one broker address")
 	}
 
+	bootstrap := addrs
+	addrs, err := conf.bootstrapAddrs(addrs)
+	if err != nil {
+		return nil, err
+	}
+
 	client := &client{
 		conf:                    conf,
 		closer:                  make(chan none),
@@ -160,19 +294,32 @@ func NewClient(addrs []string, conf *Config) (Client, error) {
 		brokers:                 make(map[int32]*Broker),
 		metadata:                make(map[string]map[int32]*PartitionMetadata),
 		metadataTopics:          make(map[string]none),
+		topicIDs:                make(map[string]Uuid),
 		cachedPartitionsResults: make(map[string][maxPartitionIndex][]int32),
 		coordinators:            make(map[string]int32),
+		coordinatorBrokers:      make(map[int32]*Broker),
+		brokerFailures:          make(map[string]time.Time),
+		events:                  newEventBus(conf.ChannelBufferSize),
 	}
 
+	if hasSRVAddrs(bootstrap) {
+		client.bootstrap = bootstrap
+	}
 	client.randomizeSeedBrokers(addrs)
+	client.publishRouting()
 
-	if conf.Metadata.Full {
+	if snapshot != nil {
+		debugf("client/metadata seeded from a snapshot taken %s ago\n", time.Since(snapshot.Time))
+		if _, err := client.updateMetadata(snapshot.Metadata, true); err != nil {
+			warnf("client/metadata the snapshot holds errors: %s\n", err)
+		}
+	} else if conf.Metadata.Full {
 		// do an initial fetch of all cluster metadata by specifying an empty list of topics
 		err := client.RefreshMetadata()
 		if err == nil {
 		} else if errors.Is(err, ErrLeaderNotAvailable) || errors.Is(err, ErrReplicaNotAvailable) || errors.Is(err, ErrTopicAuthorizationFailed) || errors.Is(err, ErrClusterAuthorizationFailed) {
 			// indicates that maybe part of the cluster is down, but is not fatal to creating the client
-			Logger.Println(err)
+			warnf("%v", err)
 		} else {
 			close(client.closed) // we haven't started the background updater yet, so we have to do this manually
 			_ = client.Close()
@@ -180,8 +327,12 @@ func NewClient(addrs []string, conf *Config) (Client, error) {
 		}
 	}
 	go withRecover(client.backgroundMetadataUpdater)
+	if conf.Telemetry.Enable {
+		client.telemetry = newTelemetryReporter(client)
+		go withRecover(client.telemetry.run)
+	}
 
-	DebugLogger.Println("Successfully initialized new client")
+	debugf("Successfully initialized new client")
 
 	return client, nil
 }
@@ -211,6 +362,10 @@ func (client *client) Broker(brokerID int32) (*Broker, error) {
 	return broker, nil
 }
 
+func (client *client) LeastLoadedBroker() *Broker {
+	return client.any()
+}
+
 func (client *client) InitProducerID() (*InitProducerIDResponse, error) {
 	brokerErrors := make([]error, 0)
 	for broker := client.any(); broker != nil; broker = client.any() {
@@ -222,7 +377,7 @@ func (client *client) InitProducerID() (*InitProducerIDResponse, error) {
 			return response, nil
 		} else {
 			// some error, remove that broker and try again
-			Logger.Printf("Client got error from broker %d when issuing InitProducerID : %v\n", broker.ID(), err)
+			broker.log().warnf("Client got error from broker %d when issuing InitProducerID : %v\n", broker.ID(), err)
 			_ = broker.Close()
 			brokerErrors = append(brokerErrors, err)
 			client.deregisterBroker(broker)
@@ -236,17 +391,23 @@ func (client *client) Close() error {
 	if client.Closed() {
 		// Chances are this is being called from a defer() and the error will go unobserved
 		// so we go ahead and log the event in this case.
-		Logger.Printf("Close() called on already closed client")
+		infof("Close() called on already closed client")
 		return ErrClosedClient
 	}
 
 	// shutdown and wait for the background thread before we take the lock, to avoid races
-	close(client.closer)
+	client.closeOnce.Do(func() {
+		close(client.closer)
+	})
 	<-client.closed
+	if client.telemetry != nil {
+		// pushes the metrics a last time, before the brokers are closed
+		client.telemetry.close()
+	}
 
 	client.lock.Lock()
 	defer client.lock.Unlock()
-	DebugLogger.Println("Closing Client")
+	debugf("Closing Client")
 
 	for _, broker := range client.brokers {
 		safeAsyncClose(broker)
@@ -256,18 +417,93 @@ func (client *client) Close() error {
 		safeAsyncClose(broker)
 	}
 
+	for _, broker := range client.coordinatorBrokers {
+		safeAsyncClose(broker)
+	}
+
 	client.brokers = nil
 	client.metadata = nil
 	client.metadataTopics = nil
+	client.publishRouting()
+	client.events.close()
 
 	return nil
 }
 
+func (client *client) CloseContext(ctx context.Context) error {
+	if client.Closed() {
+		return ErrClosedClient
+	}
+
+	client.lock.RLock()
+	brokers := make([]*Broker, 0, len(client.seedBrokers)+len(client.brokers))
+	brokers = append(brokers, client.seedBrokers...)
+	for _, broker := range client.brokers {
+		brokers = append(brokers, broker)
+	}
+	for _, broker := range client.coordinatorBrokers {
+		brokers = append(brokers, broker)
+	}
+	client.lock.RUnlock()
+
+	ticker := time.NewTicker(closePollInterval)
+	defer ticker.Stop()
+	for {
+		var busy []string
+		for _, broker := range brokers {
+			if atomic.LoadInt32(&broker.pendingRequests) > 0 {
+				busy = append(busy, broker.Addr())
+			}
+		}
+		if len(busy) == 0 {
+			return client.Close()
+		}
+
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			infof("client/brokers closing with requests in flight to %v\n", busy)
+			_ = client.Close()
+			return &CloseAbandonedError{Err: ctx.Err(), Brokers: busy}
+		}
+	}
+}
+
 func (client *client) Closed() bool {
+	return client.routingTable().brokers == nil
+}
+
+func (client *client) Subscribe(handler func(*ClientEvent)) func() {
+	return client.events.subscribe(handler)
+}
+
+func (client *client) DebugState() ClientDebugState {
 	client.lock.RLock()
-	defer client.lock.RUnlock()
+	state := ClientDebugState{
+		Closed:       client.brokers == nil,
+		ControllerID: -1,
+		Coordinators: make(map[string]int32, len(client.coordinators)),
+	}
+	if _, ok := client.brokers[client.controllerID]; ok {
+		state.ControllerID = client.controllerID
+	}
+	brokers := make([]*Broker, 0, len(client.brokers))
+	for _, broker := range client.brokers {
+		brokers = append(brokers, broker)
+	}
+	seeds := append([]*Broker(nil), client.seedBrokers...)
+	for topic := range client.metadata {
+		state.Topics = append(state.Topics, topic)
+	}
+	for group, coordinator := range client.coordinators {
+		state.Coordinators[group] = coordinator
+	}
+	client.lock.RUnlock()
 
-	return client.brokers == nil
+	state.Brokers = brokersDebugState(brokers)
+	state.SeedBrokers = brokersDebugState(seeds)
+	sort.Strings(state.Topics)
+	return state
 }
 
 func (client *client) Topics() ([]string, error) {
@@ -307,6 +543,8 @@ func (client *client) Partitions(topic string) ([]int32, error) {
 		return nil, ErrClosedClient
 	}
 
+	client.markTopicUsed(topic)
+
 	partitions := client.cachedPartitions(topic, allPartitions)
 
 	if len(partitions) == 0 {
@@ -325,11 +563,34 @@ func (client *client) Partitions(topic string) ([]int32, error) {
 	return partitions, nil
 }
 
+func (client *client) TopicID(topic string) (Uuid, error) {
+	if client.Closed() {
+		return Uuid{}, ErrClosedClient
+	}
+	if !client.conf.Version.IsAtLeast(V2_8_0_0) {
+		return Uuid{}, ErrUnsupportedVersion
+	}
+
+	if _, err := client.Partitions(topic); err != nil {
+		return Uuid{}, err
+	}
+
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	topicID, ok := client.topicIDs[topic]
+	if !ok {
+		return Uuid{}, ErrUnknownTopicOrPartition
+	}
+	return topicID, nil
+}
+
 func (client *client) WritablePartitions(topic string) ([]int32, error) {
 	if client.Closed() {
 		return nil, ErrClosedClient
 	}
 
+	client.markTopicUsed(topic)
+
 	partitions := client.cachedPartitions(topic, writablePartitions)
 
 	// len==0 catches when it's nil (no such topic) and the odd case when every single
@@ -358,6 +619,8 @@ func (client *client) Replicas(topic string, partitionID int32) ([]int32, error)
 		return nil, ErrClosedClient
 	}
 
+	client.markTopicUsed(topic)
+
 	metadata := client.cachedMetadata(topic, partitionID)
 
 	if metadata == nil {
@@ -383,6 +646,8 @@ func (client *client) InSyncReplicas(topic string, partitionID int32) ([]int32,
 		return nil, ErrClosedClient
 	}
 
+	client.markTopicUsed(topic)
+
 	metadata := client.cachedMetadata(topic, partitionID)
 
 	if metadata == nil {
@@ -408,6 +673,8 @@ func (client *client) OfflineReplicas(topic string, partitionID int32) ([]int32,
 		return nil, ErrClosedClient
 	}
 
+	client.markTopicUsed(topic)
+
 	metadata := client.cachedMetadata(topic, partitionID)
 
 	if metadata == nil {
@@ -433,6 +700,8 @@ func (client *client) Leader(topic string, partitionID int32) (*Broker, error) {
 		return nil, ErrClosedClient
 	}
 
+	client.markTopicUsed(topic)
+
 	leader, err := client.cachedLeader(topic, partitionID)
 
 	if leader == nil {
@@ -451,6 +720,12 @@ func (client *client) RefreshBrokers(addrs []string) error {
 		return ErrClosedClient
 	}
 
+	bootstrap := addrs
+	addrs, err := client.conf.bootstrapAddrs(addrs)
+	if err != nil {
+		return err
+	}
+
 	client.lock.Lock()
 	defer client.lock.Unlock()
 
@@ -458,9 +733,14 @@ func (client *client) RefreshBrokers(addrs []string) error {
 		_ = broker.Close()
 		delete(client.brokers, broker.ID())
 	}
+	client.publishRouting()
 
 	client.seedBrokers = nil
 	client.deadSeeds = nil
+	client.bootstrap = nil
+	if hasSRVAddrs(bootstrap) {
+		client.bootstrap = bootstrap
+	}
 
 	client.randomizeSeedBrokers(addrs)
 
@@ -504,6 +784,80 @@ func (client *client) GetOffset(topic string, partitionID int32, time int64) (in
 	return offset, err
 }
 
+func (client *client) GetOffsets(topicPartitions map[string][]int32, time int64) (map[string]map[int32]int64, error) {
+	if client.Closed() {
+		return nil, ErrClosedClient
+	}
+
+	times := make(map[string]map[int32]int64, len(topicPartitions))
+	for topic, partitions := range topicPartitions {
+		times[topic] = make(map[int32]int64, len(partitions))
+		for _, partitionID := range partitions {
+			times[topic][partitionID] = time
+		}
+	}
+
+	version := int16(0)
+	if client.conf.Version.IsAtLeast(V0_10_1_0) {
+		version = 1
+	}
+
+	offsets := make(map[string]map[int32]int64, len(topicPartitions))
+	err := client.listOffsets(times, version, func(topic string, partitionID int32, block *OffsetResponseBlock) error {
+		if len(block.Offsets) != 1 {
+			return ErrOffsetOutOfRange
+		}
+		if offsets[topic] == nil {
+			offsets[topic] = make(map[int32]int64)
+		}
+		offsets[topic][partitionID] = block.Offsets[0]
+		return nil
+	})
+
+	return offsets, err
+}
+
+func (client *client) OffsetsForTimes(timestamps map[string]map[int32]int64) (map[string]map[int32]*OffsetAndTimestamp, error) {
+	if client.Closed() {
+		return nil, ErrClosedClient
+	}
+
+	var version int16
+	switch {
+	case client.conf.Version.IsAtLeast(V2_1_0_0):
+		version = 4
+	case client.conf.Version.IsAtLeast(V2_0_0_0):
+		version = 3
+	case client.conf.Version.IsAtLeast(V0_11_0_0):
+		version = 2
+	case client.conf.Version.IsAtLeast(V0_10_1_0):
+		version = 1
+	default:
+		// the version 0 of the ListOffsets API only looks up the offsets of
+		// log segments, not of messages
+		return nil, ErrUnsupportedVersion
+	}
+
+	offsets := make(map[string]map[int32]*OffsetAndTimestamp, len(timestamps))
+	err := client.listOffsets(timestamps, version, func(topic string, partitionID int32, block *OffsetResponseBlock) error {
+		if block.Offset < 0 {
+			// no message at or after the timestamp
+			return nil
+		}
+		if offsets[topic] == nil {
+			offsets[topic] = make(map[int32]*OffsetAndTimestamp)
+		}
+		offsets[topic][partitionID] = &OffsetAndTimestamp{
+			Offset:      block.Offset,
+			Timestamp:   block.Timestamp,
+			LeaderEpoch: block.LeaderEpoch,
+		}
+		return nil
+	})
+
+	return offsets, err
+}
+
 func (client *client) Controller() (*Broker, error) {
 	if client.Closed() {
 		return nil, ErrClosedClient
@@ -529,11 +883,119 @@ func (client *client) Controller() (*Broker, error) {
 	return controller, nil
 }
 
+func (client *client) ControllerID() (int32, error) {
+	if client.Closed() {
+		return -1, ErrClosedClient
+	}
+
+	if !client.conf.Version.IsAtLeast(V0_10_0_0) {
+		return -1, ErrUnsupportedVersion
+	}
+
+	controller := client.cachedController()
+	if controller == nil {
+		if err := client.refreshMetadata(); err != nil {
+			return -1, err
+		}
+		controller = client.cachedController()
+	}
+
+	if controller == nil {
+		return -1, ErrControllerNotAvailable
+	}
+
+	return controller.ID(), nil
+}
+
+func (client *client) ClusterID() (string, error) {
+	if client.Closed() {
+		return "", ErrClosedClient
+	}
+
+	if !client.conf.Version.IsAtLeast(V1_0_0_0) {
+		return "", ErrUnsupportedVersion
+	}
+
+	clusterID := client.cachedClusterID()
+	if clusterID == "" {
+		if err := client.refreshMetadata(); err != nil {
+			return "", err
+		}
+		clusterID = client.cachedClusterID()
+	}
+
+	if clusterID == "" {
+		return "", ErrIncompleteResponse
+	}
+
+	return clusterID, nil
+}
+
+func (client *client) Racks() map[int32]string {
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+
+	racks := make(map[int32]string)
+	for id, broker := range client.brokers {
+		if broker.rack != nil {
+			racks[id] = *broker.rack
+		}
+	}
+	return racks
+}
+
+func (client *client) BrokerApiVersions() map[int32]map[int16]ApiVersionsResponseKey {
+	return brokerApiVersions(client.Brokers())
+}
+
+func (client *client) ApiVersionRange(apiKey int16) (minVersion, maxVersion int16, ok bool) {
+	return apiVersionRange(client.BrokerApiVersions(), apiKey)
+}
+
+// brokerApiVersions returns the known versions of the APIs supported by each of
+// brokers, by ID.
+func brokerApiVersions(brokers []*Broker) map[int32]map[int16]ApiVersionsResponseKey {
+	apiVersions := make(map[int32]map[int16]ApiVersionsResponseKey)
+	for _, broker := range brokers {
+		if versions := broker.ConnectionState().ApiVersions; versions != nil {
+			apiVersions[broker.ID()] = versions
+		}
+	}
+	return apiVersions
+}
+
+// apiVersionRange returns the intersection of the versions of apiKey in
+// apiVersions.
+func apiVersionRange(apiVersions map[int32]map[int16]ApiVersionsResponseKey, apiKey int16) (minVersion, maxVersion int16, ok bool) {
+	if len(apiVersions) == 0 {
+		return 0, 0, false
+	}
+
+	minVersion, maxVersion = 0, math.MaxInt16
+	for _, versions := range apiVersions {
+		key, supported := versions[apiKey]
+		if !supported {
+			return 0, 0, false
+		}
+		if key.MinVersion > minVersion {
+			minVersion = key.MinVersion
+		}
+		if key.MaxVersion < maxVersion {
+			maxVersion = key.MaxVersion
+		}
+	}
+	if minVersion > maxVersion {
+		return 0, 0, false
+	}
+	return minVersion, maxVersion, true
+}
+
 // deregisterController removes the cached controllerID
 func (client *client) deregisterController() {
 	client.lock.Lock()
 	defer client.lock.Unlock()
 	delete(client.brokers, client.controllerID)
+	client.publishRouting()
 }
 
 // RefreshController retrieves the cluster controller from fresh metadata
@@ -602,7 +1064,21 @@ func (client *client) RefreshCoordinator(consumerGroup string) error {
 func (client *client) randomizeSeedBrokers(addrs []string) {
 	random := rand.New(rand.NewSource(time.Now().UnixNano()))
 	for _, index := range random.Perm(len(addrs)) {
-		client.seedBrokers = append(client.seedBrokers, NewBroker(addrs[index]))
+		broker := NewBroker(addrs[index])
+		broker.events = client.events
+		client.seedBrokers = append(client.seedBrokers, broker)
+	}
+}
+
+// selectListener points the broker at the address of the listener selected
+// by Net.Listeners instead of the address it advertised.
+func (client *client) selectListener(broker *Broker) {
+	if len(client.conf.Net.Listeners.Preference) == 0 || broker.advertisedAddr != "" {
+		return
+	}
+	if addr := client.conf.listenerAddr(broker.id, broker.addr); addr != broker.addr {
+		logWith(brokerField(broker.id), addrField(addr)).debugf("client/brokers selected %s for broker #%d advertised at %s", addr, broker.id, broker.addr)
+		broker.advertisedAddr, broker.addr = broker.addr, addr
 	}
 }
 
@@ -610,14 +1086,17 @@ func (client *client) updateBroker(brokers []*Broker) {
 	currentBroker := make(map[int32]*Broker, len(brokers))
 
 	for _, broker := range brokers {
+		client.selectListener(broker)
 		currentBroker[broker.ID()] = broker
 		if client.brokers[broker.ID()] == nil { // add new broker
+			broker.events = client.events
 			client.brokers[broker.ID()] = broker
-			DebugLogger.Printf("client/brokers registered new broker #%d at %s", broker.ID(), broker.Addr())
+			broker.log().debugf("client/brokers registered new broker #%d at %s", broker.ID(), broker.Addr())
 		} else if broker.Addr() != client.brokers[broker.ID()].Addr() { // replace broker with new address
 			safeAsyncClose(client.brokers[broker.ID()])
+			broker.events = client.events
 			client.brokers[broker.ID()] = broker
-			Logger.Printf("client/brokers replaced registered broker #%d with %s", broker.ID(), broker.Addr())
+			broker.log().infof("client/brokers replaced registered broker #%d with %s", broker.ID(), broker.Addr())
 		}
 	}
 
@@ -625,7 +1104,11 @@ func (client *client) updateBroker(brokers []*Broker) {
 		if _, exist := currentBroker[id]; !exist { // remove old broker
 			safeAsyncClose(broker)
 			delete(client.brokers, id)
-			Logger.Printf("client/broker remove invalid broker #%d with %s", broker.ID(), broker.Addr())
+			if dedicated := client.coordinatorBrokers[id]; dedicated != nil {
+				safeAsyncClose(dedicated)
+				delete(client.coordinatorBrokers, id)
+			}
+			broker.log().warnf("client/broker remove invalid broker #%d with %s", broker.ID(), broker.Addr())
 		}
 	}
 }
@@ -635,18 +1118,22 @@ func (client *client) updateBroker(brokers []*Broker) {
 // or a previously registered Broker instance. You must hold the write lock before calling this function.
 func (client *client) registerBroker(broker *Broker) {
 	if client.brokers == nil {
-		Logger.Printf("cannot register broker #%d at %s, client already closed", broker.ID(), broker.Addr())
+		broker.log().warnf("cannot register broker #%d at %s, client already closed", broker.ID(), broker.Addr())
 		return
 	}
 
+	client.selectListener(broker)
 	if client.brokers[broker.ID()] == nil {
+		broker.events = client.events
 		client.brokers[broker.ID()] = broker
-		DebugLogger.Printf("client/brokers registered new broker #%d at %s", broker.ID(), broker.Addr())
+		broker.log().debugf("client/brokers registered new broker #%d at %s", broker.ID(), broker.Addr())
 	} else if broker.Addr() != client.brokers[broker.ID()].Addr() {
 		safeAsyncClose(client.brokers[broker.ID()])
+		broker.events = client.events
 		client.brokers[broker.ID()] = broker
-		Logger.Printf("client/brokers replaced registered broker #%d with %s", broker.ID(), broker.Addr())
+		broker.log().infof("client/brokers replaced registered broker #%d with %s", broker.ID(), broker.Addr())
 	}
+	client.publishRouting()
 }
 
 // deregisterBroker removes a broker from the seedsBroker list, and if it's
@@ -655,16 +1142,28 @@ func (client *client) deregisterBroker(broker *Broker) {
 	client.lock.Lock()
 	defer client.lock.Unlock()
 
-	if len(client.seedBrokers) > 0 && broker == client.seedBrokers[0] {
+	now := time.Now()
+	if client.brokerFailures == nil {
+		client.brokerFailures = make(map[string]time.Time)
+	}
+	for addr, failure := range client.brokerFailures {
+		if now.Sub(failure) >= brokerFailureBackoff {
+			delete(client.brokerFailures, addr)
+		}
+	}
+	client.brokerFailures[broker.Addr()] = now
+
+	if i := indexOfBroker(client.seedBrokers, broker); i >= 0 {
 		client.deadSeeds = append(client.deadSeeds, broker)
-		client.seedBrokers = client.seedBrokers[1:]
+		client.seedBrokers = append(client.seedBrokers[:i:i], client.seedBrokers[i+1:]...)
 	} else {
 		// we do this so that our loop in `tryRefreshMetadata` doesn't go on forever,
 		// but we really shouldn't have to; once that loop is made better this case can be
 		// removed, and the function generally can be renamed from `deregisterBroker` to
 		// `nextSeedBroker` or something
-		DebugLogger.Printf("client/brokers deregistered broker #%d at %s", broker.ID(), broker.Addr())
+		broker.log().debugf("client/brokers deregistered broker #%d at %s", broker.ID(), broker.Addr())
 		delete(client.brokers, broker.ID())
+		client.publishRouting()
 	}
 }
 
@@ -672,27 +1171,79 @@ func (client *client) resurrectDeadBrokers() {
 	client.lock.Lock()
 	defer client.lock.Unlock()
 
-	Logger.Printf("client/brokers resurrecting %d dead seed brokers", len(client.deadSeeds))
+	infof("client/brokers resurrecting %d dead seed brokers", len(client.deadSeeds))
 	client.seedBrokers = append(client.seedBrokers, client.deadSeeds...)
 	client.deadSeeds = nil
 }
 
+// any returns the least loaded broker to send a request to, among the seed
+// brokers and the brokers of the cluster. The brokers which failed a request
+// recently come last, followed by the ones the client is not connected to;
+// among the others, the broker with the fewest requests awaiting a response
+// wins, the seed brokers being preferred on a tie.
 func (client *client) any() *Broker {
 	client.lock.RLock()
-	defer client.lock.RUnlock()
+	candidates := make([]*Broker, 0, len(client.seedBrokers)+len(client.brokers))
+	candidates = append(candidates, client.seedBrokers...)
+	ids := make([]int32, 0, len(client.brokers))
+	for id := range client.brokers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		candidates = append(candidates, client.brokers[id])
+	}
+
+	now := time.Now()
+	var best *Broker
+	var bestLoad brokerLoad
+	for _, broker := range candidates {
+		failure, failed := client.brokerFailures[broker.Addr()]
+		load := brokerLoad{
+			failed:   failed && now.Sub(failure) < brokerFailureBackoff,
+			closed:   atomic.LoadInt32(&broker.opened) == 0,
+			requests: atomic.LoadInt32(&broker.pendingRequests),
+		}
+		if best == nil || load.less(bestLoad) {
+			best, bestLoad = broker, load
+		}
+	}
+	client.lock.RUnlock()
 
-	if len(client.seedBrokers) > 0 {
-		_ = client.seedBrokers[0].Open(client.conf)
-		return client.seedBrokers[0]
+	if best != nil {
+		_ = best.Open(client.conf)
 	}
+	return best
+}
 
-	// not guaranteed to be random *or* deterministic
-	for _, broker := range client.brokers {
-		_ = broker.Open(client.conf)
-		return broker
+// brokerFailureBackoff is how long a broker which failed a request is only
+// picked by any when no other broker is left.
+const brokerFailureBackoff = 10 * time.Second
+
+// brokerLoad ranks the brokers any picks from.
+type brokerLoad struct {
+	failed   bool
+	closed   bool
+	requests int32
+}
+
+func (l brokerLoad) less(other brokerLoad) bool {
+	if l.failed != other.failed {
+		return !l.failed
 	}
+	if l.closed != other.closed {
+		return !l.closed
+	}
+	return l.requests < other.requests
+}
 
-	return nil
+func indexOfBroker(brokers []*Broker, broker *Broker) int {
+	for i, b := range brokers {
+		if b == broker {
+			return i
+		}
+	}
+	return -1
 }
 
 // private caching/lazy metadata helpers
@@ -708,11 +1259,48 @@ const (
 	maxPartitionIndex
 )
 
-func (client *client) cachedMetadata(topic string, partitionID int32) *PartitionMetadata {
-	client.lock.RLock()
-	defer client.lock.RUnlock()
+// routingTable is an immutable copy of the brokers, the partition metadata and
+// the cached partition lists of a client. The lookups on the path of every
+// message, and Closed, read it rather than the client maps, so that hundreds
+// of producer and consumer goroutines don't contend on the client lock, nor
+// wait for the metadata updates holding it.
+type routingTable struct {
+	brokers    map[int32]*Broker
+	metadata   map[string]map[int32]*PartitionMetadata
+	partitions map[string][maxPartitionIndex][]int32
+}
 
-	partitions := client.metadata[topic]
+// publishRouting republishes the routing table from the client maps. You must
+// hold the write lock before calling this function. The partition maps of the
+// topics are shared, as updateMetadata replaces them rather than modifying
+// them.
+func (client *client) publishRouting() {
+	table := &routingTable{
+		metadata:   make(map[string]map[int32]*PartitionMetadata, len(client.metadata)),
+		partitions: make(map[string][maxPartitionIndex][]int32, len(client.cachedPartitionsResults)),
+	}
+	// the brokers stay nil once the client is closed
+	if client.brokers != nil {
+		table.brokers = make(map[int32]*Broker, len(client.brokers))
+	}
+	for id, broker := range client.brokers {
+		table.brokers[id] = broker
+	}
+	for topic, partitions := range client.metadata {
+		table.metadata[topic] = partitions
+	}
+	for topic, partitions := range client.cachedPartitionsResults {
+		table.partitions[topic] = partitions
+	}
+	client.routing.Store(table)
+}
+
+func (client *client) routingTable() *routingTable {
+	return client.routing.Load().(*routingTable)
+}
+
+func (client *client) cachedMetadata(topic string, partitionID int32) *PartitionMetadata {
+	partitions := client.routingTable().metadata[topic]
 	if partitions != nil {
 		return partitions[partitionID]
 	}
@@ -721,10 +1309,7 @@ func (client *client) cachedMetadata(topic string, partitionID int32) *Partition
 }
 
 func (client *client) cachedPartitions(topic string, partitionSet partitionType) []int32 {
-	client.lock.RLock()
-	defer client.lock.RUnlock()
-
-	partitions, exists := client.cachedPartitionsResults[topic]
+	partitions, exists := client.routingTable().partitions[topic]
 
 	if !exists {
 		return nil
@@ -752,17 +1337,16 @@ func (client *client) setPartitionCache(topic string, partitionSet partitionType
 }
 
 func (client *client) cachedLeader(topic string, partitionID int32) (*Broker, error) {
-	client.lock.RLock()
-	defer client.lock.RUnlock()
+	table := client.routingTable()
 
-	partitions := client.metadata[topic]
+	partitions := table.metadata[topic]
 	if partitions != nil {
 		metadata, ok := partitions[partitionID]
 		if ok {
 			if errors.Is(metadata.Err, ErrLeaderNotAvailable) {
 				return nil, ErrLeaderNotAvailable
 			}
-			b := client.brokers[metadata.Leader]
+			b := table.brokers[metadata.Leader]
 			if b == nil {
 				return nil, ErrLeaderNotAvailable
 			}
@@ -807,6 +1391,106 @@ func (client *client) getOffset(topic string, partitionID int32, time int64) (in
 	return block.Offsets[0], nil
 }
 
+// listOffsets looks up the offsets at the times of the partitions, sending a
+// single OffsetRequest of the given version to each leader, and passes the
+// blocks of the responses to found, one at a time. The partitions which failed
+// are retried once their metadata is refreshed; the first error of those which
+// failed again is returned.
+func (client *client) listOffsets(times map[string]map[int32]int64, version int16, found func(topic string, partitionID int32, block *OffsetResponseBlock) error) error {
+	failed, err := client.tryListOffsets(times, version, found)
+	if err == nil {
+		return nil
+	}
+
+	topics := make([]string, 0, len(failed))
+	for topic := range failed {
+		topics = append(topics, topic)
+	}
+	if err := client.RefreshMetadata(topics...); err != nil {
+		return err
+	}
+	_, err = client.tryListOffsets(failed, version, found)
+	return err
+}
+
+// tryListOffsets is a single attempt of listOffsets. It returns the times of
+// the partitions which failed, along with the first error.
+func (client *client) tryListOffsets(times map[string]map[int32]int64, version int16, found func(topic string, partitionID int32, block *OffsetResponseBlock) error) (map[string]map[int32]int64, error) {
+	var (
+		lock     sync.Mutex
+		failed   = make(map[string]map[int32]int64)
+		firstErr error
+	)
+	fail := func(topic string, partitionID int32, err error) {
+		if failed[topic] == nil {
+			failed[topic] = make(map[int32]int64)
+		}
+		failed[topic][partitionID] = times[topic][partitionID]
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	requests := make(map[*Broker]*OffsetRequest)
+	for topic, partitions := range times {
+		for partitionID, time := range partitions {
+			broker, err := client.Leader(topic, partitionID)
+			if err != nil {
+				fail(topic, partitionID, err)
+				continue
+			}
+			request, ok := requests[broker]
+			if !ok {
+				request = &OffsetRequest{Version: version}
+				if version >= 2 {
+					request.IsolationLevel = client.conf.Consumer.IsolationLevel
+				}
+				requests[broker] = request
+			}
+			request.AddBlock(topic, partitionID, time, 1)
+		}
+	}
+
+	var wg sync.WaitGroup
+	for broker, request := range requests {
+		broker, request := broker, request
+		wg.Add(1)
+		go withRecover(func() {
+			defer wg.Done()
+			response, err := broker.GetAvailableOffsets(request)
+
+			lock.Lock()
+			defer lock.Unlock()
+			if err != nil {
+				_ = broker.Close()
+			}
+			for topic, blocks := range request.blocks {
+				for partitionID := range blocks {
+					if err != nil {
+						fail(topic, partitionID, err)
+						continue
+					}
+					block := response.GetBlock(topic, partitionID)
+					switch {
+					case block == nil:
+						_ = broker.Close()
+						fail(topic, partitionID, ErrIncompleteResponse)
+					case !errors.Is(block.Err, ErrNoError):
+						fail(topic, partitionID, block.Err)
+					default:
+						if err := found(topic, partitionID, block); err != nil {
+							fail(topic, partitionID, err)
+						}
+					}
+				}
+			}
+		})
+	}
+	wg.Wait()
+
+	return failed, firstErr
+}
+
 // core metadata update logic
 
 func (client *client) backgroundMetadataUpdater() {
@@ -816,21 +1500,193 @@ func (client *client) backgroundMetadataUpdater() {
 		return
 	}
 
-	ticker := time.NewTicker(client.conf.Metadata.RefreshFrequency)
-	defer ticker.Stop()
+	interval := client.conf.Metadata.RefreshFrequency
+	timer := time.NewTimer(client.jitterRefreshInterval(interval))
+	defer timer.Stop()
 
+	var fingerprint uint64
 	for {
 		select {
-		case <-ticker.C:
-			if err := client.refreshMetadata(); err != nil {
-				Logger.Println("Client background metadata update:", err)
+		case <-timer.C:
+			client.expireIdleTopics()
+			client.resolveSeedBrokers()
+			err := client.refreshMetadata()
+			if err != nil {
+				warnf("Client background metadata update: %v", err)
+			}
+
+			// back off while the metadata does not change
+			previous := fingerprint
+			fingerprint = client.metadataFingerprint()
+			if err != nil || fingerprint != previous {
+				interval = client.conf.Metadata.RefreshFrequency
+			} else if maxInterval := client.conf.Metadata.MaxRefreshFrequency; interval < maxInterval {
+				interval *= 2
+				if interval > maxInterval {
+					interval = maxInterval
+				}
 			}
+			timer.Reset(client.jitterRefreshInterval(interval))
 		case <-client.closer:
 			return
 		}
 	}
 }
 
+// resolveSeedBrokers resolves the bootstrap addresses again when some of them
+// come from SRV records, replacing the seed brokers no longer listed by those
+// which appeared.
+func (client *client) resolveSeedBrokers() {
+	client.lock.RLock()
+	bootstrap := client.bootstrap
+	client.lock.RUnlock()
+	if bootstrap == nil {
+		return
+	}
+
+	addrs, err := client.conf.bootstrapAddrs(bootstrap)
+	if err != nil {
+		warnf("Client background bootstrap resolution: %v", err)
+		return
+	}
+
+	client.lock.Lock()
+	defer client.lock.Unlock()
+
+	current := make(map[string]*Broker, len(client.seedBrokers))
+	for _, broker := range client.seedBrokers {
+		current[broker.Addr()] = broker
+	}
+	dead := make(map[string]bool, len(client.deadSeeds))
+	for _, broker := range client.deadSeeds {
+		dead[broker.Addr()] = true
+	}
+
+	seeds := make([]*Broker, 0, len(addrs))
+	for _, addr := range addrs {
+		if broker, ok := current[addr]; ok {
+			seeds = append(seeds, broker)
+			delete(current, addr)
+		} else if !dead[addr] {
+			broker := NewBroker(addr)
+			broker.events = client.events
+			seeds = append(seeds, broker)
+			logWith(addrField(addr)).debugf("client/brokers added seed broker %s", addr)
+		}
+	}
+	for addr, broker := range current {
+		safeAsyncClose(broker)
+		debugf("client/brokers removed seed broker %s", addr)
+	}
+	client.seedBrokers = seeds
+}
+
+// jitterRefreshInterval randomly moves interval by up to
+// Metadata.RefreshJitter of it.
+func (client *client) jitterRefreshInterval(interval time.Duration) time.Duration {
+	jitter := client.conf.Metadata.RefreshJitter
+	if jitter <= 0 {
+		return interval
+	}
+	return time.Duration(float64(interval) * (1 + jitter*(2*rand.Float64()-1)))
+}
+
+// metadataFingerprint returns a hash of the cached cluster metadata, which
+// changes along with the brokers, the controller or the partitions.
+func (client *client) metadataFingerprint() uint64 {
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+
+	h := fnv.New64a()
+	write := func(values ...interface{}) {
+		for _, v := range values {
+			fmt.Fprint(h, v, "\x00")
+		}
+	}
+
+	ids := make([]int32, 0, len(client.brokers))
+	for id := range client.brokers {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	for _, id := range ids {
+		write(id, client.brokers[id].Addr())
+	}
+	write(client.controllerID)
+
+	topics := make([]string, 0, len(client.metadata))
+	for topic := range client.metadata {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+	for _, topic := range topics {
+		write(topic)
+		partitions := client.metadata[topic]
+		partitionIDs := make([]int32, 0, len(partitions))
+		for id := range partitions {
+			partitionIDs = append(partitionIDs, id)
+		}
+		sort.Slice(partitionIDs, func(i, j int) bool { return partitionIDs[i] < partitionIDs[j] })
+		for _, id := range partitionIDs {
+			p := partitions[id]
+			write(id, p.Leader, p.Replicas, p.Isr, p.OfflineReplicas, p.Err)
+		}
+	}
+	return h.Sum64()
+}
+
+// markTopicUsed records that the metadata of topic has just been looked up.
+func (client *client) markTopicUsed(topic string) {
+	if client.conf.Metadata.Full || client.conf.Metadata.MaxIdleTime <= 0 {
+		return
+	}
+	now := time.Now().UnixNano()
+	if lastUsed, ok := client.lastUsed.Load(topic); ok {
+		atomic.StoreInt64(lastUsed.(*int64), now)
+		return
+	}
+	if lastUsed, loaded := client.lastUsed.LoadOrStore(topic, &now); loaded {
+		atomic.StoreInt64(lastUsed.(*int64), now)
+	}
+}
+
+// expireIdleTopics discards the metadata of the topics which have not been
+// looked up for Metadata.MaxIdleTime, so that they are no longer refreshed.
+func (client *client) expireIdleTopics() {
+	if client.conf.Metadata.Full || client.conf.Metadata.MaxIdleTime <= 0 {
+		return
+	}
+	topics, err := client.MetadataTopics()
+	if err != nil {
+		return
+	}
+
+	now := time.Now().UnixNano()
+	var idle []string
+	for _, topic := range topics {
+		started := now
+		// tracked without having been looked up yet, start the clock
+		lastUsed, loaded := client.lastUsed.LoadOrStore(topic, &started)
+		if loaded && time.Duration(now-atomic.LoadInt64(lastUsed.(*int64))) >= client.conf.Metadata.MaxIdleTime {
+			idle = append(idle, topic)
+			client.lastUsed.Delete(topic)
+		}
+	}
+
+	if len(idle) == 0 {
+		return
+	}
+	debugf("client/metadata discarding the metadata of idle topics %v\n", idle)
+	client.lock.Lock()
+	defer client.lock.Unlock()
+	for _, topic := range idle {
+		delete(client.metadataTopics, topic)
+		delete(client.metadata, topic)
+		delete(client.cachedPartitionsResults, topic)
+	}
+	client.publishRouting()
+}
+
 func (client *client) refreshMetadata() error {
 	var topics []string
 
@@ -860,13 +1716,13 @@ func (client *client) tryRefreshMetadata(topics []string, attemptsRemaining int,
 		return false
 	}
 	retry := func(err error) error {
-		if attemptsRemaining > 0 {
+		if attemptsRemaining > 0 && client.conf.allowRetry("client/metadata") {
 			backoff := client.computeBackoff(attemptsRemaining)
 			if pastDeadline(backoff) {
-				Logger.Println("client/metadata skipping last retries as we would go past the metadata timeout")
+				infof("client/metadata skipping last retries as we would go past the metadata timeout")
 				return err
 			}
-			Logger.Printf("client/metadata retrying after %dms... (%d attempts remaining)\n", backoff/time.Millisecond, attemptsRemaining)
+			infof("client/metadata retrying after %dms... (%d attempts remaining)\n", backoff/time.Millisecond, attemptsRemaining)
 			if backoff > 0 {
 				time.Sleep(backoff)
 			}
@@ -880,14 +1736,16 @@ func (client *client) tryRefreshMetadata(topics []string, attemptsRemaining int,
 	for ; broker != nil && !pastDeadline(0); broker = client.any() {
 		allowAutoTopicCreation := client.conf.Metadata.AllowAutoTopicCreation
 		if len(topics) > 0 {
-			DebugLogger.Printf("client/metadata fetching metadata for %v from broker %s\n", topics, broker.addr)
+			broker.log().debugf("client/metadata fetching metadata for %v from broker %s\n", topics, broker.addr)
 		} else {
 			allowAutoTopicCreation = false
-			DebugLogger.Printf("client/metadata fetching metadata for all topics from broker %s\n", broker.addr)
+			broker.log().debugf("client/metadata fetching metadata for all topics from broker %s\n", broker.addr)
 		}
 
 		req := &MetadataRequest{Topics: topics, AllowAutoTopicCreation: allowAutoTopicCreation}
-		if client.conf.Version.IsAtLeast(V1_0_0_0) {
+		if client.conf.Version.IsAtLeast(V2_8_0_0) {
+			req.Version = 10
+		} else if client.conf.Version.IsAtLeast(V1_0_0_0) {
 			req.Version = 5
 		} else if client.conf.Version.IsAtLeast(V0_10_0_0) {
 			req.Version = 1
@@ -900,7 +1758,7 @@ func (client *client) tryRefreshMetadata(topics []string, attemptsRemaining int,
 			// valid response, use it
 			shouldRetry, err := client.updateMetadata(response, allKnownMetaData)
 			if shouldRetry {
-				Logger.Println("client/metadata found some partitions to be leaderless")
+				infof("client/metadata found some partitions to be leaderless")
 				return retry(err) // note: err can be nil
 			}
 			return err
@@ -910,21 +1768,21 @@ func (client *client) tryRefreshMetadata(topics []string, attemptsRemaining int,
 		} else if errors.As(err, &kerror) {
 			// if SASL auth error return as this _should_ be a non retryable err for all brokers
 			if errors.Is(err, ErrSASLAuthenticationFailed) {
-				Logger.Println("client/metadata failed SASL authentication")
+				warnf("client/metadata failed SASL authentication")
 				return err
 			}
 
 			if errors.Is(err, ErrTopicAuthorizationFailed) {
-				Logger.Println("client is not authorized to access this topic. The topics were: ", topics)
+				warnf("client is not authorized to access this topic. The topics were: %v", topics)
 				return err
 			}
 			// else remove that broker and try again
-			Logger.Printf("client/metadata got error from broker %d while fetching metadata: %v\n", broker.ID(), err)
+			broker.log().warnf("client/metadata got error from broker %d while fetching metadata: %v\n", broker.ID(), err)
 			_ = broker.Close()
 			client.deregisterBroker(broker)
 		} else {
 			// some other error, remove that broker and try again
-			Logger.Printf("client/metadata got error from broker %d while fetching metadata: %v\n", broker.ID(), err)
+			broker.log().warnf("client/metadata got error from broker %d while fetching metadata: %v\n", broker.ID(), err)
 			brokerErrors = append(brokerErrors, err)
 			_ = broker.Close()
 			client.deregisterBroker(broker)
@@ -933,24 +1791,27 @@ func (client *client) tryRefreshMetadata(topics []string, attemptsRemaining int,
 
 	error := Wrap(ErrOutOfBrokers, brokerErrors...)
 	if broker != nil {
-		Logger.Printf("client/metadata not fetching metadata from broker %s as we would go past the metadata timeout\n", broker.addr)
+		broker.log().warnf("client/metadata not fetching metadata from broker %s as we would go past the metadata timeout\n", broker.addr)
 		return retry(error)
 	}
 
-	Logger.Println("client/metadata no available broker to send metadata request to")
+	warnf("client/metadata no available broker to send metadata request to")
 	client.resurrectDeadBrokers()
 	return retry(error)
 }
 
 // if no fatal error, returns a list of topics that need retrying due to ErrLeaderNotAvailable
 func (client *client) updateMetadata(data *MetadataResponse, allKnownMetaData bool) (retry bool, err error) {
-	if client.Closed() {
-		return
-	}
-
 	client.lock.Lock()
 	defer client.lock.Unlock()
 
+	// the client may have been closed, by CloseContext, while the request
+	// was in flight
+	if client.brokers == nil {
+		return
+	}
+	defer client.publishRouting()
+
 	// For all the brokers we received:
 	// - if it is a new ID, save it
 	// - if it is an existing ID, but the address we have is stale, discard the old one and save it
@@ -959,7 +1820,11 @@ func (client *client) updateMetadata(data *MetadataResponse, allKnownMetaData bo
 	client.updateBroker(data.Brokers)
 
 	client.controllerID = data.ControllerID
+	if data.ClusterID != nil {
+		client.clusterID = *data.ClusterID
+	}
 
+	previousMetadata := client.metadata
 	if allKnownMetaData {
 		client.metadata = make(map[string]map[int32]*PartitionMetadata)
 		client.metadataTopics = make(map[string]none)
@@ -972,6 +1837,7 @@ func (client *client) updateMetadata(data *MetadataResponse, allKnownMetaData bo
 		if _, exists := client.metadataTopics[topic.Name]; !exists {
 			client.metadataTopics[topic.Name] = none{}
 		}
+		previous := previousMetadata[topic.Name]
 		delete(client.metadata, topic.Name)
 		delete(client.cachedPartitionsResults, topic.Name)
 
@@ -988,17 +1854,34 @@ func (client *client) updateMetadata(data *MetadataResponse, allKnownMetaData bo
 		case ErrLeaderNotAvailable: // retry, but store partial partition results
 			retry = true
 		default: // don't retry, don't store partial results
-			Logger.Printf("Unexpected topic-level metadata error: %s", topic.Err)
+			logWith(topicField(topic.Name)).warnf("Unexpected topic-level metadata error: %s", topic.Err)
 			err = topic.Err
 			continue
 		}
 
+		if topic.TopicID != (Uuid{}) {
+			if previousID, ok := client.topicIDs[topic.Name]; ok && previousID != topic.TopicID {
+				logWith(topicField(topic.Name)).infof("client/metadata topic %s was recreated, its ID changed from %s to %s\n", topic.Name, previousID, topic.TopicID)
+				client.events.publish(&ClientEvent{Type: EventTopicRecreated, Topic: topic.Name, Err: ErrTopicRecreated})
+			}
+			client.topicIDs[topic.Name] = topic.TopicID
+		}
+
 		client.metadata[topic.Name] = make(map[int32]*PartitionMetadata, len(topic.Partitions))
 		for _, partition := range topic.Partitions {
 			client.metadata[topic.Name][partition.ID] = partition
 			if errors.Is(partition.Err, ErrLeaderNotAvailable) {
 				retry = true
 			}
+			if old, ok := previous[partition.ID]; ok && old.Leader != partition.Leader {
+				client.events.publish(&ClientEvent{
+					Type:           EventLeaderChanged,
+					Topic:          topic.Name,
+					Partition:      partition.ID,
+					Leader:         partition.Leader,
+					PreviousLeader: old.Leader,
+				})
+			}
 		}
 
 		var partitionCache [maxPartitionIndex][]int32
@@ -1007,18 +1890,62 @@ func (client *client) updateMetadata(data *MetadataResponse, allKnownMetaData bo
 		client.cachedPartitionsResults[topic.Name] = partitionCache
 	}
 
+	client.events.publish(&ClientEvent{Type: EventMetadataUpdated, Err: err})
 	return
 }
 
 func (client *client) cachedCoordinator(consumerGroup string) *Broker {
 	client.lock.RLock()
-	defer client.lock.RUnlock()
+	coordinatorID, ok := client.coordinators[consumerGroup]
+	broker := client.brokers[coordinatorID]
+	dedicated := client.coordinatorBrokers[coordinatorID]
+	client.lock.RUnlock()
+	if !ok {
+		return nil
+	}
+	if broker == nil || !client.conf.Consumer.Group.DedicatedCoordinatorConnection {
+		return broker
+	}
+	if dedicated != nil && dedicated.Addr() == broker.Addr() {
+		return dedicated
+	}
+
+	// the dedicated connection is missing or stale
+	client.lock.Lock()
+	defer client.lock.Unlock()
 	if coordinatorID, ok := client.coordinators[consumerGroup]; ok {
-		return client.brokers[coordinatorID]
+		return client.coordinatorBroker(coordinatorID)
 	}
 	return nil
 }
 
+// coordinatorBroker returns the broker to talk to the coordinator with the
+// given ID through: a Broker of its own unless
+// Consumer.Group.DedicatedCoordinatorConnection is disabled, which is replaced
+// whenever the coordinator moves to another address. You must hold the write
+// lock before calling this function.
+func (client *client) coordinatorBroker(coordinatorID int32) *Broker {
+	broker := client.brokers[coordinatorID]
+	if broker == nil || !client.conf.Consumer.Group.DedicatedCoordinatorConnection {
+		return broker
+	}
+
+	dedicated := client.coordinatorBrokers[coordinatorID]
+	if dedicated == nil || dedicated.Addr() != broker.Addr() {
+		if dedicated != nil {
+			safeAsyncClose(dedicated)
+		}
+		dedicated = NewBroker(broker.Addr())
+		dedicated.id = broker.id
+		dedicated.rack = broker.rack
+		dedicated.events = client.events
+		dedicated.coordinatorConnection = true
+		client.coordinatorBrokers[coordinatorID] = dedicated
+		broker.log().debugf("client/coordinator dedicated a connection to coordinator #%d at %s\n", coordinatorID, broker.Addr())
+	}
+	return dedicated
+}
+
 func (client *client) cachedController() *Broker {
 	client.lock.RLock()
 	defer client.lock.RUnlock()
@@ -1026,6 +1953,13 @@ func (client *client) cachedController() *Broker {
 	return client.brokers[client.controllerID]
 }
 
+func (client *client) cachedClusterID() string {
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+
+	return client.clusterID
+}
+
 func (client *client) computeBackoff(attemptsRemaining int) time.Duration {
 	if client.conf.Metadata.Retry.BackoffFunc != nil {
 		maxRetries := client.conf.Metadata.Retry.Max
@@ -1037,9 +1971,9 @@ func (client *client) computeBackoff(attemptsRemaining int) time.Duration {
 
 func (client *client) getConsumerMetadata(consumerGroup string, attemptsRemaining int) (*FindCoordinatorResponse, error) {
 	retry := func(err error) (*FindCoordinatorResponse, error) {
-		if attemptsRemaining > 0 {
+		if attemptsRemaining > 0 && client.conf.allowRetry("client/coordinator") {
 			backoff := client.computeBackoff(attemptsRemaining)
-			Logger.Printf("client/coordinator retrying after %dms... (%d attempts remaining)\n", backoff/time.Millisecond, attemptsRemaining)
+			logWith(groupField(consumerGroup)).infof("client/coordinator retrying after %dms... (%d attempts remaining)\n", backoff/time.Millisecond, attemptsRemaining)
 			time.Sleep(backoff)
 			return client.getConsumerMetadata(consumerGroup, attemptsRemaining-1)
 		}
@@ -1048,7 +1982,7 @@ func (client *client) getConsumerMetadata(consumerGroup string, attemptsRemainin
 
 	brokerErrors := make([]error, 0)
 	for broker := client.any(); broker != nil; broker = client.any() {
-		DebugLogger.Printf("client/coordinator requesting coordinator for consumergroup %s from %s\n", consumerGroup, broker.Addr())
+		broker.log().with(groupField(consumerGroup)).debugf("client/coordinator requesting coordinator for consumergroup %s from %s\n", consumerGroup, broker.Addr())
 
 		request := new(FindCoordinatorRequest)
 		request.CoordinatorKey = consumerGroup
@@ -1056,7 +1990,7 @@ func (client *client) getConsumerMetadata(consumerGroup string, attemptsRemainin
 
 		response, err := broker.FindCoordinator(request)
 		if err != nil {
-			Logger.Printf("client/coordinator request to broker %s failed: %s\n", broker.Addr(), err)
+			broker.log().with(groupField(consumerGroup)).warnf("client/coordinator request to broker %s failed: %s\n", broker.Addr(), err)
 
 			var packetEncodingError PacketEncodingError
 			if errors.As(err, &packetEncodingError) {
@@ -1070,29 +2004,29 @@ func (client *client) getConsumerMetadata(consumerGroup string, attemptsRemainin
 		}
 
 		if errors.Is(response.Err, ErrNoError) {
-			DebugLogger.Printf("client/coordinator coordinator for consumergroup %s is #%d (%s)\n", consumerGroup, response.Coordinator.ID(), response.Coordinator.Addr())
+			response.Coordinator.log().with(groupField(consumerGroup)).debugf("client/coordinator coordinator for consumergroup %s is #%d (%s)\n", consumerGroup, response.Coordinator.ID(), response.Coordinator.Addr())
 			return response, nil
 		} else if errors.Is(response.Err, ErrConsumerCoordinatorNotAvailable) {
-			Logger.Printf("client/coordinator coordinator for consumer group %s is not available\n", consumerGroup)
+			logWith(groupField(consumerGroup)).warnf("client/coordinator coordinator for consumer group %s is not available\n", consumerGroup)
 
 			// This is very ugly, but this scenario will only happen once per cluster.
 			// The __consumer_offsets topic only has to be created one time.
 			// The number of partitions not configurable, but partition 0 should always exist.
 			if _, err := client.Leader("__consumer_offsets", 0); err != nil {
-				Logger.Printf("client/coordinator the __consumer_offsets topic is not initialized completely yet. Waiting 2 seconds...\n")
+				infof("client/coordinator the __consumer_offsets topic is not initialized completely yet. Waiting 2 seconds...\n")
 				time.Sleep(2 * time.Second)
 			}
 
 			return retry(ErrConsumerCoordinatorNotAvailable)
 		} else if errors.Is(response.Err, ErrGroupAuthorizationFailed) {
-			Logger.Printf("client was not authorized to access group %s while attempting to find coordinator", consumerGroup)
+			logWith(groupField(consumerGroup)).warnf("client was not authorized to access group %s while attempting to find coordinator", consumerGroup)
 			return retry(ErrGroupAuthorizationFailed)
 		} else {
 			return nil, response.Err
 		}
 	}
 
-	Logger.Println("client/coordinator no available broker to send consumer metadata request to")
+	warnf("client/coordinator no available broker to send consumer metadata request to")
 	client.resurrectDeadBrokers()
 	return retry(Wrap(ErrOutOfBrokers, brokerErrors...))
 }
@@ -1111,3 +2045,9 @@ type nopCloserClient struct {
 func (ncc *nopCloserClient) Close() error {
 	return nil
 }
+
+// CloseContext intercepts and purposely does not call the underlying
+// client's CloseContext() method.
+func (ncc *nopCloserClient) CloseContext(ctx context.Context) error {
+	return nil
+}
@@ -1,8 +1,11 @@
 package sarama
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"io"
+	"reflect"
 	"sync"
 	"sync/atomic"
 	"syscall"
@@ -62,6 +65,7 @@ func TestCachedPartitions(t *testing.T) {
 	// Verify we actually use the cache at all!
 	tmp[allPartitions] = []int32{1, 2, 3, 4}
 	client.cachedPartitionsResults["my_topic"] = tmp
+	client.publishRouting()
 	if len(client.cachedPartitions("my_topic", allPartitions)) != 4 {
 		t.Fatal("Not using the cache!")
 	}
@@ -351,6 +355,141 @@ func TestClientGetOffset(t *testing.T) {
 	safeClose(t, client)
 }
 
+func TestClientGetOffsets(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	leader1 := NewMockBroker(t, 2)
+	defer leader1.Close()
+	leader2 := NewMockBroker(t, 3)
+	defer leader2.Close()
+
+	metadata := new(MetadataResponse)
+	metadata.AddBroker(leader1.Addr(), leader1.BrokerID())
+	metadata.AddBroker(leader2.Addr(), leader2.BrokerID())
+	metadata.AddTopicPartition("foo", 0, leader1.BrokerID(), nil, nil, nil, ErrNoError)
+	metadata.AddTopicPartition("foo", 1, leader1.BrokerID(), nil, nil, nil, ErrNoError)
+	metadata.AddTopicPartition("foo", 2, leader2.BrokerID(), nil, nil, nil, ErrNoError)
+	metadata.AddTopicPartition("bar", 0, leader2.BrokerID(), nil, nil, nil, ErrNoError)
+	seedBroker.Returns(metadata)
+
+	client, err := NewClient([]string{seedBroker.Addr()}, NewTestConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	// a single request is expected by each leader
+	offsetResponse := new(OffsetResponse)
+	offsetResponse.AddTopicPartition("foo", 0, 100)
+	offsetResponse.AddTopicPartition("foo", 1, 101)
+	leader1.Returns(offsetResponse)
+	offsetResponse = new(OffsetResponse)
+	offsetResponse.AddTopicPartition("foo", 2, 102)
+	offsetResponse.AddTopicPartition("bar", 0, 200)
+	leader2.Returns(offsetResponse)
+
+	offsets, err := client.GetOffsets(map[string][]int32{"foo": {0, 1, 2}, "bar": {0}}, OffsetNewest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[int32]int64{
+		"foo": {0: 100, 1: 101, 2: 102},
+		"bar": {0: 200},
+	}
+	if !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("expected the offsets %v, got %v", expected, offsets)
+	}
+
+	// bar/0 moved to leader1, which is found once the metadata is refreshed
+	offsetResponse = new(OffsetResponse)
+	offsetResponse.AddTopicPartition("foo", 0, 110)
+	leader1.Returns(offsetResponse)
+	offsetResponse = new(OffsetResponse)
+	offsetResponse.AddTopicPartition("bar", 0, -1)
+	offsetResponse.GetBlock("bar", 0).Err = ErrNotLeaderForPartition
+	leader2.Returns(offsetResponse)
+
+	metadata = new(MetadataResponse)
+	metadata.AddBroker(leader1.Addr(), leader1.BrokerID())
+	metadata.AddBroker(leader2.Addr(), leader2.BrokerID())
+	metadata.AddTopicPartition("bar", 0, leader1.BrokerID(), nil, nil, nil, ErrNoError)
+	seedBroker.Returns(metadata)
+	offsetResponse = new(OffsetResponse)
+	offsetResponse.AddTopicPartition("bar", 0, 210)
+	leader1.Returns(offsetResponse)
+
+	offsets, err = client.GetOffsets(map[string][]int32{"foo": {0}, "bar": {0}}, OffsetNewest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected = map[string]map[int32]int64{
+		"foo": {0: 110},
+		"bar": {0: 210},
+	}
+	if !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("expected the offsets %v, got %v", expected, offsets)
+	}
+}
+
+func TestClientOffsetsForTimes(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	offsetResponse := &OffsetResponse{Version: 4}
+	offsetResponse.AddTopicPartition("foo", 0, 42)
+	offsetResponse.GetBlock("foo", 0).Timestamp = 1000
+	offsetResponse.GetBlock("foo", 0).LeaderEpoch = 7
+	// no message at or after the timestamp in foo/1
+	offsetResponse.AddTopicPartition("foo", 1, -1)
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetLeader("foo", 0, seedBroker.BrokerID()).
+			SetLeader("foo", 1, seedBroker.BrokerID()),
+		"OffsetRequest": NewMockWrapper(offsetResponse),
+	})
+
+	conf := NewTestConfig()
+	conf.Version = V2_1_0_0
+	client, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	offsets, err := client.OffsetsForTimes(map[string]map[int32]int64{"foo": {0: 900, 1: 900}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]map[int32]*OffsetAndTimestamp{
+		"foo": {0: {Offset: 42, Timestamp: 1000, LeaderEpoch: 7}},
+	}
+	if !reflect.DeepEqual(offsets, expected) {
+		t.Errorf("expected the offsets %v, got %v", expected, offsets)
+	}
+	for _, rr := range seedBroker.History() {
+		if request, ok := rr.Request.(*OffsetRequest); ok && request.Version != 4 {
+			t.Errorf("expected a version 4 OffsetRequest, got version %d", request.Version)
+		}
+	}
+}
+
+func TestClientOffsetsForTimesUnsupported(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.Returns(new(MetadataResponse))
+
+	client, err := NewClient([]string{seedBroker.Addr()}, NewTestConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	if _, err := client.OffsetsForTimes(map[string]map[int32]int64{"foo": {0: 900}}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected ErrUnsupportedVersion before Kafka 0.10.1, got %v", err)
+	}
+}
+
 func TestClientReceivingUnknownTopicWithBackoffFunc(t *testing.T) {
 	seedBroker := NewMockBroker(t, 1)
 
@@ -719,6 +858,254 @@ func TestClientController(t *testing.T) {
 	})
 }
 
+func TestClientClusterIdentity(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	controller := NewMockBroker(t, 2)
+	defer controller.Close()
+
+	clusterID := "my-cluster"
+	rack := "rack-a"
+	metadata := &MetadataResponse{Version: 5, ClusterID: &clusterID, ControllerID: controller.BrokerID()}
+	metadata.AddBroker(seedBroker.Addr(), seedBroker.BrokerID())
+	metadata.AddBroker(controller.Addr(), controller.BrokerID())
+	metadata.Brokers[1].rack = &rack
+	seedBroker.Returns(metadata)
+
+	conf := NewTestConfig()
+	conf.Version = V1_0_0_0
+	client, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	if id, err := client.ClusterID(); err != nil || id != clusterID {
+		t.Errorf("expected the cluster ID %q, got %q (%v)", clusterID, id, err)
+	}
+	if id, err := client.ControllerID(); err != nil || id != controller.BrokerID() {
+		t.Errorf("expected the controller #%d, got #%d (%v)", controller.BrokerID(), id, err)
+	}
+	if racks := client.Racks(); !reflect.DeepEqual(racks, map[int32]string{controller.BrokerID(): rack}) {
+		t.Errorf("expected the controller to be in %s only, got %v", rack, racks)
+	}
+}
+
+func TestClientApiVersions(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	leader1 := NewMockBroker(t, 2)
+	defer leader1.Close()
+	leader2 := NewMockBroker(t, 3)
+	defer leader2.Close()
+
+	metadata := NewMockMetadataResponse(t).
+		SetBroker(leader1.Addr(), leader1.BrokerID()).
+		SetBroker(leader2.Addr(), leader2.BrokerID()).
+		SetLeader("my_topic", 0, leader1.BrokerID()).
+		SetLeader("my_topic", 1, leader2.BrokerID())
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest":    metadata,
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+	})
+	leader1.SetHandlerByMap(map[string]MockResponse{
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+	})
+	leader2.SetHandlerByMap(map[string]MockResponse{
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t).SetApiKeys([]ApiVersionsResponseKey{
+			{ApiKey: 1, MinVersion: 4, MaxVersion: 9},
+		}),
+	})
+
+	conf := NewTestConfig()
+	conf.Version = V2_4_0_0
+	client, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	if _, _, ok := client.ApiVersionRange(1); ok {
+		t.Error("expected no versions before connecting to the brokers")
+	}
+	for partition := int32(0); partition < 2; partition++ {
+		if _, err := client.Leader("my_topic", partition); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// the versions are requested once the brokers are connected
+	deadline := time.Now().Add(5 * time.Second)
+	for len(client.BrokerApiVersions()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	apiVersions := client.BrokerApiVersions()
+	if len(apiVersions) != 2 || apiVersions[leader2.BrokerID()][1].MaxVersion != 9 {
+		t.Fatalf("expected the versions of both the leaders, got %v", apiVersions)
+	}
+
+	if minVersion, maxVersion, ok := client.ApiVersionRange(1); !ok || minVersion != 7 || maxVersion != 9 {
+		t.Errorf("expected the versions 7 to 9 of the API 1, got %d to %d (%v)", minVersion, maxVersion, ok)
+	}
+	if _, _, ok := client.ApiVersionRange(0); ok {
+		t.Error("expected the API 0 to be unsupported by #3")
+	}
+	broker, _ := client.Broker(leader2.BrokerID())
+	if minVersion, maxVersion, ok := broker.ApiVersionRange(1); !ok || minVersion != 4 || maxVersion != 9 {
+		t.Errorf("expected #3 to support the versions 4 to 9 of the API 1, got %d to %d (%v)", minVersion, maxVersion, ok)
+	}
+}
+
+func TestClientMetadataSnapshot(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	leader := NewMockBroker(t, 2)
+	defer leader.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(leader.Addr(), leader.BrokerID()).
+			SetLeader("my_topic", 0, leader.BrokerID()).
+			SetLeader("my_topic", 1, leader.BrokerID()),
+	})
+
+	conf := NewTestConfig()
+	client, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot, err := client.MetadataSnapshot()
+	safeClose(t, client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	requests := len(seedBroker.History())
+	client, err = NewClientFromMetadataSnapshot([]string{seedBroker.Addr()}, conf, snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	if partitions, err := client.Partitions("my_topic"); err != nil || !reflect.DeepEqual(partitions, []int32{0, 1}) {
+		t.Errorf("expected the partitions of the snapshot, got %v (%v)", partitions, err)
+	}
+	broker, err := client.Leader("my_topic", 1)
+	if err != nil || broker.ID() != leader.BrokerID() || broker.Addr() != leader.Addr() {
+		t.Errorf("expected the leader of the snapshot, got %v (%v)", broker, err)
+	}
+	if len(seedBroker.History()) != requests {
+		t.Error("expected the client seeded from the snapshot not to request the metadata")
+	}
+
+	if _, err := NewClientFromMetadataSnapshot([]string{seedBroker.Addr()}, conf, []byte{0, 1}); err == nil {
+		t.Error("expected an invalid snapshot to be rejected")
+	}
+}
+
+func TestClientListenerPreference(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	leader := NewMockBroker(t, 2)
+	defer leader.Close()
+
+	// the brokers advertise an unreachable listener, whose addresses are
+	// mapped to those of the mock brokers
+	advertised := map[string]string{"internal-1:9092": seedBroker.Addr(), "internal-2:9092": leader.Addr()}
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker("internal-1:9092", seedBroker.BrokerID()).
+			SetBroker("internal-2:9092", leader.BrokerID()).
+			SetLeader("my_topic", 0, leader.BrokerID()),
+	})
+
+	conf := NewTestConfig()
+	conf.Net.Listeners.Preference = []string{"MISSING", "EXTERNAL"}
+	conf.Net.Listeners.Addresses = map[string]ListenerAddressFunc{
+		"MISSING": func(id int32, addr string) string { return "" },
+		"EXTERNAL": func(id int32, addr string) string {
+			return advertised[addr]
+		},
+	}
+	client, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	broker, err := client.Leader("my_topic", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if broker.Addr() != leader.Addr() {
+		t.Errorf("expected the leader at the address of the preferred listener %s, got %s", leader.Addr(), broker.Addr())
+	}
+
+	snapshot, err := client.MetadataSnapshot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := new(metadataSnapshot)
+	if err := decode(snapshot, s); err != nil {
+		t.Fatal(err)
+	}
+	for _, b := range s.Metadata.Brokers {
+		if _, ok := advertised[b.Addr()]; !ok {
+			t.Errorf("expected the snapshot to hold the advertised addresses, got %s", b.Addr())
+		}
+	}
+}
+
+func TestClientTopicRecreated(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	topicID := Uuid{1}
+	metadata := NewMockMetadataResponse(t).
+		SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+		SetLeader("my_topic", 0, seedBroker.BrokerID()).
+		SetTopicID("my_topic", topicID)
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest":    metadata,
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+	})
+
+	conf := NewTestConfig()
+	conf.Version = V2_8_0_0
+	conf.Metadata.RefreshFrequency = 0
+	client, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	if id, err := client.TopicID("my_topic"); err != nil || id != topicID {
+		t.Errorf("expected the ID %s, got %s (%v)", topicID, id, err)
+	}
+
+	events := make(chan *ClientEvent, 10)
+	client.Subscribe(func(event *ClientEvent) {
+		if event.Type == EventTopicRecreated {
+			events <- event
+		}
+	})
+
+	recreatedID := Uuid{2}
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest":    metadata.SetTopicID("my_topic", recreatedID),
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+	})
+	if err := client.RefreshMetadata("my_topic"); err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); event.Topic != "my_topic" || !errors.Is(event.Err, ErrTopicRecreated) {
+		t.Errorf("expected my_topic to be recreated, got %+v", event)
+	}
+	if id, _ := client.TopicID("my_topic"); id != recreatedID {
+		t.Errorf("expected the ID %s, got %s", recreatedID, id)
+	}
+}
+
 func TestClientMetadataTimeout(t *testing.T) {
 	tests := []struct {
 		name    string
@@ -883,6 +1270,65 @@ func TestClientCoordinatorWithConsumerOffsetsTopic(t *testing.T) {
 	safeClose(t, client)
 }
 
+func TestClientDedicatedCoordinatorConnection(t *testing.T) {
+	for _, dedicated := range []bool{true, false} {
+		seedBroker := NewMockBroker(t, 1)
+		seedBroker.SetHandlerByMap(map[string]MockResponse{
+			"MetadataRequest": NewMockMetadataResponse(t).
+				SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+				SetLeader("my_topic", 0, seedBroker.BrokerID()),
+			"FindCoordinatorRequest": NewMockFindCoordinatorResponse(t).
+				SetCoordinator(CoordinatorGroup, "my_group", seedBroker),
+		})
+
+		conf := NewTestConfig()
+		conf.Consumer.Group.DedicatedCoordinatorConnection = dedicated
+		client, err := NewClient([]string{seedBroker.Addr()}, conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		leader, err := client.Leader("my_topic", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		coordinator, err := client.Coordinator("my_group")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if coordinator.ID() != leader.ID() || coordinator.Addr() != leader.Addr() {
+			t.Errorf("expected the leader #%d to coordinate the group, got #%d", leader.ID(), coordinator.ID())
+		}
+		if (coordinator != leader) != dedicated {
+			t.Errorf("expected a dedicated connection to the coordinator: %v", dedicated)
+		}
+		if cached, _ := client.Coordinator("my_group"); cached != coordinator {
+			t.Error("expected the connection to the coordinator to be reused")
+		}
+
+		if dedicated {
+			if _, err := leader.Connected(); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := coordinator.Connected(); err != nil {
+				t.Fatal(err)
+			}
+			if conf.MetricRegistry.Get("request-rate-for-broker-2147483646") == nil {
+				t.Error("expected the dedicated connection to have metrics of its own")
+			}
+			if err := coordinator.Close(); err != nil {
+				t.Error(err)
+			}
+			if conf.MetricRegistry.Get("request-rate-for-broker-1") == nil {
+				t.Error("expected the metrics of the leader to outlive the dedicated connection")
+			}
+		}
+
+		safeClose(t, client)
+		seedBroker.Close()
+	}
+}
+
 func TestClientCoordinatorWithoutConsumerOffsetsTopic(t *testing.T) {
 	seedBroker := NewMockBroker(t, 1)
 	coordinator := NewMockBroker(t, 2)
@@ -979,6 +1425,213 @@ func TestClientAutorefreshShutdownRace(t *testing.T) {
 	time.Sleep(10 * time.Millisecond)
 }
 
+func TestClientExpireIdleTopics(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetLeader("foo", 0, seedBroker.BrokerID()).
+			SetLeader("bar", 0, seedBroker.BrokerID()),
+	})
+
+	conf := NewTestConfig()
+	conf.Metadata.Full = false
+	conf.Metadata.RefreshFrequency = 0
+	conf.Metadata.MaxIdleTime = time.Minute
+	c, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, c)
+	client := c.(*client)
+
+	for _, topic := range []string{"foo", "bar"} {
+		if _, err := client.Partitions(topic); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	idleSince := time.Now().Add(-2 * time.Minute).UnixNano()
+	client.lastUsed.Store("bar", &idleSince)
+	client.expireIdleTopics()
+
+	if topics, _ := client.MetadataTopics(); len(topics) != 1 || topics[0] != "foo" {
+		t.Errorf("expected only foo to be kept, got %v", topics)
+	}
+	if topics, _ := client.Topics(); len(topics) != 1 || topics[0] != "foo" {
+		t.Errorf("expected the metadata of bar to be discarded, got %v", topics)
+	}
+
+	// looking bar up again fetches its metadata
+	if partitions, err := client.Partitions("bar"); err != nil || len(partitions) != 1 {
+		t.Errorf("expected bar to be refreshed, got %v %v", partitions, err)
+	}
+	if topics, _ := client.MetadataTopics(); len(topics) != 2 {
+		t.Errorf("expected bar to be tracked again, got %v", topics)
+	}
+}
+
+func TestClientMetadataFingerprint(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	metadata := NewMockMetadataResponse(t).
+		SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+		SetLeader("foo", 0, seedBroker.BrokerID())
+	seedBroker.SetHandlerByMap(map[string]MockResponse{"MetadataRequest": metadata})
+
+	conf := NewTestConfig()
+	conf.Metadata.RefreshFrequency = 0
+	c, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, c)
+	client := c.(*client)
+
+	fingerprint := client.metadataFingerprint()
+	if err := client.RefreshMetadata(); err != nil {
+		t.Fatal(err)
+	}
+	if client.metadataFingerprint() != fingerprint {
+		t.Error("expected the fingerprint of unchanged metadata to be stable")
+	}
+
+	metadata.SetLeader("foo", 1, seedBroker.BrokerID())
+	if err := client.RefreshMetadata(); err != nil {
+		t.Fatal(err)
+	}
+	if client.metadataFingerprint() == fingerprint {
+		t.Error("expected the fingerprint to change along with the partitions")
+	}
+}
+
+func TestClientJitterRefreshInterval(t *testing.T) {
+	conf := NewTestConfig()
+	conf.Metadata.RefreshJitter = 0.2
+	client := &client{conf: conf}
+
+	for i := 0; i < 100; i++ {
+		if interval := client.jitterRefreshInterval(time.Minute); interval < 48*time.Second || interval > 72*time.Second {
+			t.Fatalf("expected the interval to be within 20%% of a minute, got %v", interval)
+		}
+	}
+}
+
+func TestClientLeastLoadedBroker(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	broker2 := NewMockBroker(t, 2)
+	defer broker2.Close()
+	broker3 := NewMockBroker(t, 3)
+	defer broker3.Close()
+
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(broker2.Addr(), broker2.BrokerID()).
+			SetBroker(broker3.Addr(), broker3.BrokerID()),
+	})
+
+	conf := NewTestConfig()
+	conf.Metadata.RefreshFrequency = 0
+	c, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, c)
+	client := c.(*client)
+
+	seed := client.seedBrokers[0]
+	if b := client.LeastLoadedBroker(); b != seed {
+		t.Fatalf("expected the connected seed broker, got %s", b.Addr())
+	}
+
+	// connected brokers with fewer requests in flight are preferred
+	atomic.StoreInt32(&seed.pendingRequests, 2)
+	b2, _ := client.Broker(2)
+	b3, _ := client.Broker(3)
+	if b := client.LeastLoadedBroker(); b != b2 {
+		t.Errorf("expected broker #2, got %s", b.Addr())
+	}
+	atomic.StoreInt32(&b2.pendingRequests, 1)
+	if b := client.LeastLoadedBroker(); b != b3 {
+		t.Errorf("expected broker #3, got %s", b.Addr())
+	}
+
+	// brokers which failed recently are avoided
+	client.lock.Lock()
+	client.brokerFailures[b3.Addr()] = time.Now()
+	client.lock.Unlock()
+	if b := client.LeastLoadedBroker(); b != b2 {
+		t.Errorf("expected broker #2, got %s", b.Addr())
+	}
+
+	atomic.StoreInt32(&seed.pendingRequests, 0)
+	atomic.StoreInt32(&b2.pendingRequests, 0)
+}
+
+// delayedMockResponse delays the responses of a MockResponse by delay
+// nanoseconds, which can be changed while the broker is serving.
+type delayedMockResponse struct {
+	MockResponse
+	delay int64
+}
+
+func (r *delayedMockResponse) For(reqBody versionedDecoder) encoderWithHeader {
+	time.Sleep(time.Duration(atomic.LoadInt64(&r.delay)))
+	return r.MockResponse.For(reqBody)
+}
+
+func TestClientCloseContext(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	metadataResponse := &delayedMockResponse{MockResponse: NewMockMetadataResponse(t)}
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": metadataResponse,
+	})
+
+	conf := NewTestConfig()
+	conf.Metadata.RefreshFrequency = 0
+
+	closeWhileRefreshing := func(latency, deadline time.Duration) error {
+		c, err := NewClient([]string{seedBroker.Addr()}, conf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		seed := c.(*client).seedBrokers[0]
+
+		atomic.StoreInt64(&metadataResponse.delay, int64(latency))
+		defer atomic.StoreInt64(&metadataResponse.delay, 0)
+		go func() { _ = c.RefreshMetadata() }()
+		for atomic.LoadInt32(&seed.pendingRequests) == 0 {
+			time.Sleep(time.Millisecond)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), deadline)
+		defer cancel()
+		err = c.CloseContext(ctx)
+		if !c.Closed() {
+			t.Error("expected the client to be closed")
+		}
+		return err
+	}
+
+	// the request in flight completes before the deadline
+	if err := closeWhileRefreshing(50*time.Millisecond, 5*time.Second); err != nil {
+		t.Errorf("expected the client to close cleanly, got %v", err)
+	}
+
+	// the request in flight is abandoned at the deadline
+	err := closeWhileRefreshing(time.Second, 50*time.Millisecond)
+	var abandoned *CloseAbandonedError
+	if !errors.As(err, &abandoned) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected a CloseAbandonedError, got %v", err)
+	}
+	if len(abandoned.Brokers) != 1 || abandoned.Brokers[0] != seedBroker.Addr() {
+		t.Errorf("expected the seed broker to be abandoned, got %v", abandoned.Brokers)
+	}
+}
+
 func TestClientConnectionRefused(t *testing.T) {
 	t.Parallel()
 	seedBroker := NewMockBroker(t, 1)
@@ -1049,3 +1702,100 @@ func TestInitProducerIDConnectionRefused(t *testing.T) {
 
 	safeClose(t, client)
 }
+
+func newBenchmarkClient(b *testing.B) (Client, *MetadataResponse, func()) {
+	seedBroker := NewMockBroker(nil, 1)
+	leader := NewMockBroker(nil, 2)
+
+	metadataResponse := new(MetadataResponse)
+	metadataResponse.AddBroker(leader.Addr(), leader.BrokerID())
+	for topic := 0; topic < 10; topic++ {
+		for partition := int32(0); partition < 32; partition++ {
+			metadataResponse.AddTopicPartition(fmt.Sprintf("topic-%d", topic), partition, leader.BrokerID(), nil, nil, nil, ErrNoError)
+		}
+	}
+	seedBroker.Returns(metadataResponse)
+
+	config := NewTestConfig()
+	config.Metadata.Full = true
+	client, err := NewClient([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		b.Fatal(err)
+	}
+	return client, metadataResponse, func() {
+		_ = client.Close()
+		leader.Close()
+		seedBroker.Close()
+	}
+}
+
+// BenchmarkClientLeader measures the leader lookups of many goroutines, as
+// done for every message produced.
+func BenchmarkClientLeader(b *testing.B) {
+	client, _, closeClient := newBenchmarkClient(b)
+	defer closeClient()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int32(0)
+		for pb.Next() {
+			if _, err := client.Leader("topic-3", i%32); err != nil {
+				b.Error(err)
+			}
+			i++
+		}
+	})
+}
+
+// BenchmarkClientLeaderDuringMetadataUpdates measures the leader lookups of
+// many goroutines while the metadata keeps being updated, which doesn't stall
+// them.
+func BenchmarkClientLeaderDuringMetadataUpdates(b *testing.B) {
+	c, metadataResponse, closeClient := newBenchmarkClient(b)
+	defer closeClient()
+	client := c.(*client)
+
+	done := make(chan none)
+	updated := make(chan none)
+	go func() {
+		defer close(updated)
+		for {
+			select {
+			case <-done:
+				return
+			default:
+				_, _ = client.updateMetadata(metadataResponse, true)
+			}
+		}
+	}()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		i := int32(0)
+		for pb.Next() {
+			if _, err := client.Leader("topic-3", i%32); err != nil {
+				b.Error(err)
+			}
+			i++
+		}
+	})
+	b.StopTimer()
+	close(done)
+	<-updated
+}
+
+// BenchmarkClientPartitions measures the partition lookups of many
+// goroutines, as done by the partitioners.
+func BenchmarkClientPartitions(b *testing.B) {
+	client, _, closeClient := newBenchmarkClient(b)
+	defer closeClient()
+
+	b.ResetTimer()
+	b.RunParallel(func(pb *testing.PB) {
+		for pb.Next() {
+			if _, err := client.WritablePartitions("topic-3"); err != nil {
+				b.Error(err)
+			}
+		}
+	})
+}
@@ -10,6 +10,8 @@ import (
 	"net"
 	"testing"
 	"time"
+
+	"github.com/rcrowley/go-metrics"
 )
 
 func TestTLS(t *testing.T) {
@@ -211,6 +213,9 @@ func doListenerTLSTest(t *testing.T, expectSuccess bool, serverConfig, clientCon
 		if err != nil {
 			t.Fatal(err)
 		}
+		if handshakes := config.MetricRegistry.Get("tls-handshake-time-in-ms").(metrics.Histogram); handshakes.Count() == 0 {
+			t.Error("expected the TLS handshake to be timed")
+		}
 	} else {
 		if err == nil {
 			t.Fatal("expected failure")
@@ -0,0 +1,87 @@
+package sarama
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// closePollInterval is how often Client.CloseContext checks whether requests
+// are still in flight.
+const closePollInterval = 10 * time.Millisecond
+
+// CloseAbandonedError is returned by the CloseContext methods when their
+// context is done before everything was drained. The resources it lists were
+// abandoned and their connections torn down regardless.
+type CloseAbandonedError struct {
+	// Err is the error of the context.
+	Err error
+	// Brokers are the addresses of the brokers whose connections were closed
+	// while requests were in flight.
+	Brokers []string
+	// Messages is the number of produced messages which were neither
+	// delivered nor failed yet.
+	Messages int
+	// Partitions are the partitions, keyed by topic, whose consumers had not
+	// stopped yet.
+	Partitions map[string][]int32
+	// Errors are the errors reported before the context was done.
+	Errors []error
+}
+
+func (e *CloseAbandonedError) Error() string {
+	var abandoned []string
+	if e.Messages > 0 {
+		abandoned = append(abandoned, fmt.Sprintf("%d messages in flight", e.Messages))
+	}
+	if len(e.Partitions) > 0 {
+		abandoned = append(abandoned, fmt.Sprintf("the consumers of %v", e.Partitions))
+	}
+	if len(e.Brokers) > 0 {
+		abandoned = append(abandoned, "requests in flight to "+strings.Join(e.Brokers, ", "))
+	}
+	if len(abandoned) == 0 {
+		return fmt.Sprintf("kafka: close did not complete: %s", e.Err)
+	}
+	return fmt.Sprintf("kafka: close abandoned %s: %s", strings.Join(abandoned, ", "), e.Err)
+}
+
+func (e *CloseAbandonedError) Unwrap() error {
+	return e.Err
+}
+
+// merge adds the resources abandoned by err, if it is a CloseAbandonedError,
+// to e.
+func (e *CloseAbandonedError) merge(err error) {
+	var other *CloseAbandonedError
+	if !errors.As(err, &other) {
+		return
+	}
+	e.Brokers = append(e.Brokers, other.Brokers...)
+	e.Messages += other.Messages
+	for topic, partitions := range other.Partitions {
+		if e.Partitions == nil {
+			e.Partitions = make(map[string][]int32)
+		}
+		e.Partitions[topic] = append(e.Partitions[topic], partitions...)
+	}
+	e.Errors = append(e.Errors, other.Errors...)
+}
+
+// waitContext calls wait and returns once it does, or with the error of ctx
+// if it is done first, leaving wait running in the background.
+func waitContext(ctx context.Context, wait func()) error {
+	done := make(chan none)
+	go withRecover(func() {
+		wait()
+		close(done)
+	})
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
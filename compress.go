@@ -6,7 +6,6 @@ import (
 	"fmt"
 	"sync"
 
-	snappy "github.com/eapache/go-xerial-snappy"
 	"github.com/pierrec/lz4"
 )
 
@@ -105,7 +104,25 @@ var (
 	}
 )
 
-func compress(cc CompressionCodec, level int, data []byte) ([]byte, error) {
+// compressionOptions are the settings of the codecs beyond the level, see
+// Producer.Zstd and Producer.SnappyFraming.
+type compressionOptions struct {
+	zstd          ZstdEncoderParams
+	snappyFraming SnappyFraming
+}
+
+func (c *Config) compressionOptions() compressionOptions {
+	return compressionOptions{
+		zstd: ZstdEncoderParams{
+			WindowSize:  c.Producer.Zstd.WindowSize,
+			Concurrency: c.Producer.Zstd.Concurrency,
+			LowMemory:   c.Producer.Zstd.LowMemory,
+		},
+		snappyFraming: c.Producer.SnappyFraming,
+	}
+}
+
+func compress(cc CompressionCodec, level int, opts compressionOptions, data []byte) ([]byte, error) {
 	switch cc {
 	case CompressionNone:
 		return data, nil
@@ -171,7 +188,7 @@ func compress(cc CompressionCodec, level int, data []byte) ([]byte, error) {
 		}
 		return buf.Bytes(), nil
 	case CompressionSnappy:
-		return snappy.Encode(data), nil
+		return snappyEncode(opts.snappyFraming, data), nil
 	case CompressionLZ4:
 		writer := lz4WriterPool.Get().(*lz4.Writer)
 		defer lz4WriterPool.Put(writer)
@@ -187,7 +204,9 @@ func compress(cc CompressionCodec, level int, data []byte) ([]byte, error) {
 		}
 		return buf.Bytes(), nil
 	case CompressionZSTD:
-		return zstdCompress(ZstdEncoderParams{level}, nil, data)
+		zstdParams := opts.zstd
+		zstdParams.Level = level
+		return zstdCompress(zstdParams, nil, data)
 	default:
 		return nil, PacketEncodingError{fmt.Sprintf("unsupported compression codec (%d)", cc)}
 	}
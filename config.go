@@ -2,13 +2,16 @@ package sarama
 
 import (
 	"compress/gzip"
+	"context"
 	"crypto/tls"
 	"fmt"
 	"io"
 	"net"
 	"regexp"
+	"strconv"
 	"time"
 
+	"github.com/klauspost/compress/zstd"
 	"github.com/rcrowley/go-metrics"
 	"golang.org/x/net/proxy"
 )
@@ -24,9 +27,15 @@ type Config struct {
 		Retry struct {
 			// The total number of times to retry sending (retriable) admin requests (default 5).
 			// Similar to the `retries` setting of the JVM AdminClientConfig.
+			// Requests sent to the controller are retried against a freshly resolved
+			// controller when it has moved or cannot be reached.
 			Max int
 			// Backoff time between retries of a failed request (default 100ms)
 			Backoff time.Duration
+			// Called to compute backoff time dynamically, e.g. by
+			// NewExponentialBackoff. This takes precedence over `Backoff` if
+			// set.
+			BackoffFunc func(retries, maxRetries int) time.Duration
 		}
 		// The maximum duration the administrative Kafka client will wait for ClusterAdmin operations,
 		// including topics, brokers, configurations and ACLs (defaults to 3 seconds).
@@ -66,7 +75,8 @@ type Config struct {
 			// (defaults to false).
 			Enable bool
 			// SASLMechanism is the name of the enabled SASL mechanism.
-			// Possible values: OAUTHBEARER, PLAIN (defaults to PLAIN).
+			// Possible values: OAUTHBEARER, PLAIN, SCRAM-SHA-256, SCRAM-SHA-512,
+			// GSSAPI and AWS_MSK_IAM (defaults to PLAIN).
 			Mechanism SASLMechanism
 			// Version is the SASL Protocol Version to use
 			// Kafka > 1.x should use V1, except on Azure EventHub which use V0
@@ -93,16 +103,43 @@ type Config struct {
 			// TokenProvider is a user-defined callback for generating
 			// access tokens for SASL/OAUTHBEARER auth. See the
 			// AccessTokenProvider interface docs for proper implementation
-			// guidelines.
+			// guidelines. NewClientCredentialsTokenProvider provides tokens
+			// through the OAuth2 client_credentials grant.
 			TokenProvider AccessTokenProvider
 
 			GSSAPI GSSAPIConfig
+
+			// AWSMSKIAM configures the AWS_MSK_IAM mechanism used by Amazon MSK
+			// clusters with IAM access control.
+			AWSMSKIAM AWSMSKIAMConfig
 		}
 
 		// KeepAlive specifies the keep-alive period for an active network connection (defaults to 0).
 		// If zero or positive, keep-alives are enabled.
 		// If negative, keep-alives are disabled.
 		KeepAlive time.Duration
+		// KeepAliveInterval is the time between keep-alive probes once a
+		// connection has been idle for the KeepAlive period, and
+		// KeepAliveCount the number of unanswered probes after which it is
+		// dropped (default 0, the operating system defaults).
+		KeepAliveInterval time.Duration
+		KeepAliveCount    int
+
+		// ReadBufferSize and WriteBufferSize are the sizes, in bytes, of the
+		// socket receive and send buffers, as `receive.buffer.bytes` and
+		// `send.buffer.bytes` in the JVM version (default 0, the operating
+		// system defaults). Larger buffers help on links with a high
+		// bandwidth-delay product.
+		ReadBufferSize  int
+		WriteBufferSize int
+
+		// DSCP is the Differentiated Services Code Point the packets sent to
+		// the brokers are marked with, from 0 to 63 (default 0, unmarked).
+		DSCP int
+
+		// The socket options above are set by the default dialer, but not when
+		// dialing through a proxy or DialContext, and are only supported on
+		// Linux.
 
 		// LocalAddr is the local address to use when dialing an
 		// address. The address must be of a compatible type for the
@@ -110,12 +147,103 @@ type Config struct {
 		// If nil, a local address is automatically chosen.
 		LocalAddr net.Addr
 
+		// LenientDecoding makes the responses of the brokers decode even when
+		// bytes trail the fields this version of Sarama knows of, e.g. the
+		// fields a newer broker or a proxy appends, rather than fail with a
+		// PacketDecodingError (defaults to false, strict decoding).
+		LenientDecoding bool
+
+		// ResponseHandlers is the number of responses to AsyncProduce of a
+		// connection decoded concurrently while the next responses are read
+		// off the connection, so that decoding a large response doesn't hold
+		// up the others (defaults to 0, each response is decoded by the
+		// goroutine reading them before it reads the next). The callbacks
+		// are still called one at a time in the order of the requests, as
+		// the producer requires. The responses of the other requests are
+		// decoded by the goroutines waiting for them either way.
+		ResponseHandlers int
+
 		Proxy struct {
 			// Whether or not to use proxy when connecting to the broker
 			// (defaults to false).
 			Enable bool
 			// The proxy dialer to use enabled (defaults to nil).
 			Dialer proxy.Dialer
+			// DialerFunc, if set, returns the proxy dialer to use for the
+			// broker at addr instead of Dialer, so that brokers can be reached
+			// through different proxies. Returning nil dials the broker
+			// directly, bypassing the proxy.
+			DialerFunc func(addr string) proxy.Dialer
+		}
+
+		// DialContext, if set, establishes the connections to the brokers
+		// instead of the default dialer or the proxy, e.g. to go through a
+		// custom transport or an in-process network. The context is done
+		// once DialTimeout has elapsed; it only bounds the dial, not the
+		// lifetime of the connection.
+		DialContext DialContextFunc
+
+		// DialerSelector, if set, returns the function dialing the broker at
+		// addr, so that each broker can use a different transport. When it
+		// returns nil, DialContext, the proxy or the default dialer is used.
+		DialerSelector func(addr string) DialContextFunc
+
+		// BrokerSecurity, if set, is called with a copy of the Config before
+		// connecting to the broker at addr, to adapt its Net.TLS and Net.SASL
+		// settings to that broker, e.g. another CA or server name for the
+		// brokers reached through a gateway, or while the listeners of a
+		// cluster migrate to another mechanism. It must replace, rather than
+		// modify, the values the copy shares with the Config, such as
+		// Net.TLS.Config.
+		BrokerSecurity func(addr string, conf *Config)
+
+		// DNSLookup controls how the host names of the brokers are resolved,
+		// as client.dns.lookup of the Java client (defaults to
+		// DNSLookupDefault).
+		DNSLookup DNSLookup
+		// Resolver looks up the host names of the brokers when DNSLookup is
+		// not DNSLookupDefault (defaults to net.DefaultResolver).
+		Resolver *net.Resolver
+
+		// Listeners selects the listener the client connects to the brokers
+		// through, when the cluster has several of them, e.g. one for the
+		// clients inside and one for those outside of its network. The
+		// brokers only advertise the addresses of the listener the client
+		// bootstrapped through, so those of the other listeners are derived
+		// from them.
+		Listeners struct {
+			// Preference lists the names of the listeners, in Addresses, to
+			// connect through, most preferred first. Each broker is reached
+			// at the address of the first listener returning one for it, and
+			// at its advertised address when none does (defaults to none).
+			Preference []string
+			// Addresses maps the name of each listener to the function
+			// deriving the address of a broker on that listener from the
+			// address it advertises, e.g. ListenerPort.
+			Addresses map[string]ListenerAddressFunc
+		}
+
+		// WireTap, if set, is called with each request sent to the brokers,
+		// once its response has been read or the request failed, with both
+		// as they went over the wire, e.g. to capture golden files or to
+		// analyse the traffic. The requests of the SASL authentication are
+		// not captured. It is called by the goroutine reading the responses
+		// of the broker, before the response is handled, so it must not
+		// block. It may keep the record, but not modify its bytes.
+		WireTap func(record *WireTapRecord)
+
+		// Throttle configures the callback told about the requests the
+		// brokers throttle because of quotas.
+		Throttle struct {
+			// Threshold is the throttle time above which the requests are
+			// reported to OnThreshold (defaults to 0, any throttled request).
+			Threshold time.Duration
+			// OnThreshold, if set, is called with the broker, the name of the
+			// API, e.g. "Produce" or "Fetch", and the throttle time of each
+			// request throttled for longer than Threshold, e.g. to shed load
+			// before the quotas are exhausted. It is called by the goroutine
+			// handling the response, so it must not block.
+			OnThreshold func(broker *Broker, api string, throttle time.Duration)
 		}
 	}
 
@@ -138,6 +266,16 @@ type Config struct {
 		// Defaults to 10 minutes. Set to 0 to disable. Similar to
 		// `topic.metadata.refresh.interval.ms` in the JVM version.
 		RefreshFrequency time.Duration
+		// The interval the background refresh backs off to while the metadata
+		// is stable: every refresh which finds the metadata unchanged doubles
+		// the interval, starting from RefreshFrequency, up to this value, and
+		// any change brings it back to RefreshFrequency. Defaults to 0, which
+		// keeps refreshing every RefreshFrequency.
+		MaxRefreshFrequency time.Duration
+		// The fraction of the refresh interval by which each background
+		// refresh is randomly moved earlier or later, so that many clients
+		// started together do not refresh at the same time (defaults to 0).
+		RefreshJitter float64
 
 		// Whether to maintain a full set of metadata for all topics, or just
 		// the minimal set that has been necessary so far. The full set is simpler
@@ -156,14 +294,35 @@ type Config struct {
 		// the broker may auto-create topics that we requested which do not already exist,
 		// if it is configured to do so (`auto.create.topics.enable` is true). Defaults to true.
 		AllowAutoTopicCreation bool
+
+		// How long a topic can go unused before its metadata is discarded and
+		// no longer refreshed in the background, when Full is false. A topic
+		// is used when its partitions, leaders or replicas are looked up
+		// through the Client, and its metadata is fetched again on the next
+		// lookup. Defaults to 0, which keeps every topic. Similar to
+		// `metadata.max.idle.ms` in the JVM version.
+		MaxIdleTime time.Duration
 	}
 
 	// Producer is the namespace for configuration related to producing messages,
 	// used by the Producer.
 	Producer struct {
-		// The maximum permitted size of a message (defaults to 1000000). Should be
-		// set equal to or smaller than the broker's `message.max.bytes`.
+		// The maximum permitted size of the record batch, or message set, sent
+		// to a partition in a request (defaults to 1000000), including its
+		// overhead. Should be set equal to or smaller than the broker's
+		// `message.max.bytes`, which the broker checks the batches against once
+		// compressed. Messages which don't fit alone in a batch are failed with
+		// ErrMessageSizeTooLarge, unless Compression is enabled; batches are
+		// then filled up to MaxMessageBytes according to the compression ratio
+		// observed on the topic, as the JVM producer does.
 		MaxMessageBytes int
+		// The maximum size of a produce request, which bounds the batches sent
+		// to all the partitions of a broker together, as well as every single
+		// message, before compression (defaults to 0, MaxRequestSize less
+		// 10KiB of overhead). Equivalent to `max.request.size` of the JVM
+		// producer; should be smaller than the broker's
+		// `socket.request.max.bytes`.
+		MaxRequestBytes int
 		// The level of acknowledgement reliability needed from the broker (defaults
 		// to WaitForLocal). Equivalent to the `request.required.acks` setting of the
 		// JVM producer.
@@ -181,6 +340,26 @@ type Config struct {
 		// on the actual compression type used and defaults to default compression
 		// level for the codec.
 		CompressionLevel int
+		// Zstd tunes the zstd encoders compressing the batches with
+		// CompressionZSTD, one being shared by all the producers with the same
+		// settings.
+		Zstd struct {
+			// The maximum back-reference distance, a power of 2 between 1KiB
+			// and 512MiB. Larger windows compress better but take more memory.
+			// Defaults to 0, derived from CompressionLevel.
+			WindowSize int
+			// The number of batches an encoder compresses at once, each taking
+			// its own memory (defaults to 0, GOMAXPROCS).
+			Concurrency int
+			// LowMemory allocates the encoder memory as needed rather than
+			// upfront (default false).
+			LowMemory bool
+		}
+		// SnappyFraming is how the batches compressed with CompressionSnappy
+		// are framed: SnappyRawBlock (default) produces a single snappy
+		// block, SnappyXerialFraming the xerial framing of the JVM producer.
+		// The consumers decode either, as well as the snappy framing format.
+		SnappyFraming SnappyFraming
 		// Generates partitioners for choosing the partition to send messages to
 		// (defaults to hashing the message key). Similar to the `partitioner.class`
 		// setting for the JVM producer.
@@ -296,6 +475,15 @@ type Config struct {
 				// coordinator for the group.
 				UserData []byte
 			}
+			// DedicatedCoordinatorConnection makes the client talk to the
+			// coordinators of the groups over connections of their own, as the
+			// JVM consumer does, so that heartbeats, offset commits and
+			// rebalances are never queued behind the fetch and produce requests
+			// sent to the same broker, which could delay them past
+			// Session.Timeout (default false). The metrics of these
+			// connections are recorded for the broker ID 2147483647 minus
+			// the ID of the coordinator, as the JVM client does.
+			DedicatedCoordinatorConnection bool
 		}
 
 		Retry struct {
@@ -329,6 +517,36 @@ type Config struct {
 			// (no limit). Similar to the JVM's `fetch.message.max.bytes`. The
 			// global `sarama.MaxResponseSize` still applies.
 			Max int32
+			// Lazy makes the consumer decode the record batches of a fetch
+			// response one at a time as it delivers their messages, rather than
+			// all at once, bounding the memory used by large fetches (default
+			// false). The responses of Broker.Fetch then leave their record
+			// batches undecoded, to iterate with FetchResponseBlock.RecordsIterator.
+			Lazy bool
+			// CRCValidation is how the CRCs of the record batches fetched are
+			// validated: CRCValidateAndFail (default) fails the fetch on a
+			// mismatch, CRCValidateAndSkip drops the corrupt batches and
+			// consumes the next ones, and CRCSkipValidation spares computing
+			// them. The corrupt batches detected are counted by the
+			// consumer-crc-errors metric.
+			CRCValidation CRCValidation
+			// LazyHeaders leaves the headers of the records fetched undecoded
+			// until ConsumerMessage.RecordHeaders is first called, sparing their
+			// allocations to the consumers which never read them (default
+			// false). ConsumerMessage.Headers is then nil until that call.
+			LazyHeaders bool
+			// MaxDecodedBytes caps the size of the records decoded from a
+			// fetch response once decompressed, summed over all its record
+			// batches, so that a crafted length or a compression bomb can't
+			// make the client allocate gigabytes. Going over it fails the
+			// decoding with a FetchLimitError before the memory is allocated,
+			// as do the zstd frames with a window larger than it. Defaults to
+			// 0 (no limit).
+			MaxDecodedBytes int64
+			// MaxBatchRecords caps the number of records of a fetched record
+			// batch, decoding a larger batch failing with a FetchLimitError.
+			// Defaults to 0 (no limit beyond the one of the protocol).
+			MaxBatchRecords int
 		}
 		// The maximum amount of time the broker will wait for Consumer.Fetch.Min
 		// bytes to become available before it returns fewer than that anyways. The
@@ -408,6 +626,21 @@ type Config struct {
 		//	- use `ReadCommitted` to hide messages that are part of an aborted transaction
 		IsolationLevel IsolationLevel
 
+		// If enabled, the consumed messages are leased from a pool along with
+		// copies of their Key, Value and Headers, and the application hands
+		// each back with ConsumerMessage.Release once done with it, sparing
+		// allocations and releasing fetch responses early (default false). The
+		// compressed batches of a partition are then also decompressed into
+		// reused buffers. A message must not be used once released, nor its
+		// Key, Value or Headers.
+		PoolMessages bool
+
+		// Zstd tunes the zstd decoders decompressing the batches fetched, one
+		// being shared by all the consumers with the same settings. Setting
+		// LowMemory and a Concurrency of 1 bounds the memory they hold on to
+		// between batches, for consumers which seldom see zstd data.
+		Zstd ZstdDecoderParams
+
 		// Interceptors to be called just before the record is sent to the
 		// messages channel. Interceptors allows to intercept and possible
 		// mutate the message before they are returned to the client.
@@ -417,6 +650,16 @@ type Config struct {
 		Interceptors []ConsumerInterceptor
 	}
 
+	// Telemetry is the namespace for the client metrics pushed to the
+	// brokers which subscribe to them, see KIP-714.
+	Telemetry struct {
+		// Whether to push the metrics of MetricRegistry the brokers subscribe
+		// to, like enable.metrics.push of the Java client (defaults to
+		// false). It requires Version >= V3_7_0_0, and brokers configured
+		// with client metrics subscriptions.
+		Enable bool
+	}
+
 	// A user-provided string sent with every request to the brokers for logging,
 	// debugging, and auditing purposes. Defaults to "sarama", but you should
 	// probably set it to something specific to your application.
@@ -448,6 +691,18 @@ type Config struct {
 	// prior to starting Sarama.
 	// See Examples on how to use the metrics registry
 	MetricRegistry metrics.Registry
+	// MetricsRecorder, if set, records the metrics instead of MetricRegistry,
+	// e.g. to export them to Prometheus or OpenTelemetry without the sampling
+	// of go-metrics (defaults to recording them in MetricRegistry, see
+	// NewGoMetricsRecorder).
+	MetricsRecorder MetricsRecorder
+
+	// RetryBudget, if set, caps the retries of the metadata, coordinator,
+	// producer, consumer and admin requests, on top of their own Retry
+	// settings, so that retries cannot swamp a struggling cluster (defaults
+	// to nil, no cap). Share it between Configs to cap several clients
+	// together.
+	RetryBudget *RetryBudget
 }
 
 // NewConfig returns a new configuration instance with sane defaults.
@@ -462,6 +717,7 @@ func NewConfig() *Config {
 	c.Net.DialTimeout = 30 * time.Second
 	c.Net.ReadTimeout = 30 * time.Second
 	c.Net.WriteTimeout = 30 * time.Second
+	c.Net.DNSLookup = DNSLookupDefault
 	c.Net.SASL.Handshake = true
 	c.Net.SASL.Version = SASLHandshakeV0
 
@@ -507,68 +763,173 @@ func NewConfig() *Config {
 	return c
 }
 
+// forBroker returns the Config to connect to the broker at addr with, adapted
+// by Net.BrokerSecurity.
+func (c *Config) forBroker(addr string) (*Config, error) {
+	if c.Net.BrokerSecurity == nil {
+		return c, nil
+	}
+	conf := *c
+	c.Net.BrokerSecurity(addr, &conf)
+	if err := conf.validate(); err != nil {
+		return nil, err
+	}
+	return &conf, nil
+}
+
+// maxRequestBytes returns the size produce requests are kept under.
+func (c *Config) maxRequestBytes() int {
+	if max := c.Producer.MaxRequestBytes; max > 0 && max < int(MaxRequestSize) {
+		return max
+	}
+	// 10KiB is arbitrary overhead for safety
+	return int(MaxRequestSize - (10 * 1024))
+}
+
+// ConfigWarning is a setting of a Config which is valid, but likely to be a
+// mistake or to perform poorly.
+type ConfigWarning struct {
+	// Field is the path of the field the warning is about, e.g.
+	// "Producer.MaxMessageBytes".
+	Field string
+	// Message describes the issue.
+	Message string
+}
+
+func (w ConfigWarning) String() string {
+	return w.Message
+}
+
 // Validate checks a Config instance. It will return a
-// ConfigurationError if the specified values don't make sense.
+// ConfigurationError if the specified values don't make sense. The
+// warnings of ValidateWithWarnings are logged.
 func (c *Config) Validate() error {
-	// some configuration values should be warned on but not fail completely, do those first
+	warnings, err := c.ValidateWithWarnings()
+	for _, warning := range warnings {
+		warnf("%s", warning.Message)
+	}
+	return err
+}
+
+// ValidateWithWarnings checks a Config instance like Validate, also returning
+// the settings which don't fail the validation but should be reviewed, so
+// that applications and tests can check them.
+func (c *Config) ValidateWithWarnings() ([]ConfigWarning, error) {
+	return c.warnings(), c.validate()
+}
+
+// warnings returns the configuration values which should be warned on but
+// not fail completely.
+func (c *Config) warnings() []ConfigWarning {
+	var warnings []ConfigWarning
+	warn := func(field, message string) {
+		warnings = append(warnings, ConfigWarning{Field: field, Message: message})
+	}
+
 	if !c.Net.TLS.Enable && c.Net.TLS.Config != nil {
-		Logger.Println("Net.TLS is disabled but a non-nil configuration was provided.")
+		warn("Net.TLS.Config", "Net.TLS is disabled but a non-nil configuration was provided.")
 	}
 	if !c.Net.SASL.Enable {
 		if c.Net.SASL.User != "" {
-			Logger.Println("Net.SASL is disabled but a non-empty username was provided.")
+			warn("Net.SASL.User", "Net.SASL is disabled but a non-empty username was provided.")
 		}
 		if c.Net.SASL.Password != "" {
-			Logger.Println("Net.SASL is disabled but a non-empty password was provided.")
+			warn("Net.SASL.Password", "Net.SASL is disabled but a non-empty password was provided.")
 		}
 	}
 	if c.Producer.RequiredAcks > 1 {
-		Logger.Println("Producer.RequiredAcks > 1 is deprecated and will raise an exception with kafka >= 0.8.2.0.")
+		warn("Producer.RequiredAcks", "Producer.RequiredAcks > 1 is deprecated and will raise an exception with kafka >= 0.8.2.0.")
 	}
 	if c.Producer.MaxMessageBytes >= int(MaxRequestSize) {
-		Logger.Println("Producer.MaxMessageBytes must be smaller than MaxRequestSize; it will be ignored.")
+		warn("Producer.MaxMessageBytes", "Producer.MaxMessageBytes must be smaller than MaxRequestSize; it will be ignored.")
+	}
+	if c.Producer.MaxRequestBytes >= int(MaxRequestSize) {
+		warn("Producer.MaxRequestBytes", "Producer.MaxRequestBytes must be smaller than MaxRequestSize; it will be ignored.")
 	}
 	if c.Producer.Flush.Bytes >= int(MaxRequestSize) {
-		Logger.Println("Producer.Flush.Bytes must be smaller than MaxRequestSize; it will be ignored.")
+		warn("Producer.Flush.Bytes", "Producer.Flush.Bytes must be smaller than MaxRequestSize; it will be ignored.")
 	}
 	if (c.Producer.Flush.Bytes > 0 || c.Producer.Flush.Messages > 0) && c.Producer.Flush.Frequency == 0 {
-		Logger.Println("Producer.Flush: Bytes or Messages are set, but Frequency is not; messages may not get flushed.")
+		warn("Producer.Flush.Frequency", "Producer.Flush: Bytes or Messages are set, but Frequency is not; messages may not get flushed.")
 	}
 	if c.Producer.Timeout%time.Millisecond != 0 {
-		Logger.Println("Producer.Timeout only supports millisecond resolution; nanoseconds will be truncated.")
+		warn("Producer.Timeout", "Producer.Timeout only supports millisecond resolution; nanoseconds will be truncated.")
 	}
 	if c.Consumer.MaxWaitTime < 100*time.Millisecond {
-		Logger.Println("Consumer.MaxWaitTime is very low, which can cause high CPU and network usage. See documentation for details.")
+		warn("Consumer.MaxWaitTime", "Consumer.MaxWaitTime is very low, which can cause high CPU and network usage. See documentation for details.")
 	}
 	if c.Consumer.MaxWaitTime%time.Millisecond != 0 {
-		Logger.Println("Consumer.MaxWaitTime only supports millisecond precision; nanoseconds will be truncated.")
+		warn("Consumer.MaxWaitTime", "Consumer.MaxWaitTime only supports millisecond precision; nanoseconds will be truncated.")
 	}
 	if c.Consumer.Offsets.Retention%time.Millisecond != 0 {
-		Logger.Println("Consumer.Offsets.Retention only supports millisecond precision; nanoseconds will be truncated.")
+		warn("Consumer.Offsets.Retention", "Consumer.Offsets.Retention only supports millisecond precision; nanoseconds will be truncated.")
 	}
 	if c.Consumer.Group.Session.Timeout%time.Millisecond != 0 {
-		Logger.Println("Consumer.Group.Session.Timeout only supports millisecond precision; nanoseconds will be truncated.")
+		warn("Consumer.Group.Session.Timeout", "Consumer.Group.Session.Timeout only supports millisecond precision; nanoseconds will be truncated.")
 	}
 	if c.Consumer.Group.Heartbeat.Interval%time.Millisecond != 0 {
-		Logger.Println("Consumer.Group.Heartbeat.Interval only supports millisecond precision; nanoseconds will be truncated.")
+		warn("Consumer.Group.Heartbeat.Interval", "Consumer.Group.Heartbeat.Interval only supports millisecond precision; nanoseconds will be truncated.")
 	}
 	if c.Consumer.Group.Rebalance.Timeout%time.Millisecond != 0 {
-		Logger.Println("Consumer.Group.Rebalance.Timeout only supports millisecond precision; nanoseconds will be truncated.")
+		warn("Consumer.Group.Rebalance.Timeout", "Consumer.Group.Rebalance.Timeout only supports millisecond precision; nanoseconds will be truncated.")
 	}
 	if c.ClientID == defaultClientID {
-		Logger.Println("ClientID is the default of 'sarama', you should consider setting it to something application-specific.")
+		warn("ClientID", "ClientID is the default of 'sarama', you should consider setting it to something application-specific.")
+	}
+	if c.Net.TLS.Enable && c.Net.TLS.Config != nil && c.Net.TLS.Config.InsecureSkipVerify {
+		warn("Net.TLS.Config", "Net.TLS.Config skips the verification of the broker certificates, which exposes the connections to man-in-the-middle attacks.")
+	}
+	if c.Net.SASL.Enable && !c.Net.TLS.Enable && (c.Net.SASL.Mechanism == "" || c.Net.SASL.Mechanism == SASLTypePlaintext) {
+		warn("Net.SASL.Mechanism", "Net.SASL.Mechanism PLAIN is used without Net.TLS; the password is sent in clear text.")
+	}
+	if c.Consumer.Group.Heartbeat.Interval > c.Consumer.Group.Session.Timeout/3 {
+		warn("Consumer.Group.Heartbeat.Interval", "Consumer.Group.Heartbeat.Interval is more than a third of Consumer.Group.Session.Timeout; a late heartbeat may evict the member from the group.")
+	}
+	if c.Consumer.Fetch.Default > 0 && int(c.Consumer.Fetch.Default) < c.Producer.MaxMessageBytes {
+		warn("Consumer.Fetch.Default", "Consumer.Fetch.Default is smaller than Producer.MaxMessageBytes; the largest messages will take several fetches to consume.")
 	}
+	if c.Consumer.Fetch.Max > 0 && int(c.Consumer.Fetch.Max) < c.Producer.MaxMessageBytes {
+		warn("Consumer.Fetch.Max", "Consumer.Fetch.Max is smaller than Producer.MaxMessageBytes; the largest messages may never be consumed.")
+	}
+
+	return warnings
+}
 
+// validate returns a ConfigurationError if the values of the Config don't
+// make sense.
+func (c *Config) validate() error {
 	// validate Net values
 	switch {
 	case c.Net.MaxOpenRequests <= 0:
 		return ConfigurationError("Net.MaxOpenRequests must be > 0")
+	case c.Net.ResponseHandlers < 0:
+		return ConfigurationError("Net.ResponseHandlers must be >= 0")
 	case c.Net.DialTimeout <= 0:
 		return ConfigurationError("Net.DialTimeout must be > 0")
 	case c.Net.ReadTimeout <= 0:
 		return ConfigurationError("Net.ReadTimeout must be > 0")
 	case c.Net.WriteTimeout <= 0:
 		return ConfigurationError("Net.WriteTimeout must be > 0")
+	case c.Net.KeepAliveInterval < 0:
+		return ConfigurationError("Net.KeepAliveInterval must be >= 0")
+	case c.Net.KeepAliveCount < 0:
+		return ConfigurationError("Net.KeepAliveCount must be >= 0")
+	case c.Net.ReadBufferSize < 0:
+		return ConfigurationError("Net.ReadBufferSize must be >= 0")
+	case c.Net.WriteBufferSize < 0:
+		return ConfigurationError("Net.WriteBufferSize must be >= 0")
+	case c.Net.DSCP < 0 || c.Net.DSCP > 63:
+		return ConfigurationError("Net.DSCP must be between 0 and 63")
+	case !socketOptionsSupported && c.hasSocketOptions():
+		return ConfigurationError("Net.KeepAliveInterval, Net.KeepAliveCount, Net.ReadBufferSize, Net.WriteBufferSize and Net.DSCP are only supported on Linux")
+	case c.Net.DNSLookup != "" && c.Net.DNSLookup != DNSLookupDefault && c.Net.DNSLookup != DNSLookupUseAllIPs && c.Net.DNSLookup != DNSLookupResolveCanonicalBootstrapServersOnly:
+		return ConfigurationError(fmt.Sprintf("Net.DNSLookup must be one of %s, %s or %s", DNSLookupDefault, DNSLookupUseAllIPs, DNSLookupResolveCanonicalBootstrapServersOnly))
+	case c.Net.Proxy.Enable && c.Net.Proxy.Dialer == nil && c.Net.Proxy.DialerFunc == nil:
+		return ConfigurationError("Net.Proxy.Dialer or Net.Proxy.DialerFunc must be set when Net.Proxy.Enable is true")
+	case c.Net.Throttle.Threshold < 0:
+		return ConfigurationError("Net.Throttle.Threshold must be >= 0")
+	case c.unknownListener() != "":
+		return ConfigurationError(fmt.Sprintf("Net.Listeners.Preference names the listener %q, which is not in Net.Listeners.Addresses", c.unknownListener()))
 	case c.Net.SASL.Enable:
 		if c.Net.SASL.Mechanism == "" {
 			c.Net.SASL.Mechanism = SASLTypePlaintext
@@ -611,21 +972,31 @@ func (c *Config) Validate() error {
 					return ConfigurationError("Net.SASL.GSSAPI.KeyTabPath must not be empty when GSS-API mechanism is used" +
 						" and  Net.SASL.GSSAPI.AuthType = KRB5_KEYTAB_AUTH")
 				}
+			} else if c.Net.SASL.GSSAPI.AuthType == KRB5_CCACHE_AUTH {
+				if c.Net.SASL.GSSAPI.CCachePath == "" {
+					return ConfigurationError("Net.SASL.GSSAPI.CCachePath must not be empty when GSS-API mechanism is used" +
+						" and Net.SASL.GSSAPI.AuthType = KRB5_CCACHE_AUTH")
+				}
 			} else {
-				return ConfigurationError("Net.SASL.GSSAPI.AuthType is invalid. Possible values are KRB5_USER_AUTH and KRB5_KEYTAB_AUTH")
+				return ConfigurationError("Net.SASL.GSSAPI.AuthType is invalid. Possible values are KRB5_USER_AUTH, KRB5_KEYTAB_AUTH and KRB5_CCACHE_AUTH")
 			}
 			if c.Net.SASL.GSSAPI.KerberosConfigPath == "" {
 				return ConfigurationError("Net.SASL.GSSAPI.KerberosConfigPath must not be empty when GSS-API mechanism is used")
 			}
-			if c.Net.SASL.GSSAPI.Username == "" {
+			// the principal of a credential cache is read from the cache
+			if c.Net.SASL.GSSAPI.AuthType != KRB5_CCACHE_AUTH && c.Net.SASL.GSSAPI.Username == "" {
 				return ConfigurationError("Net.SASL.GSSAPI.Username must not be empty when GSS-API mechanism is used")
 			}
-			if c.Net.SASL.GSSAPI.Realm == "" {
+			if c.Net.SASL.GSSAPI.AuthType != KRB5_CCACHE_AUTH && c.Net.SASL.GSSAPI.Realm == "" {
 				return ConfigurationError("Net.SASL.GSSAPI.Realm must not be empty when GSS-API mechanism is used")
 			}
+		case SASLTypeAWSMSKIAM:
+			if !c.Version.IsAtLeast(V1_0_0_0) {
+				return ConfigurationError("The AWS_MSK_IAM SASL mechanism requires Version >= V1_0_0_0")
+			}
 		default:
-			msg := fmt.Sprintf("The SASL mechanism configuration is invalid. Possible values are `%s`, `%s`, `%s`, `%s` and `%s`",
-				SASLTypeOAuth, SASLTypePlaintext, SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512, SASLTypeGSSAPI)
+			msg := fmt.Sprintf("The SASL mechanism configuration is invalid. Possible values are `%s`, `%s`, `%s`, `%s`, `%s` and `%s`",
+				SASLTypeOAuth, SASLTypePlaintext, SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512, SASLTypeGSSAPI, SASLTypeAWSMSKIAM)
 			return ConfigurationError(msg)
 		}
 	}
@@ -644,12 +1015,20 @@ func (c *Config) Validate() error {
 		return ConfigurationError("Metadata.Retry.Backoff must be >= 0")
 	case c.Metadata.RefreshFrequency < 0:
 		return ConfigurationError("Metadata.RefreshFrequency must be >= 0")
+	case c.Metadata.MaxRefreshFrequency < 0:
+		return ConfigurationError("Metadata.MaxRefreshFrequency must be >= 0")
+	case c.Metadata.RefreshJitter < 0 || c.Metadata.RefreshJitter >= 1:
+		return ConfigurationError("Metadata.RefreshJitter must be >= 0 and < 1")
+	case c.Metadata.MaxIdleTime < 0:
+		return ConfigurationError("Metadata.MaxIdleTime must be >= 0")
 	}
 
 	// validate the Producer values
 	switch {
 	case c.Producer.MaxMessageBytes <= 0:
 		return ConfigurationError("Producer.MaxMessageBytes must be > 0")
+	case c.Producer.MaxRequestBytes < 0:
+		return ConfigurationError("Producer.MaxRequestBytes must be >= 0")
 	case c.Producer.RequiredAcks < -1:
 		return ConfigurationError("Producer.RequiredAcks must be >= -1")
 	case c.Producer.Timeout <= 0:
@@ -688,6 +1067,15 @@ func (c *Config) Validate() error {
 		return ConfigurationError("zstd compression requires Version >= V2_1_0_0")
 	}
 
+	switch window := c.Producer.Zstd.WindowSize; {
+	case window != 0 && (window < zstd.MinWindowSize || window > zstd.MaxWindowSize || window&(window-1) != 0):
+		return ConfigurationError("Producer.Zstd.WindowSize must be 0 or a power of 2 between 1KiB and 512MiB")
+	case c.Producer.Zstd.Concurrency < 0:
+		return ConfigurationError("Producer.Zstd.Concurrency must be >= 0")
+	case c.Producer.SnappyFraming != SnappyRawBlock && c.Producer.SnappyFraming != SnappyXerialFraming:
+		return ConfigurationError("Producer.SnappyFraming must be SnappyRawBlock or SnappyXerialFraming")
+	}
+
 	if c.Producer.Idempotent {
 		if !c.Version.IsAtLeast(V0_11_0_0) {
 			return ConfigurationError("Idempotent producer requires Version >= V0_11_0_0")
@@ -725,10 +1113,20 @@ func (c *Config) Validate() error {
 		return ConfigurationError("Consumer.Offsets.Retry.Max must be >= 0")
 	case c.Consumer.IsolationLevel != ReadUncommitted && c.Consumer.IsolationLevel != ReadCommitted:
 		return ConfigurationError("Consumer.IsolationLevel must be ReadUncommitted or ReadCommitted")
+	case c.Consumer.Fetch.CRCValidation < CRCValidateAndFail || c.Consumer.Fetch.CRCValidation > CRCSkipValidation:
+		return ConfigurationError("Consumer.Fetch.CRCValidation must be CRCValidateAndFail, CRCValidateAndSkip or CRCSkipValidation")
+	case c.Consumer.Fetch.MaxDecodedBytes < 0:
+		return ConfigurationError("Consumer.Fetch.MaxDecodedBytes must be >= 0")
+	case c.Consumer.Fetch.MaxBatchRecords < 0:
+		return ConfigurationError("Consumer.Fetch.MaxBatchRecords must be >= 0")
+	case c.Consumer.Zstd.MaxWindowSize != 0 && c.Consumer.Zstd.MaxWindowSize < zstd.MinWindowSize:
+		return ConfigurationError("Consumer.Zstd.MaxWindowSize must be 0 or >= 1KiB")
+	case c.Consumer.Zstd.Concurrency < 0:
+		return ConfigurationError("Consumer.Zstd.Concurrency must be >= 0")
 	}
 
 	if c.Consumer.Offsets.CommitInterval != 0 {
-		Logger.Println("Deprecation warning: Consumer.Offsets.CommitInterval exists for historical compatibility" +
+		warnf("Deprecation warning: Consumer.Offsets.CommitInterval exists for historical compatibility" +
 			" and should not be used. Please use Consumer.Offsets.AutoCommit, the current value will be ignored")
 	}
 
@@ -755,6 +1153,11 @@ func (c *Config) Validate() error {
 		return ConfigurationError("Consumer.Group.Rebalance.Retry.Backoff must be >= 0")
 	}
 
+	// validate the Telemetry values
+	if c.Telemetry.Enable && !c.Version.IsAtLeast(V3_7_0_0) {
+		return ConfigurationError("Telemetry requires Version >= V3_7_0_0")
+	}
+
 	// validate misc shared values
 	switch {
 	case c.ChannelBufferSize < 0:
@@ -766,15 +1169,118 @@ func (c *Config) Validate() error {
 	return nil
 }
 
-func (c *Config) getDialer() proxy.Dialer {
+// ListenerAddressFunc returns the address of the broker id on a listener,
+// given the address it advertises, or "" when it has none on that listener.
+type ListenerAddressFunc func(id int32, advertised string) string
+
+// ListenerPort returns a ListenerAddressFunc for a listener bound to port on
+// the same hosts as the advertised one.
+func ListenerPort(port int) ListenerAddressFunc {
+	return func(id int32, advertised string) string {
+		host, _, err := net.SplitHostPort(advertised)
+		if err != nil {
+			return ""
+		}
+		return net.JoinHostPort(host, strconv.Itoa(port))
+	}
+}
+
+// unknownListener returns the first listener of Net.Listeners.Preference
+// missing from Net.Listeners.Addresses, if any.
+func (c *Config) unknownListener() string {
+	for _, name := range c.Net.Listeners.Preference {
+		if c.Net.Listeners.Addresses[name] == nil {
+			return name
+		}
+	}
+	return ""
+}
+
+// listenerAddr returns the address to connect to the broker id through,
+// according to Net.Listeners, given the address it advertises.
+func (c *Config) listenerAddr(id int32, advertised string) string {
+	for _, name := range c.Net.Listeners.Preference {
+		if addr := c.Net.Listeners.Addresses[name](id, advertised); addr != "" {
+			return addr
+		}
+	}
+	return advertised
+}
+
+// metricsRecorder returns the MetricsRecorder recording the metrics, if any.
+func (c *Config) metricsRecorder() MetricsRecorder {
+	if c.MetricsRecorder != nil {
+		return c.MetricsRecorder
+	}
+	if c.MetricRegistry == nil {
+		return nil
+	}
+	return NewGoMetricsRecorder(c.MetricRegistry)
+}
+
+// DialContextFunc connects to the address on the named network, like
+// net.Dialer.DialContext.
+type DialContextFunc func(ctx context.Context, network, addr string) (net.Conn, error)
+
+// dial connects to the broker at addr with the dialer selected for it.
+func (c *Config) dial(addr string) (net.Conn, error) {
+	return c.dialContext(context.Background(), addr)
+}
+
+// dialContext is dial, giving up as soon as ctx is done.
+func (c *Config) dialContext(ctx context.Context, addr string) (net.Conn, error) {
+	dial := c.dialContextFor(addr)
+	if c.Net.DNSLookup == DNSLookupUseAllIPs || c.Net.DNSLookup == DNSLookupResolveCanonicalBootstrapServersOnly {
+		return c.dialAllIPs(ctx, dial, addr)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, c.Net.DialTimeout)
+	defer cancel()
+	return dial(ctx, "tcp", addr)
+}
+
+func (c *Config) dialContextFor(addr string) DialContextFunc {
+	if c.Net.DialerSelector != nil {
+		if dial := c.Net.DialerSelector(addr); dial != nil {
+			return dial
+		}
+	}
+	if c.Net.DialContext != nil {
+		return c.Net.DialContext
+	}
+
+	dialer := c.getDialer(addr)
+	if contextDialer, ok := dialer.(proxy.ContextDialer); ok {
+		return contextDialer.DialContext
+	}
+	return func(_ context.Context, network, addr string) (net.Conn, error) {
+		return dialer.Dial(network, addr)
+	}
+}
+
+func (c *Config) getDialer(addr string) proxy.Dialer {
 	if c.Net.Proxy.Enable {
-		Logger.Printf("using proxy %s", c.Net.Proxy.Dialer)
-		return c.Net.Proxy.Dialer
-	} else {
-		return &net.Dialer{
-			Timeout:   c.Net.DialTimeout,
-			KeepAlive: c.Net.KeepAlive,
-			LocalAddr: c.Net.LocalAddr,
+		dialer := c.Net.Proxy.Dialer
+		if c.Net.Proxy.DialerFunc != nil {
+			dialer = c.Net.Proxy.DialerFunc(addr)
+		}
+		if dialer != nil {
+			infof("using proxy %s for %s", dialer, addr)
+			return dialer
+		}
+	}
+	dialer := &net.Dialer{
+		Timeout:   c.Net.DialTimeout,
+		KeepAlive: c.Net.KeepAlive,
+		LocalAddr: c.Net.LocalAddr,
+	}
+	if c.hasSocketOptions() {
+		dialer.Control = c.controlSocket
+		if c.keepAliveTuned() {
+			// the keep-alive options are all set by controlSocket, as the
+			// dialer would otherwise override the interval
+			dialer.KeepAlive = -1
 		}
 	}
+	return dialer
 }
@@ -0,0 +1,196 @@
+package sarama
+
+import (
+	"crypto/tls"
+)
+
+// Option configures the Config built by NewConfigWith. The options set the
+// related fields together, so that they form a combination which passes
+// Config.Validate, e.g. WithIdempotence also requires acks from all the
+// in-sync replicas and a single request in flight per broker.
+type Option func(o *configOptions)
+
+// configOptions is the Config being built by NewConfigWith.
+type configOptions struct {
+	conf *Config
+	// versionSet is whether WithVersion was given, in which case the
+	// Version is never raised to minVersion
+	versionSet bool
+	// minVersion is the lowest Version which supports all the options
+	minVersion KafkaVersion
+	// finish is run once all the options are applied
+	finish []func(c *Config)
+}
+
+func (o *configOptions) requireVersion(version KafkaVersion) {
+	if !o.minVersion.IsAtLeast(version) {
+		o.minVersion = version
+	}
+}
+
+// NewConfigWith returns a new Config with the defaults of NewConfig, updated by
+// options, and validated. Unless WithVersion is given, the Version is raised to
+// the lowest one supporting all the options, e.g. V0_11_0_0 with
+// WithIdempotence; if it is given, a Version too low for the options is
+// reported by the validation instead.
+func NewConfigWith(options ...Option) (*Config, error) {
+	o := &configOptions{conf: NewConfig()}
+	o.minVersion = o.conf.Version
+	for _, option := range options {
+		option(o)
+	}
+
+	if !o.versionSet && !o.conf.Version.IsAtLeast(o.minVersion) {
+		o.conf.Version = o.minVersion
+	}
+	for _, finish := range o.finish {
+		finish(o.conf)
+	}
+
+	if err := o.conf.Validate(); err != nil {
+		return nil, err
+	}
+	return o.conf, nil
+}
+
+// WithConfigFunc calls f with the Config, to set the fields which no other
+// Option covers.
+func WithConfigFunc(f func(c *Config)) Option {
+	return func(o *configOptions) {
+		f(o.conf)
+	}
+}
+
+// WithVersion sets the Kafka version the client speaks.
+func WithVersion(version KafkaVersion) Option {
+	return func(o *configOptions) {
+		o.conf.Version = version
+		o.versionSet = true
+	}
+}
+
+// WithClientID sets the ClientID the client identifies itself with.
+func WithClientID(clientID string) Option {
+	return func(o *configOptions) {
+		o.conf.ClientID = clientID
+	}
+}
+
+// WithTLS connects to the brokers over TLS, configured by tlsConfig, which
+// may be nil for the defaults.
+func WithTLS(tlsConfig *tls.Config) Option {
+	return func(o *configOptions) {
+		o.conf.Net.TLS.Enable = true
+		o.conf.Net.TLS.Config = tlsConfig
+	}
+}
+
+// WithSASLPlain authenticates with SASL/PLAIN as user. The version of the SASL
+// handshake follows the Version.
+func WithSASLPlain(user, password string) Option {
+	return func(o *configOptions) {
+		o.enableSASL(SASLTypePlaintext)
+		o.conf.Net.SASL.User = user
+		o.conf.Net.SASL.Password = password
+	}
+}
+
+// WithSASLSCRAM authenticates with SASL/SCRAM as user, mechanism being
+// SASLTypeSCRAMSHA256 or SASLTypeSCRAMSHA512, through the SCRAM clients
+// returned by generator. It requires at least V0_10_2_0.
+func WithSASLSCRAM(mechanism SASLMechanism, user, password string, generator func() SCRAMClient) Option {
+	return func(o *configOptions) {
+		o.enableSASL(mechanism)
+		o.conf.Net.SASL.User = user
+		o.conf.Net.SASL.Password = password
+		o.conf.Net.SASL.SCRAMClientGeneratorFunc = generator
+		o.requireVersion(V0_10_2_0)
+	}
+}
+
+// WithSASLOAuthBearer authenticates with SASL/OAUTHBEARER, with the tokens of
+// provider. It requires at least V1_0_0_0, for the version 1 of the SASL
+// handshake.
+func WithSASLOAuthBearer(provider AccessTokenProvider) Option {
+	return func(o *configOptions) {
+		o.enableSASL(SASLTypeOAuth)
+		o.conf.Net.SASL.TokenProvider = provider
+		o.requireVersion(V1_0_0_0)
+	}
+}
+
+// enableSASL enables the SASL mechanism, with the latest version of the
+// handshake the Version supports.
+func (o *configOptions) enableSASL(mechanism SASLMechanism) {
+	o.conf.Net.SASL.Enable = true
+	o.conf.Net.SASL.Mechanism = mechanism
+	o.conf.Net.SASL.Handshake = true
+	o.finish = append(o.finish, func(c *Config) {
+		if c.Version.IsAtLeast(V1_0_0_0) {
+			c.Net.SASL.Version = SASLHandshakeV1
+		} else {
+			c.Net.SASL.Version = SASLHandshakeV0
+		}
+	})
+}
+
+// WithIdempotence enables the idempotent producer, along with the settings it
+// requires: acks from all the in-sync replicas, retries and a single request
+// in flight per broker. It requires at least V0_11_0_0.
+func WithIdempotence() Option {
+	return func(o *configOptions) {
+		o.conf.Producer.Idempotent = true
+		o.conf.Producer.RequiredAcks = WaitForAll
+		o.conf.Net.MaxOpenRequests = 1
+		if o.conf.Producer.Retry.Max < 1 {
+			o.conf.Producer.Retry.Max = 1
+		}
+		o.requireVersion(V0_11_0_0)
+	}
+}
+
+// WithCompression compresses the produced messages with codec, at level,
+// which may be CompressionLevelDefault. LZ4 requires at least V0_10_0_0 and
+// ZSTD V2_1_0_0.
+func WithCompression(codec CompressionCodec, level int) Option {
+	return func(o *configOptions) {
+		o.conf.Producer.Compression = codec
+		o.conf.Producer.CompressionLevel = level
+		switch codec {
+		case CompressionLZ4:
+			o.requireVersion(V0_10_0_0)
+		case CompressionZSTD:
+			o.requireVersion(V2_1_0_0)
+		}
+	}
+}
+
+// WithSyncProducer returns both the successes and the errors of the producer,
+// as a SyncProducer requires.
+func WithSyncProducer() Option {
+	return func(o *configOptions) {
+		o.conf.Producer.Return.Successes = true
+		o.conf.Producer.Return.Errors = true
+	}
+}
+
+// WithReadCommitted only consumes the messages of committed transactions. It
+// requires at least V0_11_0_0.
+func WithReadCommitted() Option {
+	return func(o *configOptions) {
+		o.conf.Consumer.IsolationLevel = ReadCommitted
+		o.requireVersion(V0_11_0_0)
+	}
+}
+
+// WithConsumerGroup sets the strategy assigning the partitions to the members
+// of consumer groups, and where they start consuming partitions without a
+// committed offset, OffsetOldest or OffsetNewest. Consumer groups require at
+// least V0_10_2_0.
+func WithConsumerGroup(strategy BalanceStrategy, initial int64) Option {
+	return func(o *configOptions) {
+		o.conf.Consumer.Group.Rebalance.Strategy = strategy
+		o.conf.Consumer.Offsets.Initial = initial
+		o.requireVersion(V0_10_2_0)
+	}
+}
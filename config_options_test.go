@@ -0,0 +1,47 @@
+package sarama
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestNewConfigWith(t *testing.T) {
+	conf, err := NewConfigWith(
+		WithClientID("my-service"),
+		WithSASLPlain("alice", "secret"),
+		WithIdempotence(),
+		WithCompression(CompressionZSTD, CompressionLevelDefault),
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if conf.ClientID != "my-service" {
+		t.Errorf("unexpected ClientID %q", conf.ClientID)
+	}
+	if conf.Version != V2_1_0_0 {
+		t.Errorf("expected the Version to be raised to %s for ZSTD, got %s", V2_1_0_0, conf.Version)
+	}
+	if !conf.Net.SASL.Enable || conf.Net.SASL.Version != SASLHandshakeV1 {
+		t.Error("expected SASL to be enabled with the version 1 of the handshake")
+	}
+	if conf.Producer.RequiredAcks != WaitForAll || conf.Net.MaxOpenRequests != 1 {
+		t.Error("expected the settings required by the idempotent producer")
+	}
+}
+
+func TestNewConfigWithVersion(t *testing.T) {
+	conf, err := NewConfigWith(WithSASLPlain("alice", "secret"), WithVersion(V0_10_2_0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if conf.Net.SASL.Version != SASLHandshakeV0 {
+		t.Error("expected the version 0 of the SASL handshake before Kafka 1.0")
+	}
+
+	// an explicit Version is not raised for the options
+	_, err = NewConfigWith(WithVersion(V0_10_2_0), WithReadCommitted())
+	if !errors.As(err, new(ConfigurationError)) {
+		t.Errorf("expected a ConfigurationError, got %v", err)
+	}
+}
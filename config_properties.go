@@ -0,0 +1,385 @@
+package sarama
+
+import (
+	"bufio"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NewConfigFromProperties returns a new Config with the defaults of NewConfig,
+// overridden by props, which hold settings under the keys of the Java client
+// and librdkafka, e.g. security.protocol or linger.ms, so that configuration
+// can be shared with clients in other languages and their tooling. It also
+// returns the broker addresses listed by bootstrap.servers.
+//
+// The properties without an equivalent in Config are logged and ignored. The
+// Config is not validated, so that the settings without a property, such as
+// Net.SASL.SCRAMClientGeneratorFunc, can be filled in first.
+func NewConfigFromProperties(props map[string]string) (*Config, []string, error) {
+	c := NewConfig()
+
+	keys := make([]string, 0, len(props))
+	for key := range props {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var addrs []string
+	for _, key := range keys {
+		value := strings.TrimSpace(props[key])
+		switch {
+		case key == "bootstrap.servers":
+			addrs = splitProperty(value)
+		case key == "security.protocol" || strings.HasPrefix(key, "ssl.") || key == "enable.ssl.certificate.verification":
+			// applied once all the properties are known
+		default:
+			set, ok := configProperties[key]
+			if !ok {
+				infof("Ignoring property %s, which has no equivalent in the Config\n", key)
+				continue
+			}
+			if err := set(c, value); err != nil {
+				return nil, nil, ConfigurationError(fmt.Sprintf("Invalid property %s: %s", key, err))
+			}
+		}
+	}
+
+	if err := c.applySecurityProperties(props); err != nil {
+		return nil, nil, err
+	}
+	return c, addrs, nil
+}
+
+// PropertiesFromEnv returns the properties set by the environment variables
+// whose name starts with prefix, for NewConfigFromProperties. The rest of the
+// name is lower-cased, with single underscores standing for dots and double
+// underscores for underscores, e.g. KAFKA_BOOTSTRAP_SERVERS sets
+// bootstrap.servers with the prefix "KAFKA_".
+func PropertiesFromEnv(prefix string) map[string]string {
+	props := make(map[string]string)
+	for _, env := range os.Environ() {
+		name, value, ok := cutProperty(env, "=")
+		if !ok || !strings.HasPrefix(name, prefix) || len(name) == len(prefix) {
+			continue
+		}
+		key := strings.ToLower(name[len(prefix):])
+		key = strings.ReplaceAll(key, "__", "\x00")
+		key = strings.ReplaceAll(key, "_", ".")
+		key = strings.ReplaceAll(key, "\x00", "_")
+		props[key] = value
+	}
+	return props
+}
+
+// ReadProperties reads properties for NewConfigFromProperties from r, in the
+// format of the configuration files of the Java client and librdkafka: a
+// key=value or key:value per line, the lines starting with # or ! being
+// comments and those ending with a backslash continuing on the next one.
+func ReadProperties(r io.Reader) (map[string]string, error) {
+	props := make(map[string]string)
+	scanner := bufio.NewScanner(r)
+	var line string
+	for scanner.Scan() {
+		text := strings.TrimSpace(scanner.Text())
+		if line == "" && (text == "" || text[0] == '#' || text[0] == '!') {
+			continue
+		}
+		if strings.HasSuffix(text, "\\") && !strings.HasSuffix(text, "\\\\") {
+			line += text[:len(text)-1]
+			continue
+		}
+		line += text
+
+		i := strings.IndexAny(line, "=:")
+		if i <= 0 {
+			return nil, ConfigurationError(fmt.Sprintf("Invalid property line %q, should be key=value", line))
+		}
+		props[strings.TrimSpace(line[:i])] = strings.TrimSpace(line[i+1:])
+		line = ""
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	if line != "" {
+		return nil, ConfigurationError(fmt.Sprintf("Invalid property line %q, continued past the end", line))
+	}
+	return props, nil
+}
+
+// configProperties sets the Config fields equivalent to each property.
+var configProperties = map[string]func(c *Config, value string) error{
+	"client.id": func(c *Config, value string) error {
+		c.ClientID = value
+		return nil
+	},
+	"client.rack": func(c *Config, value string) error {
+		c.RackID = value
+		return nil
+	},
+	"client.dns.lookup": func(c *Config, value string) error {
+		c.Net.DNSLookup = DNSLookup(value)
+		return nil
+	},
+	"broker.version.fallback": func(c *Config, value string) (err error) {
+		c.Version, err = ParseKafkaVersion(value)
+		return err
+	},
+	"socket.connection.setup.timeout.ms":    durationProperty(func(c *Config) *time.Duration { return &c.Net.DialTimeout }),
+	"request.timeout.ms":                    durationProperty(func(c *Config) *time.Duration { return &c.Net.ReadTimeout }),
+	"send.buffer.bytes":                     bufferSizeProperty(func(c *Config) *int { return &c.Net.WriteBufferSize }),
+	"receive.buffer.bytes":                  bufferSizeProperty(func(c *Config) *int { return &c.Net.ReadBufferSize }),
+	"max.in.flight.requests.per.connection": intProperty(func(c *Config) *int { return &c.Net.MaxOpenRequests }),
+	"metadata.max.age.ms":                   durationProperty(func(c *Config) *time.Duration { return &c.Metadata.RefreshFrequency }),
+	"retry.backoff.ms": func(c *Config, value string) error {
+		backoff, err := parseMillis(value)
+		c.Metadata.Retry.Backoff = backoff
+		c.Producer.Retry.Backoff = backoff
+		c.Consumer.Retry.Backoff = backoff
+		return err
+	},
+
+	"sasl.mechanism": func(c *Config, value string) error {
+		c.Net.SASL.Mechanism = SASLMechanism(strings.ToUpper(value))
+		return nil
+	},
+	"sasl.mechanisms": func(c *Config, value string) error {
+		c.Net.SASL.Mechanism = SASLMechanism(strings.ToUpper(value))
+		return nil
+	},
+	"sasl.username": func(c *Config, value string) error {
+		c.Net.SASL.User = value
+		return nil
+	},
+	"sasl.password": func(c *Config, value string) error {
+		c.Net.SASL.Password = value
+		return nil
+	},
+	"sasl.jaas.config": func(c *Config, value string) error {
+		options := jaasOptions(value)
+		if options["username"] == "" {
+			return errors.New("no username option found")
+		}
+		c.Net.SASL.User = options["username"]
+		c.Net.SASL.Password = options["password"]
+		return nil
+	},
+	"sasl.kerberos.service.name": func(c *Config, value string) error {
+		c.Net.SASL.GSSAPI.ServiceName = value
+		return nil
+	},
+
+	"acks": func(c *Config, value string) error {
+		switch strings.ToLower(value) {
+		case "all", "-1":
+			c.Producer.RequiredAcks = WaitForAll
+		case "0":
+			c.Producer.RequiredAcks = NoResponse
+		case "1":
+			c.Producer.RequiredAcks = WaitForLocal
+		default:
+			return errors.New("must be all, -1, 0 or 1")
+		}
+		return nil
+	},
+	"compression.type": func(c *Config, value string) error {
+		return c.Producer.Compression.UnmarshalText([]byte(strings.ToLower(value)))
+	},
+	"linger.ms":         durationProperty(func(c *Config) *time.Duration { return &c.Producer.Flush.Frequency }),
+	"batch.size":        intProperty(func(c *Config) *int { return &c.Producer.Flush.Bytes }),
+	"max.request.size":  intProperty(func(c *Config) *int { return &c.Producer.MaxRequestBytes }),
+	"message.max.bytes": intProperty(func(c *Config) *int { return &c.Producer.MaxMessageBytes }),
+	"retries":           intProperty(func(c *Config) *int { return &c.Producer.Retry.Max }),
+	"enable.idempotence": func(c *Config, value string) (err error) {
+		c.Producer.Idempotent, err = strconv.ParseBool(value)
+		return err
+	},
+
+	"auto.offset.reset": func(c *Config, value string) error {
+		switch strings.ToLower(value) {
+		case "earliest", "smallest", "beginning":
+			c.Consumer.Offsets.Initial = OffsetOldest
+		case "latest", "largest", "end":
+			c.Consumer.Offsets.Initial = OffsetNewest
+		default:
+			return errors.New("must be earliest or latest")
+		}
+		return nil
+	},
+	"enable.auto.commit": func(c *Config, value string) (err error) {
+		c.Consumer.Offsets.AutoCommit.Enable, err = strconv.ParseBool(value)
+		return err
+	},
+	"auto.commit.interval.ms": durationProperty(func(c *Config) *time.Duration { return &c.Consumer.Offsets.AutoCommit.Interval }),
+	"session.timeout.ms":      durationProperty(func(c *Config) *time.Duration { return &c.Consumer.Group.Session.Timeout }),
+	"heartbeat.interval.ms":   durationProperty(func(c *Config) *time.Duration { return &c.Consumer.Group.Heartbeat.Interval }),
+	"max.poll.interval.ms":    durationProperty(func(c *Config) *time.Duration { return &c.Consumer.Group.Rebalance.Timeout }),
+	"partition.assignment.strategy": func(c *Config, value string) error {
+		for _, name := range splitProperty(value) {
+			// Java clients name the assignor classes
+			name = strings.ToLower(name[strings.LastIndex(name, ".")+1:])
+			switch strings.TrimSuffix(name, "assignor") {
+			case "range":
+				c.Consumer.Group.Rebalance.Strategy = BalanceStrategyRange
+			case "roundrobin":
+				c.Consumer.Group.Rebalance.Strategy = BalanceStrategyRoundRobin
+			case "sticky":
+				c.Consumer.Group.Rebalance.Strategy = BalanceStrategySticky
+			default:
+				continue
+			}
+			return nil
+		}
+		return errors.New("must list range, roundrobin or sticky")
+	},
+	"fetch.min.bytes":           int32Property(func(c *Config) *int32 { return &c.Consumer.Fetch.Min }),
+	"fetch.max.bytes":           int32Property(func(c *Config) *int32 { return &c.Consumer.Fetch.Max }),
+	"max.partition.fetch.bytes": int32Property(func(c *Config) *int32 { return &c.Consumer.Fetch.Default }),
+	"fetch.max.wait.ms":         durationProperty(func(c *Config) *time.Duration { return &c.Consumer.MaxWaitTime }),
+	"isolation.level": func(c *Config, value string) error {
+		switch strings.ToLower(value) {
+		case "read_uncommitted":
+			c.Consumer.IsolationLevel = ReadUncommitted
+		case "read_committed":
+			c.Consumer.IsolationLevel = ReadCommitted
+		default:
+			return errors.New("must be read_uncommitted or read_committed")
+		}
+		return nil
+	},
+}
+
+// applySecurityProperties enables TLS and SASL as security.protocol says,
+// configuring TLS from the ssl.* properties.
+func (c *Config) applySecurityProperties(props map[string]string) error {
+	switch protocol := strings.ToUpper(strings.TrimSpace(props["security.protocol"])); protocol {
+	case "", "PLAINTEXT":
+	case "SSL":
+		c.Net.TLS.Enable = true
+	case "SASL_PLAINTEXT":
+		c.Net.SASL.Enable = true
+	case "SASL_SSL":
+		c.Net.TLS.Enable = true
+		c.Net.SASL.Enable = true
+	default:
+		return ConfigurationError(fmt.Sprintf("Invalid property security.protocol: %s is not one of PLAINTEXT, SSL, SASL_PLAINTEXT or SASL_SSL", protocol))
+	}
+	if !c.Net.TLS.Enable {
+		return nil
+	}
+
+	tlsConfig := new(tls.Config)
+	if location := props["ssl.ca.location"]; location != "" {
+		pem, err := os.ReadFile(location)
+		if err != nil {
+			return ConfigurationError(fmt.Sprintf("Invalid property ssl.ca.location: %s", err))
+		}
+		tlsConfig.RootCAs = x509.NewCertPool()
+		if !tlsConfig.RootCAs.AppendCertsFromPEM(pem) {
+			return ConfigurationError(fmt.Sprintf("Invalid property ssl.ca.location: no certificate found in %s", location))
+		}
+	}
+	if certificate := props["ssl.certificate.location"]; certificate != "" {
+		cert, err := tls.LoadX509KeyPair(certificate, props["ssl.key.location"])
+		if err != nil {
+			return ConfigurationError(fmt.Sprintf("Invalid property ssl.certificate.location: %s", err))
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	if verify := props["enable.ssl.certificate.verification"]; verify != "" {
+		enabled, err := strconv.ParseBool(verify)
+		if err != nil {
+			return ConfigurationError(fmt.Sprintf("Invalid property enable.ssl.certificate.verification: %s", err))
+		}
+		tlsConfig.InsecureSkipVerify = !enabled
+	}
+	for key := range props {
+		switch key {
+		case "ssl.ca.location", "ssl.certificate.location", "ssl.key.location":
+		default:
+			if strings.HasPrefix(key, "ssl.") {
+				infof("Ignoring property %s, which has no equivalent in the Config\n", key)
+			}
+		}
+	}
+	c.Net.TLS.Config = tlsConfig
+	return nil
+}
+
+func durationProperty(field func(c *Config) *time.Duration) func(c *Config, value string) error {
+	return func(c *Config, value string) (err error) {
+		*field(c), err = parseMillis(value)
+		return err
+	}
+}
+
+func intProperty(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) (err error) {
+		*field(c), err = strconv.Atoi(value)
+		return err
+	}
+}
+
+func int32Property(field func(c *Config) *int32) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		n, err := strconv.ParseInt(value, 10, 32)
+		*field(c) = int32(n)
+		return err
+	}
+}
+
+// bufferSizeProperty sets a socket buffer size, -1 standing for the
+// operating system default as in the Java client.
+func bufferSizeProperty(field func(c *Config) *int) func(c *Config, value string) error {
+	return func(c *Config, value string) error {
+		size, err := strconv.Atoi(value)
+		if size == -1 {
+			size = 0
+		}
+		*field(c) = size
+		return err
+	}
+}
+
+func parseMillis(value string) (time.Duration, error) {
+	ms, err := strconv.ParseInt(value, 10, 64)
+	return time.Duration(ms) * time.Millisecond, err
+}
+
+// splitProperty splits a comma separated list.
+func splitProperty(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func cutProperty(s, sep string) (before, after string, found bool) {
+	if i := strings.Index(s, sep); i >= 0 {
+		return s[:i], s[i+len(sep):], true
+	}
+	return s, "", false
+}
+
+var jaasOption = regexp.MustCompile(`(\w+)\s*=\s*"((?:[^"\\]|\\.)*)"`)
+
+// jaasOptions returns the options of a JAAS login module configuration such
+// as `PlainLoginModule required username="alice" password="secret";`.
+func jaasOptions(config string) map[string]string {
+	options := make(map[string]string)
+	for _, match := range jaasOption.FindAllStringSubmatch(config, -1) {
+		options[match[1]] = strings.NewReplacer(`\"`, `"`, `\\`, `\`).Replace(match[2])
+	}
+	return options
+}
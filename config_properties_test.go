@@ -0,0 +1,142 @@
+package sarama
+
+import (
+	"errors"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestNewConfigFromProperties(t *testing.T) {
+	conf, addrs, err := NewConfigFromProperties(map[string]string{
+		"bootstrap.servers":                   "broker1:9092, broker2:9092",
+		"client.id":                           "my-service",
+		"security.protocol":                   "sasl_ssl",
+		"sasl.mechanism":                      "plain",
+		"sasl.jaas.config":                    `org.apache.kafka.common.security.plain.PlainLoginModule required username="alice" password="s3\"cret";`,
+		"enable.ssl.certificate.verification": "false",
+		"acks":                                "all",
+		"compression.type":                    "zstd",
+		"linger.ms":                           "5",
+		"auto.offset.reset":                   "earliest",
+		"partition.assignment.strategy":       "org.apache.kafka.clients.consumer.CooperativeStickyAssignor,org.apache.kafka.clients.consumer.RoundRobinAssignor",
+		"broker.version.fallback":             "2.8.0",
+		"key.serializer":                      "org.apache.kafka.common.serialization.StringSerializer",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if expected := []string{"broker1:9092", "broker2:9092"}; !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("expected the bootstrap servers %v, got %v", expected, addrs)
+	}
+	if conf.ClientID != "my-service" {
+		t.Errorf("unexpected ClientID %q", conf.ClientID)
+	}
+	if !conf.Net.TLS.Enable || !conf.Net.TLS.Config.InsecureSkipVerify {
+		t.Error("expected TLS to be enabled without certificate verification")
+	}
+	if !conf.Net.SASL.Enable || conf.Net.SASL.Mechanism != SASLTypePlaintext ||
+		conf.Net.SASL.User != "alice" || conf.Net.SASL.Password != `s3"cret` {
+		t.Errorf("expected SASL/PLAIN as alice, got %s as %s", conf.Net.SASL.Mechanism, conf.Net.SASL.User)
+	}
+	if conf.Producer.RequiredAcks != WaitForAll || conf.Producer.Compression != CompressionZSTD ||
+		conf.Producer.Flush.Frequency != 5*time.Millisecond {
+		t.Error("unexpected producer settings")
+	}
+	if conf.Consumer.Offsets.Initial != OffsetOldest || conf.Consumer.Group.Rebalance.Strategy != BalanceStrategyRoundRobin {
+		t.Error("unexpected consumer settings")
+	}
+	if conf.Version != V2_8_0_0 {
+		t.Errorf("unexpected Version %s", conf.Version)
+	}
+	if err := conf.Validate(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestNewConfigFromPropertiesMaxBytes(t *testing.T) {
+	conf, _, err := NewConfigFromProperties(map[string]string{
+		"max.request.size":  "2000000",
+		"message.max.bytes": "500000",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the size of a produce request, and the per-batch limit of the brokers
+	if conf.Producer.MaxRequestBytes != 2000000 {
+		t.Errorf("expected max.request.size to set MaxRequestBytes, got %d", conf.Producer.MaxRequestBytes)
+	}
+	if conf.Producer.MaxMessageBytes != 500000 {
+		t.Errorf("expected message.max.bytes to set MaxMessageBytes, got %d", conf.Producer.MaxMessageBytes)
+	}
+}
+
+func TestNewConfigFromPropertiesErrors(t *testing.T) {
+	for key, value := range map[string]string{
+		"linger.ms":         "soon",
+		"acks":              "2",
+		"security.protocol": "TLS",
+		"sasl.jaas.config":  "PlainLoginModule required;",
+		"isolation.level":   "serializable",
+	} {
+		_, _, err := NewConfigFromProperties(map[string]string{key: value})
+		if !errors.As(err, new(ConfigurationError)) {
+			t.Errorf("expected a ConfigurationError for %s=%s, got %v", key, value, err)
+		}
+	}
+}
+
+func TestPropertiesFromEnv(t *testing.T) {
+	for name, value := range map[string]string{
+		"SARAMA_TEST_BOOTSTRAP_SERVERS":  "localhost:9092",
+		"SARAMA_TEST_SASL_JAAS_CONFIG":   "config",
+		"SARAMA_TEST_SOME__KEY_WITH__US": "value",
+	} {
+		if err := os.Setenv(name, value); err != nil {
+			t.Fatal(err)
+		}
+		defer os.Unsetenv(name)
+	}
+
+	expected := map[string]string{
+		"bootstrap.servers": "localhost:9092",
+		"sasl.jaas.config":  "config",
+		"some_key.with_us":  "value",
+	}
+	if props := PropertiesFromEnv("SARAMA_TEST_"); !reflect.DeepEqual(props, expected) {
+		t.Errorf("expected %v, got %v", expected, props)
+	}
+}
+
+func TestReadProperties(t *testing.T) {
+	props, err := ReadProperties(strings.NewReader(`
+# the cluster
+bootstrap.servers=broker1:9092,broker2:9092
+security.protocol = SASL_SSL
+! the credentials
+sasl.jaas.config=org.apache.kafka.common.security.plain.PlainLoginModule required \
+    username="alice" password="secret";
+client.id: my-service
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]string{
+		"bootstrap.servers": "broker1:9092,broker2:9092",
+		"security.protocol": "SASL_SSL",
+		"sasl.jaas.config":  `org.apache.kafka.common.security.plain.PlainLoginModule required username="alice" password="secret";`,
+		"client.id":         "my-service",
+	}
+	if !reflect.DeepEqual(props, expected) {
+		t.Errorf("expected %v, got %v", expected, props)
+	}
+
+	for _, invalid := range []string{"bootstrap.servers", "=value", "key=value \\"} {
+		if _, err := ReadProperties(strings.NewReader(invalid)); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
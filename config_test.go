@@ -38,6 +38,30 @@ func TestEmptyClientIDConfigValidates(t *testing.T) {
 	}
 }
 
+func TestConfigValidateWithWarnings(t *testing.T) {
+	config := NewTestConfig()
+	config.ClientID = "my-service"
+	if warnings, err := config.ValidateWithWarnings(); err != nil || len(warnings) != 0 {
+		t.Errorf("Expected no warnings, got %v, %v", warnings, err)
+	}
+
+	config.Net.SASL.Enable = true
+	config.Net.SASL.User = "alice"
+	config.Net.SASL.Password = "secret"
+	config.Consumer.Fetch.Max = 1024
+	warnings, err := config.ValidateWithWarnings()
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := make([]string, 0, len(warnings))
+	for _, warning := range warnings {
+		fields = append(fields, warning.Field)
+	}
+	if len(fields) != 2 || fields[0] != "Net.SASL.Mechanism" || fields[1] != "Consumer.Fetch.Max" {
+		t.Errorf("Expected warnings on Net.SASL.Mechanism and Consumer.Fetch.Max, got %v", warnings)
+	}
+}
+
 type DummyTokenProvider struct{}
 
 func (t *DummyTokenProvider) Token() (*AccessToken, error) {
@@ -57,6 +81,13 @@ func TestNetConfigValidates(t *testing.T) {
 			},
 			"Net.MaxOpenRequests must be > 0",
 		},
+		{
+			"ResponseHandlers",
+			func(cfg *Config) {
+				cfg.Net.ResponseHandlers = -1
+			},
+			"Net.ResponseHandlers must be >= 0",
+		},
 		{
 			"DialTimeout",
 			func(cfg *Config) {
@@ -78,6 +109,41 @@ func TestNetConfigValidates(t *testing.T) {
 			},
 			"Net.WriteTimeout must be > 0",
 		},
+		{
+			"Metadata.RefreshJitter",
+			func(cfg *Config) {
+				cfg.Metadata.RefreshJitter = 1
+			},
+			"Metadata.RefreshJitter must be >= 0 and < 1",
+		},
+		{
+			"Net.DSCP",
+			func(cfg *Config) {
+				cfg.Net.DSCP = 64
+			},
+			"Net.DSCP must be between 0 and 63",
+		},
+		{
+			"Net.Throttle.Threshold",
+			func(cfg *Config) {
+				cfg.Net.Throttle.Threshold = -1
+			},
+			"Net.Throttle.Threshold must be >= 0",
+		},
+		{
+			"Net.DNSLookup",
+			func(cfg *Config) {
+				cfg.Net.DNSLookup = "use_first_ip"
+			},
+			"Net.DNSLookup must be one of default, use_all_dns_ips or resolve_canonical_bootstrap_servers_only",
+		},
+		{
+			"Net.Proxy.Dialer",
+			func(cfg *Config) {
+				cfg.Net.Proxy.Enable = true
+			},
+			"Net.Proxy.Dialer or Net.Proxy.DialerFunc must be set when Net.Proxy.Enable is true",
+		},
 		{
 			"SASL.User",
 			func(cfg *Config) {
@@ -102,7 +168,7 @@ func TestNetConfigValidates(t *testing.T) {
 				cfg.Net.SASL.Mechanism = "AnIncorrectSASLMechanism"
 				cfg.Net.SASL.TokenProvider = &DummyTokenProvider{}
 			},
-			"The SASL mechanism configuration is invalid. Possible values are `OAUTHBEARER`, `PLAIN`, `SCRAM-SHA-256`, `SCRAM-SHA-512` and `GSSAPI`",
+			"The SASL mechanism configuration is invalid. Possible values are `OAUTHBEARER`, `PLAIN`, `SCRAM-SHA-256`, `SCRAM-SHA-512`, `GSSAPI` and `AWS_MSK_IAM`",
 		},
 		{
 			"SASL.Mechanism.OAUTHBEARER - Missing token provider",
@@ -113,6 +179,15 @@ func TestNetConfigValidates(t *testing.T) {
 			},
 			"An AccessTokenProvider instance must be provided to Net.SASL.TokenProvider",
 		},
+		{
+			"SASL.Mechanism.AWS_MSK_IAM - Unsupported version",
+			func(cfg *Config) {
+				cfg.Net.SASL.Enable = true
+				cfg.Net.SASL.Mechanism = SASLTypeAWSMSKIAM
+				cfg.Version = V0_10_2_0
+			},
+			"The AWS_MSK_IAM SASL mechanism requires Version >= V1_0_0_0",
+		},
 		{
 			"SASL.Mechanism SCRAM-SHA-256 - Missing SCRAM client",
 			func(cfg *Config) {
@@ -163,6 +238,18 @@ func TestNetConfigValidates(t *testing.T) {
 			"Net.SASL.GSSAPI.KeyTabPath must not be empty when GSS-API mechanism is used" +
 				" and  Net.SASL.GSSAPI.AuthType = KRB5_KEYTAB_AUTH",
 		},
+		{
+			"SASL.Mechanism GSSAPI (Kerberos) - Using credential cache, Missing CCachePath field",
+			func(cfg *Config) {
+				cfg.Net.SASL.Enable = true
+				cfg.Net.SASL.Mechanism = SASLTypeGSSAPI
+				cfg.Net.SASL.GSSAPI.AuthType = KRB5_CCACHE_AUTH
+				cfg.Net.SASL.GSSAPI.ServiceName = "kafka"
+				cfg.Net.SASL.GSSAPI.KerberosConfigPath = "/etc/krb5.conf"
+			},
+			"Net.SASL.GSSAPI.CCachePath must not be empty when GSS-API mechanism is used" +
+				" and Net.SASL.GSSAPI.AuthType = KRB5_CCACHE_AUTH",
+		},
 		{
 			"SASL.Mechanism GSSAPI (Kerberos) - Missing username",
 			func(cfg *Config) {
@@ -200,7 +287,7 @@ func TestNetConfigValidates(t *testing.T) {
 				cfg.Net.SASL.GSSAPI.Realm = "kafka"
 				cfg.Net.SASL.GSSAPI.KerberosConfigPath = "/etc/krb5.conf"
 			},
-			"Net.SASL.GSSAPI.AuthType is invalid. Possible values are KRB5_USER_AUTH and KRB5_KEYTAB_AUTH",
+			"Net.SASL.GSSAPI.AuthType is invalid. Possible values are KRB5_USER_AUTH, KRB5_KEYTAB_AUTH and KRB5_CCACHE_AUTH",
 		},
 		{
 			"SASL.Mechanism GSSAPI (Kerberos) - Missing KerberosConfigPath",
@@ -228,6 +315,14 @@ func TestNetConfigValidates(t *testing.T) {
 			},
 			"Net.SASL.GSSAPI.Realm must not be empty when GSS-API mechanism is used",
 		},
+		{
+			"Listeners - Unknown preference",
+			func(cfg *Config) {
+				cfg.Net.Listeners.Preference = []string{"INTERNAL"}
+				cfg.Net.Listeners.Addresses = map[string]ListenerAddressFunc{"EXTERNAL": ListenerPort(9094)}
+			},
+			`Net.Listeners.Preference names the listener "INTERNAL", which is not in Net.Listeners.Addresses`,
+		},
 	}
 
 	for i, test := range tests {
@@ -241,6 +336,15 @@ func TestNetConfigValidates(t *testing.T) {
 	}
 }
 
+func TestListenerPort(t *testing.T) {
+	if addr := ListenerPort(9094)(1, "kafka-1:9092"); addr != "kafka-1:9094" {
+		t.Errorf("expected kafka-1:9094, got %s", addr)
+	}
+	if addr := ListenerPort(9094)(1, "kafka-1"); addr != "" {
+		t.Errorf("expected no address for an invalid advertised address, got %s", addr)
+	}
+}
+
 func TestMetadataConfigValidates(t *testing.T) {
 	tests := []struct {
 		name string
@@ -320,6 +424,13 @@ func TestProducerConfigValidates(t *testing.T) {
 			},
 			"Producer.MaxMessageBytes must be > 0",
 		},
+		{
+			"MaxRequestBytes",
+			func(cfg *Config) {
+				cfg.Producer.MaxRequestBytes = -1
+			},
+			"Producer.MaxRequestBytes must be >= 0",
+		},
 		{
 			"RequiredAcks",
 			func(cfg *Config) {
@@ -425,6 +536,27 @@ func TestProducerConfigValidates(t *testing.T) {
 			},
 			"Idempotent producer requires Net.MaxOpenRequests to be 1",
 		},
+		{
+			"Zstd window size",
+			func(cfg *Config) {
+				cfg.Producer.Zstd.WindowSize = 3 << 10
+			},
+			"Producer.Zstd.WindowSize must be 0 or a power of 2 between 1KiB and 512MiB",
+		},
+		{
+			"Zstd concurrency",
+			func(cfg *Config) {
+				cfg.Producer.Zstd.Concurrency = -1
+			},
+			"Producer.Zstd.Concurrency must be >= 0",
+		},
+		{
+			"Snappy framing",
+			func(cfg *Config) {
+				cfg.Producer.SnappyFraming = SnappyFraming(42)
+			},
+			"Producer.SnappyFraming must be SnappyRawBlock or SnappyXerialFraming",
+		},
 	}
 
 	for i, test := range tests {
@@ -460,6 +592,41 @@ func TestConsumerConfigValidates(t *testing.T) {
 			},
 			"Consumer.IsolationLevel must be ReadUncommitted or ReadCommitted",
 		},
+		{
+			"Incorrect CRC validation",
+			func(cfg *Config) {
+				cfg.Consumer.Fetch.CRCValidation = CRCValidation(42)
+			},
+			"Consumer.Fetch.CRCValidation must be CRCValidateAndFail, CRCValidateAndSkip or CRCSkipValidation",
+		},
+		{
+			"Negative MaxDecodedBytes",
+			func(cfg *Config) {
+				cfg.Consumer.Fetch.MaxDecodedBytes = -1
+			},
+			"Consumer.Fetch.MaxDecodedBytes must be >= 0",
+		},
+		{
+			"Negative MaxBatchRecords",
+			func(cfg *Config) {
+				cfg.Consumer.Fetch.MaxBatchRecords = -1
+			},
+			"Consumer.Fetch.MaxBatchRecords must be >= 0",
+		},
+		{
+			"Zstd max window size",
+			func(cfg *Config) {
+				cfg.Consumer.Zstd.MaxWindowSize = 512
+			},
+			"Consumer.Zstd.MaxWindowSize must be 0 or >= 1KiB",
+		},
+		{
+			"Zstd concurrency",
+			func(cfg *Config) {
+				cfg.Consumer.Zstd.Concurrency = -1
+			},
+			"Consumer.Zstd.Concurrency must be >= 0",
+		},
 	}
 
 	for i, test := range tests {
@@ -503,6 +670,20 @@ func TestZstdConfigValidation(t *testing.T) {
 
 // This example shows how to integrate with an existing registry as well as publishing metrics
 // on the standard output
+func TestTelemetryConfigValidation(t *testing.T) {
+	config := NewTestConfig()
+	config.Telemetry.Enable = true
+	err := config.Validate()
+	var target ConfigurationError
+	if !errors.As(err, &target) || string(target) != "Telemetry requires Version >= V3_7_0_0" {
+		t.Error("Expected invalid telemetry/kafka version error, got ", err)
+	}
+	config.Version = V3_7_0_0
+	if err := config.Validate(); err != nil {
+		t.Error("Expected telemetry to work, got ", err)
+	}
+}
+
 func ExampleConfig_metrics() {
 	// Our application registry
 	appMetricRegistry := metrics.NewRegistry()
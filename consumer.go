@@ -1,14 +1,14 @@
 package sarama
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"sync"
 	"sync/atomic"
 	"time"
-
-	"github.com/rcrowley/go-metrics"
 )
 
 // ConsumerMessage encapsulates a Kafka message returned by the consumer.
@@ -21,6 +21,44 @@ type ConsumerMessage struct {
 	Topic      string
 	Partition  int32
 	Offset     int64
+
+	// pooled reports whether the message was leased from consumerMessagePool,
+	// with its Key and Value copied into buf
+	pooled bool
+	buf    []byte
+	// rawHeaders holds the headers left undecoded by
+	// Consumer.Fetch.LazyHeaders until RecordHeaders is called
+	rawHeaders []byte
+}
+
+// consumerMessagePool holds the messages released with Consumer.PoolMessages.
+var consumerMessagePool = sync.Pool{
+	New: func() interface{} {
+		return new(ConsumerMessage)
+	},
+}
+
+// Release hands the message back to its pool when Consumer.PoolMessages is
+// enabled, after which neither it nor its Key, Value and Headers may be used,
+// as the message may be leased again right away. It must be called at most
+// once per message received. It does nothing for other messages.
+func (m *ConsumerMessage) Release() {
+	if !m.pooled {
+		return
+	}
+	*m = ConsumerMessage{buf: m.buf[:0]}
+	consumerMessagePool.Put(m)
+}
+
+// RecordHeaders returns the headers of the message, decoding them on the first
+// call when Consumer.Fetch.LazyHeaders left them undecoded, after which they
+// are also set in Headers. Headers which fail to decode are returned as nil.
+func (m *ConsumerMessage) RecordHeaders() []*RecordHeader {
+	if m.rawHeaders != nil {
+		m.Headers, _ = decodeRawRecordHeaders(m.rawHeaders)
+		m.rawHeaders = nil
+	}
+	return m.Headers
 }
 
 // ConsumerError is what is provided to the user when an error occurs.
@@ -75,6 +113,13 @@ type Consumer interface {
 	// PartitionConsumers have already been closed.
 	Close() error
 
+	// CloseContext closes the PartitionConsumers still running and waits for
+	// them to stop, before shutting down the consumer like Close. If ctx is
+	// done first, the connections of the consumer are torn down regardless,
+	// unless it was created from a client, and a *CloseAbandonedError lists
+	// the partitions whose consumers were abandoned.
+	CloseContext(ctx context.Context) error
+
 	// Pause suspends fetching from the requested partitions. Future calls to the broker will not return any
 	// records from these partitions until they have been resumed using Resume()/ResumeAll().
 	// Note that this method does not affect partition subscription.
@@ -114,12 +159,18 @@ func NewConsumer(addrs []string, config *Config) (Consumer, error) {
 }
 
 // NewConsumerFromClient creates a new consumer using the given client. It is still
-// necessary to call Close() on the underlying client when shutting down this consumer.
+// necessary to call Close() on the underlying client when shutting down this consumer,
+// unless it was shared with ShareClient.
 func NewConsumerFromClient(client Client) (Consumer, error) {
-	// For clients passed in by the client, ensure we don't
-	// call Close() on it.
-	cli := &nopCloserClient{client}
-	return newConsumer(cli)
+	cli, err := borrowClient(client)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newConsumer(cli)
+	if err != nil {
+		_ = cli.Close()
+	}
+	return c, err
 }
 
 func newConsumer(client Client) (Consumer, error) {
@@ -142,6 +193,67 @@ func (c *consumer) Close() error {
 	return c.client.Close()
 }
 
+func (c *consumer) CloseContext(ctx context.Context) error {
+	c.lock.Lock()
+	var children []*partitionConsumer
+	for _, partitions := range c.children {
+		for _, child := range partitions {
+			children = append(children, child)
+		}
+	}
+	c.lock.Unlock()
+
+	var (
+		lock    sync.Mutex
+		errors  ConsumerErrors
+		stopped = make(map[*partitionConsumer]bool, len(children))
+		wg      sync.WaitGroup
+	)
+	for _, child := range children {
+		child := child
+		wg.Add(1)
+		go withRecover(func() {
+			defer wg.Done()
+			child.AsyncClose()
+			for err := range child.errors {
+				lock.Lock()
+				errors = append(errors, err)
+				lock.Unlock()
+			}
+			lock.Lock()
+			stopped[child] = true
+			lock.Unlock()
+		})
+	}
+
+	if err := waitContext(ctx, wg.Wait); err != nil {
+		abandoned := &CloseAbandonedError{Err: err, Partitions: make(map[string][]int32)}
+		lock.Lock()
+		for _, child := range children {
+			if !stopped[child] {
+				abandoned.Partitions[child.topic] = append(abandoned.Partitions[child.topic], child.partition)
+			}
+		}
+		for _, err := range errors {
+			abandoned.Errors = append(abandoned.Errors, err)
+		}
+		lock.Unlock()
+
+		done, cancel := context.WithCancel(context.Background())
+		cancel()
+		abandoned.merge(c.client.CloseContext(done))
+		return abandoned
+	}
+
+	if err := c.client.CloseContext(ctx); err != nil {
+		return err
+	}
+	if len(errors) > 0 {
+		return errors
+	}
+	return nil
+}
+
 func (c *consumer) Topics() ([]string, error) {
 	return c.client.Topics()
 }
@@ -158,11 +270,12 @@ func (c *consumer) ConsumePartition(topic string, partition int32, offset int64)
 		partition:            partition,
 		messages:             make(chan *ConsumerMessage, c.conf.ChannelBufferSize),
 		errors:               make(chan *ConsumerError, c.conf.ChannelBufferSize),
-		feeder:               make(chan *FetchResponse, 1),
+		feeder:               make(chan fetchedBlock, 1),
 		preferredReadReplica: invalidPreferredReplicaID,
 		trigger:              make(chan none, 1),
 		dying:                make(chan none),
 		fetchSize:            c.conf.Consumer.Fetch.Default,
+		buffers:              new(decompressBuffers),
 	}
 
 	if err := child.chooseStartingOffset(offset); err != nil {
@@ -174,6 +287,9 @@ func (c *consumer) ConsumePartition(topic string, partition int32, offset int64)
 	if leader, err = c.client.Leader(child.topic, child.partition); err != nil {
 		return nil, err
 	}
+	if c.conf.Version.IsAtLeast(V2_8_0_0) {
+		child.topicID, _ = c.client.TopicID(child.topic)
+	}
 
 	if err := c.addChild(child); err != nil {
 		return nil, err
@@ -204,6 +320,35 @@ func (c *consumer) HighWaterMarks() map[string]map[int32]int64 {
 	return hwms
 }
 
+// debugState returns the states of the partitions consumed, sorted by topic
+// and partition, without the offsets marked.
+func (c *consumer) debugState() []ConsumerPartitionState {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	var states []ConsumerPartitionState
+	for topic, p := range c.children {
+		for partition, pc := range p {
+			states = append(states, ConsumerPartitionState{
+				Topic:               topic,
+				Partition:           partition,
+				Offset:              atomic.LoadInt64(&pc.fetchOffset),
+				MarkedOffset:        -1,
+				HighWaterMarkOffset: pc.HighWaterMarkOffset(),
+				Buffered:            len(pc.messages),
+				Paused:              pc.IsPaused(),
+			})
+		}
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].Topic != states[j].Topic {
+			return states[i].Topic < states[j].Topic
+		}
+		return states[i].Partition < states[j].Partition
+	})
+	return states
+}
+
 func (c *consumer) addChild(child *partitionConsumer) error {
 	c.lock.Lock()
 	defer c.lock.Unlock()
@@ -386,13 +531,15 @@ type PartitionConsumer interface {
 
 type partitionConsumer struct {
 	highWaterMarkOffset int64 // must be at the top of the struct because https://golang.org/pkg/sync/atomic/#pkg-note-BUG
+	// fetchOffset publishes offset for DebugState, as of the last fetch request
+	fetchOffset int64
 
 	consumer *consumer
 	conf     *Config
 	broker   *brokerConsumer
 	messages chan *ConsumerMessage
 	errors   chan *ConsumerError
-	feeder   chan *FetchResponse
+	feeder   chan fetchedBlock
 
 	preferredReadReplica int32
 
@@ -400,14 +547,57 @@ type partitionConsumer struct {
 	closeOnce      sync.Once
 	topic          string
 	partition      int32
+	// topicID is the ID of the topic when the consumer started, zero unless
+	// known, to stop consuming if the topic is recreated
+	topicID        Uuid
 	responseResult error
 	fetchSize      int32
 	offset         int64
 	retries        int32
+	// fetch is the response being fed batch by batch with Consumer.Fetch.Lazy
+	fetch *lazyFetch
+	// buffers are those the record batches of the partition are decompressed
+	// into, reused with Consumer.PoolMessages
+	buffers *decompressBuffers
 
 	paused int32
 }
 
+// fetchedBlock is the block of a fetch response handed off to the
+// partitionConsumer of the partition, which owns it from then on: its records
+// are decoded from their own copy of the response (see FetchRequest.ownBlocks)
+// and the brokerConsumer keeps no reference to the response, so that each
+// block is released once its partition is done with it rather than once every
+// subscription is.
+type fetchedBlock struct {
+	// block is nil if the response holds no block for the partition
+	block        *FetchResponseBlock
+	throttleTime time.Duration
+	// empty is set if the response holds no block at all
+	empty bool
+}
+
+// takeFetchedBlock takes the block of the partition out of response.
+func takeFetchedBlock(response *FetchResponse, topic string, partition int32) fetchedBlock {
+	return fetchedBlock{
+		block:        response.takeBlock(topic, partition),
+		throttleTime: response.ThrottleTime,
+		empty:        len(response.Blocks) == 0,
+	}
+}
+
+// lazyFetch is the state of a fetch response whose record batches are decoded
+// and fed one at a time.
+type lazyFetch struct {
+	block               *FetchResponseBlock
+	batches             *RecordsIterator
+	abortedProducerIDs  map[int64]struct{}
+	abortedTransactions []*AbortedTransaction
+	records             int
+	partial             bool
+	batchSizeMetric     MetricsHistogram
+}
+
 var errTimedOut = errors.New("timed out feeding messages to the user") // not user-facing
 
 func (child *partitionConsumer) sendError(err error) {
@@ -420,10 +610,15 @@ func (child *partitionConsumer) sendError(err error) {
 	if child.conf.Consumer.Return.Errors {
 		child.errors <- cErr
 	} else {
-		Logger.Println(cErr)
+		child.log().warnf("%v", cErr)
 	}
 }
 
+// log returns the logContext of the records about the partition.
+func (child *partitionConsumer) log() logContext {
+	return logWith(topicField(child.topic), partitionField(child.partition))
+}
+
 func (child *partitionConsumer) computeBackoff() time.Duration {
 	if child.conf.Consumer.Retry.BackoffFunc != nil {
 		retries := atomic.AddInt32(&child.retries, 1)
@@ -438,6 +633,15 @@ func (child *partitionConsumer) dispatcher() {
 		case <-child.dying:
 			close(child.trigger)
 		case <-time.After(child.computeBackoff()):
+			if !child.conf.RetryBudget.AllowRetry() {
+				child.log().infof("consumer/%s/%d not retrying as the retry budget is exhausted\n", child.topic, child.partition)
+				// try again after another backoff, unless already triggered
+				select {
+				case child.trigger <- none{}:
+				default:
+				}
+				continue
+			}
 			if child.broker != nil {
 				child.consumer.unrefBrokerConsumer(child.broker)
 				child.broker = nil
@@ -463,7 +667,7 @@ func (child *partitionConsumer) preferredBroker() (*Broker, error) {
 		if err == nil {
 			return broker, nil
 		}
-		Logger.Printf(
+		child.log().warnf(
 			"consumer/%s/%d failed to find active broker for preferred read replica %d - will fallback to leader",
 			child.topic, child.partition, child.preferredReadReplica)
 
@@ -481,6 +685,9 @@ func (child *partitionConsumer) dispatch() error {
 	if err := child.consumer.client.RefreshMetadata(child.topic); err != nil {
 		return err
 	}
+	if err := child.checkTopicID(); err != nil {
+		return err
+	}
 
 	broker, err := child.preferredBroker()
 	if err != nil {
@@ -494,6 +701,22 @@ func (child *partitionConsumer) dispatch() error {
 	return nil
 }
 
+// checkTopicID stops consuming the partition if its topic was recreated, as the
+// offset does not apply to the new topic.
+func (child *partitionConsumer) checkTopicID() error {
+	if child.topicID == (Uuid{}) {
+		return nil
+	}
+	topicID, err := child.consumer.client.TopicID(child.topic)
+	if err != nil || topicID == child.topicID {
+		return nil
+	}
+
+	child.log().infof("consumer/%s/%d stopping as the topic was recreated\n", child.topic, child.partition)
+	child.AsyncClose()
+	return ErrTopicRecreated
+}
+
 func (child *partitionConsumer) chooseStartingOffset(offset int64) error {
 	newestOffset, err := child.consumer.client.GetOffset(child.topic, child.partition, OffsetNewest)
 	if err != nil {
@@ -517,6 +740,7 @@ func (child *partitionConsumer) chooseStartingOffset(offset int64) error {
 	default:
 		return ErrOffsetOutOfRange
 	}
+	atomic.StoreInt64(&child.fetchOffset, child.offset)
 
 	return nil
 }
@@ -558,49 +782,65 @@ func (child *partitionConsumer) HighWaterMarkOffset() int64 {
 }
 
 func (child *partitionConsumer) responseFeeder() {
-	var msgs []*ConsumerMessage
 	expiryTicker := time.NewTicker(child.conf.Consumer.MaxProcessingTime)
 	firstAttempt := true
 
 feederLoop:
-	for response := range child.feeder {
-		msgs, child.responseResult = child.parseResponse(response)
+	for fetched := range child.feeder {
+		// msgs is scoped to the response so as not to retain it while waiting
+		// for the next one
+		var msgs []*ConsumerMessage
+		msgs, child.responseResult = child.parseResponse(fetched)
 
 		if child.responseResult == nil {
 			atomic.StoreInt32(&child.retries, 0)
 		}
 
-		for i, msg := range msgs {
-			child.interceptors(msg)
-		messageSelect:
-			select {
-			case <-child.dying:
-				child.broker.acks.Done()
-				continue feederLoop
-			case child.messages <- msg:
-				firstAttempt = true
-			case <-expiryTicker.C:
-				if !firstAttempt {
-					child.responseResult = errTimedOut
+		for len(msgs) > 0 {
+			for i, msg := range msgs {
+				child.interceptors(msg)
+			messageSelect:
+				select {
+				case <-child.dying:
+					child.fetch = nil
 					child.broker.acks.Done()
-				remainingLoop:
-					for _, msg = range msgs[i:] {
-						child.interceptors(msg)
-						select {
-						case child.messages <- msg:
-						case <-child.dying:
-							break remainingLoop
+					continue feederLoop
+				case child.messages <- msg:
+					firstAttempt = true
+				case <-expiryTicker.C:
+					if !firstAttempt {
+						child.responseResult = errTimedOut
+						child.broker.acks.Done()
+						remaining := msgs[i:]
+					remainingLoop:
+						for len(remaining) > 0 {
+							for _, msg = range remaining {
+								child.interceptors(msg)
+								select {
+								case child.messages <- msg:
+								case <-child.dying:
+									child.fetch = nil
+									break remainingLoop
+								}
+							}
+							var err error
+							if remaining, err = child.nextMessages(); err != nil {
+								child.sendError(err)
+							}
 						}
+						child.broker.input <- child
+						continue feederLoop
+					} else {
+						// current message has not been sent, return to select
+						// statement
+						firstAttempt = false
+						goto messageSelect
 					}
-					child.broker.input <- child
-					continue feederLoop
-				} else {
-					// current message has not been sent, return to select
-					// statement
-					firstAttempt = false
-					goto messageSelect
 				}
 			}
+
+			// feed the next batch of a lazy fetch, if any
+			msgs, child.responseResult = child.nextMessages()
 		}
 
 		child.broker.acks.Done()
@@ -611,6 +851,46 @@ feederLoop:
 	close(child.errors)
 }
 
+// newMessage returns a message of the partition for key, value and headers,
+// either decoded or left raw by Consumer.Fetch.LazyHeaders. With
+// Consumer.PoolMessages, it is leased from consumerMessagePool and holds copies
+// of them, so as not to retain the fetch response nor the buffer they were
+// decompressed into.
+func (child *partitionConsumer) newMessage(key, value []byte, headers []*RecordHeader, rawHeaders []byte) *ConsumerMessage {
+	if !child.conf.Consumer.PoolMessages {
+		return &ConsumerMessage{Topic: child.topic, Partition: child.partition, Key: key, Value: value, Headers: headers, rawHeaders: rawHeaders}
+	}
+
+	msg := consumerMessagePool.Get().(*ConsumerMessage)
+	msg.pooled = true
+	msg.Topic = child.topic
+	msg.Partition = child.partition
+	msg.Headers = headers
+
+	// copied at once, the slices of buf being taken once it is grown
+	buf := append(append(append(msg.buf[:0], key...), value...), rawHeaders...)
+	for _, header := range headers {
+		buf = append(append(buf, header.Key...), header.Value...)
+	}
+	msg.buf = buf
+	take := func(b []byte) []byte {
+		if b == nil {
+			return nil
+		}
+		taken := buf[:len(b):len(b)]
+		buf = buf[len(b):]
+		return taken
+	}
+	msg.Key = take(key)
+	msg.Value = take(value)
+	msg.rawHeaders = take(rawHeaders)
+	for _, header := range headers {
+		header.Key = take(header.Key)
+		header.Value = take(header.Value)
+	}
+	return msg
+}
+
 func (child *partitionConsumer) parseMessages(msgSet *MessageSet) ([]*ConsumerMessage, error) {
 	var messages []*ConsumerMessage
 	for _, msgBlock := range msgSet.Messages {
@@ -627,15 +907,11 @@ func (child *partitionConsumer) parseMessages(msgSet *MessageSet) ([]*ConsumerMe
 			if offset < child.offset {
 				continue
 			}
-			messages = append(messages, &ConsumerMessage{
-				Topic:          child.topic,
-				Partition:      child.partition,
-				Key:            msg.Msg.Key,
-				Value:          msg.Msg.Value,
-				Offset:         offset,
-				Timestamp:      timestamp,
-				BlockTimestamp: msgBlock.Msg.Timestamp,
-			})
+			message := child.newMessage(msg.Msg.Key, msg.Msg.Value, nil, nil)
+			message.Offset = offset
+			message.Timestamp = timestamp
+			message.BlockTimestamp = msgBlock.Msg.Timestamp
+			messages = append(messages, message)
 			child.offset = offset + 1
 		}
 	}
@@ -657,15 +933,10 @@ func (child *partitionConsumer) parseRecords(batch *RecordBatch) ([]*ConsumerMes
 		if batch.LogAppendTime {
 			timestamp = batch.MaxTimestamp
 		}
-		messages = append(messages, &ConsumerMessage{
-			Topic:     child.topic,
-			Partition: child.partition,
-			Key:       rec.Key,
-			Value:     rec.Value,
-			Offset:    offset,
-			Timestamp: timestamp,
-			Headers:   rec.Headers,
-		})
+		message := child.newMessage(rec.Key, rec.Value, rec.Headers, rec.rawHeaders)
+		message.Offset = offset
+		message.Timestamp = timestamp
+		messages = append(messages, message)
 		child.offset = offset + 1
 	}
 	if len(messages) == 0 {
@@ -674,25 +945,25 @@ func (child *partitionConsumer) parseRecords(batch *RecordBatch) ([]*ConsumerMes
 	return messages, nil
 }
 
-func (child *partitionConsumer) parseResponse(response *FetchResponse) ([]*ConsumerMessage, error) {
+func (child *partitionConsumer) parseResponse(fetched fetchedBlock) ([]*ConsumerMessage, error) {
 	var (
-		metricRegistry          = child.conf.MetricRegistry
-		consumerBatchSizeMetric metrics.Histogram
+		recorder                = child.conf.metricsRecorder()
+		consumerBatchSizeMetric MetricsHistogram
 	)
 
-	if metricRegistry != nil {
-		consumerBatchSizeMetric = getOrRegisterHistogram("consumer-batch-size", metricRegistry)
+	if recorder != nil {
+		consumerBatchSizeMetric = recorder.Histogram("consumer-batch-size", nil)
 	}
 
 	// If request was throttled and empty we log and return without error
-	if response.ThrottleTime != time.Duration(0) && len(response.Blocks) == 0 {
-		Logger.Printf(
+	if fetched.throttleTime != time.Duration(0) && fetched.empty {
+		child.log().with(brokerField(child.broker.broker.ID())).infof(
 			"consumer/broker/%d FetchResponse throttled %v\n",
-			child.broker.broker.ID(), response.ThrottleTime)
+			child.broker.broker.ID(), fetched.throttleTime)
 		return nil, nil
 	}
 
-	block := response.GetBlock(child.topic, child.partition)
+	block := fetched.block
 	if block == nil {
 		return nil, ErrIncompleteResponse
 	}
@@ -701,12 +972,26 @@ func (child *partitionConsumer) parseResponse(response *FetchResponse) ([]*Consu
 		return nil, block.Err
 	}
 
+	if child.conf.Consumer.Fetch.Lazy {
+		if block.PreferredReadReplica != invalidPreferredReplicaID {
+			child.preferredReadReplica = block.PreferredReadReplica
+		}
+		child.fetch = &lazyFetch{
+			block:               block,
+			batches:             block.RecordsIterator(),
+			abortedProducerIDs:  make(map[int64]struct{}, len(block.AbortedTransactions)),
+			abortedTransactions: block.getAbortedTransactions(),
+			batchSizeMetric:     consumerBatchSizeMetric,
+		}
+		return child.nextMessages()
+	}
+
 	nRecs, err := block.numRecords()
 	if err != nil {
 		return nil, err
 	}
 
-	consumerBatchSizeMetric.Update(int64(nRecs))
+	consumerBatchSizeMetric.Observe(int64(nRecs))
 
 	if block.PreferredReadReplica != invalidPreferredReplicaID {
 		child.preferredReadReplica = block.PreferredReadReplica
@@ -717,29 +1002,7 @@ func (child *partitionConsumer) parseResponse(response *FetchResponse) ([]*Consu
 		if err != nil {
 			return nil, err
 		}
-		// We got no messages. If we got a trailing one then we need to ask for more data.
-		// Otherwise we just poll again and wait for one to be produced...
-		if partialTrailingMessage {
-			if child.conf.Consumer.Fetch.Max > 0 && child.fetchSize == child.conf.Consumer.Fetch.Max {
-				// we can't ask for more data, we've hit the configured limit
-				child.sendError(ErrMessageTooLarge)
-				child.offset++ // skip this one so we can keep processing future messages
-			} else {
-				child.fetchSize *= 2
-				// check int32 overflow
-				if child.fetchSize < 0 {
-					child.fetchSize = math.MaxInt32
-				}
-				if child.conf.Consumer.Fetch.Max > 0 && child.fetchSize > child.conf.Consumer.Fetch.Max {
-					child.fetchSize = child.conf.Consumer.Fetch.Max
-				}
-			}
-		} else if block.LastRecordsBatchOffset != nil && *block.LastRecordsBatchOffset < block.HighWaterMarkOffset {
-			// check last record offset to avoid stuck if high watermark was not reached
-			Logger.Printf("consumer/broker/%d received batch with zero records but high watermark was not reached, topic %s, partition %d, offset %d\n", child.broker.broker.ID(), child.topic, child.partition, *block.LastRecordsBatchOffset)
-			child.offset = *block.LastRecordsBatchOffset + 1
-		}
-
+		child.handleEmptyFetch(block, partialTrailingMessage)
 		return nil, nil
 	}
 
@@ -755,72 +1018,172 @@ func (child *partitionConsumer) parseResponse(response *FetchResponse) ([]*Consu
 
 	var messages []*ConsumerMessage
 	for _, records := range block.RecordsSet {
-		switch records.recordsType {
-		case legacyRecords:
-			messageSetMessages, err := child.parseMessages(records.MsgSet)
-			if err != nil {
+		var batchMessages []*ConsumerMessage
+		batchMessages, abortedTransactions, err = child.parseBatch(records, abortedProducerIDs, abortedTransactions)
+		if err != nil {
+			return nil, err
+		}
+		messages = append(messages, batchMessages...)
+	}
+
+	return messages, nil
+}
+
+// nextMessages decodes the batches of the lazy fetch in progress up to the next
+// one holding messages to deliver, and returns them. Once the batches are
+// exhausted, it wraps the fetch up and returns no messages.
+func (child *partitionConsumer) nextMessages() ([]*ConsumerMessage, error) {
+	fetch := child.fetch
+	if fetch == nil {
+		return nil, nil
+	}
+
+	for fetch.batches.Next() {
+		records := fetch.batches.Records()
+		n, err := records.numRecords()
+		if err != nil {
+			child.fetch = nil
+			return nil, err
+		}
+		if n == 0 {
+			// only a truncated first batch is yielded without records
+			if fetch.partial, err = records.isPartial(); err != nil {
+				child.fetch = nil
 				return nil, err
 			}
+			continue
+		}
 
-			messages = append(messages, messageSetMessages...)
-		case defaultRecords:
-			// Consume remaining abortedTransaction up to last offset of current batch
-			for _, txn := range abortedTransactions {
-				if txn.FirstOffset > records.RecordBatch.LastOffset() {
-					break
-				}
-				abortedProducerIDs[txn.ProducerID] = struct{}{}
-				// Pop abortedTransactions so that we never add it again
-				abortedTransactions = abortedTransactions[1:]
+		if fetch.records == 0 {
+			// we got messages, reset our fetch size in case it was increased for a previous request
+			child.fetchSize = child.conf.Consumer.Fetch.Default
+			atomic.StoreInt64(&child.highWaterMarkOffset, fetch.block.HighWaterMarkOffset)
+		}
+		fetch.records += n
+
+		var messages []*ConsumerMessage
+		messages, fetch.abortedTransactions, err = child.parseBatch(records, fetch.abortedProducerIDs, fetch.abortedTransactions)
+		if err != nil {
+			child.fetch = nil
+			return nil, err
+		}
+		if len(messages) > 0 {
+			return messages, nil
+		}
+	}
+
+	child.fetch = nil
+	if err := fetch.batches.Err(); err != nil {
+		return nil, err
+	}
+
+	fetch.batchSizeMetric.Observe(int64(fetch.records))
+
+	if fetch.records == 0 {
+		child.handleEmptyFetch(fetch.block, fetch.partial || fetch.block.Partial)
+	}
+
+	return nil, nil
+}
+
+// handleEmptyFetch adjusts the next fetch after one returned no records: it
+// asks for more data when the block ended with a partial trailing message, and
+// moves past batches emptied by compaction otherwise.
+func (child *partitionConsumer) handleEmptyFetch(block *FetchResponseBlock, partialTrailingMessage bool) {
+	// We got no messages. If we got a trailing one then we need to ask for more data.
+	// Otherwise we just poll again and wait for one to be produced...
+	if partialTrailingMessage {
+		if child.conf.Consumer.Fetch.Max > 0 && child.fetchSize == child.conf.Consumer.Fetch.Max {
+			// we can't ask for more data, we've hit the configured limit
+			child.sendError(ErrMessageTooLarge)
+			child.offset++ // skip this one so we can keep processing future messages
+		} else {
+			child.fetchSize *= 2
+			// check int32 overflow
+			if child.fetchSize < 0 {
+				child.fetchSize = math.MaxInt32
 			}
+			if child.conf.Consumer.Fetch.Max > 0 && child.fetchSize > child.conf.Consumer.Fetch.Max {
+				child.fetchSize = child.conf.Consumer.Fetch.Max
+			}
+		}
+	} else if block.LastRecordsBatchOffset != nil && *block.LastRecordsBatchOffset < block.HighWaterMarkOffset {
+		// check last record offset to avoid stuck if high watermark was not reached
+		child.log().with(brokerField(child.broker.broker.ID())).infof("consumer/broker/%d received batch with zero records but high watermark was not reached, topic %s, partition %d, offset %d\n", child.broker.broker.ID(), child.topic, child.partition, *block.LastRecordsBatchOffset)
+		child.offset = *block.LastRecordsBatchOffset + 1
+	}
+}
 
-			recordBatchMessages, err := child.parseRecords(records.RecordBatch)
-			if err != nil {
-				return nil, err
+// parseBatch returns the messages of a record batch to deliver, along with the
+// aborted transactions left once those the batch reaches are consumed.
+func (child *partitionConsumer) parseBatch(records *Records, abortedProducerIDs map[int64]struct{}, abortedTransactions []*AbortedTransaction) ([]*ConsumerMessage, []*AbortedTransaction, error) {
+	switch records.recordsType {
+	case legacyRecords:
+		messageSetMessages, err := child.parseMessages(records.MsgSet)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		return messageSetMessages, abortedTransactions, nil
+	case defaultRecords:
+		if child.conf.Consumer.PoolMessages {
+			// the messages hold copies of the records once parsed
+			defer records.RecordBatch.release()
+		}
+		// Consume remaining abortedTransaction up to last offset of current batch
+		for _, txn := range abortedTransactions {
+			if txn.FirstOffset > records.RecordBatch.LastOffset() {
+				break
 			}
+			abortedProducerIDs[txn.ProducerID] = struct{}{}
+			// Pop abortedTransactions so that we never add it again
+			abortedTransactions = abortedTransactions[1:]
+		}
 
-			// Parse and commit offset but do not expose messages that are:
-			// - control records
-			// - part of an aborted transaction when set to `ReadCommitted`
+		recordBatchMessages, err := child.parseRecords(records.RecordBatch)
+		if err != nil {
+			return nil, nil, err
+		}
+
+		// Parse and commit offset but do not expose messages that are:
+		// - control records
+		// - part of an aborted transaction when set to `ReadCommitted`
 
-			// control record
-			isControl, err := records.isControl()
+		// control record
+		isControl, err := records.isControl()
+		if err != nil {
+			// I don't know why there is this continue in case of error to begin with
+			// Safe bet is to ignore control messages if ReadUncommitted
+			// and block on them in case of error and ReadCommitted
+			if child.conf.Consumer.IsolationLevel == ReadCommitted {
+				return nil, nil, err
+			}
+			return nil, abortedTransactions, nil
+		}
+		if isControl {
+			controlRecord, err := records.getControlRecord()
 			if err != nil {
-				// I don't know why there is this continue in case of error to begin with
-				// Safe bet is to ignore control messages if ReadUncommitted
-				// and block on them in case of error and ReadCommitted
-				if child.conf.Consumer.IsolationLevel == ReadCommitted {
-					return nil, err
-				}
-				continue
+				return nil, nil, err
 			}
-			if isControl {
-				controlRecord, err := records.getControlRecord()
-				if err != nil {
-					return nil, err
-				}
 
-				if controlRecord.Type == ControlRecordAbort {
-					delete(abortedProducerIDs, records.RecordBatch.ProducerID)
-				}
-				continue
+			if controlRecord.Type == ControlRecordAbort {
+				delete(abortedProducerIDs, records.RecordBatch.ProducerID)
 			}
+			return nil, abortedTransactions, nil
+		}
 
-			// filter aborted transactions
-			if child.conf.Consumer.IsolationLevel == ReadCommitted {
-				_, isAborted := abortedProducerIDs[records.RecordBatch.ProducerID]
-				if records.RecordBatch.IsTransactional && isAborted {
-					continue
-				}
+		// filter aborted transactions
+		if child.conf.Consumer.IsolationLevel == ReadCommitted {
+			_, isAborted := abortedProducerIDs[records.RecordBatch.ProducerID]
+			if records.RecordBatch.IsTransactional && isAborted {
+				return nil, abortedTransactions, nil
 			}
-
-			messages = append(messages, recordBatchMessages...)
-		default:
-			return nil, fmt.Errorf("unknown records type: %v", records.recordsType)
 		}
-	}
 
-	return messages, nil
+		return recordBatchMessages, abortedTransactions, nil
+	default:
+		return nil, nil, fmt.Errorf("unknown records type: %v", records.recordsType)
+	}
 }
 
 func (child *partitionConsumer) interceptors(msg *ConsumerMessage) {
@@ -908,7 +1271,7 @@ func (bc *brokerConsumer) subscriptionManager() {
 				}
 			}
 
-			Logger.Printf(
+			bc.broker.log().infof(
 				"consumer/broker/%d accumulated %d new subscriptions\n",
 				bc.broker.ID(), len(partitionConsumers))
 
@@ -946,14 +1309,16 @@ func (bc *brokerConsumer) subscriptionConsumer() {
 
 		response, err := bc.fetchNewMessages()
 		if err != nil {
-			Logger.Printf("consumer/broker/%d disconnecting due to error processing FetchRequest: %s\n", bc.broker.ID(), err)
+			bc.broker.log().warnf("consumer/broker/%d disconnecting due to error processing FetchRequest: %s\n", bc.broker.ID(), err)
 			bc.abort(err)
 			return
 		}
 
+		// hand each subscription its block off, the brokerConsumer keeping no
+		// reference to the response past this point
 		bc.acks.Add(len(bc.subscriptions))
 		for child := range bc.subscriptions {
-			child.feeder <- response
+			child.feeder <- takeFetchedBlock(response, child.topic, child.partition)
 		}
 		bc.acks.Wait()
 		bc.handleResponses()
@@ -963,13 +1328,13 @@ func (bc *brokerConsumer) subscriptionConsumer() {
 func (bc *brokerConsumer) updateSubscriptions(newSubscriptions []*partitionConsumer) {
 	for _, child := range newSubscriptions {
 		bc.subscriptions[child] = none{}
-		Logger.Printf("consumer/broker/%d added subscription to %s/%d\n", bc.broker.ID(), child.topic, child.partition)
+		child.log().with(brokerField(bc.broker.ID())).infof("consumer/broker/%d added subscription to %s/%d\n", bc.broker.ID(), child.topic, child.partition)
 	}
 
 	for child := range bc.subscriptions {
 		select {
 		case <-child.dying:
-			Logger.Printf("consumer/broker/%d closed dead subscription to %s/%d\n", bc.broker.ID(), child.topic, child.partition)
+			child.log().with(brokerField(bc.broker.ID())).infof("consumer/broker/%d closed dead subscription to %s/%d\n", bc.broker.ID(), child.topic, child.partition)
 			close(child.trigger)
 			delete(bc.subscriptions, child)
 		default:
@@ -988,7 +1353,7 @@ func (bc *brokerConsumer) handleResponses() {
 			if preferredBroker, err := child.preferredBroker(); err == nil {
 				if bc.broker.ID() != preferredBroker.ID() {
 					// not an error but needs redispatching to consume from preferred replica
-					Logger.Printf(
+					bc.broker.log().infof(
 						"consumer/broker/%d abandoned in favor of preferred replica broker/%d\n",
 						bc.broker.ID(), preferredBroker.ID())
 					child.trigger <- none{}
@@ -1002,26 +1367,26 @@ func (bc *brokerConsumer) handleResponses() {
 		child.preferredReadReplica = invalidPreferredReplicaID
 
 		if errors.Is(result, errTimedOut) {
-			Logger.Printf("consumer/broker/%d abandoned subscription to %s/%d because consuming was taking too long\n",
+			child.log().with(brokerField(bc.broker.ID())).infof("consumer/broker/%d abandoned subscription to %s/%d because consuming was taking too long\n",
 				bc.broker.ID(), child.topic, child.partition)
 			delete(bc.subscriptions, child)
 		} else if errors.Is(result, ErrOffsetOutOfRange) {
 			// there's no point in retrying this it will just fail the same way again
 			// shut it down and force the user to choose what to do
 			child.sendError(result)
-			Logger.Printf("consumer/%s/%d shutting down because %s\n", child.topic, child.partition, result)
+			child.log().infof("consumer/%s/%d shutting down because %s\n", child.topic, child.partition, result)
 			close(child.trigger)
 			delete(bc.subscriptions, child)
 		} else if errors.Is(result, ErrUnknownTopicOrPartition) || errors.Is(result, ErrNotLeaderForPartition) || errors.Is(result, ErrLeaderNotAvailable) || errors.Is(result, ErrReplicaNotAvailable) {
 			// not an error, but does need redispatching
-			Logger.Printf("consumer/broker/%d abandoned subscription to %s/%d because %s\n",
+			child.log().with(brokerField(bc.broker.ID())).infof("consumer/broker/%d abandoned subscription to %s/%d because %s\n",
 				bc.broker.ID(), child.topic, child.partition, result)
 			child.trigger <- none{}
 			delete(bc.subscriptions, child)
 		} else {
 			// dunno, tell the user and try redispatching
 			child.sendError(result)
-			Logger.Printf("consumer/broker/%d abandoned subscription to %s/%d because %s\n",
+			child.log().with(brokerField(bc.broker.ID())).infof("consumer/broker/%d abandoned subscription to %s/%d because %s\n",
 				bc.broker.ID(), child.topic, child.partition, result)
 			child.trigger <- none{}
 			delete(bc.subscriptions, child)
@@ -1054,6 +1419,8 @@ func (bc *brokerConsumer) fetchNewMessages() (*FetchResponse, error) {
 	request := &FetchRequest{
 		MinBytes:    bc.consumer.conf.Consumer.Fetch.Min,
 		MaxWaitTime: int32(bc.consumer.conf.Consumer.MaxWaitTime / time.Millisecond),
+		// each subscription is handed its block off, released on its own
+		ownBlocks: true,
 	}
 	if bc.consumer.conf.Version.IsAtLeast(V0_9_0_0) {
 		request.Version = 1
@@ -1088,8 +1455,52 @@ func (bc *brokerConsumer) fetchNewMessages() (*FetchResponse, error) {
 	for child := range bc.subscriptions {
 		if !child.IsPaused() {
 			request.AddBlock(child.topic, child.partition, child.offset, child.fetchSize)
+			request.setBuffers(child.topic, child.partition, child.buffers)
+			atomic.StoreInt64(&child.fetchOffset, child.offset)
+		}
+	}
+
+	start := time.Now()
+	response, err := bc.broker.Fetch(request)
+	if err == nil {
+		bc.recordFetchMetrics(response, time.Since(start))
+	}
+	return response, err
+}
+
+// recordFetchMetrics records the latency of a fetch and the records and bytes
+// it returned, for all brokers, for the broker and for each topic fetched.
+func (bc *brokerConsumer) recordFetchMetrics(response *FetchResponse, latency time.Duration) {
+	recorder := bc.consumer.conf.metricsRecorder()
+	if recorder == nil {
+		return
+	}
+
+	var records, bytes int64
+	for topic, blocks := range response.Blocks {
+		var topicRecords, topicBytes int64
+		for _, block := range blocks {
+			if n, err := block.numRecords(); err == nil {
+				topicRecords += int64(n)
+			}
+			topicBytes += int64(block.recordsSize)
 		}
+		observeFetch(recorder, MetricLabels{MetricLabelTopic: topic}, latency, topicRecords, topicBytes)
+		records += topicRecords
+		bytes += topicBytes
+	}
+	observeFetch(recorder, nil, latency, records, bytes)
+	if bc.broker.ID() >= 0 {
+		observeFetch(recorder, bc.broker.metricLabels(), latency, records, bytes)
 	}
+}
 
-	return bc.broker.Fetch(request)
+func observeFetch(recorder MetricsRecorder, labels MetricLabels, latency time.Duration, records, bytes int64) {
+	recorder.Counter("fetch-rate", labels).Add(1)
+	if records == 0 {
+		recorder.Counter("empty-fetch-rate", labels).Add(1)
+	}
+	recorder.Histogram("fetch-latency-in-ms", labels).Observe(int64(latency / time.Millisecond))
+	recorder.Histogram("records-per-fetch", labels).Observe(records)
+	recorder.Histogram("bytes-per-fetch", labels).Observe(bytes)
 }
@@ -7,8 +7,6 @@ import (
 	"sort"
 	"sync"
 	"time"
-
-	"github.com/rcrowley/go-metrics"
 )
 
 // ErrClosedConsumerGroup is the error returned when a method is called on a consumer group that has been closed.
@@ -55,6 +53,12 @@ type ConsumerGroup interface {
 	// this function before the object passes out of scope, as it will otherwise leak memory.
 	Close() error
 
+	// CloseContext stops the ConsumerGroup like Close, leaving the group, until
+	// ctx is done. The connections of the ConsumerGroup are then torn down
+	// regardless, unless it was created from a client, and a
+	// *CloseAbandonedError is returned.
+	CloseContext(ctx context.Context) error
+
 	// Pause suspends fetching from the requested partitions. Future calls to the broker will not return any
 	// records from these partitions until they have been resumed using Resume()/ResumeAll().
 	// Note that this method does not affect partition subscription.
@@ -74,6 +78,12 @@ type ConsumerGroup interface {
 	// Resume resumes all partitions which have been paused with Pause()/PauseAll().
 	// New calls to the broker will return records from these partitions if there are any to be fetched.
 	ResumeAll()
+
+	// DebugState returns a snapshot of the state of the group: the member and
+	// generation of the current session, its claims, the fetch positions and
+	// offsets marked of the partitions consumed, and the state of its client.
+	// It is meant to diagnose a stuck consumer in production.
+	DebugState() ConsumerGroupDebugState
 }
 
 type consumerGroup struct {
@@ -90,6 +100,10 @@ type consumerGroup struct {
 	closeOnce sync.Once
 
 	userData []byte
+
+	// session is the current session, for DebugState
+	session     *consumerGroupSession
+	sessionLock sync.Mutex
 }
 
 // NewConsumerGroup creates a new consumer group the given broker addresses and configuration.
@@ -107,13 +121,19 @@ func NewConsumerGroup(addrs []string, groupID string, config *Config) (ConsumerG
 }
 
 // NewConsumerGroupFromClient creates a new consumer group using the given client. It is still
-// necessary to call Close() on the underlying client when shutting down this consumer.
+// necessary to call Close() on the underlying client when shutting down this consumer,
+// unless it was shared with ShareClient.
 // PLEASE NOTE: consumer groups can only re-use but not share clients.
 func NewConsumerGroupFromClient(groupID string, client Client) (ConsumerGroup, error) {
-	// For clients passed in by the client, ensure we don't
-	// call Close() on it.
-	cli := &nopCloserClient{client}
-	return newConsumerGroup(groupID, cli)
+	cli, err := borrowClient(client)
+	if err != nil {
+		return nil, err
+	}
+	c, err := newConsumerGroup(groupID, cli)
+	if err != nil {
+		_ = cli.Close()
+	}
+	return c, err
 }
 
 func newConsumerGroup(groupID string, client Client) (ConsumerGroup, error) {
@@ -141,6 +161,11 @@ func newConsumerGroup(groupID string, client Client) (ConsumerGroup, error) {
 // Errors implements ConsumerGroup.
 func (c *consumerGroup) Errors() <-chan error { return c.errors }
 
+// log returns the logContext of the records about the group.
+func (c *consumerGroup) log() logContext {
+	return logWith(groupField(c.groupID))
+}
+
 // Close implements ConsumerGroup.
 func (c *consumerGroup) Close() (err error) {
 	c.closeOnce.Do(func() {
@@ -166,6 +191,19 @@ func (c *consumerGroup) Close() (err error) {
 	return
 }
 
+// CloseContext implements ConsumerGroup.
+func (c *consumerGroup) CloseContext(ctx context.Context) error {
+	var err error
+	if waitErr := waitContext(ctx, func() { err = c.Close() }); waitErr != nil {
+		abandoned := &CloseAbandonedError{Err: waitErr}
+		done, cancel := context.WithCancel(context.Background())
+		cancel()
+		abandoned.merge(c.client.CloseContext(done))
+		return abandoned
+	}
+	return err
+}
+
 // Consume implements ConsumerGroup.
 func (c *consumerGroup) Consume(ctx context.Context, topics []string, handler ConsumerGroupHandler) error {
 	// Ensure group is not closed
@@ -196,6 +234,9 @@ func (c *consumerGroup) Consume(ctx context.Context, topics []string, handler Co
 		return err
 	}
 
+	c.setSession(sess)
+	defer c.setSession(nil)
+
 	// loop check topic partition numbers changed
 	// will trigger rebalance when any topic partitions number had changed
 	// avoid Consume function called again that will generate more than loopCheckPartitionNumbers coroutine
@@ -208,6 +249,46 @@ func (c *consumerGroup) Consume(ctx context.Context, topics []string, handler Co
 	return sess.release(true)
 }
 
+func (c *consumerGroup) setSession(sess *consumerGroupSession) {
+	c.sessionLock.Lock()
+	c.session = sess
+	c.sessionLock.Unlock()
+}
+
+// DebugState implements ConsumerGroup.
+func (c *consumerGroup) DebugState() ConsumerGroupDebugState {
+	state := ConsumerGroupDebugState{
+		GroupID: c.groupID,
+		Client:  c.client.DebugState(),
+	}
+	if consumer, ok := c.consumer.(*consumer); ok {
+		state.Partitions = consumer.debugState()
+	}
+
+	c.sessionLock.Lock()
+	sess := c.session
+	c.sessionLock.Unlock()
+	if sess == nil {
+		return state
+	}
+
+	state.MemberID = sess.memberID
+	state.GenerationID = sess.generationID
+	state.Claims = make(map[string][]int32, len(sess.claims))
+	for topic, partitions := range sess.claims {
+		state.Claims[topic] = append([]int32(nil), partitions...)
+	}
+	for i := range state.Partitions {
+		partition := &state.Partitions[i]
+		if pom := sess.offsets.findPOM(partition.Topic, partition.Partition); pom != nil {
+			if offset, _ := pom.NextOffset(); offset >= 0 {
+				partition.MarkedOffset = offset
+			}
+		}
+	}
+	return state
+}
+
 // Pause implements ConsumerGroup.
 func (c *consumerGroup) Pause(partitions map[string][]int32) {
 	c.consumer.Pause(partitions)
@@ -256,35 +337,36 @@ func (c *consumerGroup) newSession(ctx context.Context, topics []string, handler
 	}
 
 	var (
-		metricRegistry          = c.config.MetricRegistry
-		consumerGroupJoinTotal  metrics.Counter
-		consumerGroupJoinFailed metrics.Counter
-		consumerGroupSyncTotal  metrics.Counter
-		consumerGroupSyncFailed metrics.Counter
+		recorder                = c.config.metricsRecorder()
+		consumerGroupJoinTotal  MetricsCounter
+		consumerGroupJoinFailed MetricsCounter
+		consumerGroupSyncTotal  MetricsCounter
+		consumerGroupSyncFailed MetricsCounter
 	)
 
-	if metricRegistry != nil {
-		consumerGroupJoinTotal = metrics.GetOrRegisterCounter(fmt.Sprintf("consumer-group-join-total-%s", c.groupID), metricRegistry)
-		consumerGroupJoinFailed = metrics.GetOrRegisterCounter(fmt.Sprintf("consumer-group-join-failed-%s", c.groupID), metricRegistry)
-		consumerGroupSyncTotal = metrics.GetOrRegisterCounter(fmt.Sprintf("consumer-group-sync-total-%s", c.groupID), metricRegistry)
-		consumerGroupSyncFailed = metrics.GetOrRegisterCounter(fmt.Sprintf("consumer-group-sync-failed-%s", c.groupID), metricRegistry)
+	if recorder != nil {
+		labels := MetricLabels{MetricLabelGroup: c.groupID}
+		consumerGroupJoinTotal = recorder.Counter("consumer-group-join-total", labels)
+		consumerGroupJoinFailed = recorder.Counter("consumer-group-join-failed", labels)
+		consumerGroupSyncTotal = recorder.Counter("consumer-group-sync-total", labels)
+		consumerGroupSyncFailed = recorder.Counter("consumer-group-sync-failed", labels)
 	}
 
 	// Join consumer group
 	join, err := c.joinGroupRequest(coordinator, topics)
 	if consumerGroupJoinTotal != nil {
-		consumerGroupJoinTotal.Inc(1)
+		consumerGroupJoinTotal.Add(1)
 	}
 	if err != nil {
 		_ = coordinator.Close()
 		if consumerGroupJoinFailed != nil {
-			consumerGroupJoinFailed.Inc(1)
+			consumerGroupJoinFailed.Add(1)
 		}
 		return nil, err
 	}
 	if !errors.Is(join.Err, ErrNoError) {
 		if consumerGroupJoinFailed != nil {
-			consumerGroupJoinFailed.Inc(1)
+			consumerGroupJoinFailed.Add(1)
 		}
 	}
 	switch join.Err {
@@ -293,6 +375,9 @@ func (c *consumerGroup) newSession(ctx context.Context, topics []string, handler
 	case ErrUnknownMemberId, ErrIllegalGeneration: // reset member ID and retry immediately
 		c.memberID = ""
 		return c.newSession(ctx, topics, handler, retries)
+	case ErrMemberIdRequired: // retry immediately with the member ID assigned, since JoinGroup v4
+		c.memberID = join.MemberId
+		return c.newSession(ctx, topics, handler, retries)
 	case ErrNotCoordinatorForConsumer: // retry after backoff with coordinator refresh
 		if retries <= 0 {
 			return nil, join.Err
@@ -326,18 +411,18 @@ func (c *consumerGroup) newSession(ctx context.Context, topics []string, handler
 	// Sync consumer group
 	groupRequest, err := c.syncGroupRequest(coordinator, plan, join.GenerationId)
 	if consumerGroupSyncTotal != nil {
-		consumerGroupSyncTotal.Inc(1)
+		consumerGroupSyncTotal.Add(1)
 	}
 	if err != nil {
 		_ = coordinator.Close()
 		if consumerGroupSyncFailed != nil {
-			consumerGroupSyncFailed.Inc(1)
+			consumerGroupSyncFailed.Add(1)
 		}
 		return nil, err
 	}
 	if !errors.Is(groupRequest.Err, ErrNoError) {
 		if consumerGroupSyncFailed != nil {
-			consumerGroupSyncFailed.Inc(1)
+			consumerGroupSyncFailed.Add(1)
 		}
 	}
 
@@ -395,8 +480,21 @@ func (c *consumerGroup) joinGroupRequest(coordinator *Broker, topics []string) (
 		SessionTimeout: int32(c.config.Consumer.Group.Session.Timeout / time.Millisecond),
 		ProtocolType:   "consumer",
 	}
-	if c.config.Version.IsAtLeast(V0_10_1_0) {
+	switch {
+	case c.config.Version.IsAtLeast(V2_4_0_0):
+		req.Version = 6
+	case c.config.Version.IsAtLeast(V2_3_0_0):
+		req.Version = 5
+	case c.config.Version.IsAtLeast(V2_2_0_0):
+		req.Version = 4
+	case c.config.Version.IsAtLeast(V2_0_0_0):
+		req.Version = 3
+	case c.config.Version.IsAtLeast(V0_11_0_0):
+		req.Version = 2
+	case c.config.Version.IsAtLeast(V0_10_1_0):
 		req.Version = 1
+	}
+	if req.Version >= 1 {
 		req.RebalanceTimeout = int32(c.config.Consumer.Group.Rebalance.Timeout / time.Millisecond)
 	}
 
@@ -414,6 +512,7 @@ func (c *consumerGroup) joinGroupRequest(coordinator *Broker, topics []string) (
 
 func (c *consumerGroup) syncGroupRequest(coordinator *Broker, plan BalanceStrategyPlan, generationID int32) (*SyncGroupResponse, error) {
 	req := &SyncGroupRequest{
+		Version:      groupRequestVersion(c.config.Version),
 		GroupId:      c.groupID,
 		MemberId:     c.memberID,
 		GenerationId: generationID,
@@ -435,6 +534,7 @@ func (c *consumerGroup) syncGroupRequest(coordinator *Broker, plan BalanceStrate
 
 func (c *consumerGroup) heartbeatRequest(coordinator *Broker, memberID string, generationID int32) (*HeartbeatResponse, error) {
 	req := &HeartbeatRequest{
+		Version:      groupRequestVersion(c.config.Version),
 		GroupId:      c.groupID,
 		MemberId:     memberID,
 		GenerationId: generationID,
@@ -443,6 +543,24 @@ func (c *consumerGroup) heartbeatRequest(coordinator *Broker, memberID string, g
 	return coordinator.Heartbeat(req)
 }
 
+// groupRequestVersion returns the version of the SyncGroup, Heartbeat and
+// LeaveGroup requests, which share their version history, to send to brokers
+// of the given version.
+func groupRequestVersion(version KafkaVersion) int16 {
+	switch {
+	case version.IsAtLeast(V2_4_0_0):
+		return 4
+	case version.IsAtLeast(V2_3_0_0):
+		return 3
+	case version.IsAtLeast(V2_0_0_0):
+		return 2
+	case version.IsAtLeast(V0_11_0_0):
+		return 1
+	default:
+		return 0
+	}
+}
+
 func (c *consumerGroup) balance(members map[string]ConsumerGroupMemberMetadata) (BalanceStrategyPlan, error) {
 	topics := make(map[string][]int32)
 	for _, meta := range members {
@@ -476,10 +594,16 @@ func (c *consumerGroup) leave() error {
 		return err
 	}
 
-	resp, err := coordinator.LeaveGroup(&LeaveGroupRequest{
-		GroupId:  c.groupID,
-		MemberId: c.memberID,
-	})
+	req := &LeaveGroupRequest{
+		Version: groupRequestVersion(c.config.Version),
+		GroupId: c.groupID,
+	}
+	if req.Version >= 3 {
+		req.Members = []MemberIdentity{{MemberId: c.memberID}}
+	} else {
+		req.MemberId = c.memberID
+	}
+	resp, err := coordinator.LeaveGroup(req)
 	if err != nil {
 		_ = coordinator.Close()
 		return err
@@ -488,12 +612,16 @@ func (c *consumerGroup) leave() error {
 	// Unset memberID
 	c.memberID = ""
 
-	// Check response
-	switch resp.Err {
+	// Check response, whose members have errors of their own since version 3
+	kerr := resp.Err
+	if kerr == ErrNoError && len(resp.Members) > 0 {
+		kerr = resp.Members[0].Err
+	}
+	switch kerr {
 	case ErrRebalanceInProgress, ErrUnknownMemberId, ErrNoError:
 		return nil
 	default:
-		return resp.Err
+		return kerr
 	}
 }
 
@@ -508,7 +636,7 @@ func (c *consumerGroup) handleError(err error, topic string, partition int32) {
 	}
 
 	if !c.config.Consumer.Return.Errors {
-		Logger.Println(err)
+		c.log().warnf("%v", err)
 		return
 	}
 
@@ -548,7 +676,7 @@ func (c *consumerGroup) loopCheckPartitionNumbers(topics []string, session *cons
 		select {
 		case <-pause.C:
 		case <-session.ctx.Done():
-			Logger.Printf(
+			c.log().infof(
 				"consumergroup/%s loop check partition number coroutine will exit, topics %s\n",
 				c.groupID, topics)
 			// if session closed by other, should be exited
@@ -563,9 +691,9 @@ func (c *consumerGroup) topicToPartitionNumbers(topics []string) (map[string]int
 	topicToPartitionNum := make(map[string]int, len(topics))
 	for _, topic := range topics {
 		if partitionNum, err := c.client.Partitions(topic); err != nil {
-			Logger.Printf(
+			c.log().with(topicField(topic)).warnf(
 				"consumergroup/%s topic %s get partition number failed %v\n",
-				c.groupID, err)
+				c.groupID, topic, err)
 			return nil, err
 		} else {
 			topicToPartitionNum[topic] = len(partitionNum)
@@ -811,7 +939,7 @@ func (s *consumerGroupSession) release(withCleanup bool) (err error) {
 		<-s.hbDead
 	})
 
-	Logger.Printf(
+	s.parent.log().infof(
 		"consumergroup/session/%s/%d released\n",
 		s.MemberID(), s.GenerationID())
 
@@ -822,7 +950,7 @@ func (s *consumerGroupSession) heartbeatLoop() {
 	defer close(s.hbDead)
 	defer s.cancel() // trigger the end of the session on exit
 	defer func() {
-		Logger.Printf(
+		s.parent.log().infof(
 			"consumergroup/session/%s/%d heartbeat loop stopped\n",
 			s.MemberID(), s.GenerationID())
 	}()
@@ -78,6 +78,55 @@ func TestConsumerOffsetManual(t *testing.T) {
 	broker0.Close()
 }
 
+func TestConsumerFetchMetrics(t *testing.T) {
+	broker0 := NewMockBroker(t, 0)
+	defer broker0.Close()
+	broker0.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(broker0.Addr(), broker0.BrokerID()).
+			SetLeader("my_topic", 0, broker0.BrokerID()),
+		"OffsetRequest": NewMockOffsetResponse(t).
+			SetOffset("my_topic", 0, OffsetOldest, 0).
+			SetOffset("my_topic", 0, OffsetNewest, 2),
+		"FetchRequest": NewMockFetchResponse(t, 1).
+			SetMessage("my_topic", 0, 0, testMsg).
+			SetMessage("my_topic", 0, 1, testMsg),
+	})
+
+	recorder := newTestMetricsRecorder()
+	config := NewTestConfig()
+	config.MetricsRecorder = recorder
+	master, err := NewConsumer([]string{broker0.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, master)
+	consumer, err := master.ConsumePartition("my_topic", 0, OffsetOldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, consumer)
+
+	for i := int64(0); i < 2; i++ {
+		assertMessageOffset(t, <-consumer.Messages(), i)
+	}
+	topic := MetricLabels{MetricLabelTopic: "my_topic"}
+	for deadline := time.Now().Add(5 * time.Second); recorder.value("empty-fetch-rate", topic) == 0; time.Sleep(10 * time.Millisecond) {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for an empty fetch")
+		}
+	}
+
+	if records := recorder.value("records-per-fetch", topic); records != 2 {
+		t.Errorf("expected the 2 records fetched to be recorded, got %d", records)
+	}
+	if bytes := recorder.value("bytes-per-fetch", MetricLabels{MetricLabelBroker: "0"}); bytes == 0 {
+		t.Error("expected the bytes fetched from the broker to be recorded")
+	}
+	if fetches := recorder.value("fetch-rate", nil); fetches < 3 {
+		t.Errorf("expected at least 3 fetches, got %d", fetches)
+	}
+}
 func TestPauseResumeConsumption(t *testing.T) {
 	// Given
 	broker0 := NewMockBroker(t, 0)
@@ -1819,6 +1868,145 @@ func TestExcludeUncommitted(t *testing.T) {
 	broker0.Close()
 }
 
+func TestConsumerLazyFetch(t *testing.T) {
+	// Given
+	broker0 := NewMockBroker(t, 0)
+
+	fetchResponse := &FetchResponse{
+		Version: 4,
+		Blocks: map[string]map[int32]*FetchResponseBlock{"my_topic": {0: {
+			AbortedTransactions: []*AbortedTransaction{{ProducerID: 7, FirstOffset: 1236}},
+		}}},
+	}
+	fetchResponse.AddRecordBatch("my_topic", 0, nil, testMsg, 1234, 7, false)  // committed msg
+	fetchResponse.AddRecordBatch("my_topic", 0, nil, testMsg, 1235, 7, false)  // committed msg
+	fetchResponse.AddRecordBatch("my_topic", 0, nil, testMsg, 1236, 7, true)   // uncommitted msg
+	fetchResponse.AddControlRecord("my_topic", 0, 1237, 7, ControlRecordAbort) // abort control record
+	fetchResponse.AddRecordBatch("my_topic", 0, nil, testMsg, 1238, 7, true)   // committed msg
+
+	broker0.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(broker0.Addr(), broker0.BrokerID()).
+			SetLeader("my_topic", 0, broker0.BrokerID()),
+		"OffsetRequest": NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset("my_topic", 0, OffsetOldest, 0).
+			SetOffset("my_topic", 0, OffsetNewest, 1239),
+		"FetchRequest": NewMockSequence(fetchResponse, NewMockFetchResponse(t, 1)),
+	})
+
+	cfg := NewTestConfig()
+	cfg.Consumer.Return.Errors = true
+	cfg.Version = V0_11_0_0
+	cfg.Consumer.IsolationLevel = ReadCommitted
+	cfg.Consumer.Fetch.Lazy = true
+
+	// When
+	master, err := NewConsumer([]string{broker0.Addr()}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consumer, err := master.ConsumePartition("my_topic", 0, 1234)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Then: the committed messages are returned batch by batch
+	for _, offset := range []int64{1234, 1235, 1238} {
+		select {
+		case message := <-consumer.Messages():
+			assertMessageOffset(t, message, offset)
+		case err := <-consumer.Errors():
+			t.Error(err)
+		}
+	}
+
+	safeClose(t, consumer)
+	safeClose(t, master)
+	broker0.Close()
+}
+
+func TestConsumerPoolMessages(t *testing.T) {
+	// Given
+	broker0 := NewMockBroker(t, 0)
+
+	mockFetchResponse := NewMockFetchResponse(t, 1)
+	for i := int64(0); i < 10; i++ {
+		mockFetchResponse.SetMessage("my_topic", 0, i, testMsg)
+	}
+
+	broker0.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(broker0.Addr(), broker0.BrokerID()).
+			SetLeader("my_topic", 0, broker0.BrokerID()),
+		"OffsetRequest": NewMockOffsetResponse(t).
+			SetOffset("my_topic", 0, OffsetOldest, 0).
+			SetOffset("my_topic", 0, OffsetNewest, 10),
+		"FetchRequest": mockFetchResponse,
+	})
+
+	cfg := NewTestConfig()
+	cfg.Consumer.PoolMessages = true
+
+	// When
+	master, err := NewConsumer([]string{broker0.Addr()}, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	consumer, err := master.ConsumePartition("my_topic", 0, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Then: the messages hold their own copy of the value until released
+	for i := int64(0); i < 10; i++ {
+		select {
+		case message := <-consumer.Messages():
+			assertMessageOffset(t, message, i)
+			if message.Topic != "my_topic" || message.Key != nil || string(message.Value) != string(testMsg) {
+				t.Errorf("Unexpected pooled message %+v", message)
+			}
+			message.Release()
+		case err := <-consumer.Errors():
+			t.Error(err)
+		}
+	}
+
+	safeClose(t, consumer)
+	safeClose(t, master)
+	broker0.Close()
+}
+
+func TestConsumerPoolMessagesCopyRecords(t *testing.T) {
+	cfg := NewTestConfig()
+	cfg.Consumer.PoolMessages = true
+	child := &partitionConsumer{conf: cfg, topic: "my_topic"}
+
+	// as decompressed into a buffer reused once the batch is parsed
+	buf := []byte("keyvaluehkeyhvalueraw")
+	headers := []*RecordHeader{{Key: buf[8:12], Value: buf[12:18]}}
+	msg := child.newMessage(buf[:3], buf[3:8], headers, buf[18:])
+	for i := range buf {
+		buf[i] = 0
+	}
+
+	if string(msg.Key) != "key" || string(msg.Value) != "value" || string(msg.rawHeaders) != "raw" ||
+		string(msg.Headers[0].Key) != "hkey" || string(msg.Headers[0].Value) != "hvalue" {
+		t.Errorf("Expected the pooled message to hold copies of its record, got %+v", msg)
+	}
+	msg.Release()
+}
+
+func TestConsumerMessageReleaseUnpooled(t *testing.T) {
+	msg := &ConsumerMessage{Topic: "my_topic", Value: []byte(testMsg)}
+	msg.Release()
+	if msg.Topic != "my_topic" || string(msg.Value) != string(testMsg) {
+		t.Errorf("Releasing a message not leased from the pool changed it: %+v", msg)
+	}
+}
+
 func assertMessageOffset(t *testing.T, msg *ConsumerMessage, expectedOffset int64) {
 	t.Helper()
 	if msg.Offset != expectedOffset {
@@ -1905,7 +2093,7 @@ func Test_partitionConsumer_parseResponse(t *testing.T) {
 				},
 				conf: &Config{},
 			}
-			got, err := child.parseResponse(tt.args.response)
+			got, err := child.parseResponse(takeFetchedBlock(tt.args.response, child.topic, child.partition))
 			if (err != nil) != tt.wantErr {
 				t.Errorf("partitionConsumer.parseResponse() error = %v, wantErr %v", err, tt.wantErr)
 				return
@@ -1937,7 +2125,7 @@ func Test_partitionConsumer_parseResponseEmptyBatch(t *testing.T) {
 		topic:     "my_topic",
 		partition: 0,
 	}
-	got, err := child.parseResponse(response)
+	got, err := child.parseResponse(takeFetchedBlock(response, child.topic, child.partition))
 	if err != nil {
 		t.Errorf("partitionConsumer.parseResponse() error = %v", err)
 		return
@@ -2069,3 +2257,27 @@ func TestConsumerError(t *testing.T) {
 		t.Error("unexpected errors.Is")
 	}
 }
+
+func TestTakeFetchedBlock(t *testing.T) {
+	response := &FetchResponse{ThrottleTime: time.Millisecond}
+	response.AddError("my_topic", 0, ErrNoError)
+	response.AddError("my_topic", 1, ErrNoError)
+	block0, block1 := response.GetBlock("my_topic", 0), response.GetBlock("my_topic", 1)
+
+	fetched := takeFetchedBlock(response, "my_topic", 0)
+	if fetched.block != block0 || fetched.empty || fetched.throttleTime != time.Millisecond {
+		t.Errorf("unexpected block handed off %+v", fetched)
+	}
+	if response.GetBlock("my_topic", 0) != nil {
+		t.Error("expected the response to release the block handed off")
+	}
+	if fetched := takeFetchedBlock(response, "my_topic", 0); fetched.block != nil || fetched.empty {
+		t.Errorf("expected a block to be handed off once, got %+v", fetched)
+	}
+	if fetched := takeFetchedBlock(response, "my_topic", 1); fetched.block != block1 {
+		t.Errorf("expected the block of partition 1, got %+v", fetched)
+	}
+	if fetched := takeFetchedBlock(&FetchResponse{}, "my_topic", 0); !fetched.empty {
+		t.Errorf("expected an empty response, got %+v", fetched)
+	}
+}
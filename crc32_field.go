@@ -30,8 +30,34 @@ func releaseCrc32Field(c *crc32Field) {
 	crc32FieldPool.Put(c)
 }
 
+// castagnoliTable is the table hash/crc32 recognizes to compute Castagnoli
+// CRCs with the SSE4.2 or ARMv8 CRC32 instructions where available, as
+// crc32.MakeTable returns it for crc32.Castagnoli.
 var castagnoliTable = crc32.MakeTable(crc32.Castagnoli)
 
+// crcMismatchError is the PacketDecodingError of a CRC not matching the bytes
+// it covers.
+type crcMismatchError struct {
+	expected, actual uint32
+}
+
+func (err crcMismatchError) Error() string {
+	return err.packetDecodingError().Error()
+}
+
+func (err crcMismatchError) packetDecodingError() PacketDecodingError {
+	return PacketDecodingError{fmt.Sprintf("CRC didn't match expected %#x got %#x", err.expected, err.actual)}
+}
+
+// As lets errors.As match the mismatch as a PacketDecodingError.
+func (err crcMismatchError) As(target interface{}) bool {
+	if pde, ok := target.(*PacketDecodingError); ok {
+		*pde = err.packetDecodingError()
+		return true
+	}
+	return false
+}
+
 // crc32Field implements the pushEncoder and pushDecoder interfaces for calculating CRC32s.
 type crc32Field struct {
 	startOffset int
@@ -59,6 +85,25 @@ func (c *crc32Field) run(curOffset int, buf []byte) error {
 	return nil
 }
 
+func (c *crc32Field) runVectored(curOffset int, buf []byte, external []externalBytes) error {
+	tab, err := c.table()
+	if err != nil {
+		return err
+	}
+	var crc uint32
+	pos := c.startOffset + 4
+	for _, ext := range external {
+		if ext.off >= pos && ext.off <= curOffset {
+			crc = crc32.Update(crc, tab, buf[pos:ext.off])
+			crc = crc32.Update(crc, tab, ext.data)
+			pos = ext.off
+		}
+	}
+	crc = crc32.Update(crc, tab, buf[pos:curOffset])
+	binary.BigEndian.PutUint32(buf[c.startOffset:], crc)
+	return nil
+}
+
 func (c *crc32Field) check(curOffset int, buf []byte) error {
 	crc, err := c.crc(curOffset, buf)
 	if err != nil {
@@ -67,21 +112,27 @@ func (c *crc32Field) check(curOffset int, buf []byte) error {
 
 	expected := binary.BigEndian.Uint32(buf[c.startOffset:])
 	if crc != expected {
-		return PacketDecodingError{fmt.Sprintf("CRC didn't match expected %#x got %#x", expected, crc)}
+		return crcMismatchError{expected: expected, actual: crc}
 	}
 
 	return nil
 }
 
 func (c *crc32Field) crc(curOffset int, buf []byte) (uint32, error) {
-	var tab *crc32.Table
+	tab, err := c.table()
+	if err != nil {
+		return 0, err
+	}
+	return crc32.Checksum(buf[c.startOffset+4:curOffset], tab), nil
+}
+
+func (c *crc32Field) table() (*crc32.Table, error) {
 	switch c.polynomial {
 	case crcIEEE:
-		tab = crc32.IEEETable
+		return crc32.IEEETable, nil
 	case crcCastagnoli:
-		tab = castagnoliTable
+		return castagnoliTable, nil
 	default:
-		return 0, PacketDecodingError{"invalid CRC type"}
+		return nil, PacketDecodingError{"invalid CRC type"}
 	}
-	return crc32.Checksum(buf[c.startOffset+4:curOffset], tab), nil
 }
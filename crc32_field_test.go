@@ -0,0 +1,25 @@
+package sarama
+
+import (
+	"hash/crc32"
+	"testing"
+)
+
+func TestCastagnoliTableIsAccelerated(t *testing.T) {
+	// hash/crc32 only computes the Castagnoli CRCs with the SSE4.2 or ARMv8
+	// instructions for the very table it returns
+	if castagnoliTable != crc32.MakeTable(crc32.Castagnoli) {
+		t.Error("castagnoliTable is not the table of hash/crc32")
+	}
+}
+
+func BenchmarkCRC32FieldCastagnoli(b *testing.B) {
+	buf := make([]byte, 4+64*1024)
+	field := newCRC32Field(crcCastagnoli)
+	b.SetBytes(int64(len(buf) - 4))
+	for i := 0; i < b.N; i++ {
+		if err := field.run(len(buf), buf); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -3,21 +3,31 @@ package sarama
 import "time"
 
 type CreatePartitionsRequest struct {
+	// Version 1 is the same as version 0, version 2 is the first flexible
+	// version.
+	Version         int16
 	TopicPartitions map[string]*TopicPartition
 	Timeout         time.Duration
 	ValidateOnly    bool
 }
 
-func (c *CreatePartitionsRequest) encode(pe packetEncoder) error {
-	if err := pe.putArrayLength(len(c.TopicPartitions)); err != nil {
+func (c *CreatePartitionsRequest) encode(pe packetEncoder) (err error) {
+	if c.Version >= 2 {
+		pe.putCompactArrayLength(len(c.TopicPartitions))
+	} else if err = pe.putArrayLength(len(c.TopicPartitions)); err != nil {
 		return err
 	}
 
 	for topic, partition := range c.TopicPartitions {
-		if err := pe.putString(topic); err != nil {
+		if c.Version >= 2 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
+		if err != nil {
 			return err
 		}
-		if err := partition.encode(pe); err != nil {
+		if err = partition.encode(pe, c.Version); err != nil {
 			return err
 		}
 	}
@@ -26,17 +36,33 @@ func (c *CreatePartitionsRequest) encode(pe packetEncoder) error {
 
 	pe.putBool(c.ValidateOnly)
 
+	if c.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (c *CreatePartitionsRequest) decode(pd packetDecoder, version int16) (err error) {
-	n, err := pd.getArrayLength()
+	c.Version = version
+
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
 	c.TopicPartitions = make(map[string]*TopicPartition, n)
 	for i := 0; i < n; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if version >= 2 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
@@ -56,6 +82,12 @@ func (c *CreatePartitionsRequest) decode(pd packetDecoder, version int16) (err e
 		return err
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -64,15 +96,25 @@ func (r *CreatePartitionsRequest) key() int16 {
 }
 
 func (r *CreatePartitionsRequest) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *CreatePartitionsRequest) headerVersion() int16 {
+	if r.Version >= 2 {
+		return 2
+	}
 	return 1
 }
 
 func (r *CreatePartitionsRequest) requiredVersion() KafkaVersion {
-	return V1_0_0_0
+	switch r.Version {
+	case 2:
+		return V2_4_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V1_0_0_0
+	}
 }
 
 type TopicPartition struct {
@@ -80,22 +122,39 @@ type TopicPartition struct {
 	Assignment [][]int32
 }
 
-func (t *TopicPartition) encode(pe packetEncoder) error {
+func (t *TopicPartition) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt32(t.Count)
 
-	if len(t.Assignment) == 0 {
+	switch {
+	case len(t.Assignment) == 0 && version >= 2:
+		pe.putCompactArrayLength(-1)
+	case len(t.Assignment) == 0:
 		pe.putInt32(-1)
-		return nil
+	case version >= 2:
+		pe.putCompactArrayLength(len(t.Assignment))
+	default:
+		err = pe.putArrayLength(len(t.Assignment))
 	}
-
-	if err := pe.putArrayLength(len(t.Assignment)); err != nil {
+	if err != nil {
 		return err
 	}
 
 	for _, assign := range t.Assignment {
-		if err := pe.putInt32Array(assign); err != nil {
+		if version >= 2 {
+			err = pe.putCompactInt32Array(assign)
+		} else {
+			err = pe.putInt32Array(assign)
+		}
+		if err != nil {
 			return err
 		}
+		if version >= 2 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
@@ -106,17 +165,39 @@ func (t *TopicPartition) decode(pd packetDecoder, version int16) (err error) {
 		return err
 	}
 
-	n, err := pd.getInt32()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		var length int32
+		length, err = pd.getInt32()
+		n = int(length)
+	}
 	if err != nil {
 		return err
 	}
-	if n <= 0 {
-		return nil
+	if n > 0 {
+		t.Assignment = make([][]int32, n)
+	}
+
+	for i := range t.Assignment {
+		if version >= 2 {
+			t.Assignment[i], err = pd.getCompactInt32Array()
+		} else {
+			t.Assignment[i], err = pd.getInt32Array()
+		}
+		if err != nil {
+			return err
+		}
+		if version >= 2 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
 	}
-	t.Assignment = make([][]int32, n)
 
-	for i := 0; i < int(n); i++ {
-		if t.Assignment[i], err = pd.getInt32Array(); err != nil {
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
 			return err
 		}
 	}
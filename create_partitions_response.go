@@ -6,43 +6,67 @@ import (
 )
 
 type CreatePartitionsResponse struct {
+	Version              int16
 	ThrottleTime         time.Duration
 	TopicPartitionErrors map[string]*TopicPartitionError
 }
 
-func (c *CreatePartitionsResponse) encode(pe packetEncoder) error {
+func (c *CreatePartitionsResponse) encode(pe packetEncoder) (err error) {
 	pe.putInt32(int32(c.ThrottleTime / time.Millisecond))
-	if err := pe.putArrayLength(len(c.TopicPartitionErrors)); err != nil {
+	if c.Version >= 2 {
+		pe.putCompactArrayLength(len(c.TopicPartitionErrors))
+	} else if err = pe.putArrayLength(len(c.TopicPartitionErrors)); err != nil {
 		return err
 	}
 
 	for topic, partitionError := range c.TopicPartitionErrors {
-		if err := pe.putString(topic); err != nil {
+		if c.Version >= 2 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
+		if err != nil {
 			return err
 		}
-		if err := partitionError.encode(pe); err != nil {
+		if err = partitionError.encode(pe, c.Version); err != nil {
 			return err
 		}
 	}
 
+	if c.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (c *CreatePartitionsResponse) decode(pd packetDecoder, version int16) (err error) {
+	c.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
 	}
 	c.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
 
 	c.TopicPartitionErrors = make(map[string]*TopicPartitionError, n)
 	for i := 0; i < n; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if version >= 2 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
@@ -52,6 +76,12 @@ func (c *CreatePartitionsResponse) decode(pd packetDecoder, version int16) (err
 		}
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -60,15 +90,25 @@ func (r *CreatePartitionsResponse) key() int16 {
 }
 
 func (r *CreatePartitionsResponse) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *CreatePartitionsResponse) headerVersion() int16 {
+	if r.Version >= 2 {
+		return 1
+	}
 	return 0
 }
 
 func (r *CreatePartitionsResponse) requiredVersion() KafkaVersion {
-	return V1_0_0_0
+	switch r.Version {
+	case 2:
+		return V2_4_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V1_0_0_0
+	}
 }
 
 type TopicPartitionError struct {
@@ -88,13 +128,22 @@ func (t *TopicPartitionError) Unwrap() error {
 	return t.Err
 }
 
-func (t *TopicPartitionError) encode(pe packetEncoder) error {
+func (t *TopicPartitionError) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt16(int16(t.Err))
 
-	if err := pe.putNullableString(t.ErrMsg); err != nil {
+	if version >= 2 {
+		err = pe.putNullableCompactString(t.ErrMsg)
+	} else {
+		err = pe.putNullableString(t.ErrMsg)
+	}
+	if err != nil {
 		return err
 	}
 
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
@@ -105,9 +154,28 @@ func (t *TopicPartitionError) decode(pd packetDecoder, version int16) (err error
 	}
 	t.Err = KError(kerr)
 
-	if t.ErrMsg, err = pd.getNullableString(); err != nil {
+	if version >= 2 {
+		t.ErrMsg, err = pd.getCompactNullableString()
+	} else {
+		t.ErrMsg, err = pd.getNullableString()
+	}
+	if err != nil {
 		return err
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+func (c *CreatePartitionsResponse) throttleTime() time.Duration {
+	return c.ThrottleTime
+}
+
+func (c *CreatePartitionsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
+}
@@ -23,6 +23,16 @@ var (
 		0, 37, // partition error
 		0, 5, 'e', 'r', 'r', 'o', 'r',
 	}
+
+	createPartitionResponseFailV2 = []byte{
+		0, 0, 0, 100, // throttleTimeMs
+		2, // one topic (compact array length)
+		6, 't', 'o', 'p', 'i', 'c',
+		0, 37, // partition error
+		6, 'e', 'r', 'r', 'o', 'r',
+		0, // empty topic tagged fields
+		0, // empty tagged fields
+	}
 )
 
 func TestCreatePartitionsResponse(t *testing.T) {
@@ -50,6 +60,14 @@ func TestCreatePartitionsResponse(t *testing.T) {
 	if !reflect.DeepEqual(decodedresp, resp) {
 		t.Errorf("Decoding error: expected %v but got %v", decodedresp, resp)
 	}
+
+	resp.Version = 2
+	testResponse(t, "with errors v2", resp, createPartitionResponseFailV2)
+	decodedresp = new(CreatePartitionsResponse)
+	testVersionDecodable(t, "with errors v2", decodedresp, createPartitionResponseFailV2, 2)
+	if !reflect.DeepEqual(decodedresp, resp) {
+		t.Errorf("Decoding error: expected %v but got %v", decodedresp, resp)
+	}
 }
 
 func TestTopicPartitionError(t *testing.T) {
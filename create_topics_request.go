@@ -13,14 +13,20 @@ type CreateTopicsRequest struct {
 }
 
 func (c *CreateTopicsRequest) encode(pe packetEncoder) error {
-	if err := pe.putArrayLength(len(c.TopicDetails)); err != nil {
+	if c.Version >= 5 {
+		pe.putCompactArrayLength(len(c.TopicDetails))
+	} else if err := pe.putArrayLength(len(c.TopicDetails)); err != nil {
 		return err
 	}
 	for topic, detail := range c.TopicDetails {
-		if err := pe.putString(topic); err != nil {
+		if c.Version >= 5 {
+			if err := pe.putCompactString(topic); err != nil {
+				return err
+			}
+		} else if err := pe.putString(topic); err != nil {
 			return err
 		}
-		if err := detail.encode(pe); err != nil {
+		if err := detail.encode(pe, c.Version); err != nil {
 			return err
 		}
 	}
@@ -31,11 +37,20 @@ func (c *CreateTopicsRequest) encode(pe packetEncoder) error {
 		pe.putBool(c.ValidateOnly)
 	}
 
+	if c.Version >= 5 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (c *CreateTopicsRequest) decode(pd packetDecoder, version int16) (err error) {
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 5 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -43,7 +58,12 @@ func (c *CreateTopicsRequest) decode(pd packetDecoder, version int16) (err error
 	c.TopicDetails = make(map[string]*TopicDetail, n)
 
 	for i := 0; i < n; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if version >= 5 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
@@ -68,6 +88,12 @@ func (c *CreateTopicsRequest) decode(pd packetDecoder, version int16) (err error
 		c.Version = version
 	}
 
+	if version >= 5 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -80,11 +106,18 @@ func (c *CreateTopicsRequest) version() int16 {
 }
 
 func (r *CreateTopicsRequest) headerVersion() int16 {
+	if r.Version >= 5 {
+		return 2
+	}
 	return 1
 }
 
 func (c *CreateTopicsRequest) requiredVersion() KafkaVersion {
 	switch c.Version {
+	case 5, 4:
+		return V2_4_0_0
+	case 3:
+		return V2_0_0_0
 	case 2:
 		return V1_0_0_0
 	case 1:
@@ -101,24 +134,43 @@ type TopicDetail struct {
 	ConfigEntries     map[string]*string
 }
 
-func (t *TopicDetail) encode(pe packetEncoder) error {
+func (t *TopicDetail) encode(pe packetEncoder, version int16) error {
 	pe.putInt32(t.NumPartitions)
 	pe.putInt16(t.ReplicationFactor)
 
-	if err := pe.putArrayLength(len(t.ReplicaAssignment)); err != nil {
+	if version >= 5 {
+		pe.putCompactArrayLength(len(t.ReplicaAssignment))
+	} else if err := pe.putArrayLength(len(t.ReplicaAssignment)); err != nil {
 		return err
 	}
 	for partition, assignment := range t.ReplicaAssignment {
 		pe.putInt32(partition)
-		if err := pe.putInt32Array(assignment); err != nil {
+		if version >= 5 {
+			if err := pe.putCompactInt32Array(assignment); err != nil {
+				return err
+			}
+			pe.putEmptyTaggedFieldArray()
+		} else if err := pe.putInt32Array(assignment); err != nil {
 			return err
 		}
 	}
 
-	if err := pe.putArrayLength(len(t.ConfigEntries)); err != nil {
+	if version >= 5 {
+		pe.putCompactArrayLength(len(t.ConfigEntries))
+	} else if err := pe.putArrayLength(len(t.ConfigEntries)); err != nil {
 		return err
 	}
 	for configKey, configValue := range t.ConfigEntries {
+		if version >= 5 {
+			if err := pe.putCompactString(configKey); err != nil {
+				return err
+			}
+			if err := pe.putNullableCompactString(configValue); err != nil {
+				return err
+			}
+			pe.putEmptyTaggedFieldArray()
+			continue
+		}
 		if err := pe.putString(configKey); err != nil {
 			return err
 		}
@@ -127,6 +179,10 @@ func (t *TopicDetail) encode(pe packetEncoder) error {
 		}
 	}
 
+	if version >= 5 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
@@ -138,7 +194,12 @@ func (t *TopicDetail) decode(pd packetDecoder, version int16) (err error) {
 		return err
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 5 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -150,13 +211,24 @@ func (t *TopicDetail) decode(pd packetDecoder, version int16) (err error) {
 			if err != nil {
 				return err
 			}
-			if t.ReplicaAssignment[replica], err = pd.getInt32Array(); err != nil {
+			if version >= 5 {
+				if t.ReplicaAssignment[replica], err = pd.getCompactInt32Array(); err != nil {
+					return err
+				}
+				if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+					return err
+				}
+			} else if t.ReplicaAssignment[replica], err = pd.getInt32Array(); err != nil {
 				return err
 			}
 		}
 	}
 
-	n, err = pd.getArrayLength()
+	if version >= 5 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -164,6 +236,19 @@ func (t *TopicDetail) decode(pd packetDecoder, version int16) (err error) {
 	if n > 0 {
 		t.ConfigEntries = make(map[string]*string, n)
 		for i := 0; i < n; i++ {
+			if version >= 5 {
+				configKey, err := pd.getCompactString()
+				if err != nil {
+					return err
+				}
+				if t.ConfigEntries[configKey], err = pd.getCompactNullableString(); err != nil {
+					return err
+				}
+				if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+					return err
+				}
+				continue
+			}
 			configKey, err := pd.getString()
 			if err != nil {
 				return err
@@ -174,5 +259,11 @@ func (t *TopicDetail) decode(pd packetDecoder, version int16) (err error) {
 		}
 	}
 
+	if version >= 5 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
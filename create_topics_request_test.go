@@ -20,6 +20,25 @@ var (
 	}
 
 	createTopicsRequestV1 = append(createTopicsRequestV0, byte(1))
+
+	createTopicsRequestV5 = []byte{
+		2,
+		6, 't', 'o', 'p', 'i', 'c',
+		255, 255, 255, 255,
+		255, 255,
+		2, // 1 replica assignment
+		0, 0, 0, 0,
+		4, 0, 0, 0, 0, 0, 0, 0, 1, 0, 0, 0, 2,
+		0, // empty tags
+		2, // 1 config
+		13, 'r', 'e', 't', 'e', 'n', 't', 'i', 'o', 'n', '.', 'm', 's',
+		3, '-', '1',
+		0, // empty tags
+		0, // empty tags
+		0, 0, 0, 100,
+		1, // validate only
+		0, // empty tags
+	}
 )
 
 func TestCreateTopicsRequest(t *testing.T) {
@@ -47,4 +66,7 @@ func TestCreateTopicsRequest(t *testing.T) {
 	req.ValidateOnly = true
 
 	testRequest(t, "version 1", req, createTopicsRequestV1)
+
+	req.Version = 5
+	testRequest(t, "version 5", req, createTopicsRequestV5)
 }
@@ -1,6 +1,7 @@
 package sarama
 
 import (
+	"errors"
 	"fmt"
 	"time"
 )
@@ -9,6 +10,29 @@ type CreateTopicsResponse struct {
 	Version      int16
 	ThrottleTime time.Duration
 	TopicErrors  map[string]*TopicError
+	// TopicDetails holds, for version 5 and later, the partition count,
+	// replication factor and configuration of each topic created (or, in
+	// validate-only mode, that would be created) without error.
+	TopicDetails map[string]*CreatedTopicDetail
+}
+
+// CreatedTopicDetail describes a topic as it was created by the broker,
+// including the defaults it filled in.
+type CreatedTopicDetail struct {
+	NumPartitions     int32
+	ReplicationFactor int16
+	// Configs is nil if the broker withheld the configuration because of
+	// missing DescribeConfigs permissions.
+	Configs []*CreatedTopicConfig
+}
+
+// CreatedTopicConfig is a configuration entry of a created topic.
+type CreatedTopicConfig struct {
+	Name      string
+	Value     *string
+	ReadOnly  bool
+	Source    ConfigSource
+	Sensitive bool
 }
 
 func (c *CreateTopicsResponse) encode(pe packetEncoder) error {
@@ -16,16 +40,36 @@ func (c *CreateTopicsResponse) encode(pe packetEncoder) error {
 		pe.putInt32(int32(c.ThrottleTime / time.Millisecond))
 	}
 
-	if err := pe.putArrayLength(len(c.TopicErrors)); err != nil {
+	if c.Version >= 5 {
+		pe.putCompactArrayLength(len(c.TopicErrors))
+	} else if err := pe.putArrayLength(len(c.TopicErrors)); err != nil {
 		return err
 	}
 	for topic, topicError := range c.TopicErrors {
-		if err := pe.putString(topic); err != nil {
+		if c.Version >= 5 {
+			if err := pe.putCompactString(topic); err != nil {
+				return err
+			}
+		} else if err := pe.putString(topic); err != nil {
 			return err
 		}
 		if err := topicError.encode(pe, c.Version); err != nil {
 			return err
 		}
+		if c.Version >= 5 {
+			detail := c.TopicDetails[topic]
+			if detail == nil {
+				detail = &CreatedTopicDetail{NumPartitions: -1, ReplicationFactor: -1}
+			}
+			if err := detail.encode(pe); err != nil {
+				return err
+			}
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if c.Version >= 5 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
@@ -42,14 +86,27 @@ func (c *CreateTopicsResponse) decode(pd packetDecoder, version int16) (err erro
 		c.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 5 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
 
 	c.TopicErrors = make(map[string]*TopicError, n)
+	if version >= 5 {
+		c.TopicDetails = make(map[string]*CreatedTopicDetail, n)
+	}
 	for i := 0; i < n; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if version >= 5 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
@@ -57,6 +114,24 @@ func (c *CreateTopicsResponse) decode(pd packetDecoder, version int16) (err erro
 		if err := c.TopicErrors[topic].decode(pd, version); err != nil {
 			return err
 		}
+		if version >= 5 {
+			detail := new(CreatedTopicDetail)
+			if err := detail.decode(pd); err != nil {
+				return err
+			}
+			if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+			if errors.Is(c.TopicErrors[topic].Err, ErrNoError) {
+				c.TopicDetails[topic] = detail
+			}
+		}
+	}
+
+	if version >= 5 {
+		if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -71,11 +146,18 @@ func (c *CreateTopicsResponse) version() int16 {
 }
 
 func (c *CreateTopicsResponse) headerVersion() int16 {
+	if c.Version >= 5 {
+		return 1
+	}
 	return 0
 }
 
 func (c *CreateTopicsResponse) requiredVersion() KafkaVersion {
 	switch c.Version {
+	case 5, 4:
+		return V2_4_0_0
+	case 3:
+		return V2_0_0_0
 	case 2:
 		return V1_0_0_0
 	case 1:
@@ -105,7 +187,11 @@ func (t *TopicError) Unwrap() error {
 func (t *TopicError) encode(pe packetEncoder, version int16) error {
 	pe.putInt16(int16(t.Err))
 
-	if version >= 1 {
+	if version >= 5 {
+		if err := pe.putNullableCompactString(t.ErrMsg); err != nil {
+			return err
+		}
+	} else if version >= 1 {
 		if err := pe.putNullableString(t.ErrMsg); err != nil {
 			return err
 		}
@@ -121,7 +207,11 @@ func (t *TopicError) decode(pd packetDecoder, version int16) (err error) {
 	}
 	t.Err = KError(kErr)
 
-	if version >= 1 {
+	if version >= 5 {
+		if t.ErrMsg, err = pd.getCompactNullableString(); err != nil {
+			return err
+		}
+	} else if version >= 1 {
 		if t.ErrMsg, err = pd.getNullableString(); err != nil {
 			return err
 		}
@@ -129,3 +219,81 @@ func (t *TopicError) decode(pd packetDecoder, version int16) (err error) {
 
 	return nil
 }
+
+func (d *CreatedTopicDetail) encode(pe packetEncoder) error {
+	pe.putInt32(d.NumPartitions)
+	pe.putInt16(d.ReplicationFactor)
+
+	if d.Configs == nil {
+		pe.putCompactArrayLength(-1)
+		return nil
+	}
+	pe.putCompactArrayLength(len(d.Configs))
+	for _, config := range d.Configs {
+		if err := pe.putCompactString(config.Name); err != nil {
+			return err
+		}
+		if err := pe.putNullableCompactString(config.Value); err != nil {
+			return err
+		}
+		pe.putBool(config.ReadOnly)
+		pe.putInt8(int8(config.Source))
+		pe.putBool(config.Sensitive)
+		pe.putEmptyTaggedFieldArray()
+	}
+
+	return nil
+}
+
+func (d *CreatedTopicDetail) decode(pd packetDecoder) (err error) {
+	if d.NumPartitions, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if d.ReplicationFactor, err = pd.getInt16(); err != nil {
+		return err
+	}
+
+	n, err := pd.getCompactArrayLength()
+	if err != nil {
+		return err
+	}
+	if n <= 0 {
+		return nil
+	}
+
+	d.Configs = make([]*CreatedTopicConfig, n)
+	for i := range d.Configs {
+		config := new(CreatedTopicConfig)
+		if config.Name, err = pd.getCompactString(); err != nil {
+			return err
+		}
+		if config.Value, err = pd.getCompactNullableString(); err != nil {
+			return err
+		}
+		if config.ReadOnly, err = pd.getBool(); err != nil {
+			return err
+		}
+		source, err := pd.getInt8()
+		if err != nil {
+			return err
+		}
+		config.Source = ConfigSource(source)
+		if config.Sensitive, err = pd.getBool(); err != nil {
+			return err
+		}
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+		d.Configs[i] = config
+	}
+
+	return nil
+}
+
+func (c *CreateTopicsResponse) throttleTime() time.Duration {
+	return c.ThrottleTime
+}
+
+func (c *CreateTopicsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 3
+}
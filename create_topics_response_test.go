@@ -27,6 +27,25 @@ var (
 		0, 42,
 		0, 3, 'm', 's', 'g',
 	}
+
+	createTopicsResponseV5 = []byte{
+		0, 0, 0, 100,
+		2,
+		6, 't', 'o', 'p', 'i', 'c',
+		0, 0,
+		0,          // no error message
+		0, 0, 0, 3, // partitions
+		0, 2, // replication factor
+		2, // 1 config
+		13, 'r', 'e', 't', 'e', 'n', 't', 'i', 'o', 'n', '.', 'm', 's',
+		3, '-', '1',
+		0, // read only
+		1, // topic config
+		0, // sensitive
+		0, // empty tags
+		0, // empty tags
+		0, // empty tags
+	}
 )
 
 func TestCreateTopicsResponse(t *testing.T) {
@@ -52,6 +71,29 @@ func TestCreateTopicsResponse(t *testing.T) {
 	testResponse(t, "version 2", resp, createTopicsResponseV2)
 }
 
+func TestCreateTopicsResponseV5(t *testing.T) {
+	resp := &CreateTopicsResponse{
+		Version:      5,
+		ThrottleTime: 100 * time.Millisecond,
+		TopicErrors: map[string]*TopicError{
+			"topic": {Err: ErrNoError},
+		},
+		TopicDetails: map[string]*CreatedTopicDetail{
+			"topic": {
+				NumPartitions:     3,
+				ReplicationFactor: 2,
+				Configs: []*CreatedTopicConfig{{
+					Name:   "retention.ms",
+					Value:  nullString("-1"),
+					Source: SourceTopic,
+				}},
+			},
+		},
+	}
+
+	testResponse(t, "version 5", resp, createTopicsResponseV5)
+}
+
 func TestTopicError(t *testing.T) {
 	// Assert that TopicError satisfies error interface
 	var err error = &TopicError{
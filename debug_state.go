@@ -0,0 +1,160 @@
+package sarama
+
+import (
+	"sort"
+	"sync/atomic"
+	"time"
+)
+
+// BrokerDebugState is the state of the connection to a broker, as reported by
+// the DebugState methods.
+type BrokerDebugState struct {
+	ID   int32
+	Addr string
+	// State is whether the broker is connecting, connected or closed.
+	State BrokerState
+	// ConnectedAt is when the connection was established, zero unless
+	// connected.
+	ConnectedAt time.Time
+	// LastError is the error of the last connection attempt, nil if it
+	// succeeded.
+	LastError error
+	// ConnectionID identifies the connection, see
+	// BrokerConnectionState.ConnectionID.
+	ConnectionID string
+	// InFlight is the number of requests awaiting a response.
+	InFlight int
+	// ThrottledUntil is when the broker stops throttling the client, zero
+	// unless throttled.
+	ThrottledUntil time.Time
+}
+
+// ClientDebugState is a snapshot of the state of a Client, as returned by
+// Client.DebugState.
+type ClientDebugState struct {
+	Closed bool
+	// ControllerID is the ID of the controller broker, -1 if unknown.
+	ControllerID int32
+	// Brokers are the brokers of the cluster, sorted by ID.
+	Brokers []BrokerDebugState
+	// SeedBrokers are the brokers the client bootstraps from.
+	SeedBrokers []BrokerDebugState
+	// Topics are the topics whose metadata the client keeps, sorted.
+	Topics []string
+	// Coordinators are the IDs of the coordinators of the consumer groups, by
+	// group.
+	Coordinators map[string]int32
+}
+
+// ProducerPartitionState is the state of a partition an AsyncProducer
+// produces to.
+type ProducerPartitionState struct {
+	Topic     string
+	Partition int32
+	// Leader is the ID of the broker the messages are sent to, -1 while it is
+	// looked up.
+	Leader int32
+	// Queued is the number of messages waiting to be dispatched to the broker.
+	Queued int
+	// RetryLevel is how many times the messages being sent were retried, 0
+	// unless the partition is retrying messages.
+	RetryLevel int
+	// RetryBuffered is the number of messages held back, to keep them in
+	// order, until the messages of higher retry levels are sent.
+	RetryBuffered int
+}
+
+// ProducerBrokerState is the state of the messages an AsyncProducer sends to a
+// broker.
+type ProducerBrokerState struct {
+	Broker int32
+	// Partitions is the number of partitions producing to the broker.
+	Partitions int
+	// Buffered and BufferedBytes are the number and size of the messages
+	// buffered for the next request.
+	Buffered      int
+	BufferedBytes int
+}
+
+// ProducerDebugState is a snapshot of the state of an AsyncProducer, as
+// returned by AsyncProducer.DebugState.
+type ProducerDebugState struct {
+	// Pending is the number of messages neither delivered nor failed yet.
+	Pending int
+	// Partitions are the partitions produced to, sorted by topic and
+	// partition.
+	Partitions []ProducerPartitionState
+	// Brokers are the brokers produced to, sorted by ID.
+	Brokers []ProducerBrokerState
+	// Client is the state of the client of the producer.
+	Client ClientDebugState
+}
+
+// ConsumerPartitionState is the state of a partition a ConsumerGroup consumes.
+type ConsumerPartitionState struct {
+	Topic     string
+	Partition int32
+	// Offset is the offset fetched next.
+	Offset int64
+	// MarkedOffset is the offset committed by the group, or marked by the
+	// session since, -1 if none.
+	MarkedOffset int64
+	// HighWaterMarkOffset is the high water mark of the partition as of the
+	// last fetch.
+	HighWaterMarkOffset int64
+	// Buffered is the number of messages fetched but not consumed yet.
+	Buffered int
+	Paused   bool
+}
+
+// ConsumerGroupDebugState is a snapshot of the state of a ConsumerGroup, as
+// returned by ConsumerGroup.DebugState.
+type ConsumerGroupDebugState struct {
+	GroupID string
+	// MemberID and GenerationID are those of the current session, empty and
+	// 0 unless consuming.
+	MemberID     string
+	GenerationID int32
+	// Claims are the partitions claimed by the current session, by topic.
+	Claims map[string][]int32
+	// Partitions are the partitions consumed, sorted by topic and partition.
+	Partitions []ConsumerPartitionState
+	// Client is the state of the client of the group.
+	Client ClientDebugState
+}
+
+// debugState returns the state of the connection to the broker.
+func (b *Broker) debugState() BrokerDebugState {
+	conn := b.ConnectionState()
+	state := BrokerDebugState{
+		ID:           b.ID(),
+		Addr:         b.Addr(),
+		State:        conn.State,
+		ConnectedAt:  conn.ConnectedAt,
+		LastError:    conn.LastError,
+		ConnectionID: conn.ConnectionID,
+		InFlight:     int(atomic.LoadInt32(&b.pendingRequests)),
+	}
+
+	b.throttleLock.Lock()
+	if b.throttleUntil.After(time.Now()) {
+		state.ThrottledUntil = b.throttleUntil
+	}
+	b.throttleLock.Unlock()
+	return state
+}
+
+// brokersDebugState returns the states of brokers, sorted by ID and address.
+func brokersDebugState(brokers []*Broker) []BrokerDebugState {
+	states := make([]BrokerDebugState, 0, len(brokers))
+	for _, broker := range brokers {
+		states = append(states, broker.debugState())
+	}
+	sort.Slice(states, func(i, j int) bool {
+		if states[i].ID != states[j].ID {
+			return states[i].ID < states[j].ID
+		}
+		return states[i].Addr < states[j].Addr
+	})
+	return states
+}
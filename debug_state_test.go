@@ -0,0 +1,166 @@
+package sarama
+
+import (
+	"context"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestClientDebugState(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetController(seedBroker.BrokerID()).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetLeader("my_topic", 0, seedBroker.BrokerID()),
+	})
+
+	config := NewTestConfig()
+	config.Version = V0_10_0_0
+	client, err := NewClient([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	state := client.DebugState()
+	if state.Closed || state.ControllerID != seedBroker.BrokerID() || !reflect.DeepEqual(state.Topics, []string{"my_topic"}) {
+		t.Errorf("unexpected state %+v", state)
+	}
+	if len(state.Brokers) != 1 || state.Brokers[0].ID != seedBroker.BrokerID() || state.Brokers[0].Addr != seedBroker.Addr() {
+		t.Errorf("unexpected brokers %+v", state.Brokers)
+	}
+	if len(state.SeedBrokers) != 1 || state.SeedBrokers[0].State != BrokerConnected {
+		t.Errorf("expected the seed broker to be connected, got %+v", state.SeedBrokers)
+	}
+
+	safeClose(t, client)
+	if !client.DebugState().Closed {
+		t.Error("expected the client to be reported closed")
+	}
+}
+
+func TestAsyncProducerDebugState(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetLeader("my_topic", 0, seedBroker.BrokerID()),
+		"ProduceRequest": NewMockProduceResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Producer.Flush.Messages = 2
+	producer, err := NewAsyncProducer([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	producer.Input() <- &ProducerMessage{Topic: "my_topic", Value: StringEncoder("ABC")}
+	var state ProducerDebugState
+	for deadline := time.Now().Add(5 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		state = producer.DebugState()
+		if len(state.Brokers) == 1 && state.Brokers[0].Buffered == 1 {
+			break
+		}
+	}
+	expectedPartitions := []ProducerPartitionState{{Topic: "my_topic", Partition: 0, Leader: seedBroker.BrokerID()}}
+	if state.Pending != 1 || !reflect.DeepEqual(state.Partitions, expectedPartitions) {
+		t.Errorf("expected the message to be pending on my_topic/0, got %+v", state)
+	}
+	if len(state.Brokers) != 1 || state.Brokers[0].Broker != seedBroker.BrokerID() || state.Brokers[0].Partitions != 1 ||
+		state.Brokers[0].Buffered != 1 || state.Brokers[0].BufferedBytes == 0 {
+		t.Errorf("expected the message to be buffered for broker 1, got %+v", state.Brokers)
+	}
+	if len(state.Client.Brokers) != 1 {
+		t.Errorf("expected the state of the client, got %+v", state.Client)
+	}
+
+	// fill the batch for it to be flushed
+	producer.Input() <- &ProducerMessage{Topic: "my_topic", Value: StringEncoder("DEF")}
+	closeProducer(t, producer)
+}
+
+type debugStateGroupHandler struct {
+	claimed chan none
+}
+
+func (h *debugStateGroupHandler) Setup(ConsumerGroupSession) error   { return nil }
+func (h *debugStateGroupHandler) Cleanup(ConsumerGroupSession) error { return nil }
+func (h *debugStateGroupHandler) ConsumeClaim(sess ConsumerGroupSession, claim ConsumerGroupClaim) error {
+	sess.MarkOffset(claim.Topic(), claim.Partition(), 7, "")
+	h.claimed <- none{}
+	<-sess.Context().Done()
+	return nil
+}
+
+func TestConsumerGroupDebugState(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetLeader("my_topic", 0, seedBroker.BrokerID()),
+		"FindCoordinatorRequest": NewMockFindCoordinatorResponse(t).
+			SetCoordinator(CoordinatorGroup, "my_group", seedBroker),
+		"JoinGroupRequest": NewMockJoinGroupResponse(t).
+			SetGenerationId(3).
+			SetMemberId("member").
+			SetLeaderId("leader"),
+		"SyncGroupRequest": NewMockSyncGroupResponse(t).
+			SetMemberAssignment(&ConsumerGroupMemberAssignment{Topics: map[string][]int32{"my_topic": {0}}}),
+		"OffsetFetchRequest": NewMockOffsetFetchResponse(t).
+			SetOffset("my_group", "my_topic", 0, 5, "", ErrNoError),
+		"OffsetRequest": NewMockOffsetResponse(t).
+			SetVersion(1).
+			SetOffset("my_topic", 0, OffsetOldest, 0).
+			SetOffset("my_topic", 0, OffsetNewest, 10),
+		"FetchRequest": NewMockFetchResponse(t, 1).
+			SetVersion(3).
+			SetHighWaterMark("my_topic", 0, 10),
+		"HeartbeatRequest":    NewMockHeartbeatResponse(t),
+		"OffsetCommitRequest": NewMockOffsetCommitResponse(t),
+		"LeaveGroupRequest":   NewMockLeaveGroupResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Version = V0_10_2_0
+	group, err := NewConsumerGroup([]string{seedBroker.Addr()}, "my_group", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if state := group.DebugState(); state.GroupID != "my_group" || state.MemberID != "" || len(state.Partitions) != 0 {
+		t.Errorf("expected no session before consuming, got %+v", state)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	handler := &debugStateGroupHandler{claimed: make(chan none, 1)}
+	consumed := make(chan error, 1)
+	go func() { consumed <- group.Consume(ctx, []string{"my_topic"}, handler) }()
+
+	select {
+	case <-handler.claimed:
+	case err := <-consumed:
+		t.Fatal(err)
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for the claim")
+	}
+	state := group.DebugState()
+	if state.MemberID != "member" || state.GenerationID != 3 || !reflect.DeepEqual(state.Claims, map[string][]int32{"my_topic": {0}}) {
+		t.Errorf("unexpected session state %+v", state)
+	}
+	if len(state.Partitions) != 1 || state.Partitions[0].Offset != 5 || state.Partitions[0].MarkedOffset != 7 {
+		t.Errorf("expected my_topic/0 to be fetched from 5 and marked at 7, got %+v", state.Partitions)
+	}
+
+	cancel()
+	if err := <-consumed; err != nil {
+		t.Error(err)
+	}
+	if state := group.DebugState(); state.MemberID != "" {
+		t.Errorf("expected no session once consumed, got %+v", state)
+	}
+	safeClose(t, group)
+}
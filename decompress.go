@@ -3,11 +3,12 @@ package sarama
 import (
 	"bytes"
 	"compress/gzip"
+	"errors"
 	"fmt"
 	"io"
 	"sync"
 
-	snappy "github.com/eapache/go-xerial-snappy"
+	"github.com/klauspost/compress/zstd"
 	"github.com/pierrec/lz4"
 )
 
@@ -19,9 +20,16 @@ var (
 	}
 
 	gzipReaderPool sync.Pool
+
+	// errDecompressionLimit is returned by decompress rather than decompress
+	// more than its maxSize
+	errDecompressionLimit = errors.New("kafka: decompressed data over its limit")
 )
 
-func decompress(cc CompressionCodec, data []byte) ([]byte, error) {
+// decompress decompresses data into dst[:0], growing it if needed, failing
+// with errDecompressionLimit rather than decompress more than maxSize bytes,
+// unless maxSize is negative.
+func decompress(cc CompressionCodec, zstdParams ZstdDecoderParams, maxSize int, dst, data []byte) ([]byte, error) {
 	switch cc {
 	case CompressionNone:
 		return data, nil
@@ -40,9 +48,9 @@ func decompress(cc CompressionCodec, data []byte) ([]byte, error) {
 
 		defer gzipReaderPool.Put(reader)
 
-		return io.ReadAll(reader)
+		return readAllLimited(reader, maxSize, dst)
 	case CompressionSnappy:
-		return snappy.Decode(data)
+		return snappyDecode(dst, data, maxSize)
 	case CompressionLZ4:
 		reader, ok := lz4ReaderPool.Get().(*lz4.Reader)
 		if !ok {
@@ -52,10 +60,114 @@ func decompress(cc CompressionCodec, data []byte) ([]byte, error) {
 		}
 		defer lz4ReaderPool.Put(reader)
 
-		return io.ReadAll(reader)
+		return readAllLimited(reader, maxSize, dst)
 	case CompressionZSTD:
-		return zstdDecompress(ZstdDecoderParams{}, nil, data)
+		// the shared decoders stop at the maxMemory of their params, rather
+		// than at maxSize which would take a decoder per size
+		out, err := zstdDecompress(zstdParams, dst[:0], data)
+		if errors.Is(err, zstd.ErrDecoderSizeExceeded) || (err == nil && maxSize >= 0 && len(out) > maxSize) {
+			return nil, errDecompressionLimit
+		}
+		// the decoders also lower their window to maxMemory, a frame with a
+		// larger window then decompressing past it
+		if errors.Is(err, zstd.ErrWindowSizeExceeded) && zstdParams.maxMemory != 0 &&
+			(zstdParams.MaxWindowSize == 0 || zstdParams.MaxWindowSize > zstdParams.maxMemory) {
+			return nil, errDecompressionLimit
+		}
+		if err != nil {
+			return nil, err
+		}
+		return out, nil
 	default:
 		return nil, PacketDecodingError{fmt.Sprintf("invalid compression specified (%d)", cc)}
 	}
 }
+
+// readAllLimited reads r until EOF into dst[:0], growing it only when it is
+// full. It fails with errDecompressionLimit rather than read more than maxSize
+// bytes, unless maxSize is negative.
+func readAllLimited(r io.Reader, maxSize int, dst []byte) ([]byte, error) {
+	if maxSize >= 0 {
+		r = io.LimitReader(r, int64(maxSize)+1)
+	}
+
+	// as io.ReadAll, from the capacity of dst
+	b := dst[:0]
+	for {
+		if len(b) == cap(b) {
+			b = append(b, 0)[:len(b)]
+		}
+		n, err := r.Read(b[len(b):cap(b)])
+		b = b[:len(b)+n]
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if maxSize >= 0 && len(b) > maxSize {
+		return nil, errDecompressionLimit
+	}
+	return b, nil
+}
+
+// maxDecompressBuffers is the number of buffers decompressBuffers keeps for
+// reuse, enough for the batches of a fetch response of a partition.
+const maxDecompressBuffers = 16
+
+// decompressBuffers are the buffers the record batches of a partition are
+// decompressed into. New ones are sized by the largest of its recent batches
+// rather than grown batch after batch, and those released once the messages
+// copied their data out, with Consumer.PoolMessages, are reused. It is safe
+// for concurrent use, the batches being decoded and released by different
+// goroutines, and a nil *decompressBuffers allocates new buffers as needed.
+type decompressBuffers struct {
+	lock sync.Mutex
+	free [][]byte
+	// size is the size of the largest of the recent batches, decaying
+	// batch after batch so that a single outsized batch is forgotten
+	size int
+}
+
+// get returns an empty buffer to decompress a batch into.
+func (d *decompressBuffers) get() []byte {
+	if d == nil {
+		return nil
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if n := len(d.free); n > 0 {
+		buf := d.free[n-1]
+		d.free = d.free[:n-1]
+		return buf
+	}
+	return make([]byte, 0, d.size)
+}
+
+// observe records the size of a batch decompressed into a buffer from get.
+func (d *decompressBuffers) observe(size int) {
+	if d == nil {
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if decayed := d.size - d.size/8; size < decayed {
+		size = decayed
+	}
+	d.size = size
+}
+
+// put hands buf back for reuse, once nothing references its data, unless it
+// is outsized compared to the recent batches.
+func (d *decompressBuffers) put(buf []byte) {
+	if d == nil || buf == nil {
+		return
+	}
+	d.lock.Lock()
+	defer d.lock.Unlock()
+	if len(d.free) < maxDecompressBuffers && cap(buf) <= 2*d.size {
+		d.free = append(d.free, buf[:0])
+	}
+}
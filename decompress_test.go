@@ -0,0 +1,117 @@
+package sarama
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestDecompressIntoBuffers(t *testing.T) {
+	first := bytes.Repeat([]byte("first "), 1000)
+	second := bytes.Repeat([]byte("second "), 2000)
+	for _, codec := range []CompressionCodec{CompressionGZIP, CompressionLZ4, CompressionZSTD, CompressionSnappy} {
+		opts := compressionOptions{snappyFraming: SnappyXerialFraming}
+		compressedFirst, err := compress(codec, CompressionLevelDefault, opts, first)
+		if err != nil {
+			t.Fatal(err)
+		}
+		compressedSecond, err := compress(codec, CompressionLevelDefault, opts, second)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		buffers := new(decompressBuffers)
+		out, err := decompress(codec, ZstdDecoderParams{}, -1, buffers.get(), compressedFirst)
+		if err != nil {
+			t.Fatal(codec, err)
+		}
+		buffers.observe(len(out))
+		// the records reference the decompressed data, which must not be
+		// overwritten by the next batch until released
+		if _, err := decompress(codec, ZstdDecoderParams{}, -1, buffers.get(), compressedSecond); err != nil {
+			t.Fatal(codec, err)
+		}
+		if !bytes.Equal(out, first) {
+			t.Errorf("%s: decompressed data overwritten by the next decompression", codec)
+		}
+
+		buffers.put(out)
+		reused, err := decompress(codec, ZstdDecoderParams{}, -1, buffers.get(), compressedFirst)
+		if err != nil {
+			t.Fatal(codec, err)
+		}
+		if !bytes.Equal(reused, first) {
+			t.Errorf("%s: unexpected data decompressed into a released buffer", codec)
+		}
+		if &reused[0] != &out[:1][0] {
+			t.Errorf("%s: expected the released buffer to be reused", codec)
+		}
+	}
+}
+
+func TestDecompressBuffersSizing(t *testing.T) {
+	buffers := new(decompressBuffers)
+	buffers.observe(1000)
+	if buf := buffers.get(); len(buf) != 0 || cap(buf) != 1000 {
+		t.Errorf("expected a new buffer sized by the largest batch, got %d of %d bytes", len(buf), cap(buf))
+	}
+
+	// an outsized batch is forgotten batch after batch, and its buffer not kept
+	buffers.observe(100000)
+	for i := 0; i < 50; i++ {
+		buffers.observe(1000)
+	}
+	if buffers.size != 1000 {
+		t.Errorf("expected the size to decay back to 1000 bytes, got %d", buffers.size)
+	}
+	buffers.put(make([]byte, 100000))
+	if len(buffers.free) != 0 {
+		t.Error("expected an outsized buffer not to be kept")
+	}
+
+	for i := 0; i < 2*maxDecompressBuffers; i++ {
+		buffers.put(make([]byte, 1000))
+	}
+	if len(buffers.free) != maxDecompressBuffers {
+		t.Errorf("expected %d buffers to be kept, got %d", maxDecompressBuffers, len(buffers.free))
+	}
+
+	var none *decompressBuffers
+	none.observe(1000)
+	none.put(make([]byte, 1000))
+	if buf := none.get(); buf != nil {
+		t.Error("expected no buffer from nil buffers")
+	}
+}
+
+func BenchmarkDecompress(b *testing.B) {
+	data := bytes.Repeat([]byte("a fairly compressible record value "), 10000)
+	for _, codec := range []CompressionCodec{CompressionGZIP, CompressionLZ4, CompressionZSTD, CompressionSnappy} {
+		compressed, err := compress(codec, CompressionLevelDefault, compressionOptions{}, data)
+		if err != nil {
+			b.Fatal(err)
+		}
+		b.Run(codec.String(), func(b *testing.B) {
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				if _, err := decompress(codec, ZstdDecoderParams{}, -1, nil, compressed); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		// as the batches of a partition with Consumer.PoolMessages
+		b.Run(codec.String()+"/buffers", func(b *testing.B) {
+			buffers := new(decompressBuffers)
+			b.ReportAllocs()
+			b.SetBytes(int64(len(data)))
+			for i := 0; i < b.N; i++ {
+				out, err := decompress(codec, ZstdDecoderParams{}, -1, buffers.get(), compressed)
+				if err != nil {
+					b.Fatal(err)
+				}
+				buffers.observe(len(out))
+				buffers.put(out)
+			}
+		})
+	}
+}
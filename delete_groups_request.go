@@ -1,14 +1,32 @@
 package sarama
 
 type DeleteGroupsRequest struct {
-	Groups []string
+	// Version 1 is the same as version 0, version 2 is the first flexible
+	// version.
+	Version int16
+	Groups  []string
 }
 
 func (r *DeleteGroupsRequest) encode(pe packetEncoder) error {
+	if r.Version >= 2 {
+		if err := pe.putCompactStringArray(r.Groups); err != nil {
+			return err
+		}
+		pe.putEmptyTaggedFieldArray()
+		return nil
+	}
 	return pe.putStringArray(r.Groups)
 }
 
 func (r *DeleteGroupsRequest) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if version >= 2 {
+		if r.Groups, err = pd.getCompactStringArray(); err != nil {
+			return
+		}
+		_, err = pd.getEmptyTaggedFieldArray()
+		return
+	}
 	r.Groups, err = pd.getStringArray()
 	return
 }
@@ -18,15 +36,25 @@ func (r *DeleteGroupsRequest) key() int16 {
 }
 
 func (r *DeleteGroupsRequest) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *DeleteGroupsRequest) headerVersion() int16 {
+	if r.Version >= 2 {
+		return 2
+	}
 	return 1
 }
 
 func (r *DeleteGroupsRequest) requiredVersion() KafkaVersion {
-	return V1_1_0_0
+	switch r.Version {
+	case 2:
+		return V2_4_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V1_1_0_0
+	}
 }
 
 func (r *DeleteGroupsRequest) AddGroup(group string) {
@@ -15,6 +15,12 @@ var (
 		0, 3, 'f', 'o', 'o', // group name: foo
 		0, 3, 'b', 'a', 'r', // group name: foo
 	}
+
+	singleDeleteGroupsRequestV2 = []byte{
+		2,                // 1 group (compact array length)
+		4, 'f', 'o', 'o', // group name: foo
+		0, // empty tagged fields
+	}
 )
 
 func TestDeleteGroupsRequest(t *testing.T) {
@@ -31,4 +37,8 @@ func TestDeleteGroupsRequest(t *testing.T) {
 	request.AddGroup("foo")
 	request.AddGroup("bar")
 	testRequest(t, "two groups", request, doubleDeleteGroupsRequest)
+
+	request = &DeleteGroupsRequest{Version: 2}
+	request.AddGroup("foo")
+	testRequest(t, "one group v2", request, singleDeleteGroupsRequestV2)
 }
@@ -5,44 +5,70 @@ import (
 )
 
 type DeleteGroupsResponse struct {
+	Version         int16
 	ThrottleTime    time.Duration
 	GroupErrorCodes map[string]KError
 }
 
-func (r *DeleteGroupsResponse) encode(pe packetEncoder) error {
+func (r *DeleteGroupsResponse) encode(pe packetEncoder) (err error) {
 	pe.putInt32(int32(r.ThrottleTime / time.Millisecond))
 
-	if err := pe.putArrayLength(len(r.GroupErrorCodes)); err != nil {
+	if r.Version >= 2 {
+		pe.putCompactArrayLength(len(r.GroupErrorCodes))
+	} else if err = pe.putArrayLength(len(r.GroupErrorCodes)); err != nil {
 		return err
 	}
 	for groupID, errorCode := range r.GroupErrorCodes {
-		if err := pe.putString(groupID); err != nil {
+		if r.Version >= 2 {
+			err = pe.putCompactString(groupID)
+		} else {
+			err = pe.putString(groupID)
+		}
+		if err != nil {
 			return err
 		}
 		pe.putInt16(int16(errorCode))
+		if r.Version >= 2 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if r.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
 }
 
 func (r *DeleteGroupsResponse) decode(pd packetDecoder, version int16) error {
+	r.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
 	}
 	r.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
-	if n == 0 {
-		return nil
-	}
 
-	r.GroupErrorCodes = make(map[string]KError, n)
+	if n > 0 {
+		r.GroupErrorCodes = make(map[string]KError, n)
+	}
 	for i := 0; i < n; i++ {
-		groupID, err := pd.getString()
+		var groupID string
+		if version >= 2 {
+			groupID, err = pd.getCompactString()
+		} else {
+			groupID, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
@@ -50,10 +76,21 @@ func (r *DeleteGroupsResponse) decode(pd packetDecoder, version int16) error {
 		if err != nil {
 			return err
 		}
+		if version >= 2 {
+			if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
 
 		r.GroupErrorCodes[groupID] = KError(errorCode)
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -62,13 +99,31 @@ func (r *DeleteGroupsResponse) key() int16 {
 }
 
 func (r *DeleteGroupsResponse) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *DeleteGroupsResponse) headerVersion() int16 {
+	if r.Version >= 2 {
+		return 1
+	}
 	return 0
 }
 
 func (r *DeleteGroupsResponse) requiredVersion() KafkaVersion {
-	return V1_1_0_0
+	switch r.Version {
+	case 2:
+		return V2_4_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V1_1_0_0
+	}
+}
+
+func (r *DeleteGroupsResponse) throttleTime() time.Duration {
+	return r.ThrottleTime
+}
+
+func (r *DeleteGroupsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
 }
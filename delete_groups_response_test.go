@@ -24,6 +24,15 @@ var (
 		0, 3, 'f', 'o', 'o', // group name
 		0, 0, // no error
 	}
+
+	errorDeleteGroupsResponseV2 = []byte{
+		0, 0, 0, 0, // does not violate any quota
+		2,                // 1 group (compact array length)
+		4, 'f', 'o', 'o', // group name
+		0, 31, // error ErrClusterAuthorizationFailed
+		0, // empty group tagged fields
+		0, // empty tagged fields
+	}
 )
 
 func TestDeleteGroupsResponse(t *testing.T) {
@@ -55,4 +64,10 @@ func TestDeleteGroupsResponse(t *testing.T) {
 	if !errors.Is(response.GroupErrorCodes["foo"], ErrNoError) {
 		t.Error("Expected error ErrClusterAuthorizationFailed, found:", response.GroupErrorCodes["foo"])
 	}
+
+	response = &DeleteGroupsResponse{
+		Version:         2,
+		GroupErrorCodes: map[string]KError{"foo": ErrClusterAuthorizationFailed},
+	}
+	testResponse(t, "error v2", response, errorDeleteGroupsResponseV2)
 }
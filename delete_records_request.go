@@ -13,12 +13,15 @@ import (
 //  id(int32) offset(int64)
 
 type DeleteRecordsRequest struct {
+	Version int16
 	Topics  map[string]*DeleteRecordsRequestTopic
 	Timeout time.Duration
 }
 
 func (d *DeleteRecordsRequest) encode(pe packetEncoder) error {
-	if err := pe.putArrayLength(len(d.Topics)); err != nil {
+	if d.Version >= 2 {
+		pe.putCompactArrayLength(len(d.Topics))
+	} else if err := pe.putArrayLength(len(d.Topics)); err != nil {
 		return err
 	}
 	keys := make([]string, 0, len(d.Topics))
@@ -27,20 +30,35 @@ func (d *DeleteRecordsRequest) encode(pe packetEncoder) error {
 	}
 	sort.Strings(keys)
 	for _, topic := range keys {
-		if err := pe.putString(topic); err != nil {
+		if d.Version >= 2 {
+			if err := pe.putCompactString(topic); err != nil {
+				return err
+			}
+		} else if err := pe.putString(topic); err != nil {
 			return err
 		}
-		if err := d.Topics[topic].encode(pe); err != nil {
+		if err := d.Topics[topic].encode(pe, d.Version); err != nil {
 			return err
 		}
 	}
 	pe.putInt32(int32(d.Timeout / time.Millisecond))
 
+	if d.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (d *DeleteRecordsRequest) decode(pd packetDecoder, version int16) error {
-	n, err := pd.getArrayLength()
+func (d *DeleteRecordsRequest) decode(pd packetDecoder, version int16) (err error) {
+	d.Version = version
+
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -48,7 +66,12 @@ func (d *DeleteRecordsRequest) decode(pd packetDecoder, version int16) error {
 	if n > 0 {
 		d.Topics = make(map[string]*DeleteRecordsRequestTopic, n)
 		for i := 0; i < n; i++ {
-			topic, err := pd.getString()
+			var topic string
+			if version >= 2 {
+				topic, err = pd.getCompactString()
+			} else {
+				topic, err = pd.getString()
+			}
 			if err != nil {
 				return err
 			}
@@ -66,6 +89,12 @@ func (d *DeleteRecordsRequest) decode(pd packetDecoder, version int16) error {
 	}
 	d.Timeout = time.Duration(timeout) * time.Millisecond
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -74,23 +103,35 @@ func (d *DeleteRecordsRequest) key() int16 {
 }
 
 func (d *DeleteRecordsRequest) version() int16 {
-	return 0
+	return d.Version
 }
 
 func (d *DeleteRecordsRequest) headerVersion() int16 {
+	if d.Version >= 2 {
+		return 2
+	}
 	return 1
 }
 
 func (d *DeleteRecordsRequest) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch d.Version {
+	case 2:
+		return V2_4_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
 }
 
 type DeleteRecordsRequestTopic struct {
 	PartitionOffsets map[int32]int64 // partition => offset
 }
 
-func (t *DeleteRecordsRequestTopic) encode(pe packetEncoder) error {
-	if err := pe.putArrayLength(len(t.PartitionOffsets)); err != nil {
+func (t *DeleteRecordsRequestTopic) encode(pe packetEncoder, version int16) error {
+	if version >= 2 {
+		pe.putCompactArrayLength(len(t.PartitionOffsets))
+	} else if err := pe.putArrayLength(len(t.PartitionOffsets)); err != nil {
 		return err
 	}
 	keys := make([]int32, 0, len(t.PartitionOffsets))
@@ -101,12 +142,23 @@ func (t *DeleteRecordsRequestTopic) encode(pe packetEncoder) error {
 	for _, partition := range keys {
 		pe.putInt32(partition)
 		pe.putInt64(t.PartitionOffsets[partition])
+		if version >= 2 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
 	}
 	return nil
 }
 
-func (t *DeleteRecordsRequestTopic) decode(pd packetDecoder, version int16) error {
-	n, err := pd.getArrayLength()
+func (t *DeleteRecordsRequestTopic) decode(pd packetDecoder, version int16) (err error) {
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -123,6 +175,17 @@ func (t *DeleteRecordsRequestTopic) decode(pd packetDecoder, version int16) erro
 				return err
 			}
 			t.PartitionOffsets[partition] = offset
+			if version >= 2 {
+				if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
 		}
 	}
 
@@ -18,6 +18,24 @@ var deleteRecordsRequest = []byte{
 	0, 0, 0, 100,
 }
 
+var deleteRecordsRequestV2 = []byte{
+	3,
+	6, 'o', 't', 'h', 'e', 'r',
+	1,
+	0,
+	6, 't', 'o', 'p', 'i', 'c',
+	3,
+	0, 0, 0, 19,
+	0, 0, 0, 0, 0, 0, 0, 200,
+	0,
+	0, 0, 0, 20,
+	0, 0, 0, 0, 0, 0, 0, 190,
+	0,
+	0,
+	0, 0, 0, 100,
+	0,
+}
+
 func TestDeleteRecordsRequest(t *testing.T) {
 	req := &DeleteRecordsRequest{
 		Topics: map[string]*DeleteRecordsRequestTopic{
@@ -33,4 +51,7 @@ func TestDeleteRecordsRequest(t *testing.T) {
 	}
 
 	testRequest(t, "", req, deleteRecordsRequest)
+
+	req.Version = 2
+	testRequest(t, "v2", req, deleteRecordsRequestV2)
 }
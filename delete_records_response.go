@@ -21,7 +21,9 @@ type DeleteRecordsResponse struct {
 func (d *DeleteRecordsResponse) encode(pe packetEncoder) error {
 	pe.putInt32(int32(d.ThrottleTime / time.Millisecond))
 
-	if err := pe.putArrayLength(len(d.Topics)); err != nil {
+	if d.Version >= 2 {
+		pe.putCompactArrayLength(len(d.Topics))
+	} else if err := pe.putArrayLength(len(d.Topics)); err != nil {
 		return err
 	}
 	keys := make([]string, 0, len(d.Topics))
@@ -30,17 +32,26 @@ func (d *DeleteRecordsResponse) encode(pe packetEncoder) error {
 	}
 	sort.Strings(keys)
 	for _, topic := range keys {
-		if err := pe.putString(topic); err != nil {
+		if d.Version >= 2 {
+			if err := pe.putCompactString(topic); err != nil {
+				return err
+			}
+		} else if err := pe.putString(topic); err != nil {
 			return err
 		}
-		if err := d.Topics[topic].encode(pe); err != nil {
+		if err := d.Topics[topic].encode(pe, d.Version); err != nil {
 			return err
 		}
 	}
+
+	if d.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (d *DeleteRecordsResponse) decode(pd packetDecoder, version int16) error {
+func (d *DeleteRecordsResponse) decode(pd packetDecoder, version int16) (err error) {
 	d.Version = version
 
 	throttleTime, err := pd.getInt32()
@@ -49,7 +60,12 @@ func (d *DeleteRecordsResponse) decode(pd packetDecoder, version int16) error {
 	}
 	d.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -57,7 +73,12 @@ func (d *DeleteRecordsResponse) decode(pd packetDecoder, version int16) error {
 	if n > 0 {
 		d.Topics = make(map[string]*DeleteRecordsResponseTopic, n)
 		for i := 0; i < n; i++ {
-			topic, err := pd.getString()
+			var topic string
+			if version >= 2 {
+				topic, err = pd.getCompactString()
+			} else {
+				topic, err = pd.getString()
+			}
 			if err != nil {
 				return err
 			}
@@ -69,6 +90,12 @@ func (d *DeleteRecordsResponse) decode(pd packetDecoder, version int16) error {
 		}
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -77,23 +104,35 @@ func (d *DeleteRecordsResponse) key() int16 {
 }
 
 func (d *DeleteRecordsResponse) version() int16 {
-	return 0
+	return d.Version
 }
 
 func (d *DeleteRecordsResponse) headerVersion() int16 {
+	if d.Version >= 2 {
+		return 1
+	}
 	return 0
 }
 
 func (d *DeleteRecordsResponse) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch d.Version {
+	case 2:
+		return V2_4_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
 }
 
 type DeleteRecordsResponseTopic struct {
 	Partitions map[int32]*DeleteRecordsResponsePartition
 }
 
-func (t *DeleteRecordsResponseTopic) encode(pe packetEncoder) error {
-	if err := pe.putArrayLength(len(t.Partitions)); err != nil {
+func (t *DeleteRecordsResponseTopic) encode(pe packetEncoder, version int16) error {
+	if version >= 2 {
+		pe.putCompactArrayLength(len(t.Partitions))
+	} else if err := pe.putArrayLength(len(t.Partitions)); err != nil {
 		return err
 	}
 	keys := make([]int32, 0, len(t.Partitions))
@@ -103,15 +142,23 @@ func (t *DeleteRecordsResponseTopic) encode(pe packetEncoder) error {
 	sort.Slice(keys, func(i, j int) bool { return keys[i] < keys[j] })
 	for _, partition := range keys {
 		pe.putInt32(partition)
-		if err := t.Partitions[partition].encode(pe); err != nil {
+		if err := t.Partitions[partition].encode(pe, version); err != nil {
 			return err
 		}
 	}
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
-func (t *DeleteRecordsResponseTopic) decode(pd packetDecoder, version int16) error {
-	n, err := pd.getArrayLength()
+func (t *DeleteRecordsResponseTopic) decode(pd packetDecoder, version int16) (err error) {
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -131,6 +178,12 @@ func (t *DeleteRecordsResponseTopic) decode(pd packetDecoder, version int16) err
 		}
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -139,9 +192,12 @@ type DeleteRecordsResponsePartition struct {
 	Err          KError
 }
 
-func (t *DeleteRecordsResponsePartition) encode(pe packetEncoder) error {
+func (t *DeleteRecordsResponsePartition) encode(pe packetEncoder, version int16) error {
 	pe.putInt64(t.LowWatermark)
 	pe.putInt16(int16(t.Err))
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
@@ -158,5 +214,19 @@ func (t *DeleteRecordsResponsePartition) decode(pd packetDecoder, version int16)
 	}
 	t.Err = KError(kErr)
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+func (d *DeleteRecordsResponse) throttleTime() time.Duration {
+	return d.ThrottleTime
+}
+
+func (d *DeleteRecordsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
+}
@@ -20,6 +20,26 @@ var deleteRecordsResponse = []byte{
 	0, 3,
 }
 
+var deleteRecordsResponseV2 = []byte{
+	0, 0, 0, 100,
+	3,
+	6, 'o', 't', 'h', 'e', 'r',
+	1,
+	0,
+	6, 't', 'o', 'p', 'i', 'c',
+	3,
+	0, 0, 0, 19,
+	0, 0, 0, 0, 0, 0, 0, 200,
+	0, 0,
+	0,
+	0, 0, 0, 20,
+	255, 255, 255, 255, 255, 255, 255, 255,
+	0, 3,
+	0,
+	0,
+	0,
+}
+
 func TestDeleteRecordsResponse(t *testing.T) {
 	resp := &DeleteRecordsResponse{
 		Version:      0,
@@ -36,4 +56,7 @@ func TestDeleteRecordsResponse(t *testing.T) {
 	}
 
 	testResponse(t, "", resp, deleteRecordsResponse)
+
+	resp.Version = 2
+	testResponse(t, "v2", resp, deleteRecordsResponseV2)
 }
@@ -8,17 +8,31 @@ type DeleteTopicsRequest struct {
 	Timeout time.Duration
 }
 
-func (d *DeleteTopicsRequest) encode(pe packetEncoder) error {
-	if err := pe.putStringArray(d.Topics); err != nil {
+func (d *DeleteTopicsRequest) encode(pe packetEncoder) (err error) {
+	if d.Version >= 4 {
+		err = pe.putCompactStringArray(d.Topics)
+	} else {
+		err = pe.putStringArray(d.Topics)
+	}
+	if err != nil {
 		return err
 	}
 	pe.putInt32(int32(d.Timeout / time.Millisecond))
 
+	if d.Version >= 4 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (d *DeleteTopicsRequest) decode(pd packetDecoder, version int16) (err error) {
-	if d.Topics, err = pd.getStringArray(); err != nil {
+	if version >= 4 {
+		d.Topics, err = pd.getCompactStringArray()
+	} else {
+		d.Topics, err = pd.getStringArray()
+	}
+	if err != nil {
 		return err
 	}
 	timeout, err := pd.getInt32()
@@ -26,6 +40,11 @@ func (d *DeleteTopicsRequest) decode(pd packetDecoder, version int16) (err error
 		return err
 	}
 	d.Timeout = time.Duration(timeout) * time.Millisecond
+	if version >= 4 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 	d.Version = version
 	return nil
 }
@@ -39,11 +58,20 @@ func (d *DeleteTopicsRequest) version() int16 {
 }
 
 func (d *DeleteTopicsRequest) headerVersion() int16 {
+	if d.Version >= 4 {
+		return 2
+	}
 	return 1
 }
 
 func (d *DeleteTopicsRequest) requiredVersion() KafkaVersion {
 	switch d.Version {
+	case 4:
+		return V2_4_0_0
+	case 3:
+		return V2_3_0_0
+	case 2:
+		return V2_1_0_0
 	case 1:
 		return V0_11_0_0
 	default:
@@ -12,6 +12,14 @@ var deleteTopicsRequest = []byte{
 	0, 0, 0, 100,
 }
 
+var deleteTopicsRequestV4 = []byte{
+	3,
+	6, 't', 'o', 'p', 'i', 'c',
+	6, 'o', 't', 'h', 'e', 'r',
+	0, 0, 0, 100,
+	0,
+}
+
 func TestDeleteTopicsRequestV0(t *testing.T) {
 	req := &DeleteTopicsRequest{
 		Version: 0,
@@ -31,3 +39,13 @@ func TestDeleteTopicsRequestV1(t *testing.T) {
 
 	testRequest(t, "", req, deleteTopicsRequest)
 }
+
+func TestDeleteTopicsRequestV4(t *testing.T) {
+	req := &DeleteTopicsRequest{
+		Version: 4,
+		Topics:  []string{"topic", "other"},
+		Timeout: 100 * time.Millisecond,
+	}
+
+	testRequest(t, "", req, deleteTopicsRequestV4)
+}
@@ -13,14 +13,27 @@ func (d *DeleteTopicsResponse) encode(pe packetEncoder) error {
 		pe.putInt32(int32(d.ThrottleTime / time.Millisecond))
 	}
 
-	if err := pe.putArrayLength(len(d.TopicErrorCodes)); err != nil {
+	if d.Version >= 4 {
+		pe.putCompactArrayLength(len(d.TopicErrorCodes))
+	} else if err := pe.putArrayLength(len(d.TopicErrorCodes)); err != nil {
 		return err
 	}
 	for topic, errorCode := range d.TopicErrorCodes {
-		if err := pe.putString(topic); err != nil {
+		if d.Version >= 4 {
+			if err := pe.putCompactString(topic); err != nil {
+				return err
+			}
+		} else if err := pe.putString(topic); err != nil {
 			return err
 		}
 		pe.putInt16(int16(errorCode))
+		if d.Version >= 4 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if d.Version >= 4 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
@@ -37,7 +50,12 @@ func (d *DeleteTopicsResponse) decode(pd packetDecoder, version int16) (err erro
 		d.Version = version
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 4 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -45,7 +63,12 @@ func (d *DeleteTopicsResponse) decode(pd packetDecoder, version int16) (err erro
 	d.TopicErrorCodes = make(map[string]KError, n)
 
 	for i := 0; i < n; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if version >= 4 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
@@ -55,6 +78,18 @@ func (d *DeleteTopicsResponse) decode(pd packetDecoder, version int16) (err erro
 		}
 
 		d.TopicErrorCodes[topic] = KError(errorCode)
+
+		if version >= 4 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if version >= 4 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -69,14 +104,31 @@ func (d *DeleteTopicsResponse) version() int16 {
 }
 
 func (d *DeleteTopicsResponse) headerVersion() int16 {
+	if d.Version >= 4 {
+		return 1
+	}
 	return 0
 }
 
 func (d *DeleteTopicsResponse) requiredVersion() KafkaVersion {
 	switch d.Version {
+	case 4:
+		return V2_4_0_0
+	case 3:
+		return V2_3_0_0
+	case 2:
+		return V2_1_0_0
 	case 1:
 		return V0_11_0_0
 	default:
 		return V0_10_1_0
 	}
 }
+
+func (d *DeleteTopicsResponse) throttleTime() time.Duration {
+	return d.ThrottleTime
+}
+
+func (d *DeleteTopicsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 2
+}
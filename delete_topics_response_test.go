@@ -18,6 +18,15 @@ var (
 		0, 5, 't', 'o', 'p', 'i', 'c',
 		0, 0,
 	}
+
+	deleteTopicsResponseV4 = []byte{
+		0, 0, 0, 100,
+		2,
+		6, 't', 'o', 'p', 'i', 'c',
+		0, 0,
+		0,
+		0,
+	}
 )
 
 func TestDeleteTopicsResponse(t *testing.T) {
@@ -33,4 +42,8 @@ func TestDeleteTopicsResponse(t *testing.T) {
 	resp.ThrottleTime = 100 * time.Millisecond
 
 	testResponse(t, "version 1", resp, deleteTopicsResponseV1)
+
+	resp.Version = 4
+
+	testResponse(t, "version 4", resp, deleteTopicsResponseV4)
 }
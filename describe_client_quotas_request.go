@@ -11,6 +11,7 @@ package sarama
 // Components: the components to filter on
 // Strict: whether the filter only includes specified components
 type DescribeClientQuotasRequest struct {
+	Version    int16 // Version 1 is the first flexible version
 	Components []QuotaFilterComponent
 	Strict     bool
 }
@@ -27,11 +28,13 @@ type QuotaFilterComponent struct {
 
 func (d *DescribeClientQuotasRequest) encode(pe packetEncoder) error {
 	// Components
-	if err := pe.putArrayLength(len(d.Components)); err != nil {
+	if d.Version >= 1 {
+		pe.putCompactArrayLength(len(d.Components))
+	} else if err := pe.putArrayLength(len(d.Components)); err != nil {
 		return err
 	}
 	for _, c := range d.Components {
-		if err := c.encode(pe); err != nil {
+		if err := c.encode(pe, d.Version); err != nil {
 			return err
 		}
 	}
@@ -39,12 +42,23 @@ func (d *DescribeClientQuotasRequest) encode(pe packetEncoder) error {
 	// Strict
 	pe.putBool(d.Strict)
 
+	if d.Version >= 1 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (d *DescribeClientQuotasRequest) decode(pd packetDecoder, version int16) error {
+func (d *DescribeClientQuotasRequest) decode(pd packetDecoder, version int16) (err error) {
+	d.Version = version
+
 	// Components
-	componentCount, err := pd.getArrayLength()
+	var componentCount int
+	if version >= 1 {
+		componentCount, err = pd.getCompactArrayLength()
+	} else {
+		componentCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -68,12 +82,22 @@ func (d *DescribeClientQuotasRequest) decode(pd packetDecoder, version int16) er
 	}
 	d.Strict = strict
 
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (d *QuotaFilterComponent) encode(pe packetEncoder) error {
+func (d *QuotaFilterComponent) encode(pe packetEncoder, version int16) error {
 	// EntityType
-	if err := pe.putString(string(d.EntityType)); err != nil {
+	if version >= 1 {
+		if err := pe.putCompactString(string(d.EntityType)); err != nil {
+			return err
+		}
+	} else if err := pe.putString(string(d.EntityType)); err != nil {
 		return err
 	}
 
@@ -81,26 +105,32 @@ func (d *QuotaFilterComponent) encode(pe packetEncoder) error {
 	pe.putInt8(int8(d.MatchType))
 
 	// Match
-	if d.MatchType == QuotaMatchAny {
-		if err := pe.putNullableString(nil); err != nil {
-			return err
-		}
-	} else if d.MatchType == QuotaMatchDefault {
-		if err := pe.putString(""); err != nil {
-			return err
-		}
-	} else {
-		if err := pe.putString(d.Match); err != nil {
+	var match *string
+	if d.MatchType == QuotaMatchDefault {
+		match = new(string)
+	} else if d.MatchType != QuotaMatchAny {
+		match = &d.Match
+	}
+	if version >= 1 {
+		if err := pe.putNullableCompactString(match); err != nil {
 			return err
 		}
+		pe.putEmptyTaggedFieldArray()
+	} else if err := pe.putNullableString(match); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-func (d *QuotaFilterComponent) decode(pd packetDecoder, version int16) error {
+func (d *QuotaFilterComponent) decode(pd packetDecoder, version int16) (err error) {
 	// EntityType
-	entityType, err := pd.getString()
+	var entityType string
+	if version >= 1 {
+		entityType, err = pd.getCompactString()
+	} else {
+		entityType, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
@@ -114,13 +144,24 @@ func (d *QuotaFilterComponent) decode(pd packetDecoder, version int16) error {
 	d.MatchType = QuotaMatchType(matchType)
 
 	// Match
-	match, err := pd.getNullableString()
+	var match *string
+	if version >= 1 {
+		match, err = pd.getCompactNullableString()
+	} else {
+		match, err = pd.getNullableString()
+	}
 	if err != nil {
 		return err
 	}
 	if match != nil {
 		d.Match = *match
 	}
+
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -129,13 +170,21 @@ func (d *DescribeClientQuotasRequest) key() int16 {
 }
 
 func (d *DescribeClientQuotasRequest) version() int16 {
-	return 0
+	return d.Version
 }
 
 func (d *DescribeClientQuotasRequest) headerVersion() int16 {
+	if d.Version >= 1 {
+		return 2
+	}
 	return 1
 }
 
 func (d *DescribeClientQuotasRequest) requiredVersion() KafkaVersion {
-	return V2_6_0_0
+	switch d.Version {
+	case 1:
+		return V2_8_0_0
+	default:
+		return V2_6_0_0
+	}
 }
@@ -34,6 +34,20 @@ var (
 		0, 0, // match *string
 		0, // strict
 	}
+
+	describeClientQuotasRequestMultiComponentsV1 = []byte{
+		3,                     // components len (compact)
+		5, 'u', 's', 'e', 'r', // entity type
+		2,                                               // match type (any)
+		0,                                               // match *string (null)
+		0,                                               // empty tagged fields
+		10, 'c', 'l', 'i', 'e', 'n', 't', '-', 'i', 'd', // entity type
+		1, // match type (default)
+		1, // match *string
+		0, // empty tagged fields
+		0, // strict
+		0, // empty tagged fields
+	}
 )
 
 func TestDescribeClientQuotasRequest(t *testing.T) {
@@ -81,4 +95,7 @@ func TestDescribeClientQuotasRequest(t *testing.T) {
 		Strict:     false,
 	}
 	testRequest(t, "Match default client-id of any user", req, describeClientQuotasRequestMultiComponents)
+
+	req.Version = 1
+	testRequest(t, "Match default client-id of any user v1", req, describeClientQuotasRequestMultiComponentsV1)
 }
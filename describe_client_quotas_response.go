@@ -17,6 +17,7 @@ import (
 //       value => FLOAT64
 
 type DescribeClientQuotasResponse struct {
+	Version      int16                       // Version 1 is the first flexible version
 	ThrottleTime time.Duration               // The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
 	ErrorCode    KError                      // The error code, or `0` if the quota description succeeded.
 	ErrorMsg     *string                     // The error message, or `null` if the quota description succeeded.
@@ -42,24 +43,36 @@ func (d *DescribeClientQuotasResponse) encode(pe packetEncoder) error {
 	pe.putInt16(int16(d.ErrorCode))
 
 	// ErrorMsg
-	if err := pe.putNullableString(d.ErrorMsg); err != nil {
+	if d.Version >= 1 {
+		if err := pe.putNullableCompactString(d.ErrorMsg); err != nil {
+			return err
+		}
+	} else if err := pe.putNullableString(d.ErrorMsg); err != nil {
 		return err
 	}
 
 	// Entries
-	if err := pe.putArrayLength(len(d.Entries)); err != nil {
+	if d.Version >= 1 {
+		pe.putCompactArrayLength(len(d.Entries))
+	} else if err := pe.putArrayLength(len(d.Entries)); err != nil {
 		return err
 	}
 	for _, e := range d.Entries {
-		if err := e.encode(pe); err != nil {
+		if err := e.encode(pe, d.Version); err != nil {
 			return err
 		}
 	}
 
+	if d.Version >= 1 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (d *DescribeClientQuotasResponse) decode(pd packetDecoder, version int16) error {
+	d.Version = version
+
 	// ThrottleTime
 	throttleTime, err := pd.getInt32()
 	if err != nil {
@@ -75,14 +88,22 @@ func (d *DescribeClientQuotasResponse) decode(pd packetDecoder, version int16) e
 	d.ErrorCode = KError(errCode)
 
 	// ErrorMsg
-	errMsg, err := pd.getNullableString()
+	if version >= 1 {
+		d.ErrorMsg, err = pd.getCompactNullableString()
+	} else {
+		d.ErrorMsg, err = pd.getNullableString()
+	}
 	if err != nil {
 		return err
 	}
-	d.ErrorMsg = errMsg
 
 	// Entries
-	entryCount, err := pd.getArrayLength()
+	var entryCount int
+	if version >= 1 {
+		entryCount, err = pd.getCompactArrayLength()
+	} else {
+		entryCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -99,39 +120,65 @@ func (d *DescribeClientQuotasResponse) decode(pd packetDecoder, version int16) e
 		d.Entries = []DescribeClientQuotasEntry{}
 	}
 
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (d *DescribeClientQuotasEntry) encode(pe packetEncoder) error {
+func (d *DescribeClientQuotasEntry) encode(pe packetEncoder, version int16) error {
 	// Entity
-	if err := pe.putArrayLength(len(d.Entity)); err != nil {
+	if version >= 1 {
+		pe.putCompactArrayLength(len(d.Entity))
+	} else if err := pe.putArrayLength(len(d.Entity)); err != nil {
 		return err
 	}
 	for _, e := range d.Entity {
-		if err := e.encode(pe); err != nil {
+		if err := e.encode(pe, version); err != nil {
 			return err
 		}
 	}
 
 	// Values
-	if err := pe.putArrayLength(len(d.Values)); err != nil {
+	if version >= 1 {
+		pe.putCompactArrayLength(len(d.Values))
+	} else if err := pe.putArrayLength(len(d.Values)); err != nil {
 		return err
 	}
 	for key, value := range d.Values {
 		// key
-		if err := pe.putString(key); err != nil {
+		if version >= 1 {
+			if err := pe.putCompactString(key); err != nil {
+				return err
+			}
+		} else if err := pe.putString(key); err != nil {
 			return err
 		}
 		// value
 		pe.putFloat64(value)
+		if version >= 1 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if version >= 1 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
 }
 
-func (d *DescribeClientQuotasEntry) decode(pd packetDecoder, version int16) error {
+func (d *DescribeClientQuotasEntry) decode(pd packetDecoder, version int16) (err error) {
 	// Entity
-	componentCount, err := pd.getArrayLength()
+	var componentCount int
+	if version >= 1 {
+		componentCount, err = pd.getCompactArrayLength()
+	} else {
+		componentCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -149,7 +196,12 @@ func (d *DescribeClientQuotasEntry) decode(pd packetDecoder, version int16) erro
 	}
 
 	// Values
-	valueCount, err := pd.getArrayLength()
+	var valueCount int
+	if version >= 1 {
+		valueCount, err = pd.getCompactArrayLength()
+	} else {
+		valueCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -157,7 +209,12 @@ func (d *DescribeClientQuotasEntry) decode(pd packetDecoder, version int16) erro
 		d.Values = make(map[string]float64, valueCount)
 		for i := 0; i < valueCount; i++ {
 			// key
-			key, err := pd.getString()
+			var key string
+			if version >= 1 {
+				key, err = pd.getCompactString()
+			} else {
+				key, err = pd.getString()
+			}
 			if err != nil {
 				return err
 			}
@@ -166,44 +223,72 @@ func (d *DescribeClientQuotasEntry) decode(pd packetDecoder, version int16) erro
 			if err != nil {
 				return err
 			}
+			if version >= 1 {
+				if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+					return err
+				}
+			}
 			d.Values[key] = value
 		}
 	} else {
 		d.Values = map[string]float64{}
 	}
 
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (c *QuotaEntityComponent) encode(pe packetEncoder) error {
+func (c *QuotaEntityComponent) encode(pe packetEncoder, version int16) error {
 	// entity_type
-	if err := pe.putString(string(c.EntityType)); err != nil {
+	if version >= 1 {
+		if err := pe.putCompactString(string(c.EntityType)); err != nil {
+			return err
+		}
+	} else if err := pe.putString(string(c.EntityType)); err != nil {
 		return err
 	}
 	// entity_name
-	if c.MatchType == QuotaMatchDefault {
-		if err := pe.putNullableString(nil); err != nil {
-			return err
-		}
-	} else {
-		if err := pe.putString(c.Name); err != nil {
+	var name *string
+	if c.MatchType != QuotaMatchDefault {
+		name = &c.Name
+	}
+	if version >= 1 {
+		if err := pe.putNullableCompactString(name); err != nil {
 			return err
 		}
+		pe.putEmptyTaggedFieldArray()
+	} else if err := pe.putNullableString(name); err != nil {
+		return err
 	}
 
 	return nil
 }
 
-func (c *QuotaEntityComponent) decode(pd packetDecoder, version int16) error {
+func (c *QuotaEntityComponent) decode(pd packetDecoder, version int16) (err error) {
 	// entity_type
-	entityType, err := pd.getString()
+	var entityType string
+	if version >= 1 {
+		entityType, err = pd.getCompactString()
+	} else {
+		entityType, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
 	c.EntityType = QuotaEntityType(entityType)
 
 	// entity_name
-	entityName, err := pd.getNullableString()
+	var entityName *string
+	if version >= 1 {
+		entityName, err = pd.getCompactNullableString()
+	} else {
+		entityName, err = pd.getNullableString()
+	}
 	if err != nil {
 		return err
 	}
@@ -215,6 +300,12 @@ func (c *QuotaEntityComponent) decode(pd packetDecoder, version int16) error {
 		c.Name = *entityName
 	}
 
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -223,13 +314,29 @@ func (d *DescribeClientQuotasResponse) key() int16 {
 }
 
 func (d *DescribeClientQuotasResponse) version() int16 {
-	return 0
+	return d.Version
 }
 
 func (d *DescribeClientQuotasResponse) headerVersion() int16 {
+	if d.Version >= 1 {
+		return 1
+	}
 	return 0
 }
 
 func (d *DescribeClientQuotasResponse) requiredVersion() KafkaVersion {
-	return V2_6_0_0
+	switch d.Version {
+	case 1:
+		return V2_8_0_0
+	default:
+		return V2_6_0_0
+	}
+}
+
+func (d *DescribeClientQuotasResponse) throttleTime() time.Duration {
+	return d.ThrottleTime
+}
+
+func (d *DescribeClientQuotasResponse) shouldClientThrottle(version int16) bool {
+	return true
 }
@@ -41,6 +41,23 @@ var (
 		0, 18, 'c', 'o', 'n', 's', 'u', 'm', 'e', 'r', '_', 'b', 'y', 't', 'e', '_', 'r', 'a', 't', 'e',
 		65, 46, 132, 128, 0, 0, 0, 0, // 1000000
 	}
+
+	describeClientQuotasResponseSingleValueV1 = []byte{
+		0, 0, 0, 0, // ThrottleTime
+		0, 0, // ErrorCode
+		0,                     // ErrorMsg (nil)
+		2,                     // Entries
+		2,                     // Entity
+		5, 'u', 's', 'e', 'r', // Entity type
+		0, // Entity name (nil)
+		0, // empty tagged fields
+		2, // Values
+		19, 'p', 'r', 'o', 'd', 'u', 'c', 'e', 'r', '_', 'b', 'y', 't', 'e', '_', 'r', 'a', 't', 'e',
+		65, 46, 132, 128, 0, 0, 0, 0, // 1000000
+		0, // empty value tagged fields
+		0, // empty entry tagged fields
+		0, // empty tagged fields
+	}
 )
 
 func TestDescribeClientQuotasResponse(t *testing.T) {
@@ -71,6 +88,9 @@ func TestDescribeClientQuotasResponse(t *testing.T) {
 	}
 	testResponse(t, "Single Value", res, describeClientQuotasResponseSingleValue)
 
+	res.Version = 1
+	testResponse(t, "Single Value v1", res, describeClientQuotasResponseSingleValueV1)
+
 	// Complex Quota entry
 	saramaClientIDComponent := QuotaEntityComponent{
 		EntityType: QuotaEntityClientID,
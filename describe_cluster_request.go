@@ -0,0 +1,74 @@
+package sarama
+
+// DescribeClusterEndpointType selects the endpoints a DescribeClusterRequest
+// describes, see KIP-919.
+type DescribeClusterEndpointType int8
+
+const (
+	// DescribeClusterBrokers describes the brokers of the cluster.
+	DescribeClusterBrokers DescribeClusterEndpointType = 1
+	// DescribeClusterControllers describes the KRaft controllers of the
+	// cluster, and is only answered by the controllers themselves.
+	DescribeClusterControllers DescribeClusterEndpointType = 2
+)
+
+// DescribeClusterRequest describes the nodes of the cluster, either its
+// brokers or, from version 1, its KRaft controllers.
+type DescribeClusterRequest struct {
+	Version                            int16
+	IncludeClusterAuthorizedOperations bool
+	// EndpointType is DescribeClusterBrokers unless set, and only sent from
+	// version 1.
+	EndpointType DescribeClusterEndpointType
+}
+
+func (r *DescribeClusterRequest) encode(pe packetEncoder) error {
+	pe.putBool(r.IncludeClusterAuthorizedOperations)
+	if r.Version >= 1 {
+		endpointType := r.EndpointType
+		if endpointType == 0 {
+			endpointType = DescribeClusterBrokers
+		}
+		pe.putInt8(int8(endpointType))
+	}
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *DescribeClusterRequest) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if r.IncludeClusterAuthorizedOperations, err = pd.getBool(); err != nil {
+		return err
+	}
+	r.EndpointType = DescribeClusterBrokers
+	if r.Version >= 1 {
+		endpointType, err := pd.getInt8()
+		if err != nil {
+			return err
+		}
+		r.EndpointType = DescribeClusterEndpointType(endpointType)
+	}
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *DescribeClusterRequest) key() int16 {
+	return 60
+}
+
+func (r *DescribeClusterRequest) version() int16 {
+	return r.Version
+}
+
+func (r *DescribeClusterRequest) headerVersion() int16 {
+	return 2
+}
+
+func (r *DescribeClusterRequest) requiredVersion() KafkaVersion {
+	switch r.Version {
+	case 1:
+		return V3_7_0_0
+	default:
+		return V2_8_0_0
+	}
+}
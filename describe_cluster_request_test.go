@@ -0,0 +1,30 @@
+package sarama
+
+import "testing"
+
+var (
+	describeClusterRequestV0 = []byte{
+		1, // include cluster authorized operations
+		0, // empty tagged fields
+	}
+
+	describeClusterRequestControllersV1 = []byte{
+		0, // do not include cluster authorized operations
+		2, // controllers
+		0, // empty tagged fields
+	}
+)
+
+func TestDescribeClusterRequest(t *testing.T) {
+	request := &DescribeClusterRequest{
+		IncludeClusterAuthorizedOperations: true,
+		EndpointType:                       DescribeClusterBrokers,
+	}
+	testRequest(t, "v0", request, describeClusterRequestV0)
+
+	request = &DescribeClusterRequest{
+		Version:      1,
+		EndpointType: DescribeClusterControllers,
+	}
+	testRequest(t, "v1 controllers", request, describeClusterRequestControllersV1)
+}
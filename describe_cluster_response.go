@@ -0,0 +1,170 @@
+package sarama
+
+import (
+	"net"
+	"strconv"
+	"time"
+)
+
+// DescribeClusterResponse describes the brokers or the KRaft controllers of
+// the cluster.
+type DescribeClusterResponse struct {
+	Version                     int16
+	ThrottleTimeMs              int32
+	Err                         KError
+	ErrorMessage                *string
+	EndpointType                DescribeClusterEndpointType // Version 1
+	ClusterID                   string
+	ControllerID                int32
+	Brokers                     []*DescribeClusterBroker
+	ClusterAuthorizedOperations int32
+}
+
+// DescribeClusterBroker is a broker, or a KRaft controller, described by a
+// DescribeClusterResponse.
+type DescribeClusterBroker struct {
+	BrokerID int32
+	Host     string
+	Port     int32
+	Rack     *string
+}
+
+// Broker returns a Broker, not yet connected, for the node.
+func (b *DescribeClusterBroker) Broker() *Broker {
+	broker := NewBroker(net.JoinHostPort(b.Host, strconv.Itoa(int(b.Port))))
+	broker.id = b.BrokerID
+	broker.rack = b.Rack
+	return broker
+}
+
+func (b *DescribeClusterBroker) encode(pe packetEncoder) error {
+	pe.putInt32(b.BrokerID)
+	if err := pe.putCompactString(b.Host); err != nil {
+		return err
+	}
+	pe.putInt32(b.Port)
+	if err := pe.putNullableCompactString(b.Rack); err != nil {
+		return err
+	}
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (b *DescribeClusterBroker) decode(pd packetDecoder) (err error) {
+	if b.BrokerID, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if b.Host, err = pd.getCompactString(); err != nil {
+		return err
+	}
+	if b.Port, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if b.Rack, err = pd.getCompactNullableString(); err != nil {
+		return err
+	}
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *DescribeClusterResponse) encode(pe packetEncoder) error {
+	pe.putInt32(r.ThrottleTimeMs)
+	pe.putInt16(int16(r.Err))
+	if err := pe.putNullableCompactString(r.ErrorMessage); err != nil {
+		return err
+	}
+	if r.Version >= 1 {
+		endpointType := r.EndpointType
+		if endpointType == 0 {
+			endpointType = DescribeClusterBrokers
+		}
+		pe.putInt8(int8(endpointType))
+	}
+	if err := pe.putCompactString(r.ClusterID); err != nil {
+		return err
+	}
+	pe.putInt32(r.ControllerID)
+	pe.putCompactArrayLength(len(r.Brokers))
+	for _, broker := range r.Brokers {
+		if err := broker.encode(pe); err != nil {
+			return err
+		}
+	}
+	pe.putInt32(r.ClusterAuthorizedOperations)
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *DescribeClusterResponse) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if r.ThrottleTimeMs, err = pd.getInt32(); err != nil {
+		return err
+	}
+	kerr, err := pd.getInt16()
+	if err != nil {
+		return err
+	}
+	r.Err = KError(kerr)
+	if r.ErrorMessage, err = pd.getCompactNullableString(); err != nil {
+		return err
+	}
+	r.EndpointType = DescribeClusterBrokers
+	if r.Version >= 1 {
+		endpointType, err := pd.getInt8()
+		if err != nil {
+			return err
+		}
+		r.EndpointType = DescribeClusterEndpointType(endpointType)
+	}
+	if r.ClusterID, err = pd.getCompactString(); err != nil {
+		return err
+	}
+	if r.ControllerID, err = pd.getInt32(); err != nil {
+		return err
+	}
+	n, err := pd.getCompactArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Brokers = make([]*DescribeClusterBroker, n)
+	for i := range r.Brokers {
+		r.Brokers[i] = new(DescribeClusterBroker)
+		if err = r.Brokers[i].decode(pd); err != nil {
+			return err
+		}
+	}
+	if r.ClusterAuthorizedOperations, err = pd.getInt32(); err != nil {
+		return err
+	}
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *DescribeClusterResponse) key() int16 {
+	return 60
+}
+
+func (r *DescribeClusterResponse) version() int16 {
+	return r.Version
+}
+
+func (r *DescribeClusterResponse) headerVersion() int16 {
+	return 1
+}
+
+func (r *DescribeClusterResponse) requiredVersion() KafkaVersion {
+	switch r.Version {
+	case 1:
+		return V3_7_0_0
+	default:
+		return V2_8_0_0
+	}
+}
+
+func (r *DescribeClusterResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTimeMs) * time.Millisecond
+}
+
+func (r *DescribeClusterResponse) shouldClientThrottle(version int16) bool {
+	return true
+}
@@ -0,0 +1,43 @@
+package sarama
+
+import "testing"
+
+var describeClusterResponseControllersV1 = []byte{
+	0, 0, 0, 0, // throttle time
+	0, 0, // no error
+	0,           // null error message
+	2,           // controllers
+	3, 'a', 'b', // cluster ID "ab"
+	0, 0, 0x0b, 0xb9, // controller 3001
+	2,                // 2-1=1 broker
+	0, 0, 0x0b, 0xb9, // broker 3001
+	2, 'h', // host "h"
+	0, 0, 0x23, 0x84, // port 9092
+	4, 'r', '-', '1', // rack "r-1"
+	0,             // empty tagged fields
+	0x80, 0, 0, 0, // cluster authorized operations not requested
+	0, // empty tagged fields
+}
+
+func TestDescribeClusterResponse(t *testing.T) {
+	response := &DescribeClusterResponse{
+		Version:      1,
+		Err:          ErrNoError,
+		EndpointType: DescribeClusterControllers,
+		ClusterID:    "ab",
+		ControllerID: 3001,
+		Brokers: []*DescribeClusterBroker{{
+			BrokerID: 3001,
+			Host:     "h",
+			Port:     9092,
+			Rack:     nullString("r-1"),
+		}},
+		ClusterAuthorizedOperations: -2147483648,
+	}
+	testResponse(t, "v1 controllers", response, describeClusterResponseControllersV1)
+
+	broker := response.Brokers[0].Broker()
+	if broker.ID() != 3001 || broker.Addr() != "h:9092" || broker.Rack() != "r-1" {
+		t.Errorf("unexpected broker #%d at %s in %s", broker.ID(), broker.Addr(), broker.Rack())
+	}
+}
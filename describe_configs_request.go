@@ -1,9 +1,10 @@
 package sarama
 
 type DescribeConfigsRequest struct {
-	Version         int16
-	Resources       []*ConfigResource
-	IncludeSynonyms bool
+	Version              int16
+	Resources            []*ConfigResource
+	IncludeSynonyms      bool
+	IncludeDocumentation bool // Version 3
 }
 
 type ConfigResource struct {
@@ -12,35 +13,68 @@ type ConfigResource struct {
 	ConfigNames []string
 }
 
-func (r *DescribeConfigsRequest) encode(pe packetEncoder) error {
-	if err := pe.putArrayLength(len(r.Resources)); err != nil {
+func (r *DescribeConfigsRequest) encode(pe packetEncoder) (err error) {
+	if r.Version >= 4 {
+		pe.putCompactArrayLength(len(r.Resources))
+	} else if err = pe.putArrayLength(len(r.Resources)); err != nil {
 		return err
 	}
 
 	for _, c := range r.Resources {
 		pe.putInt8(int8(c.Type))
-		if err := pe.putString(c.Name); err != nil {
+		if r.Version >= 4 {
+			err = pe.putCompactString(c.Name)
+		} else {
+			err = pe.putString(c.Name)
+		}
+		if err != nil {
 			return err
 		}
 
 		if len(c.ConfigNames) == 0 {
-			pe.putInt32(-1)
+			if r.Version >= 4 {
+				pe.putCompactArrayLength(-1)
+				pe.putEmptyTaggedFieldArray()
+			} else {
+				pe.putInt32(-1)
+			}
 			continue
 		}
-		if err := pe.putStringArray(c.ConfigNames); err != nil {
+		if r.Version >= 4 {
+			err = pe.putCompactStringArray(c.ConfigNames)
+		} else {
+			err = pe.putStringArray(c.ConfigNames)
+		}
+		if err != nil {
 			return err
 		}
+		if r.Version >= 4 {
+			pe.putEmptyTaggedFieldArray()
+		}
 	}
 
 	if r.Version >= 1 {
 		pe.putBool(r.IncludeSynonyms)
 	}
 
+	if r.Version >= 3 {
+		pe.putBool(r.IncludeDocumentation)
+	}
+
+	if r.Version >= 4 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (r *DescribeConfigsRequest) decode(pd packetDecoder, version int16) (err error) {
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 4 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -54,12 +88,27 @@ func (r *DescribeConfigsRequest) decode(pd packetDecoder, version int16) (err er
 			return err
 		}
 		r.Resources[i].Type = ConfigResourceType(t)
-		name, err := pd.getString()
+		var name string
+		if version >= 4 {
+			name, err = pd.getCompactString()
+		} else {
+			name, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
 		r.Resources[i].Name = name
 
+		if version >= 4 {
+			if r.Resources[i].ConfigNames, err = pd.getCompactStringArray(); err != nil {
+				return err
+			}
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+			continue
+		}
+
 		confLength, err := pd.getArrayLength()
 		if err != nil {
 			return err
@@ -88,6 +137,18 @@ func (r *DescribeConfigsRequest) decode(pd packetDecoder, version int16) (err er
 		r.IncludeSynonyms = b
 	}
 
+	if r.Version >= 3 {
+		if r.IncludeDocumentation, err = pd.getBool(); err != nil {
+			return err
+		}
+	}
+
+	if r.Version >= 4 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -100,6 +161,9 @@ func (r *DescribeConfigsRequest) version() int16 {
 }
 
 func (r *DescribeConfigsRequest) headerVersion() int16 {
+	if r.Version >= 4 {
+		return 2
+	}
 	return 1
 }
 
@@ -109,6 +173,10 @@ func (r *DescribeConfigsRequest) requiredVersion() KafkaVersion {
 		return V1_1_0_0
 	case 2:
 		return V2_0_0_0
+	case 3:
+		return V2_6_0_0
+	case 4:
+		return V2_8_0_0
 	default:
 		return V0_11_0_0
 	}
@@ -115,3 +115,41 @@ func TestDescribeConfigsRequestv1(t *testing.T) {
 
 	testRequest(t, "one topic, all configs", request, singleDescribeConfigsRequestAllConfigsv1)
 }
+
+var singleDescribeConfigsRequestv4 = []byte{
+	3,                // 2 resources
+	2,                // a topic
+	4, 'f', 'o', 'o', // topic name: foo
+	2,  // 1 config name
+	11, // 10 chars
+	's', 'e', 'g', 'm', 'e', 'n', 't', '.', 'm', 's',
+	0,                // empty tagged fields
+	2,                // a topic
+	4, 'b', 'a', 'r', // topic name: bar
+	0, // all configs
+	0, // empty tagged fields
+	1, // synonyms
+	1, // documentation
+	0, // empty tagged fields
+}
+
+func TestDescribeConfigsRequestv4(t *testing.T) {
+	request := &DescribeConfigsRequest{
+		Version: 4,
+		Resources: []*ConfigResource{
+			{
+				Type:        TopicResource,
+				Name:        "foo",
+				ConfigNames: []string{"segment.ms"},
+			},
+			{
+				Type: TopicResource,
+				Name: "bar",
+			},
+		},
+		IncludeSynonyms:      true,
+		IncludeDocumentation: true,
+	}
+
+	testRequest(t, "two topics, v4", request, singleDescribeConfigsRequestv4)
+}
@@ -34,6 +34,49 @@ const (
 	SourceDefault
 )
 
+// ConfigType is the type of the value of a configuration entry, returned
+// from version 3 of DescribeConfigsResponse.
+type ConfigType int8
+
+const (
+	ConfigTypeUnknown ConfigType = iota
+	ConfigTypeBoolean
+	ConfigTypeString
+	ConfigTypeInt
+	ConfigTypeShort
+	ConfigTypeLong
+	ConfigTypeDouble
+	ConfigTypeList
+	ConfigTypeClass
+	ConfigTypePassword
+)
+
+func (t ConfigType) String() string {
+	switch t {
+	case ConfigTypeUnknown:
+		return "Unknown"
+	case ConfigTypeBoolean:
+		return "Boolean"
+	case ConfigTypeString:
+		return "String"
+	case ConfigTypeInt:
+		return "Int"
+	case ConfigTypeShort:
+		return "Short"
+	case ConfigTypeLong:
+		return "Long"
+	case ConfigTypeDouble:
+		return "Double"
+	case ConfigTypeList:
+		return "List"
+	case ConfigTypeClass:
+		return "Class"
+	case ConfigTypePassword:
+		return "Password"
+	}
+	return fmt.Sprintf("Type Invalid: %d", int(t))
+}
+
 type DescribeConfigsResponse struct {
 	Version      int16
 	ThrottleTime time.Duration
@@ -49,13 +92,15 @@ type ResourceResponse struct {
 }
 
 type ConfigEntry struct {
-	Name      string
-	Value     string
-	ReadOnly  bool
-	Default   bool
-	Source    ConfigSource
-	Sensitive bool
-	Synonyms  []*ConfigSynonym
+	Name          string
+	Value         string
+	ReadOnly      bool
+	Default       bool
+	Source        ConfigSource
+	Sensitive     bool
+	Synonyms      []*ConfigSynonym
+	Type          ConfigType // Version 3
+	Documentation *string    // Version 3
 }
 
 type ConfigSynonym struct {
@@ -66,7 +111,9 @@ type ConfigSynonym struct {
 
 func (r *DescribeConfigsResponse) encode(pe packetEncoder) (err error) {
 	pe.putInt32(int32(r.ThrottleTime / time.Millisecond))
-	if err = pe.putArrayLength(len(r.Resources)); err != nil {
+	if r.Version >= 4 {
+		pe.putCompactArrayLength(len(r.Resources))
+	} else if err = pe.putArrayLength(len(r.Resources)); err != nil {
 		return err
 	}
 
@@ -76,6 +123,10 @@ func (r *DescribeConfigsResponse) encode(pe packetEncoder) (err error) {
 		}
 	}
 
+	if r.Version >= 4 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
@@ -87,7 +138,12 @@ func (r *DescribeConfigsResponse) decode(pd packetDecoder, version int16) (err e
 	}
 	r.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 4 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -101,6 +157,12 @@ func (r *DescribeConfigsResponse) decode(pd packetDecoder, version int16) (err e
 		r.Resources[i] = rr
 	}
 
+	if version >= 4 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -113,6 +175,9 @@ func (r *DescribeConfigsResponse) version() int16 {
 }
 
 func (r *DescribeConfigsResponse) headerVersion() int16 {
+	if r.Version >= 4 {
+		return 1
+	}
 	return 0
 }
 
@@ -122,6 +187,10 @@ func (r *DescribeConfigsResponse) requiredVersion() KafkaVersion {
 		return V1_0_0_0
 	case 2:
 		return V2_0_0_0
+	case 3:
+		return V2_6_0_0
+	case 4:
+		return V2_8_0_0
 	default:
 		return V0_11_0_0
 	}
@@ -130,17 +199,29 @@ func (r *DescribeConfigsResponse) requiredVersion() KafkaVersion {
 func (r *ResourceResponse) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt16(r.ErrorCode)
 
-	if err = pe.putString(r.ErrorMsg); err != nil {
+	if version >= 4 {
+		err = pe.putCompactString(r.ErrorMsg)
+	} else {
+		err = pe.putString(r.ErrorMsg)
+	}
+	if err != nil {
 		return err
 	}
 
 	pe.putInt8(int8(r.Type))
 
-	if err = pe.putString(r.Name); err != nil {
+	if version >= 4 {
+		err = pe.putCompactString(r.Name)
+	} else {
+		err = pe.putString(r.Name)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err = pe.putArrayLength(len(r.Configs)); err != nil {
+	if version >= 4 {
+		pe.putCompactArrayLength(len(r.Configs))
+	} else if err = pe.putArrayLength(len(r.Configs)); err != nil {
 		return err
 	}
 
@@ -149,6 +230,10 @@ func (r *ResourceResponse) encode(pe packetEncoder, version int16) (err error) {
 			return err
 		}
 	}
+
+	if version >= 4 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
@@ -159,11 +244,21 @@ func (r *ResourceResponse) decode(pd packetDecoder, version int16) (err error) {
 	}
 	r.ErrorCode = ec
 
-	em, err := pd.getString()
-	if err != nil {
-		return err
+	if version >= 4 {
+		em, err := pd.getCompactNullableString()
+		if err != nil {
+			return err
+		}
+		if em != nil {
+			r.ErrorMsg = *em
+		}
+	} else {
+		em, err := pd.getString()
+		if err != nil {
+			return err
+		}
+		r.ErrorMsg = em
 	}
-	r.ErrorMsg = em
 
 	t, err := pd.getInt8()
 	if err != nil {
@@ -171,13 +266,23 @@ func (r *ResourceResponse) decode(pd packetDecoder, version int16) (err error) {
 	}
 	r.Type = ConfigResourceType(t)
 
-	name, err := pd.getString()
+	var name string
+	if version >= 4 {
+		name, err = pd.getCompactString()
+	} else {
+		name, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
 	r.Name = name
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 4 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -190,15 +295,31 @@ func (r *ResourceResponse) decode(pd packetDecoder, version int16) (err error) {
 		}
 		r.Configs[i] = c
 	}
+
+	if version >= 4 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (r *ConfigEntry) encode(pe packetEncoder, version int16) (err error) {
-	if err = pe.putString(r.Name); err != nil {
+	if version >= 4 {
+		err = pe.putCompactString(r.Name)
+	} else {
+		err = pe.putString(r.Name)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err = pe.putString(r.Value); err != nil {
+	if version >= 4 {
+		err = pe.putCompactString(r.Value)
+	} else {
+		err = pe.putString(r.Value)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -211,7 +332,9 @@ func (r *ConfigEntry) encode(pe packetEncoder, version int16) (err error) {
 		pe.putInt8(int8(r.Source))
 		pe.putBool(r.Sensitive)
 
-		if err := pe.putArrayLength(len(r.Synonyms)); err != nil {
+		if version >= 4 {
+			pe.putCompactArrayLength(len(r.Synonyms))
+		} else if err := pe.putArrayLength(len(r.Synonyms)); err != nil {
 			return err
 		}
 		for _, c := range r.Synonyms {
@@ -221,6 +344,22 @@ func (r *ConfigEntry) encode(pe packetEncoder, version int16) (err error) {
 		}
 	}
 
+	if version >= 3 {
+		pe.putInt8(int8(r.Type))
+		if version >= 4 {
+			err = pe.putNullableCompactString(r.Documentation)
+		} else {
+			err = pe.putNullableString(r.Documentation)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if version >= 4 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
@@ -229,17 +368,32 @@ func (r *ConfigEntry) decode(pd packetDecoder, version int16) (err error) {
 	if version == 0 {
 		r.Source = SourceUnknown
 	}
-	name, err := pd.getString()
+	var name string
+	if version >= 4 {
+		name, err = pd.getCompactString()
+	} else {
+		name, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
 	r.Name = name
 
-	value, err := pd.getString()
-	if err != nil {
-		return err
+	if version >= 4 {
+		value, err := pd.getCompactNullableString()
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			r.Value = *value
+		}
+	} else {
+		value, err := pd.getString()
+		if err != nil {
+			return err
+		}
+		r.Value = value
 	}
-	r.Value = value
 
 	read, err := pd.getBool()
 	if err != nil {
@@ -272,7 +426,12 @@ func (r *ConfigEntry) decode(pd packetDecoder, version int16) (err error) {
 	r.Sensitive = sensitive
 
 	if version > 0 {
-		n, err := pd.getArrayLength()
+		var n int
+		if version >= 4 {
+			n, err = pd.getCompactArrayLength()
+		} else {
+			n, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -286,42 +445,106 @@ func (r *ConfigEntry) decode(pd packetDecoder, version int16) (err error) {
 			r.Synonyms[i] = s
 		}
 	}
+
+	if version >= 3 {
+		configType, err := pd.getInt8()
+		if err != nil {
+			return err
+		}
+		r.Type = ConfigType(configType)
+
+		if version >= 4 {
+			r.Documentation, err = pd.getCompactNullableString()
+		} else {
+			r.Documentation, err = pd.getNullableString()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if version >= 4 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
 func (c *ConfigSynonym) encode(pe packetEncoder, version int16) (err error) {
-	err = pe.putString(c.ConfigName)
+	if version >= 4 {
+		err = pe.putCompactString(c.ConfigName)
+	} else {
+		err = pe.putString(c.ConfigName)
+	}
 	if err != nil {
 		return err
 	}
 
-	err = pe.putString(c.ConfigValue)
+	if version >= 4 {
+		err = pe.putCompactString(c.ConfigValue)
+	} else {
+		err = pe.putString(c.ConfigValue)
+	}
 	if err != nil {
 		return err
 	}
 
 	pe.putInt8(int8(c.Source))
 
+	if version >= 4 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (c *ConfigSynonym) decode(pd packetDecoder, version int16) error {
-	name, err := pd.getString()
+func (c *ConfigSynonym) decode(pd packetDecoder, version int16) (err error) {
+	var name string
+	if version >= 4 {
+		name, err = pd.getCompactString()
+	} else {
+		name, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
 	c.ConfigName = name
 
-	value, err := pd.getString()
-	if err != nil {
-		return err
+	if version >= 4 {
+		value, err := pd.getCompactNullableString()
+		if err != nil {
+			return err
+		}
+		if value != nil {
+			c.ConfigValue = *value
+		}
+	} else {
+		value, err := pd.getString()
+		if err != nil {
+			return err
+		}
+		c.ConfigValue = value
 	}
-	c.ConfigValue = value
 
 	source, err := pd.getInt8()
 	if err != nil {
 		return err
 	}
 	c.Source = ConfigSource(source)
+
+	if version >= 4 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
+
+func (r *DescribeConfigsResponse) throttleTime() time.Duration {
+	return r.ThrottleTime
+}
+
+func (r *DescribeConfigsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 2
+}
@@ -273,3 +273,58 @@ func TestDescribeConfigsResponseWithDefaultv1(t *testing.T) {
 	}
 	testResponse(t, "response with error", response, describeConfigsResponseWithDefaultv1)
 }
+
+var describeConfigsResponseWithDocumentationv4 = []byte{
+	0, 0, 0, 0, // throttle
+	2,    // response
+	0, 0, // errorcode
+	1, // string
+	2, // topic
+	4, 'f', 'o', 'o',
+	2, // configs
+	11, 's', 'e', 'g', 'm', 'e', 'n', 't', '.', 'm', 's',
+	5, '1', '0', '0', '0',
+	0, // ReadOnly
+	4, // Source
+	0, // Sensitive
+	2, // 1 Synonym
+	15, 'l', 'o', 'g', '.', 's', 'e', 'g', 'm', 'e', 'n', 't', '.', 'm', 's',
+	5, '1', '0', '0', '0',
+	4, // Source
+	0, // empty tagged fields
+	5, // Long
+	5, 'd', 'o', 'c', 's',
+	0, // empty tagged fields
+	0, // empty tagged fields
+	0, // empty tagged fields
+}
+
+func TestDescribeConfigsResponseWithDocumentationv4(t *testing.T) {
+	docs := "docs"
+	response := &DescribeConfigsResponse{
+		Version: 4,
+		Resources: []*ResourceResponse{
+			{
+				Type: TopicResource,
+				Name: "foo",
+				Configs: []*ConfigEntry{
+					{
+						Name:   "segment.ms",
+						Value:  "1000",
+						Source: SourceStaticBroker,
+						Synonyms: []*ConfigSynonym{
+							{
+								ConfigName:  "log.segment.ms",
+								ConfigValue: "1000",
+								Source:      SourceStaticBroker,
+							},
+						},
+						Type:          ConfigTypeLong,
+						Documentation: &docs,
+					},
+				},
+			},
+		},
+	}
+	testResponse(t, "response with documentation", response, describeConfigsResponseWithDocumentationv4)
+}
@@ -1,15 +1,47 @@
 package sarama
 
 type DescribeGroupsRequest struct {
-	Groups []string
+	Version                     int16
+	Groups                      []string
+	IncludeAuthorizedOperations bool // Version 3
 }
 
-func (r *DescribeGroupsRequest) encode(pe packetEncoder) error {
-	return pe.putStringArray(r.Groups)
+func (r *DescribeGroupsRequest) encode(pe packetEncoder) (err error) {
+	if r.Version >= 5 {
+		err = pe.putCompactStringArray(r.Groups)
+	} else {
+		err = pe.putStringArray(r.Groups)
+	}
+	if err != nil {
+		return err
+	}
+	if r.Version >= 3 {
+		pe.putBool(r.IncludeAuthorizedOperations)
+	}
+	if r.Version >= 5 {
+		pe.putEmptyTaggedFieldArray()
+	}
+	return nil
 }
 
 func (r *DescribeGroupsRequest) decode(pd packetDecoder, version int16) (err error) {
-	r.Groups, err = pd.getStringArray()
+	r.Version = version
+	if version >= 5 {
+		r.Groups, err = pd.getCompactStringArray()
+	} else {
+		r.Groups, err = pd.getStringArray()
+	}
+	if err != nil {
+		return
+	}
+	if version >= 3 {
+		if r.IncludeAuthorizedOperations, err = pd.getBool(); err != nil {
+			return
+		}
+	}
+	if version >= 5 {
+		_, err = pd.getEmptyTaggedFieldArray()
+	}
 	return
 }
 
@@ -18,15 +50,29 @@ func (r *DescribeGroupsRequest) key() int16 {
 }
 
 func (r *DescribeGroupsRequest) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *DescribeGroupsRequest) headerVersion() int16 {
+	if r.Version >= 5 {
+		return 2
+	}
 	return 1
 }
 
 func (r *DescribeGroupsRequest) requiredVersion() KafkaVersion {
-	return V0_9_0_0
+	switch r.Version {
+	case 5, 4:
+		return V2_4_0_0
+	case 3:
+		return V2_3_0_0
+	case 2:
+		return V2_0_0_0
+	case 1:
+		return V0_11_0_0
+	default:
+		return V0_9_0_0
+	}
 }
 
 func (r *DescribeGroupsRequest) AddGroup(group string) {
@@ -32,3 +32,16 @@ func TestDescribeGroupsRequest(t *testing.T) {
 	request.AddGroup("bar")
 	testRequest(t, "two groups", request, doubleDescribeGroupsRequest)
 }
+
+var describeGroupsRequestV5 = []byte{
+	2,                // 1 group
+	4, 'f', 'o', 'o', // group name: foo
+	1, // include authorized operations
+	0, // tagged fields
+}
+
+func TestDescribeGroupsRequestV5(t *testing.T) {
+	request := &DescribeGroupsRequest{Version: 5, IncludeAuthorizedOperations: true}
+	request.AddGroup("foo")
+	testRequest(t, "v5", request, describeGroupsRequestV5)
+}
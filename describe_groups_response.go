@@ -1,25 +1,52 @@
 package sarama
 
+import "time"
+
 type DescribeGroupsResponse struct {
-	Groups []*GroupDescription
+	Version      int16
+	ThrottleTime int32
+	Groups       []*GroupDescription
 }
 
-func (r *DescribeGroupsResponse) encode(pe packetEncoder) error {
-	if err := pe.putArrayLength(len(r.Groups)); err != nil {
+func (r *DescribeGroupsResponse) encode(pe packetEncoder) (err error) {
+	if r.Version >= 1 {
+		pe.putInt32(r.ThrottleTime)
+	}
+
+	if r.Version >= 5 {
+		pe.putCompactArrayLength(len(r.Groups))
+	} else if err = pe.putArrayLength(len(r.Groups)); err != nil {
 		return err
 	}
 
 	for _, groupDescription := range r.Groups {
-		if err := groupDescription.encode(pe); err != nil {
+		if err = groupDescription.encode(pe, r.Version); err != nil {
 			return err
 		}
 	}
 
+	if r.Version >= 5 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (r *DescribeGroupsResponse) decode(pd packetDecoder, version int16) (err error) {
-	n, err := pd.getArrayLength()
+	r.Version = version
+
+	if version >= 1 {
+		if r.ThrottleTime, err = pd.getInt32(); err != nil {
+			return err
+		}
+	}
+
+	var n int
+	if version >= 5 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -27,7 +54,13 @@ func (r *DescribeGroupsResponse) decode(pd packetDecoder, version int16) (err er
 	r.Groups = make([]*GroupDescription, n)
 	for i := 0; i < n; i++ {
 		r.Groups[i] = new(GroupDescription)
-		if err := r.Groups[i].decode(pd); err != nil {
+		if err := r.Groups[i].decode(pd, version); err != nil {
+			return err
+		}
+	}
+
+	if version >= 5 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
 			return err
 		}
 	}
@@ -40,59 +73,117 @@ func (r *DescribeGroupsResponse) key() int16 {
 }
 
 func (r *DescribeGroupsResponse) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *DescribeGroupsResponse) headerVersion() int16 {
+	if r.Version >= 5 {
+		return 1
+	}
 	return 0
 }
 
 func (r *DescribeGroupsResponse) requiredVersion() KafkaVersion {
-	return V0_9_0_0
+	switch r.Version {
+	case 5, 4:
+		return V2_4_0_0
+	case 3:
+		return V2_3_0_0
+	case 2:
+		return V2_0_0_0
+	case 1:
+		return V0_11_0_0
+	default:
+		return V0_9_0_0
+	}
+}
+
+func (r *DescribeGroupsResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTime) * time.Millisecond
+}
+
+func (r *DescribeGroupsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 2
 }
 
 type GroupDescription struct {
-	Err          KError
-	GroupId      string
-	State        string
-	ProtocolType string
-	Protocol     string
-	Members      map[string]*GroupMemberDescription
+	Err                  KError
+	GroupId              string
+	State                string
+	ProtocolType         string
+	Protocol             string
+	Members              map[string]*GroupMemberDescription
+	AuthorizedOperations int32 // Version 3
 }
 
-func (gd *GroupDescription) encode(pe packetEncoder) error {
+func (gd *GroupDescription) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt16(int16(gd.Err))
 
-	if err := pe.putString(gd.GroupId); err != nil {
+	if version >= 5 {
+		err = pe.putCompactString(gd.GroupId)
+	} else {
+		err = pe.putString(gd.GroupId)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putString(gd.State); err != nil {
+	if version >= 5 {
+		err = pe.putCompactString(gd.State)
+	} else {
+		err = pe.putString(gd.State)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putString(gd.ProtocolType); err != nil {
+	if version >= 5 {
+		err = pe.putCompactString(gd.ProtocolType)
+	} else {
+		err = pe.putString(gd.ProtocolType)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putString(gd.Protocol); err != nil {
+	if version >= 5 {
+		err = pe.putCompactString(gd.Protocol)
+	} else {
+		err = pe.putString(gd.Protocol)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := pe.putArrayLength(len(gd.Members)); err != nil {
+	if version >= 5 {
+		pe.putCompactArrayLength(len(gd.Members))
+	} else if err = pe.putArrayLength(len(gd.Members)); err != nil {
 		return err
 	}
 
 	for memberId, groupMemberDescription := range gd.Members {
-		if err := pe.putString(memberId); err != nil {
+		if version >= 5 {
+			err = pe.putCompactString(memberId)
+		} else {
+			err = pe.putString(memberId)
+		}
+		if err != nil {
 			return err
 		}
-		if err := groupMemberDescription.encode(pe); err != nil {
+		if err = groupMemberDescription.encode(pe, version); err != nil {
 			return err
 		}
 	}
 
+	if version >= 3 {
+		pe.putInt32(gd.AuthorizedOperations)
+	}
+
+	if version >= 5 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (gd *GroupDescription) decode(pd packetDecoder) (err error) {
+func (gd *GroupDescription) decode(pd packetDecoder, version int16) (err error) {
 	kerr, err := pd.getInt16()
 	if err != nil {
 		return err
@@ -100,36 +191,77 @@ func (gd *GroupDescription) decode(pd packetDecoder) (err error) {
 
 	gd.Err = KError(kerr)
 
-	if gd.GroupId, err = pd.getString(); err != nil {
+	if version >= 5 {
+		gd.GroupId, err = pd.getCompactString()
+	} else {
+		gd.GroupId, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
-	if gd.State, err = pd.getString(); err != nil {
+	if version >= 5 {
+		gd.State, err = pd.getCompactString()
+	} else {
+		gd.State, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
-	if gd.ProtocolType, err = pd.getString(); err != nil {
+	if version >= 5 {
+		gd.ProtocolType, err = pd.getCompactString()
+	} else {
+		gd.ProtocolType, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
-	if gd.Protocol, err = pd.getString(); err != nil {
+	if version >= 5 {
+		gd.Protocol, err = pd.getCompactString()
+	} else {
+		gd.Protocol, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 5 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
-	if n == 0 {
-		return nil
-	}
 
-	gd.Members = make(map[string]*GroupMemberDescription)
+	if n > 0 {
+		gd.Members = make(map[string]*GroupMemberDescription)
+	}
 	for i := 0; i < n; i++ {
-		memberId, err := pd.getString()
+		var memberId string
+		if version >= 5 {
+			memberId, err = pd.getCompactString()
+		} else {
+			memberId, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
 
 		gd.Members[memberId] = new(GroupMemberDescription)
-		if err := gd.Members[memberId].decode(pd); err != nil {
+		if err := gd.Members[memberId].decode(pd, version); err != nil {
+			return err
+		}
+	}
+
+	if version >= 3 {
+		if gd.AuthorizedOperations, err = pd.getInt32(); err != nil {
+			return err
+		}
+	}
+
+	if version >= 5 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
 			return err
 		}
 	}
@@ -138,44 +270,107 @@ func (gd *GroupDescription) decode(pd packetDecoder) (err error) {
 }
 
 type GroupMemberDescription struct {
+	GroupInstanceId  *string // Version 4
 	ClientId         string
 	ClientHost       string
 	MemberMetadata   []byte
 	MemberAssignment []byte
 }
 
-func (gmd *GroupMemberDescription) encode(pe packetEncoder) error {
-	if err := pe.putString(gmd.ClientId); err != nil {
+func (gmd *GroupMemberDescription) encode(pe packetEncoder, version int16) (err error) {
+	if version >= 5 {
+		err = pe.putNullableCompactString(gmd.GroupInstanceId)
+	} else if version >= 4 {
+		err = pe.putNullableString(gmd.GroupInstanceId)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putString(gmd.ClientHost); err != nil {
+	if version >= 5 {
+		err = pe.putCompactString(gmd.ClientId)
+	} else {
+		err = pe.putString(gmd.ClientId)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putBytes(gmd.MemberMetadata); err != nil {
+	if version >= 5 {
+		err = pe.putCompactString(gmd.ClientHost)
+	} else {
+		err = pe.putString(gmd.ClientHost)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putBytes(gmd.MemberAssignment); err != nil {
+	if version >= 5 {
+		err = pe.putCompactBytes(gmd.MemberMetadata)
+	} else {
+		err = pe.putBytes(gmd.MemberMetadata)
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 5 {
+		err = pe.putCompactBytes(gmd.MemberAssignment)
+	} else {
+		err = pe.putBytes(gmd.MemberAssignment)
+	}
+	if err != nil {
 		return err
 	}
+	if version >= 5 {
+		pe.putEmptyTaggedFieldArray()
+	}
 
 	return nil
 }
 
-func (gmd *GroupMemberDescription) decode(pd packetDecoder) (err error) {
-	if gmd.ClientId, err = pd.getString(); err != nil {
+func (gmd *GroupMemberDescription) decode(pd packetDecoder, version int16) (err error) {
+	if version >= 5 {
+		gmd.GroupInstanceId, err = pd.getCompactNullableString()
+	} else if version >= 4 {
+		gmd.GroupInstanceId, err = pd.getNullableString()
+	}
+	if err != nil {
+		return
+	}
+	if version >= 5 {
+		gmd.ClientId, err = pd.getCompactString()
+	} else {
+		gmd.ClientId, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
-	if gmd.ClientHost, err = pd.getString(); err != nil {
+	if version >= 5 {
+		gmd.ClientHost, err = pd.getCompactString()
+	} else {
+		gmd.ClientHost, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
-	if gmd.MemberMetadata, err = pd.getBytes(); err != nil {
+	if version >= 5 {
+		gmd.MemberMetadata, err = pd.getCompactBytes()
+	} else {
+		gmd.MemberMetadata, err = pd.getBytes()
+	}
+	if err != nil {
 		return
 	}
-	if gmd.MemberAssignment, err = pd.getBytes(); err != nil {
+	if version >= 5 {
+		gmd.MemberAssignment, err = pd.getCompactBytes()
+	} else {
+		gmd.MemberAssignment, err = pd.getBytes()
+	}
+	if err != nil {
 		return
 	}
+	if version >= 5 {
+		_, err = pd.getEmptyTaggedFieldArray()
+	}
 
-	return nil
+	return
 }
 
 func (gmd *GroupMemberDescription) GetMemberAssignment() (*ConsumerGroupMemberAssignment, error) {
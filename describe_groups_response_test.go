@@ -90,3 +90,48 @@ func TestDescribeGroupsResponse(t *testing.T) {
 		t.Error("Unxpected groups[1].Members, found", group0.Members)
 	}
 }
+
+var describeGroupsResponseV5 = []byte{
+	0, 0, 0, 100, // throttle time
+	2,    // 1 group
+	0, 0, // no error
+	4, 'f', 'o', 'o', // Group ID
+	7, 'S', 't', 'a', 'b', 'l', 'e', // State
+	9, 'c', 'o', 'n', 's', 'u', 'm', 'e', 'r', // ConsumerProtocol type
+	6, 'r', 'a', 'n', 'g', 'e', // Protocol name
+	2,                // 1 member
+	4, 'i', 'd', '1', // member ID
+	5, 'i', 'n', 's', 't', // group instance ID
+	4, 's', 'a', 'r', // client ID
+	5, 'l', 'o', 'c', 'a', // client host
+	4, 0x01, 0x02, 0x03, // MemberMetadata
+	4, 0x04, 0x05, 0x06, // MemberAssignment
+	0,                // member tagged fields
+	0, 0, 0x0b, 0xb8, // authorized operations
+	0, // group tagged fields
+	0, // tagged fields
+}
+
+func TestDescribeGroupsResponseV5(t *testing.T) {
+	response := &DescribeGroupsResponse{
+		Version:      5,
+		ThrottleTime: 100,
+		Groups: []*GroupDescription{{
+			GroupId:      "foo",
+			State:        "Stable",
+			ProtocolType: "consumer",
+			Protocol:     "range",
+			Members: map[string]*GroupMemberDescription{
+				"id1": {
+					GroupInstanceId:  nullString("inst"),
+					ClientId:         "sar",
+					ClientHost:       "loca",
+					MemberMetadata:   []byte{0x01, 0x02, 0x03},
+					MemberAssignment: []byte{0x04, 0x05, 0x06},
+				},
+			},
+			AuthorizedOperations: 3000,
+		}},
+	}
+	testResponse(t, "v5", response, describeGroupsResponseV5)
+}
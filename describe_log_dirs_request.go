@@ -4,6 +4,7 @@ package sarama
 type DescribeLogDirsRequest struct {
 	// Version 0 and 1 are equal
 	// The version number is bumped to indicate that on quota violation brokers send out responses before throttling.
+	// Version 2 is the first flexible version.
 	Version int16
 
 	// If this is an empty array, all topics will be queried
@@ -16,32 +17,59 @@ type DescribeLogDirsRequestTopic struct {
 	PartitionIDs []int32
 }
 
-func (r *DescribeLogDirsRequest) encode(pe packetEncoder) error {
+func (r *DescribeLogDirsRequest) encode(pe packetEncoder) (err error) {
 	length := len(r.DescribeTopics)
 	if length == 0 {
 		// In order to query all topics we must send null
 		length = -1
 	}
 
-	if err := pe.putArrayLength(length); err != nil {
+	if r.Version >= 2 {
+		pe.putCompactArrayLength(length)
+	} else if err = pe.putArrayLength(length); err != nil {
 		return err
 	}
 
 	for _, d := range r.DescribeTopics {
-		if err := pe.putString(d.Topic); err != nil {
+		if r.Version >= 2 {
+			err = pe.putCompactString(d.Topic)
+		} else {
+			err = pe.putString(d.Topic)
+		}
+		if err != nil {
 			return err
 		}
 
-		if err := pe.putInt32Array(d.PartitionIDs); err != nil {
+		if r.Version >= 2 {
+			err = pe.putCompactInt32Array(d.PartitionIDs)
+		} else {
+			err = pe.putInt32Array(d.PartitionIDs)
+		}
+		if err != nil {
 			return err
 		}
+
+		if r.Version >= 2 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if r.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
 }
 
-func (r *DescribeLogDirsRequest) decode(pd packetDecoder, version int16) error {
-	n, err := pd.getArrayLength()
+func (r *DescribeLogDirsRequest) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -53,20 +81,42 @@ func (r *DescribeLogDirsRequest) decode(pd packetDecoder, version int16) error {
 	for i := 0; i < n; i++ {
 		topics[i] = DescribeLogDirsRequestTopic{}
 
-		topic, err := pd.getString()
+		var topic string
+		if version >= 2 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
 		topics[i].Topic = topic
 
-		pIDs, err := pd.getInt32Array()
+		var pIDs []int32
+		if version >= 2 {
+			pIDs, err = pd.getCompactInt32Array()
+		} else {
+			pIDs, err = pd.getInt32Array()
+		}
 		if err != nil {
 			return err
 		}
 		topics[i].PartitionIDs = pIDs
+
+		if version >= 2 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
 	}
 	r.DescribeTopics = topics
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -79,9 +129,19 @@ func (r *DescribeLogDirsRequest) version() int16 {
 }
 
 func (r *DescribeLogDirsRequest) headerVersion() int16 {
+	if r.Version >= 2 {
+		return 2
+	}
 	return 1
 }
 
 func (r *DescribeLogDirsRequest) requiredVersion() KafkaVersion {
-	return V1_0_0_0
+	switch r.Version {
+	case 2:
+		return V2_6_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V1_0_0_0
+	}
 }
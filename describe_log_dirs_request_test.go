@@ -12,6 +12,16 @@ var (
 		0, 0, 0, 25, // PartitionID 25
 		0, 0, 0, 26, // PartitionID 26
 	}
+	topicDescribeLogDirsRequestV2 = []byte{
+		2,                            // DescribeTopics compact array, Array length 1
+		7,                            // Topic name compact length 6
+		'r', 'a', 'n', 'd', 'o', 'm', // Topic name
+		3,           // PartitionIDs compact int32 array, Array length 2
+		0, 0, 0, 25, // PartitionID 25
+		0, 0, 0, 26, // PartitionID 26
+		0, // empty tagged fields
+		0, // empty tagged fields
+	}
 )
 
 func TestDescribeLogDirsRequest(t *testing.T) {
@@ -29,3 +39,19 @@ func TestDescribeLogDirsRequest(t *testing.T) {
 	}
 	testRequest(t, "no topics", request, topicDescribeLogDirsRequest)
 }
+
+func TestDescribeLogDirsRequestV2(t *testing.T) {
+	request := &DescribeLogDirsRequest{
+		Version:        2,
+		DescribeTopics: []DescribeLogDirsRequestTopic{},
+	}
+	testRequest(t, "no topics", request, []byte{0, 0})
+
+	request.DescribeTopics = []DescribeLogDirsRequestTopic{
+		{
+			Topic:        "random",
+			PartitionIDs: []int32{25, 26},
+		},
+	}
+	testRequest(t, "one topic", request, topicDescribeLogDirsRequestV2)
+}
@@ -7,6 +7,7 @@ type DescribeLogDirsResponse struct {
 
 	// Version 0 and 1 are equal
 	// The version number is bumped to indicate that on quota violation brokers send out responses before throttling.
+	// Version 2 is the first flexible version.
 	Version int16
 
 	LogDirs []DescribeLogDirsResponseDirMetadata
@@ -15,20 +16,28 @@ type DescribeLogDirsResponse struct {
 func (r *DescribeLogDirsResponse) encode(pe packetEncoder) error {
 	pe.putInt32(int32(r.ThrottleTime / time.Millisecond))
 
-	if err := pe.putArrayLength(len(r.LogDirs)); err != nil {
+	if r.Version >= 2 {
+		pe.putCompactArrayLength(len(r.LogDirs))
+	} else if err := pe.putArrayLength(len(r.LogDirs)); err != nil {
 		return err
 	}
 
 	for _, dir := range r.LogDirs {
-		if err := dir.encode(pe); err != nil {
+		if err := dir.encode(pe, r.Version); err != nil {
 			return err
 		}
 	}
 
+	if r.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (r *DescribeLogDirsResponse) decode(pd packetDecoder, version int16) error {
+func (r *DescribeLogDirsResponse) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
@@ -36,7 +45,12 @@ func (r *DescribeLogDirsResponse) decode(pd packetDecoder, version int16) error
 	r.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
 	// Decode array of DescribeLogDirsResponseDirMetadata
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -50,6 +64,12 @@ func (r *DescribeLogDirsResponse) decode(pd packetDecoder, version int16) error
 		r.LogDirs[i] = dir
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -62,11 +82,21 @@ func (r *DescribeLogDirsResponse) version() int16 {
 }
 
 func (r *DescribeLogDirsResponse) headerVersion() int16 {
+	if r.Version >= 2 {
+		return 1
+	}
 	return 0
 }
 
 func (r *DescribeLogDirsResponse) requiredVersion() KafkaVersion {
-	return V1_0_0_0
+	switch r.Version {
+	case 2:
+		return V2_6_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V1_0_0_0
+	}
 }
 
 type DescribeLogDirsResponseDirMetadata struct {
@@ -77,40 +107,61 @@ type DescribeLogDirsResponseDirMetadata struct {
 	Topics []DescribeLogDirsResponseTopic
 }
 
-func (r *DescribeLogDirsResponseDirMetadata) encode(pe packetEncoder) error {
+func (r *DescribeLogDirsResponseDirMetadata) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt16(int16(r.ErrorCode))
 
-	if err := pe.putString(r.Path); err != nil {
+	if version >= 2 {
+		err = pe.putCompactString(r.Path)
+	} else {
+		err = pe.putString(r.Path)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := pe.putArrayLength(len(r.Topics)); err != nil {
+	if version >= 2 {
+		pe.putCompactArrayLength(len(r.Topics))
+	} else if err = pe.putArrayLength(len(r.Topics)); err != nil {
 		return err
 	}
 	for _, topic := range r.Topics {
-		if err := topic.encode(pe); err != nil {
+		if err = topic.encode(pe, version); err != nil {
 			return err
 		}
 	}
 
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (r *DescribeLogDirsResponseDirMetadata) decode(pd packetDecoder, version int16) error {
+func (r *DescribeLogDirsResponseDirMetadata) decode(pd packetDecoder, version int16) (err error) {
 	errCode, err := pd.getInt16()
 	if err != nil {
 		return err
 	}
 	r.ErrorCode = KError(errCode)
 
-	path, err := pd.getString()
+	var path string
+	if version >= 2 {
+		path, err = pd.getCompactString()
+	} else {
+		path, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
 	r.Path = path
 
 	// Decode array of DescribeLogDirsResponseTopic
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -126,6 +177,12 @@ func (r *DescribeLogDirsResponseDirMetadata) decode(pd packetDecoder, version in
 		r.Topics[i] = t
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -135,31 +192,52 @@ type DescribeLogDirsResponseTopic struct {
 	Partitions []DescribeLogDirsResponsePartition
 }
 
-func (r *DescribeLogDirsResponseTopic) encode(pe packetEncoder) error {
-	if err := pe.putString(r.Topic); err != nil {
+func (r *DescribeLogDirsResponseTopic) encode(pe packetEncoder, version int16) (err error) {
+	if version >= 2 {
+		err = pe.putCompactString(r.Topic)
+	} else {
+		err = pe.putString(r.Topic)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := pe.putArrayLength(len(r.Partitions)); err != nil {
+	if version >= 2 {
+		pe.putCompactArrayLength(len(r.Partitions))
+	} else if err = pe.putArrayLength(len(r.Partitions)); err != nil {
 		return err
 	}
 	for _, partition := range r.Partitions {
-		if err := partition.encode(pe); err != nil {
+		if err = partition.encode(pe, version); err != nil {
 			return err
 		}
 	}
 
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (r *DescribeLogDirsResponseTopic) decode(pd packetDecoder, version int16) error {
-	t, err := pd.getString()
+func (r *DescribeLogDirsResponseTopic) decode(pd packetDecoder, version int16) (err error) {
+	var t string
+	if version >= 2 {
+		t, err = pd.getCompactString()
+	} else {
+		t, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
 	r.Topic = t
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -172,6 +250,12 @@ func (r *DescribeLogDirsResponseTopic) decode(pd packetDecoder, version int16) e
 		r.Partitions[i] = p
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -191,12 +275,16 @@ type DescribeLogDirsResponsePartition struct {
 	IsTemporary bool
 }
 
-func (r *DescribeLogDirsResponsePartition) encode(pe packetEncoder) error {
+func (r *DescribeLogDirsResponsePartition) encode(pe packetEncoder, version int16) error {
 	pe.putInt32(r.PartitionID)
 	pe.putInt64(r.Size)
 	pe.putInt64(r.OffsetLag)
 	pe.putBool(r.IsTemporary)
 
+	if version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
@@ -225,5 +313,19 @@ func (r *DescribeLogDirsResponsePartition) decode(pd packetDecoder, version int1
 	}
 	r.IsTemporary = isTemp
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
+
+func (r *DescribeLogDirsResponse) throttleTime() time.Duration {
+	return r.ThrottleTime
+}
+
+func (r *DescribeLogDirsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
+}
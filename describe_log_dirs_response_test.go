@@ -29,6 +29,26 @@ var (
 		0, 0, 0, 0, 0, 0, 0, 0, // OffsetLag
 		0, // IsTemporary = false
 	}
+
+	describeLogDirsResponseOnePartitionV2 = []byte{
+		0, 0, 0, 0, // no throttle time
+		2,    // One describe log dir (compact array length)
+		0, 0, // No error code
+		7, // Compact length of path (6 chars)
+		'/', 'k', 'a', 'f', 'k', 'a',
+		2,                            // One DescribeLogDirsResponseTopic (compact array length)
+		7,                            // Compact length of "random" topic (6 chars)
+		'r', 'a', 'n', 'd', 'o', 'm', // Topic name
+		2,           // One DescribeLogDirsResponsePartition (compact array length)
+		0, 0, 0, 25, // PartitionID 25
+		0, 0, 0, 0, 0, 0, 0, 125, // Log Size
+		0, 0, 0, 0, 0, 0, 0, 0, // OffsetLag
+		0, // IsTemporary = false
+		0, // empty partition tagged fields
+		0, // empty topic tagged fields
+		0, // empty log dir tagged fields
+		0, // empty tagged fields
+	}
 )
 
 func TestDescribeLogDirsResponse(t *testing.T) {
@@ -77,3 +97,23 @@ func TestDescribeLogDirsResponse(t *testing.T) {
 		t.Error("Expected two partitions")
 	}
 }
+
+func TestDescribeLogDirsResponseV2(t *testing.T) {
+	response := &DescribeLogDirsResponse{
+		Version: 2,
+		LogDirs: []DescribeLogDirsResponseDirMetadata{
+			{
+				Path: "/kafka",
+				Topics: []DescribeLogDirsResponseTopic{
+					{
+						Topic: "random",
+						Partitions: []DescribeLogDirsResponsePartition{
+							{PartitionID: 25, Size: 125},
+						},
+					},
+				},
+			},
+		},
+	}
+	testResponse(t, "one partition", response, describeLogDirsResponseOnePartitionV2)
+}
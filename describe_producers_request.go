@@ -0,0 +1,7 @@
+package sarama
+
+//go:generate go run ./tools/kafka-protocol-gen ./tools/kafka-protocol-gen/messages/DescribeProducersRequest.json
+
+func (r *DescribeProducersRequest) requiredVersion() KafkaVersion {
+	return V2_8_0_0
+}
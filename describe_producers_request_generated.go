@@ -0,0 +1,103 @@
+// Code generated by kafka-protocol-gen from DescribeProducersRequest.json. DO NOT EDIT.
+
+package sarama
+
+// DescribeProducersRequest is the request of the Kafka protocol with the api key 61, in versions 0.
+type DescribeProducersRequest struct {
+	Version int16
+	// The topics to list producers for.
+	Topics []*DescribeProducersRequestTopicRequest
+}
+
+func (r *DescribeProducersRequest) encode(pe packetEncoder) error {
+	version := r.Version
+	pe.putCompactArrayLength(len(r.Topics))
+	for i := range r.Topics {
+		if err := r.Topics[i].encode(pe, version); err != nil {
+			return err
+		}
+	}
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *DescribeProducersRequest) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	{
+		var n int
+		n, err = pd.getCompactArrayLength()
+		if err != nil {
+			return err
+		}
+		if n >= 0 {
+			r.Topics = make([]*DescribeProducersRequestTopicRequest, n)
+		}
+		for i := 0; i < n; i++ {
+			r.Topics[i] = new(DescribeProducersRequestTopicRequest)
+			if err = r.Topics[i].decode(pd, version); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DescribeProducersRequestTopicRequest is a TopicRequest of DescribeProducersRequest.
+type DescribeProducersRequestTopicRequest struct {
+	// The topic name.
+	Name string
+	// The indexes of the partitions to list producers for.
+	PartitionIndexes []int32
+}
+
+func (s *DescribeProducersRequestTopicRequest) encode(pe packetEncoder, version int16) error {
+	if err := pe.putCompactString(s.Name); err != nil {
+		return err
+	}
+	pe.putCompactArrayLength(len(s.PartitionIndexes))
+	for i := range s.PartitionIndexes {
+		pe.putInt32(s.PartitionIndexes[i])
+	}
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (s *DescribeProducersRequestTopicRequest) decode(pd packetDecoder, version int16) (err error) {
+	if s.Name, err = pd.getCompactString(); err != nil {
+		return err
+	}
+	{
+		var n int
+		n, err = pd.getCompactArrayLength()
+		if err != nil {
+			return err
+		}
+		if n >= 0 {
+			s.PartitionIndexes = make([]int32, n)
+		}
+		for i := 0; i < n; i++ {
+			if s.PartitionIndexes[i], err = pd.getInt32(); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *DescribeProducersRequest) key() int16 {
+	return 61
+}
+
+func (r *DescribeProducersRequest) version() int16 {
+	return r.Version
+}
+
+func (r *DescribeProducersRequest) headerVersion() int16 {
+	return 2
+}
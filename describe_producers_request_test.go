@@ -0,0 +1,23 @@
+package sarama
+
+import "testing"
+
+var describeProducersRequestV0 = []byte{
+	2,                // 2-1=1 topic
+	4, 'f', 'o', 'o', // topic "foo"
+	3,          // 3-1=2 partitions
+	0, 0, 0, 0, // partition 0
+	0, 0, 0, 2, // partition 2
+	0, // empty tagged fields
+	0, // empty tagged fields
+}
+
+func TestDescribeProducersRequest(t *testing.T) {
+	request := &DescribeProducersRequest{
+		Topics: []*DescribeProducersRequestTopicRequest{{
+			Name:             "foo",
+			PartitionIndexes: []int32{0, 2},
+		}},
+	}
+	testRequest(t, "v0", request, describeProducersRequestV0)
+}
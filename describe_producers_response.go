@@ -0,0 +1,13 @@
+package sarama
+
+import "time"
+
+//go:generate go run ./tools/kafka-protocol-gen ./tools/kafka-protocol-gen/messages/DescribeProducersResponse.json
+
+func (r *DescribeProducersResponse) requiredVersion() KafkaVersion {
+	return V2_8_0_0
+}
+
+func (r *DescribeProducersResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTimeMs) * time.Millisecond
+}
@@ -0,0 +1,224 @@
+// Code generated by kafka-protocol-gen from DescribeProducersResponse.json. DO NOT EDIT.
+
+package sarama
+
+// DescribeProducersResponse is the response of the Kafka protocol with the api key 61, in versions 0.
+type DescribeProducersResponse struct {
+	Version int16
+	// The duration in milliseconds for which the request was throttled due to a quota violation, or zero if the request did not violate any quota.
+	ThrottleTimeMs int32
+	// Each topic in the response.
+	Topics []*DescribeProducersResponseTopicResponse
+}
+
+func (r *DescribeProducersResponse) encode(pe packetEncoder) error {
+	version := r.Version
+	pe.putInt32(r.ThrottleTimeMs)
+	pe.putCompactArrayLength(len(r.Topics))
+	for i := range r.Topics {
+		if err := r.Topics[i].encode(pe, version); err != nil {
+			return err
+		}
+	}
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *DescribeProducersResponse) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if r.ThrottleTimeMs, err = pd.getInt32(); err != nil {
+		return err
+	}
+	{
+		var n int
+		n, err = pd.getCompactArrayLength()
+		if err != nil {
+			return err
+		}
+		if n >= 0 {
+			r.Topics = make([]*DescribeProducersResponseTopicResponse, n)
+		}
+		for i := 0; i < n; i++ {
+			r.Topics[i] = new(DescribeProducersResponseTopicResponse)
+			if err = r.Topics[i].decode(pd, version); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DescribeProducersResponseTopicResponse is a TopicResponse of DescribeProducersResponse.
+type DescribeProducersResponseTopicResponse struct {
+	// The topic name.
+	Name string
+	// Each partition in the response.
+	Partitions []*DescribeProducersResponsePartitionResponse
+}
+
+func (s *DescribeProducersResponseTopicResponse) encode(pe packetEncoder, version int16) error {
+	if err := pe.putCompactString(s.Name); err != nil {
+		return err
+	}
+	pe.putCompactArrayLength(len(s.Partitions))
+	for i := range s.Partitions {
+		if err := s.Partitions[i].encode(pe, version); err != nil {
+			return err
+		}
+	}
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (s *DescribeProducersResponseTopicResponse) decode(pd packetDecoder, version int16) (err error) {
+	if s.Name, err = pd.getCompactString(); err != nil {
+		return err
+	}
+	{
+		var n int
+		n, err = pd.getCompactArrayLength()
+		if err != nil {
+			return err
+		}
+		if n >= 0 {
+			s.Partitions = make([]*DescribeProducersResponsePartitionResponse, n)
+		}
+		for i := 0; i < n; i++ {
+			s.Partitions[i] = new(DescribeProducersResponsePartitionResponse)
+			if err = s.Partitions[i].decode(pd, version); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DescribeProducersResponsePartitionResponse is a PartitionResponse of DescribeProducersResponse.
+type DescribeProducersResponsePartitionResponse struct {
+	// The partition index.
+	PartitionIndex int32
+	// The partition error code, or 0 if there was no error.
+	ErrorCode int16
+	// The partition error message, which may be null if no additional details are available.
+	ErrorMessage *string
+	// The active producers of the partition.
+	ActiveProducers []*DescribeProducersResponseProducerState
+}
+
+func (s *DescribeProducersResponsePartitionResponse) encode(pe packetEncoder, version int16) error {
+	pe.putInt32(s.PartitionIndex)
+	pe.putInt16(s.ErrorCode)
+	if err := pe.putNullableCompactString(s.ErrorMessage); err != nil {
+		return err
+	}
+	pe.putCompactArrayLength(len(s.ActiveProducers))
+	for i := range s.ActiveProducers {
+		if err := s.ActiveProducers[i].encode(pe, version); err != nil {
+			return err
+		}
+	}
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (s *DescribeProducersResponsePartitionResponse) decode(pd packetDecoder, version int16) (err error) {
+	if s.PartitionIndex, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if s.ErrorCode, err = pd.getInt16(); err != nil {
+		return err
+	}
+	if s.ErrorMessage, err = pd.getCompactNullableString(); err != nil {
+		return err
+	}
+	{
+		var n int
+		n, err = pd.getCompactArrayLength()
+		if err != nil {
+			return err
+		}
+		if n >= 0 {
+			s.ActiveProducers = make([]*DescribeProducersResponseProducerState, n)
+		}
+		for i := 0; i < n; i++ {
+			s.ActiveProducers[i] = new(DescribeProducersResponseProducerState)
+			if err = s.ActiveProducers[i].decode(pd, version); err != nil {
+				return err
+			}
+		}
+	}
+	if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// DescribeProducersResponseProducerState is a ProducerState of DescribeProducersResponse.
+type DescribeProducersResponseProducerState struct {
+	// The producer id.
+	ProducerId int64
+	// The producer epoch.
+	ProducerEpoch int32
+	// The last sequence number of the producer.
+	LastSequence int32
+	// The timestamp of the last batch of the producer.
+	LastTimestamp int64
+	// The epoch of the transaction coordinator.
+	CoordinatorEpoch int32
+	// The offset of the first record of the ongoing transaction of the producer, or -1.
+	CurrentTxnStartOffset int64
+}
+
+func (s *DescribeProducersResponseProducerState) encode(pe packetEncoder, version int16) error {
+	pe.putInt64(s.ProducerId)
+	pe.putInt32(s.ProducerEpoch)
+	pe.putInt32(s.LastSequence)
+	pe.putInt64(s.LastTimestamp)
+	pe.putInt32(s.CoordinatorEpoch)
+	pe.putInt64(s.CurrentTxnStartOffset)
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (s *DescribeProducersResponseProducerState) decode(pd packetDecoder, version int16) (err error) {
+	if s.ProducerId, err = pd.getInt64(); err != nil {
+		return err
+	}
+	if s.ProducerEpoch, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if s.LastSequence, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if s.LastTimestamp, err = pd.getInt64(); err != nil {
+		return err
+	}
+	if s.CoordinatorEpoch, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if s.CurrentTxnStartOffset, err = pd.getInt64(); err != nil {
+		return err
+	}
+	if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+		return err
+	}
+	return nil
+}
+
+func (r *DescribeProducersResponse) key() int16 {
+	return 61
+}
+
+func (r *DescribeProducersResponse) version() int16 {
+	return r.Version
+}
+
+func (r *DescribeProducersResponse) headerVersion() int16 {
+	return 1
+}
@@ -0,0 +1,55 @@
+package sarama
+
+import "testing"
+
+var describeProducersResponseV0 = []byte{
+	0, 0, 0, 0x64, // throttle time 100ms
+	2,                // 2-1=1 topic
+	4, 'f', 'o', 'o', // topic "foo"
+	3,          // 3-1=2 partitions
+	0, 0, 0, 0, // partition 0
+	0, 0, // no error
+	0,                            // null error message
+	2,                            // 2-1=1 active producer
+	0, 0, 0, 0, 0, 0, 0x03, 0xe8, // producer ID 1000
+	0, 0, 0, 1, // producer epoch 1
+	0, 0, 0, 0x0a, // last sequence 10
+	0, 0, 0, 0, 0, 0, 0x01, 0xf4, // last timestamp 500
+	0, 0, 0, 3, // coordinator epoch 3
+	0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, 0xff, // no ongoing transaction
+	0,          // empty tagged fields
+	0,          // empty tagged fields
+	0, 0, 0, 2, // partition 2
+	0, 0x03, // unknown topic or partition
+	4, 'b', 'a', 'd', // error message "bad"
+	1, // no active producers
+	0, // empty tagged fields
+	0, // empty tagged fields
+	0, // empty tagged fields
+}
+
+func TestDescribeProducersResponse(t *testing.T) {
+	response := &DescribeProducersResponse{
+		ThrottleTimeMs: 100,
+		Topics: []*DescribeProducersResponseTopicResponse{{
+			Name: "foo",
+			Partitions: []*DescribeProducersResponsePartitionResponse{{
+				PartitionIndex: 0,
+				ActiveProducers: []*DescribeProducersResponseProducerState{{
+					ProducerId:            1000,
+					ProducerEpoch:         1,
+					LastSequence:          10,
+					LastTimestamp:         500,
+					CoordinatorEpoch:      3,
+					CurrentTxnStartOffset: -1,
+				}},
+			}, {
+				PartitionIndex:  2,
+				ErrorCode:       int16(ErrUnknownTopicOrPartition),
+				ErrorMessage:    nullString("bad"),
+				ActiveProducers: []*DescribeProducersResponseProducerState{},
+			}},
+		}},
+	}
+	testResponse(t, "v0", response, describeProducersResponseV0)
+}
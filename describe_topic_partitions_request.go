@@ -0,0 +1,120 @@
+package sarama
+
+// DescribeTopicPartitionsCursor points to the first partition of a page of
+// DescribeTopicPartitions results.
+type DescribeTopicPartitionsCursor struct {
+	TopicName      string
+	PartitionIndex int32
+}
+
+func encodeDescribeTopicPartitionsCursor(pe packetEncoder, c *DescribeTopicPartitionsCursor) error {
+	if c == nil {
+		pe.putInt8(-1)
+		return nil
+	}
+	pe.putInt8(1)
+	if err := pe.putCompactString(c.TopicName); err != nil {
+		return err
+	}
+	pe.putInt32(c.PartitionIndex)
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func decodeDescribeTopicPartitionsCursor(pd packetDecoder) (*DescribeTopicPartitionsCursor, error) {
+	present, err := pd.getInt8()
+	if err != nil || present < 0 {
+		return nil, err
+	}
+	c := new(DescribeTopicPartitionsCursor)
+	if c.TopicName, err = pd.getCompactString(); err != nil {
+		return nil, err
+	}
+	if c.PartitionIndex, err = pd.getInt32(); err != nil {
+		return nil, err
+	}
+	if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// DescribeTopicPartitionsRequest describes the partitions of topics a page
+// at a time, see KIP-966.
+type DescribeTopicPartitionsRequest struct {
+	Version int16
+	// Topics to describe, every topic of the cluster when empty.
+	Topics []string
+	// ResponsePartitionLimit is the maximum number of partitions included in
+	// the response.
+	ResponsePartitionLimit int32
+	// Cursor is the first partition to describe, taken from the NextCursor
+	// of the previous response.
+	Cursor *DescribeTopicPartitionsCursor
+}
+
+func (r *DescribeTopicPartitionsRequest) encode(pe packetEncoder) error {
+	pe.putCompactArrayLength(len(r.Topics))
+	for _, topic := range r.Topics {
+		if err := pe.putCompactString(topic); err != nil {
+			return err
+		}
+		pe.putEmptyTaggedFieldArray()
+	}
+
+	pe.putInt32(r.ResponsePartitionLimit)
+
+	if err := encodeDescribeTopicPartitionsCursor(pe, r.Cursor); err != nil {
+		return err
+	}
+
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *DescribeTopicPartitionsRequest) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+
+	n, err := pd.getCompactArrayLength()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		r.Topics = make([]string, n)
+	}
+	for i := 0; i < n; i++ {
+		if r.Topics[i], err = pd.getCompactString(); err != nil {
+			return err
+		}
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
+	if r.ResponsePartitionLimit, err = pd.getInt32(); err != nil {
+		return err
+	}
+
+	if r.Cursor, err = decodeDescribeTopicPartitionsCursor(pd); err != nil {
+		return err
+	}
+
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *DescribeTopicPartitionsRequest) key() int16 {
+	return 75
+}
+
+func (r *DescribeTopicPartitionsRequest) version() int16 {
+	return r.Version
+}
+
+func (r *DescribeTopicPartitionsRequest) headerVersion() int16 {
+	return 2
+}
+
+func (r *DescribeTopicPartitionsRequest) requiredVersion() KafkaVersion {
+	return V3_8_0_0
+}
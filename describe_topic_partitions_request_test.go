@@ -0,0 +1,38 @@
+package sarama
+
+import "testing"
+
+var (
+	describeTopicPartitionsRequestAllTopics = []byte{
+		1,                // 1-1=0 topics, meaning every topic
+		0, 0, 0x07, 0xd0, // response partition limit 2000
+		255, // null cursor
+		0,   // empty tagged fields
+	}
+
+	describeTopicPartitionsRequestCursor = []byte{
+		3,                   // 3-1=2 topics
+		4, 'f', 'o', 'o', 0, // topic "foo" and empty tagged fields
+		4, 'b', 'a', 'r', 0, // topic "bar" and empty tagged fields
+		0, 0, 0, 10, // response partition limit 10
+		1,                // cursor present
+		4, 'f', 'o', 'o', // cursor topic "foo"
+		0, 0, 0, 5, // cursor partition 5
+		0, // empty tagged fields
+		0, // empty tagged fields
+	}
+)
+
+func TestDescribeTopicPartitionsRequest(t *testing.T) {
+	request := &DescribeTopicPartitionsRequest{
+		ResponsePartitionLimit: 2000,
+	}
+	testRequest(t, "all topics", request, describeTopicPartitionsRequestAllTopics)
+
+	request = &DescribeTopicPartitionsRequest{
+		Topics:                 []string{"foo", "bar"},
+		ResponsePartitionLimit: 10,
+		Cursor:                 &DescribeTopicPartitionsCursor{TopicName: "foo", PartitionIndex: 5},
+	}
+	testRequest(t, "with cursor", request, describeTopicPartitionsRequestCursor)
+}
@@ -0,0 +1,223 @@
+package sarama
+
+import "time"
+
+// DescribeTopicPartitionsResponse holds a page of DescribeTopicPartitions
+// results.
+type DescribeTopicPartitionsResponse struct {
+	Version        int16
+	ThrottleTimeMs int32
+	Topics         []*DescribeTopicPartitionsResponseTopic
+	// NextCursor points to the first partition of the next page, nil when
+	// this page is the last one.
+	NextCursor *DescribeTopicPartitionsCursor
+}
+
+// DescribeTopicPartitionsResponseTopic describes a topic and the partitions
+// of it included in the page.
+type DescribeTopicPartitionsResponseTopic struct {
+	Err                       KError
+	Name                      string
+	TopicID                   Uuid
+	IsInternal                bool
+	Partitions                []*DescribeTopicPartitionsResponsePartition
+	TopicAuthorizedOperations int32
+}
+
+// DescribeTopicPartitionsResponsePartition describes the leadership and
+// replicas of a partition.
+type DescribeTopicPartitionsResponsePartition struct {
+	Err                    KError
+	ID                     int32
+	Leader                 int32
+	LeaderEpoch            int32
+	Replicas               []int32
+	Isr                    []int32
+	EligibleLeaderReplicas []int32
+	LastKnownElr           []int32
+	OfflineReplicas        []int32
+}
+
+func putNonNullCompactInt32Array(pe packetEncoder, in []int32) error {
+	if in == nil {
+		in = []int32{}
+	}
+	return pe.putCompactInt32Array(in)
+}
+
+func (p *DescribeTopicPartitionsResponsePartition) encode(pe packetEncoder) error {
+	pe.putInt16(int16(p.Err))
+	pe.putInt32(p.ID)
+	pe.putInt32(p.Leader)
+	pe.putInt32(p.LeaderEpoch)
+	if err := putNonNullCompactInt32Array(pe, p.Replicas); err != nil {
+		return err
+	}
+	if err := putNonNullCompactInt32Array(pe, p.Isr); err != nil {
+		return err
+	}
+	if err := pe.putNullableCompactInt32Array(p.EligibleLeaderReplicas); err != nil {
+		return err
+	}
+	if err := pe.putNullableCompactInt32Array(p.LastKnownElr); err != nil {
+		return err
+	}
+	if err := putNonNullCompactInt32Array(pe, p.OfflineReplicas); err != nil {
+		return err
+	}
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (p *DescribeTopicPartitionsResponsePartition) decode(pd packetDecoder) (err error) {
+	kerr, err := pd.getInt16()
+	if err != nil {
+		return err
+	}
+	p.Err = KError(kerr)
+	if p.ID, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if p.Leader, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if p.LeaderEpoch, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if p.Replicas, err = pd.getCompactInt32Array(); err != nil {
+		return err
+	}
+	if p.Isr, err = pd.getCompactInt32Array(); err != nil {
+		return err
+	}
+	if p.EligibleLeaderReplicas, err = pd.getCompactInt32Array(); err != nil {
+		return err
+	}
+	if p.LastKnownElr, err = pd.getCompactInt32Array(); err != nil {
+		return err
+	}
+	if p.OfflineReplicas, err = pd.getCompactInt32Array(); err != nil {
+		return err
+	}
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (t *DescribeTopicPartitionsResponseTopic) encode(pe packetEncoder) error {
+	pe.putInt16(int16(t.Err))
+	if err := pe.putNullableCompactString(&t.Name); err != nil {
+		return err
+	}
+	if err := t.TopicID.encode(pe); err != nil {
+		return err
+	}
+	pe.putBool(t.IsInternal)
+	pe.putCompactArrayLength(len(t.Partitions))
+	for _, partition := range t.Partitions {
+		if err := partition.encode(pe); err != nil {
+			return err
+		}
+	}
+	pe.putInt32(t.TopicAuthorizedOperations)
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (t *DescribeTopicPartitionsResponseTopic) decode(pd packetDecoder) (err error) {
+	kerr, err := pd.getInt16()
+	if err != nil {
+		return err
+	}
+	t.Err = KError(kerr)
+	name, err := pd.getCompactNullableString()
+	if err != nil {
+		return err
+	}
+	if name != nil {
+		t.Name = *name
+	}
+	if err = t.TopicID.decode(pd); err != nil {
+		return err
+	}
+	if t.IsInternal, err = pd.getBool(); err != nil {
+		return err
+	}
+	n, err := pd.getCompactArrayLength()
+	if err != nil {
+		return err
+	}
+	t.Partitions = make([]*DescribeTopicPartitionsResponsePartition, n)
+	for i := range t.Partitions {
+		t.Partitions[i] = new(DescribeTopicPartitionsResponsePartition)
+		if err = t.Partitions[i].decode(pd); err != nil {
+			return err
+		}
+	}
+	if t.TopicAuthorizedOperations, err = pd.getInt32(); err != nil {
+		return err
+	}
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *DescribeTopicPartitionsResponse) encode(pe packetEncoder) error {
+	pe.putInt32(r.ThrottleTimeMs)
+	pe.putCompactArrayLength(len(r.Topics))
+	for _, topic := range r.Topics {
+		if err := topic.encode(pe); err != nil {
+			return err
+		}
+	}
+	if err := encodeDescribeTopicPartitionsCursor(pe, r.NextCursor); err != nil {
+		return err
+	}
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *DescribeTopicPartitionsResponse) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if r.ThrottleTimeMs, err = pd.getInt32(); err != nil {
+		return err
+	}
+	n, err := pd.getCompactArrayLength()
+	if err != nil {
+		return err
+	}
+	r.Topics = make([]*DescribeTopicPartitionsResponseTopic, n)
+	for i := range r.Topics {
+		r.Topics[i] = new(DescribeTopicPartitionsResponseTopic)
+		if err = r.Topics[i].decode(pd); err != nil {
+			return err
+		}
+	}
+	if r.NextCursor, err = decodeDescribeTopicPartitionsCursor(pd); err != nil {
+		return err
+	}
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *DescribeTopicPartitionsResponse) key() int16 {
+	return 75
+}
+
+func (r *DescribeTopicPartitionsResponse) version() int16 {
+	return r.Version
+}
+
+func (r *DescribeTopicPartitionsResponse) headerVersion() int16 {
+	return 1
+}
+
+func (r *DescribeTopicPartitionsResponse) requiredVersion() KafkaVersion {
+	return V3_8_0_0
+}
+
+func (r *DescribeTopicPartitionsResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTimeMs) * time.Millisecond
+}
+
+func (r *DescribeTopicPartitionsResponse) shouldClientThrottle(version int16) bool {
+	return true
+}
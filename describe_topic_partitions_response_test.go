@@ -0,0 +1,87 @@
+package sarama
+
+import "testing"
+
+var (
+	describeTopicPartitionsResponseLastPage = []byte{
+		0, 0, 0, 0, // throttle time
+		2,    // 2-1=1 topic
+		0, 0, // no error
+		4, 'f', 'o', 'o', // topic name "foo"
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, // topic id
+		0,    // not internal
+		2,    // 2-1=1 partition
+		0, 0, // no error
+		0, 0, 0, 1, // partition 1
+		0, 0, 0, 2, // leader 2
+		0, 0, 0, 7, // leader epoch 7
+		3, 0, 0, 0, 2, 0, 0, 0, 3, // replicas [2 3]
+		2, 0, 0, 0, 2, // isr [2]
+		0,             // null eligible leader replicas
+		1,             // empty last known elr
+		1,             // empty offline replicas
+		0,             // empty tagged fields
+		0x80, 0, 0, 0, // topic authorized operations not requested
+		0,   // empty tagged fields
+		255, // null next cursor
+		0,   // empty tagged fields
+	}
+
+	describeTopicPartitionsResponseNextPage = []byte{
+		0, 0, 0, 0, // throttle time
+		2,    // 2-1=1 topic
+		0, 3, // ErrUnknownTopicOrPartition
+		4, 'b', 'a', 'r', // topic name "bar"
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // zero topic id
+		0,             // not internal
+		1,             // 1-1=0 partitions
+		0x80, 0, 0, 0, // topic authorized operations not requested
+		0,                // empty tagged fields
+		1,                // next cursor present
+		4, 'f', 'o', 'o', // cursor topic "foo"
+		0, 0, 0, 2, // cursor partition 2
+		0, // empty tagged fields
+		0, // empty tagged fields
+	}
+)
+
+func TestDescribeTopicPartitionsResponse(t *testing.T) {
+	response := &DescribeTopicPartitionsResponse{
+		Topics: []*DescribeTopicPartitionsResponseTopic{{
+			Err:        ErrNoError,
+			Name:       "foo",
+			TopicID:    Uuid{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+			IsInternal: false,
+			Partitions: []*DescribeTopicPartitionsResponsePartition{{
+				Err:             ErrNoError,
+				ID:              1,
+				Leader:          2,
+				LeaderEpoch:     7,
+				Replicas:        []int32{2, 3},
+				Isr:             []int32{2},
+				LastKnownElr:    []int32{},
+				OfflineReplicas: []int32{},
+			}},
+			TopicAuthorizedOperations: -2147483648,
+		}},
+	}
+	testResponse(t, "last page", response, describeTopicPartitionsResponseLastPage)
+
+	response = &DescribeTopicPartitionsResponse{
+		Topics: []*DescribeTopicPartitionsResponseTopic{{
+			Err:                       ErrUnknownTopicOrPartition,
+			Name:                      "bar",
+			Partitions:                []*DescribeTopicPartitionsResponsePartition{},
+			TopicAuthorizedOperations: -2147483648,
+		}},
+		NextCursor: &DescribeTopicPartitionsCursor{TopicName: "foo", PartitionIndex: 2},
+	}
+	testResponse(t, "next page", response, describeTopicPartitionsResponseNextPage)
+}
+
+func TestUuidString(t *testing.T) {
+	id := Uuid{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15}
+	if s := id.String(); s != "AAECAwQFBgcICQoLDA0ODw" {
+		t.Errorf("unexpected uuid string %q", s)
+	}
+}
@@ -166,3 +166,11 @@ func (r *DescribeUserScramCredentialsResponse) headerVersion() int16 {
 func (r *DescribeUserScramCredentialsResponse) requiredVersion() KafkaVersion {
 	return V2_7_0_0
 }
+
+func (r *DescribeUserScramCredentialsResponse) throttleTime() time.Duration {
+	return r.ThrottleTime
+}
+
+func (r *DescribeUserScramCredentialsResponse) shouldClientThrottle(version int16) bool {
+	return true
+}
@@ -0,0 +1,192 @@
+package sarama
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// srvScheme prefixes the bootstrap addresses which are DNS names whose SRV
+// records list the brokers, e.g. srv://_kafka._tcp.example.com.
+const srvScheme = "srv://"
+
+// DNSLookup controls how the host names of the brokers are resolved, as the
+// client.dns.lookup setting of the Java client.
+type DNSLookup string
+
+const (
+	// DNSLookupDefault leaves the resolution of broker host names to the
+	// dialer. The default dialer resolves them on every connection and tries
+	// their addresses in turn, sharing DialTimeout between them.
+	DNSLookupDefault DNSLookup = "default"
+	// DNSLookupUseAllIPs resolves the host name of a broker on every
+	// connection and dials each of its A/AAAA records in turn, each with
+	// its own DialTimeout, until one of them accepts the connection. This
+	// suits brokers reached through Kubernetes services or load balancers
+	// with several addresses, whose records change over time. The dialer
+	// selected for the broker is called with the resolved address.
+	DNSLookupUseAllIPs DNSLookup = "use_all_dns_ips"
+	// DNSLookupResolveCanonicalBootstrapServersOnly replaces every bootstrap
+	// address by the canonical host names of its A/AAAA records, so that a
+	// single DNS alias can stand for the whole cluster, e.g. with Kerberos
+	// which requires the actual host names of the brokers. Broker addresses
+	// are then resolved as with DNSLookupUseAllIPs.
+	DNSLookupResolveCanonicalBootstrapServersOnly DNSLookup = "resolve_canonical_bootstrap_servers_only"
+)
+
+func (c *Config) resolver() *net.Resolver {
+	if c.Net.Resolver != nil {
+		return c.Net.Resolver
+	}
+	return net.DefaultResolver
+}
+
+// dialAllIPs resolves the host of addr and dials its addresses in turn with
+// dial, returning the first connection established, or giving up once parent
+// is done.
+func (c *Config) dialAllIPs(parent context.Context, dial DialContextFunc, addr string) (net.Conn, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil || net.ParseIP(host) != nil {
+		ctx, cancel := context.WithTimeout(parent, c.Net.DialTimeout)
+		defer cancel()
+		return dial(ctx, "tcp", addr)
+	}
+
+	ctx, cancel := context.WithTimeout(parent, c.Net.DialTimeout)
+	ips, err := c.resolver().LookupIPAddr(ctx, host)
+	cancel()
+	if err != nil {
+		return nil, err
+	}
+
+	for _, ip := range ips {
+		ipAddr := net.JoinHostPort(ip.String(), port)
+		ctx, cancel := context.WithTimeout(parent, c.Net.DialTimeout)
+		var conn net.Conn
+		conn, err = dial(ctx, "tcp", ipAddr)
+		cancel()
+		if err == nil {
+			return conn, nil
+		}
+		if parent.Err() != nil {
+			return nil, err
+		}
+		debugf("Failed to connect to %s at %s: %s\n", addr, ipAddr, err)
+	}
+	return nil, err
+}
+
+// bootstrapAddrs returns the addresses the client is bootstrapped from:
+// addrs, with the srv:// addresses replaced by the targets of their SRV
+// records, or their canonical host names with
+// DNSLookupResolveCanonicalBootstrapServersOnly.
+func (c *Config) bootstrapAddrs(addrs []string) ([]string, error) {
+	addrs, err := c.srvAddrs(addrs)
+	if err != nil {
+		return nil, err
+	}
+	if c.Net.DNSLookup != DNSLookupResolveCanonicalBootstrapServersOnly {
+		return addrs, nil
+	}
+
+	seen := make(map[string]bool)
+	var canonical []string
+	for _, addr := range addrs {
+		resolved, err := c.canonicalAddrs(addr)
+		if err != nil {
+			infof("Couldn't resolve bootstrap address %s: %s\n", addr, err)
+			continue
+		}
+		for _, r := range resolved {
+			if !seen[r] {
+				seen[r] = true
+				canonical = append(canonical, r)
+			}
+		}
+	}
+	if len(canonical) == 0 {
+		return nil, ConfigurationError(fmt.Sprintf("None of the bootstrap addresses %v could be resolved", addrs))
+	}
+	return canonical, nil
+}
+
+// canonicalAddrs resolves the host of addr and returns the canonical host
+// name of each of its addresses, or the address itself if it has none,
+// along with the port of addr.
+func (c *Config) canonicalAddrs(addr string) ([]string, error) {
+	host, port, err := net.SplitHostPort(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), c.Net.DialTimeout)
+	defer cancel()
+	ips, err := c.resolver().LookupIPAddr(ctx, host)
+	if err != nil {
+		return nil, err
+	}
+
+	resolved := make([]string, 0, len(ips))
+	for _, ip := range ips {
+		name := ip.String()
+		if names, err := c.resolver().LookupAddr(ctx, name); err == nil && len(names) > 0 {
+			name = strings.TrimSuffix(names[0], ".")
+		}
+		resolved = append(resolved, net.JoinHostPort(name, port))
+	}
+	return resolved, nil
+}
+
+// hasSRVAddrs returns whether some of addrs are resolved from SRV records.
+func hasSRVAddrs(addrs []string) bool {
+	for _, addr := range addrs {
+		if strings.HasPrefix(addr, srvScheme) {
+			return true
+		}
+	}
+	return false
+}
+
+// srvAddrs replaces the srv:// addresses of addrs by the targets of their SRV
+// records, in the order of their priority and weight.
+func (c *Config) srvAddrs(addrs []string) ([]string, error) {
+	if !hasSRVAddrs(addrs) {
+		return addrs, nil
+	}
+
+	var resolved []string
+	for _, addr := range addrs {
+		if !strings.HasPrefix(addr, srvScheme) {
+			resolved = append(resolved, addr)
+			continue
+		}
+		targets, err := c.lookupSRV(strings.TrimPrefix(addr, srvScheme))
+		if err != nil {
+			infof("Couldn't resolve the SRV records of bootstrap address %s: %s\n", addr, err)
+			continue
+		}
+		resolved = append(resolved, targets...)
+	}
+	if len(resolved) == 0 {
+		return nil, ConfigurationError(fmt.Sprintf("None of the bootstrap addresses %v could be resolved", addrs))
+	}
+	return resolved, nil
+}
+
+func (c *Config) lookupSRV(name string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), c.Net.DialTimeout)
+	defer cancel()
+	_, records, err := c.resolver().LookupSRV(ctx, "", "", name)
+	if err != nil {
+		return nil, err
+	}
+
+	targets := make([]string, 0, len(records))
+	for _, record := range records {
+		host := strings.TrimSuffix(record.Target, ".")
+		targets = append(targets, net.JoinHostPort(host, strconv.Itoa(int(record.Port))))
+	}
+	return targets, nil
+}
@@ -0,0 +1,250 @@
+package sarama
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"io"
+	"net"
+	"reflect"
+	"strconv"
+	"sync"
+	"testing"
+
+	"golang.org/x/net/dns/dnsmessage"
+)
+
+// hostsOnlyResolver resolves names from the hosts file only.
+func hostsOnlyResolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(context.Context, string, string) (net.Conn, error) {
+			return nil, errors.New("no DNS server")
+		},
+	}
+}
+
+func TestDNSLookupUseAllIPs(t *testing.T) {
+	mb := NewMockBroker(t, 0)
+	defer mb.Close()
+	_, port, err := net.SplitHostPort(mb.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var lock sync.Mutex
+	var dialed []string
+	conf := NewTestConfig()
+	conf.Net.DNSLookup = DNSLookupUseAllIPs
+	conf.Net.Resolver = hostsOnlyResolver()
+	conf.Net.DialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+		lock.Lock()
+		dialed = append(dialed, addr)
+		lock.Unlock()
+		return (&net.Dialer{}).DialContext(ctx, network, addr)
+	}
+
+	broker := NewBroker(net.JoinHostPort("localhost", port))
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, broker)
+	if connected, err := broker.Connected(); !connected || err != nil {
+		t.Fatalf("expected the broker to connect, got %v", err)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(dialed) == 0 || dialed[len(dialed)-1] != net.JoinHostPort("127.0.0.1", port) {
+		t.Errorf("expected the addresses of localhost to be dialed in turn, got %v", dialed)
+	}
+	for _, addr := range dialed {
+		if host, _, _ := net.SplitHostPort(addr); net.ParseIP(host) == nil {
+			t.Errorf("expected only resolved addresses to be dialed, got %s", addr)
+		}
+	}
+}
+
+func TestDNSLookupResolveCanonicalBootstrapServersOnly(t *testing.T) {
+	conf := NewTestConfig()
+	conf.Net.DNSLookup = DNSLookupResolveCanonicalBootstrapServersOnly
+	conf.Net.Resolver = hostsOnlyResolver()
+
+	addrs, err := conf.bootstrapAddrs([]string{"127.0.0.1:9092", "unknown.example:9092", "localhost:9093"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"localhost:9092", "localhost:9093"}; !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("expected %v, got %v", expected, addrs)
+	}
+
+	if _, err := conf.bootstrapAddrs([]string{"unknown.example:9092"}); !errors.As(err, new(ConfigurationError)) {
+		t.Errorf("expected a ConfigurationError when no address resolves, got %v", err)
+	}
+
+	conf.Net.DNSLookup = DNSLookupUseAllIPs
+	if addrs, _ := conf.bootstrapAddrs([]string{"unknown.example:9092"}); len(addrs) != 1 || addrs[0] != "unknown.example:9092" {
+		t.Errorf("expected the bootstrap addresses to be kept, got %v", addrs)
+	}
+}
+
+// srvServer is an in-process DNS server answering the SRV queries for name.
+type srvServer struct {
+	name    string
+	lock    sync.Mutex
+	records []net.SRV
+}
+
+func (s *srvServer) setRecords(records ...net.SRV) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.records = records
+}
+
+// resolver returns a resolver querying s only.
+func (s *srvServer) resolver() *net.Resolver {
+	return &net.Resolver{
+		PreferGo: true,
+		Dial: func(context.Context, string, string) (net.Conn, error) {
+			client, server := net.Pipe()
+			go s.serve(server)
+			return client, nil
+		},
+	}
+}
+
+// serve answers the queries sent over conn, which are framed as over TCP.
+func (s *srvServer) serve(conn net.Conn) {
+	defer conn.Close()
+	for {
+		var length [2]byte
+		if _, err := io.ReadFull(conn, length[:]); err != nil {
+			return
+		}
+		query := make([]byte, binary.BigEndian.Uint16(length[:]))
+		if _, err := io.ReadFull(conn, query); err != nil {
+			return
+		}
+		answer, err := s.answer(query)
+		if err != nil {
+			return
+		}
+		binary.BigEndian.PutUint16(length[:], uint16(len(answer)))
+		if _, err := conn.Write(append(length[:], answer...)); err != nil {
+			return
+		}
+	}
+}
+
+func (s *srvServer) answer(query []byte) ([]byte, error) {
+	var parser dnsmessage.Parser
+	header, err := parser.Start(query)
+	if err != nil {
+		return nil, err
+	}
+	question, err := parser.Question()
+	if err != nil {
+		return nil, err
+	}
+
+	s.lock.Lock()
+	records := s.records
+	s.lock.Unlock()
+
+	found := question.Type == dnsmessage.TypeSRV && question.Name.String() == s.name+"."
+	header.Response = true
+	header.Authoritative = true
+	if !found {
+		header.RCode = dnsmessage.RCodeNameError
+	}
+	builder := dnsmessage.NewBuilder(nil, header)
+	if err := builder.StartQuestions(); err != nil {
+		return nil, err
+	}
+	if err := builder.Question(question); err != nil {
+		return nil, err
+	}
+	if err := builder.StartAnswers(); err != nil {
+		return nil, err
+	}
+	if found {
+		for _, record := range records {
+			target, err := dnsmessage.NewName(record.Target + ".")
+			if err != nil {
+				return nil, err
+			}
+			err = builder.SRVResource(
+				dnsmessage.ResourceHeader{Name: question.Name, Class: dnsmessage.ClassINET, TTL: 60},
+				dnsmessage.SRVResource{Priority: record.Priority, Weight: record.Weight, Port: record.Port, Target: target},
+			)
+			if err != nil {
+				return nil, err
+			}
+		}
+	}
+	return builder.Finish()
+}
+
+func TestSRVBootstrapAddrs(t *testing.T) {
+	server := &srvServer{name: "_kafka._tcp.example.test"}
+	server.setRecords(
+		net.SRV{Target: "broker2.example.test", Port: 9093, Priority: 20},
+		net.SRV{Target: "broker1.example.test", Port: 9092, Priority: 10},
+	)
+	conf := NewTestConfig()
+	conf.Net.Resolver = server.resolver()
+
+	addrs, err := conf.bootstrapAddrs([]string{"srv://_kafka._tcp.example.test", "localhost:9094"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expected := []string{"broker1.example.test:9092", "broker2.example.test:9093", "localhost:9094"}; !reflect.DeepEqual(addrs, expected) {
+		t.Errorf("expected %v, got %v", expected, addrs)
+	}
+
+	if _, err := conf.bootstrapAddrs([]string{"srv://_kafka._tcp.unknown.test"}); !errors.As(err, new(ConfigurationError)) {
+		t.Errorf("expected a ConfigurationError when no SRV record is found, got %v", err)
+	}
+}
+
+func TestClientResolvesSRVSeedBrokers(t *testing.T) {
+	seed1 := NewMockBroker(t, 1)
+	defer seed1.Close()
+	seed2 := NewMockBroker(t, 2)
+	defer seed2.Close()
+	seed1.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t),
+	})
+
+	// SRV records name hosts, not addresses
+	localhost := func(broker *MockBroker) string {
+		_, port, _ := net.SplitHostPort(broker.Addr())
+		return net.JoinHostPort("localhost", port)
+	}
+	srvRecord := func(broker *MockBroker) net.SRV {
+		_, port, _ := net.SplitHostPort(broker.Addr())
+		p, _ := strconv.Atoi(port)
+		return net.SRV{Target: "localhost", Port: uint16(p)}
+	}
+	server := &srvServer{name: "_kafka._tcp.example.test"}
+	server.setRecords(srvRecord(seed1))
+
+	conf := NewTestConfig()
+	conf.Metadata.RefreshFrequency = 0
+	conf.Net.Resolver = server.resolver()
+	c, err := NewClient([]string{"srv://_kafka._tcp.example.test"}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, c)
+	client := c.(*client)
+
+	server.setRecords(srvRecord(seed2))
+	client.resolveSeedBrokers()
+
+	client.lock.RLock()
+	defer client.lock.RUnlock()
+	if len(client.seedBrokers) != 1 || client.seedBrokers[0].Addr() != localhost(seed2) {
+		t.Errorf("expected the seed brokers to follow the SRV records, got %v", client.seedBrokers)
+	}
+}
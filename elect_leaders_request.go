@@ -0,0 +1,157 @@
+package sarama
+
+// ElectionType is the type of leader election requested by ElectLeaders.
+type ElectionType int8
+
+const (
+	// PreferredElection elects the preferred replica, the first one of the
+	// replica list, as leader if it is in sync.
+	PreferredElection ElectionType = 0
+	// UncleanElection elects any live replica as leader when no in-sync
+	// replica is available, possibly losing data.
+	UncleanElection ElectionType = 1
+)
+
+// ElectLeadersRequest triggers leader elections for the given partitions.
+type ElectLeadersRequest struct {
+	Version int16
+	Type    ElectionType // version 1 or later
+	// TopicPartitions lists the partitions to run elections for, nil
+	// meaning every partition of the cluster.
+	TopicPartitions map[string][]int32
+	TimeoutMs       int32
+}
+
+func (r *ElectLeadersRequest) encode(pe packetEncoder) error {
+	if r.Version >= 1 {
+		pe.putInt8(int8(r.Type))
+	}
+
+	if r.Version >= 2 {
+		if r.TopicPartitions == nil {
+			pe.putCompactArrayLength(-1)
+		} else {
+			pe.putCompactArrayLength(len(r.TopicPartitions))
+		}
+	} else {
+		length := len(r.TopicPartitions)
+		if r.TopicPartitions == nil {
+			length = -1
+		}
+		if err := pe.putArrayLength(length); err != nil {
+			return err
+		}
+	}
+
+	for topic, partitions := range r.TopicPartitions {
+		if r.Version >= 2 {
+			if err := pe.putCompactString(topic); err != nil {
+				return err
+			}
+			if err := pe.putCompactInt32Array(partitions); err != nil {
+				return err
+			}
+			pe.putEmptyTaggedFieldArray()
+			continue
+		}
+		if err := pe.putString(topic); err != nil {
+			return err
+		}
+		if err := pe.putInt32Array(partitions); err != nil {
+			return err
+		}
+	}
+
+	pe.putInt32(r.TimeoutMs)
+
+	if r.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
+	return nil
+}
+
+func (r *ElectLeadersRequest) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+
+	if r.Version >= 1 {
+		electionType, err := pd.getInt8()
+		if err != nil {
+			return err
+		}
+		r.Type = ElectionType(electionType)
+	}
+
+	var n int
+	if r.Version >= 2 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
+	if err != nil {
+		return err
+	}
+
+	if n > 0 {
+		r.TopicPartitions = make(map[string][]int32, n)
+	}
+	for i := 0; i < n; i++ {
+		var topic string
+		var partitions []int32
+		if r.Version >= 2 {
+			if topic, err = pd.getCompactString(); err != nil {
+				return err
+			}
+			if partitions, err = pd.getCompactInt32Array(); err != nil {
+				return err
+			}
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		} else {
+			if topic, err = pd.getString(); err != nil {
+				return err
+			}
+			if partitions, err = pd.getInt32Array(); err != nil {
+				return err
+			}
+		}
+		r.TopicPartitions[topic] = partitions
+	}
+
+	if r.TimeoutMs, err = pd.getInt32(); err != nil {
+		return err
+	}
+
+	if r.Version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func (r *ElectLeadersRequest) key() int16 {
+	return 43
+}
+
+func (r *ElectLeadersRequest) version() int16 {
+	return r.Version
+}
+
+func (r *ElectLeadersRequest) headerVersion() int16 {
+	if r.Version >= 2 {
+		return 2
+	}
+	return 1
+}
+
+func (r *ElectLeadersRequest) requiredVersion() KafkaVersion {
+	switch r.Version {
+	case 2, 1:
+		return V2_4_0_0
+	default:
+		return V2_2_0_0
+	}
+}
@@ -0,0 +1,53 @@
+package sarama
+
+import "testing"
+
+var (
+	electLeadersRequestV0 = []byte{
+		0, 0, 0, 1, // 1 topic
+		0, 5, 't', 'o', 'p', 'i', 'c',
+		0, 0, 0, 1, // 1 partition
+		0, 0, 0, 0, // partition 0
+		0, 0, 39, 16, // timeout 10000
+	}
+
+	electLeadersRequestV1AllPartitions = []byte{
+		1,                  // unclean election
+		255, 255, 255, 255, // null topics, meaning every partition
+		0, 0, 39, 16, // timeout 10000
+	}
+
+	electLeadersRequestV2 = []byte{
+		0,                          // preferred election
+		2,                          // 2-1=1 topic
+		6, 't', 'o', 'p', 'i', 'c', // topic name "topic" as compact string
+		2,          // 2-1=1 partition
+		0, 0, 0, 0, // partition 0
+		0,            // empty tagged fields
+		0, 0, 39, 16, // timeout 10000
+		0, // empty tagged fields
+	}
+)
+
+func TestElectLeadersRequest(t *testing.T) {
+	request := &ElectLeadersRequest{
+		TopicPartitions: map[string][]int32{"topic": {0}},
+		TimeoutMs:       10000,
+	}
+	testRequest(t, "v0", request, electLeadersRequestV0)
+
+	request = &ElectLeadersRequest{
+		Version:   1,
+		Type:      UncleanElection,
+		TimeoutMs: 10000,
+	}
+	testRequest(t, "v1 all partitions", request, electLeadersRequestV1AllPartitions)
+
+	request = &ElectLeadersRequest{
+		Version:         2,
+		Type:            PreferredElection,
+		TopicPartitions: map[string][]int32{"topic": {0}},
+		TimeoutMs:       10000,
+	}
+	testRequest(t, "v2", request, electLeadersRequestV2)
+}
@@ -0,0 +1,46 @@
+package sarama
+
+import "testing"
+
+var (
+	electLeadersResponseV0 = []byte{
+		0, 0, 0, 0, // throttle time
+		0, 0, 0, 1, // 1 topic
+		0, 5, 't', 'o', 'p', 'i', 'c',
+		0, 0, 0, 1, // 1 partition
+		0, 0, 0, 0, // partition 0
+		0, 0, // no error
+		255, 255, // null error message
+	}
+
+	electLeadersResponseV2 = []byte{
+		0, 0, 0, 0, // throttle time
+		0, 0, // no error
+		2,                          // 2-1=1 topic
+		6, 't', 'o', 'p', 'i', 'c', // topic name "topic" as compact string
+		2,          // 2-1=1 partition
+		0, 0, 0, 0, // partition 0
+		0, 84, // ErrElectionNotNeeded
+		9, 'n', 'o', 't', ' ', 'n', 'e', 'e', 'd', // error message as compact string
+		0, // empty tagged fields
+		0, // empty tagged fields
+		0, // empty tagged fields
+	}
+)
+
+func TestElectLeadersResponse(t *testing.T) {
+	response := &ElectLeadersResponse{
+		ReplicaElectionResults: map[string]map[int32]*PartitionResult{
+			"topic": {0: {ErrorCode: ErrNoError}},
+		},
+	}
+	testResponse(t, "v0", response, electLeadersResponseV0)
+
+	response = &ElectLeadersResponse{
+		Version: 2,
+		ReplicaElectionResults: map[string]map[int32]*PartitionResult{
+			"topic": {0: {ErrorCode: ErrElectionNotNeeded, ErrorMessage: nullString("not need")}},
+		},
+	}
+	testResponse(t, "v2", response, electLeadersResponseV2)
+}
@@ -2,8 +2,7 @@ package sarama
 
 import (
 	"fmt"
-
-	"github.com/rcrowley/go-metrics"
+	"net"
 )
 
 // Encoder is the interface that wraps the basic Encode method.
@@ -18,7 +17,7 @@ type encoderWithHeader interface {
 }
 
 // Encode takes an Encoder and turns it into bytes while potentially recording metrics.
-func encode(e encoder, metricRegistry metrics.Registry) ([]byte, error) {
+func encode(e encoder, recorder MetricsRecorder) ([]byte, error) {
 	if e == nil {
 		return nil, nil
 	}
@@ -36,7 +35,7 @@ func encode(e encoder, metricRegistry metrics.Registry) ([]byte, error) {
 	}
 
 	realEnc.raw = make([]byte, prepEnc.length)
-	realEnc.registry = metricRegistry
+	realEnc.recorder = recorder
 	err = e.encode(&realEnc)
 	if err != nil {
 		return nil, err
@@ -45,6 +44,31 @@ func encode(e encoder, metricRegistry metrics.Registry) ([]byte, error) {
 	return realEnc.raw, nil
 }
 
+// encodeVectored encodes e as encode does, but references the raw bytes of at
+// least vectoredMinBytes, such as the records of the batches, rather than copy
+// them. It returns the buffers to write in order, and their total length.
+func encodeVectored(e encoder, recorder MetricsRecorder) (net.Buffers, int, error) {
+	prepEnc := prepEncoder{vectored: true}
+	if err := e.encode(&prepEnc); err != nil {
+		return nil, 0, err
+	}
+
+	if prepEnc.length < 0 || prepEnc.length > int(MaxRequestSize) {
+		return nil, 0, PacketEncodingError{fmt.Sprintf("invalid request size (%d)", prepEnc.length)}
+	}
+
+	realEnc := realEncoder{
+		raw:      make([]byte, prepEnc.length-prepEnc.external),
+		recorder: recorder,
+		vectored: true,
+	}
+	if err := e.encode(&realEnc); err != nil {
+		return nil, 0, err
+	}
+
+	return realEnc.buffers(), prepEnc.length, nil
+}
+
 // decoder is the interface that wraps the basic Decode method.
 // Anything implementing Decoder can be extracted from bytes using Kafka's encoding rules.
 type decoder interface {
@@ -57,13 +81,14 @@ type versionedDecoder interface {
 
 // decode takes bytes and a decoder and fills the fields of the decoder from the bytes,
 // interpreted using Kafka's encoding rules.
-func decode(buf []byte, in decoder) error {
+func decode(buf []byte, in decoder) (err error) {
 	if buf == nil {
 		return nil
 	}
+	defer recoverDecodingPanic(in, &err)
 
 	helper := realDecoder{raw: buf}
-	err := in.decode(&helper)
+	err = in.decode(&helper)
 	if err != nil {
 		return err
 	}
@@ -76,21 +101,47 @@ func decode(buf []byte, in decoder) error {
 }
 
 func versionedDecode(buf []byte, in versionedDecoder, version int16) error {
-	if buf == nil {
-		return nil
-	}
-
-	helper := realDecoder{raw: buf}
-	err := in.decode(&helper, version)
+	off, err := versionedDecodePrefix(buf, in, version)
 	if err != nil {
 		return err
 	}
 
-	if helper.off != len(buf) {
+	if off != len(buf) {
 		return PacketDecodingError{
-			Info: fmt.Sprintf("invalid length (off=%d, len=%d)", helper.off, len(buf)),
+			Info: fmt.Sprintf("invalid length (off=%d, len=%d)", off, len(buf)),
 		}
 	}
 
 	return nil
 }
+
+// lenientVersionedDecode behaves as versionedDecode but ignores the bytes
+// trailing the decoded structure, such as the fields a newer broker appends.
+func lenientVersionedDecode(buf []byte, in versionedDecoder, version int16) error {
+	_, err := versionedDecodePrefix(buf, in, version)
+	return err
+}
+
+// versionedDecodePrefix decodes in from the start of buf, returning the offset
+// of the bytes following it.
+func versionedDecodePrefix(buf []byte, in versionedDecoder, version int16) (off int, err error) {
+	if buf == nil {
+		return 0, nil
+	}
+	defer recoverDecodingPanic(in, &err)
+
+	helper := realDecoder{raw: buf}
+	if err := in.decode(&helper, version); err != nil {
+		return 0, err
+	}
+	return helper.off, nil
+}
+
+// recoverDecodingPanic turns a panic decoding in, on malformed input the
+// decoders do not check the bounds of, into a PacketDecodingError set to err.
+// It must be deferred.
+func recoverDecodingPanic(in interface{}, err *error) {
+	if r := recover(); r != nil {
+		*err = PacketDecodingError{fmt.Sprintf("malformed %T: %v", in, r)}
+	}
+}
@@ -0,0 +1,70 @@
+package sarama
+
+import (
+	"bytes"
+	"testing"
+	"time"
+)
+
+func TestEncodeVectored(t *testing.T) {
+	large := bytes.Repeat([]byte{'v'}, 2*vectoredMinBytes)
+	small := []byte("small")
+
+	for _, version := range []int16{2, 3, 9} {
+		newRequest := func(values ...[]byte) *request {
+			req := &ProduceRequest{Version: version, RequiredAcks: WaitForAll, Timeout: 1000}
+			for partition, value := range values {
+				if version < 3 {
+					req.AddMessage("topic", int32(partition), &Message{Version: 1, Value: value, Timestamp: time.Unix(1, 0)})
+					continue
+				}
+				batch := &RecordBatch{Version: 2, Codec: CompressionNone, FirstTimestamp: time.Unix(1, 0)}
+				batch.addRecord(&Record{Value: value})
+				batch.addRecord(&Record{Value: value})
+				req.AddBatch("topic", int32(partition), batch)
+			}
+			return &request{correlationID: 1, clientID: "client", body: req}
+		}
+
+		// a single partition, the partitions being encoded in map order
+		r := newRequest(large)
+		expected, err := encode(r, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		bufs, length, err := encodeVectored(r, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(bufs) == 1 {
+			t.Errorf("v%d: expected the large records to be referenced in their own buffer", version)
+		}
+		if actual := bytes.Join(bufs, nil); !bytes.Equal(actual, expected) {
+			t.Errorf("v%d: vectored encoding differs from the encoding", version)
+		} else if length != len(actual) {
+			t.Errorf("v%d: expected a length of %d, got %d", version, len(actual), length)
+		}
+
+		// several partitions, whose lengths and CRCs are checked decoding them
+		values := [][]byte{large, small, large}
+		bufs, _, err = encodeVectored(newRequest(values...), nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		decoded, _, err := decodeRequest(bytes.NewReader(bytes.Join(bufs, nil)))
+		if err != nil {
+			t.Fatalf("v%d: %v", version, err)
+		}
+		for partition, records := range decoded.body.(*ProduceRequest).records["topic"] {
+			var value []byte
+			if version < 3 {
+				value = records.MsgSet.Messages[0].Msg.Value
+			} else {
+				value = records.RecordBatch.Records[1].Value
+			}
+			if !bytes.Equal(value, values[partition]) {
+				t.Errorf("v%d: unexpected value of partition %d", version, partition)
+			}
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package sarama
+
+import (
+	"strconv"
+	"time"
+)
+
+// EndToEndLatencyHeader is the header EndToEndLatency stamps the messages
+// produced with: the time they were sent at, in milliseconds since the epoch.
+const EndToEndLatencyHeader = "sarama-produced-at"
+
+// EndToEndLatency measures how long the messages take from being sent by a
+// producer to being consumed, as a signal of the freshness of the data. It is
+// both a ProducerInterceptor, stamping the messages sent with
+// EndToEndLatencyHeader, and a ConsumerInterceptor, recording the latency of
+// the messages consumed which carry the header as the end-to-end-latency-in-ms
+// histograms, for all topics and by topic. Add it to Producer.Interceptors on
+// the producing side and to Consumer.Interceptors on the consuming side. As the
+// latency is measured across hosts, it includes the skew of their clocks.
+// Headers require Version >= V0_11_0_0, the messages are not stamped
+// otherwise.
+type EndToEndLatency struct {
+	stamp    bool
+	recorder MetricsRecorder
+	now      func() time.Time
+}
+
+// NewEndToEndLatency returns an EndToEndLatency recording the latencies in the
+// metrics of conf, that of the producer or consumer it intercepts the messages
+// of.
+func NewEndToEndLatency(conf *Config) *EndToEndLatency {
+	return &EndToEndLatency{
+		stamp:    conf.Version.IsAtLeast(V0_11_0_0),
+		recorder: conf.metricsRecorder(),
+		now:      time.Now,
+	}
+}
+
+// OnSend implements ProducerInterceptor. The messages already stamped, e.g.
+// retried, keep their header.
+func (l *EndToEndLatency) OnSend(msg *ProducerMessage) {
+	if !l.stamp {
+		return
+	}
+	for _, header := range msg.Headers {
+		if string(header.Key) == EndToEndLatencyHeader {
+			return
+		}
+	}
+	msg.Headers = append(msg.Headers, RecordHeader{
+		Key:   []byte(EndToEndLatencyHeader),
+		Value: []byte(strconv.FormatInt(l.now().UnixNano()/int64(time.Millisecond), 10)),
+	})
+}
+
+// OnConsume implements ConsumerInterceptor.
+func (l *EndToEndLatency) OnConsume(msg *ConsumerMessage) {
+	producedAt, ok := ProducedAt(msg)
+	if !ok || l.recorder == nil {
+		return
+	}
+	latency := int64(l.now().Sub(producedAt) / time.Millisecond)
+	if latency < 0 {
+		latency = 0
+	}
+	l.recorder.Histogram("end-to-end-latency-in-ms", nil).Observe(latency)
+	l.recorder.Histogram("end-to-end-latency-in-ms", MetricLabels{MetricLabelTopic: msg.Topic}).Observe(latency)
+}
+
+// ProducedAt returns the time msg was sent at, as stamped by EndToEndLatency,
+// and whether it was.
+func ProducedAt(msg *ConsumerMessage) (time.Time, bool) {
+	for _, header := range msg.Headers {
+		if header == nil || string(header.Key) != EndToEndLatencyHeader {
+			continue
+		}
+		millis, err := strconv.ParseInt(string(header.Value), 10, 64)
+		if err != nil {
+			return time.Time{}, false
+		}
+		return time.Unix(0, millis*int64(time.Millisecond)), true
+	}
+	return time.Time{}, false
+}
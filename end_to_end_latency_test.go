@@ -0,0 +1,44 @@
+package sarama
+
+import (
+	"testing"
+	"time"
+)
+
+func TestEndToEndLatency(t *testing.T) {
+	recorder := newTestMetricsRecorder()
+	config := NewTestConfig()
+	config.Version = V0_11_0_0
+	config.MetricsRecorder = recorder
+	latency := NewEndToEndLatency(config)
+	sentAt := time.Unix(1700000000, 0)
+	latency.now = func() time.Time { return sentAt }
+
+	msg := &ProducerMessage{Topic: "my_topic", Value: StringEncoder("ABC")}
+	latency.OnSend(msg)
+	latency.OnSend(msg)
+	if len(msg.Headers) != 1 || string(msg.Headers[0].Key) != EndToEndLatencyHeader {
+		t.Fatalf("expected the message to be stamped once, got %+v", msg.Headers)
+	}
+
+	consumed := &ConsumerMessage{Topic: "my_topic", Headers: []*RecordHeader{&msg.Headers[0]}}
+	if producedAt, ok := ProducedAt(consumed); !ok || !producedAt.Equal(sentAt) {
+		t.Errorf("expected the message to be produced at %v, got %v", sentAt, producedAt)
+	}
+	latency.now = func() time.Time { return sentAt.Add(150 * time.Millisecond) }
+	latency.OnConsume(consumed)
+	latency.OnConsume(&ConsumerMessage{Topic: "my_topic"})
+	if ms := recorder.value("end-to-end-latency-in-ms", MetricLabels{MetricLabelTopic: "my_topic"}); ms != 150 {
+		t.Errorf("expected a latency of 150ms for the topic, got %d", ms)
+	}
+	if ms := recorder.value("end-to-end-latency-in-ms", nil); ms != 150 {
+		t.Errorf("expected a latency of 150ms for all topics, got %d", ms)
+	}
+
+	config.Version = V0_10_2_0
+	msg = &ProducerMessage{Topic: "my_topic"}
+	NewEndToEndLatency(config).OnSend(msg)
+	if msg.Headers != nil {
+		t.Error("expected no header before Kafka 0.11")
+	}
+}
@@ -1,14 +1,20 @@
 package sarama
 
 type EndTxnRequest struct {
+	Version           int16
 	TransactionalID   string
 	ProducerID        int64
 	ProducerEpoch     int16
 	TransactionResult bool
 }
 
-func (a *EndTxnRequest) encode(pe packetEncoder) error {
-	if err := pe.putString(a.TransactionalID); err != nil {
+func (a *EndTxnRequest) encode(pe packetEncoder) (err error) {
+	if a.Version >= 3 {
+		err = pe.putCompactString(a.TransactionalID)
+	} else {
+		err = pe.putString(a.TransactionalID)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -18,11 +24,21 @@ func (a *EndTxnRequest) encode(pe packetEncoder) error {
 
 	pe.putBool(a.TransactionResult)
 
+	if a.Version >= 3 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (a *EndTxnRequest) decode(pd packetDecoder, version int16) (err error) {
-	if a.TransactionalID, err = pd.getString(); err != nil {
+	a.Version = version
+	if version >= 3 {
+		a.TransactionalID, err = pd.getCompactString()
+	} else {
+		a.TransactionalID, err = pd.getString()
+	}
+	if err != nil {
 		return err
 	}
 	if a.ProducerID, err = pd.getInt64(); err != nil {
@@ -34,6 +50,11 @@ func (a *EndTxnRequest) decode(pd packetDecoder, version int16) (err error) {
 	if a.TransactionResult, err = pd.getBool(); err != nil {
 		return err
 	}
+	if version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -42,13 +63,25 @@ func (a *EndTxnRequest) key() int16 {
 }
 
 func (a *EndTxnRequest) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (r *EndTxnRequest) headerVersion() int16 {
+	if r.Version >= 3 {
+		return 2
+	}
 	return 1
 }
 
 func (a *EndTxnRequest) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch a.Version {
+	case 3:
+		return V2_8_0_0
+	case 2:
+		return V2_7_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
 }
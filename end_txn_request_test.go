@@ -19,3 +19,23 @@ func TestEndTxnRequest(t *testing.T) {
 
 	testRequest(t, "", req, endTxnRequest)
 }
+
+var endTxnRequestV3 = []byte{
+	4, 't', 'x', 'n',
+	0, 0, 0, 0, 0, 0, 31, 64,
+	0, 1,
+	1,
+	0,
+}
+
+func TestEndTxnRequestV3(t *testing.T) {
+	req := &EndTxnRequest{
+		Version:           3,
+		TransactionalID:   "txn",
+		ProducerID:        8000,
+		ProducerEpoch:     1,
+		TransactionResult: true,
+	}
+
+	testRequest(t, "", req, endTxnRequestV3)
+}
@@ -5,6 +5,7 @@ import (
 )
 
 type EndTxnResponse struct {
+	Version      int16
 	ThrottleTime time.Duration
 	Err          KError
 }
@@ -12,10 +13,15 @@ type EndTxnResponse struct {
 func (e *EndTxnResponse) encode(pe packetEncoder) error {
 	pe.putInt32(int32(e.ThrottleTime / time.Millisecond))
 	pe.putInt16(int16(e.Err))
+	if e.Version >= 3 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
 func (e *EndTxnResponse) decode(pd packetDecoder, version int16) (err error) {
+	e.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
@@ -28,21 +34,51 @@ func (e *EndTxnResponse) decode(pd packetDecoder, version int16) (err error) {
 	}
 	e.Err = KError(kerr)
 
+	if version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
 func (e *EndTxnResponse) key() int16 {
-	return 25
+	return 26
 }
 
 func (e *EndTxnResponse) version() int16 {
-	return 0
+	return e.Version
 }
 
 func (r *EndTxnResponse) headerVersion() int16 {
+	if r.Version >= 3 {
+		return 1
+	}
 	return 0
 }
 
 func (e *EndTxnResponse) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch e.Version {
+	case 3:
+		return V2_8_0_0
+	case 2:
+		return V2_7_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
+}
+
+func (e *EndTxnResponse) throttleTime() time.Duration {
+	return e.ThrottleTime
+}
+
+func (e *EndTxnResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
+}
+
+func (e *EndTxnResponse) hasErrorCode() bool {
+	return e.Err != ErrNoError
 }
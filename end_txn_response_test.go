@@ -18,3 +18,19 @@ func TestEndTxnResponse(t *testing.T) {
 
 	testResponse(t, "", resp, endTxnResponse)
 }
+
+var endTxnResponseV3 = []byte{
+	0, 0, 0, 100,
+	0, 49,
+	0,
+}
+
+func TestEndTxnResponseV3(t *testing.T) {
+	resp := &EndTxnResponse{
+		Version:      3,
+		ThrottleTime: 100 * time.Millisecond,
+		Err:          ErrInvalidProducerIDMapping,
+	}
+
+	testResponse(t, "", resp, endTxnResponseV3)
+}
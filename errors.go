@@ -67,6 +67,15 @@ var ErrDeleteRecords = errors.New("kafka server: failed to delete records")
 // ErrCreateACLs is the type of error returned when ACL creation failed
 var ErrCreateACLs = errors.New("kafka server: failed to create one or more ACL rules")
 
+// ErrTopicRecreated is returned when the ID of a topic changed, as it was
+// deleted and recreated under the same name, so that the offsets within the
+// previous topic do not apply to the new one. Requires Kafka 2.8 or higher.
+var ErrTopicRecreated = errors.New("kafka: topic was deleted and recreated")
+
+// ErrNotSupportedByControllers is returned by a ClusterAdmin created by
+// NewClusterAdminFromControllers for the operations which require the brokers.
+var ErrNotSupportedByControllers = errors.New("kafka: operation not supported when talking to the KRaft controllers")
+
 // MultiErrorFormat specifies the formatter applied to format multierrors. The
 // default implementation is a consensed version of the hashicorp/go-multierror
 // default one
@@ -138,6 +147,36 @@ func (err PacketDecodingError) Error() string {
 	return fmt.Sprintf("kafka: error decoding packet: %s", err.Info)
 }
 
+// UnsupportedApiVersionError is returned instead of sending a request to a
+// broker which does not support its version, according to the versions the
+// broker returned to the ApiVersionsRequest (see Config.ApiVersionsRequest),
+// e.g. as Kafka 4.0 removed the oldest versions of many APIs. Raising Config.Version
+// selects more recent versions. It matches ErrUnsupportedVersion with
+// errors.Is.
+type UnsupportedApiVersionError struct {
+	// ApiKey is the key of the API, named by ApiName
+	ApiKey  int16
+	ApiName string
+	// Version is the version of the request
+	Version int16
+	// MinVersion and MaxVersion are the versions of the API the broker
+	// supports, both -1 if it does not support the API at all
+	MinVersion int16
+	MaxVersion int16
+}
+
+func (err UnsupportedApiVersionError) Error() string {
+	if err.MinVersion < 0 {
+		return fmt.Sprintf("kafka: broker does not support the %s API (key %d)", err.ApiName, err.ApiKey)
+	}
+	return fmt.Sprintf("kafka: broker does not support version %d of the %s API (key %d), only versions %d to %d",
+		err.Version, err.ApiName, err.ApiKey, err.MinVersion, err.MaxVersion)
+}
+
+func (err UnsupportedApiVersionError) Is(target error) bool {
+	return target == ErrUnsupportedVersion
+}
+
 // ConfigurationError is the type of error returned from a constructor (e.g. NewClient, or NewConsumer)
 // when the specified configuration is invalid.
 type ConfigurationError string
@@ -242,6 +281,8 @@ const (
 	ErrGroupSubscribedToTopic             KError = 86
 	ErrInvalidRecord                      KError = 87
 	ErrUnstableOffsetCommit               KError = 88
+	ErrUnknownSubscriptionID              KError = 117
+	ErrTelemetryTooLarge                  KError = 118
 )
 
 func (err KError) Error() string {
@@ -428,6 +469,10 @@ func (err KError) Error() string {
 		return "kafka server: This record has failed the validation on broker and hence will be rejected"
 	case ErrUnstableOffsetCommit:
 		return "kafka server: There are unstable offsets that need to be cleared"
+	case ErrUnknownSubscriptionID:
+		return "kafka server: Client sent a push telemetry request with an invalid or outdated subscription ID"
+	case ErrTelemetryTooLarge:
+		return "kafka server: Client sent a push telemetry request larger than the maximum size the broker will accept"
 	}
 
 	return fmt.Sprintf("Unknown error, how did this happen? Error code = %d", err)
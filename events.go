@@ -0,0 +1,176 @@
+package sarama
+
+import (
+	"sync"
+	"time"
+)
+
+// ClientEventType is the type of a ClientEvent.
+type ClientEventType int
+
+const (
+	// EventBrokerConnected is published once the connection to a broker is
+	// established, and authenticated if SASL is enabled.
+	EventBrokerConnected ClientEventType = iota
+	// EventBrokerConnectFailed is published when a broker cannot be dialed,
+	// with the error in Err.
+	EventBrokerConnectFailed
+	// EventBrokerDisconnected is published when the connection to a broker
+	// is closed.
+	EventBrokerDisconnected
+	// EventAuthenticationFailed is published when the SASL authentication
+	// with a broker fails, with the error in Err.
+	EventAuthenticationFailed
+	// EventMetadataUpdated is published whenever the client stores metadata
+	// received from the cluster, with the error of the update in Err if
+	// some topics could not be updated.
+	EventMetadataUpdated
+	// EventLeaderChanged is published when the metadata of a partition shows
+	// a leader other than the one previously known, in Leader and
+	// PreviousLeader.
+	EventLeaderChanged
+	// EventThrottled is published when a broker reports that it throttled
+	// the client, for the duration in Throttle.
+	EventThrottled
+	// EventTopicRecreated is published when the metadata of Topic shows an
+	// ID other than the one previously known, as it was deleted and
+	// recreated, with ErrTopicRecreated in Err. Requires Kafka 2.8 or
+	// higher.
+	EventTopicRecreated
+)
+
+func (t ClientEventType) String() string {
+	switch t {
+	case EventBrokerConnected:
+		return "BrokerConnected"
+	case EventBrokerConnectFailed:
+		return "BrokerConnectFailed"
+	case EventBrokerDisconnected:
+		return "BrokerDisconnected"
+	case EventAuthenticationFailed:
+		return "AuthenticationFailed"
+	case EventMetadataUpdated:
+		return "MetadataUpdated"
+	case EventLeaderChanged:
+		return "LeaderChanged"
+	case EventThrottled:
+		return "Throttled"
+	case EventTopicRecreated:
+		return "TopicRecreated"
+	}
+	return "Unknown"
+}
+
+// ClientEvent is a notification of a transition in the state of a Client, see
+// Client.Subscribe. The fields which do not apply to its Type are left
+// zero.
+type ClientEvent struct {
+	Type ClientEventType
+	Time time.Time
+
+	// Broker is the broker the event is about.
+	Broker *Broker
+	// Topic and Partition are the partition whose leader changed.
+	Topic     string
+	Partition int32
+	// Leader and PreviousLeader are the IDs of the new and previous leaders
+	// of the partition, -1 when it has none.
+	Leader         int32
+	PreviousLeader int32
+	// Throttle is how long the broker throttled the client.
+	Throttle time.Duration
+	// API is the name of the API of the throttled request, e.g. "Produce".
+	API string
+	// Err is the error which caused the event.
+	Err error
+}
+
+// eventSubscriber is a handler registered with Client.Subscribe.
+type eventSubscriber struct {
+	id      int
+	handler func(*ClientEvent)
+}
+
+// eventBus hands the events published by a client and its brokers to the
+// subscribed handlers, in order, from a goroutine of its own so that
+// publishing never blocks on the handlers.
+type eventBus struct {
+	lock        sync.RWMutex
+	subscribers []eventSubscriber
+	nextID      int
+	stopped     bool
+	events      chan *ClientEvent
+}
+
+func newEventBus(bufferSize int) *eventBus {
+	bus := &eventBus{events: make(chan *ClientEvent, bufferSize)}
+	go withRecover(bus.dispatch)
+	return bus
+}
+
+func (bus *eventBus) subscribe(handler func(*ClientEvent)) func() {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+
+	id := bus.nextID
+	bus.nextID++
+	bus.subscribers = append(bus.subscribers, eventSubscriber{id: id, handler: handler})
+
+	var once sync.Once
+	return func() {
+		once.Do(func() {
+			bus.lock.Lock()
+			defer bus.lock.Unlock()
+			for i, subscriber := range bus.subscribers {
+				if subscriber.id == id {
+					bus.subscribers = append(bus.subscribers[:i:i], bus.subscribers[i+1:]...)
+					break
+				}
+			}
+		})
+	}
+}
+
+// publish queues event for the subscribers, if there are any. The event is
+// dropped if the subscribers do not keep up with the events, rather than
+// holding up the publisher. It is safe to call on a nil eventBus.
+func (bus *eventBus) publish(event *ClientEvent) {
+	if bus == nil {
+		return
+	}
+
+	bus.lock.RLock()
+	defer bus.lock.RUnlock()
+	if bus.stopped || len(bus.subscribers) == 0 {
+		return
+	}
+
+	event.Time = time.Now()
+	select {
+	case bus.events <- event:
+	default:
+		infof("client/events dropped a %s event as the subscribers are not keeping up\n", event.Type)
+	}
+}
+
+func (bus *eventBus) dispatch() {
+	for event := range bus.events {
+		bus.lock.RLock()
+		subscribers := bus.subscribers
+		bus.lock.RUnlock()
+
+		for _, subscriber := range subscribers {
+			subscriber.handler(event)
+		}
+	}
+}
+
+// close stops the bus once the events already published are dispatched.
+func (bus *eventBus) close() {
+	bus.lock.Lock()
+	defer bus.lock.Unlock()
+	if !bus.stopped {
+		bus.stopped = true
+		close(bus.events)
+	}
+}
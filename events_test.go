@@ -0,0 +1,113 @@
+package sarama
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientEvents(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	leader1 := NewMockBroker(t, 2)
+	defer leader1.Close()
+	leader2 := NewMockBroker(t, 3)
+	defer leader2.Close()
+
+	metadataResponse := new(MetadataResponse)
+	metadataResponse.AddBroker(leader1.Addr(), leader1.BrokerID())
+	metadataResponse.AddBroker(leader2.Addr(), leader2.BrokerID())
+	metadataResponse.AddTopicPartition("my_topic", 0, leader1.BrokerID(), nil, nil, nil, ErrNoError)
+	seedBroker.Returns(metadataResponse)
+
+	conf := NewTestConfig()
+	conf.Metadata.RefreshFrequency = 0
+	client, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	events := make(chan *ClientEvent, 10)
+	unsubscribe := client.Subscribe(func(event *ClientEvent) {
+		events <- event
+	})
+
+	metadataResponse = new(MetadataResponse)
+	metadataResponse.AddBroker(leader1.Addr(), leader1.BrokerID())
+	metadataResponse.AddBroker(leader2.Addr(), leader2.BrokerID())
+	metadataResponse.AddTopicPartition("my_topic", 0, leader2.BrokerID(), nil, nil, nil, ErrNoError)
+	seedBroker.Returns(metadataResponse)
+	if err := client.RefreshMetadata("my_topic"); err != nil {
+		t.Fatal(err)
+	}
+
+	event := nextEvent(t, events)
+	if event.Type != EventLeaderChanged || event.Topic != "my_topic" || event.Partition != 0 ||
+		event.Leader != leader2.BrokerID() || event.PreviousLeader != leader1.BrokerID() {
+		t.Errorf("expected the leader of my_topic/0 to change from #2 to #3, got %+v", event)
+	}
+	if event := nextEvent(t, events); event.Type != EventMetadataUpdated || event.Err != nil {
+		t.Errorf("expected the metadata to be updated, got %+v", event)
+	}
+
+	broker, err := client.Leader("my_topic", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if event := nextEvent(t, events); event.Type != EventBrokerConnected || event.Broker != broker {
+		t.Errorf("expected the new leader to be connected, got %+v", event)
+	}
+
+	unsubscribe()
+	unsubscribe()
+	_ = broker.Close()
+	select {
+	case event := <-events:
+		t.Errorf("expected no event once unsubscribed, got %+v", event)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func TestEventBusDropsEventsWhenBusy(t *testing.T) {
+	bus := newEventBus(1)
+	defer bus.close()
+
+	block := make(chan none)
+	received := make(chan ClientEventType, 3)
+	bus.subscribe(func(event *ClientEvent) {
+		<-block
+		received <- event.Type
+	})
+
+	bus.publish(&ClientEvent{Type: EventBrokerConnected})
+	// wait for the first event to be handed to the blocked handler
+	for len(bus.events) > 0 {
+		time.Sleep(time.Millisecond)
+	}
+	bus.publish(&ClientEvent{Type: EventThrottled})
+	bus.publish(&ClientEvent{Type: EventBrokerDisconnected})
+	close(block)
+
+	if got := <-received; got != EventBrokerConnected {
+		t.Errorf("expected %s, got %s", EventBrokerConnected, got)
+	}
+	if got := <-received; got != EventThrottled {
+		t.Errorf("expected %s, got %s", EventThrottled, got)
+	}
+	select {
+	case got := <-received:
+		t.Errorf("expected the last event to be dropped, got %s", got)
+	case <-time.After(50 * time.Millisecond):
+	}
+}
+
+func nextEvent(t *testing.T, events <-chan *ClientEvent) *ClientEvent {
+	t.Helper()
+	select {
+	case event := <-events:
+		return event
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for an event")
+		return nil
+	}
+}
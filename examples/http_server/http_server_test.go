@@ -14,7 +14,11 @@ import (
 // and one data collector entry. Let's assume both will succeed.
 // We should return a HTTP 200 status.
 func TestCollectSuccessfully(t *testing.T) {
-	dataCollectorMock := mocks.NewSyncProducer(t, nil)
+	// The mock producer returns the partition of the message, which we
+	// pin to partition 0.
+	config := mocks.NewTestConfig()
+	config.Producer.Partitioner = sarama.NewManualPartitioner
+	dataCollectorMock := mocks.NewSyncProducer(t, config)
 	dataCollectorMock.ExpectSendMessageAndSucceed()
 
 	accessLogProducerMock := mocks.NewAsyncProducer(t, nil)
@@ -0,0 +1,539 @@
+package sarama
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// FailoverConfig configures how a FailoverClient checks its clusters and fails
+// over between them.
+type FailoverConfig struct {
+	// HealthCheckInterval is how often the clusters are checked (defaults to
+	// 10s).
+	HealthCheckInterval time.Duration
+	// FailureThreshold is the number of consecutive failed checks of the
+	// active cluster after which the client fails over to the first healthy
+	// standby, i.e. whose last check passed (defaults to 3).
+	FailureThreshold int
+	// FailBack makes the client return to the primary cluster once it passed
+	// FailureThreshold consecutive checks again.
+	FailBack bool
+	// HealthCheck checks a cluster through its client, returning an error if
+	// it is unavailable. It defaults to refreshing the metadata of the
+	// cluster.
+	HealthCheck func(client Client) error
+	// TranslateOffsets returns the offsets from which the consumer group
+	// resumes on the cluster to when failing over from the cluster from, e.g.
+	// from the checkpoints of MirrorMaker 2. They are committed before the
+	// group joins the new cluster. If it is nil, the group resumes from the
+	// offsets already committed on the new cluster.
+	TranslateOffsets func(group string, from, to Client) (map[string]map[int32]GroupOffset, error)
+	// OnFailover, if set, is called when the active cluster changes, with the
+	// indexes of the clusters, 0 being the primary.
+	OnFailover func(from, to int)
+}
+
+// NewFailoverConfig returns a new FailoverConfig with the default values.
+func NewFailoverConfig() *FailoverConfig {
+	return &FailoverConfig{
+		HealthCheckInterval: 10 * time.Second,
+		FailureThreshold:    3,
+		HealthCheck: func(client Client) error {
+			return client.RefreshMetadata()
+		},
+	}
+}
+
+// Validate checks a FailoverConfig instance. It will return a
+// ConfigurationError if the specified values don't make sense.
+func (c *FailoverConfig) Validate() error {
+	switch {
+	case c.HealthCheckInterval <= 0:
+		return ConfigurationError("HealthCheckInterval must be > 0")
+	case c.FailureThreshold <= 0:
+		return ConfigurationError("FailureThreshold must be > 0")
+	case c.HealthCheck == nil:
+		return ConfigurationError("HealthCheck must be set")
+	}
+	return nil
+}
+
+// FailoverClient manages the clients of a primary cluster and of one or more
+// standby clusters, checks their health, and fails over to a standby on a
+// sustained outage of the active cluster. The producers and consumer groups it
+// creates follow the active cluster.
+type FailoverClient struct {
+	conf     FailoverConfig
+	clusters []Client
+
+	lock sync.RWMutex
+	// active is the index of the active cluster
+	active int
+	// switched is closed and replaced when the active cluster changes
+	switched chan none
+	// failures and successes are the numbers of consecutive failed and
+	// passed checks of each cluster
+	failures  []int
+	successes []int
+
+	closer    chan none
+	closeOnce sync.Once
+	wg        sync.WaitGroup
+}
+
+// NewFailoverClient creates the clients of the clusters, each given by its
+// broker addresses, the first being the primary cluster and the others the
+// standbys in order of preference, all with conf. If failover is nil, the
+// defaults of NewFailoverConfig are used.
+func NewFailoverClient(clusters [][]string, conf *Config, failover *FailoverConfig) (*FailoverClient, error) {
+	clients := make([]Client, 0, len(clusters))
+	for _, addrs := range clusters {
+		client, err := NewClient(addrs, conf)
+		if err != nil {
+			for _, client := range clients {
+				_ = client.Close()
+			}
+			return nil, err
+		}
+		clients = append(clients, client)
+	}
+
+	fc, err := NewFailoverClientFromClients(clients, failover)
+	if err != nil {
+		for _, client := range clients {
+			_ = client.Close()
+		}
+		return nil, err
+	}
+	return fc, nil
+}
+
+// NewFailoverClientFromClients creates a FailoverClient from the clients of the
+// clusters, the first being the primary cluster and the others the standbys in
+// order of preference. The clients are closed by FailoverClient.Close.
+func NewFailoverClientFromClients(clients []Client, failover *FailoverConfig) (*FailoverClient, error) {
+	if failover == nil {
+		failover = NewFailoverConfig()
+	}
+	if err := failover.Validate(); err != nil {
+		return nil, err
+	}
+	if len(clients) < 2 {
+		return nil, ConfigurationError("a FailoverClient requires a primary cluster and at least one standby")
+	}
+
+	fc := &FailoverClient{
+		conf:      *failover,
+		clusters:  clients,
+		switched:  make(chan none),
+		failures:  make([]int, len(clients)),
+		successes: make([]int, len(clients)),
+		closer:    make(chan none),
+	}
+	fc.wg.Add(1)
+	go withRecover(fc.healthChecker)
+	return fc, nil
+}
+
+// Active returns the client of the active cluster.
+func (fc *FailoverClient) Active() Client {
+	fc.lock.RLock()
+	defer fc.lock.RUnlock()
+	return fc.clusters[fc.active]
+}
+
+// ActiveCluster returns the index of the active cluster, 0 being the primary.
+func (fc *FailoverClient) ActiveCluster() int {
+	fc.lock.RLock()
+	defer fc.lock.RUnlock()
+	return fc.active
+}
+
+// Cluster returns the client of the cluster at index.
+func (fc *FailoverClient) Cluster(index int) Client {
+	return fc.clusters[index]
+}
+
+// Failover makes the cluster at index the active one, e.g. to fail over by
+// hand.
+func (fc *FailoverClient) Failover(index int) {
+	fc.lock.Lock()
+	from := fc.active
+	if from == index {
+		fc.lock.Unlock()
+		return
+	}
+	fc.active = index
+	close(fc.switched)
+	fc.switched = make(chan none)
+	fc.lock.Unlock()
+
+	warnf("failover/%d failed over to cluster %d\n", from, index)
+	if fc.conf.OnFailover != nil {
+		fc.conf.OnFailover(from, index)
+	}
+}
+
+// current returns the index of the active cluster and a channel closed once
+// it changes.
+func (fc *FailoverClient) current() (int, <-chan none) {
+	fc.lock.RLock()
+	defer fc.lock.RUnlock()
+	return fc.active, fc.switched
+}
+
+// Close stops checking the clusters and closes their clients. The producers
+// and consumer groups created from the FailoverClient must be closed first.
+func (fc *FailoverClient) Close() error {
+	var err error
+	fc.closeOnce.Do(func() {
+		close(fc.closer)
+		fc.wg.Wait()
+		for _, client := range fc.clusters {
+			if cerr := client.Close(); cerr != nil && err == nil {
+				err = cerr
+			}
+		}
+	})
+	return err
+}
+
+func (fc *FailoverClient) healthChecker() {
+	defer fc.wg.Done()
+
+	ticker := time.NewTicker(fc.conf.HealthCheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ticker.C:
+			fc.checkClusters()
+		case <-fc.closer:
+			return
+		}
+	}
+}
+
+// checkClusters checks all the clusters, then fails over if the active one
+// failed too many times in a row.
+func (fc *FailoverClient) checkClusters() {
+	errs := make([]error, len(fc.clusters))
+	var wg sync.WaitGroup
+	for i, client := range fc.clusters {
+		wg.Add(1)
+		go withRecover(func(i int, client Client) func() {
+			return func() {
+				defer wg.Done()
+				errs[i] = fc.conf.HealthCheck(client)
+			}
+		}(i, client))
+	}
+	wg.Wait()
+
+	fc.lock.Lock()
+	for i, err := range errs {
+		if err != nil {
+			warnf("failover/%d health check failed: %s\n", i, err)
+			fc.failures[i]++
+			fc.successes[i] = 0
+		} else {
+			fc.failures[i] = 0
+			fc.successes[i]++
+		}
+	}
+	active := fc.active
+	target := -1
+	if fc.failures[active] >= fc.conf.FailureThreshold {
+		for i := range fc.clusters {
+			if i != active && fc.failures[i] == 0 {
+				target = i
+				break
+			}
+		}
+	} else if fc.conf.FailBack && active != 0 && fc.successes[0] >= fc.conf.FailureThreshold {
+		target = 0
+	}
+	fc.lock.Unlock()
+
+	if target >= 0 {
+		fc.Failover(target)
+	}
+}
+
+// NewSyncProducer returns a SyncProducer sending the messages to the active
+// cluster. The messages which failed on a cluster are not resent to the next
+// one, the caller retries them as with any error.
+func (fc *FailoverClient) NewSyncProducer() (SyncProducer, error) {
+	p := &failoverSyncProducer{fc: fc, cluster: -1}
+	if _, err := p.acquire(); err != nil {
+		return nil, err
+	}
+	p.lock.RUnlock()
+	return p, nil
+}
+
+type failoverSyncProducer struct {
+	fc *FailoverClient
+
+	// lock is held for reading while sending, and for writing while the
+	// producer is replaced
+	lock     sync.RWMutex
+	cluster  int
+	producer SyncProducer
+	closed   bool
+}
+
+// acquire returns the producer of the active cluster, with lock held for
+// reading.
+func (p *failoverSyncProducer) acquire() (SyncProducer, error) {
+	for {
+		p.lock.RLock()
+		if p.closed {
+			p.lock.RUnlock()
+			return nil, ErrShuttingDown
+		}
+		if p.producer != nil && p.cluster == p.fc.ActiveCluster() {
+			return p.producer, nil
+		}
+		p.lock.RUnlock()
+
+		if err := p.replace(); err != nil {
+			return nil, err
+		}
+	}
+}
+
+// replace closes the producer of the previous cluster and creates one for the
+// active cluster.
+func (p *failoverSyncProducer) replace() error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	active := p.fc.ActiveCluster()
+	if p.closed || (p.producer != nil && p.cluster == active) {
+		return nil
+	}
+	if p.producer != nil {
+		if err := p.producer.Close(); err != nil {
+			warnf("failover/%d error while closing the producer: %s\n", p.cluster, err)
+		}
+		p.producer = nil
+	}
+
+	producer, err := NewSyncProducerFromClient(p.fc.Cluster(active))
+	if err != nil {
+		return err
+	}
+	p.producer, p.cluster = producer, active
+	return nil
+}
+
+func (p *failoverSyncProducer) SendMessage(msg *ProducerMessage) (partition int32, offset int64, err error) {
+	producer, err := p.acquire()
+	if err != nil {
+		return -1, -1, err
+	}
+	defer p.lock.RUnlock()
+	return producer.SendMessage(msg)
+}
+
+func (p *failoverSyncProducer) SendMessages(msgs []*ProducerMessage) error {
+	producer, err := p.acquire()
+	if err != nil {
+		return err
+	}
+	defer p.lock.RUnlock()
+	return producer.SendMessages(msgs)
+}
+
+func (p *failoverSyncProducer) Close() error {
+	return p.CloseContext(context.Background())
+}
+
+func (p *failoverSyncProducer) CloseContext(ctx context.Context) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.closed {
+		return nil
+	}
+	p.closed = true
+	if p.producer == nil {
+		return nil
+	}
+	return p.producer.CloseContext(ctx)
+}
+
+// NewConsumerGroup returns a ConsumerGroup consuming from the active cluster.
+// When the cluster changes, the running Consume returns nil, as on a
+// rebalance, and the next call joins the group on the new cluster, after
+// committing the offsets of FailoverConfig.TranslateOffsets.
+func (fc *FailoverClient) NewConsumerGroup(groupID string) (ConsumerGroup, error) {
+	g := &failoverConsumerGroup{
+		fc:      fc,
+		groupID: groupID,
+		cluster: -1,
+		errors:  make(chan error, fc.Active().Config().ChannelBufferSize),
+		closed:  make(chan none),
+	}
+	if _, _, err := g.current(); err != nil {
+		return nil, err
+	}
+	return g, nil
+}
+
+type failoverConsumerGroup struct {
+	fc      *FailoverClient
+	groupID string
+	errors  chan error
+
+	lock    sync.Mutex
+	cluster int
+	group   ConsumerGroup
+	closed  chan none
+	// forwarders forward the errors of the groups of each cluster
+	forwarders sync.WaitGroup
+}
+
+// current returns the group of the active cluster, replacing the group of the
+// previous cluster, and a channel closed once the active cluster changes.
+func (g *failoverConsumerGroup) current() (ConsumerGroup, <-chan none, error) {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+
+	select {
+	case <-g.closed:
+		return nil, nil, ErrClosedConsumerGroup
+	default:
+	}
+
+	active, switched := g.fc.current()
+	if g.group != nil && g.cluster == active {
+		return g.group, switched, nil
+	}
+
+	if g.group != nil {
+		if err := g.group.Close(); err != nil {
+			warnf("failover/%d error while closing consumer group %s: %s\n", g.cluster, g.groupID, err)
+		}
+		g.group = nil
+
+		if translate := g.fc.conf.TranslateOffsets; translate != nil {
+			to := g.fc.Cluster(active)
+			offsets, err := translate(g.groupID, g.fc.Cluster(g.cluster), to)
+			if err == nil && len(offsets) > 0 {
+				err = commitGroupOffsets(to, g.groupID, offsets)
+			}
+			if err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+
+	group, err := NewConsumerGroupFromClient(g.groupID, g.fc.Cluster(active))
+	if err != nil {
+		return nil, nil, err
+	}
+	g.group, g.cluster = group, active
+
+	g.forwarders.Add(1)
+	go withRecover(func() {
+		defer g.forwarders.Done()
+		for err := range group.Errors() {
+			g.errors <- err
+		}
+	})
+	return group, switched, nil
+}
+
+func (g *failoverConsumerGroup) Consume(ctx context.Context, topics []string, handler ConsumerGroupHandler) error {
+	group, switched, err := g.current()
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	go withRecover(func() {
+		select {
+		case <-switched:
+			cancel()
+		case <-ctx.Done():
+		}
+	})
+
+	err = group.Consume(ctx, topics, handler)
+	select {
+	case <-switched:
+		// the next call joins the group on the new cluster
+		return nil
+	default:
+		return err
+	}
+}
+
+func (g *failoverConsumerGroup) Errors() <-chan error { return g.errors }
+
+func (g *failoverConsumerGroup) Close() error {
+	return g.CloseContext(context.Background())
+}
+
+func (g *failoverConsumerGroup) CloseContext(ctx context.Context) (err error) {
+	g.lock.Lock()
+	select {
+	case <-g.closed:
+		g.lock.Unlock()
+		return nil
+	default:
+	}
+	close(g.closed)
+	if g.group != nil {
+		err = g.group.CloseContext(ctx)
+	}
+	g.lock.Unlock()
+
+	go withRecover(func() {
+		g.forwarders.Wait()
+		close(g.errors)
+	})
+	return err
+}
+
+func (g *failoverConsumerGroup) Pause(partitions map[string][]int32) {
+	if group := g.currentGroup(); group != nil {
+		group.Pause(partitions)
+	}
+}
+
+func (g *failoverConsumerGroup) Resume(partitions map[string][]int32) {
+	if group := g.currentGroup(); group != nil {
+		group.Resume(partitions)
+	}
+}
+
+func (g *failoverConsumerGroup) PauseAll() {
+	if group := g.currentGroup(); group != nil {
+		group.PauseAll()
+	}
+}
+
+func (g *failoverConsumerGroup) ResumeAll() {
+	if group := g.currentGroup(); group != nil {
+		group.ResumeAll()
+	}
+}
+
+// DebugState reports the state of the group of the active cluster, or the
+// state of the client of the active cluster until the group is consumed.
+func (g *failoverConsumerGroup) DebugState() ConsumerGroupDebugState {
+	if group := g.currentGroup(); group != nil {
+		return group.DebugState()
+	}
+	return ConsumerGroupDebugState{GroupID: g.groupID, Client: g.fc.Active().DebugState()}
+}
+
+func (g *failoverConsumerGroup) currentGroup() ConsumerGroup {
+	g.lock.Lock()
+	defer g.lock.Unlock()
+	return g.group
+}
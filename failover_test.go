@@ -0,0 +1,123 @@
+package sarama
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+func newFailoverTestCluster(t *testing.T) (*MockBroker, Client) {
+	t.Helper()
+	mb := NewMockBroker(t, 1)
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(mb.Addr(), mb.BrokerID()).
+			SetLeader("my_topic", 0, mb.BrokerID()),
+		"ProduceRequest": NewMockProduceResponse(t),
+	})
+
+	conf := NewTestConfig()
+	conf.Producer.Return.Successes = true
+	client, err := NewClient([]string{mb.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return mb, client
+}
+
+func TestFailoverClientHealthChecks(t *testing.T) {
+	primary, primaryClient := newFailoverTestCluster(t)
+	defer primary.Close()
+	standby, standbyClient := newFailoverTestCluster(t)
+	defer standby.Close()
+
+	var lock sync.Mutex
+	primaryDown := true
+	var failovers [][2]int
+	conf := NewFailoverConfig()
+	conf.HealthCheckInterval = time.Hour
+	conf.FailureThreshold = 2
+	conf.FailBack = true
+	conf.HealthCheck = func(client Client) error {
+		lock.Lock()
+		defer lock.Unlock()
+		if client == primaryClient && primaryDown {
+			return errors.New("down")
+		}
+		return nil
+	}
+	conf.OnFailover = func(from, to int) {
+		failovers = append(failovers, [2]int{from, to})
+	}
+
+	fc, err := NewFailoverClientFromClients([]Client{primaryClient, standbyClient}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, fc)
+
+	fc.checkClusters()
+	if fc.ActiveCluster() != 0 {
+		t.Error("expected a single failed check not to fail over")
+	}
+	fc.checkClusters()
+	if fc.ActiveCluster() != 1 || fc.Active() != standbyClient {
+		t.Fatal("expected to fail over to the standby")
+	}
+
+	lock.Lock()
+	primaryDown = false
+	lock.Unlock()
+	fc.checkClusters()
+	fc.checkClusters()
+	if fc.ActiveCluster() != 0 {
+		t.Error("expected to fail back to the primary once healthy")
+	}
+	if len(failovers) != 2 || failovers[0] != [2]int{0, 1} || failovers[1] != [2]int{1, 0} {
+		t.Errorf("unexpected failovers %v", failovers)
+	}
+}
+
+func TestFailoverClientSyncProducer(t *testing.T) {
+	primary, primaryClient := newFailoverTestCluster(t)
+	defer primary.Close()
+	standby, standbyClient := newFailoverTestCluster(t)
+	defer standby.Close()
+
+	conf := NewFailoverConfig()
+	conf.HealthCheckInterval = time.Hour
+	fc, err := NewFailoverClientFromClients([]Client{primaryClient, standbyClient}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, fc)
+
+	producer, err := fc.NewSyncProducer()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err := producer.SendMessage(&ProducerMessage{Topic: "my_topic", Value: StringEncoder("a")}); err != nil {
+		t.Fatal(err)
+	}
+	fc.Failover(1)
+	if _, _, err := producer.SendMessage(&ProducerMessage{Topic: "my_topic", Value: StringEncoder("b")}); err != nil {
+		t.Fatal(err)
+	}
+	safeClose(t, producer)
+
+	if _, _, err := producer.SendMessage(&ProducerMessage{Topic: "my_topic"}); !errors.Is(err, ErrShuttingDown) {
+		t.Errorf("expected ErrShuttingDown once closed, got %v", err)
+	}
+	for _, cluster := range []*MockBroker{primary, standby} {
+		produced := 0
+		for _, message := range cluster.History() {
+			if _, ok := message.Request.(*ProduceRequest); ok {
+				produced++
+			}
+		}
+		if produced != 1 {
+			t.Errorf("expected a message to be produced to each cluster, got %d to %s", produced, cluster.Addr())
+		}
+	}
+}
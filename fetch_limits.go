@@ -0,0 +1,84 @@
+package sarama
+
+import (
+	"errors"
+	"fmt"
+	"sync/atomic"
+)
+
+// maxInt is the largest int, to size the slices on 32 bit platforms.
+const maxInt = int64(^uint(0) >> 1)
+
+// FetchLimitError is returned when decoding a fetch response would go over
+// Consumer.Fetch.MaxDecodedBytes or Consumer.Fetch.MaxBatchRecords, before
+// the memory is allocated.
+type FetchLimitError struct {
+	// Limit is the field of Consumer.Fetch gone over
+	Limit string
+	// Max is the value of the limit
+	Max int64
+}
+
+func (err FetchLimitError) Error() string {
+	return fmt.Sprintf("kafka: fetch response over Consumer.Fetch.%s (%d)", err.Limit, err.Max)
+}
+
+// fetchLimits bounds the memory decoding the record batches of a fetch
+// response allocates. It is shared by the blocks of the response, which the
+// partition consumers may decode concurrently, and nil when unlimited.
+type fetchLimits struct {
+	// decoded is the size of the records decoded so far, first to be aligned
+	// for the atomic operations
+	decoded    int64
+	maxBytes   int64
+	maxRecords int
+}
+
+func newFetchLimits(conf *Config) *fetchLimits {
+	if conf.Consumer.Fetch.MaxDecodedBytes == 0 && conf.Consumer.Fetch.MaxBatchRecords == 0 {
+		return nil
+	}
+	return &fetchLimits{
+		maxBytes:   conf.Consumer.Fetch.MaxDecodedBytes,
+		maxRecords: conf.Consumer.Fetch.MaxBatchRecords,
+	}
+}
+
+// checkRecords fails if a batch of n records goes over MaxBatchRecords.
+func (l *fetchLimits) checkRecords(n int) error {
+	if l == nil || l.maxRecords == 0 || n <= l.maxRecords {
+		return nil
+	}
+	return FetchLimitError{Limit: "MaxBatchRecords", Max: int64(l.maxRecords)}
+}
+
+// decompress decompresses data into dst[:0], accounting for the decompressed
+// records and failing rather than decompress past MaxDecodedBytes.
+func (l *fetchLimits) decompress(cc CompressionCodec, zstdParams ZstdDecoderParams, dst, data []byte) ([]byte, error) {
+	if l == nil || l.maxBytes == 0 {
+		return decompress(cc, zstdParams, -1, dst, data)
+	}
+	left := l.maxBytes - atomic.LoadInt64(&l.decoded)
+	if left < 0 {
+		left = 0
+	}
+	if left > maxInt {
+		left = maxInt
+	}
+	zstdParams.maxMemory = uint64(l.maxBytes)
+	out, err := decompress(cc, zstdParams, int(left), dst, data)
+	if errors.Is(err, errDecompressionLimit) {
+		return nil, l.bytesError()
+	}
+	if err != nil {
+		return nil, err
+	}
+	if atomic.AddInt64(&l.decoded, int64(len(out))) > l.maxBytes {
+		return nil, l.bytesError()
+	}
+	return out, nil
+}
+
+func (l *fetchLimits) bytesError() error {
+	return FetchLimitError{Limit: "MaxDecodedBytes", Max: l.maxBytes}
+}
@@ -0,0 +1,84 @@
+package sarama
+
+import (
+	"bytes"
+	"errors"
+	"testing"
+)
+
+func TestFetchLimitsDecompress(t *testing.T) {
+	data := bytes.Repeat([]byte("compressible "), 10000)
+	for _, tc := range []struct {
+		name  string
+		codec CompressionCodec
+		opts  compressionOptions
+	}{
+		{"none", CompressionNone, compressionOptions{}},
+		{"gzip", CompressionGZIP, compressionOptions{}},
+		{"snappy", CompressionSnappy, compressionOptions{}},
+		{"xerial snappy", CompressionSnappy, compressionOptions{snappyFraming: SnappyXerialFraming}},
+		{"lz4", CompressionLZ4, compressionOptions{}},
+		{"zstd", CompressionZSTD, compressionOptions{}},
+	} {
+		compressed, err := compress(tc.codec, CompressionLevelDefault, tc.opts, data)
+		if err != nil {
+			t.Fatal(tc.name, err)
+		}
+
+		limits := &fetchLimits{maxBytes: int64(len(data))}
+		if out, err := limits.decompress(tc.codec, ZstdDecoderParams{}, nil, compressed); err != nil {
+			t.Errorf("%s: %v", tc.name, err)
+		} else if !bytes.Equal(out, data) {
+			t.Errorf("%s: unexpected decompressed data", tc.name)
+		}
+
+		// the limit is shared by the batches of the response
+		var limitErr FetchLimitError
+		if _, err := limits.decompress(tc.codec, ZstdDecoderParams{}, nil, compressed); !errors.As(err, &limitErr) {
+			t.Errorf("%s: expected a FetchLimitError decompressing past the limit, got %v", tc.name, err)
+		} else if limitErr.Limit != "MaxDecodedBytes" || limitErr.Max != int64(len(data)) {
+			t.Errorf("%s: unexpected %v", tc.name, limitErr)
+		}
+
+		limits = &fetchLimits{maxBytes: 1024}
+		if _, err := limits.decompress(tc.codec, ZstdDecoderParams{}, nil, compressed); !errors.As(err, &limitErr) {
+			t.Errorf("%s: expected a FetchLimitError decompressing a single large batch, got %v", tc.name, err)
+		}
+	}
+}
+
+func TestFetchLimitsBatchRecords(t *testing.T) {
+	batch := &RecordBatch{Version: 2, Codec: CompressionNone}
+	for i := 0; i < 3; i++ {
+		batch.addRecord(&Record{Value: []byte{byte(i)}})
+	}
+	buf, err := encode(batch, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	decoded := new(RecordBatch)
+	if err := decoded.decode(&realDecoder{raw: buf, limits: &fetchLimits{maxRecords: 3}}); err != nil {
+		t.Fatal(err)
+	}
+	if len(decoded.Records) != 3 {
+		t.Errorf("expected 3 records, got %d", len(decoded.Records))
+	}
+
+	var limitErr FetchLimitError
+	err = new(RecordBatch).decode(&realDecoder{raw: buf, limits: &fetchLimits{maxRecords: 2}})
+	if !errors.As(err, &limitErr) || limitErr.Limit != "MaxBatchRecords" || limitErr.Max != 2 {
+		t.Errorf("expected a FetchLimitError on MaxBatchRecords, got %v", err)
+	}
+}
+
+func TestNewFetchLimits(t *testing.T) {
+	conf := NewTestConfig()
+	if limits := newFetchLimits(conf); limits != nil {
+		t.Errorf("expected no limits by default, got %+v", limits)
+	}
+	conf.Consumer.Fetch.MaxDecodedBytes = 1 << 20
+	if limits := newFetchLimits(conf); limits == nil || limits.maxBytes != 1<<20 {
+		t.Errorf("expected a limit of 1MiB, got %+v", limits)
+	}
+}
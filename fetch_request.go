@@ -4,6 +4,7 @@ type fetchRequestBlock struct {
 	Version            int16
 	currentLeaderEpoch int32
 	fetchOffset        int64
+	lastFetchedEpoch   int32
 	logStartOffset     int64
 	maxBytes           int32
 }
@@ -14,10 +15,16 @@ func (b *fetchRequestBlock) encode(pe packetEncoder, version int16) error {
 		pe.putInt32(b.currentLeaderEpoch)
 	}
 	pe.putInt64(b.fetchOffset)
+	if b.Version >= 12 {
+		pe.putInt32(b.lastFetchedEpoch)
+	}
 	if b.Version >= 5 {
 		pe.putInt64(b.logStartOffset)
 	}
 	pe.putInt32(b.maxBytes)
+	if b.Version >= 12 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
@@ -31,6 +38,11 @@ func (b *fetchRequestBlock) decode(pd packetDecoder, version int16) (err error)
 	if b.fetchOffset, err = pd.getInt64(); err != nil {
 		return err
 	}
+	if b.Version >= 12 {
+		if b.lastFetchedEpoch, err = pd.getInt32(); err != nil {
+			return err
+		}
+	}
 	if b.Version >= 5 {
 		if b.logStartOffset, err = pd.getInt64(); err != nil {
 			return err
@@ -39,6 +51,11 @@ func (b *fetchRequestBlock) decode(pd packetDecoder, version int16) (err error)
 	if b.maxBytes, err = pd.getInt32(); err != nil {
 		return err
 	}
+	if b.Version >= 12 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -56,6 +73,12 @@ type FetchRequest struct {
 	blocks       map[string]map[int32]*fetchRequestBlock
 	forgotten    map[string][]int32
 	RackID       string
+	// buffers are those of the partitions fetched to decompress the records
+	// of the response into
+	buffers map[string]map[int32]*decompressBuffers
+	// ownBlocks copies the records of each block of the response out of it,
+	// see FetchResponse
+	ownBlocks bool
 }
 
 type IsolationLevel int8
@@ -79,17 +102,23 @@ func (r *FetchRequest) encode(pe packetEncoder) (err error) {
 		pe.putInt32(r.SessionID)
 		pe.putInt32(r.SessionEpoch)
 	}
-	err = pe.putArrayLength(len(r.blocks))
-	if err != nil {
+	if r.Version >= 12 {
+		pe.putCompactArrayLength(len(r.blocks))
+	} else if err = pe.putArrayLength(len(r.blocks)); err != nil {
 		return err
 	}
 	for topic, blocks := range r.blocks {
-		err = pe.putString(topic)
+		if r.Version >= 12 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
 		if err != nil {
 			return err
 		}
-		err = pe.putArrayLength(len(blocks))
-		if err != nil {
+		if r.Version >= 12 {
+			pe.putCompactArrayLength(len(blocks))
+		} else if err = pe.putArrayLength(len(blocks)); err != nil {
 			return err
 		}
 		for partition, block := range blocks {
@@ -99,31 +128,48 @@ func (r *FetchRequest) encode(pe packetEncoder) (err error) {
 				return err
 			}
 		}
+		if r.Version >= 12 {
+			pe.putEmptyTaggedFieldArray()
+		}
 	}
 	if r.Version >= 7 {
-		err = pe.putArrayLength(len(r.forgotten))
-		if err != nil {
+		if r.Version >= 12 {
+			pe.putCompactArrayLength(len(r.forgotten))
+		} else if err = pe.putArrayLength(len(r.forgotten)); err != nil {
 			return err
 		}
 		for topic, partitions := range r.forgotten {
-			err = pe.putString(topic)
+			if r.Version >= 12 {
+				err = pe.putCompactString(topic)
+			} else {
+				err = pe.putString(topic)
+			}
 			if err != nil {
 				return err
 			}
-			err = pe.putArrayLength(len(partitions))
+			if r.Version >= 12 {
+				err = pe.putCompactInt32Array(partitions)
+			} else {
+				err = pe.putInt32Array(partitions)
+			}
 			if err != nil {
 				return err
 			}
-			for _, partition := range partitions {
-				pe.putInt32(partition)
+			if r.Version >= 12 {
+				pe.putEmptyTaggedFieldArray()
 			}
 		}
 	}
-	if r.Version >= 11 {
+	if r.Version >= 12 {
+		err = pe.putCompactString(r.RackID)
+	} else if r.Version >= 11 {
 		err = pe.putString(r.RackID)
-		if err != nil {
-			return err
-		}
+	}
+	if err != nil {
+		return err
+	}
+	if r.Version >= 12 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
@@ -163,20 +209,37 @@ func (r *FetchRequest) decode(pd packetDecoder, version int16) (err error) {
 			return err
 		}
 	}
-	topicCount, err := pd.getArrayLength()
+	var topicCount int
+	if r.Version >= 12 {
+		topicCount, err = pd.getCompactArrayLength()
+	} else {
+		topicCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
-	if topicCount == 0 {
+	if topicCount == 0 && r.Version < 7 {
 		return nil
 	}
-	r.blocks = make(map[string]map[int32]*fetchRequestBlock)
+	if topicCount > 0 {
+		r.blocks = make(map[string]map[int32]*fetchRequestBlock)
+	}
 	for i := 0; i < topicCount; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if r.Version >= 12 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
-		partitionCount, err := pd.getArrayLength()
+		var partitionCount int
+		if r.Version >= 12 {
+			partitionCount, err = pd.getCompactArrayLength()
+		} else {
+			partitionCount, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -192,38 +255,61 @@ func (r *FetchRequest) decode(pd packetDecoder, version int16) (err error) {
 			}
 			r.blocks[topic][partition] = fetchBlock
 		}
+		if r.Version >= 12 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
 	}
 
 	if r.Version >= 7 {
-		forgottenCount, err := pd.getArrayLength()
+		var forgottenCount int
+		if r.Version >= 12 {
+			forgottenCount, err = pd.getCompactArrayLength()
+		} else {
+			forgottenCount, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
 		r.forgotten = make(map[string][]int32)
 		for i := 0; i < forgottenCount; i++ {
-			topic, err := pd.getString()
+			var topic string
+			if r.Version >= 12 {
+				topic, err = pd.getCompactString()
+			} else {
+				topic, err = pd.getString()
+			}
 			if err != nil {
 				return err
 			}
-			partitionCount, err := pd.getArrayLength()
+			if r.Version >= 12 {
+				r.forgotten[topic], err = pd.getCompactInt32Array()
+			} else {
+				r.forgotten[topic], err = pd.getInt32Array()
+			}
 			if err != nil {
 				return err
 			}
-			r.forgotten[topic] = make([]int32, partitionCount)
-
-			for j := 0; j < partitionCount; j++ {
-				partition, err := pd.getInt32()
-				if err != nil {
+			if r.Version >= 12 {
+				if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
 					return err
 				}
-				r.forgotten[topic][j] = partition
 			}
 		}
 	}
 
-	if r.Version >= 11 {
+	if r.Version >= 12 {
+		r.RackID, err = pd.getCompactString()
+	} else if r.Version >= 11 {
 		r.RackID, err = pd.getString()
-		if err != nil {
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.Version >= 12 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
 			return err
 		}
 	}
@@ -240,6 +326,9 @@ func (r *FetchRequest) version() int16 {
 }
 
 func (r *FetchRequest) headerVersion() int16 {
+	if r.Version >= 12 {
+		return 2
+	}
 	return 1
 }
 
@@ -265,6 +354,8 @@ func (r *FetchRequest) requiredVersion() KafkaVersion {
 		return V2_1_0_0
 	case 11:
 		return V2_3_0_0
+	case 12:
+		return V2_7_0_0
 	default:
 		return MaxVersion
 	}
@@ -290,6 +381,20 @@ func (r *FetchRequest) AddBlock(topic string, partitionID int32, fetchOffset int
 	if r.Version >= 9 {
 		tmp.currentLeaderEpoch = int32(-1)
 	}
+	if r.Version >= 12 {
+		tmp.lastFetchedEpoch = int32(-1)
+	}
 
 	r.blocks[topic][partitionID] = tmp
 }
+
+// setBuffers sets the buffers to decompress the records of the partition into.
+func (r *FetchRequest) setBuffers(topic string, partitionID int32, buffers *decompressBuffers) {
+	if r.buffers == nil {
+		r.buffers = make(map[string]map[int32]*decompressBuffers)
+	}
+	if r.buffers[topic] == nil {
+		r.buffers[topic] = make(map[int32]*decompressBuffers)
+	}
+	r.buffers[topic][partitionID] = buffers
+}
@@ -1,7 +1,6 @@
 package sarama
 
 import (
-	"errors"
 	"sort"
 	"time"
 )
@@ -13,7 +12,7 @@ type AbortedTransaction struct {
 	FirstOffset int64
 }
 
-func (t *AbortedTransaction) decode(pd packetDecoder) (err error) {
+func (t *AbortedTransaction) decode(pd packetDecoder, version int16) (err error) {
 	if t.ProducerID, err = pd.getInt64(); err != nil {
 		return err
 	}
@@ -22,13 +21,23 @@ func (t *AbortedTransaction) decode(pd packetDecoder) (err error) {
 		return err
 	}
 
+	if version >= 12 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (t *AbortedTransaction) encode(pe packetEncoder) (err error) {
+func (t *AbortedTransaction) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt64(t.ProducerID)
 	pe.putInt64(t.FirstOffset)
 
+	if version >= 12 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
@@ -43,9 +52,50 @@ type FetchResponseBlock struct {
 	Records                *Records // deprecated: use FetchResponseBlock.RecordsSet
 	RecordsSet             []*Records
 	Partial                bool
+	// recordsSize is the size in bytes of the records, as decoded
+	recordsSize int
+	// rawRecords holds the record batches a lazy decoding left undecoded, to
+	// decode one at a time with a RecordsIterator
+	rawRecords []byte
+	// decoding is how the record batches are decoded
+	decoding recordsDecoding
 }
 
-func (b *FetchResponseBlock) decode(pd packetDecoder, version int16) (err error) {
+// recordsDecoding is how the record batches of a fetch response are decoded,
+// as configured by Consumer.Fetch.
+type recordsDecoding struct {
+	// lazy leaves the record batches undecoded, see Consumer.Fetch.Lazy
+	lazy bool
+	// crcValidation is Consumer.Fetch.CRCValidation
+	crcValidation CRCValidation
+	// crcErrors counts the record batches failing the validation, if not nil
+	crcErrors MetricsCounter
+	// lazyHeaders leaves the headers of the records undecoded, see
+	// Consumer.Fetch.LazyHeaders
+	lazyHeaders bool
+	// zstd selects the decoder decompressing the records, see Consumer.Zstd
+	zstd ZstdDecoderParams
+	// limits bounds the memory decoding the records allocates, see
+	// Consumer.Fetch.MaxDecodedBytes and MaxBatchRecords
+	limits *fetchLimits
+	// buffers are those of the partition of the block to decompress the
+	// records into
+	buffers *decompressBuffers
+	// copyRecords decodes the records from a copy of their bytes rather than
+	// from the response
+	copyRecords bool
+}
+
+func (b *FetchResponseBlock) decode(pd packetDecoder, version int16) error {
+	return b.decodeBlock(pd, version, recordsDecoding{})
+}
+
+// decodeBlock decodes the block, leaving its record batches undecoded in
+// rawRecords when decoding is lazy, unless they are legacy message sets, which
+// can only be decoded at once.
+func (b *FetchResponseBlock) decodeBlock(pd packetDecoder, version int16, decoding recordsDecoding) (err error) {
+	b.decoding = decoding
+
 	tmp, err := pd.getInt16()
 	if err != nil {
 		return err
@@ -70,7 +120,12 @@ func (b *FetchResponseBlock) decode(pd packetDecoder, version int16) (err error)
 			}
 		}
 
-		numTransact, err := pd.getArrayLength()
+		var numTransact int
+		if version >= 12 {
+			numTransact, err = pd.getCompactArrayLength()
+		} else {
+			numTransact, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -81,7 +136,7 @@ func (b *FetchResponseBlock) decode(pd packetDecoder, version int16) (err error)
 
 		for i := 0; i < numTransact; i++ {
 			transact := new(AbortedTransaction)
-			if err = transact.decode(pd); err != nil {
+			if err = transact.decode(pd, version); err != nil {
 				return err
 			}
 			b.AbortedTransactions[i] = transact
@@ -97,62 +152,52 @@ func (b *FetchResponseBlock) decode(pd packetDecoder, version int16) (err error)
 		b.PreferredReadReplica = -1
 	}
 
-	recordsSize, err := pd.getInt32()
+	var recordsSize int
+	if version >= 12 {
+		// compact records, null as 0
+		var n uint64
+		if n, err = pd.getUVarint(); err == nil && n > 0 {
+			recordsSize = int(n - 1)
+		}
+	} else {
+		var n int32
+		n, err = pd.getInt32()
+		recordsSize = int(n)
+	}
 	if err != nil {
 		return err
 	}
+	b.recordsSize = recordsSize
 
-	recordsDecoder, err := pd.getSubset(int(recordsSize))
+	raw, err := pd.getRawBytes(recordsSize)
 	if err != nil {
 		return err
 	}
+	if decoding.copyRecords {
+		raw = append([]byte(nil), raw...)
+	}
 
 	b.RecordsSet = []*Records{}
 
-	for recordsDecoder.remaining() > 0 {
-		records := &Records{}
-		if err := records.decode(recordsDecoder); err != nil {
-			// If we have at least one decoded records, this is not an error
-			if errors.Is(err, ErrInsufficientData) {
-				if len(b.RecordsSet) == 0 {
-					b.Partial = true
-				}
-				break
-			}
-			return err
-		}
-
-		b.LastRecordsBatchOffset, err = records.recordsOffset()
-		if err != nil {
-			return err
-		}
-
-		partial, err := records.isPartial()
-		if err != nil {
-			return err
+	if decoding.lazy && (len(raw) <= magicOffset || raw[magicOffset] >= 2) {
+		b.rawRecords = raw
+	} else {
+		batches := b.iterateRecords(raw)
+		for batches.Next() {
+			b.RecordsSet = append(b.RecordsSet, batches.Records())
 		}
-
-		n, err := records.numRecords()
-		if err != nil {
+		if err = batches.Err(); err != nil {
 			return err
 		}
-
-		if n > 0 || (partial && len(b.RecordsSet) == 0) {
-			b.RecordsSet = append(b.RecordsSet, records)
-
-			if b.Records == nil {
-				b.Records = records
-			}
+		if len(b.RecordsSet) > 0 {
+			b.Records = b.RecordsSet[0]
 		}
+	}
 
-		overflow, err := records.isOverflow()
-		if err != nil {
+	if version >= 12 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
 			return err
 		}
-
-		if partial || overflow {
-			break
-		}
 	}
 
 	return nil
@@ -170,6 +215,16 @@ func (b *FetchResponseBlock) numRecords() (int, error) {
 		sum += count
 	}
 
+	// count the records left undecoded from the headers of their batches
+	for raw := b.rawRecords; ; {
+		header, ok := peekRecordBatchHeader(raw)
+		if !ok {
+			break
+		}
+		sum += int(header.numRecords)
+		raw = raw[header.size:]
+	}
+
 	return sum, nil
 }
 
@@ -197,11 +252,13 @@ func (b *FetchResponseBlock) encode(pe packetEncoder, version int16) (err error)
 			pe.putInt64(b.LogStartOffset)
 		}
 
-		if err = pe.putArrayLength(len(b.AbortedTransactions)); err != nil {
+		if version >= 12 {
+			pe.putCompactArrayLength(len(b.AbortedTransactions))
+		} else if err = pe.putArrayLength(len(b.AbortedTransactions)); err != nil {
 			return err
 		}
 		for _, transact := range b.AbortedTransactions {
-			if err = transact.encode(pe); err != nil {
+			if err = transact.encode(pe, version); err != nil {
 				return err
 			}
 		}
@@ -211,13 +268,32 @@ func (b *FetchResponseBlock) encode(pe packetEncoder, version int16) (err error)
 		pe.putInt32(b.PreferredReadReplica)
 	}
 
-	pe.push(&lengthField{})
+	if version >= 12 {
+		// the length of compact records is a varint, sized by encoding
+		// them once beforehand
+		var prep prepEncoder
+		for _, records := range b.RecordsSet {
+			if err = records.encode(&prep); err != nil {
+				return err
+			}
+		}
+		pe.putUVarint(uint64(prep.length+len(b.rawRecords)) + 1)
+	} else {
+		pe.push(&lengthField{})
+	}
+	if err = pe.putRawBytes(b.rawRecords); err != nil {
+		return err
+	}
 	for _, records := range b.RecordsSet {
 		err = records.encode(pe)
 		if err != nil {
 			return err
 		}
 	}
+	if version >= 12 {
+		pe.putEmptyTaggedFieldArray()
+		return nil
+	}
 	return pe.pop()
 }
 
@@ -240,6 +316,15 @@ type FetchResponse struct {
 	Version       int16
 	LogAppendTime bool
 	Timestamp     time.Time
+	// decoding is how the record batches of the blocks are decoded
+	decoding recordsDecoding
+	// buffers are those of the partitions of the blocks to decompress their
+	// records into, see FetchRequest
+	buffers map[string]map[int32]*decompressBuffers
+	// ownBlocks copies the records of each block out of the response when it
+	// holds several, so that each block is released on its own rather than
+	// retaining the whole response until the last one is
+	ownBlocks bool
 }
 
 func (r *FetchResponse) decode(pd packetDecoder, version int16) (err error) {
@@ -264,19 +349,34 @@ func (r *FetchResponse) decode(pd packetDecoder, version int16) (err error) {
 		}
 	}
 
-	numTopics, err := pd.getArrayLength()
+	var numTopics int
+	if r.Version >= 12 {
+		numTopics, err = pd.getCompactArrayLength()
+	} else {
+		numTopics, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
 
 	r.Blocks = make(map[string]map[int32]*FetchResponseBlock, numTopics)
 	for i := 0; i < numTopics; i++ {
-		name, err := pd.getString()
+		var name string
+		if r.Version >= 12 {
+			name, err = pd.getCompactString()
+		} else {
+			name, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
 
-		numBlocks, err := pd.getArrayLength()
+		var numBlocks int
+		if r.Version >= 12 {
+			numBlocks, err = pd.getCompactArrayLength()
+		} else {
+			numBlocks, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -290,12 +390,27 @@ func (r *FetchResponse) decode(pd packetDecoder, version int16) (err error) {
 			}
 
 			block := new(FetchResponseBlock)
-			err = block.decode(pd, version)
+			decoding := r.decoding
+			decoding.buffers = r.buffers[name][id]
+			decoding.copyRecords = r.ownBlocks && (numTopics > 1 || numBlocks > 1)
+			err = block.decodeBlock(pd, version, decoding)
 			if err != nil {
 				return err
 			}
 			r.Blocks[name][id] = block
 		}
+
+		if r.Version >= 12 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.Version >= 12 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -311,19 +426,25 @@ func (r *FetchResponse) encode(pe packetEncoder) (err error) {
 		pe.putInt32(r.SessionID)
 	}
 
-	err = pe.putArrayLength(len(r.Blocks))
-	if err != nil {
+	if r.Version >= 12 {
+		pe.putCompactArrayLength(len(r.Blocks))
+	} else if err = pe.putArrayLength(len(r.Blocks)); err != nil {
 		return err
 	}
 
 	for topic, partitions := range r.Blocks {
-		err = pe.putString(topic)
+		if r.Version >= 12 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
 		if err != nil {
 			return err
 		}
 
-		err = pe.putArrayLength(len(partitions))
-		if err != nil {
+		if r.Version >= 12 {
+			pe.putCompactArrayLength(len(partitions))
+		} else if err = pe.putArrayLength(len(partitions)); err != nil {
 			return err
 		}
 
@@ -334,6 +455,14 @@ func (r *FetchResponse) encode(pe packetEncoder) (err error) {
 				return err
 			}
 		}
+
+		if r.Version >= 12 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if r.Version >= 12 {
+		pe.putEmptyTaggedFieldArray()
 	}
 	return nil
 }
@@ -347,6 +476,9 @@ func (r *FetchResponse) version() int16 {
 }
 
 func (r *FetchResponse) headerVersion() int16 {
+	if r.Version >= 12 {
+		return 1
+	}
 	return 0
 }
 
@@ -372,6 +504,8 @@ func (r *FetchResponse) requiredVersion() KafkaVersion {
 		return V2_1_0_0
 	case 11:
 		return V2_3_0_0
+	case 12:
+		return V2_7_0_0
 	default:
 		return MaxVersion
 	}
@@ -389,6 +523,16 @@ func (r *FetchResponse) GetBlock(topic string, partition int32) *FetchResponseBl
 	return r.Blocks[topic][partition]
 }
 
+// takeBlock removes the block of the partition from the response and returns
+// it, handing its ownership off to the caller.
+func (r *FetchResponse) takeBlock(topic string, partition int32) *FetchResponseBlock {
+	block := r.GetBlock(topic, partition)
+	if block != nil {
+		delete(r.Blocks[topic], partition)
+	}
+	return block
+}
+
 func (r *FetchResponse) AddError(topic string, partition int32, err KError) {
 	if r.Blocks == nil {
 		r.Blocks = make(map[string]map[int32]*FetchResponseBlock)
@@ -555,3 +699,25 @@ func (r *FetchResponse) SetLastStableOffset(topic string, partition int32, offse
 	frb := r.getOrCreateBlock(topic, partition)
 	frb.LastStableOffset = offset
 }
+
+func (r *FetchResponse) throttleTime() time.Duration {
+	return r.ThrottleTime
+}
+
+func (r *FetchResponse) shouldClientThrottle(version int16) bool {
+	return version >= 8
+}
+
+func (r *FetchResponse) hasErrorCode() bool {
+	if KError(r.ErrorCode) != ErrNoError {
+		return true
+	}
+	for _, partitions := range r.Blocks {
+		for _, block := range partitions {
+			if block.Err != ErrNoError {
+				return true
+			}
+		}
+	}
+	return false
+}
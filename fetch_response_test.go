@@ -3,6 +3,8 @@ package sarama
 import (
 	"bytes"
 	"errors"
+	"fmt"
+	"runtime"
 	"testing"
 )
 
@@ -200,6 +202,38 @@ var (
 		0xFF, 0xFF, 0xFF, 0xFF,
 		0x00, 0x00, 0x00, 0x02, 0x00, 0xEE,
 	}
+
+	abortedTransactionsFetchResponseV12 = []byte{
+		0x00, 0x00, 0x00, 0x00, // ThrottleTime
+		0x00, 0x00, // ErrorCode
+		0x00, 0x00, 0x00, 0xAC, // SessionID
+		0x02,                          // Number of Topics
+		0x06, 't', 'o', 'p', 'i', 'c', // Topic
+		0x02,                   // Number of Partitions
+		0x00, 0x00, 0x00, 0x05, // Partition
+		0x00, 0x00, // Error
+		0x00, 0x00, 0x00, 0x00, 0x10, 0x10, 0x10, 0x10, // High Watermark Offset
+		0x00, 0x00, 0x00, 0x00, 0x10, 0x10, 0x10, 0x09, // Last Stable Offset
+		0x00, 0x00, 0x00, 0x00, 0x01, 0x01, 0x01, 0x01, // Log Start Offset
+		0x02,                                           // Number of Aborted Transactions
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x07, // Producer ID
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x55, 0x00, 0x00, // First Offset
+		0x00,                   // Aborted Transaction Tagged Fields
+		0xFF, 0xFF, 0xFF, 0xFF, // Preferred Read Replica
+		0x1D,
+		// messageSet
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x55, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x10,
+		// message
+		0x23, 0x96, 0x4a, 0xf7, // CRC
+		0x00,
+		0x00,
+		0xFF, 0xFF, 0xFF, 0xFF,
+		0x00, 0x00, 0x00, 0x02, 0x00, 0xEE,
+		0x00, // Partition Tagged Fields
+		0x00, // Topic Tagged Fields
+		0x00, // Tagged Fields
+	}
 )
 
 func TestEmptyFetchResponse(t *testing.T) {
@@ -590,3 +624,104 @@ func TestPreferredReplicaFetchResponseV11(t *testing.T) {
 		t.Error("Decoding produced incorrect message value.")
 	}
 }
+
+func TestAbortedTransactionsFetchResponseV12(t *testing.T) {
+	response := FetchResponse{}
+	testVersionDecodable(
+		t, "aborted transactions fetch response v12", &response,
+		abortedTransactionsFetchResponseV12, 12)
+
+	if response.SessionID != 0x000000AC {
+		t.Fatal("Decoding produced incorrect session ID.")
+	}
+	block := response.GetBlock("topic", 5)
+	if block == nil {
+		t.Fatal("GetBlock didn't return block.")
+	}
+	if block.LastStableOffset != 0x10101009 {
+		t.Error("Decoding didn't produce correct last stable offset.")
+	}
+	if len(block.AbortedTransactions) != 1 ||
+		block.AbortedTransactions[0].ProducerID != 7 ||
+		block.AbortedTransactions[0].FirstOffset != 0x550000 {
+		t.Errorf("Decoding produced incorrect aborted transactions: %v", block.AbortedTransactions)
+	}
+	if block.PreferredReadReplica != -1 {
+		t.Error("Decoding didn't produce correct preferred read replica.")
+	}
+	n, err := block.numRecords()
+	if err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if n != 1 {
+		t.Fatal("Decoding produced incorrect number of records.")
+	}
+	if !bytes.Equal(block.RecordsSet[0].MsgSet.Messages[0].Msg.Value, []byte{0x00, 0xEE}) {
+		t.Error("Decoding produced incorrect message value.")
+	}
+
+	testEncodable(t, "aborted transactions fetch response v12", &response, abortedTransactionsFetchResponseV12)
+}
+
+// ownedBlocksResponse returns the encoding of a fetch response holding a
+// record of size bytes in each of the partitions.
+func ownedBlocksResponse(tb testing.TB, partitions int32, size int) []byte {
+	response := &FetchResponse{Version: 4}
+	for partition := int32(0); partition < partitions; partition++ {
+		response.AddRecord("my_topic", partition, nil, ByteEncoder(bytes.Repeat([]byte{byte(partition)}, size)), 0)
+	}
+	buf, err := encode(response, nil)
+	if err != nil {
+		tb.Fatal(err)
+	}
+	return buf
+}
+
+func TestFetchResponseOwnBlocks(t *testing.T) {
+	buf := ownedBlocksResponse(t, 2, 16)
+	for _, own := range []bool{false, true} {
+		data := append([]byte(nil), buf...)
+		response := &FetchResponse{ownBlocks: own}
+		if err := versionedDecode(data, response, 4); err != nil {
+			t.Fatal(err)
+		}
+		for i := range data {
+			data[i] = 0xff
+		}
+		value := response.takeBlock("my_topic", 1).RecordsSet[0].RecordBatch.Records[0].Value
+		if owned := bytes.Equal(value, bytes.Repeat([]byte{1}, 16)); owned != own {
+			t.Errorf("expected the block to own its records %v, got %v", own, owned)
+		}
+	}
+}
+
+// BenchmarkFetchResponseTakeBlock reports the memory a single block taken out
+// of a fetch response retains once the response is dropped.
+func BenchmarkFetchResponseTakeBlock(b *testing.B) {
+	buf := ownedBlocksResponse(b, 16, 64*1024)
+	for _, own := range []bool{false, true} {
+		b.Run(fmt.Sprintf("ownBlocks=%v", own), func(b *testing.B) {
+			var before, after runtime.MemStats
+			var retained uint64
+			for i := 0; i < b.N; i++ {
+				runtime.GC()
+				runtime.ReadMemStats(&before)
+				data := append([]byte(nil), buf...)
+				response := &FetchResponse{ownBlocks: own}
+				if err := versionedDecode(data, response, 4); err != nil {
+					b.Fatal(err)
+				}
+				data = nil
+				block := response.takeBlock("my_topic", 0)
+				response = nil
+				runtime.GC()
+				runtime.ReadMemStats(&after)
+				if after.HeapAlloc > before.HeapAlloc {
+					retained += after.HeapAlloc - before.HeapAlloc
+				}
+				runtime.KeepAlive(block)
+			}
+			b.ReportMetric(float64(retained)/float64(b.N), "retained-B/op")
+		})
+	}
+}
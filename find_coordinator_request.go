@@ -13,8 +13,13 @@ type FindCoordinatorRequest struct {
 	CoordinatorType CoordinatorType
 }
 
-func (f *FindCoordinatorRequest) encode(pe packetEncoder) error {
-	if err := pe.putString(f.CoordinatorKey); err != nil {
+func (f *FindCoordinatorRequest) encode(pe packetEncoder) (err error) {
+	if f.Version >= 3 {
+		err = pe.putCompactString(f.CoordinatorKey)
+	} else {
+		err = pe.putString(f.CoordinatorKey)
+	}
+	if err != nil {
 		return err
 	}
 
@@ -22,16 +27,25 @@ func (f *FindCoordinatorRequest) encode(pe packetEncoder) error {
 		pe.putInt8(int8(f.CoordinatorType))
 	}
 
+	if f.Version >= 3 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (f *FindCoordinatorRequest) decode(pd packetDecoder, version int16) (err error) {
-	if f.CoordinatorKey, err = pd.getString(); err != nil {
+	f.Version = version
+	if version >= 3 {
+		f.CoordinatorKey, err = pd.getCompactString()
+	} else {
+		f.CoordinatorKey, err = pd.getString()
+	}
+	if err != nil {
 		return err
 	}
 
 	if version >= 1 {
-		f.Version = version
 		coordinatorType, err := pd.getInt8()
 		if err != nil {
 			return err
@@ -40,6 +54,12 @@ func (f *FindCoordinatorRequest) decode(pd packetDecoder, version int16) (err er
 		f.CoordinatorType = CoordinatorType(coordinatorType)
 	}
 
+	if version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -52,6 +72,9 @@ func (f *FindCoordinatorRequest) version() int16 {
 }
 
 func (r *FindCoordinatorRequest) headerVersion() int16 {
+	if r.Version >= 3 {
+		return 2
+	}
 	return 1
 }
 
@@ -59,6 +82,10 @@ func (f *FindCoordinatorRequest) requiredVersion() KafkaVersion {
 	switch f.Version {
 	case 1:
 		return V0_11_0_0
+	case 2:
+		return V2_0_0_0
+	case 3:
+		return V2_4_0_0
 	default:
 		return V0_8_2_0
 	}
@@ -12,6 +12,12 @@ var (
 		0, 13, 't', 'r', 'a', 'n', 's', 'a', 'c', 't', 'i', 'o', 'n', 'i', 'd',
 		1,
 	}
+
+	findCoordinatorRequestConsumerGroupV3 = []byte{
+		6, 'g', 'r', 'o', 'u', 'p',
+		0,
+		0, // tagged fields
+	}
 )
 
 func TestFindCoordinatorRequest(t *testing.T) {
@@ -30,4 +36,12 @@ func TestFindCoordinatorRequest(t *testing.T) {
 	}
 
 	testRequest(t, "version 1 - transaction", req, findCoordinatorRequestTransaction)
+
+	req = &FindCoordinatorRequest{
+		Version:         3,
+		CoordinatorKey:  "group",
+		CoordinatorType: CoordinatorGroup,
+	}
+
+	testRequest(t, "version 3 - group", req, findCoordinatorRequestConsumerGroupV3)
 }
@@ -1,6 +1,9 @@
 package sarama
 
 import (
+	"fmt"
+	"net"
+	"strconv"
 	"time"
 )
 
@@ -15,9 +18,8 @@ type FindCoordinatorResponse struct {
 }
 
 func (f *FindCoordinatorResponse) decode(pd packetDecoder, version int16) (err error) {
+	f.Version = version
 	if version >= 1 {
-		f.Version = version
-
 		throttleTime, err := pd.getInt32()
 		if err != nil {
 			return err
@@ -31,7 +33,11 @@ func (f *FindCoordinatorResponse) decode(pd packetDecoder, version int16) (err e
 	}
 	f.Err = KError(tmp)
 
-	if version >= 1 {
+	if version >= 3 {
+		if f.ErrMsg, err = pd.getCompactNullableString(); err != nil {
+			return err
+		}
+	} else if version >= 1 {
 		if f.ErrMsg, err = pd.getNullableString(); err != nil {
 			return err
 		}
@@ -40,7 +46,15 @@ func (f *FindCoordinatorResponse) decode(pd packetDecoder, version int16) (err e
 	coordinator := new(Broker)
 	// The version is hardcoded to 0, as version 1 of the Broker-decode
 	// contains the rack-field which is not present in the FindCoordinatorResponse.
-	if err := coordinator.decode(pd, 0); err != nil {
+	// Neither are the tagged fields of its flexible version.
+	if version >= 3 {
+		if err := decodeCompactCoordinator(pd, coordinator); err != nil {
+			return err
+		}
+		if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	} else if err := coordinator.decode(pd, 0); err != nil {
 		return err
 	}
 	if coordinator.addr == ":0" {
@@ -51,6 +65,39 @@ func (f *FindCoordinatorResponse) decode(pd packetDecoder, version int16) (err e
 	return nil
 }
 
+func decodeCompactCoordinator(pd packetDecoder, coordinator *Broker) (err error) {
+	if coordinator.id, err = pd.getInt32(); err != nil {
+		return err
+	}
+	host, err := pd.getCompactString()
+	if err != nil {
+		return err
+	}
+	port, err := pd.getInt32()
+	if err != nil {
+		return err
+	}
+	coordinator.addr = net.JoinHostPort(host, fmt.Sprint(port))
+	return nil
+}
+
+func encodeCompactCoordinator(pe packetEncoder, coordinator *Broker) error {
+	host, portstr, err := net.SplitHostPort(coordinator.addr)
+	if err != nil {
+		return err
+	}
+	port, err := strconv.ParseInt(portstr, 10, 32)
+	if err != nil {
+		return err
+	}
+	pe.putInt32(coordinator.id)
+	if err := pe.putCompactString(host); err != nil {
+		return err
+	}
+	pe.putInt32(int32(port))
+	return nil
+}
+
 func (f *FindCoordinatorResponse) encode(pe packetEncoder) error {
 	if f.Version >= 1 {
 		pe.putInt32(int32(f.ThrottleTime / time.Millisecond))
@@ -58,7 +105,11 @@ func (f *FindCoordinatorResponse) encode(pe packetEncoder) error {
 
 	pe.putInt16(int16(f.Err))
 
-	if f.Version >= 1 {
+	if f.Version >= 3 {
+		if err := pe.putNullableCompactString(f.ErrMsg); err != nil {
+			return err
+		}
+	} else if f.Version >= 1 {
 		if err := pe.putNullableString(f.ErrMsg); err != nil {
 			return err
 		}
@@ -68,6 +119,13 @@ func (f *FindCoordinatorResponse) encode(pe packetEncoder) error {
 	if coordinator == nil {
 		coordinator = NoNode
 	}
+	if f.Version >= 3 {
+		if err := encodeCompactCoordinator(pe, coordinator); err != nil {
+			return err
+		}
+		pe.putEmptyTaggedFieldArray()
+		return nil
+	}
 	if err := coordinator.encode(pe, 0); err != nil {
 		return err
 	}
@@ -83,6 +141,9 @@ func (f *FindCoordinatorResponse) version() int16 {
 }
 
 func (r *FindCoordinatorResponse) headerVersion() int16 {
+	if r.Version >= 3 {
+		return 1
+	}
 	return 0
 }
 
@@ -90,7 +151,23 @@ func (f *FindCoordinatorResponse) requiredVersion() KafkaVersion {
 	switch f.Version {
 	case 1:
 		return V0_11_0_0
+	case 2:
+		return V2_0_0_0
+	case 3:
+		return V2_4_0_0
 	default:
 		return V0_8_2_0
 	}
 }
+
+func (f *FindCoordinatorResponse) throttleTime() time.Duration {
+	return f.ThrottleTime
+}
+
+func (f *FindCoordinatorResponse) shouldClientThrottle(version int16) bool {
+	return version >= 2
+}
+
+func (f *FindCoordinatorResponse) hasErrorCode() bool {
+	return f.Err != ErrNoError
+}
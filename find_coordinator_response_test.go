@@ -77,6 +77,44 @@ func TestFindCoordinatorResponse(t *testing.T) {
 			0, 0, // Coordinator.Host: ""
 			255, 255, 255, 255, // Coordinator.Port: -1
 		},
+	}, {
+		desc: "version 3 - no error",
+		response: &FindCoordinatorResponse{
+			Version:      3,
+			ThrottleTime: 100 * time.Millisecond,
+			Err:          ErrNoError,
+			Coordinator: &Broker{
+				id:   7,
+				addr: "host:9092",
+			},
+		},
+		encoded: []byte{
+			0, 0, 0, 100, // ThrottleTime
+			0, 0, // Err
+			0,          // ErrMsg: empty
+			0, 0, 0, 7, // Coordinator.ID
+			5, 'h', 'o', 's', 't', // Coordinator.Host
+			0, 0, 35, 132, // Coordinator.Port
+			0, // Tagged fields
+		},
+	}, {
+		desc: "version 3 - error",
+		response: &FindCoordinatorResponse{
+			Version:      3,
+			ThrottleTime: 100 * time.Millisecond,
+			Err:          ErrConsumerCoordinatorNotAvailable,
+			ErrMsg:       &errMsg,
+			Coordinator:  NoNode,
+		},
+		encoded: []byte{
+			0, 0, 0, 100, // ThrottleTime
+			0, 15, // Err
+			7, 'k', 'a', 'b', 'o', 'o', 'm', // ErrMsg
+			255, 255, 255, 255, // Coordinator.ID: -1
+			1,                  // Coordinator.Host: ""
+			255, 255, 255, 255, // Coordinator.Port: -1
+			0, // Tagged fields
+		},
 	}} {
 		testResponse(t, tc.desc, tc.response, tc.encoded)
 	}
@@ -8,15 +8,11 @@ import (
 	"errors"
 	"fmt"
 	"log"
-	"net/url"
 	"os"
-	"os/exec"
-	"strconv"
-	"strings"
 	"testing"
 	"time"
 
-	toxiproxy "github.com/Shopify/toxiproxy/v2/client"
+	"github.com/Shopify/sarama/testkafka"
 )
 
 const uncommittedTopic = "uncommitted-topic-test-4"
@@ -41,7 +37,7 @@ var (
 		},
 	}
 
-	FunctionalTestEnv *testEnvironment
+	FunctionalTestEnv *testkafka.Environment
 )
 
 func TestMain(m *testing.M) {
@@ -64,205 +60,73 @@ func TestMain(m *testing.M) {
 
 func testMain(m *testing.M) int {
 	ctx := context.Background()
-	var env testEnvironment
 
 	if os.Getenv("DEBUG") == "true" {
 		Logger = log.New(os.Stdout, "[sarama] ", log.LstdFlags)
 	}
 
-	usingExisting, err := existingEnvironment(ctx, &env)
+	Logger.Println("bringing up the test environment")
+	env, err := testkafka.Start(ctx, testkafka.Options{Ready: brokersUp})
 	if err != nil {
 		panic(err)
 	}
-	if !usingExisting {
-		err := prepareDockerTestEnvironment(ctx, &env)
-		if err != nil {
-			_ = tearDownDockerTestEnvironment(ctx, &env)
-			panic(err)
-		}
-		defer tearDownDockerTestEnvironment(ctx, &env) // nolint:errcheck
-	}
-	if err := prepareTestTopics(ctx, &env); err != nil {
+	defer env.Close(ctx) // nolint:errcheck
+	if err := prepareTestTopics(ctx, env); err != nil {
 		panic(err)
 	}
-	FunctionalTestEnv = &env
+	FunctionalTestEnv = env
 	return m.Run()
 }
 
-type testEnvironment struct {
-	ToxiproxyClient  *toxiproxy.Client
-	Proxies          map[string]*toxiproxy.Proxy
-	KafkaBrokerAddrs []string
-	KafkaVersion     string
-}
-
-// setupToxiProxies will configure the toxiproxy proxies with routes for the
-// kafka brokers if they don't already exist
-func setupToxiProxies(env *testEnvironment, endpoint string) error {
-	env.ToxiproxyClient = toxiproxy.NewClient(endpoint)
-	env.Proxies = map[string]*toxiproxy.Proxy{}
-	env.KafkaBrokerAddrs = nil
-	for i := 1; i <= 5; i++ {
-		proxyName := fmt.Sprintf("kafka%d", i)
-		proxy, err := env.ToxiproxyClient.Proxy(proxyName)
-		if err != nil {
-			proxy, err = env.ToxiproxyClient.CreateProxy(
-				proxyName,
-				fmt.Sprintf("0.0.0.0:%d", 29090+i),
-				fmt.Sprintf("kafka-%d:%d", i, 29090+i),
-			)
-			if err != nil {
-				return fmt.Errorf("failed to create toxiproxy: %w", err)
-			}
-		}
-		env.Proxies[proxyName] = proxy
-		env.KafkaBrokerAddrs = append(env.KafkaBrokerAddrs, fmt.Sprintf("127.0.0.1:%d", 29090+i))
-	}
-	return nil
-}
-
-func prepareDockerTestEnvironment(ctx context.Context, env *testEnvironment) error {
-	Logger.Println("bringing up docker-based test environment")
-
-	// Always (try to) tear down first.
-	if err := tearDownDockerTestEnvironment(ctx, env); err != nil {
-		return fmt.Errorf("failed to tear down existing env: %w", err)
-	}
-
-	if version, ok := os.LookupEnv("KAFKA_VERSION"); ok {
-		env.KafkaVersion = version
-	} else {
-		env.KafkaVersion = "3.1.0"
-	}
-
-	c := exec.Command("docker-compose", "up", "-d")
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	c.Env = append(os.Environ(), fmt.Sprintf("KAFKA_VERSION=%s", env.KafkaVersion))
-	err := c.Run()
+// brokersUp returns nil once all the brokers of env joined the cluster.
+func brokersUp(ctx context.Context, env *testkafka.Environment) error {
+	Logger.Println("waiting for kafka brokers to come up")
+	config := NewTestConfig()
+	var err error
+	config.Version, err = ParseKafkaVersion(env.KafkaVersion)
 	if err != nil {
-		return fmt.Errorf("failed to run docker-compose to start test environment: %w", err)
-	}
-
-	if err := setupToxiProxies(env, "http://localhost:8474"); err != nil {
-		return fmt.Errorf("failed to setup toxiproxies: %w", err)
+		return err
 	}
+	config.Net.DialTimeout = 1 * time.Second
+	config.Net.ReadTimeout = 1 * time.Second
+	config.Net.WriteTimeout = 1 * time.Second
+	config.ClientID = "sarama-tests"
 
-	// Wait for the kafka broker to come up
-	allBrokersUp := false
-	for i := 0; i < 90 && !allBrokersUp; i++ {
-		Logger.Println("waiting for kafka brokers to come up")
-		time.Sleep(1 * time.Second)
-		config := NewTestConfig()
-		config.Version, err = ParseKafkaVersion(env.KafkaVersion)
+	for _, addr := range env.KafkaBrokerAddrs {
+		client, err := NewClient([]string{addr}, config)
 		if err != nil {
 			return err
 		}
-		config.Net.DialTimeout = 1 * time.Second
-		config.Net.ReadTimeout = 1 * time.Second
-		config.Net.WriteTimeout = 1 * time.Second
-		config.ClientID = "sarama-tests"
-		brokersOk := make([]bool, len(env.KafkaBrokerAddrs))
-	retryLoop:
-		for j, addr := range env.KafkaBrokerAddrs {
-			client, err := NewClient([]string{addr}, config)
-			if err != nil {
-				continue
-			}
-			err = client.RefreshMetadata()
+		err = client.RefreshMetadata()
+		brokers := client.Brokers()
+		if err == nil && len(brokers) < len(env.KafkaBrokerAddrs) {
+			err = fmt.Errorf("only %d brokers joined the cluster", len(brokers))
+		}
+		for _, broker := range brokers {
 			if err != nil {
-				continue
+				break
 			}
-			brokers := client.Brokers()
-			if len(brokers) < 5 {
-				continue
+			if err = broker.Open(client.Config()); err == nil {
+				_, err = broker.Connected()
 			}
-			for _, broker := range brokers {
-				err := broker.Open(client.Config())
-				if err != nil {
-					continue retryLoop
-				}
-				connected, err := broker.Connected()
-				if err != nil || !connected {
-					continue retryLoop
-				}
-			}
-			brokersOk[j] = true
 		}
-		allBrokersUp = true
-		for _, u := range brokersOk {
-			allBrokersUp = allBrokersUp && u
+		_ = client.Close()
+		if err != nil {
+			return err
 		}
 	}
-	if !allBrokersUp {
-		return fmt.Errorf("timed out waiting for broker to come up")
-	}
-
-	return nil
-}
-
-func existingEnvironment(ctx context.Context, env *testEnvironment) (bool, error) {
-	toxiproxyAddr, ok := os.LookupEnv("TOXIPROXY_ADDR")
-	if !ok {
-		return false, nil
-	}
-	toxiproxyURL, err := url.Parse(toxiproxyAddr)
-	if err != nil {
-		return false, fmt.Errorf("$TOXIPROXY_ADDR not parseable as url")
-	}
-	if err := setupToxiProxies(env, toxiproxyURL.String()); err != nil {
-		return false, fmt.Errorf("failed to setup toxiproxies: %w", err)
-	}
-
-	env.KafkaVersion, ok = os.LookupEnv("KAFKA_VERSION")
-	if !ok {
-		return false, fmt.Errorf("KAFKA_VERSION needs to be provided with TOXIPROXY_ADDR")
-	}
-	return true, nil
-}
-
-func tearDownDockerTestEnvironment(ctx context.Context, env *testEnvironment) error {
-	c := exec.Command("docker-compose", "down", "--volumes")
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	downErr := c.Run()
-
-	c = exec.Command("docker-compose", "rm", "-v", "--force", "--stop")
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	rmErr := c.Run()
-	if downErr != nil {
-		return fmt.Errorf("failed to run docker-compose to stop test environment: %w", downErr)
-	}
-	if rmErr != nil {
-		return fmt.Errorf("failed to run docker-compose to rm test environment: %w", rmErr)
-	}
 	return nil
 }
 
 func startDockerTestBroker(ctx context.Context, brokerID int32) error {
-	service := fmt.Sprintf("kafka-%d", brokerID)
-	c := exec.Command("docker-compose", "start", service)
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	if err := c.Run(); err != nil {
-		return fmt.Errorf("failed to run docker-compose to start test broker kafka-%d: %w", brokerID, err)
-	}
-	return nil
+	return FunctionalTestEnv.StartBroker(ctx, brokerID)
 }
 
 func stopDockerTestBroker(ctx context.Context, brokerID int32) error {
-	service := fmt.Sprintf("kafka-%d", brokerID)
-	c := exec.Command("docker-compose", "stop", service)
-	c.Stdout = os.Stdout
-	c.Stderr = os.Stderr
-	if err := c.Run(); err != nil {
-		return fmt.Errorf("failed to run docker-compose to stop test broker kafka-%d: %w", brokerID, err)
-	}
-	return nil
+	return FunctionalTestEnv.StopBroker(ctx, brokerID)
 }
 
-func prepareTestTopics(ctx context.Context, env *testEnvironment) error {
+func prepareTestTopics(ctx context.Context, env *testkafka.Environment) error {
 	Logger.Println("creating test topics")
 	var testTopicNames []string
 	for topic := range testTopicDetails {
@@ -389,17 +253,13 @@ func checkKafkaVersion(t testing.TB, requiredVersion string) {
 	kafkaVersion := FunctionalTestEnv.KafkaVersion
 	if kafkaVersion == "" {
 		t.Skipf("No KAFKA_VERSION set. This test requires Kafka version %s or higher. Continuing...", requiredVersion)
-	} else {
-		available := parseKafkaVersion(kafkaVersion)
-		required := parseKafkaVersion(requiredVersion)
-		if !available.satisfies(required) {
-			t.Skipf("Kafka version %s is required for this test; you have %s. Skipping...", requiredVersion, kafkaVersion)
-		}
+	} else if !FunctionalTestEnv.KafkaVersionAtLeast(requiredVersion) {
+		t.Skipf("Kafka version %s is required for this test; you have %s. Skipping...", requiredVersion, kafkaVersion)
 	}
 }
 
 func resetProxies(t testing.TB) {
-	if err := FunctionalTestEnv.ToxiproxyClient.ResetState(); err != nil {
+	if err := FunctionalTestEnv.ResetProxies(); err != nil {
 		t.Error(err)
 	}
 }
@@ -417,34 +277,3 @@ func setupFunctionalTest(t testing.TB) {
 func teardownFunctionalTest(t testing.TB) {
 	resetProxies(t)
 }
-
-type kafkaVersion []int
-
-func (kv kafkaVersion) satisfies(other kafkaVersion) bool {
-	var ov int
-	for index, v := range kv {
-		if len(other) <= index {
-			ov = 0
-		} else {
-			ov = other[index]
-		}
-
-		if v < ov {
-			return false
-		} else if v > ov {
-			return true
-		}
-	}
-	return true
-}
-
-func parseKafkaVersion(version string) kafkaVersion {
-	numbers := strings.Split(version, ".")
-	result := make(kafkaVersion, 0, len(numbers))
-	for _, number := range numbers {
-		nr, _ := strconv.Atoi(number)
-		result = append(result, nr)
-	}
-
-	return result
-}
@@ -0,0 +1,80 @@
+//go:build go1.18
+// +build go1.18
+
+package sarama
+
+import (
+	"errors"
+	"testing"
+)
+
+// fuzzedResponses allocates the responses FuzzResponseDecoders decodes.
+var fuzzedResponses = []func() versionedDecoder{
+	func() versionedDecoder { return new(AddOffsetsToTxnResponse) },
+	func() versionedDecoder { return new(AddPartitionsToTxnResponse) },
+	func() versionedDecoder { return new(AlterClientQuotasResponse) },
+	func() versionedDecoder { return new(AlterConfigsResponse) },
+	func() versionedDecoder { return new(AlterPartitionReassignmentsResponse) },
+	func() versionedDecoder { return new(AlterUserScramCredentialsResponse) },
+	func() versionedDecoder { return new(ApiVersionsResponse) },
+	func() versionedDecoder { return new(ConsumerMetadataResponse) },
+	func() versionedDecoder { return new(CreateAclsResponse) },
+	func() versionedDecoder { return new(CreatePartitionsResponse) },
+	func() versionedDecoder { return new(CreateTopicsResponse) },
+	func() versionedDecoder { return new(DeleteAclsResponse) },
+	func() versionedDecoder { return new(DeleteGroupsResponse) },
+	func() versionedDecoder { return new(DeleteOffsetsResponse) },
+	func() versionedDecoder { return new(DeleteRecordsResponse) },
+	func() versionedDecoder { return new(DeleteTopicsResponse) },
+	func() versionedDecoder { return new(DescribeAclsResponse) },
+	func() versionedDecoder { return new(DescribeClientQuotasResponse) },
+	func() versionedDecoder { return new(DescribeClusterResponse) },
+	func() versionedDecoder { return new(DescribeConfigsResponse) },
+	func() versionedDecoder { return new(DescribeGroupsResponse) },
+	func() versionedDecoder { return new(DescribeLogDirsResponse) },
+	func() versionedDecoder { return new(DescribeTopicPartitionsResponse) },
+	func() versionedDecoder { return new(DescribeUserScramCredentialsResponse) },
+	func() versionedDecoder { return new(ElectLeadersResponse) },
+	func() versionedDecoder { return new(EndTxnResponse) },
+	func() versionedDecoder { return new(FetchResponse) },
+	func() versionedDecoder { return new(FindCoordinatorResponse) },
+	func() versionedDecoder { return new(GetTelemetrySubscriptionsResponse) },
+	func() versionedDecoder { return new(HeartbeatResponse) },
+	func() versionedDecoder { return new(IncrementalAlterConfigsResponse) },
+	func() versionedDecoder { return new(InitProducerIDResponse) },
+	func() versionedDecoder { return new(JoinGroupResponse) },
+	func() versionedDecoder { return new(LeaveGroupResponse) },
+	func() versionedDecoder { return new(ListGroupsResponse) },
+	func() versionedDecoder { return new(ListPartitionReassignmentsResponse) },
+	func() versionedDecoder { return new(MetadataResponse) },
+	func() versionedDecoder { return new(OffsetCommitResponse) },
+	func() versionedDecoder { return new(OffsetFetchResponse) },
+	func() versionedDecoder { return new(OffsetResponse) },
+	func() versionedDecoder { return new(ProduceResponse) },
+	func() versionedDecoder { return new(PushTelemetryResponse) },
+	func() versionedDecoder { return new(SaslAuthenticateResponse) },
+	func() versionedDecoder { return new(SaslHandshakeResponse) },
+	func() versionedDecoder { return new(SyncGroupResponse) },
+	func() versionedDecoder { return new(TxnOffsetCommitResponse) },
+}
+
+// FuzzResponseDecoders decodes arbitrary bytes as each of the responses, of
+// each of the versions, which must fail with a PacketDecodingError or an
+// ErrInsufficientData rather than panic.
+func FuzzResponseDecoders(f *testing.F) {
+	for i := range fuzzedResponses {
+		f.Add(uint8(i), int16(0), []byte{})
+		f.Add(uint8(i), int16(1), []byte{0, 0, 0, 0, 0, 0, 0, 1, 0xff, 0xff, 0xff, 0xff})
+		f.Add(uint8(i), int16(4), []byte{0x7f, 0x7f, 0x7f, 0x7f, 0x7f, 0x7f, 0x7f, 0x7f})
+		f.Add(uint8(i), int16(12), []byte{0, 0, 0, 0, 0x80, 0x80, 0x80, 0x80, 0x08, 0, 0})
+	}
+
+	f.Fuzz(func(t *testing.T, i uint8, version int16, buf []byte) {
+		res := fuzzedResponses[int(i)%len(fuzzedResponses)]()
+		err := versionedDecode(buf, res, version)
+		var decodingErr PacketDecodingError
+		if err != nil && !errors.As(err, &decodingErr) && !errors.Is(err, ErrInsufficientData) {
+			t.Errorf("%T decoding failed with an untyped error: %v", res, err)
+		}
+	})
+}
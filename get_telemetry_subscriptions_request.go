@@ -0,0 +1,43 @@
+package sarama
+
+// GetTelemetrySubscriptionsRequest asks a broker which client metrics to push
+// to it, and how often, see KIP-714.
+type GetTelemetrySubscriptionsRequest struct {
+	Version int16
+	// ClientInstanceID identifies the client, zero for the broker to assign
+	// it an ID on the first request.
+	ClientInstanceID Uuid
+}
+
+func (r *GetTelemetrySubscriptionsRequest) encode(pe packetEncoder) error {
+	if err := r.ClientInstanceID.encode(pe); err != nil {
+		return err
+	}
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *GetTelemetrySubscriptionsRequest) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if err = r.ClientInstanceID.decode(pd); err != nil {
+		return err
+	}
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *GetTelemetrySubscriptionsRequest) key() int16 {
+	return 71
+}
+
+func (r *GetTelemetrySubscriptionsRequest) version() int16 {
+	return r.Version
+}
+
+func (r *GetTelemetrySubscriptionsRequest) headerVersion() int16 {
+	return 2
+}
+
+func (r *GetTelemetrySubscriptionsRequest) requiredVersion() KafkaVersion {
+	return V3_7_0_0
+}
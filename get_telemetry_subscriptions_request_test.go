@@ -0,0 +1,25 @@
+package sarama
+
+import "testing"
+
+var (
+	getTelemetrySubscriptionsRequestNew = []byte{
+		0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, 0, // zero client instance id
+		0, // empty tagged fields
+	}
+
+	getTelemetrySubscriptionsRequestKnown = []byte{
+		0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, // client instance id
+		0, // empty tagged fields
+	}
+)
+
+func TestGetTelemetrySubscriptionsRequest(t *testing.T) {
+	request := &GetTelemetrySubscriptionsRequest{}
+	testRequest(t, "new client", request, getTelemetrySubscriptionsRequestNew)
+
+	request = &GetTelemetrySubscriptionsRequest{
+		ClientInstanceID: Uuid{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+	}
+	testRequest(t, "known client", request, getTelemetrySubscriptionsRequestKnown)
+}
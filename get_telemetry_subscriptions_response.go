@@ -0,0 +1,125 @@
+package sarama
+
+import "time"
+
+// GetTelemetrySubscriptionsResponse tells the client which metrics to push to
+// the broker, and how.
+type GetTelemetrySubscriptionsResponse struct {
+	Version        int16
+	ThrottleTimeMs int32
+	Err            KError
+	// ClientInstanceID is the ID of the client, assigned by the broker if the
+	// request had none.
+	ClientInstanceID Uuid
+	// SubscriptionID identifies the subscription, to be sent along with the
+	// metrics pushed.
+	SubscriptionID int32
+	// AcceptedCompressionTypes are the compression codecs the metrics may be
+	// pushed with, by preference; they may always be pushed uncompressed.
+	AcceptedCompressionTypes []CompressionCodec
+	// PushIntervalMs is the interval to push the metrics at.
+	PushIntervalMs int32
+	// TelemetryMaxBytes is the maximum size of the metrics pushed at once.
+	TelemetryMaxBytes int32
+	// DeltaTemporality tells whether the sums pushed are the deltas since the
+	// previous push, rather than cumulative.
+	DeltaTemporality bool
+	// RequestedMetrics are the prefixes of the names of the metrics to push:
+	// none when empty, all of them when it holds an empty prefix.
+	RequestedMetrics []string
+}
+
+func (r *GetTelemetrySubscriptionsResponse) encode(pe packetEncoder) error {
+	pe.putInt32(r.ThrottleTimeMs)
+	pe.putInt16(int16(r.Err))
+	if err := r.ClientInstanceID.encode(pe); err != nil {
+		return err
+	}
+	pe.putInt32(r.SubscriptionID)
+	pe.putCompactArrayLength(len(r.AcceptedCompressionTypes))
+	for _, codec := range r.AcceptedCompressionTypes {
+		pe.putInt8(int8(codec))
+	}
+	pe.putInt32(r.PushIntervalMs)
+	pe.putInt32(r.TelemetryMaxBytes)
+	pe.putBool(r.DeltaTemporality)
+	if err := pe.putCompactStringArray(r.RequestedMetrics); err != nil {
+		return err
+	}
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *GetTelemetrySubscriptionsResponse) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if r.ThrottleTimeMs, err = pd.getInt32(); err != nil {
+		return err
+	}
+	kerr, err := pd.getInt16()
+	if err != nil {
+		return err
+	}
+	r.Err = KError(kerr)
+	if err = r.ClientInstanceID.decode(pd); err != nil {
+		return err
+	}
+	if r.SubscriptionID, err = pd.getInt32(); err != nil {
+		return err
+	}
+	n, err := pd.getCompactArrayLength()
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		r.AcceptedCompressionTypes = make([]CompressionCodec, n)
+	}
+	for i := 0; i < n; i++ {
+		codec, err := pd.getInt8()
+		if err != nil {
+			return err
+		}
+		r.AcceptedCompressionTypes[i] = CompressionCodec(codec)
+	}
+	if r.PushIntervalMs, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if r.TelemetryMaxBytes, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if r.DeltaTemporality, err = pd.getBool(); err != nil {
+		return err
+	}
+	if r.RequestedMetrics, err = pd.getCompactStringArray(); err != nil {
+		return err
+	}
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *GetTelemetrySubscriptionsResponse) key() int16 {
+	return 71
+}
+
+func (r *GetTelemetrySubscriptionsResponse) version() int16 {
+	return r.Version
+}
+
+func (r *GetTelemetrySubscriptionsResponse) headerVersion() int16 {
+	return 1
+}
+
+func (r *GetTelemetrySubscriptionsResponse) requiredVersion() KafkaVersion {
+	return V3_7_0_0
+}
+
+func (r *GetTelemetrySubscriptionsResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTimeMs) * time.Millisecond
+}
+
+func (r *GetTelemetrySubscriptionsResponse) shouldClientThrottle(version int16) bool {
+	return true
+}
+
+func (r *GetTelemetrySubscriptionsResponse) hasErrorCode() bool {
+	return r.Err != ErrNoError
+}
@@ -0,0 +1,31 @@
+package sarama
+
+import "testing"
+
+var getTelemetrySubscriptionsResponse = []byte{
+	0, 0, 0, 0, // throttle time
+	0, 0, // no error
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, // client instance id
+	0, 0, 0, 7, // subscription id 7
+	3, 4, 1, // accepted compression types [zstd gzip]
+	0, 0, 0x75, 0x30, // push interval 30000ms
+	0, 0, 0x10, 0, // telemetry max bytes 4096
+	1,                                         // delta temporality
+	2,                                         // 2-1=1 requested metric
+	9, 'o', 'r', 'g', '.', 'k', 'a', 'f', 'k', // prefix "org.kafk"
+	0, // empty tagged fields
+}
+
+func TestGetTelemetrySubscriptionsResponse(t *testing.T) {
+	response := &GetTelemetrySubscriptionsResponse{
+		Err:                      ErrNoError,
+		ClientInstanceID:         Uuid{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		SubscriptionID:           7,
+		AcceptedCompressionTypes: []CompressionCodec{CompressionZSTD, CompressionGZIP},
+		PushIntervalMs:           30000,
+		TelemetryMaxBytes:        4096,
+		DeltaTemporality:         true,
+		RequestedMetrics:         []string{"org.kafk"},
+	}
+	testResponse(t, "subscription", response, getTelemetrySubscriptionsResponse)
+}
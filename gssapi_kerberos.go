@@ -23,6 +23,7 @@ const (
 	GSS_API_GENERIC_TAG = 0x60
 	KRB5_USER_AUTH      = 1
 	KRB5_KEYTAB_AUTH    = 2
+	KRB5_CCACHE_AUTH    = 3
 	GSS_API_INITIAL     = 1
 	GSS_API_VERIFY      = 2
 	GSS_API_FINISH      = 3
@@ -31,6 +32,7 @@ const (
 type GSSAPIConfig struct {
 	AuthType           int
 	KeyTabPath         string
+	CCachePath         string
 	KerberosConfigPath string
 	ServiceName        string
 	Username           string
@@ -195,19 +197,32 @@ func (krbAuth *GSSAPIKerberosAuth) initSecContext(bytes []byte, kerberosClient K
 	return nil, nil
 }
 
-/* This does the handshake for authorization */
-func (krbAuth *GSSAPIKerberosAuth) Authorize(broker *Broker) error {
+// kerberosClient returns a logged in client along with the function to call
+// once done with it. Unless NewKerberosClientFunc is set, the client is
+// shared with the other connections using the same configuration.
+func (krbAuth *GSSAPIKerberosAuth) kerberosClient() (KerberosClient, func(), error) {
+	if krbAuth.NewKerberosClientFunc == nil {
+		return sharedKerberosLogin(krbAuth.Config).acquire()
+	}
+
 	kerberosClient, err := krbAuth.NewKerberosClientFunc(krbAuth.Config)
 	if err != nil {
-		Logger.Printf("Kerberos client error: %s", err)
-		return err
+		return nil, nil, err
 	}
+	if err := kerberosClient.Login(); err != nil {
+		return nil, nil, err
+	}
+	return kerberosClient, kerberosClient.Destroy, nil
+}
 
-	err = kerberosClient.Login()
+/* This does the handshake for authorization */
+func (krbAuth *GSSAPIKerberosAuth) Authorize(broker *Broker) error {
+	kerberosClient, release, err := krbAuth.kerberosClient()
 	if err != nil {
-		Logger.Printf("Kerberos client error: %s", err)
+		warnf("Kerberos client error: %s", err)
 		return err
 	}
+	defer release()
 	// Construct SPN using serviceName and host
 	// SPN format: <SERVICE>/<FQDN>
 
@@ -216,24 +231,23 @@ func (krbAuth *GSSAPIKerberosAuth) Authorize(broker *Broker) error {
 
 	ticket, encKey, err := kerberosClient.GetServiceTicket(spn)
 	if err != nil {
-		Logger.Printf("Error getting Kerberos service ticket : %s", err)
+		warnf("Error getting Kerberos service ticket : %s", err)
 		return err
 	}
 	krbAuth.ticket = ticket
 	krbAuth.encKey = encKey
 	krbAuth.step = GSS_API_INITIAL
 	var receivedBytes []byte = nil
-	defer kerberosClient.Destroy()
 	for {
 		packBytes, err := krbAuth.initSecContext(receivedBytes, kerberosClient)
 		if err != nil {
-			Logger.Printf("Error while performing GSSAPI Kerberos Authentication: %s\n", err)
+			warnf("Error while performing GSSAPI Kerberos Authentication: %s\n", err)
 			return err
 		}
 		requestTime := time.Now()
 		bytesWritten, err := krbAuth.writePackage(broker, packBytes)
 		if err != nil {
-			Logger.Printf("Error while performing GSSAPI Kerberos Authentication: %s\n", err)
+			warnf("Error while performing GSSAPI Kerberos Authentication: %s\n", err)
 			return err
 		}
 		broker.updateOutgoingCommunicationMetrics(bytesWritten)
@@ -243,7 +257,7 @@ func (krbAuth *GSSAPIKerberosAuth) Authorize(broker *Broker) error {
 			requestLatency := time.Since(requestTime)
 			broker.updateIncomingCommunicationMetrics(bytesRead, requestLatency)
 			if err != nil {
-				Logger.Printf("Error while performing GSSAPI Kerberos Authentication: %s\n", err)
+				warnf("Error while performing GSSAPI Kerberos Authentication: %s\n", err)
 				return err
 			}
 		} else if krbAuth.step == GSS_API_FINISH {
@@ -1,35 +1,85 @@
 package sarama
 
 type HeartbeatRequest struct {
-	GroupId      string
-	GenerationId int32
-	MemberId     string
+	Version         int16
+	GroupId         string
+	GenerationId    int32
+	MemberId        string
+	GroupInstanceId *string // Version 3
 }
 
-func (r *HeartbeatRequest) encode(pe packetEncoder) error {
-	if err := pe.putString(r.GroupId); err != nil {
+func (r *HeartbeatRequest) encode(pe packetEncoder) (err error) {
+	if r.Version >= 4 {
+		err = pe.putCompactString(r.GroupId)
+	} else {
+		err = pe.putString(r.GroupId)
+	}
+	if err != nil {
 		return err
 	}
 
 	pe.putInt32(r.GenerationId)
 
-	if err := pe.putString(r.MemberId); err != nil {
+	if r.Version >= 4 {
+		err = pe.putCompactString(r.MemberId)
+	} else {
+		err = pe.putString(r.MemberId)
+	}
+	if err != nil {
 		return err
 	}
 
+	if r.Version >= 4 {
+		err = pe.putNullableCompactString(r.GroupInstanceId)
+	} else if r.Version >= 3 {
+		err = pe.putNullableString(r.GroupInstanceId)
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.Version >= 4 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (r *HeartbeatRequest) decode(pd packetDecoder, version int16) (err error) {
-	if r.GroupId, err = pd.getString(); err != nil {
+	r.Version = version
+
+	if version >= 4 {
+		r.GroupId, err = pd.getCompactString()
+	} else {
+		r.GroupId, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
 	if r.GenerationId, err = pd.getInt32(); err != nil {
 		return
 	}
-	if r.MemberId, err = pd.getString(); err != nil {
+	if version >= 4 {
+		r.MemberId, err = pd.getCompactString()
+	} else {
+		r.MemberId, err = pd.getString()
+	}
+	if err != nil {
+		return
+	}
+	if version >= 4 {
+		r.GroupInstanceId, err = pd.getCompactNullableString()
+	} else if version >= 3 {
+		r.GroupInstanceId, err = pd.getNullableString()
+	}
+	if err != nil {
 		return
 	}
+	if version >= 4 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return
+		}
+	}
 
 	return nil
 }
@@ -39,13 +89,27 @@ func (r *HeartbeatRequest) key() int16 {
 }
 
 func (r *HeartbeatRequest) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *HeartbeatRequest) headerVersion() int16 {
+	if r.Version >= 4 {
+		return 2
+	}
 	return 1
 }
 
 func (r *HeartbeatRequest) requiredVersion() KafkaVersion {
-	return V0_9_0_0
+	switch r.Version {
+	case 4:
+		return V2_4_0_0
+	case 3:
+		return V2_3_0_0
+	case 2:
+		return V2_0_0_0
+	case 1:
+		return V0_11_0_0
+	default:
+		return V0_9_0_0
+	}
 }
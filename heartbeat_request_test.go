@@ -15,3 +15,21 @@ func TestHeartbeatRequest(t *testing.T) {
 	request.MemberId = "baz"
 	testRequest(t, "basic", request, basicHeartbeatRequest)
 }
+
+var heartbeatRequestV4 = []byte{
+	4, 'f', 'o', 'o', // Group ID
+	0x00, 0x01, 0x02, 0x03, // Generation ID
+	4, 'b', 'a', 'z', // Member ID
+	5, 'i', 'n', 's', 't', // Group instance ID
+	0, // Tagged fields
+}
+
+func TestHeartbeatRequestV4(t *testing.T) {
+	request := new(HeartbeatRequest)
+	request.Version = 4
+	request.GroupId = "foo"
+	request.GenerationId = 66051
+	request.MemberId = "baz"
+	request.GroupInstanceId = nullString("inst")
+	testRequest(t, "v4", request, heartbeatRequestV4)
+}
@@ -16,3 +16,14 @@ func TestHeartbeatResponse(t *testing.T) {
 		t.Error("Decoding error failed: no error expected but found", response.Err)
 	}
 }
+
+var heartbeatResponseV4WithError = []byte{
+	0, 0, 0, 100, // Throttle time
+	0, 27, // ErrRebalanceInProgress
+	0, // Tagged fields
+}
+
+func TestHeartbeatResponseV4(t *testing.T) {
+	response := &HeartbeatResponse{Version: 4, ThrottleTime: 100, Err: ErrRebalanceInProgress}
+	testResponse(t, "v4 with error", response, heartbeatResponseV4WithError)
+}
@@ -11,6 +11,7 @@ const (
 
 // IncrementalAlterConfigsRequest is an incremental alter config request type
 type IncrementalAlterConfigsRequest struct {
+	Version      int16
 	Resources    []*IncrementalAlterConfigsResource
 	ValidateOnly bool
 }
@@ -27,22 +28,35 @@ type IncrementalAlterConfigsEntry struct {
 }
 
 func (a *IncrementalAlterConfigsRequest) encode(pe packetEncoder) error {
-	if err := pe.putArrayLength(len(a.Resources)); err != nil {
+	if a.Version >= 1 {
+		pe.putCompactArrayLength(len(a.Resources))
+	} else if err := pe.putArrayLength(len(a.Resources)); err != nil {
 		return err
 	}
 
 	for _, r := range a.Resources {
-		if err := r.encode(pe); err != nil {
+		if err := r.encode(pe, a.Version); err != nil {
 			return err
 		}
 	}
 
 	pe.putBool(a.ValidateOnly)
+
+	if a.Version >= 1 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
-func (a *IncrementalAlterConfigsRequest) decode(pd packetDecoder, version int16) error {
-	resourceCount, err := pd.getArrayLength()
+func (a *IncrementalAlterConfigsRequest) decode(pd packetDecoder, version int16) (err error) {
+	a.Version = version
+
+	var resourceCount int
+	if version >= 1 {
+		resourceCount, err = pd.getCompactArrayLength()
+	} else {
+		resourceCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -64,47 +78,79 @@ func (a *IncrementalAlterConfigsRequest) decode(pd packetDecoder, version int16)
 
 	a.ValidateOnly = validateOnly
 
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (a *IncrementalAlterConfigsResource) encode(pe packetEncoder) error {
+func (a *IncrementalAlterConfigsResource) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt8(int8(a.Type))
 
-	if err := pe.putString(a.Name); err != nil {
+	if version >= 1 {
+		err = pe.putCompactString(a.Name)
+	} else {
+		err = pe.putString(a.Name)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := pe.putArrayLength(len(a.ConfigEntries)); err != nil {
+	if version >= 1 {
+		pe.putCompactArrayLength(len(a.ConfigEntries))
+	} else if err = pe.putArrayLength(len(a.ConfigEntries)); err != nil {
 		return err
 	}
 
 	for name, e := range a.ConfigEntries {
-		if err := pe.putString(name); err != nil {
+		if version >= 1 {
+			err = pe.putCompactString(name)
+		} else {
+			err = pe.putString(name)
+		}
+		if err != nil {
 			return err
 		}
 
-		if err := e.encode(pe); err != nil {
+		if err = e.encode(pe, version); err != nil {
 			return err
 		}
 	}
 
+	if version >= 1 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (a *IncrementalAlterConfigsResource) decode(pd packetDecoder, version int16) error {
+func (a *IncrementalAlterConfigsResource) decode(pd packetDecoder, version int16) (err error) {
 	t, err := pd.getInt8()
 	if err != nil {
 		return err
 	}
 	a.Type = ConfigResourceType(t)
 
-	name, err := pd.getString()
+	var name string
+	if version >= 1 {
+		name, err = pd.getCompactString()
+	} else {
+		name, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
 	a.Name = name
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 1 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -112,7 +158,12 @@ func (a *IncrementalAlterConfigsResource) decode(pd packetDecoder, version int16
 	if n > 0 {
 		a.ConfigEntries = make(map[string]IncrementalAlterConfigsEntry, n)
 		for i := 0; i < n; i++ {
-			name, err := pd.getString()
+			var name string
+			if version >= 1 {
+				name, err = pd.getCompactString()
+			} else {
+				name, err = pd.getString()
+			}
 			if err != nil {
 				return err
 			}
@@ -126,33 +177,59 @@ func (a *IncrementalAlterConfigsResource) decode(pd packetDecoder, version int16
 			a.ConfigEntries[name] = v
 		}
 	}
+
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 	return err
 }
 
-func (a *IncrementalAlterConfigsEntry) encode(pe packetEncoder) error {
+func (a *IncrementalAlterConfigsEntry) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt8(int8(a.Operation))
 
-	if err := pe.putNullableString(a.Value); err != nil {
+	if version >= 1 {
+		err = pe.putNullableCompactString(a.Value)
+	} else {
+		err = pe.putNullableString(a.Value)
+	}
+	if err != nil {
 		return err
 	}
 
+	if version >= 1 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (a *IncrementalAlterConfigsEntry) decode(pd packetDecoder, version int16) error {
+func (a *IncrementalAlterConfigsEntry) decode(pd packetDecoder, version int16) (err error) {
 	t, err := pd.getInt8()
 	if err != nil {
 		return err
 	}
 	a.Operation = IncrementalAlterConfigsOperation(t)
 
-	s, err := pd.getNullableString()
+	var s *string
+	if version >= 1 {
+		s, err = pd.getCompactNullableString()
+	} else {
+		s, err = pd.getNullableString()
+	}
 	if err != nil {
 		return err
 	}
 
 	a.Value = s
 
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -161,13 +238,21 @@ func (a *IncrementalAlterConfigsRequest) key() int16 {
 }
 
 func (a *IncrementalAlterConfigsRequest) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *IncrementalAlterConfigsRequest) headerVersion() int16 {
+	if a.Version >= 1 {
+		return 2
+	}
 	return 1
 }
 
 func (a *IncrementalAlterConfigsRequest) requiredVersion() KafkaVersion {
-	return V2_3_0_0
+	switch a.Version {
+	case 1:
+		return V2_4_0_0
+	default:
+		return V2_3_0_0
+	}
 }
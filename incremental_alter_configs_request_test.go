@@ -96,3 +96,40 @@ func TestIncrementalAlterConfigsRequest(t *testing.T) {
 
 	testRequest(t, "two configs", request, doubleIncrementalAlterConfigsRequest)
 }
+
+var singleIncrementalAlterConfigsRequestV1 = []byte{
+	2,                // 1 config
+	2,                // a topic
+	4, 'f', 'o', 'o', // topic name: foo
+	2,  // 1 config name
+	11, // 10 chars
+	's', 'e', 'g', 'm', 'e', 'n', 't', '.', 'm', 's',
+	0, // OperationSet
+	5,
+	'1', '0', '0', '0',
+	0, // empty tagged fields
+	0, // empty tagged fields
+	0, // don't validate
+	0, // empty tagged fields
+}
+
+func TestIncrementalAlterConfigsRequestV1(t *testing.T) {
+	configValue := "1000"
+	request := &IncrementalAlterConfigsRequest{
+		Version: 1,
+		Resources: []*IncrementalAlterConfigsResource{
+			{
+				Type: TopicResource,
+				Name: "foo",
+				ConfigEntries: map[string]IncrementalAlterConfigsEntry{
+					"segment.ms": {
+						Operation: IncrementalAlterConfigsOperationSet,
+						Value:     &configValue,
+					},
+				},
+			},
+		},
+	}
+
+	testRequest(t, "one config v1", request, singleIncrementalAlterConfigsRequestV1)
+}
@@ -4,34 +4,55 @@ import "time"
 
 // IncrementalAlterConfigsResponse is a response type for incremental alter config
 type IncrementalAlterConfigsResponse struct {
+	Version      int16
 	ThrottleTime time.Duration
 	Resources    []*AlterConfigsResourceResponse
 }
 
+// alterConfigsVersion is the version of AlterConfigsResponse sharing the
+// layout of the resource responses: IncrementalAlterConfigs became flexible
+// at version 1, one version before AlterConfigs.
+func (a *IncrementalAlterConfigsResponse) alterConfigsVersion() int16 {
+	return a.Version + 1
+}
+
 func (a *IncrementalAlterConfigsResponse) encode(pe packetEncoder) error {
 	pe.putInt32(int32(a.ThrottleTime / time.Millisecond))
 
-	if err := pe.putArrayLength(len(a.Resources)); err != nil {
+	if a.Version >= 1 {
+		pe.putCompactArrayLength(len(a.Resources))
+	} else if err := pe.putArrayLength(len(a.Resources)); err != nil {
 		return err
 	}
 
 	for _, v := range a.Resources {
-		if err := v.encode(pe); err != nil {
+		if err := v.encode(pe, a.alterConfigsVersion()); err != nil {
 			return err
 		}
 	}
 
+	if a.Version >= 1 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
-func (a *IncrementalAlterConfigsResponse) decode(pd packetDecoder, version int16) error {
+func (a *IncrementalAlterConfigsResponse) decode(pd packetDecoder, version int16) (err error) {
+	a.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
 	}
 	a.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
-	responseCount, err := pd.getArrayLength()
+	var responseCount int
+	if version >= 1 {
+		responseCount, err = pd.getCompactArrayLength()
+	} else {
+		responseCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -41,7 +62,13 @@ func (a *IncrementalAlterConfigsResponse) decode(pd packetDecoder, version int16
 	for i := range a.Resources {
 		a.Resources[i] = new(AlterConfigsResourceResponse)
 
-		if err := a.Resources[i].decode(pd, version); err != nil {
+		if err := a.Resources[i].decode(pd, a.alterConfigsVersion()); err != nil {
+			return err
+		}
+	}
+
+	if version >= 1 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
 			return err
 		}
 	}
@@ -54,13 +81,29 @@ func (a *IncrementalAlterConfigsResponse) key() int16 {
 }
 
 func (a *IncrementalAlterConfigsResponse) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *IncrementalAlterConfigsResponse) headerVersion() int16 {
+	if a.Version >= 1 {
+		return 1
+	}
 	return 0
 }
 
 func (a *IncrementalAlterConfigsResponse) requiredVersion() KafkaVersion {
-	return V2_3_0_0
+	switch a.Version {
+	case 1:
+		return V2_4_0_0
+	default:
+		return V2_3_0_0
+	}
+}
+
+func (a *IncrementalAlterConfigsResponse) throttleTime() time.Duration {
+	return a.ThrottleTime
+}
+
+func (a *IncrementalAlterConfigsResponse) shouldClientThrottle(version int16) bool {
+	return true
 }
@@ -18,6 +18,17 @@ var (
 		2, // topic
 		0, 3, 'f', 'o', 'o',
 	}
+
+	incrementalAlterResponsePopulatedV1 = []byte{
+		0, 0, 0, 0, // throttle
+		2,    // response
+		0, 0, // errorcode
+		1, // string
+		2, // topic
+		4, 'f', 'o', 'o',
+		0, // empty tagged fields
+		0, // empty tagged fields
+	}
 )
 
 func TestIncrementalAlterConfigsResponse(t *testing.T) {
@@ -42,4 +53,7 @@ func TestIncrementalAlterConfigsResponse(t *testing.T) {
 		},
 	}
 	testResponse(t, "response with error", response, incrementalAlterResponsePopulated)
+
+	response.Version = 1
+	testResponse(t, "response with error v1", response, incrementalAlterResponsePopulatedV1)
 }
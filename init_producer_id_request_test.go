@@ -15,6 +15,14 @@ var (
 		0, 3, 't', 'x', 'n',
 		0, 0, 0, 100,
 	}
+
+	initProducerIDRequestV3 = []byte{
+		4, 't', 'x', 'n',
+		0, 0, 0, 100,
+		0, 0, 0, 0, 0, 0, 31, 64, // producerID = 8000
+		0, 1, // epoch
+		0, // empty tagged fields
+	}
 )
 
 func TestInitProducerIDRequest(t *testing.T) {
@@ -29,3 +37,16 @@ func TestInitProducerIDRequest(t *testing.T) {
 
 	testRequest(t, "transaction id", req, initProducerIDRequest)
 }
+
+func TestInitProducerIDRequestV3(t *testing.T) {
+	transactionID := "txn"
+	req := &InitProducerIDRequest{
+		Version:            3,
+		TransactionalID:    &transactionID,
+		TransactionTimeout: 100 * time.Millisecond,
+		ProducerID:         8000,
+		ProducerEpoch:      1,
+	}
+
+	testRequest(t, "v3", req, initProducerIDRequestV3)
+}
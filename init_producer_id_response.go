@@ -3,6 +3,7 @@ package sarama
 import "time"
 
 type InitProducerIDResponse struct {
+	Version       int16
 	ThrottleTime  time.Duration
 	Err           KError
 	ProducerID    int64
@@ -15,10 +16,16 @@ func (i *InitProducerIDResponse) encode(pe packetEncoder) error {
 	pe.putInt64(i.ProducerID)
 	pe.putInt16(i.ProducerEpoch)
 
+	if i.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (i *InitProducerIDResponse) decode(pd packetDecoder, version int16) (err error) {
+	i.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
@@ -39,6 +46,12 @@ func (i *InitProducerIDResponse) decode(pd packetDecoder, version int16) (err er
 		return err
 	}
 
+	if version >= 2 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -47,13 +60,39 @@ func (i *InitProducerIDResponse) key() int16 {
 }
 
 func (i *InitProducerIDResponse) version() int16 {
-	return 0
+	return i.Version
 }
 
 func (i *InitProducerIDResponse) headerVersion() int16 {
+	if i.Version >= 2 {
+		return 1
+	}
 	return 0
 }
 
 func (i *InitProducerIDResponse) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch i.Version {
+	case 4:
+		return V2_7_0_0
+	case 3:
+		return V2_5_0_0
+	case 2:
+		return V2_4_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
+}
+
+func (i *InitProducerIDResponse) throttleTime() time.Duration {
+	return i.ThrottleTime
+}
+
+func (i *InitProducerIDResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
+}
+
+func (i *InitProducerIDResponse) hasErrorCode() bool {
+	return i.Err != ErrNoError
 }
@@ -19,6 +19,14 @@ var (
 		255, 255, 255, 255, 255, 255, 255, 255,
 		0, 0,
 	}
+
+	initProducerIDResponseV2 = []byte{
+		0, 0, 0, 100,
+		0, 0,
+		0, 0, 0, 0, 0, 0, 31, 64, // producerID = 8000
+		0, 1, // epoch
+		0, // empty tagged fields
+	}
 )
 
 func TestInitProducerIDResponse(t *testing.T) {
@@ -35,3 +43,14 @@ func TestInitProducerIDResponse(t *testing.T) {
 
 	testResponse(t, "with error", resp, initProducerIDRequestError)
 }
+
+func TestInitProducerIDResponseV2(t *testing.T) {
+	resp := &InitProducerIDResponse{
+		Version:       2,
+		ThrottleTime:  100 * time.Millisecond,
+		ProducerID:    8000,
+		ProducerEpoch: 1,
+	}
+
+	testResponse(t, "v2", resp, initProducerIDResponseV2)
+}
@@ -5,22 +5,49 @@ type GroupProtocol struct {
 	Metadata []byte
 }
 
-func (p *GroupProtocol) decode(pd packetDecoder) (err error) {
-	p.Name, err = pd.getString()
+func (p *GroupProtocol) decode(pd packetDecoder, version int16) (err error) {
+	if version >= 6 {
+		p.Name, err = pd.getCompactString()
+	} else {
+		p.Name, err = pd.getString()
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 6 {
+		p.Metadata, err = pd.getCompactBytes()
+	} else {
+		p.Metadata, err = pd.getBytes()
+	}
 	if err != nil {
 		return err
 	}
-	p.Metadata, err = pd.getBytes()
+	if version >= 6 {
+		_, err = pd.getEmptyTaggedFieldArray()
+	}
 	return err
 }
 
-func (p *GroupProtocol) encode(pe packetEncoder) (err error) {
-	if err := pe.putString(p.Name); err != nil {
+func (p *GroupProtocol) encode(pe packetEncoder, version int16) (err error) {
+	if version >= 6 {
+		err = pe.putCompactString(p.Name)
+	} else {
+		err = pe.putString(p.Name)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putBytes(p.Metadata); err != nil {
+	if version >= 6 {
+		err = pe.putCompactBytes(p.Metadata)
+	} else {
+		err = pe.putBytes(p.Metadata)
+	}
+	if err != nil {
 		return err
 	}
+	if version >= 6 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
@@ -30,51 +57,76 @@ type JoinGroupRequest struct {
 	SessionTimeout        int32
 	RebalanceTimeout      int32
 	MemberId              string
+	GroupInstanceId       *string // Version 5
 	ProtocolType          string
 	GroupProtocols        map[string][]byte // deprecated; use OrderedGroupProtocols
 	OrderedGroupProtocols []*GroupProtocol
 }
 
-func (r *JoinGroupRequest) encode(pe packetEncoder) error {
-	if err := pe.putString(r.GroupId); err != nil {
+func (r *JoinGroupRequest) encode(pe packetEncoder) (err error) {
+	if r.Version >= 6 {
+		err = pe.putCompactString(r.GroupId)
+	} else {
+		err = pe.putString(r.GroupId)
+	}
+	if err != nil {
 		return err
 	}
 	pe.putInt32(r.SessionTimeout)
 	if r.Version >= 1 {
 		pe.putInt32(r.RebalanceTimeout)
 	}
-	if err := pe.putString(r.MemberId); err != nil {
+	if r.Version >= 6 {
+		err = pe.putCompactString(r.MemberId)
+	} else {
+		err = pe.putString(r.MemberId)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putString(r.ProtocolType); err != nil {
+	if r.Version >= 6 {
+		if err := pe.putNullableCompactString(r.GroupInstanceId); err != nil {
+			return err
+		}
+	} else if r.Version >= 5 {
+		if err := pe.putNullableString(r.GroupInstanceId); err != nil {
+			return err
+		}
+	}
+	if r.Version >= 6 {
+		err = pe.putCompactString(r.ProtocolType)
+	} else {
+		err = pe.putString(r.ProtocolType)
+	}
+	if err != nil {
 		return err
 	}
 
+	protocols := r.OrderedGroupProtocols
 	if len(r.GroupProtocols) > 0 {
 		if len(r.OrderedGroupProtocols) > 0 {
 			return PacketDecodingError{"cannot specify both GroupProtocols and OrderedGroupProtocols on JoinGroupRequest"}
 		}
 
-		if err := pe.putArrayLength(len(r.GroupProtocols)); err != nil {
-			return err
-		}
+		protocols = make([]*GroupProtocol, 0, len(r.GroupProtocols))
 		for name, metadata := range r.GroupProtocols {
-			if err := pe.putString(name); err != nil {
-				return err
-			}
-			if err := pe.putBytes(metadata); err != nil {
-				return err
-			}
+			protocols = append(protocols, &GroupProtocol{Name: name, Metadata: metadata})
 		}
-	} else {
-		if err := pe.putArrayLength(len(r.OrderedGroupProtocols)); err != nil {
+	}
+
+	if r.Version >= 6 {
+		pe.putCompactArrayLength(len(protocols))
+	} else if err := pe.putArrayLength(len(protocols)); err != nil {
+		return err
+	}
+	for _, protocol := range protocols {
+		if err := protocol.encode(pe, r.Version); err != nil {
 			return err
 		}
-		for _, protocol := range r.OrderedGroupProtocols {
-			if err := protocol.encode(pe); err != nil {
-				return err
-			}
-		}
+	}
+
+	if r.Version >= 6 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
@@ -83,7 +135,12 @@ func (r *JoinGroupRequest) encode(pe packetEncoder) error {
 func (r *JoinGroupRequest) decode(pd packetDecoder, version int16) (err error) {
 	r.Version = version
 
-	if r.GroupId, err = pd.getString(); err != nil {
+	if version >= 6 {
+		r.GroupId, err = pd.getCompactString()
+	} else {
+		r.GroupId, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
 
@@ -97,32 +154,62 @@ func (r *JoinGroupRequest) decode(pd packetDecoder, version int16) (err error) {
 		}
 	}
 
-	if r.MemberId, err = pd.getString(); err != nil {
+	if version >= 6 {
+		r.MemberId, err = pd.getCompactString()
+	} else {
+		r.MemberId, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
 
-	if r.ProtocolType, err = pd.getString(); err != nil {
+	if version >= 6 {
+		if r.GroupInstanceId, err = pd.getCompactNullableString(); err != nil {
+			return
+		}
+	} else if version >= 5 {
+		if r.GroupInstanceId, err = pd.getNullableString(); err != nil {
+			return
+		}
+	}
+
+	if version >= 6 {
+		r.ProtocolType, err = pd.getCompactString()
+	} else {
+		r.ProtocolType, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 6 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
-	if n == 0 {
-		return nil
-	}
 
-	r.GroupProtocols = make(map[string][]byte)
+	if n > 0 {
+		r.GroupProtocols = make(map[string][]byte)
+	}
 	for i := 0; i < n; i++ {
 		protocol := &GroupProtocol{}
-		if err := protocol.decode(pd); err != nil {
+		if err := protocol.decode(pd, version); err != nil {
 			return err
 		}
 		r.GroupProtocols[protocol.Name] = protocol.Metadata
 		r.OrderedGroupProtocols = append(r.OrderedGroupProtocols, protocol)
 	}
 
+	if version >= 6 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -135,11 +222,22 @@ func (r *JoinGroupRequest) version() int16 {
 }
 
 func (r *JoinGroupRequest) headerVersion() int16 {
+	if r.Version >= 6 {
+		return 2
+	}
 	return 1
 }
 
 func (r *JoinGroupRequest) requiredVersion() KafkaVersion {
 	switch r.Version {
+	case 6:
+		return V2_4_0_0
+	case 5:
+		return V2_3_0_0
+	case 4:
+		return V2_2_0_0
+	case 3:
+		return V2_0_0_0
 	case 2:
 		return V0_11_0_0
 	case 1:
@@ -31,6 +31,20 @@ var (
 		0, 3, 'o', 'n', 'e', // Protocol name
 		0, 0, 0, 3, 0x01, 0x02, 0x03, // protocol metadata
 	}
+
+	joinGroupRequestV6 = []byte{
+		10, 'T', 'e', 's', 't', 'G', 'r', 'o', 'u', 'p', // Group ID
+		0, 0, 0, 100, // Session timeout
+		0, 0, 0, 200, // Rebalance timeout
+		12, 'O', 'n', 'e', 'P', 'r', 'o', 't', 'o', 'c', 'o', 'l', // Member ID
+		5, 'i', 'n', 's', 't', // Group instance ID
+		9, 'c', 'o', 'n', 's', 'u', 'm', 'e', 'r', // Protocol Type
+		2,                // 1 group protocol
+		4, 'o', 'n', 'e', // Protocol name
+		4, 0x01, 0x02, 0x03, // protocol metadata
+		0, // protocol tagged fields
+		0, // tagged fields
+	}
 )
 
 func TestJoinGroupRequest(t *testing.T) {
@@ -81,3 +95,19 @@ func TestJoinGroupRequestV1(t *testing.T) {
 	request.GroupProtocols["one"] = []byte{0x01, 0x02, 0x03}
 	testRequestDecode(t, "V1", request, packet)
 }
+
+func TestJoinGroupRequestV6(t *testing.T) {
+	request := new(JoinGroupRequest)
+	request.Version = 6
+	request.GroupId = "TestGroup"
+	request.SessionTimeout = 100
+	request.RebalanceTimeout = 200
+	request.MemberId = "OneProtocol"
+	request.GroupInstanceId = nullString("inst")
+	request.ProtocolType = "consumer"
+	request.AddGroupProtocol("one", []byte{0x01, 0x02, 0x03})
+	packet := testRequestEncode(t, "V6", request, joinGroupRequestV6)
+	request.GroupProtocols = make(map[string][]byte)
+	request.GroupProtocols["one"] = []byte{0x01, 0x02, 0x03}
+	testRequestDecode(t, "V6", request, packet)
+}
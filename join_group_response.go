@@ -1,5 +1,7 @@
 package sarama
 
+import "time"
+
 type JoinGroupResponse struct {
 	Version       int16
 	ThrottleTime  int32
@@ -8,7 +10,80 @@ type JoinGroupResponse struct {
 	GroupProtocol string
 	LeaderId      string
 	MemberId      string
-	Members       map[string][]byte
+	Members       map[string][]byte // deprecated; use OrderedMembers
+	// OrderedMembers are the members of the group, in the order of the
+	// response, with their group instance IDs since version 5.
+	OrderedMembers []*GroupMember
+}
+
+// GroupMember is a member of a group as returned to its leader by a
+// JoinGroupRequest.
+type GroupMember struct {
+	MemberId        string
+	GroupInstanceId *string // Version 5
+	Metadata        []byte
+}
+
+func (m *GroupMember) encode(pe packetEncoder, version int16) (err error) {
+	if version >= 6 {
+		err = pe.putCompactString(m.MemberId)
+	} else {
+		err = pe.putString(m.MemberId)
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 6 {
+		err = pe.putNullableCompactString(m.GroupInstanceId)
+	} else if version >= 5 {
+		err = pe.putNullableString(m.GroupInstanceId)
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 6 {
+		err = pe.putCompactBytes(m.Metadata)
+	} else {
+		err = pe.putBytes(m.Metadata)
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 6 {
+		pe.putEmptyTaggedFieldArray()
+	}
+	return nil
+}
+
+func (m *GroupMember) decode(pd packetDecoder, version int16) (err error) {
+	if version >= 6 {
+		m.MemberId, err = pd.getCompactString()
+	} else {
+		m.MemberId, err = pd.getString()
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 6 {
+		m.GroupInstanceId, err = pd.getCompactNullableString()
+	} else if version >= 5 {
+		m.GroupInstanceId, err = pd.getNullableString()
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 6 {
+		m.Metadata, err = pd.getCompactBytes()
+	} else {
+		m.Metadata, err = pd.getBytes()
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 6 {
+		_, err = pd.getEmptyTaggedFieldArray()
+	}
+	return err
 }
 
 func (r *JoinGroupResponse) GetMembers() (map[string]ConsumerGroupMemberMetadata, error) {
@@ -23,35 +98,60 @@ func (r *JoinGroupResponse) GetMembers() (map[string]ConsumerGroupMemberMetadata
 	return members, nil
 }
 
-func (r *JoinGroupResponse) encode(pe packetEncoder) error {
+func (r *JoinGroupResponse) encode(pe packetEncoder) (err error) {
 	if r.Version >= 2 {
 		pe.putInt32(r.ThrottleTime)
 	}
 	pe.putInt16(int16(r.Err))
 	pe.putInt32(r.GenerationId)
 
-	if err := pe.putString(r.GroupProtocol); err != nil {
+	if r.Version >= 6 {
+		err = pe.putCompactString(r.GroupProtocol)
+	} else {
+		err = pe.putString(r.GroupProtocol)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putString(r.LeaderId); err != nil {
+	if r.Version >= 6 {
+		err = pe.putCompactString(r.LeaderId)
+	} else {
+		err = pe.putString(r.LeaderId)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putString(r.MemberId); err != nil {
+	if r.Version >= 6 {
+		err = pe.putCompactString(r.MemberId)
+	} else {
+		err = pe.putString(r.MemberId)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := pe.putArrayLength(len(r.Members)); err != nil {
+	members := r.OrderedMembers
+	if len(members) == 0 {
+		members = make([]*GroupMember, 0, len(r.Members))
+		for memberId, memberMetadata := range r.Members {
+			members = append(members, &GroupMember{MemberId: memberId, Metadata: memberMetadata})
+		}
+	}
+
+	if r.Version >= 6 {
+		pe.putCompactArrayLength(len(members))
+	} else if err = pe.putArrayLength(len(members)); err != nil {
 		return err
 	}
 
-	for memberId, memberMetadata := range r.Members {
-		if err := pe.putString(memberId); err != nil {
+	for _, member := range members {
+		if err = member.encode(pe, r.Version); err != nil {
 			return err
 		}
+	}
 
-		if err := pe.putBytes(memberMetadata); err != nil {
-			return err
-		}
+	if r.Version >= 6 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
@@ -77,39 +177,60 @@ func (r *JoinGroupResponse) decode(pd packetDecoder, version int16) (err error)
 		return
 	}
 
-	if r.GroupProtocol, err = pd.getString(); err != nil {
+	if version >= 6 {
+		r.GroupProtocol, err = pd.getCompactString()
+	} else {
+		r.GroupProtocol, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
 
-	if r.LeaderId, err = pd.getString(); err != nil {
+	if version >= 6 {
+		r.LeaderId, err = pd.getCompactString()
+	} else {
+		r.LeaderId, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
 
-	if r.MemberId, err = pd.getString(); err != nil {
+	if version >= 6 {
+		r.MemberId, err = pd.getCompactString()
+	} else {
+		r.MemberId, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 6 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
-	if n == 0 {
-		return nil
-	}
 
-	r.Members = make(map[string][]byte)
+	if n > 0 {
+		r.Members = make(map[string][]byte)
+	}
 	for i := 0; i < n; i++ {
-		memberId, err := pd.getString()
-		if err != nil {
+		member := new(GroupMember)
+		if err := member.decode(pd, version); err != nil {
 			return err
 		}
 
-		memberMetadata, err := pd.getBytes()
-		if err != nil {
+		r.Members[member.MemberId] = member.Metadata
+		r.OrderedMembers = append(r.OrderedMembers, member)
+	}
+
+	if version >= 6 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
 			return err
 		}
-
-		r.Members[memberId] = memberMetadata
 	}
 
 	return nil
@@ -124,11 +245,22 @@ func (r *JoinGroupResponse) version() int16 {
 }
 
 func (r *JoinGroupResponse) headerVersion() int16 {
+	if r.Version >= 6 {
+		return 1
+	}
 	return 0
 }
 
 func (r *JoinGroupResponse) requiredVersion() KafkaVersion {
 	switch r.Version {
+	case 6:
+		return V2_4_0_0
+	case 5:
+		return V2_3_0_0
+	case 4:
+		return V2_2_0_0
+	case 3:
+		return V2_0_0_0
 	case 2:
 		return V0_11_0_0
 	case 1:
@@ -137,3 +269,15 @@ func (r *JoinGroupResponse) requiredVersion() KafkaVersion {
 		return V0_9_0_0
 	}
 }
+
+func (r *JoinGroupResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTime) * time.Millisecond
+}
+
+func (r *JoinGroupResponse) shouldClientThrottle(version int16) bool {
+	return version >= 3
+}
+
+func (r *JoinGroupResponse) hasErrorCode() bool {
+	return r.Err != ErrNoError
+}
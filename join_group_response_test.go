@@ -54,6 +54,21 @@ var (
 		0, 3, 'b', 'a', 'r', // Member ID
 		0, 0, 0, 0, // No member info
 	}
+
+	joinGroupResponseV6 = []byte{
+		0, 0, 0, 100,
+		0x00, 0x00, // No error
+		0x00, 0x01, 0x02, 0x03, // Generation ID
+		9, 'p', 'r', 'o', 't', 'o', 'c', 'o', 'l', // Protocol name chosen
+		4, 'f', 'o', 'o', // Leader ID
+		4, 'f', 'o', 'o', // Member ID == Leader ID
+		2,                // 1 member
+		4, 'f', 'o', 'o', // Member ID
+		5, 'i', 'n', 's', 't', // Group instance ID
+		4, 0x01, 0x02, 0x03, // Member metadata
+		0, // member tagged fields
+		0, // tagged fields
+	}
 )
 
 func TestJoinGroupResponseV0(t *testing.T) {
@@ -171,3 +186,19 @@ func TestJoinGroupResponseV2(t *testing.T) {
 		t.Error("Decoding Members failed, found:", response.Members)
 	}
 }
+
+func TestJoinGroupResponseV6(t *testing.T) {
+	response := &JoinGroupResponse{
+		Version:       6,
+		ThrottleTime:  100,
+		GenerationId:  66051,
+		GroupProtocol: "protocol",
+		LeaderId:      "foo",
+		MemberId:      "foo",
+		Members:       map[string][]byte{"foo": {0x01, 0x02, 0x03}},
+		OrderedMembers: []*GroupMember{
+			{MemberId: "foo", GroupInstanceId: nullString("inst"), Metadata: []byte{0x01, 0x02, 0x03}},
+		},
+	}
+	testResponse(t, "V6", response, joinGroupResponseV6)
+}
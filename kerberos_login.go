@@ -0,0 +1,155 @@
+package sarama
+
+import (
+	"os"
+	"sync"
+	"time"
+)
+
+var (
+	kerberosLoginsLock sync.Mutex
+	kerberosLogins     = make(map[GSSAPIConfig]*kerberosLogin)
+)
+
+// sharedKerberosLogin returns the login shared by the connections using
+// config, like the LoginManager of the Java client, so that connections do
+// not each perform an AS exchange with the KDC.
+func sharedKerberosLogin(config *GSSAPIConfig) *kerberosLogin {
+	kerberosLoginsLock.Lock()
+	defer kerberosLoginsLock.Unlock()
+
+	login, ok := kerberosLogins[*config]
+	if !ok {
+		login = newKerberosLogin(*config, NewKerberosClient)
+		kerberosLogins[*config] = login
+	}
+	return login
+}
+
+// kerberosLogin keeps a Kerberos client logged in across connections.
+//
+// gokrb5 renews the TGT of keytab and password logins before it expires.
+// Logins from a credential cache cannot be renewed by the client: they are
+// reloaded once the cache is updated on disk, typically by kinit or a
+// sidecar. Any login is also recreated when its keytab, credential cache or
+// Kerberos configuration changes on disk, e.g. after a keytab rotation,
+// without restarting the client.
+type kerberosLogin struct {
+	config    GSSAPIConfig
+	newClient func(config *GSSAPIConfig) (KerberosClient, error)
+
+	lock    sync.Mutex
+	current *kerberosLoginClient
+}
+
+// kerberosLoginClient is a logged in client, destroyed once it has been
+// replaced and no connection is using it anymore.
+type kerberosLoginClient struct {
+	client  KerberosClient
+	files   []kerberosFileStamp
+	refs    int
+	retired bool
+}
+
+// kerberosFileStamp identifies a version of a file on disk.
+type kerberosFileStamp struct {
+	path    string
+	modTime time.Time
+	size    int64
+}
+
+func newKerberosLogin(config GSSAPIConfig, newClient func(config *GSSAPIConfig) (KerberosClient, error)) *kerberosLogin {
+	return &kerberosLogin{config: config, newClient: newClient}
+}
+
+// acquire returns the logged in client, logging in again first if the files
+// it was created from changed, along with the function to call once done
+// with it.
+func (l *kerberosLogin) acquire() (KerberosClient, func(), error) {
+	l.lock.Lock()
+	defer l.lock.Unlock()
+
+	if err := l.refresh(); err != nil {
+		return nil, nil, err
+	}
+
+	current := l.current
+	current.refs++
+	release := func() {
+		l.lock.Lock()
+		defer l.lock.Unlock()
+		current.refs--
+		if current.retired && current.refs == 0 {
+			current.client.Destroy()
+		}
+	}
+	return current.client, release, nil
+}
+
+func (l *kerberosLogin) refresh() error {
+	files := l.stampFiles()
+	if l.current != nil && kerberosFilesEqual(files, l.current.files) {
+		return nil
+	}
+
+	client, err := l.newClient(&l.config)
+	if err == nil {
+		if err = client.Login(); err != nil {
+			client.Destroy()
+		}
+	}
+	if err != nil {
+		if l.current == nil {
+			return err
+		}
+		// the files may be in the middle of being rewritten, keep using
+		// the current login and try again on the next connection
+		warnf("Failed to renew the Kerberos login of %s, keeping the current one: %s\n", l.config.Username, err)
+		return nil
+	}
+
+	if l.current != nil {
+		debugf("Renewed the Kerberos login of %s after its credentials changed\n", l.config.Username)
+		l.current.retired = true
+		if l.current.refs == 0 {
+			l.current.client.Destroy()
+		}
+	}
+	l.current = &kerberosLoginClient{client: client, files: files}
+	return nil
+}
+
+// stampFiles returns the current versions of the files the client is
+// created from.
+func (l *kerberosLogin) stampFiles() []kerberosFileStamp {
+	paths := []string{l.config.KerberosConfigPath}
+	switch l.config.AuthType {
+	case KRB5_KEYTAB_AUTH:
+		paths = append(paths, l.config.KeyTabPath)
+	case KRB5_CCACHE_AUTH:
+		paths = append(paths, l.config.CCachePath)
+	}
+
+	files := make([]kerberosFileStamp, 0, len(paths))
+	for _, path := range paths {
+		stamp := kerberosFileStamp{path: path}
+		if info, err := os.Stat(path); err == nil {
+			stamp.modTime = info.ModTime()
+			stamp.size = info.Size()
+		}
+		files = append(files, stamp)
+	}
+	return files
+}
+
+func kerberosFilesEqual(a, b []kerberosFileStamp) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].path != b[i].path || !a[i].modTime.Equal(b[i].modTime) || a[i].size != b[i].size {
+			return false
+		}
+	}
+	return true
+}
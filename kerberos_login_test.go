@@ -0,0 +1,89 @@
+package sarama
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+type countingKerberosClient struct {
+	MockKerberosClient
+	destroyed bool
+}
+
+func (c *countingKerberosClient) Destroy() {
+	c.destroyed = true
+}
+
+func TestKerberosLoginRenewsOnKeytabChange(t *testing.T) {
+	keytab := filepath.Join(t.TempDir(), "kafka.keytab")
+	if err := os.WriteFile(keytab, []byte("v1"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	var clients []*countingKerberosClient
+	var loginErr error
+	login := newKerberosLogin(GSSAPIConfig{AuthType: KRB5_KEYTAB_AUTH, KeyTabPath: keytab}, func(*GSSAPIConfig) (KerberosClient, error) {
+		client := &countingKerberosClient{MockKerberosClient: MockKerberosClient{mockError: loginErr, errorStage: "login"}}
+		clients = append(clients, client)
+		return client, nil
+	})
+
+	first, releaseFirst, err := login.acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, releaseSecond, err := login.acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseSecond()
+	if first != second || len(clients) != 1 {
+		t.Fatalf("expected the login to be shared, got %d clients", len(clients))
+	}
+
+	// rotate the keytab while the first connection still uses the client
+	if err := os.WriteFile(keytab, []byte("v2 rotated"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	third, releaseThird, err := login.acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer releaseThird()
+	if third == first || len(clients) != 2 {
+		t.Fatalf("expected a new login after the keytab changed, got %d clients", len(clients))
+	}
+	if clients[0].destroyed {
+		t.Fatal("expected the previous client to be kept until released")
+	}
+	releaseFirst()
+	if !clients[0].destroyed {
+		t.Error("expected the previous client to be destroyed once released")
+	}
+
+	// a failed login keeps the current client
+	loginErr = errors.New("KDC unreachable")
+	if err := os.WriteFile(keytab, []byte("v3 partially written"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	fourth, releaseFourth, err := login.acquire()
+	if err != nil {
+		t.Fatal(err)
+	}
+	releaseFourth()
+	if fourth != third || clients[1].destroyed {
+		t.Error("expected the current client to be kept when the login fails")
+	}
+}
+
+func TestKerberosLoginFailure(t *testing.T) {
+	expected := errors.New("KDC unreachable")
+	login := newKerberosLogin(GSSAPIConfig{AuthType: KRB5_USER_AUTH}, func(*GSSAPIConfig) (KerberosClient, error) {
+		return &MockKerberosClient{mockError: expected, errorStage: "login"}, nil
+	})
+	if _, _, err := login.acquire(); !errors.Is(err, expected) {
+		t.Errorf("expected %v, got %v", expected, err)
+	}
+}
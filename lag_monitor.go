@@ -0,0 +1,306 @@
+package sarama
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// PartitionLag is the lag of a consumer group on a partition: how many
+// messages were produced after its committed offset.
+type PartitionLag struct {
+	Group     string
+	Topic     string
+	Partition int32
+	// Committed is the offset committed by the group.
+	Committed int64
+	// End is the offset of the message to be produced next.
+	End int64
+	// Lag is End minus Committed.
+	Lag int64
+}
+
+// LagMonitorOptions configure a LagMonitor.
+type LagMonitorOptions struct {
+	// Interval is the time between two checks of the lags (defaults to 30s).
+	Interval time.Duration
+	// Threshold is the lag above which a partition is reported to
+	// OnThreshold (defaults to 0, no threshold).
+	Threshold int64
+	// OnThreshold, if set, is called when the lag of a partition goes above
+	// Threshold, with exceeded set, and when it goes back to Threshold or
+	// below, with exceeded unset.
+	OnThreshold func(lag PartitionLag, exceeded bool)
+	// OnCheck, if set, is called with the lags of every check.
+	OnCheck func(lags []PartitionLag)
+	// OnError, if set, is called when the lags of a group could not be
+	// checked; the error is logged otherwise.
+	OnError func(group string, err error)
+}
+
+// LagMonitor checks the lags of consumer groups at regular intervals, on the
+// partitions they committed offsets for. It records them as the
+// consumer-group-lag gauges, by group, topic and partition, and their sum by
+// group as the consumer-group-lag-sum gauges, and reports the partitions whose
+// lag exceeds a threshold. It requires Version >= V0_10_2_0, to fetch all the
+// offsets committed by the groups.
+type LagMonitor interface {
+	// Check checks the lags straight away, and returns them sorted by group,
+	// topic and partition, along with the first error of the groups which
+	// could not be checked.
+	Check() ([]PartitionLag, error)
+
+	// Lags returns the lags of the last check.
+	Lags() []PartitionLag
+
+	// Close stops checking the lags and drops their metrics. It does not
+	// close the client.
+	Close() error
+}
+
+type lagMonitor struct {
+	admin  *clusterAdmin
+	groups []string
+	opts   LagMonitorOptions
+
+	lock       sync.Mutex
+	lags       []PartitionLag
+	exceeded   map[lagKey]bool
+	partitions map[lagKey]bool
+
+	closer, closed chan none
+	closeOnce      sync.Once
+}
+
+// lagKey identifies the partition of a group.
+type lagKey struct {
+	group     string
+	topic     string
+	partition int32
+}
+
+// NewLagMonitor starts checking the lags of groups through client. The client
+// must be closed after the LagMonitor.
+func NewLagMonitor(client Client, groups []string, opts LagMonitorOptions) (LagMonitor, error) {
+	if len(groups) == 0 {
+		return nil, ConfigurationError("NewLagMonitor needs at least one group")
+	}
+	if opts.Interval < 0 {
+		return nil, ConfigurationError("LagMonitorOptions.Interval must be >= 0")
+	}
+	if opts.Interval == 0 {
+		opts.Interval = 30 * time.Second
+	}
+	if !client.Config().Version.IsAtLeast(V0_10_2_0) {
+		return nil, ConfigurationError("LagMonitor requires Version >= V0_10_2_0")
+	}
+
+	m := &lagMonitor{
+		admin:      &clusterAdmin{client: client, conf: client.Config()},
+		groups:     groups,
+		opts:       opts,
+		exceeded:   make(map[lagKey]bool),
+		partitions: make(map[lagKey]bool),
+		closer:     make(chan none),
+		closed:     make(chan none),
+	}
+	go withRecover(m.run)
+	return m, nil
+}
+
+func (m *lagMonitor) run() {
+	defer close(m.closed)
+
+	ticker := time.NewTicker(m.opts.Interval)
+	defer ticker.Stop()
+	for {
+		_, _ = m.Check()
+		select {
+		case <-ticker.C:
+		case <-m.closer:
+			return
+		}
+	}
+}
+
+func (m *lagMonitor) Check() ([]PartitionLag, error) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	var (
+		lags     []PartitionLag
+		firstErr error
+	)
+	for _, group := range m.groups {
+		groupLags, err := m.checkGroup(group)
+		if err != nil {
+			if m.opts.OnError != nil {
+				m.opts.OnError(group, err)
+			} else {
+				logWith(groupField(group)).warnf("lag-monitor/%s failed to check the lag: %v\n", group, err)
+			}
+			if firstErr == nil {
+				firstErr = err
+			}
+			// keep the lags of the previous check of the group
+			for _, lag := range m.lags {
+				if lag.Group == group {
+					groupLags = append(groupLags, lag)
+				}
+			}
+		}
+		lags = append(lags, groupLags...)
+	}
+	sort.Slice(lags, func(i, j int) bool {
+		a, b := lags[i], lags[j]
+		if a.Group != b.Group {
+			return a.Group < b.Group
+		}
+		if a.Topic != b.Topic {
+			return a.Topic < b.Topic
+		}
+		return a.Partition < b.Partition
+	})
+
+	m.record(lags)
+	m.lags = lags
+	if m.opts.OnCheck != nil {
+		m.opts.OnCheck(lags)
+	}
+	return lags, firstErr
+}
+
+// checkGroup returns the lags of group on the partitions it committed offsets
+// for.
+func (m *lagMonitor) checkGroup(group string) ([]PartitionLag, error) {
+	committed, err := m.admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !errors.Is(committed.Err, ErrNoError) {
+		return nil, committed.Err
+	}
+
+	partitions := make(map[string][]int32)
+	for topic, blocks := range committed.Blocks {
+		for partition, block := range blocks {
+			if !errors.Is(block.Err, ErrNoError) {
+				return nil, fmt.Errorf("[%s-%d]: %w", topic, partition, block.Err)
+			}
+			if block.Offset >= 0 {
+				partitions[topic] = append(partitions[topic], partition)
+			}
+		}
+	}
+	if len(partitions) == 0 {
+		return nil, nil
+	}
+
+	ends, err := m.admin.ListOffsets(partitions, OffsetNewest)
+	if err != nil {
+		return nil, err
+	}
+
+	lags := make([]PartitionLag, 0, len(partitions))
+	for topic, topicPartitions := range partitions {
+		for _, partition := range topicPartitions {
+			end, ok := ends[topic][partition]
+			if !ok {
+				continue
+			}
+			lag := PartitionLag{
+				Group:     group,
+				Topic:     topic,
+				Partition: partition,
+				Committed: committed.Blocks[topic][partition].Offset,
+				End:       end,
+			}
+			if lag.End > lag.Committed {
+				lag.Lag = lag.End - lag.Committed
+			}
+			lags = append(lags, lag)
+		}
+	}
+	return lags, nil
+}
+
+// record records the lags as metrics, drops those of the partitions no longer
+// checked, and reports the partitions crossing the threshold. m.lock must be
+// held.
+func (m *lagMonitor) record(lags []PartitionLag) {
+	recorder := m.admin.conf.metricsRecorder()
+	sums := make(map[string]int64)
+	partitions := make(map[lagKey]bool, len(lags))
+	for _, lag := range lags {
+		key := lagKey{lag.Group, lag.Topic, lag.Partition}
+		partitions[key] = true
+		sums[lag.Group] += lag.Lag
+		if recorder != nil {
+			recorder.Gauge("consumer-group-lag", key.metricLabels()).Set(lag.Lag)
+		}
+
+		if m.opts.Threshold > 0 && m.opts.OnThreshold != nil {
+			exceeded := lag.Lag > m.opts.Threshold
+			if exceeded != m.exceeded[key] {
+				m.opts.OnThreshold(lag, exceeded)
+			}
+			if exceeded {
+				m.exceeded[key] = true
+			} else {
+				delete(m.exceeded, key)
+			}
+		}
+	}
+	if recorder != nil {
+		for _, group := range m.groups {
+			recorder.Gauge("consumer-group-lag-sum", MetricLabels{MetricLabelGroup: group}).Set(sums[group])
+		}
+	}
+
+	for key := range m.partitions {
+		if !partitions[key] {
+			delete(m.exceeded, key)
+			if unregisterer, ok := recorder.(metricsUnregisterer); ok {
+				unregisterer.Unregister("consumer-group-lag", key.metricLabels())
+			}
+		}
+	}
+	m.partitions = partitions
+}
+
+func (k lagKey) metricLabels() MetricLabels {
+	return MetricLabels{
+		MetricLabelGroup:     k.group,
+		MetricLabelTopic:     k.topic,
+		MetricLabelPartition: strconv.Itoa(int(k.partition)),
+	}
+}
+
+func (m *lagMonitor) Lags() []PartitionLag {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]PartitionLag(nil), m.lags...)
+}
+
+func (m *lagMonitor) Close() error {
+	m.closeOnce.Do(func() {
+		close(m.closer)
+	})
+	<-m.closed
+
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	if unregisterer, ok := m.admin.conf.metricsRecorder().(metricsUnregisterer); ok {
+		for key := range m.partitions {
+			unregisterer.Unregister("consumer-group-lag", key.metricLabels())
+		}
+		for _, group := range m.groups {
+			unregisterer.Unregister("consumer-group-lag-sum", MetricLabels{MetricLabelGroup: group})
+		}
+	}
+	m.partitions = nil
+	return nil
+}
@@ -0,0 +1,120 @@
+package sarama
+
+import (
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestLagMonitor(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+
+	handlers := func(end int64) map[string]MockResponse {
+		return map[string]MockResponse{
+			"MetadataRequest": NewMockMetadataResponse(t).
+				SetController(seedBroker.BrokerID()).
+				SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+				SetLeader("orders", 0, seedBroker.BrokerID()).
+				SetLeader("orders", 1, seedBroker.BrokerID()),
+			"FindCoordinatorRequest": NewMockFindCoordinatorResponse(t).
+				SetCoordinator(CoordinatorGroup, "billing", seedBroker),
+			"OffsetFetchRequest": NewMockOffsetFetchResponse(t).
+				SetOffset("billing", "orders", 0, 10, "", ErrNoError).
+				SetOffset("billing", "orders", 1, 40, "", ErrNoError),
+			"OffsetRequest": NewMockOffsetResponse(t).
+				SetVersion(1).
+				SetOffset("orders", 0, OffsetNewest, end).
+				SetOffset("orders", 1, OffsetNewest, 42),
+		}
+	}
+	seedBroker.SetHandlerByMap(handlers(110))
+
+	recorder := newTestMetricsRecorder()
+	config := NewTestConfig()
+	config.Version = V1_0_0_0
+	config.MetricsRecorder = recorder
+	client, err := NewClient([]string{seedBroker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	var (
+		lock      sync.Mutex
+		crossings []PartitionLag
+		exceeded  []bool
+	)
+	monitor, err := NewLagMonitor(client, []string{"billing"}, LagMonitorOptions{
+		Interval:  time.Hour,
+		Threshold: 50,
+		OnThreshold: func(lag PartitionLag, e bool) {
+			lock.Lock()
+			defer lock.Unlock()
+			crossings = append(crossings, lag)
+			exceeded = append(exceeded, e)
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	lags, err := monitor.Check()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []PartitionLag{
+		{Group: "billing", Topic: "orders", Partition: 0, Committed: 10, End: 110, Lag: 100},
+		{Group: "billing", Topic: "orders", Partition: 1, Committed: 40, End: 42, Lag: 2},
+	}
+	if !reflect.DeepEqual(lags, expected) {
+		t.Fatalf("expected %+v, got %+v", expected, lags)
+	}
+	if !reflect.DeepEqual(monitor.Lags(), expected) {
+		t.Errorf("expected the lags of the last check, got %+v", monitor.Lags())
+	}
+	partition0 := MetricLabels{MetricLabelGroup: "billing", MetricLabelTopic: "orders", MetricLabelPartition: "0"}
+	if lag := recorder.value("consumer-group-lag", partition0); lag != 100 {
+		t.Errorf("expected the lag of partition 0 to be recorded, got %d", lag)
+	}
+	if sum := recorder.value("consumer-group-lag-sum", MetricLabels{MetricLabelGroup: "billing"}); sum != 102 {
+		t.Errorf("expected the lags of the group to be summed, got %d", sum)
+	}
+
+	seedBroker.SetHandlerByMap(handlers(30))
+	if _, err := monitor.Check(); err != nil {
+		t.Fatal(err)
+	}
+	lock.Lock()
+	if len(crossings) != 2 || crossings[0].Partition != 0 || !exceeded[0] || crossings[1].Lag != 20 || exceeded[1] {
+		t.Errorf("expected partition 0 to exceed the threshold and go back below it, got %+v %v", crossings, exceeded)
+	}
+	lock.Unlock()
+
+	safeClose(t, monitor)
+	if len(recorder.unregistered) != 3 {
+		t.Errorf("expected the metrics to be unregistered once closed, got %v", recorder.unregistered)
+	}
+}
+
+func TestLagMonitorValidation(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).SetBroker(seedBroker.Addr(), seedBroker.BrokerID()),
+	})
+
+	client, err := NewClient([]string{seedBroker.Addr()}, NewTestConfig())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	if _, err := NewLagMonitor(client, nil, LagMonitorOptions{}); err == nil {
+		t.Error("expected an error without groups")
+	}
+	if _, err := NewLagMonitor(client, []string{"billing"}, LagMonitorOptions{}); err == nil {
+		t.Error("expected an error for versions without the offsets of all the partitions")
+	}
+}
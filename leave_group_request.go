@@ -1,29 +1,126 @@
 package sarama
 
+// MemberIdentity is a member leaving its group in a LeaveGroupRequest of
+// version 3 or later.
+type MemberIdentity struct {
+	MemberId        string
+	GroupInstanceId *string
+}
+
 type LeaveGroupRequest struct {
+	Version  int16
 	GroupId  string
-	MemberId string
+	MemberId string           // Version 0-2
+	Members  []MemberIdentity // Version 3
 }
 
-func (r *LeaveGroupRequest) encode(pe packetEncoder) error {
-	if err := pe.putString(r.GroupId); err != nil {
+func (r *LeaveGroupRequest) encode(pe packetEncoder) (err error) {
+	if r.Version >= 4 {
+		err = pe.putCompactString(r.GroupId)
+	} else {
+		err = pe.putString(r.GroupId)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putString(r.MemberId); err != nil {
+
+	if r.Version < 3 {
+		return pe.putString(r.MemberId)
+	}
+
+	if r.Version >= 4 {
+		pe.putCompactArrayLength(len(r.Members))
+	} else if err = pe.putArrayLength(len(r.Members)); err != nil {
 		return err
 	}
+	for _, member := range r.Members {
+		if r.Version >= 4 {
+			err = pe.putCompactString(member.MemberId)
+		} else {
+			err = pe.putString(member.MemberId)
+		}
+		if err != nil {
+			return err
+		}
+		if r.Version >= 4 {
+			err = pe.putNullableCompactString(member.GroupInstanceId)
+		} else {
+			err = pe.putNullableString(member.GroupInstanceId)
+		}
+		if err != nil {
+			return err
+		}
+		if r.Version >= 4 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if r.Version >= 4 {
+		pe.putEmptyTaggedFieldArray()
+	}
 
 	return nil
 }
 
 func (r *LeaveGroupRequest) decode(pd packetDecoder, version int16) (err error) {
-	if r.GroupId, err = pd.getString(); err != nil {
+	r.Version = version
+
+	if version >= 4 {
+		r.GroupId, err = pd.getCompactString()
+	} else {
+		r.GroupId, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
-	if r.MemberId, err = pd.getString(); err != nil {
+
+	if version < 3 {
+		r.MemberId, err = pd.getString()
 		return
 	}
 
+	var n int
+	if version >= 4 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
+	if err != nil {
+		return err
+	}
+	if n > 0 {
+		r.Members = make([]MemberIdentity, n)
+	}
+	for i := range r.Members {
+		if version >= 4 {
+			r.Members[i].MemberId, err = pd.getCompactString()
+		} else {
+			r.Members[i].MemberId, err = pd.getString()
+		}
+		if err != nil {
+			return err
+		}
+		if version >= 4 {
+			r.Members[i].GroupInstanceId, err = pd.getCompactNullableString()
+		} else {
+			r.Members[i].GroupInstanceId, err = pd.getNullableString()
+		}
+		if err != nil {
+			return err
+		}
+		if version >= 4 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if version >= 4 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -32,13 +129,27 @@ func (r *LeaveGroupRequest) key() int16 {
 }
 
 func (r *LeaveGroupRequest) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *LeaveGroupRequest) headerVersion() int16 {
+	if r.Version >= 4 {
+		return 2
+	}
 	return 1
 }
 
 func (r *LeaveGroupRequest) requiredVersion() KafkaVersion {
-	return V0_9_0_0
+	switch r.Version {
+	case 4:
+		return V2_4_0_0
+	case 3:
+		return V2_3_0_0
+	case 2:
+		return V2_0_0_0
+	case 1:
+		return V0_11_0_0
+	default:
+		return V0_9_0_0
+	}
 }
@@ -13,3 +13,44 @@ func TestLeaveGroupRequest(t *testing.T) {
 	request.MemberId = "bar"
 	testRequest(t, "basic", request, basicLeaveGroupRequest)
 }
+
+var (
+	leaveGroupRequestV3 = []byte{
+		0, 3, 'f', 'o', 'o',
+		0, 0, 0, 2,
+		0, 3, 'b', 'a', 'r',
+		255, 255, // no group instance ID
+		0, 3, 'b', 'a', 'z',
+		0, 4, 'i', 'n', 's', 't',
+	}
+
+	leaveGroupRequestV4 = []byte{
+		4, 'f', 'o', 'o',
+		2,
+		4, 'b', 'a', 'r',
+		5, 'i', 'n', 's', 't',
+		0, // member tagged fields
+		0, // tagged fields
+	}
+)
+
+func TestLeaveGroupRequestBatches(t *testing.T) {
+	request := &LeaveGroupRequest{
+		Version: 3,
+		GroupId: "foo",
+		Members: []MemberIdentity{
+			{MemberId: "bar"},
+			{MemberId: "baz", GroupInstanceId: nullString("inst")},
+		},
+	}
+	testRequest(t, "v3", request, leaveGroupRequestV3)
+
+	request = &LeaveGroupRequest{
+		Version: 4,
+		GroupId: "foo",
+		Members: []MemberIdentity{
+			{MemberId: "bar", GroupInstanceId: nullString("inst")},
+		},
+	}
+	testRequest(t, "v4", request, leaveGroupRequestV4)
+}
@@ -1,21 +1,129 @@
 package sarama
 
+import "time"
+
+// MemberResponse is the outcome of the leave of a member in a
+// LeaveGroupResponse of version 3 or later.
+type MemberResponse struct {
+	MemberId        string
+	GroupInstanceId *string
+	Err             KError
+}
+
 type LeaveGroupResponse struct {
-	Err KError
+	Version      int16
+	ThrottleTime int32
+	Err          KError
+	Members      []MemberResponse // Version 3
 }
 
-func (r *LeaveGroupResponse) encode(pe packetEncoder) error {
+func (r *LeaveGroupResponse) encode(pe packetEncoder) (err error) {
+	if r.Version >= 1 {
+		pe.putInt32(r.ThrottleTime)
+	}
 	pe.putInt16(int16(r.Err))
+
+	if r.Version >= 3 {
+		if r.Version >= 4 {
+			pe.putCompactArrayLength(len(r.Members))
+		} else if err = pe.putArrayLength(len(r.Members)); err != nil {
+			return err
+		}
+		for _, member := range r.Members {
+			if r.Version >= 4 {
+				err = pe.putCompactString(member.MemberId)
+			} else {
+				err = pe.putString(member.MemberId)
+			}
+			if err != nil {
+				return err
+			}
+			if r.Version >= 4 {
+				err = pe.putNullableCompactString(member.GroupInstanceId)
+			} else {
+				err = pe.putNullableString(member.GroupInstanceId)
+			}
+			if err != nil {
+				return err
+			}
+			pe.putInt16(int16(member.Err))
+			if r.Version >= 4 {
+				pe.putEmptyTaggedFieldArray()
+			}
+		}
+	}
+
+	if r.Version >= 4 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (r *LeaveGroupResponse) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+
+	if version >= 1 {
+		if r.ThrottleTime, err = pd.getInt32(); err != nil {
+			return err
+		}
+	}
+
 	kerr, err := pd.getInt16()
 	if err != nil {
 		return err
 	}
 	r.Err = KError(kerr)
 
+	if version >= 3 {
+		var n int
+		if version >= 4 {
+			n, err = pd.getCompactArrayLength()
+		} else {
+			n, err = pd.getArrayLength()
+		}
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			r.Members = make([]MemberResponse, n)
+		}
+		for i := range r.Members {
+			if version >= 4 {
+				r.Members[i].MemberId, err = pd.getCompactString()
+			} else {
+				r.Members[i].MemberId, err = pd.getString()
+			}
+			if err != nil {
+				return err
+			}
+			if version >= 4 {
+				r.Members[i].GroupInstanceId, err = pd.getCompactNullableString()
+			} else {
+				r.Members[i].GroupInstanceId, err = pd.getNullableString()
+			}
+			if err != nil {
+				return err
+			}
+			memberErr, err := pd.getInt16()
+			if err != nil {
+				return err
+			}
+			r.Members[i].Err = KError(memberErr)
+			if version >= 4 {
+				if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+					return err
+				}
+			}
+		}
+	}
+
+	if version >= 4 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -24,13 +132,47 @@ func (r *LeaveGroupResponse) key() int16 {
 }
 
 func (r *LeaveGroupResponse) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *LeaveGroupResponse) headerVersion() int16 {
+	if r.Version >= 4 {
+		return 1
+	}
 	return 0
 }
 
 func (r *LeaveGroupResponse) requiredVersion() KafkaVersion {
-	return V0_9_0_0
+	switch r.Version {
+	case 4:
+		return V2_4_0_0
+	case 3:
+		return V2_3_0_0
+	case 2:
+		return V2_0_0_0
+	case 1:
+		return V0_11_0_0
+	default:
+		return V0_9_0_0
+	}
+}
+
+func (r *LeaveGroupResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTime) * time.Millisecond
+}
+
+func (r *LeaveGroupResponse) shouldClientThrottle(version int16) bool {
+	return version >= 2
+}
+
+func (r *LeaveGroupResponse) hasErrorCode() bool {
+	if r.Err != ErrNoError {
+		return true
+	}
+	for _, member := range r.Members {
+		if member.Err != ErrNoError {
+			return true
+		}
+	}
+	return false
 }
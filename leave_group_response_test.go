@@ -25,3 +25,25 @@ func TestLeaveGroupResponse(t *testing.T) {
 		t.Error("Decoding error failed: ErrUnknownMemberId expected but found", response.Err)
 	}
 }
+
+var leaveGroupResponseV4 = []byte{
+	0, 0, 0, 100, // Throttle time
+	0, 0, // No error
+	2,
+	4, 'b', 'a', 'r',
+	5, 'i', 'n', 's', 't',
+	0, 25, // ErrUnknownMemberId
+	0, // member tagged fields
+	0, // tagged fields
+}
+
+func TestLeaveGroupResponseV4(t *testing.T) {
+	response := &LeaveGroupResponse{
+		Version:      4,
+		ThrottleTime: 100,
+		Members: []MemberResponse{
+			{MemberId: "bar", GroupInstanceId: nullString("inst"), Err: ErrUnknownMemberId},
+		},
+	}
+	testResponse(t, "v4", response, leaveGroupResponseV4)
+}
@@ -50,6 +50,17 @@ func (l *lengthField) run(curOffset int, buf []byte) error {
 	return nil
 }
 
+func (l *lengthField) runVectored(curOffset int, buf []byte, external []externalBytes) error {
+	length := curOffset - l.startOffset - 4
+	for _, ext := range external {
+		if ext.off >= l.startOffset+4 && ext.off <= curOffset {
+			length += len(ext.data)
+		}
+	}
+	binary.BigEndian.PutUint32(buf[l.startOffset:], uint32(length))
+	return nil
+}
+
 func (l *lengthField) check(curOffset int, buf []byte) error {
 	if int32(curOffset-l.startOffset-4) != l.length {
 		return PacketDecodingError{"length field invalid"}
@@ -1,12 +1,47 @@
 package sarama
 
-type ListGroupsRequest struct{}
+type ListGroupsRequest struct {
+	Version      int16
+	StatesFilter []string // version 4 or later
+	TypesFilter  []string // version 5 or later
+}
 
 func (r *ListGroupsRequest) encode(pe packetEncoder) error {
+	if r.Version >= 4 {
+		if err := pe.putCompactStringArray(r.StatesFilter); err != nil {
+			return err
+		}
+	}
+	if r.Version >= 5 {
+		if err := pe.putCompactStringArray(r.TypesFilter); err != nil {
+			return err
+		}
+	}
+	if r.Version >= 3 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
 func (r *ListGroupsRequest) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if r.Version >= 4 {
+		if r.StatesFilter, err = pd.getCompactStringArray(); err != nil {
+			return err
+		}
+	}
+	if r.Version >= 5 {
+		if r.TypesFilter, err = pd.getCompactStringArray(); err != nil {
+			return err
+		}
+	}
+	if r.Version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -15,13 +50,29 @@ func (r *ListGroupsRequest) key() int16 {
 }
 
 func (r *ListGroupsRequest) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *ListGroupsRequest) headerVersion() int16 {
+	if r.Version >= 3 {
+		return 2
+	}
 	return 1
 }
 
 func (r *ListGroupsRequest) requiredVersion() KafkaVersion {
-	return V0_9_0_0
+	switch r.Version {
+	case 5:
+		return V3_8_0_0
+	case 4:
+		return V2_6_0_0
+	case 3:
+		return V2_4_0_0
+	case 2:
+		return V2_0_0_0
+	case 1:
+		return V0_11_0_0
+	default:
+		return V0_9_0_0
+	}
 }
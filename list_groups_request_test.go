@@ -4,4 +4,25 @@ import "testing"
 
 func TestListGroupsRequest(t *testing.T) {
 	testRequest(t, "ListGroupsRequest", &ListGroupsRequest{}, []byte{})
+
+	testRequest(t, "ListGroupsRequest", &ListGroupsRequest{
+		Version:      4,
+		StatesFilter: []string{"Stable"},
+	}, []byte{
+		2,                               // 1 state
+		7, 'S', 't', 'a', 'b', 'l', 'e', // state filter
+		0, // empty tags
+	})
+
+	testRequest(t, "ListGroupsRequest", &ListGroupsRequest{
+		Version:      5,
+		StatesFilter: []string{"Stable"},
+		TypesFilter:  []string{"consumer"},
+	}, []byte{
+		2,                               // 1 state
+		7, 'S', 't', 'a', 'b', 'l', 'e', // state filter
+		2,                                         // 1 type
+		9, 'c', 'o', 'n', 's', 'u', 'm', 'e', 'r', // type filter
+		0, // empty tags
+	})
 }
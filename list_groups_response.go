@@ -1,29 +1,80 @@
 package sarama
 
+import "time"
+
 type ListGroupsResponse struct {
-	Err    KError
-	Groups map[string]string
+	Version      int16
+	ThrottleTime int32
+	Err          KError
+	Groups       map[string]string
+	GroupsData   map[string]GroupData // version 4 or later
+}
+
+// GroupData holds the state and type of a group returned by ListGroups.
+type GroupData struct {
+	GroupState string // version 4 or later
+	GroupType  string // version 5 or later
 }
 
 func (r *ListGroupsResponse) encode(pe packetEncoder) error {
+	if r.Version >= 1 {
+		pe.putInt32(r.ThrottleTime)
+	}
+
 	pe.putInt16(int16(r.Err))
 
-	if err := pe.putArrayLength(len(r.Groups)); err != nil {
-		return err
+	if r.Version <= 2 {
+		if err := pe.putArrayLength(len(r.Groups)); err != nil {
+			return err
+		}
+		for groupId, protocolType := range r.Groups {
+			if err := pe.putString(groupId); err != nil {
+				return err
+			}
+			if err := pe.putString(protocolType); err != nil {
+				return err
+			}
+		}
+		return nil
 	}
+
+	pe.putCompactArrayLength(len(r.Groups))
 	for groupId, protocolType := range r.Groups {
-		if err := pe.putString(groupId); err != nil {
+		if err := pe.putCompactString(groupId); err != nil {
 			return err
 		}
-		if err := pe.putString(protocolType); err != nil {
+		if err := pe.putCompactString(protocolType); err != nil {
 			return err
 		}
+
+		if r.Version >= 4 {
+			groupData := r.GroupsData[groupId]
+			if err := pe.putCompactString(groupData.GroupState); err != nil {
+				return err
+			}
+			if r.Version >= 5 {
+				if err := pe.putCompactString(groupData.GroupType); err != nil {
+					return err
+				}
+			}
+		}
+
+		pe.putEmptyTaggedFieldArray()
 	}
 
+	pe.putEmptyTaggedFieldArray()
 	return nil
 }
 
 func (r *ListGroupsResponse) decode(pd packetDecoder, version int16) error {
+	r.Version = version
+	if r.Version >= 1 {
+		var err error
+		if r.ThrottleTime, err = pd.getInt32(); err != nil {
+			return err
+		}
+	}
+
 	kerr, err := pd.getInt16()
 	if err != nil {
 		return err
@@ -31,26 +82,66 @@ func (r *ListGroupsResponse) decode(pd packetDecoder, version int16) error {
 
 	r.Err = KError(kerr)
 
-	n, err := pd.getArrayLength()
+	var n int
+	if r.Version <= 2 {
+		n, err = pd.getArrayLength()
+	} else {
+		n, err = pd.getCompactArrayLength()
+	}
 	if err != nil {
 		return err
 	}
-	if n == 0 {
-		return nil
-	}
 
-	r.Groups = make(map[string]string)
-	for i := 0; i < n; i++ {
-		groupId, err := pd.getString()
-		if err != nil {
-			return err
+	if n > 0 {
+		r.Groups = make(map[string]string, n)
+		if r.Version >= 4 {
+			r.GroupsData = make(map[string]GroupData, n)
 		}
-		protocolType, err := pd.getString()
-		if err != nil {
-			return err
+	}
+	for i := 0; i < n; i++ {
+		var groupId, protocolType string
+		if r.Version <= 2 {
+			if groupId, err = pd.getString(); err != nil {
+				return err
+			}
+			if protocolType, err = pd.getString(); err != nil {
+				return err
+			}
+		} else {
+			if groupId, err = pd.getCompactString(); err != nil {
+				return err
+			}
+			if protocolType, err = pd.getCompactString(); err != nil {
+				return err
+			}
 		}
 
 		r.Groups[groupId] = protocolType
+
+		if r.Version >= 4 {
+			var groupData GroupData
+			if groupData.GroupState, err = pd.getCompactString(); err != nil {
+				return err
+			}
+			if r.Version >= 5 {
+				if groupData.GroupType, err = pd.getCompactString(); err != nil {
+					return err
+				}
+			}
+			r.GroupsData[groupId] = groupData
+		}
+
+		if r.Version >= 3 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if r.Version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -61,13 +152,37 @@ func (r *ListGroupsResponse) key() int16 {
 }
 
 func (r *ListGroupsResponse) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *ListGroupsResponse) headerVersion() int16 {
+	if r.Version >= 3 {
+		return 1
+	}
 	return 0
 }
 
 func (r *ListGroupsResponse) requiredVersion() KafkaVersion {
-	return V0_9_0_0
+	switch r.Version {
+	case 5:
+		return V3_8_0_0
+	case 4:
+		return V2_6_0_0
+	case 3:
+		return V2_4_0_0
+	case 2:
+		return V2_0_0_0
+	case 1:
+		return V0_11_0_0
+	default:
+		return V0_9_0_0
+	}
+}
+
+func (r *ListGroupsResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTime) * time.Millisecond
+}
+
+func (r *ListGroupsResponse) shouldClientThrottle(version int16) bool {
+	return version >= 2
 }
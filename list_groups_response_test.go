@@ -57,3 +57,35 @@ func TestListGroupsResponse(t *testing.T) {
 		t.Error("Expected foo group to use consumer protocol")
 	}
 }
+
+var listGroupsResponseV5 = []byte{
+	0, 0, 0, 0, // throttle time
+	0, 0, // no error
+	2,                // 1 group
+	4, 'f', 'o', 'o', // group name
+	9, 'c', 'o', 'n', 's', 'u', 'm', 'e', 'r', // protocol type
+	7, 'S', 't', 'a', 'b', 'l', 'e', // group state
+	8, 'c', 'l', 'a', 's', 's', 'i', 'c', // group type
+	0, // empty tags
+	0, // empty tags
+}
+
+func TestListGroupsResponseV5(t *testing.T) {
+	response := &ListGroupsResponse{
+		Version: 5,
+		Groups:  map[string]string{"foo": "consumer"},
+		GroupsData: map[string]GroupData{
+			"foo": {GroupState: "Stable", GroupType: "classic"},
+		},
+	}
+	testResponse(t, "v5", response, listGroupsResponseV5)
+
+	decoded := new(ListGroupsResponse)
+	testVersionDecodable(t, "v5", decoded, listGroupsResponseV5, 5)
+	if decoded.Groups["foo"] != "consumer" {
+		t.Error("Expected foo group to use consumer protocol")
+	}
+	if data := decoded.GroupsData["foo"]; data.GroupState != "Stable" || data.GroupType != "classic" {
+		t.Errorf("Expected foo group to be a Stable classic group, got %+v", data)
+	}
+}
@@ -0,0 +1,162 @@
+package sarama
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"strconv"
+)
+
+// LogLevel is the severity of a log record. The levels have the values of
+// those of log/slog, so that they convert to slog.Level.
+type LogLevel int
+
+const (
+	// LogLevelDebug is the level of the verbose records Sarama used to write to
+	// DebugLogger.
+	LogLevelDebug LogLevel = -4
+	// LogLevelInfo is the level of the connection and metadata management
+	// events.
+	LogLevelInfo LogLevel = 0
+	// LogLevelWarn is the level of the errors Sarama recovers from, e.g. by
+	// retrying.
+	LogLevelWarn LogLevel = 4
+	// LogLevelError is the level of the errors Sarama gives up on.
+	LogLevelError LogLevel = 8
+)
+
+func (l LogLevel) String() string {
+	switch l {
+	case LogLevelDebug:
+		return "DEBUG"
+	case LogLevelInfo:
+		return "INFO"
+	case LogLevelWarn:
+		return "WARN"
+	case LogLevelError:
+		return "ERROR"
+	default:
+		return "LogLevel(" + strconv.Itoa(int(l)) + ")"
+	}
+}
+
+// The keys of the fields of the log records.
+const (
+	LogFieldBroker        = "broker_id"
+	LogFieldAddr          = "addr"
+	LogFieldTopic         = "topic"
+	LogFieldPartition     = "partition"
+	LogFieldGroup         = "group"
+	LogFieldCorrelationID = "correlation_id"
+	LogFieldConnectionID  = "connection_id"
+	LogFieldAPI           = "api"
+)
+
+// LogField is a structured field of a log record, e.g. the topic it is about.
+type LogField struct {
+	Key   string
+	Value interface{}
+}
+
+// StructuredLogger writes the log records of Sarama, with their levels and
+// structured fields. NewSlogLogger returns one writing to a log/slog handler.
+type StructuredLogger interface {
+	// Enabled reports whether the records of level are written, so that
+	// formatting the others is skipped.
+	Enabled(level LogLevel) bool
+	// Log writes a record. The message is formatted as Sarama used to write it
+	// to Logger, including the values of most fields.
+	Log(level LogLevel, msg string, fields []LogField)
+}
+
+// StructuredLog is the StructuredLogger Sarama writes its log records to. By
+// default, it writes the debug records to DebugLogger and the others to
+// Logger, as they used to be written, without their fields.
+var StructuredLog StructuredLogger = stdLoggerShim{}
+
+// stdLoggerShim writes the log records to Logger and DebugLogger, or to its
+// own loggers when set.
+type stdLoggerShim struct {
+	logger, debugLogger StdLogger
+}
+
+// loggerOf returns the StdLogger the records of level are written to.
+func (s stdLoggerShim) loggerOf(level LogLevel) StdLogger {
+	if level <= LogLevelDebug {
+		if s.debugLogger != nil {
+			return s.debugLogger
+		}
+		return DebugLogger
+	}
+	if s.logger != nil {
+		return s.logger
+	}
+	return Logger
+}
+
+// Enabled is false for the records which would be discarded, as by the
+// default Logger, so that they are not formatted.
+func (s stdLoggerShim) Enabled(level LogLevel) bool {
+	logger := s.loggerOf(level)
+	if _, ok := logger.(*debugLogger); ok {
+		// which writes to Logger
+		logger = Logger
+	}
+	switch logger := logger.(type) {
+	case nil:
+		return false
+	case *log.Logger:
+		return logger.Writer() != io.Discard
+	default:
+		return true
+	}
+}
+
+func (s stdLoggerShim) Log(level LogLevel, msg string, fields []LogField) {
+	s.loggerOf(level).Print(msg)
+}
+
+// logContext holds the fields of the log records about the same subject, e.g.
+// a broker or a partition.
+type logContext []LogField
+
+// logWith returns the logContext of fields.
+func logWith(fields ...LogField) logContext {
+	return fields
+}
+
+// with returns the logContext of the fields of c and of fields.
+func (c logContext) with(fields ...LogField) logContext {
+	return append(c[:len(c):len(c)], fields...)
+}
+
+func (c logContext) logf(level LogLevel, format string, v ...interface{}) {
+	c.logTo(StructuredLog, level, format, v...)
+}
+
+// logTo writes a record to logger rather than to StructuredLog.
+func (c logContext) logTo(logger StructuredLogger, level LogLevel, format string, v ...interface{}) {
+	if logger != nil && logger.Enabled(level) {
+		logger.Log(level, fmt.Sprintf(format, v...), c)
+	}
+}
+
+func (c logContext) debugf(format string, v ...interface{}) { c.logf(LogLevelDebug, format, v...) }
+func (c logContext) infof(format string, v ...interface{})  { c.logf(LogLevelInfo, format, v...) }
+func (c logContext) warnf(format string, v ...interface{})  { c.logf(LogLevelWarn, format, v...) }
+func (c logContext) errorf(format string, v ...interface{}) { c.logf(LogLevelError, format, v...) }
+
+// debugf, infof, warnf and errorf write log records without fields.
+func debugf(format string, v ...interface{}) { logContext(nil).logf(LogLevelDebug, format, v...) }
+func infof(format string, v ...interface{})  { logContext(nil).logf(LogLevelInfo, format, v...) }
+func warnf(format string, v ...interface{})  { logContext(nil).logf(LogLevelWarn, format, v...) }
+func errorf(format string, v ...interface{}) { logContext(nil).logf(LogLevelError, format, v...) }
+
+func brokerField(id int32) LogField        { return LogField{LogFieldBroker, id} }
+func addrField(addr string) LogField       { return LogField{LogFieldAddr, addr} }
+func topicField(topic string) LogField     { return LogField{LogFieldTopic, topic} }
+func partitionField(id int32) LogField     { return LogField{LogFieldPartition, id} }
+func groupField(group string) LogField     { return LogField{LogFieldGroup, group} }
+func correlationIDField(id int32) LogField { return LogField{LogFieldCorrelationID, id} }
+func connectionIDField(id string) LogField { return LogField{LogFieldConnectionID, id} }
+func apiField(key int16) LogField          { return LogField{LogFieldAPI, apiName(key)} }
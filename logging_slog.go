@@ -0,0 +1,35 @@
+//go:build go1.21
+// +build go1.21
+
+package sarama
+
+import (
+	"context"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// NewSlogLogger returns a StructuredLogger writing the log records to handler,
+// with their fields as attributes, e.g.
+//
+//	sarama.StructuredLog = sarama.NewSlogLogger(slog.Default().Handler())
+func NewSlogLogger(handler slog.Handler) StructuredLogger {
+	return slogLogger{handler: handler}
+}
+
+type slogLogger struct {
+	handler slog.Handler
+}
+
+func (l slogLogger) Enabled(level LogLevel) bool {
+	return l.handler.Enabled(context.Background(), slog.Level(level))
+}
+
+func (l slogLogger) Log(level LogLevel, msg string, fields []LogField) {
+	record := slog.NewRecord(time.Now(), slog.Level(level), strings.TrimSuffix(msg, "\n"), 0)
+	for _, field := range fields {
+		record.AddAttrs(slog.Any(field.Key, field.Value))
+	}
+	_ = l.handler.Handle(context.Background(), record)
+}
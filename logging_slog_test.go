@@ -0,0 +1,30 @@
+//go:build go1.21
+// +build go1.21
+
+package sarama
+
+import (
+	"bytes"
+	"log/slog"
+	"testing"
+)
+
+func TestSlogLogger(t *testing.T) {
+	var out bytes.Buffer
+	logger := NewSlogLogger(slog.NewTextHandler(&out, &slog.HandlerOptions{
+		ReplaceAttr: func(groups []string, attr slog.Attr) slog.Attr {
+			if attr.Key == slog.TimeKey {
+				return slog.Attr{}
+			}
+			return attr
+		},
+	}))
+
+	logWith(topicField("my_topic"), partitionField(0)).logTo(logger, LogLevelWarn, "consumer/%s/%d failed\n", "my_topic", 0)
+	logContext(nil).logTo(logger, LogLevelDebug, "not written")
+
+	expected := "level=WARN msg=\"consumer/my_topic/0 failed\" topic=my_topic partition=0\n"
+	if out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,105 @@
+package sarama
+
+import (
+	"bytes"
+	"io"
+	"log"
+	"reflect"
+	"testing"
+)
+
+// recordingLogger is a StructuredLogger recording the records of level Info
+// and more.
+type recordingLogger struct {
+	levels   []LogLevel
+	messages []string
+	fields   [][]LogField
+}
+
+func (l *recordingLogger) Enabled(level LogLevel) bool {
+	return level >= LogLevelInfo
+}
+
+func (l *recordingLogger) Log(level LogLevel, msg string, fields []LogField) {
+	l.levels = append(l.levels, level)
+	l.messages = append(l.messages, msg)
+	l.fields = append(l.fields, fields)
+}
+
+// The tests write to their own loggers rather than swap StructuredLog, which
+// the brokers of other tests may still be logging to.
+
+func TestStructuredLog(t *testing.T) {
+	logger := &recordingLogger{}
+
+	logWith(topicField("my_topic")).with(partitionField(1)).logTo(logger, LogLevelWarn, "consumer/%s/%d failed\n", "my_topic", 1)
+	logContext(nil).logTo(logger, LogLevelDebug, "not recorded %d", 1)
+
+	if len(logger.messages) != 1 {
+		t.Fatalf("expected a single record, got %v", logger.messages)
+	}
+	if logger.levels[0] != LogLevelWarn || logger.messages[0] != "consumer/my_topic/1 failed\n" {
+		t.Errorf("unexpected record %s %q", logger.levels[0], logger.messages[0])
+	}
+	expected := []LogField{{LogFieldTopic, "my_topic"}, {LogFieldPartition, int32(1)}}
+	if len(logger.fields[0]) != 2 || logger.fields[0][0] != expected[0] || logger.fields[0][1] != expected[1] {
+		t.Errorf("expected the fields %v, got %v", expected, logger.fields[0])
+	}
+}
+
+func TestStructuredLogShim(t *testing.T) {
+	var out, debugOut bytes.Buffer
+	shim := stdLoggerShim{logger: log.New(&out, "", 0), debugLogger: log.New(&debugOut, "", 0)}
+
+	logWith(brokerField(1)).logTo(shim, LogLevelInfo, "broker/%d connected\n", 1)
+	logContext(nil).logTo(shim, LogLevelDebug, "client/metadata fetching metadata")
+
+	if out.String() != "broker/1 connected\n" {
+		t.Errorf("expected the record in Logger, got %q", out.String())
+	}
+	if debugOut.String() != "client/metadata fetching metadata\n" {
+		t.Errorf("expected the debug record in DebugLogger, got %q", debugOut.String())
+	}
+}
+
+func TestStructuredLogShimDiscard(t *testing.T) {
+	discard := log.New(io.Discard, "", 0)
+	if (stdLoggerShim{logger: discard, debugLogger: discard}).Enabled(LogLevelDebug) {
+		t.Error("expected the records discarded by the loggers not to be enabled")
+	}
+	if !(stdLoggerShim{logger: discard, debugLogger: log.New(&bytes.Buffer{}, "", 0)}).Enabled(LogLevelDebug) {
+		t.Error("expected the debug records to be enabled with a DebugLogger writing them")
+	}
+	if (stdLoggerShim{}).Enabled(LogLevelDebug) || (stdLoggerShim{}).Enabled(LogLevelError) {
+		t.Error("expected the records not to be enabled with the default loggers, which discard them")
+	}
+}
+
+func TestBrokerRequestLogFields(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	conf := NewTestConfig()
+	conf.ApiVersionsRequest = false
+	broker := NewBroker(mb.Addr())
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.Connected(); err != nil {
+		t.Fatal(err)
+	}
+	connectionID := broker.ConnectionState().ConnectionID
+	logger := &recordingLogger{}
+	broker.requestLog(3, 7).logTo(logger, LogLevelWarn, "broker/%d failed\n", broker.ID())
+	safeClose(t, broker)
+
+	expected := []LogField{
+		{LogFieldBroker, int32(-1)},
+		{LogFieldAddr, mb.Addr()},
+		{LogFieldConnectionID, connectionID},
+		{LogFieldAPI, "Metadata"},
+		{LogFieldCorrelationID, int32(7)},
+	}
+	if connectionID == "" || len(logger.fields) != 1 || !reflect.DeepEqual(logger.fields[0], expected) {
+		t.Errorf("expected the fields %v, got %v", expected, logger.fields)
+	}
+}
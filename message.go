@@ -76,7 +76,8 @@ type Message struct {
 	Timestamp        time.Time        // the timestamp of the message (version 1+ only)
 
 	compressedCache []byte
-	compressedSize  int // used for computing the compression ratio metrics
+	compressedSize  int                // used for computing the compression ratio metrics
+	compression     compressionOptions // configures the codec beyond CompressionLevel
 }
 
 func (m *Message) encode(pe packetEncoder) error {
@@ -107,7 +108,7 @@ func (m *Message) encode(pe packetEncoder) error {
 		payload = m.compressedCache
 		m.compressedCache = nil
 	} else if m.Value != nil {
-		payload, err = compress(m.Codec, m.CompressionLevel, m.Value)
+		payload, err = compress(m.Codec, m.CompressionLevel, m.compression, m.Value)
 		if err != nil {
 			return err
 		}
@@ -169,7 +170,7 @@ func (m *Message) decode(pd packetDecoder) (err error) {
 	m.compressedSize = len(m.Value)
 
 	if m.Value != nil && m.Codec != CompressionNone {
-		m.Value, err = decompress(m.Codec, m.Value)
+		m.Value, err = pd.recordsLimits().decompress(m.Codec, pd.zstdDecoderParams(), nil, m.Value)
 		if err != nil {
 			return err
 		}
@@ -4,12 +4,20 @@ type MetadataRequest struct {
 	Version                int16
 	Topics                 []string
 	AllowAutoTopicCreation bool
+	// IncludeClusterAuthorizedOperations and IncludeTopicAuthorizedOperations
+	// request the operations the client is authorized to perform on the
+	// cluster and on the topics, from version 8.
+	IncludeClusterAuthorizedOperations bool
+	IncludeTopicAuthorizedOperations   bool
 }
 
 func (r *MetadataRequest) encode(pe packetEncoder) error {
-	if r.Version < 0 || r.Version > 5 {
+	if r.Version < 0 || r.Version > 10 {
 		return PacketEncodingError{"invalid or unsupported MetadataRequest version field"}
 	}
+	if r.Version >= 9 {
+		return r.encodeFlexible(pe)
+	}
 	if r.Version == 0 || len(r.Topics) > 0 {
 		err := pe.putArrayLength(len(r.Topics))
 		if err != nil {
@@ -28,11 +36,47 @@ func (r *MetadataRequest) encode(pe packetEncoder) error {
 	if r.Version > 3 {
 		pe.putBool(r.AllowAutoTopicCreation)
 	}
+	if r.Version >= 8 {
+		pe.putBool(r.IncludeClusterAuthorizedOperations)
+		pe.putBool(r.IncludeTopicAuthorizedOperations)
+	}
+	return nil
+}
+
+// encodeFlexible encodes the versions 9 and 10, the topics of version 10 being
+// named rather than identified by their ID.
+func (r *MetadataRequest) encodeFlexible(pe packetEncoder) error {
+	if len(r.Topics) > 0 {
+		pe.putCompactArrayLength(len(r.Topics))
+		for i := range r.Topics {
+			if r.Version >= 10 {
+				var topicID Uuid
+				if err := topicID.encode(pe); err != nil {
+					return err
+				}
+				if err := pe.putNullableCompactString(&r.Topics[i]); err != nil {
+					return err
+				}
+			} else if err := pe.putCompactString(r.Topics[i]); err != nil {
+				return err
+			}
+			pe.putEmptyTaggedFieldArray()
+		}
+	} else {
+		pe.putCompactArrayLength(-1)
+	}
+	pe.putBool(r.AllowAutoTopicCreation)
+	pe.putBool(r.IncludeClusterAuthorizedOperations)
+	pe.putBool(r.IncludeTopicAuthorizedOperations)
+	pe.putEmptyTaggedFieldArray()
 	return nil
 }
 
 func (r *MetadataRequest) decode(pd packetDecoder, version int16) error {
 	r.Version = version
+	if r.Version >= 9 {
+		return r.decodeFlexible(pd)
+	}
 	size, err := pd.getInt32()
 	if err != nil {
 		return err
@@ -54,9 +98,58 @@ func (r *MetadataRequest) decode(pd packetDecoder, version int16) error {
 		}
 		r.AllowAutoTopicCreation = autoCreation
 	}
+	if r.Version >= 8 {
+		if r.IncludeClusterAuthorizedOperations, err = pd.getBool(); err != nil {
+			return err
+		}
+		if r.IncludeTopicAuthorizedOperations, err = pd.getBool(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
+func (r *MetadataRequest) decodeFlexible(pd packetDecoder) (err error) {
+	size, err := pd.getCompactArrayLength()
+	if err != nil {
+		return err
+	}
+	if size > 0 {
+		r.Topics = make([]string, size)
+		for i := range r.Topics {
+			if r.Version >= 10 {
+				var topicID Uuid
+				if err := topicID.decode(pd); err != nil {
+					return err
+				}
+				topic, err := pd.getCompactNullableString()
+				if err != nil {
+					return err
+				}
+				if topic != nil {
+					r.Topics[i] = *topic
+				}
+			} else if r.Topics[i], err = pd.getCompactString(); err != nil {
+				return err
+			}
+			if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+	if r.AllowAutoTopicCreation, err = pd.getBool(); err != nil {
+		return err
+	}
+	if r.IncludeClusterAuthorizedOperations, err = pd.getBool(); err != nil {
+		return err
+	}
+	if r.IncludeTopicAuthorizedOperations, err = pd.getBool(); err != nil {
+		return err
+	}
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
 func (r *MetadataRequest) key() int16 {
 	return 3
 }
@@ -66,6 +159,9 @@ func (r *MetadataRequest) version() int16 {
 }
 
 func (r *MetadataRequest) headerVersion() int16 {
+	if r.Version >= 9 {
+		return 2
+	}
 	return 1
 }
 
@@ -79,6 +175,16 @@ func (r *MetadataRequest) requiredVersion() KafkaVersion {
 		return V0_11_0_0
 	case 5:
 		return V1_0_0_0
+	case 6:
+		return V2_0_0_0
+	case 7:
+		return V2_1_0_0
+	case 8:
+		return V2_3_0_0
+	case 9:
+		return V2_4_0_0
+	case 10:
+		return V2_8_0_0
 	default:
 		return MinVersion
 	}
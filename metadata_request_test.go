@@ -136,3 +136,25 @@ func TestMetadataRequestV5(t *testing.T) {
 	request.AllowAutoTopicCreation = false
 	testRequest(t, "one topic", request, metadataRequestNoAutoCreateV5)
 }
+
+func TestMetadataRequestV10(t *testing.T) {
+	request := new(MetadataRequest)
+	request.Version = 10
+	testRequest(t, "no topics", request, []byte{
+		0x00,
+		0x00, 0x00, 0x00,
+		0x00,
+	})
+
+	request.Topics = []string{"topic1"}
+	request.AllowAutoTopicCreation = true
+	request.IncludeTopicAuthorizedOperations = true
+	testRequest(t, "one topic", request, []byte{
+		0x02,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x07, 't', 'o', 'p', 'i', 'c', '1',
+		0x00,
+		0x01, 0x00, 0x01,
+		0x00,
+	})
+}
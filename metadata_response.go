@@ -1,9 +1,13 @@
 package sarama
 
+import "time"
+
 type PartitionMetadata struct {
-	Err             KError
-	ID              int32
-	Leader          int32
+	Err    KError
+	ID     int32
+	Leader int32
+	// LeaderEpoch is the epoch of the leader, from version 7, -1 before.
+	LeaderEpoch     int32
 	Replicas        []int32
 	Isr             []int32
 	OfflineReplicas []int32
@@ -26,24 +30,35 @@ func (pm *PartitionMetadata) decode(pd packetDecoder, version int16) (err error)
 		return err
 	}
 
-	pm.Replicas, err = pd.getInt32Array()
+	pm.LeaderEpoch = -1
+	if version >= 7 {
+		pm.LeaderEpoch, err = pd.getInt32()
+		if err != nil {
+			return err
+		}
+	}
+
+	pm.Replicas, err = getMetadataInt32Array(pd, version)
 	if err != nil {
 		return err
 	}
 
-	pm.Isr, err = pd.getInt32Array()
+	pm.Isr, err = getMetadataInt32Array(pd, version)
 	if err != nil {
 		return err
 	}
 
 	if version >= 5 {
-		pm.OfflineReplicas, err = pd.getInt32Array()
+		pm.OfflineReplicas, err = getMetadataInt32Array(pd, version)
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	if version >= 9 {
+		_, err = pd.getEmptyTaggedFieldArray()
+	}
+	return err
 }
 
 func (pm *PartitionMetadata) encode(pe packetEncoder, version int16) (err error) {
@@ -51,31 +66,65 @@ func (pm *PartitionMetadata) encode(pe packetEncoder, version int16) (err error)
 	pe.putInt32(pm.ID)
 	pe.putInt32(pm.Leader)
 
-	err = pe.putInt32Array(pm.Replicas)
+	if version >= 7 {
+		pe.putInt32(pm.LeaderEpoch)
+	}
+
+	err = putMetadataInt32Array(pe, version, pm.Replicas)
 	if err != nil {
 		return err
 	}
 
-	err = pe.putInt32Array(pm.Isr)
+	err = putMetadataInt32Array(pe, version, pm.Isr)
 	if err != nil {
 		return err
 	}
 
 	if version >= 5 {
-		err = pe.putInt32Array(pm.OfflineReplicas)
+		err = putMetadataInt32Array(pe, version, pm.OfflineReplicas)
 		if err != nil {
 			return err
 		}
 	}
 
+	if version >= 9 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
+// getMetadataInt32Array and putMetadataInt32Array handle the arrays of the
+// metadata, compact from version 9.
+func getMetadataInt32Array(pd packetDecoder, version int16) ([]int32, error) {
+	if version >= 9 {
+		return pd.getCompactInt32Array()
+	}
+	return pd.getInt32Array()
+}
+
+func putMetadataInt32Array(pe packetEncoder, version int16, in []int32) error {
+	if version >= 9 {
+		if in == nil {
+			// the compact arrays of the metadata are not nullable
+			in = []int32{}
+		}
+		return pe.putCompactInt32Array(in)
+	}
+	return pe.putInt32Array(in)
+}
+
 type TopicMetadata struct {
-	Err        KError
-	Name       string
+	Err  KError
+	Name string
+	// TopicID is the ID of the topic, which changes when the topic is
+	// deleted and recreated under the same name, from version 10.
+	TopicID    Uuid
 	IsInternal bool // Only valid for Version >= 1
 	Partitions []*PartitionMetadata
+	// TopicAuthorizedOperations is the bit field of the operations the
+	// client is authorized to perform on the topic, from version 8 if
+	// requested with IncludeTopicAuthorizedOperations.
+	TopicAuthorizedOperations int32
 }
 
 func (tm *TopicMetadata) decode(pd packetDecoder, version int16) (err error) {
@@ -85,7 +134,23 @@ func (tm *TopicMetadata) decode(pd packetDecoder, version int16) (err error) {
 	}
 	tm.Err = KError(tmp)
 
-	tm.Name, err = pd.getString()
+	switch {
+	case version >= 10:
+		name, err := pd.getCompactNullableString()
+		if err != nil {
+			return err
+		}
+		if name != nil {
+			tm.Name = *name
+		}
+		if err := tm.TopicID.decode(pd); err != nil {
+			return err
+		}
+	case version >= 9:
+		tm.Name, err = pd.getCompactString()
+	default:
+		tm.Name, err = pd.getString()
+	}
 	if err != nil {
 		return err
 	}
@@ -97,7 +162,12 @@ func (tm *TopicMetadata) decode(pd packetDecoder, version int16) (err error) {
 		}
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 9 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -110,13 +180,33 @@ func (tm *TopicMetadata) decode(pd packetDecoder, version int16) (err error) {
 		}
 	}
 
-	return nil
+	if version >= 8 {
+		tm.TopicAuthorizedOperations, err = pd.getInt32()
+		if err != nil {
+			return err
+		}
+	}
+
+	if version >= 9 {
+		_, err = pd.getEmptyTaggedFieldArray()
+	}
+	return err
 }
 
 func (tm *TopicMetadata) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt16(int16(tm.Err))
 
-	err = pe.putString(tm.Name)
+	switch {
+	case version >= 10:
+		if err = pe.putNullableCompactString(&tm.Name); err != nil {
+			return err
+		}
+		err = tm.TopicID.encode(pe)
+	case version >= 9:
+		err = pe.putCompactString(tm.Name)
+	default:
+		err = pe.putString(tm.Name)
+	}
 	if err != nil {
 		return err
 	}
@@ -125,8 +215,9 @@ func (tm *TopicMetadata) encode(pe packetEncoder, version int16) (err error) {
 		pe.putBool(tm.IsInternal)
 	}
 
-	err = pe.putArrayLength(len(tm.Partitions))
-	if err != nil {
+	if version >= 9 {
+		pe.putCompactArrayLength(len(tm.Partitions))
+	} else if err = pe.putArrayLength(len(tm.Partitions)); err != nil {
 		return err
 	}
 
@@ -137,6 +228,13 @@ func (tm *TopicMetadata) encode(pe packetEncoder, version int16) (err error) {
 		}
 	}
 
+	if version >= 8 {
+		pe.putInt32(tm.TopicAuthorizedOperations)
+	}
+
+	if version >= 9 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
@@ -147,6 +245,10 @@ type MetadataResponse struct {
 	ClusterID      *string
 	ControllerID   int32
 	Topics         []*TopicMetadata
+	// ClusterAuthorizedOperations is the bit field of the operations the
+	// client is authorized to perform on the cluster, in the versions 8 to 10
+	// if requested with IncludeClusterAuthorizedOperations.
+	ClusterAuthorizedOperations int32
 }
 
 func (r *MetadataResponse) decode(pd packetDecoder, version int16) (err error) {
@@ -159,7 +261,12 @@ func (r *MetadataResponse) decode(pd packetDecoder, version int16) (err error) {
 		}
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 9 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -173,11 +280,13 @@ func (r *MetadataResponse) decode(pd packetDecoder, version int16) (err error) {
 		}
 	}
 
-	if version >= 2 {
+	if version >= 9 {
+		r.ClusterID, err = pd.getCompactNullableString()
+	} else if version >= 2 {
 		r.ClusterID, err = pd.getNullableString()
-		if err != nil {
-			return err
-		}
+	}
+	if err != nil {
+		return err
 	}
 
 	if version >= 1 {
@@ -189,7 +298,11 @@ func (r *MetadataResponse) decode(pd packetDecoder, version int16) (err error) {
 		r.ControllerID = -1
 	}
 
-	n, err = pd.getArrayLength()
+	if version >= 9 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -203,7 +316,17 @@ func (r *MetadataResponse) decode(pd packetDecoder, version int16) (err error) {
 		}
 	}
 
-	return nil
+	if version >= 8 {
+		r.ClusterAuthorizedOperations, err = pd.getInt32()
+		if err != nil {
+			return err
+		}
+	}
+
+	if version >= 9 {
+		_, err = pd.getEmptyTaggedFieldArray()
+	}
+	return err
 }
 
 func (r *MetadataResponse) encode(pe packetEncoder) error {
@@ -211,18 +334,24 @@ func (r *MetadataResponse) encode(pe packetEncoder) error {
 		pe.putInt32(r.ThrottleTimeMs)
 	}
 
-	err := pe.putArrayLength(len(r.Brokers))
-	if err != nil {
+	if r.Version >= 9 {
+		pe.putCompactArrayLength(len(r.Brokers))
+	} else if err := pe.putArrayLength(len(r.Brokers)); err != nil {
 		return err
 	}
 	for _, broker := range r.Brokers {
-		err = broker.encode(pe, r.Version)
+		err := broker.encode(pe, r.Version)
 		if err != nil {
 			return err
 		}
 	}
 
-	if r.Version >= 2 {
+	if r.Version >= 9 {
+		err := pe.putNullableCompactString(r.ClusterID)
+		if err != nil {
+			return err
+		}
+	} else if r.Version >= 2 {
 		err := pe.putNullableString(r.ClusterID)
 		if err != nil {
 			return err
@@ -233,17 +362,25 @@ func (r *MetadataResponse) encode(pe packetEncoder) error {
 		pe.putInt32(r.ControllerID)
 	}
 
-	err = pe.putArrayLength(len(r.Topics))
-	if err != nil {
+	if r.Version >= 9 {
+		pe.putCompactArrayLength(len(r.Topics))
+	} else if err := pe.putArrayLength(len(r.Topics)); err != nil {
 		return err
 	}
 	for _, tm := range r.Topics {
-		err = tm.encode(pe, r.Version)
+		err := tm.encode(pe, r.Version)
 		if err != nil {
 			return err
 		}
 	}
 
+	if r.Version >= 8 {
+		pe.putInt32(r.ClusterAuthorizedOperations)
+	}
+
+	if r.Version >= 9 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
@@ -256,6 +393,9 @@ func (r *MetadataResponse) version() int16 {
 }
 
 func (r *MetadataResponse) headerVersion() int16 {
+	if r.Version >= 9 {
+		return 1
+	}
 	return 0
 }
 
@@ -269,6 +409,16 @@ func (r *MetadataResponse) requiredVersion() KafkaVersion {
 		return V0_11_0_0
 	case 5:
 		return V1_0_0_0
+	case 6:
+		return V2_0_0_0
+	case 7:
+		return V2_1_0_0
+	case 8:
+		return V2_3_0_0
+	case 9:
+		return V2_4_0_0
+	case 10:
+		return V2_8_0_0
 	default:
 		return MinVersion
 	}
@@ -323,3 +473,25 @@ foundPartition:
 	pmatch.OfflineReplicas = offline
 	pmatch.Err = err
 }
+
+func (r *MetadataResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTimeMs) * time.Millisecond
+}
+
+func (r *MetadataResponse) shouldClientThrottle(version int16) bool {
+	return version >= 6
+}
+
+func (r *MetadataResponse) hasErrorCode() bool {
+	for _, topic := range r.Topics {
+		if topic.Err != ErrNoError {
+			return true
+		}
+		for _, partition := range topic.Partitions {
+			if partition.Err != ErrNoError {
+				return true
+			}
+		}
+	}
+	return false
+}
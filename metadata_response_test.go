@@ -297,3 +297,26 @@ func TestMetadataResponseWithOfflineReplicasV5(t *testing.T) {
 		t.Error("Decoding produced", len(response.Topics[0].Partitions[0].OfflineReplicas), "should have been 1!")
 	}
 }
+
+func TestMetadataResponseV10(t *testing.T) {
+	response := &MetadataResponse{
+		Version:      10,
+		ClusterID:    nullString("clusterId"),
+		ControllerID: 1,
+		Topics: []*TopicMetadata{{
+			Name:    "foo",
+			TopicID: Uuid{1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, 16},
+			Partitions: []*PartitionMetadata{{
+				ID:              0,
+				Leader:          1,
+				LeaderEpoch:     7,
+				Replicas:        []int32{1, 2},
+				Isr:             []int32{1},
+				OfflineReplicas: []int32{2},
+			}},
+			TopicAuthorizedOperations: -2147483648,
+		}},
+	}
+	response.AddBroker("localhost:9092", 1)
+	testResponse(t, "one topic", response, nil)
+}
@@ -0,0 +1,113 @@
+package sarama
+
+import (
+	"fmt"
+	"sort"
+	"time"
+)
+
+// metadataSnapshotVersion is the version of the format of the snapshots
+// returned by Client.MetadataSnapshot.
+const metadataSnapshotVersion int16 = 0
+
+// metadataSnapshotMetadataVersion is the version of the MetadataResponse a
+// snapshot holds, the latest one, storing the racks of the brokers, the
+// cluster ID, the epochs of the leaders and the IDs of the topics.
+const metadataSnapshotMetadataVersion int16 = 10
+
+// metadataSnapshot is the metadata cached by a client, as a MetadataResponse,
+// and when it was taken.
+type metadataSnapshot struct {
+	Time     time.Time
+	Metadata *MetadataResponse
+}
+
+func (s *metadataSnapshot) encode(pe packetEncoder) error {
+	pe.putInt16(metadataSnapshotVersion)
+	pe.putInt64(s.Time.UnixNano() / int64(time.Millisecond))
+	pe.putInt16(s.Metadata.Version)
+	return s.Metadata.encode(pe)
+}
+
+func (s *metadataSnapshot) decode(pd packetDecoder) error {
+	version, err := pd.getInt16()
+	if err != nil {
+		return err
+	}
+	if version != metadataSnapshotVersion {
+		return PacketDecodingError{fmt.Sprintf("unknown metadata snapshot version (%d)", version)}
+	}
+
+	millis, err := pd.getInt64()
+	if err != nil {
+		return err
+	}
+	s.Time = time.Unix(0, millis*int64(time.Millisecond))
+
+	metadataVersion, err := pd.getInt16()
+	if err != nil {
+		return err
+	}
+	s.Metadata = new(MetadataResponse)
+	return s.Metadata.decode(pd, metadataVersion)
+}
+
+func (client *client) MetadataSnapshot() ([]byte, error) {
+	if client.Closed() {
+		return nil, ErrClosedClient
+	}
+
+	client.lock.RLock()
+	metadata := &MetadataResponse{
+		Version:      metadataSnapshotMetadataVersion,
+		ControllerID: client.controllerID,
+	}
+	if client.clusterID != "" {
+		clusterID := client.clusterID
+		metadata.ClusterID = &clusterID
+	}
+	for _, broker := range client.brokers {
+		addr := broker.addr
+		if broker.advertisedAddr != "" {
+			addr = broker.advertisedAddr
+		}
+		metadata.Brokers = append(metadata.Brokers, &Broker{id: broker.id, addr: addr, rack: broker.rack})
+	}
+	for topic, partitions := range client.metadata {
+		tm := &TopicMetadata{Name: topic, TopicID: client.topicIDs[topic], Partitions: make([]*PartitionMetadata, 0, len(partitions))}
+		for _, partition := range partitions {
+			tm.Partitions = append(tm.Partitions, partition)
+		}
+		sort.Slice(tm.Partitions, func(i, j int) bool {
+			return tm.Partitions[i].ID < tm.Partitions[j].ID
+		})
+		metadata.Topics = append(metadata.Topics, tm)
+	}
+	client.lock.RUnlock()
+
+	sort.Slice(metadata.Brokers, func(i, j int) bool {
+		return metadata.Brokers[i].id < metadata.Brokers[j].id
+	})
+	sort.Slice(metadata.Topics, func(i, j int) bool {
+		return metadata.Topics[i].Name < metadata.Topics[j].Name
+	})
+
+	return encode(&metadataSnapshot{Time: time.Now(), Metadata: metadata}, nil)
+}
+
+// NewClientFromMetadataSnapshot creates a new Client as NewClient does, but
+// seeds its metadata with snapshot, as returned by Client.MetadataSnapshot,
+// instead of fetching the metadata of the cluster, so that many clients
+// starting at once do not all request it. The snapshot is validated lazily:
+// stale leaders are refreshed on the first error, as with any outdated
+// metadata, and the topics of the snapshot by the background metadata refresh.
+func NewClientFromMetadataSnapshot(addrs []string, conf *Config, snapshot []byte) (Client, error) {
+	s := new(metadataSnapshot)
+	if err := decode(snapshot, s); err != nil {
+		return nil, err
+	}
+	if s.Metadata == nil {
+		return nil, ConfigurationError("the metadata snapshot must not be empty")
+	}
+	return newClient(addrs, conf, s)
+}
@@ -41,3 +41,147 @@ func getOrRegisterTopicMeter(name string, topic string, r metrics.Registry) metr
 func getOrRegisterTopicHistogram(name string, topic string, r metrics.Registry) metrics.Histogram {
 	return getOrRegisterHistogram(getMetricNameForTopic(name, topic), r)
 }
+
+// MetricLabels qualify a metric recorded by a MetricsRecorder, e.g. with the
+// broker or the topic it is about, by the names of the labels.
+type MetricLabels map[string]string
+
+// The labels of the metrics Sarama records.
+const (
+	// MetricLabelBroker is the ID of the broker the metric is about.
+	MetricLabelBroker = "broker"
+	// MetricLabelTopic is the topic the metric is about.
+	MetricLabelTopic = "topic"
+	// MetricLabelPartition is the partition of the topic the metric is about.
+	MetricLabelPartition = "partition"
+	// MetricLabelGroup is the consumer group the metric is about.
+	MetricLabelGroup = "group"
+	// MetricLabelAPI is the name of the API of the requests the metric is
+	// about, e.g. "Produce" or "OffsetCommit".
+	MetricLabelAPI = "api"
+	// MetricLabelCodec is the compression codec the metric is about, e.g.
+	// "none" or "zstd".
+	MetricLabelCodec = "codec"
+)
+
+// MetricsRecorder records the metrics of Sarama, by name and labels, in a
+// metrics library. The metrics are those documented in the package
+// documentation, the broker, topic or group they are about being given as
+// labels rather than being part of their names. The counters named "*-rate"
+// count the events whose rate go-metrics meters.
+//
+// The brokers look their metrics up once, but the producers and consumers
+// look some of theirs up for each request, so looking them up should be cheap.
+// Recording must be safe for concurrent use. A MetricsRecorder also implementing
+// Unregister(name string, labels MetricLabels) is told about the metrics of
+// the brokers once they are closed.
+type MetricsRecorder interface {
+	// Counter returns the counter of the events name, e.g. the requests sent.
+	Counter(name string, labels MetricLabels) MetricsCounter
+	// Gauge returns the gauge name, e.g. the requests in flight.
+	Gauge(name string, labels MetricLabels) MetricsGauge
+	// Histogram returns the histogram of the values name, e.g. the sizes of
+	// the requests.
+	Histogram(name string, labels MetricLabels) MetricsHistogram
+}
+
+// MetricsCounter counts events.
+type MetricsCounter interface {
+	// Add counts delta more events.
+	Add(delta int64)
+}
+
+// MetricsGauge measures a value going up and down.
+type MetricsGauge interface {
+	// Add adds delta, which may be negative, to the value.
+	Add(delta int64)
+	// Set sets the value.
+	Set(value int64)
+}
+
+// MetricsHistogram records the distribution of values.
+type MetricsHistogram interface {
+	// Observe records value.
+	Observe(value int64)
+}
+
+// metricsUnregisterer is implemented by the MetricsRecorders dropping the
+// metrics of the closed brokers.
+type metricsUnregisterer interface {
+	Unregister(name string, labels MetricLabels)
+}
+
+// NewGoMetricsRecorder returns a MetricsRecorder recording the metrics in
+// registry with go-metrics, as Sarama does by default in Config.MetricRegistry.
+// The counters named "*-rate" are recorded as meters, the other counters and
+// the gauges as counters, and the histograms sample the values as the Java
+// client does. The labels are appended to the names, as "-for-broker-<id>",
+// "-for-topic-<topic>", "-for-partition-<partition>", "-<group>", "-<api>" and
+// "-<codec>".
+func NewGoMetricsRecorder(registry metrics.Registry) MetricsRecorder {
+	return goMetricsRecorder{registry: registry}
+}
+
+type goMetricsRecorder struct {
+	registry metrics.Registry
+}
+
+func (r goMetricsRecorder) Counter(name string, labels MetricLabels) MetricsCounter {
+	if strings.HasSuffix(name, "-rate") {
+		return goMetricsMeter{metrics.GetOrRegisterMeter(goMetricsName(name, labels), r.registry)}
+	}
+	return goMetricsCounter{metrics.GetOrRegisterCounter(goMetricsName(name, labels), r.registry)}
+}
+
+func (r goMetricsRecorder) Gauge(name string, labels MetricLabels) MetricsGauge {
+	return goMetricsCounter{metrics.GetOrRegisterCounter(goMetricsName(name, labels), r.registry)}
+}
+
+func (r goMetricsRecorder) Histogram(name string, labels MetricLabels) MetricsHistogram {
+	return goMetricsHistogram{getOrRegisterHistogram(goMetricsName(name, labels), r.registry)}
+}
+
+func (r goMetricsRecorder) Unregister(name string, labels MetricLabels) {
+	r.registry.Unregister(goMetricsName(name, labels))
+}
+
+// goMetricsName returns the name of the metric name in a go-metrics registry,
+// as Sarama has always named them.
+func goMetricsName(name string, labels MetricLabels) string {
+	if group, ok := labels[MetricLabelGroup]; ok {
+		name += "-" + group
+	}
+	if api, ok := labels[MetricLabelAPI]; ok {
+		name += "-" + api
+	}
+	if codec, ok := labels[MetricLabelCodec]; ok {
+		name += "-" + codec
+	}
+	if broker, ok := labels[MetricLabelBroker]; ok {
+		name += "-for-broker-" + broker
+	}
+	if topic, ok := labels[MetricLabelTopic]; ok {
+		name = getMetricNameForTopic(name, topic)
+	}
+	if partition, ok := labels[MetricLabelPartition]; ok {
+		name += "-for-partition-" + partition
+	}
+	return name
+}
+
+type goMetricsMeter struct{ metrics.Meter }
+
+func (m goMetricsMeter) Add(delta int64) { m.Mark(delta) }
+
+type goMetricsCounter struct{ metrics.Counter }
+
+func (c goMetricsCounter) Add(delta int64) { c.Inc(delta) }
+
+func (c goMetricsCounter) Set(value int64) {
+	c.Clear()
+	c.Inc(value)
+}
+
+type goMetricsHistogram struct{ metrics.Histogram }
+
+func (h goMetricsHistogram) Observe(value int64) { h.Update(value) }
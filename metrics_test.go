@@ -1,6 +1,9 @@
 package sarama
 
 import (
+	"fmt"
+	"sort"
+	"sync"
 	"testing"
 
 	"github.com/rcrowley/go-metrics"
@@ -37,6 +40,195 @@ func TestGetMetricNameForBroker(t *testing.T) {
 	}
 }
 
+func TestGoMetricsRecorder(t *testing.T) {
+	registry := metrics.NewRegistry()
+	recorder := NewGoMetricsRecorder(registry)
+
+	recorder.Counter("record-send-rate", MetricLabels{MetricLabelTopic: "my.topic"}).Add(3)
+	recorder.Counter("consumer-group-join-total", MetricLabels{MetricLabelGroup: "my_group"}).Add(1)
+	recorder.Gauge("requests-in-flight", MetricLabels{MetricLabelBroker: "1"}).Add(2)
+	recorder.Histogram("request-size", nil).Observe(10)
+	recorder.Histogram("api-latency-in-ms", MetricLabels{MetricLabelBroker: "1", MetricLabelAPI: "Fetch"}).Observe(5)
+	recorder.Histogram("compression-ratio", MetricLabels{MetricLabelTopic: "my_topic", MetricLabelCodec: "zstd"}).Observe(250)
+
+	if meter, ok := registry.Get("record-send-rate-for-topic-my_topic").(metrics.Meter); !ok || meter.Count() != 3 {
+		t.Errorf("expected a meter for the topic, got %v", registry.Get("record-send-rate-for-topic-my_topic"))
+	}
+	if counter, ok := registry.Get("consumer-group-join-total-my_group").(metrics.Counter); !ok || counter.Count() != 1 {
+		t.Errorf("expected a counter for the group, got %v", registry.Get("consumer-group-join-total-my_group"))
+	}
+	if counter, ok := registry.Get("requests-in-flight-for-broker-1").(metrics.Counter); !ok || counter.Count() != 2 {
+		t.Errorf("expected a counter for the broker, got %v", registry.Get("requests-in-flight-for-broker-1"))
+	}
+	if histogram, ok := registry.Get("request-size").(metrics.Histogram); !ok || histogram.Max() != 10 {
+		t.Errorf("expected a histogram, got %v", registry.Get("request-size"))
+	}
+
+	if histogram, ok := registry.Get("api-latency-in-ms-Fetch-for-broker-1").(metrics.Histogram); !ok || histogram.Max() != 5 {
+		t.Errorf("expected a histogram for the API and the broker, got %v", registry.Get("api-latency-in-ms-Fetch-for-broker-1"))
+	}
+	if histogram, ok := registry.Get("compression-ratio-zstd-for-topic-my_topic").(metrics.Histogram); !ok || histogram.Max() != 250 {
+		t.Errorf("expected a histogram for the codec and the topic, got %v", registry.Get("compression-ratio-zstd-for-topic-my_topic"))
+	}
+
+	recorder.(metricsUnregisterer).Unregister("requests-in-flight", MetricLabels{MetricLabelBroker: "1"})
+	if registry.Get("requests-in-flight-for-broker-1") != nil {
+		t.Error("expected the metric to be unregistered")
+	}
+}
+
+// testMetricsRecorder sums the values recorded by metric name and labels.
+type testMetricsRecorder struct {
+	lock         sync.Mutex
+	values       map[string]int64
+	unregistered []string
+}
+
+func newTestMetricsRecorder() *testMetricsRecorder {
+	return &testMetricsRecorder{values: make(map[string]int64)}
+}
+
+func (r *testMetricsRecorder) metric(name string, labels MetricLabels) testMetric {
+	keys := make([]string, 0, len(labels))
+	for key, value := range labels {
+		keys = append(keys, fmt.Sprintf("%s=%s", key, value))
+	}
+	sort.Strings(keys)
+	return testMetric{recorder: r, key: fmt.Sprint(name, keys)}
+}
+
+func (r *testMetricsRecorder) value(name string, labels MetricLabels) int64 {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.values[r.metric(name, labels).key]
+}
+
+func (r *testMetricsRecorder) Counter(name string, labels MetricLabels) MetricsCounter {
+	return r.metric(name, labels)
+}
+
+func (r *testMetricsRecorder) Gauge(name string, labels MetricLabels) MetricsGauge {
+	return r.metric(name, labels)
+}
+
+func (r *testMetricsRecorder) Histogram(name string, labels MetricLabels) MetricsHistogram {
+	return r.metric(name, labels)
+}
+
+func (r *testMetricsRecorder) Unregister(name string, labels MetricLabels) {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	r.unregistered = append(r.unregistered, r.metric(name, labels).key)
+}
+
+type testMetric struct {
+	recorder *testMetricsRecorder
+	key      string
+}
+
+func (m testMetric) Add(delta int64) {
+	m.recorder.lock.Lock()
+	defer m.recorder.lock.Unlock()
+	m.recorder.values[m.key] += delta
+}
+
+func (m testMetric) Set(value int64) {
+	m.recorder.lock.Lock()
+	defer m.recorder.lock.Unlock()
+	m.recorder.values[m.key] = value
+}
+
+func (m testMetric) Observe(value int64) {
+	m.Add(value)
+}
+
+func TestMetricsRecorder(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).SetBroker(mb.Addr(), mb.BrokerID()),
+	})
+
+	recorder := newTestMetricsRecorder()
+	conf := NewTestConfig()
+	conf.MetricsRecorder = recorder
+	broker := NewBroker(mb.Addr())
+	broker.id = 1
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.GetMetadata(&MetadataRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	safeClose(t, broker)
+
+	labels := MetricLabels{MetricLabelBroker: "1"}
+	if requests := recorder.value("request-rate", nil); requests != 1 {
+		t.Errorf("expected a request to be recorded, got %d", requests)
+	}
+	if requests := recorder.value("request-rate", labels); requests != 1 {
+		t.Errorf("expected a request to be recorded for the broker, got %d", requests)
+	}
+	if inFlight := recorder.value("requests-in-flight", labels); inFlight != 0 {
+		t.Errorf("expected no request in flight, got %d", inFlight)
+	}
+	if size := recorder.value("response-size", labels); size <= 0 {
+		t.Errorf("expected the size of the response to be recorded, got %d", size)
+	}
+	if len(recorder.unregistered) == 0 {
+		t.Error("expected the metrics of the broker to be unregistered once closed")
+	}
+	if len(conf.MetricRegistry.GetAll()) != 0 {
+		t.Errorf("expected no metrics in the registry, got %v", conf.MetricRegistry.GetAll())
+	}
+}
+
+func TestAPIMetrics(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest":  NewMockMetadataResponse(t).SetBroker(mb.Addr(), mb.BrokerID()),
+		"HeartbeatRequest": NewMockHeartbeatResponse(t).SetError(ErrRebalanceInProgress),
+	})
+
+	recorder := newTestMetricsRecorder()
+	conf := NewTestConfig()
+	conf.MetricsRecorder = recorder
+	conf.Version = V0_10_0_0
+	broker := NewBroker(mb.Addr())
+	broker.id = 1
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.GetMetadata(&MetadataRequest{}); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 2; i++ {
+		if _, err := broker.Heartbeat(&HeartbeatRequest{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	safeClose(t, broker)
+
+	metadata := MetricLabels{MetricLabelBroker: "1", MetricLabelAPI: "Metadata"}
+	heartbeat := MetricLabels{MetricLabelBroker: "1", MetricLabelAPI: "Heartbeat"}
+	if errs := recorder.value("api-error-rate", metadata); errs != 0 {
+		t.Errorf("expected no Metadata error, got %d", errs)
+	}
+	if errs := recorder.value("api-error-rate", heartbeat); errs != 2 {
+		t.Errorf("expected 2 Heartbeat errors, got %d", errs)
+	}
+	unregistered := false
+	for _, key := range recorder.unregistered {
+		if key == recorder.metric("api-latency-in-ms", heartbeat).key {
+			unregistered = true
+		}
+	}
+	if !unregistered {
+		t.Errorf("expected the Heartbeat latency to be unregistered once closed, got %v", recorder.unregistered)
+	}
+}
+
 // Common type and functions for metric validation
 type metricValidator struct {
 	name      string
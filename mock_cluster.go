@@ -0,0 +1,320 @@
+package sarama
+
+import (
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// MockCluster is a cluster of MockBrokers sharing consistent metadata, for
+// the failover of producers and consumers to be tested deterministically. The
+// brokers answer the MetadataRequests themselves, from the leaders set with
+// SetLeader, and the ProduceRequests, FetchRequests and OffsetRequests for
+// the partitions they do not lead with ErrNotLeaderForPartition. The other
+// requests, and those for the partitions they lead, are answered by the
+// MockResponses set with SetHandlerByMap.
+//
+// The events of the cluster are scripted by the test:
+//
+//	cluster.SetLeader("my_topic", 3, 2) // partition 3 leader moves to broker 2
+//	cluster.StopBroker(1)               // broker 1 goes down
+//
+// The partitions led by a stopped broker have no leader, and are reported with
+// ErrLeaderNotAvailable, until moved to another broker or the broker started
+// again.
+type MockCluster struct {
+	t TestReporter
+
+	lock     sync.Mutex
+	brokers  map[int32]*MockBroker
+	addrs    map[int32]string
+	leaders  map[string]map[int32]int32
+	handlers map[string]MockResponse
+}
+
+// NewMockCluster launches a MockCluster of brokers MockBrokers, of IDs 1 to
+// brokers.
+func NewMockCluster(t TestReporter, brokers int) *MockCluster {
+	c := &MockCluster{
+		t:        t,
+		brokers:  make(map[int32]*MockBroker),
+		addrs:    make(map[int32]string),
+		leaders:  make(map[string]map[int32]int32),
+		handlers: make(map[string]MockResponse),
+	}
+	for id := int32(1); id <= int32(brokers); id++ {
+		broker := NewMockBroker(t, id)
+		c.brokers[id] = broker
+		c.addrs[id] = broker.Addr()
+		c.serve(broker)
+	}
+	return c
+}
+
+// Addrs returns the addresses of the brokers of the cluster, by ID, to
+// bootstrap the clients from.
+func (c *MockCluster) Addrs() []string {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	addrs := make([]string, 0, len(c.addrs))
+	for _, id := range c.ids() {
+		addrs = append(addrs, c.addrs[id])
+	}
+	return addrs
+}
+
+// Broker returns the broker of the cluster with the ID, nil if it is stopped.
+func (c *MockCluster) Broker(id int32) *MockBroker {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	return c.brokers[id]
+}
+
+// SetHandlerByMap sets the MockResponses answering the requests of the
+// brokers, by request type, as MockBroker.SetHandlerByMap does. Those of the
+// MetadataRequests are ignored.
+func (c *MockCluster) SetHandlerByMap(handlers map[string]MockResponse) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.handlers = make(map[string]MockResponse, len(handlers))
+	for reqType, handler := range handlers {
+		c.handlers[reqType] = handler
+	}
+}
+
+// SetLeader sets the broker leading the partition, adding the partition to the
+// cluster if it is not in it yet.
+func (c *MockCluster) SetLeader(topic string, partition, brokerID int32) *MockCluster {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	if _, ok := c.addrs[brokerID]; !ok {
+		c.t.Errorf("mockcluster: broker %d is not in the cluster", brokerID)
+		return c
+	}
+	if c.leaders[topic] == nil {
+		c.leaders[topic] = make(map[int32]int32)
+	}
+	c.leaders[topic][partition] = brokerID
+	return c
+}
+
+// Leader returns the ID of the broker leading the partition, -1 if the
+// partition is not in the cluster.
+func (c *MockCluster) Leader(topic string, partition int32) int32 {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	leader, ok := c.leaders[topic][partition]
+	if !ok {
+		return -1
+	}
+	return leader
+}
+
+// StopBroker closes the broker with the ID, as if it went down. Its address is
+// kept for StartBroker.
+func (c *MockCluster) StopBroker(id int32) {
+	c.lock.Lock()
+	broker := c.brokers[id]
+	delete(c.brokers, id)
+	c.lock.Unlock()
+	if broker != nil {
+		broker.Close()
+	}
+}
+
+// StartBroker starts the broker with the ID again, listening on the address
+// it had, after StopBroker.
+func (c *MockCluster) StartBroker(id int32) {
+	c.lock.Lock()
+	addr, ok := c.addrs[id]
+	running := c.brokers[id] != nil
+	c.lock.Unlock()
+	if !ok || running {
+		return
+	}
+
+	broker := NewMockBrokerAddr(c.t, id, addr)
+	c.serve(broker)
+	c.lock.Lock()
+	c.brokers[id] = broker
+	c.lock.Unlock()
+}
+
+// Close closes the brokers of the cluster.
+func (c *MockCluster) Close() {
+	c.lock.Lock()
+	brokers := c.brokers
+	c.brokers = make(map[int32]*MockBroker)
+	c.lock.Unlock()
+	for _, broker := range brokers {
+		broker.Close()
+	}
+}
+
+// ids returns the IDs of the brokers of the cluster, sorted.
+func (c *MockCluster) ids() []int32 {
+	ids := make([]int32, 0, len(c.addrs))
+	for id := range c.addrs {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// serve makes broker answer the requests as a broker of the cluster.
+func (c *MockCluster) serve(broker *MockBroker) {
+	id := broker.BrokerID()
+	broker.setHandler(func(req *request) encoderWithHeader {
+		return c.handle(id, req.body)
+	})
+}
+
+// handle returns the response of the broker with the ID to reqBody.
+func (c *MockCluster) handle(id int32, reqBody versionedDecoder) encoderWithHeader {
+	if req, ok := reqBody.(*MetadataRequest); ok {
+		return c.metadata(req)
+	}
+
+	c.lock.Lock()
+	handler := c.handlers[reflect.TypeOf(reqBody).Elem().Name()]
+	led, notLed := c.splitByLeader(id, requestPartitions(reqBody))
+	c.lock.Unlock()
+
+	var res encoderWithHeader
+	if handler != nil && (len(notLed) == 0 || len(led) > 0) {
+		res = handler.For(reqBody)
+	}
+	if len(notLed) == 0 {
+		return res
+	}
+
+	switch req := reqBody.(type) {
+	case *ProduceRequest:
+		produceRes, ok := res.(*ProduceResponse)
+		if !ok {
+			produceRes = &ProduceResponse{Version: req.Version}
+		}
+		for topic, partitions := range notLed {
+			for _, partition := range partitions {
+				produceRes.AddTopicPartition(topic, partition, ErrNotLeaderForPartition)
+			}
+		}
+		return produceRes
+	case *FetchRequest:
+		fetchRes, ok := res.(*FetchResponse)
+		if !ok {
+			fetchRes = &FetchResponse{Version: req.Version}
+		}
+		for topic, partitions := range notLed {
+			for _, partition := range partitions {
+				fetchRes.AddError(topic, partition, ErrNotLeaderForPartition)
+			}
+		}
+		return fetchRes
+	case *OffsetRequest:
+		offsetRes, ok := res.(*OffsetResponse)
+		if !ok {
+			offsetRes = &OffsetResponse{Version: req.Version}
+		}
+		for topic, partitions := range notLed {
+			for _, partition := range partitions {
+				offsetRes.AddTopicPartition(topic, partition, -1)
+				offsetRes.Blocks[topic][partition].Err = ErrNotLeaderForPartition
+			}
+		}
+		return offsetRes
+	}
+	return res
+}
+
+// metadata answers req from the leaders of the cluster.
+func (c *MockCluster) metadata(req *MetadataRequest) encoderWithHeader {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	res := &MetadataResponse{Version: req.version(), ControllerID: -1}
+	var replicas []int32
+	for _, id := range c.ids() {
+		if c.brokers[id] == nil {
+			continue
+		}
+		res.AddBroker(c.addrs[id], id)
+		replicas = append(replicas, id)
+		if res.ControllerID < 0 {
+			res.ControllerID = id
+		}
+	}
+
+	topics := req.Topics
+	if len(topics) == 0 {
+		for topic := range c.leaders {
+			topics = append(topics, topic)
+		}
+		sort.Strings(topics)
+	}
+	for _, topic := range topics {
+		partitions, ok := c.leaders[topic]
+		if !ok {
+			res.AddTopic(topic, ErrUnknownTopicOrPartition)
+			continue
+		}
+		for partition, leader := range partitions {
+			err := ErrNoError
+			if c.brokers[leader] == nil {
+				leader, err = -1, ErrLeaderNotAvailable
+			}
+			res.AddTopicPartition(topic, partition, leader, replicas, replicas, nil, err)
+		}
+	}
+	return res
+}
+
+// splitByLeader splits partitions into those led by the broker with the ID
+// and the others.
+func (c *MockCluster) splitByLeader(id int32, partitions map[string][]int32) (led, notLed map[string][]int32) {
+	for topic, ps := range partitions {
+		for _, partition := range ps {
+			if leader, ok := c.leaders[topic][partition]; ok && leader == id {
+				if led == nil {
+					led = make(map[string][]int32)
+				}
+				led[topic] = append(led[topic], partition)
+			} else {
+				if notLed == nil {
+					notLed = make(map[string][]int32)
+				}
+				notLed[topic] = append(notLed[topic], partition)
+			}
+		}
+	}
+	return led, notLed
+}
+
+// requestPartitions returns the partitions of reqBody, by topic, if it must be
+// sent to their leaders.
+func requestPartitions(reqBody versionedDecoder) map[string][]int32 {
+	partitions := make(map[string][]int32)
+	switch req := reqBody.(type) {
+	case *ProduceRequest:
+		for topic, blocks := range req.records {
+			for partition := range blocks {
+				partitions[topic] = append(partitions[topic], partition)
+			}
+		}
+	case *FetchRequest:
+		for topic, blocks := range req.blocks {
+			for partition := range blocks {
+				partitions[topic] = append(partitions[topic], partition)
+			}
+		}
+	case *OffsetRequest:
+		for topic, blocks := range req.blocks {
+			for partition := range blocks {
+				partitions[topic] = append(partitions[topic], partition)
+			}
+		}
+	default:
+		return nil
+	}
+	return partitions
+}
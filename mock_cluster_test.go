@@ -0,0 +1,89 @@
+package sarama
+
+import (
+	"testing"
+	"time"
+)
+
+// produceResponses returns the responses of the broker to the
+// ProduceRequests.
+func produceResponses(broker *MockBroker) []*ProduceResponse {
+	var responses []*ProduceResponse
+	for _, rr := range broker.History() {
+		if res, ok := rr.Response.(*ProduceResponse); ok {
+			responses = append(responses, res)
+		}
+	}
+	return responses
+}
+
+func TestMockClusterLeadershipMovement(t *testing.T) {
+	cluster := NewMockCluster(t, 2)
+	defer cluster.Close()
+	cluster.SetLeader("my_topic", 0, 1).SetLeader("my_topic", 1, 2)
+	cluster.SetHandlerByMap(map[string]MockResponse{
+		"ProduceRequest": NewMockProduceResponse(t),
+	})
+
+	config := NewTestConfig()
+	config.Producer.Partitioner = NewManualPartitioner
+	config.Producer.Return.Successes = true
+	config.Producer.Retry.Backoff = 10 * time.Millisecond
+	config.Metadata.Retry.Backoff = 10 * time.Millisecond
+	client, err := NewClient(cluster.Addrs(), config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+	producer, err := NewSyncProducerFromClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, producer)
+
+	send := func() {
+		t.Helper()
+		if _, _, err := producer.SendMessage(&ProducerMessage{Topic: "my_topic", Partition: 0, Value: StringEncoder("ABC")}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	send()
+	if produced := len(produceResponses(cluster.Broker(1))); produced != 1 {
+		t.Errorf("expected broker 1 to answer 1 ProduceRequest, got %d", produced)
+	}
+
+	// the producer retries on the new leader once broker 1 answers it is not
+	// the leader anymore
+	cluster.SetLeader("my_topic", 0, 2)
+	send()
+	if responses := produceResponses(cluster.Broker(1)); len(responses) != 2 {
+		t.Errorf("expected broker 1 to answer 2 ProduceRequests, got %d", len(responses))
+	} else if block := responses[1].GetBlock("my_topic", 0); block == nil || block.Err != ErrNotLeaderForPartition {
+		t.Errorf("expected broker 1 to answer it is not the leader, got %+v", block)
+	}
+	if produced := len(produceResponses(cluster.Broker(2))); produced != 1 {
+		t.Errorf("expected broker 2 to answer 1 ProduceRequest, got %d", produced)
+	}
+
+	cluster.StopBroker(2)
+	if err := client.RefreshMetadata("my_topic"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := client.Leader("my_topic", 1); err != ErrLeaderNotAvailable {
+		t.Errorf("expected partition 1 to have no leader while broker 2 is down, got %v", err)
+	}
+	cluster.SetLeader("my_topic", 0, 1)
+	send()
+	if produced := len(produceResponses(cluster.Broker(1))); produced != 3 {
+		t.Errorf("expected broker 1 to answer 3 ProduceRequests, got %d", produced)
+	}
+
+	cluster.StartBroker(2)
+	if err := client.RefreshMetadata("my_topic"); err != nil {
+		t.Fatal(err)
+	}
+	if leader, err := client.Leader("my_topic", 1); err != nil || leader.ID() != 2 {
+		t.Errorf("expected broker 2 to lead partition 1 again, got %v", err)
+	}
+}
@@ -0,0 +1,367 @@
+package sarama
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"sync"
+)
+
+// MockGroupCoordinator is the coordinator of the consumer groups of a
+// MockBroker. It answers the FindCoordinator, JoinGroup, SyncGroup, Heartbeat,
+// LeaveGroup, OffsetFetch and OffsetCommit requests coherently, as a broker
+// would, so that ConsumerGroups and their rebalances can be tested against a
+// MockBroker:
+//
+//   - a member joining or leaving the group, or joining it again with other
+//     metadata, starts a new generation, and the heartbeats of the members
+//     which have not joined it yet are answered with ErrRebalanceInProgress,
+//     for them to join it;
+//   - the first member of the group leads it, and the SyncGroup requests of
+//     the other members are answered with ErrRebalanceInProgress until the
+//     leader sent the assignments of the generation;
+//   - the offsets committed are kept by group, and returned by the
+//     OffsetFetch requests.
+//
+// Unlike a broker, it never waits for the members to join or sync, which
+// would hold up the MockBroker, so the members retry until the generation is
+// complete: the tests should keep Consumer.Group.Rebalance.Retry.Backoff short.
+type MockGroupCoordinator struct {
+	t      TestReporter
+	broker *MockBroker
+
+	lock         sync.Mutex
+	groups       map[string]*mockGroup
+	offsets      map[string]map[string]map[int32]*OffsetFetchResponseBlock
+	nextMemberID int
+}
+
+// mockGroup is the state of a consumer group of a MockGroupCoordinator.
+type mockGroup struct {
+	generation int32
+	protocol   string
+	leader     string
+	// members are the IDs of the members of the group, in the order they
+	// joined it
+	members []string
+	// metadata is the metadata of each member, by protocol
+	metadata map[string]map[string][]byte
+	// joined are the members which joined the current generation
+	joined map[string]bool
+	// assignments are those the leader sent for the current generation, nil
+	// until it did
+	assignments map[string][]byte
+	// pending are the member IDs assigned to the members joining with
+	// JoinGroup v4 or later, which must join again with them
+	pending map[string]bool
+}
+
+// NewMockGroupCoordinator returns a MockGroupCoordinator coordinating the
+// groups on broker. Its Handlers must be set on the broker.
+func NewMockGroupCoordinator(t TestReporter, broker *MockBroker) *MockGroupCoordinator {
+	return &MockGroupCoordinator{
+		t:       t,
+		broker:  broker,
+		groups:  make(map[string]*mockGroup),
+		offsets: make(map[string]map[string]map[int32]*OffsetFetchResponseBlock),
+	}
+}
+
+// Handlers returns the MockResponses of the requests of the consumer groups,
+// by request type, to be set on the MockBroker with SetHandlerByMap along with
+// those of the other requests.
+func (c *MockGroupCoordinator) Handlers() map[string]MockResponse {
+	return map[string]MockResponse{
+		"FindCoordinatorRequest": mockGroupHandler(c.findCoordinator),
+		"JoinGroupRequest":       mockGroupHandler(c.joinGroup),
+		"SyncGroupRequest":       mockGroupHandler(c.syncGroup),
+		"HeartbeatRequest":       mockGroupHandler(c.heartbeat),
+		"LeaveGroupRequest":      mockGroupHandler(c.leaveGroup),
+		"OffsetFetchRequest":     mockGroupHandler(c.offsetFetch),
+		"OffsetCommitRequest":    mockGroupHandler(c.offsetCommit),
+	}
+}
+
+// mockGroupHandler is a MockResponse answering the requests with a function.
+type mockGroupHandler func(reqBody versionedDecoder) encoderWithHeader
+
+func (h mockGroupHandler) For(reqBody versionedDecoder) encoderWithHeader {
+	return h(reqBody)
+}
+
+// SetOffset sets the offset committed by group on the partition.
+func (c *MockGroupCoordinator) SetOffset(group, topic string, partition int32, offset int64, metadata string) *MockGroupCoordinator {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	c.commit(group, topic, partition, offset, metadata)
+	return c
+}
+
+// Offset returns the offset committed by group on the partition, and whether
+// it committed one.
+func (c *MockGroupCoordinator) Offset(group, topic string, partition int32) (int64, bool) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	block, ok := c.offsets[group][topic][partition]
+	if !ok {
+		return 0, false
+	}
+	return block.Offset, true
+}
+
+// Generation returns the current generation of group and the IDs of its
+// members, sorted.
+func (c *MockGroupCoordinator) Generation(group string) (int32, []string) {
+	c.lock.Lock()
+	defer c.lock.Unlock()
+	g, ok := c.groups[group]
+	if !ok {
+		return 0, nil
+	}
+	members := append([]string(nil), g.members...)
+	sort.Strings(members)
+	return g.generation, members
+}
+
+func (c *MockGroupCoordinator) commit(group, topic string, partition int32, offset int64, metadata string) {
+	if c.offsets[group] == nil {
+		c.offsets[group] = make(map[string]map[int32]*OffsetFetchResponseBlock)
+	}
+	if c.offsets[group][topic] == nil {
+		c.offsets[group][topic] = make(map[int32]*OffsetFetchResponseBlock)
+	}
+	c.offsets[group][topic][partition] = &OffsetFetchResponseBlock{Offset: offset, LeaderEpoch: -1, Metadata: metadata, Err: ErrNoError}
+}
+
+func (c *MockGroupCoordinator) findCoordinator(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*FindCoordinatorRequest)
+	return &FindCoordinatorResponse{
+		Version:     req.Version,
+		Err:         ErrNoError,
+		Coordinator: &Broker{id: c.broker.BrokerID(), addr: c.broker.Addr()},
+	}
+}
+
+func (c *MockGroupCoordinator) joinGroup(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*JoinGroupRequest)
+	res := &JoinGroupResponse{Version: req.Version}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	g := c.groups[req.GroupId]
+	if g == nil {
+		g = &mockGroup{metadata: make(map[string]map[string][]byte), joined: make(map[string]bool), pending: make(map[string]bool)}
+		c.groups[req.GroupId] = g
+	}
+
+	memberID := req.MemberId
+	switch {
+	case memberID == "" && req.Version >= 4:
+		c.nextMemberID++
+		res.Err = ErrMemberIdRequired
+		res.MemberId = fmt.Sprintf("mock-member-%d", c.nextMemberID)
+		g.pending[res.MemberId] = true
+		return res
+	case memberID == "":
+		c.nextMemberID++
+		memberID = fmt.Sprintf("mock-member-%d", c.nextMemberID)
+		g.members = append(g.members, memberID)
+		g.newGeneration()
+	case g.pending[memberID]:
+		delete(g.pending, memberID)
+		g.members = append(g.members, memberID)
+		g.newGeneration()
+	case g.metadata[memberID] == nil:
+		res.Err = ErrUnknownMemberId
+		return res
+	}
+
+	metadata := make(map[string][]byte)
+	for _, protocol := range req.OrderedGroupProtocols {
+		metadata[protocol.Name] = protocol.Metadata
+	}
+	if g.joined[memberID] && g.assignments != nil && !reflect.DeepEqual(metadata, g.metadata[memberID]) {
+		// a member of the completed generation joining again with other
+		// metadata, e.g. other topics, rebalances the group
+		g.newGeneration()
+	}
+	g.joined[memberID] = true
+	g.metadata[memberID] = metadata
+	if g.protocol == "" || g.metadata[memberID][g.protocol] == nil {
+		if len(req.OrderedGroupProtocols) == 0 {
+			res.Err = ErrInconsistentGroupProtocol
+			return res
+		}
+		g.protocol = req.OrderedGroupProtocols[0].Name
+	}
+	if g.leader == "" {
+		g.leader = memberID
+	}
+
+	res.Err = ErrNoError
+	res.GenerationId = g.generation
+	res.GroupProtocol = g.protocol
+	res.LeaderId = g.leader
+	res.MemberId = memberID
+	if memberID == g.leader {
+		res.Members = make(map[string][]byte, len(g.members))
+		for _, member := range g.members {
+			res.Members[member] = g.metadata[member][g.protocol]
+		}
+	}
+	return res
+}
+
+// newGeneration starts a new generation of the group, which its members must
+// join.
+func (g *mockGroup) newGeneration() {
+	g.generation++
+	g.joined = make(map[string]bool)
+	g.assignments = nil
+	if len(g.members) > 0 {
+		g.leader = g.members[0]
+	} else {
+		g.leader = ""
+		g.protocol = ""
+	}
+}
+
+// member returns the error answering a request of member at generation, if
+// it is not a member of the current generation of group.
+func (c *MockGroupCoordinator) member(group, member string, generation int32) (*mockGroup, KError) {
+	g := c.groups[group]
+	switch {
+	case g == nil || g.metadata[member] == nil:
+		return nil, ErrUnknownMemberId
+	case generation != g.generation:
+		return nil, ErrIllegalGeneration
+	}
+	return g, ErrNoError
+}
+
+func (c *MockGroupCoordinator) syncGroup(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*SyncGroupRequest)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	g, err := c.member(req.GroupId, req.MemberId, req.GenerationId)
+	if err != ErrNoError {
+		return &SyncGroupResponse{Version: req.Version, Err: err}
+	}
+	if req.MemberId == g.leader {
+		g.assignments = req.GroupAssignments
+		if g.assignments == nil {
+			g.assignments = make(map[string][]byte)
+		}
+	}
+	if g.assignments == nil {
+		return &SyncGroupResponse{Version: req.Version, Err: ErrRebalanceInProgress}
+	}
+	return &SyncGroupResponse{Version: req.Version, Err: ErrNoError, MemberAssignment: g.assignments[req.MemberId]}
+}
+
+func (c *MockGroupCoordinator) heartbeat(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*HeartbeatRequest)
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	g, err := c.member(req.GroupId, req.MemberId, req.GenerationId)
+	switch {
+	case err == ErrIllegalGeneration, err == ErrNoError && !g.joined[req.MemberId]:
+		return &HeartbeatResponse{Version: req.Version, Err: ErrRebalanceInProgress}
+	default:
+		return &HeartbeatResponse{Version: req.Version, Err: err}
+	}
+}
+
+func (c *MockGroupCoordinator) leaveGroup(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*LeaveGroupRequest)
+	res := &LeaveGroupResponse{Version: req.Version, Err: ErrNoError}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	if req.Version < 3 {
+		res.Err = c.leave(req.GroupId, req.MemberId)
+		return res
+	}
+	// since version 3, members leave in batches
+	for _, member := range req.Members {
+		err := c.leave(req.GroupId, member.MemberId)
+		res.Members = append(res.Members, MemberResponse{MemberId: member.MemberId, GroupInstanceId: member.GroupInstanceId, Err: err})
+	}
+	return res
+}
+
+// leave removes member from group, starting a new generation.
+func (c *MockGroupCoordinator) leave(group, member string) KError {
+	g := c.groups[group]
+	if g == nil || g.metadata[member] == nil {
+		return ErrUnknownMemberId
+	}
+	delete(g.metadata, member)
+	for i, m := range g.members {
+		if m == member {
+			g.members = append(g.members[:i:i], g.members[i+1:]...)
+			break
+		}
+	}
+	g.newGeneration()
+	return ErrNoError
+}
+
+func (c *MockGroupCoordinator) offsetFetch(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*OffsetFetchRequest)
+	res := &OffsetFetchResponse{Version: req.Version, Err: ErrNoError}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	committed := c.offsets[req.ConsumerGroup]
+	if req.partitions == nil {
+		// all the partitions the group committed offsets for
+		for topic, partitions := range committed {
+			for partition, block := range partitions {
+				res.AddBlock(topic, partition, block)
+			}
+		}
+		return res
+	}
+	for topic, partitions := range req.partitions {
+		for _, partition := range partitions {
+			block, ok := committed[topic][partition]
+			if !ok {
+				block = &OffsetFetchResponseBlock{Offset: -1, LeaderEpoch: -1, Err: ErrNoError}
+			}
+			res.AddBlock(topic, partition, block)
+		}
+	}
+	return res
+}
+
+func (c *MockGroupCoordinator) offsetCommit(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*OffsetCommitRequest)
+	res := &OffsetCommitResponse{Version: req.Version}
+
+	c.lock.Lock()
+	defer c.lock.Unlock()
+
+	err := ErrNoError
+	if req.Version >= 1 && req.ConsumerGroupGeneration >= 0 {
+		// the commits of a member of the group must be of its current
+		// generation, those of the admins are not checked
+		_, err = c.member(req.ConsumerGroup, req.ConsumerID, req.ConsumerGroupGeneration)
+	}
+	for topic, partitions := range req.blocks {
+		for partition, block := range partitions {
+			if err == ErrNoError {
+				c.commit(req.ConsumerGroup, topic, partition, block.offset, block.metadata)
+			}
+			res.AddError(topic, partition, err)
+		}
+	}
+	return res
+}
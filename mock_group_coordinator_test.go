@@ -0,0 +1,185 @@
+package sarama
+
+import (
+	"context"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+// claimsRecorder is a ConsumerGroupHandler recording the claims of the
+// sessions, and marking an offset on each partition claimed.
+type claimsRecorder struct {
+	lock   sync.Mutex
+	claims map[string]int32
+}
+
+func (h *claimsRecorder) Setup(sess ConsumerGroupSession) error {
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	h.claims[sess.MemberID()] = 0
+	for topic, partitions := range sess.Claims() {
+		for _, partition := range partitions {
+			sess.MarkOffset(topic, partition, 5+int64(partition), "")
+			h.claims[sess.MemberID()]++
+		}
+	}
+	return nil
+}
+
+func (h *claimsRecorder) Cleanup(ConsumerGroupSession) error { return nil }
+
+func (h *claimsRecorder) ConsumeClaim(sess ConsumerGroupSession, claim ConsumerGroupClaim) error {
+	<-sess.Context().Done()
+	return nil
+}
+
+func (h *claimsRecorder) waitFor(t *testing.T, expected map[string]int32) {
+	t.Helper()
+	for deadline := time.Now().Add(10 * time.Second); time.Now().Before(deadline); time.Sleep(10 * time.Millisecond) {
+		h.lock.Lock()
+		done := reflect.DeepEqual(h.claims, expected)
+		h.lock.Unlock()
+		if done {
+			return
+		}
+	}
+	h.lock.Lock()
+	defer h.lock.Unlock()
+	t.Fatalf("expected the claims %v, got %v", expected, h.claims)
+}
+
+func TestMockGroupCoordinator(t *testing.T) {
+	broker := NewMockBroker(t, 1)
+	defer broker.Close()
+	coordinator := NewMockGroupCoordinator(t, broker)
+	handlers := coordinator.Handlers()
+	handlers["MetadataRequest"] = NewMockMetadataResponse(t).
+		SetBroker(broker.Addr(), broker.BrokerID()).
+		SetLeader("my_topic", 0, broker.BrokerID()).
+		SetLeader("my_topic", 1, broker.BrokerID())
+	handlers["OffsetRequest"] = NewMockOffsetResponse(t).
+		SetVersion(1).
+		SetOffset("my_topic", 0, OffsetOldest, 0).
+		SetOffset("my_topic", 1, OffsetOldest, 0).
+		SetOffset("my_topic", 0, OffsetNewest, 10).
+		SetOffset("my_topic", 1, OffsetNewest, 10)
+	handlers["FetchRequest"] = NewMockFetchResponse(t, 1).SetVersion(3)
+	broker.SetHandlerByMap(handlers)
+
+	config := NewTestConfig()
+	config.Version = V0_10_2_0
+	config.Consumer.Offsets.AutoCommit.Interval = 10 * time.Millisecond
+	config.Consumer.Group.Heartbeat.Interval = 10 * time.Millisecond
+	config.Consumer.Group.Rebalance.Retry.Backoff = 10 * time.Millisecond
+	config.Consumer.Group.Rebalance.Retry.Max = 100
+
+	handler := &claimsRecorder{claims: make(map[string]int32)}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	consume := func() (ConsumerGroup, chan none) {
+		group, err := NewConsumerGroup([]string{broker.Addr()}, "my_group", config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		done := make(chan none)
+		go func() {
+			defer close(done)
+			for ctx.Err() == nil {
+				err := group.Consume(ctx, []string{"my_topic"}, handler)
+				if err == ErrClosedConsumerGroup {
+					return
+				} else if err != nil {
+					t.Error(err)
+					return
+				}
+			}
+		}()
+		return group, done
+	}
+
+	group1, done1 := consume()
+	handler.waitFor(t, map[string]int32{"mock-member-1": 2})
+	group2, done2 := consume()
+	handler.waitFor(t, map[string]int32{"mock-member-1": 1, "mock-member-2": 1})
+	if generation, members := coordinator.Generation("my_group"); generation != 2 || len(members) != 2 {
+		t.Errorf("expected the second member to start generation 2, got %d with %v", generation, members)
+	}
+
+	safeClose(t, group2)
+	<-done2
+	delete(handler.claims, "mock-member-2")
+	handler.waitFor(t, map[string]int32{"mock-member-1": 2})
+	if generation, members := coordinator.Generation("my_group"); generation != 3 || !reflect.DeepEqual(members, []string{"mock-member-1"}) {
+		t.Errorf("expected the second member to leave in generation 3, got %d with %v", generation, members)
+	}
+
+	cancel()
+	<-done1
+	safeClose(t, group1)
+	for partition := int32(0); partition < 2; partition++ {
+		if offset, ok := coordinator.Offset("my_group", "my_topic", partition); !ok || offset != 5+int64(partition) {
+			t.Errorf("expected the offset marked on partition %d to be committed, got %d", partition, offset)
+		}
+	}
+}
+
+func TestConsumerGroupRequestVersions(t *testing.T) {
+	for _, tc := range []struct {
+		version  KafkaVersion
+		expected map[string]int16
+	}{
+		{V0_10_2_0, map[string]int16{"JoinGroupRequest": 1, "SyncGroupRequest": 0, "HeartbeatRequest": 0, "LeaveGroupRequest": 0, "OffsetFetchRequest": 2}},
+		{V2_0_0_0, map[string]int16{"JoinGroupRequest": 3, "SyncGroupRequest": 2, "HeartbeatRequest": 2, "LeaveGroupRequest": 2, "OffsetFetchRequest": 4}},
+		{V2_4_0_0, map[string]int16{"JoinGroupRequest": 6, "SyncGroupRequest": 4, "HeartbeatRequest": 4, "LeaveGroupRequest": 4, "OffsetFetchRequest": 6}},
+	} {
+		t.Run(tc.version.String(), func(t *testing.T) {
+			broker := NewMockBroker(t, 1)
+			defer broker.Close()
+			coordinator := NewMockGroupCoordinator(t, broker)
+			handlers := coordinator.Handlers()
+			handlers["MetadataRequest"] = NewMockMetadataResponse(t).
+				SetBroker(broker.Addr(), broker.BrokerID()).
+				SetLeader("my_topic", 0, broker.BrokerID())
+			handlers["OffsetRequest"] = NewMockOffsetResponse(t).
+				SetOffset("my_topic", 0, OffsetOldest, 0).
+				SetOffset("my_topic", 0, OffsetNewest, 10)
+			handlers["FetchRequest"] = NewMockFetchResponse(t, 1)
+			handlers["ApiVersionsRequest"] = NewMockApiVersionsResponse(t)
+			broker.SetHandlerByMap(handlers)
+
+			config := NewTestConfig()
+			config.Version = tc.version
+			config.Consumer.Group.Heartbeat.Interval = 10 * time.Millisecond
+			group, err := NewConsumerGroup([]string{broker.Addr()}, "my_group", config)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			handler := &claimsRecorder{claims: make(map[string]int32)}
+			ctx, cancel := context.WithCancel(context.Background())
+			done := make(chan error, 1)
+			go func() { done <- group.Consume(ctx, []string{"my_topic"}, handler) }()
+			handler.waitFor(t, map[string]int32{"mock-member-1": 1})
+			// let a heartbeat be sent
+			time.Sleep(50 * time.Millisecond)
+			cancel()
+			if err := <-done; err != nil {
+				t.Error(err)
+			}
+			safeClose(t, group)
+
+			versions := make(map[string]int16)
+			for _, entry := range broker.History() {
+				name := reflect.TypeOf(entry.Request).Elem().Name()
+				if _, ok := tc.expected[name]; ok {
+					versions[name] = entry.Request.version()
+				}
+			}
+			if !reflect.DeepEqual(versions, tc.expected) {
+				t.Errorf("expected the versions %v, got %v", tc.expected, versions)
+			}
+		})
+	}
+}
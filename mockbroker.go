@@ -45,6 +45,11 @@ type RequestNotifierFunc func(bytesRead, bytesWritten int)
 // provided by Sarama. But users can develop MockRequests of their own and use
 // them along with or instead of the standard ones.
 //
+// Faults can be injected in the answers of a MockBroker with InjectFault, to
+// exercise the retry and timeout paths of the clients. It serves TLS when
+// created with NewMockBrokerTLS, and authenticates the connections with SASL
+// when given a MockSASL with SetSASL.
+//
 // When running tests with MockBroker it is strongly recommended to specify
 // a timeout to `go test` so that if the broker hangs waiting for a response,
 // the test panics.
@@ -65,6 +70,8 @@ type MockBroker struct {
 	history       []RequestResponse
 	lock          sync.Mutex
 	gssApiHandler GSSApiHandlerFunc
+	faults        map[string]*mockFault
+	sasl          *MockSASL
 }
 
 // RequestResponse represents a Request/Response pair processed by MockBroker.
@@ -240,6 +247,8 @@ func (b *MockBroker) handleRequests(conn io.ReadWriteCloser, idx int, wg *sync.W
 
 	var bytesWritten int
 	var bytesRead int
+	// saslConn is the SASL exchange of the connection, if authenticated
+	var saslConn *mockSASLConn
 	for {
 		buffer, err := b.readToBytes(conn)
 		if err != nil {
@@ -258,15 +267,37 @@ func (b *MockBroker) handleRequests(conn io.ReadWriteCloser, idx int, wg *sync.W
 				break
 			}
 
-			if b.latency > 0 {
-				time.Sleep(b.latency)
+			b.lock.Lock()
+			fault := b.takeFault(req.body)
+			b.lock.Unlock()
+
+			if b.latency+fault.Latency > 0 {
+				time.Sleep(b.latency + fault.Latency)
 			}
 
+			var res encoderWithHeader
 			b.lock.Lock()
-			res := b.handler(req)
+			if b.sasl != nil && saslConn == nil {
+				saslConn = &mockSASLConn{sasl: b.sasl}
+			}
+			if fault.UnsupportedVersion {
+				res = unsupportedVersionResponse(req.body)
+			} else if !fault.DropConnection {
+				if saslConn != nil {
+					res = saslConn.answer(req.body)
+				}
+				if res == nil {
+					res = b.handler(req)
+				}
+			}
 			b.history = append(b.history, RequestResponse{req.body, res})
 			b.lock.Unlock()
 
+			if res == nil && (fault.DropConnection || fault.UnsupportedVersion) {
+				Logger.Printf("*** mockbroker/%d/%d: dropped the connection on %T", b.brokerID, idx, req.body)
+				break
+			}
+
 			if res == nil {
 				Logger.Printf("*** mockbroker/%d/%d: ignored %v", b.brokerID, idx, spew.Sdump(req))
 				continue
@@ -283,6 +314,7 @@ func (b *MockBroker) handleRequests(conn io.ReadWriteCloser, idx int, wg *sync.W
 				b.serverError(err)
 				break
 			}
+			encodedRes = applyFault(fault, encodedRes)
 			if len(encodedRes) == 0 {
 				b.lock.Lock()
 				if b.notifier != nil {
@@ -0,0 +1,96 @@
+package sarama
+
+import (
+	"reflect"
+	"time"
+)
+
+// MockFault is a fault a MockBroker injects in its answers to a type of request, to
+// exercise the retry and timeout paths of the clients.
+type MockFault struct {
+	// Latency delays the answers, on top of the latency of the broker.
+	Latency time.Duration
+	// DropConnection closes the connection instead of answering.
+	DropConnection bool
+	// TruncateResponse sends only the first half of the responses, framed as
+	// complete ones, for the clients to fail decoding them.
+	TruncateResponse bool
+	// CorruptResponse overwrites the bytes of the responses with garbage,
+	// keeping their length and correlation ID.
+	CorruptResponse bool
+	// UnsupportedVersion answers as a broker not supporting the version of
+	// the requests: the ApiVersionsRequests with an ApiVersionsResponse of
+	// ErrUnsupportedVersion, the other requests by closing the connection.
+	UnsupportedVersion bool
+	// Times is the number of requests the fault is injected in, 0 for all.
+	Times int
+}
+
+// mockFault is a MockFault injected by a MockBroker, with the number of requests it
+// is still injected in.
+type mockFault struct {
+	MockFault
+	remaining int
+}
+
+// InjectFault makes the broker inject fault in its answers to the requests of
+// reqType, the name of their type as in SetHandlerByMap. It replaces the fault
+// injected in them before.
+func (b *MockBroker) InjectFault(reqType string, fault MockFault) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	if b.faults == nil {
+		b.faults = make(map[string]*mockFault)
+	}
+	b.faults[reqType] = &mockFault{MockFault: fault, remaining: fault.Times}
+}
+
+// ClearFaults stops the broker from injecting faults.
+func (b *MockBroker) ClearFaults() {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.faults = nil
+}
+
+// takeFault returns the fault to inject in the answer to req, the zero MockFault if
+// there is none. It must be called with the lock held.
+func (b *MockBroker) takeFault(req protocolBody) MockFault {
+	reqType := reflect.TypeOf(req).Elem().Name()
+	fault := b.faults[reqType]
+	if fault == nil {
+		return MockFault{}
+	}
+	if fault.Times > 0 {
+		fault.remaining--
+		if fault.remaining <= 0 {
+			delete(b.faults, reqType)
+		}
+	}
+	return fault.MockFault
+}
+
+// unsupportedVersionResponse returns the answer to req of a broker not supporting
+// its version, nil if the connection must be closed instead.
+func unsupportedVersionResponse(req protocolBody) encoderWithHeader {
+	if _, ok := req.(*ApiVersionsRequest); ok {
+		return &ApiVersionsResponse{ErrorCode: int16(ErrUnsupportedVersion)}
+	}
+	return nil
+}
+
+// applyFault returns the encoded response res altered by fault.
+func applyFault(fault MockFault, res []byte) []byte {
+	if fault.TruncateResponse {
+		res = res[:len(res)/2]
+	}
+	if fault.CorruptResponse {
+		// lengths of arrays and strings beyond the end of the response, for
+		// the decoding to fail rather than to allocate them
+		corrupted := make([]byte, len(res))
+		for i := range corrupted {
+			corrupted[i] = 0x7f
+		}
+		res = corrupted
+	}
+	return res
+}
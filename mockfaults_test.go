@@ -0,0 +1,64 @@
+package sarama
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestMockBrokerFaults(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		fault MockFault
+	}{
+		{"latency", MockFault{Latency: 500 * time.Millisecond, Times: 1}},
+		{"dropped connection", MockFault{DropConnection: true, Times: 1}},
+		{"truncated response", MockFault{TruncateResponse: true, Times: 1}},
+		{"corrupted response", MockFault{CorruptResponse: true, Times: 1}},
+		{"unsupported version", MockFault{UnsupportedVersion: true, Times: 1}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			mb := NewMockBroker(t, 1)
+			defer mb.Close()
+			mb.SetHandlerByMap(map[string]MockResponse{
+				"MetadataRequest": NewMockMetadataResponse(t).SetBroker(mb.Addr(), mb.BrokerID()),
+			})
+			mb.InjectFault("MetadataRequest", tc.fault)
+
+			conf := NewTestConfig()
+			conf.Net.ReadTimeout = 100 * time.Millisecond
+			broker := NewBroker(mb.Addr())
+			if err := broker.Open(conf); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := broker.GetMetadata(&MetadataRequest{}); err == nil {
+				t.Error("expected the fault to fail the request")
+			}
+			_ = broker.Close()
+
+			// the fault is injected once
+			if err := broker.Open(conf); err != nil {
+				t.Fatal(err)
+			}
+			defer safeClose(t, broker)
+			if _, err := broker.GetMetadata(&MetadataRequest{}); err != nil {
+				t.Errorf("expected the request to succeed once the fault was injected, got %v", err)
+			}
+		})
+	}
+}
+
+func TestMockBrokerUnsupportedApiVersions(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.InjectFault("ApiVersionsRequest", MockFault{UnsupportedVersion: true})
+
+	broker := NewBroker(mb.Addr())
+	if err := broker.Open(NewTestConfig()); err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, broker)
+	if _, err := broker.ApiVersions(&ApiVersionsRequest{}); !errors.Is(err, ErrUnsupportedVersion) {
+		t.Errorf("expected the version to be unsupported, got %v", err)
+	}
+}
@@ -0,0 +1,383 @@
+package sarama
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MockFixtureFile is the content of a fixture file loaded by NewMockFixtures:
+// record batches of a partition, one after the other. Several files may hold
+// batches of the same partition, which follow each other in the order of the
+// names of the files.
+type MockFixtureFile struct {
+	Topic     string             `json:"topic"`
+	Partition int32              `json:"partition"`
+	Batches   []MockFixtureBatch `json:"batches"`
+}
+
+// MockFixtureBatch is a record batch of a fixture file.
+type MockFixtureBatch struct {
+	// BaseOffset is the offset of the first record of the batch, following
+	// the previous batch of the partition by default. It may skip offsets,
+	// as compaction does, but not go back.
+	BaseOffset *int64 `json:"base_offset,omitempty"`
+	// Codec is the compression of the batch: none (the default), gzip,
+	// snappy, lz4 or zstd.
+	Codec CompressionCodec `json:"codec,omitempty"`
+	// Timestamp is the timestamp of the records not having one.
+	Timestamp time.Time `json:"timestamp,omitempty"`
+	// ProducerID and ProducerEpoch are those of the producer of the batch.
+	ProducerID    int64 `json:"producer_id,omitempty"`
+	ProducerEpoch int16 `json:"producer_epoch,omitempty"`
+	// Transactional makes the batch part of the transaction of ProducerID,
+	// open until a control batch ends it.
+	Transactional bool `json:"transactional,omitempty"`
+	// Control makes the batch the control batch ending the transaction of
+	// ProducerID: "commit" or "abort". It has no records.
+	Control string `json:"control,omitempty"`
+	// Records are the records of the batch.
+	Records []MockFixtureRecord `json:"records,omitempty"`
+}
+
+// MockFixtureRecord is a record of a fixture file.
+type MockFixtureRecord struct {
+	// Key and Value are nil unless set.
+	Key       *string           `json:"key,omitempty"`
+	Value     *string           `json:"value,omitempty"`
+	Headers   map[string]string `json:"headers,omitempty"`
+	Timestamp time.Time         `json:"timestamp,omitempty"`
+}
+
+// MockFixtures answers the FetchRequests and OffsetRequests of a MockBroker
+// from the record batches of fixture files, as the log of a broker would:
+//
+//	mb.SetHandlerByMap(map[string]MockResponse{
+//		"MetadataRequest": ...,
+//		"FetchRequest":    fixtures,
+//		"OffsetRequest":   fixtures,
+//	})
+//
+// The fetches start at the batch holding the offset requested and hold all
+// the batches following it, up to the last stable offset for the consumers
+// reading committed records, with the transactions aborted. The fetches need
+// Kafka 0.11.0.0 or later, FetchRequest v4, to carry record batches.
+type MockFixtures struct {
+	t          TestReporter
+	lock       sync.Mutex
+	partitions map[string]map[int32]*mockFixturePartition
+}
+
+// mockFixturePartition is the log of a partition of MockFixtures.
+type mockFixturePartition struct {
+	batches  []MockFixtureBatch
+	offsets  []int64 // of the first record of each batch
+	aborted  []mockAbortedTransaction
+	logStart int64
+	// highWaterMark is the offset following the last batch, and lastStable
+	// that of the first batch of the first transaction still open
+	highWaterMark, lastStable int64
+}
+
+// mockAbortedTransaction is an aborted transaction, with the offset of its abort
+// marker.
+type mockAbortedTransaction struct {
+	AbortedTransaction
+	markerOffset int64
+}
+
+// NewMockFixtures loads the fixture files of dir, the files with the .json
+// extension, each holding a MockFixtureFile. It fails t if they are invalid.
+func NewMockFixtures(t TestReporter, dir string) *MockFixtures {
+	mf := &MockFixtures{t: t, partitions: make(map[string]map[int32]*mockFixturePartition)}
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(files)
+	for _, file := range files {
+		if err := mf.load(file); err != nil {
+			t.Fatal(fmt.Errorf("fixture %s: %w", file, err))
+		}
+	}
+	return mf
+}
+
+func (mf *MockFixtures) load(file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	var fixture MockFixtureFile
+	dec := json.NewDecoder(f)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&fixture); err != nil {
+		return err
+	}
+
+	partitions := mf.partitions[fixture.Topic]
+	if partitions == nil {
+		partitions = make(map[int32]*mockFixturePartition)
+		mf.partitions[fixture.Topic] = partitions
+	}
+	p := partitions[fixture.Partition]
+	if p == nil {
+		p = &mockFixturePartition{}
+		partitions[fixture.Partition] = p
+	}
+	for _, batch := range fixture.Batches {
+		if err := p.append(batch); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// append appends batch to the log of the partition.
+func (p *mockFixturePartition) append(batch MockFixtureBatch) error {
+	offset := p.highWaterMark
+	if batch.BaseOffset != nil {
+		if *batch.BaseOffset < offset {
+			return fmt.Errorf("batch at offset %d before the end of the partition at %d", *batch.BaseOffset, offset)
+		}
+		offset = *batch.BaseOffset
+	}
+	if len(p.batches) == 0 {
+		p.logStart = offset
+	}
+
+	switch batch.Control {
+	case "":
+		if len(batch.Records) == 0 {
+			return fmt.Errorf("batch at offset %d has no records", offset)
+		}
+	case "commit", "abort":
+		if len(batch.Records) != 0 {
+			return fmt.Errorf("control batch at offset %d has records", offset)
+		}
+		batch.Transactional = true
+	default:
+		return fmt.Errorf("batch at offset %d has an unknown control %q", offset, batch.Control)
+	}
+
+	p.batches = append(p.batches, batch)
+	p.offsets = append(p.offsets, offset)
+	p.highWaterMark = offset + int64(len(batch.Records))
+	if batch.Control != "" {
+		// the control record
+		p.highWaterMark++
+	}
+
+	// the transactions still open, by producer ID, with their first offset
+	open := make(map[int64]int64)
+	p.aborted = nil
+	for i, b := range p.batches {
+		switch {
+		case b.Control == "abort":
+			if first, ok := open[b.ProducerID]; ok {
+				p.aborted = append(p.aborted, mockAbortedTransaction{
+					AbortedTransaction: AbortedTransaction{ProducerID: b.ProducerID, FirstOffset: first},
+					markerOffset:       p.offsets[i],
+				})
+			}
+			delete(open, b.ProducerID)
+		case b.Control == "commit":
+			delete(open, b.ProducerID)
+		case b.Transactional:
+			if _, ok := open[b.ProducerID]; !ok {
+				open[b.ProducerID] = p.offsets[i]
+			}
+		}
+	}
+	p.lastStable = p.highWaterMark
+	for _, first := range open {
+		if first < p.lastStable {
+			p.lastStable = first
+		}
+	}
+	return nil
+}
+
+// recordBatch returns the i-th batch of the partition, allocated for each
+// response as encoding a RecordBatch caches its compressed records.
+func (p *mockFixturePartition) recordBatch(i int) *RecordBatch {
+	fixture := p.batches[i]
+	batch := &RecordBatch{
+		Version:         2,
+		FirstOffset:     p.offsets[i],
+		Codec:           fixture.Codec,
+		FirstTimestamp:  fixture.Timestamp,
+		MaxTimestamp:    fixture.Timestamp,
+		ProducerID:      fixture.ProducerID,
+		ProducerEpoch:   fixture.ProducerEpoch,
+		FirstSequence:   -1,
+		IsTransactional: fixture.Transactional,
+		Control:         fixture.Control != "",
+	}
+	if !fixture.Transactional && fixture.ProducerID == 0 {
+		batch.ProducerID = -1
+		batch.ProducerEpoch = -1
+	}
+
+	if batch.Control {
+		control := ControlRecord{Type: ControlRecordCommit}
+		if fixture.Control == "abort" {
+			control.Type = ControlRecordAbort
+		}
+		key := &realEncoder{raw: make([]byte, 4)}
+		value := &realEncoder{raw: make([]byte, 6)}
+		control.encode(key, value)
+		batch.addRecord(&Record{Key: key.raw, Value: value.raw})
+		return batch
+	}
+
+	for _, record := range fixture.Records {
+		if !record.Timestamp.IsZero() && (batch.FirstTimestamp.IsZero() || record.Timestamp.Before(batch.FirstTimestamp)) {
+			batch.FirstTimestamp = record.Timestamp
+		}
+		if record.Timestamp.After(batch.MaxTimestamp) {
+			batch.MaxTimestamp = record.Timestamp
+		}
+	}
+	for j, record := range fixture.Records {
+		timestamp := record.Timestamp
+		if timestamp.IsZero() {
+			timestamp = fixture.Timestamp
+		}
+		if timestamp.IsZero() {
+			timestamp = batch.FirstTimestamp
+		}
+		rec := &Record{OffsetDelta: int64(j), TimestampDelta: timestamp.Sub(batch.FirstTimestamp)}
+		if record.Key != nil {
+			rec.Key = []byte(*record.Key)
+		}
+		if record.Value != nil {
+			rec.Value = []byte(*record.Value)
+		}
+		keys := make([]string, 0, len(record.Headers))
+		for key := range record.Headers {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+		for _, key := range keys {
+			rec.Headers = append(rec.Headers, &RecordHeader{Key: []byte(key), Value: []byte(record.Headers[key])})
+		}
+		batch.addRecord(rec)
+	}
+	batch.LastOffsetDelta = int32(len(fixture.Records) - 1)
+	return batch
+}
+
+// For answers the FetchRequests and OffsetRequests.
+func (mf *MockFixtures) For(reqBody versionedDecoder) encoderWithHeader {
+	mf.lock.Lock()
+	defer mf.lock.Unlock()
+
+	switch req := reqBody.(type) {
+	case *FetchRequest:
+		return mf.fetch(req)
+	case *OffsetRequest:
+		return mf.offsets(req)
+	}
+	mf.t.Errorf("fixtures cannot answer %T", reqBody)
+	return nil
+}
+
+func (mf *MockFixtures) fetch(req *FetchRequest) encoderWithHeader {
+	if req.Version < 4 {
+		mf.t.Errorf("fixtures cannot answer a FetchRequest v%d, v4 carrying the first record batches", req.Version)
+		return nil
+	}
+	res := &FetchResponse{Version: req.Version}
+	for topic, blocks := range req.blocks {
+		for partition, block := range blocks {
+			p := mf.partitions[topic][partition]
+			if p == nil {
+				res.AddError(topic, partition, ErrUnknownTopicOrPartition)
+				continue
+			}
+			if block.fetchOffset < p.logStart || block.fetchOffset > p.highWaterMark {
+				res.AddError(topic, partition, ErrOffsetOutOfRange)
+				continue
+			}
+
+			end := p.highWaterMark
+			if req.Isolation == ReadCommitted {
+				end = p.lastStable
+			}
+			frb := res.getOrCreateBlock(topic, partition)
+			frb.HighWaterMarkOffset = p.highWaterMark
+			frb.LastStableOffset = p.lastStable
+			frb.LogStartOffset = p.logStart
+			frb.PreferredReadReplica = -1
+			for i := range p.batches {
+				batch := p.recordBatch(i)
+				if batch.LastOffset() < block.fetchOffset || batch.FirstOffset >= end {
+					continue
+				}
+				records := newDefaultRecords(batch)
+				frb.RecordsSet = append(frb.RecordsSet, &records)
+			}
+			if req.Isolation == ReadCommitted {
+				for _, aborted := range p.aborted {
+					if aborted.markerOffset >= block.fetchOffset && aborted.FirstOffset < end {
+						transaction := aborted.AbortedTransaction
+						frb.AbortedTransactions = append(frb.AbortedTransactions, &transaction)
+					}
+				}
+			}
+		}
+	}
+	return res
+}
+
+func (mf *MockFixtures) offsets(req *OffsetRequest) encoderWithHeader {
+	res := &OffsetResponse{Version: req.Version}
+	for topic, blocks := range req.blocks {
+		for partition, block := range blocks {
+			p := mf.partitions[topic][partition]
+			if p == nil {
+				res.AddTopicPartition(topic, partition, -1)
+				res.Blocks[topic][partition].Err = ErrUnknownTopicOrPartition
+				continue
+			}
+
+			offset := int64(-1)
+			switch block.time {
+			case OffsetNewest:
+				offset = p.highWaterMark
+				if req.IsolationLevel == ReadCommitted {
+					offset = p.lastStable
+				}
+			case OffsetOldest:
+				offset = p.logStart
+			default:
+				offset = p.offsetForTime(block.time)
+			}
+			res.AddTopicPartition(topic, partition, offset)
+		}
+	}
+	return res
+}
+
+// offsetForTime returns the offset of the first record of a timestamp of at
+// least timestamp, in milliseconds, -1 if there is none.
+func (p *mockFixturePartition) offsetForTime(timestamp int64) int64 {
+	for i := range p.batches {
+		batch := p.recordBatch(i)
+		if batch.Control {
+			continue
+		}
+		for _, record := range batch.Records {
+			if batch.FirstTimestamp.Add(record.TimestampDelta).UnixNano()/int64(time.Millisecond) >= timestamp {
+				return batch.FirstOffset + record.OffsetDelta
+			}
+		}
+	}
+	return -1
+}
@@ -0,0 +1,82 @@
+package sarama
+
+import (
+	"testing"
+	"time"
+)
+
+func TestMockFixtures(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	fixtures := NewMockFixtures(t, "testdata/fixtures")
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(mb.Addr(), mb.BrokerID()).
+			SetLeader("my_topic", 0, mb.BrokerID()),
+		"FetchRequest":  fixtures,
+		"OffsetRequest": fixtures,
+	})
+
+	for _, tc := range []struct {
+		isolation IsolationLevel
+		values    []string
+		newest    int64
+	}{
+		{ReadUncommitted, []string{"first", "second", "aborted", "committed", "open"}, 17},
+		{ReadCommitted, []string{"first", "second", "committed"}, 16},
+	} {
+		config := NewTestConfig()
+		config.Version = V2_0_0_0
+		config.Consumer.IsolationLevel = tc.isolation
+		client, err := NewClient([]string{mb.Addr()}, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+
+		if oldest, err := client.GetOffset("my_topic", 0, OffsetOldest); err != nil || oldest != 10 {
+			t.Errorf("expected the oldest offset to be 10, got %d and %v", oldest, err)
+		}
+		leader, err := client.Leader("my_topic", 0)
+		if err != nil {
+			t.Fatal(err)
+		}
+		request := &OffsetRequest{Version: 2, IsolationLevel: tc.isolation}
+		request.AddBlock("my_topic", 0, OffsetNewest, 1)
+		if res, err := leader.GetAvailableOffsets(request); err != nil {
+			t.Error(err)
+		} else if block := res.GetBlock("my_topic", 0); block == nil || block.Offset != tc.newest {
+			t.Errorf("expected the newest offset to be %d, got %+v", tc.newest, block)
+		}
+		at := time.Date(2022, 2, 1, 10, 0, 1, 0, time.UTC).UnixNano() / int64(time.Millisecond)
+		if offset, err := client.GetOffset("my_topic", 0, at); err != nil || offset != 11 {
+			t.Errorf("expected the offset of the second record at its timestamp, got %d and %v", offset, err)
+		}
+
+		consumer, err := NewConsumerFromClient(client)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pc, err := consumer.ConsumePartition("my_topic", 0, OffsetOldest)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, value := range tc.values {
+			select {
+			case msg := <-pc.Messages():
+				if string(msg.Value) != value {
+					t.Errorf("expected %q at offset %d, got %q", value, msg.Offset, msg.Value)
+				}
+				if value == "first" && (len(msg.Headers) != 1 || string(msg.Headers[0].Value) != "1") {
+					t.Errorf("expected the header of the first record, got %v", msg.Headers)
+				}
+			case err := <-pc.Errors():
+				t.Fatal(err)
+			case <-time.After(5 * time.Second):
+				t.Fatalf("timed out waiting for %q", value)
+			}
+		}
+		safeClose(t, pc)
+		safeClose(t, consumer)
+		safeClose(t, client)
+	}
+}
@@ -0,0 +1,374 @@
+package sarama
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"sync"
+)
+
+// RecordedExchange is a request and its response, as recorded by a
+// RecordingProxy. The request is encoded without its header, the response
+// following its correlation ID.
+type RecordedExchange struct {
+	APIKey     int16  `json:"api_key"`
+	APIVersion int16  `json:"api_version"`
+	Request    []byte `json:"request"`
+	Response   []byte `json:"response"`
+}
+
+// Recording is the traffic recorded by a RecordingProxy, for a replay broker
+// to answer it again.
+type Recording struct {
+	Exchanges []RecordedExchange `json:"exchanges"`
+}
+
+// Save writes the recording to w, as JSON.
+func (r *Recording) Save(w io.Writer) error {
+	return json.NewEncoder(w).Encode(r)
+}
+
+// LoadRecording reads a recording written by Recording.Save from r.
+func LoadRecording(r io.Reader) (*Recording, error) {
+	recording := &Recording{}
+	if err := json.NewDecoder(r).Decode(recording); err != nil {
+		return nil, err
+	}
+	return recording, nil
+}
+
+// RecordingProxy sits between clients and a real broker, forwarding their
+// traffic and recording the requests and responses exchanged, keyed by API
+// key and version, so that NewReplayBroker can answer them again later in
+// hermetic regression tests.
+//
+// The proxy rewrites the addresses of the brokers listed in the Metadata and
+// FindCoordinator responses to its own, for the clients to keep talking to the
+// cluster through it, which suits single-broker clusters. The SASL exchanges
+// outside of Kafka requests (GSSAPI, SaslHandshakeRequest v0) are forwarded
+// without being recorded.
+type RecordingProxy struct {
+	t        TestReporter
+	target   string
+	listener net.Listener
+
+	lock      sync.Mutex
+	recording Recording
+	conns     map[net.Conn]none
+	closed    bool
+	wg        sync.WaitGroup
+}
+
+// NewRecordingProxy launches a proxy to the broker at target, listening on a
+// random local port.
+func NewRecordingProxy(t TestReporter, target string) *RecordingProxy {
+	listener, err := net.Listen("tcp", "localhost:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	p := &RecordingProxy{
+		t:        t,
+		target:   target,
+		listener: listener,
+		conns:    make(map[net.Conn]none),
+	}
+	p.wg.Add(1)
+	go p.serve()
+	Logger.Printf("*** recordingproxy: listening on %s, forwarding to %s", p.Addr(), target)
+	return p
+}
+
+// Addr returns the address the clients connect to instead of the broker's.
+func (p *RecordingProxy) Addr() string {
+	return p.listener.Addr().String()
+}
+
+// Recording returns the exchanges recorded so far, in the order of the responses.
+func (p *RecordingProxy) Recording() *Recording {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	return &Recording{Exchanges: append([]RecordedExchange(nil), p.recording.Exchanges...)}
+}
+
+// Close stops the proxy, closing the connections through it.
+func (p *RecordingProxy) Close() {
+	p.lock.Lock()
+	p.closed = true
+	for conn := range p.conns {
+		_ = conn.Close()
+	}
+	p.lock.Unlock()
+
+	if err := p.listener.Close(); err != nil {
+		p.t.Error(err)
+	}
+	p.wg.Wait()
+}
+
+func (p *RecordingProxy) serve() {
+	defer p.wg.Done()
+	for {
+		conn, err := p.listener.Accept()
+		if err != nil {
+			return
+		}
+		upstream, err := net.Dial("tcp", p.target)
+		if err != nil {
+			Logger.Printf("*** recordingproxy: failed to connect to %s: %v", p.target, err)
+			_ = conn.Close()
+			continue
+		}
+		if !p.track(conn, upstream) {
+			return
+		}
+
+		pending := &proxyPending{exchanges: make(map[int32]*RecordedExchange)}
+		p.wg.Add(2)
+		go p.forwardRequests(conn, upstream, pending)
+		go p.forwardResponses(upstream, conn, pending)
+	}
+}
+
+// track registers conns to be closed with the proxy, closing them instead if it
+// already is.
+func (p *RecordingProxy) track(conns ...net.Conn) bool {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+	for _, conn := range conns {
+		if p.closed {
+			_ = conn.Close()
+			continue
+		}
+		p.conns[conn] = none{}
+	}
+	return !p.closed
+}
+
+// proxyPending is the requests of a connection through a RecordingProxy awaiting
+// their response, by correlation ID.
+type proxyPending struct {
+	lock      sync.Mutex
+	exchanges map[int32]*RecordedExchange
+}
+
+func (p *RecordingProxy) forwardRequests(client, upstream net.Conn, pending *proxyPending) {
+	defer p.wg.Done()
+	defer func() {
+		_ = upstream.Close()
+	}()
+
+	for {
+		frame, err := readFrame(client)
+		if err != nil {
+			return
+		}
+		if req, _, err := decodeRequest(bytes.NewReader(frame)); err == nil {
+			body, err := encode(req.body, nil)
+			if err == nil {
+				pending.lock.Lock()
+				pending.exchanges[req.correlationID] = &RecordedExchange{
+					APIKey:     req.body.key(),
+					APIVersion: req.body.version(),
+					Request:    body,
+				}
+				pending.lock.Unlock()
+			}
+		} else {
+			Logger.Printf("*** recordingproxy: forwarding an unrecorded request: %v", err)
+		}
+		if _, err := upstream.Write(frame); err != nil {
+			return
+		}
+	}
+}
+
+func (p *RecordingProxy) forwardResponses(upstream, client net.Conn, pending *proxyPending) {
+	defer p.wg.Done()
+	defer func() {
+		_ = client.Close()
+	}()
+
+	for {
+		frame, err := readFrame(upstream)
+		if err != nil {
+			return
+		}
+		correlationID := int32(binary.BigEndian.Uint32(frame[4:]))
+		pending.lock.Lock()
+		// the requests not expecting a response, such as the produce requests of
+		// acks=0, are dropped as the later ones are answered
+		exchange := pending.exchanges[correlationID]
+		delete(pending.exchanges, correlationID)
+		pending.lock.Unlock()
+
+		if exchange != nil {
+			exchange.Response = frame[8:]
+			p.lock.Lock()
+			p.recording.Exchanges = append(p.recording.Exchanges, *exchange)
+			p.lock.Unlock()
+
+			rewritten, err := rewriteBrokerAddrs(exchange.APIKey, exchange.APIVersion, exchange.Response, p.Addr())
+			if err != nil {
+				Logger.Printf("*** recordingproxy: failed to rewrite the brokers of a response: %v", err)
+			} else {
+				frame = responseFrame(correlationID, rewritten)
+			}
+		}
+		if _, err := client.Write(frame); err != nil {
+			return
+		}
+	}
+}
+
+// NewReplayBroker returns a MockBroker answering the requests with the
+// responses of the exchanges of recording of the same API key and version.
+// Each request is answered with the first exchange not replayed yet whose
+// request is identical, else the first of the same API version not replayed
+// yet, else the last replayed, so that the clients may repeat requests such as
+// the metadata refreshes. Like the RecordingProxy, it rewrites the addresses of
+// the brokers of the Metadata and FindCoordinator responses to its own.
+func NewReplayBroker(t TestReporter, brokerID int32, recording *Recording) *MockBroker {
+	b := NewMockBroker(t, brokerID)
+	replay := &mockReplay{
+		exchanges: recording.Exchanges,
+		replayed:  make([]bool, len(recording.Exchanges)),
+		last:      make(map[[2]int16]int),
+	}
+	b.setHandler(func(req *request) encoderWithHeader {
+		res, err := replay.answer(req.body, b.Addr())
+		if err != nil {
+			t.Errorf("replay broker: %v", err)
+			return nil
+		}
+		return res
+	})
+	return b
+}
+
+// mockReplay is the state of the replay of a recording.
+type mockReplay struct {
+	exchanges []RecordedExchange
+	replayed  []bool
+	// last is the index of the last exchange replayed, by API key and version
+	last map[[2]int16]int
+}
+
+func (r *mockReplay) answer(req protocolBody, addr string) (encoderWithHeader, error) {
+	body, err := encode(req, nil)
+	if err != nil {
+		return nil, err
+	}
+	api := [2]int16{req.key(), req.version()}
+
+	found := -1
+	for i, exchange := range r.exchanges {
+		if r.replayed[i] || exchange.APIKey != api[0] || exchange.APIVersion != api[1] {
+			continue
+		}
+		if bytes.Equal(exchange.Request, body) {
+			found = i
+			break
+		}
+		if found < 0 {
+			found = i
+		}
+	}
+	if found < 0 {
+		last, ok := r.last[api]
+		if !ok {
+			return nil, fmt.Errorf("no %T of version %d recorded", req, req.version())
+		}
+		found = last
+	}
+	r.replayed[found] = true
+	r.last[api] = found
+
+	res, err := rewriteBrokerAddrs(api[0], api[1], r.exchanges[found].Response, addr)
+	if err != nil {
+		return nil, err
+	}
+	return rawResponse(res), nil
+}
+
+// rawResponse is an encoded response, following the correlation ID.
+type rawResponse []byte
+
+func (r rawResponse) encode(pe packetEncoder) error {
+	return pe.putRawBytes(r)
+}
+
+func (r rawResponse) headerVersion() int16 {
+	return 0
+}
+
+// rewriteBrokerAddrs returns res, a response following its correlation ID to a
+// request of the API key and version, with the addresses of the brokers it
+// lists replaced by addr. The responses listing no brokers are returned as is.
+func rewriteBrokerAddrs(key, version int16, res []byte, addr string) ([]byte, error) {
+	var decoded encoderWithHeader
+	switch key {
+	case (&MetadataRequest{}).key():
+		decoded = &MetadataResponse{Version: version}
+	case (&FindCoordinatorRequest{}).key():
+		decoded = &FindCoordinatorResponse{Version: version}
+	default:
+		return res, nil
+	}
+
+	// the tagged fields of the flexible response headers
+	var header []byte
+	if decoded.headerVersion() >= 1 {
+		if len(res) == 0 || res[0] != 0 {
+			return nil, PacketDecodingError{"unexpected tagged fields in the response header"}
+		}
+		header, res = res[:1], res[1:]
+	}
+	if err := versionedDecode(res, decoded.(versionedDecoder), version); err != nil {
+		return nil, err
+	}
+
+	switch decoded := decoded.(type) {
+	case *MetadataResponse:
+		for _, broker := range decoded.Brokers {
+			broker.addr = addr
+		}
+	case *FindCoordinatorResponse:
+		if decoded.Coordinator != nil {
+			decoded.Coordinator.addr = addr
+		}
+	}
+	body, err := encode(decoded, nil)
+	if err != nil {
+		return nil, err
+	}
+	return append(header, body...), nil
+}
+
+// readFrame reads a size-delimited request or response from r, with its size.
+func readFrame(r io.Reader) ([]byte, error) {
+	frame := make([]byte, 4)
+	if _, err := io.ReadFull(r, frame); err != nil {
+		return nil, err
+	}
+	length := int32(binary.BigEndian.Uint32(frame))
+	if length < 4 || length > MaxResponseSize {
+		return nil, PacketDecodingError{fmt.Sprintf("message of length %d too large or too small", length)}
+	}
+	frame = append(frame, make([]byte, length)...)
+	if _, err := io.ReadFull(r, frame[4:]); err != nil {
+		return nil, err
+	}
+	return frame, nil
+}
+
+// responseFrame returns the size-delimited response of correlationID, res
+// following the correlation ID.
+func responseFrame(correlationID int32, res []byte) []byte {
+	frame := make([]byte, 8, 8+len(res))
+	binary.BigEndian.PutUint32(frame, uint32(4+len(res)))
+	binary.BigEndian.PutUint32(frame[4:], uint32(correlationID))
+	return append(frame, res...)
+}
@@ -0,0 +1,67 @@
+package sarama
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRecordingProxyReplay(t *testing.T) {
+	seed := NewMockBroker(t, 1)
+	seed.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(seed.Addr(), seed.BrokerID()).
+			SetLeader("my_topic", 0, seed.BrokerID()),
+		"ProduceRequest": NewMockProduceResponse(t).SetVersion(3),
+	})
+
+	produce := func(addr string) {
+		config := NewTestConfig()
+		config.Version = V2_0_0_0
+		config.Producer.Return.Successes = true
+		producer, err := NewSyncProducer([]string{addr}, config)
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer safeClose(t, producer)
+		if _, _, err := producer.SendMessage(&ProducerMessage{Topic: "my_topic", Value: StringEncoder("hello")}); err != nil {
+			t.Error(err)
+		}
+	}
+
+	proxy := NewRecordingProxy(t, seed.Addr())
+	produce(proxy.Addr())
+	proxy.Close()
+	seed.Close()
+
+	var saved bytes.Buffer
+	if err := proxy.Recording().Save(&saved); err != nil {
+		t.Fatal(err)
+	}
+	recording, err := LoadRecording(&saved)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var recordedProduce bool
+	for _, exchange := range recording.Exchanges {
+		if exchange.APIKey == (&ProduceRequest{}).key() && exchange.APIVersion == 3 {
+			recordedProduce = true
+		}
+	}
+	if !recordedProduce {
+		t.Fatalf("expected the produce request to be recorded, got %+v", recording.Exchanges)
+	}
+
+	// the producer talks to the replay broker only, the seed broker being gone
+	replay := NewReplayBroker(t, 1, recording)
+	defer replay.Close()
+	produce(replay.Addr())
+	var replayedProduce bool
+	for _, rr := range replay.History() {
+		if _, ok := rr.Request.(*ProduceRequest); ok {
+			replayedProduce = true
+		}
+	}
+	if !replayedProduce {
+		t.Error("expected the produce request to be replayed")
+	}
+}
@@ -1,7 +1,10 @@
 package sarama
 
 import (
+	"errors"
 	"fmt"
+	"math"
+	"sort"
 	"strings"
 	"sync"
 )
@@ -69,31 +72,67 @@ func (mc *MockSequence) For(reqBody versionedDecoder) (res encoderWithHeader) {
 }
 
 type MockListGroupsResponse struct {
-	groups map[string]string
-	t      TestReporter
+	groups     map[string]string
+	groupsData map[string]GroupData
+	t          TestReporter
 }
 
 func NewMockListGroupsResponse(t TestReporter) *MockListGroupsResponse {
 	return &MockListGroupsResponse{
-		groups: make(map[string]string),
-		t:      t,
+		groups:     make(map[string]string),
+		groupsData: make(map[string]GroupData),
+		t:          t,
 	}
 }
 
 func (m *MockListGroupsResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	request := reqBody.(*ListGroupsRequest)
-	_ = request
 	response := &ListGroupsResponse{
-		Groups: m.groups,
+		Version: request.Version,
+		Groups:  make(map[string]string),
+	}
+	if request.Version >= 4 {
+		response.GroupsData = make(map[string]GroupData)
+	}
+	for groupID, protocolType := range m.groups {
+		data := m.groupsData[groupID]
+		if !mockFilterMatches(request.StatesFilter, data.GroupState) ||
+			!mockFilterMatches(request.TypesFilter, data.GroupType) {
+			continue
+		}
+		response.Groups[groupID] = protocolType
+		if request.Version >= 4 {
+			response.GroupsData[groupID] = data
+		}
 	}
 	return response
 }
 
+func mockFilterMatches(filter []string, value string) bool {
+	if len(filter) == 0 {
+		return true
+	}
+	for _, f := range filter {
+		if strings.EqualFold(f, value) {
+			return true
+		}
+	}
+	return false
+}
+
 func (m *MockListGroupsResponse) AddGroup(groupID, protocolType string) *MockListGroupsResponse {
 	m.groups[groupID] = protocolType
 	return m
 }
 
+// AddGroupWithData adds a group reporting the given state and type to
+// ListGroups requests of version 4 and later.
+func (m *MockListGroupsResponse) AddGroupWithData(groupID, protocolType string, data GroupData) *MockListGroupsResponse {
+	m.groups[groupID] = protocolType
+	m.groupsData[groupID] = data
+	return m
+}
+
 type MockDescribeGroupsResponse struct {
 	groups map[string]*GroupDescription
 	t      TestReporter
@@ -114,7 +153,7 @@ func (m *MockDescribeGroupsResponse) AddGroupDescription(groupID string, descrip
 func (m *MockDescribeGroupsResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	request := reqBody.(*DescribeGroupsRequest)
 
-	response := &DescribeGroupsResponse{}
+	response := &DescribeGroupsResponse{Version: request.Version}
 	for _, requestedGroup := range request.Groups {
 		if group, ok := m.groups[requestedGroup]; ok {
 			response.Groups = append(response.Groups, group)
@@ -136,14 +175,16 @@ type MockMetadataResponse struct {
 	controllerID int32
 	leaders      map[string]map[int32]int32
 	brokers      map[string]int32
+	topicIDs     map[string]Uuid
 	t            TestReporter
 }
 
 func NewMockMetadataResponse(t TestReporter) *MockMetadataResponse {
 	return &MockMetadataResponse{
-		leaders: make(map[string]map[int32]int32),
-		brokers: make(map[string]int32),
-		t:       t,
+		leaders:  make(map[string]map[int32]int32),
+		brokers:  make(map[string]int32),
+		topicIDs: make(map[string]Uuid),
+		t:        t,
 	}
 }
 
@@ -162,6 +203,12 @@ func (mmr *MockMetadataResponse) SetBroker(addr string, brokerID int32) *MockMet
 	return mmr
 }
 
+// SetTopicID sets the ID of the topic, returned from version 10.
+func (mmr *MockMetadataResponse) SetTopicID(topic string, topicID Uuid) *MockMetadataResponse {
+	mmr.topicIDs[topic] = topicID
+	return mmr
+}
+
 func (mmr *MockMetadataResponse) SetController(brokerID int32) *MockMetadataResponse {
 	mmr.controllerID = brokerID
 	return mmr
@@ -190,6 +237,7 @@ func (mmr *MockMetadataResponse) For(reqBody versionedDecoder) encoderWithHeader
 				metadataResponse.AddTopicPartition(topic, partition, brokerID, replicas, replicas, offlineReplicas, ErrNoError)
 			}
 		}
+		mmr.setTopicIDs(metadataResponse)
 		return metadataResponse
 	}
 	for _, topic := range metadataRequest.Topics {
@@ -197,14 +245,22 @@ func (mmr *MockMetadataResponse) For(reqBody versionedDecoder) encoderWithHeader
 			metadataResponse.AddTopicPartition(topic, partition, brokerID, replicas, replicas, offlineReplicas, ErrNoError)
 		}
 	}
+	mmr.setTopicIDs(metadataResponse)
 	return metadataResponse
 }
 
+func (mmr *MockMetadataResponse) setTopicIDs(metadataResponse *MetadataResponse) {
+	for _, topic := range metadataResponse.Topics {
+		topic.TopicID = mmr.topicIDs[topic.Name]
+	}
+}
+
 // MockOffsetResponse is an `OffsetResponse` builder.
 type MockOffsetResponse struct {
-	offsets map[string]map[int32]map[int64]int64
-	t       TestReporter
-	version int16
+	offsets    map[string]map[int32]map[int64]int64
+	t          TestReporter
+	version    int16
+	versionSet bool
 }
 
 func NewMockOffsetResponse(t TestReporter) *MockOffsetResponse {
@@ -214,8 +270,11 @@ func NewMockOffsetResponse(t TestReporter) *MockOffsetResponse {
 	}
 }
 
+// SetVersion sets the version of the responses, that of the requests by
+// default.
 func (mor *MockOffsetResponse) SetVersion(version int16) *MockOffsetResponse {
 	mor.version = version
+	mor.versionSet = true
 	return mor
 }
 
@@ -236,7 +295,10 @@ func (mor *MockOffsetResponse) SetOffset(topic string, partition int32, time, of
 
 func (mor *MockOffsetResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	offsetRequest := reqBody.(*OffsetRequest)
-	offsetResponse := &OffsetResponse{Version: mor.version}
+	offsetResponse := &OffsetResponse{Version: offsetRequest.Version}
+	if mor.versionSet {
+		offsetResponse.Version = mor.version
+	}
 	for topic, partitions := range offsetRequest.blocks {
 		for partition, block := range partitions {
 			offset := mor.getOffset(topic, partition, block.time)
@@ -270,6 +332,7 @@ type MockFetchResponse struct {
 	t              TestReporter
 	batchSize      int
 	version        int16
+	versionSet     bool
 }
 
 func NewMockFetchResponse(t TestReporter, batchSize int) *MockFetchResponse {
@@ -282,8 +345,11 @@ func NewMockFetchResponse(t TestReporter, batchSize int) *MockFetchResponse {
 	}
 }
 
+// SetVersion sets the version of the responses, that of the requests by
+// default.
 func (mfr *MockFetchResponse) SetVersion(version int16) *MockFetchResponse {
 	mfr.version = version
+	mfr.versionSet = true
 	return mfr
 }
 
@@ -317,7 +383,10 @@ func (mfr *MockFetchResponse) SetHighWaterMark(topic string, partition int32, of
 func (mfr *MockFetchResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	fetchRequest := reqBody.(*FetchRequest)
 	res := &FetchResponse{
-		Version: mfr.version,
+		Version: fetchRequest.Version,
+	}
+	if mfr.versionSet {
+		res.Version = mfr.version
 	}
 	for topic, partitions := range fetchRequest.blocks {
 		for partition, block := range partitions {
@@ -453,7 +522,7 @@ func (mr *MockFindCoordinatorResponse) SetError(coordinatorType CoordinatorType,
 
 func (mr *MockFindCoordinatorResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*FindCoordinatorRequest)
-	res := &FindCoordinatorResponse{}
+	res := &FindCoordinatorResponse{Version: req.Version}
 	var v interface{}
 	switch req.CoordinatorType {
 	case CoordinatorGroup:
@@ -501,7 +570,7 @@ func (mr *MockOffsetCommitResponse) SetError(group, topic string, partition int3
 func (mr *MockOffsetCommitResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*OffsetCommitRequest)
 	group := req.ConsumerGroup
-	res := &OffsetCommitResponse{}
+	res := &OffsetCommitResponse{Version: req.Version}
 	for topic, partitions := range req.blocks {
 		for partition := range partitions {
 			res.AddError(topic, partition, mr.getError(group, topic, partition))
@@ -528,17 +597,21 @@ func (mr *MockOffsetCommitResponse) getError(group, topic string, partition int3
 
 // MockProduceResponse is a `ProduceResponse` builder.
 type MockProduceResponse struct {
-	version int16
-	errors  map[string]map[int32]KError
-	t       TestReporter
+	version    int16
+	versionSet bool
+	errors     map[string]map[int32]KError
+	t          TestReporter
 }
 
 func NewMockProduceResponse(t TestReporter) *MockProduceResponse {
 	return &MockProduceResponse{t: t}
 }
 
+// SetVersion sets the version of the responses, that of the requests by
+// default.
 func (mr *MockProduceResponse) SetVersion(version int16) *MockProduceResponse {
 	mr.version = version
+	mr.versionSet = true
 	return mr
 }
 
@@ -558,7 +631,10 @@ func (mr *MockProduceResponse) SetError(topic string, partition int32, kerror KE
 func (mr *MockProduceResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*ProduceRequest)
 	res := &ProduceResponse{
-		Version: mr.version,
+		Version: req.Version,
+	}
+	if mr.versionSet {
+		res.Version = mr.version
 	}
 	for topic, partitions := range req.records {
 		for partition := range partitions {
@@ -657,6 +733,28 @@ func (mr *MockCreateTopicsResponse) For(reqBody versionedDecoder) encoderWithHea
 		}
 		res.TopicErrors[topic] = &TopicError{Err: ErrNoError}
 	}
+
+	if res.Version >= 5 {
+		res.TopicDetails = make(map[string]*CreatedTopicDetail)
+		for topic, detail := range req.TopicDetails {
+			if !errors.Is(res.TopicErrors[topic].Err, ErrNoError) {
+				continue
+			}
+			created := &CreatedTopicDetail{
+				NumPartitions:     detail.NumPartitions,
+				ReplicationFactor: detail.ReplicationFactor,
+				Configs:           []*CreatedTopicConfig{},
+			}
+			for name, value := range detail.ConfigEntries {
+				created.Configs = append(created.Configs, &CreatedTopicConfig{
+					Name:   name,
+					Value:  value,
+					Source: SourceTopic,
+				})
+			}
+			res.TopicDetails[topic] = created
+		}
+	}
 	return res
 }
 
@@ -690,7 +788,7 @@ func NewMockCreatePartitionsResponse(t TestReporter) *MockCreatePartitionsRespon
 
 func (mr *MockCreatePartitionsResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*CreatePartitionsRequest)
-	res := &CreatePartitionsResponse{}
+	res := &CreatePartitionsResponse{Version: req.Version}
 	res.TopicPartitionErrors = make(map[string]*TopicPartitionError)
 
 	for topic := range req.TopicPartitions {
@@ -717,8 +815,7 @@ func NewMockAlterPartitionReassignmentsResponse(t TestReporter) *MockAlterPartit
 
 func (mr *MockAlterPartitionReassignmentsResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*AlterPartitionReassignmentsRequest)
-	_ = req
-	res := &AlterPartitionReassignmentsResponse{}
+	res := &AlterPartitionReassignmentsResponse{Version: req.Version}
 	return res
 }
 
@@ -732,8 +829,7 @@ func NewMockListPartitionReassignmentsResponse(t TestReporter) *MockListPartitio
 
 func (mr *MockListPartitionReassignmentsResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*ListPartitionReassignmentsRequest)
-	_ = req
-	res := &ListPartitionReassignmentsResponse{}
+	res := &ListPartitionReassignmentsResponse{Version: req.Version}
 
 	for topic, partitions := range req.blocks {
 		for _, partition := range partitions {
@@ -744,6 +840,116 @@ func (mr *MockListPartitionReassignmentsResponse) For(reqBody versionedDecoder)
 	return res
 }
 
+type MockElectLeadersResponse struct {
+	t TestReporter
+}
+
+func NewMockElectLeadersResponse(t TestReporter) *MockElectLeadersResponse {
+	return &MockElectLeadersResponse{t: t}
+}
+
+func (mr *MockElectLeadersResponse) For(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*ElectLeadersRequest)
+	res := &ElectLeadersResponse{
+		Version:                req.Version,
+		ReplicaElectionResults: make(map[string]map[int32]*PartitionResult),
+	}
+
+	for topic, partitions := range req.TopicPartitions {
+		res.ReplicaElectionResults[topic] = make(map[int32]*PartitionResult)
+		for _, partition := range partitions {
+			res.ReplicaElectionResults[topic][partition] = &PartitionResult{ErrorCode: ErrNoError}
+		}
+	}
+
+	return res
+}
+
+// MockDescribeTopicPartitionsResponse is a `DescribeTopicPartitionsResponse`
+// builder which honours the partition limit and cursor of the requests, so
+// that clients paginate through the topics set on it.
+type MockDescribeTopicPartitionsResponse struct {
+	t          TestReporter
+	partitions map[string]int32
+	replicas   map[string][]int32
+}
+
+func NewMockDescribeTopicPartitionsResponse(t TestReporter) *MockDescribeTopicPartitionsResponse {
+	return &MockDescribeTopicPartitionsResponse{
+		t:          t,
+		partitions: make(map[string]int32),
+		replicas:   make(map[string][]int32),
+	}
+}
+
+// SetTopic sets the number of partitions of topic, each of them replicated
+// on replicas and led by the first one, broker 0 by default.
+func (mr *MockDescribeTopicPartitionsResponse) SetTopic(topic string, partitions int32, replicas ...int32) *MockDescribeTopicPartitionsResponse {
+	if len(replicas) == 0 {
+		replicas = []int32{0}
+	}
+	mr.partitions[topic] = partitions
+	mr.replicas[topic] = replicas
+	return mr
+}
+
+func (mr *MockDescribeTopicPartitionsResponse) For(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*DescribeTopicPartitionsRequest)
+	res := &DescribeTopicPartitionsResponse{Version: req.Version}
+
+	var names []string
+	if len(req.Topics) == 0 {
+		for name := range mr.partitions {
+			names = append(names, name)
+		}
+	} else {
+		names = append(names, req.Topics...)
+	}
+	sort.Strings(names)
+
+	count := int32(0)
+	for _, name := range names {
+		first := int32(0)
+		if req.Cursor != nil {
+			if name < req.Cursor.TopicName {
+				continue
+			}
+			if name == req.Cursor.TopicName {
+				first = req.Cursor.PartitionIndex
+			}
+		}
+
+		partitions, ok := mr.partitions[name]
+		if !ok {
+			res.Topics = append(res.Topics, &DescribeTopicPartitionsResponseTopic{Err: ErrUnknownTopicOrPartition, Name: name})
+			continue
+		}
+		if req.ResponsePartitionLimit > 0 && count == req.ResponsePartitionLimit && first < partitions {
+			res.NextCursor = &DescribeTopicPartitionsCursor{TopicName: name, PartitionIndex: first}
+			return res
+		}
+
+		topic := &DescribeTopicPartitionsResponseTopic{Err: ErrNoError, Name: name}
+		res.Topics = append(res.Topics, topic)
+		replicas := mr.replicas[name]
+		for partition := first; partition < partitions; partition++ {
+			if req.ResponsePartitionLimit > 0 && count == req.ResponsePartitionLimit {
+				res.NextCursor = &DescribeTopicPartitionsCursor{TopicName: name, PartitionIndex: partition}
+				return res
+			}
+			topic.Partitions = append(topic.Partitions, &DescribeTopicPartitionsResponsePartition{
+				Err:      ErrNoError,
+				ID:       partition,
+				Leader:   replicas[0],
+				Replicas: replicas,
+				Isr:      replicas,
+			})
+			count++
+		}
+	}
+	return res
+}
+
 type MockDeleteRecordsResponse struct {
 	t TestReporter
 }
@@ -754,7 +960,7 @@ func NewMockDeleteRecordsResponse(t TestReporter) *MockDeleteRecordsResponse {
 
 func (mr *MockDeleteRecordsResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*DeleteRecordsRequest)
-	res := &DeleteRecordsResponse{}
+	res := &DeleteRecordsResponse{Version: req.Version}
 	res.Topics = make(map[string]*DeleteRecordsResponseTopic)
 
 	for topic, deleteRecordRequestTopic := range req.Topics {
@@ -900,7 +1106,7 @@ func NewMockAlterConfigsResponse(t TestReporter) *MockAlterConfigsResponse {
 
 func (mr *MockAlterConfigsResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*AlterConfigsRequest)
-	res := &AlterConfigsResponse{}
+	res := &AlterConfigsResponse{Version: req.Version}
 
 	for _, r := range req.Resources {
 		res.Resources = append(res.Resources, &AlterConfigsResourceResponse{
@@ -922,7 +1128,7 @@ func NewMockAlterConfigsResponseWithErrorCode(t TestReporter) *MockAlterConfigsR
 
 func (mr *MockAlterConfigsResponseWithErrorCode) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*AlterConfigsRequest)
-	res := &AlterConfigsResponse{}
+	res := &AlterConfigsResponse{Version: req.Version}
 
 	for _, r := range req.Resources {
 		res.Resources = append(res.Resources, &AlterConfigsResourceResponse{
@@ -945,7 +1151,7 @@ func NewMockIncrementalAlterConfigsResponse(t TestReporter) *MockIncrementalAlte
 
 func (mr *MockIncrementalAlterConfigsResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*IncrementalAlterConfigsRequest)
-	res := &IncrementalAlterConfigsResponse{}
+	res := &IncrementalAlterConfigsResponse{Version: req.Version}
 
 	for _, r := range req.Resources {
 		res.Resources = append(res.Resources, &AlterConfigsResourceResponse{
@@ -967,7 +1173,7 @@ func NewMockIncrementalAlterConfigsResponseWithErrorCode(t TestReporter) *MockIn
 
 func (mr *MockIncrementalAlterConfigsResponseWithErrorCode) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*IncrementalAlterConfigsRequest)
-	res := &IncrementalAlterConfigsResponse{}
+	res := &IncrementalAlterConfigsResponse{Version: req.Version}
 
 	for _, r := range req.Resources {
 		res.Resources = append(res.Resources, &AlterConfigsResourceResponse{
@@ -990,7 +1196,7 @@ func NewMockCreateAclsResponse(t TestReporter) *MockCreateAclsResponse {
 
 func (mr *MockCreateAclsResponse) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*CreateAclsRequest)
-	res := &CreateAclsResponse{}
+	res := &CreateAclsResponse{Version: req.Version}
 
 	for range req.AclCreations {
 		res.AclCreationResponses = append(res.AclCreationResponses, &AclCreationResponse{Err: ErrNoError})
@@ -1008,7 +1214,7 @@ func NewMockCreateAclsResponseWithError(t TestReporter) *MockCreateAclsResponseE
 
 func (mr *MockCreateAclsResponseError) For(reqBody versionedDecoder) encoderWithHeader {
 	req := reqBody.(*CreateAclsRequest)
-	res := &CreateAclsResponse{}
+	res := &CreateAclsResponse{Version: req.Version}
 
 	for range req.AclCreations {
 		res.AclCreationResponses = append(res.AclCreationResponses, &AclCreationResponse{Err: ErrInvalidRequest})
@@ -1057,9 +1263,10 @@ func (mr *MockListAclsResponse) For(reqBody versionedDecoder) encoderWithHeader
 }
 
 type MockSaslAuthenticateResponse struct {
-	t             TestReporter
-	kerror        KError
-	saslAuthBytes []byte
+	t                 TestReporter
+	kerror            KError
+	saslAuthBytes     []byte
+	sessionLifetimeMs int64
 }
 
 func NewMockSaslAuthenticateResponse(t TestReporter) *MockSaslAuthenticateResponse {
@@ -1067,9 +1274,11 @@ func NewMockSaslAuthenticateResponse(t TestReporter) *MockSaslAuthenticateRespon
 }
 
 func (msar *MockSaslAuthenticateResponse) For(reqBody versionedDecoder) encoderWithHeader {
-	res := &SaslAuthenticateResponse{}
+	req := reqBody.(*SaslAuthenticateRequest)
+	res := &SaslAuthenticateResponse{Version: req.Version}
 	res.Err = msar.kerror
 	res.SaslAuthBytes = msar.saslAuthBytes
+	res.SessionLifetimeMs = msar.sessionLifetimeMs
 	return res
 }
 
@@ -1083,6 +1292,11 @@ func (msar *MockSaslAuthenticateResponse) SetAuthBytes(saslAuthBytes []byte) *Mo
 	return msar
 }
 
+func (msar *MockSaslAuthenticateResponse) SetSessionLifetimeMs(sessionLifetimeMs int64) *MockSaslAuthenticateResponse {
+	msar.sessionLifetimeMs = sessionLifetimeMs
+	return msar
+}
+
 type MockDeleteAclsResponse struct {
 	t TestReporter
 }
@@ -1145,7 +1359,9 @@ func (m *MockDeleteGroupsResponse) SetDeletedGroups(groups []string) *MockDelete
 }
 
 func (m *MockDeleteGroupsResponse) For(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*DeleteGroupsRequest)
 	resp := &DeleteGroupsResponse{
+		Version:         req.Version,
 		GroupErrorCodes: map[string]KError{},
 	}
 	for _, group := range m.deletedGroups {
@@ -1267,8 +1483,10 @@ func NewMockLeaveGroupResponse(t TestReporter) *MockLeaveGroupResponse {
 }
 
 func (m *MockLeaveGroupResponse) For(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*LeaveGroupRequest)
 	resp := &LeaveGroupResponse{
-		Err: m.Err,
+		Version: req.Version,
+		Err:     m.Err,
 	}
 	return resp
 }
@@ -1290,7 +1508,9 @@ func NewMockSyncGroupResponse(t TestReporter) *MockSyncGroupResponse {
 }
 
 func (m *MockSyncGroupResponse) For(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*SyncGroupRequest)
 	resp := &SyncGroupResponse{
+		Version:          req.Version,
 		Err:              m.Err,
 		MemberAssignment: m.MemberAssignment,
 	}
@@ -1322,7 +1542,8 @@ func NewMockHeartbeatResponse(t TestReporter) *MockHeartbeatResponse {
 }
 
 func (m *MockHeartbeatResponse) For(reqBody versionedDecoder) encoderWithHeader {
-	resp := &HeartbeatResponse{}
+	req := reqBody.(*HeartbeatRequest)
+	resp := &HeartbeatResponse{Version: req.Version, Err: m.Err}
 	return resp
 }
 
@@ -1367,7 +1588,9 @@ func (m *MockDescribeLogDirsResponse) SetLogDirs(logDirPath string, topicPartiti
 }
 
 func (m *MockDescribeLogDirsResponse) For(reqBody versionedDecoder) encoderWithHeader {
+	req := reqBody.(*DescribeLogDirsRequest)
 	resp := &DescribeLogDirsResponse{
+		Version: req.Version,
 		LogDirs: m.logDirs,
 	}
 	return resp
@@ -1378,22 +1601,36 @@ type MockApiVersionsResponse struct {
 	apiKeys []ApiVersionsResponseKey
 }
 
+// NewMockApiVersionsResponse returns a MockApiVersionsResponse advertising the
+// versions 5 to 9 of Produce, 7 to 12 of Fetch, and any version of the other
+// APIs Sarama implements, as the broker rejects the requests of the versions it
+// does not advertise.
 func NewMockApiVersionsResponse(t TestReporter) *MockApiVersionsResponse {
-	return &MockApiVersionsResponse{
-		t: t,
-		apiKeys: []ApiVersionsResponseKey{
-			{
-				ApiKey:     0,
-				MinVersion: 5,
-				MaxVersion: 8,
-			},
-			{
-				ApiKey:     1,
-				MinVersion: 7,
-				MaxVersion: 11,
-			},
+	apiKeys := []ApiVersionsResponseKey{
+		{
+			ApiKey:     0,
+			MinVersion: 5,
+			MaxVersion: 9,
+		},
+		{
+			ApiKey:     1,
+			MinVersion: 7,
+			MaxVersion: 12,
 		},
 	}
+	for key := range apiNames {
+		if key > 1 {
+			apiKeys = append(apiKeys, ApiVersionsResponseKey{ApiKey: key, MaxVersion: math.MaxInt16})
+		}
+	}
+	sort.Slice(apiKeys, func(i, j int) bool {
+		return apiKeys[i].ApiKey < apiKeys[j].ApiKey
+	})
+
+	return &MockApiVersionsResponse{
+		t:       t,
+		apiKeys: apiKeys,
+	}
 }
 
 func (m *MockApiVersionsResponse) SetApiKeys(apiKeys []ApiVersionsResponseKey) *MockApiVersionsResponse {
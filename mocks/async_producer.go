@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"errors"
 	"sync"
 
@@ -21,7 +22,8 @@ type AsyncProducer struct {
 	input        chan *sarama.ProducerMessage
 	successes    chan *sarama.ProducerMessage
 	errors       chan *sarama.ProducerError
-	lastOffset   int64
+	offsets      offsetCounter
+	messages     []*sarama.ProducerMessage
 	*TopicConfig
 }
 
@@ -59,6 +61,7 @@ func NewAsyncProducer(t ErrorReporter, config *sarama.Config) *AsyncProducer {
 				partitioners[msg.Topic] = partitioner
 			}
 			mp.l.Lock()
+			mp.messages = append(mp.messages, msg)
 			if mp.expectations == nil || len(mp.expectations) == 0 {
 				mp.expectations = nil
 				mp.t.Errorf("No more expectation set on this mock producer to handle the input message.")
@@ -80,9 +83,9 @@ func NewAsyncProducer(t ErrorReporter, config *sarama.Config) *AsyncProducer {
 						}
 					}
 					if errors.Is(expectation.Result, errProduceSuccess) {
-						mp.lastOffset++
+						offset := mp.offsets.next(msg.Topic, partition)
 						if config.Producer.Return.Successes {
-							msg.Offset = mp.lastOffset
+							msg.Offset = offset
 							mp.successes <- msg
 						}
 					} else {
@@ -125,6 +128,18 @@ func (mp *AsyncProducer) Close() error {
 	return nil
 }
 
+// CloseContext corresponds with the CloseContext method of sarama's Producer implementation.
+// It behaves as Close, returning the error of ctx if it is done first.
+func (mp *AsyncProducer) CloseContext(ctx context.Context) error {
+	mp.AsyncClose()
+	select {
+	case <-mp.closed:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
 // Input corresponds with the Input method of sarama's Producer implementation.
 // You have to set expectations on the mock producer before writing messages to the Input
 // channel, so it knows how to handle them. If there is no more remaining expectations and
@@ -144,6 +159,34 @@ func (mp *AsyncProducer) Errors() <-chan *sarama.ProducerError {
 	return mp.errors
 }
 
+// DebugState corresponds with the DebugState method of sarama's Producer implementation.
+// It reports the messages queued on the Input channel as pending, the mock producer having
+// no partitions, brokers nor client.
+func (mp *AsyncProducer) DebugState() sarama.ProducerDebugState {
+	return sarama.ProducerDebugState{Pending: len(mp.input)}
+}
+
+// SetNextOffset sets the offset of the next message produced successfully to
+// topic/partition. The mock producer then assigns offsets per partition, counting
+// from 0 for the partitions not set, instead of from a single counter shared by
+// all the partitions, starting at 1. The partitions are those of the partitioner
+// of the config the mock producer was created with.
+func (mp *AsyncProducer) SetNextOffset(topic string, partition int32, offset int64) *AsyncProducer {
+	mp.l.Lock()
+	defer mp.l.Unlock()
+	mp.offsets.setNext(topic, partition, offset)
+	return mp
+}
+
+// Messages returns the messages provided on the input channel and handled by the
+// mock producer so far, in order, whether they met an expectation or not, for
+// assertions on them.
+func (mp *AsyncProducer) Messages() []*sarama.ProducerMessage {
+	mp.l.Lock()
+	defer mp.l.Unlock()
+	return append([]*sarama.ProducerMessage(nil), mp.messages...)
+}
+
 ////////////////////////////////////////////////
 // Setting expectations
 ////////////////////////////////////////////////
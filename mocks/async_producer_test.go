@@ -171,3 +171,51 @@ func (brokePartitioner) Partition(msg *sarama.ProducerMessage, n int32) (int32,
 }
 
 func (brokePartitioner) RequiresConsistency() bool { return false }
+
+func TestProducerWithMatchersRecordsMessages(t *testing.T) {
+	trm := newTestReporterMock()
+	mp := NewAsyncProducer(trm, nil).
+		ExpectInputWithMessageCheckerFunctionAndSucceed(MatchAll(MatchTopic("test"), MatchValue([]byte("test")))).
+		ExpectInputWithMessageCheckerFunctionAndSucceed(MatchHeader([]byte("trace"), []byte("1")))
+
+	mp.Input() <- &sarama.ProducerMessage{Topic: "test", Value: sarama.StringEncoder("test")}
+	mp.Input() <- &sarama.ProducerMessage{Topic: "test", Value: sarama.StringEncoder("test")}
+	if err := mp.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if len(mp.Errors()) != 1 {
+		t.Error("Expected to report an error")
+	}
+
+	err1 := <-mp.Errors()
+	if !strings.HasPrefix(err1.Err.Error(), "Expected message header") {
+		t.Error("Expected to report a header check error, found: ", err1.Err)
+	}
+	if messages := mp.Messages(); len(messages) != 2 {
+		t.Errorf("Expected the 2 messages provided to be recorded, found %v.", messages)
+	}
+}
+
+func TestProducerAssignsOffsetsPerPartition(t *testing.T) {
+	config := NewTestConfig()
+	config.Producer.Return.Successes = true
+	config.Producer.Partitioner = sarama.NewManualPartitioner
+	mp := NewAsyncProducer(t, config).SetNextOffset("test", 1, 42)
+	mp.ExpectInputAndSucceed()
+	mp.ExpectInputAndSucceed()
+	mp.ExpectInputAndSucceed()
+
+	mp.Input() <- &sarama.ProducerMessage{Topic: "test", Partition: 1}
+	mp.Input() <- &sarama.ProducerMessage{Topic: "test", Partition: 0}
+	mp.Input() <- &sarama.ProducerMessage{Topic: "test", Partition: 1}
+	for _, expected := range []int64{42, 0, 43} {
+		if msg := <-mp.Successes(); msg.Offset != expected {
+			t.Errorf("Expected offset %d, found %d on partition %d.", expected, msg.Offset, msg.Partition)
+		}
+	}
+
+	if err := mp.Close(); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,392 @@
+package mocks
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// clusterAdminType is the sarama.ClusterAdmin interface, to validate the
+// expectations against the signatures of its methods.
+var clusterAdminType = reflect.TypeOf((*sarama.ClusterAdmin)(nil)).Elem()
+
+// ClusterAdmin implements sarama's ClusterAdmin interface for testing purposes.
+// Before you can use it, you have to set expectations on the mock ClusterAdmin
+// to tell it which calls to expect, in order, and what to return from them, so
+// you can easily test your admin workflows in success and failure scenarios.
+type ClusterAdmin struct {
+	l            sync.Mutex
+	t            ErrorReporter
+	expectations []*AdminExpectation
+}
+
+// AdminExpectation is an expectation set on a mock ClusterAdmin with Expect.
+type AdminExpectation struct {
+	t       ErrorReporter
+	method  reflect.Method
+	args    []interface{}
+	checker func(args []interface{}) error
+	results []interface{}
+}
+
+// NewClusterAdmin instantiates a new ClusterAdmin mock. The t argument should
+// be the *testing.T instance of your test method. An error will be written to it if
+// an expectation is violated.
+func NewClusterAdmin(t ErrorReporter) *ClusterAdmin {
+	return &ClusterAdmin{t: t}
+}
+
+////////////////////////////////////////////////
+// Setting expectations
+////////////////////////////////////////////////
+
+// Expect sets an expectation on the mock ClusterAdmin that method, the name of
+// a method of the sarama.ClusterAdmin interface, is the next one called. The
+// call returns the zero values of the results of method unless Return is called
+// on the expectation. WithContext and Close take no expectation.
+func (ca *ClusterAdmin) Expect(method string) *AdminExpectation {
+	m, ok := clusterAdminType.MethodByName(method)
+	if !ok || method == "WithContext" || method == "Close" {
+		ca.t.Errorf("%s is not a method of sarama.ClusterAdmin expectations can be set on.", method)
+		ok = false
+	}
+	expectation := &AdminExpectation{t: ca.t, method: m}
+	if ok {
+		expectation.results = make([]interface{}, m.Type.NumOut())
+	}
+
+	ca.l.Lock()
+	defer ca.l.Unlock()
+	ca.expectations = append(ca.expectations, expectation)
+	return expectation
+}
+
+// WithArgs makes the expectation check that the method is called with args,
+// compared with reflect.DeepEqual.
+func (e *AdminExpectation) WithArgs(args ...interface{}) *AdminExpectation {
+	if e.method.Type != nil && len(args) != e.method.Type.NumIn() {
+		e.t.Errorf("%s takes %d arguments, %d were expected.", e.method.Name, e.method.Type.NumIn(), len(args))
+	}
+	e.args = args
+	return e
+}
+
+// WithChecker makes the expectation pass the arguments of the call to checker,
+// which fails the call by returning an error.
+func (e *AdminExpectation) WithChecker(checker func(args []interface{}) error) *AdminExpectation {
+	e.checker = checker
+	return e
+}
+
+// Return sets the results returned by the call, which must match the results
+// of the method in number and types, nil standing for zero values.
+func (e *AdminExpectation) Return(results ...interface{}) *AdminExpectation {
+	if e.method.Type == nil {
+		return e
+	}
+	if len(results) != e.method.Type.NumOut() {
+		e.t.Errorf("%s returns %d results, %d were set.", e.method.Name, e.method.Type.NumOut(), len(results))
+		return e
+	}
+	for i, result := range results {
+		if result != nil && !reflect.TypeOf(result).AssignableTo(e.method.Type.Out(i)) {
+			e.t.Errorf("Result %d of %s is a %s, %T was set.", i, e.method.Name, e.method.Type.Out(i), result)
+			return e
+		}
+	}
+	e.results = results
+	return e
+}
+
+// call consumes the next expectation for a call of method with args, returning
+// the results of the call.
+func (ca *ClusterAdmin) call(method string, args ...interface{}) []interface{} {
+	ca.l.Lock()
+	defer ca.l.Unlock()
+
+	m, _ := clusterAdminType.MethodByName(method)
+	failed := make([]interface{}, m.Type.NumOut())
+	failed[len(failed)-1] = errOutOfExpectations
+
+	if len(ca.expectations) == 0 {
+		ca.t.Errorf("No more expectation set on this mock cluster admin to handle the call to %s.", method)
+		return failed
+	}
+	expectation := ca.expectations[0]
+	ca.expectations = ca.expectations[1:]
+
+	if expectation.method.Name != method {
+		ca.t.Errorf("Expected a call to %s, got a call to %s.", expectation.method.Name, method)
+		return failed
+	}
+	if expectation.args != nil && !reflect.DeepEqual(expectation.args, args) {
+		ca.t.Errorf("Expected %s to be called with %v, got %v.", method, expectation.args, args)
+		failed[len(failed)-1] = fmt.Errorf("unexpected arguments to %s", method)
+		return failed
+	}
+	if expectation.checker != nil {
+		if err := expectation.checker(args); err != nil {
+			ca.t.Errorf("Check function returned an error: %s", err.Error())
+			failed[len(failed)-1] = err
+			return failed
+		}
+	}
+	return expectation.results
+}
+
+// resultError returns the result i of results as an error.
+func resultError(results []interface{}, i int) error {
+	err, _ := results[i].(error)
+	return err
+}
+
+////////////////////////////////////////////////
+// Implement ClusterAdmin interface
+////////////////////////////////////////////////
+
+// CreateTopic implements the CreateTopic method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) CreateTopic(topic string, detail *sarama.TopicDetail, validateOnly bool) error {
+	res := ca.call("CreateTopic", topic, detail, validateOnly)
+	return resultError(res, 0)
+}
+
+// CreateTopics implements the CreateTopics method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) CreateTopics(topics map[string]*sarama.TopicDetail, validateOnly bool) (map[string]*sarama.CreateTopicResult, error) {
+	res := ca.call("CreateTopics", topics, validateOnly)
+	result, _ := res[0].(map[string]*sarama.CreateTopicResult)
+	return result, resultError(res, 1)
+}
+
+// ListTopics implements the ListTopics method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) ListTopics() (map[string]sarama.TopicDetail, error) {
+	res := ca.call("ListTopics")
+	result, _ := res[0].(map[string]sarama.TopicDetail)
+	return result, resultError(res, 1)
+}
+
+// DescribeTopics implements the DescribeTopics method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DescribeTopics(topics []string) ([]*sarama.TopicMetadata, error) {
+	res := ca.call("DescribeTopics", topics)
+	result, _ := res[0].([]*sarama.TopicMetadata)
+	return result, resultError(res, 1)
+}
+
+// DescribeTopicPartitions implements the DescribeTopicPartitions method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DescribeTopicPartitions(topics []string) ([]*sarama.DescribeTopicPartitionsResponseTopic, error) {
+	res := ca.call("DescribeTopicPartitions", topics)
+	result, _ := res[0].([]*sarama.DescribeTopicPartitionsResponseTopic)
+	return result, resultError(res, 1)
+}
+
+// DeleteTopic implements the DeleteTopic method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DeleteTopic(topic string) error {
+	res := ca.call("DeleteTopic", topic)
+	return resultError(res, 0)
+}
+
+// CreatePartitions implements the CreatePartitions method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) CreatePartitions(topic string, count int32, assignment [][]int32, validateOnly bool) error {
+	res := ca.call("CreatePartitions", topic, count, assignment, validateOnly)
+	return resultError(res, 0)
+}
+
+// AlterPartitionReassignments implements the AlterPartitionReassignments method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) AlterPartitionReassignments(topic string, assignment [][]int32) error {
+	res := ca.call("AlterPartitionReassignments", topic, assignment)
+	return resultError(res, 0)
+}
+
+// ListPartitionReassignments implements the ListPartitionReassignments method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) ListPartitionReassignments(topics string, partitions []int32) (map[string]map[int32]*sarama.PartitionReplicaReassignmentsStatus, error) {
+	res := ca.call("ListPartitionReassignments", topics, partitions)
+	result, _ := res[0].(map[string]map[int32]*sarama.PartitionReplicaReassignmentsStatus)
+	return result, resultError(res, 1)
+}
+
+// ElectLeaders implements the ElectLeaders method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) ElectLeaders(electionType sarama.ElectionType, partitions map[string][]int32) (map[string]map[int32]*sarama.PartitionResult, error) {
+	res := ca.call("ElectLeaders", electionType, partitions)
+	result, _ := res[0].(map[string]map[int32]*sarama.PartitionResult)
+	return result, resultError(res, 1)
+}
+
+// DeleteRecords implements the DeleteRecords method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DeleteRecords(topic string, partitionOffsets map[int32]int64) error {
+	res := ca.call("DeleteRecords", topic, partitionOffsets)
+	return resultError(res, 0)
+}
+
+// DescribeConfig implements the DescribeConfig method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DescribeConfig(resource sarama.ConfigResource) ([]sarama.ConfigEntry, error) {
+	res := ca.call("DescribeConfig", resource)
+	result, _ := res[0].([]sarama.ConfigEntry)
+	return result, resultError(res, 1)
+}
+
+// AlterConfig implements the AlterConfig method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) AlterConfig(resourceType sarama.ConfigResourceType, name string, entries map[string]*string, validateOnly bool) error {
+	res := ca.call("AlterConfig", resourceType, name, entries, validateOnly)
+	return resultError(res, 0)
+}
+
+// IncrementalAlterConfig implements the IncrementalAlterConfig method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) IncrementalAlterConfig(resourceType sarama.ConfigResourceType, name string, entries map[string]sarama.IncrementalAlterConfigsEntry, validateOnly bool) error {
+	res := ca.call("IncrementalAlterConfig", resourceType, name, entries, validateOnly)
+	return resultError(res, 0)
+}
+
+// CreateACL implements the CreateACL method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) CreateACL(resource sarama.Resource, acl sarama.Acl) error {
+	res := ca.call("CreateACL", resource, acl)
+	return resultError(res, 0)
+}
+
+// CreateACLs implements the CreateACLs method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) CreateACLs(resourceACLs []*sarama.ResourceAcls) error {
+	res := ca.call("CreateACLs", resourceACLs)
+	return resultError(res, 0)
+}
+
+// ListAcls implements the ListAcls method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) ListAcls(filter sarama.AclFilter) ([]sarama.ResourceAcls, error) {
+	res := ca.call("ListAcls", filter)
+	result, _ := res[0].([]sarama.ResourceAcls)
+	return result, resultError(res, 1)
+}
+
+// DeleteACL implements the DeleteACL method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DeleteACL(filter sarama.AclFilter, validateOnly bool) ([]sarama.MatchingAcl, error) {
+	res := ca.call("DeleteACL", filter, validateOnly)
+	result, _ := res[0].([]sarama.MatchingAcl)
+	return result, resultError(res, 1)
+}
+
+// ListConsumerGroups implements the ListConsumerGroups method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) ListConsumerGroups() (map[string]string, error) {
+	res := ca.call("ListConsumerGroups")
+	result, _ := res[0].(map[string]string)
+	return result, resultError(res, 1)
+}
+
+// ListConsumerGroupsWithOptions implements the ListConsumerGroupsWithOptions method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) ListConsumerGroupsWithOptions(opts sarama.ListConsumerGroupsOptions) (map[string]sarama.ConsumerGroupListing, error) {
+	res := ca.call("ListConsumerGroupsWithOptions", opts)
+	result, _ := res[0].(map[string]sarama.ConsumerGroupListing)
+	return result, resultError(res, 1)
+}
+
+// DescribeConsumerGroups implements the DescribeConsumerGroups method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DescribeConsumerGroups(groups []string) ([]*sarama.GroupDescription, error) {
+	res := ca.call("DescribeConsumerGroups", groups)
+	result, _ := res[0].([]*sarama.GroupDescription)
+	return result, resultError(res, 1)
+}
+
+// ListConsumerGroupOffsets implements the ListConsumerGroupOffsets method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) ListConsumerGroupOffsets(group string, topicPartitions map[string][]int32) (*sarama.OffsetFetchResponse, error) {
+	res := ca.call("ListConsumerGroupOffsets", group, topicPartitions)
+	result, _ := res[0].(*sarama.OffsetFetchResponse)
+	return result, resultError(res, 1)
+}
+
+// AlterConsumerGroupOffsets implements the AlterConsumerGroupOffsets method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) AlterConsumerGroupOffsets(group string, offsets map[string]map[int32]sarama.GroupOffset) error {
+	res := ca.call("AlterConsumerGroupOffsets", group, offsets)
+	return resultError(res, 0)
+}
+
+// ListOffsets implements the ListOffsets method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) ListOffsets(topicPartitions map[string][]int32, time int64) (map[string]map[int32]int64, error) {
+	res := ca.call("ListOffsets", topicPartitions, time)
+	result, _ := res[0].(map[string]map[int32]int64)
+	return result, resultError(res, 1)
+}
+
+// DeleteConsumerGroupOffset implements the DeleteConsumerGroupOffset method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DeleteConsumerGroupOffset(group string, topic string, partition int32) error {
+	res := ca.call("DeleteConsumerGroupOffset", group, topic, partition)
+	return resultError(res, 0)
+}
+
+// DeleteConsumerGroup implements the DeleteConsumerGroup method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DeleteConsumerGroup(group string) error {
+	res := ca.call("DeleteConsumerGroup", group)
+	return resultError(res, 0)
+}
+
+// DescribeCluster implements the DescribeCluster method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DescribeCluster() ([]*sarama.Broker, int32, error) {
+	res := ca.call("DescribeCluster")
+	brokers, _ := res[0].([]*sarama.Broker)
+	controllerID, _ := res[1].(int32)
+	return brokers, controllerID, resultError(res, 2)
+}
+
+// DescribeLogDirs implements the DescribeLogDirs method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DescribeLogDirs(brokers []int32) (map[int32][]sarama.DescribeLogDirsResponseDirMetadata, error) {
+	res := ca.call("DescribeLogDirs", brokers)
+	result, _ := res[0].(map[int32][]sarama.DescribeLogDirsResponseDirMetadata)
+	return result, resultError(res, 1)
+}
+
+// DescribeUserScramCredentials implements the DescribeUserScramCredentials method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DescribeUserScramCredentials(users []string) ([]*sarama.DescribeUserScramCredentialsResult, error) {
+	res := ca.call("DescribeUserScramCredentials", users)
+	result, _ := res[0].([]*sarama.DescribeUserScramCredentialsResult)
+	return result, resultError(res, 1)
+}
+
+// DeleteUserScramCredentials implements the DeleteUserScramCredentials method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DeleteUserScramCredentials(delete []sarama.AlterUserScramCredentialsDelete) ([]*sarama.AlterUserScramCredentialsResult, error) {
+	res := ca.call("DeleteUserScramCredentials", delete)
+	result, _ := res[0].([]*sarama.AlterUserScramCredentialsResult)
+	return result, resultError(res, 1)
+}
+
+// UpsertUserScramCredentials implements the UpsertUserScramCredentials method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) UpsertUserScramCredentials(upsert []sarama.AlterUserScramCredentialsUpsert) ([]*sarama.AlterUserScramCredentialsResult, error) {
+	res := ca.call("UpsertUserScramCredentials", upsert)
+	result, _ := res[0].([]*sarama.AlterUserScramCredentialsResult)
+	return result, resultError(res, 1)
+}
+
+// DescribeClientQuotas implements the DescribeClientQuotas method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) DescribeClientQuotas(components []sarama.QuotaFilterComponent, strict bool) ([]sarama.DescribeClientQuotasEntry, error) {
+	res := ca.call("DescribeClientQuotas", components, strict)
+	result, _ := res[0].([]sarama.DescribeClientQuotasEntry)
+	return result, resultError(res, 1)
+}
+
+// AlterClientQuotas implements the AlterClientQuotas method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) AlterClientQuotas(entity []sarama.QuotaEntityComponent, op sarama.ClientQuotasOp, validateOnly bool) error {
+	res := ca.call("AlterClientQuotas", entity, op, validateOnly)
+	return resultError(res, 0)
+}
+
+// Controller implements the Controller method from the sarama.ClusterAdmin interface.
+func (ca *ClusterAdmin) Controller() (*sarama.Broker, error) {
+	res := ca.call("Controller")
+	result, _ := res[0].(*sarama.Broker)
+	return result, resultError(res, 1)
+}
+
+// WithContext implements the WithContext method from the sarama.ClusterAdmin
+// interface. It returns the mock itself, the calls sharing its expectations.
+func (ca *ClusterAdmin) WithContext(ctx context.Context) sarama.ClusterAdmin {
+	return ca
+}
+
+// Close corresponds with the Close method of sarama's ClusterAdmin interface. It
+// will verify whether all the expectations set on the mock were met.
+func (ca *ClusterAdmin) Close() error {
+	ca.l.Lock()
+	defer ca.l.Unlock()
+
+	if len(ca.expectations) > 0 {
+		ca.t.Errorf("Expected to exhaust all expectations, but %d are left.", len(ca.expectations))
+	}
+	return nil
+}
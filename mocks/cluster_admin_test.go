@@ -0,0 +1,76 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestMockClusterAdminImplementsClusterAdminInterface(t *testing.T) {
+	var ca interface{} = &ClusterAdmin{}
+	if _, ok := ca.(sarama.ClusterAdmin); !ok {
+		t.Error("The mock cluster admin should implement the sarama.ClusterAdmin interface.")
+	}
+}
+
+func TestMockClusterAdminExpectations(t *testing.T) {
+	detail := &sarama.TopicDetail{NumPartitions: 3, ReplicationFactor: 1}
+	resource := sarama.ConfigResource{Type: sarama.TopicResource, Name: "test"}
+	entries := []sarama.ConfigEntry{{Name: "retention.ms", Value: "1000"}}
+
+	ca := NewClusterAdmin(t)
+	ca.Expect("CreateTopic").WithArgs("test", detail, false)
+	ca.Expect("DescribeConfig").WithArgs(resource).Return(entries, nil)
+	ca.Expect("DeleteTopic").Return(sarama.ErrUnknownTopicOrPartition)
+
+	admin := ca.WithContext(context.Background())
+	if err := admin.CreateTopic("test", detail, false); err != nil {
+		t.Error(err)
+	}
+	if found, err := admin.DescribeConfig(resource); err != nil || len(found) != 1 || found[0].Value != "1000" {
+		t.Errorf("Expected the config entries set, found %v and %v.", found, err)
+	}
+	if err := admin.DeleteTopic("test"); !errors.Is(err, sarama.ErrUnknownTopicOrPartition) {
+		t.Errorf("Expected the error set, found %v.", err)
+	}
+	if err := ca.Close(); err != nil {
+		t.Error(err)
+	}
+}
+
+func TestMockClusterAdminViolatedExpectations(t *testing.T) {
+	trm := newTestReporterMock()
+	ca := NewClusterAdmin(trm)
+	ca.Expect("ListTopics")
+	ca.Expect("CreateACL").WithChecker(func(args []interface{}) error {
+		return errors.New("rejected")
+	})
+	ca.Expect("DeleteTopic")
+
+	if _, err := ca.DescribeTopics([]string{"test"}); !errors.Is(err, errOutOfExpectations) {
+		t.Errorf("Expected the call to the wrong method to fail, found %v.", err)
+	}
+	if err := ca.CreateACL(sarama.Resource{}, sarama.Acl{}); err == nil {
+		t.Error("Expected the call rejected by the checker to fail.")
+	}
+	if err := ca.Close(); err != nil {
+		t.Error(err)
+	}
+	if len(trm.errors) != 3 {
+		t.Errorf("Expected 3 errors to be reported, found %v.", trm.errors)
+	}
+}
+
+func TestMockClusterAdminInvalidReturn(t *testing.T) {
+	trm := newTestReporterMock()
+	ca := NewClusterAdmin(trm)
+	ca.Expect("ListTopics").Return(nil)
+	ca.Expect("DeleteTopic").Return("not an error")
+	ca.Expect("Unknown")
+
+	if len(trm.errors) != 3 {
+		t.Errorf("Expected 3 errors to be reported, found %v.", trm.errors)
+	}
+}
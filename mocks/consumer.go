@@ -1,6 +1,8 @@
 package mocks
 
 import (
+	"context"
+	"math"
 	"sync"
 	"sync/atomic"
 
@@ -127,6 +129,12 @@ func (c *Consumer) Close() error {
 	return nil
 }
 
+// CloseContext implements the CloseContext method from the sarama.Consumer interface.
+// As the mock PartitionConsumer instances close immediately, it behaves as Close.
+func (c *Consumer) CloseContext(ctx context.Context) error {
+	return c.Close()
+}
+
 // Pause implements Consumer.
 func (c *Consumer) Pause(topicPartitions map[string][]int32) {
 	c.l.Lock()
@@ -217,14 +225,15 @@ func (c *Consumer) ExpectConsumePartition(topic string, partition int32, offset
 		}
 
 		c.partitionConsumers[topic][partition] = &PartitionConsumer{
-			highWaterMarkOffset: highWatermarkOffset,
-			t:                   c.t,
-			topic:               topic,
-			partition:           partition,
-			offset:              offset,
-			messages:            make(chan *sarama.ConsumerMessage, c.config.ChannelBufferSize),
-			suppressedMessages:  make(chan *sarama.ConsumerMessage, c.config.ChannelBufferSize),
-			errors:              make(chan *sarama.ConsumerError, c.config.ChannelBufferSize),
+			highWaterMarkOffset:         highWatermarkOffset,
+			reportedHighWaterMarkOffset: math.MinInt64,
+			t:                           c.t,
+			topic:                       topic,
+			partition:                   partition,
+			offset:                      offset,
+			messages:                    make(chan *sarama.ConsumerMessage, c.config.ChannelBufferSize),
+			suppressedMessages:          make(chan *sarama.ConsumerMessage, c.config.ChannelBufferSize),
+			errors:                      make(chan *sarama.ConsumerError, c.config.ChannelBufferSize),
 		}
 	}
 
@@ -242,6 +251,7 @@ func (c *Consumer) ExpectConsumePartition(topic string, partition int32, offset
 // channels using YieldMessage and YieldError.
 type PartitionConsumer struct {
 	highWaterMarkOffset           int64 // must be at the top of the struct because https://golang.org/pkg/sync/atomic/#pkg-note-BUG
+	reportedHighWaterMarkOffset   int64 // set by SetHighWaterMarkOffset, 64-bit aligned as well
 	l                             sync.Mutex
 	t                             ErrorReporter
 	topic                         string
@@ -256,6 +266,15 @@ type PartitionConsumer struct {
 	errorsShouldBeDrained         bool
 	messagesShouldBeDrained       bool
 	paused                        bool
+	scheduled                     []*scheduledEvent
+}
+
+// scheduledEvent is an error or a high water mark offset scheduled on a
+// PartitionConsumer, due once a number of messages more are yielded.
+type scheduledEvent struct {
+	after int
+	err   error
+	hwm   int64
 }
 
 ///////////////////////////////////////////////////
@@ -338,8 +357,15 @@ func (pc *PartitionConsumer) Messages() <-chan *sarama.ConsumerMessage {
 	return pc.messages
 }
 
+// HighWaterMarkOffset implements the HighWaterMarkOffset method from the
+// sarama.PartitionConsumer interface. It follows the messages yielded, unless
+// SetHighWaterMarkOffset set it ahead of them.
 func (pc *PartitionConsumer) HighWaterMarkOffset() int64 {
-	return atomic.LoadInt64(&pc.highWaterMarkOffset) + 1
+	hwm := atomic.LoadInt64(&pc.highWaterMarkOffset) + 1
+	if reported := atomic.LoadInt64(&pc.reportedHighWaterMarkOffset); reported > hwm {
+		return reported
+	}
+	return hwm
 }
 
 // Pause implements the Pause method from the sarama.PartitionConsumer interface.
@@ -398,6 +424,17 @@ func (pc *PartitionConsumer) YieldMessage(msg *sarama.ConsumerMessage) *Partitio
 		pc.messages <- msg
 	}
 
+	// the events scheduled after this message
+	var pending []*scheduledEvent
+	for _, event := range pc.scheduled {
+		if event.after--; event.after > 0 {
+			pending = append(pending, event)
+			continue
+		}
+		pc.fire(event)
+	}
+	pc.scheduled = pending
+
 	return pc
 }
 
@@ -416,6 +453,54 @@ func (pc *PartitionConsumer) YieldError(err error) *PartitionConsumer {
 	return pc
 }
 
+// YieldErrorAfter schedules an error on the Errors channel of this partition
+// consumer, yielded along with the nth message yielded from now on, so that
+// the application gets it once it consumed the messages before. This lets you
+// test partition-level error handling, such as a sarama.ErrOffsetOutOfRange
+// after a number of messages. An n of 0 or less yields the error right away.
+func (pc *PartitionConsumer) YieldErrorAfter(n int, err error) *PartitionConsumer {
+	return pc.schedule(&scheduledEvent{after: n, err: err})
+}
+
+// SetHighWaterMarkOffset sets the offset returned by HighWaterMarkOffset,
+// ahead of the messages yielded so far, for the application to see the
+// partition lag behind. The high water mark offset keeps following the
+// messages yielded once they catch up with it.
+func (pc *PartitionConsumer) SetHighWaterMarkOffset(offset int64) *PartitionConsumer {
+	atomic.StoreInt64(&pc.reportedHighWaterMarkOffset, offset)
+
+	return pc
+}
+
+// SetHighWaterMarkOffsetAfter schedules SetHighWaterMarkOffset along with the
+// nth message yielded from now on, for the high water mark offset to progress
+// over time as the messages are consumed. An n of 0 or less sets it right
+// away.
+func (pc *PartitionConsumer) SetHighWaterMarkOffsetAfter(n int, offset int64) *PartitionConsumer {
+	return pc.schedule(&scheduledEvent{after: n, hwm: offset})
+}
+
+func (pc *PartitionConsumer) schedule(event *scheduledEvent) *PartitionConsumer {
+	pc.l.Lock()
+	defer pc.l.Unlock()
+
+	if event.after <= 0 {
+		pc.fire(event)
+	} else {
+		pc.scheduled = append(pc.scheduled, event)
+	}
+
+	return pc
+}
+
+func (pc *PartitionConsumer) fire(event *scheduledEvent) {
+	if event.err != nil {
+		pc.YieldError(event.err)
+	} else {
+		pc.SetHighWaterMarkOffset(event.hwm)
+	}
+}
+
 // ExpectMessagesDrainedOnClose sets an expectation on the partition consumer
 // that the messages channel will be fully drained when Close is called. If this
 // expectation is not met, an error is reported to the error reporter.
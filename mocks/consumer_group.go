@@ -0,0 +1,577 @@
+package mocks
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/Shopify/sarama"
+)
+
+// ConsumerGroup implements sarama's ConsumerGroup interface for testing purposes.
+// It drives the ConsumerGroupHandler given to Consume through its sessions, without
+// a broker: each session claims the partitions set with SetClaims, runs Setup,
+// ConsumeClaim for each claim and Cleanup, and feeds the claims the messages
+// yielded with YieldMessage. The offsets marked by the handler can be checked with
+// MarkedOffset or ExpectMarkedOffset.
+//
+// A session ends when the context given to Consume is canceled, when Rebalance or
+// Close is called, or when one of the ConsumeClaim calls returns. The claims are then
+// fed the messages left for their partitions before their messages channels are
+// closed, and the context of the session is canceled once all the ConsumeClaim calls
+// returned, so that the messages yielded are consumed deterministically.
+type ConsumerGroup struct {
+	l          sync.Mutex
+	t          ErrorReporter
+	config     *sarama.Config
+	claims     map[string][]int32
+	queues     map[string]map[int32][]*sarama.ConsumerMessage
+	hwms       map[string]map[int32]int64
+	offsets    map[string]map[int32]*markedOffset
+	expected   map[string]map[int32]int64
+	paused     map[string]map[int32]bool
+	generation int32
+	session    *consumerGroupSession
+	started    chan struct{}
+	yielded    chan struct{}
+	errors     chan error
+	closed     bool
+}
+
+// markedOffset is an offset marked by the sessions of a mock ConsumerGroup.
+type markedOffset struct {
+	offset   int64
+	metadata string
+}
+
+// NewConsumerGroup returns a new mock ConsumerGroup instance. The t argument should
+// be the *testing.T instance of your test method. An error will be written to it if
+// an expectation is violated. The config argument can be set to nil.
+func NewConsumerGroup(t ErrorReporter, config *sarama.Config) *ConsumerGroup {
+	if config == nil {
+		config = sarama.NewConfig()
+	}
+
+	return &ConsumerGroup{
+		t:        t,
+		config:   config,
+		claims:   make(map[string][]int32),
+		queues:   make(map[string]map[int32][]*sarama.ConsumerMessage),
+		hwms:     make(map[string]map[int32]int64),
+		offsets:  make(map[string]map[int32]*markedOffset),
+		expected: make(map[string]map[int32]int64),
+		paused:   make(map[string]map[int32]bool),
+		started:  make(chan struct{}),
+		yielded:  make(chan struct{}),
+		errors:   make(chan error, config.ChannelBufferSize),
+	}
+}
+
+///////////////////////////////////////////////////
+// ConsumerGroup interface implementation
+///////////////////////////////////////////////////
+
+// Consume implements the Consume method from the sarama.ConsumerGroup interface. It
+// runs a session of handler claiming the partitions of topics set with SetClaims, and
+// returns once the session ended.
+func (c *ConsumerGroup) Consume(ctx context.Context, topics []string, handler sarama.ConsumerGroupHandler) error {
+	c.l.Lock()
+	if c.closed {
+		c.l.Unlock()
+		return sarama.ErrClosedConsumerGroup
+	}
+	if c.session != nil {
+		c.l.Unlock()
+		c.t.Errorf("Consume called while a session is running")
+		return errSessionRunning
+	}
+
+	claims := make(map[string][]int32)
+	for _, topic := range topics {
+		if partitions := c.claims[topic]; len(partitions) > 0 {
+			claims[topic] = append([]int32(nil), partitions...)
+		}
+	}
+	c.generation++
+	sess := newConsumerGroupSession(ctx, c, claims, c.generation)
+	c.session = sess
+	close(c.started)
+	c.started = make(chan struct{})
+	c.l.Unlock()
+
+	err := sess.run(handler)
+
+	c.l.Lock()
+	c.session = nil
+	c.l.Unlock()
+	return err
+}
+
+// Errors implements the Errors method from the sarama.ConsumerGroup interface.
+func (c *ConsumerGroup) Errors() <-chan error {
+	return c.errors
+}
+
+// Close implements the Close method from the sarama.ConsumerGroup interface. It ends
+// the running session, if any, and verifies the expectations set on the offsets
+// marked. Consume returns sarama.ErrClosedConsumerGroup once closed.
+func (c *ConsumerGroup) Close() error {
+	c.l.Lock()
+	if c.closed {
+		c.l.Unlock()
+		return sarama.ErrClosedConsumerGroup
+	}
+	c.closed = true
+	sess := c.session
+	c.l.Unlock()
+
+	if sess != nil {
+		sess.end()
+		<-sess.done
+	}
+
+	c.l.Lock()
+	defer c.l.Unlock()
+	for topic, partitions := range c.expected {
+		for partition, offset := range partitions {
+			marked, ok := c.offsets[topic][partition]
+			if !ok {
+				c.t.Errorf("Expected offset %d to be marked on %s/%d, but none was.", offset, topic, partition)
+			} else if marked.offset != offset {
+				c.t.Errorf("Expected offset %d to be marked on %s/%d, but found %d.", offset, topic, partition, marked.offset)
+			}
+		}
+	}
+	close(c.errors)
+	return nil
+}
+
+// CloseContext implements the CloseContext method from the sarama.ConsumerGroup
+// interface. As the mock sessions have no offsets to commit to a broker, it behaves
+// as Close.
+func (c *ConsumerGroup) CloseContext(ctx context.Context) error {
+	return c.Close()
+}
+
+// Pause implements the Pause method from the sarama.ConsumerGroup interface. The
+// messages yielded to the partitions paused are held back until they are resumed.
+func (c *ConsumerGroup) Pause(partitions map[string][]int32) {
+	c.setPaused(partitions, true)
+}
+
+// Resume implements the Resume method from the sarama.ConsumerGroup interface.
+func (c *ConsumerGroup) Resume(partitions map[string][]int32) {
+	c.setPaused(partitions, false)
+}
+
+// PauseAll implements the PauseAll method from the sarama.ConsumerGroup interface.
+func (c *ConsumerGroup) PauseAll() {
+	c.setPaused(c.claimedPartitions(), true)
+}
+
+// ResumeAll implements the ResumeAll method from the sarama.ConsumerGroup interface.
+func (c *ConsumerGroup) ResumeAll() {
+	c.l.Lock()
+	c.paused = make(map[string]map[int32]bool)
+	c.notifyYielded()
+	c.l.Unlock()
+}
+
+// DebugState implements the DebugState method from the sarama.ConsumerGroup
+// interface. It reports the member, generation and claims of the running session.
+func (c *ConsumerGroup) DebugState() sarama.ConsumerGroupDebugState {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	var state sarama.ConsumerGroupDebugState
+	if c.session != nil {
+		state.MemberID = c.session.MemberID()
+		state.GenerationID = c.session.GenerationID()
+		state.Claims = c.session.Claims()
+	}
+	return state
+}
+
+///////////////////////////////////////////////////
+// Expectation API
+///////////////////////////////////////////////////
+
+// SetClaims sets the partitions claimed by the sessions, by topic. It takes effect
+// on the next session, see Rebalance.
+func (c *ConsumerGroup) SetClaims(claims map[string][]int32) *ConsumerGroup {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.claims = make(map[string][]int32, len(claims))
+	for topic, partitions := range claims {
+		c.claims[topic] = append([]int32(nil), partitions...)
+	}
+	return c
+}
+
+// SetOffset sets the offset committed on the partition, which the claims of the
+// partition start from. Without it they start from Consumer.Offsets.Initial.
+func (c *ConsumerGroup) SetOffset(topic string, partition int32, offset int64) *ConsumerGroup {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	c.markOffset(topic, partition, offset, "", true)
+	return c
+}
+
+// YieldMessage queues msg to be fed to the claim of its partition, in the running
+// session if it claims the partition, or in the next one which does.
+func (c *ConsumerGroup) YieldMessage(msg *sarama.ConsumerMessage) *ConsumerGroup {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.queues[msg.Topic] == nil {
+		c.queues[msg.Topic] = make(map[int32][]*sarama.ConsumerMessage)
+		c.hwms[msg.Topic] = make(map[int32]int64)
+	}
+	c.queues[msg.Topic][msg.Partition] = append(c.queues[msg.Topic][msg.Partition], msg)
+	if msg.Offset >= c.hwms[msg.Topic][msg.Partition] {
+		c.hwms[msg.Topic][msg.Partition] = msg.Offset + 1
+	}
+	c.notifyYielded()
+	return c
+}
+
+// YieldError sends err on the Errors channel of the group, if
+// Consumer.Return.Errors is set.
+func (c *ConsumerGroup) YieldError(err error) *ConsumerGroup {
+	c.handleError(err)
+	return c
+}
+
+// Rebalance waits for a session to run, then ends it as a rebalance would, once the
+// messages yielded to its claims were consumed, and returns once it ended. Consume
+// returns nil, to be called again for the next session, which claims the partitions
+// set with SetClaims.
+func (c *ConsumerGroup) Rebalance() {
+	c.l.Lock()
+	sess, started := c.session, c.started
+	c.l.Unlock()
+
+	if sess == nil {
+		<-started
+		c.l.Lock()
+		sess = c.session
+		c.l.Unlock()
+		if sess == nil {
+			return
+		}
+	}
+	sess.end()
+	<-sess.done
+}
+
+// MarkedOffset returns the offset marked last on the partition, or set with
+// SetOffset, and its metadata. The offset is -1 if none was.
+func (c *ConsumerGroup) MarkedOffset(topic string, partition int32) (int64, string) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	marked, ok := c.offsets[topic][partition]
+	if !ok {
+		return -1, ""
+	}
+	return marked.offset, marked.metadata
+}
+
+// ExpectMarkedOffset sets the expectation that offset is the last offset marked on
+// the partition, verified on Close.
+func (c *ConsumerGroup) ExpectMarkedOffset(topic string, partition int32, offset int64) *ConsumerGroup {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.expected[topic] == nil {
+		c.expected[topic] = make(map[int32]int64)
+	}
+	c.expected[topic][partition] = offset
+	return c
+}
+
+// markOffset marks offset on the partition. Unless reset, only offsets greater than
+// the one marked are.
+func (c *ConsumerGroup) markOffset(topic string, partition int32, offset int64, metadata string, reset bool) {
+	if c.offsets[topic] == nil {
+		c.offsets[topic] = make(map[int32]*markedOffset)
+	}
+	if marked, ok := c.offsets[topic][partition]; ok && !reset && offset <= marked.offset {
+		return
+	}
+	c.offsets[topic][partition] = &markedOffset{offset: offset, metadata: metadata}
+}
+
+// nextMessage pops the next message yielded to the partition, nil if there is none
+// or the partition is paused.
+func (c *ConsumerGroup) nextMessage(topic string, partition int32) *sarama.ConsumerMessage {
+	queue := c.queues[topic][partition]
+	if len(queue) == 0 || c.paused[topic][partition] {
+		return nil
+	}
+	c.queues[topic][partition] = queue[1:]
+	return queue[0]
+}
+
+// notifyYielded wakes up the claims waiting for messages.
+func (c *ConsumerGroup) notifyYielded() {
+	close(c.yielded)
+	c.yielded = make(chan struct{})
+}
+
+func (c *ConsumerGroup) setPaused(partitions map[string][]int32, paused bool) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	for topic, ps := range partitions {
+		if c.paused[topic] == nil {
+			c.paused[topic] = make(map[int32]bool)
+		}
+		for _, partition := range ps {
+			c.paused[topic][partition] = paused
+		}
+	}
+	c.notifyYielded()
+}
+
+func (c *ConsumerGroup) claimedPartitions() map[string][]int32 {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if c.session == nil {
+		return nil
+	}
+	return c.session.claims
+}
+
+func (c *ConsumerGroup) handleError(err error) {
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	if !c.config.Consumer.Return.Errors || c.closed {
+		return
+	}
+	select {
+	case c.errors <- err:
+	default:
+		c.t.Errorf("The errors channel of the consumer group is full, dropping %v", err)
+	}
+}
+
+///////////////////////////////////////////////////
+// ConsumerGroupSession mock type
+///////////////////////////////////////////////////
+
+// consumerGroupSession implements sarama's ConsumerGroupSession interface for the
+// sessions of a mock ConsumerGroup.
+type consumerGroupSession struct {
+	parent     *ConsumerGroup
+	ctx        context.Context
+	cancel     context.CancelFunc
+	claims     map[string][]int32
+	generation int32
+	ending     chan struct{}
+	endOnce    sync.Once
+	done       chan struct{}
+}
+
+func newConsumerGroupSession(ctx context.Context, parent *ConsumerGroup, claims map[string][]int32, generation int32) *consumerGroupSession {
+	ctx, cancel := context.WithCancel(ctx)
+	return &consumerGroupSession{
+		parent:     parent,
+		ctx:        ctx,
+		cancel:     cancel,
+		claims:     claims,
+		generation: generation,
+		ending:     make(chan struct{}),
+		done:       make(chan struct{}),
+	}
+}
+
+// run runs the session through handler.
+func (s *consumerGroupSession) run(handler sarama.ConsumerGroupHandler) error {
+	defer close(s.done)
+	defer s.cancel()
+
+	if err := handler.Setup(s); err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for topic, partitions := range s.claims {
+		for _, partition := range partitions {
+			claim := s.newClaim(topic, partition)
+			wg.Add(2)
+			go func() {
+				defer wg.Done()
+				s.feed(claim)
+			}()
+			go func() {
+				defer wg.Done()
+				// end the session as soon as the first claim is done with
+				defer s.end()
+				defer close(claim.consumed)
+				if err := handler.ConsumeClaim(s, claim); err != nil {
+					s.parent.handleError(err)
+				}
+			}()
+		}
+	}
+
+	select {
+	case <-s.ctx.Done():
+		s.end()
+	case <-s.ending:
+	}
+	wg.Wait()
+	s.cancel()
+
+	return handler.Cleanup(s)
+}
+
+// end ends the session once the messages yielded to its claims were fed.
+func (s *consumerGroupSession) end() {
+	s.endOnce.Do(func() {
+		close(s.ending)
+	})
+}
+
+// feed feeds claim the messages yielded to its partition, until the session ends
+// and none is left, or the handler is done with the claim.
+func (s *consumerGroupSession) feed(claim *consumerGroupClaim) {
+	defer close(claim.messages)
+
+	c := s.parent
+	for {
+		c.l.Lock()
+		msg := c.nextMessage(claim.topic, claim.partition)
+		yielded := c.yielded
+		c.l.Unlock()
+
+		if msg == nil {
+			select {
+			case <-s.ending:
+				return
+			case <-claim.consumed:
+				return
+			case <-yielded:
+			}
+			continue
+		}
+
+		select {
+		case claim.messages <- msg:
+		case <-claim.consumed:
+			return
+		}
+	}
+}
+
+func (s *consumerGroupSession) newClaim(topic string, partition int32) *consumerGroupClaim {
+	c := s.parent
+	c.l.Lock()
+	defer c.l.Unlock()
+
+	claim := &consumerGroupClaim{
+		topic:         topic,
+		partition:     partition,
+		initialOffset: c.config.Consumer.Offsets.Initial,
+		parent:        c,
+		messages:      make(chan *sarama.ConsumerMessage, c.config.ChannelBufferSize),
+		consumed:      make(chan struct{}),
+	}
+	if marked, ok := c.offsets[topic][partition]; ok {
+		claim.initialOffset = marked.offset
+	}
+	return claim
+}
+
+// Claims implements the Claims method from the sarama.ConsumerGroupSession interface.
+func (s *consumerGroupSession) Claims() map[string][]int32 {
+	claims := make(map[string][]int32, len(s.claims))
+	for topic, partitions := range s.claims {
+		claims[topic] = append([]int32(nil), partitions...)
+		sort.Slice(claims[topic], func(i, j int) bool { return claims[topic][i] < claims[topic][j] })
+	}
+	return claims
+}
+
+// MemberID implements the MemberID method from the sarama.ConsumerGroupSession interface.
+func (s *consumerGroupSession) MemberID() string {
+	return "mock-member"
+}
+
+// GenerationID implements the GenerationID method from the sarama.ConsumerGroupSession
+// interface. The generation starts at 1 and is incremented by each session.
+func (s *consumerGroupSession) GenerationID() int32 {
+	return s.generation
+}
+
+// MarkOffset implements the MarkOffset method from the sarama.ConsumerGroupSession interface.
+func (s *consumerGroupSession) MarkOffset(topic string, partition int32, offset int64, metadata string) {
+	s.parent.l.Lock()
+	defer s.parent.l.Unlock()
+	s.parent.markOffset(topic, partition, offset, metadata, false)
+}
+
+// Commit implements the Commit method from the sarama.ConsumerGroupSession interface.
+// The offsets are committed as soon as marked.
+func (s *consumerGroupSession) Commit() {}
+
+// ResetOffset implements the ResetOffset method from the sarama.ConsumerGroupSession interface.
+func (s *consumerGroupSession) ResetOffset(topic string, partition int32, offset int64, metadata string) {
+	s.parent.l.Lock()
+	defer s.parent.l.Unlock()
+	s.parent.markOffset(topic, partition, offset, metadata, true)
+}
+
+// MarkMessage implements the MarkMessage method from the sarama.ConsumerGroupSession interface.
+func (s *consumerGroupSession) MarkMessage(msg *sarama.ConsumerMessage, metadata string) {
+	s.MarkOffset(msg.Topic, msg.Partition, msg.Offset+1, metadata)
+}
+
+// Context implements the Context method from the sarama.ConsumerGroupSession interface.
+func (s *consumerGroupSession) Context() context.Context {
+	return s.ctx
+}
+
+///////////////////////////////////////////////////
+// ConsumerGroupClaim mock type
+///////////////////////////////////////////////////
+
+// consumerGroupClaim implements sarama's ConsumerGroupClaim interface for the claims
+// of the sessions of a mock ConsumerGroup.
+type consumerGroupClaim struct {
+	topic         string
+	partition     int32
+	initialOffset int64
+	parent        *ConsumerGroup
+	messages      chan *sarama.ConsumerMessage
+	// consumed is closed once the handler is done with the claim
+	consumed chan struct{}
+}
+
+// Topic implements the Topic method from the sarama.ConsumerGroupClaim interface.
+func (c *consumerGroupClaim) Topic() string { return c.topic }
+
+// Partition implements the Partition method from the sarama.ConsumerGroupClaim interface.
+func (c *consumerGroupClaim) Partition() int32 { return c.partition }
+
+// InitialOffset implements the InitialOffset method from the sarama.ConsumerGroupClaim
+// interface. It is the offset marked last on the partition, or
+// Consumer.Offsets.Initial if none was.
+func (c *consumerGroupClaim) InitialOffset() int64 { return c.initialOffset }
+
+// HighWaterMarkOffset implements the HighWaterMarkOffset method from the
+// sarama.ConsumerGroupClaim interface. It is the offset following the highest offset
+// yielded to the partition.
+func (c *consumerGroupClaim) HighWaterMarkOffset() int64 {
+	c.parent.l.Lock()
+	defer c.parent.l.Unlock()
+	return c.parent.hwms[c.topic][c.partition]
+}
+
+// Messages implements the Messages method from the sarama.ConsumerGroupClaim interface.
+func (c *consumerGroupClaim) Messages() <-chan *sarama.ConsumerMessage { return c.messages }
@@ -0,0 +1,115 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+// countingHandler is a ConsumerGroupHandler marking the messages it consumes and
+// recording the generations of its sessions.
+type countingHandler struct {
+	l           sync.Mutex
+	generations []int32
+	consumed    int
+}
+
+func (h *countingHandler) Setup(sess sarama.ConsumerGroupSession) error {
+	h.l.Lock()
+	defer h.l.Unlock()
+	h.generations = append(h.generations, sess.GenerationID())
+	return nil
+}
+
+func (h *countingHandler) Cleanup(sarama.ConsumerGroupSession) error { return nil }
+
+func (h *countingHandler) ConsumeClaim(sess sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		h.l.Lock()
+		h.consumed++
+		h.l.Unlock()
+		sess.MarkMessage(msg, "")
+	}
+	return nil
+}
+
+func TestMockConsumerGroupImplementsConsumerGroupInterface(t *testing.T) {
+	var cg interface{} = &ConsumerGroup{}
+	if _, ok := cg.(sarama.ConsumerGroup); !ok {
+		t.Error("The mock consumer group should implement the sarama.ConsumerGroup interface.")
+	}
+}
+
+func TestMockConsumerGroupDrivesHandler(t *testing.T) {
+	group := NewConsumerGroup(t, NewTestConfig()).
+		SetClaims(map[string][]int32{"test": {0}}).
+		SetOffset("test", 1, 10)
+	group.YieldMessage(&sarama.ConsumerMessage{Topic: "test", Partition: 0, Offset: 0})
+	group.YieldMessage(&sarama.ConsumerMessage{Topic: "test", Partition: 0, Offset: 1})
+	group.YieldMessage(&sarama.ConsumerMessage{Topic: "test", Partition: 1, Offset: 10})
+	group.ExpectMarkedOffset("test", 0, 2).ExpectMarkedOffset("test", 1, 11)
+
+	handler := &countingHandler{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for {
+			if err := group.Consume(context.Background(), []string{"test"}, handler); err != nil {
+				if err != sarama.ErrClosedConsumerGroup {
+					t.Error(err)
+				}
+				return
+			}
+		}
+	}()
+
+	// the message of partition 1 waits for a session claiming it
+	group.Rebalance()
+	if offset, _ := group.MarkedOffset("test", 1); offset != 10 {
+		t.Errorf("Expected the offset set on test/1 to be kept until claimed, found %d.", offset)
+	}
+	group.SetClaims(map[string][]int32{"test": {0, 1}})
+	group.Rebalance()
+	if err := group.Close(); err != nil {
+		t.Error(err)
+	}
+	<-done
+
+	handler.l.Lock()
+	defer handler.l.Unlock()
+	if handler.consumed != 3 {
+		t.Errorf("Expected 3 messages to be consumed, found %d.", handler.consumed)
+	}
+	if len(handler.generations) < 2 || handler.generations[0] != 1 || handler.generations[1] != 2 {
+		t.Errorf("Expected the sessions to be of generations 1 and 2, found %v.", handler.generations)
+	}
+}
+
+func TestMockConsumerGroupPausedPartitionsAndExpectations(t *testing.T) {
+	trm := newTestReporterMock()
+	group := NewConsumerGroup(trm, NewTestConfig()).SetClaims(map[string][]int32{"test": {0}})
+	group.Pause(map[string][]int32{"test": {0}})
+	group.YieldMessage(&sarama.ConsumerMessage{Topic: "test", Partition: 0, Offset: 0})
+	group.ExpectMarkedOffset("test", 0, 1)
+
+	handler := &countingHandler{}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		_ = group.Consume(context.Background(), []string{"test"}, handler)
+	}()
+	group.Rebalance()
+	<-done
+	if err := group.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if handler.consumed != 0 {
+		t.Errorf("Expected the message of the paused partition to be held back, found %d consumed.", handler.consumed)
+	}
+	if len(trm.errors) != 1 {
+		t.Errorf("Expected the unmarked offset to be reported, found %v.", trm.errors)
+	}
+}
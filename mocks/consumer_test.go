@@ -395,3 +395,48 @@ func TestConsumerOffsetsAreManagedCorrectlyWithSpecifiedOffset(t *testing.T) {
 		t.Errorf("Expected to not report any errors, found: %v", trm.errors)
 	}
 }
+
+func TestConsumerSchedulesErrorsAndHighWaterMarks(t *testing.T) {
+	trm := newTestReporterMock()
+	consumer := NewConsumer(trm, NewTestConfig())
+	pcmock := consumer.ExpectConsumePartition("test", 0, sarama.OffsetOldest).
+		SetHighWaterMarkOffset(10).
+		SetHighWaterMarkOffsetAfter(2, 20).
+		YieldErrorAfter(2, sarama.ErrOffsetOutOfRange)
+
+	pc, err := consumer.ConsumePartition("test", 0, sarama.OffsetOldest)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if hwm := pc.HighWaterMarkOffset(); hwm != 10 {
+		t.Errorf("Expected the high water mark offset to be 10, got %d", hwm)
+	}
+
+	pcmock.YieldMessage(&sarama.ConsumerMessage{Value: []byte("hello")})
+	if len(pc.Errors()) != 0 {
+		t.Error("Expected no error before the second message")
+	}
+	if hwm := consumer.HighWaterMarks()["test"][0]; hwm != 10 {
+		t.Errorf("Expected the high water mark offset to still be 10, got %d", hwm)
+	}
+
+	pcmock.YieldMessage(&sarama.ConsumerMessage{Value: []byte("hello")})
+	for i := int64(0); i < 2; i++ {
+		if msg := <-pc.Messages(); msg.Offset != i {
+			t.Errorf("Expected offset %d, got %d", i, msg.Offset)
+		}
+	}
+	if err := <-pc.Errors(); !errors.Is(err, sarama.ErrOffsetOutOfRange) {
+		t.Error("Expected sarama.ErrOffsetOutOfRange, found:", err)
+	}
+	if hwm := pc.HighWaterMarkOffset(); hwm != 20 {
+		t.Errorf("Expected the high water mark offset to be 20, got %d", hwm)
+	}
+
+	if err := consumer.Close(); err != nil {
+		t.Error(err)
+	}
+	if len(trm.errors) != 0 {
+		t.Errorf("Expected to not report any errors, found: %v", trm.errors)
+	}
+}
@@ -14,6 +14,7 @@ guarantee of Sarama as it is still considered experimental.
 package mocks
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 
@@ -50,10 +51,85 @@ func messageValueChecker(f ValueChecker) MessageChecker {
 	}
 }
 
+// MatchTopic returns a MessageChecker checking that the message is produced to topic.
+func MatchTopic(topic string) MessageChecker {
+	return func(msg *sarama.ProducerMessage) error {
+		if msg.Topic != topic {
+			return fmt.Errorf("Expected message to topic %s, got topic %s", topic, msg.Topic)
+		}
+		return nil
+	}
+}
+
+// MatchKey returns a MessageChecker checking that the message key encodes to key,
+// a nil key matching messages without one.
+func MatchKey(key []byte) MessageChecker {
+	return func(msg *sarama.ProducerMessage) error {
+		encoded, err := encodeChecked(msg.Key)
+		if err != nil {
+			return fmt.Errorf("Input message key encoding failed: %w", err)
+		}
+		if !bytes.Equal(encoded, key) || (encoded == nil) != (key == nil) {
+			return fmt.Errorf("Expected message key %q, got %q", key, encoded)
+		}
+		return nil
+	}
+}
+
+// MatchValue returns a MessageChecker checking that the message value encodes to
+// value, a nil value matching messages without one.
+func MatchValue(value []byte) MessageChecker {
+	return func(msg *sarama.ProducerMessage) error {
+		encoded, err := encodeChecked(msg.Value)
+		if err != nil {
+			return fmt.Errorf("Input message encoding failed: %w", err)
+		}
+		if !bytes.Equal(encoded, value) || (encoded == nil) != (value == nil) {
+			return fmt.Errorf("Expected message value %q, got %q", value, encoded)
+		}
+		return nil
+	}
+}
+
+// MatchHeader returns a MessageChecker checking that the message has a header
+// named key of the given value.
+func MatchHeader(key, value []byte) MessageChecker {
+	return func(msg *sarama.ProducerMessage) error {
+		for _, header := range msg.Headers {
+			if bytes.Equal(header.Key, key) && bytes.Equal(header.Value, value) {
+				return nil
+			}
+		}
+		return fmt.Errorf("Expected message header %q=%q, got %v", key, value, msg.Headers)
+	}
+}
+
+// MatchAll returns a MessageChecker checking the message with each of checkers,
+// returning the error of the first one failing.
+func MatchAll(checkers ...MessageChecker) MessageChecker {
+	return func(msg *sarama.ProducerMessage) error {
+		for _, checker := range checkers {
+			if err := checker(msg); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+}
+
+// encodeChecked encodes e, returning nil for a nil encoder.
+func encodeChecked(e sarama.Encoder) ([]byte, error) {
+	if e == nil {
+		return nil, nil
+	}
+	return e.Encode()
+}
+
 var (
 	errProduceSuccess              error = nil
 	errOutOfExpectations                 = errors.New("No more expectations set on mock")
 	errPartitionConsumerNotStarted       = errors.New("The partition consumer was never started")
+	errSessionRunning                    = errors.New("A session of the consumer group is already running")
 )
 
 const AnyOffset int64 = -1000
@@ -63,6 +139,40 @@ type producerExpectation struct {
 	CheckFunction MessageChecker
 }
 
+// offsetCounter assigns the offsets of the messages the mock producers produce
+// successfully: from a single counter shared by all the partitions, or, once
+// a next offset is set, from a counter per partition as a broker does.
+type offsetCounter struct {
+	last         int64
+	perPartition map[string]map[int32]int64
+}
+
+// setNext sets the offset of the next message produced to topic/partition,
+// switching to counting offsets per partition.
+func (oc *offsetCounter) setNext(topic string, partition int32, offset int64) {
+	if oc.perPartition == nil {
+		oc.perPartition = make(map[string]map[int32]int64)
+	}
+	if oc.perPartition[topic] == nil {
+		oc.perPartition[topic] = make(map[int32]int64)
+	}
+	oc.perPartition[topic][partition] = offset
+}
+
+// next returns the offset of a message produced to topic/partition.
+func (oc *offsetCounter) next(topic string, partition int32) int64 {
+	if oc.perPartition == nil {
+		oc.last++
+		return oc.last
+	}
+	if oc.perPartition[topic] == nil {
+		oc.perPartition[topic] = make(map[int32]int64)
+	}
+	offset := oc.perPartition[topic][partition]
+	oc.perPartition[topic][partition] = offset + 1
+	return offset
+}
+
 // TopicConfig describes a mock topic structure for the mock producers’ partitioning needs.
 type TopicConfig struct {
 	overridePartitions map[string]int32
@@ -1,6 +1,7 @@
 package mocks
 
 import (
+	"context"
 	"errors"
 	"sync"
 
@@ -15,7 +16,8 @@ type SyncProducer struct {
 	l            sync.Mutex
 	t            ErrorReporter
 	expectations []*producerExpectation
-	lastOffset   int64
+	offsets      offsetCounter
+	messages     []*sarama.ProducerMessage
 
 	*TopicConfig
 	newPartitioner sarama.PartitionerConstructor
@@ -51,6 +53,7 @@ func NewSyncProducer(t ErrorReporter, config *sarama.Config) *SyncProducer {
 func (sp *SyncProducer) SendMessage(msg *sarama.ProducerMessage) (partition int32, offset int64, err error) {
 	sp.l.Lock()
 	defer sp.l.Unlock()
+	sp.messages = append(sp.messages, msg)
 
 	if len(sp.expectations) > 0 {
 		expectation := sp.expectations[0]
@@ -70,9 +73,8 @@ func (sp *SyncProducer) SendMessage(msg *sarama.ProducerMessage) (partition int3
 			}
 		}
 		if errors.Is(expectation.Result, errProduceSuccess) {
-			sp.lastOffset++
-			msg.Offset = sp.lastOffset
-			return 0, msg.Offset, nil
+			msg.Offset = sp.offsets.next(topic, partition)
+			return partition, msg.Offset, nil
 		}
 		return -1, -1, expectation.Result
 	}
@@ -87,6 +89,7 @@ func (sp *SyncProducer) SendMessage(msg *sarama.ProducerMessage) (partition int3
 func (sp *SyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
 	sp.l.Lock()
 	defer sp.l.Unlock()
+	sp.messages = append(sp.messages, msgs...)
 
 	if len(sp.expectations) >= len(msgs) {
 		expectations := sp.expectations[0:len(msgs)]
@@ -110,8 +113,7 @@ func (sp *SyncProducer) SendMessages(msgs []*sarama.ProducerMessage) error {
 			if !errors.Is(expectation.Result, errProduceSuccess) {
 				return expectation.Result
 			}
-			sp.lastOffset++
-			msgs[i].Offset = sp.lastOffset
+			msgs[i].Offset = sp.offsets.next(topic, partition)
 		}
 		return nil
 	}
@@ -142,6 +144,32 @@ func (sp *SyncProducer) Close() error {
 	return nil
 }
 
+// CloseContext corresponds with the CloseContext method of sarama's SyncProducer implementation.
+// As the mock syncproducer has no messages in flight, it behaves as Close.
+func (sp *SyncProducer) CloseContext(ctx context.Context) error {
+	return sp.Close()
+}
+
+// SetNextOffset sets the offset of the next message produced successfully to
+// topic/partition. The mock producer then assigns offsets per partition, counting
+// from 0 for the partitions not set, instead of from a single counter shared by
+// all the partitions, starting at 1. The partitions are those of the partitioner
+// of the config the mock producer was created with.
+func (sp *SyncProducer) SetNextOffset(topic string, partition int32, offset int64) *SyncProducer {
+	sp.l.Lock()
+	defer sp.l.Unlock()
+	sp.offsets.setNext(topic, partition, offset)
+	return sp
+}
+
+// Messages returns the messages sent to the mock producer so far, in order,
+// whether they met an expectation or not, for assertions on them.
+func (sp *SyncProducer) Messages() []*sarama.ProducerMessage {
+	sp.l.Lock()
+	defer sp.l.Unlock()
+	return append([]*sarama.ProducerMessage(nil), sp.messages...)
+}
+
 ////////////////////////////////////////////////
 // Setting expectations
 ////////////////////////////////////////////////
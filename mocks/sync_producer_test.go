@@ -254,3 +254,67 @@ func (f faultyEncoder) Encode() ([]byte, error) {
 func (f faultyEncoder) Length() int {
 	return len(f)
 }
+
+func TestSyncProducerWithMatchers(t *testing.T) {
+	trm := newTestReporterMock()
+
+	sp := NewSyncProducer(trm, nil).
+		ExpectSendMessageWithMessageCheckerFunctionAndSucceed(MatchAll(
+			MatchTopic("test"),
+			MatchKey([]byte("key")),
+			MatchValue([]byte("value")),
+			MatchHeader([]byte("trace"), []byte("1")),
+		)).
+		ExpectSendMessageWithMessageCheckerFunctionAndSucceed(MatchKey([]byte("key")))
+
+	msg := &sarama.ProducerMessage{
+		Topic:   "test",
+		Key:     sarama.StringEncoder("key"),
+		Value:   sarama.StringEncoder("value"),
+		Headers: []sarama.RecordHeader{{Key: []byte("trace"), Value: []byte("1")}},
+	}
+	if _, _, err := sp.SendMessage(msg); err != nil {
+		t.Error("No error expected on first SendMessage call, found: ", err)
+	}
+	if _, _, err := sp.SendMessage(&sarama.ProducerMessage{Topic: "test"}); err == nil || !strings.HasPrefix(err.Error(), "Expected message key") {
+		t.Error("Error during key check expected on second SendMessage call, found:", err)
+	}
+
+	if err := sp.Close(); err != nil {
+		t.Error(err)
+	}
+
+	if len(trm.errors) != 1 {
+		t.Error("Expected to report an error")
+	}
+	if messages := sp.Messages(); len(messages) != 2 || messages[0] != msg {
+		t.Errorf("Expected the 2 messages sent to be recorded, found %v.", messages)
+	}
+}
+
+func TestSyncProducerAssignsOffsetsPerPartition(t *testing.T) {
+	config := NewTestConfig()
+	config.Producer.Partitioner = sarama.NewManualPartitioner
+	sp := NewSyncProducer(t, config).SetNextOffset("test", 1, 42)
+	sp.ExpectSendMessageAndSucceed()
+	sp.ExpectSendMessageAndSucceed()
+	sp.ExpectSendMessageAndSucceed()
+
+	for _, expected := range []struct {
+		partition int32
+		offset    int64
+	}{{1, 42}, {0, 0}, {1, 43}} {
+		msg := &sarama.ProducerMessage{Topic: "test", Partition: expected.partition}
+		partition, offset, err := sp.SendMessage(msg)
+		if err != nil {
+			t.Error(err)
+		}
+		if partition != expected.partition || offset != expected.offset || offset != msg.Offset {
+			t.Errorf("Expected the message to be assigned %d/%d, got %d/%d", expected.partition, expected.offset, partition, offset)
+		}
+	}
+
+	if err := sp.Close(); err != nil {
+		t.Error(err)
+	}
+}
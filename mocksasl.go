@@ -0,0 +1,307 @@
+package sarama
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"encoding/base64"
+	"fmt"
+	"hash"
+	"strings"
+	"sync"
+	"time"
+)
+
+// mockSCRAMIterations is the number of iterations of the SCRAM exchanges of
+// MockSASL, the minimum Kafka accepts.
+const mockSCRAMIterations = 4096
+
+// MockSASL emulates the SASL authentication of a broker, to be set on a
+// MockBroker with SetSASL. It answers the SaslHandshakeRequests and
+// SaslAuthenticateRequests of each connection coherently, verifying the
+// credentials of the users and tokens added to it, for the PLAIN, SCRAM-SHA-256,
+// SCRAM-SHA-512 and OAUTHBEARER mechanisms. The clients must use
+// Net.SASL.Version = SASLHandshakeV1.
+type MockSASL struct {
+	lock          sync.Mutex
+	mechanisms    []string
+	passwords     map[string]string
+	tokens        map[string]bool
+	lifetime      time.Duration
+	authenticated []string
+}
+
+// NewMockSASL returns a MockSASL enabling all the mechanisms it supports, with
+// no user nor token.
+func NewMockSASL() *MockSASL {
+	return &MockSASL{
+		mechanisms: []string{SASLTypePlaintext, SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512, SASLTypeOAuth},
+		passwords:  make(map[string]string),
+		tokens:     make(map[string]bool),
+	}
+}
+
+// SetMechanisms sets the mechanisms enabled, the handshakes for the others
+// being answered with ErrUnsupportedSASLMechanism.
+func (m *MockSASL) SetMechanisms(mechanisms ...string) *MockSASL {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.mechanisms = mechanisms
+	return m
+}
+
+// AddUser adds a user authenticating with password, with PLAIN or SCRAM.
+func (m *MockSASL) AddUser(user, password string) *MockSASL {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.passwords[user] = password
+	return m
+}
+
+// AddToken adds a token authenticating with OAUTHBEARER.
+func (m *MockSASL) AddToken(token string) *MockSASL {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.tokens[token] = true
+	return m
+}
+
+// SetSessionLifetime sets the lifetime of the sessions authenticated, after
+// which the clients must authenticate again, 0 for no limit.
+func (m *MockSASL) SetSessionLifetime(lifetime time.Duration) *MockSASL {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	m.lifetime = lifetime
+	return m
+}
+
+// Authenticated returns the users, and the tokens, authenticated, in order.
+func (m *MockSASL) Authenticated() []string {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return append([]string(nil), m.authenticated...)
+}
+
+// SetSASL makes the broker authenticate the connections with sasl.
+func (b *MockBroker) SetSASL(sasl *MockSASL) {
+	b.lock.Lock()
+	defer b.lock.Unlock()
+	b.sasl = sasl
+}
+
+// NewMockBrokerTLS behaves like NewMockBroker but serves TLS with config.
+func NewMockBrokerTLS(t TestReporter, brokerID int32, config *tls.Config) *MockBroker {
+	listener, err := tls.Listen("tcp", "localhost:0", config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return NewMockBrokerListener(t, brokerID, listener)
+}
+
+// mockSASLConn is the SASL exchange of a connection to a MockBroker.
+type mockSASLConn struct {
+	sasl      *MockSASL
+	mechanism string
+	// scram is the SCRAM exchange in progress, nil unless one is
+	scram *mockSCRAMExchange
+	// challenged is whether an OAUTHBEARER token was rejected
+	challenged bool
+}
+
+// answer returns the response to req if it is part of the SASL exchange, nil
+// otherwise.
+func (c *mockSASLConn) answer(req protocolBody) encoderWithHeader {
+	switch req := req.(type) {
+	case *SaslHandshakeRequest:
+		return c.handshake(req)
+	case *SaslAuthenticateRequest:
+		res := &SaslAuthenticateResponse{Version: req.Version, Err: ErrNoError}
+		principal, challenge, err := c.authenticate(req.SaslAuthBytes)
+		switch {
+		case err != ErrNoError:
+			res.Err = err
+			msg := err.Error()
+			res.ErrorMessage = &msg
+		case principal != "":
+			c.sasl.lock.Lock()
+			c.sasl.authenticated = append(c.sasl.authenticated, principal)
+			res.SessionLifetimeMs = int64(c.sasl.lifetime / time.Millisecond)
+			c.sasl.lock.Unlock()
+		}
+		res.SaslAuthBytes = challenge
+		return res
+	}
+	return nil
+}
+
+func (c *mockSASLConn) handshake(req *SaslHandshakeRequest) encoderWithHeader {
+	c.sasl.lock.Lock()
+	defer c.sasl.lock.Unlock()
+
+	res := &SaslHandshakeResponse{Err: ErrUnsupportedSASLMechanism, EnabledMechanisms: c.sasl.mechanisms}
+	for _, mechanism := range c.sasl.mechanisms {
+		if mechanism == req.Mechanism {
+			res.Err = ErrNoError
+			c.mechanism = mechanism
+			c.scram = nil
+			c.challenged = false
+		}
+	}
+	return res
+}
+
+// authenticate steps the exchange of the mechanism with msg, returning the
+// principal once authenticated and the challenge to send back.
+func (c *mockSASLConn) authenticate(msg []byte) (string, []byte, KError) {
+	c.sasl.lock.Lock()
+	defer c.sasl.lock.Unlock()
+
+	switch c.mechanism {
+	case SASLTypePlaintext:
+		// authzid NUL user NUL password
+		fields := bytes.Split(msg, []byte{0})
+		if len(fields) != 3 {
+			return "", nil, ErrSASLAuthenticationFailed
+		}
+		user, password := string(fields[1]), string(fields[2])
+		if expected, ok := c.sasl.passwords[user]; !ok || expected != password {
+			return "", nil, ErrSASLAuthenticationFailed
+		}
+		return user, nil, ErrNoError
+	case SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512:
+		if c.scram == nil {
+			c.scram = newMockSCRAMExchange(c.mechanism, c.sasl.passwords)
+		}
+		user, challenge, err := c.scram.step(string(msg))
+		if err != ErrNoError || user != "" {
+			c.scram = nil
+		}
+		return user, []byte(challenge), err
+	case SASLTypeOAuth:
+		if c.challenged {
+			// the client aborts the exchange once its token is rejected
+			return "", nil, ErrSASLAuthenticationFailed
+		}
+		for _, field := range strings.Split(string(msg), "\x01") {
+			if token := strings.TrimPrefix(field, "auth=Bearer "); token != field && c.sasl.tokens[token] {
+				return token, nil, ErrNoError
+			}
+		}
+		c.challenged = true
+		return "", []byte(`{"status":"invalid_token"}`), ErrNoError
+	}
+	return "", nil, ErrIllegalSASLState
+}
+
+// mockSCRAMExchange is the server side of a SCRAM exchange, as of RFC 5802.
+type mockSCRAMExchange struct {
+	hash      func() hash.Hash
+	passwords map[string]string
+
+	user            string
+	nonce           string
+	salt            []byte
+	clientFirstBare string
+	serverFirst     string
+}
+
+func newMockSCRAMExchange(mechanism string, passwords map[string]string) *mockSCRAMExchange {
+	h := sha256.New
+	if mechanism == SASLTypeSCRAMSHA512 {
+		h = sha512.New
+	}
+	return &mockSCRAMExchange{hash: h, passwords: passwords}
+}
+
+// step answers the client message msg, returning the user once authenticated.
+func (e *mockSCRAMExchange) step(msg string) (string, string, KError) {
+	if e.serverFirst == "" {
+		// gs2-header, n=user,r=client-nonce
+		parts := strings.SplitN(msg, ",", 3)
+		if len(parts) != 3 {
+			return "", "", ErrSASLAuthenticationFailed
+		}
+		e.clientFirstBare = parts[2]
+		attrs := scramAttributes(e.clientFirstBare)
+		e.user = attrs["n"]
+		if _, ok := e.passwords[e.user]; !ok || attrs["r"] == "" {
+			return "", "", ErrSASLAuthenticationFailed
+		}
+		random := make([]byte, 32)
+		if _, err := rand.Read(random); err != nil {
+			return "", "", ErrSASLAuthenticationFailed
+		}
+		e.salt = random[:16]
+		e.nonce = attrs["r"] + base64.RawStdEncoding.EncodeToString(random[16:])
+		e.serverFirst = fmt.Sprintf("r=%s,s=%s,i=%d", e.nonce, base64.StdEncoding.EncodeToString(e.salt), mockSCRAMIterations)
+		return "", e.serverFirst, ErrNoError
+	}
+
+	// c=channel-binding,r=nonce,p=proof
+	i := strings.LastIndex(msg, ",p=")
+	if i < 0 {
+		return "", "", ErrSASLAuthenticationFailed
+	}
+	withoutProof := msg[:i]
+	proof, err := base64.StdEncoding.DecodeString(msg[i+len(",p="):])
+	if err != nil || scramAttributes(withoutProof)["r"] != e.nonce {
+		return "", "", ErrSASLAuthenticationFailed
+	}
+
+	salted := pbkdf2([]byte(e.passwords[e.user]), e.salt, mockSCRAMIterations, e.hash)
+	clientKey := scramHMAC(e.hash, salted, "Client Key")
+	storedKey := e.hash()
+	storedKey.Write(clientKey)
+	authMessage := e.clientFirstBare + "," + e.serverFirst + "," + withoutProof
+	signature := scramHMAC(e.hash, storedKey.Sum(nil), authMessage)
+	if len(proof) != len(signature) {
+		return "", "", ErrSASLAuthenticationFailed
+	}
+	for j := range proof {
+		proof[j] ^= signature[j]
+	}
+	if !hmac.Equal(proof, clientKey) {
+		return "", "", ErrSASLAuthenticationFailed
+	}
+	serverKey := scramHMAC(e.hash, salted, "Server Key")
+	verifier := base64.StdEncoding.EncodeToString(scramHMAC(e.hash, serverKey, authMessage))
+	return e.user, "v=" + verifier, ErrNoError
+}
+
+// scramAttributes returns the attributes of the SCRAM message msg, by name.
+func scramAttributes(msg string) map[string]string {
+	attrs := make(map[string]string)
+	for _, attr := range strings.Split(msg, ",") {
+		if len(attr) >= 2 && attr[1] == '=' {
+			attrs[attr[:1]] = attr[2:]
+		}
+	}
+	return attrs
+}
+
+func scramHMAC(h func() hash.Hash, key []byte, msg string) []byte {
+	mac := hmac.New(h, key)
+	mac.Write([]byte(msg))
+	return mac.Sum(nil)
+}
+
+// pbkdf2 derives a key from password as of RFC 8018, of the size of h.
+func pbkdf2(password, salt []byte, iterations int, h func() hash.Hash) []byte {
+	mac := hmac.New(h, password)
+	mac.Write(salt)
+	mac.Write([]byte{0, 0, 0, 1})
+	u := mac.Sum(nil)
+	key := append([]byte(nil), u...)
+	for i := 1; i < iterations; i++ {
+		mac.Reset()
+		mac.Write(u)
+		u = mac.Sum(u[:0])
+		for j := range key {
+			key[j] ^= u[j]
+		}
+	}
+	return key
+}
@@ -0,0 +1,168 @@
+package sarama
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"math/big"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/xdg-go/scram"
+)
+
+// xdgSCRAMClient is a SCRAMClient of github.com/xdg-go/scram, as in the
+// sasl_scram_client example.
+type xdgSCRAMClient struct {
+	*scram.Client
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func (x *xdgSCRAMClient) Begin(userName, password, authzID string) (err error) {
+	x.Client, err = x.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	x.ClientConversation = x.Client.NewConversation()
+	return nil
+}
+
+func (x *xdgSCRAMClient) Step(challenge string) (string, error) {
+	return x.ClientConversation.Step(challenge)
+}
+
+func (x *xdgSCRAMClient) Done() bool {
+	return x.ClientConversation.Done()
+}
+
+func TestMockSASL(t *testing.T) {
+	for _, tc := range []struct {
+		name      string
+		mechanism SASLMechanism
+		password  string
+		token     string
+		principal string
+		disabled  bool
+	}{
+		{name: "PLAIN", mechanism: SASLTypePlaintext, password: "secret", principal: "alice"},
+		{name: "PLAIN wrong password", mechanism: SASLTypePlaintext, password: "guess"},
+		{name: "SCRAM-SHA-256", mechanism: SASLTypeSCRAMSHA256, password: "secret", principal: "alice"},
+		{name: "SCRAM-SHA-512", mechanism: SASLTypeSCRAMSHA512, password: "secret", principal: "alice"},
+		{name: "SCRAM-SHA-512 wrong password", mechanism: SASLTypeSCRAMSHA512, password: "guess"},
+		{name: "OAUTHBEARER", mechanism: SASLTypeOAuth, token: "my-token", principal: "my-token"},
+		{name: "OAUTHBEARER wrong token", mechanism: SASLTypeOAuth, token: "other-token"},
+		{name: "PLAIN disabled", mechanism: SASLTypePlaintext, password: "secret", disabled: true},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			sasl := NewMockSASL().AddUser("alice", "secret").AddToken("my-token")
+			if tc.disabled {
+				sasl.SetMechanisms(SASLTypeSCRAMSHA256, SASLTypeSCRAMSHA512)
+			}
+			mb := NewMockBroker(t, 1)
+			defer mb.Close()
+			mb.SetSASL(sasl)
+
+			conf := NewTestConfig()
+			conf.Version = V1_0_0_0
+			conf.Net.SASL.Enable = true
+			conf.Net.SASL.Version = SASLHandshakeV1
+			conf.Net.SASL.Mechanism = tc.mechanism
+			conf.Net.SASL.User = "alice"
+			conf.Net.SASL.Password = tc.password
+			conf.Net.SASL.TokenProvider = newTokenProvider(&AccessToken{Token: tc.token}, nil)
+			conf.Net.SASL.SCRAMClientGeneratorFunc = func() SCRAMClient {
+				if tc.mechanism == SASLTypeSCRAMSHA512 {
+					return &xdgSCRAMClient{HashGeneratorFcn: sha512.New}
+				}
+				return &xdgSCRAMClient{HashGeneratorFcn: sha256.New}
+			}
+
+			broker := NewBroker(mb.Addr())
+			if err := broker.Open(conf); err != nil {
+				t.Fatal(err)
+			}
+			defer func() { _ = broker.Close() }()
+			_, err := broker.Connected()
+
+			switch {
+			case tc.principal != "":
+				if err != nil {
+					t.Fatalf("expected the authentication to succeed, got %v", err)
+				}
+				if authenticated := sasl.Authenticated(); len(authenticated) != 1 || authenticated[0] != tc.principal {
+					t.Errorf("expected %s to be authenticated, got %v", tc.principal, authenticated)
+				}
+			case tc.disabled:
+				if !errors.Is(err, ErrUnsupportedSASLMechanism) {
+					t.Errorf("expected the mechanism to be unsupported, got %v", err)
+				}
+			default:
+				if !errors.Is(err, ErrSASLAuthenticationFailed) {
+					t.Errorf("expected the authentication to fail, got %v", err)
+				}
+				if authenticated := sasl.Authenticated(); len(authenticated) != 0 {
+					t.Errorf("expected nobody to be authenticated, got %v", authenticated)
+				}
+			}
+		})
+	}
+}
+
+func TestMockBrokerTLS(t *testing.T) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		IPAddresses:  []net.IP{net.IPv4(127, 0, 0, 1)},
+		DNSNames:     []string{"localhost"},
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	mb := NewMockBrokerTLS(t, 1, &tls.Config{
+		Certificates: []tls.Certificate{{Certificate: [][]byte{der}, PrivateKey: key}},
+	})
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).SetBroker(mb.Addr(), mb.BrokerID()),
+	})
+	mb.SetSASL(NewMockSASL().AddUser("alice", "secret"))
+
+	conf := NewTestConfig()
+	conf.Net.TLS.Enable = true
+	conf.Net.TLS.Config = &tls.Config{RootCAs: pool, ServerName: "localhost"}
+	conf.Net.SASL.Enable = true
+	conf.Net.SASL.Version = SASLHandshakeV1
+	conf.Net.SASL.User = "alice"
+	conf.Net.SASL.Password = "secret"
+	conf.Version = V1_0_0_0
+
+	broker := NewBroker(mb.Addr())
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, broker)
+	if _, err := broker.GetMetadata(&MetadataRequest{}); err != nil {
+		t.Errorf("expected the request to succeed over TLS once authenticated, got %v", err)
+	}
+}
@@ -13,19 +13,30 @@ const ReceiveTime int64 = -1
 const GroupGenerationUndefined = -1
 
 type offsetCommitRequestBlock struct {
-	offset    int64
-	timestamp int64
-	metadata  string
+	offset               int64
+	committedLeaderEpoch int32 // Only used in version 6+
+	timestamp            int64
+	metadata             string
 }
 
-func (b *offsetCommitRequestBlock) encode(pe packetEncoder, version int16) error {
+func (b *offsetCommitRequestBlock) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt64(b.offset)
+	if version >= 6 {
+		pe.putInt32(b.committedLeaderEpoch)
+	}
 	if version == 1 {
 		pe.putInt64(b.timestamp)
 	} else if b.timestamp != 0 {
-		Logger.Println("Non-zero timestamp specified for OffsetCommitRequest not v1, it will be ignored")
+		warnf("Non-zero timestamp specified for OffsetCommitRequest not v1, it will be ignored")
 	}
 
+	if version >= 8 {
+		if err = pe.putCompactString(b.metadata); err != nil {
+			return err
+		}
+		pe.putEmptyTaggedFieldArray()
+		return nil
+	}
 	return pe.putString(b.metadata)
 }
 
@@ -33,20 +44,34 @@ func (b *offsetCommitRequestBlock) decode(pd packetDecoder, version int16) (err
 	if b.offset, err = pd.getInt64(); err != nil {
 		return err
 	}
+	b.committedLeaderEpoch = -1
+	if version >= 6 {
+		if b.committedLeaderEpoch, err = pd.getInt32(); err != nil {
+			return err
+		}
+	}
 	if version == 1 {
 		if b.timestamp, err = pd.getInt64(); err != nil {
 			return err
 		}
 	}
+	if version >= 8 {
+		if b.metadata, err = pd.getCompactString(); err != nil {
+			return err
+		}
+		_, err = pd.getEmptyTaggedFieldArray()
+		return err
+	}
 	b.metadata, err = pd.getString()
 	return err
 }
 
 type OffsetCommitRequest struct {
 	ConsumerGroup           string
-	ConsumerGroupGeneration int32  // v1 or later
-	ConsumerID              string // v1 or later
-	RetentionTime           int64  // v2 or later
+	ConsumerGroupGeneration int32   // v1 or later
+	ConsumerID              string  // v1 or later
+	GroupInstanceId         *string // v7 or later
+	RetentionTime           int64   // v2 to v4
 
 	// Version can be:
 	// - 0 (kafka 0.8.1 and later)
@@ -54,55 +79,92 @@ type OffsetCommitRequest struct {
 	// - 2 (kafka 0.9.0 and later)
 	// - 3 (kafka 0.11.0 and later)
 	// - 4 (kafka 2.0.0 and later)
+	// - 5&6 (kafka 2.1.0 and later)
+	// - 7 (kafka 2.3.0 and later)
+	// - 8 (kafka 2.4.0 and later)
 	Version int16
 	blocks  map[string]map[int32]*offsetCommitRequestBlock
 }
 
-func (r *OffsetCommitRequest) encode(pe packetEncoder) error {
-	if r.Version < 0 || r.Version > 4 {
+func (r *OffsetCommitRequest) encode(pe packetEncoder) (err error) {
+	if r.Version < 0 || r.Version > 8 {
 		return PacketEncodingError{"invalid or unsupported OffsetCommitRequest version field"}
 	}
 
-	if err := pe.putString(r.ConsumerGroup); err != nil {
+	if r.Version >= 8 {
+		err = pe.putCompactString(r.ConsumerGroup)
+	} else {
+		err = pe.putString(r.ConsumerGroup)
+	}
+	if err != nil {
 		return err
 	}
 
 	if r.Version >= 1 {
 		pe.putInt32(r.ConsumerGroupGeneration)
-		if err := pe.putString(r.ConsumerID); err != nil {
+		if r.Version >= 8 {
+			err = pe.putCompactString(r.ConsumerID)
+		} else {
+			err = pe.putString(r.ConsumerID)
+		}
+		if err != nil {
 			return err
 		}
 	} else {
 		if r.ConsumerGroupGeneration != 0 {
-			Logger.Println("Non-zero ConsumerGroupGeneration specified for OffsetCommitRequest v0, it will be ignored")
+			warnf("Non-zero ConsumerGroupGeneration specified for OffsetCommitRequest v0, it will be ignored")
 		}
 		if r.ConsumerID != "" {
-			Logger.Println("Non-empty ConsumerID specified for OffsetCommitRequest v0, it will be ignored")
+			warnf("Non-empty ConsumerID specified for OffsetCommitRequest v0, it will be ignored")
 		}
 	}
 
-	if r.Version >= 2 {
+	if r.Version >= 8 {
+		err = pe.putNullableCompactString(r.GroupInstanceId)
+	} else if r.Version >= 7 {
+		err = pe.putNullableString(r.GroupInstanceId)
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.Version >= 2 && r.Version <= 4 {
 		pe.putInt64(r.RetentionTime)
 	} else if r.RetentionTime != 0 {
-		Logger.Println("Non-zero RetentionTime specified for OffsetCommitRequest version <2, it will be ignored")
+		warnf("Non-zero RetentionTime specified for OffsetCommitRequest version <2 or >4, it will be ignored")
 	}
 
-	if err := pe.putArrayLength(len(r.blocks)); err != nil {
+	if r.Version >= 8 {
+		pe.putCompactArrayLength(len(r.blocks))
+	} else if err = pe.putArrayLength(len(r.blocks)); err != nil {
 		return err
 	}
 	for topic, partitions := range r.blocks {
-		if err := pe.putString(topic); err != nil {
+		if r.Version >= 8 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
+		if err != nil {
 			return err
 		}
-		if err := pe.putArrayLength(len(partitions)); err != nil {
+		if r.Version >= 8 {
+			pe.putCompactArrayLength(len(partitions))
+		} else if err = pe.putArrayLength(len(partitions)); err != nil {
 			return err
 		}
 		for partition, block := range partitions {
 			pe.putInt32(partition)
-			if err := block.encode(pe, r.Version); err != nil {
+			if err = block.encode(pe, r.Version); err != nil {
 				return err
 			}
 		}
+		if r.Version >= 8 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+	if r.Version >= 8 {
+		pe.putEmptyTaggedFieldArray()
 	}
 	return nil
 }
@@ -110,7 +172,12 @@ func (r *OffsetCommitRequest) encode(pe packetEncoder) error {
 func (r *OffsetCommitRequest) decode(pd packetDecoder, version int16) (err error) {
 	r.Version = version
 
-	if r.ConsumerGroup, err = pd.getString(); err != nil {
+	if r.Version >= 8 {
+		r.ConsumerGroup, err = pd.getCompactString()
+	} else {
+		r.ConsumerGroup, err = pd.getString()
+	}
+	if err != nil {
 		return err
 	}
 
@@ -118,31 +185,59 @@ func (r *OffsetCommitRequest) decode(pd packetDecoder, version int16) (err error
 		if r.ConsumerGroupGeneration, err = pd.getInt32(); err != nil {
 			return err
 		}
-		if r.ConsumerID, err = pd.getString(); err != nil {
+		if r.Version >= 8 {
+			r.ConsumerID, err = pd.getCompactString()
+		} else {
+			r.ConsumerID, err = pd.getString()
+		}
+		if err != nil {
 			return err
 		}
 	}
 
-	if r.Version >= 2 {
+	if r.Version >= 8 {
+		r.GroupInstanceId, err = pd.getCompactNullableString()
+	} else if r.Version >= 7 {
+		r.GroupInstanceId, err = pd.getNullableString()
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.Version >= 2 && r.Version <= 4 {
 		if r.RetentionTime, err = pd.getInt64(); err != nil {
 			return err
 		}
 	}
 
-	topicCount, err := pd.getArrayLength()
+	var topicCount int
+	if r.Version >= 8 {
+		topicCount, err = pd.getCompactArrayLength()
+	} else {
+		topicCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
-	if topicCount == 0 {
-		return nil
+	if topicCount > 0 {
+		r.blocks = make(map[string]map[int32]*offsetCommitRequestBlock)
 	}
-	r.blocks = make(map[string]map[int32]*offsetCommitRequestBlock)
 	for i := 0; i < topicCount; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if r.Version >= 8 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
-		partitionCount, err := pd.getArrayLength()
+		var partitionCount int
+		if r.Version >= 8 {
+			partitionCount, err = pd.getCompactArrayLength()
+		} else {
+			partitionCount, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -158,6 +253,16 @@ func (r *OffsetCommitRequest) decode(pd packetDecoder, version int16) (err error
 			}
 			r.blocks[topic][partition] = block
 		}
+		if r.Version >= 8 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+	if r.Version >= 8 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -171,6 +276,9 @@ func (r *OffsetCommitRequest) version() int16 {
 }
 
 func (r *OffsetCommitRequest) headerVersion() int16 {
+	if r.Version >= 8 {
+		return 2
+	}
 	return 1
 }
 
@@ -184,12 +292,24 @@ func (r *OffsetCommitRequest) requiredVersion() KafkaVersion {
 		return V0_11_0_0
 	case 4:
 		return V2_0_0_0
+	case 5, 6:
+		return V2_1_0_0
+	case 7:
+		return V2_3_0_0
+	case 8:
+		return V2_4_0_0
 	default:
 		return MinVersion
 	}
 }
 
 func (r *OffsetCommitRequest) AddBlock(topic string, partitionID int32, offset int64, timestamp int64, metadata string) {
+	r.AddBlockWithLeaderEpoch(topic, partitionID, offset, -1, timestamp, metadata)
+}
+
+// AddBlockWithLeaderEpoch adds the offset of a partition along with the epoch
+// of the leader of the last record consumed, sent from version 6.
+func (r *OffsetCommitRequest) AddBlockWithLeaderEpoch(topic string, partitionID int32, offset int64, leaderEpoch int32, timestamp int64, metadata string) {
 	if r.blocks == nil {
 		r.blocks = make(map[string]map[int32]*offsetCommitRequestBlock)
 	}
@@ -198,7 +318,7 @@ func (r *OffsetCommitRequest) AddBlock(topic string, partitionID int32, offset i
 		r.blocks[topic] = make(map[int32]*offsetCommitRequestBlock)
 	}
 
-	r.blocks[topic][partitionID] = &offsetCommitRequestBlock{offset, timestamp, metadata}
+	r.blocks[topic][partitionID] = &offsetCommitRequestBlock{offset, leaderEpoch, timestamp, metadata}
 }
 
 func (r *OffsetCommitRequest) Offset(topic string, partitionID int32) (int64, string, error) {
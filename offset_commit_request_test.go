@@ -61,6 +61,23 @@ var (
 		0x00, 0x00, 0x00, 0x00, 0xDE, 0xAD, 0xBE, 0xEF,
 		0x00, 0x08, 'm', 'e', 't', 'a', 'd', 'a', 't', 'a',
 	}
+
+	offsetCommitRequestOneBlockV8 = []byte{
+		0x07, 'f', 'o', 'o', 'b', 'a', 'r',
+		0x00, 0x00, 0x11, 0x22,
+		0x05, 'c', 'o', 'n', 's',
+		0x05, 'i', 'n', 's', 't',
+		0x02,
+		0x06, 't', 'o', 'p', 'i', 'c',
+		0x02,
+		0x00, 0x00, 0x52, 0x21,
+		0x00, 0x00, 0x00, 0x00, 0xDE, 0xAD, 0xBE, 0xEF,
+		0x00, 0x00, 0x00, 0x07,
+		0x09, 'm', 'e', 't', 'a', 'd', 'a', 't', 'a',
+		0x00,
+		0x00,
+		0x00,
+	}
 )
 
 func TestOffsetCommitRequestV0(t *testing.T) {
@@ -99,3 +116,15 @@ func TestOffsetCommitRequestV2ToV4(t *testing.T) {
 		testRequest(t, fmt.Sprintf("one block v%d", version), request, offsetCommitRequestOneBlockV2)
 	}
 }
+
+func TestOffsetCommitRequestV8(t *testing.T) {
+	instanceId := "inst"
+	request := new(OffsetCommitRequest)
+	request.ConsumerGroup = "foobar"
+	request.ConsumerID = "cons"
+	request.ConsumerGroupGeneration = 0x1122
+	request.GroupInstanceId = &instanceId
+	request.Version = 8
+	request.AddBlockWithLeaderEpoch("topic", 0x5221, 0xDEADBEEF, 7, 0, "metadata")
+	testRequest(t, "one block v8", request, offsetCommitRequestOneBlockV8)
+}
@@ -1,5 +1,7 @@
 package sarama
 
+import "time"
+
 type OffsetCommitResponse struct {
 	Version        int16
 	ThrottleTimeMs int32
@@ -18,24 +20,42 @@ func (r *OffsetCommitResponse) AddError(topic string, partition int32, kerror KE
 	partitions[partition] = kerror
 }
 
-func (r *OffsetCommitResponse) encode(pe packetEncoder) error {
+func (r *OffsetCommitResponse) encode(pe packetEncoder) (err error) {
 	if r.Version >= 3 {
 		pe.putInt32(r.ThrottleTimeMs)
 	}
-	if err := pe.putArrayLength(len(r.Errors)); err != nil {
+	if r.Version >= 8 {
+		pe.putCompactArrayLength(len(r.Errors))
+	} else if err = pe.putArrayLength(len(r.Errors)); err != nil {
 		return err
 	}
 	for topic, partitions := range r.Errors {
-		if err := pe.putString(topic); err != nil {
+		if r.Version >= 8 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
+		if err != nil {
 			return err
 		}
-		if err := pe.putArrayLength(len(partitions)); err != nil {
+		if r.Version >= 8 {
+			pe.putCompactArrayLength(len(partitions))
+		} else if err = pe.putArrayLength(len(partitions)); err != nil {
 			return err
 		}
 		for partition, kerror := range partitions {
 			pe.putInt32(partition)
 			pe.putInt16(int16(kerror))
+			if r.Version >= 8 {
+				pe.putEmptyTaggedFieldArray()
+			}
 		}
+		if r.Version >= 8 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+	if r.Version >= 8 {
+		pe.putEmptyTaggedFieldArray()
 	}
 	return nil
 }
@@ -50,19 +70,36 @@ func (r *OffsetCommitResponse) decode(pd packetDecoder, version int16) (err erro
 		}
 	}
 
-	numTopics, err := pd.getArrayLength()
-	if err != nil || numTopics == 0 {
+	var numTopics int
+	if version >= 8 {
+		numTopics, err = pd.getCompactArrayLength()
+	} else {
+		numTopics, err = pd.getArrayLength()
+	}
+	if err != nil {
 		return err
 	}
 
-	r.Errors = make(map[string]map[int32]KError, numTopics)
+	if numTopics > 0 {
+		r.Errors = make(map[string]map[int32]KError, numTopics)
+	}
 	for i := 0; i < numTopics; i++ {
-		name, err := pd.getString()
+		var name string
+		if version >= 8 {
+			name, err = pd.getCompactString()
+		} else {
+			name, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
 
-		numErrors, err := pd.getArrayLength()
+		var numErrors int
+		if version >= 8 {
+			numErrors, err = pd.getCompactArrayLength()
+		} else {
+			numErrors, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -80,6 +117,24 @@ func (r *OffsetCommitResponse) decode(pd packetDecoder, version int16) (err erro
 				return err
 			}
 			r.Errors[name][id] = KError(tmp)
+
+			if version >= 8 {
+				if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+					return err
+				}
+			}
+		}
+
+		if version >= 8 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if version >= 8 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
 		}
 	}
 
@@ -95,6 +150,9 @@ func (r *OffsetCommitResponse) version() int16 {
 }
 
 func (r *OffsetCommitResponse) headerVersion() int16 {
+	if r.Version >= 8 {
+		return 1
+	}
 	return 0
 }
 
@@ -108,7 +166,32 @@ func (r *OffsetCommitResponse) requiredVersion() KafkaVersion {
 		return V0_11_0_0
 	case 4:
 		return V2_0_0_0
+	case 5, 6:
+		return V2_1_0_0
+	case 7:
+		return V2_3_0_0
+	case 8:
+		return V2_4_0_0
 	default:
 		return MinVersion
 	}
 }
+
+func (r *OffsetCommitResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTimeMs) * time.Millisecond
+}
+
+func (r *OffsetCommitResponse) shouldClientThrottle(version int16) bool {
+	return version >= 4
+}
+
+func (r *OffsetCommitResponse) hasErrorCode() bool {
+	for _, partitions := range r.Errors {
+		for _, kerr := range partitions {
+			if kerr != ErrNoError {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -24,7 +24,8 @@ func TestNormalOffsetCommitResponse(t *testing.T) {
 }
 
 func TestOffsetCommitResponseWithThrottleTime(t *testing.T) {
-	for version := 3; version <= 4; version++ {
+	// every version since 3 has the throttle time, up to the flexible 8
+	for version := 3; version <= 8; version++ {
 		response := OffsetCommitResponse{
 			Version:        int16(version),
 			ThrottleTimeMs: 123,
@@ -1,5 +1,7 @@
 package sarama
 
+import "time"
+
 type OffsetFetchResponseBlock struct {
 	Offset      int64
 	LeaderEpoch int32
@@ -278,3 +280,25 @@ func (r *OffsetFetchResponse) AddBlock(topic string, partition int32, block *Off
 	}
 	partitions[partition] = block
 }
+
+func (r *OffsetFetchResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTimeMs) * time.Millisecond
+}
+
+func (r *OffsetFetchResponse) shouldClientThrottle(version int16) bool {
+	return version >= 4
+}
+
+func (r *OffsetFetchResponse) hasErrorCode() bool {
+	if r.Err != ErrNoError {
+		return true
+	}
+	for _, partitions := range r.Blocks {
+		for _, block := range partitions {
+			if block.Err != ErrNoError {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -148,7 +148,22 @@ func (om *offsetManager) fetchInitialOffset(topic string, partition int32, retri
 	}
 
 	req := new(OffsetFetchRequest)
-	req.Version = 1
+	switch {
+	case om.conf.Version.IsAtLeast(V2_5_0_0):
+		req.Version = 7
+	case om.conf.Version.IsAtLeast(V2_4_0_0):
+		req.Version = 6
+	case om.conf.Version.IsAtLeast(V2_1_0_0):
+		req.Version = 5
+	case om.conf.Version.IsAtLeast(V2_0_0_0):
+		req.Version = 4
+	case om.conf.Version.IsAtLeast(V0_11_0_0):
+		req.Version = 3
+	case om.conf.Version.IsAtLeast(V0_10_2_0):
+		req.Version = 2
+	default:
+		req.Version = 1
+	}
 	req.ConsumerGroup = om.group
 	req.AddPartition(topic, partition)
 
@@ -161,33 +176,38 @@ func (om *offsetManager) fetchInitialOffset(topic string, partition int32, retri
 		return om.fetchInitialOffset(topic, partition, retries-1)
 	}
 
-	block := resp.GetBlock(topic, partition)
-	if block == nil {
-		return 0, "", ErrIncompleteResponse
+	// since version 2, the errors of the group come without the blocks
+	kerr := resp.Err
+	var block *OffsetFetchResponseBlock
+	if kerr == ErrNoError {
+		if block = resp.GetBlock(topic, partition); block == nil {
+			return 0, "", ErrIncompleteResponse
+		}
+		kerr = block.Err
 	}
 
-	switch block.Err {
+	switch kerr {
 	case ErrNoError:
 		return block.Offset, block.Metadata, nil
 	case ErrNotCoordinatorForConsumer:
 		if retries <= 0 {
-			return 0, "", block.Err
+			return 0, "", kerr
 		}
 		om.releaseCoordinator(broker)
 		return om.fetchInitialOffset(topic, partition, retries-1)
 	case ErrOffsetsLoadInProgress:
 		if retries <= 0 {
-			return 0, "", block.Err
+			return 0, "", kerr
 		}
 		backoff := om.computeBackoff(retries)
 		select {
 		case <-om.closing:
-			return 0, "", block.Err
+			return 0, "", kerr
 		case <-time.After(backoff):
 		}
 		return om.fetchInitialOffset(topic, partition, retries-1)
 	default:
-		return 0, "", block.Err
+		return 0, "", kerr
 	}
 }
 
@@ -582,7 +602,7 @@ func (pom *partitionOffsetManager) handleError(err error) {
 	if pom.parent.conf.Consumer.Return.Errors {
 		pom.errors <- cErr
 	} else {
-		Logger.Println(cErr)
+		logWith(topicField(pom.topic), partitionField(pom.partition), groupField(pom.parent.group)).warnf("%v", cErr)
 	}
 }
 
@@ -1,20 +1,33 @@
 package sarama
 
 type offsetRequestBlock struct {
-	time       int64
-	maxOffsets int32 // Only used in version 0
+	leaderEpoch int32 // Only used in version 4+
+	time        int64
+	maxOffsets  int32 // Only used in version 0
 }
 
 func (b *offsetRequestBlock) encode(pe packetEncoder, version int16) error {
+	if version >= 4 {
+		pe.putInt32(b.leaderEpoch)
+	}
 	pe.putInt64(b.time)
 	if version == 0 {
 		pe.putInt32(b.maxOffsets)
 	}
+	if version >= 6 {
+		pe.putEmptyTaggedFieldArray()
+	}
 
 	return nil
 }
 
 func (b *offsetRequestBlock) decode(pd packetDecoder, version int16) (err error) {
+	b.leaderEpoch = -1
+	if version >= 4 {
+		if b.leaderEpoch, err = pd.getInt32(); err != nil {
+			return err
+		}
+	}
 	if b.time, err = pd.getInt64(); err != nil {
 		return err
 	}
@@ -23,6 +36,11 @@ func (b *offsetRequestBlock) decode(pd packetDecoder, version int16) (err error)
 			return err
 		}
 	}
+	if version >= 6 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 	return nil
 }
 
@@ -46,17 +64,24 @@ func (r *OffsetRequest) encode(pe packetEncoder) error {
 		pe.putBool(r.IsolationLevel == ReadCommitted)
 	}
 
-	err := pe.putArrayLength(len(r.blocks))
-	if err != nil {
+	var err error
+	if r.Version >= 6 {
+		pe.putCompactArrayLength(len(r.blocks))
+	} else if err = pe.putArrayLength(len(r.blocks)); err != nil {
 		return err
 	}
 	for topic, partitions := range r.blocks {
-		err = pe.putString(topic)
+		if r.Version >= 6 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
 		if err != nil {
 			return err
 		}
-		err = pe.putArrayLength(len(partitions))
-		if err != nil {
+		if r.Version >= 6 {
+			pe.putCompactArrayLength(len(partitions))
+		} else if err = pe.putArrayLength(len(partitions)); err != nil {
 			return err
 		}
 		for partition, block := range partitions {
@@ -65,6 +90,12 @@ func (r *OffsetRequest) encode(pe packetEncoder) error {
 				return err
 			}
 		}
+		if r.Version >= 6 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+	if r.Version >= 6 {
+		pe.putEmptyTaggedFieldArray()
 	}
 	return nil
 }
@@ -92,20 +123,34 @@ func (r *OffsetRequest) decode(pd packetDecoder, version int16) error {
 		}
 	}
 
-	blockCount, err := pd.getArrayLength()
+	var blockCount int
+	if r.Version >= 6 {
+		blockCount, err = pd.getCompactArrayLength()
+	} else {
+		blockCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
-	if blockCount == 0 {
-		return nil
+	if blockCount > 0 {
+		r.blocks = make(map[string]map[int32]*offsetRequestBlock)
 	}
-	r.blocks = make(map[string]map[int32]*offsetRequestBlock)
 	for i := 0; i < blockCount; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if r.Version >= 6 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
-		partitionCount, err := pd.getArrayLength()
+		var partitionCount int
+		if r.Version >= 6 {
+			partitionCount, err = pd.getCompactArrayLength()
+		} else {
+			partitionCount, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -121,6 +166,16 @@ func (r *OffsetRequest) decode(pd packetDecoder, version int16) error {
 			}
 			r.blocks[topic][partition] = block
 		}
+		if r.Version >= 6 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+	if r.Version >= 6 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 	return nil
 }
@@ -134,6 +189,9 @@ func (r *OffsetRequest) version() int16 {
 }
 
 func (r *OffsetRequest) headerVersion() int16 {
+	if r.Version >= 6 {
+		return 2
+	}
 	return 1
 }
 
@@ -143,6 +201,14 @@ func (r *OffsetRequest) requiredVersion() KafkaVersion {
 		return V0_10_1_0
 	case 2:
 		return V0_11_0_0
+	case 3:
+		return V2_0_0_0
+	case 4:
+		return V2_1_0_0
+	case 5:
+		return V2_2_0_0
+	case 6:
+		return V2_5_0_0
 	default:
 		return MinVersion
 	}
@@ -170,6 +236,7 @@ func (r *OffsetRequest) AddBlock(topic string, partitionID int32, time int64, ma
 	}
 
 	tmp := new(offsetRequestBlock)
+	tmp.leaderEpoch = -1
 	tmp.time = time
 	if r.Version == 0 {
 		tmp.maxOffsets = maxOffsets
@@ -42,6 +42,29 @@ var (
 		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
 	}
 
+	offsetRequestOneBlockV4 = []byte{
+		0xFF, 0xFF, 0xFF, 0xFF,
+		0x00, 0x00, 0x00, 0x00, 0x01,
+		0x00, 0x03, 'b', 'a', 'r',
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x04,
+		0xFF, 0xFF, 0xFF, 0xFF,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+	}
+
+	offsetRequestOneBlockV6 = []byte{
+		0xFF, 0xFF, 0xFF, 0xFF,
+		0x01,
+		0x02, 0x04, 'b', 'a', 'r',
+		0x02,
+		0x00, 0x00, 0x00, 0x04,
+		0xFF, 0xFF, 0xFF, 0xFF,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x01,
+		0x00, // partition tagged fields
+		0x00, // topic tagged fields
+		0x00, // tagged fields
+	}
+
 	offsetRequestReplicaID = []byte{
 		0x00, 0x00, 0x00, 0x2a,
 		0x00, 0x00, 0x00, 0x00,
@@ -75,6 +98,21 @@ func TestOffsetRequestV2(t *testing.T) {
 	testRequest(t, "one block", request, offsetRequestOneBlockReadCommittedV2)
 }
 
+func TestOffsetRequestV4(t *testing.T) {
+	request := new(OffsetRequest)
+	request.Version = 4
+	request.AddBlock("bar", 4, 1, 2)
+	testRequest(t, "one block", request, offsetRequestOneBlockV4)
+}
+
+func TestOffsetRequestV6(t *testing.T) {
+	request := new(OffsetRequest)
+	request.Version = 6
+	request.IsolationLevel = ReadCommitted
+	request.AddBlock("bar", 4, 1, 2)
+	testRequest(t, "one block", request, offsetRequestOneBlockV6)
+}
+
 func TestOffsetRequestReplicaID(t *testing.T) {
 	request := new(OffsetRequest)
 	replicaID := int32(42)
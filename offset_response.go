@@ -1,10 +1,13 @@
 package sarama
 
+import "time"
+
 type OffsetResponseBlock struct {
-	Err       KError
-	Offsets   []int64 // Version 0
-	Offset    int64   // Version 1
-	Timestamp int64   // Version 1
+	Err         KError
+	Offsets     []int64 // Version 0
+	Offset      int64   // Version 1
+	Timestamp   int64   // Version 1
+	LeaderEpoch int32   // Version 4
 }
 
 func (b *OffsetResponseBlock) decode(pd packetDecoder, version int16) (err error) {
@@ -13,6 +16,7 @@ func (b *OffsetResponseBlock) decode(pd packetDecoder, version int16) (err error
 		return err
 	}
 	b.Err = KError(tmp)
+	b.LeaderEpoch = -1
 
 	if version == 0 {
 		b.Offsets, err = pd.getInt64Array()
@@ -30,6 +34,18 @@ func (b *OffsetResponseBlock) decode(pd packetDecoder, version int16) (err error
 		return err
 	}
 
+	if version >= 4 {
+		if b.LeaderEpoch, err = pd.getInt32(); err != nil {
+			return err
+		}
+	}
+
+	if version >= 6 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	// For backwards compatibility put the offset in the offsets array too
 	b.Offsets = []int64{b.Offset}
 
@@ -45,6 +61,12 @@ func (b *OffsetResponseBlock) encode(pe packetEncoder, version int16) (err error
 
 	pe.putInt64(b.Timestamp)
 	pe.putInt64(b.Offset)
+	if version >= 4 {
+		pe.putInt32(b.LeaderEpoch)
+	}
+	if version >= 6 {
+		pe.putEmptyTaggedFieldArray()
+	}
 
 	return nil
 }
@@ -56,6 +78,7 @@ type OffsetResponse struct {
 }
 
 func (r *OffsetResponse) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
 	if version >= 2 {
 		r.ThrottleTimeMs, err = pd.getInt32()
 		if err != nil {
@@ -63,19 +86,34 @@ func (r *OffsetResponse) decode(pd packetDecoder, version int16) (err error) {
 		}
 	}
 
-	numTopics, err := pd.getArrayLength()
+	var numTopics int
+	if version >= 6 {
+		numTopics, err = pd.getCompactArrayLength()
+	} else {
+		numTopics, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
 
 	r.Blocks = make(map[string]map[int32]*OffsetResponseBlock, numTopics)
 	for i := 0; i < numTopics; i++ {
-		name, err := pd.getString()
+		var name string
+		if version >= 6 {
+			name, err = pd.getCompactString()
+		} else {
+			name, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
 
-		numBlocks, err := pd.getArrayLength()
+		var numBlocks int
+		if version >= 6 {
+			numBlocks, err = pd.getCompactArrayLength()
+		} else {
+			numBlocks, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -95,6 +133,18 @@ func (r *OffsetResponse) decode(pd packetDecoder, version int16) (err error) {
 			}
 			r.Blocks[name][id] = block
 		}
+
+		if version >= 6 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if version >= 6 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -132,15 +182,24 @@ func (r *OffsetResponse) encode(pe packetEncoder) (err error) {
 		pe.putInt32(r.ThrottleTimeMs)
 	}
 
-	if err = pe.putArrayLength(len(r.Blocks)); err != nil {
+	if r.Version >= 6 {
+		pe.putCompactArrayLength(len(r.Blocks))
+	} else if err = pe.putArrayLength(len(r.Blocks)); err != nil {
 		return err
 	}
 
 	for topic, partitions := range r.Blocks {
-		if err = pe.putString(topic); err != nil {
+		if r.Version >= 6 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
+		if err != nil {
 			return err
 		}
-		if err = pe.putArrayLength(len(partitions)); err != nil {
+		if r.Version >= 6 {
+			pe.putCompactArrayLength(len(partitions))
+		} else if err = pe.putArrayLength(len(partitions)); err != nil {
 			return err
 		}
 		for partition, block := range partitions {
@@ -149,6 +208,13 @@ func (r *OffsetResponse) encode(pe packetEncoder) (err error) {
 				return err
 			}
 		}
+		if r.Version >= 6 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if r.Version >= 6 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
@@ -163,6 +229,9 @@ func (r *OffsetResponse) version() int16 {
 }
 
 func (r *OffsetResponse) headerVersion() int16 {
+	if r.Version >= 6 {
+		return 1
+	}
 	return 0
 }
 
@@ -172,6 +241,14 @@ func (r *OffsetResponse) requiredVersion() KafkaVersion {
 		return V0_10_1_0
 	case 2:
 		return V0_11_0_0
+	case 3:
+		return V2_0_0_0
+	case 4:
+		return V2_1_0_0
+	case 5:
+		return V2_2_0_0
+	case 6:
+		return V2_5_0_0
 	default:
 		return MinVersion
 	}
@@ -188,5 +265,24 @@ func (r *OffsetResponse) AddTopicPartition(topic string, partition int32, offset
 		byTopic = make(map[int32]*OffsetResponseBlock)
 		r.Blocks[topic] = byTopic
 	}
-	byTopic[partition] = &OffsetResponseBlock{Offsets: []int64{offset}, Offset: offset}
+	byTopic[partition] = &OffsetResponseBlock{Offsets: []int64{offset}, Offset: offset, LeaderEpoch: -1}
+}
+
+func (r *OffsetResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTimeMs) * time.Millisecond
+}
+
+func (r *OffsetResponse) shouldClientThrottle(version int16) bool {
+	return version >= 3
+}
+
+func (r *OffsetResponse) hasErrorCode() bool {
+	for _, partitions := range r.Blocks {
+		for _, block := range partitions {
+			if block.Err != ErrNoError {
+				return true
+			}
+		}
+	}
+	return false
 }
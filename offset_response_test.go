@@ -38,6 +38,35 @@ var (
 		0x00, 0x00, 0x01, 0x58, 0x1A, 0xE6, 0x48, 0x86,
 		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x06,
 	}
+
+	normalOffsetResponseV4 = []byte{
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x01,
+
+		0x00, 0x01, 'z',
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x00, 0x02,
+		0x00, 0x00,
+		0x00, 0x00, 0x01, 0x58, 0x1A, 0xE6, 0x48, 0x86,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x06,
+		0x00, 0x00, 0x00, 0x03,
+	}
+
+	normalOffsetResponseV6 = []byte{
+		0x00, 0x00, 0x00, 0x00,
+		0x02,
+
+		0x02, 'z',
+		0x02,
+		0x00, 0x00, 0x00, 0x02,
+		0x00, 0x00,
+		0x00, 0x00, 0x01, 0x58, 0x1A, 0xE6, 0x48, 0x86,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x06,
+		0x00, 0x00, 0x00, 0x03,
+		0x00,
+		0x00,
+		0x00,
+	}
 )
 
 func TestEmptyOffsetResponse(t *testing.T) {
@@ -115,3 +144,33 @@ func TestNormalOffsetResponseV1(t *testing.T) {
 		t.Fatal("Decoding produced invalid offsets for topic z partition 2.")
 	}
 }
+
+func TestNormalOffsetResponseV4(t *testing.T) {
+	response := OffsetResponse{}
+
+	testVersionDecodable(t, "normal", &response, normalOffsetResponseV4, 4)
+
+	block := response.GetBlock("z", 2)
+	if block == nil {
+		t.Fatal("Decoding produced no block for topic z partition 2.")
+	}
+	if block.Timestamp != 1477920049286 || block.Offset != 6 || block.LeaderEpoch != 3 {
+		t.Fatal("Decoding produced an invalid block for topic z partition 2.", block)
+	}
+}
+
+func TestNormalOffsetResponseV6(t *testing.T) {
+	response := OffsetResponse{}
+
+	testVersionDecodable(t, "normal", &response, normalOffsetResponseV6, 6)
+
+	block := response.GetBlock("z", 2)
+	if block == nil {
+		t.Fatal("Decoding produced no block for topic z partition 2.")
+	}
+	if block.Timestamp != 1477920049286 || block.Offset != 6 || block.LeaderEpoch != 3 {
+		t.Fatal("Decoding produced an invalid block for topic z partition 2.", block)
+	}
+
+	testEncodable(t, "normal", &response, normalOffsetResponseV6)
+}
@@ -0,0 +1,96 @@
+package otelsarama
+
+import (
+	"github.com/Shopify/sarama"
+	"go.opentelemetry.io/otel/propagation"
+)
+
+// ProducerMessageCarrier injects and extracts the trace context in the
+// headers of a ProducerMessage.
+type ProducerMessageCarrier struct {
+	msg *sarama.ProducerMessage
+}
+
+var _ propagation.TextMapCarrier = ProducerMessageCarrier{}
+
+// NewProducerMessageCarrier returns the carrier of the headers of msg.
+func NewProducerMessageCarrier(msg *sarama.ProducerMessage) ProducerMessageCarrier {
+	return ProducerMessageCarrier{msg: msg}
+}
+
+// Get returns the value of the header key.
+func (c ProducerMessageCarrier) Get(key string) string {
+	for _, h := range c.msg.Headers {
+		if string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces the header key with value.
+func (c ProducerMessageCarrier) Set(key, value string) {
+	for i := 0; i < len(c.msg.Headers); i++ {
+		if string(c.msg.Headers[i].Key) == key {
+			c.msg.Headers = append(c.msg.Headers[:i], c.msg.Headers[i+1:]...)
+			i--
+		}
+	}
+	c.msg.Headers = append(c.msg.Headers, sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+// Keys returns the keys of the headers.
+func (c ProducerMessageCarrier) Keys() []string {
+	keys := make([]string, len(c.msg.Headers))
+	for i, h := range c.msg.Headers {
+		keys[i] = string(h.Key)
+	}
+	return keys
+}
+
+// ConsumerMessageCarrier injects and extracts the trace context in the
+// headers of a ConsumerMessage.
+type ConsumerMessageCarrier struct {
+	msg *sarama.ConsumerMessage
+}
+
+var _ propagation.TextMapCarrier = ConsumerMessageCarrier{}
+
+// NewConsumerMessageCarrier returns the carrier of the headers of msg.
+func NewConsumerMessageCarrier(msg *sarama.ConsumerMessage) ConsumerMessageCarrier {
+	return ConsumerMessageCarrier{msg: msg}
+}
+
+// Get returns the value of the header key.
+func (c ConsumerMessageCarrier) Get(key string) string {
+	for _, h := range c.msg.RecordHeaders() {
+		if h != nil && string(h.Key) == key {
+			return string(h.Value)
+		}
+	}
+	return ""
+}
+
+// Set replaces the header key with value.
+func (c ConsumerMessageCarrier) Set(key, value string) {
+	c.msg.RecordHeaders()
+	for i := 0; i < len(c.msg.Headers); i++ {
+		if h := c.msg.Headers[i]; h != nil && string(h.Key) == key {
+			c.msg.Headers = append(c.msg.Headers[:i], c.msg.Headers[i+1:]...)
+			i--
+		}
+	}
+	c.msg.Headers = append(c.msg.Headers, &sarama.RecordHeader{Key: []byte(key), Value: []byte(value)})
+}
+
+// Keys returns the keys of the headers.
+func (c ConsumerMessageCarrier) Keys() []string {
+	headers := c.msg.RecordHeaders()
+	keys := make([]string, 0, len(headers))
+	for _, h := range headers {
+		if h != nil {
+			keys = append(keys, string(h.Key))
+		}
+	}
+	return keys
+}
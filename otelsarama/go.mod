@@ -0,0 +1,12 @@
+module github.com/Shopify/sarama/otelsarama
+
+go 1.16
+
+replace github.com/Shopify/sarama => ../
+
+require (
+	github.com/Shopify/sarama v1.32.0
+	go.opentelemetry.io/otel v1.7.0
+	go.opentelemetry.io/otel/sdk v1.7.0
+	go.opentelemetry.io/otel/trace v1.7.0
+)
@@ -0,0 +1,70 @@
+package otelsarama
+
+import (
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// instrumentationName identifies the tracer of the interceptors.
+const instrumentationName = "github.com/Shopify/sarama/otelsarama"
+
+type config struct {
+	tracerProvider trace.TracerProvider
+	propagators    propagation.TextMapPropagator
+	clientID       string
+	consumerGroup  string
+
+	tracer trace.Tracer
+}
+
+func newConfig(opts []Option) config {
+	c := config{
+		tracerProvider: otel.GetTracerProvider(),
+		propagators:    otel.GetTextMapPropagator(),
+	}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	c.tracer = c.tracerProvider.Tracer(instrumentationName)
+	return c
+}
+
+// Option configures the interceptors.
+type Option func(*config)
+
+// WithTracerProvider sets the provider of the tracer creating the spans
+// (defaults to the global provider).
+func WithTracerProvider(provider trace.TracerProvider) Option {
+	return func(c *config) {
+		if provider != nil {
+			c.tracerProvider = provider
+		}
+	}
+}
+
+// WithPropagators sets the propagators injecting and extracting the trace
+// context in the headers of the messages (defaults to the global ones).
+func WithPropagators(propagators propagation.TextMapPropagator) Option {
+	return func(c *config) {
+		if propagators != nil {
+			c.propagators = propagators
+		}
+	}
+}
+
+// WithClientID records the Config.ClientID of the producer or consumer on
+// the spans, as the messaging.kafka.client_id attribute.
+func WithClientID(clientID string) Option {
+	return func(c *config) {
+		c.clientID = clientID
+	}
+}
+
+// WithConsumerGroup records the consumer group on the spans of the consumer
+// interceptor, as the messaging.kafka.consumer_group attribute.
+func WithConsumerGroup(group string) Option {
+	return func(c *config) {
+		c.consumerGroup = group
+	}
+}
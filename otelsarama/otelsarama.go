@@ -0,0 +1,146 @@
+// Package otelsarama instruments Sarama producers and consumers with
+// OpenTelemetry tracing, through the interceptors of the sarama.Config.
+//
+// The producer interceptor starts a span for each message sent, as a child of
+// the trace context the message carries, if any, and injects the context of
+// that span in its headers. The consumer interceptor starts a span for each
+// message received, as a child of the context extracted from its headers, and
+// replaces it in the headers with the context of that span, so that
+// ContextFromMessage returns the parent of the spans processing the message.
+//
+// The interceptors only see the messages once, so their spans end right away:
+// they mark when the messages are sent and received, not the time it takes to
+// produce or process them. The spans follow the messaging semantic conventions.
+package otelsarama
+
+import (
+	"context"
+	"strconv"
+
+	"github.com/Shopify/sarama"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Configure adds the interceptors to conf, to trace the messages produced and
+// consumed with it.
+func Configure(conf *sarama.Config, opts ...Option) {
+	if conf.ClientID != "" {
+		opts = append([]Option{WithClientID(conf.ClientID)}, opts...)
+	}
+	conf.Producer.Interceptors = append(conf.Producer.Interceptors, NewProducerInterceptor(opts...))
+	conf.Consumer.Interceptors = append(conf.Consumer.Interceptors, NewConsumerInterceptor(opts...))
+}
+
+// ProducerInterceptor is a sarama.ProducerInterceptor tracing the messages
+// sent.
+type ProducerInterceptor struct {
+	conf config
+}
+
+// NewProducerInterceptor returns a ProducerInterceptor, to be added to the
+// Producer.Interceptors of the sarama.Config.
+func NewProducerInterceptor(opts ...Option) *ProducerInterceptor {
+	return &ProducerInterceptor{conf: newConfig(opts)}
+}
+
+// OnSend starts and ends the span of msg, and injects its context in the
+// headers of msg.
+func (pi *ProducerInterceptor) OnSend(msg *sarama.ProducerMessage) {
+	carrier := NewProducerMessageCarrier(msg)
+	ctx := pi.conf.propagators.Extract(context.Background(), carrier)
+
+	attrs := pi.conf.attributes(msg.Topic)
+	if key, err := encodeKey(msg.Key); err == nil && key != nil {
+		attrs = append(attrs, semconv.MessagingKafkaMessageKeyKey.String(string(key)))
+	}
+	if msg.Value == nil {
+		attrs = append(attrs, semconv.MessagingKafkaTombstoneKey.Bool(true))
+	}
+
+	ctx, span := pi.conf.tracer.Start(ctx, msg.Topic+" send",
+		trace.WithSpanKind(trace.SpanKindProducer),
+		trace.WithAttributes(attrs...),
+	)
+	pi.conf.propagators.Inject(ctx, carrier)
+	span.End()
+}
+
+// ConsumerInterceptor is a sarama.ConsumerInterceptor tracing the messages
+// received.
+type ConsumerInterceptor struct {
+	conf config
+}
+
+// NewConsumerInterceptor returns a ConsumerInterceptor, to be added to the
+// Consumer.Interceptors of the sarama.Config.
+func NewConsumerInterceptor(opts ...Option) *ConsumerInterceptor {
+	return &ConsumerInterceptor{conf: newConfig(opts)}
+}
+
+// OnConsume starts and ends the span of msg, and replaces the trace context in
+// the headers of msg with the context of that span.
+func (ci *ConsumerInterceptor) OnConsume(msg *sarama.ConsumerMessage) {
+	carrier := NewConsumerMessageCarrier(msg)
+	ctx := ci.conf.propagators.Extract(context.Background(), carrier)
+
+	attrs := append(ci.conf.attributes(msg.Topic),
+		semconv.MessagingOperationReceive,
+		semconv.MessagingKafkaPartitionKey.Int64(int64(msg.Partition)),
+		semconv.MessagingMessageIDKey.String(strconv.FormatInt(msg.Offset, 10)),
+		semconv.MessagingMessagePayloadSizeBytesKey.Int(len(msg.Value)),
+	)
+	if msg.Key != nil {
+		attrs = append(attrs, semconv.MessagingKafkaMessageKeyKey.String(string(msg.Key)))
+	}
+	if msg.Value == nil {
+		attrs = append(attrs, semconv.MessagingKafkaTombstoneKey.Bool(true))
+	}
+	if ci.conf.consumerGroup != "" {
+		attrs = append(attrs, semconv.MessagingKafkaConsumerGroupKey.String(ci.conf.consumerGroup))
+	}
+
+	ctx, span := ci.conf.tracer.Start(ctx, msg.Topic+" receive",
+		trace.WithSpanKind(trace.SpanKindConsumer),
+		trace.WithAttributes(attrs...),
+	)
+	ci.conf.propagators.Inject(ctx, carrier)
+	span.End()
+}
+
+// Inject injects the trace context of ctx in the headers of msg, so that the
+// span of the producer interceptor is its child. It uses the global
+// propagators unless others are given.
+func Inject(ctx context.Context, msg *sarama.ProducerMessage, opts ...Option) {
+	newConfig(opts).propagators.Inject(ctx, NewProducerMessageCarrier(msg))
+}
+
+// ContextFromMessage returns ctx with the trace context extracted from the
+// headers of msg, that of the span of the consumer interceptor, to start the
+// spans processing msg from. It uses the global propagators unless others are
+// given.
+func ContextFromMessage(ctx context.Context, msg *sarama.ConsumerMessage, opts ...Option) context.Context {
+	return newConfig(opts).propagators.Extract(ctx, NewConsumerMessageCarrier(msg))
+}
+
+func (c *config) attributes(topic string) []attribute.KeyValue {
+	attrs := []attribute.KeyValue{
+		semconv.MessagingSystemKey.String("kafka"),
+		semconv.MessagingDestinationKindTopic,
+		semconv.MessagingDestinationKey.String(topic),
+	}
+	if c.clientID != "" {
+		attrs = append(attrs, semconv.MessagingKafkaClientIDKey.String(c.clientID))
+	}
+	return attrs
+}
+
+// encodeKey returns the bytes of the key of a ProducerMessage, which the
+// producer encodes again when sending it.
+func encodeKey(key sarama.Encoder) ([]byte, error) {
+	if key == nil {
+		return nil, nil
+	}
+	return key.Encode()
+}
@@ -0,0 +1,113 @@
+package otelsarama
+
+import (
+	"context"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.10.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+func newTestOptions() (*tracetest.SpanRecorder, []Option) {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	return recorder, []Option{
+		WithTracerProvider(provider),
+		WithPropagators(propagation.TraceContext{}),
+		WithClientID("my_client"),
+	}
+}
+
+func hasAttribute(span sdktrace.ReadOnlySpan, kv attribute.KeyValue) bool {
+	for _, attr := range span.Attributes() {
+		if attr == kv {
+			return true
+		}
+	}
+	return false
+}
+
+func TestProducerConsumerInterceptors(t *testing.T) {
+	recorder, opts := newTestOptions()
+
+	parent, parentSpan := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "parent")
+	parentSpan.End()
+	msg := &sarama.ProducerMessage{Topic: "my_topic", Key: sarama.StringEncoder("my_key"), Value: sarama.StringEncoder("a")}
+	Inject(parent, msg, opts...)
+	NewProducerInterceptor(opts...).OnSend(msg)
+
+	spans := recorder.Ended()
+	if len(spans) != 1 {
+		t.Fatalf("expected a producer span, got %d spans", len(spans))
+	}
+	producerSpan := spans[0]
+	if producerSpan.SpanKind() != trace.SpanKindProducer || producerSpan.Name() != "my_topic send" {
+		t.Errorf("unexpected producer span %s (%s)", producerSpan.Name(), producerSpan.SpanKind())
+	}
+	if producerSpan.Parent().SpanID() != parentSpan.SpanContext().SpanID() {
+		t.Error("expected the producer span to be a child of the injected context")
+	}
+	for _, kv := range []attribute.KeyValue{
+		semconv.MessagingSystemKey.String("kafka"),
+		semconv.MessagingDestinationKey.String("my_topic"),
+		semconv.MessagingKafkaMessageKeyKey.String("my_key"),
+		semconv.MessagingKafkaClientIDKey.String("my_client"),
+	} {
+		if !hasAttribute(producerSpan, kv) {
+			t.Errorf("expected the producer span to have the attribute %v", kv)
+		}
+	}
+	if len(msg.Headers) != 1 || string(msg.Headers[0].Key) != "traceparent" {
+		t.Fatalf("expected the trace context to replace the injected one, got %v", msg.Headers)
+	}
+
+	consumed := &sarama.ConsumerMessage{Topic: "my_topic", Partition: 3, Offset: 42, Value: []byte("a")}
+	for i := range msg.Headers {
+		consumed.Headers = append(consumed.Headers, &msg.Headers[i])
+	}
+	NewConsumerInterceptor(append(opts, WithConsumerGroup("my_group"))...).OnConsume(consumed)
+
+	spans = recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("expected a consumer span, got %d spans", len(spans))
+	}
+	consumerSpan := spans[1]
+	if consumerSpan.SpanKind() != trace.SpanKindConsumer || consumerSpan.Name() != "my_topic receive" {
+		t.Errorf("unexpected consumer span %s (%s)", consumerSpan.Name(), consumerSpan.SpanKind())
+	}
+	if consumerSpan.Parent().SpanID() != producerSpan.SpanContext().SpanID() {
+		t.Error("expected the consumer span to be a child of the producer span")
+	}
+	for _, kv := range []attribute.KeyValue{
+		semconv.MessagingOperationReceive,
+		semconv.MessagingKafkaPartitionKey.Int64(3),
+		semconv.MessagingMessageIDKey.String("42"),
+		semconv.MessagingKafkaConsumerGroupKey.String("my_group"),
+	} {
+		if !hasAttribute(consumerSpan, kv) {
+			t.Errorf("expected the consumer span to have the attribute %v", kv)
+		}
+	}
+
+	ctx := ContextFromMessage(context.Background(), consumed, opts...)
+	if trace.SpanContextFromContext(ctx).SpanID() != consumerSpan.SpanContext().SpanID() {
+		t.Error("expected the context of the message to be that of the consumer span")
+	}
+}
+
+func TestConfigure(t *testing.T) {
+	conf := sarama.NewConfig()
+	conf.ClientID = "my_client"
+	Configure(conf)
+	if len(conf.Producer.Interceptors) != 1 || len(conf.Consumer.Interceptors) != 1 {
+		t.Fatal("expected the interceptors to be added")
+	}
+	if pi := conf.Producer.Interceptors[0].(*ProducerInterceptor); pi.conf.clientID != "my_client" {
+		t.Errorf("expected the client ID of the config, got %q", pi.conf.clientID)
+	}
+}
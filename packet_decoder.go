@@ -30,6 +30,7 @@ type packetDecoder interface {
 	getInt32Array() ([]int32, error)
 	getInt64Array() ([]int64, error)
 	getStringArray() ([]string, error)
+	getCompactStringArray() ([]string, error)
 
 	// Subsets
 	remaining() int
@@ -40,6 +41,19 @@ type packetDecoder interface {
 	// Stacks, see PushDecoder
 	push(in pushDecoder) error
 	pop() error
+
+	// lazyRecordHeaders reports whether to leave the headers of the records
+	// undecoded, see Consumer.Fetch.LazyHeaders
+	lazyRecordHeaders() bool
+	// zstdDecoderParams select the decoder decompressing the records, see
+	// Consumer.Zstd
+	zstdDecoderParams() ZstdDecoderParams
+	// recordsLimits bounds the memory decoding the records allocates, see
+	// Consumer.Fetch.MaxDecodedBytes, nil for no limit
+	recordsLimits() *fetchLimits
+	// recordsBuffers are the buffers of the partition to decompress the
+	// records into, nil for new ones
+	recordsBuffers() *decompressBuffers
 }
 
 // PushDecoder is the interface for decoding fields like CRCs and lengths where the validity
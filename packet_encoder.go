@@ -1,7 +1,5 @@
 package sarama
 
-import "github.com/rcrowley/go-metrics"
-
 // PacketEncoder is the interface providing helpers for writing with Kafka's encoding rules.
 // Types implementing Encoder only need to worry about calling methods like PutString,
 // not about how a string is represented in Kafka.
@@ -28,6 +26,7 @@ type packetEncoder interface {
 	putString(in string) error
 	putNullableString(in *string) error
 	putStringArray(in []string) error
+	putCompactStringArray(in []string) error
 	putCompactInt32Array(in []int32) error
 	putNullableCompactInt32Array(in []int32) error
 	putInt32Array(in []int32) error
@@ -42,7 +41,7 @@ type packetEncoder interface {
 	pop() error
 
 	// To record metrics when provided
-	metricRegistry() metrics.Registry
+	metricsRecorder() MetricsRecorder
 }
 
 // PushEncoder is the interface for encoding fields like CRCs and lengths where the value
@@ -62,6 +61,16 @@ type pushEncoder interface {
 	run(curOffset int, buf []byte) error
 }
 
+// vectoredPushEncoder extends the interface of pushEncoder for the fields
+// computed from the data they cover, which a vectored realEncoder may hold
+// partly in external bytes rather than in buf.
+type vectoredPushEncoder interface {
+	pushEncoder
+
+	// Like run, with the external bytes inserted in buf at their offsets.
+	runVectored(curOffset int, buf []byte, external []externalBytes) error
+}
+
 // dynamicPushEncoder extends the interface of pushEncoder for uses cases where the length of the
 // fields itself is unknown until its value was computed (for instance varint encoded length
 // fields).
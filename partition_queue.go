@@ -0,0 +1,115 @@
+package sarama
+
+import "sync"
+
+// partitionQueueMinSize is the initial capacity of the ring buffer of a
+// partitionQueue, which grows up to the ChannelBufferSize as needed.
+const partitionQueueMinSize = 16
+
+// partitionQueue is the queue of the messages of a partitionProducer: a ring
+// buffer, allocated and grown as needed up to its maximum size and shrunk
+// once drained, so that the idle partitions cost no goroutine and little
+// memory. Its consumer runs only while the queue holds messages: push and
+// close report when it must be started, and pop stops it once the queue is
+// empty.
+type partitionQueue struct {
+	lock    sync.Mutex
+	notFull sync.Cond
+
+	buf        []*ProducerMessage
+	head, size int
+	max        int
+
+	// running is set while the consumer runs, closed once close is called
+	running, closed bool
+}
+
+func newPartitionQueue(max int) *partitionQueue {
+	if max < 1 {
+		max = 1
+	}
+	q := &partitionQueue{max: max}
+	q.notFull.L = &q.lock
+	return q
+}
+
+// push appends msg to the queue, waiting for space while it is full, and
+// reports whether the consumer must be started.
+func (q *partitionQueue) push(msg *ProducerMessage) (start bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	for q.size == q.max {
+		q.notFull.Wait()
+	}
+	if q.size == len(q.buf) {
+		q.grow()
+	}
+	q.buf[(q.head+q.size)%len(q.buf)] = msg
+	q.size++
+
+	start = !q.running
+	q.running = true
+	return start
+}
+
+// grow doubles the capacity of the ring buffer, up to max.
+func (q *partitionQueue) grow() {
+	size := 2 * len(q.buf)
+	if size < partitionQueueMinSize {
+		size = partitionQueueMinSize
+	}
+	if size > q.max {
+		size = q.max
+	}
+	buf := make([]*ProducerMessage, size)
+	n := copy(buf, q.buf[q.head:])
+	copy(buf[n:], q.buf[:q.head])
+	q.buf, q.head = buf, 0
+}
+
+// pop removes the first message of the queue. Once the queue is empty it
+// returns false, and the consumer must return; done is then set if the queue
+// is closed as well, in which case the consumer is not started again.
+func (q *partitionQueue) pop() (msg *ProducerMessage, ok, done bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	if q.size == 0 {
+		if len(q.buf) > partitionQueueMinSize {
+			// release the grown buffer of the idle partition
+			q.buf = nil
+		}
+		q.head = 0
+		if !q.closed {
+			q.running = false
+		}
+		return nil, false, q.closed
+	}
+
+	msg = q.buf[q.head]
+	q.buf[q.head] = nil
+	q.head = (q.head + 1) % len(q.buf)
+	q.size--
+	q.notFull.Signal()
+	return msg, true, false
+}
+
+// close closes the queue, the consumer stopping for good once it is drained,
+// and reports whether the consumer must be started.
+func (q *partitionQueue) close() (start bool) {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+
+	q.closed = true
+	start = !q.running
+	q.running = true
+	return start
+}
+
+// len returns the number of messages in the queue.
+func (q *partitionQueue) len() int {
+	q.lock.Lock()
+	defer q.lock.Unlock()
+	return q.size
+}
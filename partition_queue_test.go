@@ -0,0 +1,96 @@
+package sarama
+
+import (
+	"sync"
+	"testing"
+)
+
+func TestPartitionQueue(t *testing.T) {
+	q := newPartitionQueue(64)
+
+	if !q.push(&ProducerMessage{Partition: 0}) {
+		t.Fatal("expected the first push to start the consumer")
+	}
+	for i := int32(1); i < 40; i++ {
+		if q.push(&ProducerMessage{Partition: i}) {
+			t.Fatal("expected the consumer to be started once")
+		}
+	}
+	if q.len() != 40 {
+		t.Fatalf("expected 40 queued messages, got %d", q.len())
+	}
+
+	// interleave the pops with pushes to wrap around the ring buffer
+	for i := int32(0); i < 100; i++ {
+		msg, ok, done := q.pop()
+		if !ok || done {
+			t.Fatalf("expected a message, got ok=%v done=%v", ok, done)
+		}
+		if msg.Partition != i {
+			t.Fatalf("expected message %d, got %d", i, msg.Partition)
+		}
+		if i < 60 {
+			q.push(&ProducerMessage{Partition: i + 40})
+		}
+	}
+
+	if _, ok, done := q.pop(); ok || done {
+		t.Fatalf("expected the queue to be empty and open, got ok=%v done=%v", ok, done)
+	}
+	if q.buf != nil {
+		t.Error("expected the grown buffer to be released once drained")
+	}
+	if !q.push(&ProducerMessage{}) {
+		t.Error("expected a push to restart the stopped consumer")
+	}
+	if q.close() {
+		t.Error("expected close not to start the running consumer")
+	}
+	if _, ok, _ := q.pop(); !ok {
+		t.Error("expected the queued message to be popped after close")
+	}
+	if _, ok, done := q.pop(); ok || !done {
+		t.Errorf("expected the queue to be done, got ok=%v done=%v", ok, done)
+	}
+}
+
+func TestPartitionQueueCloseIdle(t *testing.T) {
+	q := newPartitionQueue(1)
+	q.push(&ProducerMessage{})
+	q.pop()
+	if _, ok, done := q.pop(); ok || done {
+		t.Fatalf("expected the queue to be empty and open, got ok=%v done=%v", ok, done)
+	}
+	if !q.close() {
+		t.Error("expected close to start the idle consumer")
+	}
+}
+
+func TestPartitionQueueBlocksWhenFull(t *testing.T) {
+	q := newPartitionQueue(2)
+	q.push(&ProducerMessage{Partition: 0})
+	q.push(&ProducerMessage{Partition: 1})
+
+	pushed := make(chan none)
+	go func() {
+		q.push(&ProducerMessage{Partition: 2})
+		close(pushed)
+	}()
+
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := int32(0); i < 3; i++ {
+			msg, ok, _ := q.pop()
+			for !ok {
+				msg, ok, _ = q.pop()
+			}
+			if msg.Partition != i {
+				t.Errorf("expected message %d, got %d", i, msg.Partition)
+			}
+		}
+	}()
+	<-pushed
+	wg.Wait()
+}
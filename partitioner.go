@@ -3,6 +3,7 @@ package sarama
 import (
 	"hash"
 	"hash/fnv"
+	"io"
 	"math/rand"
 	"time"
 )
@@ -126,6 +127,9 @@ type hashPartitioner struct {
 	random       Partitioner
 	hasher       hash.Hash32
 	referenceAbs bool
+	// scratch holds the string keys while they are hashed, the partitioner of
+	// a topic only being called by the goroutine producing to it
+	scratch []byte
 }
 
 // NewCustomHashPartitioner is a wrapper around NewHashPartitioner, allowing the use of custom hasher.
@@ -183,13 +187,7 @@ func (p *hashPartitioner) Partition(message *ProducerMessage, numPartitions int3
 	if message.Key == nil {
 		return p.random.Partition(message, numPartitions)
 	}
-	bytes, err := message.Key.Encode()
-	if err != nil {
-		return -1, err
-	}
-	p.hasher.Reset()
-	_, err = p.hasher.Write(bytes)
-	if err != nil {
+	if err := p.hashKey(message.Key); err != nil {
 		return -1, err
 	}
 	var partition int32
@@ -208,6 +206,27 @@ func (p *hashPartitioner) Partition(message *ProducerMessage, numPartitions int3
 	return partition, nil
 }
 
+// hashKey resets the hasher and writes the encoded key to it, without
+// allocating for the string keys.
+func (p *hashPartitioner) hashKey(key Encoder) error {
+	p.hasher.Reset()
+	if s, ok := key.(StringEncoder); ok {
+		if w, ok := p.hasher.(io.StringWriter); ok {
+			_, err := w.WriteString(string(s))
+			return err
+		}
+		p.scratch = append(p.scratch[:0], s...)
+		_, err := p.hasher.Write(p.scratch)
+		return err
+	}
+	bytes, err := key.Encode()
+	if err != nil {
+		return err
+	}
+	_, err = p.hasher.Write(bytes)
+	return err
+}
+
 func (p *hashPartitioner) RequiresConsistency() bool {
 	return true
 }
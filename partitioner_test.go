@@ -2,6 +2,7 @@ package sarama
 
 import (
 	"crypto/rand"
+	"hash/crc32"
 	"hash/fnv"
 	"log"
 	"testing"
@@ -311,3 +312,37 @@ func ExamplePartitioner_per_topic() {
 
 	// ...
 }
+
+func TestHashPartitionerStringKey(t *testing.T) {
+	for name, partitioner := range map[string]Partitioner{
+		"fnv":    NewHashPartitioner("mytopic"),
+		"custom": NewCustomHashPartitioner(crc32.NewIEEE)("mytopic"),
+	} {
+		for _, key := range []string{"", "a", "some longer key", "1468509572224"} {
+			fromString, err := partitioner.Partition(&ProducerMessage{Key: StringEncoder(key)}, 50)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			fromBytes, err := partitioner.Partition(&ProducerMessage{Key: ByteEncoder(key)}, 50)
+			if err != nil {
+				t.Fatal(name, err)
+			}
+			if fromString != fromBytes {
+				t.Errorf("%s: key %q sent to %d as a string but to %d as bytes", name, key, fromString, fromBytes)
+			}
+		}
+	}
+}
+
+func BenchmarkHashPartitioner(b *testing.B) {
+	partitioner := NewHashPartitioner("mytopic")
+	msg := &ProducerMessage{Key: StringEncoder("some key of a typical length")}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if _, err := partitioner.Partition(msg, 50); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -5,13 +5,15 @@ import (
 	"errors"
 	"fmt"
 	"math"
-
-	"github.com/rcrowley/go-metrics"
 )
 
 type prepEncoder struct {
 	stack  []pushEncoder
 	length int
+	// vectored sizes the encoding of encodeVectored, whose external bytes
+	// are referenced rather than copied
+	vectored bool
+	external int
 }
 
 // primitives
@@ -105,6 +107,9 @@ func (pe *prepEncoder) putRawBytes(in []byte) error {
 		return PacketEncodingError{fmt.Sprintf("byteslice too long (%d)", len(in))}
 	}
 	pe.length += len(in)
+	if pe.vectored && len(in) >= vectoredMinBytes {
+		pe.external += len(in)
+	}
 	return nil
 }
 
@@ -140,6 +145,18 @@ func (pe *prepEncoder) putStringArray(in []string) error {
 	return nil
 }
 
+func (pe *prepEncoder) putCompactStringArray(in []string) error {
+	pe.putCompactArrayLength(len(in))
+
+	for _, val := range in {
+		if err := pe.putCompactString(val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (pe *prepEncoder) putCompactInt32Array(in []int32) error {
 	if in == nil {
 		return errors.New("expected int32 array to be non null")
@@ -206,6 +223,6 @@ func (pe *prepEncoder) pop() error {
 }
 
 // we do not record metrics during the prep encoder pass
-func (pe *prepEncoder) metricRegistry() metrics.Registry {
+func (pe *prepEncoder) metricsRecorder() MetricsRecorder {
 	return nil
 }
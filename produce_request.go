@@ -1,7 +1,5 @@
 package sarama
 
-import "github.com/rcrowley/go-metrics"
-
 // RequiredAcks is used in Produce Requests to tell the broker how many replica acknowledgements
 // it must see before responding. Any of the constants defined here are valid. On broker versions
 // prior to 0.8.2.0 any other positive int16 is also valid (the broker will wait for that many
@@ -24,12 +22,29 @@ type ProduceRequest struct {
 	TransactionalID *string
 	RequiredAcks    RequiredAcks
 	Timeout         int32
-	Version         int16 // v1 requires Kafka 0.9, v2 requires Kafka 0.10, v3 requires Kafka 0.11
+	Version         int16 // v1 requires Kafka 0.9, v2 requires Kafka 0.10, v3 requires Kafka 0.11, v9 is flexible
 	records         map[string]map[int32]Records
+	// maxBatchBytes is Producer.MaxMessageBytes, the batches are recorded in
+	// percent of, 0 when not produced by a producer.
+	maxBatchBytes int
+}
+
+// compressionRatioMetrics record the compression ratios of the batches of a
+// topic, for all topics and for the topic, by codec or not.
+type compressionRatioMetrics struct {
+	recorder      MetricsRecorder
+	topic         string
+	all, forTopic MetricsHistogram
 }
 
-func updateMsgSetMetrics(msgSet *MessageSet, compressionRatioMetric metrics.Histogram,
-	topicCompressionRatioMetric metrics.Histogram) int64 {
+func (m compressionRatioMetrics) observe(codec CompressionCodec, ratio int64) {
+	m.all.Observe(ratio)
+	m.forTopic.Observe(ratio)
+	m.recorder.Histogram("compression-ratio", MetricLabels{MetricLabelCodec: codec.String()}).Observe(ratio)
+	m.recorder.Histogram("compression-ratio", MetricLabels{MetricLabelCodec: codec.String(), MetricLabelTopic: m.topic}).Observe(ratio)
+}
+
+func updateMsgSetMetrics(msgSet *MessageSet, compressionRatios compressionRatioMetrics) int64 {
 	var topicRecordCount int64
 	for _, messageBlock := range msgSet.Messages {
 		// Is this a fake "message" wrapping real messages?
@@ -45,92 +60,135 @@ func updateMsgSetMetrics(msgSet *MessageSet, compressionRatioMetric metrics.Hist
 				float64(messageBlock.Msg.compressedSize)
 			// Histogram do not support decimal values, let's multiple it by 100 for better precision
 			intCompressionRatio := int64(100 * compressionRatio)
-			compressionRatioMetric.Update(intCompressionRatio)
-			topicCompressionRatioMetric.Update(intCompressionRatio)
+			compressionRatios.observe(messageBlock.Msg.Codec, intCompressionRatio)
 		}
 	}
 	return topicRecordCount
 }
 
-func updateBatchMetrics(recordBatch *RecordBatch, compressionRatioMetric metrics.Histogram,
-	topicCompressionRatioMetric metrics.Histogram) int64 {
+func updateBatchMetrics(recordBatch *RecordBatch, compressionRatios compressionRatioMetrics) int64 {
 	if recordBatch.compressedRecords != nil {
 		compressionRatio := int64(float64(recordBatch.recordsLen) / float64(len(recordBatch.compressedRecords)) * 100)
-		compressionRatioMetric.Update(compressionRatio)
-		topicCompressionRatioMetric.Update(compressionRatio)
+		compressionRatios.observe(recordBatch.Codec, compressionRatio)
 	}
 
 	return int64(len(recordBatch.Records))
 }
 
 func (r *ProduceRequest) encode(pe packetEncoder) error {
-	if r.Version >= 3 {
+	if r.Version >= 9 {
+		if err := pe.putNullableCompactString(r.TransactionalID); err != nil {
+			return err
+		}
+	} else if r.Version >= 3 {
 		if err := pe.putNullableString(r.TransactionalID); err != nil {
 			return err
 		}
 	}
 	pe.putInt16(int16(r.RequiredAcks))
 	pe.putInt32(r.Timeout)
-	metricRegistry := pe.metricRegistry()
-	var batchSizeMetric metrics.Histogram
-	var compressionRatioMetric metrics.Histogram
-	if metricRegistry != nil {
-		batchSizeMetric = getOrRegisterHistogram("batch-size", metricRegistry)
-		compressionRatioMetric = getOrRegisterHistogram("compression-ratio", metricRegistry)
+	recorder := pe.metricsRecorder()
+	var batchSizeMetric MetricsHistogram
+	var compressionRatioMetric MetricsHistogram
+	if recorder != nil {
+		batchSizeMetric = recorder.Histogram("batch-size", nil)
+		compressionRatioMetric = recorder.Histogram("compression-ratio", nil)
 	}
 	totalRecordCount := int64(0)
 
-	err := pe.putArrayLength(len(r.records))
-	if err != nil {
+	var err error
+	if r.Version >= 9 {
+		pe.putCompactArrayLength(len(r.records))
+	} else if err = pe.putArrayLength(len(r.records)); err != nil {
 		return err
 	}
 
 	for topic, partitions := range r.records {
-		err = pe.putString(topic)
+		if r.Version >= 9 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
 		if err != nil {
 			return err
 		}
-		err = pe.putArrayLength(len(partitions))
-		if err != nil {
+		if r.Version >= 9 {
+			pe.putCompactArrayLength(len(partitions))
+		} else if err = pe.putArrayLength(len(partitions)); err != nil {
 			return err
 		}
 		topicRecordCount := int64(0)
-		var topicCompressionRatioMetric metrics.Histogram
-		if metricRegistry != nil {
-			topicCompressionRatioMetric = getOrRegisterTopicHistogram("compression-ratio", topic, metricRegistry)
+		topicLabels := MetricLabels{MetricLabelTopic: topic}
+		var compressionRatios compressionRatioMetrics
+		if recorder != nil {
+			compressionRatios = compressionRatioMetrics{
+				recorder: recorder,
+				topic:    topic,
+				all:      compressionRatioMetric,
+				forTopic: recorder.Histogram("compression-ratio", topicLabels),
+			}
 		}
 		for id, records := range partitions {
 			startOffset := pe.offset()
 			pe.putInt32(id)
-			pe.push(&lengthField{})
+			if r.Version >= 9 {
+				// the length of compact records is a varint, sized by
+				// encoding them once beforehand
+				var prep prepEncoder
+				if err = records.encode(&prep); err != nil {
+					return err
+				}
+				pe.putUVarint(uint64(prep.length) + 1)
+			} else {
+				pe.push(&lengthField{})
+			}
+			recordsOffset := pe.offset()
 			err = records.encode(pe)
 			if err != nil {
 				return err
 			}
-			err = pe.pop()
-			if err != nil {
+			recordsSize := pe.offset() - recordsOffset
+			if r.Version >= 9 {
+				pe.putEmptyTaggedFieldArray()
+			} else if err = pe.pop(); err != nil {
 				return err
 			}
-			if metricRegistry != nil {
+			if recorder != nil {
+				var recordCount int64
 				if r.Version >= 3 {
-					topicRecordCount += updateBatchMetrics(records.RecordBatch, compressionRatioMetric, topicCompressionRatioMetric)
+					recordCount = updateBatchMetrics(records.RecordBatch, compressionRatios)
 				} else {
-					topicRecordCount += updateMsgSetMetrics(records.MsgSet, compressionRatioMetric, topicCompressionRatioMetric)
+					recordCount = updateMsgSetMetrics(records.MsgSet, compressionRatios)
 				}
+				topicRecordCount += recordCount
+				recorder.Histogram("records-per-batch", nil).Observe(recordCount)
+				recorder.Histogram("records-per-batch", topicLabels).Observe(recordCount)
 				batchSize := int64(pe.offset() - startOffset)
-				batchSizeMetric.Update(batchSize)
-				getOrRegisterTopicHistogram("batch-size", topic, metricRegistry).Update(batchSize)
+				batchSizeMetric.Observe(batchSize)
+				recorder.Histogram("batch-size", topicLabels).Observe(batchSize)
+				if r.maxBatchBytes > 0 {
+					fill := int64(recordsSize) * 100 / int64(r.maxBatchBytes)
+					recorder.Histogram("batch-fill-percentage", nil).Observe(fill)
+					recorder.Histogram("batch-fill-percentage", topicLabels).Observe(fill)
+				}
 			}
 		}
 		if topicRecordCount > 0 {
-			getOrRegisterTopicMeter("record-send-rate", topic, metricRegistry).Mark(topicRecordCount)
-			getOrRegisterTopicHistogram("records-per-request", topic, metricRegistry).Update(topicRecordCount)
+			recorder.Counter("record-send-rate", topicLabels).Add(topicRecordCount)
+			recorder.Histogram("records-per-request", topicLabels).Observe(topicRecordCount)
 			totalRecordCount += topicRecordCount
 		}
+		if r.Version >= 9 {
+			pe.putEmptyTaggedFieldArray()
+		}
 	}
 	if totalRecordCount > 0 {
-		metrics.GetOrRegisterMeter("record-send-rate", metricRegistry).Mark(totalRecordCount)
-		getOrRegisterHistogram("records-per-request", metricRegistry).Update(totalRecordCount)
+		recorder.Counter("record-send-rate", nil).Add(totalRecordCount)
+		recorder.Histogram("records-per-request", nil).Observe(totalRecordCount)
+	}
+
+	if r.Version >= 9 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
@@ -139,12 +197,14 @@ func (r *ProduceRequest) encode(pe packetEncoder) error {
 func (r *ProduceRequest) decode(pd packetDecoder, version int16) error {
 	r.Version = version
 
-	if version >= 3 {
-		id, err := pd.getNullableString()
-		if err != nil {
-			return err
-		}
-		r.TransactionalID = id
+	var err error
+	if version >= 9 {
+		r.TransactionalID, err = pd.getCompactNullableString()
+	} else if version >= 3 {
+		r.TransactionalID, err = pd.getNullableString()
+	}
+	if err != nil {
+		return err
 	}
 	requiredAcks, err := pd.getInt16()
 	if err != nil {
@@ -154,21 +214,39 @@ func (r *ProduceRequest) decode(pd packetDecoder, version int16) error {
 	if r.Timeout, err = pd.getInt32(); err != nil {
 		return err
 	}
-	topicCount, err := pd.getArrayLength()
+	var topicCount int
+	if version >= 9 {
+		topicCount, err = pd.getCompactArrayLength()
+	} else {
+		topicCount, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
-	if topicCount == 0 {
-		return nil
+	if topicCount <= 0 {
+		if version >= 9 {
+			_, err = pd.getEmptyTaggedFieldArray()
+		}
+		return err
 	}
 
 	r.records = make(map[string]map[int32]Records)
 	for i := 0; i < topicCount; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if version >= 9 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
-		partitionCount, err := pd.getArrayLength()
+		var partitionCount int
+		if version >= 9 {
+			partitionCount, err = pd.getCompactArrayLength()
+		} else {
+			partitionCount, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -179,11 +257,22 @@ func (r *ProduceRequest) decode(pd packetDecoder, version int16) error {
 			if err != nil {
 				return err
 			}
-			size, err := pd.getInt32()
+			var size int
+			if version >= 9 {
+				var n uint64
+				if n, err = pd.getUVarint(); err == nil && n == 0 {
+					err = PacketDecodingError{"null compact records"}
+				}
+				size = int(n) - 1
+			} else {
+				var n int32
+				n, err = pd.getInt32()
+				size = int(n)
+			}
 			if err != nil {
 				return err
 			}
-			recordsDecoder, err := pd.getSubset(int(size))
+			recordsDecoder, err := pd.getSubset(size)
 			if err != nil {
 				return err
 			}
@@ -192,6 +281,22 @@ func (r *ProduceRequest) decode(pd packetDecoder, version int16) error {
 				return err
 			}
 			r.records[topic][partition] = records
+			if version >= 9 {
+				if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+					return err
+				}
+			}
+		}
+		if version >= 9 {
+			if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if version >= 9 {
+		if _, err := pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
 		}
 	}
 
@@ -207,6 +312,9 @@ func (r *ProduceRequest) version() int16 {
 }
 
 func (r *ProduceRequest) headerVersion() int16 {
+	if r.Version >= 9 {
+		return 2
+	}
 	return 1
 }
 
@@ -220,6 +328,10 @@ func (r *ProduceRequest) requiredVersion() KafkaVersion {
 		return V0_11_0_0
 	case 7:
 		return V2_1_0_0
+	case 8:
+		return V2_4_0_0
+	case 9:
+		return V2_8_0_0
 	default:
 		return MinVersion
 	}
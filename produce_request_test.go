@@ -71,6 +71,44 @@ var (
 		0x06, 0x08, 0x09, 0x0A,
 		0x04, 0x0B, 0x0C,
 	}
+
+	produceRequestOneRecordV9 = []byte{
+		0x00,       // Transaction ID
+		0x01, 0x23, // Required Acks
+		0x00, 0x00, 0x04, 0x44, // Timeout
+		0x02,                          // Number of Topics
+		0x06, 't', 'o', 'p', 'i', 'c', // Topic
+		0x02,                   // Number of Partitions
+		0x00, 0x00, 0x00, 0xAD, // Partition
+		0x53, // Records length
+		// recordBatch
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x46,
+		0x00, 0x00, 0x00, 0x00,
+		0x02,
+		0xCA, 0x33, 0xBC, 0x05,
+		0x00, 0x00,
+		0x00, 0x00, 0x00, 0x01,
+		0x00, 0x00, 0x01, 0x58, 0x8D, 0xCD, 0x59, 0x38,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00,
+		0x00, 0x00, 0x00, 0x00,
+		0x00, 0x00, 0x00, 0x01,
+		// record
+		0x28,
+		0x00,
+		0x0A,
+		0x00,
+		0x08, 0x01, 0x02, 0x03, 0x04,
+		0x06, 0x05, 0x06, 0x07,
+		0x02,
+		0x06, 0x08, 0x09, 0x0A,
+		0x04, 0x0B, 0x0C,
+		0x00, // Partition tagged fields
+		0x00, // Topic tagged fields
+		0x00, // Tagged fields
+	}
 )
 
 func TestProduceRequest(t *testing.T) {
@@ -106,4 +144,9 @@ func TestProduceRequest(t *testing.T) {
 	// are only interested in decoded records.
 	batch.compressedRecords = nil
 	testRequestDecode(t, "one record", request, packet)
+
+	request.Version = 9
+	packet = testRequestEncode(t, "one record flexible", request, produceRequestOneRecordV9)
+	batch.compressedRecords = nil
+	testRequestDecode(t, "one record flexible", request, packet)
 }
@@ -9,23 +9,74 @@ import (
 // v1
 // v2 = v3 = v4
 // v5 = v6 = v7
-// Produce Response (Version: 7) => [responses] throttle_time_ms
+// v8
+// v9, flexible
+// Produce Response (Version: 8) => [responses] throttle_time_ms
 //   responses => topic [partition_responses]
 //     topic => STRING
-//     partition_responses => partition error_code base_offset log_append_time log_start_offset
+//     partition_responses => partition error_code base_offset log_append_time log_start_offset [record_errors] error_message
 //       partition => INT32
 //       error_code => INT16
 //       base_offset => INT64
 //       log_append_time => INT64
 //       log_start_offset => INT64
+//       record_errors => batch_index batch_index_error_message
+//         batch_index => INT32
+//         batch_index_error_message => NULLABLE_STRING
+//       error_message => NULLABLE_STRING
 //   throttle_time_ms => INT32
 
 // partition_responses in protocol
 type ProduceResponseBlock struct {
-	Err         KError    // v0, error_code
-	Offset      int64     // v0, base_offset
-	Timestamp   time.Time // v2, log_append_time, and the broker is configured with `LogAppendTime`
-	StartOffset int64     // v5, log_start_offset
+	Err          KError                        // v0, error_code
+	Offset       int64                         // v0, base_offset
+	Timestamp    time.Time                     // v2, log_append_time, and the broker is configured with `LogAppendTime`
+	StartOffset  int64                         // v5, log_start_offset
+	RecordErrors []*ProduceResponseRecordError // v8, record_errors
+	ErrorMessage *string                       // v8, error_message
+}
+
+// ProduceResponseRecordError is a record of the batch which made the broker
+// reject it, record_errors in protocol.
+type ProduceResponseRecordError struct {
+	BatchIndex             int32   // v8, batch_index
+	BatchIndexErrorMessage *string // v8, batch_index_error_message
+}
+
+func (e *ProduceResponseRecordError) decode(pd packetDecoder, version int16) (err error) {
+	if e.BatchIndex, err = pd.getInt32(); err != nil {
+		return err
+	}
+	if version >= 9 {
+		e.BatchIndexErrorMessage, err = pd.getCompactNullableString()
+	} else {
+		e.BatchIndexErrorMessage, err = pd.getNullableString()
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 9 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e *ProduceResponseRecordError) encode(pe packetEncoder, version int16) (err error) {
+	pe.putInt32(e.BatchIndex)
+	if version >= 9 {
+		err = pe.putNullableCompactString(e.BatchIndexErrorMessage)
+	} else {
+		err = pe.putNullableString(e.BatchIndexErrorMessage)
+	}
+	if err != nil {
+		return err
+	}
+	if version >= 9 {
+		pe.putEmptyTaggedFieldArray()
+	}
+	return nil
 }
 
 func (b *ProduceResponseBlock) decode(pd packetDecoder, version int16) (err error) {
@@ -55,6 +106,42 @@ func (b *ProduceResponseBlock) decode(pd packetDecoder, version int16) (err erro
 		}
 	}
 
+	if version >= 8 {
+		var n int
+		if version >= 9 {
+			n, err = pd.getCompactArrayLength()
+		} else {
+			n, err = pd.getArrayLength()
+		}
+		if err != nil {
+			return err
+		}
+		if n > 0 {
+			b.RecordErrors = make([]*ProduceResponseRecordError, n)
+			for i := range b.RecordErrors {
+				b.RecordErrors[i] = new(ProduceResponseRecordError)
+				if err = b.RecordErrors[i].decode(pd, version); err != nil {
+					return err
+				}
+			}
+		}
+
+		if version >= 9 {
+			b.ErrorMessage, err = pd.getCompactNullableString()
+		} else {
+			b.ErrorMessage, err = pd.getNullableString()
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if version >= 9 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -76,6 +163,32 @@ func (b *ProduceResponseBlock) encode(pe packetEncoder, version int16) (err erro
 		pe.putInt64(b.StartOffset)
 	}
 
+	if version >= 8 {
+		if version >= 9 {
+			pe.putCompactArrayLength(len(b.RecordErrors))
+		} else if err = pe.putArrayLength(len(b.RecordErrors)); err != nil {
+			return err
+		}
+		for _, recordError := range b.RecordErrors {
+			if err = recordError.encode(pe, version); err != nil {
+				return err
+			}
+		}
+
+		if version >= 9 {
+			err = pe.putNullableCompactString(b.ErrorMessage)
+		} else {
+			err = pe.putNullableString(b.ErrorMessage)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	if version >= 9 {
+		pe.putEmptyTaggedFieldArray()
+	}
+
 	return nil
 }
 
@@ -88,19 +201,34 @@ type ProduceResponse struct {
 func (r *ProduceResponse) decode(pd packetDecoder, version int16) (err error) {
 	r.Version = version
 
-	numTopics, err := pd.getArrayLength()
+	var numTopics int
+	if version >= 9 {
+		numTopics, err = pd.getCompactArrayLength()
+	} else {
+		numTopics, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
 
 	r.Blocks = make(map[string]map[int32]*ProduceResponseBlock, numTopics)
 	for i := 0; i < numTopics; i++ {
-		name, err := pd.getString()
+		var name string
+		if version >= 9 {
+			name, err = pd.getCompactString()
+		} else {
+			name, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
 
-		numBlocks, err := pd.getArrayLength()
+		var numBlocks int
+		if version >= 9 {
+			numBlocks, err = pd.getCompactArrayLength()
+		} else {
+			numBlocks, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -120,6 +248,12 @@ func (r *ProduceResponse) decode(pd packetDecoder, version int16) (err error) {
 			}
 			r.Blocks[name][id] = block
 		}
+
+		if version >= 9 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
 	}
 
 	if r.Version >= 1 {
@@ -131,21 +265,33 @@ func (r *ProduceResponse) decode(pd packetDecoder, version int16) (err error) {
 		r.ThrottleTime = time.Duration(millis) * time.Millisecond
 	}
 
+	if version >= 9 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
-func (r *ProduceResponse) encode(pe packetEncoder) error {
-	err := pe.putArrayLength(len(r.Blocks))
-	if err != nil {
+func (r *ProduceResponse) encode(pe packetEncoder) (err error) {
+	if r.Version >= 9 {
+		pe.putCompactArrayLength(len(r.Blocks))
+	} else if err = pe.putArrayLength(len(r.Blocks)); err != nil {
 		return err
 	}
 	for topic, partitions := range r.Blocks {
-		err = pe.putString(topic)
+		if r.Version >= 9 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
 		if err != nil {
 			return err
 		}
-		err = pe.putArrayLength(len(partitions))
-		if err != nil {
+		if r.Version >= 9 {
+			pe.putCompactArrayLength(len(partitions))
+		} else if err = pe.putArrayLength(len(partitions)); err != nil {
 			return err
 		}
 		for id, prb := range partitions {
@@ -155,11 +301,17 @@ func (r *ProduceResponse) encode(pe packetEncoder) error {
 				return err
 			}
 		}
+		if r.Version >= 9 {
+			pe.putEmptyTaggedFieldArray()
+		}
 	}
 
 	if r.Version >= 1 {
 		pe.putInt32(int32(r.ThrottleTime / time.Millisecond))
 	}
+	if r.Version >= 9 {
+		pe.putEmptyTaggedFieldArray()
+	}
 	return nil
 }
 
@@ -172,6 +324,9 @@ func (r *ProduceResponse) version() int16 {
 }
 
 func (r *ProduceResponse) headerVersion() int16 {
+	if r.Version >= 9 {
+		return 1
+	}
 	return 0
 }
 
@@ -210,3 +365,22 @@ func (r *ProduceResponse) AddTopicPartition(topic string, partition int32, err K
 	}
 	byTopic[partition] = block
 }
+
+func (r *ProduceResponse) throttleTime() time.Duration {
+	return r.ThrottleTime
+}
+
+func (r *ProduceResponse) shouldClientThrottle(version int16) bool {
+	return version >= 6
+}
+
+func (r *ProduceResponse) hasErrorCode() bool {
+	for _, partitions := range r.Blocks {
+		for _, block := range partitions {
+			if block.Err != ErrNoError {
+				return true
+			}
+		}
+	}
+	return false
+}
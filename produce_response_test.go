@@ -63,6 +63,41 @@ var (
 
 			0x00, 0x00, 0x00, 0x64, // 100 ms throttle time
 		},
+		8: { // version 8 adds RecordErrors and ErrorMessage
+			0x00, 0x00, 0x00, 0x01,
+
+			0x00, 0x03, 'f', 'o', 'o',
+			0x00, 0x00, 0x00, 0x01,
+
+			0x00, 0x00, 0x00, 0x01, // Partition 1
+			0x00, 0x02, // ErrInvalidMessage
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF, // Offset 255
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0xE8, // Timestamp January 1st 0001 at 00:00:01,000 UTC (LogAppendTime was used)
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x32, // StartOffset 50
+			0x00, 0x00, 0x00, 0x00, // No RecordErrors
+			0xFF, 0xFF, // No ErrorMessage
+
+			0x00, 0x00, 0x00, 0x64, // 100 ms throttle time
+		},
+		9: { // version 9 is flexible
+			0x02,
+
+			0x04, 'f', 'o', 'o',
+			0x02,
+
+			0x00, 0x00, 0x00, 0x01, // Partition 1
+			0x00, 0x02, // ErrInvalidMessage
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0xFF, // Offset 255
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x03, 0xE8, // Timestamp January 1st 0001 at 00:00:01,000 UTC (LogAppendTime was used)
+			0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x00, 0x32, // StartOffset 50
+			0x01, // No RecordErrors
+			0x00, // No ErrorMessage
+			0x00, // Partition tagged fields
+			0x00, // Topic tagged fields
+
+			0x00, 0x00, 0x00, 0x64, // 100 ms throttle time
+			0x00, // Tagged fields
+		},
 	}
 )
 
@@ -152,3 +187,21 @@ func TestProduceResponseEncodeInvalidTimestamp(t *testing.T) {
 		t.Error("Expecting PacketEncodingError, got:", err)
 	}
 }
+
+func TestProduceResponseRecordErrors(t *testing.T) {
+	message := "invalid record"
+	for _, version := range []int16{8, 9} {
+		response := &ProduceResponse{Version: version, ThrottleTime: 100 * time.Millisecond}
+		response.Blocks = map[string]map[int32]*ProduceResponseBlock{
+			"foo": {1: {
+				Err:          ErrInvalidRecord,
+				Offset:       -1,
+				Timestamp:    time.Unix(1, 0),
+				StartOffset:  50,
+				RecordErrors: []*ProduceResponseRecordError{{BatchIndex: 2, BatchIndexErrorMessage: &message}},
+				ErrorMessage: &message,
+			}},
+		}
+		testResponse(t, fmt.Sprintf("record errors version %d", version), response, nil)
+	}
+}
@@ -3,6 +3,8 @@ package sarama
 import (
 	"encoding/binary"
 	"errors"
+	"math"
+	"sync"
 	"time"
 )
 
@@ -20,8 +22,17 @@ type produceSet struct {
 
 	bufferBytes int
 	bufferCount int
+
+	// records and headers are allocated in slabs rather than one by one, the
+	// records of a set living as long as the set itself
+	records []Record
+	headers []*RecordHeader
 }
 
+// produceSetSlabSize is the number of records, or of header pointers, of the
+// slabs allocated by a produceSet.
+const produceSetSlabSize = 64
+
 func newProduceSet(parent *asyncProducer) *produceSet {
 	pid, epoch := parent.txnmgr.getProducerID()
 	return &produceSet{
@@ -72,6 +83,7 @@ func (ps *produceSet) add(msg *ProducerMessage) error {
 				CompressionLevel: ps.parent.conf.Producer.CompressionLevel,
 				ProducerID:       ps.producerID,
 				ProducerEpoch:    ps.producerEpoch,
+				compression:      ps.parent.conf.compressionOptions(),
 			}
 			if ps.parent.conf.Producer.Idempotent {
 				batch.FirstSequence = msg.sequenceNumber
@@ -96,14 +108,13 @@ func (ps *produceSet) add(msg *ProducerMessage) error {
 	if ps.parent.conf.Version.IsAtLeast(V0_11_0_0) {
 		// We are being conservative here to avoid having to prep encode the record
 		size += maximumRecordOverhead
-		rec := &Record{
-			Key:            key,
-			Value:          val,
-			TimestampDelta: timestamp.Sub(set.recordsToSend.RecordBatch.FirstTimestamp),
-		}
+		rec := ps.newRecord()
+		rec.Key = key
+		rec.Value = val
+		rec.TimestampDelta = timestamp.Sub(set.recordsToSend.RecordBatch.FirstTimestamp)
 		size += len(key) + len(val)
 		if len(msg.Headers) > 0 {
-			rec.Headers = make([]*RecordHeader, len(msg.Headers))
+			rec.Headers = ps.newHeaders(len(msg.Headers))
 			for i := range msg.Headers {
 				rec.Headers[i] = &msg.Headers[i]
 				size += len(rec.Headers[i].Key) + len(rec.Headers[i].Value) + 2*binary.MaxVarintLen32
@@ -127,10 +138,35 @@ func (ps *produceSet) add(msg *ProducerMessage) error {
 	return nil
 }
 
+// newRecord returns a zeroed record from the current slab.
+func (ps *produceSet) newRecord() *Record {
+	if len(ps.records) == 0 {
+		ps.records = make([]Record, produceSetSlabSize)
+	}
+	rec := &ps.records[0]
+	ps.records = ps.records[1:]
+	return rec
+}
+
+// newHeaders returns n header pointers from the current slab, capped so that
+// appending to them never overwrites those of another record.
+func (ps *produceSet) newHeaders(n int) []*RecordHeader {
+	if n > produceSetSlabSize {
+		return make([]*RecordHeader, n)
+	}
+	if len(ps.headers) < n {
+		ps.headers = make([]*RecordHeader, produceSetSlabSize)
+	}
+	headers := ps.headers[:n:n]
+	ps.headers = ps.headers[n:]
+	return headers
+}
+
 func (ps *produceSet) buildRequest() *ProduceRequest {
 	req := &ProduceRequest{
-		RequiredAcks: ps.parent.conf.Producer.RequiredAcks,
-		Timeout:      int32(ps.parent.conf.Producer.Timeout / time.Millisecond),
+		RequiredAcks:  ps.parent.conf.Producer.RequiredAcks,
+		Timeout:       int32(ps.parent.conf.Producer.Timeout / time.Millisecond),
+		maxBatchBytes: ps.parent.conf.Producer.MaxMessageBytes,
 	}
 	if ps.parent.conf.Version.IsAtLeast(V0_10_0_0) {
 		req.Version = 2
@@ -181,14 +217,15 @@ func (ps *produceSet) buildRequest() *ProduceRequest {
 						msg.Offset = int64(i)
 					}
 				}
-				payload, err := encode(set.recordsToSend.MsgSet, ps.parent.conf.MetricRegistry)
+				payload, err := encode(set.recordsToSend.MsgSet, ps.parent.conf.metricsRecorder())
 				if err != nil {
-					Logger.Println(err) // if this happens, it's basically our fault.
+					errorf("%v", err) // if this happens, it's basically our fault.
 					panic(err)
 				}
 				compMsg := &Message{
 					Codec:            ps.parent.conf.Producer.Compression,
 					CompressionLevel: ps.parent.conf.Producer.CompressionLevel,
+					compression:      ps.parent.conf.compressionOptions(),
 					Key:              nil,
 					Value:            payload,
 					Set:              set.recordsToSend.MsgSet, // Provide the underlying message set for accurate metrics
@@ -234,12 +271,12 @@ func (ps *produceSet) wouldOverflow(msg *ProducerMessage) bool {
 	}
 
 	switch {
-	// Would we overflow our maximum possible size-on-the-wire? 10KiB is arbitrary overhead for safety.
-	case ps.bufferBytes+msg.byteSize(version) >= int(MaxRequestSize-(10*1024)):
+	// Would we overflow our maximum possible size-on-the-wire?
+	case ps.bufferBytes+msg.byteSize(version) >= ps.parent.conf.maxRequestBytes():
 		return true
-	// Would we overflow the size-limit of a message-batch for this partition?
+	// Would we overflow the size-limit of a message-batch for this partition, once compressed?
 	case ps.msgs[msg.Topic] != nil && ps.msgs[msg.Topic][msg.Partition] != nil &&
-		ps.msgs[msg.Topic][msg.Partition].bufferBytes+msg.byteSize(version) >= ps.parent.conf.Producer.MaxMessageBytes:
+		ps.batchBytes(msg.Topic, ps.msgs[msg.Topic][msg.Partition].bufferBytes+msg.byteSize(version), version) >= ps.parent.conf.Producer.MaxMessageBytes:
 		return true
 	// Would we overflow simply in number of messages?
 	case ps.parent.conf.Producer.Flush.MaxMessages > 0 && ps.bufferCount >= ps.parent.conf.Producer.Flush.MaxMessages:
@@ -249,6 +286,18 @@ func (ps *produceSet) wouldOverflow(msg *ProducerMessage) bool {
 	}
 }
 
+// batchBytes estimates the size of a batch of the topic holding bufferBytes
+// of records once compressed, from the compression ratio observed on the
+// topic. Only record batches are estimated; message sets are not, as they
+// are compressed differently.
+func (ps *produceSet) batchBytes(topic string, bufferBytes int, version int) int {
+	if ps.parent.conf.Producer.Compression == CompressionNone || version < 2 {
+		return bufferBytes
+	}
+	ratio := ps.parent.compressionRatios.ratio(topic)
+	return recordBatchOverhead + int(float64(bufferBytes-recordBatchOverhead)*ratio)
+}
+
 func (ps *produceSet) readyToFlush() bool {
 	switch {
 	// If we don't have any messages, nothing else matters
@@ -271,3 +320,75 @@ func (ps *produceSet) readyToFlush() bool {
 func (ps *produceSet) empty() bool {
 	return ps.bufferCount == 0
 }
+
+const (
+	// compressionRatioImproveStep and compressionRatioDeteriorateStep are how
+	// much the estimated compression ratio of a topic moves towards a better
+	// or worse observed ratio, as in the JVM producer.
+	compressionRatioImproveStep     = 0.005
+	compressionRatioDeteriorateStep = 0.05
+)
+
+// compressionEstimator estimates the ratio of the compressed to the
+// uncompressed size of the record batches of each topic, so that batches can
+// be filled up to Producer.MaxMessageBytes once compressed. The estimates
+// start at 1, and quickly follow worse ratios but slowly better ones.
+type compressionEstimator struct {
+	lock   sync.Mutex
+	ratios map[string]float64
+}
+
+func newCompressionEstimator() *compressionEstimator {
+	return &compressionEstimator{ratios: make(map[string]float64)}
+}
+
+// ratio returns the estimated compression ratio of topic. It is safe to call
+// on a nil compressionEstimator, which never compresses.
+func (e *compressionEstimator) ratio(topic string) float64 {
+	if e == nil {
+		return 1
+	}
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	if ratio, ok := e.ratios[topic]; ok {
+		return ratio
+	}
+	return 1
+}
+
+// observe updates the estimated compression ratio of topic with that of a
+// batch compressed from uncompressed to compressed bytes.
+func (e *compressionEstimator) observe(topic string, uncompressed, compressed int) {
+	if e == nil || uncompressed <= 0 {
+		return
+	}
+	observed := float64(compressed) / float64(uncompressed)
+
+	e.lock.Lock()
+	defer e.lock.Unlock()
+	estimate, ok := e.ratios[topic]
+	if !ok {
+		estimate = 1
+	}
+	if observed > estimate {
+		estimate = math.Max(estimate+compressionRatioDeteriorateStep, observed)
+	} else if observed < estimate {
+		estimate = math.Max(estimate-compressionRatioImproveStep, observed)
+	}
+	e.ratios[topic] = estimate
+}
+
+// observeRequest observes the compressed record batches of an encoded
+// request.
+func (e *compressionEstimator) observeRequest(req *ProduceRequest) {
+	if e == nil {
+		return
+	}
+	for topic, partitions := range req.records {
+		for _, records := range partitions {
+			if batch := records.RecordBatch; batch != nil && batch.compressedRecords != nil && batch.Codec != CompressionNone {
+				e.observe(topic, batch.recordsLen, len(batch.compressedRecords))
+			}
+		}
+	}
+}
@@ -425,3 +425,145 @@ func TestProduceSetConsistentTimestamps(t *testing.T) {
 		t.Errorf("Message timestamps do not match: %v, %v", time1, time2)
 	}
 }
+
+func TestProduceSetAddingMessagesOverflowRequestBytesLimit(t *testing.T) {
+	parent, ps := makeProduceSet()
+	parent.conf.Producer.MaxRequestBytes = 1000
+
+	msg := &ProducerMessage{Key: StringEncoder(TestMessage), Value: StringEncoder(TestMessage)}
+	for ps.bufferBytes+msg.byteSize(2) < parent.conf.Producer.MaxRequestBytes {
+		// spread the messages so that no partition fills up
+		msg = &ProducerMessage{Partition: int32(ps.bufferCount), Key: StringEncoder(TestMessage), Value: StringEncoder(TestMessage)}
+		if ps.wouldOverflow(msg) {
+			t.Error("set shouldn't fill up before 1000 bytes")
+		}
+		safeAddMessage(t, ps, msg)
+	}
+
+	if !ps.wouldOverflow(msg) {
+		t.Error("set should be full after 1000 bytes")
+	}
+}
+
+func TestProduceSetCompressedBatchBytesLimit(t *testing.T) {
+	parent, ps := makeProduceSet()
+	parent.conf.Version = V0_11_0_0
+	parent.conf.Producer.Compression = CompressionGZIP
+	parent.conf.Producer.MaxMessageBytes = 1000
+	parent.compressionRatios = newCompressionEstimator()
+	parent.compressionRatios.ratios["t1"] = 0.5
+
+	msg := &ProducerMessage{Topic: "t1", Key: StringEncoder(TestMessage), Value: StringEncoder(TestMessage)}
+	for !ps.wouldOverflow(msg) {
+		safeAddMessage(t, ps, msg)
+	}
+
+	if ps.bufferBytes < 1500 || ps.bufferBytes > 2000 {
+		t.Errorf("expected the batch to hold about 2000 uncompressed bytes, got %d", ps.bufferBytes)
+	}
+}
+
+func TestCompressionEstimator(t *testing.T) {
+	e := newCompressionEstimator()
+	if ratio := e.ratio("t1"); ratio != 1 {
+		t.Errorf("expected topics to start without compression, got %v", ratio)
+	}
+
+	// better ratios are followed slowly
+	e.observe("t1", 1000, 250)
+	if ratio := e.ratio("t1"); ratio != 1-compressionRatioImproveStep {
+		t.Errorf("expected the ratio to improve by one step, got %v", ratio)
+	}
+	for i := 0; i < 1000; i++ {
+		e.observe("t1", 1000, 250)
+	}
+	if ratio := e.ratio("t1"); ratio != 0.25 {
+		t.Errorf("expected the ratio to reach the observed ratio, got %v", ratio)
+	}
+
+	// worse ratios are followed at once
+	e.observe("t1", 1000, 500)
+	if ratio := e.ratio("t1"); ratio != 0.5 {
+		t.Errorf("expected the ratio to deteriorate to the observed ratio, got %v", ratio)
+	}
+	e.observe("t1", 1000, 510)
+	if ratio := e.ratio("t1"); ratio != 0.5+compressionRatioDeteriorateStep {
+		t.Errorf("expected the ratio to deteriorate by one step, got %v", ratio)
+	}
+}
+
+func TestProduceSetBatchMetrics(t *testing.T) {
+	parent, ps := makeProduceSet()
+	parent.conf.Version = V0_11_0_0
+	parent.conf.Producer.Compression = CompressionGZIP
+	parent.conf.Producer.MaxMessageBytes = 1000
+
+	for i := 0; i < 10; i++ {
+		safeAddMessage(t, ps, &ProducerMessage{Topic: "t1", Partition: 0, Value: StringEncoder(TestMessage)})
+	}
+	recorder := newTestMetricsRecorder()
+	if _, err := encode(ps.buildRequest(), recorder); err != nil {
+		t.Fatal(err)
+	}
+
+	topic := MetricLabels{MetricLabelTopic: "t1"}
+	if records := recorder.value("records-per-batch", topic); records != 10 {
+		t.Errorf("expected a batch of 10 records, got %d", records)
+	}
+	if fill := recorder.value("batch-fill-percentage", topic); fill <= 0 || fill > 100 {
+		t.Errorf("expected the batch to fill a part of MaxMessageBytes, got %d%%", fill)
+	}
+	ratio := recorder.value("compression-ratio", topic)
+	if ratio <= 100 {
+		t.Errorf("expected the batch to be compressed, got a ratio of %d", ratio)
+	}
+	if byCodec := recorder.value("compression-ratio", MetricLabels{MetricLabelCodec: "gzip", MetricLabelTopic: "t1"}); byCodec != ratio {
+		t.Errorf("expected the ratio to be recorded for gzip, got %d", byCodec)
+	}
+}
+
+func TestProduceSetHeadersSlab(t *testing.T) {
+	parent, ps := makeProduceSet()
+	parent.conf.Version = V0_11_0_0
+
+	for i := 0; i < 2*produceSetSlabSize; i++ {
+		safeAddMessage(t, ps, &ProducerMessage{
+			Topic:   "t1",
+			Value:   StringEncoder(TestMessage),
+			Headers: []RecordHeader{{Key: []byte("i"), Value: []byte(fmt.Sprint(i))}, {Key: []byte("k")}},
+		})
+	}
+
+	records := ps.msgs["t1"][0].recordsToSend.RecordBatch.Records
+	for i, rec := range records {
+		if len(rec.Headers) != 2 || cap(rec.Headers) != 2 {
+			t.Fatalf("record %d: expected 2 headers, got %d of capacity %d", i, len(rec.Headers), cap(rec.Headers))
+		}
+		if v := string(rec.Headers[0].Value); v != fmt.Sprint(i) {
+			t.Errorf("record %d: expected the header %d, got %s", i, i, v)
+		}
+	}
+}
+
+func BenchmarkProduceSetAdd(b *testing.B) {
+	parent, _ := makeProduceSet()
+	parent.conf.Version = V0_11_0_0
+	msg := &ProducerMessage{
+		Topic:   "t1",
+		Key:     StringEncoder("key"),
+		Value:   StringEncoder(TestMessage),
+		Headers: []RecordHeader{{Key: []byte("h"), Value: []byte("v")}},
+	}
+
+	b.ReportAllocs()
+	b.ResetTimer()
+	var ps *produceSet
+	for i := 0; i < b.N; i++ {
+		if i%1000 == 0 {
+			ps = newProduceSet(parent)
+		}
+		if err := ps.add(msg); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package promsarama
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+type kind int
+
+const (
+	counterKind kind = iota
+	gaugeKind
+	histogramKind
+)
+
+// definition describes how a metric of Sarama is exported.
+type definition struct {
+	kind kind
+	// name is the name of the metric in Prometheus, without the namespace.
+	name string
+	help string
+	// labels are the labels the metric is recorded with; the recordings
+	// without them, which aggregate the labelled ones, are not exported.
+	labels []string
+	// divisor converts the values recorded to the unit of the metric.
+	divisor float64
+	buckets []float64
+}
+
+var (
+	bytesBuckets   = prometheus.ExponentialBuckets(64, 4, 10)    // 64B to 16MiB
+	secondsBuckets = prometheus.ExponentialBuckets(0.001, 2, 16) // 1ms to 32s
+	countBuckets   = prometheus.ExponentialBuckets(1, 4, 10)     // 1 to 262144
+	ratioBuckets   = prometheus.ExponentialBuckets(1, 1.5, 10)   // 1 to 38
+	percentBuckets = prometheus.LinearBuckets(10, 10, 10)        // 10% to 100%
+	brokerLabels   = []string{sarama.MetricLabelBroker}
+	topicLabels    = []string{sarama.MetricLabelTopic}
+	groupLabels    = []string{sarama.MetricLabelGroup}
+	codecLabels    = []string{sarama.MetricLabelCodec, sarama.MetricLabelTopic}
+	lagLabels      = []string{sarama.MetricLabelGroup, sarama.MetricLabelTopic, sarama.MetricLabelPartition}
+	apiLabels      = []string{sarama.MetricLabelAPI, sarama.MetricLabelBroker}
+	fallbackBucket = prometheus.DefBuckets
+)
+
+// definitions are those of the metrics documented by Sarama, by their names in
+// Sarama.
+var definitions = map[string]definition{
+	"incoming-byte-rate": {kind: counterKind, name: "incoming_bytes_total", help: "Bytes read from the brokers.", labels: brokerLabels},
+	"outgoing-byte-rate": {kind: counterKind, name: "outgoing_bytes_total", help: "Bytes written to the brokers.", labels: brokerLabels},
+	"request-rate":       {kind: counterKind, name: "requests_total", help: "Requests sent to the brokers.", labels: brokerLabels},
+	"response-rate":      {kind: counterKind, name: "responses_total", help: "Responses received from the brokers.", labels: brokerLabels},
+	"request-size":       {kind: histogramKind, name: "request_size_bytes", help: "Size of the requests sent to the brokers.", labels: brokerLabels, buckets: bytesBuckets},
+	"response-size":      {kind: histogramKind, name: "response_size_bytes", help: "Size of the responses received from the brokers.", labels: brokerLabels, buckets: bytesBuckets},
+	"request-latency-in-ms": {
+		kind: histogramKind, name: "request_latency_seconds", help: "Latency of the requests to the brokers.",
+		labels: brokerLabels, divisor: 1000, buckets: secondsBuckets,
+	},
+	"requests-in-flight": {kind: gaugeKind, name: "requests_in_flight", help: "Requests awaiting a response from the brokers.", labels: brokerLabels},
+	"throttle-time-in-ms": {
+		kind: histogramKind, name: "throttle_time_seconds", help: "Time the brokers throttled the requests for because of quotas.",
+		labels: brokerLabels, divisor: 1000, buckets: secondsBuckets,
+	},
+	"throttle-wait-in-ms": {
+		kind: histogramKind, name: "throttle_wait_seconds", help: "Time the requests were held back for while the brokers throttled the client.",
+		labels: brokerLabels, divisor: 1000, buckets: secondsBuckets,
+	},
+	"api-latency-in-ms": {
+		kind: histogramKind, name: "api_request_latency_seconds", help: "Latency of the requests to the brokers by API.",
+		labels: apiLabels, divisor: 1000, buckets: secondsBuckets,
+	},
+	"reconnect-rate": {kind: counterKind, name: "reconnects_total", help: "Connections re-established to the brokers.", labels: brokerLabels},
+	"tls-handshake-time-in-ms": {
+		kind: histogramKind, name: "tls_handshake_seconds", help: "Time the TLS handshakes with the brokers took.",
+		labels: brokerLabels, divisor: 1000, buckets: secondsBuckets,
+	},
+	"failed-authentication-rate": {kind: counterKind, name: "failed_authentications_total", help: "SASL authentications with the brokers which failed.", labels: brokerLabels},
+	"connection-age-in-ms":       {kind: gaugeKind, name: "connection_age_milliseconds", help: "Age of the connections to the brokers as of their last request.", labels: brokerLabels},
+	"api-throttle-time-in-ms": {
+		kind: histogramKind, name: "api_throttle_time_seconds", help: "Time the brokers throttled the requests for because of quotas, by API.",
+		labels: apiLabels, divisor: 1000, buckets: secondsBuckets,
+	},
+	"api-error-rate": {kind: counterKind, name: "api_request_errors_total", help: "Requests to the brokers which failed or were answered with an error code, by API.", labels: apiLabels},
+
+	"batch-size":          {kind: histogramKind, name: "batch_size_bytes", help: "Bytes sent per partition per request.", labels: topicLabels, buckets: bytesBuckets},
+	"record-send-rate":    {kind: counterKind, name: "records_sent_total", help: "Records sent to the topics.", labels: topicLabels},
+	"records-per-request": {kind: histogramKind, name: "records_per_request", help: "Records sent per request.", labels: topicLabels, buckets: countBuckets},
+	"records-per-batch":   {kind: histogramKind, name: "records_per_batch", help: "Records sent per partition per request.", labels: topicLabels, buckets: countBuckets},
+	"batch-fill-percentage": {
+		kind: histogramKind, name: "batch_fill_percentage", help: "Size of the batches sent in percent of Producer.MaxMessageBytes.",
+		labels: topicLabels, buckets: percentBuckets,
+	},
+	"compression-ratio": {
+		kind: histogramKind, name: "compression_ratio", help: "Compression ratio of the record batches sent, by codec.",
+		labels: codecLabels, divisor: 100, buckets: ratioBuckets,
+	},
+
+	"fetch-rate":       {kind: counterKind, name: "fetches_total", help: "FetchRequests sent, by topic.", labels: topicLabels},
+	"empty-fetch-rate": {kind: counterKind, name: "empty_fetches_total", help: "FetchRequests which returned no records, by topic.", labels: topicLabels},
+	"fetch-latency-in-ms": {
+		kind: histogramKind, name: "fetch_latency_seconds", help: "Latency of the FetchRequests, by topic.",
+		labels: topicLabels, divisor: 1000, buckets: secondsBuckets,
+	},
+	"records-per-fetch": {kind: histogramKind, name: "records_per_fetch", help: "Records returned per FetchRequest, by topic.", labels: topicLabels, buckets: countBuckets},
+	"bytes-per-fetch":   {kind: histogramKind, name: "bytes_per_fetch", help: "Bytes of records returned per FetchRequest, by topic.", labels: topicLabels, buckets: bytesBuckets},
+
+	"end-to-end-latency-in-ms": {
+		kind: histogramKind, name: "end_to_end_latency_seconds", help: "Time from the messages being sent to their being consumed, by topic.",
+		labels: topicLabels, divisor: 1000, buckets: secondsBuckets,
+	},
+
+	"consumer-batch-size":        {kind: histogramKind, name: "consumer_batch_size", help: "Messages per batch consumed.", buckets: countBuckets},
+	"consumer-group-join-total":  {kind: counterKind, name: "consumer_group_joins_total", help: "Attempts to join the consumer groups.", labels: groupLabels},
+	"consumer-group-join-failed": {kind: counterKind, name: "consumer_group_join_failures_total", help: "Failures to join the consumer groups.", labels: groupLabels},
+	"consumer-group-sync-total":  {kind: counterKind, name: "consumer_group_syncs_total", help: "Attempts to sync the consumer groups.", labels: groupLabels},
+	"consumer-group-sync-failed": {kind: counterKind, name: "consumer_group_sync_failures_total", help: "Failures to sync the consumer groups.", labels: groupLabels},
+	"consumer-group-lag":         {kind: gaugeKind, name: "consumer_group_lag", help: "Messages produced after the offsets committed by the consumer groups, by partition.", labels: lagLabels},
+	"consumer-group-lag-sum":     {kind: gaugeKind, name: "consumer_group_lag_sum", help: "Messages produced after the offsets committed by the consumer groups.", labels: groupLabels},
+}
+
+// defaultDefinition returns the definition of a metric missing from
+// definitions, named after its name in Sarama, and labelled as first recorded.
+func defaultDefinition(name string, k kind, labels sarama.MetricLabels) definition {
+	def := definition{
+		kind:    k,
+		name:    strings.Replace(name, "-", "_", -1),
+		help:    "Sarama metric " + name + ".",
+		buckets: fallbackBucket,
+	}
+	if k == counterKind {
+		def.name = strings.TrimSuffix(def.name, "_rate")
+		if !strings.HasSuffix(def.name, "_total") {
+			def.name += "_total"
+		}
+	}
+	for label := range labels {
+		def.labels = append(def.labels, label)
+	}
+	sort.Strings(def.labels)
+	return def
+}
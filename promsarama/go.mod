@@ -0,0 +1,10 @@
+module github.com/Shopify/sarama/promsarama
+
+go 1.16
+
+replace github.com/Shopify/sarama => ../
+
+require (
+	github.com/Shopify/sarama v1.32.0
+	github.com/prometheus/client_golang v1.12.2
+)
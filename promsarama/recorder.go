@@ -0,0 +1,225 @@
+// Package promsarama exports the metrics of Sarama to Prometheus.
+//
+// A Recorder is both the sarama.MetricsRecorder of the Config of the clients,
+// producers and consumers and the prometheus.Collector of their metrics:
+//
+//	recorder := promsarama.NewRecorder()
+//	prometheus.MustRegister(recorder)
+//	config := sarama.NewConfig()
+//	config.MetricsRecorder = recorder
+//
+// The metrics are named after those documented by Sarama, in the "sarama"
+// namespace, with the units and suffixes of Prometheus, e.g. request-rate is
+// sarama_requests_total and request-latency-in-ms is
+// sarama_request_latency_seconds. They are labelled by broker, topic or
+// group, and the latency and errors of the requests also by API; the metrics Sarama records for all the brokers or all the topics are
+// left out, as they are the sums of the labelled ones.
+package promsarama
+
+import (
+	"sync"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// Recorder records the metrics of Sarama as Prometheus metrics. It is safe for
+// concurrent use and may be shared by several sarama.Configs.
+type Recorder struct {
+	namespace   string
+	constLabels prometheus.Labels
+	buckets     map[string][]float64
+
+	lock     sync.Mutex
+	families map[string]*family
+}
+
+var (
+	_ sarama.MetricsRecorder = (*Recorder)(nil)
+	_ prometheus.Collector   = (*Recorder)(nil)
+)
+
+// Option configures a Recorder.
+type Option func(*Recorder)
+
+// WithNamespace sets the namespace of the metrics (defaults to "sarama").
+func WithNamespace(namespace string) Option {
+	return func(r *Recorder) {
+		r.namespace = namespace
+	}
+}
+
+// WithConstLabels sets labels added to all the metrics, e.g. to tell apart the
+// clusters of several clients.
+func WithConstLabels(labels prometheus.Labels) Option {
+	return func(r *Recorder) {
+		r.constLabels = labels
+	}
+}
+
+// WithBuckets sets the buckets of the histogram name, by its name in Sarama,
+// e.g. "request-latency-in-ms", in the unit of the Prometheus metric.
+func WithBuckets(name string, buckets []float64) Option {
+	return func(r *Recorder) {
+		r.buckets[name] = buckets
+	}
+}
+
+// NewRecorder returns a Recorder, to be set as the MetricsRecorder of the
+// sarama.Config and registered with Prometheus.
+func NewRecorder(opts ...Option) *Recorder {
+	r := &Recorder{
+		namespace: "sarama",
+		buckets:   make(map[string][]float64),
+		families:  make(map[string]*family),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	return r
+}
+
+// family is the vector of the metrics of a name, created once recorded.
+type family struct {
+	def       definition
+	collector prometheus.Collector
+}
+
+func (r *Recorder) family(name string, k kind, labels sarama.MetricLabels) *family {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+
+	f := r.families[name]
+	if f == nil {
+		def, ok := definitions[name]
+		if !ok {
+			def = defaultDefinition(name, k, labels)
+		}
+		if buckets, ok := r.buckets[name]; ok {
+			def.buckets = buckets
+		}
+		f = &family{def: def}
+		switch def.kind {
+		case counterKind:
+			f.collector = prometheus.NewCounterVec(prometheus.CounterOpts{
+				Namespace: r.namespace, Name: def.name, Help: def.help, ConstLabels: r.constLabels,
+			}, def.labels)
+		case gaugeKind:
+			f.collector = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+				Namespace: r.namespace, Name: def.name, Help: def.help, ConstLabels: r.constLabels,
+			}, def.labels)
+		case histogramKind:
+			f.collector = prometheus.NewHistogramVec(prometheus.HistogramOpts{
+				Namespace: r.namespace, Name: def.name, Help: def.help, ConstLabels: r.constLabels, Buckets: def.buckets,
+			}, def.labels)
+		}
+		r.families[name] = f
+	}
+
+	// the recordings of another kind, or with other labels, e.g. those
+	// aggregating the labelled ones, are not exported
+	if f.def.kind != k || len(f.def.labels) != len(labels) {
+		return nil
+	}
+	for _, label := range f.def.labels {
+		if _, ok := labels[label]; !ok {
+			return nil
+		}
+	}
+	return f
+}
+
+// Counter implements sarama.MetricsRecorder.
+func (r *Recorder) Counter(name string, labels sarama.MetricLabels) sarama.MetricsCounter {
+	f := r.family(name, counterKind, labels)
+	if f == nil {
+		return discard{}
+	}
+	return counter{f.collector.(*prometheus.CounterVec).With(prometheus.Labels(labels))}
+}
+
+// Gauge implements sarama.MetricsRecorder.
+func (r *Recorder) Gauge(name string, labels sarama.MetricLabels) sarama.MetricsGauge {
+	f := r.family(name, gaugeKind, labels)
+	if f == nil {
+		return discard{}
+	}
+	return gauge{f.collector.(*prometheus.GaugeVec).With(prometheus.Labels(labels))}
+}
+
+// Histogram implements sarama.MetricsRecorder.
+func (r *Recorder) Histogram(name string, labels sarama.MetricLabels) sarama.MetricsHistogram {
+	f := r.family(name, histogramKind, labels)
+	if f == nil {
+		return discard{}
+	}
+	divisor := f.def.divisor
+	if divisor == 0 {
+		divisor = 1
+	}
+	return histogram{f.collector.(*prometheus.HistogramVec).With(prometheus.Labels(labels)), divisor}
+}
+
+// Unregister drops the metric name with labels, e.g. that of a closed broker.
+func (r *Recorder) Unregister(name string, labels sarama.MetricLabels) {
+	r.lock.Lock()
+	f := r.families[name]
+	r.lock.Unlock()
+	if f == nil {
+		return
+	}
+	switch vec := f.collector.(type) {
+	case *prometheus.CounterVec:
+		vec.Delete(prometheus.Labels(labels))
+	case *prometheus.GaugeVec:
+		vec.Delete(prometheus.Labels(labels))
+	case *prometheus.HistogramVec:
+		vec.Delete(prometheus.Labels(labels))
+	}
+}
+
+// Describe implements prometheus.Collector. It describes no metric, as they
+// are created once recorded, which makes the Recorder an unchecked collector.
+func (r *Recorder) Describe(chan<- *prometheus.Desc) {}
+
+// Collect implements prometheus.Collector.
+func (r *Recorder) Collect(ch chan<- prometheus.Metric) {
+	r.lock.Lock()
+	collectors := make([]prometheus.Collector, 0, len(r.families))
+	for _, f := range r.families {
+		collectors = append(collectors, f.collector)
+	}
+	r.lock.Unlock()
+
+	for _, c := range collectors {
+		c.Collect(ch)
+	}
+}
+
+type counter struct{ prometheus.Counter }
+
+func (c counter) Add(delta int64) {
+	if delta > 0 {
+		c.Counter.Add(float64(delta))
+	}
+}
+
+type gauge struct{ prometheus.Gauge }
+
+func (g gauge) Add(delta int64) { g.Gauge.Add(float64(delta)) }
+
+func (g gauge) Set(value int64) { g.Gauge.Set(float64(value)) }
+
+type histogram struct {
+	prometheus.Observer
+	divisor float64
+}
+
+func (h histogram) Observe(value int64) { h.Observer.Observe(float64(value) / h.divisor) }
+
+// discard is the metric of the recordings which are not exported.
+type discard struct{}
+
+func (discard) Add(int64)     {}
+func (discard) Set(int64)     {}
+func (discard) Observe(int64) {}
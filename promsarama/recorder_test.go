@@ -0,0 +1,94 @@
+package promsarama
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestRecorder(t *testing.T) {
+	recorder := NewRecorder(WithConstLabels(prometheus.Labels{"cluster": "main"}))
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(recorder)
+
+	broker := sarama.MetricLabels{sarama.MetricLabelBroker: "1"}
+	recorder.Counter("request-rate", nil).Add(5)
+	recorder.Counter("request-rate", broker).Add(2)
+	recorder.Gauge("requests-in-flight", broker).Add(3)
+	recorder.Gauge("requests-in-flight", broker).Add(-1)
+	recorder.Histogram("request-latency-in-ms", broker).Observe(1500)
+	recorder.Counter("record-send-rate", sarama.MetricLabels{sarama.MetricLabelTopic: "my_topic"}).Add(10)
+	recorder.Histogram("consumer-batch-size", nil).Observe(4)
+	recorder.Counter("api-error-rate", sarama.MetricLabels{sarama.MetricLabelBroker: "1", sarama.MetricLabelAPI: "Heartbeat"}).Add(1)
+	recorder.Counter("undocumented-rate", sarama.MetricLabels{"partition": "0"}).Add(1)
+
+	expected := `
+# HELP sarama_api_request_errors_total Requests to the brokers which failed or were answered with an error code, by API.
+# TYPE sarama_api_request_errors_total counter
+sarama_api_request_errors_total{api="Heartbeat",broker="1",cluster="main"} 1
+# HELP sarama_consumer_batch_size Messages per batch consumed.
+# TYPE sarama_consumer_batch_size histogram
+sarama_consumer_batch_size_bucket{cluster="main",le="1"} 0
+sarama_consumer_batch_size_bucket{cluster="main",le="4"} 1
+sarama_consumer_batch_size_bucket{cluster="main",le="16"} 1
+sarama_consumer_batch_size_bucket{cluster="main",le="64"} 1
+sarama_consumer_batch_size_bucket{cluster="main",le="256"} 1
+sarama_consumer_batch_size_bucket{cluster="main",le="1024"} 1
+sarama_consumer_batch_size_bucket{cluster="main",le="4096"} 1
+sarama_consumer_batch_size_bucket{cluster="main",le="16384"} 1
+sarama_consumer_batch_size_bucket{cluster="main",le="65536"} 1
+sarama_consumer_batch_size_bucket{cluster="main",le="262144"} 1
+sarama_consumer_batch_size_bucket{cluster="main",le="+Inf"} 1
+sarama_consumer_batch_size_sum{cluster="main"} 4
+sarama_consumer_batch_size_count{cluster="main"} 1
+# HELP sarama_records_sent_total Records sent to the topics.
+# TYPE sarama_records_sent_total counter
+sarama_records_sent_total{cluster="main",topic="my_topic"} 10
+# HELP sarama_requests_in_flight Requests awaiting a response from the brokers.
+# TYPE sarama_requests_in_flight gauge
+sarama_requests_in_flight{broker="1",cluster="main"} 2
+# HELP sarama_requests_total Requests sent to the brokers.
+# TYPE sarama_requests_total counter
+sarama_requests_total{broker="1",cluster="main"} 2
+# HELP sarama_undocumented_total Sarama metric undocumented-rate.
+# TYPE sarama_undocumented_total counter
+sarama_undocumented_total{cluster="main",partition="0"} 1
+`
+	names := []string{
+		"sarama_api_request_errors_total", "sarama_consumer_batch_size", "sarama_records_sent_total", "sarama_requests_in_flight",
+		"sarama_requests_total", "sarama_undocumented_total",
+	}
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected), names...); err != nil {
+		t.Error(err)
+	}
+
+	if count := testutil.CollectAndCount(recorder, "sarama_request_latency_seconds"); count != 1 {
+		t.Errorf("expected the latency of the broker, got %d metrics", count)
+	}
+	recorder.Unregister("request-latency-in-ms", broker)
+	if count := testutil.CollectAndCount(recorder, "sarama_request_latency_seconds"); count != 0 {
+		t.Errorf("expected the latency of the broker to be unregistered, got %d metrics", count)
+	}
+}
+
+func TestRecorderBuckets(t *testing.T) {
+	recorder := NewRecorder(WithNamespace("kafka"), WithBuckets("request-latency-in-ms", []float64{0.5, 1}))
+	broker := sarama.MetricLabels{sarama.MetricLabelBroker: "1"}
+	recorder.Histogram("request-latency-in-ms", broker).Observe(700)
+
+	expected := `
+# HELP kafka_request_latency_seconds Latency of the requests to the brokers.
+# TYPE kafka_request_latency_seconds histogram
+kafka_request_latency_seconds_bucket{broker="1",le="0.5"} 0
+kafka_request_latency_seconds_bucket{broker="1",le="1"} 1
+kafka_request_latency_seconds_bucket{broker="1",le="+Inf"} 1
+kafka_request_latency_seconds_sum{broker="1"} 0.7
+kafka_request_latency_seconds_count{broker="1"} 1
+`
+	if err := testutil.CollectAndCompare(recorder, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
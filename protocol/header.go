@@ -0,0 +1,106 @@
+package protocol
+
+import (
+	"encoding/binary"
+
+	"github.com/Shopify/sarama"
+)
+
+var errTruncatedHeader = sarama.PacketDecodingError{Info: "truncated header"}
+
+// decoder decodes the fields of the headers, keeping the first error.
+type decoder struct {
+	buf []byte
+	off int
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || len(d.buf)-d.off < n {
+		d.err = errTruncatedHeader
+		return nil
+	}
+	b := d.buf[d.off : d.off+n]
+	d.off += n
+	return b
+}
+
+func (d *decoder) int16() int16 {
+	if b := d.take(2); b != nil {
+		return int16(binary.BigEndian.Uint16(b))
+	}
+	return 0
+}
+
+func (d *decoder) int32() int32 {
+	if b := d.take(4); b != nil {
+		return int32(binary.BigEndian.Uint32(b))
+	}
+	return 0
+}
+
+func (d *decoder) uvarint() uint64 {
+	if d.err != nil {
+		return 0
+	}
+	v, n := binary.Uvarint(d.buf[d.off:])
+	if n <= 0 {
+		d.err = errTruncatedHeader
+		return 0
+	}
+	d.off += n
+	return v
+}
+
+func (d *decoder) nullableString() *string {
+	n := d.int16()
+	if d.err != nil || n == -1 {
+		return nil
+	}
+	if b := d.take(int(n)); b != nil {
+		s := string(b)
+		return &s
+	}
+	return nil
+}
+
+// skipTaggedFields skips the tagged fields of a flexible header, none being
+// defined by the protocol yet.
+func (d *decoder) skipTaggedFields() {
+	for n := d.uvarint(); n > 0 && d.err == nil; n-- {
+		d.uvarint()
+		size := d.uvarint()
+		if size > uint64(len(d.buf)) {
+			d.err = errTruncatedHeader
+			return
+		}
+		d.take(int(size))
+	}
+}
+
+// encoder encodes the fields of the headers.
+type encoder struct {
+	buf []byte
+}
+
+func (e *encoder) int16(v int16) {
+	e.buf = append(e.buf, byte(uint16(v)>>8), byte(v))
+}
+
+func (e *encoder) int32(v int32) {
+	var b [4]byte
+	binary.BigEndian.PutUint32(b[:], uint32(v))
+	e.buf = append(e.buf, b[:]...)
+}
+
+func (e *encoder) nullableString(s *string) {
+	if s == nil {
+		e.int16(-1)
+		return
+	}
+	e.int16(int16(len(*s)))
+	e.buf = append(e.buf, *s...)
+}
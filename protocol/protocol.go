@@ -0,0 +1,178 @@
+/*
+Package protocol reads and writes the requests and the responses of the Kafka
+protocol, headers included, using the request and response types of Sarama.
+
+Sarama speaks the protocol as a client; this package lets the programs on the
+other side of the connection reuse it too, such as the proxies, the gateways
+and the test doubles of Kafka written in Go. A server reads the requests with
+ReadRequest and answers them with WriteResponse:
+
+	for {
+		req, err := protocol.ReadRequest(conn)
+		if err != nil {
+			return err
+		}
+		switch body := req.Body.(type) {
+		case *sarama.MetadataRequest:
+			res := &sarama.MetadataResponse{Version: body.Version}
+			// ...
+			err = protocol.WriteResponse(conn, &protocol.Response{CorrelationID: req.CorrelationID, Body: res})
+		}
+	}
+
+while a client, or a proxy forwarding the requests, writes them with
+WriteRequest and reads the responses with ReadResponse.
+*/
+package protocol
+
+import (
+	"bufio"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/Shopify/sarama"
+)
+
+// Body is the body of a request or of a response, a pointer to one of the
+// request and response types of Sarama, such as *sarama.MetadataRequest.
+type Body = sarama.ProtocolBody
+
+// Request is a request with its header.
+type Request struct {
+	CorrelationID int32
+	// ClientID is the client ID of the header, nil when null
+	ClientID *string
+	Body     Body
+}
+
+// Response is a response with its header.
+type Response struct {
+	CorrelationID int32
+	Body          Body
+}
+
+// ErrUnknownAPI is returned when reading a request or a response of an API
+// key Sarama does not know.
+var ErrUnknownAPI = errors.New("protocol: unknown API key")
+
+// ReadRequest reads a request of up to sarama.MaxRequestSize bytes from r.
+func ReadRequest(r io.Reader) (*Request, error) {
+	buf, err := readSized(r, sarama.MaxRequestSize)
+	if err != nil {
+		return nil, err
+	}
+	d := decoder{buf: buf}
+	key := d.int16()
+	version := d.int16()
+	req := &Request{CorrelationID: d.int32()}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if req.Body = sarama.NewProtocolRequestBody(key, version); req.Body == nil {
+		return nil, fmt.Errorf("%w %d", ErrUnknownAPI, key)
+	}
+	_, _, headerVersion := sarama.ProtocolBodyHeader(req.Body)
+	if headerVersion >= 1 {
+		req.ClientID = d.nullableString()
+	}
+	if headerVersion >= 2 {
+		d.skipTaggedFields()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := sarama.DecodeProtocolBody(d.buf[d.off:], req.Body, version); err != nil {
+		return nil, err
+	}
+	return req, nil
+}
+
+// WriteRequest writes req, as a single write to w.
+func WriteRequest(w io.Writer, req *Request) error {
+	key, version, headerVersion := sarama.ProtocolBodyHeader(req.Body)
+	var e encoder
+	e.int16(key)
+	e.int16(version)
+	e.int32(req.CorrelationID)
+	if headerVersion >= 1 {
+		e.nullableString(req.ClientID)
+	}
+	if headerVersion >= 2 {
+		// no tagged fields
+		e.buf = append(e.buf, 0)
+	}
+	return writeSized(w, e.buf, req.Body)
+}
+
+// ReadResponse reads a response of up to sarama.MaxResponseSize bytes from r,
+// to a request of the API key and version.
+func ReadResponse(r io.Reader, key, version int16) (*Response, error) {
+	body := sarama.NewProtocolResponseBody(key, version)
+	if body == nil {
+		return nil, fmt.Errorf("%w %d", ErrUnknownAPI, key)
+	}
+	buf, err := readSized(r, sarama.MaxResponseSize)
+	if err != nil {
+		return nil, err
+	}
+	d := decoder{buf: buf}
+	res := &Response{CorrelationID: d.int32(), Body: body}
+	if _, _, headerVersion := sarama.ProtocolBodyHeader(body); headerVersion >= 1 {
+		d.skipTaggedFields()
+	}
+	if d.err != nil {
+		return nil, d.err
+	}
+	if err := sarama.DecodeProtocolBody(d.buf[d.off:], body, version); err != nil {
+		return nil, err
+	}
+	return res, nil
+}
+
+// WriteResponse writes res, as a single write to w.
+func WriteResponse(w io.Writer, res *Response) error {
+	var e encoder
+	e.int32(res.CorrelationID)
+	if _, _, headerVersion := sarama.ProtocolBodyHeader(res.Body); headerVersion >= 1 {
+		// no tagged fields
+		e.buf = append(e.buf, 0)
+	}
+	return writeSized(w, e.buf, res.Body)
+}
+
+// readSized reads a message prefixed by its size, of at most max bytes.
+func readSized(r io.Reader, max int32) ([]byte, error) {
+	var size [4]byte
+	if _, err := io.ReadFull(r, size[:]); err != nil {
+		return nil, err
+	}
+	n := int32(binary.BigEndian.Uint32(size[:]))
+	if n < 4 || n > max {
+		return nil, sarama.PacketDecodingError{Info: fmt.Sprintf("message of length %d too large or too small", n)}
+	}
+	buf := make([]byte, n)
+	if _, err := io.ReadFull(r, buf); err != nil {
+		if errors.Is(err, io.EOF) {
+			err = io.ErrUnexpectedEOF
+		}
+		return nil, err
+	}
+	return buf, nil
+}
+
+// writeSized writes the header and the body prefixed by their size.
+func writeSized(w io.Writer, header []byte, body Body) error {
+	encoded, err := sarama.EncodeProtocolBody(body)
+	if err != nil {
+		return err
+	}
+	bw := bufio.NewWriterSize(w, 4+len(header)+len(encoded))
+	var size [4]byte
+	binary.BigEndian.PutUint32(size[:], uint32(len(header)+len(encoded)))
+	_, _ = bw.Write(size[:])
+	_, _ = bw.Write(header)
+	_, _ = bw.Write(encoded)
+	return bw.Flush()
+}
@@ -0,0 +1,153 @@
+package protocol
+
+import (
+	"bytes"
+	"errors"
+	"net"
+	"reflect"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestRequestRoundTrip(t *testing.T) {
+	clientID := "client"
+	for _, req := range []*Request{
+		{CorrelationID: 1, ClientID: &clientID, Body: &sarama.MetadataRequest{Version: 1, Topics: []string{"foo"}}},
+		{CorrelationID: 2, Body: &sarama.DescribeProducersRequest{Topics: []*sarama.DescribeProducersRequestTopicRequest{{
+			Name:             "foo",
+			PartitionIndexes: []int32{0},
+		}}}},
+	} {
+		var buf bytes.Buffer
+		if err := WriteRequest(&buf, req); err != nil {
+			t.Fatal(err)
+		}
+		decoded, err := ReadRequest(&buf)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(req, decoded) {
+			t.Errorf("expected %+v, got %+v", req, decoded)
+		}
+		if buf.Len() != 0 {
+			t.Errorf("expected the request to be read whole, %d bytes remaining", buf.Len())
+		}
+	}
+}
+
+func TestResponseRoundTrip(t *testing.T) {
+	errorMessage := "bad"
+	res := &Response{CorrelationID: 3, Body: &sarama.DescribeProducersResponse{
+		ThrottleTimeMs: 100,
+		Topics: []*sarama.DescribeProducersResponseTopicResponse{{
+			Name: "foo",
+			Partitions: []*sarama.DescribeProducersResponsePartitionResponse{{
+				ErrorCode:       int16(sarama.ErrUnknownTopicOrPartition),
+				ErrorMessage:    &errorMessage,
+				ActiveProducers: []*sarama.DescribeProducersResponseProducerState{},
+			}},
+		}},
+	}}
+	var buf bytes.Buffer
+	if err := WriteResponse(&buf, res); err != nil {
+		t.Fatal(err)
+	}
+	decoded, err := ReadResponse(&buf, 61, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(res, decoded) {
+		t.Errorf("expected %+v, got %+v", res, decoded)
+	}
+}
+
+func TestReadUnknownAPI(t *testing.T) {
+	buf := []byte{
+		0, 0, 0, 8, // size
+		0x7f, 0x7f, // unknown api key
+		0, 0, // version
+		0, 0, 0, 1, // correlation ID
+	}
+	if _, err := ReadRequest(bytes.NewReader(buf)); !errors.Is(err, ErrUnknownAPI) {
+		t.Errorf("expected ErrUnknownAPI, got %v", err)
+	}
+	if _, err := ReadResponse(bytes.NewReader(buf), 0x7f7f, 0); !errors.Is(err, ErrUnknownAPI) {
+		t.Errorf("expected ErrUnknownAPI, got %v", err)
+	}
+}
+
+func TestReadTruncatedHeader(t *testing.T) {
+	buf := []byte{
+		0, 0, 0, 6, // size
+		0, 3, // metadata
+		0, 1, // version
+		0, 0, // truncated correlation ID
+	}
+	var decodingErr sarama.PacketDecodingError
+	if _, err := ReadRequest(bytes.NewReader(buf)); !errors.As(err, &decodingErr) {
+		t.Errorf("expected a PacketDecodingError, got %v", err)
+	}
+}
+
+// TestServer serves a sarama.Broker from a server written with the package.
+func TestServer(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer listener.Close()
+
+	served := make(chan error, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			served <- err
+			return
+		}
+		defer conn.Close()
+		for {
+			req, err := ReadRequest(conn)
+			if err != nil {
+				served <- err
+				return
+			}
+			var body Body
+			switch r := req.Body.(type) {
+			case *sarama.ApiVersionsRequest:
+				body = &sarama.ApiVersionsResponse{Version: r.Version, ApiKeys: []sarama.ApiVersionsResponseKey{
+					{Version: r.Version, ApiKey: 3, MinVersion: 0, MaxVersion: 7},
+					{Version: r.Version, ApiKey: 18, MinVersion: 0, MaxVersion: 3},
+				}}
+			case *sarama.MetadataRequest:
+				metadata := &sarama.MetadataResponse{Version: r.Version}
+				metadata.AddBroker(listener.Addr().String(), 1)
+				metadata.AddTopicPartition("foo", 0, 1, nil, nil, nil, sarama.ErrNoError)
+				body = metadata
+			}
+			if err := WriteResponse(conn, &Response{CorrelationID: req.CorrelationID, Body: body}); err != nil {
+				served <- err
+				return
+			}
+		}
+	}()
+
+	conf := sarama.NewConfig()
+	conf.Version = sarama.V2_4_0_0
+	broker := sarama.NewBroker(listener.Addr().String())
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	metadata, err := broker.GetMetadata(&sarama.MetadataRequest{Version: 5, Topics: []string{"foo"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(metadata.Topics) != 1 || metadata.Topics[0].Name != "foo" || len(metadata.Topics[0].Partitions) != 1 {
+		t.Errorf("unexpected metadata %+v", metadata)
+	}
+	_ = broker.Close()
+
+	if err := <-served; err == nil {
+		t.Error("expected the connection to be closed")
+	}
+}
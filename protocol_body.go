@@ -0,0 +1,145 @@
+package sarama
+
+// ProtocolBody is the body of a request or of a response of the Kafka
+// protocol, implemented by the request and response types of Sarama. The
+// protocol package reads and writes them, headers included, for the programs
+// speaking the protocol on either side of the connection.
+type ProtocolBody interface {
+	protocolBody
+}
+
+// NewProtocolRequestBody returns an empty request body of the API key and
+// version, or nil if Sarama does not know the API.
+func NewProtocolRequestBody(key, version int16) ProtocolBody {
+	return allocateBody(key, version)
+}
+
+// NewProtocolResponseBody returns an empty response body of the API key and
+// version, or nil if Sarama does not know the API.
+func NewProtocolResponseBody(key, version int16) ProtocolBody {
+	return allocateResponseBody(key, version)
+}
+
+// ProtocolAPIName returns the name of the API key as in the Kafka protocol
+// documentation, such as "Metadata" for 3, or "ApiKey(n)" if Sarama does not
+// know the API.
+func ProtocolAPIName(key int16) string {
+	return apiName(key)
+}
+
+// ProtocolBodyHeader returns the API key and version of body, and the version
+// of the request or response header preceding it.
+func ProtocolBodyHeader(body ProtocolBody) (key, version, headerVersion int16) {
+	return body.key(), body.version(), body.headerVersion()
+}
+
+// EncodeProtocolBody encodes body, without its header.
+func EncodeProtocolBody(body ProtocolBody) ([]byte, error) {
+	return encode(body, nil)
+}
+
+// DecodeProtocolBody decodes buf, a body without its header, into body as of
+// version, failing if bytes trail it.
+func DecodeProtocolBody(buf []byte, body ProtocolBody, version int16) error {
+	return versionedDecode(buf, body, version)
+}
+
+// allocateResponseBody returns the response body of the API key and version,
+// or nil for an unknown key, as allocateBody does for the requests.
+func allocateResponseBody(key, version int16) protocolBody {
+	switch key {
+	case 0:
+		return &ProduceResponse{Version: version}
+	case 1:
+		return &FetchResponse{Version: version}
+	case 2:
+		return &OffsetResponse{Version: version}
+	case 3:
+		return &MetadataResponse{Version: version}
+	case 8:
+		return &OffsetCommitResponse{Version: version}
+	case 9:
+		return &OffsetFetchResponse{Version: version}
+	case 10:
+		return &FindCoordinatorResponse{Version: version}
+	case 11:
+		return &JoinGroupResponse{Version: version}
+	case 12:
+		return &HeartbeatResponse{Version: version}
+	case 13:
+		return &LeaveGroupResponse{Version: version}
+	case 14:
+		return &SyncGroupResponse{Version: version}
+	case 15:
+		return &DescribeGroupsResponse{Version: version}
+	case 16:
+		return &ListGroupsResponse{Version: version}
+	case 17:
+		return &SaslHandshakeResponse{}
+	case 18:
+		return &ApiVersionsResponse{Version: version}
+	case 19:
+		return &CreateTopicsResponse{Version: version}
+	case 20:
+		return &DeleteTopicsResponse{Version: version}
+	case 21:
+		return &DeleteRecordsResponse{Version: version}
+	case 22:
+		return &InitProducerIDResponse{Version: version}
+	case 24:
+		return &AddPartitionsToTxnResponse{Version: version}
+	case 25:
+		return &AddOffsetsToTxnResponse{Version: version}
+	case 26:
+		return &EndTxnResponse{Version: version}
+	case 28:
+		return &TxnOffsetCommitResponse{Version: version}
+	case 29:
+		return &DescribeAclsResponse{Version: version}
+	case 30:
+		return &CreateAclsResponse{Version: version}
+	case 31:
+		return &DeleteAclsResponse{Version: version}
+	case 32:
+		return &DescribeConfigsResponse{Version: version}
+	case 33:
+		return &AlterConfigsResponse{Version: version}
+	case 35:
+		return &DescribeLogDirsResponse{Version: version}
+	case 36:
+		return &SaslAuthenticateResponse{Version: version}
+	case 37:
+		return &CreatePartitionsResponse{Version: version}
+	case 42:
+		return &DeleteGroupsResponse{Version: version}
+	case 43:
+		return &ElectLeadersResponse{Version: version}
+	case 44:
+		return &IncrementalAlterConfigsResponse{Version: version}
+	case 45:
+		return &AlterPartitionReassignmentsResponse{}
+	case 46:
+		return &ListPartitionReassignmentsResponse{}
+	case 47:
+		return &DeleteOffsetsResponse{}
+	case 48:
+		return &DescribeClientQuotasResponse{Version: version}
+	case 49:
+		return &AlterClientQuotasResponse{Version: version}
+	case 50:
+		return &DescribeUserScramCredentialsResponse{}
+	case 51:
+		return &AlterUserScramCredentialsResponse{}
+	case 60:
+		return &DescribeClusterResponse{Version: version}
+	case 61:
+		return &DescribeProducersResponse{Version: version}
+	case 71:
+		return &GetTelemetrySubscriptionsResponse{Version: version}
+	case 72:
+		return &PushTelemetryResponse{Version: version}
+	case 75:
+		return &DescribeTopicPartitionsResponse{Version: version}
+	}
+	return nil
+}
@@ -0,0 +1,20 @@
+package sarama
+
+import "testing"
+
+func TestAllocateResponseBody(t *testing.T) {
+	for key := range apiNames {
+		request := allocateBody(key, 0)
+		response := allocateResponseBody(key, 0)
+		if request == nil || response == nil {
+			t.Errorf("%s: expected a request and a response body", apiName(key))
+			continue
+		}
+		if response.key() != key {
+			t.Errorf("%s: expected the response body of key %d, got %T", apiName(key), key, response)
+		}
+	}
+	if NewProtocolResponseBody(-1, 0) != nil {
+		t.Error("expected no response body of an unknown key")
+	}
+}
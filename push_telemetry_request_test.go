@@ -0,0 +1,23 @@
+package sarama
+
+import "testing"
+
+var pushTelemetryRequestTerminating = []byte{
+	0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15, // client instance id
+	0, 0, 0, 7, // subscription id 7
+	1,                // terminating
+	1,                // gzip
+	4, 0x0a, 0x01, 0, // 4-1=3 bytes of metrics
+	0, // empty tagged fields
+}
+
+func TestPushTelemetryRequest(t *testing.T) {
+	request := &PushTelemetryRequest{
+		ClientInstanceID: Uuid{0, 1, 2, 3, 4, 5, 6, 7, 8, 9, 10, 11, 12, 13, 14, 15},
+		SubscriptionID:   7,
+		Terminating:      true,
+		CompressionType:  CompressionGZIP,
+		Metrics:          []byte{0x0a, 0x01, 0},
+	}
+	testRequest(t, "terminating", request, pushTelemetryRequestTerminating)
+}
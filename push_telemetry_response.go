@@ -0,0 +1,60 @@
+package sarama
+
+import "time"
+
+// PushTelemetryResponse acknowledges the metrics pushed by a
+// PushTelemetryRequest.
+type PushTelemetryResponse struct {
+	Version        int16
+	ThrottleTimeMs int32
+	Err            KError
+}
+
+func (r *PushTelemetryResponse) encode(pe packetEncoder) error {
+	pe.putInt32(r.ThrottleTimeMs)
+	pe.putInt16(int16(r.Err))
+	pe.putEmptyTaggedFieldArray()
+	return nil
+}
+
+func (r *PushTelemetryResponse) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if r.ThrottleTimeMs, err = pd.getInt32(); err != nil {
+		return err
+	}
+	kerr, err := pd.getInt16()
+	if err != nil {
+		return err
+	}
+	r.Err = KError(kerr)
+	_, err = pd.getEmptyTaggedFieldArray()
+	return err
+}
+
+func (r *PushTelemetryResponse) key() int16 {
+	return 72
+}
+
+func (r *PushTelemetryResponse) version() int16 {
+	return r.Version
+}
+
+func (r *PushTelemetryResponse) headerVersion() int16 {
+	return 1
+}
+
+func (r *PushTelemetryResponse) requiredVersion() KafkaVersion {
+	return V3_7_0_0
+}
+
+func (r *PushTelemetryResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTimeMs) * time.Millisecond
+}
+
+func (r *PushTelemetryResponse) shouldClientThrottle(version int16) bool {
+	return true
+}
+
+func (r *PushTelemetryResponse) hasErrorCode() bool {
+	return r.Err != ErrNoError
+}
@@ -0,0 +1,17 @@
+package sarama
+
+import "testing"
+
+var pushTelemetryResponseUnknownSubscription = []byte{
+	0, 0, 0, 10, // throttle time 10ms
+	0, 117, // ErrUnknownSubscriptionID
+	0, // empty tagged fields
+}
+
+func TestPushTelemetryResponse(t *testing.T) {
+	response := &PushTelemetryResponse{
+		ThrottleTimeMs: 10,
+		Err:            ErrUnknownSubscriptionID,
+	}
+	testResponse(t, "unknown subscription", response, pushTelemetryResponseUnknownSubscription)
+}
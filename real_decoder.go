@@ -18,6 +18,18 @@ type realDecoder struct {
 	raw   []byte
 	off   int
 	stack []pushDecoder
+	// skipCRC leaves the CRC fields unchecked, see CRCSkipValidation
+	skipCRC bool
+	// lazyHeaders leaves the headers of the records undecoded, see
+	// Consumer.Fetch.LazyHeaders
+	lazyHeaders bool
+	// zstd selects the decoder decompressing the records, see Consumer.Zstd
+	zstd ZstdDecoderParams
+	// limits bounds the memory decoding the records allocates, see
+	// Consumer.Fetch.MaxDecodedBytes
+	limits *fetchLimits
+	// buffers are those of the partition to decompress the records into
+	buffers *decompressBuffers
 }
 
 // primitives
@@ -112,7 +124,7 @@ func (rd *realDecoder) getArrayLength() (int, error) {
 	if tmp > rd.remaining() {
 		rd.off = len(rd.raw)
 		return -1, ErrInsufficientData
-	} else if tmp > 2*math.MaxUint16 {
+	} else if tmp > 2*math.MaxUint16 || tmp < -1 {
 		return -1, errInvalidArrayLength
 	}
 	return tmp, nil
@@ -128,6 +140,12 @@ func (rd *realDecoder) getCompactArrayLength() (int, error) {
 		return 0, nil
 	}
 
+	if n-1 > uint64(rd.remaining()) {
+		rd.off = len(rd.raw)
+		return -1, ErrInsufficientData
+	} else if n-1 > 2*math.MaxUint16 {
+		return -1, errInvalidArrayLength
+	}
 	return int(n) - 1, nil
 }
 
@@ -200,6 +218,10 @@ func (rd *realDecoder) getCompactBytes() ([]byte, error) {
 		return nil, err
 	}
 
+	if n > uint64(rd.remaining())+1 {
+		rd.off = len(rd.raw)
+		return nil, ErrInsufficientData
+	}
 	length := int(n - 1)
 	return rd.getRawBytes(length)
 }
@@ -254,6 +276,9 @@ func (rd *realDecoder) getCompactString() (string, error) {
 	length := int(n - 1)
 	if length < 0 {
 		return "", errInvalidByteSliceLength
+	} else if n > uint64(rd.remaining())+1 {
+		rd.off = len(rd.raw)
+		return "", ErrInsufficientData
 	}
 	tmpStr := string(rd.raw[rd.off : rd.off+length])
 	rd.off += length
@@ -270,6 +295,9 @@ func (rd *realDecoder) getCompactNullableString() (*string, error) {
 
 	if length < 0 {
 		return nil, err
+	} else if n > uint64(rd.remaining())+1 {
+		rd.off = len(rd.raw)
+		return nil, ErrInsufficientData
 	}
 
 	tmpStr := string(rd.raw[rd.off : rd.off+length])
@@ -288,6 +316,10 @@ func (rd *realDecoder) getCompactInt32Array() ([]int32, error) {
 	}
 
 	arrayLength := int(n) - 1
+	if n-1 > uint64(rd.remaining()/4) {
+		rd.off = len(rd.raw)
+		return nil, ErrInsufficientData
+	}
 
 	ret := make([]int32, arrayLength)
 
@@ -361,7 +393,7 @@ func (rd *realDecoder) getStringArray() ([]string, error) {
 		rd.off = len(rd.raw)
 		return nil, ErrInsufficientData
 	}
-	n := int(binary.BigEndian.Uint32(rd.raw[rd.off:]))
+	n := int(int32(binary.BigEndian.Uint32(rd.raw[rd.off:])))
 	rd.off += 4
 
 	if n == 0 {
@@ -372,6 +404,12 @@ func (rd *realDecoder) getStringArray() ([]string, error) {
 		return nil, errInvalidArrayLength
 	}
 
+	// each string takes at least its length
+	if rd.remaining() < 2*n {
+		rd.off = len(rd.raw)
+		return nil, ErrInsufficientData
+	}
+
 	ret := make([]string, n)
 	for i := range ret {
 		str, err := rd.getString()
@@ -384,6 +422,28 @@ func (rd *realDecoder) getStringArray() ([]string, error) {
 	return ret, nil
 }
 
+func (rd *realDecoder) getCompactStringArray() ([]string, error) {
+	n, err := rd.getCompactArrayLength()
+	if err != nil {
+		return nil, err
+	}
+
+	if n <= 0 {
+		return nil, nil
+	}
+
+	ret := make([]string, n)
+	for i := range ret {
+		str, err := rd.getCompactString()
+		if err != nil {
+			return nil, err
+		}
+
+		ret[i] = str
+	}
+	return ret, nil
+}
+
 // subsets
 
 func (rd *realDecoder) remaining() int {
@@ -452,10 +512,29 @@ func (rd *realDecoder) push(in pushDecoder) error {
 	return nil
 }
 
+func (rd *realDecoder) lazyRecordHeaders() bool {
+	return rd.lazyHeaders
+}
+
+func (rd *realDecoder) zstdDecoderParams() ZstdDecoderParams {
+	return rd.zstd
+}
+
+func (rd *realDecoder) recordsLimits() *fetchLimits {
+	return rd.limits
+}
+
+func (rd *realDecoder) recordsBuffers() *decompressBuffers {
+	return rd.buffers
+}
+
 func (rd *realDecoder) pop() error {
 	// this is go's ugly pop pattern (the inverse of append)
 	in := rd.stack[len(rd.stack)-1]
 	rd.stack = rd.stack[:len(rd.stack)-1]
 
+	if _, ok := in.(*crc32Field); ok && rd.skipCRC {
+		return nil
+	}
 	return in.check(rd.off, rd.raw)
 }
@@ -4,15 +4,30 @@ import (
 	"encoding/binary"
 	"errors"
 	"math"
-
-	"github.com/rcrowley/go-metrics"
+	"net"
 )
 
+// vectoredMinBytes is the size from which encodeVectored references the raw
+// bytes rather than copy them, such as the records of a batch.
+const vectoredMinBytes = 16 * 1024
+
 type realEncoder struct {
 	raw      []byte
 	off      int
 	stack    []pushEncoder
-	registry metrics.Registry
+	recorder MetricsRecorder
+	// vectored references the raw bytes of at least vectoredMinBytes in
+	// external rather than copy them into raw, see encodeVectored
+	vectored    bool
+	external    []externalBytes
+	externalLen int
+}
+
+// externalBytes are raw bytes referenced by a vectored realEncoder, as if
+// they were inserted at off in its raw.
+type externalBytes struct {
+	off  int
+	data []byte
 }
 
 // primitives
@@ -71,6 +86,11 @@ func (re *realEncoder) putBool(in bool) {
 // collection
 
 func (re *realEncoder) putRawBytes(in []byte) error {
+	if re.vectored && len(in) >= vectoredMinBytes {
+		re.external = append(re.external, externalBytes{off: re.off, data: in})
+		re.externalLen += len(in)
+		return nil
+	}
 	copy(re.raw[re.off:], in)
 	re.off += len(in)
 	return nil
@@ -142,6 +162,18 @@ func (re *realEncoder) putStringArray(in []string) error {
 	return nil
 }
 
+func (re *realEncoder) putCompactStringArray(in []string) error {
+	re.putCompactArrayLength(len(in))
+
+	for _, val := range in {
+		if err := re.putCompactString(val); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func (re *realEncoder) putCompactInt32Array(in []int32) error {
 	if in == nil {
 		return errors.New("expected int32 array to be non null")
@@ -194,7 +226,7 @@ func (re *realEncoder) putEmptyTaggedFieldArray() {
 }
 
 func (re *realEncoder) offset() int {
-	return re.off
+	return re.off + re.externalLen
 }
 
 // stacks
@@ -210,10 +242,28 @@ func (re *realEncoder) pop() error {
 	in := re.stack[len(re.stack)-1]
 	re.stack = re.stack[:len(re.stack)-1]
 
+	if v, ok := in.(vectoredPushEncoder); ok && len(re.external) > 0 {
+		return v.runVectored(re.off, re.raw, re.external)
+	}
 	return in.run(re.off, re.raw)
 }
 
+// buffers returns the encoding of a vectored realEncoder, its raw split
+// around the external bytes.
+func (re *realEncoder) buffers() net.Buffers {
+	bufs := make(net.Buffers, 0, 2*len(re.external)+1)
+	pos := 0
+	for _, ext := range re.external {
+		if ext.off > pos {
+			bufs = append(bufs, re.raw[pos:ext.off])
+			pos = ext.off
+		}
+		bufs = append(bufs, ext.data)
+	}
+	return append(bufs, re.raw[pos:])
+}
+
 // we do record metrics during the real encoder pass
-func (re *realEncoder) metricRegistry() metrics.Registry {
-	return re.registry
+func (re *realEncoder) metricsRecorder() MetricsRecorder {
+	return re.recorder
 }
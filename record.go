@@ -45,6 +45,10 @@ type Record struct {
 	Key            []byte
 	Value          []byte
 	length         varintLengthField
+
+	// rawHeaders holds the still encoded headers, count included, when
+	// decoded with Consumer.Fetch.LazyHeaders
+	rawHeaders []byte
 }
 
 func (r *Record) encode(pe packetEncoder) error {
@@ -73,6 +77,7 @@ func (r *Record) decode(pd packetDecoder) (err error) {
 	if err = pd.push(&r.length); err != nil {
 		return err
 	}
+	end := pd.remaining() - int(r.length.length)
 
 	if r.Attributes, err = pd.getInt8(); err != nil {
 		return err
@@ -96,21 +101,49 @@ func (r *Record) decode(pd packetDecoder) (err error) {
 		return err
 	}
 
+	if pd.lazyRecordHeaders() {
+		if r.rawHeaders, err = pd.getRawBytes(pd.remaining() - end); err != nil {
+			return err
+		}
+		return pd.pop()
+	}
+
+	if r.Headers, err = decodeRecordHeaders(pd); err != nil {
+		return err
+	}
+
+	return pd.pop()
+}
+
+// decodeRawRecordHeaders decodes the headers, count included, of raw.
+func decodeRawRecordHeaders(raw []byte) ([]*RecordHeader, error) {
+	helper := realDecoder{raw: raw}
+	headers, err := decodeRecordHeaders(&helper)
+	if err != nil {
+		return nil, err
+	}
+	if helper.remaining() != 0 {
+		return nil, PacketDecodingError{"invalid length"}
+	}
+	return headers, nil
+}
+
+func decodeRecordHeaders(pd packetDecoder) ([]*RecordHeader, error) {
 	numHeaders, err := pd.getVarint()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
+	var headers []*RecordHeader
 	if numHeaders >= 0 {
-		r.Headers = make([]*RecordHeader, numHeaders)
+		headers = make([]*RecordHeader, numHeaders)
 	}
 	for i := int64(0); i < numHeaders; i++ {
 		hdr := new(RecordHeader)
 		if err := hdr.decode(pd); err != nil {
-			return err
+			return nil, err
 		}
-		r.Headers[i] = hdr
+		headers[i] = hdr
 	}
-
-	return pd.pop()
+	return headers, nil
 }
@@ -1,6 +1,7 @@
 package sarama
 
 import (
+	"encoding/binary"
 	"errors"
 	"fmt"
 	"time"
@@ -8,6 +9,10 @@ import (
 
 const recordBatchOverhead = 49
 
+// recordBatchHeaderSize is the size of the header of a record batch, from its
+// first offset up to its number of records included.
+const recordBatchHeaderSize = 12 + recordBatchOverhead
+
 type recordsArray []*Record
 
 func (e recordsArray) encode(pe packetEncoder) error {
@@ -30,6 +35,26 @@ func (e recordsArray) decode(pd packetDecoder) error {
 	return nil
 }
 
+// decodeRecords decodes the records of buf as decode does, leaving their
+// headers undecoded if lazyHeaders is set, see Consumer.Fetch.LazyHeaders.
+func decodeRecords(buf []byte, records []*Record, lazyHeaders bool) (err error) {
+	if buf == nil {
+		return nil
+	}
+	defer recoverDecodingPanic(recordsArray(records), &err)
+
+	helper := realDecoder{raw: buf, lazyHeaders: lazyHeaders}
+	if err = recordsArray(records).decode(&helper); err != nil {
+		return err
+	}
+
+	if helper.off != len(buf) {
+		return PacketDecodingError{"invalid length"}
+	}
+
+	return nil
+}
+
 type RecordBatch struct {
 	FirstOffset           int64
 	PartitionLeaderEpoch  int32
@@ -50,6 +75,12 @@ type RecordBatch struct {
 
 	compressedRecords []byte
 	recordsLen        int // uncompressed records size
+	// decompressed holds the records decompressed into a buffer of buffers,
+	// handed back by release
+	decompressed []byte
+	buffers      *decompressBuffers
+	// compression configures the codec beyond CompressionLevel
+	compression compressionOptions
 }
 
 func (b *RecordBatch) LastOffset() int64 {
@@ -161,6 +192,9 @@ func (b *RecordBatch) decode(pd packetDecoder) (err error) {
 	if err != nil {
 		return err
 	}
+	if err = pd.recordsLimits().checkRecords(numRecs); err != nil {
+		return err
+	}
 	if numRecs >= 0 {
 		b.Records = make([]*Record, numRecs)
 	}
@@ -180,13 +214,20 @@ func (b *RecordBatch) decode(pd packetDecoder) (err error) {
 		return err
 	}
 
-	recBuffer, err = decompress(b.Codec, recBuffer)
-	if err != nil {
-		return err
+	if b.Codec != CompressionNone {
+		buffers := pd.recordsBuffers()
+		recBuffer, err = pd.recordsLimits().decompress(b.Codec, pd.zstdDecoderParams(), buffers.get(), recBuffer)
+		if err != nil {
+			return err
+		}
+		if buffers != nil {
+			buffers.observe(len(recBuffer))
+			b.decompressed, b.buffers = recBuffer, buffers
+		}
 	}
 
 	b.recordsLen = len(recBuffer)
-	err = decode(recBuffer, recordsArray(b.Records))
+	err = decodeRecords(recBuffer, b.Records, pd.lazyRecordHeaders())
 	if errors.Is(err, ErrInsufficientData) {
 		b.PartialTrailingRecord = true
 		b.Records = nil
@@ -195,15 +236,23 @@ func (b *RecordBatch) decode(pd packetDecoder) (err error) {
 	return err
 }
 
+// release hands the buffer the records were decompressed into back for reuse,
+// after which neither the records nor their keys, values and headers may be
+// used.
+func (b *RecordBatch) release() {
+	b.buffers.put(b.decompressed)
+	b.decompressed, b.buffers = nil, nil
+}
+
 func (b *RecordBatch) encodeRecords(pe packetEncoder) error {
 	var raw []byte
 	var err error
-	if raw, err = encode(recordsArray(b.Records), pe.metricRegistry()); err != nil {
+	if raw, err = encode(recordsArray(b.Records), pe.metricsRecorder()); err != nil {
 		return err
 	}
 	b.recordsLen = len(raw)
 
-	b.compressedRecords, err = compress(b.Codec, b.CompressionLevel, raw)
+	b.compressedRecords, err = compress(b.Codec, b.CompressionLevel, b.compression, raw)
 	return err
 }
 
@@ -224,3 +273,30 @@ func (b *RecordBatch) computeAttributes() int16 {
 func (b *RecordBatch) addRecord(r *Record) {
 	b.Records = append(b.Records, r)
 }
+
+// recordBatchHeader is the header of a record batch, read without decoding
+// the batch.
+type recordBatchHeader struct {
+	firstOffset     int64
+	lastOffsetDelta int32
+	numRecords      int32
+	size            int // the size of the whole batch, in bytes
+}
+
+// peekRecordBatchHeader reads the header of the record batch raw starts with,
+// reporting false unless raw holds a whole batch of the current format.
+func peekRecordBatchHeader(raw []byte) (recordBatchHeader, bool) {
+	if len(raw) < recordBatchHeaderSize || raw[magicOffset] != 2 {
+		return recordBatchHeader{}, false
+	}
+	batchLen := int(int32(binary.BigEndian.Uint32(raw[8:])))
+	if batchLen < recordBatchOverhead || 12+batchLen > len(raw) {
+		return recordBatchHeader{}, false
+	}
+	return recordBatchHeader{
+		firstOffset:     int64(binary.BigEndian.Uint64(raw)),
+		lastOffsetDelta: int32(binary.BigEndian.Uint32(raw[23:])),
+		numRecords:      int32(binary.BigEndian.Uint32(raw[57:])),
+		size:            12 + batchLen,
+	}, true
+}
@@ -294,3 +294,24 @@ func TestRecordBatchDecoding(t *testing.T) {
 		}
 	}
 }
+
+func TestRecordBatchLazyHeadersDecoding(t *testing.T) {
+	for _, tc := range recordBatchTestCases() {
+		batch := RecordBatch{}
+		if err := batch.decode(&realDecoder{raw: tc.encoded, lazyHeaders: true}); err != nil {
+			t.Fatalf("%s: %v", tc.name, err)
+		}
+		for i, r := range batch.Records {
+			if r.Headers != nil {
+				t.Errorf("%s: record %d headers decoded eagerly", tc.name, i)
+			}
+			msg := &ConsumerMessage{rawHeaders: r.rawHeaders}
+			if got, want := msg.RecordHeaders(), tc.batch.Records[i].Headers; !reflect.DeepEqual(got, want) {
+				t.Errorf(spew.Sprintf("%s: record %d lazily decoded headers %+v, wanted %+v", tc.name, i, got, want))
+			}
+			if msg.rawHeaders != nil || !reflect.DeepEqual(msg.Headers, tc.batch.Records[i].Headers) {
+				t.Errorf("%s: record %d headers not kept in Headers", tc.name, i)
+			}
+		}
+	}
+}
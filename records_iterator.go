@@ -0,0 +1,190 @@
+package sarama
+
+import "errors"
+
+// CRCValidation is how the CRCs of the record batches fetched are validated,
+// see Consumer.Fetch.CRCValidation.
+type CRCValidation int8
+
+const (
+	// CRCValidateAndFail fails the fetch of a record batch whose CRC doesn't
+	// match its bytes.
+	CRCValidateAndFail CRCValidation = iota
+	// CRCValidateAndSkip drops the record batches whose CRC doesn't match
+	// their bytes, going on with the next ones.
+	CRCValidateAndSkip
+	// CRCSkipValidation doesn't compute the CRCs of the record batches,
+	// trusting the brokers and the network.
+	CRCSkipValidation
+)
+
+// RecordsIterator decodes the record batches of a FetchResponseBlock one at a
+// time. Over a block decoded with Consumer.Fetch.Lazy set, it holds a single
+// batch in memory at once and can skip batches without decompressing them;
+// otherwise it walks the batches already decoded in the block's RecordsSet.
+//
+//	batches := block.RecordsIterator()
+//	for batches.Next() {
+//		records := batches.Records()
+//		...
+//	}
+//	if err := batches.Err(); err != nil {
+//		...
+//	}
+type RecordsIterator struct {
+	block   *FetchResponseBlock
+	rd      *realDecoder
+	records *Records
+	yielded int
+	done    bool
+	err     error
+}
+
+// RecordsIterator returns an iterator over the record batches of the block.
+func (b *FetchResponseBlock) RecordsIterator() *RecordsIterator {
+	if b.rawRecords != nil {
+		return b.iterateRecords(b.rawRecords)
+	}
+	return &RecordsIterator{block: b}
+}
+
+// iterateRecords returns an iterator decoding the record batches of raw.
+func (b *FetchResponseBlock) iterateRecords(raw []byte) *RecordsIterator {
+	return &RecordsIterator{block: b, rd: &realDecoder{
+		raw:         raw,
+		skipCRC:     b.decoding.crcValidation == CRCSkipValidation,
+		lazyHeaders: b.decoding.lazyHeaders,
+		zstd:        b.decoding.zstd,
+		limits:      b.decoding.limits,
+		buffers:     b.decoding.buffers,
+	}}
+}
+
+// Next decodes the next record batch holding records, or the first batch if it
+// was truncated, and reports whether there was one. Empty batches are skipped.
+func (it *RecordsIterator) Next() bool {
+	it.records = nil
+	if it.done {
+		return false
+	}
+
+	if it.rd == nil {
+		if it.yielded >= len(it.block.RecordsSet) {
+			it.done = true
+			return false
+		}
+		it.records = it.block.RecordsSet[it.yielded]
+		it.yielded++
+		return true
+	}
+
+	for it.rd.remaining() > 0 {
+		records, yield, stop, err := it.decodeBatch()
+		var mismatch crcMismatchError
+		if errors.As(err, &mismatch) {
+			if it.block.decoding.crcErrors != nil {
+				it.block.decoding.crcErrors.Add(1)
+			}
+			if it.block.decoding.crcValidation == CRCValidateAndSkip {
+				warnf("consumer/fetch dropped a corrupt record batch: %v\n", err)
+				continue
+			}
+		}
+		if err != nil {
+			it.err = err
+			it.done = true
+			return false
+		}
+		if stop {
+			it.done = true
+		}
+		if yield {
+			it.records = records
+			it.yielded++
+			return true
+		}
+		if stop {
+			return false
+		}
+	}
+
+	it.done = true
+	return false
+}
+
+// decodeBatch decodes the batch at the position of the iterator, reporting
+// whether it should be yielded and whether it is the last one to decode.
+func (it *RecordsIterator) decodeBatch() (records *Records, yield, stop bool, err error) {
+	records = &Records{}
+	defer recoverDecodingPanic(records, &err)
+
+	if err = records.decode(it.rd); err != nil {
+		// If we have at least one decoded records, this is not an error
+		if errors.Is(err, ErrInsufficientData) {
+			if it.yielded == 0 {
+				it.block.Partial = true
+			}
+			return nil, false, true, nil
+		}
+		return nil, false, true, err
+	}
+
+	if it.block.LastRecordsBatchOffset, err = records.recordsOffset(); err != nil {
+		return nil, false, true, err
+	}
+
+	partial, err := records.isPartial()
+	if err != nil {
+		return nil, false, true, err
+	}
+
+	n, err := records.numRecords()
+	if err != nil {
+		return nil, false, true, err
+	}
+
+	overflow, err := records.isOverflow()
+	if err != nil {
+		return nil, false, true, err
+	}
+
+	return records, n > 0 || (partial && it.yielded == 0), partial || overflow, nil
+}
+
+// SkipTo skips the upcoming record batches whose records all precede offset.
+// Over a lazily decoded block, the skipped batches are neither decoded nor
+// decompressed.
+func (it *RecordsIterator) SkipTo(offset int64) {
+	if it.done {
+		return
+	}
+
+	if it.rd == nil {
+		for it.yielded < len(it.block.RecordsSet) {
+			batch := it.block.RecordsSet[it.yielded].RecordBatch
+			if batch == nil || batch.LastOffset() >= offset {
+				return
+			}
+			it.yielded++
+		}
+		return
+	}
+
+	for {
+		header, ok := peekRecordBatchHeader(it.rd.raw[it.rd.off:])
+		if !ok || header.firstOffset+int64(header.lastOffsetDelta) >= offset {
+			return
+		}
+		it.rd.off += header.size
+	}
+}
+
+// Records returns the record batch decoded by the last call to Next.
+func (it *RecordsIterator) Records() *Records {
+	return it.records
+}
+
+// Err returns the error that stopped the iteration, if any.
+func (it *RecordsIterator) Err() error {
+	return it.err
+}
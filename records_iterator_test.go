@@ -0,0 +1,151 @@
+package sarama
+
+import (
+	"bytes"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func lazyFetchResponseBlocks(t *testing.T, version int16) (eager, lazy *FetchResponseBlock, packet []byte) {
+	t.Helper()
+
+	response := &FetchResponse{Version: version}
+	for offset := int64(10); offset < 13; offset++ {
+		response.AddRecordBatch("topic", 5, nil, StringEncoder("value"), offset, 0, false)
+	}
+	packet, err := encode(response, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	eagerResponse := &FetchResponse{}
+	testVersionDecodable(t, "eager", eagerResponse, packet, version)
+	lazyResponse := &FetchResponse{decoding: recordsDecoding{lazy: true}}
+	testVersionDecodable(t, "lazy", lazyResponse, packet, version)
+
+	return eagerResponse.GetBlock("topic", 5), lazyResponse.GetBlock("topic", 5), packet
+}
+
+func iteratedOffsets(t *testing.T, batches *RecordsIterator) []int64 {
+	t.Helper()
+
+	var offsets []int64
+	for batches.Next() {
+		offsets = append(offsets, batches.Records().RecordBatch.FirstOffset)
+	}
+	if err := batches.Err(); err != nil {
+		t.Fatal(err)
+	}
+	return offsets
+}
+
+func TestRecordsIteratorLazyFetchResponse(t *testing.T) {
+	for _, version := range []int16{4, 12} {
+		eager, lazy, packet := lazyFetchResponseBlocks(t, version)
+
+		if len(lazy.RecordsSet) != 0 {
+			t.Errorf("version %d: lazy decoding decoded %d record batches", version, len(lazy.RecordsSet))
+		}
+		n, err := lazy.numRecords()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if n != 3 {
+			t.Errorf("version %d: lazy block counted %d records, want 3", version, n)
+		}
+
+		want := []int64{10, 11, 12}
+		if got := iteratedOffsets(t, eager.RecordsIterator()); !reflect.DeepEqual(got, want) {
+			t.Errorf("version %d: eager iteration yielded batches %v, want %v", version, got, want)
+		}
+		if got := iteratedOffsets(t, lazy.RecordsIterator()); !reflect.DeepEqual(got, want) {
+			t.Errorf("version %d: lazy iteration yielded batches %v, want %v", version, got, want)
+		}
+		if lazy.LastRecordsBatchOffset == nil || *lazy.LastRecordsBatchOffset != 12 {
+			t.Errorf("version %d: lazy iteration didn't record the last batch offset", version)
+		}
+
+		response := &FetchResponse{Version: version}
+		response.Blocks = map[string]map[int32]*FetchResponseBlock{"topic": {5: lazy}}
+		reencoded, err := encode(response, nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(reencoded, packet) {
+			t.Errorf("version %d: lazy block encoded\n%v\nwant\n%v", version, reencoded, packet)
+		}
+	}
+}
+
+func TestRecordsIteratorSkipTo(t *testing.T) {
+	eager, lazy, _ := lazyFetchResponseBlocks(t, 4)
+
+	for name, block := range map[string]*FetchResponseBlock{"eager": eager, "lazy": lazy} {
+		batches := block.RecordsIterator()
+		batches.SkipTo(12)
+		if got, want := iteratedOffsets(t, batches), []int64{12}; !reflect.DeepEqual(got, want) {
+			t.Errorf("%s iteration yielded batches %v after skipping, want %v", name, got, want)
+		}
+	}
+}
+
+func TestRecordsIteratorPartialFetchResponse(t *testing.T) {
+	response := &FetchResponse{decoding: recordsDecoding{lazy: true}}
+	testVersionDecodable(t, "partial record", response, partialFetchResponse, 4)
+	block := response.GetBlock("topic", 5)
+
+	batches := block.RecordsIterator()
+	partial := false
+	for batches.Next() {
+		p, err := batches.Records().isPartial()
+		if err != nil {
+			t.Fatal(err)
+		}
+		partial = partial || p
+	}
+	if err := batches.Err(); err != nil {
+		t.Fatal(err)
+	}
+	if !partial && !block.Partial {
+		t.Error("Lazy iteration didn't detect the partial trailing record")
+	}
+}
+
+func TestRecordsIteratorCRCValidation(t *testing.T) {
+	_, lazy, packet := lazyFetchResponseBlocks(t, 4)
+	// corrupt the value of the record of the second batch
+	first, _ := peekRecordBatchHeader(lazy.rawRecords)
+	second := lazy.rawRecords[first.size:]
+	second[bytes.Index(second, []byte("value"))+4] = 'f'
+
+	for _, tt := range []struct {
+		name       string
+		validation CRCValidation
+		offsets    []int64
+		crcErrors  int64
+	}{
+		{"validate and fail", CRCValidateAndFail, nil, 1},
+		{"validate and skip", CRCValidateAndSkip, []int64{10, 12}, 1},
+		{"skip validation", CRCSkipValidation, []int64{10, 11, 12}, 0},
+	} {
+		recorder := newTestMetricsRecorder()
+		response := &FetchResponse{decoding: recordsDecoding{
+			crcValidation: tt.validation,
+			crcErrors:     recorder.Counter("consumer-crc-errors", nil),
+		}}
+		err := versionedDecode(packet, response, 4)
+		if tt.offsets == nil {
+			if !errors.As(err, new(PacketDecodingError)) {
+				t.Errorf("%s: expected a PacketDecodingError, got %v", tt.name, err)
+			}
+		} else if err != nil {
+			t.Errorf("%s: unexpected error %v", tt.name, err)
+		} else if got := iteratedOffsets(t, response.GetBlock("topic", 5).RecordsIterator()); !reflect.DeepEqual(got, tt.offsets) {
+			t.Errorf("%s: decoded batches %v, want %v", tt.name, got, tt.offsets)
+		}
+		if got := recorder.value("consumer-crc-errors", nil); got != tt.crcErrors {
+			t.Errorf("%s: counted %d CRC errors, want %d", tt.name, got, tt.crcErrors)
+		}
+	}
+}
@@ -74,8 +74,8 @@ func (r *request) decode(pd packetDecoder) (err error) {
 	}
 
 	if r.body.headerVersion() >= 2 {
-		// tagged field
-		_, err = pd.getUVarint()
+		// tagged fields, skipped as none is supported
+		_, err = pd.getEmptyTaggedFieldArray()
 		if err != nil {
 			return err
 		}
@@ -116,74 +116,135 @@ func decodeRequest(r io.Reader) (*request, int, error) {
 	return req, bytesRead, nil
 }
 
+// apiNames are the names of the APIs by key, as in the Kafka protocol
+// documentation.
+var apiNames = map[int16]string{
+	0:  "Produce",
+	1:  "Fetch",
+	2:  "ListOffsets",
+	3:  "Metadata",
+	8:  "OffsetCommit",
+	9:  "OffsetFetch",
+	10: "FindCoordinator",
+	11: "JoinGroup",
+	12: "Heartbeat",
+	13: "LeaveGroup",
+	14: "SyncGroup",
+	15: "DescribeGroups",
+	16: "ListGroups",
+	17: "SaslHandshake",
+	18: "ApiVersions",
+	19: "CreateTopics",
+	20: "DeleteTopics",
+	21: "DeleteRecords",
+	22: "InitProducerId",
+	24: "AddPartitionsToTxn",
+	25: "AddOffsetsToTxn",
+	26: "EndTxn",
+	28: "TxnOffsetCommit",
+	29: "DescribeAcls",
+	30: "CreateAcls",
+	31: "DeleteAcls",
+	32: "DescribeConfigs",
+	33: "AlterConfigs",
+	35: "DescribeLogDirs",
+	36: "SaslAuthenticate",
+	37: "CreatePartitions",
+	42: "DeleteGroups",
+	43: "ElectLeaders",
+	44: "IncrementalAlterConfigs",
+	45: "AlterPartitionReassignments",
+	46: "ListPartitionReassignments",
+	47: "OffsetDelete",
+	48: "DescribeClientQuotas",
+	49: "AlterClientQuotas",
+	50: "DescribeUserScramCredentials",
+	51: "AlterUserScramCredentials",
+	60: "DescribeCluster",
+	61: "DescribeProducers",
+	71: "GetTelemetrySubscriptions",
+	72: "PushTelemetry",
+	75: "DescribeTopicPartitions",
+}
+
+// apiName returns the name of the API key.
+func apiName(key int16) string {
+	if name, ok := apiNames[key]; ok {
+		return name
+	}
+	return fmt.Sprintf("ApiKey(%d)", key)
+}
+
 func allocateBody(key, version int16) protocolBody {
 	switch key {
 	case 0:
-		return &ProduceRequest{}
+		return &ProduceRequest{Version: version}
 	case 1:
 		return &FetchRequest{Version: version}
 	case 2:
 		return &OffsetRequest{Version: version}
 	case 3:
-		return &MetadataRequest{}
+		return &MetadataRequest{Version: version}
 	case 8:
 		return &OffsetCommitRequest{Version: version}
 	case 9:
 		return &OffsetFetchRequest{Version: version}
 	case 10:
-		return &FindCoordinatorRequest{}
+		return &FindCoordinatorRequest{Version: version}
 	case 11:
-		return &JoinGroupRequest{}
+		return &JoinGroupRequest{Version: version}
 	case 12:
-		return &HeartbeatRequest{}
+		return &HeartbeatRequest{Version: version}
 	case 13:
-		return &LeaveGroupRequest{}
+		return &LeaveGroupRequest{Version: version}
 	case 14:
-		return &SyncGroupRequest{}
+		return &SyncGroupRequest{Version: version}
 	case 15:
-		return &DescribeGroupsRequest{}
+		return &DescribeGroupsRequest{Version: version}
 	case 16:
-		return &ListGroupsRequest{}
+		return &ListGroupsRequest{Version: version}
 	case 17:
 		return &SaslHandshakeRequest{}
 	case 18:
 		return &ApiVersionsRequest{Version: version}
 	case 19:
-		return &CreateTopicsRequest{}
+		return &CreateTopicsRequest{Version: version}
 	case 20:
-		return &DeleteTopicsRequest{}
+		return &DeleteTopicsRequest{Version: version}
 	case 21:
-		return &DeleteRecordsRequest{}
+		return &DeleteRecordsRequest{Version: version}
 	case 22:
-		return &InitProducerIDRequest{}
+		return &InitProducerIDRequest{Version: version}
 	case 24:
-		return &AddPartitionsToTxnRequest{}
+		return &AddPartitionsToTxnRequest{Version: version}
 	case 25:
-		return &AddOffsetsToTxnRequest{}
+		return &AddOffsetsToTxnRequest{Version: version}
 	case 26:
-		return &EndTxnRequest{}
+		return &EndTxnRequest{Version: version}
 	case 28:
-		return &TxnOffsetCommitRequest{}
+		return &TxnOffsetCommitRequest{Version: version}
 	case 29:
-		return &DescribeAclsRequest{}
+		return &DescribeAclsRequest{Version: int(version)}
 	case 30:
-		return &CreateAclsRequest{}
+		return &CreateAclsRequest{Version: version}
 	case 31:
-		return &DeleteAclsRequest{}
+		return &DeleteAclsRequest{Version: int(version)}
 	case 32:
-		return &DescribeConfigsRequest{}
+		return &DescribeConfigsRequest{Version: version}
 	case 33:
-		return &AlterConfigsRequest{}
+		return &AlterConfigsRequest{Version: version}
 	case 35:
-		return &DescribeLogDirsRequest{}
+		return &DescribeLogDirsRequest{Version: version}
 	case 36:
-		return &SaslAuthenticateRequest{}
+		return &SaslAuthenticateRequest{Version: version}
 	case 37:
-		return &CreatePartitionsRequest{}
+		return &CreatePartitionsRequest{Version: version}
 	case 42:
-		return &DeleteGroupsRequest{}
+		return &DeleteGroupsRequest{Version: version}
+	case 43:
+		return &ElectLeadersRequest{Version: version}
 	case 44:
-		return &IncrementalAlterConfigsRequest{}
+		return &IncrementalAlterConfigsRequest{Version: version}
 	case 45:
 		return &AlterPartitionReassignmentsRequest{}
 	case 46:
@@ -191,13 +252,23 @@ func allocateBody(key, version int16) protocolBody {
 	case 47:
 		return &DeleteOffsetsRequest{}
 	case 48:
-		return &DescribeClientQuotasRequest{}
+		return &DescribeClientQuotasRequest{Version: version}
 	case 49:
-		return &AlterClientQuotasRequest{}
+		return &AlterClientQuotasRequest{Version: version}
 	case 50:
 		return &DescribeUserScramCredentialsRequest{}
 	case 51:
 		return &AlterUserScramCredentialsRequest{}
+	case 60:
+		return &DescribeClusterRequest{Version: version}
+	case 61:
+		return &DescribeProducersRequest{Version: version}
+	case 71:
+		return &GetTelemetrySubscriptionsRequest{Version: version}
+	case 72:
+		return &PushTelemetryRequest{Version: version}
+	case 75:
+		return &DescribeTopicPartitionsRequest{Version: version}
 	}
 	return nil
 }
@@ -2,6 +2,7 @@ package sarama
 
 import (
 	"bytes"
+	"encoding/binary"
 	"reflect"
 	"testing"
 
@@ -107,3 +108,18 @@ func testResponse(t *testing.T, name string, res protocolBody, expected []byte)
 }
 
 func nullString(s string) *string { return &s }
+
+func TestRequestHeaderTaggedFields(t *testing.T) {
+	rb := &MetadataRequest{Version: 9, Topics: []string{"my_topic"}}
+	packet := testRequestEncode(t, "metadata v9", rb, nil)
+
+	// a tagged field of 2 bytes in place of the empty tagged fields following
+	// the client ID
+	headerSize := 14 + len("foo")
+	tagged := append([]byte{}, packet[:headerSize]...)
+	tagged = append(tagged, 1, 0, 2, 0xca, 0xfe)
+	tagged = append(tagged, packet[headerSize+1:]...)
+	binary.BigEndian.PutUint32(tagged, uint32(len(tagged)-4))
+
+	testRequestDecode(t, "metadata v9 with tagged fields", rb, tagged)
+}
@@ -0,0 +1,106 @@
+package sarama
+
+import (
+	"sync"
+	"time"
+)
+
+// retryBudgetWindow is the number of seconds a RetryBudget remembers the
+// requests and retries over.
+const retryBudgetWindow = 10
+
+// RetryBudget caps the volume of retries of the clients sharing it, on top of
+// the Retry.Max of each request, so that the retries cannot swamp a cluster
+// which is already struggling: over the last 10 seconds, the retries may not
+// exceed ratio of the requests sent, plus minPerSecond retries every second.
+// Set Config.RetryBudget to apply it to the metadata, coordinator, producer,
+// consumer and admin retries. Its methods are safe for concurrent use, and do
+// nothing on a nil RetryBudget, which allows every retry.
+type RetryBudget struct {
+	ratio        float64
+	minPerSecond int
+
+	lock  sync.Mutex
+	slots [retryBudgetWindow]retryBudgetSlot
+	now   func() time.Time
+}
+
+// retryBudgetSlot counts the requests and retries of a second.
+type retryBudgetSlot struct {
+	second            int64
+	requests, retries int
+}
+
+// NewRetryBudget returns a RetryBudget allowing, over the last 10 seconds,
+// ratio retries per request sent, e.g. 0.1 for a retry every 10 requests, plus
+// minPerSecond retries every second, so that clients sending few requests can
+// still retry.
+func NewRetryBudget(ratio float64, minPerSecond int) *RetryBudget {
+	return &RetryBudget{
+		ratio:        ratio,
+		minPerSecond: minPerSecond,
+		now:          time.Now,
+	}
+}
+
+// RecordRequest records a request sent to a broker, which earns the budget
+// its ratio of a retry. The brokers of the clients whose Config.RetryBudget is
+// set record every request they send.
+func (b *RetryBudget) RecordRequest() {
+	if b == nil {
+		return
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	b.slot(b.now()).requests++
+}
+
+// AllowRetry returns whether the budget allows another retry, in which case
+// the retry is recorded.
+func (b *RetryBudget) AllowRetry() bool {
+	if b == nil {
+		return true
+	}
+
+	b.lock.Lock()
+	defer b.lock.Unlock()
+
+	now := b.now()
+	requests, retries := 0, 0
+	for _, slot := range b.slots {
+		if slot.second > now.Unix()-retryBudgetWindow {
+			requests += slot.requests
+			retries += slot.retries
+		}
+	}
+
+	allowed := float64(b.minPerSecond*retryBudgetWindow) + b.ratio*float64(requests)
+	if float64(retries+1) > allowed {
+		return false
+	}
+	b.slot(now).retries++
+	return true
+}
+
+// slot returns the slot of the second of now, reset if it was last used for
+// an earlier second. You must hold the lock.
+func (b *RetryBudget) slot(now time.Time) *retryBudgetSlot {
+	second := now.Unix()
+	slot := &b.slots[second%retryBudgetWindow]
+	if slot.second != second {
+		*slot = retryBudgetSlot{second: second}
+	}
+	return slot
+}
+
+// allowRetry returns whether the RetryBudget allows the retry described by
+// what, logging it otherwise.
+func (c *Config) allowRetry(what string) bool {
+	if c.RetryBudget.AllowRetry() {
+		return true
+	}
+	infof("%s not retrying as the retry budget is exhausted\n", what)
+	return false
+}
@@ -0,0 +1,102 @@
+package sarama
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryBudget(t *testing.T) {
+	now := time.Unix(1000, 0)
+	budget := NewRetryBudget(0.1, 1)
+	budget.now = func() time.Time { return now }
+
+	// the floor of a retry per second over the window
+	for i := 0; i < retryBudgetWindow; i++ {
+		if !budget.AllowRetry() {
+			t.Fatalf("expected retry %d to be allowed", i)
+		}
+	}
+	if budget.AllowRetry() {
+		t.Fatal("expected the retries to be capped")
+	}
+
+	// a retry every 10 requests
+	for i := 0; i < 20; i++ {
+		budget.RecordRequest()
+	}
+	if !budget.AllowRetry() || !budget.AllowRetry() || budget.AllowRetry() {
+		t.Error("expected 2 more retries for 20 requests")
+	}
+
+	// the window moves on
+	now = now.Add(retryBudgetWindow * time.Second)
+	if !budget.AllowRetry() {
+		t.Error("expected the retries to be allowed again")
+	}
+
+	var unlimited *RetryBudget
+	unlimited.RecordRequest()
+	if !unlimited.AllowRetry() {
+		t.Error("expected a nil budget to allow every retry")
+	}
+}
+
+func TestClientMetadataRetryBudget(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.Returns(new(MetadataResponse))
+
+	conf := NewTestConfig()
+	conf.Metadata.Retry.Max = 3
+	conf.Metadata.Retry.Backoff = 0
+	conf.RetryBudget = NewRetryBudget(0, 0)
+	client, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer safeClose(t, client)
+
+	leaderless := new(MetadataResponse)
+	leaderless.AddBroker(seedBroker.Addr(), seedBroker.BrokerID())
+	leaderless.AddTopicPartition("my_topic", 0, -1, nil, nil, nil, ErrLeaderNotAvailable)
+	seedBroker.Returns(leaderless)
+
+	_ = client.RefreshMetadata("my_topic")
+	if n := len(seedBroker.History()); n != 2 {
+		t.Errorf("expected the metadata not to be retried, got %d requests", n)
+	}
+}
+
+func TestAsyncProducerIdempotentRetryBudget(t *testing.T) {
+	broker := NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("my_topic", 0, broker.BrokerID()),
+		"InitProducerIDRequest": NewMockWrapper(&InitProducerIDResponse{ProducerID: 1000, ProducerEpoch: 1}),
+		"ProduceRequest":        NewMockProduceResponse(t).SetError("my_topic", 0, ErrNotEnoughReplicas),
+	})
+
+	config := NewTestConfig()
+	config.Producer.Flush.Messages = 10
+	config.Producer.Return.Successes = true
+	config.Producer.Retry.Max = 4
+	config.Producer.Retry.Backoff = 0
+	config.Producer.RequiredAcks = WaitForAll
+	config.Producer.Idempotent = true
+	config.Net.MaxOpenRequests = 1
+	config.Version = V0_11_0_0
+	config.RetryBudget = NewRetryBudget(0, 0)
+	producer, err := NewAsyncProducer([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the budget refusing to retry the batch fails every message of it
+	for i := 0; i < 10; i++ {
+		producer.Input() <- &ProducerMessage{Topic: "my_topic", Value: StringEncoder(TestMessage)}
+	}
+	expectResults(t, producer, 0, 10)
+	closeProducer(t, producer)
+}
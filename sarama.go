@@ -19,31 +19,55 @@ exactly with the protocol fields documented by Kafka at
 https://cwiki.apache.org/confluence/display/KAFKA/A+Guide+To+The+Kafka+Protocol
 
 Metrics are exposed through https://github.com/rcrowley/go-metrics library in a local registry.
+They can be recorded in another metrics library instead by setting Config.MetricsRecorder, in which case the
+broker, topic or group a metric is about is given as a label rather than being part of its name, and the
+meters are counters.
 
 Broker related metrics:
 
-	+----------------------------------------------+------------+---------------------------------------------------------------+
-	| Name                                         | Type       | Description                                                   |
-	+----------------------------------------------+------------+---------------------------------------------------------------+
-	| incoming-byte-rate                           | meter      | Bytes/second read off all brokers                             |
-	| incoming-byte-rate-for-broker-<broker-id>    | meter      | Bytes/second read off a given broker                          |
-	| outgoing-byte-rate                           | meter      | Bytes/second written off all brokers                          |
-	| outgoing-byte-rate-for-broker-<broker-id>    | meter      | Bytes/second written off a given broker                       |
-	| request-rate                                 | meter      | Requests/second sent to all brokers                           |
-	| request-rate-for-broker-<broker-id>          | meter      | Requests/second sent to a given broker                        |
-	| request-size                                 | histogram  | Distribution of the request size in bytes for all brokers     |
-	| request-size-for-broker-<broker-id>          | histogram  | Distribution of the request size in bytes for a given broker  |
-	| request-latency-in-ms                        | histogram  | Distribution of the request latency in ms for all brokers     |
-	| request-latency-in-ms-for-broker-<broker-id> | histogram  | Distribution of the request latency in ms for a given broker  |
-	| response-rate                                | meter      | Responses/second received from all brokers                    |
-	| response-rate-for-broker-<broker-id>         | meter      | Responses/second received from a given broker                 |
-	| response-size                                | histogram  | Distribution of the response size in bytes for all brokers    |
-	| response-size-for-broker-<broker-id>         | histogram  | Distribution of the response size in bytes for a given broker |
-	| requests-in-flight                           | counter    | The current number of in-flight requests awaiting a response  |
-	|                                              |            | for all brokers                                               |
-	| requests-in-flight-for-broker-<broker-id>    | counter    | The current number of in-flight requests awaiting a response  |
-	|                                              |            | for a given broker                                            |
-	+----------------------------------------------+------------+---------------------------------------------------------------+
+	+------------------------------------------------+------------+---------------------------------------------------------------+
+	| Name                                           | Type       | Description                                                   |
+	+------------------------------------------------+------------+---------------------------------------------------------------+
+	| incoming-byte-rate                             | meter      | Bytes/second read off all brokers                             |
+	| incoming-byte-rate-for-broker-<broker-id>      | meter      | Bytes/second read off a given broker                          |
+	| outgoing-byte-rate                             | meter      | Bytes/second written off all brokers                          |
+	| outgoing-byte-rate-for-broker-<broker-id>      | meter      | Bytes/second written off a given broker                       |
+	| request-rate                                   | meter      | Requests/second sent to all brokers                           |
+	| request-rate-for-broker-<broker-id>            | meter      | Requests/second sent to a given broker                        |
+	| request-size                                   | histogram  | Distribution of the request size in bytes for all brokers     |
+	| request-size-for-broker-<broker-id>            | histogram  | Distribution of the request size in bytes for a given broker  |
+	| request-latency-in-ms                          | histogram  | Distribution of the request latency in ms for all brokers     |
+	| request-latency-in-ms-for-broker-<broker-id>   | histogram  | Distribution of the request latency in ms for a given broker  |
+	| response-rate                                  | meter      | Responses/second received from all brokers                    |
+	| response-rate-for-broker-<broker-id>           | meter      | Responses/second received from a given broker                 |
+	| response-size                                  | histogram  | Distribution of the response size in bytes for all brokers    |
+	| response-size-for-broker-<broker-id>           | histogram  | Distribution of the response size in bytes for a given broker |
+	| requests-in-flight                             | counter    | The current number of in-flight requests awaiting a response  |
+	|                                                |            | for all brokers                                               |
+	| requests-in-flight-for-broker-<broker-id>      | counter    | The current number of in-flight requests awaiting a response  |
+	|                                                |            | for a given broker                                            |
+	| throttle-time-in-ms-for-broker-<broker-id>     | histogram  | Distribution of the time in ms a given broker throttled the   |
+	|                                                |            | requests for because of quotas                                |
+	| throttle-wait-in-ms-for-broker-<broker-id>     | histogram  | Distribution of the time in ms requests to a given broker     |
+	|                                                |            | were held back for while it throttled the client              |
+	| api-latency-in-ms-<api>-for-broker-<broker-id> | histogram  | Distribution of the latency in ms of the requests of an API,  |
+	|                                                |            | e.g. Produce or OffsetCommit, for a given broker              |
+	| api-error-rate-<api>-for-broker-<broker-id>    | meter      | Requests/second of an API to a given broker which failed or   |
+	|                                                |            | were answered with an error code                              |
+	+------------------------------------------------+------------+---------------------------------------------------------------+
+
+The time in ms the requests of an API, e.g. Produce or Fetch, were throttled for
+by a given broker is also recorded as the
+api-throttle-time-in-ms-<api>-for-broker-<broker-id> histograms.
+
+The health of the connections is recorded too, for all brokers and for a given
+broker with the "-for-broker-<broker-id>" suffix: the reconnect-rate meters of
+the connections re-established, the tls-handshake-time-in-ms histograms of the
+time in ms the TLS handshakes took and the failed-authentication-rate meters of
+the SASL authentications which failed. Unlike the other metrics of a broker,
+they are kept once it is closed. The connection-age-in-ms-for-broker-<broker-id>
+counters are the age in ms of the connection to a given broker as of its last
+request.
 
 Note that we do not gather specific metrics for seed brokers but they are part of the "all brokers" metrics.
 
@@ -60,20 +84,50 @@ Producer related metrics:
 	| records-per-request-for-topic-<topic>     | histogram  | Distribution of the number of records sent per request for a given topic             |
 	| compression-ratio                         | histogram  | Distribution of the compression ratio times 100 of record batches for all topics     |
 	| compression-ratio-for-topic-<topic>       | histogram  | Distribution of the compression ratio times 100 of record batches for a given topic  |
+	| records-per-batch                         | histogram  | Distribution of the number of records sent per partition per request for all topics  |
+	| records-per-batch-for-topic-<topic>       | histogram  | Distribution of the number of records sent per partition per request for a topic     |
+	| batch-fill-percentage                     | histogram  | Distribution of the size of the batches in percent of MaxMessageBytes for all topics |
+	| batch-fill-percentage-for-topic-<topic>   | histogram  | Distribution of the size of the batches in percent of MaxMessageBytes for a topic    |
+	| compression-ratio-<codec>                 | histogram  | Distribution of the compression ratio times 100 of record batches for a codec        |
 	+-------------------------------------------+------------+--------------------------------------------------------------------------------------+
 
+The compression ratios are also recorded by codec for a given topic, as the
+compression-ratio-<codec>-for-topic-<topic> histograms. The batch fill
+percentages tell how close the batches get to Producer.MaxMessageBytes, and
+along with the records per batch, whether the Producer.Flush settings let the
+batches fill up.
+
 Consumer related metrics:
 
 	+-------------------------------------------+------------+--------------------------------------------------------------------------------------+
 	| Name                                      | Type       | Description                                                                          |
 	+-------------------------------------------+------------+--------------------------------------------------------------------------------------+
 	| consumer-batch-size                       | histogram  | Distribution of the number of messages in a batch                                    |
+	| consumer-crc-errors                       | counter    | Total count of record batches fetched whose CRC didn't match                         |
 	| consumer-group-join-total-<GroupID>       | counter    | Total count of consumer group join attempts                                          |
 	| consumer-group-join-failed-<GroupID>      | counter    | Total count of consumer group join failures                                          |
 	| consumer-group-sync-total-<GroupID>       | counter    | Total count of consumer group sync attempts                                          |
 	| consumer-group-sync-failed-<GroupID>      | counter    | Total count of consumer group sync failures                                          |
 	+-------------------------------------------+------------+--------------------------------------------------------------------------------------+
 
+The consumers also record, for all brokers, for a given broker with the
+"-for-broker-<broker-id>" suffix and for a given topic with the
+"-for-topic-<topic>" suffix, the fetch-latency-in-ms, records-per-fetch and
+bytes-per-fetch histograms of the latency in ms of the FetchRequests and of the
+records and bytes they returned, and the fetch-rate and empty-fetch-rate meters
+of the FetchRequests sent and of those which returned no records. The ratio of
+the empty fetches and the records per fetch tell whether Consumer.Fetch.Min and
+Consumer.MaxWaitTime let the fetches fill up.
+
+The consumers intercepting the messages with an EndToEndLatency record the
+end-to-end-latency-in-ms histograms, for all topics and for a given topic with
+the "-for-topic-<topic>" suffix, of the time in ms from the messages being sent
+by the producers to their being consumed.
+
+The LagMonitor also records the lag of the consumer groups it checks, as the
+consumer-group-lag-<GroupID>-for-topic-<Topic>-for-partition-<Partition> gauges,
+and their sums by group, as the consumer-group-lag-sum-<GroupID> gauges.
+
 */
 package sarama
 
@@ -85,7 +139,8 @@ import (
 var (
 	// Logger is the instance of a StdLogger interface that Sarama writes connection
 	// management events to. By default it is set to discard all log messages via ioutil.Discard,
-	// but you can set it to redirect wherever you want.
+	// but you can set it to redirect wherever you want. Set StructuredLog instead to receive the
+	// levels and structured fields of the log records.
 	Logger StdLogger = log.New(io.Discard, "[Sarama] ", log.LstdFlags)
 
 	// PanicHandler is called for recovering from panics spawned internally to the library (and thus
@@ -1,6 +1,8 @@
 package sarama
 
 type SaslAuthenticateRequest struct {
+	// Version defines the protocol version to use for encode and decode
+	Version       int16
 	SaslAuthBytes []byte
 }
 
@@ -8,10 +10,25 @@ type SaslAuthenticateRequest struct {
 const APIKeySASLAuth = 36
 
 func (r *SaslAuthenticateRequest) encode(pe packetEncoder) error {
+	if r.Version >= 2 {
+		if err := pe.putCompactBytes(r.SaslAuthBytes); err != nil {
+			return err
+		}
+		pe.putEmptyTaggedFieldArray()
+		return nil
+	}
 	return pe.putBytes(r.SaslAuthBytes)
 }
 
 func (r *SaslAuthenticateRequest) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+	if version >= 2 {
+		if r.SaslAuthBytes, err = pd.getCompactBytes(); err != nil {
+			return err
+		}
+		_, err = pd.getEmptyTaggedFieldArray()
+		return err
+	}
 	r.SaslAuthBytes, err = pd.getBytes()
 	return err
 }
@@ -21,13 +38,23 @@ func (r *SaslAuthenticateRequest) key() int16 {
 }
 
 func (r *SaslAuthenticateRequest) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *SaslAuthenticateRequest) headerVersion() int16 {
+	if r.Version >= 2 {
+		return 2
+	}
 	return 1
 }
 
 func (r *SaslAuthenticateRequest) requiredVersion() KafkaVersion {
-	return V1_0_0_0
+	switch r.Version {
+	case 2:
+		return V2_4_0_0
+	case 1:
+		return V2_2_0_0
+	default:
+		return V1_0_0_0
+	}
 }
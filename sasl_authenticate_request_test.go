@@ -6,8 +6,19 @@ var saslAuthenticateRequest = []byte{
 	0, 0, 0, 3, 'f', 'o', 'o',
 }
 
+var saslAuthenticateRequestV2 = []byte{
+	4, 'f', 'o', 'o',
+	0, // empty tagged fields
+}
+
 func TestSaslAuthenticateRequest(t *testing.T) {
 	request := new(SaslAuthenticateRequest)
 	request.SaslAuthBytes = []byte(`foo`)
 	testRequest(t, "basic", request, saslAuthenticateRequest)
+
+	request = &SaslAuthenticateRequest{Version: 1, SaslAuthBytes: []byte(`foo`)}
+	testRequest(t, "v1", request, saslAuthenticateRequest)
+
+	request = &SaslAuthenticateRequest{Version: 2, SaslAuthBytes: []byte(`foo`)}
+	testRequest(t, "v2", request, saslAuthenticateRequestV2)
 }
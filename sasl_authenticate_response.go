@@ -1,20 +1,45 @@
 package sarama
 
 type SaslAuthenticateResponse struct {
+	// Version defines the protocol version to use for encode and decode
+	Version       int16
 	Err           KError
 	ErrorMessage  *string
 	SaslAuthBytes []byte
+	// SessionLifetimeMs is the time after which the broker expires the
+	// session, zero when it never does (v1+, KIP-368).
+	SessionLifetimeMs int64
 }
 
-func (r *SaslAuthenticateResponse) encode(pe packetEncoder) error {
+func (r *SaslAuthenticateResponse) encode(pe packetEncoder) (err error) {
 	pe.putInt16(int16(r.Err))
-	if err := pe.putNullableString(r.ErrorMessage); err != nil {
+	if r.Version >= 2 {
+		err = pe.putNullableCompactString(r.ErrorMessage)
+	} else {
+		err = pe.putNullableString(r.ErrorMessage)
+	}
+	if err != nil {
+		return err
+	}
+	if r.Version >= 2 {
+		err = pe.putCompactBytes(r.SaslAuthBytes)
+	} else {
+		err = pe.putBytes(r.SaslAuthBytes)
+	}
+	if err != nil {
 		return err
 	}
-	return pe.putBytes(r.SaslAuthBytes)
+	if r.Version > 0 {
+		pe.putInt64(r.SessionLifetimeMs)
+	}
+	if r.Version >= 2 {
+		pe.putEmptyTaggedFieldArray()
+	}
+	return nil
 }
 
 func (r *SaslAuthenticateResponse) decode(pd packetDecoder, version int16) error {
+	r.Version = version
 	kerr, err := pd.getInt16()
 	if err != nil {
 		return err
@@ -22,11 +47,33 @@ func (r *SaslAuthenticateResponse) decode(pd packetDecoder, version int16) error
 
 	r.Err = KError(kerr)
 
-	if r.ErrorMessage, err = pd.getNullableString(); err != nil {
+	if version >= 2 {
+		r.ErrorMessage, err = pd.getCompactNullableString()
+	} else {
+		r.ErrorMessage, err = pd.getNullableString()
+	}
+	if err != nil {
 		return err
 	}
 
-	r.SaslAuthBytes, err = pd.getBytes()
+	if version >= 2 {
+		r.SaslAuthBytes, err = pd.getCompactBytes()
+	} else {
+		r.SaslAuthBytes, err = pd.getBytes()
+	}
+	if err != nil {
+		return err
+	}
+
+	if version > 0 {
+		if r.SessionLifetimeMs, err = pd.getInt64(); err != nil {
+			return err
+		}
+	}
+
+	if version >= 2 {
+		_, err = pd.getEmptyTaggedFieldArray()
+	}
 
 	return err
 }
@@ -36,13 +83,23 @@ func (r *SaslAuthenticateResponse) key() int16 {
 }
 
 func (r *SaslAuthenticateResponse) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *SaslAuthenticateResponse) headerVersion() int16 {
+	if r.Version >= 2 {
+		return 1
+	}
 	return 0
 }
 
 func (r *SaslAuthenticateResponse) requiredVersion() KafkaVersion {
-	return V1_0_0_0
+	switch r.Version {
+	case 2:
+		return V2_4_0_0
+	case 1:
+		return V2_2_0_0
+	default:
+		return V1_0_0_0
+	}
 }
@@ -8,6 +8,21 @@ var saslAuthenticatResponseErr = []byte{
 	0, 0, 0, 3, 'm', 's', 'g',
 }
 
+var saslAuthenticateResponseV1 = []byte{
+	0, 0,
+	255, 255,
+	0, 0, 0, 3, 'm', 's', 'g',
+	0, 0, 0, 0, 0, 0, 0x0e, 0x10, // session lifetime 3600ms
+}
+
+var saslAuthenticateResponseV2 = []byte{
+	0, 58,
+	4, 'e', 'r', 'r',
+	4, 'm', 's', 'g',
+	0, 0, 0, 0, 0, 0, 0x0e, 0x10, // session lifetime 3600ms
+	0, // empty tagged fields
+}
+
 func TestSaslAuthenticateResponse(t *testing.T) {
 	response := new(SaslAuthenticateResponse)
 	response.Err = ErrSASLAuthenticationFailed
@@ -16,4 +31,21 @@ func TestSaslAuthenticateResponse(t *testing.T) {
 	response.SaslAuthBytes = []byte(`msg`)
 
 	testResponse(t, "authenticate response", response, saslAuthenticatResponseErr)
+
+	response = &SaslAuthenticateResponse{
+		Version:           1,
+		Err:               ErrNoError,
+		SaslAuthBytes:     []byte(`msg`),
+		SessionLifetimeMs: 3600,
+	}
+	testResponse(t, "v1 with session lifetime", response, saslAuthenticateResponseV1)
+
+	response = &SaslAuthenticateResponse{
+		Version:           2,
+		Err:               ErrSASLAuthenticationFailed,
+		ErrorMessage:      &msg,
+		SaslAuthBytes:     []byte(`msg`),
+		SessionLifetimeMs: 3600,
+	}
+	testResponse(t, "v2", response, saslAuthenticateResponseV2)
 }
@@ -0,0 +1,178 @@
+package sarama
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strings"
+	"time"
+)
+
+const (
+	awsMSKIAMVersion = "2020_10_22"
+	awsMSKIAMAction  = "kafka-cluster:Connect"
+	awsMSKIAMService = "kafka-cluster"
+	// awsMSKIAMExpires is the validity, in seconds, of the signed payload
+	awsMSKIAMExpires = "900"
+
+	awsV4Algorithm    = "AWS4-HMAC-SHA256"
+	awsV4TimeFormat   = "20060102T150405Z"
+	awsEmptyStringSHA = "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"
+)
+
+// AWSCredentials are the AWS credentials used to sign the AWS_MSK_IAM
+// authentication payload.
+type AWSCredentials struct {
+	AccessKeyID     string
+	SecretAccessKey string
+	// SessionToken is only set for temporary credentials.
+	SessionToken string
+}
+
+// AWSCredentialsProvider is the interface that encapsulates how implementors
+// retrieve AWS credentials, typically by wrapping a credentials provider of
+// the AWS SDK. Retrieve is called on every authentication, so it should cache
+// the credentials and refresh them before they expire.
+type AWSCredentialsProvider interface {
+	Retrieve() (*AWSCredentials, error)
+}
+
+// AWSCredentialsProviderFunc adapts a function into an AWSCredentialsProvider.
+type AWSCredentialsProviderFunc func() (*AWSCredentials, error)
+
+// Retrieve calls f.
+func (f AWSCredentialsProviderFunc) Retrieve() (*AWSCredentials, error) {
+	return f()
+}
+
+// EnvAWSCredentialsProvider retrieves AWS credentials from the
+// AWS_ACCESS_KEY_ID, AWS_SECRET_ACCESS_KEY and AWS_SESSION_TOKEN environment
+// variables.
+type EnvAWSCredentialsProvider struct{}
+
+// Retrieve reads the credentials from the environment.
+func (EnvAWSCredentialsProvider) Retrieve() (*AWSCredentials, error) {
+	creds := &AWSCredentials{
+		AccessKeyID:     os.Getenv("AWS_ACCESS_KEY_ID"),
+		SecretAccessKey: os.Getenv("AWS_SECRET_ACCESS_KEY"),
+		SessionToken:    os.Getenv("AWS_SESSION_TOKEN"),
+	}
+	if creds.AccessKeyID == "" || creds.SecretAccessKey == "" {
+		return nil, errors.New("kafka: AWS_ACCESS_KEY_ID and AWS_SECRET_ACCESS_KEY must be set")
+	}
+	return creds, nil
+}
+
+// AWSMSKIAMConfig configures the AWS_MSK_IAM mechanism, which authenticates
+// with Amazon MSK using IAM access control.
+type AWSMSKIAMConfig struct {
+	// Region is the AWS region of the cluster. When empty, it is read from
+	// the AWS_REGION or AWS_DEFAULT_REGION environment variables, or else
+	// from the broker host name.
+	Region string
+	// CredentialsProvider provides the credentials the authentication
+	// payload is signed with (defaults to EnvAWSCredentialsProvider).
+	CredentialsProvider AWSCredentialsProvider
+}
+
+func (c *AWSMSKIAMConfig) region(host string) (string, error) {
+	if c.Region != "" {
+		return c.Region, nil
+	}
+	for _, env := range []string{"AWS_REGION", "AWS_DEFAULT_REGION"} {
+		if region := os.Getenv(env); region != "" {
+			return region, nil
+		}
+	}
+	// MSK brokers are named b-1.<cluster>.<id>.c2.kafka.<region>.amazonaws.com
+	labels := strings.Split(host, ".")
+	for i := 0; i+2 < len(labels); i++ {
+		if labels[i] == "kafka" && labels[i+2] == "amazonaws" {
+			return labels[i+1], nil
+		}
+	}
+	return "", fmt.Errorf("kafka: cannot determine the AWS region of %s, set Net.SASL.AWSMSKIAM.Region", host)
+}
+
+// buildAWSMSKIAMPayload returns the AWS_MSK_IAM authentication payload for
+// host: the parameters of a SigV4 presigned kafka-cluster:Connect request,
+// as a JSON object.
+func buildAWSMSKIAMPayload(conf *AWSMSKIAMConfig, host string, now time.Time) ([]byte, error) {
+	host = strings.ToLower(host)
+	region, err := conf.region(host)
+	if err != nil {
+		return nil, err
+	}
+
+	provider := conf.CredentialsProvider
+	if provider == nil {
+		provider = EnvAWSCredentialsProvider{}
+	}
+	creds, err := provider.Retrieve()
+	if err != nil {
+		return nil, err
+	}
+
+	query := url.Values{}
+	query.Set("Action", awsMSKIAMAction)
+	query.Set("X-Amz-Expires", awsMSKIAMExpires)
+	signature := presignAWSV4(creds, region, awsMSKIAMService, host, "/", query, awsEmptyStringSHA, now)
+
+	payload := map[string]string{
+		"version":    awsMSKIAMVersion,
+		"host":       host,
+		"user-agent": defaultClientSoftwareName + "/" + version(),
+		"action":     awsMSKIAMAction,
+	}
+	for key := range query {
+		if key != "Action" {
+			payload[strings.ToLower(key)] = query.Get(key)
+		}
+	}
+	payload["x-amz-signature"] = signature
+	return json.Marshal(payload)
+}
+
+// presignAWSV4 adds the SigV4 authentication parameters of a GET request to
+// query and returns its signature, as described by
+// https://docs.aws.amazon.com/AmazonS3/latest/API/sigv4-query-string-auth.html
+func presignAWSV4(creds *AWSCredentials, region, service, host, path string, query url.Values, payloadHash string, now time.Time) string {
+	date := now.UTC().Format(awsV4TimeFormat)
+	scope := strings.Join([]string{date[:8], region, service, "aws4_request"}, "/")
+
+	query.Set("X-Amz-Algorithm", awsV4Algorithm)
+	query.Set("X-Amz-Credential", creds.AccessKeyID+"/"+scope)
+	query.Set("X-Amz-Date", date)
+	query.Set("X-Amz-SignedHeaders", "host")
+	if creds.SessionToken != "" {
+		query.Set("X-Amz-Security-Token", creds.SessionToken)
+	}
+
+	canonicalRequest := strings.Join([]string{
+		"GET",
+		path,
+		strings.ReplaceAll(query.Encode(), "+", "%20"),
+		"host:" + host + "\n",
+		"host",
+		payloadHash,
+	}, "\n")
+	canonicalHash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{awsV4Algorithm, date, scope, hex.EncodeToString(canonicalHash[:])}, "\n")
+
+	key := []byte("AWS4" + creds.SecretAccessKey)
+	for _, part := range []string{date[:8], region, service, "aws4_request"} {
+		key = hmacSHA256(key, part)
+	}
+	return hex.EncodeToString(hmacSHA256(key, stringToSign))
+}
+
+func hmacSHA256(key []byte, data string) []byte {
+	h := hmac.New(sha256.New, key)
+	h.Write([]byte(data))
+	return h.Sum(nil)
+}
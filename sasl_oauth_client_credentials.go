@@ -0,0 +1,206 @@
+package sarama
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultClientCredentialsTimeout = 10 * time.Second
+	// defaultClientCredentialsRefreshWindow is the fraction of the token
+	// lifetime after which it is refreshed, as sasl.login.refresh.window.factor
+	// of the Java client.
+	defaultClientCredentialsRefreshWindow = 0.8
+	// defaultClientCredentialsLifetime is assumed for tokens whose expiry is
+	// neither given by the token endpoint nor by an exp claim.
+	defaultClientCredentialsLifetime = 5 * time.Minute
+)
+
+// ClientCredentialsConfig configures a ClientCredentialsTokenProvider.
+type ClientCredentialsConfig struct {
+	// TokenURL is the token endpoint of the OAuth2 authorization server.
+	TokenURL string
+	// ClientID and ClientSecret are the credentials of the client.
+	ClientID     string
+	ClientSecret string
+	// Scopes optionally requested for the token.
+	Scopes []string
+	// EndpointParams are additional parameters sent to the token endpoint,
+	// such as the audience required by some authorization servers.
+	EndpointParams url.Values
+	// AuthInParams sends the client credentials as form parameters instead
+	// of the HTTP basic authentication header, for servers which do not
+	// support the latter.
+	AuthInParams bool
+	// Extensions are the SASL extensions sent along with the token, such as
+	// the logicalCluster and identityPoolId of Confluent Cloud.
+	Extensions map[string]string
+	// RefreshWindow is the fraction of the token lifetime after which a new
+	// token is requested (defaults to 0.8).
+	RefreshWindow float64
+	// HTTPClient is the client used to reach the token endpoint (defaults
+	// to a client with a 10s timeout).
+	HTTPClient *http.Client
+}
+
+// ClientCredentialsTokenProvider is an AccessTokenProvider which obtains
+// tokens through the OAuth2 client_credentials grant (RFC 6749 section 4.4),
+// as described by KIP-768. Tokens are cached and refreshed once the refresh
+// window of their lifetime has elapsed; if the refresh fails, the cached
+// token is used until it expires.
+type ClientCredentialsTokenProvider struct {
+	conf ClientCredentialsConfig
+
+	lock      sync.Mutex
+	token     *AccessToken
+	refreshAt time.Time
+	expiresAt time.Time
+
+	now func() time.Time
+}
+
+// NewClientCredentialsTokenProvider creates a ClientCredentialsTokenProvider,
+// to be set as the Net.SASL.TokenProvider of an OAUTHBEARER configuration.
+func NewClientCredentialsTokenProvider(conf ClientCredentialsConfig) (*ClientCredentialsTokenProvider, error) {
+	switch {
+	case conf.TokenURL == "":
+		return nil, ConfigurationError("ClientCredentialsConfig.TokenURL must not be empty")
+	case conf.ClientID == "":
+		return nil, ConfigurationError("ClientCredentialsConfig.ClientID must not be empty")
+	case conf.RefreshWindow < 0 || conf.RefreshWindow > 1:
+		return nil, ConfigurationError("ClientCredentialsConfig.RefreshWindow must be between 0 and 1")
+	}
+	if _, ok := conf.Extensions[SASLExtKeyAuth]; ok {
+		return nil, ConfigurationError(fmt.Sprintf("the extension `%s` is invalid", SASLExtKeyAuth))
+	}
+	if conf.RefreshWindow == 0 {
+		conf.RefreshWindow = defaultClientCredentialsRefreshWindow
+	}
+	if conf.HTTPClient == nil {
+		conf.HTTPClient = &http.Client{Timeout: defaultClientCredentialsTimeout}
+	}
+	return &ClientCredentialsTokenProvider{conf: conf, now: time.Now}, nil
+}
+
+// Token returns the cached token, requesting a new one from the token
+// endpoint when it is due for refresh.
+func (p *ClientCredentialsTokenProvider) Token() (*AccessToken, error) {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	now := p.now()
+	if p.token != nil && now.Before(p.refreshAt) {
+		return p.token, nil
+	}
+
+	token, lifetime, err := p.requestToken()
+	if err != nil {
+		if p.token != nil && now.Before(p.expiresAt) {
+			warnf("Failed to refresh OAuth token from %s, using the current one: %s\n", p.conf.TokenURL, err)
+			return p.token, nil
+		}
+		return nil, err
+	}
+
+	p.token = &AccessToken{Token: token, Extensions: p.conf.Extensions}
+	p.expiresAt = now.Add(lifetime)
+	p.refreshAt = now.Add(time.Duration(float64(lifetime) * p.conf.RefreshWindow))
+	return p.token, nil
+}
+
+type clientCredentialsResponse struct {
+	AccessToken      string `json:"access_token"`
+	TokenType        string `json:"token_type"`
+	ExpiresIn        int64  `json:"expires_in"`
+	Error            string `json:"error"`
+	ErrorDescription string `json:"error_description"`
+}
+
+// requestToken performs the client_credentials grant, returning the access
+// token and its lifetime.
+func (p *ClientCredentialsTokenProvider) requestToken() (string, time.Duration, error) {
+	form := url.Values{}
+	for key, values := range p.conf.EndpointParams {
+		form[key] = append([]string(nil), values...)
+	}
+	form.Set("grant_type", "client_credentials")
+	if len(p.conf.Scopes) > 0 {
+		form.Set("scope", strings.Join(p.conf.Scopes, " "))
+	}
+	if p.conf.AuthInParams {
+		form.Set("client_id", p.conf.ClientID)
+		form.Set("client_secret", p.conf.ClientSecret)
+	}
+
+	req, err := http.NewRequest(http.MethodPost, p.conf.TokenURL, strings.NewReader(form.Encode()))
+	if err != nil {
+		return "", 0, err
+	}
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.Header.Set("Accept", "application/json")
+	if !p.conf.AuthInParams {
+		req.SetBasicAuth(url.QueryEscape(p.conf.ClientID), url.QueryEscape(p.conf.ClientSecret))
+	}
+
+	res, err := p.conf.HTTPClient.Do(req)
+	if err != nil {
+		return "", 0, fmt.Errorf("kafka: failed to request OAuth token: %w", err)
+	}
+	defer res.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return "", 0, fmt.Errorf("kafka: failed to read OAuth token response: %w", err)
+	}
+
+	var parsed clientCredentialsResponse
+	jsonErr := json.Unmarshal(body, &parsed)
+	switch {
+	case res.StatusCode != http.StatusOK && parsed.Error != "":
+		return "", 0, fmt.Errorf("kafka: OAuth token request failed with %s: %s %s", res.Status, parsed.Error, parsed.ErrorDescription)
+	case res.StatusCode != http.StatusOK:
+		return "", 0, fmt.Errorf("kafka: OAuth token request failed with %s", res.Status)
+	case jsonErr != nil:
+		return "", 0, fmt.Errorf("kafka: invalid OAuth token response: %w", jsonErr)
+	case parsed.AccessToken == "":
+		return "", 0, errors.New("kafka: OAuth token response has no access_token")
+	}
+
+	lifetime := time.Duration(parsed.ExpiresIn) * time.Second
+	if lifetime <= 0 {
+		lifetime = jwtLifetime(parsed.AccessToken, p.now())
+	}
+	return parsed.AccessToken, lifetime, nil
+}
+
+// jwtLifetime returns the time left before the exp claim of token, if it is
+// a JWT with such claim, or else defaultClientCredentialsLifetime.
+func jwtLifetime(token string, now time.Time) time.Duration {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return defaultClientCredentialsLifetime
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimRight(parts[1], "="))
+	if err != nil {
+		return defaultClientCredentialsLifetime
+	}
+	var claims struct {
+		Exp int64 `json:"exp"`
+	}
+	if err := json.Unmarshal(payload, &claims); err != nil || claims.Exp <= 0 {
+		return defaultClientCredentialsLifetime
+	}
+	if lifetime := time.Unix(claims.Exp, 0).Sub(now); lifetime > 0 {
+		return lifetime
+	}
+	// already expired, never cache it
+	return 0
+}
@@ -0,0 +1,139 @@
+package sarama
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestClientCredentialsTokenProvider(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&requests, 1)
+		if err := r.ParseForm(); err != nil {
+			t.Error(err)
+		}
+		if user, password, ok := r.BasicAuth(); !ok || user != "client" || password != "secret" {
+			t.Errorf("unexpected basic auth %q %q", user, password)
+		}
+		if grant := r.PostForm.Get("grant_type"); grant != "client_credentials" {
+			t.Errorf("unexpected grant type %q", grant)
+		}
+		if scope := r.PostForm.Get("scope"); scope != "kafka read" {
+			t.Errorf("unexpected scope %q", scope)
+		}
+		if audience := r.PostForm.Get("audience"); audience != "cluster" {
+			t.Errorf("unexpected audience %q", audience)
+		}
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{"access_token":"token-%d","token_type":"Bearer","expires_in":100}`, n)
+	}))
+	defer server.Close()
+
+	provider, err := NewClientCredentialsTokenProvider(ClientCredentialsConfig{
+		TokenURL:       server.URL,
+		ClientID:       "client",
+		ClientSecret:   "secret",
+		Scopes:         []string{"kafka", "read"},
+		EndpointParams: map[string][]string{"audience": {"cluster"}},
+		Extensions:     map[string]string{"logicalCluster": "lkc-1"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+
+	token, err := provider.Token()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if token.Token != "token-1" || token.Extensions["logicalCluster"] != "lkc-1" {
+		t.Errorf("unexpected token %+v", token)
+	}
+
+	// cached until 80% of its lifetime
+	now = now.Add(79 * time.Second)
+	if token, _ = provider.Token(); token.Token != "token-1" {
+		t.Errorf("expected the cached token, got %s", token.Token)
+	}
+
+	now = now.Add(2 * time.Second)
+	if token, _ = provider.Token(); token.Token != "token-2" {
+		t.Errorf("expected a refreshed token, got %s", token.Token)
+	}
+	if requests != 2 {
+		t.Errorf("expected 2 token requests, got %d", requests)
+	}
+}
+
+func TestClientCredentialsTokenProviderFailure(t *testing.T) {
+	var fail int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if atomic.LoadInt32(&fail) == 1 {
+			w.WriteHeader(http.StatusUnauthorized)
+			fmt.Fprint(w, `{"error":"invalid_client","error_description":"bad secret"}`)
+			return
+		}
+		if r.PostFormValue("client_id") != "client" || r.PostFormValue("client_secret") != "secret" {
+			t.Errorf("expected the credentials in the form, got %v", r.PostForm)
+		}
+		fmt.Fprint(w, `{"access_token":"token","expires_in":100}`)
+	}))
+	defer server.Close()
+
+	provider, err := NewClientCredentialsTokenProvider(ClientCredentialsConfig{
+		TokenURL:     server.URL,
+		ClientID:     "client",
+		ClientSecret: "secret",
+		AuthInParams: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	now := time.Now()
+	provider.now = func() time.Time { return now }
+
+	if _, err := provider.Token(); err != nil {
+		t.Fatal(err)
+	}
+	atomic.StoreInt32(&fail, 1)
+
+	// the current token is used as long as it is valid
+	now = now.Add(90 * time.Second)
+	if token, err := provider.Token(); err != nil || token.Token != "token" {
+		t.Errorf("expected the current token, got %v, %v", token, err)
+	}
+
+	now = now.Add(20 * time.Second)
+	if _, err := provider.Token(); err == nil {
+		t.Error("expected an error once the token expired")
+	}
+}
+
+func TestNewClientCredentialsTokenProviderValidates(t *testing.T) {
+	var target ConfigurationError
+	if _, err := NewClientCredentialsTokenProvider(ClientCredentialsConfig{ClientID: "client"}); !errors.As(err, &target) {
+		t.Errorf("expected a ConfigurationError without TokenURL, got %v", err)
+	}
+	if _, err := NewClientCredentialsTokenProvider(ClientCredentialsConfig{TokenURL: "http://localhost", ClientID: "client", Extensions: map[string]string{"auth": "x"}}); !errors.As(err, &target) {
+		t.Errorf("expected a ConfigurationError with the auth extension, got %v", err)
+	}
+}
+
+func TestJWTLifetime(t *testing.T) {
+	now := time.Unix(1000, 0)
+	claims := base64.RawURLEncoding.EncodeToString([]byte(`{"sub":"client","exp":1600}`))
+	if lifetime := jwtLifetime("header."+claims+".signature", now); lifetime != 600*time.Second {
+		t.Errorf("expected a lifetime of 10m, got %s", lifetime)
+	}
+	if lifetime := jwtLifetime("opaque", now); lifetime != defaultClientCredentialsLifetime {
+		t.Errorf("expected the default lifetime, got %s", lifetime)
+	}
+}
@@ -0,0 +1,102 @@
+package sarama
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// ShareClient returns a Client whose lifecycle is shared by the producers,
+// consumers, consumer groups and cluster admins built from it with the
+// FromClient constructors. Each of them holds a reference to client, as does
+// the returned Client, and closing any of them releases its reference:
+// client is closed exactly once, when the last reference is released.
+//
+// This spares the caller from tracking when the client can be closed, e.g.
+//
+//	client := sarama.ShareClient(c)
+//	producer, _ := sarama.NewAsyncProducerFromClient(client)
+//	consumer, _ := sarama.NewConsumerFromClient(client)
+//	_ = client.Close() // c stays open until producer and consumer are closed
+//
+// Without ShareClient, closing a producer or consumer built from a client
+// leaves the client open, while closing a cluster admin closes it. Sharing a
+// Client returned by ShareClient returns it unchanged.
+func ShareClient(client Client) Client {
+	if _, ok := client.(*sharedClient); ok {
+		return client
+	}
+	return &sharedClient{Client: client, refs: &clientRefs{count: 1}}
+}
+
+// clientRefs counts the references to a shared Client.
+type clientRefs struct {
+	count int32
+}
+
+// sharedClient is a reference to a shared Client, see ShareClient. All the
+// methods but Close and CloseContext pass through to the shared Client.
+type sharedClient struct {
+	Client
+	refs     *clientRefs
+	released int32
+}
+
+// acquire returns a new reference to the shared Client, or ErrClosedClient if
+// sc was already released or the shared Client closed.
+func (sc *sharedClient) acquire() (*sharedClient, error) {
+	for {
+		count := atomic.LoadInt32(&sc.refs.count)
+		if count == 0 || sc.Closed() {
+			return nil, ErrClosedClient
+		}
+		if atomic.CompareAndSwapInt32(&sc.refs.count, count, count+1) {
+			return &sharedClient{Client: sc.Client, refs: sc.refs}, nil
+		}
+	}
+}
+
+// release releases the reference, returning whether it was the last one.
+func (sc *sharedClient) release() (bool, error) {
+	if !atomic.CompareAndSwapInt32(&sc.released, 0, 1) {
+		return false, ErrClosedClient
+	}
+	return atomic.AddInt32(&sc.refs.count, -1) == 0, nil
+}
+
+// Close releases the reference, closing the shared Client if it was the last
+// one.
+func (sc *sharedClient) Close() error {
+	last, err := sc.release()
+	if err != nil || !last {
+		return err
+	}
+	return sc.Client.Close()
+}
+
+// CloseContext releases the reference, closing the shared Client with
+// CloseContext if it was the last one.
+func (sc *sharedClient) CloseContext(ctx context.Context) error {
+	last, err := sc.release()
+	if err != nil || !last {
+		return err
+	}
+	return sc.Client.CloseContext(ctx)
+}
+
+// Closed returns true once the reference was released, or the shared Client
+// closed.
+func (sc *sharedClient) Closed() bool {
+	return atomic.LoadInt32(&sc.released) == 1 || sc.Client.Closed()
+}
+
+// borrowClient returns the Client to be used by a producer or consumer built
+// from client: a new reference to client if it is shared, or client with its
+// Close method disabled otherwise.
+func borrowClient(client Client) (Client, error) {
+	if shared, ok := client.(*sharedClient); ok {
+		return shared.acquire()
+	}
+	// For clients passed in by the client, ensure we don't
+	// call Close() on it.
+	return &nopCloserClient{client}, nil
+}
@@ -0,0 +1,61 @@
+package sarama
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestShareClient(t *testing.T) {
+	seedBroker := NewMockBroker(t, 1)
+	defer seedBroker.Close()
+	seedBroker.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).
+			SetBroker(seedBroker.Addr(), seedBroker.BrokerID()).
+			SetController(seedBroker.BrokerID()),
+	})
+
+	conf := NewTestConfig()
+	conf.Version = V1_0_0_0
+	c, err := NewClient([]string{seedBroker.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := ShareClient(c)
+	if ShareClient(client) != client {
+		t.Error("expected sharing a shared client to return it unchanged")
+	}
+
+	producer, err := NewAsyncProducerFromClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	consumer, err := NewConsumerFromClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+	admin, err := NewClusterAdminFromClient(client)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	safeClose(t, client)
+	if !client.Closed() || c.Closed() {
+		t.Error("expected only the reference of the caller to be released")
+	}
+	if err := client.Close(); !errors.Is(err, ErrClosedClient) {
+		t.Errorf("expected a released reference to be closed only once, got %v", err)
+	}
+	if _, err := NewConsumerFromClient(client); !errors.Is(err, ErrClosedClient) {
+		t.Errorf("expected a released reference not to be borrowed, got %v", err)
+	}
+
+	safeClose(t, producer)
+	safeClose(t, admin)
+	if c.Closed() {
+		t.Error("expected the client to stay open while the consumer uses it")
+	}
+	safeClose(t, consumer)
+	if !c.Closed() {
+		t.Error("expected the client to be closed with its last reference")
+	}
+}
@@ -0,0 +1,101 @@
+package sarama
+
+import (
+	"bytes"
+	"encoding/binary"
+
+	xerial "github.com/eapache/go-xerial-snappy"
+	"github.com/golang/snappy"
+)
+
+// SnappyFraming is how the data compressed with CompressionSnappy is framed.
+type SnappyFraming int
+
+const (
+	// SnappyRawBlock produces a single snappy block, without framing, as
+	// Sarama always did and most non-JVM clients do.
+	SnappyRawBlock SnappyFraming = iota
+	// SnappyXerialFraming produces the xerial framing of the snappy blocks,
+	// as the JVM clients do.
+	SnappyXerialFraming
+)
+
+var (
+	xerialSnappyHeader = []byte{130, 'S', 'N', 'A', 'P', 'P', 'Y', 0}
+	// xerialSnappyHeaderLen is the length of the header followed by the
+	// version and the compatible version of the framing
+	xerialSnappyHeaderLen = len(xerialSnappyHeader) + 8
+	// snappyStreamHeader starts the snappy framing format, see
+	// https://github.com/google/snappy/blob/main/framing_format.txt
+	snappyStreamHeader = []byte{0xff, 6, 0, 0, 's', 'N', 'a', 'P', 'p', 'Y'}
+)
+
+func snappyEncode(framing SnappyFraming, src []byte) []byte {
+	if framing == SnappyXerialFraming {
+		return xerial.EncodeStream(nil, src)
+	}
+	return xerial.Encode(src)
+}
+
+// snappyDecode decodes src into dst[:0], growing it if needed, whether it is a
+// raw snappy block, xerial framed or in the snappy framing format, telling
+// them apart by their headers. It fails with errDecompressionLimit rather than
+// decode more than maxSize bytes, unless maxSize is negative.
+func snappyDecode(dst, src []byte, maxSize int) ([]byte, error) {
+	switch {
+	case bytes.HasPrefix(src, xerialSnappyHeader):
+		if len(src) == xerialSnappyHeaderLen {
+			// no block follows the header of empty data, which the xerial
+			// package rejects
+			return []byte{}, nil
+		}
+		n, err := xerialDecodedLen(src)
+		if err != nil {
+			return nil, err
+		}
+		if maxSize >= 0 && n > maxSize {
+			return nil, errDecompressionLimit
+		}
+		// decoded into a buffer of the exact size rather than grown block
+		// after block
+		if cap(dst) < n {
+			dst = make([]byte, 0, n)
+		}
+		return xerial.DecodeInto(dst[:0], src)
+	case bytes.HasPrefix(src, snappyStreamHeader):
+		return readAllLimited(snappy.NewReader(bytes.NewReader(src)), maxSize, dst)
+	default:
+		n, err := snappy.DecodedLen(src)
+		if err != nil {
+			return nil, err
+		}
+		if maxSize >= 0 && n > maxSize {
+			return nil, errDecompressionLimit
+		}
+		// decoded into dst unless it is too small
+		return snappy.Decode(dst[:cap(dst)], src)
+	}
+}
+
+// xerialDecodedLen returns the length of the xerial framed src once decoded,
+// from the headers of its blocks.
+func xerialDecodedLen(src []byte) (int, error) {
+	n := 0
+	for pos := xerialSnappyHeaderLen; pos < len(src); {
+		if pos+4 > len(src) {
+			return 0, snappy.ErrCorrupt
+		}
+		size := int(binary.BigEndian.Uint32(src[pos:]))
+		pos += 4
+		if size < 0 || pos+size > len(src) {
+			return 0, snappy.ErrCorrupt
+		}
+		blockLen, err := snappy.DecodedLen(src[pos : pos+size])
+		if err != nil {
+			return 0, err
+		}
+		n += blockLen
+		pos += size
+	}
+	return n, nil
+}
@@ -0,0 +1,44 @@
+package sarama
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/golang/snappy"
+)
+
+func TestSnappyFramings(t *testing.T) {
+	for _, data := range [][]byte{{}, []byte("value"), bytes.Repeat([]byte("sarama snappy "), 5000)} {
+		framings := map[string][]byte{
+			"raw block":      snappyEncode(SnappyRawBlock, data),
+			"xerial framing": snappyEncode(SnappyXerialFraming, data),
+		}
+		// the framing format writes nothing for empty data
+		if len(data) > 0 {
+			var stream bytes.Buffer
+			if _, err := snappy.NewWriter(&stream).Write(data); err != nil {
+				t.Fatal(err)
+			}
+			framings["framing format"] = stream.Bytes()
+		}
+
+		for name, compressed := range framings {
+			decompressed, err := decompress(CompressionSnappy, ZstdDecoderParams{}, -1, nil, compressed)
+			if err != nil {
+				t.Errorf("%s of %d bytes: %v", name, len(data), err)
+			} else if !bytes.Equal(decompressed, data) {
+				t.Errorf("%s of %d bytes decoded %d bytes", name, len(data), len(decompressed))
+			}
+		}
+	}
+}
+
+func TestSnappyXerialFramingProduced(t *testing.T) {
+	compressed, err := compress(CompressionSnappy, CompressionLevelDefault, compressionOptions{snappyFraming: SnappyXerialFraming}, []byte("value"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.HasPrefix(compressed, xerialSnappyHeader) {
+		t.Errorf("expected the xerial header, got %v", compressed)
+	}
+}
@@ -0,0 +1,42 @@
+package sarama
+
+import (
+	"syscall"
+	"time"
+)
+
+// defaultKeepAlive is the keep-alive period of net.Dialer when KeepAlive is 0.
+const defaultKeepAlive = 15 * time.Second
+
+// hasSocketOptions reports whether any of the socket options of Net is set.
+func (c *Config) hasSocketOptions() bool {
+	return c.keepAliveTuned() || c.Net.ReadBufferSize > 0 || c.Net.WriteBufferSize > 0 || c.Net.DSCP > 0
+}
+
+// keepAliveTuned reports whether the keep-alive probes are enabled and tuned
+// beyond their period.
+func (c *Config) keepAliveTuned() bool {
+	return c.Net.KeepAlive >= 0 && (c.Net.KeepAliveInterval > 0 || c.Net.KeepAliveCount > 0)
+}
+
+// controlSocket is the net.Dialer Control function setting the socket
+// options of Net before connecting.
+func (c *Config) controlSocket(network, address string, conn syscall.RawConn) error {
+	var err error
+	if controlErr := conn.Control(func(fd uintptr) {
+		err = c.setSocketOptions(network, fd)
+	}); controlErr != nil {
+		return controlErr
+	}
+	return err
+}
+
+// durationSeconds rounds d up to whole seconds, the unit of the keep-alive
+// socket options.
+func durationSeconds(d time.Duration) int {
+	seconds := int((d + time.Second - 1) / time.Second)
+	if seconds < 1 {
+		return 1
+	}
+	return seconds
+}
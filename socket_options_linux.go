@@ -0,0 +1,56 @@
+//go:build linux
+// +build linux
+
+package sarama
+
+import (
+	"os"
+	"syscall"
+)
+
+const socketOptionsSupported = true
+
+func (c *Config) setSocketOptions(network string, fd uintptr) error {
+	type option struct {
+		level, name, value int
+	}
+	var options []option
+
+	if c.Net.ReadBufferSize > 0 {
+		options = append(options, option{syscall.SOL_SOCKET, syscall.SO_RCVBUF, c.Net.ReadBufferSize})
+	}
+	if c.Net.WriteBufferSize > 0 {
+		options = append(options, option{syscall.SOL_SOCKET, syscall.SO_SNDBUF, c.Net.WriteBufferSize})
+	}
+	if c.Net.DSCP > 0 {
+		// the DSCP is the upper six bits of the TOS, or traffic class, byte
+		if network == "tcp6" {
+			options = append(options, option{syscall.IPPROTO_IPV6, syscall.IPV6_TCLASS, c.Net.DSCP << 2})
+		} else {
+			options = append(options, option{syscall.IPPROTO_IP, syscall.IP_TOS, c.Net.DSCP << 2})
+		}
+	}
+	if c.keepAliveTuned() {
+		idle := c.Net.KeepAlive
+		if idle == 0 {
+			idle = defaultKeepAlive
+		}
+		options = append(options,
+			option{syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, 1},
+			option{syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE, durationSeconds(idle)},
+		)
+		if c.Net.KeepAliveInterval > 0 {
+			options = append(options, option{syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, durationSeconds(c.Net.KeepAliveInterval)})
+		}
+		if c.Net.KeepAliveCount > 0 {
+			options = append(options, option{syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, c.Net.KeepAliveCount})
+		}
+	}
+
+	for _, o := range options {
+		if err := syscall.SetsockoptInt(int(fd), o.level, o.name, o.value); err != nil {
+			return os.NewSyscallError("setsockopt", err)
+		}
+	}
+	return nil
+}
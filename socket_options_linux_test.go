@@ -0,0 +1,64 @@
+//go:build linux
+// +build linux
+
+package sarama
+
+import (
+	"net"
+	"syscall"
+	"testing"
+	"time"
+)
+
+func TestSocketOptions(t *testing.T) {
+	mb := NewMockBroker(t, 0)
+	defer mb.Close()
+
+	conf := NewTestConfig()
+	conf.Net.KeepAlive = 30 * time.Second
+	conf.Net.KeepAliveInterval = 5 * time.Second
+	conf.Net.KeepAliveCount = 4
+	conf.Net.ReadBufferSize = 64 << 10
+	conf.Net.WriteBufferSize = 32 << 10
+	conf.Net.DSCP = 46
+	if err := conf.Validate(); err != nil {
+		t.Fatal(err)
+	}
+
+	conn, err := conf.dial(mb.Addr())
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	raw, err := conn.(*net.TCPConn).SyscallConn()
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []struct {
+		name          string
+		level, opt    int
+		value, atMost int
+	}{
+		{"SO_KEEPALIVE", syscall.SOL_SOCKET, syscall.SO_KEEPALIVE, 1, 1},
+		{"TCP_KEEPIDLE", syscall.IPPROTO_TCP, syscall.TCP_KEEPIDLE, 30, 30},
+		{"TCP_KEEPINTVL", syscall.IPPROTO_TCP, syscall.TCP_KEEPINTVL, 5, 5},
+		{"TCP_KEEPCNT", syscall.IPPROTO_TCP, syscall.TCP_KEEPCNT, 4, 4},
+		// the kernel doubles the buffer sizes to account for its overhead
+		{"SO_RCVBUF", syscall.SOL_SOCKET, syscall.SO_RCVBUF, 64 << 10, 128 << 10},
+		{"SO_SNDBUF", syscall.SOL_SOCKET, syscall.SO_SNDBUF, 32 << 10, 64 << 10},
+		{"IP_TOS", syscall.IPPROTO_IP, syscall.IP_TOS, 46 << 2, 46 << 2},
+	}
+	if err := raw.Control(func(fd uintptr) {
+		for _, e := range expected {
+			value, err := syscall.GetsockoptInt(int(fd), e.level, e.opt)
+			if err != nil {
+				t.Errorf("%s: %v", e.name, err)
+			} else if value < e.value || value > e.atMost {
+				t.Errorf("expected %s to be %d, got %d", e.name, e.value, value)
+			}
+		}
+	}); err != nil {
+		t.Fatal(err)
+	}
+}
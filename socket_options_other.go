@@ -0,0 +1,12 @@
+//go:build !linux
+// +build !linux
+
+package sarama
+
+import "errors"
+
+const socketOptionsSupported = false
+
+func (c *Config) setSocketOptions(network string, fd uintptr) error {
+	return errors.New("kafka: socket options are only supported on Linux")
+}
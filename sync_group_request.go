@@ -1,71 +1,156 @@
 package sarama
 
 type SyncGroupRequest struct {
+	Version          int16
 	GroupId          string
 	GenerationId     int32
 	MemberId         string
+	GroupInstanceId  *string // Version 3
 	GroupAssignments map[string][]byte
 }
 
-func (r *SyncGroupRequest) encode(pe packetEncoder) error {
-	if err := pe.putString(r.GroupId); err != nil {
+func (r *SyncGroupRequest) encode(pe packetEncoder) (err error) {
+	if r.Version >= 4 {
+		err = pe.putCompactString(r.GroupId)
+	} else {
+		err = pe.putString(r.GroupId)
+	}
+	if err != nil {
 		return err
 	}
 
 	pe.putInt32(r.GenerationId)
 
-	if err := pe.putString(r.MemberId); err != nil {
+	if r.Version >= 4 {
+		err = pe.putCompactString(r.MemberId)
+	} else {
+		err = pe.putString(r.MemberId)
+	}
+	if err != nil {
+		return err
+	}
+
+	if r.Version >= 4 {
+		err = pe.putNullableCompactString(r.GroupInstanceId)
+	} else if r.Version >= 3 {
+		err = pe.putNullableString(r.GroupInstanceId)
+	}
+	if err != nil {
 		return err
 	}
 
-	if err := pe.putArrayLength(len(r.GroupAssignments)); err != nil {
+	if r.Version >= 4 {
+		pe.putCompactArrayLength(len(r.GroupAssignments))
+	} else if err := pe.putArrayLength(len(r.GroupAssignments)); err != nil {
 		return err
 	}
 	for memberId, memberAssignment := range r.GroupAssignments {
-		if err := pe.putString(memberId); err != nil {
+		if r.Version >= 4 {
+			err = pe.putCompactString(memberId)
+		} else {
+			err = pe.putString(memberId)
+		}
+		if err != nil {
 			return err
 		}
-		if err := pe.putBytes(memberAssignment); err != nil {
+		if r.Version >= 4 {
+			err = pe.putCompactBytes(memberAssignment)
+		} else {
+			err = pe.putBytes(memberAssignment)
+		}
+		if err != nil {
 			return err
 		}
+		if r.Version >= 4 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if r.Version >= 4 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
 }
 
 func (r *SyncGroupRequest) decode(pd packetDecoder, version int16) (err error) {
-	if r.GroupId, err = pd.getString(); err != nil {
+	r.Version = version
+
+	if version >= 4 {
+		r.GroupId, err = pd.getCompactString()
+	} else {
+		r.GroupId, err = pd.getString()
+	}
+	if err != nil {
 		return
 	}
 	if r.GenerationId, err = pd.getInt32(); err != nil {
 		return
 	}
-	if r.MemberId, err = pd.getString(); err != nil {
+	if version >= 4 {
+		r.MemberId, err = pd.getCompactString()
+	} else {
+		r.MemberId, err = pd.getString()
+	}
+	if err != nil {
+		return
+	}
+	if version >= 4 {
+		r.GroupInstanceId, err = pd.getCompactNullableString()
+	} else if version >= 3 {
+		r.GroupInstanceId, err = pd.getNullableString()
+	}
+	if err != nil {
 		return
 	}
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 4 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
-	if n == 0 {
-		return nil
-	}
 
-	r.GroupAssignments = make(map[string][]byte)
+	if n > 0 {
+		r.GroupAssignments = make(map[string][]byte)
+	}
 	for i := 0; i < n; i++ {
-		memberId, err := pd.getString()
+		var memberId string
+		if version >= 4 {
+			memberId, err = pd.getCompactString()
+		} else {
+			memberId, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
-		memberAssignment, err := pd.getBytes()
+		var memberAssignment []byte
+		if version >= 4 {
+			memberAssignment, err = pd.getCompactBytes()
+		} else {
+			memberAssignment, err = pd.getBytes()
+		}
 		if err != nil {
 			return err
 		}
+		if version >= 4 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
 
 		r.GroupAssignments[memberId] = memberAssignment
 	}
 
+	if version >= 4 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
+
 	return nil
 }
 
@@ -74,15 +159,29 @@ func (r *SyncGroupRequest) key() int16 {
 }
 
 func (r *SyncGroupRequest) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *SyncGroupRequest) headerVersion() int16 {
+	if r.Version >= 4 {
+		return 2
+	}
 	return 1
 }
 
 func (r *SyncGroupRequest) requiredVersion() KafkaVersion {
-	return V0_9_0_0
+	switch r.Version {
+	case 4:
+		return V2_4_0_0
+	case 3:
+		return V2_3_0_0
+	case 2:
+		return V2_0_0_0
+	case 1:
+		return V0_11_0_0
+	default:
+		return V0_9_0_0
+	}
 }
 
 func (r *SyncGroupRequest) AddGroupAssignment(memberId string, memberAssignment []byte) {
@@ -18,6 +18,18 @@ var (
 		0, 3, 'b', 'a', 'z', // Member ID
 		0, 0, 0, 3, 'f', 'o', 'o', // Member assignment
 	}
+
+	populatedSyncGroupRequestV4 = []byte{
+		4, 'f', 'o', 'o', // Group ID
+		0x00, 0x01, 0x02, 0x03, // Generation ID
+		4, 'b', 'a', 'z', // Member ID
+		5, 'i', 'n', 's', 't', // Group instance ID
+		2,                // one assignment
+		4, 'b', 'a', 'z', // Member ID
+		4, 'f', 'o', 'o', // Member assignment
+		0, // assignment tagged fields
+		0, // tagged fields
+	}
 )
 
 func TestSyncGroupRequest(t *testing.T) {
@@ -35,4 +47,13 @@ func TestSyncGroupRequest(t *testing.T) {
 	request.MemberId = "baz"
 	request.AddGroupAssignment("baz", []byte("foo"))
 	testRequest(t, "populated", request, populatedSyncGroupRequest)
+
+	request = new(SyncGroupRequest)
+	request.Version = 4
+	request.GroupId = "foo"
+	request.GenerationId = 66051
+	request.MemberId = "baz"
+	request.GroupInstanceId = nullString("inst")
+	request.AddGroupAssignment("baz", []byte("foo"))
+	testRequest(t, "populated v4", request, populatedSyncGroupRequestV4)
 }
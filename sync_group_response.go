@@ -1,6 +1,10 @@
 package sarama
 
+import "time"
+
 type SyncGroupResponse struct {
+	Version          int16
+	ThrottleTime     int32
 	Err              KError
 	MemberAssignment []byte
 }
@@ -11,12 +15,30 @@ func (r *SyncGroupResponse) GetMemberAssignment() (*ConsumerGroupMemberAssignmen
 	return assignment, err
 }
 
-func (r *SyncGroupResponse) encode(pe packetEncoder) error {
+func (r *SyncGroupResponse) encode(pe packetEncoder) (err error) {
+	if r.Version >= 1 {
+		pe.putInt32(r.ThrottleTime)
+	}
 	pe.putInt16(int16(r.Err))
+	if r.Version >= 4 {
+		if err = pe.putCompactBytes(r.MemberAssignment); err != nil {
+			return err
+		}
+		pe.putEmptyTaggedFieldArray()
+		return nil
+	}
 	return pe.putBytes(r.MemberAssignment)
 }
 
 func (r *SyncGroupResponse) decode(pd packetDecoder, version int16) (err error) {
+	r.Version = version
+
+	if version >= 1 {
+		if r.ThrottleTime, err = pd.getInt32(); err != nil {
+			return err
+		}
+	}
+
 	kerr, err := pd.getInt16()
 	if err != nil {
 		return err
@@ -24,6 +46,14 @@ func (r *SyncGroupResponse) decode(pd packetDecoder, version int16) (err error)
 
 	r.Err = KError(kerr)
 
+	if version >= 4 {
+		if r.MemberAssignment, err = pd.getCompactBytes(); err != nil {
+			return err
+		}
+		_, err = pd.getEmptyTaggedFieldArray()
+		return
+	}
+
 	r.MemberAssignment, err = pd.getBytes()
 	return
 }
@@ -33,13 +63,39 @@ func (r *SyncGroupResponse) key() int16 {
 }
 
 func (r *SyncGroupResponse) version() int16 {
-	return 0
+	return r.Version
 }
 
 func (r *SyncGroupResponse) headerVersion() int16 {
+	if r.Version >= 4 {
+		return 1
+	}
 	return 0
 }
 
 func (r *SyncGroupResponse) requiredVersion() KafkaVersion {
-	return V0_9_0_0
+	switch r.Version {
+	case 4:
+		return V2_4_0_0
+	case 3:
+		return V2_3_0_0
+	case 2:
+		return V2_0_0_0
+	case 1:
+		return V0_11_0_0
+	default:
+		return V0_9_0_0
+	}
+}
+
+func (r *SyncGroupResponse) throttleTime() time.Duration {
+	return time.Duration(r.ThrottleTime) * time.Millisecond
+}
+
+func (r *SyncGroupResponse) shouldClientThrottle(version int16) bool {
+	return version >= 2
+}
+
+func (r *SyncGroupResponse) hasErrorCode() bool {
+	return r.Err != ErrNoError
 }
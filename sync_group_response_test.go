@@ -16,6 +16,13 @@ var (
 		0, 27, // ErrRebalanceInProgress
 		0, 0, 0, 0, // No member assignment data
 	}
+
+	syncGroupResponseV4NoError = []byte{
+		0, 0, 0, 100, // Throttle time
+		0x00, 0x00, // No error
+		4, 0x01, 0x02, 0x03, // Member assignment data
+		0, // Tagged fields
+	}
 )
 
 func TestSyncGroupResponse(t *testing.T) {
@@ -39,3 +46,12 @@ func TestSyncGroupResponse(t *testing.T) {
 		t.Error("Decoding MemberAssignment failed, found:", response.MemberAssignment)
 	}
 }
+
+func TestSyncGroupResponseV4(t *testing.T) {
+	response := &SyncGroupResponse{
+		Version:          4,
+		ThrottleTime:     100,
+		MemberAssignment: []byte{0x01, 0x02, 0x03},
+	}
+	testResponse(t, "no error", response, syncGroupResponseV4NoError)
+}
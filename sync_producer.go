@@ -1,6 +1,9 @@
 package sarama
 
-import "sync"
+import (
+	"context"
+	"sync"
+)
 
 // SyncProducer publishes Kafka messages, blocking until they have been acknowledged. It routes messages to the correct
 // broker, refreshing metadata as appropriate, and parses responses for errors. You must call Close() on a producer
@@ -29,6 +32,12 @@ type SyncProducer interface {
 	// object passes out of scope, as it may otherwise leak memory.
 	// You must call this before calling Close on the underlying client.
 	Close() error
+
+	// CloseContext shuts down the producer like Close, waiting for the messages
+	// in flight to be delivered until ctx is done. The connections of the
+	// producer are then torn down, unless it was created from a client, and a
+	// *CloseAbandonedError reports the messages which were abandoned.
+	CloseContext(ctx context.Context) error
 }
 
 type syncProducer struct {
@@ -55,7 +64,8 @@ func NewSyncProducer(addrs []string, config *Config) (SyncProducer, error) {
 }
 
 // NewSyncProducerFromClient creates a new SyncProducer using the given client. It is still
-// necessary to call Close() on the underlying client when shutting down this producer.
+// necessary to call Close() on the underlying client when shutting down this producer,
+// unless it was shared with ShareClient.
 func NewSyncProducerFromClient(client Client) (SyncProducer, error) {
 	if err := verifyProducerConfig(client.Config()); err != nil {
 		return nil, err
@@ -146,3 +156,11 @@ func (sp *syncProducer) Close() error {
 	sp.wg.Wait()
 	return nil
 }
+
+func (sp *syncProducer) CloseContext(ctx context.Context) error {
+	sp.producer.AsyncClose()
+	if err := waitContext(ctx, sp.wg.Wait); err != nil {
+		return sp.producer.abandon(err)
+	}
+	return nil
+}
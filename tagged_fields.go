@@ -0,0 +1,67 @@
+package sarama
+
+import "sort"
+
+// taggedField is a tagged field of a flexible version, encoded by value.
+type taggedField struct {
+	tag   uint64
+	value encoder
+}
+
+// encoderFunc turns a function into an encoder.
+type encoderFunc func(pe packetEncoder) error
+
+func (f encoderFunc) encode(pe packetEncoder) error {
+	return f(pe)
+}
+
+// putTaggedFields encodes the tagged fields in the order of their tags, each
+// prefixed by its tag and its size.
+func putTaggedFields(pe packetEncoder, fields []taggedField) error {
+	sort.SliceStable(fields, func(i, j int) bool {
+		return fields[i].tag < fields[j].tag
+	})
+	pe.putUVarint(uint64(len(fields)))
+	for _, f := range fields {
+		data, err := encode(f.value, nil)
+		if err != nil {
+			return err
+		}
+		pe.putUVarint(f.tag)
+		pe.putUVarint(uint64(len(data)))
+		if err := pe.putRawBytes(data); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// getTaggedFields hands each tagged field over to fn with a decoder over its
+// value, ignoring whatever fn leaves undecoded such as the unknown tags.
+func getTaggedFields(pd packetDecoder, fn func(tag uint64, pd packetDecoder) error) error {
+	n, err := pd.getUVarint()
+	if err != nil {
+		return err
+	}
+	for i := uint64(0); i < n; i++ {
+		tag, err := pd.getUVarint()
+		if err != nil {
+			return err
+		}
+		size, err := pd.getUVarint()
+		if err != nil {
+			return err
+		}
+		if size > uint64(pd.remaining()) {
+			return ErrInsufficientData
+		}
+		data, err := pd.getRawBytes(int(size))
+		if err != nil {
+			return err
+		}
+		if err := fn(tag, &realDecoder{raw: data}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
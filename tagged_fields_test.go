@@ -0,0 +1,54 @@
+package sarama
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestTaggedFields(t *testing.T) {
+	fields := []taggedField{
+		{tag: 3, value: encoderFunc(func(pe packetEncoder) error {
+			pe.putInt16(7)
+			return nil
+		})},
+		{tag: 1, value: encoderFunc(func(pe packetEncoder) error {
+			return pe.putCompactString("ab")
+		})},
+	}
+	expected := []byte{
+		2,    // 2 tagged fields
+		1, 3, // tag 1, 3 bytes
+		3, 'a', 'b', // "ab"
+		3, 2, // tag 3, 2 bytes
+		0, 7, // 7
+	}
+
+	buf, err := encode(encoderFunc(func(pe packetEncoder) error {
+		return putTaggedFields(pe, fields)
+	}), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf, expected) {
+		t.Fatalf("expected %v, got %v", expected, buf)
+	}
+
+	var s string
+	pd := &realDecoder{raw: append(expected, 0xff)}
+	err = getTaggedFields(pd, func(tag uint64, pd packetDecoder) (err error) {
+		// tag 3 is left undecoded, as an unknown tag would be
+		if tag == 1 {
+			s, err = pd.getCompactString()
+		}
+		return err
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s != "ab" {
+		t.Errorf("expected the tagged field ab, got %q", s)
+	}
+	if pd.remaining() != 1 {
+		t.Errorf("expected the tagged fields to be consumed, %d bytes remaining", pd.remaining())
+	}
+}
@@ -0,0 +1,199 @@
+package sarama
+
+import (
+	"errors"
+	"math/rand"
+	"sync"
+	"time"
+)
+
+// defaultTelemetryInterval is how long the telemetryReporter waits before
+// asking for the subscription again when a broker could not tell it, as the
+// default push interval of KIP-714.
+const defaultTelemetryInterval = 5 * time.Minute
+
+// telemetryReporter pushes the metrics of a client to the brokers, as they
+// subscribe to them with client metrics subscriptions, see KIP-714. It asks
+// the least loaded broker for the subscription, and pushes the metrics
+// requested to that broker at the interval requested, until the client is
+// closed, when it pushes them a last time.
+type telemetryReporter struct {
+	client *client
+
+	instanceID   Uuid
+	broker       *Broker
+	subscription *GetTelemetrySubscriptionsResponse
+	// start is when the sums pushed start: when the client was created, or
+	// the previous push with delta temporality
+	start time.Time
+	// previous are the values of the sums of the previous push, by series,
+	// to push their deltas
+	previous map[string]int64
+
+	closer, done chan none
+	closeOnce    sync.Once
+}
+
+func newTelemetryReporter(client *client) *telemetryReporter {
+	return &telemetryReporter{
+		client: client,
+		start:  time.Now(),
+		closer: make(chan none),
+		done:   make(chan none),
+	}
+}
+
+func (t *telemetryReporter) run() {
+	defer close(t.done)
+
+	wait := time.Duration(0)
+	for {
+		timer := time.NewTimer(wait)
+		select {
+		case <-t.closer:
+			timer.Stop()
+			if t.subscription != nil {
+				t.push(true)
+			}
+			return
+		case <-timer.C:
+		}
+
+		if t.subscription == nil {
+			wait = t.subscribe()
+		} else {
+			wait = t.push(false)
+		}
+		if wait < 0 {
+			return
+		}
+	}
+}
+
+// close pushes the metrics a last time, and stops pushing them.
+func (t *telemetryReporter) close() {
+	t.closeOnce.Do(func() {
+		close(t.closer)
+	})
+	<-t.done
+}
+
+// subscribe gets the subscription of the client, returning how long to wait
+// before pushing the metrics, or asking for the subscription again, and -1
+// when the brokers do not support the client metrics.
+func (t *telemetryReporter) subscribe() time.Duration {
+	if t.broker == nil {
+		t.broker = t.client.LeastLoadedBroker()
+		if t.broker == nil {
+			return t.client.conf.Metadata.Retry.Backoff
+		}
+	}
+
+	response, err := t.broker.GetTelemetrySubscriptions(&GetTelemetrySubscriptionsRequest{ClientInstanceID: t.instanceID})
+	if err != nil {
+		var unsupported UnsupportedApiVersionError
+		if errors.As(err, &unsupported) || errors.Is(err, ErrUnsupportedVersion) {
+			debugf("client/telemetry the brokers do not support client metrics\n")
+			return -1
+		}
+		t.broker.log().debugf("client/telemetry failed to get the subscription from broker %d: %v\n", t.broker.ID(), err)
+		t.broker = nil
+		return t.client.conf.Metadata.Retry.Backoff
+	}
+	if response.Err != ErrNoError {
+		t.broker.log().debugf("client/telemetry failed to get the subscription from broker %d: %v\n", t.broker.ID(), response.Err)
+		return defaultTelemetryInterval
+	}
+
+	t.instanceID = response.ClientInstanceID
+	interval := time.Duration(response.PushIntervalMs) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultTelemetryInterval
+	}
+	if len(response.RequestedMetrics) == 0 {
+		// no metrics requested, check again for a new subscription later
+		return interval
+	}
+
+	t.subscription = response
+	if t.previous == nil {
+		// spread the first pushes of the clients started at the same time
+		return time.Duration(float64(interval) * (0.5 + rand.Float64()))
+	}
+	return interval
+}
+
+// push pushes the metrics requested by the subscription, returning how long
+// to wait before pushing them, or asking for the subscription again, and -1
+// when the broker rejects the metrics for good.
+func (t *telemetryReporter) push(terminating bool) time.Duration {
+	subscription := t.subscription
+	interval := time.Duration(subscription.PushIntervalMs) * time.Millisecond
+	if interval <= 0 {
+		interval = defaultTelemetryInterval
+	}
+
+	now := time.Now()
+	collected := collectTelemetryMetrics(t.client.conf.MetricRegistry, subscription.RequestedMetrics)
+	previous := make(map[string]int64)
+	for _, m := range collected {
+		if !m.sum {
+			continue
+		}
+		for i := range m.points {
+			key := telemetrySeriesKey(m.name, m.points[i].attributes)
+			previous[key] = m.points[i].value
+			if subscription.DeltaTemporality {
+				m.points[i].value -= t.previous[key]
+			}
+		}
+	}
+	payload := encodeTelemetryMetrics(collected, subscription.DeltaTemporality, t.start, now)
+	if subscription.TelemetryMaxBytes > 0 && len(payload) > int(subscription.TelemetryMaxBytes) {
+		warnf("client/telemetry the metrics take %d bytes, more than the %d the brokers accept\n", len(payload), subscription.TelemetryMaxBytes)
+		return interval
+	}
+
+	request := &PushTelemetryRequest{
+		ClientInstanceID: t.instanceID,
+		SubscriptionID:   subscription.SubscriptionID,
+		Terminating:      terminating,
+		CompressionType:  CompressionNone,
+		Metrics:          payload,
+	}
+	for _, codec := range subscription.AcceptedCompressionTypes {
+		if codec == CompressionNone {
+			break
+		}
+		if compressed, err := compress(codec, CompressionLevelDefault, compressionOptions{}, payload); err == nil {
+			request.CompressionType, request.Metrics = codec, compressed
+			break
+		}
+	}
+
+	response, err := t.broker.PushTelemetry(request)
+	if err != nil {
+		t.broker.log().debugf("client/telemetry failed to push the metrics to broker %d: %v\n", t.broker.ID(), err)
+		t.broker, t.subscription = nil, nil
+		return t.client.conf.Metadata.Retry.Backoff
+	}
+
+	switch response.Err {
+	case ErrNoError:
+		t.previous = previous
+		if subscription.DeltaTemporality {
+			t.start = now
+		}
+		return interval
+	case ErrUnknownSubscriptionID, ErrUnsupportedCompressionType:
+		// the subscriptions changed, get the new one straight away
+		t.subscription = nil
+		return 0
+	case ErrInvalidRequest, ErrInvalidRecord:
+		warnf("client/telemetry the brokers rejected the metrics, no longer pushing them: %v\n", response.Err)
+		return -1
+	default:
+		t.broker.log().debugf("client/telemetry failed to push the metrics to broker %d: %v\n", t.broker.ID(), response.Err)
+		return interval
+	}
+}
@@ -0,0 +1,314 @@
+package sarama
+
+import (
+	"encoding/binary"
+	"math"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+// telemetryMetricPrefix is the prefix of the names of the metrics pushed to
+// the brokers, as the standard client metrics of KIP-714.
+const telemetryMetricPrefix = "org.apache.kafka.client."
+
+// telemetryLabelledPrefixes are the names of the metrics of MetricRegistry
+// followed by a label, and the attribute the label is pushed as.
+var telemetryLabelledPrefixes = []struct {
+	prefix, attribute string
+}{
+	{"api-latency-in-ms-", "api"},
+	{"api-error-rate-", "api"},
+	{"api-throttle-time-in-ms-", "api"},
+	{"compression-ratio-", "compression.type"},
+	{"consumer-group-join-total-", "group.id"},
+	{"consumer-group-join-failed-", "group.id"},
+	{"consumer-group-sync-total-", "group.id"},
+	{"consumer-group-sync-failed-", "group.id"},
+}
+
+// telemetryAttribute is an attribute of a telemetryPoint.
+type telemetryAttribute struct {
+	key, value string
+}
+
+// telemetryPoint is a value of a metric pushed to the brokers, for a set of
+// attributes, e.g. the broker it is about.
+type telemetryPoint struct {
+	attributes []telemetryAttribute
+	value      int64
+	double     float64
+	isDouble   bool
+}
+
+// telemetryMetric is a metric pushed to the brokers: either a monotonic sum,
+// e.g. of the requests sent, or a gauge.
+type telemetryMetric struct {
+	name   string
+	sum    bool
+	points []telemetryPoint
+}
+
+// telemetryName returns the name of the metric name of MetricRegistry when
+// pushed to the brokers, e.g. org.apache.kafka.client.request.latency.in.ms
+// for request-latency-in-ms-for-broker-1, and the attributes of its labels.
+func telemetryName(name string) (string, []telemetryAttribute) {
+	var attributes []telemetryAttribute
+	if i := strings.Index(name, "-for-topic-"); i >= 0 {
+		attributes = append(attributes, telemetryAttribute{"topic", name[i+len("-for-topic-"):]})
+		name = name[:i]
+	}
+	if i := strings.Index(name, "-for-broker-"); i >= 0 {
+		attributes = append(attributes, telemetryAttribute{"node.id", name[i+len("-for-broker-"):]})
+		name = name[:i]
+	}
+	for _, labelled := range telemetryLabelledPrefixes {
+		if strings.HasPrefix(name, labelled.prefix) {
+			attributes = append(attributes, telemetryAttribute{labelled.attribute, name[len(labelled.prefix):]})
+			name = strings.TrimSuffix(labelled.prefix, "-")
+			break
+		}
+	}
+	sort.Slice(attributes, func(i, j int) bool { return attributes[i].key < attributes[j].key })
+	return telemetryMetricPrefix + strings.Replace(name, "-", ".", -1), attributes
+}
+
+// collectTelemetryMetrics returns the metrics of registry whose names start
+// with one of the requested prefixes. The meters are pushed as sums of their
+// counts, named "*.total" rather than "*.rate", the histograms as gauges of
+// their mean and maximum, named "*.avg" and "*.max", and the counters and
+// gauges as gauges.
+func collectTelemetryMetrics(registry metrics.Registry, requested []string) []*telemetryMetric {
+	byName := make(map[string]*telemetryMetric)
+	add := func(name string, sum bool, attributes []telemetryAttribute, point telemetryPoint) {
+		if !telemetryRequested(name, requested) {
+			return
+		}
+		m := byName[name]
+		if m == nil {
+			m = &telemetryMetric{name: name, sum: sum}
+			byName[name] = m
+		}
+		point.attributes = attributes
+		m.points = append(m.points, point)
+	}
+
+	registry.Each(func(registered string, metric interface{}) {
+		name, attributes := telemetryName(registered)
+		switch metric := metric.(type) {
+		case metrics.Meter:
+			add(strings.TrimSuffix(name, ".rate")+".total", true, attributes, telemetryPoint{value: metric.Count()})
+		case metrics.Histogram:
+			snapshot := metric.Snapshot()
+			add(name+".avg", false, attributes, telemetryPoint{double: snapshot.Mean(), isDouble: true})
+			add(name+".max", false, attributes, telemetryPoint{value: snapshot.Max()})
+		case metrics.Counter:
+			add(name, false, attributes, telemetryPoint{value: metric.Count()})
+		case metrics.Gauge:
+			add(name, false, attributes, telemetryPoint{value: metric.Value()})
+		case metrics.GaugeFloat64:
+			add(name, false, attributes, telemetryPoint{double: metric.Value(), isDouble: true})
+		}
+	})
+
+	collected := make([]*telemetryMetric, 0, len(byName))
+	for _, m := range byName {
+		sort.Slice(m.points, func(i, j int) bool {
+			return telemetryAttributesLess(m.points[i].attributes, m.points[j].attributes)
+		})
+		collected = append(collected, m)
+	}
+	sort.Slice(collected, func(i, j int) bool { return collected[i].name < collected[j].name })
+	return collected
+}
+
+// telemetryRequested reports whether the metric name is requested by the
+// prefixes of a subscription: none when empty, all when one is empty.
+func telemetryRequested(name string, requested []string) bool {
+	for _, prefix := range requested {
+		if strings.HasPrefix(name, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func telemetryAttributesLess(a, b []telemetryAttribute) bool {
+	for i := 0; i < len(a) && i < len(b); i++ {
+		if a[i] != b[i] {
+			if a[i].key != b[i].key {
+				return a[i].key < b[i].key
+			}
+			return a[i].value < b[i].value
+		}
+	}
+	return len(a) < len(b)
+}
+
+// telemetrySeriesKey identifies the series of a point, to compute the deltas
+// of the sums between pushes.
+func telemetrySeriesKey(name string, attributes []telemetryAttribute) string {
+	var b strings.Builder
+	b.WriteString(name)
+	for _, attribute := range attributes {
+		b.WriteByte(0)
+		b.WriteString(attribute.key)
+		b.WriteByte('=')
+		b.WriteString(attribute.value)
+	}
+	return b.String()
+}
+
+// The field numbers and values of the OpenTelemetry protobuf messages.
+const (
+	otlpMetricsDataResourceMetrics = 1
+
+	otlpResourceMetricsScopeMetrics = 2
+
+	otlpScopeMetricsScope   = 1
+	otlpScopeMetricsMetrics = 2
+
+	otlpScopeName = 1
+
+	otlpMetricName  = 1
+	otlpMetricGauge = 5
+	otlpMetricSum   = 7
+
+	otlpGaugeDataPoints = 1
+
+	otlpSumDataPoints             = 1
+	otlpSumAggregationTemporality = 2
+	otlpSumIsMonotonic            = 3
+
+	otlpPointStartTime  = 2
+	otlpPointTime       = 3
+	otlpPointAsDouble   = 4
+	otlpPointAsInt      = 6
+	otlpPointAttributes = 7
+
+	otlpKeyValueKey   = 1
+	otlpKeyValueValue = 2
+
+	otlpAnyValueString = 1
+
+	otlpTemporalityDelta      = 1
+	otlpTemporalityCumulative = 2
+)
+
+// encodeTelemetryMetrics encodes metrics as an OpenTelemetry MetricsData
+// protobuf message, the sums starting at start, and the points taken at now.
+func encodeTelemetryMetrics(metrics []*telemetryMetric, delta bool, start, now time.Time) []byte {
+	temporality := uint64(otlpTemporalityCumulative)
+	if delta {
+		temporality = otlpTemporalityDelta
+	}
+
+	var scopeMetrics protoWriter
+	scopeMetrics.message(otlpScopeMetricsScope, func(w *protoWriter) {
+		w.stringField(otlpScopeName, "sarama")
+	})
+	for _, m := range metrics {
+		m := m
+		scopeMetrics.message(otlpScopeMetricsMetrics, func(w *protoWriter) {
+			w.stringField(otlpMetricName, m.name)
+			if !m.sum {
+				w.message(otlpMetricGauge, func(w *protoWriter) {
+					for _, point := range m.points {
+						encodeTelemetryPoint(w, otlpGaugeDataPoints, point, time.Time{}, now)
+					}
+				})
+				return
+			}
+			w.message(otlpMetricSum, func(w *protoWriter) {
+				for _, point := range m.points {
+					encodeTelemetryPoint(w, otlpSumDataPoints, point, start, now)
+				}
+				w.varintField(otlpSumAggregationTemporality, temporality)
+				w.varintField(otlpSumIsMonotonic, 1)
+			})
+		})
+	}
+
+	var data protoWriter
+	data.message(otlpMetricsDataResourceMetrics, func(w *protoWriter) {
+		w.bytesField(otlpResourceMetricsScopeMetrics, scopeMetrics.buf)
+	})
+	return data.buf
+}
+
+func encodeTelemetryPoint(w *protoWriter, field int, point telemetryPoint, start, now time.Time) {
+	w.message(field, func(w *protoWriter) {
+		if !start.IsZero() {
+			w.fixed64Field(otlpPointStartTime, uint64(start.UnixNano()))
+		}
+		w.fixed64Field(otlpPointTime, uint64(now.UnixNano()))
+		if point.isDouble {
+			w.fixed64Field(otlpPointAsDouble, math.Float64bits(point.double))
+		} else {
+			w.fixed64Field(otlpPointAsInt, uint64(point.value))
+		}
+		for _, attribute := range point.attributes {
+			attribute := attribute
+			w.message(otlpPointAttributes, func(w *protoWriter) {
+				w.stringField(otlpKeyValueKey, attribute.key)
+				w.message(otlpKeyValueValue, func(w *protoWriter) {
+					w.stringField(otlpAnyValueString, attribute.value)
+				})
+			})
+		}
+	})
+}
+
+// protoWriter encodes protobuf messages, as much as needed by the OpenTelemetry
+// metrics.
+type protoWriter struct {
+	buf []byte
+}
+
+const (
+	protoWireVarint  = 0
+	protoWireFixed64 = 1
+	protoWireBytes   = 2
+)
+
+func (w *protoWriter) varint(v uint64) {
+	var tmp [binary.MaxVarintLen64]byte
+	w.buf = append(w.buf, tmp[:binary.PutUvarint(tmp[:], v)]...)
+}
+
+func (w *protoWriter) tag(field, wireType int) {
+	w.varint(uint64(field)<<3 | uint64(wireType))
+}
+
+func (w *protoWriter) varintField(field int, v uint64) {
+	w.tag(field, protoWireVarint)
+	w.varint(v)
+}
+
+func (w *protoWriter) fixed64Field(field int, v uint64) {
+	w.tag(field, protoWireFixed64)
+	var tmp [8]byte
+	binary.LittleEndian.PutUint64(tmp[:], v)
+	w.buf = append(w.buf, tmp[:]...)
+}
+
+func (w *protoWriter) bytesField(field int, b []byte) {
+	w.tag(field, protoWireBytes)
+	w.varint(uint64(len(b)))
+	w.buf = append(w.buf, b...)
+}
+
+func (w *protoWriter) stringField(field int, s string) {
+	w.tag(field, protoWireBytes)
+	w.varint(uint64(len(s)))
+	w.buf = append(w.buf, s...)
+}
+
+// message encodes the embedded message written by encode as field.
+func (w *protoWriter) message(field int, encode func(w *protoWriter)) {
+	var embedded protoWriter
+	encode(&embedded)
+	w.bytesField(field, embedded.buf)
+}
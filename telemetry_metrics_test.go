@@ -0,0 +1,88 @@
+package sarama
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/rcrowley/go-metrics"
+)
+
+func TestTelemetryName(t *testing.T) {
+	for _, tt := range []struct {
+		name       string
+		expected   string
+		attributes []telemetryAttribute
+	}{
+		{"request-rate", "org.apache.kafka.client.request.rate", nil},
+		{"request-latency-in-ms-for-broker-1", "org.apache.kafka.client.request.latency.in.ms", []telemetryAttribute{{"node.id", "1"}}},
+		{"record-send-rate-for-topic-my_topic", "org.apache.kafka.client.record.send.rate", []telemetryAttribute{{"topic", "my_topic"}}},
+		{"api-error-rate-Fetch-for-broker-2", "org.apache.kafka.client.api.error.rate", []telemetryAttribute{{"api", "Fetch"}, {"node.id", "2"}}},
+		{"consumer-group-sync-failed-my-group", "org.apache.kafka.client.consumer.group.sync.failed", []telemetryAttribute{{"group.id", "my-group"}}},
+		{"compression-ratio-zstd-for-topic-my_topic", "org.apache.kafka.client.compression.ratio", []telemetryAttribute{{"compression.type", "zstd"}, {"topic", "my_topic"}}},
+	} {
+		name, attributes := telemetryName(tt.name)
+		if name != tt.expected || !reflect.DeepEqual(attributes, tt.attributes) {
+			t.Errorf("%s: expected %s %v, got %s %v", tt.name, tt.expected, tt.attributes, name, attributes)
+		}
+	}
+}
+
+func TestCollectTelemetryMetrics(t *testing.T) {
+	registry := metrics.NewRegistry()
+	metrics.GetOrRegisterMeter("request-rate-for-broker-1", registry).Mark(3)
+	metrics.GetOrRegisterMeter("request-rate-for-broker-0", registry).Mark(1)
+	getOrRegisterHistogram("api-latency-in-ms-Produce-for-broker-1", registry).Update(10)
+	metrics.GetOrRegisterCounter("consumer-group-join-total-my_group", registry).Inc(2)
+	metrics.GetOrRegisterCounter("requests-in-flight", registry).Inc(1)
+	metrics.GetOrRegisterMeter("incoming-byte-rate", registry).Mark(100)
+
+	collected := collectTelemetryMetrics(registry, []string{
+		"org.apache.kafka.client.request", "org.apache.kafka.client.api", "org.apache.kafka.client.consumer",
+	})
+	latencyAttributes := []telemetryAttribute{{"api", "Produce"}, {"node.id", "1"}}
+	expected := []*telemetryMetric{
+		{name: "org.apache.kafka.client.api.latency.in.ms.avg", points: []telemetryPoint{{attributes: latencyAttributes, double: 10, isDouble: true}}},
+		{name: "org.apache.kafka.client.api.latency.in.ms.max", points: []telemetryPoint{{attributes: latencyAttributes, value: 10}}},
+		{name: "org.apache.kafka.client.consumer.group.join.total", points: []telemetryPoint{{attributes: []telemetryAttribute{{"group.id", "my_group"}}, value: 2}}},
+		{name: "org.apache.kafka.client.request.total", sum: true, points: []telemetryPoint{
+			{attributes: []telemetryAttribute{{"node.id", "0"}}, value: 1},
+			{attributes: []telemetryAttribute{{"node.id", "1"}}, value: 3},
+		}},
+		{name: "org.apache.kafka.client.requests.in.flight", points: []telemetryPoint{{value: 1}}},
+	}
+	if !reflect.DeepEqual(collected, expected) {
+		for _, m := range collected {
+			t.Logf("%+v", *m)
+		}
+		t.Error("unexpected metrics collected")
+	}
+
+	if collected := collectTelemetryMetrics(registry, nil); len(collected) != 0 {
+		t.Errorf("expected no metrics without requested prefixes, got %d", len(collected))
+	}
+	if collected := collectTelemetryMetrics(registry, []string{""}); len(collected) != 6 {
+		t.Errorf("expected all the metrics with an empty prefix, got %d", len(collected))
+	}
+}
+
+func TestEncodeTelemetryMetrics(t *testing.T) {
+	gauge := []*telemetryMetric{{name: "m", points: []telemetryPoint{{value: 5}}}}
+	encoded := encodeTelemetryMetrics(gauge, false, time.Time{}, time.Unix(0, 1))
+
+	expected := []byte{
+		0x0a, 39, // resource metrics
+		0x12, 37, // scope metrics
+		0x0a, 8, 0x0a, 6, 's', 'a', 'r', 'a', 'm', 'a', // scope named "sarama"
+		0x12, 25, // metric
+		0x0a, 1, 'm', // named "m"
+		0x2a, 20, // gauge
+		0x0a, 18, // data point
+		0x19, 1, 0, 0, 0, 0, 0, 0, 0, // time 1ns
+		0x31, 5, 0, 0, 0, 0, 0, 0, 0, // int value 5
+	}
+	if !bytes.Equal(encoded, expected) {
+		t.Errorf("expected\n%v\ngot\n%v", expected, encoded)
+	}
+}
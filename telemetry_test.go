@@ -0,0 +1,57 @@
+package sarama
+
+import (
+	"testing"
+	"time"
+)
+
+func TestClientPushesTelemetry(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"ApiVersionsRequest": NewMockApiVersionsResponse(t),
+		"MetadataRequest":    NewMockMetadataResponse(t).SetBroker(mb.Addr(), mb.BrokerID()),
+		"GetTelemetrySubscriptionsRequest": NewMockWrapper(&GetTelemetrySubscriptionsResponse{
+			ClientInstanceID: Uuid{1},
+			SubscriptionID:   7,
+			PushIntervalMs:   10,
+			RequestedMetrics: []string{"org.apache.kafka.client.request"},
+		}),
+		"PushTelemetryRequest": NewMockWrapper(&PushTelemetryResponse{}),
+	})
+
+	conf := NewTestConfig()
+	conf.Version = V3_7_0_0
+	conf.Telemetry.Enable = true
+	client, err := NewClient([]string{mb.Addr()}, conf)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushes := func() []*PushTelemetryRequest {
+		var pushes []*PushTelemetryRequest
+		for _, rr := range mb.History() {
+			if push, ok := rr.Request.(*PushTelemetryRequest); ok {
+				pushes = append(pushes, push)
+			}
+		}
+		return pushes
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for len(pushes()) < 2 && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	safeClose(t, client)
+
+	pushed := pushes()
+	if len(pushed) < 3 {
+		t.Fatalf("expected the metrics to be pushed at the interval and once closed, got %d pushes", len(pushed))
+	}
+	first, last := pushed[0], pushed[len(pushed)-1]
+	if first.ClientInstanceID != (Uuid{1}) || first.SubscriptionID != 7 || first.Terminating || len(first.Metrics) == 0 {
+		t.Errorf("unexpected push %+v", first)
+	}
+	if !last.Terminating {
+		t.Error("expected the last push to be terminating")
+	}
+}
@@ -0,0 +1,268 @@
+/*
+Package testkafka brings up the Kafka environment Sarama's functional tests run
+against, for other projects to run their own integration tests against the
+same versions of Kafka.
+
+The environment is a cluster of Kafka brokers, each reached through a proxy of
+toxiproxy so that the tests can inject network faults between the clients and
+the brokers. Either it already runs, TOXIPROXY_ADDR pointing at its toxiproxy
+and KAFKA_VERSION giving its version, or Start brings it up with
+docker-compose, from a compose file like Sarama's docker-compose.yml, of the
+version in KAFKA_VERSION (3.1.0 by default):
+
+  - the brokers are the services kafka-1 to kafka-N, listening on the ports
+    29091 to 29090+N and advertising them on localhost;
+  - toxiproxy is a service publishing its API on port 8474 and the ports of
+    the brokers, on which Start creates the proxies kafka1 to kafkaN.
+
+NOTE: this package does not fall under the API stability guarantee of Sarama as
+it is still considered experimental.
+*/
+package testkafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	toxiproxy "github.com/Shopify/toxiproxy/v2/client"
+)
+
+// DefaultKafkaVersion is the version of Kafka brought up when KAFKA_VERSION is
+// not set.
+const DefaultKafkaVersion = "3.1.0"
+
+// Options configures the environment brought up by Start.
+type Options struct {
+	// ComposeFile is the docker-compose file describing the environment
+	// (defaults to the docker-compose.yml of the working directory).
+	ComposeFile string
+	// Brokers is the number of brokers of the environment (defaults to 5).
+	Brokers int
+	// Ready returns nil once the environment brought up is ready for the
+	// tests, polled every second until ReadyTimeout. It defaults to checking
+	// that the ports of the brokers accept connections; a Kafka client should
+	// rather check that all the brokers joined the cluster.
+	Ready func(ctx context.Context, env *Environment) error
+	// ReadyTimeout is how long to wait for the environment to be ready
+	// (defaults to 90s).
+	ReadyTimeout time.Duration
+}
+
+// Environment is a Kafka environment the tests run against.
+type Environment struct {
+	// ToxiproxyClient is the client of the toxiproxy of the brokers.
+	ToxiproxyClient *toxiproxy.Client
+	// Proxies are the proxies of the brokers, by name: kafka1 to kafkaN.
+	Proxies map[string]*toxiproxy.Proxy
+	// KafkaBrokerAddrs are the addresses the clients reach the brokers at,
+	// through their proxies.
+	KafkaBrokerAddrs []string
+	// KafkaVersion is the version of Kafka of the brokers.
+	KafkaVersion string
+
+	opts Options
+	// docker is whether the environment was brought up with docker-compose,
+	// to tear it down on Close
+	docker bool
+}
+
+// Start connects to the environment TOXIPROXY_ADDR points at, else brings one
+// up with docker-compose, and waits for it to be ready.
+func Start(ctx context.Context, opts Options) (*Environment, error) {
+	if opts.Brokers <= 0 {
+		opts.Brokers = 5
+	}
+	if opts.ReadyTimeout <= 0 {
+		opts.ReadyTimeout = 90 * time.Second
+	}
+	if opts.Ready == nil {
+		opts.Ready = brokersListening
+	}
+	env := &Environment{opts: opts}
+
+	if toxiproxyAddr, ok := os.LookupEnv("TOXIPROXY_ADDR"); ok {
+		toxiproxyURL, err := url.Parse(toxiproxyAddr)
+		if err != nil {
+			return nil, fmt.Errorf("$TOXIPROXY_ADDR not parseable as url")
+		}
+		env.KafkaVersion, ok = os.LookupEnv("KAFKA_VERSION")
+		if !ok {
+			return nil, fmt.Errorf("KAFKA_VERSION needs to be provided with TOXIPROXY_ADDR")
+		}
+		if err := env.setupProxies(toxiproxyURL.String()); err != nil {
+			return nil, fmt.Errorf("failed to setup toxiproxies: %w", err)
+		}
+		return env, nil
+	}
+
+	env.docker = true
+	if err := env.up(ctx); err != nil {
+		_ = env.Close(ctx)
+		return nil, err
+	}
+	return env, nil
+}
+
+func (env *Environment) up(ctx context.Context) error {
+	// Always (try to) tear down first.
+	if err := env.down(ctx); err != nil {
+		return fmt.Errorf("failed to tear down existing env: %w", err)
+	}
+
+	env.KafkaVersion = DefaultKafkaVersion
+	if version, ok := os.LookupEnv("KAFKA_VERSION"); ok {
+		env.KafkaVersion = version
+	}
+	if err := env.compose(ctx, "up", "-d"); err != nil {
+		return fmt.Errorf("failed to run docker-compose to start test environment: %w", err)
+	}
+	if err := env.setupProxies("http://localhost:8474"); err != nil {
+		return fmt.Errorf("failed to setup toxiproxies: %w", err)
+	}
+
+	deadline := time.Now().Add(env.opts.ReadyTimeout)
+	for {
+		err := env.opts.Ready(ctx, env)
+		if err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("timed out waiting for the test environment to be ready: %w", err)
+		}
+		select {
+		case <-time.After(time.Second):
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// setupProxies configures the proxies of the brokers in the toxiproxy at
+// endpoint, unless they already exist.
+func (env *Environment) setupProxies(endpoint string) error {
+	env.ToxiproxyClient = toxiproxy.NewClient(endpoint)
+	env.Proxies = map[string]*toxiproxy.Proxy{}
+	env.KafkaBrokerAddrs = nil
+	for i := 1; i <= env.opts.Brokers; i++ {
+		proxyName := fmt.Sprintf("kafka%d", i)
+		proxy, err := env.ToxiproxyClient.Proxy(proxyName)
+		if err != nil {
+			proxy, err = env.ToxiproxyClient.CreateProxy(
+				proxyName,
+				fmt.Sprintf("0.0.0.0:%d", 29090+i),
+				fmt.Sprintf("kafka-%d:%d", i, 29090+i),
+			)
+			if err != nil {
+				return fmt.Errorf("failed to create toxiproxy: %w", err)
+			}
+		}
+		env.Proxies[proxyName] = proxy
+		env.KafkaBrokerAddrs = append(env.KafkaBrokerAddrs, fmt.Sprintf("127.0.0.1:%d", 29090+i))
+	}
+	return nil
+}
+
+// brokersListening is the default Options.Ready.
+func brokersListening(ctx context.Context, env *Environment) error {
+	for _, addr := range env.KafkaBrokerAddrs {
+		conn, err := (&net.Dialer{Timeout: time.Second}).DialContext(ctx, "tcp", addr)
+		if err != nil {
+			return err
+		}
+		_ = conn.Close()
+	}
+	return nil
+}
+
+// Close tears the environment down if Start brought it up.
+func (env *Environment) Close(ctx context.Context) error {
+	if !env.docker {
+		return nil
+	}
+	return env.down(ctx)
+}
+
+func (env *Environment) down(ctx context.Context) error {
+	downErr := env.compose(ctx, "down", "--volumes")
+	rmErr := env.compose(ctx, "rm", "-v", "--force", "--stop")
+	if downErr != nil {
+		return fmt.Errorf("failed to run docker-compose to stop test environment: %w", downErr)
+	}
+	if rmErr != nil {
+		return fmt.Errorf("failed to run docker-compose to rm test environment: %w", rmErr)
+	}
+	return nil
+}
+
+// StartBroker starts the broker of brokerID again, once stopped by StopBroker.
+func (env *Environment) StartBroker(ctx context.Context, brokerID int32) error {
+	if err := env.compose(ctx, "start", fmt.Sprintf("kafka-%d", brokerID)); err != nil {
+		return fmt.Errorf("failed to run docker-compose to start test broker kafka-%d: %w", brokerID, err)
+	}
+	return nil
+}
+
+// StopBroker stops the broker of brokerID, for the tests to exercise a failure
+// of the broker rather than of the network to it.
+func (env *Environment) StopBroker(ctx context.Context, brokerID int32) error {
+	if err := env.compose(ctx, "stop", fmt.Sprintf("kafka-%d", brokerID)); err != nil {
+		return fmt.Errorf("failed to run docker-compose to stop test broker kafka-%d: %w", brokerID, err)
+	}
+	return nil
+}
+
+func (env *Environment) compose(ctx context.Context, args ...string) error {
+	if env.opts.ComposeFile != "" {
+		args = append([]string{"-f", env.opts.ComposeFile}, args...)
+	}
+	c := exec.CommandContext(ctx, "docker-compose", args...)
+	c.Stdout = os.Stdout
+	c.Stderr = os.Stderr
+	c.Env = append(os.Environ(), fmt.Sprintf("KAFKA_VERSION=%s", env.KafkaVersion))
+	return c.Run()
+}
+
+// ResetProxies removes the toxics of the proxies and enables them all again.
+func (env *Environment) ResetProxies() error {
+	return env.ToxiproxyClient.ResetState()
+}
+
+// KafkaVersionAtLeast returns whether the version of Kafka of the environment
+// is required or a later one. An environment of an unknown version satisfies
+// none.
+func (env *Environment) KafkaVersionAtLeast(required string) bool {
+	if env.KafkaVersion == "" {
+		return false
+	}
+	return versionAtLeast(parseVersion(env.KafkaVersion), parseVersion(required))
+}
+
+func versionAtLeast(version, required []int) bool {
+	for i, v := range version {
+		var r int
+		if i < len(required) {
+			r = required[i]
+		}
+		if v != r {
+			return v > r
+		}
+	}
+	return true
+}
+
+func parseVersion(version string) []int {
+	numbers := strings.Split(version, ".")
+	result := make([]int, 0, len(numbers))
+	for _, number := range numbers {
+		nr, _ := strconv.Atoi(number)
+		result = append(result, nr)
+	}
+	return result
+}
@@ -0,0 +1,22 @@
+package testkafka
+
+import "testing"
+
+func TestKafkaVersionAtLeast(t *testing.T) {
+	for _, tc := range []struct {
+		version, required string
+		expected          bool
+	}{
+		{"3.1.0", "2.8.0", true},
+		{"3.1.0", "3.1.0", true},
+		{"3.1.0", "3.1", true},
+		{"2.8.1", "3.0.0", false},
+		{"3.1.0", "3.10.0", false},
+		{"", "0.10.0", false},
+	} {
+		env := &Environment{KafkaVersion: tc.version}
+		if actual := env.KafkaVersionAtLeast(tc.required); actual != tc.expected {
+			t.Errorf("expected %q to be at least %q: %v, got %v", tc.version, tc.required, tc.expected, actual)
+		}
+	}
+}
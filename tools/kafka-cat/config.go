@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/tools/sasl"
+)
+
+// propertiesFlag holds the properties set by repeated -X flags.
+type propertiesFlag map[string]string
+
+func (f propertiesFlag) String() string {
+	props := make([]string, 0, len(f))
+	for key, value := range f {
+		props = append(props, key+"="+value)
+	}
+	sort.Strings(props)
+	return strings.Join(props, ",")
+}
+
+func (f propertiesFlag) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("invalid property %q, should be key=value", value)
+	}
+	f[strings.TrimSpace(value[:i])] = value[i+1:]
+	return nil
+}
+
+// loadProperties returns the properties of the environment variables with
+// the prefix, unless empty, overridden by those of the file, unless empty,
+// overridden by the flags.
+func loadProperties(envPrefix, file string, flags propertiesFlag) (map[string]string, error) {
+	props := make(map[string]string)
+	if envPrefix != "" {
+		props = sarama.PropertiesFromEnv(envPrefix)
+	}
+	if file != "" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		fileProps, err := sarama.ReadProperties(f)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", file, err)
+		}
+		for key, value := range fileProps {
+			props[key] = value
+		}
+	}
+	for key, value := range flags {
+		props[key] = value
+	}
+	return props, nil
+}
+
+// newConfig returns the configuration of the properties and the addresses of
+// their bootstrap.servers, with the SCRAM clients of the tools, which have no
+// property.
+func newConfig(props map[string]string) (*sarama.Config, []string, error) {
+	config, addrs, err := sarama.NewConfigFromProperties(props)
+	if err != nil {
+		return nil, nil, err
+	}
+	mechanism := config.Net.SASL.Mechanism
+	if config.Net.SASL.Enable && (mechanism == sarama.SASLTypeSCRAMSHA256 || mechanism == sarama.SASLTypeSCRAMSHA512) {
+		if err := sasl.Configure(config, string(mechanism), config.Net.SASL.User, config.Net.SASL.Password); err != nil {
+			return nil, nil, err
+		}
+	}
+	return config, addrs, nil
+}
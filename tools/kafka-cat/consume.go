@@ -0,0 +1,224 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/signal"
+	"strconv"
+	"strings"
+	"sync"
+	"syscall"
+
+	"github.com/Shopify/sarama"
+)
+
+// consumeOptions are the options of the consume command.
+type consumeOptions struct {
+	topic      string
+	partitions string
+	offset     startOffset
+	// count is the number of messages to consume, 0 for no limit.
+	count int
+	// exit stops consuming the partitions at the end they had when starting.
+	exit bool
+}
+
+// startOffset is the offset the partitions are consumed from.
+type startOffset struct {
+	// offset is an offset, sarama.OffsetOldest or sarama.OffsetNewest, or
+	// the number of messages before the end when relative.
+	offset   int64
+	relative bool
+}
+
+// parseStartOffset parses oldest, newest, an offset, or a negative number of
+// messages before the end.
+func parseStartOffset(s string) (startOffset, error) {
+	switch s {
+	case "oldest", "beginning":
+		return startOffset{offset: sarama.OffsetOldest}, nil
+	case "newest", "end":
+		return startOffset{offset: sarama.OffsetNewest}, nil
+	}
+	offset, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return startOffset{}, fmt.Errorf("invalid offset %q, should be oldest, newest, an offset or a negative number of messages before the end", s)
+	}
+	if offset < 0 {
+		return startOffset{offset: -offset, relative: true}, nil
+	}
+	return startOffset{offset: offset}, nil
+}
+
+// resolve returns the offset to consume the partition from, between its
+// oldest and newest offsets.
+func (o startOffset) resolve(oldest, newest int64) int64 {
+	switch {
+	case o.relative:
+		if newest-o.offset < oldest {
+			return oldest
+		}
+		return newest - o.offset
+	case o.offset == sarama.OffsetOldest:
+		return oldest
+	case o.offset == sarama.OffsetNewest:
+		return newest
+	default:
+		return o.offset
+	}
+}
+
+func runConsume(args []string, config *sarama.Config) func(client sarama.Client) error {
+	flags := commandFlags("consume")
+	topic := requireTopic(flags, "the topic to consume")
+	partitions := flags.String("partitions", "all", "The partitions to consume, can be all or comma separated numbers")
+	offset := flags.String("offset", "newest", "The offset to start with. Can be oldest, newest, an offset, or a negative number of messages before the end, e.g. -10")
+	count := flags.Int("count", 0, "The number of messages to consume before exiting, 0 for no limit")
+	exit := flags.Bool("exit", false, "Whether to exit once the messages the partitions had when starting are consumed")
+	format := flags.String("format", `%s\n`, "The format of the messages, with the tokens "+formatUsage())
+	jsonOutput := flags.Bool("json", false, "Whether to print the messages as JSON objects, one per line, rather than in -format")
+	_ = flags.Parse(args)
+
+	opts := consumeOptions{topic: topic(), partitions: *partitions, count: *count, exit: *exit}
+	var err error
+	if opts.offset, err = parseStartOffset(*offset); err != nil {
+		printCommandUsageErrorAndExit(flags, err.Error())
+	}
+	if *count < 0 {
+		printCommandUsageErrorAndExit(flags, "-count must not be negative")
+	}
+	p := &messagePrinter{json: *jsonOutput}
+	if p.format, err = parseFormat(*format); err != nil {
+		printCommandUsageErrorAndExit(flags, err.Error())
+	}
+
+	return func(client sarama.Client) error {
+		closing := make(chan struct{})
+		go func() {
+			signals := make(chan os.Signal, 1)
+			signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+			<-signals
+			close(closing)
+		}()
+
+		w := bufio.NewWriter(os.Stdout)
+		p.w = w
+		err := consume(client, p, opts, closing)
+		if flushErr := w.Flush(); err == nil {
+			err = flushErr
+		}
+		return err
+	}
+}
+
+// consume prints the messages of the partitions of the topic until closing
+// is closed, or the options say to stop.
+func consume(client sarama.Client, p *messagePrinter, opts consumeOptions, closing <-chan struct{}) error {
+	partitions, err := topicPartitions(client, opts.topic, opts.partitions)
+	if err != nil {
+		return err
+	}
+	consumer, err := sarama.NewConsumerFromClient(client)
+	if err != nil {
+		return fmt.Errorf("failed to start the consumer: %w", err)
+	}
+	defer func() {
+		if err := consumer.Close(); err != nil {
+			logger.Println("Failed to close the consumer:", err)
+		}
+	}()
+
+	var (
+		messages  = make(chan *sarama.ConsumerMessage)
+		consumers = make(map[int32]sarama.PartitionConsumer, len(partitions))
+		// ends are the offsets to stop at with opts.exit
+		ends = make(map[int32]int64, len(partitions))
+		wg   sync.WaitGroup
+	)
+	closeAll := func() {
+		for _, pc := range consumers {
+			pc.AsyncClose()
+		}
+	}
+	for _, partition := range partitions {
+		oldest, err := client.GetOffset(opts.topic, partition, sarama.OffsetOldest)
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("failed to get the oldest offset of partition %d: %w", partition, err)
+		}
+		newest, err := client.GetOffset(opts.topic, partition, sarama.OffsetNewest)
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("failed to get the newest offset of partition %d: %w", partition, err)
+		}
+		start := opts.offset.resolve(oldest, newest)
+		if opts.exit && start >= newest {
+			continue
+		}
+		ends[partition] = newest
+
+		pc, err := consumer.ConsumePartition(opts.topic, partition, start)
+		if err != nil {
+			closeAll()
+			return fmt.Errorf("failed to consume partition %d: %w", partition, err)
+		}
+		consumers[partition] = pc
+		wg.Add(1)
+		go func(pc sarama.PartitionConsumer) {
+			defer wg.Done()
+			for msg := range pc.Messages() {
+				messages <- msg
+			}
+		}(pc)
+	}
+	go func() {
+		wg.Wait()
+		close(messages)
+	}()
+
+	printed := 0
+	for len(consumers) > 0 {
+		var msg *sarama.ConsumerMessage
+		select {
+		case msg = <-messages:
+		case <-closing:
+		}
+		if msg == nil {
+			break
+		}
+		if err = p.print(msg); err != nil {
+			break
+		}
+		if printed++; opts.count > 0 && printed == opts.count {
+			break
+		}
+		if opts.exit && msg.Offset+1 >= ends[msg.Partition] {
+			consumers[msg.Partition].AsyncClose()
+			delete(consumers, msg.Partition)
+		}
+	}
+
+	closeAll()
+	for range messages {
+		// drained for the partition consumers to close
+	}
+	return err
+}
+
+// topicPartitions returns the partitions of the topic, all of them or the
+// comma separated ones.
+func topicPartitions(client sarama.Client, topic, partitions string) ([]int32, error) {
+	if partitions == "all" {
+		return client.Partitions(topic)
+	}
+	var ids []int32
+	for _, partition := range strings.Split(partitions, ",") {
+		id, err := strconv.ParseInt(strings.TrimSpace(partition), 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("invalid partition %q", partition)
+		}
+		ids = append(ids, int32(id))
+	}
+	return ids, nil
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// formatTokens are the tokens of the -format of the consume command, as in
+// kcat.
+var formatTokens = map[byte]string{
+	't': "topic",
+	'p': "partition",
+	'o': "offset",
+	'k': "key",
+	's': "value",
+	'K': "key length (-1 for null)",
+	'S': "value length (-1 for null)",
+	'T': "timestamp in milliseconds (-1 if unknown)",
+	'h': "headers (key=value separated by commas)",
+	'%': "percent sign",
+}
+
+var formatEscapes = strings.NewReplacer(`\n`, "\n", `\t`, "\t", `\r`, "\r", `\\`, `\`)
+
+// parseFormat returns the format with its escape sequences replaced,
+// failing on unknown tokens.
+func parseFormat(format string) (string, error) {
+	format = formatEscapes.Replace(format)
+	for i := 0; i < len(format); i++ {
+		if format[i] != '%' {
+			continue
+		}
+		i++
+		if i == len(format) {
+			return "", fmt.Errorf("format %q ends with %%", format)
+		}
+		if _, ok := formatTokens[format[i]]; !ok {
+			return "", fmt.Errorf("unknown token %%%c in format %q", format[i], format)
+		}
+	}
+	return format, nil
+}
+
+// formatUsage describes the tokens of the formats.
+func formatUsage() string {
+	tokens := make([]string, 0, len(formatTokens))
+	for _, token := range []byte("tpokshKST%") {
+		tokens = append(tokens, fmt.Sprintf("%%%c for the %s", token, formatTokens[token]))
+	}
+	return strings.Join(tokens, "; ")
+}
+
+// messagePrinter prints the messages as JSON lines, or in a format parsed by
+// parseFormat.
+type messagePrinter struct {
+	w      io.Writer
+	format string
+	json   bool
+}
+
+// jsonMessage is a message printed as JSON, one per line.
+type jsonMessage struct {
+	Topic     string       `json:"topic"`
+	Partition int32        `json:"partition"`
+	Offset    int64        `json:"offset"`
+	Timestamp *time.Time   `json:"timestamp,omitempty"`
+	Key       *string      `json:"key"`
+	Value     *string      `json:"value"`
+	Headers   []jsonHeader `json:"headers"`
+}
+
+// jsonHeader is a header of a jsonMessage, the headers being a list as their
+// keys can repeat.
+type jsonHeader struct {
+	Key   string  `json:"key"`
+	Value *string `json:"value"`
+}
+
+func (p *messagePrinter) print(msg *sarama.ConsumerMessage) error {
+	if p.json {
+		m := jsonMessage{
+			Topic:     msg.Topic,
+			Partition: msg.Partition,
+			Offset:    msg.Offset,
+			Key:       nullableString(msg.Key),
+			Value:     nullableString(msg.Value),
+			Headers:   make([]jsonHeader, len(msg.Headers)),
+		}
+		if !msg.Timestamp.IsZero() {
+			m.Timestamp = &msg.Timestamp
+		}
+		for i, header := range msg.Headers {
+			m.Headers[i] = jsonHeader{Key: string(header.Key), Value: nullableString(header.Value)}
+		}
+		return json.NewEncoder(p.w).Encode(m)
+	}
+
+	buf := make([]byte, 0, len(p.format)+len(msg.Key)+len(msg.Value))
+	for i := 0; i < len(p.format); i++ {
+		if p.format[i] != '%' {
+			buf = append(buf, p.format[i])
+			continue
+		}
+		i++
+		switch p.format[i] {
+		case 't':
+			buf = append(buf, msg.Topic...)
+		case 'p':
+			buf = strconv.AppendInt(buf, int64(msg.Partition), 10)
+		case 'o':
+			buf = strconv.AppendInt(buf, msg.Offset, 10)
+		case 'k':
+			buf = append(buf, msg.Key...)
+		case 's':
+			buf = append(buf, msg.Value...)
+		case 'K':
+			buf = strconv.AppendInt(buf, int64(nullableLength(msg.Key)), 10)
+		case 'S':
+			buf = strconv.AppendInt(buf, int64(nullableLength(msg.Value)), 10)
+		case 'T':
+			timestamp := int64(-1)
+			if !msg.Timestamp.IsZero() {
+				timestamp = msg.Timestamp.UnixNano() / int64(time.Millisecond)
+			}
+			buf = strconv.AppendInt(buf, timestamp, 10)
+		case 'h':
+			for j, header := range msg.Headers {
+				if j > 0 {
+					buf = append(buf, ',')
+				}
+				buf = append(buf, header.Key...)
+				buf = append(buf, '=')
+				buf = append(buf, header.Value...)
+			}
+		case '%':
+			buf = append(buf, '%')
+		}
+	}
+	_, err := p.w.Write(buf)
+	return err
+}
+
+func nullableString(b []byte) *string {
+	if b == nil {
+		return nil
+	}
+	s := string(b)
+	return &s
+}
+
+func nullableLength(b []byte) int {
+	if b == nil {
+		return -1
+	}
+	return len(b)
+}
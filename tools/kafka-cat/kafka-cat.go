@@ -0,0 +1,165 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+var (
+	brokerList = flag.String("brokers", os.Getenv("KAFKA_PEERS"), "The comma separated list of brokers in the Kafka cluster, overriding bootstrap.servers. You can also set the KAFKA_PEERS environment variable")
+	configFile = flag.String("config-file", "", "A properties file configuring the client, in the format of the Java client and librdkafka")
+	envPrefix  = flag.String("env-prefix", "KAFKA_", "The prefix of the environment variables setting properties, e.g. KAFKA_SECURITY_PROTOCOL for security.protocol. Empty to ignore the environment")
+	version    = flag.String("version", "", "The version of Kafka, overriding broker.version.fallback")
+	verbose    = flag.Bool("verbose", false, "Turn on sarama logging to stderr")
+
+	properties = make(propertiesFlag)
+
+	logger = log.New(os.Stderr, "", log.LstdFlags)
+)
+
+func init() {
+	flag.Var(properties, "X", "A property configuring the client, overriding the -config-file and the environment, e.g. -X security.protocol=SASL_SSL. Can be repeated")
+}
+
+// commands are the commands of the tool. Each parses its own flags, adjusting
+// the configuration of the client, and returns the function running it with
+// the client.
+var commands = map[string]func(args []string, config *sarama.Config) func(client sarama.Client) error{
+	"produce":       runProduce,
+	"consume":       runConsume,
+	"metadata":      runMetadata,
+	"query-offsets": runQueryOffsets,
+}
+
+// commandNames lists the commands in the order of the usage.
+var commandNames = []string{"produce", "consume", "metadata", "query-offsets"}
+
+var commandUsages = map[string]string{
+	"produce":       "Produce messages to a topic, one per line of the input",
+	"consume":       "Consume the messages of the partitions of a topic",
+	"metadata":      "List the brokers and the partitions of the topics",
+	"query-offsets": "Show the offsets of the partitions of a topic, at their ends or at a time",
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		printUsageErrorAndExit("no command specified")
+	}
+	parse, ok := commands[flag.Arg(0)]
+	if !ok {
+		printUsageErrorAndExit(fmt.Sprintf("unknown command %q", flag.Arg(0)))
+	}
+
+	if *verbose {
+		sarama.Logger = logger
+	}
+
+	props, err := loadProperties(*envPrefix, *configFile, properties)
+	if err != nil {
+		printErrorAndExit(66, "Failed to read the properties: %s", err)
+	}
+	config, addrs, err := newConfig(props)
+	if err != nil {
+		printUsageErrorAndExit(err.Error())
+	}
+	if *version != "" {
+		if config.Version, err = sarama.ParseKafkaVersion(*version); err != nil {
+			printUsageErrorAndExit(fmt.Sprintf("Invalid -version: %s", err))
+		}
+	}
+	command := parse(flag.Args()[1:], config)
+
+	if *brokerList != "" {
+		addrs = strings.Split(*brokerList, ",")
+	}
+	if len(addrs) == 0 {
+		printUsageErrorAndExit("no -brokers specified, nor bootstrap.servers")
+	}
+	if err := config.Validate(); err != nil {
+		printUsageErrorAndExit(err.Error())
+	}
+
+	client, err := sarama.NewClient(addrs, config)
+	if err != nil {
+		printErrorAndExit(69, "Failed to connect to the cluster: %s", err)
+	}
+	defer func() {
+		if err := client.Close(); err != nil {
+			logger.Println("Failed to close the client:", err)
+		}
+	}()
+
+	if err := command(client); err != nil {
+		_ = client.Close()
+		printErrorAndExit(69, "%s", err)
+	}
+}
+
+// commandFlags returns the flag set of the command, exiting with its usage
+// on parsing errors.
+func commandFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] %s [command options]\n\n", os.Args[0], name)
+		fmt.Fprintln(os.Stderr, commandUsages[name]+".")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Command options:")
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// requireTopic returns the -topic flag of the command, which is required.
+func requireTopic(flags *flag.FlagSet, usage string) func() string {
+	topic := flags.String("topic", "", "REQUIRED: "+usage)
+	return func() string {
+		if *topic == "" {
+			printCommandUsageErrorAndExit(flags, "no -topic specified")
+		}
+		return *topic
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [options] <command> [command options]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range commandNames {
+		fmt.Fprintf(os.Stderr, "  %-15s %s\n", name, commandUsages[name])
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintf(os.Stderr, "Run %s <command> -help for the options of a command.\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "The client is configured by the properties of the Java client and librdkafka")
+	fmt.Fprintln(os.Stderr, "set in the environment, then in the -config-file, then with -X, the later")
+	fmt.Fprintln(os.Stderr, "overriding the earlier.")
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Options:")
+	flag.PrintDefaults()
+}
+
+func printErrorAndExit(code int, format string, values ...interface{}) {
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", fmt.Sprintf(format, values...))
+	fmt.Fprintln(os.Stderr)
+	os.Exit(code)
+}
+
+func printUsageErrorAndExit(message string) {
+	fmt.Fprintln(os.Stderr, "ERROR:", message)
+	fmt.Fprintln(os.Stderr)
+	usage()
+	os.Exit(64)
+}
+
+func printCommandUsageErrorAndExit(flags *flag.FlagSet, message string) {
+	fmt.Fprintln(os.Stderr, "ERROR:", message)
+	fmt.Fprintln(os.Stderr)
+	flags.Usage()
+	os.Exit(64)
+}
@@ -0,0 +1,228 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestLoadProperties(t *testing.T) {
+	if err := os.Setenv("KAFKA_CAT_TEST_CLIENT_ID", "from-env"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("KAFKA_CAT_TEST_CLIENT_ID")
+	if err := os.Setenv("KAFKA_CAT_TEST_SASL_MECHANISM", "SCRAM-SHA-512"); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Unsetenv("KAFKA_CAT_TEST_SASL_MECHANISM")
+
+	file := filepath.Join(t.TempDir(), "client.properties")
+	content := "bootstrap.servers=broker1:9092\nsecurity.protocol=SASL_PLAINTEXT\nclient.id=from-file\nsasl.username=alice\n"
+	if err := os.WriteFile(file, []byte(content), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	flags := make(propertiesFlag)
+	for _, prop := range []string{"sasl.username=bob", "sasl.password=secret"} {
+		if err := flags.Set(prop); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	props, err := loadProperties("KAFKA_CAT_TEST_", file, flags)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if props["client.id"] != "from-file" || props["sasl.username"] != "bob" || props["sasl.mechanism"] != "SCRAM-SHA-512" {
+		t.Errorf("unexpected properties %v", props)
+	}
+
+	config, addrs, err := newConfig(props)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(addrs) != 1 || addrs[0] != "broker1:9092" {
+		t.Errorf("unexpected addresses %v", addrs)
+	}
+	if !config.Net.SASL.Enable || config.Net.SASL.User != "bob" || config.Net.SASL.SCRAMClientGeneratorFunc == nil {
+		t.Error("expected SASL/SCRAM to be configured with a SCRAM client")
+	}
+	if err := config.Validate(); err != nil {
+		t.Error(err)
+	}
+
+	if err := flags.Set("=value"); err == nil {
+		t.Error("expected a property without key to be rejected")
+	}
+}
+
+func TestMessagePrinter(t *testing.T) {
+	msg := &sarama.ConsumerMessage{
+		Topic:     "my_topic",
+		Partition: 1,
+		Offset:    42,
+		Timestamp: time.Unix(1600000000, 0),
+		Value:     []byte("value"),
+		Headers:   []*sarama.RecordHeader{{Key: []byte("a"), Value: []byte("1")}},
+	}
+
+	format, err := parseFormat(`%t/%p@%o %K:%k=%s (%S, %T, %h) 100%%\n`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	p := &messagePrinter{w: &buf, format: format}
+	if err := p.print(msg); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "my_topic/1@42 -1:=value (5, 1600000000000, a=1) 100%\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	p.json = true
+	if err := p.print(msg); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(buf.String(), `{"topic":"my_topic","partition":1,"offset":42,"timestamp":`) ||
+		!strings.HasSuffix(buf.String(), `"key":null,"value":"value","headers":[{"key":"a","value":"1"}]}`+"\n") {
+		t.Errorf("unexpected JSON %s", buf.String())
+	}
+
+	for _, invalid := range []string{"%x", "trailing %"} {
+		if _, err := parseFormat(invalid); err == nil {
+			t.Errorf("expected %q to be rejected", invalid)
+		}
+	}
+}
+
+func TestStartOffset(t *testing.T) {
+	for s, expected := range map[string]int64{"oldest": 10, "newest": 100, "50": 50, "-5": 95, "-500": 10} {
+		offset, err := parseStartOffset(s)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if resolved := offset.resolve(10, 100); resolved != expected {
+			t.Errorf("expected %s to start at %d, got %d", s, expected, resolved)
+		}
+	}
+	if _, err := parseStartOffset("latest"); err == nil {
+		t.Error("expected an invalid offset to be rejected")
+	}
+}
+
+func newTestClient(t *testing.T, handlers map[string]sarama.MockResponse) (sarama.Client, *sarama.MockBroker) {
+	broker := sarama.NewMockBroker(t, 1)
+	handlers["MetadataRequest"] = sarama.NewMockMetadataResponse(t).
+		SetController(broker.BrokerID()).
+		SetBroker(broker.Addr(), broker.BrokerID()).
+		SetLeader("my_topic", 0, broker.BrokerID()).
+		SetLeader("my_topic", 1, broker.BrokerID())
+	broker.SetHandlerByMap(handlers)
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V1_0_0_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return client, broker
+}
+
+func TestProduce(t *testing.T) {
+	client, broker := newTestClient(t, map[string]sarama.MockResponse{
+		"ProduceRequest": sarama.NewMockProduceResponse(t),
+	})
+	defer broker.Close()
+	defer client.Close()
+
+	opts := produceOptions{topic: "my_topic", partition: -1, keySeparator: "\t", nullEmpty: true}
+	if err := produce(client, strings.NewReader("k1\tv1\n\nv2\nk3\t\n"), opts); err != nil {
+		t.Fatal(err)
+	}
+
+	produced := false
+	for _, entry := range broker.History() {
+		if _, ok := entry.Request.(*sarama.ProduceRequest); ok {
+			produced = true
+		}
+	}
+	if !produced {
+		t.Error("expected the messages to be produced")
+	}
+
+	if msg := opts.message("k3\t"); msg.Key != sarama.StringEncoder("k3") || msg.Value != nil {
+		t.Errorf("expected a tombstone, got %+v", msg)
+	}
+	if msg := opts.message("no key"); msg.Key != nil || msg.Value != sarama.StringEncoder("no key") {
+		t.Errorf("expected a message without key, got %+v", msg)
+	}
+}
+
+func TestConsumeExit(t *testing.T) {
+	client, broker := newTestClient(t, map[string]sarama.MockResponse{
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("my_topic", 0, sarama.OffsetOldest, 0).
+			SetOffset("my_topic", 0, sarama.OffsetNewest, 2).
+			SetOffset("my_topic", 1, sarama.OffsetOldest, 0).
+			SetOffset("my_topic", 1, sarama.OffsetNewest, 0),
+		"FetchRequest": sarama.NewMockFetchResponse(t, 1).
+			SetMessage("my_topic", 0, 0, sarama.StringEncoder("first")).
+			SetMessage("my_topic", 0, 1, sarama.StringEncoder("second")).
+			SetHighWaterMark("my_topic", 0, 2),
+	})
+	defer broker.Close()
+	defer client.Close()
+
+	var buf bytes.Buffer
+	p := &messagePrinter{w: &buf, format: "%p:%o:%s\n"}
+	opts := consumeOptions{topic: "my_topic", partitions: "all", offset: startOffset{offset: sarama.OffsetOldest}, exit: true}
+	if err := consume(client, p, opts, make(chan struct{})); err != nil {
+		t.Fatal(err)
+	}
+	if expected := "0:0:first\n0:1:second\n"; buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestMetadataAndOffsets(t *testing.T) {
+	client, broker := newTestClient(t, map[string]sarama.MockResponse{
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("my_topic", 0, sarama.OffsetOldest, 3).
+			SetOffset("my_topic", 0, sarama.OffsetNewest, 7).
+			SetOffset("my_topic", 1, sarama.OffsetOldest, 0).
+			SetOffset("my_topic", 1, sarama.OffsetNewest, 5),
+	})
+	defer broker.Close()
+	defer client.Close()
+
+	metadata, err := getMetadata(client, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := printMetadata(&buf, metadata); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "1 brokers:\n  broker 1 at "+broker.Addr()+" (controller)\n") ||
+		!strings.Contains(buf.String(), "  topic \"my_topic\" with 2 partitions:\n    partition 0, leader 1") {
+		t.Errorf("unexpected metadata:\n%s", buf.String())
+	}
+
+	offsets, err := queryOffsets(client, "my_topic", "all", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := printOffsets(&buf, offsets, false); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 3 || strings.Join(strings.Fields(lines[1]), " ") != "my_topic 0 3 7" || strings.Join(strings.Fields(lines[2]), " ") != "my_topic 1 0 5" {
+		t.Errorf("unexpected offsets:\n%s", buf.String())
+	}
+}
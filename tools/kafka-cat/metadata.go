@@ -0,0 +1,282 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// clusterMetadata is the metadata printed by the metadata command.
+type clusterMetadata struct {
+	Brokers []brokerMetadata `json:"brokers"`
+	Topics  []topicMetadata  `json:"topics"`
+}
+
+type brokerMetadata struct {
+	ID         int32  `json:"id"`
+	Addr       string `json:"addr"`
+	Rack       string `json:"rack,omitempty"`
+	Controller bool   `json:"controller"`
+}
+
+type topicMetadata struct {
+	Topic      string              `json:"topic"`
+	Partitions []partitionMetadata `json:"partitions"`
+}
+
+type partitionMetadata struct {
+	Partition int32 `json:"partition"`
+	// Leader is -1 when the partition has none.
+	Leader          int32   `json:"leader"`
+	Replicas        []int32 `json:"replicas"`
+	ISR             []int32 `json:"isr"`
+	OfflineReplicas []int32 `json:"offline_replicas"`
+}
+
+func runMetadata(args []string, config *sarama.Config) func(client sarama.Client) error {
+	flags := commandFlags("metadata")
+	topics := flags.String("topic", "", "The comma separated list of the topics to list, all of them by default")
+	jsonOutput := flags.Bool("json", false, "Whether to print the metadata as JSON")
+	_ = flags.Parse(args)
+
+	var topicList []string
+	if *topics != "" {
+		topicList = strings.Split(*topics, ",")
+	}
+	// listing the topics must not create them
+	config.Metadata.AllowAutoTopicCreation = false
+	return func(client sarama.Client) error {
+		metadata, err := getMetadata(client, topicList)
+		if err != nil {
+			return err
+		}
+		if *jsonOutput {
+			return json.NewEncoder(os.Stdout).Encode(metadata)
+		}
+		return printMetadata(os.Stdout, metadata)
+	}
+}
+
+// getMetadata returns the brokers and the partitions of the topics, all of
+// them when topics is empty, sorted.
+func getMetadata(client sarama.Client, topics []string) (*clusterMetadata, error) {
+	if err := client.RefreshMetadata(topics...); err != nil {
+		return nil, fmt.Errorf("failed to get the metadata: %w", err)
+	}
+
+	// the controller is unknown before Kafka 0.10.0.0
+	controllerID := int32(-1)
+	if controller, err := client.Controller(); err == nil {
+		controllerID = controller.ID()
+	}
+	metadata := &clusterMetadata{}
+	for _, broker := range client.Brokers() {
+		metadata.Brokers = append(metadata.Brokers, brokerMetadata{
+			ID:         broker.ID(),
+			Addr:       broker.Addr(),
+			Rack:       broker.Rack(),
+			Controller: broker.ID() == controllerID,
+		})
+	}
+	sort.Slice(metadata.Brokers, func(i, j int) bool { return metadata.Brokers[i].ID < metadata.Brokers[j].ID })
+
+	if len(topics) == 0 {
+		var err error
+		if topics, err = client.Topics(); err != nil {
+			return nil, fmt.Errorf("failed to list the topics: %w", err)
+		}
+		sort.Strings(topics)
+	}
+	for _, topic := range topics {
+		partitions, err := client.Partitions(topic)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the partitions of topic %s: %w", topic, err)
+		}
+		t := topicMetadata{Topic: topic}
+		for _, partition := range partitions {
+			p := partitionMetadata{Partition: partition, Leader: -1}
+			if leader, err := client.Leader(topic, partition); err == nil {
+				p.Leader = leader.ID()
+			}
+			// the replicas come with errors such as ErrReplicaNotAvailable
+			// when some of them are offline
+			p.Replicas, _ = client.Replicas(topic, partition)
+			p.ISR, _ = client.InSyncReplicas(topic, partition)
+			p.OfflineReplicas, _ = client.OfflineReplicas(topic, partition)
+			t.Partitions = append(t.Partitions, p)
+		}
+		sort.Slice(t.Partitions, func(i, j int) bool { return t.Partitions[i].Partition < t.Partitions[j].Partition })
+		metadata.Topics = append(metadata.Topics, t)
+	}
+	return metadata, nil
+}
+
+// printMetadata prints the metadata in the layout of kcat -L.
+func printMetadata(w io.Writer, metadata *clusterMetadata) error {
+	fmt.Fprintf(w, "%d brokers:\n", len(metadata.Brokers))
+	for _, broker := range metadata.Brokers {
+		fmt.Fprintf(w, "  broker %d at %s", broker.ID, broker.Addr)
+		if broker.Rack != "" {
+			fmt.Fprintf(w, " in rack %s", broker.Rack)
+		}
+		if broker.Controller {
+			fmt.Fprint(w, " (controller)")
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "%d topics:\n", len(metadata.Topics))
+	for _, topic := range metadata.Topics {
+		fmt.Fprintf(w, "  topic %q with %d partitions:\n", topic.Topic, len(topic.Partitions))
+		for _, p := range topic.Partitions {
+			fmt.Fprintf(w, "    partition %d, leader %d, replicas: %s, isrs: %s", p.Partition, p.Leader, formatBrokers(p.Replicas), formatBrokers(p.ISR))
+			if len(p.OfflineReplicas) > 0 {
+				fmt.Fprintf(w, ", offline: %s", formatBrokers(p.OfflineReplicas))
+			}
+			if _, err := fmt.Fprintln(w); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// partitionOffsets are the offsets of a partition printed by the
+// query-offsets command, either its oldest and newest offsets or its offset
+// at a time.
+type partitionOffsets struct {
+	Topic     string `json:"topic"`
+	Partition int32  `json:"partition"`
+	Oldest    *int64 `json:"oldest,omitempty"`
+	Newest    *int64 `json:"newest,omitempty"`
+	Offset    *int64 `json:"offset,omitempty"`
+}
+
+func runQueryOffsets(args []string, config *sarama.Config) func(client sarama.Client) error {
+	flags := commandFlags("query-offsets")
+	topic := requireTopic(flags, "the topic to show the offsets of")
+	partitions := flags.String("partitions", "all", "The partitions to show the offsets of, can be all or comma separated numbers")
+	at := flags.String("time", "", "The time to show the offsets at, as RFC3339 or milliseconds since the epoch: the offsets of the first messages at or after it, -1 if none. The oldest and newest offsets by default")
+	jsonOutput := flags.Bool("json", false, "Whether to print the offsets as JSON objects, one per line")
+	_ = flags.Parse(args)
+
+	timestamp := int64(0)
+	if *at != "" {
+		var err error
+		if timestamp, err = parseTime(*at); err != nil {
+			printCommandUsageErrorAndExit(flags, err.Error())
+		}
+	}
+	name := topic()
+	config.Metadata.AllowAutoTopicCreation = false
+	return func(client sarama.Client) error {
+		offsets, err := queryOffsets(client, name, *partitions, timestamp)
+		if err != nil {
+			return err
+		}
+		return printOffsets(os.Stdout, offsets, *jsonOutput)
+	}
+}
+
+// parseTime parses an RFC3339 time or milliseconds since the epoch into
+// milliseconds.
+func parseTime(s string) (int64, error) {
+	if ms, err := strconv.ParseInt(s, 10, 64); err == nil && ms >= 0 {
+		return ms, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid time %q, should be RFC3339 or milliseconds since the epoch", s)
+	}
+	return t.UnixNano() / int64(time.Millisecond), nil
+}
+
+// queryOffsets returns the oldest and newest offsets of the partitions of the
+// topic, or their offsets at the timestamp in milliseconds unless 0.
+func queryOffsets(client sarama.Client, topic, partitions string, timestamp int64) ([]partitionOffsets, error) {
+	ids, err := topicPartitions(client, topic, partitions)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+
+	get := func(time int64) (map[int32]int64, error) {
+		offsets, err := client.GetOffsets(map[string][]int32{topic: ids}, time)
+		if err != nil {
+			return nil, fmt.Errorf("failed to get the offsets of topic %s: %w", topic, err)
+		}
+		return offsets[topic], nil
+	}
+	var results []partitionOffsets
+	if timestamp != 0 {
+		offsets, err := get(timestamp)
+		if err != nil {
+			return nil, err
+		}
+		for _, id := range ids {
+			offset := offsets[id]
+			results = append(results, partitionOffsets{Topic: topic, Partition: id, Offset: &offset})
+		}
+		return results, nil
+	}
+
+	oldest, err := get(sarama.OffsetOldest)
+	if err != nil {
+		return nil, err
+	}
+	newest, err := get(sarama.OffsetNewest)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		o, n := oldest[id], newest[id]
+		results = append(results, partitionOffsets{Topic: topic, Partition: id, Oldest: &o, Newest: &n})
+	}
+	return results, nil
+}
+
+// printOffsets prints the offsets as a table, or as JSON lines.
+func printOffsets(w io.Writer, offsets []partitionOffsets, jsonOutput bool) error {
+	if jsonOutput {
+		encoder := json.NewEncoder(w)
+		for _, o := range offsets {
+			if err := encoder.Encode(o); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if len(offsets) == 0 {
+		return errors.New("no partitions")
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	if offsets[0].Offset != nil {
+		fmt.Fprintln(tw, "TOPIC\tPARTITION\tOFFSET")
+		for _, o := range offsets {
+			fmt.Fprintf(tw, "%s\t%d\t%d\n", o.Topic, o.Partition, *o.Offset)
+		}
+	} else {
+		fmt.Fprintln(tw, "TOPIC\tPARTITION\tOLDEST\tNEWEST")
+		for _, o := range offsets {
+			fmt.Fprintf(tw, "%s\t%d\t%d\t%d\n", o.Topic, o.Partition, *o.Oldest, *o.Newest)
+		}
+	}
+	return tw.Flush()
+}
+
+func formatBrokers(ids []int32) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(int(id))
+	}
+	return strings.Join(s, ",")
+}
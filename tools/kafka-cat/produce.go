@@ -0,0 +1,126 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+// produceOptions are the options of the produce command.
+type produceOptions struct {
+	topic     string
+	partition int32
+	// key is the key of all the messages, unless keySeparator is set.
+	key          string
+	keySeparator string
+	headers      []sarama.RecordHeader
+	// nullEmpty produces the empty values as null values.
+	nullEmpty bool
+}
+
+func runProduce(args []string, config *sarama.Config) func(client sarama.Client) error {
+	flags := commandFlags("produce")
+	topic := requireTopic(flags, "the topic to produce to")
+	partition := flags.Int("partition", -1, "The partition to produce to, -1 to partition the messages by key")
+	key := flags.String("key", "", "The key of the messages, unless -key-separator is set")
+	keySeparator := flags.String("key-separator", "", "The separator of the key and the value of the lines of the input")
+	headers := flags.String("headers", "", "The headers of the messages. Example: -headers=foo:bar,bar:foo")
+	nullEmpty := flags.Bool("null-empty", false, "Whether to produce the empty values as null, e.g. as the tombstones of compacted topics")
+	file := flags.String("file", "", "The file to read the messages from, rather than stdin")
+	_ = flags.Parse(args)
+
+	opts := produceOptions{
+		topic:        topic(),
+		partition:    int32(*partition),
+		key:          *key,
+		keySeparator: *keySeparator,
+		nullEmpty:    *nullEmpty,
+	}
+	if *headers != "" {
+		for _, h := range strings.Split(*headers, ",") {
+			header := strings.Split(h, ":")
+			if len(header) != 2 {
+				printCommandUsageErrorAndExit(flags, "-headers should be key:value. Example: -headers=foo:bar,bar:foo")
+			}
+			opts.headers = append(opts.headers, sarama.RecordHeader{Key: []byte(header[0]), Value: []byte(header[1])})
+		}
+	}
+	if opts.partition >= 0 {
+		config.Producer.Partitioner = sarama.NewManualPartitioner
+	}
+
+	return func(client sarama.Client) error {
+		var input io.Reader = os.Stdin
+		if *file != "" {
+			f, err := os.Open(*file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			input = f
+		}
+		return produce(client, input, opts)
+	}
+}
+
+// produce produces a message per line of r, skipping the empty lines, and
+// waits for them to be acknowledged.
+func produce(client sarama.Client, r io.Reader, opts produceOptions) error {
+	producer, err := sarama.NewAsyncProducerFromClient(client)
+	if err != nil {
+		return fmt.Errorf("failed to start the producer: %w", err)
+	}
+
+	failed := 0
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for err := range producer.Errors() {
+			logger.Println("Failed to produce message:", err)
+			failed++
+		}
+	}()
+
+	produced := 0
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(nil, client.Config().Producer.MaxMessageBytes)
+	for scanner.Scan() {
+		if line := scanner.Text(); line != "" {
+			producer.Input() <- opts.message(line)
+			produced++
+		}
+	}
+	producer.AsyncClose()
+	<-done
+
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read the messages: %w", err)
+	}
+	if failed > 0 {
+		return fmt.Errorf("failed to produce %d of the %d messages", failed, produced)
+	}
+	return nil
+}
+
+// message returns the message of the line.
+func (opts produceOptions) message(line string) *sarama.ProducerMessage {
+	msg := &sarama.ProducerMessage{Topic: opts.topic, Partition: opts.partition, Headers: opts.headers}
+	key, value := opts.key, line
+	if opts.keySeparator != "" {
+		key = ""
+		if i := strings.Index(line, opts.keySeparator); i >= 0 {
+			key, value = line[:i], line[i+len(opts.keySeparator):]
+		}
+	}
+	if key != "" {
+		msg.Key = sarama.StringEncoder(key)
+	}
+	if value != "" || !opts.nullEmpty {
+		msg.Value = sarama.StringEncoder(value)
+	}
+	return msg
+}
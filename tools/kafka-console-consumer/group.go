@@ -0,0 +1,70 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/Shopify/sarama"
+)
+
+// consumeGroup consumes the topics as a member of the consumer group until
+// closing is closed, committing the offsets of the messages printed.
+func consumeGroup(brokers []string, group string, topics []string, config *sarama.Config, p *printer, closing <-chan struct{}) {
+	config.Consumer.Return.Errors = true
+	cg, err := sarama.NewConsumerGroup(brokers, group, config)
+	if err != nil {
+		printErrorAndExit(69, "Failed to join consumer group %s: %s", group, err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-closing
+		cancel()
+	}()
+	go func() {
+		for err := range cg.Errors() {
+			logger.Println("Consumer group error:", err)
+		}
+	}()
+
+	handler := &groupHandler{printer: p}
+	for ctx.Err() == nil {
+		// Consume returns on each rebalance, to be called again for the new
+		// assignment
+		if err := cg.Consume(ctx, topics, handler); err != nil {
+			if errors.Is(err, sarama.ErrClosedConsumerGroup) {
+				break
+			}
+			printErrorAndExit(69, "Failed to consume as a member of group %s: %s", group, err)
+		}
+	}
+
+	logger.Println("Done consuming as a member of group", group)
+	if err := cg.Close(); err != nil {
+		logger.Println("Failed to close consumer group: ", err)
+	}
+}
+
+// groupHandler prints the messages of the claims of the group sessions.
+type groupHandler struct {
+	printer *printer
+}
+
+func (h *groupHandler) Setup(session sarama.ConsumerGroupSession) error {
+	logger.Printf("Joined generation %d of the group as %s, claiming %v\n", session.GenerationID(), session.MemberID(), session.Claims())
+	return nil
+}
+
+func (h *groupHandler) Cleanup(sarama.ConsumerGroupSession) error {
+	return nil
+}
+
+func (h *groupHandler) ConsumeClaim(session sarama.ConsumerGroupSession, claim sarama.ConsumerGroupClaim) error {
+	for msg := range claim.Messages() {
+		if err := h.printer.print(msg); err != nil {
+			printErrorAndExit(74, "Failed to print message: %s", err)
+		}
+		session.MarkMessage(msg, "")
+	}
+	return nil
+}
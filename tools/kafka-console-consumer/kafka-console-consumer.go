@@ -17,9 +17,15 @@ import (
 
 var (
 	brokerList    = flag.String("brokers", os.Getenv("KAFKA_PEERS"), "The comma separated list of brokers in the Kafka cluster")
-	topic         = flag.String("topic", "", "REQUIRED: the topic to consume")
+	topic         = flag.String("topic", "", "REQUIRED: the topic to consume, or the comma separated topics with -group")
 	partitions    = flag.String("partitions", "all", "The partitions to consume, can be 'all' or comma-separated numbers")
-	offset        = flag.String("offset", "newest", "The offset to start with. Can be `oldest`, `newest`")
+	offset        = flag.String("offset", "newest", "The offset to start with, without committed offsets with -group. Can be `oldest`, `newest`")
+	group         = flag.String("group", "", "The consumer group to join, consuming the partitions it assigns and committing their offsets")
+	version       = flag.String("version", sarama.DefaultVersion.String(), "The version of Kafka")
+	format        = flag.String("format", "text", "The output format. Can be text, or json for a JSON object per line")
+	encoding      = flag.String("encoding", "string", "The encoding of the keys, values and header values. Can be string, hex or base64")
+	printHeaders  = flag.Bool("print-headers", false, "Whether to print the headers of the messages in the text format")
+	printTimes    = flag.Bool("print-timestamps", false, "Whether to print the timestamps of the messages in the text format")
 	verbose       = flag.Bool("verbose", false, "Whether to turn on sarama logging")
 	tlsEnabled    = flag.Bool("tls-enabled", false, "Whether to enable TLS")
 	tlsSkipVerify = flag.Bool("tls-skip-verify", false, "Whether skip TLS server cert verification")
@@ -46,6 +52,10 @@ func main() {
 		sarama.Logger = logger
 	}
 
+	if *group != "" && *partitions != "all" {
+		printUsageErrorAndExit("-partitions can't be used with -group, the group assigning the partitions")
+	}
+
 	var initialOffset int64
 	switch *offset {
 	case "oldest":
@@ -56,7 +66,21 @@ func main() {
 		printUsageErrorAndExit("-offset should be `oldest` or `newest`")
 	}
 
+	kafkaVersion, err := sarama.ParseKafkaVersion(*version)
+	if err != nil {
+		printUsageErrorAndExit("Invalid -version: %s", err)
+	}
+
+	p, err := newPrinter(os.Stdout, *format, *encoding)
+	if err != nil {
+		printUsageErrorAndExit("%s", err)
+	}
+	p.headers = *printHeaders
+	p.timestamps = *printTimes
+
 	config := sarama.NewConfig()
+	config.Version = kafkaVersion
+	config.Consumer.Offsets.Initial = initialOffset
 	if *tlsEnabled {
 		tlsConfig, err := tls.NewConfig(*tlsClientCert, *tlsClientKey)
 		if err != nil {
@@ -68,7 +92,29 @@ func main() {
 		config.Net.TLS.Config.InsecureSkipVerify = *tlsSkipVerify
 	}
 
-	c, err := sarama.NewConsumer(strings.Split(*brokerList, ","), config)
+	closing := make(chan struct{})
+	go func() {
+		signals := make(chan os.Signal, 1)
+		signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
+		<-signals
+		logger.Println("Initiating shutdown of consumer...")
+		close(closing)
+	}()
+
+	brokers := strings.Split(*brokerList, ",")
+	if *group != "" {
+		topics := strings.Split(*topic, ",")
+		p.topic = len(topics) > 1
+		consumeGroup(brokers, *group, topics, config, p, closing)
+		return
+	}
+	consumePartitions(brokers, config, initialOffset, p, closing)
+}
+
+// consumePartitions consumes the partitions of the topic until closing is
+// closed.
+func consumePartitions(brokers []string, config *sarama.Config, initialOffset int64, p *printer, closing <-chan struct{}) {
+	c, err := sarama.NewConsumer(brokers, config)
 	if err != nil {
 		printErrorAndExit(69, "Failed to start consumer: %s", err)
 	}
@@ -80,18 +126,10 @@ func main() {
 
 	var (
 		messages = make(chan *sarama.ConsumerMessage, *bufferSize)
-		closing  = make(chan struct{})
+		printed  = make(chan struct{})
 		wg       sync.WaitGroup
 	)
 
-	go func() {
-		signals := make(chan os.Signal, 1)
-		signal.Notify(signals, syscall.SIGTERM, os.Interrupt)
-		<-signals
-		logger.Println("Initiating shutdown of consumer...")
-		close(closing)
-	}()
-
 	for _, partition := range partitionList {
 		pc, err := c.ConsumePartition(*topic, partition, initialOffset)
 		if err != nil {
@@ -113,18 +151,18 @@ func main() {
 	}
 
 	go func() {
+		defer close(printed)
 		for msg := range messages {
-			fmt.Printf("Partition:\t%d\n", msg.Partition)
-			fmt.Printf("Offset:\t%d\n", msg.Offset)
-			fmt.Printf("Key:\t%s\n", string(msg.Key))
-			fmt.Printf("Value:\t%s\n", string(msg.Value))
-			fmt.Println()
+			if err := p.print(msg); err != nil {
+				printErrorAndExit(74, "Failed to print message: %s", err)
+			}
 		}
 	}()
 
 	wg.Wait()
 	logger.Println("Done consuming topic", *topic)
 	close(messages)
+	<-printed
 
 	if err := c.Close(); err != nil {
 		logger.Println("Failed to close consumer: ", err)
@@ -0,0 +1,133 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// printer prints the consumed messages, in the text or JSON format, from any
+// number of goroutines.
+type printer struct {
+	lock sync.Mutex
+	w    *bufio.Writer
+
+	json       bool
+	encode     func([]byte) string
+	topic      bool
+	headers    bool
+	timestamps bool
+}
+
+// jsonMessage is a message printed in the JSON format, one per line.
+type jsonMessage struct {
+	Topic     string       `json:"topic"`
+	Partition int32        `json:"partition"`
+	Offset    int64        `json:"offset"`
+	Timestamp *time.Time   `json:"timestamp,omitempty"`
+	Key       *string      `json:"key"`
+	Value     *string      `json:"value"`
+	Headers   []jsonHeader `json:"headers"`
+}
+
+// jsonHeader is a header of a jsonMessage, the headers being a list as their
+// keys can repeat.
+type jsonHeader struct {
+	Key   string  `json:"key"`
+	Value *string `json:"value"`
+}
+
+// newPrinter returns a printer writing to w in the format, `text` or `json`,
+// with the keys, values and header values in the encoding, `string`, `hex` or
+// `base64`.
+func newPrinter(w io.Writer, format, encoding string) (*printer, error) {
+	p := &printer{w: bufio.NewWriter(w)}
+
+	switch format {
+	case "text":
+	case "json", "ndjson":
+		p.json = true
+	default:
+		return nil, fmt.Errorf("unknown format %q, should be `text` or `json`", format)
+	}
+
+	switch encoding {
+	case "string":
+		p.encode = func(b []byte) string { return string(b) }
+	case "hex":
+		p.encode = hex.EncodeToString
+	case "base64":
+		p.encode = base64.StdEncoding.EncodeToString
+	default:
+		return nil, fmt.Errorf("unknown encoding %q, should be `string`, `hex` or `base64`", encoding)
+	}
+
+	return p, nil
+}
+
+// print prints msg, flushing it to the writer right away.
+func (p *printer) print(msg *sarama.ConsumerMessage) error {
+	p.lock.Lock()
+	defer p.lock.Unlock()
+
+	if p.json {
+		p.printJSON(msg)
+	} else {
+		p.printText(msg)
+	}
+	return p.w.Flush()
+}
+
+func (p *printer) printText(msg *sarama.ConsumerMessage) {
+	if p.topic {
+		fmt.Fprintf(p.w, "Topic:\t%s\n", msg.Topic)
+	}
+	fmt.Fprintf(p.w, "Partition:\t%d\n", msg.Partition)
+	fmt.Fprintf(p.w, "Offset:\t%d\n", msg.Offset)
+	if p.timestamps {
+		fmt.Fprintf(p.w, "Timestamp:\t%s\n", msg.Timestamp.Format(time.RFC3339Nano))
+	}
+	fmt.Fprintf(p.w, "Key:\t%s\n", p.encode(msg.Key))
+	fmt.Fprintf(p.w, "Value:\t%s\n", p.encode(msg.Value))
+	if p.headers {
+		for _, header := range msg.Headers {
+			fmt.Fprintf(p.w, "Header:\t%s: %s\n", header.Key, p.encode(header.Value))
+		}
+	}
+	fmt.Fprintln(p.w)
+}
+
+func (p *printer) printJSON(msg *sarama.ConsumerMessage) {
+	m := jsonMessage{
+		Topic:     msg.Topic,
+		Partition: msg.Partition,
+		Offset:    msg.Offset,
+		Key:       p.encodeNullable(msg.Key),
+		Value:     p.encodeNullable(msg.Value),
+		Headers:   make([]jsonHeader, 0, len(msg.Headers)),
+	}
+	if !msg.Timestamp.IsZero() {
+		m.Timestamp = &msg.Timestamp
+	}
+	for _, header := range msg.Headers {
+		m.Headers = append(m.Headers, jsonHeader{Key: string(header.Key), Value: p.encodeNullable(header.Value)})
+	}
+	// the messages hold nothing json can't encode
+	_ = json.NewEncoder(p.w).Encode(m)
+}
+
+// encodeNullable encodes b, nil encoding to null.
+func (p *printer) encodeNullable(b []byte) *string {
+	if b == nil {
+		return nil
+	}
+	s := p.encode(b)
+	return &s
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+func testMessage() *sarama.ConsumerMessage {
+	return &sarama.ConsumerMessage{
+		Topic:     "my_topic",
+		Partition: 1,
+		Offset:    42,
+		Timestamp: time.Date(2022, 2, 1, 12, 0, 0, 0, time.UTC),
+		Value:     []byte("value"),
+		Headers: []*sarama.RecordHeader{
+			{Key: []byte("a"), Value: []byte("1")},
+			{Key: []byte("a"), Value: nil},
+		},
+	}
+}
+
+func TestPrinterText(t *testing.T) {
+	var buf bytes.Buffer
+	p, err := newPrinter(&buf, "text", "string")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.print(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	expected := "Partition:\t1\nOffset:\t42\nKey:\t\nValue:\tvalue\n\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+
+	buf.Reset()
+	p.topic, p.headers, p.timestamps = true, true, true
+	if err := p.print(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	expected = "Topic:\tmy_topic\nPartition:\t1\nOffset:\t42\nTimestamp:\t2022-02-01T12:00:00Z\nKey:\t\nValue:\tvalue\nHeader:\ta: 1\nHeader:\ta: \n\n"
+	if buf.String() != expected {
+		t.Errorf("expected %q, got %q", expected, buf.String())
+	}
+}
+
+func TestPrinterJSON(t *testing.T) {
+	var buf bytes.Buffer
+	p, err := newPrinter(&buf, "json", "hex")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := p.print(testMessage()); err != nil {
+		t.Fatal(err)
+	}
+	if err := p.print(&sarama.ConsumerMessage{Topic: "my_topic", Key: []byte{0xff}}); err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"topic":"my_topic","partition":1,"offset":42,"timestamp":"2022-02-01T12:00:00Z","key":null,"value":"76616c7565","headers":[{"key":"a","value":"31"},{"key":"a","value":null}]}` + "\n" +
+		`{"topic":"my_topic","partition":0,"offset":0,"key":"ff","value":null,"headers":[]}` + "\n"
+	if buf.String() != expected {
+		t.Errorf("expected %s, got %s", expected, buf.String())
+	}
+}
+
+func TestNewPrinterInvalid(t *testing.T) {
+	if _, err := newPrinter(nil, "xml", "string"); err == nil {
+		t.Error("expected an unknown format to fail")
+	}
+	if _, err := newPrinter(nil, "text", "rot13"); err == nil {
+		t.Error("expected an unknown encoding to fail")
+	}
+}
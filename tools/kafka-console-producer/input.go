@@ -0,0 +1,161 @@
+package main
+
+import (
+	"bufio"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+// maxLineSize is the maximum size of the lines of the input, which hold a
+// message each with the lines and json input formats.
+const maxLineSize = 16 * 1024 * 1024
+
+// reader reads the messages to produce from an input.
+type reader struct {
+	scanner *bufio.Scanner
+	// whole is set to read the whole input as the value of a single message
+	whole bool
+	input io.Reader
+	done  bool
+
+	json         bool
+	keySeparator string
+	decode       func(string) ([]byte, error)
+	topic        string
+	partition    int32
+}
+
+// jsonMessage is a message of the json input format, in the format the
+// kafka-console-consumer prints them with -format=json. The partition is
+// optional, -partition applying otherwise.
+type jsonMessage struct {
+	Partition *int32       `json:"partition"`
+	Key       *string      `json:"key"`
+	Value     *string      `json:"value"`
+	Headers   []jsonHeader `json:"headers"`
+}
+
+type jsonHeader struct {
+	Key   string  `json:"key"`
+	Value *string `json:"value"`
+}
+
+// newReader returns a reader of the messages of input in the format, `value`,
+// `lines` or `json`, the keys, values and header values of the json format
+// being in the encoding, `string`, `hex` or `base64`.
+func newReader(input io.Reader, format, encoding, keySeparator, topic string, partition int32) (*reader, error) {
+	r := &reader{input: input, keySeparator: keySeparator, topic: topic, partition: partition}
+
+	switch format {
+	case "value":
+		r.whole = true
+	case "lines":
+	case "json", "ndjson":
+		r.json = true
+	default:
+		return nil, fmt.Errorf("unknown input format %q, should be `value`, `lines` or `json`", format)
+	}
+	if !r.whole {
+		r.scanner = bufio.NewScanner(input)
+		r.scanner.Buffer(nil, maxLineSize)
+	}
+
+	switch encoding {
+	case "string":
+		r.decode = func(s string) ([]byte, error) { return []byte(s), nil }
+	case "hex":
+		r.decode = hex.DecodeString
+	case "base64":
+		r.decode = base64.StdEncoding.DecodeString
+	default:
+		return nil, fmt.Errorf("unknown encoding %q, should be `string`, `hex` or `base64`", encoding)
+	}
+
+	return r, nil
+}
+
+// next returns the next message of the input, or io.EOF once it is exhausted.
+func (r *reader) next() (*sarama.ProducerMessage, error) {
+	if r.whole {
+		if r.done {
+			return nil, io.EOF
+		}
+		r.done = true
+		value, err := io.ReadAll(r.input)
+		if err != nil {
+			return nil, err
+		}
+		return &sarama.ProducerMessage{Topic: r.topic, Partition: r.partition, Value: sarama.ByteEncoder(value)}, nil
+	}
+
+	for r.scanner.Scan() {
+		line := r.scanner.Text()
+		if line == "" {
+			continue
+		}
+		if r.json {
+			return r.parseJSON(line)
+		}
+
+		msg := &sarama.ProducerMessage{Topic: r.topic, Partition: r.partition, Value: sarama.StringEncoder(line)}
+		if r.keySeparator != "" {
+			if i := strings.Index(line, r.keySeparator); i >= 0 {
+				msg.Key = sarama.StringEncoder(line[:i])
+				msg.Value = sarama.StringEncoder(line[i+len(r.keySeparator):])
+			}
+		}
+		return msg, nil
+	}
+	if err := r.scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, io.EOF
+}
+
+func (r *reader) parseJSON(line string) (*sarama.ProducerMessage, error) {
+	var m jsonMessage
+	if err := json.Unmarshal([]byte(line), &m); err != nil {
+		return nil, fmt.Errorf("invalid JSON message %q: %w", line, err)
+	}
+
+	msg := &sarama.ProducerMessage{Topic: r.topic, Partition: r.partition}
+	if m.Partition != nil {
+		msg.Partition = *m.Partition
+	}
+	key, err := r.decodeNullable(m.Key)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key of JSON message %q: %w", line, err)
+	}
+	if key != nil {
+		msg.Key = sarama.ByteEncoder(key)
+	}
+	value, err := r.decodeNullable(m.Value)
+	if err != nil {
+		return nil, fmt.Errorf("invalid value of JSON message %q: %w", line, err)
+	}
+	if value != nil {
+		msg.Value = sarama.ByteEncoder(value)
+	}
+	for _, header := range m.Headers {
+		value, err := r.decodeNullable(header.Value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid header %s of JSON message %q: %w", header.Key, line, err)
+		}
+		msg.Headers = append(msg.Headers, sarama.RecordHeader{Key: []byte(header.Key), Value: value})
+	}
+	return msg, nil
+}
+
+// decodeNullable decodes s, null decoding to nil.
+func (r *reader) decodeNullable(s *string) ([]byte, error) {
+	if s == nil {
+		return nil, nil
+	}
+	return r.decode(*s)
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func readAll(t *testing.T, r *reader) []*sarama.ProducerMessage {
+	t.Helper()
+	var msgs []*sarama.ProducerMessage
+	for {
+		msg, err := r.next()
+		if errors.Is(err, io.EOF) {
+			return msgs
+		} else if err != nil {
+			t.Fatal(err)
+		}
+		msgs = append(msgs, msg)
+	}
+}
+
+func TestReaderValue(t *testing.T) {
+	r, err := newReader(strings.NewReader("a\nb\n"), "value", "string", "", "my_topic", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*sarama.ProducerMessage{{Topic: "my_topic", Partition: -1, Value: sarama.ByteEncoder("a\nb\n")}}
+	if msgs := readAll(t, r); !reflect.DeepEqual(msgs, expected) {
+		t.Errorf("expected %v, got %v", expected, msgs)
+	}
+}
+
+func TestReaderLines(t *testing.T) {
+	r, err := newReader(strings.NewReader("k1=v1\n\nv2\nk3=v=3\n"), "lines", "string", "=", "my_topic", 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*sarama.ProducerMessage{
+		{Topic: "my_topic", Partition: 2, Key: sarama.StringEncoder("k1"), Value: sarama.StringEncoder("v1")},
+		{Topic: "my_topic", Partition: 2, Value: sarama.StringEncoder("v2")},
+		{Topic: "my_topic", Partition: 2, Key: sarama.StringEncoder("k3"), Value: sarama.StringEncoder("v=3")},
+	}
+	if msgs := readAll(t, r); !reflect.DeepEqual(msgs, expected) {
+		t.Errorf("expected %v, got %v", expected, msgs)
+	}
+}
+
+func TestReaderJSON(t *testing.T) {
+	input := `{"topic":"ignored","partition":1,"key":null,"value":"76616c7565","headers":[{"key":"a","value":"31"},{"key":"b","value":null}]}
+{"key":"6b"}
+`
+	r, err := newReader(strings.NewReader(input), "json", "hex", "", "my_topic", -1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := []*sarama.ProducerMessage{
+		{
+			Topic:     "my_topic",
+			Partition: 1,
+			Value:     sarama.ByteEncoder("value"),
+			Headers:   []sarama.RecordHeader{{Key: []byte("a"), Value: []byte("1")}, {Key: []byte("b")}},
+		},
+		{Topic: "my_topic", Partition: -1, Key: sarama.ByteEncoder("k")},
+	}
+	if msgs := readAll(t, r); !reflect.DeepEqual(msgs, expected) {
+		t.Errorf("expected %v, got %v", expected, msgs)
+	}
+
+	r, _ = newReader(strings.NewReader(`{"value":"zz"}`), "json", "hex", "", "my_topic", -1)
+	if _, err := r.next(); err == nil {
+		t.Error("expected an invalid hex value to fail")
+	}
+	r, _ = newReader(strings.NewReader(`{"value":`), "json", "string", "", "my_topic", -1)
+	if _, err := r.next(); err == nil {
+		t.Error("expected invalid JSON to fail")
+	}
+}
+
+func TestNewReaderInvalid(t *testing.T) {
+	if _, err := newReader(nil, "csv", "string", "", "my_topic", -1); err == nil {
+		t.Error("expected an unknown input format to fail")
+	}
+	if _, err := newReader(nil, "lines", "rot13", "", "my_topic", -1); err == nil {
+		t.Error("expected an unknown encoding to fail")
+	}
+}
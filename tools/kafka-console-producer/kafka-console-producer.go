@@ -1,6 +1,7 @@
 package main
 
 import (
+	"errors"
 	"flag"
 	"fmt"
 	"io"
@@ -11,6 +12,7 @@ import (
 	"github.com/rcrowley/go-metrics"
 
 	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/tools/sasl"
 	"github.com/Shopify/sarama/tools/tls"
 )
 
@@ -20,6 +22,14 @@ var (
 	topic         = flag.String("topic", "", "REQUIRED: the topic to produce to")
 	key           = flag.String("key", "", "The key of the message to produce. Can be empty.")
 	value         = flag.String("value", "", "REQUIRED: the value of the message to produce. You can also provide the value on stdin.")
+	file          = flag.String("file", "", "The file to read the messages from, rather than stdin")
+	inputFormat   = flag.String("input-format", "value", "The format of the input: value for the whole input as the value of a single message, lines for a message per line, or json for a JSON object per line")
+	keySeparator  = flag.String("key-separator", "", "The separator of the key and the value of the lines of the input (use with -input-format=lines)")
+	encoding      = flag.String("encoding", "string", "The encoding of the keys, values and header values of the JSON input. Can be string, hex or base64")
+	rate          = flag.Float64("rate", 0, "The maximum rate to produce at, in messages per second. 0 for no limit")
+	txnID         = flag.String("transactional-id", "", "The transactional ID to produce the messages in transactions of")
+	txnSize       = flag.Int("transaction-size", 0, "The number of messages per transaction, 0 to produce all the messages in a single transaction (use with -transactional-id)")
+	version       = flag.String("version", sarama.DefaultVersion.String(), "The version of Kafka")
 	partitioner   = flag.String("partitioner", "", "The partitioning scheme to use. Can be `hash`, `manual`, or `random`")
 	partition     = flag.Int("partition", -1, "The partition to produce to.")
 	verbose       = flag.Bool("verbose", false, "Turn on sarama logging to stderr")
@@ -29,6 +39,9 @@ var (
 	tlsSkipVerify = flag.Bool("tls-skip-verify", false, "Whether skip TLS server cert verification")
 	tlsClientCert = flag.String("tls-client-cert", "", "Client cert for client authentication (use with -tls-enabled and -tls-client-key)")
 	tlsClientKey  = flag.String("tls-client-key", "", "Client key for client authentication (use with tls-enabled and -tls-client-cert)")
+	saslMechanism = flag.String("sasl-mechanism", "", "The SASL mechanism to authenticate with. Can be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512")
+	saslUser      = flag.String("sasl-user", "", "The user to authenticate as (use with -sasl-mechanism)")
+	saslPassword  = flag.String("sasl-password", os.Getenv("KAFKA_PASSWORD"), "The password to authenticate with (use with -sasl-mechanism). You can also set the KAFKA_PASSWORD environment variable")
 
 	logger = log.New(os.Stderr, "", log.LstdFlags)
 )
@@ -48,7 +61,13 @@ func main() {
 		sarama.Logger = logger
 	}
 
+	kafkaVersion, err := sarama.ParseKafkaVersion(*version)
+	if err != nil {
+		printUsageErrorAndExit(fmt.Sprintf("Invalid -version: %s", err))
+	}
+
 	config := sarama.NewConfig()
+	config.Version = kafkaVersion
 	config.Producer.RequiredAcks = sarama.WaitForAll
 	config.Producer.Return.Successes = true
 
@@ -63,6 +82,10 @@ func main() {
 		config.Net.TLS.Config.InsecureSkipVerify = *tlsSkipVerify
 	}
 
+	if err := sasl.Configure(config, *saslMechanism, *saslUser, *saslPassword); err != nil {
+		printUsageErrorAndExit(err.Error())
+	}
+
 	switch *partitioner {
 	case "":
 		if *partition >= 0 {
@@ -83,26 +106,8 @@ func main() {
 		printUsageErrorAndExit(fmt.Sprintf("Partitioner %s not supported.", *partitioner))
 	}
 
-	message := &sarama.ProducerMessage{Topic: *topic, Partition: int32(*partition)}
-
-	if *key != "" {
-		message.Key = sarama.StringEncoder(*key)
-	}
-
-	if *value != "" {
-		message.Value = sarama.StringEncoder(*value)
-	} else if stdinAvailable() {
-		bytes, err := io.ReadAll(os.Stdin)
-		if err != nil {
-			printErrorAndExit(66, "Failed to read data from the standard input: %s", err)
-		}
-		message.Value = sarama.ByteEncoder(bytes)
-	} else {
-		printUsageErrorAndExit("-value is required, or you have to provide the value on stdin")
-	}
-
+	var hdrs []sarama.RecordHeader
 	if *headers != "" {
-		var hdrs []sarama.RecordHeader
 		arrHdrs := strings.Split(*headers, ",")
 		for _, h := range arrHdrs {
 			if header := strings.Split(h, ":"); len(header) != 2 {
@@ -114,30 +119,169 @@ func main() {
 				})
 			}
 		}
+	}
 
-		if len(hdrs) != 0 {
-			message.Headers = hdrs
+	var input io.Reader
+	switch {
+	case *value != "":
+		input = strings.NewReader(*value)
+		*inputFormat = "value"
+	case *file != "":
+		f, err := os.Open(*file)
+		if err != nil {
+			printErrorAndExit(66, "Failed to open the input file: %s", err)
 		}
+		defer f.Close()
+		input = f
+	case stdinAvailable():
+		input = os.Stdin
+	default:
+		printUsageErrorAndExit("-value is required, or you have to provide the value on stdin or with -file")
+	}
+	messages, err := newReader(input, *inputFormat, *encoding, *keySeparator, *topic, int32(*partition))
+	if err != nil {
+		printUsageErrorAndExit(err.Error())
+	}
+	next := func() (*sarama.ProducerMessage, error) {
+		message, err := messages.next()
+		if err != nil {
+			return nil, err
+		}
+		if message.Key == nil && *key != "" {
+			message.Key = sarama.StringEncoder(*key)
+		}
+		message.Headers = append(message.Headers, hdrs...)
+		return message, nil
+	}
+
+	client, err := sarama.NewClient(strings.Split(*brokerList, ","), config)
+	if err != nil {
+		printErrorAndExit(69, "Failed to connect to Kafka: %s", err)
+	}
+	defer client.Close()
+
+	limit := newLimiter(*rate)
+	var produced int
+	if *txnID != "" {
+		produced = produceTransactions(client, next, limit)
+	} else {
+		produced = produce(client, next, limit)
+	}
+
+	if *showMetrics {
+		metrics.WriteOnce(config.MetricRegistry, os.Stderr)
+	}
+	if produced < 0 {
+		os.Exit(69)
 	}
+}
 
-	producer, err := sarama.NewSyncProducer(strings.Split(*brokerList, ","), config)
+// produce produces the messages with an AsyncProducer, returning how many
+// were produced or -1 if any failed.
+func produce(client sarama.Client, next func() (*sarama.ProducerMessage, error), limit *limiter) int {
+	producer, err := sarama.NewAsyncProducerFromClient(client)
 	if err != nil {
 		printErrorAndExit(69, "Failed to open Kafka producer: %s", err)
 	}
-	defer func() {
-		if err := producer.Close(); err != nil {
-			logger.Println("Failed to close Kafka producer cleanly:", err)
+
+	var (
+		produced, failed int
+		successesDone    = make(chan struct{})
+		errorsDone       = make(chan struct{})
+		ok               = true
+	)
+	go func() {
+		defer close(successesDone)
+		for message := range producer.Successes() {
+			produced++
+			printMessage(message)
+		}
+	}()
+	go func() {
+		defer close(errorsDone)
+		for err := range producer.Errors() {
+			failed++
+			logger.Println("Failed to produce message:", err)
 		}
 	}()
 
-	partition, offset, err := producer.SendMessage(message)
+	for {
+		message, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			logger.Println("Failed to read message:", err)
+			ok = false
+			break
+		}
+		limit.wait()
+		producer.Input() <- message
+	}
+
+	// Close returns the errors it drains itself, racing the goroutine above
+	if err := producer.Close(); err != nil {
+		ok = false
+		logger.Println("Failed to produce messages:", err)
+	}
+	<-successesDone
+	<-errorsDone
+	if !ok || failed > 0 {
+		return -1
+	}
+	return produced
+}
+
+// produceTransactions produces the messages in transactions of -transaction-size
+// messages, returning how many were produced or -1 if any failed.
+func produceTransactions(client sarama.Client, next func() (*sarama.ProducerMessage, error), limit *limiter) int {
+	producer, err := newTxnProducer(client, *txnID)
 	if err != nil {
-		printErrorAndExit(69, "Failed to produce message: %s", err)
-	} else if !*silent {
-		fmt.Printf("topic=%s\tpartition=%d\toffset=%d\n", *topic, partition, offset)
+		printErrorAndExit(69, "Failed to open transactional producer: %s", err)
 	}
-	if *showMetrics {
-		metrics.WriteOnce(config.MetricRegistry, os.Stderr)
+
+	var (
+		produced int
+		txn      []*sarama.ProducerMessage
+	)
+	commit := func() bool {
+		if len(txn) == 0 {
+			return true
+		}
+		if err := producer.produce(txn); err != nil {
+			logger.Printf("Failed to produce transaction of %d messages: %s\n", len(txn), err)
+			return false
+		}
+		for _, message := range txn {
+			printMessage(message)
+		}
+		produced += len(txn)
+		txn = nil
+		return true
+	}
+
+	for {
+		message, err := next()
+		if errors.Is(err, io.EOF) {
+			break
+		} else if err != nil {
+			logger.Println("Failed to read message:", err)
+			return -1
+		}
+		limit.wait()
+		txn = append(txn, message)
+		if len(txn) == *txnSize && !commit() {
+			return -1
+		}
+	}
+	if !commit() {
+		return -1
+	}
+	return produced
+}
+
+func printMessage(message *sarama.ProducerMessage) {
+	if !*silent {
+		fmt.Printf("topic=%s\tpartition=%d\toffset=%d\n", message.Topic, message.Partition, message.Offset)
 	}
 }
 
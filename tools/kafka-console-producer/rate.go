@@ -0,0 +1,38 @@
+package main
+
+import "time"
+
+// limiter paces the messages produced to a maximum rate, without bursting to
+// catch up once behind.
+type limiter struct {
+	interval time.Duration
+	next     time.Time
+	now      func() time.Time
+	sleep    func(time.Duration)
+}
+
+// newLimiter returns a limiter of rate messages per second, nil for no limit.
+func newLimiter(rate float64) *limiter {
+	if rate <= 0 {
+		return nil
+	}
+	return &limiter{
+		interval: time.Duration(float64(time.Second) / rate),
+		now:      time.Now,
+		sleep:    time.Sleep,
+	}
+}
+
+// wait waits until the next message can be produced.
+func (l *limiter) wait() {
+	if l == nil {
+		return
+	}
+	now := l.now()
+	if l.next.Before(now) {
+		l.next = now
+	} else {
+		l.sleep(l.next.Sub(now))
+	}
+	l.next = l.next.Add(l.interval)
+}
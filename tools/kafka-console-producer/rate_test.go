@@ -0,0 +1,39 @@
+package main
+
+import (
+	"testing"
+	"time"
+)
+
+func TestLimiter(t *testing.T) {
+	if newLimiter(0) != nil {
+		t.Error("expected no limiter without a rate")
+	}
+	var l *limiter
+	l.wait() // no limit
+
+	now := time.Unix(0, 0)
+	var slept []time.Duration
+	l = newLimiter(4)
+	l.now = func() time.Time { return now }
+	l.sleep = func(d time.Duration) {
+		slept = append(slept, d)
+		now = now.Add(d)
+	}
+
+	l.wait()
+	l.wait()
+	l.wait()
+	if len(slept) != 2 || slept[0] != 250*time.Millisecond || slept[1] != 250*time.Millisecond {
+		t.Errorf("expected to sleep 250ms between the messages, slept %v", slept)
+	}
+
+	// falling behind doesn't burst to catch up
+	now = now.Add(time.Second)
+	slept = nil
+	l.wait()
+	l.wait()
+	if len(slept) != 1 || slept[0] != 250*time.Millisecond {
+		t.Errorf("expected to sleep 250ms after the first message, slept %v", slept)
+	}
+}
@@ -0,0 +1,248 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Shopify/sarama"
+)
+
+// txnProducer produces messages in the transactions of a transactional ID.
+// The producers of sarama not supporting transactions, it runs the
+// transaction protocol with the coordinator and produces the transactional
+// record batches to the leaders itself, a batch per partition and transaction.
+type txnProducer struct {
+	client      sarama.Client
+	id          string
+	coordinator *sarama.Broker
+	producerID  int64
+	epoch       int16
+
+	partitioners map[string]sarama.Partitioner
+	// sequences are the sequence numbers of the next batches by partition
+	sequences map[string]map[int32]int32
+}
+
+// newTxnProducer initializes the transactional ID with its coordinator,
+// fencing off its previous producers and aborting their transactions.
+func newTxnProducer(client sarama.Client, id string) (*txnProducer, error) {
+	if !client.Config().Version.IsAtLeast(sarama.V0_11_0_0) {
+		return nil, errors.New("transactions require -version 0.11.0.0 or later")
+	}
+	p := &txnProducer{
+		client:       client,
+		id:           id,
+		partitioners: make(map[string]sarama.Partitioner),
+		sequences:    make(map[string]map[int32]int32),
+	}
+
+	broker := client.LeastLoadedBroker()
+	if broker == nil {
+		return nil, sarama.ErrOutOfBrokers
+	}
+	_ = broker.Open(client.Config())
+	coordinator, err := broker.FindCoordinator(&sarama.FindCoordinatorRequest{
+		Version:         1,
+		CoordinatorKey:  id,
+		CoordinatorType: sarama.CoordinatorTransaction,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !errors.Is(coordinator.Err, sarama.ErrNoError) {
+		return nil, fmt.Errorf("failed to find the transaction coordinator: %w", coordinator.Err)
+	}
+	p.coordinator = coordinator.Coordinator
+	_ = p.coordinator.Open(client.Config())
+
+	init, err := p.coordinator.InitProducerID(&sarama.InitProducerIDRequest{
+		TransactionalID:    &id,
+		TransactionTimeout: time.Minute,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if !errors.Is(init.Err, sarama.ErrNoError) {
+		return nil, fmt.Errorf("failed to initialize the transactional ID: %w", init.Err)
+	}
+	p.producerID, p.epoch = init.ProducerID, init.ProducerEpoch
+	return p, nil
+}
+
+// produce produces msgs in a transaction, committed once they are all
+// written and aborted otherwise, and sets their partitions and offsets.
+func (p *txnProducer) produce(msgs []*sarama.ProducerMessage) error {
+	batches := make(map[string]map[int32][]*sarama.ProducerMessage)
+	partitions := make(map[string][]int32)
+	for _, msg := range msgs {
+		if err := p.partition(msg); err != nil {
+			return err
+		}
+		if batches[msg.Topic] == nil {
+			batches[msg.Topic] = make(map[int32][]*sarama.ProducerMessage)
+		}
+		if batches[msg.Topic][msg.Partition] == nil {
+			partitions[msg.Topic] = append(partitions[msg.Topic], msg.Partition)
+		}
+		batches[msg.Topic][msg.Partition] = append(batches[msg.Topic][msg.Partition], msg)
+	}
+
+	added, err := p.coordinator.AddPartitionsToTxn(&sarama.AddPartitionsToTxnRequest{
+		TransactionalID: p.id,
+		ProducerID:      p.producerID,
+		ProducerEpoch:   p.epoch,
+		TopicPartitions: partitions,
+	})
+	if err != nil {
+		return err
+	}
+	for topic, errs := range added.Errors {
+		for _, pErr := range errs {
+			if !errors.Is(pErr.Err, sarama.ErrNoError) {
+				return p.abort(fmt.Errorf("failed to add partition %s/%d to the transaction: %w", topic, pErr.Partition, pErr.Err))
+			}
+		}
+	}
+
+	if err := p.write(batches); err != nil {
+		return p.abort(err)
+	}
+	return p.end(true)
+}
+
+// partition sets the partition of msg, with the partitioner of its topic
+// unless it is partitioned manually.
+func (p *txnProducer) partition(msg *sarama.ProducerMessage) error {
+	partitioner := p.partitioners[msg.Topic]
+	if partitioner == nil {
+		partitioner = p.client.Config().Producer.Partitioner(msg.Topic)
+		p.partitioners[msg.Topic] = partitioner
+	}
+	partitions, err := p.client.Partitions(msg.Topic)
+	if err != nil {
+		return err
+	}
+	choice, err := partitioner.Partition(msg, int32(len(partitions)))
+	if err != nil {
+		return err
+	}
+	if choice < 0 || choice >= int32(len(partitions)) {
+		return sarama.ErrInvalidPartition
+	}
+	msg.Partition = partitions[choice]
+	return nil
+}
+
+// write produces the batches to the leaders of their partitions.
+func (p *txnProducer) write(batches map[string]map[int32][]*sarama.ProducerMessage) error {
+	config := p.client.Config()
+	requests := make(map[*sarama.Broker]*sarama.ProduceRequest)
+	for topic, partitions := range batches {
+		for partition, msgs := range partitions {
+			leader, err := p.client.Leader(topic, partition)
+			if err != nil {
+				return err
+			}
+			batch, err := p.batch(topic, partition, msgs)
+			if err != nil {
+				return err
+			}
+			request := requests[leader]
+			if request == nil {
+				request = &sarama.ProduceRequest{
+					TransactionalID: &p.id,
+					RequiredAcks:    sarama.WaitForAll,
+					Timeout:         int32(config.Producer.Timeout / time.Millisecond),
+					Version:         3,
+				}
+				requests[leader] = request
+			}
+			request.AddBatch(topic, partition, batch)
+		}
+	}
+
+	for leader, request := range requests {
+		response, err := leader.Produce(request)
+		if err != nil {
+			return err
+		}
+		for topic, partitions := range response.Blocks {
+			for partition, block := range partitions {
+				if !errors.Is(block.Err, sarama.ErrNoError) {
+					return fmt.Errorf("failed to produce to %s/%d: %w", topic, partition, block.Err)
+				}
+				for i, msg := range batches[topic][partition] {
+					msg.Offset = block.Offset + int64(i)
+				}
+				p.sequences[topic][partition] += int32(len(batches[topic][partition]))
+			}
+		}
+	}
+	return nil
+}
+
+// batch returns the transactional record batch of the messages of the
+// partition.
+func (p *txnProducer) batch(topic string, partition int32, msgs []*sarama.ProducerMessage) (*sarama.RecordBatch, error) {
+	if p.sequences[topic] == nil {
+		p.sequences[topic] = make(map[int32]int32)
+	}
+	now := time.Now()
+	batch := &sarama.RecordBatch{
+		Version:          2,
+		Codec:            p.client.Config().Producer.Compression,
+		CompressionLevel: p.client.Config().Producer.CompressionLevel,
+		FirstTimestamp:   now,
+		MaxTimestamp:     now,
+		LastOffsetDelta:  int32(len(msgs) - 1),
+		ProducerID:       p.producerID,
+		ProducerEpoch:    p.epoch,
+		FirstSequence:    p.sequences[topic][partition],
+		IsTransactional:  true,
+	}
+	for i, msg := range msgs {
+		record := &sarama.Record{OffsetDelta: int64(i)}
+		var err error
+		if msg.Key != nil {
+			if record.Key, err = msg.Key.Encode(); err != nil {
+				return nil, err
+			}
+		}
+		if msg.Value != nil {
+			if record.Value, err = msg.Value.Encode(); err != nil {
+				return nil, err
+			}
+		}
+		for i := range msg.Headers {
+			record.Headers = append(record.Headers, &msg.Headers[i])
+		}
+		batch.Records = append(batch.Records, record)
+	}
+	return batch, nil
+}
+
+// abort aborts the transaction failed with err.
+func (p *txnProducer) abort(err error) error {
+	if abortErr := p.end(false); abortErr != nil {
+		return fmt.Errorf("%w, and failed to abort the transaction: %v", err, abortErr)
+	}
+	return err
+}
+
+// end commits or aborts the transaction.
+func (p *txnProducer) end(commit bool) error {
+	response, err := p.coordinator.EndTxn(&sarama.EndTxnRequest{
+		TransactionalID:   p.id,
+		ProducerID:        p.producerID,
+		ProducerEpoch:     p.epoch,
+		TransactionResult: commit,
+	})
+	if err != nil {
+		return err
+	}
+	if !errors.Is(response.Err, sarama.ErrNoError) {
+		return response.Err
+	}
+	return nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestTxnProducer(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+
+	produce := sarama.NewMockProduceResponse(t)
+	produce.SetVersion(3)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("my_topic", 0, broker.BrokerID()).
+			SetLeader("my_topic", 1, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorTransaction, "txn", broker),
+		"InitProducerIDRequest": sarama.NewMockWrapper(&sarama.InitProducerIDResponse{ProducerID: 7, ProducerEpoch: 1}),
+		"AddPartitionsToTxnRequest": sarama.NewMockWrapper(&sarama.AddPartitionsToTxnResponse{
+			Errors: map[string][]*sarama.PartitionError{"my_topic": {{Partition: 0}, {Partition: 1}}},
+		}),
+		"ProduceRequest": produce,
+		"EndTxnRequest":  sarama.NewMockWrapper(&sarama.EndTxnResponse{}),
+	})
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V0_11_0_0
+	config.Producer.Partitioner = sarama.NewManualPartitioner
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	producer, err := newTxnProducer(client, "txn")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msgs := []*sarama.ProducerMessage{
+		{Topic: "my_topic", Partition: 0, Value: sarama.StringEncoder("a")},
+		{Topic: "my_topic", Partition: 1, Value: sarama.StringEncoder("b")},
+		{Topic: "my_topic", Partition: 0, Value: sarama.StringEncoder("c")},
+	}
+	if err := producer.produce(msgs); err != nil {
+		t.Fatal(err)
+	}
+	if msgs[0].Offset != 0 || msgs[2].Offset != 1 {
+		t.Errorf("expected the offsets of partition 0 to follow each other, got %d and %d", msgs[0].Offset, msgs[2].Offset)
+	}
+	if producer.sequences["my_topic"][0] != 2 || producer.sequences["my_topic"][1] != 1 {
+		t.Errorf("unexpected sequences %v", producer.sequences)
+	}
+
+	var produced, committed bool
+	for _, rr := range broker.History() {
+		switch request := rr.Request.(type) {
+		case *sarama.ProduceRequest:
+			produced = request.TransactionalID != nil && *request.TransactionalID == "txn"
+		case *sarama.EndTxnRequest:
+			committed = request.TransactionResult && request.ProducerID == 7 && request.ProducerEpoch == 1
+		}
+	}
+	if !produced {
+		t.Error("expected a transactional produce request")
+	}
+	if !committed {
+		t.Error("expected the transaction to be committed")
+	}
+}
+
+func TestTxnProducerVersion(t *testing.T) {
+	broker := sarama.NewMockBroker(t, 1)
+	defer broker.Close()
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).SetBroker(broker.Addr(), broker.BrokerID()),
+	})
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V0_10_2_0
+	client, err := sarama.NewClient([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer client.Close()
+
+	if _, err := newTxnProducer(client, "txn"); err == nil {
+		t.Error("expected transactions to require Kafka 0.11")
+	}
+}
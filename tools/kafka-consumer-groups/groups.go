@@ -0,0 +1,414 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Shopify/sarama"
+)
+
+// topicPartitionsFlag is the list of topics, along with their partitions, set
+// by repeated -topic flags.
+type topicPartitionsFlag []topicPartitions
+
+// topicPartitions are partitions of a topic, nil standing for all of them.
+type topicPartitions struct {
+	topic      string
+	partitions []int32
+}
+
+func (f *topicPartitionsFlag) String() string {
+	var s []string
+	for _, tp := range *f {
+		s = append(s, tp.String())
+	}
+	return strings.Join(s, " ")
+}
+
+func (f *topicPartitionsFlag) Set(value string) error {
+	topic, list := value, ""
+	if i := strings.Index(value, ":"); i >= 0 {
+		topic, list = value[:i], value[i+1:]
+	}
+	if topic == "" {
+		return errors.New("missing topic")
+	}
+	tp := topicPartitions{topic: topic}
+	if list != "" {
+		for _, p := range strings.Split(list, ",") {
+			partition, err := strconv.ParseInt(p, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid partition %q of topic %s", p, topic)
+			}
+			tp.partitions = append(tp.partitions, int32(partition))
+		}
+	}
+	*f = append(*f, tp)
+	return nil
+}
+
+// toMap returns the topics and their partitions in the format of
+// sarama.ClusterAdmin, merging the repeated topics.
+func (f topicPartitionsFlag) toMap() map[string][]int32 {
+	m := make(map[string][]int32, len(f))
+	all := make(map[string]bool)
+	for _, tp := range f {
+		if tp.partitions == nil || all[tp.topic] {
+			all[tp.topic] = true
+			m[tp.topic] = nil
+			continue
+		}
+		m[tp.topic] = append(m[tp.topic], tp.partitions...)
+	}
+	return m
+}
+
+func (tp topicPartitions) String() string {
+	if tp.partitions == nil {
+		return tp.topic
+	}
+	partitions := make([]string, len(tp.partitions))
+	for i, partition := range tp.partitions {
+		partitions[i] = strconv.Itoa(int(partition))
+	}
+	return tp.topic + ":" + strings.Join(partitions, ",")
+}
+
+// listGroups prints the groups matching opts, sorted by ID.
+func listGroups(admin sarama.ClusterAdmin, w io.Writer, opts sarama.ListConsumerGroupsOptions) error {
+	groups, err := admin.ListConsumerGroupsWithOptions(opts)
+	if err != nil {
+		return err
+	}
+	ids := make([]string, 0, len(groups))
+	for id := range groups {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "GROUP\tPROTOCOL-TYPE\tSTATE")
+	for _, id := range ids {
+		group := groups[id]
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", id, orDash(group.ProtocolType), orDash(group.State))
+	}
+	return tw.Flush()
+}
+
+// describeGroups prints the state of the groups and their members, with the
+// partitions assigned to them.
+func describeGroups(admin sarama.ClusterAdmin, w io.Writer, groups []string) error {
+	descriptions, err := admin.DescribeConsumerGroups(groups)
+	if err != nil {
+		return err
+	}
+	for i, description := range descriptions {
+		if !errors.Is(description.Err, sarama.ErrNoError) {
+			return fmt.Errorf("failed to describe group %s: %w", description.GroupId, description.Err)
+		}
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintf(w, "GROUP %s: %s, protocol %s/%s, %d members\n", description.GroupId,
+			orDash(description.State), orDash(description.ProtocolType), orDash(description.Protocol), len(description.Members))
+		if len(description.Members) == 0 {
+			continue
+		}
+
+		ids := make([]string, 0, len(description.Members))
+		for id := range description.Members {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "MEMBER-ID\tCLIENT-ID\tHOST\tASSIGNMENT")
+		for _, id := range ids {
+			member := description.Members[id]
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\n", id, member.ClientId, member.ClientHost, formatAssignment(description, member))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// formatAssignment returns the partitions assigned to the member of a
+// consumer group, or a dash for the other protocols.
+func formatAssignment(description *sarama.GroupDescription, member *sarama.GroupMemberDescription) string {
+	if description.ProtocolType != "consumer" || len(member.MemberAssignment) == 0 {
+		return "-"
+	}
+	assignment, err := member.GetMemberAssignment()
+	if err != nil {
+		return "-"
+	}
+	topics := make([]string, 0, len(assignment.Topics))
+	for topic := range assignment.Topics {
+		topics = append(topics, topic)
+	}
+	sort.Strings(topics)
+
+	s := make([]string, len(topics))
+	for i, topic := range topics {
+		partitions := append([]int32(nil), assignment.Topics[topic]...)
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i] < partitions[j] })
+		s[i] = topicPartitions{topic: topic, partitions: partitions}.String()
+	}
+	return strings.Join(s, " ")
+}
+
+// lagRow is the lag of the group on a partition, along with the member the
+// partition is assigned to, if any.
+type lagRow struct {
+	sarama.PartitionLag
+	member   string
+	clientID string
+	host     string
+}
+
+// groupLags returns the lags of the group on the partitions it committed
+// offsets for or which are assigned to its members, sorted by topic and
+// partition. The partitions without committed offset have a Committed offset
+// and Lag of -1.
+func groupLags(admin sarama.ClusterAdmin, group string) ([]lagRow, error) {
+	committed, err := admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !errors.Is(committed.Err, sarama.ErrNoError) {
+		return nil, committed.Err
+	}
+	descriptions, err := admin.DescribeConsumerGroups([]string{group})
+	if err != nil {
+		return nil, err
+	}
+
+	type key struct {
+		topic     string
+		partition int32
+	}
+	rows := make(map[key]*lagRow)
+	row := func(topic string, partition int32) *lagRow {
+		k := key{topic, partition}
+		if rows[k] == nil {
+			rows[k] = &lagRow{PartitionLag: sarama.PartitionLag{Group: group, Topic: topic, Partition: partition, Committed: -1, Lag: -1}}
+		}
+		return rows[k]
+	}
+
+	for topic, partitions := range committed.Blocks {
+		for partition, block := range partitions {
+			if !errors.Is(block.Err, sarama.ErrNoError) {
+				return nil, fmt.Errorf("failed to fetch the offset of %s/%d: %w", topic, partition, block.Err)
+			}
+			if block.Offset >= 0 {
+				row(topic, partition).Committed = block.Offset
+			}
+		}
+	}
+	for _, description := range descriptions {
+		if description.GroupId != group || description.ProtocolType != "consumer" {
+			continue
+		}
+		for id, member := range description.Members {
+			if len(member.MemberAssignment) == 0 {
+				continue
+			}
+			assignment, err := member.GetMemberAssignment()
+			if err != nil {
+				return nil, fmt.Errorf("failed to decode the assignment of member %s: %w", id, err)
+			}
+			for topic, partitions := range assignment.Topics {
+				for _, partition := range partitions {
+					r := row(topic, partition)
+					r.member, r.clientID, r.host = id, member.ClientId, member.ClientHost
+				}
+			}
+		}
+	}
+	if len(rows) == 0 {
+		return nil, nil
+	}
+
+	topicPartitions := make(map[string][]int32)
+	for k := range rows {
+		topicPartitions[k.topic] = append(topicPartitions[k.topic], k.partition)
+	}
+	ends, err := admin.ListOffsets(topicPartitions, sarama.OffsetNewest)
+	if err != nil {
+		return nil, err
+	}
+
+	sorted := make([]lagRow, 0, len(rows))
+	for k, r := range rows {
+		r.End = ends[k.topic][k.partition]
+		if r.Committed >= 0 {
+			r.Lag = r.End - r.Committed
+		}
+		sorted = append(sorted, *r)
+	}
+	sort.Slice(sorted, func(i, j int) bool {
+		if sorted[i].Topic != sorted[j].Topic {
+			return sorted[i].Topic < sorted[j].Topic
+		}
+		return sorted[i].Partition < sorted[j].Partition
+	})
+	return sorted, nil
+}
+
+// groupLag prints the lags of the group, in the format of the describe
+// command of the kafka-consumer-groups tool of Kafka, and their sum.
+func groupLag(admin sarama.ClusterAdmin, w io.Writer, group string) error {
+	rows, err := groupLags(admin, group)
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		fmt.Fprintf(w, "Group %s has no committed offsets nor assigned partitions.\n", group)
+		return nil
+	}
+
+	var total int64
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOPIC\tPARTITION\tCURRENT-OFFSET\tLOG-END-OFFSET\tLAG\tCONSUMER-ID\tHOST\tCLIENT-ID")
+	for _, r := range rows {
+		if r.Lag > 0 {
+			total += r.Lag
+		}
+		fmt.Fprintf(tw, "%s\t%d\t%s\t%d\t%s\t%s\t%s\t%s\n", r.Topic, r.Partition,
+			offsetOrDash(r.Committed), r.End, offsetOrDash(r.Lag), orDash(r.member), orDash(r.host), orDash(r.clientID))
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(w, "\nTotal lag of group %s: %d\n", group, total)
+	return nil
+}
+
+// committedTopics returns the topics the group committed offsets for.
+func committedTopics(admin sarama.ClusterAdmin, group string) (topicPartitionsFlag, error) {
+	committed, err := admin.ListConsumerGroupOffsets(group, nil)
+	if err != nil {
+		return nil, err
+	}
+	if !errors.Is(committed.Err, sarama.ErrNoError) {
+		return nil, committed.Err
+	}
+	var topics topicPartitionsFlag
+	for topic := range committed.Blocks {
+		topics = append(topics, topicPartitions{topic: topic})
+	}
+	if len(topics) == 0 {
+		return nil, fmt.Errorf("group %s has no committed offsets", group)
+	}
+	sort.Slice(topics, func(i, j int) bool { return topics[i].topic < topics[j].topic })
+	return topics, nil
+}
+
+// resetTarget is the offset to reset the partitions to: a specific offset, or
+// the offset of a time in milliseconds, sarama.OffsetOldest or
+// sarama.OffsetNewest.
+type resetTarget struct {
+	time     int64
+	offset   int64
+	specific bool
+}
+
+// resetOffsets resets the offsets of the group on the topics to the target
+// and prints the new offsets, only committing them when execute is set.
+func resetOffsets(admin sarama.ClusterAdmin, w io.Writer, group string, topics topicPartitionsFlag, target resetTarget, execute bool) error {
+	var offsets map[string]map[int32]int64
+	var err error
+	if target.specific {
+		offsets, err = resetToOffset(admin, group, topics.toMap(), target.offset, execute)
+	} else {
+		offsets, err = sarama.ResetConsumerGroupOffsets(admin, group, topics.toMap(), target.time, sarama.ResetOffsetsOptions{DryRun: !execute})
+	}
+	if err != nil {
+		return err
+	}
+
+	var rows []topicPartitions
+	for topic, partitions := range offsets {
+		for partition := range partitions {
+			rows = append(rows, topicPartitions{topic: topic, partitions: []int32{partition}})
+		}
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].topic != rows[j].topic {
+			return rows[i].topic < rows[j].topic
+		}
+		return rows[i].partitions[0] < rows[j].partitions[0]
+	})
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOPIC\tPARTITION\tNEW-OFFSET")
+	for _, r := range rows {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", r.topic, r.partitions[0], offsets[r.topic][r.partitions[0]])
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	if !execute {
+		fmt.Fprintln(w, "\nDry run: the offsets were not committed, run with -execute to commit them.")
+	}
+	return nil
+}
+
+// resetToOffset resets the offsets of the group on the partitions to offset,
+// clamped to the range of offsets of each partition.
+func resetToOffset(admin sarama.ClusterAdmin, group string, partitions map[string][]int32, offset int64, execute bool) (map[string]map[int32]int64, error) {
+	earliest, err := admin.ListOffsets(partitions, sarama.OffsetOldest)
+	if err != nil {
+		return nil, err
+	}
+	latest, err := admin.ListOffsets(partitions, sarama.OffsetNewest)
+	if err != nil {
+		return nil, err
+	}
+
+	offsets := make(map[string]map[int32]int64, len(latest))
+	snapshot := &sarama.GroupOffsetsSnapshot{Group: group, Offsets: make(map[string]map[int32]sarama.GroupOffset, len(latest))}
+	for topic, partitions := range latest {
+		offsets[topic] = make(map[int32]int64, len(partitions))
+		snapshot.Offsets[topic] = make(map[int32]sarama.GroupOffset, len(partitions))
+		for partition, end := range partitions {
+			o := offset
+			if start := earliest[topic][partition]; o < start {
+				o = start
+			}
+			if o > end {
+				o = end
+			}
+			offsets[topic][partition] = o
+			snapshot.Offsets[topic][partition] = sarama.GroupOffset{Offset: o}
+		}
+	}
+
+	if !execute {
+		return offsets, nil
+	}
+	return offsets, sarama.ImportConsumerGroupOffsets(admin, group, snapshot)
+}
+
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+func offsetOrDash(offset int64) string {
+	if offset < 0 {
+		return "-"
+	}
+	return strconv.FormatInt(offset, 10)
+}
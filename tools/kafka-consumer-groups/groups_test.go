@@ -0,0 +1,150 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestTopicPartitionsFlag(t *testing.T) {
+	var f topicPartitionsFlag
+	for _, value := range []string{"orders:0,2", "orders:1", "events", "events:3"} {
+		if err := f.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := f.toMap()
+	if len(m) != 2 || len(m["orders"]) != 3 || m["events"] != nil {
+		t.Errorf("unexpected partitions: %v", m)
+	}
+	if f.String() != "orders:0,2 orders:1 events events:3" {
+		t.Errorf("unexpected string: %s", f.String())
+	}
+
+	for _, value := range []string{":0", "orders:a", "orders:0,"} {
+		if err := f.Set(value); err == nil {
+			t.Errorf("expected %q to be invalid", value)
+		}
+	}
+}
+
+// newTestAdmin returns an admin of a mock cluster holding the partitions 0
+// and 1 of my_topic, with the offsets from 10 to 100, and the group my_group,
+// whose single member is assigned partition 0 and committed offset 40 on it.
+func newTestAdmin(t *testing.T) (sarama.ClusterAdmin, *sarama.MockBroker) {
+	broker := sarama.NewMockBroker(t, 1)
+
+	sync := &sarama.SyncGroupRequest{}
+	if err := sync.AddGroupAssignmentMember("member-1", &sarama.ConsumerGroupMemberAssignment{
+		Topics: map[string][]int32{"my_topic": {0}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("my_topic", 0, broker.BrokerID()).
+			SetLeader("my_topic", 1, broker.BrokerID()),
+		"FindCoordinatorRequest": sarama.NewMockFindCoordinatorResponse(t).
+			SetCoordinator(sarama.CoordinatorGroup, "my_group", broker),
+		"OffsetFetchRequest": sarama.NewMockOffsetFetchResponse(t).
+			SetOffset("my_group", "my_topic", 0, 40, "", sarama.ErrNoError),
+		"DescribeGroupsRequest": sarama.NewMockDescribeGroupsResponse(t).
+			AddGroupDescription("my_group", &sarama.GroupDescription{
+				GroupId:      "my_group",
+				State:        "Stable",
+				ProtocolType: "consumer",
+				Protocol:     "range",
+				Members: map[string]*sarama.GroupMemberDescription{
+					"member-1": {ClientId: "client-1", ClientHost: "/127.0.0.1", MemberAssignment: sync.GroupAssignments["member-1"]},
+				},
+			}),
+		"OffsetRequest": sarama.NewMockOffsetResponse(t).
+			SetOffset("my_topic", 0, sarama.OffsetOldest, 10).
+			SetOffset("my_topic", 0, sarama.OffsetNewest, 100).
+			SetOffset("my_topic", 1, sarama.OffsetOldest, 10).
+			SetOffset("my_topic", 1, sarama.OffsetNewest, 100),
+	})
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_1_0_0
+	admin, err := sarama.NewClusterAdmin([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return admin, broker
+}
+
+func TestGroupLag(t *testing.T) {
+	admin, broker := newTestAdmin(t)
+	defer broker.Close()
+	defer admin.Close()
+
+	rows, err := groupLags(admin, "my_group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rows) != 1 {
+		t.Fatalf("expected the lag of a partition, got %+v", rows)
+	}
+	if r := rows[0]; r.Topic != "my_topic" || r.Partition != 0 || r.Committed != 40 || r.End != 100 || r.Lag != 60 ||
+		r.member != "member-1" || r.clientID != "client-1" {
+		t.Errorf("unexpected lag: %+v", r)
+	}
+
+	var buf bytes.Buffer
+	if err := groupLag(admin, &buf, "my_group"); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "Total lag of group my_group: 60") {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestDescribeGroups(t *testing.T) {
+	admin, broker := newTestAdmin(t)
+	defer broker.Close()
+	defer admin.Close()
+
+	var buf bytes.Buffer
+	if err := describeGroups(admin, &buf, []string{"my_group"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, expected := range []string{"GROUP my_group: Stable, protocol consumer/range, 1 members", "member-1", "my_topic:0"} {
+		if !strings.Contains(buf.String(), expected) {
+			t.Errorf("expected %q in output:\n%s", expected, buf.String())
+		}
+	}
+}
+
+func TestResetToOffsetDryRun(t *testing.T) {
+	admin, broker := newTestAdmin(t)
+	defer broker.Close()
+	defer admin.Close()
+
+	var topics topicPartitionsFlag
+	if err := topics.Set("my_topic"); err != nil {
+		t.Fatal(err)
+	}
+
+	var buf bytes.Buffer
+	if err := resetOffsets(admin, &buf, "my_group", topics, resetTarget{offset: 5, specific: true}, false); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 3 || strings.Fields(lines[1])[2] != "10" || strings.Fields(lines[2])[2] != "10" {
+		t.Errorf("expected offset 5 to be clamped to 10:\n%s", buf.String())
+	}
+	if !strings.Contains(buf.String(), "Dry run") {
+		t.Errorf("expected a dry run:\n%s", buf.String())
+	}
+	for _, entry := range broker.History() {
+		if _, ok := entry.Request.(*sarama.OffsetCommitRequest); ok {
+			t.Error("expected no offsets to be committed in a dry run")
+		}
+	}
+}
@@ -0,0 +1,256 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/tools/sasl"
+	"github.com/Shopify/sarama/tools/tls"
+)
+
+var (
+	brokerList    = flag.String("brokers", os.Getenv("KAFKA_PEERS"), "The comma separated list of brokers in the Kafka cluster. You can also set the KAFKA_PEERS environment variable")
+	version       = flag.String("version", sarama.DefaultVersion.String(), "The version of Kafka")
+	verbose       = flag.Bool("verbose", false, "Turn on sarama logging to stderr")
+	tlsEnabled    = flag.Bool("tls-enabled", false, "Whether to enable TLS")
+	tlsSkipVerify = flag.Bool("tls-skip-verify", false, "Whether skip TLS server cert verification")
+	tlsClientCert = flag.String("tls-client-cert", "", "Client cert for client authentication (use with -tls-enabled and -tls-client-key)")
+	tlsClientKey  = flag.String("tls-client-key", "", "Client key for client authentication (use with tls-enabled and -tls-client-cert)")
+	saslMechanism = flag.String("sasl-mechanism", "", "The SASL mechanism to authenticate with. Can be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512")
+	saslUser      = flag.String("sasl-user", "", "The user to authenticate as (use with -sasl-mechanism)")
+	saslPassword  = flag.String("sasl-password", os.Getenv("KAFKA_PASSWORD"), "The password to authenticate with (use with -sasl-mechanism). You can also set the KAFKA_PASSWORD environment variable")
+
+	logger = log.New(os.Stderr, "", log.LstdFlags)
+)
+
+// commands are the commands of the tool. Each parses its own flags and
+// returns the function running it with the cluster admin.
+var commands = map[string]func(args []string) func(admin sarama.ClusterAdmin) error{
+	"list":          runList,
+	"describe":      runDescribe,
+	"lag":           runLag,
+	"reset-offsets": runResetOffsets,
+}
+
+// commandNames lists the commands in the order of the usage.
+var commandNames = []string{"list", "describe", "lag", "reset-offsets"}
+
+var commandUsages = map[string]string{
+	"list":          "List the consumer groups",
+	"describe":      "Describe the state and members of consumer groups",
+	"lag":           "Show the committed offsets and lag of a consumer group",
+	"reset-offsets": "Reset the offsets of an empty consumer group",
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		printUsageErrorAndExit("no command specified")
+	}
+	parse, ok := commands[flag.Arg(0)]
+	if !ok {
+		printUsageErrorAndExit(fmt.Sprintf("unknown command %q", flag.Arg(0)))
+	}
+	command := parse(flag.Args()[1:])
+
+	if *brokerList == "" {
+		printUsageErrorAndExit("no -brokers specified")
+	}
+
+	if *verbose {
+		sarama.Logger = logger
+	}
+
+	kafkaVersion, err := sarama.ParseKafkaVersion(*version)
+	if err != nil {
+		printUsageErrorAndExit(fmt.Sprintf("Invalid -version: %s", err))
+	}
+
+	config := sarama.NewConfig()
+	config.Version = kafkaVersion
+
+	if *tlsEnabled {
+		tlsConfig, err := tls.NewConfig(*tlsClientCert, *tlsClientKey)
+		if err != nil {
+			printErrorAndExit(69, "Failed to create TLS config: %s", err)
+		}
+
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+		config.Net.TLS.Config.InsecureSkipVerify = *tlsSkipVerify
+	}
+
+	if err := sasl.Configure(config, *saslMechanism, *saslUser, *saslPassword); err != nil {
+		printUsageErrorAndExit(err.Error())
+	}
+
+	admin, err := sarama.NewClusterAdmin(strings.Split(*brokerList, ","), config)
+	if err != nil {
+		printErrorAndExit(69, "Failed to connect to the cluster: %s", err)
+	}
+	defer func() {
+		if err := admin.Close(); err != nil {
+			logger.Println("Failed to close the cluster admin:", err)
+		}
+	}()
+
+	if err := command(admin); err != nil {
+		_ = admin.Close()
+		printErrorAndExit(69, "%s", err)
+	}
+}
+
+// commandFlags returns the flag set of the command, exiting with its usage
+// on parsing errors.
+func commandFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] %s [command options]\n\n", os.Args[0], name)
+		fmt.Fprintln(os.Stderr, commandUsages[name]+".")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Command options:")
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+func runList(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("list")
+	states := flags.String("states", "", "The comma separated list of states to list the groups in, e.g. Stable,Empty (requires -version 2.6.0 or later)")
+	_ = flags.Parse(args)
+
+	var opts sarama.ListConsumerGroupsOptions
+	if *states != "" {
+		opts.States = strings.Split(*states, ",")
+	}
+	return func(admin sarama.ClusterAdmin) error {
+		return listGroups(admin, os.Stdout, opts)
+	}
+}
+
+func runDescribe(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("describe")
+	groups := flags.String("group", "", "REQUIRED: the comma separated list of groups to describe")
+	_ = flags.Parse(args)
+
+	if *groups == "" {
+		printCommandUsageErrorAndExit(flags, "no -group specified")
+	}
+	return func(admin sarama.ClusterAdmin) error {
+		return describeGroups(admin, os.Stdout, strings.Split(*groups, ","))
+	}
+}
+
+func runLag(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("lag")
+	group := flags.String("group", "", "REQUIRED: the group to show the lag of")
+	_ = flags.Parse(args)
+
+	if *group == "" {
+		printCommandUsageErrorAndExit(flags, "no -group specified")
+	}
+	return func(admin sarama.ClusterAdmin) error {
+		return groupLag(admin, os.Stdout, *group)
+	}
+}
+
+func runResetOffsets(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("reset-offsets")
+	group := flags.String("group", "", "REQUIRED: the group to reset the offsets of")
+	var topics topicPartitionsFlag
+	flags.Var(&topics, "topic", "The topic to reset the offsets of, optionally followed by a colon and the comma separated list of its partitions, e.g. orders:0,1. Can be repeated")
+	allTopics := flags.Bool("all-topics", false, "Reset the offsets of every topic the group committed offsets for")
+	toEarliest := flags.Bool("to-earliest", false, "Reset the offsets to the earliest offsets")
+	toLatest := flags.Bool("to-latest", false, "Reset the offsets to the latest offsets")
+	toDatetime := flags.String("to-datetime", "", "Reset the offsets to the first messages produced at or after the time, in RFC 3339 format, e.g. 2006-01-02T15:04:05Z")
+	toOffset := flags.String("to-offset", "", "Reset the offsets to the offset, clamped to the range of offsets of each partition")
+	execute := flags.Bool("execute", false, "Commit the new offsets, rather than only printing them")
+	_ = flags.Parse(args)
+
+	if *group == "" {
+		printCommandUsageErrorAndExit(flags, "no -group specified")
+	}
+	if (len(topics) == 0) == !*allTopics {
+		printCommandUsageErrorAndExit(flags, "exactly one of -topic and -all-topics must be specified")
+	}
+
+	var target resetTarget
+	targets := 0
+	if *toEarliest {
+		target = resetTarget{time: sarama.OffsetOldest}
+		targets++
+	}
+	if *toLatest {
+		target = resetTarget{time: sarama.OffsetNewest}
+		targets++
+	}
+	if *toDatetime != "" {
+		t, err := time.Parse(time.RFC3339Nano, *toDatetime)
+		if err != nil {
+			printCommandUsageErrorAndExit(flags, fmt.Sprintf("Invalid -to-datetime: %s", err))
+		}
+		target = resetTarget{time: t.UnixNano() / int64(time.Millisecond)}
+		targets++
+	}
+	if *toOffset != "" {
+		offset, err := strconv.ParseInt(*toOffset, 10, 64)
+		if err != nil {
+			printCommandUsageErrorAndExit(flags, fmt.Sprintf("Invalid -to-offset: %s", err))
+		}
+		target = resetTarget{offset: offset, specific: true}
+		targets++
+	}
+	if targets != 1 {
+		printCommandUsageErrorAndExit(flags, "exactly one of -to-earliest, -to-latest, -to-datetime and -to-offset must be specified")
+	}
+
+	return func(admin sarama.ClusterAdmin) error {
+		if *allTopics {
+			var err error
+			if topics, err = committedTopics(admin, *group); err != nil {
+				return err
+			}
+		}
+		return resetOffsets(admin, os.Stdout, *group, topics, target, *execute)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [options] <command> [command options]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range commandNames {
+		fmt.Fprintf(os.Stderr, "  %-15s %s\n", name, commandUsages[name])
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintf(os.Stderr, "Run %s <command> -help for the options of a command.\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Options:")
+	flag.PrintDefaults()
+}
+
+func printErrorAndExit(code int, format string, values ...interface{}) {
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", fmt.Sprintf(format, values...))
+	fmt.Fprintln(os.Stderr)
+	os.Exit(code)
+}
+
+func printUsageErrorAndExit(message string) {
+	fmt.Fprintln(os.Stderr, "ERROR:", message)
+	fmt.Fprintln(os.Stderr)
+	usage()
+	os.Exit(64)
+}
+
+func printCommandUsageErrorAndExit(flags *flag.FlagSet, message string) {
+	fmt.Fprintln(os.Stderr, "ERROR:", message)
+	fmt.Fprintln(os.Stderr)
+	flags.Usage()
+	os.Exit(64)
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// exporter exports the offsets the lags of the sarama.LagMonitor are computed
+// from, along with the outcome of its checks; the lags themselves are
+// exported by the promsarama.Recorder of the client.
+type exporter struct {
+	committed *prometheus.Desc
+	end       *prometheus.Desc
+	checks    *prometheus.CounterVec
+	lastCheck *prometheus.GaugeVec
+	now       func() time.Time
+
+	// failures are the groups whose current check failed, the LagMonitor
+	// calling OnError for them before OnCheck, one check at a time
+	failures map[string]bool
+
+	lock sync.Mutex
+	lags []sarama.PartitionLag
+}
+
+// newExporter returns an exporter of metrics in the namespace, to be
+// registered with prometheus.Registerer.
+func newExporter(namespace string) *exporter {
+	return &exporter{
+		committed: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "consumer_group_committed_offset"),
+			"Offsets committed by the consumer groups, by partition.",
+			[]string{sarama.MetricLabelGroup, sarama.MetricLabelTopic, sarama.MetricLabelPartition}, nil,
+		),
+		end: prometheus.NewDesc(
+			prometheus.BuildFQName(namespace, "", "partition_end_offset"),
+			"Offsets of the messages to be produced next to the partitions the consumer groups committed offsets for.",
+			[]string{sarama.MetricLabelTopic, sarama.MetricLabelPartition}, nil,
+		),
+		checks: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "consumer_group_lag_checks_total",
+			Help:      "Checks of the lags of the consumer groups, by result.",
+		}, []string{sarama.MetricLabelGroup, "result"}),
+		lastCheck: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "consumer_group_lag_last_check_timestamp_seconds",
+			Help:      "Time of the last successful check of the lags of the consumer groups.",
+		}, []string{sarama.MetricLabelGroup}),
+		now:      time.Now,
+		failures: make(map[string]bool),
+	}
+}
+
+func (e *exporter) Describe(ch chan<- *prometheus.Desc) {
+	ch <- e.committed
+	ch <- e.end
+	e.checks.Describe(ch)
+	e.lastCheck.Describe(ch)
+}
+
+func (e *exporter) Collect(ch chan<- prometheus.Metric) {
+	e.lock.Lock()
+	lags := e.lags
+	e.lock.Unlock()
+
+	// the partitions are repeated for each group consuming them
+	ends := make(map[string]map[int32]bool)
+	for _, lag := range lags {
+		partition := strconv.Itoa(int(lag.Partition))
+		ch <- prometheus.MustNewConstMetric(e.committed, prometheus.GaugeValue, float64(lag.Committed), lag.Group, lag.Topic, partition)
+		if ends[lag.Topic] == nil {
+			ends[lag.Topic] = make(map[int32]bool)
+		}
+		if !ends[lag.Topic][lag.Partition] {
+			ends[lag.Topic][lag.Partition] = true
+			ch <- prometheus.MustNewConstMetric(e.end, prometheus.GaugeValue, float64(lag.End), lag.Topic, partition)
+		}
+	}
+	e.checks.Collect(ch)
+	e.lastCheck.Collect(ch)
+}
+
+// observe records the offsets of a check of the groups, replacing those of
+// the previous check. It is the OnCheck of the LagMonitor.
+func (e *exporter) observe(groups []string, lags []sarama.PartitionLag) {
+	e.lock.Lock()
+	e.lags = lags
+	e.lock.Unlock()
+
+	now := float64(e.now().UnixNano()) / float64(time.Second)
+	for _, group := range groups {
+		if e.failures[group] {
+			e.checks.WithLabelValues(group, "failure").Inc()
+			continue
+		}
+		e.checks.WithLabelValues(group, "success").Inc()
+		e.lastCheck.WithLabelValues(group).Set(now)
+	}
+	e.failures = make(map[string]bool)
+}
+
+// failed records the failure of the current check of the group, whose
+// offsets are those of its previous check. It is the OnError of the
+// LagMonitor.
+func (e *exporter) failed(group string, err error) {
+	logger.Printf("Failed to check the lag of group %s: %s\n", group, err)
+	e.failures[group] = true
+}
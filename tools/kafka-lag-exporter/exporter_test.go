@@ -0,0 +1,53 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestExporter(t *testing.T) {
+	e := newExporter("sarama")
+	e.now = func() time.Time { return time.Unix(1600000000, 0) }
+	registry := prometheus.NewPedanticRegistry()
+	registry.MustRegister(e)
+
+	groups := []string{"group-a", "group-b"}
+	e.observe(groups, []sarama.PartitionLag{
+		{Group: "group-a", Topic: "orders", Partition: 0, Committed: 40, End: 100, Lag: 60},
+		{Group: "group-b", Topic: "orders", Partition: 0, Committed: 90, End: 100, Lag: 10},
+	})
+	e.failed("group-b", errors.New("coordinator not available"))
+	e.observe(groups, []sarama.PartitionLag{
+		{Group: "group-a", Topic: "orders", Partition: 1, Committed: 5, End: 7, Lag: 2},
+		{Group: "group-b", Topic: "orders", Partition: 0, Committed: 90, End: 100, Lag: 10},
+	})
+
+	expected := `
+# HELP sarama_consumer_group_committed_offset Offsets committed by the consumer groups, by partition.
+# TYPE sarama_consumer_group_committed_offset gauge
+sarama_consumer_group_committed_offset{group="group-a",partition="1",topic="orders"} 5
+sarama_consumer_group_committed_offset{group="group-b",partition="0",topic="orders"} 90
+# HELP sarama_consumer_group_lag_checks_total Checks of the lags of the consumer groups, by result.
+# TYPE sarama_consumer_group_lag_checks_total counter
+sarama_consumer_group_lag_checks_total{group="group-a",result="success"} 2
+sarama_consumer_group_lag_checks_total{group="group-b",result="failure"} 1
+sarama_consumer_group_lag_checks_total{group="group-b",result="success"} 1
+# HELP sarama_consumer_group_lag_last_check_timestamp_seconds Time of the last successful check of the lags of the consumer groups.
+# TYPE sarama_consumer_group_lag_last_check_timestamp_seconds gauge
+sarama_consumer_group_lag_last_check_timestamp_seconds{group="group-a"} 1.6e+09
+sarama_consumer_group_lag_last_check_timestamp_seconds{group="group-b"} 1.6e+09
+# HELP sarama_partition_end_offset Offsets of the messages to be produced next to the partitions the consumer groups committed offsets for.
+# TYPE sarama_partition_end_offset gauge
+sarama_partition_end_offset{partition="0",topic="orders"} 100
+sarama_partition_end_offset{partition="1",topic="orders"} 7
+`
+	if err := testutil.GatherAndCompare(registry, strings.NewReader(expected)); err != nil {
+		t.Error(err)
+	}
+}
@@ -0,0 +1,14 @@
+module github.com/Shopify/sarama/tools/kafka-lag-exporter
+
+go 1.16
+
+replace (
+	github.com/Shopify/sarama => ../../
+	github.com/Shopify/sarama/promsarama => ../../promsarama
+)
+
+require (
+	github.com/Shopify/sarama v1.32.0
+	github.com/Shopify/sarama/promsarama v0.0.0-00010101000000-000000000000
+	github.com/prometheus/client_golang v1.12.2
+)
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/promsarama"
+	"github.com/Shopify/sarama/tools/sasl"
+	"github.com/Shopify/sarama/tools/tls"
+)
+
+var (
+	brokerList    = flag.String("brokers", os.Getenv("KAFKA_PEERS"), "The comma separated list of brokers in the Kafka cluster. You can also set the KAFKA_PEERS environment variable")
+	groups        = flag.String("groups", "", "REQUIRED: the comma separated list of the consumer groups to export the lag of")
+	interval      = flag.Duration("interval", 30*time.Second, "The interval between two checks of the lags")
+	listenAddress = flag.String("listen-address", ":9308", "The address to serve the metrics on")
+	metricsPath   = flag.String("metrics-path", "/metrics", "The path to serve the metrics on")
+	namespace     = flag.String("namespace", "sarama", "The namespace of the metrics")
+	version       = flag.String("version", sarama.V2_0_0_0.String(), "The version of Kafka, at least 0.10.2.0")
+	verbose       = flag.Bool("verbose", false, "Turn on sarama logging to stderr")
+	tlsEnabled    = flag.Bool("tls-enabled", false, "Whether to enable TLS")
+	tlsSkipVerify = flag.Bool("tls-skip-verify", false, "Whether skip TLS server cert verification")
+	tlsClientCert = flag.String("tls-client-cert", "", "Client cert for client authentication (use with -tls-enabled and -tls-client-key)")
+	tlsClientKey  = flag.String("tls-client-key", "", "Client key for client authentication (use with tls-enabled and -tls-client-cert)")
+	saslMechanism = flag.String("sasl-mechanism", "", "The SASL mechanism to authenticate with. Can be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512")
+	saslUser      = flag.String("sasl-user", "", "The user to authenticate as (use with -sasl-mechanism)")
+	saslPassword  = flag.String("sasl-password", os.Getenv("KAFKA_PASSWORD"), "The password to authenticate with (use with -sasl-mechanism). You can also set the KAFKA_PASSWORD environment variable")
+
+	logger = log.New(os.Stderr, "", log.LstdFlags)
+)
+
+func main() {
+	flag.Parse()
+
+	if *brokerList == "" {
+		printUsageErrorAndExit("no -brokers specified")
+	}
+	if *groups == "" {
+		printUsageErrorAndExit("no -groups specified")
+	}
+	if *interval <= 0 {
+		printUsageErrorAndExit("-interval must be greater than 0")
+	}
+
+	if *verbose {
+		sarama.Logger = logger
+	}
+
+	kafkaVersion, err := sarama.ParseKafkaVersion(*version)
+	if err != nil {
+		printUsageErrorAndExit(fmt.Sprintf("Invalid -version: %s", err))
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	recorder := promsarama.NewRecorder(promsarama.WithNamespace(*namespace))
+	registry.MustRegister(recorder)
+	exporter := newExporter(*namespace)
+	registry.MustRegister(exporter)
+
+	config := sarama.NewConfig()
+	config.Version = kafkaVersion
+	config.MetricsRecorder = recorder
+
+	if *tlsEnabled {
+		tlsConfig, err := tls.NewConfig(*tlsClientCert, *tlsClientKey)
+		if err != nil {
+			printErrorAndExit(69, "Failed to create TLS config: %s", err)
+		}
+
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+		config.Net.TLS.Config.InsecureSkipVerify = *tlsSkipVerify
+	}
+
+	if err := sasl.Configure(config, *saslMechanism, *saslUser, *saslPassword); err != nil {
+		printUsageErrorAndExit(err.Error())
+	}
+
+	client, err := sarama.NewClient(strings.Split(*brokerList, ","), config)
+	if err != nil {
+		printErrorAndExit(69, "Failed to connect to the cluster: %s", err)
+	}
+
+	groupList := strings.Split(*groups, ",")
+	monitor, err := sarama.NewLagMonitor(client, groupList, sarama.LagMonitorOptions{
+		Interval: *interval,
+		OnCheck: func(lags []sarama.PartitionLag) {
+			exporter.observe(groupList, lags)
+		},
+		OnError: exporter.failed,
+	})
+	if err != nil {
+		_ = client.Close()
+		printUsageErrorAndExit(fmt.Sprintf("Failed to monitor the lags: %s", err))
+	}
+
+	mux := http.NewServeMux()
+	mux.Handle(*metricsPath, promhttp.HandlerFor(registry, promhttp.HandlerOpts{ErrorLog: logger}))
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+		fmt.Fprintf(w, "<html><body><h1>Kafka lag exporter</h1><p><a href=%q>Metrics</a></p></body></html>\n", *metricsPath)
+	})
+	server := &http.Server{Addr: *listenAddress, Handler: mux}
+
+	closing := make(chan os.Signal, 1)
+	signal.Notify(closing, syscall.SIGINT, syscall.SIGTERM)
+	go func() {
+		<-closing
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := server.Shutdown(ctx); err != nil {
+			logger.Println("Failed to shut down the server:", err)
+		}
+	}()
+
+	logger.Printf("Exporting the lags of %s on %s%s\n", strings.Join(groupList, ", "), *listenAddress, *metricsPath)
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		_ = monitor.Close()
+		_ = client.Close()
+		printErrorAndExit(69, "Failed to serve the metrics: %s", err)
+	}
+
+	if err := monitor.Close(); err != nil {
+		logger.Println("Failed to stop monitoring the lags:", err)
+	}
+	if err := client.Close(); err != nil {
+		logger.Println("Failed to close the client:", err)
+	}
+}
+
+func printErrorAndExit(code int, format string, values ...interface{}) {
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", fmt.Sprintf(format, values...))
+	fmt.Fprintln(os.Stderr)
+	os.Exit(code)
+}
+
+func printUsageErrorAndExit(message string) {
+	fmt.Fprintln(os.Stderr, "ERROR:", message)
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Available command line options:")
+	flag.PrintDefaults()
+	os.Exit(64)
+}
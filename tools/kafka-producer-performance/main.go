@@ -150,6 +150,16 @@ var (
 		"0.8.2.0",
 		"The assumed version of Kafka.",
 	)
+	reportInterval = flag.Duration(
+		"report-interval",
+		5*time.Second,
+		"The interval at which to print the metrics and the throughput of the interval.",
+	)
+	reportFile = flag.String(
+		"report-file",
+		"",
+		"The file to write a JSON summary of the run to, with the throughput over time and the produce latency percentiles (- for stdout).",
+	)
 	verbose = flag.Bool(
 		"verbose",
 		false,
@@ -234,6 +244,9 @@ func main() {
 	if *routines < 1 || *routines > *messageLoad {
 		printUsageErrorAndExit("-routines must be greater than 0 and less than or equal to -message-load")
 	}
+	if *reportInterval <= 0 {
+		printUsageErrorAndExit("-report-interval must be greater than 0")
+	}
 	if *securityProtocol != "PLAINTEXT" && *securityProtocol != "SSL" {
 		printUsageErrorAndExit(fmt.Sprintf("-security-protocol %q is not supported", *securityProtocol))
 	}
@@ -286,15 +299,47 @@ func main() {
 		printErrorAndExit(69, "Invalid configuration: %s", err)
 	}
 
+	var report io.WriteCloser
+	switch *reportFile {
+	case "":
+	case "-":
+		report = os.Stdout
+	default:
+		var err error
+		if report, err = os.Create(*reportFile); err != nil {
+			printErrorAndExit(66, "Failed to create report file: %s", err)
+		}
+	}
+	brokers := strings.Split(*brokers, ",")
+	var asyncMessages []*sarama.ProducerMessage
+	var syncMessages [][]*sarama.ProducerMessage
+	if *sync {
+		syncMessages = make([][]*sarama.ProducerMessage, *routines)
+		for i := 0; i < *routines; i++ {
+			load := *messageLoad / *routines
+			if i == *routines-1 {
+				load += *messageLoad % *routines
+			}
+			syncMessages[i] = generateMessages(*topic, *partition, load, *messageSize)
+		}
+	} else {
+		asyncMessages = generateMessages(*topic, *partition, *messageLoad, *messageSize)
+	}
+
+	// The messages generated, start the clock.
+	stats := newStats(time.Now(), *messageLoad)
+
 	// Print out metrics periodically.
 	done := make(chan struct{})
 	ctx, cancel := context.WithCancel(context.Background())
 	go func(ctx context.Context) {
 		defer close(done)
-		t := time.Tick(5 * time.Second)
+		t := time.NewTicker(*reportInterval)
+		defer t.Stop()
 		for {
 			select {
-			case <-t:
+			case now := <-t.C:
+				printInterval(os.Stdout, stats.tick(now))
 				printMetrics(os.Stdout, config.MetricRegistry)
 			case <-ctx.Done():
 				return
@@ -302,13 +347,10 @@ func main() {
 		}
 	}(ctx)
 
-	brokers := strings.Split(*brokers, ",")
 	if *sync {
-		runSyncProducer(*topic, *partition, *messageLoad, *messageSize, *routines,
-			config, brokers, *throughput)
+		runSyncProducer(syncMessages, config, brokers, *throughput, stats)
 	} else {
-		runAsyncProducer(*topic, *partition, *messageLoad, *messageSize,
-			config, brokers, *throughput)
+		runAsyncProducer(asyncMessages, config, brokers, *throughput, stats)
 	}
 
 	cancel()
@@ -316,10 +358,43 @@ func main() {
 
 	// Print final metrics.
 	printMetrics(os.Stdout, config.MetricRegistry)
+	sum := stats.summarize(time.Now(), runConfig())
+	printSummary(os.Stdout, sum)
+
+	if report != nil {
+		if err := writeSummary(report, sum); err != nil {
+			printErrorAndExit(74, "Failed to write report: %s", err)
+		}
+		if report != os.Stdout {
+			if err := report.Close(); err != nil {
+				printErrorAndExit(74, "Failed to write report: %s", err)
+			}
+		}
+	}
+}
+
+// runConfig returns the settings of the run, for its JSON summary.
+func runConfig() map[string]interface{} {
+	return map[string]interface{}{
+		"sync":               *sync,
+		"routines":           *routines,
+		"message_load":       *messageLoad,
+		"message_size":       *messageSize,
+		"throughput":         *throughput,
+		"required_acks":      *requiredAcks,
+		"partitioner":        *partitioner,
+		"compression":        *compression,
+		"flush_frequency":    flushFrequency.String(),
+		"flush_bytes":        *flushBytes,
+		"flush_messages":     *flushMessages,
+		"flush_max_messages": *flushMaxMessages,
+		"max_open_requests":  *maxOpenRequests,
+		"version":            *version,
+	}
 }
 
-func runAsyncProducer(topic string, partition, messageLoad, messageSize int,
-	config *sarama.Config, brokers []string, throughput int) {
+func runAsyncProducer(messages []*sarama.ProducerMessage,
+	config *sarama.Config, brokers []string, throughput int, stats *stats) {
 	producer, err := sarama.NewAsyncProducer(brokers, config)
 	if err != nil {
 		printErrorAndExit(69, "Failed to create producer: %s", err)
@@ -330,14 +405,13 @@ func runAsyncProducer(topic string, partition, messageLoad, messageSize int,
 		}
 	}()
 
-	messages := generateMessages(topic, partition, messageLoad, messageSize)
-
 	messagesDone := make(chan struct{})
 	go func() {
-		for i := 0; i < messageLoad; i++ {
+		for range messages {
 			select {
-			case <-producer.Successes():
-			case err = <-producer.Errors():
+			case msg := <-producer.Successes():
+				stats.record(time.Since(msg.Metadata.(time.Time)), msg.Value.Length())
+			case err := <-producer.Errors():
 				printErrorAndExit(69, "%s", err)
 			}
 		}
@@ -347,6 +421,7 @@ func runAsyncProducer(topic string, partition, messageLoad, messageSize int,
 	if throughput > 0 {
 		ticker := time.NewTicker(time.Second)
 		for idx, message := range messages {
+			message.Metadata = time.Now()
 			producer.Input() <- message
 			if (idx+1)%throughput == 0 {
 				<-ticker.C
@@ -355,6 +430,7 @@ func runAsyncProducer(topic string, partition, messageLoad, messageSize int,
 		ticker.Stop()
 	} else {
 		for _, message := range messages {
+			message.Metadata = time.Now()
 			producer.Input() <- message
 		}
 	}
@@ -363,8 +439,8 @@ func runAsyncProducer(topic string, partition, messageLoad, messageSize int,
 	close(messagesDone)
 }
 
-func runSyncProducer(topic string, partition, messageLoad, messageSize, routines int,
-	config *sarama.Config, brokers []string, throughput int) {
+func runSyncProducer(messages [][]*sarama.ProducerMessage,
+	config *sarama.Config, brokers []string, throughput int, stats *stats) {
 	producer, err := sarama.NewSyncProducer(brokers, config)
 	if err != nil {
 		printErrorAndExit(69, "Failed to create producer: %s", err)
@@ -375,15 +451,6 @@ func runSyncProducer(topic string, partition, messageLoad, messageSize, routines
 		}
 	}()
 
-	messages := make([][]*sarama.ProducerMessage, routines)
-	for i := 0; i < routines; i++ {
-		if i == routines-1 {
-			messages[i] = generateMessages(topic, partition, messageLoad/routines+messageLoad%routines, messageSize)
-		} else {
-			messages[i] = generateMessages(topic, partition, messageLoad/routines, messageSize)
-		}
-	}
-
 	var wg gosync.WaitGroup
 	if throughput > 0 {
 		for _, messages := range messages {
@@ -393,10 +460,7 @@ func runSyncProducer(topic string, partition, messageLoad, messageSize, routines
 				ticker := time.NewTicker(time.Second)
 				for _, message := range messages {
 					for i := 0; i < throughput; i++ {
-						_, _, err = producer.SendMessage(message)
-						if err != nil {
-							printErrorAndExit(69, "Failed to send message: %s", err)
-						}
+						sendMessage(producer, message, stats)
 					}
 					<-ticker.C
 				}
@@ -410,10 +474,7 @@ func runSyncProducer(topic string, partition, messageLoad, messageSize, routines
 			wg.Add(1)
 			go func() {
 				for _, message := range messages {
-					_, _, err = producer.SendMessage(message)
-					if err != nil {
-						printErrorAndExit(69, "Failed to send message: %s", err)
-					}
+					sendMessage(producer, message, stats)
 				}
 				wg.Done()
 			}()
@@ -422,6 +483,14 @@ func runSyncProducer(topic string, partition, messageLoad, messageSize, routines
 	wg.Wait()
 }
 
+func sendMessage(producer sarama.SyncProducer, message *sarama.ProducerMessage, stats *stats) {
+	start := time.Now()
+	if _, _, err := producer.SendMessage(message); err != nil {
+		printErrorAndExit(69, "Failed to send message: %s", err)
+	}
+	stats.record(time.Since(start), message.Value.Length())
+}
+
 func printMetrics(w io.Writer, r metrics.Registry) {
 	recordSendRateMetric := r.Get("record-send-rate")
 	requestLatencyMetric := r.Get("request-latency-in-ms")
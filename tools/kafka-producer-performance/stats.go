@@ -0,0 +1,190 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	gosync "sync"
+	"time"
+)
+
+// stats records the produce latency of every message, from its handing to
+// the producer to its acknowledgement, and the throughput over the intervals
+// of the run.
+type stats struct {
+	lock      gosync.Mutex
+	start     time.Time
+	latencies []time.Duration
+	bytes     int64
+
+	intervalStart   time.Time
+	intervalRecords int64
+	intervalBytes   int64
+	intervals       []interval
+}
+
+// interval is the throughput over an interval of the run.
+type interval struct {
+	ElapsedSeconds float64 `json:"elapsed_seconds"`
+	Records        int64   `json:"records"`
+	RecordsPerSec  float64 `json:"records_per_sec"`
+	MiBPerSec      float64 `json:"mib_per_sec"`
+}
+
+// latencySummary holds the produce latencies in milliseconds.
+type latencySummary struct {
+	Min  float64 `json:"min"`
+	Mean float64 `json:"mean"`
+	P50  float64 `json:"p50"`
+	P95  float64 `json:"p95"`
+	P99  float64 `json:"p99"`
+	P999 float64 `json:"p999"`
+	Max  float64 `json:"max"`
+}
+
+// summary sums up a run, written as JSON with -report-file for tracking the
+// performance across runs.
+type summary struct {
+	Config          map[string]interface{} `json:"config"`
+	Records         int64                  `json:"records"`
+	Bytes           int64                  `json:"bytes"`
+	DurationSeconds float64                `json:"duration_seconds"`
+	RecordsPerSec   float64                `json:"records_per_sec"`
+	MiBPerSec       float64                `json:"mib_per_sec"`
+	LatencyMs       latencySummary         `json:"latency_ms"`
+	Intervals       []interval             `json:"intervals"`
+}
+
+// newStats returns stats of a run starting at start, sized for messages.
+func newStats(start time.Time, messages int) *stats {
+	return &stats{
+		start:         start,
+		intervalStart: start,
+		latencies:     make([]time.Duration, 0, messages),
+	}
+}
+
+// record records the acknowledgement of a message of size bytes produced
+// with latency.
+func (s *stats) record(latency time.Duration, size int) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	s.latencies = append(s.latencies, latency)
+	s.bytes += int64(size)
+	s.intervalRecords++
+	s.intervalBytes += int64(size)
+}
+
+// tick ends the current interval at now and returns its throughput.
+func (s *stats) tick(now time.Time) interval {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.endInterval(now)
+}
+
+func (s *stats) endInterval(now time.Time) interval {
+	i := interval{
+		ElapsedSeconds: now.Sub(s.start).Seconds(),
+		Records:        s.intervalRecords,
+	}
+	if elapsed := now.Sub(s.intervalStart).Seconds(); elapsed > 0 {
+		i.RecordsPerSec = float64(s.intervalRecords) / elapsed
+		i.MiBPerSec = float64(s.intervalBytes) / elapsed / 1024 / 1024
+	}
+	s.intervals = append(s.intervals, i)
+	s.intervalStart = now
+	s.intervalRecords = 0
+	s.intervalBytes = 0
+	return i
+}
+
+// summarize sums up the run ended at now, ending its last interval.
+func (s *stats) summarize(now time.Time, config map[string]interface{}) summary {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	if s.intervalRecords > 0 {
+		s.endInterval(now)
+	}
+
+	sum := summary{
+		Config:          config,
+		Records:         int64(len(s.latencies)),
+		Bytes:           s.bytes,
+		DurationSeconds: now.Sub(s.start).Seconds(),
+		Intervals:       s.intervals,
+	}
+	if sum.DurationSeconds > 0 {
+		sum.RecordsPerSec = float64(sum.Records) / sum.DurationSeconds
+		sum.MiBPerSec = float64(sum.Bytes) / sum.DurationSeconds / 1024 / 1024
+	}
+	if sum.Intervals == nil {
+		sum.Intervals = []interval{}
+	}
+	if len(s.latencies) == 0 {
+		return sum
+	}
+
+	sorted := make([]time.Duration, len(s.latencies))
+	copy(sorted, s.latencies)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i] < sorted[j] })
+	var total time.Duration
+	for _, latency := range sorted {
+		total += latency
+	}
+	sum.LatencyMs = latencySummary{
+		Min:  milliseconds(sorted[0]),
+		Mean: milliseconds(total / time.Duration(len(sorted))),
+		P50:  milliseconds(percentile(sorted, 0.5)),
+		P95:  milliseconds(percentile(sorted, 0.95)),
+		P99:  milliseconds(percentile(sorted, 0.99)),
+		P999: milliseconds(percentile(sorted, 0.999)),
+		Max:  milliseconds(sorted[len(sorted)-1]),
+	}
+	return sum
+}
+
+// percentile returns the nearest-rank p percentile of the sorted latencies.
+func percentile(sorted []time.Duration, p float64) time.Duration {
+	rank := int(math.Ceil(p * float64(len(sorted))))
+	if rank < 1 {
+		rank = 1
+	}
+	return sorted[rank-1]
+}
+
+func milliseconds(d time.Duration) float64 {
+	return float64(d) / float64(time.Millisecond)
+}
+
+func printInterval(w io.Writer, i interval) {
+	fmt.Fprintf(w, "%.1fs: %d records acknowledged, %.1f records/sec (%.2f MiB/sec)\n",
+		i.ElapsedSeconds, i.Records, i.RecordsPerSec, i.MiBPerSec)
+}
+
+func printSummary(w io.Writer, sum summary) {
+	fmt.Fprintf(w, "%d records sent in %.1fs, %.1f records/sec (%.2f MiB/sec), "+
+		"produce latency: %.1f ms avg, %.1f ms min, %.1f ms 50th, %.1f ms 95th, "+
+		"%.1f ms 99th, %.1f ms 99.9th, %.1f ms max\n",
+		sum.Records,
+		sum.DurationSeconds,
+		sum.RecordsPerSec,
+		sum.MiBPerSec,
+		sum.LatencyMs.Mean,
+		sum.LatencyMs.Min,
+		sum.LatencyMs.P50,
+		sum.LatencyMs.P95,
+		sum.LatencyMs.P99,
+		sum.LatencyMs.P999,
+		sum.LatencyMs.Max,
+	)
+}
+
+func writeSummary(w io.Writer, sum summary) error {
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(sum)
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestStatsSummary(t *testing.T) {
+	start := time.Unix(0, 0)
+	s := newStats(start, 1000)
+	for i := 1; i <= 1000; i++ {
+		s.record(time.Duration(i)*time.Millisecond, 100)
+	}
+
+	first := s.tick(start.Add(2 * time.Second))
+	if first.Records != 1000 || first.RecordsPerSec != 500 || first.ElapsedSeconds != 2 {
+		t.Errorf("unexpected first interval: %+v", first)
+	}
+	s.record(500500*time.Microsecond, 100)
+
+	sum := s.summarize(start.Add(3*time.Second), map[string]interface{}{"sync": false})
+	if sum.Records != 1001 || sum.Bytes != 100100 || sum.DurationSeconds != 3 {
+		t.Errorf("unexpected totals: %+v", sum)
+	}
+	if len(sum.Intervals) != 2 || sum.Intervals[1].Records != 1 || sum.Intervals[1].RecordsPerSec != 1 {
+		t.Errorf("unexpected intervals: %+v", sum.Intervals)
+	}
+	expected := latencySummary{Min: 1, Mean: 500.5, P50: 500.5, P95: 950, P99: 990, P999: 999, Max: 1000}
+	if sum.LatencyMs != expected {
+		t.Errorf("expected latencies %+v, got %+v", expected, sum.LatencyMs)
+	}
+
+	var buf bytes.Buffer
+	if err := writeSummary(&buf, sum); err != nil {
+		t.Fatal(err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &decoded); err != nil {
+		t.Fatal(err)
+	}
+	if decoded["latency_ms"].(map[string]interface{})["p99"] != 990.0 {
+		t.Errorf("unexpected JSON summary: %s", buf.String())
+	}
+}
+
+func TestStatsSummaryWithoutRecords(t *testing.T) {
+	start := time.Unix(0, 0)
+	sum := newStats(start, 0).summarize(start.Add(time.Second), nil)
+	if sum.Records != 0 || sum.Intervals == nil || sum.LatencyMs != (latencySummary{}) {
+		t.Errorf("unexpected summary: %+v", sum)
+	}
+}
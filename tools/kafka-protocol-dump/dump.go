@@ -0,0 +1,328 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/protocol"
+)
+
+// message is a request or a response, as dumped.
+type message struct {
+	Time time.Time `json:"time"`
+	// Client and Broker are the endpoints of the connection.
+	Client string `json:"client"`
+	Broker string `json:"broker"`
+	// Type is "request" or "response".
+	Type          string `json:"type"`
+	API           string `json:"api,omitempty"`
+	APIKey        int16  `json:"api_key"`
+	APIVersion    int16  `json:"api_version"`
+	CorrelationID int32  `json:"correlation_id"`
+	// ClientID is the client ID of a request, when not null.
+	ClientID *string `json:"client_id,omitempty"`
+	// Size is the size of the frame, size included.
+	Size int `json:"size"`
+	// Body is the decoded body, a string when it cannot be encoded to JSON.
+	Body interface{} `json:"body,omitempty"`
+	// Error is the error decoding the frame, or capturing it.
+	Error string `json:"error,omitempty"`
+}
+
+// apiVersion is the API key and version of a request waiting for its
+// response.
+type apiVersion struct {
+	key, version int16
+}
+
+// decodeRequest decodes the request frame, headers included.
+func decodeRequest(frame []byte) *message {
+	m := &message{Type: "request", Size: len(frame), APIKey: -1}
+	if len(frame) < 12 {
+		m.Error = "request too short"
+		return m
+	}
+	m.APIKey = int16(binary.BigEndian.Uint16(frame[4:]))
+	m.APIVersion = int16(binary.BigEndian.Uint16(frame[6:]))
+	m.CorrelationID = int32(binary.BigEndian.Uint32(frame[8:]))
+	m.API = sarama.ProtocolAPIName(m.APIKey)
+
+	req, err := protocol.ReadRequest(bytes.NewReader(frame))
+	if err != nil {
+		m.Error = err.Error()
+		return m
+	}
+	m.ClientID = req.ClientID
+	m.Body = req.Body
+	return m
+}
+
+// decodeResponse decodes the response frame, headers included, to a request
+// of the API key and version.
+func decodeResponse(frame []byte, key, version int16) *message {
+	m := &message{Type: "response", Size: len(frame), APIKey: key, APIVersion: version, API: sarama.ProtocolAPIName(key)}
+	if len(frame) < 8 {
+		m.Error = "response too short"
+		return m
+	}
+	m.CorrelationID = int32(binary.BigEndian.Uint32(frame[4:]))
+
+	res, err := protocol.ReadResponse(bytes.NewReader(frame), key, version)
+	if err != nil {
+		m.Error = err.Error()
+		return m
+	}
+	m.Body = res.Body
+	return m
+}
+
+// dumper decodes the frames exchanged with the brokers and writes them to a
+// printer.
+type dumper struct {
+	printer *printer
+	// apis are the API keys to dump, all of them when nil.
+	apis map[int16]bool
+	// ports are the ports of the brokers, to tell the requests from the
+	// responses in a capture.
+	ports map[uint16]bool
+
+	streams map[string]*stream
+	// requests are the requests waiting for their response, by connection
+	// and correlation ID.
+	requests map[string]map[int32]apiVersion
+}
+
+func newDumper(p *printer, apis map[int16]bool, ports map[uint16]bool) *dumper {
+	return &dumper{
+		printer:  p,
+		apis:     apis,
+		ports:    ports,
+		streams:  make(map[string]*stream),
+		requests: make(map[string]map[int32]apiVersion),
+	}
+}
+
+// dumpPcap dumps the traffic with the brokers captured in the pcap file.
+func (d *dumper) dumpPcap(r io.Reader) error {
+	p, err := newPcapReader(r)
+	if err != nil {
+		return err
+	}
+	for {
+		seg, err := p.next()
+		if err == io.EOF {
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := d.segment(seg); err != nil {
+			return err
+		}
+	}
+}
+
+// segment dumps the frames completed by the TCP segment.
+func (d *dumper) segment(seg *segment) error {
+	var client, broker string
+	isRequest := d.ports[seg.dstPort]
+	switch {
+	case isRequest:
+		client, broker = seg.src, seg.dst
+	case d.ports[seg.srcPort]:
+		client, broker = seg.dst, seg.src
+	default:
+		return nil
+	}
+	conn := client + " " + broker
+
+	key := seg.src + " " + seg.dst
+	s := d.streams[key]
+	if s == nil {
+		maxSize := sarama.MaxResponseSize
+		if isRequest {
+			maxSize = sarama.MaxRequestSize
+		}
+		s = newStream(maxSize)
+		d.streams[key] = s
+	}
+	if seg.syn && isRequest {
+		delete(d.requests, conn)
+	}
+
+	frames, err := s.add(seg)
+	for _, frame := range frames {
+		var m *message
+		if isRequest {
+			m = d.request(conn, frame)
+		} else {
+			m = d.response(conn, frame)
+		}
+		m.Time, m.Client, m.Broker = seg.time, client, broker
+		if err := d.print(m); err != nil {
+			return err
+		}
+	}
+	if err != nil {
+		typ := "response"
+		if isRequest {
+			typ = "request"
+		}
+		m := &message{Time: seg.time, Client: client, Broker: broker, Type: typ, APIKey: -1, Error: err.Error()}
+		if err := d.print(m); err != nil {
+			return err
+		}
+	}
+
+	if seg.fin || seg.rst {
+		delete(d.streams, key)
+	}
+	return nil
+}
+
+// request decodes the request frame sent on the connection, remembering its
+// API for its response.
+func (d *dumper) request(conn string, frame []byte) *message {
+	m := decodeRequest(frame)
+	if m.APIKey < 0 {
+		return m
+	}
+	// requests expecting no response, e.g. produce requests with no acks, are
+	// only forgotten when the connection closes
+	if d.requests[conn] == nil {
+		d.requests[conn] = make(map[int32]apiVersion)
+	}
+	d.requests[conn][m.CorrelationID] = apiVersion{key: m.APIKey, version: m.APIVersion}
+	return m
+}
+
+// response decodes the response frame received on the connection, as a
+// response to the request of its correlation ID.
+func (d *dumper) response(conn string, frame []byte) *message {
+	if len(frame) < 8 {
+		return &message{Type: "response", Size: len(frame), APIKey: -1, Error: "response too short"}
+	}
+	correlationID := int32(binary.BigEndian.Uint32(frame[4:]))
+	req, ok := d.requests[conn][correlationID]
+	if !ok {
+		return &message{
+			Type:          "response",
+			Size:          len(frame),
+			APIKey:        -1,
+			CorrelationID: correlationID,
+			Error:         "response to a request missing from the capture",
+		}
+	}
+	delete(d.requests[conn], correlationID)
+	return decodeResponse(frame, req.key, req.version)
+}
+
+// dumpWireTap dumps the sarama.WireTapEntry lines written by
+// sarama.NewWireTapWriter.
+func (d *dumper) dumpWireTap(r io.Reader) error {
+	decoder := json.NewDecoder(r)
+	for {
+		var entry sarama.WireTapEntry
+		if err := decoder.Decode(&entry); err == io.EOF {
+			return nil
+		} else if err != nil {
+			return fmt.Errorf("failed to read the wire tap: %w", err)
+		}
+
+		broker := entry.Broker
+		if entry.BrokerID >= 0 {
+			broker += "/" + strconv.Itoa(int(entry.BrokerID))
+		}
+		req := decodeRequest(entry.Request)
+		req.Time, req.Client, req.Broker = entry.Time, entry.ConnectionID, broker
+		if err := d.print(req); err != nil {
+			return err
+		}
+
+		var res *message
+		switch {
+		case entry.Response != nil:
+			res = decodeResponse(entry.Response, entry.APIKey, entry.APIVersion)
+		case entry.Error != "":
+			res = &message{
+				Type:          "response",
+				API:           sarama.ProtocolAPIName(entry.APIKey),
+				APIKey:        entry.APIKey,
+				APIVersion:    entry.APIVersion,
+				CorrelationID: entry.CorrelationID,
+				Error:         entry.Error,
+			}
+		default:
+			continue
+		}
+		res.Time, res.Client, res.Broker = entry.Time.Add(time.Duration(entry.LatencyNs)), entry.ConnectionID, broker
+		if err := d.print(res); err != nil {
+			return err
+		}
+	}
+}
+
+// print prints the message, unless its API is filtered out.
+func (d *dumper) print(m *message) error {
+	if d.apis != nil && !d.apis[m.APIKey] {
+		return nil
+	}
+	return d.printer.print(m)
+}
+
+// printer writes the messages as text or as JSON lines.
+type printer struct {
+	w    io.Writer
+	json bool
+}
+
+func (p *printer) print(m *message) error {
+	if m.Body != nil {
+		if _, err := json.Marshal(m.Body); err != nil {
+			m.Body = fmt.Sprintf("%+v", m.Body)
+		}
+	}
+	if p.json {
+		return json.NewEncoder(p.w).Encode(m)
+	}
+
+	from, to := m.Client, m.Broker
+	if m.Type == "response" {
+		from, to = to, from
+	}
+	var line strings.Builder
+	fmt.Fprintf(&line, "%s %s -> %s", m.Time.Format("2006-01-02T15:04:05.000000Z07:00"), from, to)
+	if m.APIKey >= 0 {
+		fmt.Fprintf(&line, " %s v%d %s correlation_id=%d", m.API, m.APIVersion, m.Type, m.CorrelationID)
+	} else {
+		fmt.Fprintf(&line, " %s", m.Type)
+	}
+	if m.ClientID != nil {
+		fmt.Fprintf(&line, " client_id=%q", *m.ClientID)
+	}
+	if m.Size > 0 {
+		fmt.Fprintf(&line, " size=%d", m.Size)
+	}
+	if m.Error != "" {
+		fmt.Fprintf(&line, " ERROR: %s", m.Error)
+	}
+	if _, err := fmt.Fprintln(p.w, line.String()); err != nil {
+		return err
+	}
+
+	if m.Body == nil {
+		return nil
+	}
+	body, err := json.MarshalIndent(m.Body, "", "  ")
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(p.w, "%s\n\n", body)
+	return err
+}
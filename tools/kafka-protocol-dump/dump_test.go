@@ -0,0 +1,154 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/protocol"
+)
+
+func encodeRequest(t *testing.T, req *protocol.Request) []byte {
+	var buf bytes.Buffer
+	if err := protocol.WriteRequest(&buf, req); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func encodeResponse(t *testing.T, res *protocol.Response) []byte {
+	var buf bytes.Buffer
+	if err := protocol.WriteResponse(&buf, res); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestDumpPcap(t *testing.T) {
+	clientID := "sarama"
+	request := encodeRequest(t, &protocol.Request{
+		CorrelationID: 7,
+		ClientID:      &clientID,
+		Body:          &sarama.MetadataRequest{Version: 1, Topics: []string{"my_topic"}},
+	})
+	response := encodeResponse(t, &protocol.Response{
+		CorrelationID: 7,
+		Body: &sarama.MetadataResponse{
+			Version:      1,
+			Brokers:      []*sarama.Broker{sarama.NewBroker("10.0.0.2:9092")},
+			ControllerID: 1,
+		},
+	})
+	unknown := []byte{0, 0, 0, 8, 0, 0, 0, 9, 0, 0, 0, 0}
+
+	start := time.Unix(1600000000, 0)
+	client, broker := "10.0.0.1:50000", "10.0.0.2:9092"
+	w := newPcapWriter()
+	w.tcp(start, client, broker, 0, 0x02, nil)
+	w.tcp(start, broker, client, 1000, 0x12, nil)
+	// the request split in two segments
+	w.tcp(start.Add(time.Millisecond), client, broker, 1, 0x18, request[:10])
+	w.tcp(start.Add(2*time.Millisecond), client, broker, 11, 0x18, request[10:])
+	w.tcp(start.Add(3*time.Millisecond), broker, client, 1001, 0x18, response)
+	// a response to a request sent before the capture
+	w.tcp(start.Add(4*time.Millisecond), broker, client, 1001+uint32(len(response)), 0x18, unknown)
+	// traffic unrelated to the brokers
+	w.tcp(start, "10.0.0.1:50001", "10.0.0.3:443", 0, 0x18, []byte("GET /"))
+
+	var out bytes.Buffer
+	d := newDumper(&printer{w: &out}, nil, map[uint16]bool{9092: true})
+	if err := d.dumpPcap(&w.buf); err != nil {
+		t.Fatal(err)
+	}
+
+	lines := strings.Split(out.String(), "\n")
+	if !strings.HasSuffix(lines[0], "10.0.0.1:50000 -> 10.0.0.2:9092 Metadata v1 request correlation_id=7 client_id=\"sarama\" size="+strconv.Itoa(len(request))) {
+		t.Errorf("unexpected request line %q", lines[0])
+	}
+	var responseLine string
+	for _, line := range lines {
+		if strings.Contains(line, "Metadata v1 response") {
+			responseLine = line
+		}
+	}
+	if !strings.Contains(responseLine, "10.0.0.2:9092 -> 10.0.0.1:50000 Metadata v1 response correlation_id=7") {
+		t.Errorf("expected the response to be decoded, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "\"Topics\": [\n    \"my_topic\"\n  ]") {
+		t.Errorf("expected the body of the request, got:\n%s", out.String())
+	}
+	if !strings.Contains(out.String(), "response size=12 ERROR: response to a request missing from the capture") {
+		t.Errorf("expected the response to an unknown request to be reported, got:\n%s", out.String())
+	}
+}
+
+func TestDumpWireTap(t *testing.T) {
+	mb := sarama.NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).SetBroker(mb.Addr(), mb.BrokerID()),
+	})
+
+	var tap bytes.Buffer
+	config := sarama.NewConfig()
+	config.Version = sarama.V1_0_0_0
+	config.ApiVersionsRequest = false
+	config.Net.WireTap = sarama.NewWireTapWriter(&tap)
+	broker := sarama.NewBroker(mb.Addr())
+	if err := broker.Open(config); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.GetMetadata(&sarama.MetadataRequest{Version: 5, Topics: []string{"my_topic"}}); err != nil {
+		t.Fatal(err)
+	}
+	_ = broker.Close()
+
+	var out bytes.Buffer
+	d := newDumper(&printer{w: &out, json: true}, map[int16]bool{3: true}, nil)
+	if err := d.dumpWireTap(&tap); err != nil {
+		t.Fatal(err)
+	}
+
+	var messages []map[string]interface{}
+	decoder := json.NewDecoder(&out)
+	for decoder.More() {
+		var m map[string]interface{}
+		if err := decoder.Decode(&m); err != nil {
+			t.Fatal(err)
+		}
+		messages = append(messages, m)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected a request and a response, got %v", messages)
+	}
+	req, res := messages[0], messages[1]
+	if req["type"] != "request" || req["api"] != "Metadata" || req["api_version"] != 5.0 || req["broker"] != mb.Addr() || req["error"] != nil {
+		t.Errorf("unexpected request %v", req)
+	}
+	if res["type"] != "response" || res["correlation_id"] != req["correlation_id"] || res["error"] != nil {
+		t.Errorf("unexpected response %v", res)
+	}
+	if brokers := res["body"].(map[string]interface{})["Brokers"].([]interface{}); len(brokers) != 1 || brokers[0].(map[string]interface{})["addr"] != mb.Addr() {
+		t.Errorf("expected the brokers of the response, got %v", res["body"])
+	}
+}
+
+func TestParseAPIs(t *testing.T) {
+	keys, err := parseAPIs("metadata, FindCoordinator,18")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 3 || !keys[3] || !keys[10] || !keys[18] {
+		t.Errorf("unexpected keys %v", keys)
+	}
+	if _, err := parseAPIs("Metadata,Nope"); err == nil {
+		t.Error("expected an unknown API to be rejected")
+	}
+	if keys, err := parseAPIs(""); keys != nil || err != nil {
+		t.Errorf("expected all the APIs, got %v %v", keys, err)
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/Shopify/sarama"
+)
+
+var (
+	pcapFile    = flag.String("pcap", "", "The pcap file to decode, as written by tcpdump -w, or - for stdin")
+	wireTapFile = flag.String("wiretap", "", "The file of the records of the wire tap to decode, as written by sarama.NewWireTapWriter, or - for stdin")
+	ports       = flag.String("ports", "9092", "The comma separated ports of the brokers in the pcap file")
+	apis        = flag.String("apis", "", "The comma separated names or keys of the APIs to dump, e.g. Metadata,FindCoordinator or 3,10. All of them by default")
+	format      = flag.String("format", "text", "The output format. Can be text, or json for a JSON object per line")
+)
+
+func main() {
+	flag.Parse()
+
+	if (*pcapFile == "") == (*wireTapFile == "") {
+		printUsageErrorAndExit("Exactly one of -pcap and -wiretap is required")
+	}
+	if *format != "text" && *format != "json" {
+		printUsageErrorAndExit("-format should be text or json")
+	}
+	brokerPorts, err := parsePorts(*ports)
+	if err != nil {
+		printUsageErrorAndExit(fmt.Sprintf("Invalid -ports: %s", err))
+	}
+	apiKeys, err := parseAPIs(*apis)
+	if err != nil {
+		printUsageErrorAndExit(fmt.Sprintf("Invalid -apis: %s", err))
+	}
+
+	name := *pcapFile + *wireTapFile
+	var input io.Reader = os.Stdin
+	if name != "-" {
+		f, err := os.Open(name)
+		if err != nil {
+			printErrorAndExit(66, "Failed to open the input file: %s", err)
+		}
+		defer f.Close()
+		input = f
+	}
+
+	output := bufio.NewWriter(os.Stdout)
+	d := newDumper(&printer{w: output, json: *format == "json"}, apiKeys, brokerPorts)
+	if *pcapFile != "" {
+		err = d.dumpPcap(bufio.NewReader(input))
+	} else {
+		err = d.dumpWireTap(input)
+	}
+	if flushErr := output.Flush(); err == nil {
+		err = flushErr
+	}
+	if err != nil {
+		printErrorAndExit(65, "Failed to decode %s: %s", name, err)
+	}
+}
+
+// parsePorts parses the comma separated ports.
+func parsePorts(s string) (map[uint16]bool, error) {
+	ports := make(map[uint16]bool)
+	for _, port := range strings.Split(s, ",") {
+		n, err := strconv.ParseUint(strings.TrimSpace(port), 10, 16)
+		if err != nil {
+			return nil, fmt.Errorf("invalid port %q", port)
+		}
+		ports[uint16(n)] = true
+	}
+	return ports, nil
+}
+
+// maxAPIKey bounds the API keys looked up by name.
+const maxAPIKey = 256
+
+// parseAPIs parses the comma separated names or keys of the APIs, nil for all
+// of them.
+func parseAPIs(s string) (map[int16]bool, error) {
+	if s == "" {
+		return nil, nil
+	}
+	keys := make(map[int16]bool)
+	for _, api := range strings.Split(s, ",") {
+		api = strings.TrimSpace(api)
+		if key, err := strconv.ParseInt(api, 10, 16); err == nil {
+			keys[int16(key)] = true
+			continue
+		}
+		found := false
+		for key := int16(0); key < maxAPIKey; key++ {
+			if strings.EqualFold(sarama.ProtocolAPIName(key), api) {
+				keys[key], found = true, true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown API %q", api)
+		}
+	}
+	return keys, nil
+}
+
+func printErrorAndExit(code int, format string, values ...interface{}) {
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", fmt.Sprintf(format, values...))
+	fmt.Fprintln(os.Stderr)
+	os.Exit(code)
+}
+
+func printUsageErrorAndExit(message string) {
+	fmt.Fprintln(os.Stderr, "ERROR:", message)
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Available command line options:")
+	flag.PrintDefaults()
+	os.Exit(64)
+}
@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strconv"
+	"time"
+)
+
+// The link types of the pcap files, see https://www.tcpdump.org/linktypes.html.
+const (
+	linkTypeNull     = 0
+	linkTypeEthernet = 1
+	linkTypeRaw      = 101
+	linkTypeLoop     = 108
+	linkTypeLinuxSLL = 113
+	linkTypeSLL2     = 276
+	// linkTypeRawAlt is DLT_RAW, written instead of linkTypeRaw on some
+	// platforms.
+	linkTypeRawAlt = 12
+)
+
+// maxSnapLen bounds the size of the packets read, against corrupted files.
+const maxSnapLen = 1 << 20
+
+var errPcapNG = errors.New("pcapng files are not supported, convert them first with: editcap -F pcap in.pcapng out.pcap")
+
+// pcapReader reads the packets of a file in the classic libpcap format,
+// written by tcpdump -w.
+type pcapReader struct {
+	r        io.Reader
+	order    binary.ByteOrder
+	nanos    bool
+	linkType uint32
+}
+
+// segment is the payload of a TCP packet.
+type segment struct {
+	time             time.Time
+	src, dst         string
+	srcPort, dstPort uint16
+	seq              uint32
+	syn, fin, rst    bool
+	payload          []byte
+}
+
+func newPcapReader(r io.Reader) (*pcapReader, error) {
+	var header [24]byte
+	if _, err := io.ReadFull(r, header[:]); err != nil {
+		return nil, fmt.Errorf("failed to read the pcap header: %w", err)
+	}
+	p := &pcapReader{r: r}
+	switch magic := binary.LittleEndian.Uint32(header[:4]); magic {
+	case 0xa1b2c3d4:
+		p.order = binary.LittleEndian
+	case 0xa1b23c4d:
+		p.order, p.nanos = binary.LittleEndian, true
+	case 0xd4c3b2a1:
+		p.order = binary.BigEndian
+	case 0x4d3cb2a1:
+		p.order, p.nanos = binary.BigEndian, true
+	case 0x0a0d0d0a:
+		return nil, errPcapNG
+	default:
+		return nil, fmt.Errorf("not a pcap file, magic number %#x", magic)
+	}
+	p.linkType = p.order.Uint32(header[20:]) & 0xffff
+	switch p.linkType {
+	case linkTypeNull, linkTypeEthernet, linkTypeRaw, linkTypeRawAlt, linkTypeLoop, linkTypeLinuxSLL, linkTypeSLL2:
+	default:
+		return nil, fmt.Errorf("unsupported pcap link type %d", p.linkType)
+	}
+	return p, nil
+}
+
+// next returns the next TCP segment of the file, skipping the other packets,
+// and io.EOF at its end.
+func (p *pcapReader) next() (*segment, error) {
+	for {
+		var header [16]byte
+		if _, err := io.ReadFull(p.r, header[:]); err != nil {
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				return nil, fmt.Errorf("truncated pcap record header: %w", err)
+			}
+			return nil, err
+		}
+		length := p.order.Uint32(header[8:])
+		if length > maxSnapLen {
+			return nil, fmt.Errorf("pcap record of %d bytes too large", length)
+		}
+		data := make([]byte, length)
+		if _, err := io.ReadFull(p.r, data); err != nil {
+			return nil, fmt.Errorf("truncated pcap record: %w", err)
+		}
+
+		sec, frac := int64(p.order.Uint32(header[:])), int64(p.order.Uint32(header[4:]))
+		if !p.nanos {
+			frac *= int64(time.Microsecond)
+		}
+		if s := p.decode(data); s != nil {
+			s.time = time.Unix(sec, frac)
+			return s, nil
+		}
+	}
+}
+
+// decode returns the TCP segment of the packet, nil if it is not one.
+func (p *pcapReader) decode(data []byte) *segment {
+	var ethertype uint16
+	switch p.linkType {
+	case linkTypeEthernet:
+		if len(data) < 14 {
+			return nil
+		}
+		ethertype, data = binary.BigEndian.Uint16(data[12:]), data[14:]
+		// 802.1Q and 802.1ad VLAN tags
+		for (ethertype == 0x8100 || ethertype == 0x88a8) && len(data) >= 4 {
+			ethertype, data = binary.BigEndian.Uint16(data[2:]), data[4:]
+		}
+	case linkTypeLinuxSLL:
+		if len(data) < 16 {
+			return nil
+		}
+		ethertype, data = binary.BigEndian.Uint16(data[14:]), data[16:]
+	case linkTypeSLL2:
+		if len(data) < 20 {
+			return nil
+		}
+		ethertype, data = binary.BigEndian.Uint16(data), data[20:]
+	case linkTypeNull, linkTypeLoop:
+		// the address family, whose values depend on the platform; the
+		// version of the IP header tells IPv4 from IPv6 anyway
+		if len(data) < 4 {
+			return nil
+		}
+		data = data[4:]
+	}
+	if ethertype != 0 && ethertype != 0x0800 && ethertype != 0x86dd {
+		return nil
+	}
+	if len(data) == 0 {
+		return nil
+	}
+
+	var (
+		src, dst net.IP
+		tcp      []byte
+	)
+	switch data[0] >> 4 {
+	case 4:
+		if len(data) < 20 {
+			return nil
+		}
+		headerLength := int(data[0]&0x0f) * 4
+		totalLength := int(binary.BigEndian.Uint16(data[2:]))
+		// fragments, and the packets of other protocols than TCP
+		if data[9] != 6 || binary.BigEndian.Uint16(data[6:])&0x3fff != 0 ||
+			headerLength < 20 || totalLength < headerLength || totalLength > len(data) {
+			return nil
+		}
+		src, dst, tcp = net.IP(data[12:16]), net.IP(data[16:20]), data[headerLength:totalLength]
+	case 6:
+		if len(data) < 40 {
+			return nil
+		}
+		payloadLength := int(binary.BigEndian.Uint16(data[4:]))
+		if 40+payloadLength > len(data) {
+			return nil
+		}
+		src, dst = net.IP(data[8:24]), net.IP(data[24:40])
+		next, payload := data[6], data[40:40+payloadLength]
+		// the hop-by-hop, routing and destination options extension headers;
+		// fragments are skipped
+		for next == 0 || next == 43 || next == 60 {
+			if len(payload) < 8 || len(payload) < (int(payload[1])+1)*8 {
+				return nil
+			}
+			next, payload = payload[0], payload[(int(payload[1])+1)*8:]
+		}
+		if next != 6 {
+			return nil
+		}
+		tcp = payload
+	default:
+		return nil
+	}
+
+	if len(tcp) < 20 {
+		return nil
+	}
+	offset := int(tcp[12]>>4) * 4
+	if offset < 20 || offset > len(tcp) {
+		return nil
+	}
+	flags := tcp[13]
+	srcPort, dstPort := binary.BigEndian.Uint16(tcp), binary.BigEndian.Uint16(tcp[2:])
+	return &segment{
+		src:     net.JoinHostPort(src.String(), strconv.Itoa(int(srcPort))),
+		dst:     net.JoinHostPort(dst.String(), strconv.Itoa(int(dstPort))),
+		srcPort: srcPort,
+		dstPort: dstPort,
+		seq:     binary.BigEndian.Uint32(tcp[4:]),
+		fin:     flags&0x01 != 0,
+		syn:     flags&0x02 != 0,
+		rst:     flags&0x04 != 0,
+		payload: tcp[offset:],
+	}
+}
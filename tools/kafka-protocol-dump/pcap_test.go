@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"io"
+	"net"
+	"strconv"
+	"testing"
+	"time"
+)
+
+// pcapWriter writes a pcap file of Ethernet frames, for the tests.
+type pcapWriter struct {
+	buf bytes.Buffer
+}
+
+func newPcapWriter() *pcapWriter {
+	w := &pcapWriter{}
+	header := make([]byte, 24)
+	binary.LittleEndian.PutUint32(header, 0xa1b2c3d4)
+	binary.LittleEndian.PutUint16(header[4:], 2)
+	binary.LittleEndian.PutUint16(header[6:], 4)
+	binary.LittleEndian.PutUint32(header[16:], 65535)
+	binary.LittleEndian.PutUint32(header[20:], linkTypeEthernet)
+	w.buf.Write(header)
+	return w
+}
+
+// tcp writes a TCP segment over IPv4.
+func (w *pcapWriter) tcp(t time.Time, src, dst string, seq uint32, flags byte, payload []byte) {
+	srcIP, srcPort := splitEndpoint(src)
+	dstIP, dstPort := splitEndpoint(dst)
+
+	tcp := make([]byte, 20, 20+len(payload))
+	binary.BigEndian.PutUint16(tcp, srcPort)
+	binary.BigEndian.PutUint16(tcp[2:], dstPort)
+	binary.BigEndian.PutUint32(tcp[4:], seq)
+	tcp[12] = 5 << 4
+	tcp[13] = flags
+	tcp = append(tcp, payload...)
+
+	ip := make([]byte, 20, 20+len(tcp))
+	ip[0] = 0x45
+	binary.BigEndian.PutUint16(ip[2:], uint16(20+len(tcp)))
+	ip[8] = 64
+	ip[9] = 6
+	copy(ip[12:], srcIP)
+	copy(ip[16:], dstIP)
+	ip = append(ip, tcp...)
+
+	frame := make([]byte, 14, 14+len(ip))
+	binary.BigEndian.PutUint16(frame[12:], 0x0800)
+	frame = append(frame, ip...)
+
+	record := make([]byte, 16)
+	binary.LittleEndian.PutUint32(record, uint32(t.Unix()))
+	binary.LittleEndian.PutUint32(record[4:], uint32(t.Nanosecond()/1000))
+	binary.LittleEndian.PutUint32(record[8:], uint32(len(frame)))
+	binary.LittleEndian.PutUint32(record[12:], uint32(len(frame)))
+	w.buf.Write(record)
+	w.buf.Write(frame)
+}
+
+func splitEndpoint(endpoint string) (net.IP, uint16) {
+	host, port, _ := net.SplitHostPort(endpoint)
+	n, _ := strconv.Atoi(port)
+	return net.ParseIP(host).To4(), uint16(n)
+}
+
+func TestPcapReader(t *testing.T) {
+	start := time.Unix(1600000000, 123456000)
+	w := newPcapWriter()
+	w.tcp(start, "10.0.0.1:50000", "10.0.0.2:9092", 41, 0x02, nil)
+	w.tcp(start.Add(time.Second), "10.0.0.1:50000", "10.0.0.2:9092", 42, 0x18, []byte("hello"))
+
+	p, err := newPcapReader(&w.buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	syn, err := p.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !syn.syn || syn.seq != 41 || syn.src != "10.0.0.1:50000" || syn.dst != "10.0.0.2:9092" || syn.dstPort != 9092 || !syn.time.Equal(start) {
+		t.Errorf("unexpected SYN segment %+v", syn)
+	}
+	data, err := p.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if data.syn || data.seq != 42 || string(data.payload) != "hello" {
+		t.Errorf("unexpected data segment %+v", data)
+	}
+	if _, err := p.next(); err != io.EOF {
+		t.Errorf("expected the end of the file, got %v", err)
+	}
+
+	if _, err := newPcapReader(bytes.NewReader([]byte{0x0a, 0x0d, 0x0d, 0x0a, 24: 0})); err != errPcapNG {
+		t.Errorf("expected pcapng files to be rejected, got %v", err)
+	}
+}
+
+func TestStream(t *testing.T) {
+	frame := func(payload string) []byte {
+		b := make([]byte, 4, 4+len(payload))
+		binary.BigEndian.PutUint32(b, uint32(len(payload)))
+		return append(b, payload...)
+	}
+	data := append(frame("first"), frame("second")...)
+
+	s := newStream(1024)
+	if frames, err := s.add(&segment{syn: true, seq: 99}); frames != nil || err != nil {
+		t.Fatal(frames, err)
+	}
+	// out of order, then retransmitted
+	if frames, err := s.add(&segment{seq: 106, payload: data[6:]}); frames != nil || err != nil {
+		t.Fatal(frames, err)
+	}
+	frames, err := s.add(&segment{seq: 100, payload: data[:8]})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(frames) != 2 || string(frames[0][4:]) != "first" || string(frames[1][4:]) != "second" {
+		t.Errorf("unexpected frames %q", frames)
+	}
+	if frames, err := s.add(&segment{seq: 100, payload: data}); frames != nil || err != nil {
+		t.Error("expected the retransmission to be ignored", frames, err)
+	}
+
+	if _, err := s.add(&segment{seq: 100 + uint32(len(data)), payload: []byte{0xff, 0, 0, 0}}); err == nil {
+		t.Error("expected an invalid frame size to be reported")
+	}
+	if frames, err := s.add(&segment{seq: 104 + uint32(len(data)), payload: frame("third")}); frames != nil || err != nil {
+		t.Error("expected the stream out of sync to be ignored", frames, err)
+	}
+}
@@ -0,0 +1,103 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+)
+
+// maxPendingSegments bounds the segments a stream keeps while waiting for the
+// ones before them, beyond which the capture is assumed to have missed them.
+const maxPendingSegments = 256
+
+// stream reassembles one direction of a TCP connection into the frames of the
+// Kafka protocol, each prefixed by its size.
+type stream struct {
+	// maxSize is the size of the largest frame expected, larger sizes meaning
+	// the stream is not, or no longer, at the start of a frame.
+	maxSize int32
+
+	started bool
+	next    uint32
+	pending map[uint32][]byte
+	buf     []byte
+	// desync is set once the stream cannot be split into frames any longer.
+	desync bool
+}
+
+func newStream(maxSize int32) *stream {
+	return &stream{maxSize: maxSize, pending: make(map[uint32][]byte)}
+}
+
+// add adds the segment to the stream and returns the frames it completed. It
+// returns an error the first time the stream gets out of sync, after which
+// the rest of the stream is ignored.
+func (s *stream) add(seg *segment) ([][]byte, error) {
+	if seg.syn {
+		// a new connection, maybe between the same endpoints as an old one
+		s.started, s.next, s.desync = true, seg.seq+1, false
+		s.buf, s.pending = nil, make(map[uint32][]byte)
+		return nil, nil
+	}
+	if len(seg.payload) == 0 || s.desync {
+		return nil, nil
+	}
+	if !s.started {
+		// the capture started after the connection, hopefully between two
+		// frames
+		s.started, s.next = true, seg.seq
+	}
+
+	if int32(seg.seq-s.next) > 0 {
+		if len(s.pending) >= maxPendingSegments {
+			return nil, s.lose(fmt.Errorf("missing %d bytes of the stream", seg.seq-s.next))
+		}
+		s.pending[seg.seq] = append([]byte(nil), seg.payload...)
+		return nil, nil
+	}
+	s.append(seg.seq, seg.payload)
+	for progress := true; progress; {
+		progress = false
+		for seq, payload := range s.pending {
+			if int32(seq-s.next) <= 0 {
+				delete(s.pending, seq)
+				s.append(seq, payload)
+				progress = true
+			}
+		}
+	}
+
+	var frames [][]byte
+	for len(s.buf) >= 4 {
+		size := int32(binary.BigEndian.Uint32(s.buf))
+		if size < 4 || size > s.maxSize {
+			return frames, s.lose(fmt.Errorf("invalid frame size %d", size))
+		}
+		if len(s.buf) < 4+int(size) {
+			break
+		}
+		frames = append(frames, s.buf[:4+size:4+size])
+		s.buf = s.buf[4+size:]
+	}
+	if len(s.buf) == 0 {
+		s.buf = nil
+	}
+	return frames, nil
+}
+
+// append appends the payload starting at seq, minus what the stream already
+// has of it, to the buffer.
+func (s *stream) append(seq uint32, payload []byte) {
+	if overlap := int(s.next - seq); overlap > 0 {
+		if overlap >= len(payload) {
+			return
+		}
+		payload = payload[overlap:]
+	}
+	s.buf = append(s.buf, payload...)
+	s.next += uint32(len(payload))
+}
+
+func (s *stream) lose(err error) error {
+	s.desync, s.buf, s.pending = true, nil, nil
+	return fmt.Errorf("lost track of the frames: %w", err)
+}
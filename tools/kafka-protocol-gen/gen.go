@@ -0,0 +1,519 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"regexp"
+	"strings"
+)
+
+// primitive is how a primitive type of the definitions is held and encoded,
+// put and get naming the methods of packetEncoder and packetDecoder for the
+// types encoded the same in all versions.
+type primitive struct {
+	goType   string
+	put, get string
+}
+
+var primitives = map[string]primitive{
+	"bool":    {"bool", "putBool", "getBool"},
+	"int8":    {"int8", "putInt8", "getInt8"},
+	"int16":   {"int16", "putInt16", "getInt16"},
+	"uint16":  {"uint16", "", ""},
+	"int32":   {"int32", "putInt32", "getInt32"},
+	"int64":   {"int64", "putInt64", "getInt64"},
+	"float64": {"float64", "putFloat64", "getFloat64"},
+	"uuid":    {"Uuid", "", ""},
+	"string":  {"string", "", ""},
+	"bytes":   {"[]byte", "", ""},
+	"records": {"[]byte", "", ""},
+}
+
+// generator generates the Go code of a message.
+type generator struct {
+	msg *message
+	pkg string
+	// source is the file the message was read from
+	source string
+	// types collects the struct declarations and methods
+	types bytes.Buffer
+	// generated are the structs generated, those of commonStructs being
+	// shared by several fields
+	generated map[string]bool
+}
+
+// usesVersion matches the generated code using the version.
+var usesVersion = regexp.MustCompile(`\bversion\b`)
+
+func generate(msg *message, pkg, source string) ([]byte, error) {
+	g := &generator{msg: msg, pkg: pkg, source: source, generated: make(map[string]bool)}
+	g.genStruct(msg.Name, msg.Fields, msg.valid, true)
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Code generated by kafka-protocol-gen from %s. DO NOT EDIT.\n\n", source)
+	fmt.Fprintf(&out, "package %s\n\n", pkg)
+	out.Write(g.types.Bytes())
+	g.genProtocolMethods(&out)
+
+	src, err := format.Source(out.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("%s: formatting the generated code: %w\n%s", msg.Name, err, out.Bytes())
+	}
+	return src, nil
+}
+
+// structName is the name of the Go type of the struct typ, prefixed with the
+// name of the message as the definitions only scope them to the message.
+func (g *generator) structName(typ string) string {
+	return g.msg.Name + typ
+}
+
+func (g *generator) goType(f *field) string {
+	elem := primitives[f.elemType()].goType
+	switch {
+	case f.isStruct() && f.isArray():
+		elem = "*" + g.structName(f.elemType())
+	case f.isStruct():
+		elem = g.structName(f.elemType())
+	case f.elemType() == "string" && !f.isArray() && !f.nullable.empty():
+		elem = "*string"
+	}
+	if f.isArray() {
+		return "[]" + elem
+	}
+	return elem
+}
+
+// genStruct generates the declaration and the encode and decode methods of
+// the struct name, present in the versions ctx, and of the structs of its
+// fields. The message itself is the top struct, holding its Version.
+func (g *generator) genStruct(name string, fields []*field, ctx versions, top bool) {
+	if g.generated[name] {
+		return
+	}
+	g.generated[name] = true
+
+	w := &g.types
+	switch {
+	case top && g.msg.APIKey != nil:
+		fmt.Fprintf(w, "// %s is the %s of the Kafka protocol with the api key %d, in versions %s.\n", name, g.msg.Type, *g.msg.APIKey, ctx)
+	case top:
+		fmt.Fprintf(w, "// %s is the %s of the Kafka protocol, in versions %s.\n", name, g.msg.Type, ctx)
+	default:
+		fmt.Fprintf(w, "// %s is a %s of %s.\n", name, strings.TrimPrefix(name, g.msg.Name), g.msg.Name)
+	}
+	fmt.Fprintf(w, "type %s struct {\n", name)
+	if top {
+		w.WriteString("Version int16\n")
+	}
+	for _, f := range fields {
+		if f.About != "" {
+			fmt.Fprintf(w, "// %s\n", f.About)
+		}
+		fmt.Fprintf(w, "%s %s", f.Name, g.goType(f))
+		if present := f.present.intersect(ctx); present != ctx {
+			fmt.Fprintf(w, " // versions %s", present)
+		}
+		w.WriteString("\n")
+	}
+	w.WriteString("}\n\n")
+
+	recv := "s"
+	if top {
+		recv = "r"
+	}
+
+	var enc, dec bytes.Buffer
+	g.encodeFields(&enc, fields, recv, ctx)
+	g.decodeFields(&dec, fields, recv, ctx)
+
+	if top {
+		fmt.Fprintf(w, "func (%s *%s) encode(pe packetEncoder) error {\n", recv, name)
+		if usesVersion.Match(enc.Bytes()) {
+			fmt.Fprintf(w, "version := %s.Version\n", recv)
+		}
+	} else {
+		fmt.Fprintf(w, "func (%s *%s) encode(pe packetEncoder, version int16) error {\n", recv, name)
+	}
+	w.Write(enc.Bytes())
+	w.WriteString("return nil\n}\n\n")
+
+	if top {
+		fmt.Fprintf(w, "func (%s *%s) decode(pd packetDecoder, version int16) (err error) {\n", recv, name)
+		fmt.Fprintf(w, "%s.Version = version\n", recv)
+	} else {
+		fmt.Fprintf(w, "func (%s *%s) decode(pd packetDecoder, version int16) (err error) {\n", recv, name)
+	}
+	w.Write(dec.Bytes())
+	w.WriteString("return nil\n}\n\n")
+
+	for _, f := range fields {
+		switch {
+		case f.isStruct() && f.common:
+			// shared by fields present in other versions
+			g.genStruct(g.structName(f.elemType()), f.Fields, g.msg.valid, false)
+		case f.isStruct():
+			g.genStruct(g.structName(f.elemType()), f.Fields, f.present.intersect(ctx), false)
+		}
+	}
+}
+
+// genProtocolMethods generates the methods of protocolBody but
+// requiredVersion, the Kafka release of each version being unknown to the
+// definitions.
+func (g *generator) genProtocolMethods(w *bytes.Buffer) {
+	if g.msg.APIKey == nil {
+		return
+	}
+	name, valid := g.msg.Name, g.msg.valid
+	fmt.Fprintf(w, "func (r *%s) key() int16 {\nreturn %d\n}\n\n", name, *g.msg.APIKey)
+	fmt.Fprintf(w, "func (r *%s) version() int16 {\nreturn r.Version\n}\n\n", name)
+
+	classic, flexible := int16(1), int16(2)
+	if g.msg.Type == "response" {
+		classic, flexible = 0, 1
+		if *g.msg.APIKey == 18 {
+			// ApiVersionsResponse keeps the header v0 for the clients to
+			// read the versions the broker supports
+			flexible = 0
+		}
+	}
+	fmt.Fprintf(w, "func (r *%s) headerVersion() int16 {\n", name)
+	switch flex := g.msg.flexible.intersect(valid); {
+	case flex.empty():
+		fmt.Fprintf(w, "return %d\n}\n", classic)
+	case flex == valid || classic == flexible:
+		fmt.Fprintf(w, "return %d\n}\n", flexible)
+	default:
+		fmt.Fprintf(w, "if r.Version >= %d {\nreturn %d\n}\nreturn %d\n}\n", flex.low, flexible, classic)
+	}
+}
+
+// branch generates the code of in for the versions of ctx in split, and of
+// out for the others.
+func branch(w *bytes.Buffer, ctx, split versions, in, out func(ctx versions)) {
+	inside := split.intersect(ctx)
+	outside := ctx.below(split)
+	if inside.high < ctx.high {
+		// split doesn't reach the latest versions, out keeps ctx whole
+		outside = ctx
+	}
+	switch {
+	case inside.empty():
+		out(ctx)
+	case outside.empty():
+		in(ctx)
+	default:
+		fmt.Fprintf(w, "if %s {\n", inside.cond(ctx))
+		in(inside)
+		w.WriteString("} else {\n")
+		out(outside)
+		w.WriteString("}\n")
+	}
+}
+
+// inline returns the versions of ctx where f is encoded in place rather than
+// as a tagged field.
+func inline(f *field, ctx versions) versions {
+	return f.present.intersect(ctx).below(f.tagged)
+}
+
+func (g *generator) encodeFields(w *bytes.Buffer, fields []*field, recv string, ctx versions) {
+	for _, f := range fields {
+		present := inline(f, ctx)
+		if present.empty() {
+			continue
+		}
+		expr := recv + "." + f.Name
+		if cond := present.cond(ctx); cond != "" {
+			fmt.Fprintf(w, "if %s {\n", cond)
+			g.encodeField(w, f, expr, present)
+			w.WriteString("}\n")
+		} else {
+			g.encodeField(w, f, expr, present)
+		}
+	}
+
+	flexible := g.msg.flexible.intersect(ctx)
+	if flexible.empty() {
+		return
+	}
+	var tagged bytes.Buffer
+	for _, f := range fields {
+		present := f.present.intersect(f.tagged).intersect(flexible)
+		if present.empty() {
+			continue
+		}
+		expr := recv + "." + f.Name
+		conds := []string{g.nonDefault(f, expr)}
+		if cond := present.cond(flexible); cond != "" {
+			conds = append([]string{cond}, conds...)
+		}
+		fmt.Fprintf(&tagged, "if %s {\n", strings.Join(conds, " && "))
+		fmt.Fprintf(&tagged, "tagged = append(tagged, taggedField{tag: %d, value: encoderFunc(func(pe packetEncoder) error {\n", *f.Tag)
+		g.encodeField(&tagged, f, expr, present)
+		tagged.WriteString("return nil\n})})\n}\n")
+	}
+
+	cond := flexible.cond(ctx)
+	if cond != "" {
+		fmt.Fprintf(w, "if %s {\n", cond)
+	}
+	if tagged.Len() == 0 {
+		w.WriteString("pe.putEmptyTaggedFieldArray()\n")
+	} else {
+		w.WriteString("var tagged []taggedField\n")
+		w.Write(tagged.Bytes())
+		w.WriteString("if err := putTaggedFields(pe, tagged); err != nil {\nreturn err\n}\n")
+	}
+	if cond != "" {
+		w.WriteString("}\n")
+	}
+}
+
+func (g *generator) encodeField(w *bytes.Buffer, f *field, expr string, ctx versions) {
+	if !f.isArray() {
+		g.encodeValue(w, f, f.Type, expr, ctx, f.nullable)
+		return
+	}
+
+	putLength := func(put string) func(versions) {
+		return func(ctx versions) {
+			branch(w, ctx, f.nullable, func(versions) {
+				fmt.Fprintf(w, "if %s == nil {\n", expr)
+				g.checked(w, put, "-1")
+				w.WriteString("} else {\n")
+				g.checked(w, put, "len("+expr+")")
+				w.WriteString("}\n")
+			}, func(versions) {
+				g.checked(w, put, "len("+expr+")")
+			})
+		}
+	}
+	branch(w, ctx, g.msg.flexible, putLength("putCompactArrayLength"), putLength("putArrayLength"))
+
+	fmt.Fprintf(w, "for i := range %s {\n", expr)
+	g.encodeValue(w, f, f.elemType(), expr+"[i]", ctx, noVersions)
+	w.WriteString("}\n")
+}
+
+// checked generates the call to the packetEncoder method put, checking the
+// error of those returning one.
+func (g *generator) checked(w *bytes.Buffer, put, arg string) {
+	switch put {
+	case "putCompactArrayLength", "putUVarint", "putBool", "putInt8", "putInt16", "putInt32", "putInt64", "putFloat64":
+		fmt.Fprintf(w, "pe.%s(%s)\n", put, arg)
+	default:
+		fmt.Fprintf(w, "if err := pe.%s(%s); err != nil {\nreturn err\n}\n", put, arg)
+	}
+}
+
+// encodeValue generates the encoding of the value expr of type typ, an
+// element of f if f is an array.
+func (g *generator) encodeValue(w *bytes.Buffer, f *field, typ, expr string, ctx, nullable versions) {
+	if p, ok := primitives[typ]; ok && p.put != "" {
+		g.checked(w, p.put, expr)
+		return
+	}
+	pointer := typ == "string" && !f.isArray() && !f.nullable.empty()
+	switch typ {
+	case "uint16":
+		fmt.Fprintf(w, "pe.putInt16(int16(%s))\n", expr)
+	case "uuid":
+		fmt.Fprintf(w, "if err := %s.encode(pe); err != nil {\nreturn err\n}\n", expr)
+	case "string":
+		str := func(put, putNullable string) func(versions) {
+			return func(ctx versions) {
+				branch(w, ctx, nullable, func(versions) {
+					g.checked(w, putNullable, expr)
+				}, func(versions) {
+					if pointer {
+						fmt.Fprintf(w, "if %s == nil {\nreturn PacketEncodingError{\"%s.%s can't be null before versions %s\"}\n}\n", expr, g.msg.Name, f.Name, f.nullable)
+						g.checked(w, put, "*"+expr)
+					} else {
+						g.checked(w, put, expr)
+					}
+				})
+			}
+		}
+		branch(w, ctx, g.msg.flexible, str("putCompactString", "putNullableCompactString"), str("putString", "putNullableString"))
+	case "bytes", "records":
+		branch(w, ctx, g.msg.flexible, func(ctx versions) {
+			branch(w, ctx, nullable, func(versions) {
+				fmt.Fprintf(w, "if %s == nil {\npe.putUVarint(0)\n} else ", expr)
+				fmt.Fprintf(w, "if err := pe.putCompactBytes(%s); err != nil {\nreturn err\n}\n", expr)
+			}, func(versions) {
+				g.checked(w, "putCompactBytes", expr)
+			})
+		}, func(versions) {
+			g.checked(w, "putBytes", expr)
+		})
+	default:
+		fmt.Fprintf(w, "if err := %s.encode(pe, version); err != nil {\nreturn err\n}\n", expr)
+	}
+}
+
+// nonDefault returns the condition of the tagged field f holding another
+// value than its default, which isn't sent.
+func (g *generator) nonDefault(f *field, expr string) string {
+	def := strings.Trim(string(f.Default), `"`)
+	switch {
+	case f.isArray():
+		if !f.nullable.empty() {
+			return expr + " != nil"
+		}
+		return "len(" + expr + ") > 0"
+	case f.isStruct():
+		return "true"
+	}
+	switch f.Type {
+	case "string":
+		if !f.nullable.empty() {
+			return expr + " != nil"
+		}
+		return fmt.Sprintf("%s != %q", expr, def)
+	case "bytes", "records":
+		if !f.nullable.empty() {
+			return expr + " != nil"
+		}
+		return "len(" + expr + ") > 0"
+	case "uuid":
+		return expr + " != (Uuid{})"
+	case "bool":
+		if def == "" {
+			def = "false"
+		}
+		return fmt.Sprintf("%s != %s", expr, def)
+	default:
+		if def == "" {
+			def = "0"
+		}
+		return fmt.Sprintf("%s != %s", expr, def)
+	}
+}
+
+func (g *generator) decodeFields(w *bytes.Buffer, fields []*field, recv string, ctx versions) {
+	for _, f := range fields {
+		present := inline(f, ctx)
+		if present.empty() {
+			continue
+		}
+		expr := recv + "." + f.Name
+		if cond := present.cond(ctx); cond != "" {
+			fmt.Fprintf(w, "if %s {\n", cond)
+			g.decodeField(w, f, expr, present)
+			w.WriteString("}\n")
+		} else {
+			g.decodeField(w, f, expr, present)
+		}
+	}
+
+	flexible := g.msg.flexible.intersect(ctx)
+	if flexible.empty() {
+		return
+	}
+	var tagged bytes.Buffer
+	for _, f := range fields {
+		present := f.present.intersect(f.tagged).intersect(flexible)
+		if present.empty() {
+			continue
+		}
+		fmt.Fprintf(&tagged, "case %d:\n", *f.Tag)
+		g.decodeField(&tagged, f, recv+"."+f.Name, present)
+	}
+
+	cond := flexible.cond(ctx)
+	if cond != "" {
+		fmt.Fprintf(w, "if %s {\n", cond)
+	}
+	if tagged.Len() == 0 {
+		w.WriteString("if _, err = pd.getEmptyTaggedFieldArray(); err != nil {\nreturn err\n}\n")
+	} else {
+		w.WriteString("if err = getTaggedFields(pd, func(tag uint64, pd packetDecoder) (err error) {\nswitch tag {\n")
+		w.Write(tagged.Bytes())
+		w.WriteString("}\nreturn nil\n}); err != nil {\nreturn err\n}\n")
+	}
+	if cond != "" {
+		w.WriteString("}\n")
+	}
+}
+
+func (g *generator) decodeField(w *bytes.Buffer, f *field, expr string, ctx versions) {
+	if !f.isArray() {
+		g.decodeValue(w, f, f.Type, expr, ctx, f.nullable)
+		return
+	}
+
+	w.WriteString("{\nvar n int\n")
+	branch(w, ctx, g.msg.flexible, func(versions) {
+		w.WriteString("n, err = pd.getCompactArrayLength()\n")
+	}, func(versions) {
+		w.WriteString("n, err = pd.getArrayLength()\n")
+	})
+	w.WriteString("if err != nil {\nreturn err\n}\n")
+	fmt.Fprintf(w, "if n >= 0 {\n%s = make(%s, n)\n}\n", expr, g.goType(f))
+	w.WriteString("for i := 0; i < n; i++ {\n")
+	if f.isStruct() {
+		fmt.Fprintf(w, "%s[i] = new(%s)\n", expr, g.structName(f.elemType()))
+	}
+	g.decodeValue(w, f, f.elemType(), expr+"[i]", ctx, noVersions)
+	w.WriteString("}\n}\n")
+}
+
+// decodeValue generates the decoding of the value expr of type typ, an
+// element of f if f is an array.
+func (g *generator) decodeValue(w *bytes.Buffer, f *field, typ, expr string, ctx, nullable versions) {
+	if p, ok := primitives[typ]; ok && p.get != "" {
+		fmt.Fprintf(w, "if %s, err = pd.%s(); err != nil {\nreturn err\n}\n", expr, p.get)
+		return
+	}
+	pointer := typ == "string" && !f.isArray() && !f.nullable.empty()
+	switch typ {
+	case "uint16":
+		fmt.Fprintf(w, "{\nv, err := pd.getInt16()\nif err != nil {\nreturn err\n}\n%s = uint16(v)\n}\n", expr)
+	case "uuid":
+		fmt.Fprintf(w, "if err = %s.decode(pd); err != nil {\nreturn err\n}\n", expr)
+	case "string":
+		str := func(get, getNullable string) func(versions) {
+			return func(ctx versions) {
+				branch(w, ctx, nullable, func(versions) {
+					fmt.Fprintf(w, "if %s, err = pd.%s(); err != nil {\nreturn err\n}\n", expr, getNullable)
+				}, func(versions) {
+					if pointer {
+						fmt.Fprintf(w, "{\nv, err := pd.%s()\nif err != nil {\nreturn err\n}\n%s = &v\n}\n", get, expr)
+					} else {
+						fmt.Fprintf(w, "if %s, err = pd.%s(); err != nil {\nreturn err\n}\n", expr, get)
+					}
+				})
+			}
+		}
+		branch(w, ctx, g.msg.flexible, str("getCompactString", "getCompactNullableString"), str("getString", "getNullableString"))
+	case "bytes", "records":
+		branch(w, ctx, g.msg.flexible, func(ctx versions) {
+			branch(w, ctx, nullable, func(versions) {
+				fmt.Fprintf(w, "{\nn, err := pd.getUVarint()\nif err != nil {\nreturn err\n}\n")
+				fmt.Fprintf(w, "if n > 0 {\nif %s, err = pd.getRawBytes(int(n - 1)); err != nil {\nreturn err\n}\n}\n}\n", expr)
+			}, func(versions) {
+				fmt.Fprintf(w, "if %s, err = pd.getCompactBytes(); err != nil {\nreturn err\n}\n", expr)
+			})
+		}, func(versions) {
+			fmt.Fprintf(w, "if %s, err = pd.getBytes(); err != nil {\nreturn err\n}\n", expr)
+		})
+	default:
+		fmt.Fprintf(w, "if err = %s.decode(pd, version); err != nil {\nreturn err\n}\n", expr)
+	}
+}
+
+// fileName is the name of the generated file of the message name, in the
+// snake case of the hand-written ones.
+func fileName(name string) string {
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && 'A' <= r && r <= 'Z' {
+			b.WriteByte('_')
+		}
+		b.WriteRune(r)
+	}
+	return strings.ToLower(b.String()) + "_generated.go"
+}
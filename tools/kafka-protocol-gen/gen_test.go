@@ -0,0 +1,70 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseVersions(t *testing.T) {
+	for s, expected := range map[string]versions{
+		"":     noVersions,
+		"none": noVersions,
+		"3":    {3, 3},
+		"1-3":  {1, 3},
+		"2+":   {2, 32767},
+	} {
+		v, err := parseVersions(s)
+		if err != nil {
+			t.Errorf("%q: %v", s, err)
+		} else if v != expected {
+			t.Errorf("%q: expected %s, got %s", s, expected, v)
+		}
+	}
+}
+
+func TestVersionsCond(t *testing.T) {
+	within := versions{0, 5}
+	for _, tc := range []struct {
+		v    versions
+		cond string
+	}{
+		{versions{0, 32767}, ""},
+		{versions{2, 32767}, "version >= 2"},
+		{versions{0, 1}, "version <= 1"},
+		{versions{2, 3}, "version >= 2 && version <= 3"},
+		{versions{3, 3}, "version == 3"},
+		{versions{6, 32767}, "false"},
+	} {
+		if cond := tc.v.cond(within); cond != tc.cond {
+			t.Errorf("%s within %s: expected %q, got %q", tc.v, within, tc.cond, cond)
+		}
+	}
+}
+
+// TestGenerated checks that the generated files of sarama are up to date with
+// the definitions and the generator.
+func TestGenerated(t *testing.T) {
+	paths, err := filepath.Glob("messages/*.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range paths {
+		msg, err := readMessage(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		src, err := generate(msg, "sarama", filepath.Base(path))
+		if err != nil {
+			t.Fatalf("%s: %v", path, err)
+		}
+		current, err := os.ReadFile(filepath.Join("..", "..", fileName(msg.Name)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !bytes.Equal(src, current) {
+			t.Errorf("%s is stale, run go generate", fileName(msg.Name))
+		}
+	}
+}
@@ -0,0 +1,63 @@
+// Command kafka-protocol-gen generates the Go code encoding and decoding the
+// messages of the Kafka protocol from their JSON definitions.
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+var (
+	pkg = flag.String(
+		"package",
+		"sarama",
+		"The package of the generated code.",
+	)
+	out = flag.String(
+		"out",
+		".",
+		"The directory the generated files are written to.",
+	)
+)
+
+func main() {
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] definition.json...\n", os.Args[0])
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		printUsageErrorAndExit("at least one message definition is required")
+	}
+
+	for _, path := range flag.Args() {
+		msg, err := readMessage(path)
+		if err != nil {
+			printErrorAndExit(69, "Failed to read the message definition: %s", err)
+		}
+		src, err := generate(msg, *pkg, filepath.Base(path))
+		if err != nil {
+			printErrorAndExit(69, "Failed to generate %s: %s", msg.Name, err)
+		}
+		if err := os.WriteFile(filepath.Join(*out, fileName(msg.Name)), src, 0o644); err != nil {
+			printErrorAndExit(69, "Failed to write %s: %s", msg.Name, err)
+		}
+	}
+}
+
+func printErrorAndExit(code int, format string, values ...interface{}) {
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", fmt.Sprintf(format, values...))
+	fmt.Fprintln(os.Stderr)
+	os.Exit(code)
+}
+
+func printUsageErrorAndExit(message string) {
+	fmt.Fprintln(os.Stderr, "ERROR:", message)
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintln(os.Stderr, "Available command line options:")
+	flag.PrintDefaults()
+	os.Exit(64)
+}
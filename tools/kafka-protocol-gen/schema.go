@@ -0,0 +1,233 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// message is a Kafka message definition, as found in
+// clients/src/main/resources/common/message of the Kafka sources.
+type message struct {
+	APIKey           *int16   `json:"apiKey"`
+	Type             string   `json:"type"`
+	Name             string   `json:"name"`
+	ValidVersions    string   `json:"validVersions"`
+	FlexibleVersions string   `json:"flexibleVersions"`
+	Fields           []*field `json:"fields"`
+	CommonStructs    []*struct {
+		Name     string   `json:"name"`
+		Versions string   `json:"versions"`
+		Fields   []*field `json:"fields"`
+	} `json:"commonStructs"`
+
+	valid, flexible versions
+}
+
+// field is a field of a message or of one of its structs.
+type field struct {
+	Name             string          `json:"name"`
+	Type             string          `json:"type"`
+	Versions         string          `json:"versions"`
+	NullableVersions string          `json:"nullableVersions"`
+	TaggedVersions   string          `json:"taggedVersions"`
+	Tag              *int            `json:"tag"`
+	Default          json.RawMessage `json:"default"`
+	About            string          `json:"about"`
+	Fields           []*field        `json:"fields"`
+
+	present, nullable, tagged versions
+	// common is set if the struct type is one of the commonStructs
+	common bool
+}
+
+// readMessage reads the definition at path, whose comment lines the JSON
+// decoder would reject.
+func readMessage(path string) (*message, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var def bytes.Buffer
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := scanner.Text(); !strings.HasPrefix(strings.TrimSpace(line), "//") {
+			def.WriteString(line)
+			def.WriteByte('\n')
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	msg := new(message)
+	if err := json.Unmarshal(def.Bytes(), msg); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	if err := msg.resolve(); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return msg, nil
+}
+
+// resolve parses the versions of the message and its fields, and fills in
+// the fields of the structs defined in commonStructs where they are used.
+func (m *message) resolve() (err error) {
+	switch m.Type {
+	case "request", "response":
+		if m.APIKey == nil {
+			return fmt.Errorf("%s has no apiKey", m.Name)
+		}
+	case "data", "header":
+	default:
+		return fmt.Errorf("%s has the unknown type %q", m.Name, m.Type)
+	}
+	if m.valid, err = parseVersions(m.ValidVersions); err != nil {
+		return err
+	}
+	if m.flexible, err = parseVersions(m.FlexibleVersions); err != nil {
+		return err
+	}
+
+	common := make(map[string][]*field, len(m.CommonStructs))
+	for _, s := range m.CommonStructs {
+		common[s.Name] = s.Fields
+	}
+	return resolveFields(m.Fields, common)
+}
+
+func resolveFields(fields []*field, common map[string][]*field) (err error) {
+	for _, f := range fields {
+		if f.present, err = parseVersions(f.Versions); err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if f.nullable, err = parseVersions(f.NullableVersions); err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if f.tagged, err = parseVersions(f.TaggedVersions); err != nil {
+			return fmt.Errorf("%s: %w", f.Name, err)
+		}
+		if !f.tagged.empty() && f.Tag == nil {
+			return fmt.Errorf("%s is tagged without a tag", f.Name)
+		}
+		if f.isStruct() && f.Fields == nil {
+			if f.Fields = common[f.elemType()]; f.Fields == nil {
+				return fmt.Errorf("%s has the unknown type %s", f.Name, f.Type)
+			}
+			f.common = true
+		}
+		if f.isStruct() && !f.isArray() && !f.nullable.empty() {
+			return fmt.Errorf("%s: nullable structs are not supported", f.Name)
+		}
+		if err = resolveFields(f.Fields, common); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (f *field) isArray() bool {
+	return strings.HasPrefix(f.Type, "[]")
+}
+
+// elemType is the type of the elements of an array field, or the type of
+// any other field.
+func (f *field) elemType() string {
+	return strings.TrimPrefix(f.Type, "[]")
+}
+
+func (f *field) isStruct() bool {
+	_, primitive := primitives[f.elemType()]
+	return !primitive
+}
+
+// versions is a range of versions, empty when low > high.
+type versions struct {
+	low, high int16
+}
+
+var noVersions = versions{low: 1, high: 0}
+
+// parseVersions parses versions as "none", "3", "1-3" or "2+".
+func parseVersions(s string) (versions, error) {
+	switch {
+	case s == "" || s == "none":
+		return noVersions, nil
+	case strings.HasSuffix(s, "+"):
+		low, err := strconv.ParseInt(strings.TrimSuffix(s, "+"), 10, 16)
+		return versions{int16(low), math.MaxInt16}, err
+	case strings.Contains(s, "-"):
+		bounds := strings.SplitN(s, "-", 2)
+		low, err := strconv.ParseInt(bounds[0], 10, 16)
+		if err != nil {
+			return noVersions, err
+		}
+		high, err := strconv.ParseInt(bounds[1], 10, 16)
+		return versions{int16(low), int16(high)}, err
+	default:
+		v, err := strconv.ParseInt(s, 10, 16)
+		return versions{int16(v), int16(v)}, err
+	}
+}
+
+func (v versions) empty() bool {
+	return v.low > v.high
+}
+
+func (v versions) intersect(o versions) versions {
+	if o.low > v.low {
+		v.low = o.low
+	}
+	if o.high < v.high {
+		v.high = o.high
+	}
+	return v
+}
+
+// below returns the versions of v below those of o.
+func (v versions) below(o versions) versions {
+	if o.empty() {
+		return v
+	}
+	return v.intersect(versions{math.MinInt16, o.low - 1})
+}
+
+// cond returns the Go condition on version selecting the versions of v among
+// those of within, "" when it selects them all.
+func (v versions) cond(within versions) string {
+	v = v.intersect(within)
+	switch {
+	case v.empty():
+		return "false"
+	case v.low > within.low && v.high < within.high && v.low == v.high:
+		return fmt.Sprintf("version == %d", v.low)
+	case v.low > within.low && v.high < within.high:
+		return fmt.Sprintf("version >= %d && version <= %d", v.low, v.high)
+	case v.low > within.low:
+		return fmt.Sprintf("version >= %d", v.low)
+	case v.high < within.high:
+		return fmt.Sprintf("version <= %d", v.high)
+	default:
+		return ""
+	}
+}
+
+func (v versions) String() string {
+	switch {
+	case v.empty():
+		return "none"
+	case v.high == math.MaxInt16:
+		return fmt.Sprintf("%d+", v.low)
+	case v.low == v.high:
+		return fmt.Sprint(v.low)
+	default:
+		return fmt.Sprintf("%d-%d", v.low, v.high)
+	}
+}
@@ -0,0 +1,306 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	"github.com/Shopify/sarama"
+	"github.com/Shopify/sarama/tools/sasl"
+	"github.com/Shopify/sarama/tools/tls"
+)
+
+var (
+	brokerList    = flag.String("brokers", os.Getenv("KAFKA_PEERS"), "The comma separated list of brokers in the Kafka cluster. You can also set the KAFKA_PEERS environment variable")
+	version       = flag.String("version", sarama.DefaultVersion.String(), "The version of Kafka")
+	verbose       = flag.Bool("verbose", false, "Turn on sarama logging to stderr")
+	tlsEnabled    = flag.Bool("tls-enabled", false, "Whether to enable TLS")
+	tlsSkipVerify = flag.Bool("tls-skip-verify", false, "Whether skip TLS server cert verification")
+	tlsClientCert = flag.String("tls-client-cert", "", "Client cert for client authentication (use with -tls-enabled and -tls-client-key)")
+	tlsClientKey  = flag.String("tls-client-key", "", "Client key for client authentication (use with tls-enabled and -tls-client-cert)")
+	saslMechanism = flag.String("sasl-mechanism", "", "The SASL mechanism to authenticate with. Can be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512")
+	saslUser      = flag.String("sasl-user", "", "The user to authenticate as (use with -sasl-mechanism)")
+	saslPassword  = flag.String("sasl-password", os.Getenv("KAFKA_PASSWORD"), "The password to authenticate with (use with -sasl-mechanism). You can also set the KAFKA_PASSWORD environment variable")
+
+	logger = log.New(os.Stderr, "", log.LstdFlags)
+)
+
+// commands are the commands of the tool. Each parses its own flags and
+// returns the function running it with the cluster admin.
+var commands = map[string]func(args []string) func(admin sarama.ClusterAdmin) error{
+	"list":           runList,
+	"describe":       runDescribe,
+	"create":         runCreate,
+	"alter":          runAlter,
+	"delete":         runDelete,
+	"add-partitions": runAddPartitions,
+	"get-config":     runGetConfig,
+	"set-config":     runSetConfig,
+}
+
+// commandNames lists the commands in the order of the usage.
+var commandNames = []string{"list", "describe", "create", "alter", "delete", "add-partitions", "get-config", "set-config"}
+
+var commandUsages = map[string]string{
+	"list":           "List the topics",
+	"describe":       "Describe the partitions of topics, with their leaders and replicas",
+	"create":         "Create a topic",
+	"alter":          "Reassign the replicas of the partitions of a topic",
+	"delete":         "Delete topics",
+	"add-partitions": "Add partitions to a topic",
+	"get-config":     "Show the configuration of a topic",
+	"set-config":     "Set or delete configuration entries of a topic",
+}
+
+func main() {
+	flag.Usage = usage
+	flag.Parse()
+
+	if flag.NArg() == 0 {
+		printUsageErrorAndExit("no command specified")
+	}
+	parse, ok := commands[flag.Arg(0)]
+	if !ok {
+		printUsageErrorAndExit(fmt.Sprintf("unknown command %q", flag.Arg(0)))
+	}
+	command := parse(flag.Args()[1:])
+
+	if *brokerList == "" {
+		printUsageErrorAndExit("no -brokers specified")
+	}
+
+	if *verbose {
+		sarama.Logger = logger
+	}
+
+	kafkaVersion, err := sarama.ParseKafkaVersion(*version)
+	if err != nil {
+		printUsageErrorAndExit(fmt.Sprintf("Invalid -version: %s", err))
+	}
+
+	config := sarama.NewConfig()
+	config.Version = kafkaVersion
+
+	if *tlsEnabled {
+		tlsConfig, err := tls.NewConfig(*tlsClientCert, *tlsClientKey)
+		if err != nil {
+			printErrorAndExit(69, "Failed to create TLS config: %s", err)
+		}
+
+		config.Net.TLS.Enable = true
+		config.Net.TLS.Config = tlsConfig
+		config.Net.TLS.Config.InsecureSkipVerify = *tlsSkipVerify
+	}
+
+	if err := sasl.Configure(config, *saslMechanism, *saslUser, *saslPassword); err != nil {
+		printUsageErrorAndExit(err.Error())
+	}
+
+	admin, err := sarama.NewClusterAdmin(strings.Split(*brokerList, ","), config)
+	if err != nil {
+		printErrorAndExit(69, "Failed to connect to the cluster: %s", err)
+	}
+	defer func() {
+		if err := admin.Close(); err != nil {
+			logger.Println("Failed to close the cluster admin:", err)
+		}
+	}()
+
+	if err := command(admin); err != nil {
+		_ = admin.Close()
+		printErrorAndExit(69, "%s", err)
+	}
+}
+
+// commandFlags returns the flag set of the command, exiting with its usage
+// on parsing errors.
+func commandFlags(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Usage = func() {
+		fmt.Fprintf(os.Stderr, "Usage: %s [options] %s [command options]\n\n", os.Args[0], name)
+		fmt.Fprintln(os.Stderr, commandUsages[name]+".")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Command options:")
+		flags.PrintDefaults()
+	}
+	return flags
+}
+
+// requireTopic returns the -topic flag of the command, which is required.
+func requireTopic(flags *flag.FlagSet, usage string) func() string {
+	topic := flags.String("topic", "", "REQUIRED: "+usage)
+	return func() string {
+		if *topic == "" {
+			printCommandUsageErrorAndExit(flags, "no -topic specified")
+		}
+		return *topic
+	}
+}
+
+func runList(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("list")
+	internal := flags.Bool("internal", false, "Also list the internal topics, whose names start with an underscore")
+	_ = flags.Parse(args)
+
+	return func(admin sarama.ClusterAdmin) error {
+		return listTopics(admin, os.Stdout, *internal)
+	}
+}
+
+func runDescribe(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("describe")
+	topic := requireTopic(flags, "the comma separated list of topics to describe")
+	_ = flags.Parse(args)
+
+	topics := strings.Split(topic(), ",")
+	return func(admin sarama.ClusterAdmin) error {
+		return describeTopics(admin, os.Stdout, topics)
+	}
+}
+
+func runCreate(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("create")
+	topic := requireTopic(flags, "the topic to create")
+	partitions := flags.Int("partitions", 0, "The number of partitions of the topic (required unless -replica-assignment is set)")
+	replicationFactor := flags.Int("replication-factor", 1, "The number of replicas of each partition (ignored with -replica-assignment)")
+	var assignment replicaAssignmentFlag
+	flags.Var(&assignment, "replica-assignment", "The replicas of the partitions, the partitions separated by commas and their replicas by colons, e.g. 1:2,2:3,3:1")
+	configs := make(configFlag)
+	flags.Var(configs, "config", "A configuration entry of the topic, e.g. retention.ms=86400000. Can be repeated")
+	validateOnly := flags.Bool("validate-only", false, "Only validate the creation of the topic")
+	_ = flags.Parse(args)
+
+	detail := &sarama.TopicDetail{
+		NumPartitions:     int32(*partitions),
+		ReplicationFactor: int16(*replicationFactor),
+		ConfigEntries:     configs.entries(),
+	}
+	if assignment != nil {
+		detail.NumPartitions, detail.ReplicationFactor = -1, -1
+		detail.ReplicaAssignment = assignment.toMap()
+	} else if *partitions <= 0 {
+		printCommandUsageErrorAndExit(flags, "-partitions must be greater than 0")
+	}
+	name := topic()
+	return func(admin sarama.ClusterAdmin) error {
+		return createTopic(admin, os.Stdout, name, detail, *validateOnly)
+	}
+}
+
+func runAlter(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("alter")
+	topic := requireTopic(flags, "the topic to reassign the replicas of")
+	var assignment replicaAssignmentFlag
+	flags.Var(&assignment, "replica-assignment", "REQUIRED: the new replicas of the partitions, from partition 0, the partitions separated by commas and their replicas by colons, e.g. 1:2,2:3,3:1 (requires -version 2.4.0 or later)")
+	_ = flags.Parse(args)
+
+	if assignment == nil {
+		printCommandUsageErrorAndExit(flags, "no -replica-assignment specified")
+	}
+	name := topic()
+	return func(admin sarama.ClusterAdmin) error {
+		return reassignPartitions(admin, os.Stdout, name, assignment)
+	}
+}
+
+func runDelete(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("delete")
+	topic := requireTopic(flags, "the comma separated list of topics to delete")
+	_ = flags.Parse(args)
+
+	topics := strings.Split(topic(), ",")
+	return func(admin sarama.ClusterAdmin) error {
+		return deleteTopics(admin, os.Stdout, topics)
+	}
+}
+
+func runAddPartitions(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("add-partitions")
+	topic := requireTopic(flags, "the topic to add partitions to")
+	partitions := flags.Int("partitions", 0, "REQUIRED: the total number of partitions of the topic once added")
+	var assignment replicaAssignmentFlag
+	flags.Var(&assignment, "replica-assignment", "The replicas of the added partitions, the partitions separated by commas and their replicas by colons, e.g. 1:2,2:3")
+	validateOnly := flags.Bool("validate-only", false, "Only validate the addition of the partitions")
+	_ = flags.Parse(args)
+
+	if *partitions <= 0 {
+		printCommandUsageErrorAndExit(flags, "-partitions must be greater than 0")
+	}
+	name := topic()
+	return func(admin sarama.ClusterAdmin) error {
+		return addPartitions(admin, os.Stdout, name, int32(*partitions), assignment, *validateOnly)
+	}
+}
+
+func runGetConfig(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("get-config")
+	topic := requireTopic(flags, "the topic to show the configuration of")
+	names := flags.String("names", "", "The comma separated list of the configuration entries to show, all of them by default")
+	overrides := flags.Bool("overrides", false, "Only show the entries set on the topic, rather than inherited from the broker or defaults")
+	_ = flags.Parse(args)
+
+	var configNames []string
+	if *names != "" {
+		configNames = strings.Split(*names, ",")
+	}
+	name := topic()
+	return func(admin sarama.ClusterAdmin) error {
+		return getConfig(admin, os.Stdout, name, configNames, *overrides)
+	}
+}
+
+func runSetConfig(args []string) func(admin sarama.ClusterAdmin) error {
+	flags := commandFlags("set-config")
+	topic := requireTopic(flags, "the topic to configure")
+	set := make(configFlag)
+	flags.Var(set, "set", "A configuration entry to set, e.g. retention.ms=86400000. Can be repeated")
+	deleted := flags.String("delete", "", "The comma separated list of the configuration entries to delete, reverting them to their defaults")
+	validateOnly := flags.Bool("validate-only", false, "Only validate the new configuration")
+	_ = flags.Parse(args)
+
+	var deletes []string
+	if *deleted != "" {
+		deletes = strings.Split(*deleted, ",")
+	}
+	if len(set) == 0 && len(deletes) == 0 {
+		printCommandUsageErrorAndExit(flags, "no -set or -delete specified")
+	}
+	name := topic()
+	return func(admin sarama.ClusterAdmin) error {
+		return setConfig(admin, os.Stdout, name, set, deletes, *validateOnly)
+	}
+}
+
+func usage() {
+	fmt.Fprintf(os.Stderr, "Usage: %s [options] <command> [command options]\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Commands:")
+	for _, name := range commandNames {
+		fmt.Fprintf(os.Stderr, "  %-15s %s\n", name, commandUsages[name])
+	}
+	fmt.Fprintln(os.Stderr)
+	fmt.Fprintf(os.Stderr, "Run %s <command> -help for the options of a command.\n\n", os.Args[0])
+	fmt.Fprintln(os.Stderr, "Options:")
+	flag.PrintDefaults()
+}
+
+func printErrorAndExit(code int, format string, values ...interface{}) {
+	fmt.Fprintf(os.Stderr, "ERROR: %s\n", fmt.Sprintf(format, values...))
+	fmt.Fprintln(os.Stderr)
+	os.Exit(code)
+}
+
+func printUsageErrorAndExit(message string) {
+	fmt.Fprintln(os.Stderr, "ERROR:", message)
+	fmt.Fprintln(os.Stderr)
+	usage()
+	os.Exit(64)
+}
+
+func printCommandUsageErrorAndExit(flags *flag.FlagSet, message string) {
+	fmt.Fprintln(os.Stderr, "ERROR:", message)
+	fmt.Fprintln(os.Stderr)
+	flags.Usage()
+	os.Exit(64)
+}
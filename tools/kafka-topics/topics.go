@@ -0,0 +1,260 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/Shopify/sarama"
+)
+
+// replicaAssignmentFlag holds the replicas of partitions set by a
+// -replica-assignment flag, in the format of the kafka-topics tool of Kafka:
+// the partitions separated by commas and their replicas by colons.
+type replicaAssignmentFlag [][]int32
+
+func (f *replicaAssignmentFlag) String() string {
+	partitions := make([]string, len(*f))
+	for i, replicas := range *f {
+		partitions[i] = formatBrokers(replicas, ":")
+	}
+	return strings.Join(partitions, ",")
+}
+
+func (f *replicaAssignmentFlag) Set(value string) error {
+	var assignment [][]int32
+	for _, partition := range strings.Split(value, ",") {
+		var replicas []int32
+		for _, replica := range strings.Split(partition, ":") {
+			id, err := strconv.ParseInt(replica, 10, 32)
+			if err != nil {
+				return fmt.Errorf("invalid replica %q", replica)
+			}
+			replicas = append(replicas, int32(id))
+		}
+		assignment = append(assignment, replicas)
+	}
+	*f = assignment
+	return nil
+}
+
+// toMap returns the replicas by partition, the assignment starting with
+// partition 0.
+func (f replicaAssignmentFlag) toMap() map[int32][]int32 {
+	m := make(map[int32][]int32, len(f))
+	for partition, replicas := range f {
+		m[int32(partition)] = replicas
+	}
+	return m
+}
+
+// configFlag holds the configuration entries set by repeated flags.
+type configFlag map[string]string
+
+func (f configFlag) String() string {
+	entries := make([]string, 0, len(f))
+	for name, value := range f {
+		entries = append(entries, name+"="+value)
+	}
+	sort.Strings(entries)
+	return strings.Join(entries, ",")
+}
+
+func (f configFlag) Set(value string) error {
+	i := strings.Index(value, "=")
+	if i <= 0 {
+		return fmt.Errorf("invalid configuration entry %q, should be name=value", value)
+	}
+	f[value[:i]] = value[i+1:]
+	return nil
+}
+
+// entries returns the entries in the format of sarama.TopicDetail, nil if
+// there are none.
+func (f configFlag) entries() map[string]*string {
+	if len(f) == 0 {
+		return nil
+	}
+	entries := make(map[string]*string, len(f))
+	for name, value := range f {
+		value := value
+		entries[name] = &value
+	}
+	return entries
+}
+
+// listTopics prints the topics of the cluster with their number of
+// partitions and replicas, sorted by name.
+func listTopics(admin sarama.ClusterAdmin, w io.Writer, internal bool) error {
+	topics, err := admin.ListTopics()
+	if err != nil {
+		return err
+	}
+	names := make([]string, 0, len(topics))
+	for name := range topics {
+		if internal || !strings.HasPrefix(name, "_") {
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "TOPIC\tPARTITIONS\tREPLICATION-FACTOR")
+	for _, name := range names {
+		fmt.Fprintf(tw, "%s\t%d\t%d\n", name, topics[name].NumPartitions, topics[name].ReplicationFactor)
+	}
+	return tw.Flush()
+}
+
+// describeTopics prints the partitions of the topics with their leaders,
+// replicas and in-sync replicas.
+func describeTopics(admin sarama.ClusterAdmin, w io.Writer, topics []string) error {
+	metadata, err := admin.DescribeTopics(topics)
+	if err != nil {
+		return err
+	}
+	for i, topic := range metadata {
+		if !errors.Is(topic.Err, sarama.ErrNoError) {
+			return fmt.Errorf("failed to describe topic %s: %w", topic.Name, topic.Err)
+		}
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+
+		fmt.Fprintf(w, "TOPIC %s: %d partitions", topic.Name, len(topic.Partitions))
+		if topic.TopicID != (sarama.Uuid{}) {
+			fmt.Fprintf(w, ", ID %s", topic.TopicID)
+		}
+		if topic.IsInternal {
+			fmt.Fprint(w, ", internal")
+		}
+		fmt.Fprintln(w)
+
+		partitions := append([]*sarama.PartitionMetadata(nil), topic.Partitions...)
+		sort.Slice(partitions, func(i, j int) bool { return partitions[i].ID < partitions[j].ID })
+
+		tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+		fmt.Fprintln(tw, "PARTITION\tLEADER\tREPLICAS\tISR\tOFFLINE")
+		for _, partition := range partitions {
+			fmt.Fprintf(tw, "%d\t%d\t%s\t%s\t%s\n", partition.ID, partition.Leader,
+				formatBrokers(partition.Replicas, ","), formatBrokers(partition.Isr, ","), formatBrokers(partition.OfflineReplicas, ","))
+		}
+		if err := tw.Flush(); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createTopic creates the topic, or only validates its creation.
+func createTopic(admin sarama.ClusterAdmin, w io.Writer, topic string, detail *sarama.TopicDetail, validateOnly bool) error {
+	if err := admin.CreateTopic(topic, detail, validateOnly); err != nil {
+		return fmt.Errorf("failed to create topic %s: %w", topic, err)
+	}
+	if validateOnly {
+		fmt.Fprintf(w, "Topic %s can be created.\n", topic)
+	} else {
+		fmt.Fprintf(w, "Created topic %s.\n", topic)
+	}
+	return nil
+}
+
+// reassignPartitions moves the replicas of the partitions of the topic to the
+// brokers of the assignment, the reassignment then running in the background.
+func reassignPartitions(admin sarama.ClusterAdmin, w io.Writer, topic string, assignment replicaAssignmentFlag) error {
+	if err := admin.AlterPartitionReassignments(topic, assignment); err != nil {
+		return fmt.Errorf("failed to reassign the partitions of topic %s: %w", topic, err)
+	}
+	fmt.Fprintf(w, "Started the reassignment of the partitions of topic %s to %s.\n", topic, assignment.String())
+	return nil
+}
+
+// deleteTopics deletes the topics, stopping at the first failure.
+func deleteTopics(admin sarama.ClusterAdmin, w io.Writer, topics []string) error {
+	for _, topic := range topics {
+		if err := admin.DeleteTopic(topic); err != nil {
+			return fmt.Errorf("failed to delete topic %s: %w", topic, err)
+		}
+		fmt.Fprintf(w, "Deleted topic %s.\n", topic)
+	}
+	return nil
+}
+
+// addPartitions adds partitions to the topic up to count, or only validates
+// their addition.
+func addPartitions(admin sarama.ClusterAdmin, w io.Writer, topic string, count int32, assignment replicaAssignmentFlag, validateOnly bool) error {
+	if err := admin.CreatePartitions(topic, count, assignment, validateOnly); err != nil {
+		return fmt.Errorf("failed to add partitions to topic %s: %w", topic, err)
+	}
+	if validateOnly {
+		fmt.Fprintf(w, "Topic %s can have %d partitions.\n", topic, count)
+	} else {
+		fmt.Fprintf(w, "Topic %s now has %d partitions.\n", topic, count)
+	}
+	return nil
+}
+
+// getConfig prints the configuration entries of the topic, sorted by name,
+// hiding the values of the sensitive ones.
+func getConfig(admin sarama.ClusterAdmin, w io.Writer, topic string, names []string, overrides bool) error {
+	entries, err := admin.DescribeConfig(sarama.ConfigResource{
+		Type:        sarama.TopicResource,
+		Name:        topic,
+		ConfigNames: names,
+	})
+	if err != nil {
+		return fmt.Errorf("failed to describe the configuration of topic %s: %w", topic, err)
+	}
+	sort.Slice(entries, func(i, j int) bool { return entries[i].Name < entries[j].Name })
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintln(tw, "NAME\tVALUE\tSOURCE\tREAD-ONLY")
+	for _, entry := range entries {
+		if overrides && entry.Source != sarama.SourceTopic {
+			continue
+		}
+		value := entry.Value
+		if entry.Sensitive {
+			value = "(sensitive)"
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%t\n", entry.Name, value, entry.Source, entry.ReadOnly)
+	}
+	return tw.Flush()
+}
+
+// setConfig sets and deletes configuration entries of the topic, leaving the
+// others as they are, or only validates the changes.
+func setConfig(admin sarama.ClusterAdmin, w io.Writer, topic string, set configFlag, deletes []string, validateOnly bool) error {
+	entries := make(map[string]sarama.IncrementalAlterConfigsEntry, len(set)+len(deletes))
+	for name, value := range set.entries() {
+		entries[name] = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationSet, Value: value}
+	}
+	for _, name := range deletes {
+		if _, ok := entries[name]; ok {
+			return fmt.Errorf("configuration entry %s is both set and deleted", name)
+		}
+		entries[name] = sarama.IncrementalAlterConfigsEntry{Operation: sarama.IncrementalAlterConfigsOperationDelete}
+	}
+
+	if err := admin.IncrementalAlterConfig(sarama.TopicResource, topic, entries, validateOnly); err != nil {
+		return fmt.Errorf("failed to configure topic %s: %w", topic, err)
+	}
+	if validateOnly {
+		fmt.Fprintf(w, "The configuration of topic %s is valid.\n", topic)
+	} else {
+		fmt.Fprintf(w, "Configured topic %s.\n", topic)
+	}
+	return nil
+}
+
+func formatBrokers(ids []int32, sep string) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(int(id))
+	}
+	return strings.Join(s, sep)
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/Shopify/sarama"
+)
+
+func TestReplicaAssignmentFlag(t *testing.T) {
+	var f replicaAssignmentFlag
+	if err := f.Set("1:2,2:3,3:1"); err != nil {
+		t.Fatal(err)
+	}
+	if m := f.toMap(); len(m) != 3 || m[1][0] != 2 || m[1][1] != 3 {
+		t.Errorf("unexpected assignment: %v", m)
+	}
+	if f.String() != "1:2,2:3,3:1" {
+		t.Errorf("unexpected string: %s", f.String())
+	}
+	if err := f.Set("1:a"); err == nil {
+		t.Error("expected an invalid replica to be rejected")
+	}
+}
+
+func TestConfigFlag(t *testing.T) {
+	f := make(configFlag)
+	for _, value := range []string{"retention.ms=1000", "cleanup.policy=compact,delete", "empty="} {
+		if err := f.Set(value); err != nil {
+			t.Fatal(err)
+		}
+	}
+	entries := f.entries()
+	if len(entries) != 3 || *entries["cleanup.policy"] != "compact,delete" || *entries["empty"] != "" {
+		t.Errorf("unexpected entries: %v", f)
+	}
+	if err := f.Set("=1000"); err == nil {
+		t.Error("expected an entry without name to be rejected")
+	}
+	if make(configFlag).entries() != nil {
+		t.Error("expected no entries")
+	}
+}
+
+func newTestAdmin(t *testing.T) (sarama.ClusterAdmin, *sarama.MockBroker) {
+	broker := sarama.NewMockBroker(t, 1)
+	broker.SetHandlerByMap(map[string]sarama.MockResponse{
+		"MetadataRequest": sarama.NewMockMetadataResponse(t).
+			SetController(broker.BrokerID()).
+			SetBroker(broker.Addr(), broker.BrokerID()).
+			SetLeader("my_topic", 0, broker.BrokerID()).
+			SetLeader("my_topic", 1, broker.BrokerID()),
+		"DescribeConfigsRequest":         sarama.NewMockDescribeConfigsResponse(t),
+		"IncrementalAlterConfigsRequest": sarama.NewMockIncrementalAlterConfigsResponse(t),
+	})
+
+	config := sarama.NewConfig()
+	config.Version = sarama.V2_3_0_0
+	admin, err := sarama.NewClusterAdmin([]string{broker.Addr()}, config)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return admin, broker
+}
+
+func TestDescribeTopics(t *testing.T) {
+	admin, broker := newTestAdmin(t)
+	defer broker.Close()
+	defer admin.Close()
+
+	var buf bytes.Buffer
+	if err := describeTopics(admin, &buf, []string{"my_topic"}); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 4 || !strings.HasPrefix(lines[0], "TOPIC my_topic: 2 partitions") ||
+		strings.Fields(lines[2])[0] != "0" || strings.Fields(lines[3])[0] != "1" {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestGetConfig(t *testing.T) {
+	admin, broker := newTestAdmin(t)
+	defer broker.Close()
+	defer admin.Close()
+
+	var buf bytes.Buffer
+	if err := getConfig(admin, &buf, "my_topic", nil, false); err != nil {
+		t.Fatal(err)
+	}
+	lines := strings.Split(buf.String(), "\n")
+	if len(lines) < 4 || strings.Fields(lines[1])[0] != "max.message.bytes" ||
+		strings.Fields(lines[2])[1] != "(sensitive)" || strings.Fields(lines[3])[0] != "retention.ms" {
+		t.Errorf("unexpected output:\n%s", buf.String())
+	}
+}
+
+func TestSetConfig(t *testing.T) {
+	admin, broker := newTestAdmin(t)
+	defer broker.Close()
+	defer admin.Close()
+
+	set := configFlag{"retention.ms": "1000"}
+	var buf bytes.Buffer
+	if err := setConfig(admin, &buf, "my_topic", set, []string{"cleanup.policy"}, false); err != nil {
+		t.Fatal(err)
+	}
+
+	var request *sarama.IncrementalAlterConfigsRequest
+	for _, entry := range broker.History() {
+		if r, ok := entry.Request.(*sarama.IncrementalAlterConfigsRequest); ok {
+			request = r
+		}
+	}
+	if request == nil || len(request.Resources) != 1 {
+		t.Fatalf("expected the configuration of a topic to be altered, got %+v", request)
+	}
+	entries := request.Resources[0].ConfigEntries
+	if entries["retention.ms"].Operation != sarama.IncrementalAlterConfigsOperationSet || *entries["retention.ms"].Value != "1000" ||
+		entries["cleanup.policy"].Operation != sarama.IncrementalAlterConfigsOperationDelete {
+		t.Errorf("unexpected entries: %+v", entries)
+	}
+
+	if err := setConfig(admin, &buf, "my_topic", set, []string{"retention.ms"}, false); err == nil {
+		t.Error("expected an entry both set and deleted to be rejected")
+	}
+}
@@ -0,0 +1,53 @@
+package sasl
+
+import (
+	"crypto/sha256"
+	"crypto/sha512"
+	"fmt"
+
+	"github.com/xdg-go/scram"
+
+	"github.com/Shopify/sarama"
+)
+
+// Configure enables SASL authentication in config with the mechanism, which
+// can be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512; an empty mechanism leaves
+// SASL disabled.
+func Configure(config *sarama.Config, mechanism, user, password string) error {
+	switch mechanism {
+	case "":
+		return nil
+	case sarama.SASLTypePlaintext:
+	case sarama.SASLTypeSCRAMSHA256:
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{HashGeneratorFcn: sha256.New}
+		}
+	case sarama.SASLTypeSCRAMSHA512:
+		config.Net.SASL.SCRAMClientGeneratorFunc = func() sarama.SCRAMClient {
+			return &scramClient{HashGeneratorFcn: sha512.New}
+		}
+	default:
+		return fmt.Errorf("SASL mechanism %q is not supported, should be PLAIN, SCRAM-SHA-256 or SCRAM-SHA-512", mechanism)
+	}
+
+	config.Net.SASL.Enable = true
+	config.Net.SASL.Mechanism = sarama.SASLMechanism(mechanism)
+	config.Net.SASL.User = user
+	config.Net.SASL.Password = password
+	return nil
+}
+
+// scramClient is a sarama.SCRAMClient implemented with github.com/xdg-go/scram.
+type scramClient struct {
+	*scram.ClientConversation
+	scram.HashGeneratorFcn
+}
+
+func (c *scramClient) Begin(userName, password, authzID string) error {
+	client, err := c.HashGeneratorFcn.NewClient(userName, password, authzID)
+	if err != nil {
+		return err
+	}
+	c.ClientConversation = client.NewConversation()
+	return nil
+}
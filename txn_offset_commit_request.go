@@ -1,48 +1,100 @@
 package sarama
 
 type TxnOffsetCommitRequest struct {
+	Version         int16
 	TransactionalID string
 	GroupID         string
 	ProducerID      int64
 	ProducerEpoch   int16
+	GenerationID    int32   // Version 3
+	MemberID        string  // Version 3
+	GroupInstanceID *string // Version 3
 	Topics          map[string][]*PartitionOffsetMetadata
 }
 
-func (t *TxnOffsetCommitRequest) encode(pe packetEncoder) error {
-	if err := pe.putString(t.TransactionalID); err != nil {
+func (t *TxnOffsetCommitRequest) encode(pe packetEncoder) (err error) {
+	if t.Version >= 3 {
+		err = pe.putCompactString(t.TransactionalID)
+	} else {
+		err = pe.putString(t.TransactionalID)
+	}
+	if err != nil {
 		return err
 	}
-	if err := pe.putString(t.GroupID); err != nil {
+	if t.Version >= 3 {
+		err = pe.putCompactString(t.GroupID)
+	} else {
+		err = pe.putString(t.GroupID)
+	}
+	if err != nil {
 		return err
 	}
 	pe.putInt64(t.ProducerID)
 	pe.putInt16(t.ProducerEpoch)
 
-	if err := pe.putArrayLength(len(t.Topics)); err != nil {
+	if t.Version >= 3 {
+		pe.putInt32(t.GenerationID)
+		if err = pe.putCompactString(t.MemberID); err != nil {
+			return err
+		}
+		if err = pe.putNullableCompactString(t.GroupInstanceID); err != nil {
+			return err
+		}
+	}
+
+	if t.Version >= 3 {
+		pe.putCompactArrayLength(len(t.Topics))
+	} else if err = pe.putArrayLength(len(t.Topics)); err != nil {
 		return err
 	}
 	for topic, partitions := range t.Topics {
-		if err := pe.putString(topic); err != nil {
+		if t.Version >= 3 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
+		if err != nil {
 			return err
 		}
-		if err := pe.putArrayLength(len(partitions)); err != nil {
+		if t.Version >= 3 {
+			pe.putCompactArrayLength(len(partitions))
+		} else if err = pe.putArrayLength(len(partitions)); err != nil {
 			return err
 		}
 		for _, partition := range partitions {
-			if err := partition.encode(pe); err != nil {
+			if err = partition.encode(pe, t.Version); err != nil {
 				return err
 			}
 		}
+		if t.Version >= 3 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if t.Version >= 3 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
 }
 
 func (t *TxnOffsetCommitRequest) decode(pd packetDecoder, version int16) (err error) {
-	if t.TransactionalID, err = pd.getString(); err != nil {
+	t.Version = version
+
+	if version >= 3 {
+		t.TransactionalID, err = pd.getCompactString()
+	} else {
+		t.TransactionalID, err = pd.getString()
+	}
+	if err != nil {
 		return err
 	}
-	if t.GroupID, err = pd.getString(); err != nil {
+	if version >= 3 {
+		t.GroupID, err = pd.getCompactString()
+	} else {
+		t.GroupID, err = pd.getString()
+	}
+	if err != nil {
 		return err
 	}
 	if t.ProducerID, err = pd.getInt64(); err != nil {
@@ -52,19 +104,46 @@ func (t *TxnOffsetCommitRequest) decode(pd packetDecoder, version int16) (err er
 		return err
 	}
 
-	n, err := pd.getArrayLength()
+	if version >= 3 {
+		if t.GenerationID, err = pd.getInt32(); err != nil {
+			return err
+		}
+		if t.MemberID, err = pd.getCompactString(); err != nil {
+			return err
+		}
+		if t.GroupInstanceID, err = pd.getCompactNullableString(); err != nil {
+			return err
+		}
+	}
+
+	var n int
+	if version >= 3 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
 
 	t.Topics = make(map[string][]*PartitionOffsetMetadata)
 	for i := 0; i < n; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if version >= 3 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
 
-		m, err := pd.getArrayLength()
+		var m int
+		if version >= 3 {
+			m, err = pd.getCompactArrayLength()
+		} else {
+			m, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -78,6 +157,18 @@ func (t *TxnOffsetCommitRequest) decode(pd packetDecoder, version int16) (err er
 			}
 			t.Topics[topic][j] = partitionOffsetMetadata
 		}
+
+		if version >= 3 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -88,29 +179,53 @@ func (a *TxnOffsetCommitRequest) key() int16 {
 }
 
 func (a *TxnOffsetCommitRequest) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *TxnOffsetCommitRequest) headerVersion() int16 {
+	if a.Version >= 3 {
+		return 2
+	}
 	return 1
 }
 
 func (a *TxnOffsetCommitRequest) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch a.Version {
+	case 3:
+		return V2_5_0_0
+	case 2:
+		return V2_1_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
 }
 
 type PartitionOffsetMetadata struct {
-	Partition int32
-	Offset    int64
-	Metadata  *string
+	Partition   int32
+	Offset      int64
+	LeaderEpoch int32 // Version 2
+	Metadata    *string
 }
 
-func (p *PartitionOffsetMetadata) encode(pe packetEncoder) error {
+func (p *PartitionOffsetMetadata) encode(pe packetEncoder, version int16) (err error) {
 	pe.putInt32(p.Partition)
 	pe.putInt64(p.Offset)
-	if err := pe.putNullableString(p.Metadata); err != nil {
+	if version >= 2 {
+		pe.putInt32(p.LeaderEpoch)
+	}
+	if version >= 3 {
+		err = pe.putNullableCompactString(p.Metadata)
+	} else {
+		err = pe.putNullableString(p.Metadata)
+	}
+	if err != nil {
 		return err
 	}
+	if version >= 3 {
+		pe.putEmptyTaggedFieldArray()
+	}
 
 	return nil
 }
@@ -122,9 +237,24 @@ func (p *PartitionOffsetMetadata) decode(pd packetDecoder, version int16) (err e
 	if p.Offset, err = pd.getInt64(); err != nil {
 		return err
 	}
-	if p.Metadata, err = pd.getNullableString(); err != nil {
+	if version >= 2 {
+		if p.LeaderEpoch, err = pd.getInt32(); err != nil {
+			return err
+		}
+	}
+	if version >= 3 {
+		p.Metadata, err = pd.getCompactNullableString()
+	} else {
+		p.Metadata, err = pd.getNullableString()
+	}
+	if err != nil {
 		return err
 	}
+	if version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
+	}
 
 	return nil
 }
@@ -31,3 +31,46 @@ func TestTxnOffsetCommitRequest(t *testing.T) {
 
 	testRequest(t, "", req, txnOffsetCommitRequest)
 }
+
+var txnOffsetCommitRequestV3 = []byte{
+	4, 't', 'x', 'n',
+	8, 'g', 'r', 'o', 'u', 'p', 'i', 'd',
+	0, 0, 0, 0, 0, 0, 31, 64, // producer ID
+	0, 1, // producer epoch
+	0, 0, 0, 3, // generation ID
+	7, 'm', 'e', 'm', 'b', 'e', 'r', // member ID
+	0, // no group instance ID
+	2, // 1 topic
+	6, 't', 'o', 'p', 'i', 'c',
+	2,          // 1 partition
+	0, 0, 0, 2, // partition no 2
+	0, 0, 0, 0, 0, 0, 0, 123,
+	0, 0, 0, 5, // leader epoch
+	5, 'm', 'e', 't', 'a', // meta data
+	0, // empty tagged fields
+	0, // empty tagged fields
+	0, // empty tagged fields
+}
+
+func TestTxnOffsetCommitRequestV3(t *testing.T) {
+	metadata := "meta"
+	req := &TxnOffsetCommitRequest{
+		Version:         3,
+		TransactionalID: "txn",
+		GroupID:         "groupid",
+		ProducerID:      8000,
+		ProducerEpoch:   1,
+		GenerationID:    3,
+		MemberID:        "member",
+		Topics: map[string][]*PartitionOffsetMetadata{
+			"topic": {{
+				Offset:      123,
+				Partition:   2,
+				LeaderEpoch: 5,
+				Metadata:    &metadata,
+			}},
+		},
+	}
+
+	testRequest(t, "", req, txnOffsetCommitRequestV3)
+}
@@ -5,41 +5,65 @@ import (
 )
 
 type TxnOffsetCommitResponse struct {
+	Version      int16
 	ThrottleTime time.Duration
 	Topics       map[string][]*PartitionError
 }
 
-func (t *TxnOffsetCommitResponse) encode(pe packetEncoder) error {
+func (t *TxnOffsetCommitResponse) encode(pe packetEncoder) (err error) {
 	pe.putInt32(int32(t.ThrottleTime / time.Millisecond))
-	if err := pe.putArrayLength(len(t.Topics)); err != nil {
+	if t.Version >= 3 {
+		pe.putCompactArrayLength(len(t.Topics))
+	} else if err = pe.putArrayLength(len(t.Topics)); err != nil {
 		return err
 	}
 
 	for topic, e := range t.Topics {
-		if err := pe.putString(topic); err != nil {
+		if t.Version >= 3 {
+			err = pe.putCompactString(topic)
+		} else {
+			err = pe.putString(topic)
+		}
+		if err != nil {
 			return err
 		}
-		if err := pe.putArrayLength(len(e)); err != nil {
+		if t.Version >= 3 {
+			pe.putCompactArrayLength(len(e))
+		} else if err = pe.putArrayLength(len(e)); err != nil {
 			return err
 		}
 		for _, partitionError := range e {
-			if err := partitionError.encode(pe); err != nil {
+			if err = partitionError.encode(pe, t.Version); err != nil {
 				return err
 			}
 		}
+		if t.Version >= 3 {
+			pe.putEmptyTaggedFieldArray()
+		}
+	}
+
+	if t.Version >= 3 {
+		pe.putEmptyTaggedFieldArray()
 	}
 
 	return nil
 }
 
 func (t *TxnOffsetCommitResponse) decode(pd packetDecoder, version int16) (err error) {
+	t.Version = version
+
 	throttleTime, err := pd.getInt32()
 	if err != nil {
 		return err
 	}
 	t.ThrottleTime = time.Duration(throttleTime) * time.Millisecond
 
-	n, err := pd.getArrayLength()
+	var n int
+	if version >= 3 {
+		n, err = pd.getCompactArrayLength()
+	} else {
+		n, err = pd.getArrayLength()
+	}
 	if err != nil {
 		return err
 	}
@@ -47,12 +71,22 @@ func (t *TxnOffsetCommitResponse) decode(pd packetDecoder, version int16) (err e
 	t.Topics = make(map[string][]*PartitionError)
 
 	for i := 0; i < n; i++ {
-		topic, err := pd.getString()
+		var topic string
+		if version >= 3 {
+			topic, err = pd.getCompactString()
+		} else {
+			topic, err = pd.getString()
+		}
 		if err != nil {
 			return err
 		}
 
-		m, err := pd.getArrayLength()
+		var m int
+		if version >= 3 {
+			m, err = pd.getCompactArrayLength()
+		} else {
+			m, err = pd.getArrayLength()
+		}
 		if err != nil {
 			return err
 		}
@@ -65,6 +99,18 @@ func (t *TxnOffsetCommitResponse) decode(pd packetDecoder, version int16) (err e
 				return err
 			}
 		}
+
+		if version >= 3 {
+			if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+				return err
+			}
+		}
+	}
+
+	if version >= 3 {
+		if _, err = pd.getEmptyTaggedFieldArray(); err != nil {
+			return err
+		}
 	}
 
 	return nil
@@ -75,13 +121,37 @@ func (a *TxnOffsetCommitResponse) key() int16 {
 }
 
 func (a *TxnOffsetCommitResponse) version() int16 {
-	return 0
+	return a.Version
 }
 
 func (a *TxnOffsetCommitResponse) headerVersion() int16 {
+	if a.Version >= 3 {
+		return 1
+	}
 	return 0
 }
 
 func (a *TxnOffsetCommitResponse) requiredVersion() KafkaVersion {
-	return V0_11_0_0
+	switch a.Version {
+	case 3:
+		return V2_5_0_0
+	case 2:
+		return V2_1_0_0
+	case 1:
+		return V2_0_0_0
+	default:
+		return V0_11_0_0
+	}
+}
+
+func (t *TxnOffsetCommitResponse) throttleTime() time.Duration {
+	return t.ThrottleTime
+}
+
+func (t *TxnOffsetCommitResponse) shouldClientThrottle(version int16) bool {
+	return version >= 1
+}
+
+func (a *TxnOffsetCommitResponse) hasErrorCode() bool {
+	return hasPartitionError(a.Topics)
 }
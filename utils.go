@@ -48,7 +48,7 @@ func safeAsyncClose(b *Broker) {
 	go withRecover(func() {
 		if connected, _ := tmp.Connected(); connected {
 			if err := tmp.Close(); err != nil {
-				Logger.Println("Error closing broker", tmp.ID(), ":", err)
+				warnf("Error closing broker %d : %v", tmp.ID(), err)
 			}
 		}
 	})
@@ -181,6 +181,8 @@ var (
 	V2_8_1_0  = newKafkaVersion(2, 8, 1, 0)
 	V3_0_0_0  = newKafkaVersion(3, 0, 0, 0)
 	V3_1_0_0  = newKafkaVersion(3, 1, 0, 0)
+	V3_7_0_0  = newKafkaVersion(3, 7, 0, 0)
+	V3_8_0_0  = newKafkaVersion(3, 8, 0, 0)
 
 	SupportedVersions = []KafkaVersion{
 		V0_8_2_0,
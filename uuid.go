@@ -0,0 +1,25 @@
+package sarama
+
+import "encoding/base64"
+
+// Uuid is a 128 bit identifier, such as the id Kafka assigns to every topic.
+type Uuid [16]byte
+
+// String returns the URL-safe base64 form of the Uuid, as printed by the
+// Kafka tooling.
+func (u Uuid) String() string {
+	return base64.RawURLEncoding.EncodeToString(u[:])
+}
+
+func (u *Uuid) encode(pe packetEncoder) error {
+	return pe.putRawBytes(u[:])
+}
+
+func (u *Uuid) decode(pd packetDecoder) error {
+	raw, err := pd.getRawBytes(len(u))
+	if err != nil {
+		return err
+	}
+	copy(u[:], raw)
+	return nil
+}
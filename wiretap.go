@@ -0,0 +1,129 @@
+package sarama
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net"
+	"sync"
+	"time"
+)
+
+// WireTapRecord is a round trip to a broker, as captured by Net.WireTap: the
+// request and its response as they were written to and read from the
+// connection, each including its size and header.
+type WireTapRecord struct {
+	// Broker is the broker the request was sent to.
+	Broker *Broker
+	// APIKey and APIVersion are those of the request.
+	APIKey     int16
+	APIVersion int16
+	// CorrelationID matches the response with the request.
+	CorrelationID int32
+	// ConnectionID identifies the connection the request was sent on, see
+	// BrokerConnectionState.ConnectionID. Together with CorrelationID, it
+	// matches the request in the request logs of the broker.
+	ConnectionID string
+	// Request is the encoded request.
+	Request []byte
+	// Response is the encoded response, nil when the request does not expect
+	// one, e.g. a produce request with NoResponse, or when it failed.
+	Response []byte
+	// Err is the error sending the request or reading its response.
+	Err error
+	// RequestTime is when the request was sent.
+	RequestTime time.Time
+	// Latency is the time the response took to be read.
+	Latency time.Duration
+}
+
+// WireTapEntry is a WireTapRecord as written by NewWireTapWriter, one JSON
+// object per line, for tools such as kafka-protocol-dump to decode it later.
+type WireTapEntry struct {
+	Time          time.Time `json:"time"`
+	Broker        string    `json:"broker"`
+	BrokerID      int32     `json:"broker_id"`
+	ConnectionID  string    `json:"connection_id"`
+	APIKey        int16     `json:"api_key"`
+	APIVersion    int16     `json:"api_version"`
+	CorrelationID int32     `json:"correlation_id"`
+	Request       []byte    `json:"request"`
+	Response      []byte    `json:"response,omitempty"`
+	Error         string    `json:"error,omitempty"`
+	// LatencyNs is the latency in nanoseconds.
+	LatencyNs int64 `json:"latency_ns"`
+}
+
+// NewWireTapEntry returns the entry of record.
+func NewWireTapEntry(record *WireTapRecord) *WireTapEntry {
+	entry := &WireTapEntry{
+		Time:          record.RequestTime,
+		BrokerID:      -1,
+		ConnectionID:  record.ConnectionID,
+		APIKey:        record.APIKey,
+		APIVersion:    record.APIVersion,
+		CorrelationID: record.CorrelationID,
+		Request:       record.Request,
+		Response:      record.Response,
+		LatencyNs:     int64(record.Latency),
+	}
+	if record.Broker != nil {
+		entry.Broker = record.Broker.Addr()
+		entry.BrokerID = record.Broker.ID()
+	}
+	if record.Err != nil {
+		entry.Error = record.Err.Error()
+	}
+	return entry
+}
+
+// NewWireTapWriter returns a Net.WireTap writing the records to w as
+// WireTapEntry lines. The writes are serialized but not buffered, so w should
+// be fast, such as a bufio.Writer flushed on exit; the errors are logged.
+func NewWireTapWriter(w io.Writer) func(record *WireTapRecord) {
+	var lock sync.Mutex
+	encoder := json.NewEncoder(w)
+	return func(record *WireTapRecord) {
+		entry := NewWireTapEntry(record)
+		lock.Lock()
+		defer lock.Unlock()
+		if err := encoder.Encode(entry); err != nil {
+			Logger.Printf("wiretap: failed to write the record of request %d to %s: %v\n", entry.CorrelationID, entry.Broker, err)
+		}
+	}
+}
+
+// newWireTapRecord returns the WireTapRecord of the request req encoded in bufs,
+// nil when Net.WireTap is not set.
+func (b *Broker) newWireTapRecord(req *request, bufs net.Buffers, requestTime time.Time) *WireTapRecord {
+	if b.conf.Net.WireTap == nil {
+		return nil
+	}
+	buf := bufs[0]
+	if len(bufs) > 1 {
+		buf = bytes.Join(bufs, nil)
+	}
+	return &WireTapRecord{
+		Broker:        b,
+		APIKey:        req.body.key(),
+		APIVersion:    req.body.version(),
+		CorrelationID: req.correlationID,
+		ConnectionID:  b.connectionID(),
+		Request:       buf,
+		RequestTime:   requestTime,
+	}
+}
+
+// tap hands record over to Net.WireTap, once its response has been read or
+// the request failed.
+func (b *Broker) tap(record *WireTapRecord, response []byte, err error) {
+	if record == nil {
+		return
+	}
+	record.Response = response
+	record.Err = err
+	if response != nil || err != nil {
+		record.Latency = time.Since(record.RequestTime)
+	}
+	b.conf.Net.WireTap(record)
+}
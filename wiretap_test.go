@@ -0,0 +1,119 @@
+package sarama
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+func TestWireTap(t *testing.T) {
+	mb := NewMockBroker(t, 1)
+	defer mb.Close()
+	mb.SetHandlerByMap(map[string]MockResponse{
+		"MetadataRequest": NewMockMetadataResponse(t).SetBroker(mb.Addr(), mb.BrokerID()),
+	})
+
+	var (
+		lock    sync.Mutex
+		records []*WireTapRecord
+	)
+	conf := NewTestConfig()
+	conf.Version = V0_10_0_0
+	conf.ApiVersionsRequest = false
+	conf.Net.WireTap = func(record *WireTapRecord) {
+		lock.Lock()
+		defer lock.Unlock()
+		records = append(records, record)
+	}
+	broker := NewBroker(mb.Addr())
+	if err := broker.Open(conf); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.GetMetadata(&MetadataRequest{Version: 1}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := broker.Produce(&ProduceRequest{RequiredAcks: NoResponse}); err != nil {
+		t.Fatal(err)
+	}
+	connectionID := broker.ConnectionState().ConnectionID
+	safeClose(t, broker)
+
+	if !strings.HasPrefix(connectionID, mb.Addr()+"-") {
+		t.Errorf("expected the connection to be named after the broker and client addresses, got %q", connectionID)
+	}
+
+	lock.Lock()
+	defer lock.Unlock()
+	if len(records) != 2 {
+		t.Fatalf("expected 2 records, got %d", len(records))
+	}
+
+	metadata := records[0]
+	if metadata.Broker != broker || metadata.APIKey != 3 || metadata.APIVersion != 1 || metadata.ConnectionID != connectionID || metadata.Err != nil {
+		t.Errorf("unexpected metadata record %+v", metadata)
+	}
+	req, _, err := decodeRequest(bytes.NewReader(metadata.Request))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if req.correlationID != metadata.CorrelationID || req.body.key() != 3 {
+		t.Errorf("expected the encoded metadata request, got %+v", req)
+	}
+	if len(metadata.Response) < 8 || int(binary.BigEndian.Uint32(metadata.Response))+4 != len(metadata.Response) {
+		t.Fatalf("expected the encoded metadata response, got %v", metadata.Response)
+	}
+	if correlationID := int32(binary.BigEndian.Uint32(metadata.Response[4:])); correlationID != metadata.CorrelationID {
+		t.Errorf("expected the response to request %d, got %d", metadata.CorrelationID, correlationID)
+	}
+
+	produce := records[1]
+	if produce.APIKey != 0 || produce.CorrelationID != metadata.CorrelationID+1 || produce.ConnectionID != connectionID || produce.Response != nil || produce.Err != nil {
+		t.Errorf("expected a produce record without response, got %+v", produce)
+	}
+}
+
+func TestWireTapWriter(t *testing.T) {
+	var buf bytes.Buffer
+	tap := NewWireTapWriter(&buf)
+	requestTime := time.Unix(1600000000, 0)
+	tap(&WireTapRecord{
+		Broker:        NewBroker("localhost:9092"),
+		APIKey:        3,
+		APIVersion:    1,
+		CorrelationID: 7,
+		ConnectionID:  "localhost:9092-localhost:50000-0",
+		Request:       []byte{0, 0, 0, 1, 2},
+		Response:      []byte{0, 0, 0, 1, 3},
+		RequestTime:   requestTime,
+		Latency:       time.Millisecond,
+	})
+	tap(&WireTapRecord{APIKey: 0, CorrelationID: 8, Request: []byte{0}, Err: errors.New("broken pipe"), RequestTime: requestTime})
+
+	decoder := json.NewDecoder(&buf)
+	var entries []WireTapEntry
+	for decoder.More() {
+		var entry WireTapEntry
+		if err := decoder.Decode(&entry); err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, entry)
+	}
+	if len(entries) != 2 {
+		t.Fatalf("expected 2 entries, got %d", len(entries))
+	}
+
+	metadata := entries[0]
+	if metadata.Broker != "localhost:9092" || metadata.BrokerID != -1 || metadata.APIKey != 3 || metadata.APIVersion != 1 ||
+		metadata.CorrelationID != 7 || !metadata.Time.Equal(requestTime) || metadata.LatencyNs != int64(time.Millisecond) ||
+		!bytes.Equal(metadata.Request, []byte{0, 0, 0, 1, 2}) || !bytes.Equal(metadata.Response, []byte{0, 0, 0, 1, 3}) || metadata.Error != "" {
+		t.Errorf("unexpected metadata entry %+v", metadata)
+	}
+	if failed := entries[1]; failed.Broker != "" || failed.Response != nil || failed.Error != "broken pipe" {
+		t.Errorf("unexpected failed entry %+v", failed)
+	}
+}
@@ -6,45 +6,112 @@ import (
 	"github.com/klauspost/compress/zstd"
 )
 
+// ZstdEncoderParams select the shared zstd encoder compressing the batches,
+// one being created for each distinct set of params, see Producer.Zstd.
 type ZstdEncoderParams struct {
 	Level int
+	// WindowSize is the maximum back-reference distance of the encoder, a
+	// power of 2 between 1KiB and 512MiB, or 0 to derive it from the level.
+	WindowSize int
+	// Concurrency is the number of batches the encoder compresses at once, or
+	// 0 for GOMAXPROCS.
+	Concurrency int
+	// LowMemory trades allocations while compressing for a smaller encoder.
+	LowMemory bool
 }
+
+// ZstdDecoderParams select the shared zstd decoder decompressing the batches,
+// one being created for each distinct set of params, see Consumer.Zstd.
 type ZstdDecoderParams struct {
+	// MaxWindowSize is the largest window the decoder accepts, at least 1KiB,
+	// or 0 for the zstd library default of 512MiB.
+	MaxWindowSize uint64
+	// Concurrency is the number of batches the decoder decompresses at once,
+	// or 0 for the lower of 4 and GOMAXPROCS.
+	Concurrency int
+	// LowMemory trades allocations while decompressing for a smaller decoder.
+	LowMemory bool
+
+	// maxMemory is the most bytes a batch decompresses to, or 0 for no limit,
+	// see Consumer.Fetch.MaxDecodedBytes
+	maxMemory uint64
 }
 
 var zstdEncMap, zstdDecMap sync.Map
 
-func getEncoder(params ZstdEncoderParams) *zstd.Encoder {
+func (params ZstdEncoderParams) options() []zstd.EOption {
+	encoderLevel := zstd.SpeedDefault
+	if params.Level != CompressionLevelDefault {
+		encoderLevel = zstd.EncoderLevelFromZstd(params.Level)
+	}
+	opts := []zstd.EOption{
+		zstd.WithZeroFrames(true),
+		zstd.WithEncoderLevel(encoderLevel),
+		zstd.WithLowerEncoderMem(params.LowMemory),
+	}
+	if params.WindowSize != 0 {
+		opts = append(opts, zstd.WithWindowSize(params.WindowSize))
+	}
+	if params.Concurrency != 0 {
+		opts = append(opts, zstd.WithEncoderConcurrency(params.Concurrency))
+	}
+	return opts
+}
+
+func (params ZstdDecoderParams) options() []zstd.DOption {
+	opts := []zstd.DOption{zstd.WithDecoderLowmem(params.LowMemory)}
+	if params.MaxWindowSize != 0 {
+		opts = append(opts, zstd.WithDecoderMaxWindow(params.MaxWindowSize))
+	}
+	if params.Concurrency != 0 {
+		opts = append(opts, zstd.WithDecoderConcurrency(params.Concurrency))
+	}
+	if params.maxMemory != 0 {
+		opts = append(opts, zstd.WithDecoderMaxMemory(params.maxMemory))
+	}
+	return opts
+}
+
+func getEncoder(params ZstdEncoderParams) (*zstd.Encoder, error) {
 	if ret, ok := zstdEncMap.Load(params); ok {
-		return ret.(*zstd.Encoder)
+		return ret.(*zstd.Encoder), nil
 	}
 	// It's possible to race and create multiple new writers.
 	// Only one will survive GC after use.
-	encoderLevel := zstd.SpeedDefault
-	if params.Level != CompressionLevelDefault {
-		encoderLevel = zstd.EncoderLevelFromZstd(params.Level)
+	zstdEnc, err := zstd.NewWriter(nil, params.options()...)
+	if err != nil {
+		return nil, err
 	}
-	zstdEnc, _ := zstd.NewWriter(nil, zstd.WithZeroFrames(true),
-		zstd.WithEncoderLevel(encoderLevel))
 	zstdEncMap.Store(params, zstdEnc)
-	return zstdEnc
+	return zstdEnc, nil
 }
 
-func getDecoder(params ZstdDecoderParams) *zstd.Decoder {
+func getDecoder(params ZstdDecoderParams) (*zstd.Decoder, error) {
 	if ret, ok := zstdDecMap.Load(params); ok {
-		return ret.(*zstd.Decoder)
+		return ret.(*zstd.Decoder), nil
 	}
 	// It's possible to race and create multiple new readers.
 	// Only one will survive GC after use.
-	zstdDec, _ := zstd.NewReader(nil)
+	zstdDec, err := zstd.NewReader(nil, params.options()...)
+	if err != nil {
+		return nil, err
+	}
 	zstdDecMap.Store(params, zstdDec)
-	return zstdDec
+	return zstdDec, nil
 }
 
 func zstdDecompress(params ZstdDecoderParams, dst, src []byte) ([]byte, error) {
-	return getDecoder(params).DecodeAll(src, dst)
+	dec, err := getDecoder(params)
+	if err != nil {
+		return nil, err
+	}
+	return dec.DecodeAll(src, dst)
 }
 
 func zstdCompress(params ZstdEncoderParams, dst, src []byte) ([]byte, error) {
-	return getEncoder(params).EncodeAll(src, dst), nil
+	enc, err := getEncoder(params)
+	if err != nil {
+		return nil, err
+	}
+	return enc.EncodeAll(src, dst), nil
 }
@@ -0,0 +1,42 @@
+package sarama
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestZstdTunedRoundTrip(t *testing.T) {
+	data := bytes.Repeat([]byte("sarama zstd "), 1000)
+	encoder := ZstdEncoderParams{Level: 3, WindowSize: 1 << 10, Concurrency: 1, LowMemory: true}
+	decoder := ZstdDecoderParams{MaxWindowSize: 64 << 10, Concurrency: 1, LowMemory: true}
+
+	compressed, err := zstdCompress(encoder, nil, data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	decompressed, err := zstdDecompress(decoder, nil, compressed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(decompressed, data) {
+		t.Error("zstd round trip altered the data")
+	}
+
+	// a window larger than the decoder accepts is rejected
+	compressed, err = zstdCompress(ZstdEncoderParams{WindowSize: 1 << 20}, nil, bytes.Repeat(data, 100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := zstdDecompress(decoder, nil, compressed); err == nil {
+		t.Error("expected the decoder to reject the larger window")
+	}
+}
+
+func TestZstdInvalidParams(t *testing.T) {
+	if _, err := zstdCompress(ZstdEncoderParams{WindowSize: 3 << 10}, nil, []byte("value")); err == nil {
+		t.Error("expected an error for a window size which isn't a power of 2")
+	}
+	if _, err := zstdDecompress(ZstdDecoderParams{MaxWindowSize: 512}, nil, nil); err == nil {
+		t.Error("expected an error for a max window size below 1KiB")
+	}
+}